@@ -0,0 +1,185 @@
+package godocs
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	database "github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/seed"
+	"github.com/labstack/echo/v4"
+)
+
+// updateGolden rewrites the golden files from the current responses: go test . -run Golden -update
+var updateGolden = flag.Bool("update", false, "rewrite testdata/golden files from the current responses")
+
+// goldenVolatileKeys hold values that change from run to run, such as file modification, job and word count times.
+// Their values are replaced before comparing, so only their presence is checked.
+var goldenVolatileKeys = map[string]bool{
+	"modDate":         true,
+	"createdAt":       true,
+	"updatedAt":       true,
+	"startedAt":       true,
+	"completedAt":     true,
+	"lastCalculation": true,
+	"updated":         true,
+}
+
+// goldenServer is a test server seeded with the same documents and jobs on every run
+type goldenServer struct {
+	echo     *echo.Echo
+	replacer *strings.Replacer // swaps the temporary paths for stable placeholders
+}
+
+// newGoldenServer seeds a fixed set of documents with files under a temporary document root
+func newGoldenServer(t *testing.T) *goldenServer {
+	t.Helper()
+	if os.Getenv("TEST_DATABASE") != "" {
+		t.Skip("Golden files are recorded against the in-memory repository")
+	}
+	// Job times and IDs come from a fixed clock and seeded IDs, so they are the same on every run
+	t.Cleanup(database.SetClock(database.NewFixedClock(time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC), time.Second)))
+	t.Cleanup(database.SetIDGenerator(database.NewSeededIDs(42)))
+	e, serverHandler, _ := setupTestServer(t)
+	documentPath := filepath.Join(t.TempDir(), "documents")
+	ingressPath := filepath.Join(t.TempDir(), "ingress")
+	serverHandler.ServerConfig.DocumentPath = documentPath
+	serverHandler.ServerConfig.IngressPath = ingressPath
+	serverHandler.ServerConfig.TesseractPath = ""
+	serverHandler.ServerConfig.TesseractServiceURL = ""
+	serverHandler.ServerConfig.PDFServiceURL = ""
+
+	_, err := seed.Generate(serverHandler.DB, seed.Options{
+		Count:   12,
+		Root:    documentPath,
+		Folders: 3,
+		Seed:    42,
+		Start:   time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC),
+		Files:   true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to seed documents: %v", err)
+	}
+
+	ingestion, err := serverHandler.DB.CreateJob(database.JobTypeIngestion, "Starting document ingestion")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	if err := serverHandler.DB.CompleteJob(ingestion.ID, `{"filesProcessed":12}`); err != nil {
+		t.Fatalf("Failed to complete job: %v", err)
+	}
+	cleanup, err := serverHandler.DB.CreateJob(database.JobTypeCleanup, "Starting database cleanup")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	if err := serverHandler.DB.UpdateJobProgress(cleanup.ID, 40, "Checking files"); err != nil {
+		t.Fatalf("Failed to update job: %v", err)
+	}
+
+	return &goldenServer{
+		echo:     e,
+		replacer: strings.NewReplacer(documentPath, "$DOCUMENT_PATH", ingressPath, "$INGRESS_PATH"),
+	}
+}
+
+// check requests target and compares the normalised response with testdata/golden/<name>.json.
+// Values of the extra volatile keys are replaced as well as those in goldenVolatileKeys.
+func (g *goldenServer) check(t *testing.T, name, target string, volatile ...string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	g.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: expected 200, got %d: %s", target, rec.Code, rec.Body.String())
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("GET %s: invalid JSON: %v", target, err)
+	}
+	keys := make(map[string]bool, len(goldenVolatileKeys)+len(volatile))
+	for key := range goldenVolatileKeys {
+		keys[key] = true
+	}
+	for _, key := range volatile {
+		keys[key] = true
+	}
+	got, err := json.MarshalIndent(g.normalise(body, keys), "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to encode %s: %v", name, err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", "golden", name+".json")
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create golden folder: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s (run go test . -run Golden -update to create it): %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("GET %s no longer matches %s; the WASM client may not read it.\nIf the change is intended, run go test . -run Golden -update and review the diff.\nGot:\n%s", target, path, got)
+	}
+}
+
+// normalise replaces volatile values and temporary paths so a response is the same on every run
+func (g *goldenServer) normalise(value interface{}, volatile map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if volatile[key] && field != nil && field != "" {
+				v[key] = "$VOLATILE"
+				continue
+			}
+			v[key] = g.normalise(field, volatile)
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = g.normalise(v[i], volatile)
+		}
+		return v
+	case string:
+		return filepath.ToSlash(g.replacer.Replace(v))
+	default:
+		return v
+	}
+}
+
+// TestGoldenResponses compares the read endpoints the web UI depends on with their golden files
+func TestGoldenResponses(t *testing.T) {
+	g := newGoldenServer(t)
+
+	t.Run("filesystem", func(t *testing.T) {
+		g.check(t, "filesystem", "/api/documents/filesystem")
+	})
+	t.Run("search", func(t *testing.T) {
+		g.check(t, "search", "/api/search?term=invoice")
+	})
+	t.Run("search suggestions", func(t *testing.T) {
+		g.check(t, "search_suggestions", "/api/search?term=invoise")
+	})
+	t.Run("wordcloud", func(t *testing.T) {
+		g.check(t, "wordcloud", "/api/wordcloud?limit=20")
+	})
+	t.Run("about", func(t *testing.T) {
+		// The version changes with every release and the database settings with the environment
+		g.check(t, "about", "/api/about", "version", "build", "databaseType", "databaseHost", "databasePort", "databaseName")
+	})
+	t.Run("jobs", func(t *testing.T) {
+		g.check(t, "jobs", "/api/jobs")
+	})
+}
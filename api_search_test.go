@@ -1,4 +1,4 @@
-package main
+package godocs
 
 import (
 	"encoding/json"
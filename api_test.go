@@ -1,4 +1,4 @@
-package main
+package godocs
 
 import (
 	"bytes"
@@ -24,14 +24,19 @@ func setupTestServer(t *testing.T) (*echo.Echo, *engine.ServerHandler, func()) {
 	serverConfig, logger := config.SetupServer()
 	injectGlobals(logger)
 
-	// Use ephemeral PostgreSQL for tests
-	ephemeralDB, err := database.SetupEphemeralPostgresDatabase()
-	if err != nil {
-		t.Fatalf("Failed to setup ephemeral database: %v", err)
+	// Handler tests run against the in-memory repository; set TEST_DATABASE=postgres to use ephemeral PostgreSQL
+	var testDB database.Repository
+	if os.Getenv("TEST_DATABASE") == "postgres" {
+		ephemeralDB, err := database.SetupEphemeralPostgresDatabase()
+		if err != nil {
+			t.Fatalf("Failed to setup ephemeral database: %v", err)
+		}
+		testDB = ephemeralDB
+	} else {
+		testDB = database.NewMemoryDB()
 	}
-	testDB := database.Repository(ephemeralDB)
 	t.Cleanup(func() {
-		ephemeralDB.Close()
+		testDB.Close()
 	})
 
 	database.WriteConfigToDB(serverConfig, testDB)
@@ -46,23 +51,121 @@ func setupTestServer(t *testing.T) (*echo.Echo, *engine.ServerHandler, func()) {
 
 	// Setup routes
 	e.Use(middleware.CORSWithConfig(middleware.DefaultCORSConfig))
+	e.Use(serverHandler.RequireLogin())
+	e.Use(serverHandler.ReadOnlyGuard())
 	e.GET("/api/documents/latest", serverHandler.GetLatestDocuments)
 	e.GET("/api/documents/filesystem", serverHandler.GetDocumentFileSystem)
+	e.GET("/api/documents/export.ndjson", serverHandler.ExportDocumentsNDJSON)
+	e.GET("/api/documents/popular", serverHandler.GetPopularDocuments)
 	e.GET("/api/document/:id", serverHandler.GetDocument)
+	e.GET("/api/document/:id/text", serverHandler.GetDocumentText)
+	e.GET("/api/document/:id/timeline", serverHandler.GetDocumentTimeline)
+	e.GET("/api/document/:id/search", serverHandler.SearchDocumentText)
+	e.GET("/api/document/:id/coversheet.pdf", serverHandler.GetCoverSheet)
+	e.GET("/api/document/:id/qr.png", serverHandler.GetDocumentQR)
+	e.GET("/api/document/:id/signed-url", serverHandler.GetSignedDocumentURL)
+	e.POST("/api/document/:id/lock", serverHandler.LockDocument)
+	e.GET("/api/document/:id/lock", serverHandler.GetDocumentLock)
+	e.DELETE("/api/document/:id/lock", serverHandler.UnlockDocument)
+	e.POST("/api/document/:id/archive", serverHandler.ArchiveDocument)
+	e.GET("/api/document/:id/archive", serverHandler.GetDocumentArchive)
+	e.DELETE("/api/document/:id/archive", serverHandler.RestoreDocument)
+	e.GET("/api/archive", serverHandler.GetArchivedDocuments)
+	e.POST("/api/document/:id/redact", serverHandler.RedactDocument)
+	e.GET("/api/document/:id/redactions", serverHandler.GetDocumentRedactions)
+	e.GET("/api/document/:id/spreadsheet", serverHandler.GetSpreadsheetDetails)
+	e.GET("/api/document/:id/rotation", serverHandler.GetDocumentRotations)
+	e.GET("/api/document/:id/blank-pages", serverHandler.GetDocumentBlankPages)
+	e.GET("/api/document/:id/preview", serverHandler.GetSpreadsheetPreview)
+	e.GET("/api/document/:id/tags", serverHandler.GetDocumentTags)
+	e.POST("/api/document/:id/tags", serverHandler.AddDocumentTag)
+	e.DELETE("/api/document/:id/tags/:tag", serverHandler.RemoveDocumentTag)
+	e.POST("/api/holds", serverHandler.PlaceLegalHold)
+	e.GET("/api/holds", serverHandler.GetLegalHolds)
+	e.GET("/api/holds/events", serverHandler.GetLegalHoldEvents)
+	e.DELETE("/api/holds/:id", serverHandler.RemoveLegalHold)
 	e.DELETE("/api/document/*", serverHandler.DeleteFile)
 	e.PATCH("/api/document/move/*", serverHandler.MoveDocuments)
 	e.POST("/api/document/upload", serverHandler.UploadDocuments)
+	e.POST("/api/document/rescan", serverHandler.RescanDocument)
+	e.GET("/api/folders", serverHandler.GetFolders)
+	e.PATCH("/api/folders/:id", serverHandler.UpdateFolderStyle)
 	e.GET("/api/folder/:folder", serverHandler.GetFolder)
+	e.GET("/api/folder/:folder/download", serverHandler.DownloadFolder)
 	e.POST("/api/folder/*", serverHandler.CreateFolder)
 	e.GET("/api/search", serverHandler.SearchDocuments)
+	e.GET("/api/search/history", serverHandler.GetSearchHistory)
+	e.GET("/api/search/analytics", serverHandler.GetSearchAnalytics)
+	e.GET("/api/search/suggest", serverHandler.SuggestSearch)
+	e.GET("/api/tags", serverHandler.ListTags)
+	e.GET("/api/tags/:tag/documents", serverHandler.GetDocumentsByTag)
+	e.GET("/api/collections", serverHandler.ListCollections)
+	e.POST("/api/collections", serverHandler.CreateCollection)
+	e.GET("/api/collections/:id", serverHandler.GetCollection)
+	e.DELETE("/api/collections/:id", serverHandler.DeleteCollection)
+	e.GET("/api/shared/:token", serverHandler.GetSharedCollection)
+	e.GET("/api/smartfolders", serverHandler.ListSmartFolders)
+	e.POST("/api/smartfolders", serverHandler.CreateSmartFolder)
+	e.GET("/api/smartfolders/:id", serverHandler.GetSmartFolder)
+	e.DELETE("/api/smartfolders/:id", serverHandler.DeleteSmartFolder)
+	e.GET("/api/extraction/templates", serverHandler.ListExtractionTemplates)
+	e.POST("/api/extraction/templates", serverHandler.CreateExtractionTemplate)
+	e.DELETE("/api/extraction/templates/:id", serverHandler.DeleteExtractionTemplate)
+	e.POST("/api/extraction/preview", serverHandler.PreviewExtraction)
+	e.GET("/api/documents/:id/fields", serverHandler.GetDocumentFields)
+	e.GET("/api/extractions/export", serverHandler.ExportExtractions)
 	e.GET("/api/about", serverHandler.GetAboutInfo)
+	e.GET("/api/about/ocr", serverHandler.GetOCRLanguages)
+	e.GET("/api/quota", serverHandler.GetQuota)
+	e.GET("/api/schedules", serverHandler.GetSchedules)
+	e.PUT("/api/schedules", serverHandler.UpdateSchedules)
+	e.GET("/api/setup", serverHandler.GetSetup)
+	e.POST("/api/setup", serverHandler.SaveSetup)
+	e.GET("/api/health", serverHandler.GetHealth)
+	e.GET("/api/read-only", serverHandler.GetReadOnly)
+	e.PUT("/api/read-only", serverHandler.SetReadOnly)
+	e.GET("/api/admin/slow-queries", serverHandler.GetSlowQueries)
+	e.GET("/api/admin/index-usage", serverHandler.GetIndexUsage)
+	e.GET("/api/admin/client-errors", serverHandler.GetClientErrors)
+	e.POST("/api/admin/export", serverHandler.ExportArchive)
+	e.GET("/api/admin/export/:jobId/download", serverHandler.DownloadExport)
+	e.POST("/api/client-errors", serverHandler.ReportClientError)
+	e.POST("/api/auth/login", serverHandler.Login)
+	e.POST("/api/auth/logout", serverHandler.Logout)
+	e.GET("/api/auth/status", serverHandler.GetAuthStatus)
+	e.GET("/api/users", serverHandler.ListUsers)
+	e.POST("/api/users", serverHandler.CreateUser)
+	e.DELETE("/api/users/:id", serverHandler.DeleteUser)
+	e.PUT("/api/users/:id/password", serverHandler.ChangePassword)
+	e.PUT("/api/users/:id/role", serverHandler.SetUserRole)
+	e.GET("/api/folder-permissions", serverHandler.ListFolderPermissions)
+	e.PUT("/api/folder-permissions", serverHandler.SetFolderPermission)
+	e.DELETE("/api/folder-permissions", serverHandler.DeleteFolderPermission)
 	e.POST("/api/ingest", serverHandler.RunIngestNow)
+	e.GET("/api/ingest/rejections", serverHandler.GetIngestRejections)
 	e.POST("/api/clean", serverHandler.CleanDatabase)
+	e.POST("/api/documents/urls/repair", serverHandler.RepairDocumentURLs)
+	e.POST("/api/documents/rehash", serverHandler.RehashDocuments)
+	e.POST("/api/documents/optimise", serverHandler.OptimiseDocuments)
+	e.GET("/api/duplicates", serverHandler.GetDuplicates)
+	e.POST("/api/duplicates/scan", serverHandler.ScanDuplicates)
 
 	// Word cloud routes
 	e.GET("/api/wordcloud", serverHandler.GetWordCloud)
 	e.POST("/api/wordcloud/recalculate", serverHandler.RecalculateWordCloud)
 
+	// Job routes
+	e.GET("/api/jobs", serverHandler.GetRecentJobs)
+	e.GET("/api/jobs/active", serverHandler.GetActiveJobs)
+	e.GET("/api/jobs/:id", serverHandler.GetJob)
+	e.GET("/api/activity", serverHandler.GetActivity)
+
+	// Statistics routes
+	e.GET("/api/stats/timeseries", serverHandler.GetStatsTimeseries)
+
+	// Integration routes
+	e.POST("/api/integrations/dropzone", serverHandler.ReceiveDropzone)
+
 	cleanup := func() {
 		testDB.Close()
 	}
@@ -302,13 +405,23 @@ func TestGetDocument(t *testing.T) {
 	e, _, cleanup := setupTestServer(t)
 	defer cleanup()
 
-	t.Run("Get document - non-existent ID", func(t *testing.T) {
+	t.Run("Get document - malformed ID", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/api/document/nonexistent123", nil)
 		rec := httptest.NewRecorder()
 		e.ServeHTTP(rec, req)
 
-		if rec.Code != http.StatusNotFound && rec.Code != http.StatusInternalServerError {
-			t.Errorf("Expected status 404 or 500, got %d", rec.Code)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for a malformed ID, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Get document - non-existent ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/document/"+database.MakeULID().String(), nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", rec.Code)
 		}
 	})
 
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+)
+
+// Logger is global since we will need it everywhere
+var Logger *slog.Logger = slog.Default()
+
+// Key prefixes for the cached API payloads
+const (
+	KeyFileSystem = "filesystem"
+	KeyLatest     = "latest:"
+	KeyWordCloud  = "wordcloud:"
+	KeyAbout      = "about"
+)
+
+// DocumentKeys are the prefixes whose payloads change whenever a document is added, moved or removed
+var DocumentKeys = []string{KeyFileSystem, KeyLatest, KeyWordCloud}
+
+// Cache stores serialised API responses keyed by string
+type Cache interface {
+	// Get returns the cached value and whether it was found
+	Get(ctx context.Context, key string) ([]byte, bool)
+	// Set stores a value, replacing any existing entry
+	Set(ctx context.Context, key string, value []byte)
+	// DeletePrefix removes every entry whose key starts with one of the prefixes
+	DeletePrefix(ctx context.Context, prefixes ...string)
+	// Close releases any connections held by the cache
+	Close() error
+}
+
+// New creates the cache selected by the server config.
+// Redis is used when CacheType is "redis" and reachable, falling back to an in-memory LRU otherwise.
+// CacheType "none" disables caching and returns nil.
+func New(serverConfig config.ServerConfig) Cache {
+	ttl := time.Duration(serverConfig.CacheTTL) * time.Second
+	switch serverConfig.CacheType {
+	case "none":
+		Logger.Info("API response cache disabled")
+		return nil
+	case "redis":
+		c, err := NewRedis(serverConfig.RedisURL, ttl)
+		if err == nil {
+			Logger.Info("Using redis API response cache", "ttl", ttl)
+			return c
+		}
+		Logger.Warn("Unable to connect to redis cache, falling back to in-memory cache", "error", err)
+	}
+	Logger.Info("Using in-memory API response cache", "size", serverConfig.CacheSize, "ttl", ttl)
+	return NewLRU(serverConfig.CacheSize, ttl)
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LRU is an in-memory least recently used cache with an optional time to live
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List
+	entries  map[string]*list.Element
+	now      func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRU creates an in-memory cache holding at most capacity entries.
+// A ttl of zero keeps entries until they are evicted or invalidated.
+func NewLRU(capacity int, ttl time.Duration) *LRU {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &LRU{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// Get returns the cached value if present and not expired
+func (l *LRU) Get(ctx context.Context, key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	element, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*lruEntry)
+	if !entry.expires.IsZero() && l.now().After(entry.expires) {
+		l.order.Remove(element)
+		delete(l.entries, key)
+		return nil, false
+	}
+	l.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set stores a value, evicting the least recently used entry when full
+func (l *LRU) Set(ctx context.Context, key string, value []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var expires time.Time
+	if l.ttl > 0 {
+		expires = l.now().Add(l.ttl)
+	}
+	if element, ok := l.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expires = expires
+		l.order.MoveToFront(element)
+		return
+	}
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// DeletePrefix removes all entries whose key starts with any of the prefixes
+func (l *LRU) DeletePrefix(ctx context.Context, prefixes ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, element := range l.entries {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				l.order.Remove(element)
+				delete(l.entries, key)
+				break
+			}
+		}
+	}
+}
+
+// Len returns the number of entries currently held
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+// Close is a no-op for the in-memory cache
+func (l *LRU) Close() error {
+	return nil
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	// A full cache of two entries where "a" was read most recently
+	ctx := context.Background()
+	c := NewLRU(2, 0)
+	c.Set(ctx, "a", []byte("1"))
+	c.Set(ctx, "b", []byte("2"))
+	c.Get(ctx, "a")
+
+	// A third entry is added
+	c.Set(ctx, "c", []byte("3"))
+
+	// "b" is evicted and the others remain
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(ctx, key); !ok {
+			t.Errorf("Expected %s to be cached", key)
+		}
+	}
+}
+
+func TestLRUExpiresEntries(t *testing.T) {
+	// An entry with a one minute ttl
+	ctx := context.Background()
+	c := NewLRU(10, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	c.Set(ctx, "about", []byte("{}"))
+
+	// The clock moves past the ttl
+	now = now.Add(2 * time.Minute)
+
+	// The entry is gone
+	if _, ok := c.Get(ctx, "about"); ok {
+		t.Error("Expected expired entry to be a miss")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected expired entry to be removed, have %d entries", c.Len())
+	}
+}
+
+func TestLRUDeletePrefix(t *testing.T) {
+	// Document-derived and unrelated entries
+	ctx := context.Background()
+	c := NewLRU(10, 0)
+	for _, key := range []string{KeyFileSystem, KeyLatest + "1", KeyLatest + "2", KeyWordCloud + "100", KeyAbout} {
+		c.Set(ctx, key, []byte("x"))
+	}
+
+	// Document keys are invalidated
+	c.DeletePrefix(ctx, DocumentKeys...)
+
+	// Only the about payload survives
+	if c.Len() != 1 {
+		t.Errorf("Expected 1 entry left, got %d", c.Len())
+	}
+	if _, ok := c.Get(ctx, KeyAbout); !ok {
+		t.Error("Expected about payload to survive document invalidation")
+	}
+}
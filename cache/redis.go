@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix namespaces godocs keys so a shared redis instance can be used
+const redisKeyPrefix = "godocs:"
+
+// Redis is a cache backed by a redis server, shared between godocs instances
+type Redis struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedis connects to the redis server at url (e.g. redis://localhost:6379/0)
+func NewRedis(url string, ttl time.Duration) (*Redis, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(options)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &Redis{client: client, ttl: ttl}, nil
+}
+
+// Get returns the cached value, treating any redis error as a miss
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, err := r.client.Get(ctx, redisKeyPrefix+key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			Logger.Warn("Redis cache get failed", "key", key, "error", err)
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+// Set stores a value with the configured time to live
+func (r *Redis) Set(ctx context.Context, key string, value []byte) {
+	if err := r.client.Set(ctx, redisKeyPrefix+key, value, r.ttl).Err(); err != nil {
+		Logger.Warn("Redis cache set failed", "key", key, "error", err)
+	}
+}
+
+// DeletePrefix scans for and deletes all keys starting with any of the prefixes
+func (r *Redis) DeletePrefix(ctx context.Context, prefixes ...string) {
+	for _, prefix := range prefixes {
+		iter := r.client.Scan(ctx, 0, redisKeyPrefix+prefix+"*", 100).Iterator()
+		for iter.Next(ctx) {
+			if err := r.client.Del(ctx, iter.Val()).Err(); err != nil {
+				Logger.Warn("Redis cache delete failed", "key", iter.Val(), "error", err)
+			}
+		}
+		if err := iter.Err(); err != nil {
+			Logger.Warn("Redis cache scan failed", "prefix", prefix, "error", err)
+		}
+	}
+}
+
+// Close closes the redis connection pool
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,348 @@
+// Package client is a Go client for the godocs REST API.
+//
+//	c := client.New("http://localhost:8000")
+//	page, err := c.Latest(ctx, 1)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client talks to a godocs server
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	retryDelay time.Duration
+	token      string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client used for requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRetries sets how many times idempotent requests are retried and the initial backoff delay
+func WithRetries(maxRetries int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryDelay = delay
+	}
+}
+
+// WithToken sends a session token from POST /api/auth/login with every request, for servers with WEB_UI_AUTH on
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// New creates a client for the server at baseURL (e.g. http://localhost:8000)
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		maxRetries: 3,
+		retryDelay: 500 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is returned when the server responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("godocs API error %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is an API 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Latest returns a page (starting at 1) of the newest documents
+func (c *Client) Latest(ctx context.Context, page int) (*DocumentPage, error) {
+	var result DocumentPage
+	err := c.getJSON(ctx, fmt.Sprintf("/api/documents/latest?page=%d", page), &result)
+	return &result, err
+}
+
+// LatestAfter returns up to limit of the newest documents following cursor (empty for the newest).
+// Unlike Latest it stays fast deep into large libraries; continue with NextCursor while HasNext is true.
+// Page, TotalCount and TotalPages are not filled in this mode.
+func (c *Client) LatestAfter(ctx context.Context, cursor string, limit int) (*DocumentPage, error) {
+	var result DocumentPage
+	err := c.getJSON(ctx, fmt.Sprintf("/api/documents/latest?cursor=%s&limit=%d", url.QueryEscape(cursor), limit), &result)
+	return &result, err
+}
+
+// GetDocument fetches a document by ULID
+func (c *Client) GetDocument(ctx context.Context, id string) (*Document, error) {
+	var result Document
+	err := c.getJSON(ctx, "/api/document/"+url.PathEscape(id), &result)
+	return &result, err
+}
+
+// DocumentText fetches the extracted full text of a document
+func (c *Client) DocumentText(ctx context.Context, id string) (string, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/document/"+url.PathEscape(id)+"/text", nil, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	text, err := io.ReadAll(resp.Body)
+	return string(text), err
+}
+
+// DocumentTextPage fetches up to limit characters of a document's text starting at offset. Continue from
+// NextOffset until it is 0 to read a large OCR output a piece at a time.
+func (c *Client) DocumentTextPage(ctx context.Context, id string, offset, limit int) (*TextPage, error) {
+	var result TextPage
+	err := c.getJSON(ctx, fmt.Sprintf("/api/document/%s/text?offset=%d&limit=%d", url.PathEscape(id), offset, limit), &result)
+	return &result, err
+}
+
+// Folder lists the documents in a folder
+func (c *Client) Folder(ctx context.Context, folder string) ([]Document, error) {
+	var result []Document
+	err := c.getJSON(ctx, "/api/folder/"+url.PathEscape(folder), &result)
+	return result, err
+}
+
+// FolderTree lists the documents in a folder and all of its subfolders
+func (c *Client) FolderTree(ctx context.Context, folder string) ([]Document, error) {
+	var result []Document
+	err := c.getJSON(ctx, "/api/folder/"+url.PathEscape(folder)+"?recursive=true", &result)
+	return result, err
+}
+
+// FileSystem returns the complete document tree
+func (c *Client) FileSystem(ctx context.Context) (*FileSystem, error) {
+	var result FileSystem
+	err := c.getJSON(ctx, "/api/documents/filesystem", &result)
+	return &result, err
+}
+
+// Search runs a full-text search and returns the matching documents (an empty slice when nothing matches)
+func (c *Client) Search(ctx context.Context, term string) ([]FileNode, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/search?term="+url.QueryEscape(term), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return []FileNode{}, nil
+	}
+	var result FileSystem
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding search results: %w", err)
+	}
+	documents := make([]FileNode, 0, len(result.FileSystem))
+	for _, node := range result.FileSystem {
+		if node.ID != "SearchResults" { // synthetic root node
+			documents = append(documents, node)
+		}
+	}
+	return documents, nil
+}
+
+// Upload sends a file to be ingested, optionally under a folder relative to the ingress root.
+// It returns the path the server stored the upload at.
+func (c *Client) Upload(ctx context.Context, filename string, content io.Reader, folder string) (string, error) {
+	if folder != "" && !strings.HasSuffix(folder, "/") {
+		folder += "/"
+	}
+	return c.upload(ctx, filename, content, "path", folder)
+}
+
+// UploadToFolder stores a file directly in a folder relative to the document root, skipping the ingress folder.
+// It returns the path of the stored document.
+func (c *Client) UploadToFolder(ctx context.Context, filename string, content io.Reader, folder string) (string, error) {
+	return c.upload(ctx, filename, content, "folder", folder)
+}
+
+// upload posts a file with one extra form field naming where it should go
+func (c *Client) upload(ctx context.Context, filename string, content io.Reader, field string, value string) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField(field, value); err != nil {
+		return "", err
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/api/document/upload", body.Bytes(), writer.FormDataContentType())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var path string
+	if err := json.NewDecoder(resp.Body).Decode(&path); err != nil {
+		return "", fmt.Errorf("decoding upload response: %w", err)
+	}
+	return path, nil
+}
+
+// Download writes the document file to w
+func (c *Client) Download(ctx context.Context, id string, w io.Writer) error {
+	resp, err := c.do(ctx, http.MethodGet, "/document/view/"+url.PathEscape(id), nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// StartIngest triggers ingestion of the ingress folder and returns the job ID
+func (c *Client) StartIngest(ctx context.Context) (string, error) {
+	return c.startJob(ctx, "/api/ingest")
+}
+
+// StartClean triggers a database cleanup and returns the job ID
+func (c *Client) StartClean(ctx context.Context, dryRun bool) (string, error) {
+	return c.startJob(ctx, fmt.Sprintf("/api/clean?dryRun=%t", dryRun))
+}
+
+// Job fetches the current state of a job
+func (c *Client) Job(ctx context.Context, id string) (*Job, error) {
+	var result Job
+	err := c.getJSON(ctx, "/api/jobs/"+url.PathEscape(id), &result)
+	return &result, err
+}
+
+// WaitForJob polls a job every interval until it finishes or ctx is done
+func (c *Client) WaitForJob(ctx context.Context, id string, interval time.Duration) (*Job, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := c.Job(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Finished() {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// startJob posts to an endpoint that responds with a jobId
+func (c *Client) startJob(ctx context.Context, path string) (string, error) {
+	resp, err := c.do(ctx, http.MethodPost, path, nil, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		JobID string `json:"jobId"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decoding job response: %w", err)
+	}
+	return result.JobID, nil
+}
+
+// getJSON performs a GET and decodes the JSON response into out
+func (c *Client) getJSON(ctx context.Context, path string, out interface{}) error {
+	resp, err := c.do(ctx, http.MethodGet, path, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return nil
+}
+
+// do sends a request and returns the response for 2xx statuses.
+// GET requests are retried with exponential backoff on network errors, 429 and 5xx responses.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, contentType string) (*http.Response, error) {
+	attempts := 1
+	if method == http.MethodGet {
+		attempts += c.maxRetries
+	}
+	delay := c.retryDelay
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		lastErr = readAPIError(resp)
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return nil, lastErr
+		}
+	}
+	return nil, lastErr
+}
+
+// readAPIError builds an APIError from an error response, using its "error" field when present
+func readAPIError(resp *http.Response) error {
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	message := strings.TrimSpace(string(data))
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		message = body.Error
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: message}
+}
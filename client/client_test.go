@@ -0,0 +1,204 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return New(server.URL, WithRetries(3, time.Millisecond))
+}
+
+func TestLatestDecodesPage(t *testing.T) {
+	// A server returning one page of documents
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/documents/latest" || r.URL.Query().Get("page") != "2" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"documents":[{"Name":"invoice.pdf","ULID":"01HXYZ","URL":"/document/view/01HXYZ"}],"page":2,"totalCount":21,"hasPrevious":true}`))
+	}))
+
+	page, err := c.Latest(context.Background(), 2)
+
+	// The response is decoded into typed fields
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if page.Page != 2 || page.TotalCount != 21 || !page.HasPrevious || len(page.Documents) != 1 {
+		t.Fatalf("Unexpected page: %+v", page)
+	}
+	if page.Documents[0].Name != "invoice.pdf" || page.Documents[0].ULID != "01HXYZ" {
+		t.Errorf("Unexpected document: %+v", page.Documents[0])
+	}
+}
+
+func TestGetRetriesServerErrors(t *testing.T) {
+	// A server that fails twice before succeeding
+	var calls int32
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"ULID":"01HXYZ"}`))
+	}))
+
+	doc, err := c.GetDocument(context.Background(), "01HXYZ")
+
+	// The request succeeds on the third attempt
+	if err != nil {
+		t.Fatalf("GetDocument failed: %v", err)
+	}
+	if doc.ULID != "01HXYZ" || atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("Expected success after 3 calls, got %d calls and %+v", calls, doc)
+	}
+}
+
+func TestClientErrorsAreNotRetried(t *testing.T) {
+	// A server that reports a missing document
+	var calls int32
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"Document not found"}`))
+	}))
+
+	_, err := c.GetDocument(context.Background(), "missing")
+
+	// A single request is made and the API message is surfaced
+	if !IsNotFound(err) {
+		t.Fatalf("Expected not found error, got %v", err)
+	}
+	if err.(*APIError).Message != "Document not found" || calls != 1 {
+		t.Errorf("Unexpected error %v after %d calls", err, calls)
+	}
+}
+
+func TestSearchSkipsRootNode(t *testing.T) {
+	// A server returning search results and one with no matches
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("term") == "none" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(FileSystem{FileSystem: []FileNode{
+			{ID: "SearchResults", IsDir: true},
+			{ID: "01HXYZ", Name: "invoice.pdf", ParentID: "SearchResults"},
+		}})
+	}))
+
+	results, err := c.Search(context.Background(), "invoice")
+	empty, emptyErr := c.Search(context.Background(), "none")
+
+	// Only documents are returned
+	if err != nil || len(results) != 1 || results[0].Name != "invoice.pdf" {
+		t.Errorf("Unexpected results %+v, %v", results, err)
+	}
+	if emptyErr != nil || len(empty) != 0 {
+		t.Errorf("Expected no results, got %+v, %v", empty, emptyErr)
+	}
+}
+
+func TestUploadSendsMultipartForm(t *testing.T) {
+	// A server accepting uploads
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("Missing file: %v", err)
+		}
+		content, _ := io.ReadAll(file)
+		if header.Filename != "scan.pdf" || string(content) != "%PDF" || r.FormValue("path") != "bills/" {
+			t.Errorf("Unexpected upload %q %q path=%q", header.Filename, content, r.FormValue("path"))
+		}
+		json.NewEncoder(w).Encode("/ingress/bills/scan.pdf")
+	}))
+
+	// Uploading into a folder
+	path, err := c.Upload(context.Background(), "scan.pdf", bytes.NewBufferString("%PDF"), "bills")
+
+	// The stored path is returned
+	if err != nil || path != "/ingress/bills/scan.pdf" {
+		t.Errorf("Unexpected upload result %q, %v", path, err)
+	}
+}
+
+func TestUploadToFolderSendsFolderField(t *testing.T) {
+	// A server accepting uploads
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("folder") != "bills/2024" || r.FormValue("path") != "" {
+			t.Errorf("Unexpected fields folder=%q path=%q", r.FormValue("folder"), r.FormValue("path"))
+		}
+		json.NewEncoder(w).Encode("/documents/bills/2024/scan.pdf")
+	}))
+
+	// Uploading straight into a document folder
+	path, err := c.UploadToFolder(context.Background(), "scan.pdf", bytes.NewBufferString("%PDF"), "bills/2024")
+
+	// The stored document path is returned
+	if err != nil || path != "/documents/bills/2024/scan.pdf" {
+		t.Errorf("Unexpected upload result %q, %v", path, err)
+	}
+}
+
+func TestWaitForJobPollsUntilFinished(t *testing.T) {
+	// An ingest job that completes on the third poll
+	var polls int32
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/ingest":
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"message":"started","jobId":"job-1"}`))
+		case "/api/jobs/job-1":
+			status := JobStatusRunning
+			if atomic.AddInt32(&polls, 1) >= 3 {
+				status = JobStatusCompleted
+			}
+			json.NewEncoder(w).Encode(Job{ID: "job-1", Status: status})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	// Starting ingestion and waiting for it
+	jobID, err := c.StartIngest(context.Background())
+	if err != nil || jobID != "job-1" {
+		t.Fatalf("StartIngest returned %q, %v", jobID, err)
+	}
+	job, err := c.WaitForJob(context.Background(), jobID, time.Millisecond)
+
+	// The finished job is returned
+	if err != nil || job.Status != JobStatusCompleted || atomic.LoadInt32(&polls) != 3 {
+		t.Errorf("Unexpected job %+v after %d polls, %v", job, polls, err)
+	}
+}
+
+func TestWithTokenSendsBearerToken(t *testing.T) {
+	// A client holding a session token
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"Sign in to continue","code":"GODOCS_UNAUTHORIZED"}`))
+			return
+		}
+		w.Write([]byte(`{"ULID":"01HXYZ"}`))
+	}))
+	defer server.Close()
+	c := New(server.URL, WithToken("abc123"))
+
+	_, err := c.GetDocument(context.Background(), "01HXYZ")
+
+	// The token was sent as a Bearer token
+	if err != nil {
+		t.Fatalf("Expected the request to be signed in, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import "time"
+
+// Document is a document as returned by the godocs API
+type Document struct {
+	StormID      int
+	Name         string
+	Path         string
+	IngressTime  time.Time
+	Folder       string
+	Hash         string
+	ULID         string
+	DocumentType string
+	FullText     string // empty in listings; fetch with DocumentText
+	URL          string
+}
+
+// TextPage is one page of a document's text. Offsets and lengths count characters.
+type TextPage struct {
+	Text       string `json:"text"`
+	Offset     int    `json:"offset"`
+	Length     int    `json:"length"`
+	Total      int    `json:"total"`                // characters in the whole text
+	NextOffset int    `json:"nextOffset,omitempty"` // 0 on the last page
+}
+
+// DocumentPage is one page of the latest documents listing
+type DocumentPage struct {
+	Documents   []Document `json:"documents"`
+	Page        int        `json:"page"`
+	PageSize    int        `json:"pageSize"`
+	TotalCount  int        `json:"totalCount"`
+	TotalPages  int        `json:"totalPages"`
+	HasNext     bool       `json:"hasNext"`
+	HasPrevious bool       `json:"hasPrevious"`
+	NextCursor  string     `json:"nextCursor,omitempty"` // pass to LatestAfter to continue with keyset pagination
+}
+
+// FileNode is an entry in a file tree or search result listing
+type FileNode struct {
+	ID          string   `json:"id"`
+	ULID        string   `json:"ulid"`
+	Name        string   `json:"name"`
+	Size        int64    `json:"size"`
+	ModDate     string   `json:"modDate"`
+	Openable    bool     `json:"openable"`
+	ParentID    string   `json:"parentID"`
+	IsDir       bool     `json:"isDir"`
+	ChildrenIDs []string `json:"childrenIDs"`
+	FullPath    string   `json:"fullPath"`
+	FileURL     string   `json:"fileURL"`
+}
+
+// FileSystem is the response of the filesystem and search endpoints
+type FileSystem struct {
+	FileSystem []FileNode `json:"fileSystem"`
+	Error      string     `json:"error"`
+}
+
+// Job status values reported by the API
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// Job is a background job such as ingestion or cleanup
+type Job struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status"`
+	Progress    int        `json:"progress"`
+	CurrentStep string     `json:"currentStep"`
+	TotalSteps  int        `json:"totalSteps"`
+	Message     string     `json:"message"`
+	Error       string     `json:"error,omitempty"`
+	Result      string     `json:"result,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// Finished reports whether the job has stopped running
+func (j *Job) Finished() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed || j.Status == JobStatusCancelled
+}
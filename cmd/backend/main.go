@@ -1,19 +1,26 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 
+	cache "github.com/drummonds/godocs/cache"
 	config "github.com/drummonds/godocs/config"
 	database "github.com/drummonds/godocs/database"
 	engine "github.com/drummonds/godocs/engine"
+	"github.com/drummonds/godocs/internal/build"
+	"github.com/drummonds/godocs/internal/daemon"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/drummonds/godocs/sources"
 )
 
 // Logger is global since we will need it everywhere
@@ -25,6 +32,8 @@ func injectGlobals(logger *slog.Logger) {
 	database.Logger = Logger
 	config.Logger = Logger
 	engine.Logger = Logger
+	cache.Logger = Logger
+	sources.Logger = Logger
 }
 
 // @title godocs Backend API
@@ -63,19 +72,109 @@ func injectGlobals(logger *slog.Logger) {
 func main() {
 	// Parse command-line flags
 	port := flag.String("port", "8000", "Port to run backend server on")
+	demo := flag.Bool("demo", false, "Start with an in-memory database preloaded with sample documents")
+	noWorker := flag.Bool("no-worker", false, "Serve the API only; scheduled ingestion, OCR, cleanup and backups are left to a godocs-worker sharing the database")
+	workDir := flag.String(daemon.WorkDirFlag, "", "Change to this folder before loading config files (set when installed as a service)")
+	validate := flag.Bool("validate", false, "Check the configuration, database, folders, OCR and services, print a report and exit; same as the check command")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [check|%s]\n", os.Args[0], strings.Join(daemon.Actions, "|"))
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	if *workDir != "" {
+		if err := os.Chdir(*workDir); err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to change to working folder:", err)
+			os.Exit(1)
+		}
+	}
+	serviceArgs := []string{"-port", *port}
+	if *demo {
+		serviceArgs = append(serviceArgs, "-demo")
+	}
+	if *noWorker {
+		serviceArgs = append(serviceArgs, "-no-worker")
+	}
+	serviceConfig, err := daemon.Config("godocs-backend", "godocs backend", "godocs document management API server", serviceArgs...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to configure service:", err)
+		os.Exit(1)
+	}
+
+	// install, uninstall, start, stop, restart and status manage the backend as a Windows service or systemd unit
+	// check validates the configuration without starting the server, e.g. in a container entrypoint
+	if *validate || flag.Arg(0) == "check" {
+		if !check() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.NArg() > 0 {
+		if !daemon.IsAction(flag.Arg(0)) {
+			flag.Usage()
+			os.Exit(2)
+		}
+		message, err := daemon.Control(serviceConfig, flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Service %s failed: %v\n", flag.Arg(0), err)
+			os.Exit(1)
+		}
+		fmt.Println(message)
+		return
+	}
+
+	if err := daemon.Run(serviceConfig, func(stop <-chan struct{}) { serve(*port, *demo, *noWorker, stop) }); err != nil {
+		fmt.Fprintln(os.Stderr, "Service failed:", err)
+		os.Exit(1)
+	}
+}
+
+// check loads the configuration, prints a report on it and reports whether it is usable
+func check() bool {
+	serverConfig, logger := config.SetupServer()
+	injectGlobals(logger)
+	fmt.Printf("\nChecking godocs backend %s configuration\n\n", build.Version)
+	return engine.WriteConfigReport(os.Stdout, engine.CheckConfig(serverConfig))
+}
+
+// serve runs the API server until it fails or, when running as a service, until stop is closed
+func serve(port string, demo, noWorker bool, stop <-chan struct{}) {
+
 	fmt.Println("\n" + strings.Repeat("=", 50))
 	fmt.Println("🔧  godocs Backend API Server")
 	fmt.Println(strings.Repeat("=", 50))
 	fmt.Println("• API-only mode (no frontend)")
 	fmt.Println("• All endpoints under /api/*")
 	fmt.Println("• CORS enabled for frontend access")
+	if noWorker {
+		fmt.Println("• Scheduled jobs left to godocs-worker")
+	}
 	fmt.Println(strings.Repeat("=", 50) + "\n")
 
 	serverConfig, logger := config.SetupServer()
 	injectGlobals(logger) //inject the logger into all of the packages
 
+	if demo {
+		cleanupDemo, err := engine.DemoConfig(&serverConfig)
+		if err != nil {
+			Logger.Error("Unable to start demo mode", "error", err)
+			os.Exit(1)
+		}
+		defer cleanupDemo()
+		fmt.Println("🎬  DEMO MODE")
+		fmt.Println("• In-memory database with sample documents, discarded on exit")
+		fmt.Println()
+	}
+
+	// With no config file or environment settings, run on an in-memory database until setup is completed
+	if !demo && !config.Configured() {
+		serverConfig.DatabaseType = "memory"
+		fmt.Println("🧭  FIRST-RUN SETUP")
+		fmt.Println("• No configuration found, complete setup with GET/POST /api/setup")
+		fmt.Println()
+	}
+
 	// Show info banner if using ephemeral database
 	if serverConfig.DatabaseType == "ephemeral" {
 		fmt.Println("🚀  EPHEMERAL DATABASE MODE")
@@ -83,12 +182,15 @@ func main() {
 		fmt.Println()
 	}
 
+	// Trace requests, ingestion and queries when OTLP_ENDPOINT is set
+	defer engine.SetupTracing(serverConfig)()
+
 	// Setup document repository
 	repo := database.NewRepository(serverConfig)
 	defer repo.Close()
 
-	// Write config to database if it's a fresh ephemeral database
-	if serverConfig.DatabaseType == "ephemeral" {
+	// Write config to database if it's a fresh ephemeral or in-memory database
+	if serverConfig.DatabaseType == "ephemeral" || serverConfig.DatabaseType == "memory" {
 		database.WriteConfigToDB(serverConfig, repo)
 	}
 
@@ -107,6 +209,7 @@ func main() {
 			// Return JSON for API endpoints
 			c.JSON(http.StatusNotFound, map[string]string{
 				"error":   "Not Found",
+				"code":    string(dto.CodeNotFound),
 				"message": "The requested API endpoint does not exist",
 				"path":    c.Request().URL.Path,
 			})
@@ -117,12 +220,32 @@ func main() {
 		e.DefaultHTTPErrorHandler(err, c)
 	}
 
-	serverHandler := engine.ServerHandler{DB: repo, Echo: e, ServerConfig: serverConfig}
+	serverHandler := engine.ServerHandler{DB: repo, Echo: e, ServerConfig: serverConfig, Cache: cache.New(serverConfig)}
 	Logger.Info("Initializing backend services...")
-	serverHandler.InitializeSchedules(repo) //initialize all the cron jobs
-	serverHandler.StartupChecks()           //Run all the sanity checks
+	if noWorker {
+		Logger.Info("Not running scheduled jobs; a separate worker runs them")
+		defer serverHandler.FollowWorkerChanges()()
+	} else {
+		serverHandler.InitializeSchedules(repo) //initialize all the cron jobs
+	}
+	// Run all the sanity checks; a sidecar service that never comes up is fatal
+	if err := serverHandler.StartupChecks(); err != nil {
+		Logger.Error("Startup checks failed", "error", err)
+		fmt.Println("Startup checks failed:", err)
+		os.Exit(1)
+	}
+	if err := serverHandler.EnsureInitialUser(); err != nil {
+		Logger.Error("Unable to create the first account", "error", err)
+	}
+	if demo {
+		if _, err := serverHandler.LoadDemoDocuments(); err != nil {
+			Logger.Error("Unable to load demo documents", "error", err)
+		}
+	}
 	Logger.Info("Backend services initialized")
 
+	e.Use(serverHandler.TraceRequests()) // Span per request when OTLP_ENDPOINT is set
+
 	// CORS configuration - allow frontend from different origin
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"*"}, // In production, specify your frontend URL
@@ -130,6 +253,10 @@ func main() {
 		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
 	}))
 
+	e.Use(serverHandler.DocumentViewAuth()) // Check signatures on /document/view links
+	e.Use(serverHandler.RequireLogin())     // Require a session when WEB_UI_AUTH is on
+	e.Use(serverHandler.ReadOnlyGuard())    // Refuse changes during maintenance windows
+
 	// Request logging
 	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
 		Format: "method=${method}, uri=${uri}, status=${status}, latency=${latency_human}\n",
@@ -140,48 +267,137 @@ func main() {
 	// Document API routes
 	e.GET("/api/documents/latest", serverHandler.GetLatestDocuments)
 	e.GET("/api/documents/filesystem", serverHandler.GetDocumentFileSystem)
+	e.GET("/api/documents/export.ndjson", serverHandler.ExportDocumentsNDJSON)
+	e.GET("/api/documents/popular", serverHandler.GetPopularDocuments)
 	e.GET("/api/document/:id", serverHandler.GetDocument)
+	e.GET("/api/document/:id/text", serverHandler.GetDocumentText)
+	e.GET("/api/document/:id/timeline", serverHandler.GetDocumentTimeline)
+	e.GET("/api/document/:id/search", serverHandler.SearchDocumentText)
+	e.GET("/api/document/:id/coversheet.pdf", serverHandler.GetCoverSheet)
+	e.GET("/api/document/:id/qr.png", serverHandler.GetDocumentQR)
+	e.GET("/api/document/:id/signed-url", serverHandler.GetSignedDocumentURL)
+	e.POST("/api/document/:id/lock", serverHandler.LockDocument)
+	e.GET("/api/document/:id/lock", serverHandler.GetDocumentLock)
+	e.DELETE("/api/document/:id/lock", serverHandler.UnlockDocument)
+	e.POST("/api/document/:id/archive", serverHandler.ArchiveDocument)
+	e.GET("/api/document/:id/archive", serverHandler.GetDocumentArchive)
+	e.DELETE("/api/document/:id/archive", serverHandler.RestoreDocument)
+	e.GET("/api/archive", serverHandler.GetArchivedDocuments)
+	e.POST("/api/document/:id/redact", serverHandler.RedactDocument)
+	e.GET("/api/document/:id/redactions", serverHandler.GetDocumentRedactions)
+	e.GET("/api/document/:id/spreadsheet", serverHandler.GetSpreadsheetDetails)
+	e.GET("/api/document/:id/rotation", serverHandler.GetDocumentRotations)
+	e.GET("/api/document/:id/blank-pages", serverHandler.GetDocumentBlankPages)
+	e.GET("/api/document/:id/preview", serverHandler.GetSpreadsheetPreview)
+	e.GET("/api/document/:id/tags", serverHandler.GetDocumentTags)
+	e.POST("/api/document/:id/tags", serverHandler.AddDocumentTag)
+	e.DELETE("/api/document/:id/tags/:tag", serverHandler.RemoveDocumentTag)
+	e.POST("/api/holds", serverHandler.PlaceLegalHold)
+	e.GET("/api/holds", serverHandler.GetLegalHolds)
+	e.GET("/api/holds/events", serverHandler.GetLegalHoldEvents)
+	e.DELETE("/api/holds/:id", serverHandler.RemoveLegalHold)
 	e.DELETE("/api/document/*", serverHandler.DeleteFile)
 	e.PATCH("/api/document/move/*", serverHandler.MoveDocuments)
 	e.POST("/api/document/upload", serverHandler.UploadDocuments)
+	e.POST("/api/document/rescan", serverHandler.RescanDocument)
 
 	// Folder API routes
+	e.GET("/api/folders", serverHandler.GetFolders)
+	e.PATCH("/api/folders/:id", serverHandler.UpdateFolderStyle)
 	e.GET("/api/folder/:folder", serverHandler.GetFolder)
+	e.GET("/api/folder/:folder/download", serverHandler.DownloadFolder)
 	e.POST("/api/folder/*", serverHandler.CreateFolder)
 
 	// Search API routes
 	e.GET("/api/search", serverHandler.SearchDocuments)
 	e.POST("/api/search/reindex", serverHandler.ReindexSearchDocuments)
+	e.GET("/api/search/history", serverHandler.GetSearchHistory)
+	e.GET("/api/search/analytics", serverHandler.GetSearchAnalytics)
+	e.GET("/api/search/suggest", serverHandler.SuggestSearch)
+	e.GET("/api/tags", serverHandler.ListTags)
+	e.GET("/api/tags/:tag/documents", serverHandler.GetDocumentsByTag)
+	e.PUT("/api/tags/:tag", serverHandler.RenameTag)
+	e.POST("/api/tags/:tag/merge", serverHandler.MergeTag)
+	e.GET("/api/collections", serverHandler.ListCollections)
+	e.POST("/api/collections", serverHandler.CreateCollection)
+	e.GET("/api/collections/:id", serverHandler.GetCollection)
+	e.DELETE("/api/collections/:id", serverHandler.DeleteCollection)
+	e.GET("/api/shared/:token", serverHandler.GetSharedCollection)
+	e.GET("/api/smartfolders", serverHandler.ListSmartFolders)
+	e.POST("/api/smartfolders", serverHandler.CreateSmartFolder)
+	e.GET("/api/smartfolders/:id", serverHandler.GetSmartFolder)
+	e.DELETE("/api/smartfolders/:id", serverHandler.DeleteSmartFolder)
+
+	// Extraction template routes
+	e.GET("/api/extraction/templates", serverHandler.ListExtractionTemplates)
+	e.POST("/api/extraction/templates", serverHandler.CreateExtractionTemplate)
+	e.DELETE("/api/extraction/templates/:id", serverHandler.DeleteExtractionTemplate)
+	e.POST("/api/extraction/preview", serverHandler.PreviewExtraction)
+	e.GET("/api/documents/:id/fields", serverHandler.GetDocumentFields)
+	e.GET("/api/extractions/export", serverHandler.ExportExtractions)
 
 	// Admin API routes
 	e.POST("/api/ingest", serverHandler.RunIngestNow)
+	e.GET("/api/ingest/rejections", serverHandler.GetIngestRejections)
 	e.POST("/api/clean", serverHandler.CleanDatabase)
+	e.POST("/api/documents/urls/repair", serverHandler.RepairDocumentURLs)
+	e.POST("/api/documents/rehash", serverHandler.RehashDocuments)
+	e.POST("/api/documents/optimise", serverHandler.OptimiseDocuments)
+	e.GET("/api/duplicates", serverHandler.GetDuplicates)
+	e.POST("/api/duplicates/scan", serverHandler.ScanDuplicates)
 	e.GET("/api/about", serverHandler.GetAboutInfo)
+	e.GET("/api/about/ocr", serverHandler.GetOCRLanguages)
+	e.GET("/api/quota", serverHandler.GetQuota)
+	e.GET("/api/schedules", serverHandler.GetSchedules)
+	e.PUT("/api/schedules", serverHandler.UpdateSchedules)
+	e.GET("/api/setup", serverHandler.GetSetup)
+	e.POST("/api/setup", serverHandler.SaveSetup)
 
 	// Word cloud API routes
 	e.GET("/api/wordcloud", serverHandler.GetWordCloud)
 	e.POST("/api/wordcloud/recalculate", serverHandler.RecalculateWordCloud)
 
+	// Statistics API routes
+	e.GET("/api/stats/timeseries", serverHandler.GetStatsTimeseries)
+
+	// Integration routes
+	e.POST("/api/integrations/dropzone", serverHandler.ReceiveDropzone)
+
 	// Job tracking API routes
 	e.GET("/api/jobs", serverHandler.GetRecentJobs)
 	e.GET("/api/jobs/active", serverHandler.GetActiveJobs)
 	e.GET("/api/jobs/:id", serverHandler.GetJob)
+	e.GET("/api/activity", serverHandler.GetActivity)
 
 	// Document view routes (serve actual PDF/document files)
 	// These are not under /api/* because they serve files, not JSON
 	serverHandler.AddDocumentViewRoutes()
 
-	// Health check endpoint
-	e.GET("/api/health", func(c echo.Context) error {
-		return c.JSON(http.StatusOK, map[string]string{
-			"status":  "healthy",
-			"service": "godocs Backend API",
-		})
-	})
+	// Health check endpoint (includes sidecar services)
+	e.GET("/api/health", serverHandler.GetHealth)
+	e.GET("/api/read-only", serverHandler.GetReadOnly)
+	e.PUT("/api/read-only", serverHandler.SetReadOnly)
+	e.GET("/api/admin/slow-queries", serverHandler.GetSlowQueries)
+	e.GET("/api/admin/index-usage", serverHandler.GetIndexUsage)
+	e.GET("/api/admin/client-errors", serverHandler.GetClientErrors)
+	e.POST("/api/admin/export", serverHandler.ExportArchive)
+	e.GET("/api/admin/export/:jobId/download", serverHandler.DownloadExport)
+	e.POST("/api/client-errors", serverHandler.ReportClientError)
+	e.POST("/api/auth/login", serverHandler.Login)
+	e.POST("/api/auth/logout", serverHandler.Logout)
+	e.GET("/api/auth/status", serverHandler.GetAuthStatus)
+	e.GET("/api/users", serverHandler.ListUsers)
+	e.POST("/api/users", serverHandler.CreateUser)
+	e.DELETE("/api/users/:id", serverHandler.DeleteUser)
+	e.PUT("/api/users/:id/password", serverHandler.ChangePassword)
+	e.PUT("/api/users/:id/role", serverHandler.SetUserRole)
+	e.GET("/api/folder-permissions", serverHandler.ListFolderPermissions)
+	e.PUT("/api/folder-permissions", serverHandler.SetFolderPermission)
+	e.DELETE("/api/folder-permissions", serverHandler.DeleteFolderPermission)
 
 	// Override port if specified via flag
-	if *port != "8000" {
-		serverConfig.ListenAddrPort = *port
+	if port != "8000" {
+		serverConfig.ListenAddrPort = port
 	}
 
 	// Start server
@@ -191,6 +407,19 @@ func main() {
 	fmt.Printf("📡  API endpoints available at http://%s/api/\n", addr)
 	fmt.Printf("🏥  Health check: http://%s/api/health\n\n", addr)
 
+	// The service manager asks for a graceful shutdown so in-flight requests finish and deferred cleanup runs
+	if stop != nil {
+		go func() {
+			<-stop
+			Logger.Info("Service stop requested, shutting down")
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+			defer cancel()
+			if err := e.Shutdown(ctx); err != nil {
+				Logger.Error("Graceful shutdown failed", "error", err)
+			}
+		}()
+	}
+
 	if err := e.Start(addr); err != nil && err != http.ErrServerClosed {
 		Logger.Error("Server failed to start", "error", err)
 		os.Exit(1)
@@ -0,0 +1,165 @@
+// Command godocs runs the combined backend and go-app UI server, in a terminal or as a service
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs"
+	config "github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/engine"
+	"github.com/drummonds/godocs/internal/build"
+	"github.com/drummonds/godocs/internal/daemon"
+)
+
+func main() {
+	demo := flag.Bool("demo", false, "Start with an in-memory database preloaded with sample documents")
+	noWorker := flag.Bool("no-worker", false, "Serve the API and web UI only; scheduled ingestion, OCR, cleanup and backups are left to a godocs-worker sharing the database")
+	workDir := flag.String(daemon.WorkDirFlag, "", "Change to this folder before loading config files (set when installed as a service)")
+	validate := flag.Bool("validate", false, "Check the configuration, database, folders, OCR and services, print a report and exit; same as the check command")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [check|%s]\n", os.Args[0], strings.Join(daemon.Actions, "|"))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *workDir != "" {
+		if err := os.Chdir(*workDir); err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to change to working folder:", err)
+			os.Exit(1)
+		}
+	}
+	var serviceArgs []string
+	if *demo {
+		serviceArgs = append(serviceArgs, "-demo")
+	}
+	if *noWorker {
+		serviceArgs = append(serviceArgs, "-no-worker")
+	}
+	serviceConfig, err := daemon.Config("godocs", "godocs", "godocs document management server", serviceArgs...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to configure service:", err)
+		os.Exit(1)
+	}
+
+	// install, uninstall, start, stop, restart and status manage godocs as a Windows service or systemd unit
+	// check validates the configuration without starting the server, e.g. in a container entrypoint
+	if *validate || flag.Arg(0) == "check" {
+		if !check() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.NArg() > 0 {
+		if !daemon.IsAction(flag.Arg(0)) {
+			flag.Usage()
+			os.Exit(2)
+		}
+		message, err := daemon.Control(serviceConfig, flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Service %s failed: %v\n", flag.Arg(0), err)
+			os.Exit(1)
+		}
+		fmt.Println(message)
+		return
+	}
+
+	if err := daemon.Run(serviceConfig, func(stop <-chan struct{}) { serve(*demo, *noWorker, stop) }); err != nil {
+		fmt.Fprintln(os.Stderr, "Service failed:", err)
+		os.Exit(1)
+	}
+}
+
+// check loads the configuration, prints a report on it and reports whether it is usable
+func check() bool {
+	serverConfig, logger := config.SetupServer()
+	engine.Logger = logger
+	database.Logger = logger
+	fmt.Printf("\nChecking godocs %s configuration\n\n", build.Version)
+	return engine.WriteConfigReport(os.Stdout, engine.CheckConfig(serverConfig))
+}
+
+// serve runs the server until it fails or, when running as a service, until stop is closed
+func serve(demo, noWorker bool, stop <-chan struct{}) {
+	serverConfig, logger := config.SetupServer()
+
+	// With no config file or environment settings, run on an in-memory database until setup is completed
+	if !demo && !config.Configured() {
+		serverConfig.DatabaseType = "memory"
+		fmt.Println("\n" + strings.Repeat("=", 50))
+		fmt.Println("🧭  FIRST-RUN SETUP")
+		fmt.Println(strings.Repeat("=", 50))
+		fmt.Println("• No configuration found")
+		fmt.Printf("• Open http://localhost:%s/setup to configure godocs\n", serverConfig.ListenAddrPort)
+		fmt.Println(strings.Repeat("=", 50) + "\n")
+	}
+
+	// Log version information
+	logger.Info("Starting godocs", "version", build.Version)
+	fmt.Printf("\n🚀  godocs version %s\n", build.Version)
+
+	// Show info banner if using ephemeral database
+	if serverConfig.DatabaseType == "ephemeral" {
+		fmt.Println("\n" + strings.Repeat("=", 50))
+		fmt.Println("🚀  EPHEMERAL DATABASE MODE")
+		fmt.Println(strings.Repeat("=", 50))
+		fmt.Println("• Database will be destroyed on exit")
+		fmt.Println("• Perfect for testing and development")
+		fmt.Println("• No persistent data storage")
+		fmt.Println(strings.Repeat("=", 50) + "\n")
+	}
+
+	options := []godocs.Option{godocs.WithLogger(logger), godocs.WithPortRetries(4)}
+	if demo {
+		options = append(options, godocs.WithDemo())
+	}
+	if noWorker {
+		options = append(options, godocs.WithoutWorker())
+	}
+	srv, err := godocs.New(serverConfig, options...)
+	if err != nil {
+		logger.Error("Unable to start godocs", "error", err)
+		fmt.Println("Unable to start godocs:", err)
+		os.Exit(1)
+	}
+	if demo {
+		fmt.Println("\n" + strings.Repeat("=", 50))
+		fmt.Println("🎬  DEMO MODE")
+		fmt.Println(strings.Repeat("=", 50))
+		fmt.Println("• In-memory database with sample documents")
+		fmt.Println("• Documents live in", srv.Config().DocumentPath)
+		fmt.Println("• Everything is discarded on exit")
+		fmt.Println(strings.Repeat("=", 50) + "\n")
+	}
+
+	// The service manager asks for a graceful shutdown so in-flight requests finish and the database is closed
+	done := make(chan struct{})
+	if stop != nil {
+		go func() {
+			defer close(done)
+			<-stop
+			logger.Info("Service stop requested, shutting down")
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+			defer cancel()
+			if err := srv.Shutdown(ctx); err != nil {
+				logger.Error("Graceful shutdown failed", "error", err)
+			}
+		}()
+	}
+
+	logger.Info("Starting HTTP server")
+	if err := srv.Start(); err != nil {
+		logger.Error("Failed to start server", "error", err)
+		srv.Shutdown(context.Background())
+		os.Exit(1)
+	}
+	if stop != nil {
+		<-done
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	config "github.com/drummonds/godocs/config"
+	database "github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/seed"
+)
+
+// seed fills the configured database with synthetic documents for capacity and load testing.
+// It uses the same configuration (.env, config.env, environment) as the server.
+func main() {
+	count := flag.Int("n", 10000, "Number of documents to generate")
+	folders := flag.Int("folders", 50, "Number of leaf folders to spread documents over")
+	randomSeed := flag.Int64("seed", 1, "Random seed; the same seed gives the same documents")
+	files := flag.Bool("files", true, "Create a sparse file at each document path so the documents can be opened and cleaned up")
+	root := flag.String("root", "", "Document root for generated paths (defaults to DOCUMENT_PATH)")
+	flag.Parse()
+
+	serverConfig, logger := config.SetupServer()
+	database.Logger = logger
+	if *root == "" {
+		*root = serverConfig.DocumentPath
+	}
+
+	repo := database.NewRepository(serverConfig)
+	defer repo.Close()
+
+	started := time.Now()
+	fmt.Printf("Generating %d documents in %d folders under %s\n", *count, *folders, *root)
+	saved, err := seed.Generate(repo, seed.Options{
+		Count:   *count,
+		Root:    *root,
+		Folders: *folders,
+		Seed:    *randomSeed,
+		Files:   *files,
+		Progress: func(done int) {
+			if done%10000 == 0 || done == *count {
+				fmt.Printf("  %d/%d documents (%s)\n", done, *count, time.Since(started).Round(time.Millisecond))
+			}
+		},
+	})
+	if err != nil {
+		fmt.Println("Seeding failed:", err)
+		repo.Close()
+		os.Exit(1)
+	}
+	fmt.Printf("Seeded %d documents in %s\n", saved, time.Since(started).Round(time.Millisecond))
+}
@@ -9,6 +9,10 @@ import (
 )
 
 func main() {
+	// Report panics in event handlers and updates, which run on this goroutine, before the app stops
+	defer webapp.RecoverPanic()
+	webapp.InstallErrorReporting()
+
 	// Register routes for the client-side app - all use App component with navbar/sidebar
 	app.Route("/", func() app.Composer { return &webapp.App{} })
 	app.Route("/browse", func() app.Composer { return &webapp.App{} })
@@ -16,7 +20,12 @@ func main() {
 	app.Route("/clean", func() app.Composer { return &webapp.App{} })
 	app.Route("/search", func() app.Composer { return &webapp.App{} })
 	app.Route("/wordcloud", func() app.Composer { return &webapp.App{} })
+	app.Route("/stats", func() app.Composer { return &webapp.App{} })
 	app.Route("/about", func() app.Composer { return &webapp.App{} })
+	app.Route("/setup", func() app.Composer { return &webapp.App{} })
+	app.Route("/collection", func() app.Composer { return &webapp.App{} })
+	app.Route("/scan", func() app.Composer { return &webapp.App{} })
+	app.Route("/login", func() app.Composer { return &webapp.App{} })
 
 	// This main function is for the WASM build only
 	// It initializes the go-app when running in the browser
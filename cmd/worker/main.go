@@ -0,0 +1,133 @@
+// Command worker runs godocs' scheduled jobs (ingestion with OCR, cleanup, backups, reindexing, rescans,
+// remote sources and archiving) without serving the API. Run it on a machine with the CPU for OCR, pointed
+// at the same database and document folders as an API server started with -no-worker.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	cache "github.com/drummonds/godocs/cache"
+	config "github.com/drummonds/godocs/config"
+	database "github.com/drummonds/godocs/database"
+	engine "github.com/drummonds/godocs/engine"
+	"github.com/drummonds/godocs/internal/build"
+	"github.com/drummonds/godocs/internal/daemon"
+	"github.com/drummonds/godocs/sources"
+)
+
+// Logger is global since we will need it everywhere
+var Logger *slog.Logger
+
+// injectGlobals injects all of our globals into their packages
+func injectGlobals(logger *slog.Logger) {
+	Logger = logger
+	database.Logger = Logger
+	config.Logger = Logger
+	engine.Logger = Logger
+	cache.Logger = Logger
+	sources.Logger = Logger
+}
+
+func main() {
+	workDir := flag.String(daemon.WorkDirFlag, "", "Change to this folder before loading config files (set when installed as a service)")
+	validate := flag.Bool("validate", false, "Check the configuration, database, folders, OCR and services, print a report and exit; same as the check command")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [check|%s]\n", os.Args[0], strings.Join(daemon.Actions, "|"))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *workDir != "" {
+		if err := os.Chdir(*workDir); err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to change to working folder:", err)
+			os.Exit(1)
+		}
+	}
+	serviceConfig, err := daemon.Config("godocs-worker", "godocs worker", "godocs ingestion, OCR and maintenance jobs")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to configure service:", err)
+		os.Exit(1)
+	}
+
+	// install, uninstall, start, stop, restart and status manage the worker as a Windows service or systemd unit
+	if *validate || flag.Arg(0) == "check" {
+		if !check() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.NArg() > 0 {
+		if !daemon.IsAction(flag.Arg(0)) {
+			flag.Usage()
+			os.Exit(2)
+		}
+		message, err := daemon.Control(serviceConfig, flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Service %s failed: %v\n", flag.Arg(0), err)
+			os.Exit(1)
+		}
+		fmt.Println(message)
+		return
+	}
+
+	if err := daemon.Run(serviceConfig, work); err != nil {
+		fmt.Fprintln(os.Stderr, "Service failed:", err)
+		os.Exit(1)
+	}
+}
+
+// check loads the configuration, prints a report on it and reports whether it is usable by a worker
+func check() bool {
+	serverConfig, logger := config.SetupServer()
+	injectGlobals(logger)
+	fmt.Printf("\nChecking godocs worker %s configuration\n\n", build.Version)
+	ok := engine.WriteConfigReport(os.Stdout, engine.CheckConfig(serverConfig))
+	if err := engine.CheckWorkerConfig(serverConfig); err != nil {
+		fmt.Println("Worker:", err)
+		return false
+	}
+	return ok
+}
+
+// work runs the scheduled jobs until the process exits or, when running as a service, until stop is closed
+func work(stop <-chan struct{}) {
+	fmt.Println("\n" + strings.Repeat("=", 50))
+	fmt.Println("⚙️  godocs Worker")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Println("• Scheduled ingestion, OCR, cleanup and backups")
+	fmt.Println("• No API; run the API server with -no-worker")
+	fmt.Println(strings.Repeat("=", 50) + "\n")
+
+	serverConfig, logger := config.SetupServer()
+	injectGlobals(logger)
+	logger.Info("Starting godocs worker", "version", build.Version)
+
+	if !config.Configured() {
+		Logger.Error("No configuration found; the worker needs the API server's database and folders")
+		os.Exit(1)
+	}
+	if err := engine.CheckWorkerConfig(serverConfig); err != nil {
+		Logger.Error("Unable to start worker", "error", err)
+		os.Exit(1)
+	}
+
+	defer engine.SetupTracing(serverConfig)() // trace ingestion and queries when OTLP_ENDPOINT is set
+	repo := database.NewRepository(serverConfig)
+	defer repo.Close()
+
+	// Document changes are reported through the database, so an API server with an in-memory cache
+	// clears its listings within seconds; sharing the cache (CACHE_TYPE=redis) clears them straight away
+	serverHandler := engine.ServerHandler{DB: repo, ServerConfig: serverConfig, Cache: cache.New(serverConfig)}
+	if err := serverHandler.StartupChecks(); err != nil {
+		Logger.Error("Startup checks failed", "error", err)
+		os.Exit(1)
+	}
+	Logger.Info("Worker running scheduled jobs")
+	serverHandler.RunWorker(stop)
+	Logger.Info("Worker stopped")
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log/slog"
@@ -8,8 +10,10 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
+	"golang.org/x/text/language"
 )
 
 // Logger is global since we will need it everywhere
@@ -42,9 +46,81 @@ type ServerConfig struct {
 	UseReverseProxy      bool
 	BaseURL              string
 	IngressInterval      int
+	IngestBatchSize      int    // documents written per transaction by ingestion jobs, 1 writes each immediately
+	IngestWorkers        int    // documents extracted and OCRed at once; uploads go first and one slot is kept for them
+	RescanInterval       int    // minutes between changed file scans, 0 disables
+	URLSigningKey        string `json:"-"`
+	SignedURLTTL         int    // seconds a signed document URL stays valid by default
+	RequireSignedURLs    bool   // reject unsigned /document/view requests
+	CacheType            string // memory, redis or none
+	RedisURL             string `json:"-"`
+	CacheTTL             int    // seconds, 0 keeps entries until invalidated
+	CacheSize            int    // maximum entries for the in-memory cache
+	DropzoneAPIKey       string `json:"-"` // key required by the dropzone webhook, empty disables it
+	DropzoneFolder       string // ingress subfolder that receives dropzone pushes
+	DropzoneMaxMB        int    // largest file the dropzone webhook accepts, 0 for no limit
+	NextcloudURL         string // WebDAV root, e.g. https://cloud.example.com/remote.php/dav/files/alice/
+	NextcloudUser        string
+	NextcloudPassword    string `json:"-"`
+	NextcloudIngestPath  string // remote folder documents are pulled from
+	NextcloudWriteBack   string // remote folder processed documents are copied to, empty disables
+	SMBHost              string // host or host:port of an SMB share to ingest from, empty disables
+	SMBShare             string
+	SMBUser              string
+	SMBPassword          string `json:"-"`
+	SMBDomain            string
+	SMBIngestPath        string           // folder inside the share documents are pulled from
+	PDFServiceURL        string           // PDF sidecar service, e.g. http://pdf-service:3000, empty if not used
+	TesseractServiceURL  string           // Tesseract sidecar service, empty if not used
+	ServiceCheckRetries  int              // startup retries before a missing sidecar is fatal
+	ServiceCheckInterval int              // seconds between sidecar startup retries
+	TempPath             string           // root for OCR work directories
+	OCRMaxPages          int              // PDF pages OCRed per document, 0 for all
+	OCRMaxFileMB         int              // largest PDF that is rendered for OCR, 0 for no limit
+	OCRLanguages         string           // tesseract language packs to OCR with, e.g. eng+deu; empty for tesseract's default
+	AutoRotate           bool             // turn upside-down and sideways scans upright before they are stored, using tesseract's OSD
+	BlankPages           string           // off, detect or remove: what is done about blank pages in scans
+	BlankPageInk         float64          // percent of a page that must be inked for it not to count as blank
+	OptimiseDPI          int              // resolution scans are downsampled to when recompressed to save storage, 0 keeps it
+	OptimiseJPEGQuality  int              // JPEG quality, 1-100, recompressed scans are written with; 0 for the encoder default
+	OptimiseMinSaving    int              // percent smaller a recompressed scan must be to replace the stored file
+	OriginalsPath        string           // folder originals are kept in when scans are recompressed; empty replaces them
+	FolderQuotas         map[string]int64 // bytes allowed under each folder, keyed relative to DocumentPath ("" is the root)
+	QuotaWarnPercents    []int            // usage percentages that raise a warning, ascending
+	IngestExtensions     []string         // lower case file extensions, with the dot, that are ingested
+	DBRetryAttempts      int              // tries for a job's database write that fails transiently, 1 disables retrying
+	DBRetryBackoffMS     int              // milliseconds before the first retry, doubled for each one after
+	SlowQueryMS          int              // queries taking at least this many milliseconds are logged, 0 disables
+	HashAlgorithm        string           // md5, sha1 or sha256; new and rehashed documents are stored with it
+	Schedules            JobSchedules     // cron expression per scheduled job, an empty one disables the job
+	QuietHours           string           // HH:MM-HH:MM window, in server time, when OCR jobs are deferred
+	JobTimeouts          JobTimeouts      // how long an active job of each type may go without progress before it is failed
+	BackupPath           string           // folder the scheduled backup job writes metadata exports to
+	PostIngestCommand    string           // command run after each document is ingested, given its metadata; empty disables
+	PostIngestWebhooks   []string         `json:"-"` // URLs each ingested document's metadata is POSTed to; they may carry tokens
+	PostIngestTimeout    int              // seconds each post-ingestion hook may run
+	PreIngestCommand     string           // command that may transform each file before it is ingested; empty disables
+	PreIngestURL         string           `json:"-"` // service each file is POSTed to for transforming before it is ingested; it may carry a token
+	PreIngestTimeout     int              // seconds each pre-ingestion transform may run
+	QuarantinePath       string           // folder files a pre-ingestion transform failed on are moved to
+	UpdateCheck          bool             // check once a day whether a newer release has been published
+	UpdateCheckURL       string           // GitHub API URL of the latest release
+	SortLocale           string           // BCP 47 language tag whose rules order names in the file tree; empty for language-neutral
+	ArchiveAfterDays     int              // days after ingestion a document is moved to cold storage, 0 disables archiving
+	ArchivePath          string           // folder archived documents are compressed into; empty keeps them beside the original
+	AdminUsers           []string         // users, as sent by basic auth or the proxy, who may place and remove legal holds
+	ReadOnly             bool             // start in read-only mode, refusing every change until it is turned off
+	ReadOnlyMessage      string           // shown to clients whose changes are refused in read-only mode
+	SessionHours         int              // hours a sign-in lasts when WEB_UI_AUTH is on
+	OTLPEndpoint         string           // OpenTelemetry collector spans are sent to over OTLP/HTTP, e.g. http://collector:4318; empty disables tracing
+	TraceServiceName     string           // service name traces are reported under
+	TraceSampleRatio     float64          // share of traces kept, over 0 and up to 1; 0 keeps them all
 	FrontEndConfig
 }
 
+// JobSchedules maps a scheduled job (ingest, cleanup, backup or reindex) to its cron expression
+type JobSchedules map[string]string
+
 // FrontEndConfig stores all of the frontend settings
 type FrontEndConfig struct {
 	NewDocumentNumber int
@@ -85,6 +161,19 @@ func getEnvInt(key string, defaultValue int) int {
 	return intVal
 }
 
+// getEnvFloat gets a decimal environment variable with a default value
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	floatVal, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return floatVal
+}
+
 // SetupServer loads configuration and returns ServerConfig and Logger
 func SetupServer() (ServerConfig, *slog.Logger) {
 	serverConfigLive := ServerConfig{}
@@ -92,9 +181,9 @@ func SetupServer() (ServerConfig, *slog.Logger) {
 
 	// Load .env file (silently ignore if doesn't exist)
 	// Try production location first, then local development files
-	_ = godotenv.Load("/etc/godocs.env")
-	_ = godotenv.Load(".env")
-	_ = godotenv.Load("config.env")
+	for _, file := range ConfigFiles {
+		_ = godotenv.Load(file)
+	}
 
 	logger := setupLogging()
 	Logger = logger
@@ -125,8 +214,30 @@ func SetupServer() (ServerConfig, *slog.Logger) {
 	serverConfigLive.IngressPath = ingressDirAbs
 
 	serverConfigLive.IngressInterval = getEnvInt("INGRESS_INTERVAL", 10)
+	serverConfigLive.IngestBatchSize = getEnvInt("INGEST_BATCH_SIZE", 50)
+	serverConfigLive.IngestWorkers = getEnvInt("INGEST_WORKERS", 2)
 	serverConfigLive.IngressPreserve = getEnvBool("INGRESS_PRESERVE_STRUCTURE", true)
 	serverConfigLive.IngressDelete = getEnvBool("INGRESS_DELETE", true) // Changed default to true - delete source files after ingestion
+	serverConfigLive.RescanInterval = getEnvInt("RESCAN_INTERVAL", 60)
+
+	// Job schedules are cron expressions, and may be changed later through the schedules API
+	serverConfigLive.Schedules = JobSchedules{
+		"ingest":  getEnv("SCHEDULE_INGEST", fmt.Sprintf("@every %dm", serverConfigLive.IngressInterval)),
+		"cleanup": getEnv("SCHEDULE_CLEANUP", ""),
+		"backup":  getEnv("SCHEDULE_BACKUP", ""),
+		"reindex": getEnv("SCHEDULE_REINDEX", ""),
+	}
+	serverConfigLive.QuietHours = getEnv("QUIET_HOURS", "")
+	jobTimeouts, err := ParseJobTimeouts(getEnv("JOB_TIMEOUTS", ""))
+	if err != nil {
+		logger.Error("Ignoring invalid JOB_TIMEOUTS", "error", err)
+	}
+	serverConfigLive.JobTimeouts = jobTimeouts
+	backupPath, err := filepath.Abs(filepath.ToSlash(getEnv("BACKUP_PATH", "backups")))
+	if err != nil {
+		logger.Error("Failed creating absolute path for backup directory", "error", err)
+	}
+	serverConfigLive.BackupPath = backupPath
 
 	// IngressMoveFolder is now deprecated - we delete files instead of moving them
 	// Kept for backwards compatibility but not created by default
@@ -179,10 +290,74 @@ func SetupServer() (ServerConfig, *slog.Logger) {
 		serverConfigLive.TesseractPath = ""
 	}
 
+	// Temp directory for OCR artifacts; each conversion gets its own subdirectory which is removed afterwards
+	tempPathAbs, err := filepath.Abs(getEnv("TEMP_PATH", filepath.Join(os.TempDir(), "godocs")))
+	if err != nil {
+		logger.Error("Failed creating absolute path for temp directory", "error", err)
+	}
+	serverConfigLive.TempPath = tempPathAbs
+
+	// Guards against rendering huge scanned PDFs for OCR
+	serverConfigLive.OCRMaxPages = getEnvInt("OCR_MAX_PAGES", 200)
+	serverConfigLive.OCRMaxFileMB = getEnvInt("OCR_MAX_FILE_MB", 200)
+	serverConfigLive.OCRLanguages = getEnv("OCR_LANGUAGES", "")
+	serverConfigLive.AutoRotate = getEnvBool("AUTO_ROTATE", false)
+	serverConfigLive.BlankPages = getEnv("BLANK_PAGES", "off")
+	serverConfigLive.BlankPageInk = getEnvFloat("BLANK_PAGE_INK", 0.1)
+
+	// Recompressing scans to save storage, run as a job
+	serverConfigLive.OptimiseDPI = getEnvInt("OPTIMISE_DPI", 150)
+	serverConfigLive.OptimiseJPEGQuality = getEnvInt("OPTIMISE_JPEG_QUALITY", 75)
+	serverConfigLive.OptimiseMinSaving = getEnvInt("OPTIMISE_MIN_SAVING", 10)
+	if originalsPath := getEnv("OPTIMISE_ORIGINALS_PATH", ""); originalsPath != "" {
+		absolute, err := filepath.Abs(filepath.ToSlash(originalsPath))
+		if err != nil {
+			logger.Error("Failed creating absolute path for optimised originals directory", "error", err)
+		}
+		serverConfigLive.OriginalsPath = absolute
+	}
+
+	// Storage quotas per folder, enforced at upload and ingestion
+	quotas, err := ParseFolderQuotas(getEnv("FOLDER_QUOTAS", ""))
+	if err != nil {
+		logger.Error("Ignoring invalid FOLDER_QUOTAS", "error", err)
+	}
+	serverConfigLive.FolderQuotas = quotas
+	warnPercents, err := ParseWarnPercents(getEnv("QUOTA_WARN_PERCENT", "80,95"))
+	if err != nil {
+		logger.Error("Ignoring invalid QUOTA_WARN_PERCENT", "error", err)
+	}
+	serverConfigLive.QuotaWarnPercents = warnPercents
+
+	// File types accepted by ingestion, uploads and the orphan scan
+	extensions, err := ParseExtensions(getEnv("PROCESSABLE_EXTENSIONS", strings.Join(DefaultProcessableExtensions, ",")))
+	if err != nil {
+		logger.Error("Ignoring invalid PROCESSABLE_EXTENSIONS", "error", err)
+		extensions = DefaultProcessableExtensions
+	}
+	serverConfigLive.IngestExtensions = extensions
+
+	// Retries for ingestion and cleanup job writes when the database connection drops
+	serverConfigLive.DBRetryAttempts = getEnvInt("DB_RETRY_ATTEMPTS", 3)
+	serverConfigLive.DBRetryBackoffMS = getEnvInt("DB_RETRY_BACKOFF_MS", 200)
+
+	// Queries slower than this are logged with their caller and listed at /api/admin/slow-queries
+	serverConfigLive.SlowQueryMS = getEnvInt("SLOW_QUERY_MS", 500)
+
+	// Algorithm document hashes are stored with, existing documents move over with the rehash job
+	serverConfigLive.HashAlgorithm = getEnv("HASH_ALGORITHM", "sha256")
+
+	// Sidecar services (containerised deployments), checked at startup and by /api/health
+	serverConfigLive.PDFServiceURL = getEnv("PDF_SERVICE_URL", "")
+	serverConfigLive.TesseractServiceURL = getEnv("TESSERACT_SERVICE_URL", "")
+	serverConfigLive.ServiceCheckRetries = getEnvInt("SERVICE_CHECK_RETRIES", 10)
+	serverConfigLive.ServiceCheckInterval = getEnvInt("SERVICE_CHECK_INTERVAL", 3)
+
 	// Authentication configuration
 	serverConfigLive.WebUIPass = getEnvBool("WEB_UI_AUTH", false)
 	serverConfigLive.ClientUsername = getEnv("WEB_UI_USER", "admin")
 	serverConfigLive.ClientPassword = getEnv("WEB_UI_PASSWORD", "Password1")
+	serverConfigLive.SessionHours = getEnvInt("SESSION_HOURS", 168)
 
 	// Reverse proxy configuration
 	serverConfigLive.UseReverseProxy = getEnvBool("PROXY_ENABLED", false)
@@ -199,9 +374,112 @@ func SetupServer() (ServerConfig, *slog.Logger) {
 	frontEndConfigLive.ServerAPIURL = getEnv("SERVER_API_URL", "")
 	serverConfigLive.FrontEndConfig = frontEndConfigLive
 
+	// Signed document URL configuration
+	serverConfigLive.URLSigningKey = getEnv("URL_SIGNING_KEY", "")
+	if serverConfigLive.URLSigningKey == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			logger.Error("Unable to generate URL signing key", "error", err)
+		}
+		serverConfigLive.URLSigningKey = hex.EncodeToString(key)
+		logger.Warn("URL_SIGNING_KEY not set, generated a random key; signed document links will stop working on restart")
+	}
+	serverConfigLive.SignedURLTTL = getEnvInt("SIGNED_URL_TTL", 900)
+	serverConfigLive.RequireSignedURLs = getEnvBool("REQUIRE_SIGNED_URLS", false)
+
+	// API response cache configuration
+	serverConfigLive.CacheType = getEnv("CACHE_TYPE", "memory")
+	serverConfigLive.RedisURL = getEnv("REDIS_URL", "redis://localhost:6379/0")
+	serverConfigLive.CacheTTL = getEnvInt("CACHE_TTL", 300)
+	serverConfigLive.CacheSize = getEnvInt("CACHE_SIZE", 1000)
+
+	// Dropzone webhook configuration
+	serverConfigLive.DropzoneAPIKey = getEnv("DROPZONE_API_KEY", "")
+	serverConfigLive.DropzoneFolder = getEnv("DROPZONE_FOLDER", "dropzone")
+	serverConfigLive.DropzoneMaxMB = getEnvInt("DROPZONE_MAX_MB", 100)
+
+	// Nextcloud (WebDAV) ingest source configuration
+	serverConfigLive.NextcloudURL = getEnv("NEXTCLOUD_URL", "")
+	serverConfigLive.NextcloudUser = getEnv("NEXTCLOUD_USER", "")
+	serverConfigLive.NextcloudPassword = getEnv("NEXTCLOUD_PASSWORD", "")
+	serverConfigLive.NextcloudIngestPath = getEnv("NEXTCLOUD_INGEST_PATH", "/Scans")
+	serverConfigLive.NextcloudWriteBack = getEnv("NEXTCLOUD_WRITEBACK_PATH", "")
+
+	// SMB/CIFS ingest source configuration
+	serverConfigLive.SMBHost = getEnv("SMB_HOST", "")
+	serverConfigLive.SMBShare = getEnv("SMB_SHARE", "")
+	serverConfigLive.SMBUser = getEnv("SMB_USER", "")
+	serverConfigLive.SMBPassword = getEnv("SMB_PASSWORD", "")
+	serverConfigLive.SMBDomain = getEnv("SMB_DOMAIN", "")
+	serverConfigLive.SMBIngestPath = getEnv("SMB_INGEST_PATH", "")
+
 	// Notifications
 	serverConfigLive.PushBulletToken = getEnv("PUSHBULLET_TOKEN", "")
 
+	// Post-ingestion hooks, e.g. to push documents into accounting software
+	serverConfigLive.PostIngestCommand = getEnv("POST_INGEST_COMMAND", "")
+	webhooks, err := ParseWebhookURLs(getEnv("POST_INGEST_WEBHOOKS", ""))
+	if err != nil {
+		logger.Error("Ignoring invalid POST_INGEST_WEBHOOKS", "error", err)
+	}
+	serverConfigLive.PostIngestWebhooks = webhooks
+	serverConfigLive.PostIngestTimeout = getEnvInt("POST_INGEST_TIMEOUT", 30)
+
+	// Pre-ingestion transforms, e.g. to decrypt or convert files; files they fail on are quarantined
+	serverConfigLive.PreIngestCommand = getEnv("PRE_INGEST_COMMAND", "")
+	if preIngestURL := getEnv("PRE_INGEST_URL", ""); preIngestURL != "" {
+		if urls, err := ParseWebhookURLs(preIngestURL); err != nil || len(urls) != 1 {
+			logger.Error("Ignoring PRE_INGEST_URL, it must be one http or https URL", "error", err)
+		} else {
+			serverConfigLive.PreIngestURL = urls[0]
+		}
+	}
+	serverConfigLive.PreIngestTimeout = getEnvInt("PRE_INGEST_TIMEOUT", 120)
+	quarantinePath, err := filepath.Abs(filepath.ToSlash(getEnv("QUARANTINE_PATH", "quarantine")))
+	if err != nil {
+		logger.Error("Failed creating absolute path for quarantine directory", "error", err)
+	}
+	serverConfigLive.QuarantinePath = quarantinePath
+
+	// Release check, off by default so godocs does not call out unless asked to
+	serverConfigLive.UpdateCheck = getEnvBool("UPDATE_CHECK", false)
+	serverConfigLive.UpdateCheckURL = getEnv("UPDATE_CHECK_URL", "https://api.github.com/repos/drummonds/godocs/releases/latest")
+
+	// Ordering of names in the file tree
+	serverConfigLive.SortLocale = getEnv("SORT_LOCALE", "")
+	if serverConfigLive.SortLocale != "" {
+		if _, err := language.Parse(serverConfigLive.SortLocale); err != nil {
+			logger.Warn("Ignoring SORT_LOCALE, expected a language tag such as en, de or sv", "locale", serverConfigLive.SortLocale, "error", err)
+			serverConfigLive.SortLocale = ""
+		}
+	}
+
+	// Cold storage for old documents, which stay searchable but are restored before they are viewed
+	serverConfigLive.ArchiveAfterDays = getEnvInt("ARCHIVE_AFTER_DAYS", 0)
+	if archivePath := getEnv("ARCHIVE_PATH", ""); archivePath != "" {
+		absolute, err := filepath.Abs(filepath.ToSlash(archivePath))
+		if err != nil {
+			logger.Error("Failed creating absolute path for archive directory", "error", err)
+		}
+		serverConfigLive.ArchivePath = absolute
+	}
+
+	// Administrators, the only users who may place or lift legal holds
+	serverConfigLive.AdminUsers = ParseUserList(getEnv("ADMIN_USERS", ""))
+
+	// Read-only mode for maintenance windows such as storage migrations and backups
+	serverConfigLive.ReadOnly = getEnvBool("READ_ONLY", false)
+	serverConfigLive.ReadOnlyMessage = getEnv("READ_ONLY_MESSAGE", "")
+
+	// Tracing of requests, ingestion steps and database queries, sent to an OpenTelemetry collector
+	serverConfigLive.OTLPEndpoint = getEnv("OTLP_ENDPOINT", "")
+	serverConfigLive.TraceServiceName = getEnv("OTEL_SERVICE_NAME", "godocs")
+	serverConfigLive.TraceSampleRatio = getEnvFloat("TRACE_SAMPLE_RATIO", 1)
+	if ratio := serverConfigLive.TraceSampleRatio; ratio <= 0 || ratio > 1 {
+		logger.Warn("Ignoring TRACE_SAMPLE_RATIO, expected a number over 0 and up to 1", "ratio", ratio)
+		serverConfigLive.TraceSampleRatio = 1
+	}
+
 	logger.Info("About to setup database", "type", serverConfigLive.DatabaseType)
 
 	return serverConfigLive, logger
@@ -211,9 +489,9 @@ func SetupServer() (ServerConfig, *slog.Logger) {
 func SetupFrontend() (FrontEndConfig, *slog.Logger) {
 	// Load .env file (silently ignore if doesn't exist)
 	// Try production location first, then local development files
-	_ = godotenv.Load("/etc/godocs.env")
-	_ = godotenv.Load(".env")
-	_ = godotenv.Load("config.env")
+	for _, file := range ConfigFiles {
+		_ = godotenv.Load(file)
+	}
 	_ = godotenv.Load("frontend.env")
 
 	logger := setupLogging()
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultProcessableExtensions are the file types godocs can extract text from, used when
+// PROCESSABLE_EXTENSIONS is not set
+var DefaultProcessableExtensions = []string{".pdf", ".txt", ".rtf", ".doc", ".docx", ".odf", ".tiff", ".jpg", ".jpeg", ".png"}
+
+// ParseExtensions reads PROCESSABLE_EXTENSIONS, a comma separated list of file extensions such as
+// "pdf,.png,JPG". Extensions are lower cased, given a leading dot and de-duplicated, keeping their order.
+func ParseExtensions(value string) ([]string, error) {
+	var extensions []string
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		ext := strings.ToLower(strings.TrimSpace(entry))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if ext == "." || strings.ContainsAny(ext[1:], `./\ `) {
+			return nil, fmt.Errorf("invalid file extension %q", strings.TrimSpace(entry))
+		}
+		if !seen[ext] {
+			seen[ext] = true
+			extensions = append(extensions, ext)
+		}
+	}
+	if len(extensions) == 0 {
+		return nil, fmt.Errorf("no file extensions given")
+	}
+	return extensions, nil
+}
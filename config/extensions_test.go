@@ -0,0 +1,21 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExtensions(t *testing.T) {
+	extensions, err := ParseExtensions(" pdf, .PNG,,jpg,.pdf ")
+	if err != nil {
+		t.Fatalf("ParseExtensions failed: %v", err)
+	}
+	if want := []string{".pdf", ".png", ".jpg"}; !reflect.DeepEqual(extensions, want) {
+		t.Errorf("ParseExtensions = %v, want %v", extensions, want)
+	}
+	for _, value := range []string{"", " , ", ".", "tar.gz", "a/b", "my ext"} {
+		if _, err := ParseExtensions(value); err == nil {
+			t.Errorf("ParseExtensions(%q) should fail", value)
+		}
+	}
+}
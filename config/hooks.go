@@ -0,0 +1,24 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseWebhookURLs reads POST_INGEST_WEBHOOKS, a comma separated list of http or https URLs, and PRE_INGEST_URL
+func ParseWebhookURLs(value string) ([]string, error) {
+	var urls []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parsed, err := url.Parse(entry)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("webhook %q is not an http or https URL", entry)
+		}
+		urls = append(urls, entry)
+	}
+	return urls, nil
+}
@@ -0,0 +1,18 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWebhookURLs(t *testing.T) {
+	urls, err := ParseWebhookURLs(" https://books.example.com/hook?token=abc, http://localhost:9000/in ,")
+	if err != nil || !reflect.DeepEqual(urls, []string{"https://books.example.com/hook?token=abc", "http://localhost:9000/in"}) {
+		t.Errorf("ParseWebhookURLs = %v, %v", urls, err)
+	}
+	for _, value := range []string{"books.example.com/hook", "ftp://example.com/in", "https://"} {
+		if _, err := ParseWebhookURLs(value); err == nil {
+			t.Errorf("ParseWebhookURLs(%q) should fail", value)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// JobTimeouts maps a job type (ingestion, backup, ...) to how long it may go without progress
+type JobTimeouts map[string]time.Duration
+
+// ParseJobTimeouts reads JOB_TIMEOUTS, a comma separated list of type=duration pairs such as
+// "ingestion=2h,backup=30m", giving how long an active job of each type may go without progress
+func ParseJobTimeouts(value string) (JobTimeouts, error) {
+	timeouts := make(JobTimeouts)
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		jobType, duration, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("job timeout %q is not type=duration", strings.TrimSpace(entry))
+		}
+		jobType = strings.ToLower(strings.TrimSpace(jobType))
+		timeout, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q for %s jobs", strings.TrimSpace(duration), jobType)
+		}
+		timeouts[jobType] = timeout
+	}
+	return timeouts, nil
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseJobTimeouts(t *testing.T) {
+	timeouts, err := ParseJobTimeouts(" Ingestion=2h, backup=30m,")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := JobTimeouts{"ingestion": 2 * time.Hour, "backup": 30 * time.Minute}
+	if !reflect.DeepEqual(timeouts, want) {
+		t.Errorf("ParseJobTimeouts = %v, want %v", timeouts, want)
+	}
+	for _, value := range []string{"ingestion", "ingestion=soon", "backup=-5m"} {
+		if _, err := ParseJobTimeouts(value); err == nil {
+			t.Errorf("ParseJobTimeouts(%q) should fail", value)
+		}
+	}
+}
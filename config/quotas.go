@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// byteUnits are the size suffixes accepted in quotas, in powers of 1024
+var byteUnits = map[string]int64{"": 1, "B": 1, "KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30, "TB": 1 << 40}
+
+// ParseByteSize reads a size such as 500MB, 1.5GB or 2048 (bytes)
+func ParseByteSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	number := strings.TrimRightFunc(value, func(r rune) bool { return r >= 'A' && r <= 'Z' })
+	unit, ok := byteUnits[strings.TrimSpace(value[len(number):])]
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit in %q", value)
+	}
+	size, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(size * float64(unit)), nil
+}
+
+// ParseFolderQuotas reads FOLDER_QUOTAS, a comma separated list of folder=size pairs with folders relative
+// to the document root, e.g. "tax=1GB,scans/2024=500MB". A quota covers the folder and its subfolders;
+// "/" covers the whole document root.
+func ParseFolderQuotas(value string) (map[string]int64, error) {
+	quotas := make(map[string]int64)
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		folder, size, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("quota %q is not folder=size", strings.TrimSpace(entry))
+		}
+		limit, err := ParseByteSize(size)
+		if err != nil {
+			return nil, fmt.Errorf("quota for %q: %w", strings.TrimSpace(folder), err)
+		}
+		folder = strings.Trim(path.Clean("/"+strings.ReplaceAll(strings.TrimSpace(folder), `\`, "/")), "/")
+		quotas[folder] = limit
+	}
+	return quotas, nil
+}
+
+// ParseWarnPercents reads QUOTA_WARN_PERCENT, a comma separated list of usage percentages that raise a
+// warning as a folder fills up, returned in ascending order
+func ParseWarnPercents(value string) ([]int, error) {
+	var percents []int
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		percent, err := strconv.Atoi(strings.TrimSpace(entry))
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid warning percentage %q, expected 1 to 100", strings.TrimSpace(entry))
+		}
+		percents = append(percents, percent)
+	}
+	sort.Ints(percents)
+	return percents, nil
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	for value, want := range map[string]int64{"2048": 2048, "10 KB": 10 << 10, "500mb": 500 << 20, "1.5GB": 3 << 29, "1TB": 1 << 40} {
+		if got, err := ParseByteSize(value); err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "MB", "-5MB", "12 parsecs"} {
+		if _, err := ParseByteSize(value); err == nil {
+			t.Errorf("ParseByteSize(%q) should fail", value)
+		}
+	}
+}
+
+func TestParseFolderQuotas(t *testing.T) {
+	quotas, err := ParseFolderQuotas(" tax=1GB, /scans/2024/=500MB,\\letters=10KB,/=2TB,")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]int64{"tax": 1 << 30, "scans/2024": 500 << 20, "letters": 10 << 10, "": 2 << 40}
+	if !reflect.DeepEqual(quotas, want) {
+		t.Errorf("ParseFolderQuotas = %v, want %v", quotas, want)
+	}
+	for _, value := range []string{"tax", "tax=lots"} {
+		if _, err := ParseFolderQuotas(value); err == nil {
+			t.Errorf("ParseFolderQuotas(%q) should fail", value)
+		}
+	}
+}
+
+func TestParseWarnPercents(t *testing.T) {
+	if got, err := ParseWarnPercents("95, 80"); err != nil || !reflect.DeepEqual(got, []int{80, 95}) {
+		t.Errorf("ParseWarnPercents = %v, %v", got, err)
+	}
+	if _, err := ParseWarnPercents("120"); err == nil {
+		t.Error("Percentages over 100 should be rejected")
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+// ConfigFiles are the env files loaded at startup, production location first.
+// Variables already set in the environment are never overridden.
+var ConfigFiles = []string{"/etc/godocs.env", ".env", "config.env"}
+
+// SetupFile is where the first-run setup wizard saves its settings
+var SetupFile = ".env"
+
+// setupEnvVars are the settings that show a deployment is configured through the environment alone
+var setupEnvVars = []string{"DATABASE_TYPE", "DATABASE_HOST", "DATABASE_NAME", "DOCUMENT_PATH", "INGRESS_PATH"}
+
+// Configured reports whether any config file exists or the core settings come from the environment.
+// When it is false the server starts in setup mode.
+func Configured() bool {
+	for _, file := range ConfigFiles {
+		if _, err := os.Stat(file); err == nil {
+			return true
+		}
+	}
+	for _, key := range setupEnvVars {
+		if os.Getenv(key) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteSetupFile saves settings as KEY=value lines in SetupFile, readable only by the owner since it holds passwords
+func WriteSetupFile(settings map[string]string) error {
+	content, err := godotenv.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(SetupFile, []byte(content+"\n"), 0600)
+}
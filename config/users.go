@@ -0,0 +1,18 @@
+package config
+
+import "strings"
+
+// ParseUserList reads ADMIN_USERS, a comma separated list of user names; blanks and duplicates are dropped
+func ParseUserList(value string) []string {
+	var users []string
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		users = append(users, entry)
+	}
+	return users
+}
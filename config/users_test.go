@@ -0,0 +1,16 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseUserList(t *testing.T) {
+	users := ParseUserList(" alice, bob ,,alice")
+	if !reflect.DeepEqual(users, []string{"alice", "bob"}) {
+		t.Errorf("ParseUserList = %v", users)
+	}
+	if users := ParseUserList(""); users != nil {
+		t.Errorf("ParseUserList(\"\") = %v, want none", users)
+	}
+}
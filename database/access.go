@@ -0,0 +1,107 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// DocumentAccess is a document with how often its file has been viewed or downloaded
+type DocumentAccess struct {
+	Document
+	AccessCount  int        // all-time opens, from the counter on the document
+	PeriodCount  int        // opens since the requested day, from the daily rollups
+	LastAccessed *time.Time // nil if the document has never been opened
+}
+
+// accessDay is the rollup bucket for an access, as an ISO date in UTC
+func accessDay(at time.Time) string {
+	return at.UTC().Format(time.DateOnly)
+}
+
+// documentAccessQuery builds the access statistics query shared by the SQL repositories.
+// With a zero since the all-time counter is the period count and the rollups are not read.
+// Popular listings only include opened documents; leastUsed lists every document, never-opened and oldest first.
+func documentAccessQuery(since time.Time, leastUsed bool, placeholder func(n int) string) (string, []interface{}) {
+	columns := `d.id, d.name, d.path, d.ingress_time, d.folder, d.hash, d.ulid, d.document_type, d.mime_type, d.size, d.page_count, d.ocr_status, d.url, d.access_count, d.last_accessed`
+	var query string
+	var args []interface{}
+	if since.IsZero() {
+		query = `SELECT ` + columns + `, d.access_count AS period_count FROM documents d`
+		if !leastUsed {
+			query += ` WHERE d.access_count > 0`
+		}
+	} else {
+		query = `SELECT ` + columns + `, COALESCE(SUM(a.count), 0) AS period_count FROM documents d
+			LEFT JOIN document_access_daily a ON a.document_ulid = d.ulid AND a.day >= ` + placeholder(1) + `
+			GROUP BY d.id`
+		if !leastUsed {
+			query += ` HAVING COALESCE(SUM(a.count), 0) > 0`
+		}
+		args = append(args, accessDay(since))
+	}
+	if leastUsed {
+		query += ` ORDER BY period_count ASC, d.ingress_time ASC, d.id ASC`
+	} else {
+		query += ` ORDER BY period_count DESC, d.ingress_time DESC, d.id DESC`
+	}
+	return query + ` LIMIT ` + placeholder(len(args)+1), args
+}
+
+// RecordDocumentAccess counts one view or download of a document, on its counter and in today's rollup
+func (p *PostgresDB) RecordDocumentAccess(ulidStr string, at time.Time) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE documents SET access_count = access_count + 1, last_accessed = $1 WHERE ulid = $2`, at, ulidStr)
+	if err != nil {
+		return err
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = tx.Exec(`INSERT INTO document_access_daily (document_ulid, day, count) VALUES ($1, $2, 1)
+		ON CONFLICT (document_ulid, day) DO UPDATE SET count = document_access_daily.count + 1`, ulidStr, accessDay(at))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetDocumentAccessStats lists documents by how often they were opened since the given day (zero for all time)
+func (p *PostgresDB) GetDocumentAccessStats(since time.Time, limit int, leastUsed bool) ([]DocumentAccess, error) {
+	query, args := documentAccessQuery(since, leastUsed, func(n int) string { return fmt.Sprintf("$%d", n) })
+	rows, err := p.db.Query(query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []DocumentAccess
+	for rows.Next() {
+		var stat DocumentAccess
+		var ulidStr string
+		var lastAccessed sql.NullTime
+		err := rows.Scan(
+			&stat.StormID, &stat.Name, &stat.Path, &stat.IngressTime,
+			&stat.Folder, &stat.Hash, &ulidStr, &stat.DocumentType, &stat.MIMEType, &stat.Size, &stat.PageCount, &stat.OCRStatus, &stat.URL,
+			&stat.AccessCount, &lastAccessed, &stat.PeriodCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if stat.ULID, err = ulid.Parse(ulidStr); err != nil {
+			return nil, fmt.Errorf("failed to parse ULID: %w", err)
+		}
+		if lastAccessed.Valid {
+			stat.LastAccessed = &lastAccessed.Time
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
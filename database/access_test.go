@@ -0,0 +1,110 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/oklog/ulid/v2"
+)
+
+// testRepositories opens an empty repository of each kind that runs without a database server
+func testRepositories() map[string]func() Repository {
+	if Logger == nil {
+		Logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	}
+	return map[string]func() Repository{
+		"sqlite": func() Repository {
+			return NewRepository(config.ServerConfig{DatabaseType: "sqlite", DatabaseDbname: ":memory:"})
+		},
+		"memory": func() Repository { return NewMemoryDB() },
+	}
+}
+
+func TestDocumentAccessStats(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// Three documents, one opened three times last month and once today, one opened twice today, one never
+			db := open()
+			defer db.Close()
+			now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+			var docs []*Document
+			for i := 0; i < 3; i++ {
+				doc := &Document{
+					Name:         fmt.Sprintf("doc%d.pdf", i),
+					Path:         fmt.Sprintf("/docs/doc%d.pdf", i),
+					IngressTime:  now.Add(time.Duration(i-10) * time.Hour),
+					Folder:       "/docs",
+					Hash:         fmt.Sprintf("hash%d", i),
+					ULID:         ulid.Make(),
+					DocumentType: ".pdf",
+					FullText:     "text",
+				}
+				if err := db.SaveDocument(doc); err != nil {
+					t.Fatalf("SaveDocument failed: %v", err)
+				}
+				docs = append(docs, doc)
+			}
+			accesses := []struct {
+				doc *Document
+				at  time.Time
+			}{
+				{docs[0], now.AddDate(0, -1, 0)},
+				{docs[0], now.AddDate(0, -1, 0)},
+				{docs[0], now.AddDate(0, -1, 1)},
+				{docs[0], now},
+				{docs[1], now},
+				{docs[1], now.Add(time.Hour)},
+			}
+			for _, access := range accesses {
+				if err := db.RecordDocumentAccess(access.doc.ULID.String(), access.at); err != nil {
+					t.Fatalf("RecordDocumentAccess failed: %v", err)
+				}
+			}
+
+			// Listing all-time, recent and least used documents
+			allTime, err := db.GetDocumentAccessStats(time.Time{}, 10, false)
+			if err != nil {
+				t.Fatalf("GetDocumentAccessStats failed: %v", err)
+			}
+			recent, err := db.GetDocumentAccessStats(now.AddDate(0, 0, -7), 10, false)
+			if err != nil {
+				t.Fatalf("GetDocumentAccessStats failed: %v", err)
+			}
+			leastUsed, err := db.GetDocumentAccessStats(time.Time{}, 2, true)
+			if err != nil {
+				t.Fatalf("GetDocumentAccessStats failed: %v", err)
+			}
+
+			// All-time follows the counter, the window follows the rollups, and unopened documents come first when pruning
+			if len(allTime) != 2 || allTime[0].ULID != docs[0].ULID || allTime[0].PeriodCount != 4 || allTime[1].PeriodCount != 2 {
+				t.Errorf("Unexpected all-time stats: %+v", allTime)
+			}
+			if allTime[0].LastAccessed == nil || !allTime[0].LastAccessed.Equal(now) {
+				t.Errorf("Expected last access %v, got %v", now, allTime[0].LastAccessed)
+			}
+			if len(recent) != 2 || recent[0].ULID != docs[1].ULID || recent[0].PeriodCount != 2 || recent[1].PeriodCount != 1 || recent[1].AccessCount != 4 {
+				t.Errorf("Unexpected recent stats: %+v", recent)
+			}
+			if len(leastUsed) != 2 || leastUsed[0].ULID != docs[2].ULID || leastUsed[0].AccessCount != 0 || leastUsed[0].LastAccessed != nil {
+				t.Errorf("Expected the unopened document first, got %+v", leastUsed)
+			}
+
+			// Unknown documents are reported and deleted documents drop out of the stats
+			if err := db.RecordDocumentAccess(ulid.Make().String(), now); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows for an unknown document, got %v", err)
+			}
+			if err := db.DeleteDocument(docs[0].ULID.String()); err != nil {
+				t.Fatalf("DeleteDocument failed: %v", err)
+			}
+			if stats, _ := db.GetDocumentAccessStats(time.Time{}, 10, false); len(stats) != 1 {
+				t.Errorf("Expected only the remaining opened document, got %+v", stats)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// AuditEvent is one entry in the audit log of changes to documents and settings. With finished jobs it
+// makes up the activity feed.
+type AuditEvent struct {
+	ID        string    `json:"id"`
+	Action    string    `json:"action"` // one of the Audit* actions
+	Target    string    `json:"target"` // document ULID, or the settings that changed
+	Name      string    `json:"name"`   // document name, kept so the entry reads well after a delete
+	Detail    string    `json:"detail"` // e.g. the folder a document moved to
+	User      string    `json:"user"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Audit log actions
+const (
+	AuditDocumentAdded   = "document_added"
+	AuditDocumentMoved   = "document_moved"
+	AuditDocumentDeleted = "document_deleted"
+	AuditSettingsChanged = "settings_changed"
+	AuditTagRenamed      = "tag_renamed"
+	AuditTagMerged       = "tag_merged"
+)
+
+// prepareAuditEvent fills in the fields RecordAuditEvent sets on a new entry
+func prepareAuditEvent(event *AuditEvent) {
+	if event.ID == "" {
+		event.ID = MakeULID().String()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = Now()
+	}
+	event.CreatedAt = event.CreatedAt.UTC()
+}
+
+const auditEventColumns = `id, action, target, name, detail, user_name, created_at`
+
+// RecordAuditEvent adds an entry to the audit log, setting its ID and CreatedAt when missing
+func (p *PostgresDB) RecordAuditEvent(event *AuditEvent) error {
+	prepareAuditEvent(event)
+	_, err := p.db.Exec(`INSERT INTO audit_events (`+auditEventColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		event.ID, event.Action, event.Target, event.Name, event.Detail, event.User, event.CreatedAt)
+	return err
+}
+
+// ListAuditEvents returns up to limit audit log entries after the cursor, newest first. A nil cursor
+// starts from the newest entry.
+func (p *PostgresDB) ListAuditEvents(before *ActivityCursor, limit int) ([]AuditEvent, error) {
+	if before == nil {
+		before = &ActivityCursor{At: farFuture}
+	}
+	rows, err := p.db.Query(`SELECT `+auditEventColumns+` FROM audit_events WHERE (created_at, id) < ($1, $2) ORDER BY created_at DESC, id DESC LIMIT $3`, before.At, before.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []AuditEvent
+	for rows.Next() {
+		var event AuditEvent
+		if err := rows.Scan(&event.ID, &event.Action, &event.Target, &event.Name, &event.Detail, &event.User, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// ListFinishedJobs returns up to limit jobs that completed, failed or were cancelled after the cursor,
+// most recently finished first. A nil cursor starts from the most recent.
+func (p *PostgresDB) ListFinishedJobs(before *ActivityCursor, limit int) ([]Job, error) {
+	if before == nil {
+		before = &ActivityCursor{At: farFuture}
+	}
+	query := `
+		SELECT id, type, status, progress, current_step, total_steps, message, error, result,
+		       created_at, updated_at, started_at, completed_at
+		FROM jobs
+		WHERE status IN ($1, $2, $3) AND (completed_at, id) < ($4, $5)
+		ORDER BY completed_at DESC, id DESC
+		LIMIT $6
+	`
+	rows, err := p.db.Query(query, JobStatusCompleted, JobStatusFailed, JobStatusCancelled, before.At, before.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var job Job
+		var idStr string
+		err := rows.Scan(&idStr, &job.Type, &job.Status, &job.Progress, &job.CurrentStep, &job.TotalSteps,
+			&job.Message, &job.Error, &job.Result, &job.CreatedAt, &job.UpdatedAt, &job.StartedAt, &job.CompletedAt)
+		if err != nil {
+			return nil, err
+		}
+		if job.ID, err = ulid.Parse(idStr); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// ActivityCursor marks a position in the newest-first activity feed for keyset pagination. Audit entries
+// and finished jobs are ordered by time then ID, both descending, so entries sharing a time are neither
+// skipped nor repeated across pages.
+type ActivityCursor struct {
+	At time.Time
+	ID string
+}
+
+// follows reports whether the entry at at with id comes after the cursor; everything follows a nil cursor
+func (cursor *ActivityCursor) follows(at time.Time, id string) bool {
+	return cursor == nil || at.Before(cursor.At) || (at.Equal(cursor.At) && id < cursor.ID)
+}
+
+// farFuture stands in for "no upper bound" in the before queries
+var farFuture = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
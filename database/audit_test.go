@@ -0,0 +1,114 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuditEventsAndFinishedJobs(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// Three audit entries a minute apart, and a finished, a failed and a running job
+			db := open()
+			defer db.Close()
+			defer SetClock(NewFixedClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), time.Minute))()
+			for _, action := range []string{AuditDocumentAdded, AuditDocumentMoved, AuditDocumentDeleted} {
+				if err := db.RecordAuditEvent(&AuditEvent{Action: action, Target: "01ARZ3NDEKTSV4RRFFQ69G5FAV", Name: "bill.pdf", User: "alice"}); err != nil {
+					t.Fatalf("RecordAuditEvent failed: %v", err)
+				}
+			}
+			completed, _ := db.CreateJob(JobTypeIngestion, "ingest")
+			failed, _ := db.CreateJob(JobTypeBackup, "backup")
+			db.CreateJob(JobTypeCleanup, "still running")
+			if err := db.CompleteJob(completed.ID, `{}`); err != nil {
+				t.Fatalf("CompleteJob failed: %v", err)
+			}
+			if err := db.UpdateJobError(failed.ID, "disk full"); err != nil {
+				t.Fatalf("UpdateJobError failed: %v", err)
+			}
+
+			// The newest two entries are listed, then the rest
+			events, err := db.ListAuditEvents(nil, 2)
+			if err != nil {
+				t.Fatalf("ListAuditEvents failed: %v", err)
+			}
+			if len(events) != 2 || events[0].Action != AuditDocumentDeleted || events[1].Action != AuditDocumentMoved || events[0].User != "alice" {
+				t.Fatalf("Expected the delete then the move, got %+v", events)
+			}
+			rest, err := db.ListAuditEvents(&ActivityCursor{At: events[1].CreatedAt, ID: events[1].ID}, 10)
+			if err != nil || len(rest) != 1 || rest[0].Action != AuditDocumentAdded || rest[0].Name != "bill.pdf" {
+				t.Errorf("Expected only the add before the move, got %+v, %v", rest, err)
+			}
+
+			// Only finished jobs are listed, most recently finished first
+			jobs, err := db.ListFinishedJobs(nil, 10)
+			if err != nil {
+				t.Fatalf("ListFinishedJobs failed: %v", err)
+			}
+			if len(jobs) != 2 || jobs[0].ID != failed.ID || jobs[1].ID != completed.ID {
+				t.Fatalf("Expected the failed then the completed job, got %+v", jobs)
+			}
+			older, err := db.ListFinishedJobs(&ActivityCursor{At: *jobs[0].CompletedAt, ID: jobs[0].ID.String()}, 10)
+			if err != nil || len(older) != 1 || older[0].ID != completed.ID {
+				t.Errorf("Expected the completed job before the failure, got %+v, %v", older, err)
+			}
+		})
+	}
+}
+
+func TestActivityCursorKeepsEntriesSharingATime(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			db := open()
+			defer db.Close()
+			defer SetClock(NewFixedClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), 0))()
+			defer SetIDGenerator(NewSeededIDs(1))()
+			for i := 0; i < 3; i++ {
+				db.RecordAuditEvent(&AuditEvent{Action: AuditDocumentAdded, Name: "bill.pdf"})
+				job, _ := db.CreateJob(JobTypeBackup, "backup")
+				db.CompleteJob(job.ID, `{}`)
+			}
+
+			t.Run("audit events", func(t *testing.T) {
+				var cursor *ActivityCursor
+				seen := map[string]bool{}
+				for {
+					events, err := db.ListAuditEvents(cursor, 2)
+					if err != nil {
+						t.Fatalf("ListAuditEvents failed: %v", err)
+					}
+					for _, event := range events {
+						seen[event.ID] = true
+					}
+					if len(events) < 2 {
+						break
+					}
+					cursor = &ActivityCursor{At: events[1].CreatedAt, ID: events[1].ID}
+				}
+				if len(seen) != 3 {
+					t.Errorf("Expected all 3 entries across the pages, got %d", len(seen))
+				}
+			})
+			t.Run("finished jobs", func(t *testing.T) {
+				var cursor *ActivityCursor
+				seen := map[string]bool{}
+				for {
+					jobs, err := db.ListFinishedJobs(cursor, 2)
+					if err != nil {
+						t.Fatalf("ListFinishedJobs failed: %v", err)
+					}
+					for _, job := range jobs {
+						seen[job.ID.String()] = true
+					}
+					if len(jobs) < 2 {
+						break
+					}
+					cursor = &ActivityCursor{At: *jobs[1].CompletedAt, ID: jobs[1].ID.String()}
+				}
+				if len(seen) != 3 {
+					t.Errorf("Expected all 3 jobs across the pages, got %d", len(seen))
+				}
+			})
+		})
+	}
+}
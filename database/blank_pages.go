@@ -0,0 +1,48 @@
+package database
+
+import "fmt"
+
+// BlankPage is a page of a scan found blank at ingestion, numbered as it was scanned
+type BlankPage struct {
+	Page    int     `json:"page"`    // from 1
+	Ink     float64 `json:"ink"`     // percent of the page inked
+	Removed bool    `json:"removed"` // dropped from the stored document
+}
+
+// SaveBlankPages records the blank pages found in a document, replacing what was recorded before
+func (p *PostgresDB) SaveBlankPages(documentULID string, pages []BlankPage) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM document_blank_pages WHERE document_ulid = $1`, documentULID); err != nil {
+		return err
+	}
+	for _, page := range pages {
+		if _, err := tx.Exec(`INSERT INTO document_blank_pages (document_ulid, page, ink, removed) VALUES ($1, $2, $3, $4)`,
+			documentULID, page.Page, page.Ink, page.Removed); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetBlankPages returns the blank pages found in a document in page order, none when there were none
+func (p *PostgresDB) GetBlankPages(documentULID string) ([]BlankPage, error) {
+	rows, err := p.db.Query(`SELECT page, ink, removed FROM document_blank_pages WHERE document_ulid = $1 ORDER BY page`, documentULID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	pages := []BlankPage{}
+	for rows.Next() {
+		var page BlankPage
+		if err := rows.Scan(&page.Page, &page.Ink, &page.Removed); err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+	return pages, rows.Err()
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBlankPages(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// No blank pages recorded for a document
+			db := open()
+			defer db.Close()
+			const id = "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+			if pages, err := db.GetBlankPages(id); err != nil || len(pages) != 0 {
+				t.Fatalf("Expected no blank pages before any are saved, got %v, %v", pages, err)
+			}
+
+			// The blank backs of a duplex scan are saved, one of them kept
+			saved := []BlankPage{{Page: 4, Ink: 0.02, Removed: true}, {Page: 2, Ink: 0.05, Removed: false}}
+			if err := db.SaveBlankPages(id, saved); err != nil {
+				t.Fatalf("SaveBlankPages failed: %v", err)
+			}
+
+			// They are returned in page order
+			pages, err := db.GetBlankPages(id)
+			want := []BlankPage{{Page: 2, Ink: 0.05, Removed: false}, {Page: 4, Ink: 0.02, Removed: true}}
+			if err != nil || !slices.Equal(pages, want) {
+				t.Errorf("Expected %v, got %v, %v", want, pages, err)
+			}
+
+			// Saving again replaces them
+			if err := db.SaveBlankPages(id, nil); err != nil {
+				t.Fatalf("SaveBlankPages failed: %v", err)
+			}
+			if pages, err := db.GetBlankPages(id); err != nil || len(pages) != 0 {
+				t.Errorf("Expected the blank pages to be cleared, got %v, %v", pages, err)
+			}
+		})
+	}
+}
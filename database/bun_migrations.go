@@ -49,6 +49,40 @@ func runMigrations(ctx context.Context, db *bun.DB) error {
 		{"002", "add_fulltext_search", init002AddFullTextSearch},
 		{"003", "add_word_cloud", init003AddWordCloud},
 		{"004", "create_jobs_table", init004CreateJobsTable},
+		{"005", "add_keyset_index", init005AddKeysetIndex},
+		{"006", "create_folders_table", init006CreateFoldersTable},
+		{"007", "add_folder_prefix_index", init007AddFolderPrefixIndex},
+		{"008", "add_document_access", init008AddDocumentAccess},
+		{"009", "create_search_queries", init009CreateSearchQueries},
+		{"010", "create_collections", init010CreateCollections},
+		{"011", "add_document_mime_type", init011AddDocumentMIMEType},
+		{"012", "create_job_schedules", init012CreateJobSchedules},
+		{"013", "create_document_events", init013CreateDocumentEvents},
+		{"014", "create_smart_folders", init014CreateSmartFolders},
+		{"015", "create_document_locks", init015CreateDocumentLocks},
+		{"016", "add_folder_style", init016AddFolderStyle},
+		{"017", "add_document_size_pages", init017AddDocumentSizePages},
+		{"018", "create_document_archives", init018CreateDocumentArchives},
+		{"019", "create_legal_holds", init019CreateLegalHolds},
+		{"020", "create_document_redactions", init020CreateDocumentRedactions},
+		{"021", "add_document_ocr_status", init021AddDocumentOCRStatus},
+		{"022", "create_audit_events", init022CreateAuditEvents},
+		{"023", "create_ingest_rejections", init023CreateIngestRejections},
+		{"024", "create_document_spreadsheets", init024CreateDocumentSpreadsheets},
+		{"025", "create_users", init025CreateUsers},
+		{"026", "add_roles_and_folder_permissions", init026AddRolesAndFolderPermissions},
+		{"027", "add_filter_indexes", init027AddFilterIndexes},
+		{"028", "create_tags", init028CreateTags},
+		{"029", "create_client_errors", init029CreateClientErrors},
+		{"030", "create_document_rotations", init030CreateDocumentRotations},
+		{"031", "create_document_blank_pages", init031CreateDocumentBlankPages},
+		{"032", "create_document_optimisations", init032CreateDocumentOptimisations},
+		{"033", "create_document_fingerprints", init033CreateDocumentFingerprints},
+		{"034", "create_extraction_templates", init034CreateExtractionTemplates},
+		{"035", "add_smart_folder_tag", init035AddSmartFolderTag},
+		{"036", "add_collection_owner", init036AddCollectionOwner},
+		{"037", "create_server_state", init037CreateServerState},
+		{"038", "add_smart_folder_correspondent", init038AddSmartFolderCorrespondent},
 	}
 
 	for _, m := range migrations {
@@ -449,3 +483,1099 @@ func init004RollbackJobsTable(ctx context.Context, db *bun.DB) error {
 	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS jobs")
 	return err
 }
+
+// Migration 005: Composite index for keyset pagination of the latest documents
+func init005AddKeysetIndex(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 005: Add keyset pagination index")
+
+	_, err := db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_documents_ingress_time_id ON documents(ingress_time DESC, id DESC)")
+	if err != nil {
+		return fmt.Errorf("failed to create keyset index: %w", err)
+	}
+
+	Logger.Info("Migration 005 completed successfully")
+	return nil
+}
+
+func init005RollbackKeysetIndex(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 005")
+
+	_, err := db.ExecContext(ctx, "DROP INDEX IF EXISTS idx_documents_ingress_time_id")
+	return err
+}
+
+// Migration 006: Create folders table for the materialised folder tree
+func init006CreateFoldersTable(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 006: Create folders table")
+
+	_, isPostgres := db.Dialect().(interface{ SupportsReturning() bool })
+	idColumn := "id INTEGER PRIMARY KEY AUTOINCREMENT"
+	if isPostgres {
+		idColumn = "id SERIAL PRIMARY KEY"
+	}
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS folders (
+			`+idColumn+`,
+			path TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL,
+			parent_id INTEGER REFERENCES folders(id) ON DELETE CASCADE,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create folders table: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_folders_parent_id ON folders(parent_id)"); err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
+	Logger.Info("Migration 006 completed successfully")
+	return nil
+}
+
+func init006RollbackFoldersTable(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 006")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS folders")
+	return err
+}
+
+// Migration 007: Index for recursive folder listings.
+// SQLite answers the prefix query as a range scan on idx_documents_folder; PostgreSQL needs a pattern index for LIKE.
+func init007AddFolderPrefixIndex(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 007: Add folder prefix index")
+
+	_, isPostgres := db.Dialect().(interface{ SupportsReturning() bool })
+	if !isPostgres {
+		Logger.Info("Migration 007 not needed for SQLite")
+		return nil
+	}
+
+	_, err := db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_documents_folder_pattern ON documents(folder text_pattern_ops)")
+	if err != nil {
+		return fmt.Errorf("failed to create folder prefix index: %w", err)
+	}
+
+	Logger.Info("Migration 007 completed successfully")
+	return nil
+}
+
+func init007RollbackFolderPrefixIndex(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 007")
+
+	_, err := db.ExecContext(ctx, "DROP INDEX IF EXISTS idx_documents_folder_pattern")
+	return err
+}
+
+// Migration 008: Document access counter and daily rollups for the popular documents listing
+func init008AddDocumentAccess(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 008: Add document access statistics")
+
+	_, isPostgres := db.Dialect().(interface{ SupportsReturning() bool })
+	dayColumn := "day TEXT NOT NULL" // ISO dates compare correctly as text
+	if isPostgres {
+		dayColumn = "day DATE NOT NULL"
+	}
+
+	statements := []string{
+		"ALTER TABLE documents ADD COLUMN access_count INTEGER NOT NULL DEFAULT 0",
+		"ALTER TABLE documents ADD COLUMN last_accessed TIMESTAMP",
+		`CREATE TABLE IF NOT EXISTS document_access_daily (
+			document_ulid TEXT NOT NULL,
+			` + dayColumn + `,
+			count INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (document_ulid, day)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_document_access_daily_day ON document_access_daily(day)",
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to add document access statistics: %w", err)
+		}
+	}
+
+	Logger.Info("Migration 008 completed successfully")
+	return nil
+}
+
+func init008RollbackDocumentAccess(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 008")
+
+	for _, statement := range []string{
+		"DROP TABLE IF EXISTS document_access_daily",
+		"ALTER TABLE documents DROP COLUMN last_accessed",
+		"ALTER TABLE documents DROP COLUMN access_count",
+	} {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migration 009: Search history for recent searches and zero-result analytics
+func init009CreateSearchQueries(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 009: Create search queries table")
+
+	_, isPostgres := db.Dialect().(interface{ SupportsReturning() bool })
+	idColumn := "id INTEGER PRIMARY KEY AUTOINCREMENT"
+	if isPostgres {
+		idColumn = "id SERIAL PRIMARY KEY"
+	}
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS search_queries (
+			`+idColumn+`,
+			term TEXT NOT NULL,
+			result_count INTEGER NOT NULL DEFAULT 0,
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			user_name TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create search_queries table: %w", err)
+	}
+
+	for _, index := range []string{
+		"CREATE INDEX IF NOT EXISTS idx_search_queries_user_created ON search_queries(user_name, created_at DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_search_queries_created_at ON search_queries(created_at)",
+	} {
+		if _, err := db.ExecContext(ctx, index); err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+
+	Logger.Info("Migration 009 completed successfully")
+	return nil
+}
+
+func init009RollbackSearchQueries(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 009")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS search_queries")
+	return err
+}
+
+// Migration 010: Collections of documents saved from search results
+func init010CreateCollections(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 010: Create collections tables")
+
+	_, isPostgres := db.Dialect().(interface{ SupportsReturning() bool })
+	idColumn := "id INTEGER PRIMARY KEY AUTOINCREMENT"
+	if isPostgres {
+		idColumn = "id SERIAL PRIMARY KEY"
+	}
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS collections (
+			` + idColumn + `,
+			ulid TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL,
+			term TEXT NOT NULL DEFAULT '',
+			share_token TEXT UNIQUE,
+			document_count INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS collection_documents (
+			collection_ulid TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			document_ulid TEXT NOT NULL,
+			PRIMARY KEY (collection_ulid, position)
+		)`,
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create collections tables: %w", err)
+		}
+	}
+
+	Logger.Info("Migration 010 completed successfully")
+	return nil
+}
+
+func init010RollbackCollections(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 010")
+
+	for _, table := range []string{"collection_documents", "collections"} {
+		if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS "+table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migration 011: Content type detected from each document's bytes at ingestion
+func init011AddDocumentMIMEType(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 011: Add document MIME type")
+
+	if _, err := db.ExecContext(ctx, "ALTER TABLE documents ADD COLUMN mime_type TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("failed to add document MIME type: %w", err)
+	}
+
+	Logger.Info("Migration 011 completed successfully")
+	return nil
+}
+
+func init011RollbackDocumentMIMEType(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 011")
+
+	_, err := db.ExecContext(ctx, "ALTER TABLE documents DROP COLUMN mime_type")
+	return err
+}
+
+// Migration 012: Cron schedules saved through the schedules API
+func init012CreateJobSchedules(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 012: Create job schedules table")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS job_schedules (
+			name TEXT PRIMARY KEY,
+			spec TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create job_schedules table: %w", err)
+	}
+
+	Logger.Info("Migration 012 completed successfully")
+	return nil
+}
+
+func init012RollbackJobSchedules(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 012")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS job_schedules")
+	return err
+}
+
+// Migration 013: Processing timeline of each document
+func init013CreateDocumentEvents(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 013: Create document events table")
+
+	_, isPostgres := db.Dialect().(interface{ SupportsReturning() bool })
+	idColumn := "id INTEGER PRIMARY KEY AUTOINCREMENT"
+	if isPostgres {
+		idColumn = "id SERIAL PRIMARY KEY"
+	}
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS document_events (
+			`+idColumn+`,
+			document_ulid TEXT NOT NULL,
+			stage TEXT NOT NULL,
+			detail TEXT NOT NULL DEFAULT '',
+			at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create document_events table: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_document_events_document ON document_events(document_ulid, at)"); err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
+	Logger.Info("Migration 013 completed successfully")
+	return nil
+}
+
+func init013RollbackDocumentEvents(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 013")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS document_events")
+	return err
+}
+
+// Migration 014: Smart folders defined by a saved query
+func init014CreateSmartFolders(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 014: Create smart folders table")
+
+	_, isPostgres := db.Dialect().(interface{ SupportsReturning() bool })
+	idColumn := "id INTEGER PRIMARY KEY AUTOINCREMENT"
+	if isPostgres {
+		idColumn = "id SERIAL PRIMARY KEY"
+	}
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS smart_folders (
+			`+idColumn+`,
+			ulid TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL,
+			term TEXT NOT NULL DEFAULT '',
+			date_from TEXT NOT NULL DEFAULT '',
+			date_to TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create smart_folders table: %w", err)
+	}
+
+	Logger.Info("Migration 014 completed successfully")
+	return nil
+}
+
+func init014RollbackSmartFolders(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 014")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS smart_folders")
+	return err
+}
+
+// Migration 015: Document locks (check-out for editing)
+func init015CreateDocumentLocks(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 015: Create document locks table")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS document_locks (
+			document_ulid TEXT PRIMARY KEY,
+			holder TEXT NOT NULL,
+			acquired_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create document_locks table: %w", err)
+	}
+
+	Logger.Info("Migration 015 completed successfully")
+	return nil
+}
+
+func init015RollbackDocumentLocks(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 015")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS document_locks")
+	return err
+}
+
+// Migration 016: Colour label and icon for each folder
+func init016AddFolderStyle(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 016: Add folder colour and icon")
+
+	for _, column := range []string{"color", "icon"} {
+		if _, err := db.ExecContext(ctx, "ALTER TABLE folders ADD COLUMN "+column+" TEXT NOT NULL DEFAULT ''"); err != nil {
+			return fmt.Errorf("failed to add folder %s: %w", column, err)
+		}
+	}
+
+	Logger.Info("Migration 016 completed successfully")
+	return nil
+}
+
+func init016RollbackFolderStyle(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 016")
+
+	for _, column := range []string{"icon", "color"} {
+		if _, err := db.ExecContext(ctx, "ALTER TABLE folders DROP COLUMN "+column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migration 017: File size and PDF page count of each document
+func init017AddDocumentSizePages(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 017: Add document size and page count")
+
+	for _, column := range []string{"size BIGINT", "page_count INTEGER"} {
+		if _, err := db.ExecContext(ctx, "ALTER TABLE documents ADD COLUMN "+column+" NOT NULL DEFAULT 0"); err != nil {
+			return fmt.Errorf("failed to add document %s: %w", column, err)
+		}
+	}
+
+	Logger.Info("Migration 017 completed successfully")
+	return nil
+}
+
+func init017RollbackDocumentSizePages(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 017")
+
+	for _, column := range []string{"page_count", "size"} {
+		if _, err := db.ExecContext(ctx, "ALTER TABLE documents DROP COLUMN "+column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migration 018: Document archives (cold storage)
+func init018CreateDocumentArchives(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 018: Create document archives table")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS document_archives (
+			document_ulid TEXT PRIMARY KEY,
+			archive_path TEXT NOT NULL,
+			archived_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			compressed_size BIGINT NOT NULL DEFAULT 0
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create document_archives table: %w", err)
+	}
+
+	Logger.Info("Migration 018 completed successfully")
+	return nil
+}
+
+func init018RollbackDocumentArchives(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 018")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS document_archives")
+	return err
+}
+
+// Migration 019: Legal holds and their audit trail
+func init019CreateLegalHolds(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 019: Create legal holds tables")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS legal_holds (
+			id TEXT PRIMARY KEY,
+			scope TEXT NOT NULL,
+			target TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			placed_by TEXT NOT NULL DEFAULT '',
+			placed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (scope, target)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create legal_holds table: %w", err)
+	}
+
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS legal_hold_events (
+			id TEXT PRIMARY KEY,
+			hold_id TEXT NOT NULL,
+			action TEXT NOT NULL,
+			scope TEXT NOT NULL,
+			target TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			user_name TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create legal_hold_events table: %w", err)
+	}
+
+	_, err = db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_legal_hold_events_created_at ON legal_hold_events(created_at)")
+	if err != nil {
+		return fmt.Errorf("failed to create legal hold events index: %w", err)
+	}
+
+	Logger.Info("Migration 019 completed successfully")
+	return nil
+}
+
+func init019RollbackLegalHolds(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 019")
+
+	if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS legal_hold_events"); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS legal_holds")
+	return err
+}
+
+// Migration 020: Redacted copy links
+func init020CreateDocumentRedactions(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 020: Create document redactions table")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS document_redactions (
+			document_ulid TEXT PRIMARY KEY,
+			source_ulid TEXT NOT NULL,
+			regions TEXT NOT NULL DEFAULT '[]',
+			created_by TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create document_redactions table: %w", err)
+	}
+
+	_, err = db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_document_redactions_source ON document_redactions(source_ulid)")
+	if err != nil {
+		return fmt.Errorf("failed to create document redactions index: %w", err)
+	}
+
+	Logger.Info("Migration 020 completed successfully")
+	return nil
+}
+
+func init020RollbackDocumentRedactions(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 020")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS document_redactions")
+	return err
+}
+
+// Migration 021: OCR status of each document
+func init021AddDocumentOCRStatus(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 021: Add document OCR status")
+
+	if _, err := db.ExecContext(ctx, "ALTER TABLE documents ADD COLUMN ocr_status TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("failed to add document ocr_status: %w", err)
+	}
+
+	Logger.Info("Migration 021 completed successfully")
+	return nil
+}
+
+func init021RollbackDocumentOCRStatus(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 021")
+
+	_, err := db.ExecContext(ctx, "ALTER TABLE documents DROP COLUMN ocr_status")
+	return err
+}
+
+// Migration 022: Audit log for the activity feed
+func init022CreateAuditEvents(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 022: Create audit events table")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS audit_events (
+			id TEXT PRIMARY KEY,
+			action TEXT NOT NULL,
+			target TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL DEFAULT '',
+			detail TEXT NOT NULL DEFAULT '',
+			user_name TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit_events table: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at)"); err != nil {
+		return fmt.Errorf("failed to create audit events index: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_jobs_completed_at ON jobs(completed_at)"); err != nil {
+		return fmt.Errorf("failed to create jobs completed_at index: %w", err)
+	}
+
+	Logger.Info("Migration 022 completed successfully")
+	return nil
+}
+
+func init022RollbackAuditEvents(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 022")
+
+	if _, err := db.ExecContext(ctx, "DROP INDEX IF EXISTS idx_jobs_completed_at"); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS audit_events")
+	return err
+}
+
+// Migration 023: Files rejected from the ingress folder
+func init023CreateIngestRejections(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 023: Create ingest rejections table")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS ingest_rejections (
+			id TEXT PRIMARY KEY,
+			path TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL DEFAULT '',
+			rejected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create ingest_rejections table: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_ingest_rejections_rejected_at ON ingest_rejections(rejected_at)"); err != nil {
+		return fmt.Errorf("failed to create ingest rejections index: %w", err)
+	}
+
+	Logger.Info("Migration 023 completed successfully")
+	return nil
+}
+
+func init023RollbackIngestRejections(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 023")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS ingest_rejections")
+	return err
+}
+
+// Migration 024: Sheet, row and column counts of spreadsheet documents
+func init024CreateDocumentSpreadsheets(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 024: Create document spreadsheets table")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS document_spreadsheets (
+			document_ulid TEXT PRIMARY KEY,
+			sheets INTEGER NOT NULL DEFAULT 0,
+			row_count INTEGER NOT NULL DEFAULT 0,
+			column_count INTEGER NOT NULL DEFAULT 0
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create document_spreadsheets table: %w", err)
+	}
+
+	Logger.Info("Migration 024 completed successfully")
+	return nil
+}
+
+func init024RollbackDocumentSpreadsheets(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 024")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS document_spreadsheets")
+	return err
+}
+
+func init025CreateUsers(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 025: Create users and sessions tables")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS users (
+			id TEXT PRIMARY KEY,
+			username TEXT NOT NULL UNIQUE,
+			password_hash TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create users table: %w", err)
+	}
+
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS sessions (
+			token_hash TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create sessions table: %w", err)
+	}
+
+	for _, index := range []string{
+		"CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id)",
+		"CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at)",
+	} {
+		if _, err := db.ExecContext(ctx, index); err != nil {
+			return fmt.Errorf("failed to create sessions index: %w", err)
+		}
+	}
+
+	Logger.Info("Migration 025 completed successfully")
+	return nil
+}
+
+func init025RollbackUsers(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 025")
+
+	if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS sessions"); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS users")
+	return err
+}
+
+// Migration 026: Add account roles and per-folder permissions
+func init026AddRolesAndFolderPermissions(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 026: Add account roles and folder permissions")
+
+	statements := []string{
+		"ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'editor'",
+		// Accounts from before roles existed could do everything
+		"UPDATE users SET role = 'admin'",
+		`CREATE TABLE IF NOT EXISTS folder_permissions (
+			folder TEXT NOT NULL,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			access TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (folder, user_id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_folder_permissions_user_id ON folder_permissions(user_id)",
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to add roles and folder permissions: %w", err)
+		}
+	}
+
+	Logger.Info("Migration 026 completed successfully")
+	return nil
+}
+
+func init026RollbackRolesAndFolderPermissions(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 026")
+
+	for _, statement := range []string{
+		"DROP TABLE IF EXISTS folder_permissions",
+		"ALTER TABLE users DROP COLUMN role",
+	} {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migration 027: Composite indexes for the common document filters
+func init027AddFilterIndexes(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 027: Add document filter indexes")
+
+	for _, statement := range []string{
+		"CREATE INDEX IF NOT EXISTS idx_documents_folder_ingress_time ON documents(folder, ingress_time DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_documents_document_type ON documents(document_type)",
+	} {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create document filter index: %w", err)
+		}
+	}
+
+	Logger.Info("Migration 027 completed successfully")
+	return nil
+}
+
+func init027RollbackFilterIndexes(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 027")
+
+	for _, statement := range []string{
+		"DROP INDEX IF EXISTS idx_documents_document_type",
+		"DROP INDEX IF EXISTS idx_documents_folder_ingress_time",
+	} {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migration 028: Tags and the documents that carry them
+func init028CreateTags(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 028: Create tags")
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS tags (
+			name TEXT PRIMARY KEY,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS document_tags (
+			document_ulid TEXT NOT NULL,
+			tag TEXT NOT NULL REFERENCES tags(name) ON DELETE CASCADE,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (document_ulid, tag)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_document_tags_tag ON document_tags(tag)",
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create tags: %w", err)
+		}
+	}
+
+	Logger.Info("Migration 028 completed successfully")
+	return nil
+}
+
+func init028RollbackTags(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 028")
+
+	for _, statement := range []string{
+		"DROP TABLE IF EXISTS document_tags",
+		"DROP TABLE IF EXISTS tags",
+	} {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migration 029: Errors reported by the web UI
+func init029CreateClientErrors(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 029: Create client errors table")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS client_errors (
+			id TEXT PRIMARY KEY,
+			kind TEXT NOT NULL DEFAULT '',
+			message TEXT NOT NULL DEFAULT '',
+			stack TEXT NOT NULL DEFAULT '',
+			page TEXT NOT NULL DEFAULT '',
+			user_agent TEXT NOT NULL DEFAULT '',
+			username TEXT NOT NULL DEFAULT '',
+			reported_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create client_errors table: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_client_errors_reported_at ON client_errors(reported_at)"); err != nil {
+		return fmt.Errorf("failed to create client errors index: %w", err)
+	}
+
+	Logger.Info("Migration 029 completed successfully")
+	return nil
+}
+
+func init029RollbackClientErrors(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 029")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS client_errors")
+	return err
+}
+
+// Migration 030: Pages of scans turned upright at ingestion
+func init030CreateDocumentRotations(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 030: Create document rotations table")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS document_rotations (
+			document_ulid TEXT NOT NULL,
+			page INTEGER NOT NULL,
+			degrees INTEGER NOT NULL,
+			PRIMARY KEY (document_ulid, page)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create document_rotations table: %w", err)
+	}
+
+	Logger.Info("Migration 030 completed successfully")
+	return nil
+}
+
+func init030RollbackDocumentRotations(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 030")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS document_rotations")
+	return err
+}
+
+// Migration 031: Blank pages found in scans at ingestion
+func init031CreateDocumentBlankPages(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 031: Create document blank pages table")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS document_blank_pages (
+			document_ulid TEXT NOT NULL,
+			page INTEGER NOT NULL,
+			ink DOUBLE PRECISION NOT NULL DEFAULT 0,
+			removed BOOLEAN NOT NULL DEFAULT FALSE,
+			PRIMARY KEY (document_ulid, page)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create document_blank_pages table: %w", err)
+	}
+
+	Logger.Info("Migration 031 completed successfully")
+	return nil
+}
+
+func init031RollbackDocumentBlankPages(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 031")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS document_blank_pages")
+	return err
+}
+
+// Migration 032: Scanned documents recompressed to save storage
+func init032CreateDocumentOptimisations(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 032: Create document optimisations table")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS document_optimisations (
+			document_ulid TEXT PRIMARY KEY,
+			original_hash TEXT NOT NULL,
+			original_size BIGINT NOT NULL DEFAULT 0,
+			optimised_size BIGINT NOT NULL DEFAULT 0,
+			original_path TEXT NOT NULL DEFAULT '',
+			optimised_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create document_optimisations table: %w", err)
+	}
+	_, err = db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_document_optimisations_original_hash ON document_optimisations (original_hash)`)
+	if err != nil {
+		return fmt.Errorf("failed to create document_optimisations index: %w", err)
+	}
+
+	Logger.Info("Migration 032 completed successfully")
+	return nil
+}
+
+func init032RollbackDocumentOptimisations(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 032")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS document_optimisations")
+	return err
+}
+
+// Migration 033: Similarity hashes for finding near-duplicates
+func init033CreateDocumentFingerprints(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 033: Create document fingerprints table")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS document_fingerprints (
+			document_ulid TEXT PRIMARY KEY,
+			image_hash TEXT NOT NULL DEFAULT '',
+			text_hash TEXT NOT NULL DEFAULT ''
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create document_fingerprints table: %w", err)
+	}
+
+	Logger.Info("Migration 033 completed successfully")
+	return nil
+}
+
+func init033RollbackDocumentFingerprints(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 033")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS document_fingerprints")
+	return err
+}
+
+// Migration 034: Extraction templates and the fields they read
+func init034CreateExtractionTemplates(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 034: Create extraction templates and document fields tables")
+
+	_, isPostgres := db.Dialect().(interface{ SupportsReturning() bool })
+	idColumn := "id INTEGER PRIMARY KEY AUTOINCREMENT"
+	if isPostgres {
+		idColumn = "id SERIAL PRIMARY KEY"
+	}
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS extraction_templates (
+			`+idColumn+`,
+			ulid TEXT NOT NULL UNIQUE,
+			correspondent TEXT NOT NULL,
+			match_pattern TEXT NOT NULL,
+			fields TEXT NOT NULL DEFAULT '[]',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create extraction_templates table: %w", err)
+	}
+
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS document_fields (
+			document_ulid TEXT NOT NULL,
+			name TEXT NOT NULL,
+			value TEXT NOT NULL DEFAULT '',
+			template_ulid TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (document_ulid, name)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create document_fields table: %w", err)
+	}
+
+	Logger.Info("Migration 034 completed successfully")
+	return nil
+}
+
+func init034RollbackExtractionTemplates(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 034")
+
+	if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS document_fields"); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS extraction_templates")
+	return err
+}
+
+// Migration 035: Tag filter of smart folders
+func init035AddSmartFolderTag(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 035: Add smart folder tag")
+
+	if _, err := db.ExecContext(ctx, "ALTER TABLE smart_folders ADD COLUMN tag TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("failed to add smart folder tag: %w", err)
+	}
+
+	Logger.Info("Migration 035 completed successfully")
+	return nil
+}
+
+func init035RollbackSmartFolderTag(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 035")
+
+	_, err := db.ExecContext(ctx, "ALTER TABLE smart_folders DROP COLUMN tag")
+	return err
+}
+
+// Migration 036: Account that created each collection, whose folder permissions its share link keeps
+func init036AddCollectionOwner(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 036: Add collection owner")
+
+	if _, err := db.ExecContext(ctx, "ALTER TABLE collections ADD COLUMN created_by TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("failed to add collection owner: %w", err)
+	}
+
+	Logger.Info("Migration 036 completed successfully")
+	return nil
+}
+
+func init036RollbackCollectionOwner(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 036")
+
+	_, err := db.ExecContext(ctx, "ALTER TABLE collections DROP COLUMN created_by")
+	return err
+}
+
+// Migration 037: State shared by the API server and a separate worker, such as read-only mode
+func init037CreateServerState(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 037: Create server state table")
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS server_state (
+			name TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create server_state table: %w", err)
+	}
+
+	Logger.Info("Migration 037 completed successfully")
+	return nil
+}
+
+func init037RollbackServerState(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 037")
+
+	_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS server_state")
+	return err
+}
+
+// Migration 038: Correspondent filter of smart folders
+func init038AddSmartFolderCorrespondent(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Running migration 038: Add smart folder correspondent")
+
+	if _, err := db.ExecContext(ctx, "ALTER TABLE smart_folders ADD COLUMN correspondent TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("failed to add smart folder correspondent: %w", err)
+	}
+
+	Logger.Info("Migration 038 completed successfully")
+	return nil
+}
+
+func init038RollbackSmartFolderCorrespondent(ctx context.Context, db *bun.DB) error {
+	Logger.Info("Rolling back migration 038")
+
+	_, err := db.ExecContext(ctx, "ALTER TABLE smart_folders DROP COLUMN correspondent")
+	return err
+}
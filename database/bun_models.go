@@ -1,6 +1,7 @@
 package database
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/oklog/ulid/v2"
@@ -19,6 +20,10 @@ type BunDocument struct {
 	Hash           string    `bun:"hash,notnull"`
 	ULID           string    `bun:"ulid,notnull,unique"` // Stored as string in DB
 	DocumentType   string    `bun:"document_type,notnull"`
+	MIMEType       string    `bun:"mime_type,notnull,default:''"`
+	Size           int64     `bun:"size,notnull,default:0"`
+	PageCount      int       `bun:"page_count,notnull,default:0"`
+	OCRStatus      string    `bun:"ocr_status,notnull,default:''"`
 	FullText       string    `bun:"full_text,nullzero"`
 	URL            string    `bun:"url,nullzero"`
 	FullTextSearch string    `bun:"full_text_search,type:tsvector,nullzero"` // PostgreSQL-specific
@@ -42,6 +47,10 @@ func (bd *BunDocument) ToDocument() (*Document, error) {
 		Hash:         bd.Hash,
 		ULID:         parsedULID,
 		DocumentType: bd.DocumentType,
+		MIMEType:     bd.MIMEType,
+		Size:         bd.Size,
+		PageCount:    bd.PageCount,
+		OCRStatus:    bd.OCRStatus,
 		FullText:     bd.FullText,
 		URL:          bd.URL,
 	}, nil
@@ -58,6 +67,10 @@ func FromDocument(doc *Document) *BunDocument {
 		Hash:         doc.Hash,
 		ULID:         doc.ULID.String(),
 		DocumentType: doc.DocumentType,
+		MIMEType:     doc.MIMEType,
+		Size:         doc.Size,
+		PageCount:    doc.PageCount,
+		OCRStatus:    doc.OCRStatus,
 		FullText:     doc.FullText,
 		URL:          doc.URL,
 	}
@@ -153,6 +166,24 @@ func FromJob(job *Job) *BunJob {
 	}
 }
 
+// BunFolder represents the folders table for Bun ORM
+type BunFolder struct {
+	bun.BaseModel `bun:"table:folders,alias:f"`
+
+	ID        int       `bun:"id,pk,autoincrement"`
+	Path      string    `bun:"path,notnull,unique"`
+	Name      string    `bun:"name,notnull"`
+	ParentID  int       `bun:"parent_id,nullzero"`
+	Color     string    `bun:"color,notnull,default:''"`
+	Icon      string    `bun:"icon,notnull,default:''"`
+	CreatedAt time.Time `bun:"created_at,notnull,default:current_timestamp"`
+}
+
+// ToFolder converts BunFolder to Folder
+func (bf *BunFolder) ToFolder() Folder {
+	return Folder{ID: bf.ID, Path: bf.Path, Name: bf.Name, ParentID: bf.ParentID, Color: bf.Color, Icon: bf.Icon}
+}
+
 // BunWordFrequency represents the word_frequencies table for Bun ORM
 type BunWordFrequency struct {
 	bun.BaseModel `bun:"table:word_frequencies,alias:wf"`
@@ -198,3 +229,513 @@ func (bwcm *BunWordCloudMetadata) ToWordCloudMetadata() *WordCloudMetadata {
 
 	return meta
 }
+
+// BunSearchQuery represents the search_queries table for Bun ORM
+type BunSearchQuery struct {
+	bun.BaseModel `bun:"table:search_queries,alias:sq"`
+
+	ID          int       `bun:"id,pk,autoincrement"`
+	Term        string    `bun:"term,notnull"`
+	ResultCount int       `bun:"result_count,notnull"`
+	DurationMs  int64     `bun:"duration_ms,notnull"`
+	User        string    `bun:"user_name,notnull"`
+	CreatedAt   time.Time `bun:"created_at,notnull"`
+}
+
+// ToSearchQuery converts BunSearchQuery to SearchQuery
+func (bsq *BunSearchQuery) ToSearchQuery() SearchQuery {
+	return SearchQuery{
+		ID:          bsq.ID,
+		Term:        bsq.Term,
+		ResultCount: bsq.ResultCount,
+		DurationMs:  bsq.DurationMs,
+		User:        bsq.User,
+		CreatedAt:   bsq.CreatedAt,
+	}
+}
+
+// BunCollection represents the collections table for Bun ORM
+type BunCollection struct {
+	bun.BaseModel `bun:"table:collections,alias:col"`
+
+	ID            int       `bun:"id,pk,autoincrement"`
+	ULID          string    `bun:"ulid,notnull,unique"`
+	Name          string    `bun:"name,notnull"`
+	Term          string    `bun:"term,notnull"`
+	ShareToken    string    `bun:"share_token,nullzero"`
+	DocumentCount int       `bun:"document_count,notnull"`
+	CreatedBy     string    `bun:"created_by,notnull"`
+	CreatedAt     time.Time `bun:"created_at,notnull"`
+}
+
+// BunCollectionDocument represents the collection_documents table for Bun ORM
+type BunCollectionDocument struct {
+	bun.BaseModel `bun:"table:collection_documents,alias:cd"`
+
+	CollectionULID string `bun:"collection_ulid,pk"`
+	Position       int    `bun:"position,pk"`
+	DocumentULID   string `bun:"document_ulid,notnull"`
+}
+
+// ToCollection converts BunCollection to Collection
+func (bc *BunCollection) ToCollection() (*Collection, error) {
+	parsedULID, err := ulid.Parse(bc.ULID)
+	if err != nil {
+		return nil, err
+	}
+	return &Collection{
+		ULID:          parsedULID,
+		Name:          bc.Name,
+		Term:          bc.Term,
+		ShareToken:    bc.ShareToken,
+		DocumentCount: bc.DocumentCount,
+		CreatedBy:     bc.CreatedBy,
+		CreatedAt:     bc.CreatedAt,
+	}, nil
+}
+
+// BunJobSchedule represents the job_schedules table for Bun ORM
+type BunJobSchedule struct {
+	bun.BaseModel `bun:"table:job_schedules,alias:js"`
+
+	Name      string    `bun:"name,pk"`
+	Spec      string    `bun:"spec,notnull"`
+	UpdatedAt time.Time `bun:"updated_at,notnull"`
+}
+
+// BunServerState represents the server_state table for Bun ORM
+type BunServerState struct {
+	bun.BaseModel `bun:"table:server_state,alias:ss"`
+
+	Name      string    `bun:"name,pk"`
+	Value     string    `bun:"value,notnull"`
+	UpdatedAt time.Time `bun:"updated_at,notnull"`
+}
+
+// BunDocumentEvent represents the document_events table for Bun ORM
+type BunDocumentEvent struct {
+	bun.BaseModel `bun:"table:document_events,alias:de"`
+
+	ID           int       `bun:"id,pk,autoincrement"`
+	DocumentULID string    `bun:"document_ulid,notnull"`
+	Stage        string    `bun:"stage,notnull"`
+	Detail       string    `bun:"detail,notnull"`
+	At           time.Time `bun:"at,notnull"`
+}
+
+// BunSmartFolder represents the smart_folders table for Bun ORM
+type BunSmartFolder struct {
+	bun.BaseModel `bun:"table:smart_folders,alias:sf"`
+
+	ID            int       `bun:"id,pk,autoincrement"`
+	ULID          string    `bun:"ulid,notnull,unique"`
+	Name          string    `bun:"name,notnull"`
+	Term          string    `bun:"term,notnull"`
+	DateFrom      string    `bun:"date_from,notnull"`
+	DateTo        string    `bun:"date_to,notnull"`
+	Tag           string    `bun:"tag,notnull"`
+	Correspondent string    `bun:"correspondent,notnull"`
+	CreatedAt     time.Time `bun:"created_at,notnull"`
+}
+
+// ToSmartFolder converts BunSmartFolder to SmartFolder
+func (bsf *BunSmartFolder) ToSmartFolder() (*SmartFolder, error) {
+	parsedULID, err := ulid.Parse(bsf.ULID)
+	if err != nil {
+		return nil, err
+	}
+	return &SmartFolder{
+		ULID:          parsedULID,
+		Name:          bsf.Name,
+		Term:          bsf.Term,
+		From:          bsf.DateFrom,
+		To:            bsf.DateTo,
+		Tag:           bsf.Tag,
+		Correspondent: bsf.Correspondent,
+		CreatedAt:     bsf.CreatedAt,
+	}, nil
+}
+
+// BunDocumentLock represents the document_locks table for Bun ORM
+type BunDocumentLock struct {
+	bun.BaseModel `bun:"table:document_locks,alias:dl"`
+
+	DocumentULID string    `bun:"document_ulid,pk"`
+	Holder       string    `bun:"holder,notnull"`
+	AcquiredAt   time.Time `bun:"acquired_at,notnull"`
+	ExpiresAt    time.Time `bun:"expires_at,notnull"`
+}
+
+// ToDocumentLock converts BunDocumentLock to DocumentLock
+func (bdl *BunDocumentLock) ToDocumentLock() *DocumentLock {
+	return &DocumentLock{
+		DocumentULID: bdl.DocumentULID,
+		Holder:       bdl.Holder,
+		AcquiredAt:   bdl.AcquiredAt,
+		ExpiresAt:    bdl.ExpiresAt,
+	}
+}
+
+// BunDocumentArchive represents the document_archives table for Bun ORM
+type BunDocumentArchive struct {
+	bun.BaseModel `bun:"table:document_archives,alias:da"`
+
+	DocumentULID   string    `bun:"document_ulid,pk"`
+	ArchivePath    string    `bun:"archive_path,notnull"`
+	ArchivedAt     time.Time `bun:"archived_at,notnull"`
+	CompressedSize int64     `bun:"compressed_size,notnull"`
+}
+
+// ToDocumentArchive converts BunDocumentArchive to DocumentArchive
+func (bda *BunDocumentArchive) ToDocumentArchive() *DocumentArchive {
+	return &DocumentArchive{
+		DocumentULID:   bda.DocumentULID,
+		ArchivePath:    bda.ArchivePath,
+		ArchivedAt:     bda.ArchivedAt,
+		CompressedSize: bda.CompressedSize,
+	}
+}
+
+// BunLegalHold represents the legal_holds table for Bun ORM
+type BunLegalHold struct {
+	bun.BaseModel `bun:"table:legal_holds,alias:lh"`
+
+	ID       string    `bun:"id,pk"`
+	Scope    string    `bun:"scope,notnull"`
+	Target   string    `bun:"target,notnull"`
+	Reason   string    `bun:"reason,notnull"`
+	PlacedBy string    `bun:"placed_by,notnull"`
+	PlacedAt time.Time `bun:"placed_at,notnull"`
+}
+
+// ToLegalHold converts BunLegalHold to LegalHold
+func (blh *BunLegalHold) ToLegalHold() *LegalHold {
+	return &LegalHold{
+		ID:       blh.ID,
+		Scope:    blh.Scope,
+		Target:   blh.Target,
+		Reason:   blh.Reason,
+		PlacedBy: blh.PlacedBy,
+		PlacedAt: blh.PlacedAt,
+	}
+}
+
+// BunLegalHoldEvent represents the legal_hold_events table for Bun ORM
+type BunLegalHoldEvent struct {
+	bun.BaseModel `bun:"table:legal_hold_events,alias:lhe"`
+
+	ID        string    `bun:"id,pk"`
+	HoldID    string    `bun:"hold_id,notnull"`
+	Action    string    `bun:"action,notnull"`
+	Scope     string    `bun:"scope,notnull"`
+	Target    string    `bun:"target,notnull"`
+	Reason    string    `bun:"reason,notnull"`
+	User      string    `bun:"user_name,notnull"`
+	CreatedAt time.Time `bun:"created_at,notnull"`
+}
+
+// ToLegalHoldEvent converts BunLegalHoldEvent to LegalHoldEvent
+func (blhe *BunLegalHoldEvent) ToLegalHoldEvent() *LegalHoldEvent {
+	return &LegalHoldEvent{
+		ID:        blhe.ID,
+		HoldID:    blhe.HoldID,
+		Action:    blhe.Action,
+		Scope:     blhe.Scope,
+		Target:    blhe.Target,
+		Reason:    blhe.Reason,
+		User:      blhe.User,
+		CreatedAt: blhe.CreatedAt,
+	}
+}
+
+// BunAuditEvent represents the audit_events table for Bun ORM
+type BunAuditEvent struct {
+	bun.BaseModel `bun:"table:audit_events,alias:ae"`
+
+	ID        string    `bun:"id,pk"`
+	Action    string    `bun:"action,notnull"`
+	Target    string    `bun:"target,notnull"`
+	Name      string    `bun:"name,notnull"`
+	Detail    string    `bun:"detail,notnull"`
+	User      string    `bun:"user_name,notnull"`
+	CreatedAt time.Time `bun:"created_at,notnull"`
+}
+
+// ToAuditEvent converts BunAuditEvent to AuditEvent
+func (bae *BunAuditEvent) ToAuditEvent() *AuditEvent {
+	return &AuditEvent{
+		ID:        bae.ID,
+		Action:    bae.Action,
+		Target:    bae.Target,
+		Name:      bae.Name,
+		Detail:    bae.Detail,
+		User:      bae.User,
+		CreatedAt: bae.CreatedAt,
+	}
+}
+
+// BunIngestRejection represents the ingest_rejections table for Bun ORM
+type BunIngestRejection struct {
+	bun.BaseModel `bun:"table:ingest_rejections,alias:ir"`
+
+	ID         string    `bun:"id,pk"`
+	Path       string    `bun:"path,notnull,unique"`
+	Name       string    `bun:"name,notnull"`
+	Reason     string    `bun:"reason,notnull"`
+	RejectedAt time.Time `bun:"rejected_at,notnull"`
+}
+
+// ToIngestRejection converts BunIngestRejection to IngestRejection
+func (bir *BunIngestRejection) ToIngestRejection() *IngestRejection {
+	return &IngestRejection{
+		ID:         bir.ID,
+		Path:       bir.Path,
+		Name:       bir.Name,
+		Reason:     bir.Reason,
+		RejectedAt: bir.RejectedAt,
+	}
+}
+
+// BunSpreadsheetDetails represents the document_spreadsheets table for Bun ORM
+type BunSpreadsheetDetails struct {
+	bun.BaseModel `bun:"table:document_spreadsheets,alias:ds"`
+
+	DocumentULID string `bun:"document_ulid,pk"`
+	Sheets       int    `bun:"sheets,notnull"`
+	Rows         int    `bun:"row_count,notnull"`
+	Columns      int    `bun:"column_count,notnull"`
+}
+
+// ToSpreadsheetDetails converts BunSpreadsheetDetails to SpreadsheetDetails
+func (bsd *BunSpreadsheetDetails) ToSpreadsheetDetails() *SpreadsheetDetails {
+	return &SpreadsheetDetails{
+		DocumentULID: bsd.DocumentULID,
+		Sheets:       bsd.Sheets,
+		Rows:         bsd.Rows,
+		Columns:      bsd.Columns,
+	}
+}
+
+// BunDocumentRotation represents the document_rotations table for Bun ORM
+type BunDocumentRotation struct {
+	bun.BaseModel `bun:"table:document_rotations,alias:dr"`
+
+	DocumentULID string `bun:"document_ulid,pk"`
+	Page         int    `bun:"page,pk"`
+	Degrees      int    `bun:"degrees,notnull"`
+}
+
+// BunBlankPage represents the document_blank_pages table for Bun ORM
+type BunBlankPage struct {
+	bun.BaseModel `bun:"table:document_blank_pages,alias:dbp"`
+
+	DocumentULID string  `bun:"document_ulid,pk"`
+	Page         int     `bun:"page,pk"`
+	Ink          float64 `bun:"ink,notnull"`
+	Removed      bool    `bun:"removed,notnull"`
+}
+
+// BunDocumentOptimisation represents the document_optimisations table for Bun ORM
+type BunDocumentOptimisation struct {
+	bun.BaseModel `bun:"table:document_optimisations,alias:dop"`
+
+	DocumentULID  string    `bun:"document_ulid,pk"`
+	OriginalHash  string    `bun:"original_hash,notnull"`
+	OriginalSize  int64     `bun:"original_size,notnull"`
+	OptimisedSize int64     `bun:"optimised_size,notnull"`
+	OriginalPath  string    `bun:"original_path,notnull"`
+	OptimisedAt   time.Time `bun:"optimised_at,notnull"`
+}
+
+// ToDocumentOptimisation converts BunDocumentOptimisation to DocumentOptimisation
+func (bdo *BunDocumentOptimisation) ToDocumentOptimisation() *DocumentOptimisation {
+	return &DocumentOptimisation{
+		DocumentULID:  bdo.DocumentULID,
+		OriginalHash:  bdo.OriginalHash,
+		OriginalSize:  bdo.OriginalSize,
+		OptimisedSize: bdo.OptimisedSize,
+		OriginalPath:  bdo.OriginalPath,
+		OptimisedAt:   bdo.OptimisedAt,
+	}
+}
+
+// BunDocumentFingerprint represents the document_fingerprints table for Bun ORM
+type BunDocumentFingerprint struct {
+	bun.BaseModel `bun:"table:document_fingerprints,alias:dfp"`
+
+	DocumentULID string `bun:"document_ulid,pk"`
+	ImageHash    string `bun:"image_hash,notnull"`
+	TextHash     string `bun:"text_hash,notnull"`
+}
+
+// BunExtractionTemplate represents the extraction_templates table for Bun ORM
+type BunExtractionTemplate struct {
+	bun.BaseModel `bun:"table:extraction_templates,alias:et"`
+
+	ID            int       `bun:"id,pk,autoincrement"`
+	ULID          string    `bun:"ulid,notnull,unique"`
+	Correspondent string    `bun:"correspondent,notnull"`
+	MatchPattern  string    `bun:"match_pattern,notnull"`
+	Fields        string    `bun:"fields,notnull"` // JSON array of ExtractionField
+	CreatedAt     time.Time `bun:"created_at,notnull"`
+}
+
+// ToExtractionTemplate converts BunExtractionTemplate to ExtractionTemplate
+func (bet *BunExtractionTemplate) ToExtractionTemplate() (*ExtractionTemplate, error) {
+	parsedULID, err := ulid.Parse(bet.ULID)
+	if err != nil {
+		return nil, err
+	}
+	template := &ExtractionTemplate{
+		ULID:          parsedULID,
+		Correspondent: bet.Correspondent,
+		Match:         bet.MatchPattern,
+		CreatedAt:     bet.CreatedAt,
+	}
+	if err := json.Unmarshal([]byte(bet.Fields), &template.Fields); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// BunDocumentField represents the document_fields table for Bun ORM
+type BunDocumentField struct {
+	bun.BaseModel `bun:"table:document_fields,alias:dfl"`
+
+	DocumentULID string `bun:"document_ulid,pk"`
+	Name         string `bun:"name,pk"`
+	Value        string `bun:"value,notnull"`
+	TemplateULID string `bun:"template_ulid,notnull"`
+}
+
+// BunUser represents the users table for Bun ORM
+type BunUser struct {
+	bun.BaseModel `bun:"table:users,alias:u"`
+
+	ID           string    `bun:"id,pk"`
+	Username     string    `bun:"username,notnull,unique"`
+	PasswordHash string    `bun:"password_hash,notnull"`
+	Role         string    `bun:"role,notnull"`
+	CreatedAt    time.Time `bun:"created_at,notnull"`
+}
+
+// ToUser converts BunUser to User
+func (bu *BunUser) ToUser() *User {
+	return &User{
+		ID:           bu.ID,
+		Username:     bu.Username,
+		PasswordHash: bu.PasswordHash,
+		Role:         bu.Role,
+		CreatedAt:    bu.CreatedAt,
+	}
+}
+
+// BunSession represents the sessions table for Bun ORM
+type BunSession struct {
+	bun.BaseModel `bun:"table:sessions,alias:s"`
+
+	TokenHash string    `bun:"token_hash,pk"`
+	UserID    string    `bun:"user_id,notnull"`
+	CreatedAt time.Time `bun:"created_at,notnull"`
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+}
+
+// ToSession converts BunSession to Session
+func (bs *BunSession) ToSession() *Session {
+	return &Session{
+		TokenHash: bs.TokenHash,
+		UserID:    bs.UserID,
+		CreatedAt: bs.CreatedAt,
+		ExpiresAt: bs.ExpiresAt,
+	}
+}
+
+// BunFolderPermission represents the folder_permissions table for Bun ORM
+type BunFolderPermission struct {
+	bun.BaseModel `bun:"table:folder_permissions,alias:fp"`
+
+	Folder    string    `bun:"folder,pk"`
+	UserID    string    `bun:"user_id,pk"`
+	Access    string    `bun:"access,notnull"`
+	CreatedAt time.Time `bun:"created_at,notnull"`
+}
+
+// ToFolderPermission converts BunFolderPermission to FolderPermission
+func (bp *BunFolderPermission) ToFolderPermission() FolderPermission {
+	return FolderPermission{
+		Folder:    bp.Folder,
+		UserID:    bp.UserID,
+		Access:    bp.Access,
+		CreatedAt: bp.CreatedAt,
+	}
+}
+
+// BunTag represents the tags table for Bun ORM
+type BunTag struct {
+	bun.BaseModel `bun:"table:tags,alias:t"`
+
+	Name      string    `bun:"name,pk"`
+	CreatedAt time.Time `bun:"created_at,notnull"`
+}
+
+// BunDocumentTag represents the document_tags table for Bun ORM
+type BunDocumentTag struct {
+	bun.BaseModel `bun:"table:document_tags,alias:dt"`
+
+	DocumentULID string    `bun:"document_ulid,pk"`
+	Tag          string    `bun:"tag,pk"`
+	CreatedAt    time.Time `bun:"created_at,notnull"`
+}
+
+// BunClientError represents the client_errors table for Bun ORM
+type BunClientError struct {
+	bun.BaseModel `bun:"table:client_errors,alias:ce"`
+
+	ID         string    `bun:"id,pk"`
+	Kind       string    `bun:"kind,notnull"`
+	Message    string    `bun:"message,notnull"`
+	Stack      string    `bun:"stack,notnull"`
+	Page       string    `bun:"page,notnull"`
+	UserAgent  string    `bun:"user_agent,notnull"`
+	User       string    `bun:"username,notnull"`
+	ReportedAt time.Time `bun:"reported_at,notnull"`
+}
+
+// ToClientError converts BunClientError to ClientError
+func (bce *BunClientError) ToClientError() *ClientError {
+	return &ClientError{
+		ID:         bce.ID,
+		Kind:       bce.Kind,
+		Message:    bce.Message,
+		Stack:      bce.Stack,
+		Page:       bce.Page,
+		UserAgent:  bce.UserAgent,
+		User:       bce.User,
+		ReportedAt: bce.ReportedAt,
+	}
+}
+
+// BunDocumentRedaction represents the document_redactions table for Bun ORM
+type BunDocumentRedaction struct {
+	bun.BaseModel `bun:"table:document_redactions,alias:dr"`
+
+	DocumentULID string    `bun:"document_ulid,pk"`
+	SourceULID   string    `bun:"source_ulid,notnull"`
+	Regions      string    `bun:"regions,notnull"` // JSON array of RedactionRegion
+	CreatedBy    string    `bun:"created_by,notnull"`
+	CreatedAt    time.Time `bun:"created_at,notnull"`
+}
+
+// ToDocumentRedaction converts BunDocumentRedaction to DocumentRedaction
+func (bdr *BunDocumentRedaction) ToDocumentRedaction() (*DocumentRedaction, error) {
+	redaction := &DocumentRedaction{
+		DocumentULID: bdr.DocumentULID,
+		SourceULID:   bdr.SourceULID,
+		CreatedBy:    bdr.CreatedBy,
+		CreatedAt:    bdr.CreatedAt,
+	}
+	if err := json.Unmarshal([]byte(bdr.Regions), &redaction.Regions); err != nil {
+		return nil, err
+	}
+	return redaction, nil
+}
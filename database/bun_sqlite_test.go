@@ -3,6 +3,7 @@ package database
 import (
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -247,4 +248,126 @@ func TestBunSQLiteDatabase(t *testing.T) {
 
 		t.Logf("Search test passed, found %d documents", len(results))
 	})
+
+	t.Run("Save document batch", func(t *testing.T) {
+		var docs []*Document
+		for _, name := range []string{"batch1.pdf", "batch2.pdf", "batch3.pdf"} {
+			docs = append(docs, &Document{
+				Name:         name,
+				Path:         "/tmp/" + name,
+				IngressTime:  time.Now(),
+				Folder:       "/tmp",
+				Hash:         "hash-" + name,
+				ULID:         ulid.Make(),
+				DocumentType: ".pdf",
+				FullText:     "zebrafish",
+			})
+		}
+
+		// Write the batch twice; the second write must update rather than duplicate
+		for i := 0; i < 2; i++ {
+			if err := db.SaveDocumentBatch(docs, map[string]int{"zebrafish": 3, "quokka": 1}); err != nil {
+				t.Fatalf("Failed to save batch: %v", err)
+			}
+		}
+
+		for _, doc := range docs {
+			if doc.StormID == 0 {
+				t.Errorf("Document ID was not set for %s", doc.Name)
+			}
+			saved, err := db.GetDocumentByPath(doc.Path)
+			if err != nil || saved.StormID != doc.StormID || saved.FullText != "zebrafish" {
+				t.Errorf("Unexpected saved document for %s: %+v, %v", doc.Name, saved, err)
+			}
+		}
+
+		words, err := db.GetTopWords(1000)
+		if err != nil {
+			t.Fatalf("Failed to get top words: %v", err)
+		}
+		counts := make(map[string]int)
+		for _, w := range words {
+			counts[w.Word] = w.Frequency
+		}
+		if counts["zebrafish"] != 6 || counts["quokka"] != 2 {
+			t.Errorf("Expected word counts to accumulate, got zebrafish=%d quokka=%d", counts["zebrafish"], counts["quokka"])
+		}
+	})
+
+	t.Run("Folder tree", func(t *testing.T) {
+		root, err := db.EnsureFolder("/docs", "")
+		if err != nil {
+			t.Fatalf("Failed to create root folder: %v", err)
+		}
+		child, err := db.EnsureFolder("/docs/2024", "/docs")
+		if err != nil {
+			t.Fatalf("Failed to create child folder: %v", err)
+		}
+		again, err := db.EnsureFolder("/docs/2024", "/docs")
+		if err != nil || again.ID != child.ID || child.ParentID != root.ID || child.Name != "2024" {
+			t.Errorf("Unexpected folder %+v (again %+v, %v)", child, again, err)
+		}
+		if _, err := db.EnsureFolder("/docs/2024/tax", "/docs/2024"); err != nil {
+			t.Fatalf("Failed to create grandchild folder: %v", err)
+		}
+		if _, err := db.EnsureFolder("/docs/2024-old", "/docs"); err != nil {
+			t.Fatalf("Failed to create sibling folder: %v", err)
+		}
+
+		// Deleting a folder takes its subtree but not siblings sharing a name prefix
+		removed, err := db.DeleteFolderTree("/docs/2024")
+		if err != nil || removed != 2 {
+			t.Errorf("Expected 2 folders removed, got %d, %v", removed, err)
+		}
+		folders, err := db.GetAllFolders()
+		if err != nil {
+			t.Fatalf("Failed to list folders: %v", err)
+		}
+		var paths []string
+		for _, folder := range folders {
+			paths = append(paths, folder.Path)
+		}
+		if len(paths) != 2 || paths[0] != "/docs" || paths[1] != "/docs/2024-old" {
+			t.Errorf("Unexpected folders after delete: %v", paths)
+		}
+
+		counts, err := db.CountDocumentsByFolder()
+		if err != nil || counts["/tmp"] == 0 {
+			t.Errorf("Expected documents counted in /tmp, got %v, %v", counts, err)
+		}
+	})
+
+	t.Run("Documents under folder", func(t *testing.T) {
+		for _, path := range []string{"/lib/a.pdf", "/lib/sub/b.pdf", "/lib/sub/deep/c.pdf", "/lib-old/d.pdf", "/li_/e.pdf"} {
+			doc := &Document{
+				Name:         path[strings.LastIndex(path, "/")+1:],
+				Path:         path,
+				IngressTime:  time.Now(),
+				Folder:       path[:strings.LastIndex(path, "/")],
+				Hash:         "hash-" + path,
+				ULID:         ulid.Make(),
+				DocumentType: ".pdf",
+			}
+			if err := db.SaveDocument(doc); err != nil {
+				t.Fatalf("Failed to save document: %v", err)
+			}
+		}
+
+		docs, err := db.GetDocumentsUnderFolder("/lib")
+		if err != nil {
+			t.Fatalf("Failed to list folder tree: %v", err)
+		}
+		var names []string
+		for _, doc := range docs {
+			names = append(names, doc.Name)
+		}
+		if strings.Join(names, ",") != "a.pdf,b.pdf,c.pdf" {
+			t.Errorf("Expected the /lib subtree only, got %v", names)
+		}
+
+		// Wildcard characters in the folder name match literally
+		if docs, err := db.GetDocumentsUnderFolder("/li_"); err != nil || len(docs) != 1 {
+			t.Errorf("Expected one document under /li_, got %d, %v", len(docs), err)
+		}
+	})
 }
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"time"
+)
+
+// Client error kinds, what went wrong in the web UI
+const (
+	ClientErrorPanic     = "panic"     // a Go panic in the WASM app
+	ClientErrorError     = "error"     // an uncaught JavaScript error
+	ClientErrorRejection = "rejection" // an unhandled promise rejection
+	ClientErrorFetch     = "fetch"     // an API call that failed or answered with a server error
+)
+
+// ClientError is an error the web UI reported from a user's browser, kept so problems that never reach
+// the server logs can still be seen
+type ClientError struct {
+	ID         string    `json:"id"`
+	Kind       string    `json:"kind"`
+	Message    string    `json:"message"`
+	Stack      string    `json:"stack,omitempty"`
+	Page       string    `json:"page,omitempty"` // the UI route the error happened on
+	UserAgent  string    `json:"userAgent,omitempty"`
+	User       string    `json:"user,omitempty"`
+	ReportedAt time.Time `json:"reportedAt"`
+}
+
+// prepareClientError fills in the fields RecordClientError sets on a new report
+func prepareClientError(report *ClientError) {
+	if report.ID == "" {
+		report.ID = MakeULID().String()
+	}
+	if report.ReportedAt.IsZero() {
+		report.ReportedAt = Now()
+	}
+	report.ReportedAt = report.ReportedAt.UTC()
+}
+
+const clientErrorColumns = `id, kind, message, stack, page, user_agent, username, reported_at`
+
+// RecordClientError stores an error report from the web UI
+func (p *PostgresDB) RecordClientError(report *ClientError) error {
+	prepareClientError(report)
+	_, err := p.db.Exec(`INSERT INTO client_errors (`+clientErrorColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		report.ID, report.Kind, report.Message, report.Stack, report.Page, report.UserAgent, report.User, report.ReportedAt)
+	return err
+}
+
+// ListClientErrors returns up to limit error reports, newest first
+func (p *PostgresDB) ListClientErrors(limit int) ([]ClientError, error) {
+	rows, err := p.db.Query(`SELECT `+clientErrorColumns+` FROM client_errors ORDER BY reported_at DESC, id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []ClientError
+	for rows.Next() {
+		var report ClientError
+		if err := rows.Scan(&report.ID, &report.Kind, &report.Message, &report.Stack, &report.Page,
+			&report.UserAgent, &report.User, &report.ReportedAt); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// PruneClientErrors removes all but the newest keep error reports and returns how many went
+func (p *PostgresDB) PruneClientErrors(keep int) (int, error) {
+	result, err := p.db.Exec(`DELETE FROM client_errors WHERE id NOT IN (
+		SELECT id FROM client_errors ORDER BY reported_at DESC, id DESC LIMIT $1)`, keep)
+	if err != nil {
+		return 0, err
+	}
+	removed, err := result.RowsAffected()
+	return int(removed), err
+}
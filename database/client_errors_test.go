@@ -0,0 +1,51 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientErrors(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// Three error reports a minute apart
+			db := open()
+			defer db.Close()
+			defer SetClock(NewFixedClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), time.Minute))()
+			for _, message := range []string{"first", "second", "third"} {
+				report := ClientError{Kind: ClientErrorPanic, Message: message, Stack: "goroutine 1", Page: "/search", User: "alice"}
+				if err := db.RecordClientError(&report); err != nil {
+					t.Fatalf("RecordClientError failed: %v", err)
+				}
+				if report.ID == "" || report.ReportedAt.IsZero() {
+					t.Fatalf("Expected an ID and report time, got %+v", report)
+				}
+			}
+
+			// They are listed
+			reports, err := db.ListClientErrors(2)
+
+			// The newest come first, with every field kept
+			if err != nil {
+				t.Fatalf("ListClientErrors failed: %v", err)
+			}
+			if len(reports) != 2 || reports[0].Message != "third" || reports[1].Message != "second" {
+				t.Fatalf("Expected third then second, got %+v", reports)
+			}
+			if reports[0].Kind != ClientErrorPanic || reports[0].Stack != "goroutine 1" || reports[0].Page != "/search" || reports[0].User != "alice" {
+				t.Errorf("Expected the report's details back, got %+v", reports[0])
+			}
+
+			// All but the newest is pruned
+			removed, err := db.PruneClientErrors(1)
+
+			// The two older reports go
+			if err != nil || removed != 2 {
+				t.Fatalf("Expected 2 reports pruned, got %d: %v", removed, err)
+			}
+			if reports, _ := db.ListClientErrors(10); len(reports) != 1 || reports[0].Message != "third" {
+				t.Errorf("Expected only the third report to remain, got %+v", reports)
+			}
+		})
+	}
+}
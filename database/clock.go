@@ -0,0 +1,122 @@
+package database
+
+import (
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// Clock tells the time. Ingress, job and record times and the ULIDs made from them all read it, so
+// tests and seeded demo data can fix it and get the same output on every run.
+type Clock interface {
+	Now() time.Time
+}
+
+// IDGenerator makes the ULIDs of new documents, jobs and other records
+type IDGenerator interface {
+	NewULID(t time.Time) (ulid.ULID, error)
+}
+
+// systemClock is the wall clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// timeSeededIDs seeds each ULID's entropy from its own time
+type timeSeededIDs struct{}
+
+func (timeSeededIDs) NewULID(t time.Time) (ulid.ULID, error) {
+	entropy := ulid.Monotonic(rand.New(rand.NewSource(t.UnixNano())), 0)
+	return ulid.New(ulid.Timestamp(t), entropy)
+}
+
+// FixedClock starts at a fixed time and moves on by a fixed step each time it is read, so records
+// made one after another still sort in the order they were made
+type FixedClock struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+// NewFixedClock returns a clock reading start, then start+step and so on
+func NewFixedClock(start time.Time, step time.Duration) *FixedClock {
+	return &FixedClock{now: start, step: step}
+}
+
+// Now returns the clock's time and moves it on by its step
+func (c *FixedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+// SeededIDs makes the same sequence of ULIDs for the same seed and times
+type SeededIDs struct {
+	mu      sync.Mutex
+	entropy io.Reader
+}
+
+// NewSeededIDs returns a generator whose ULIDs take their entropy from seed
+func NewSeededIDs(seed int64) *SeededIDs {
+	return &SeededIDs{entropy: rand.New(rand.NewSource(seed))}
+}
+
+// NewULID makes the next ULID in the sequence
+func (g *SeededIDs) NewULID(t time.Time) (ulid.ULID, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return ulid.New(ulid.Timestamp(t), g.entropy)
+}
+
+var (
+	clockMu     sync.RWMutex
+	clock       Clock       = systemClock{}
+	idGenerator IDGenerator = timeSeededIDs{}
+)
+
+// Now is the current time by the clock set with SetClock, the wall clock by default
+func Now() time.Time {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return clock.Now()
+}
+
+// SetClock makes c the clock for the database and engine packages, returning a function that puts
+// the previous one back: t.Cleanup(database.SetClock(...))
+func SetClock(c Clock) (restore func()) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	previous := clock
+	clock = c
+	return func() { SetClock(previous) }
+}
+
+// SetIDGenerator makes g the generator of new ULIDs, returning a function that puts the previous one back
+func SetIDGenerator(g IDGenerator) (restore func()) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	previous := idGenerator
+	idGenerator = g
+	return func() { SetIDGenerator(previous) }
+}
+
+// CalculateUUID makes the ULID of a record created at t with the generator set by SetIDGenerator
+func CalculateUUID(t time.Time) (ulid.ULID, error) {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return idGenerator.NewULID(t)
+}
+
+// MakeULID is ulid.Make by the injected clock and generator, panicking if the generator fails
+func MakeULID() ulid.ULID {
+	id, err := CalculateUUID(Now())
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixedClock(t *testing.T) {
+	// A clock starting at noon that moves on a second per reading
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewFixedClock(start, time.Second)
+
+	// Successive readings step on from the start
+	for i := 0; i < 3; i++ {
+		if got, want := clock.Now(), start.Add(time.Duration(i)*time.Second); !got.Equal(want) {
+			t.Errorf("Reading %d: expected %v, got %v", i, want, got)
+		}
+	}
+}
+
+func TestSeededIDsRepeat(t *testing.T) {
+	// Two generators with the same seed
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	first, second := NewSeededIDs(7), NewSeededIDs(7)
+
+	// They make the same ULIDs, each one new
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		a, errA := first.NewULID(at)
+		b, errB := second.NewULID(at)
+		if errA != nil || errB != nil {
+			t.Fatalf("NewULID failed: %v, %v", errA, errB)
+		}
+		if a != b {
+			t.Errorf("Expected the same ULID from the same seed, got %s and %s", a, b)
+		}
+		if seen[a.String()] {
+			t.Errorf("ULID %s repeated", a)
+		}
+		seen[a.String()] = true
+	}
+}
+
+func TestInjectedClockAndIDs(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// A fixed clock and seeded IDs in place of the wall clock
+			start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+			t.Cleanup(SetClock(NewFixedClock(start, time.Minute)))
+			t.Cleanup(SetIDGenerator(NewSeededIDs(1)))
+			db := open()
+			defer db.Close()
+			want, _ := NewSeededIDs(1).NewULID(start)
+
+			// A job is created
+			job, err := db.CreateJob(JobTypeIngestion, "Starting document ingestion")
+			if err != nil {
+				t.Fatalf("CreateJob failed: %v", err)
+			}
+
+			// Its ID and time come from them
+			if job.ID != want {
+				t.Errorf("Expected job ID %s, got %s", want, job.ID)
+			}
+			if !job.CreatedAt.Equal(start) {
+				t.Errorf("Expected the job created at %v, got %v", start, job.CreatedAt)
+			}
+		})
+	}
+}
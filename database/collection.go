@@ -0,0 +1,147 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// Collection is a named, immutable snapshot of a set of documents, such as the results of a search,
+// that can be handed on as one link
+type Collection struct {
+	ULID          ulid.ULID `json:"id"`
+	Name          string    `json:"name"`
+	Term          string    `json:"term"`                 // the search the documents came from, if any
+	ShareToken    string    `json:"shareToken,omitempty"` // empty unless the collection is shared
+	DocumentCount int       `json:"documentCount"`        // documents when the snapshot was taken
+	CreatedBy     string    `json:"createdBy,omitempty"`  // account that took it, empty without sign-in
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// nullableToken stores an empty share token as NULL so that only real tokens have to be unique
+func nullableToken(token string) sql.NullString {
+	return sql.NullString{String: token, Valid: token != ""}
+}
+
+// CreateCollection stores a collection and its documents in the given order.
+// The ULID and CreatedAt are set when missing and DocumentCount is set from the documents.
+func (p *PostgresDB) CreateCollection(collection *Collection, documentULIDs []string) error {
+	prepareCollection(collection, documentULIDs)
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO collections (ulid, name, term, share_token, document_count, created_by, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		collection.ULID.String(), collection.Name, collection.Term, nullableToken(collection.ShareToken), collection.DocumentCount, collection.CreatedBy, collection.CreatedAt)
+	if err != nil {
+		return err
+	}
+	for position, documentULID := range documentULIDs {
+		_, err := tx.Exec(`INSERT INTO collection_documents (collection_ulid, position, document_ulid) VALUES ($1, $2, $3)`,
+			collection.ULID.String(), position, documentULID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// prepareCollection fills in the fields CreateCollection sets on a new collection
+func prepareCollection(collection *Collection, documentULIDs []string) {
+	if collection.ULID == (ulid.ULID{}) {
+		collection.ULID = MakeULID()
+	}
+	if collection.CreatedAt.IsZero() {
+		collection.CreatedAt = Now()
+	}
+	collection.CreatedAt = collection.CreatedAt.UTC()
+	collection.DocumentCount = len(documentULIDs)
+}
+
+const collectionColumns = `ulid, name, term, COALESCE(share_token, ''), document_count, created_by, created_at`
+
+// scanCollection reads a row of collectionColumns
+func scanCollection(row interface{ Scan(...any) error }) (*Collection, error) {
+	var collection Collection
+	var ulidStr string
+	if err := row.Scan(&ulidStr, &collection.Name, &collection.Term, &collection.ShareToken, &collection.DocumentCount, &collection.CreatedBy, &collection.CreatedAt); err != nil {
+		return nil, err
+	}
+	parsed, err := ulid.Parse(ulidStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ULID: %w", err)
+	}
+	collection.ULID = parsed
+	return &collection, nil
+}
+
+// GetCollection returns a collection by ULID, or sql.ErrNoRows
+func (p *PostgresDB) GetCollection(ulidStr string) (*Collection, error) {
+	return scanCollection(p.db.QueryRow(`SELECT `+collectionColumns+` FROM collections WHERE ulid = $1`, ulidStr))
+}
+
+// GetCollectionByShareToken returns the collection shared with a token, or sql.ErrNoRows
+func (p *PostgresDB) GetCollectionByShareToken(token string) (*Collection, error) {
+	if token == "" {
+		return nil, sql.ErrNoRows
+	}
+	return scanCollection(p.db.QueryRow(`SELECT `+collectionColumns+` FROM collections WHERE share_token = $1`, token))
+}
+
+// ListCollections returns every collection, newest first
+func (p *PostgresDB) ListCollections() ([]Collection, error) {
+	rows, err := p.db.Query(`SELECT ` + collectionColumns + ` FROM collections ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var collections []Collection
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, *collection)
+	}
+	return collections, rows.Err()
+}
+
+// GetCollectionDocuments returns a collection's documents in snapshot order, without their text.
+// Documents deleted since the snapshot are left out.
+func (p *PostgresDB) GetCollectionDocuments(ulidStr string) ([]Document, error) {
+	rows, err := p.db.Query(`SELECT d.id, d.name, d.path, d.ingress_time, d.folder, d.hash, d.ulid, d.document_type, d.mime_type, d.size, d.page_count, d.ocr_status, '' AS full_text, d.url
+		FROM collection_documents cd JOIN documents d ON d.ulid = cd.document_ulid
+		WHERE cd.collection_ulid = $1 ORDER BY cd.position`, ulidStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDocuments(rows)
+}
+
+// DeleteCollection removes a collection; its documents are not touched
+func (p *PostgresDB) DeleteCollection(ulidStr string) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM collections WHERE ulid = $1`, ulidStr)
+	if err != nil {
+		return err
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.Exec(`DELETE FROM collection_documents WHERE collection_ulid = $1`, ulidStr); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
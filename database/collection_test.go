@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestCollections(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// Three documents and two collections of them, one shared
+			db := open()
+			defer db.Close()
+			var docs []*Document
+			for i := 0; i < 3; i++ {
+				doc := &Document{
+					Name:         fmt.Sprintf("tax%d.pdf", i),
+					Path:         fmt.Sprintf("/docs/tax%d.pdf", i),
+					IngressTime:  time.Date(2024, 1, 1+i, 0, 0, 0, 0, time.UTC),
+					Folder:       "/docs",
+					Hash:         fmt.Sprintf("hash%d", i),
+					ULID:         ulid.Make(),
+					DocumentType: ".pdf",
+					FullText:     "tax return",
+				}
+				if err := db.SaveDocument(doc); err != nil {
+					t.Fatalf("SaveDocument failed: %v", err)
+				}
+				docs = append(docs, doc)
+			}
+			bundle := &Collection{Name: "2023 tax bundle", Term: "tax", ShareToken: "secret-token", CreatedAt: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)}
+			if err := db.CreateCollection(bundle, []string{docs[2].ULID.String(), docs[0].ULID.String(), docs[1].ULID.String()}); err != nil {
+				t.Fatalf("CreateCollection failed: %v", err)
+			}
+			private := &Collection{Name: "Just one", CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+			if err := db.CreateCollection(private, []string{docs[1].ULID.String()}); err != nil {
+				t.Fatalf("CreateCollection failed: %v", err)
+			}
+
+			// A snapshotted document is deleted and the collections are read back
+			if err := db.DeleteDocument(docs[0].ULID.String()); err != nil {
+				t.Fatalf("DeleteDocument failed: %v", err)
+			}
+			got, err := db.GetCollection(bundle.ULID.String())
+			if err != nil {
+				t.Fatalf("GetCollection failed: %v", err)
+			}
+			shared, err := db.GetCollectionByShareToken("secret-token")
+			if err != nil {
+				t.Fatalf("GetCollectionByShareToken failed: %v", err)
+			}
+			members, err := db.GetCollectionDocuments(bundle.ULID.String())
+			if err != nil {
+				t.Fatalf("GetCollectionDocuments failed: %v", err)
+			}
+			all, err := db.ListCollections()
+			if err != nil {
+				t.Fatalf("ListCollections failed: %v", err)
+			}
+
+			// The snapshot keeps its order and count, less the deleted document
+			if got.Name != bundle.Name || got.Term != "tax" || got.DocumentCount != 3 || !got.CreatedAt.Equal(bundle.CreatedAt) {
+				t.Errorf("Unexpected collection %+v", got)
+			}
+			if shared.ULID != bundle.ULID {
+				t.Errorf("Expected the shared bundle, got %+v", shared)
+			}
+			if len(members) != 2 || members[0].ULID != docs[2].ULID || members[1].ULID != docs[1].ULID || members[0].FullText != "" {
+				t.Errorf("Unexpected members %+v", members)
+			}
+			if len(all) != 2 || all[0].ULID != private.ULID || all[0].ShareToken != "" {
+				t.Errorf("Expected the newest, unshared collection first, got %+v", all)
+			}
+
+			// Unknown tokens and deleted collections are not found
+			if _, err := db.GetCollectionByShareToken(""); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows for an empty token, got %v", err)
+			}
+			if err := db.DeleteCollection(bundle.ULID.String()); err != nil {
+				t.Fatalf("DeleteCollection failed: %v", err)
+			}
+			if _, err := db.GetCollection(bundle.ULID.String()); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows after delete, got %v", err)
+			}
+			if err := db.DeleteCollection(bundle.ULID.String()); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows deleting twice, got %v", err)
+			}
+		})
+	}
+}
@@ -1,16 +1,15 @@
 package database
 
 import (
-	"crypto/md5"
 	"database/sql"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
-	"math/rand"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/drummonds/godocs/config"
@@ -27,47 +26,229 @@ type Document struct {
 	Hash         string
 	ULID         ulid.ULID // Have a smaller (than hash) id that can be used in URL's, hopefully speed things up
 	DocumentType string    // type of document (pdf, txt, etc)
+	MIMEType     string    // content type detected from the file, e.g. application/pdf; empty for documents stored before it was recorded
+	Size         int64     // file size in bytes, recorded at ingestion
+	PageCount    int       // pages in a PDF, recorded at ingestion; 0 for other files
+	OCRStatus    string    // one of the OCR* statuses; empty for documents stored before it was recorded
 	FullText     string
 	URL          string
 }
 
+// OCR statuses of a document, kept up to date by the ingestion pipeline so lists can show which
+// documents are still waiting on text recognition or have no searchable text
+const (
+	OCRPending = "pending" // stored, text not extracted yet
+	OCRDone    = "done"    // text recognised by OCR
+	OCRFailed  = "failed"  // OCR or text extraction failed, so the document has no searchable text
+	OCRSkipped = "skipped" // OCR was not needed, e.g. a PDF with a text layer or a plain text file
+)
+
+// DocumentCursor marks a position in the newest-first document listing for keyset pagination.
+// Documents are ordered by ingress time then ID, both descending.
+type DocumentCursor struct {
+	IngressTime time.Time
+	ID          int
+}
+
+// CursorAfter returns the cursor that continues a listing after doc
+func CursorAfter(doc Document) *DocumentCursor {
+	return &DocumentCursor{IngressTime: doc.IngressTime, ID: doc.StormID}
+}
+
+// wordBatchSize is how many word frequency rows are upserted per statement
+const wordBatchSize = 500
+
+// likeEscape escapes the LIKE wildcards in s, using backslash as the escape character
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// folderPrefixPattern returns a LIKE pattern matching every folder below folder, with wildcards in the name escaped
+func folderPrefixPattern(folder string) string {
+	return likeEscape(strings.TrimSuffix(folder, "/")) + "/%"
+}
+
+// folderPrefixRange returns bounds that select every folder below folder in binary order;
+// '0' sorts directly after '/' so [folder/, folder0) is exactly the subtree
+func folderPrefixRange(folder string) (string, string) {
+	base := strings.TrimSuffix(folder, "/")
+	return base + "/", base + "0"
+}
+
+// sortedWords returns the words of a frequency map in a stable order, so concurrent upserts lock rows in the same order
+func sortedWords(counts map[string]int) []string {
+	words := make([]string, 0, len(counts))
+	for word := range counts {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
 // Logger is global since we will need it everywhere
 var Logger *slog.Logger
 
-// Repository defines database operations
+// Repository defines database operations.
+// List and search methods leave FullText empty; use GetDocumentText or a single-document lookup for the text.
 type Repository interface {
 	Close() error
 	SaveDocument(doc *Document) error
+	SaveDocumentBatch(docs []*Document, wordCounts map[string]int) error
 	GetDocumentByID(id int) (*Document, error)
 	GetDocumentByULID(ulid string) (*Document, error)
 	GetDocumentByPath(path string) (*Document, error)
 	GetDocumentByHash(hash string) (*Document, error)
+	GetDocumentText(ulid string) (string, error)
+	GetDocumentTextRange(ulid string, offset int, limit int) (string, int, error)
 	GetNewestDocuments(limit int) ([]Document, error)
 	GetNewestDocumentsWithPagination(page int, pageSize int) ([]Document, int, error)
+	GetNewestDocumentsAfter(cursor *DocumentCursor, limit int) ([]Document, error)
 	GetAllDocuments() ([]Document, error)
 	GetDocumentsByFolder(folder string) ([]Document, error)
+	GetDocumentsUnderFolder(folder string) ([]Document, error)
 	DeleteDocument(ulid string) error
 	UpdateDocumentURL(ulid string, url string) error
 	UpdateDocumentFolder(ulid string, folder string) error
+	UpdateDocumentFileDetails(ulid string, size int64, pageCount int) error
+	UpdateDocumentOCRStatus(ulid string, status string) error
+	UpdateDocumentHash(ulid string, hash string) error
 	SaveConfig(config *config.ServerConfig) error
 	GetConfig() (*config.ServerConfig, error)
 	SearchDocuments(searchTerm string) ([]Document, error)
+	SearchDocumentNames(fragment string, limit int) ([]Document, error)
 	ReindexSearchDocuments() (int, error)
+	// Folder tree methods
+	EnsureFolder(path string, parentPath string) (*Folder, error)
+	GetAllFolders() ([]Folder, error)
+	SetFolderStyle(id int, color string, icon string) (*Folder, error)
+	DeleteFolderTree(path string) (int, error)
+	CountDocumentsByFolder() (map[string]int, error)
+	// Access statistics methods
+	RecordDocumentAccess(ulid string, at time.Time) error
+	GetDocumentAccessStats(since time.Time, limit int, leastUsed bool) ([]DocumentAccess, error)
+	// Search history methods
+	RecordSearch(query *SearchQuery) error
+	GetSearchHistory(user string, limit int) ([]SearchQuery, error)
+	GetZeroResultSearches(since time.Time, limit int) ([]SearchTermCount, error)
+	// Collection methods
+	CreateCollection(collection *Collection, documentULIDs []string) error
+	GetCollection(ulid string) (*Collection, error)
+	GetCollectionByShareToken(token string) (*Collection, error)
+	ListCollections() ([]Collection, error)
+	GetCollectionDocuments(ulid string) ([]Document, error)
+	DeleteCollection(ulid string) error
 	// Word cloud methods
 	GetTopWords(limit int) ([]WordFrequency, error)
 	GetWordCloudMetadata() (*WordCloudMetadata, error)
 	RecalculateAllWordFrequencies() error
 	UpdateWordFrequencies(docID string) error
+	AddWordFrequencies(counts map[string]int) error
 	// Job tracking methods
 	CreateJob(jobType JobType, message string) (*Job, error)
 	UpdateJobProgress(jobID ulid.ULID, progress int, currentStep string) error
 	UpdateJobStatus(jobID ulid.ULID, status JobStatus, message string) error
 	UpdateJobError(jobID ulid.ULID, errorMsg string) error
+	TouchJob(jobID ulid.ULID) error
 	CompleteJob(jobID ulid.ULID, result string) error
 	GetJob(jobID ulid.ULID) (*Job, error)
 	GetRecentJobs(limit, offset int) ([]Job, error)
 	GetActiveJobs() ([]Job, error)
 	DeleteOldJobs(olderThan time.Duration) (int, error)
+	// Processing timeline methods
+	RecordDocumentEvents(events []DocumentEvent) error
+	GetDocumentEvents(ulid string) ([]DocumentEvent, error)
+	// Smart folder methods
+	CreateSmartFolder(folder *SmartFolder) error
+	GetSmartFolder(ulid string) (*SmartFolder, error)
+	ListSmartFolders() ([]SmartFolder, error)
+	DeleteSmartFolder(ulid string) error
+	// Document lock methods
+	AcquireDocumentLock(documentULID, holder string, expiresAt time.Time) (*DocumentLock, error)
+	GetDocumentLock(documentULID string) (*DocumentLock, error)
+	ListDocumentLocks() ([]DocumentLock, error)
+	ReleaseDocumentLock(documentULID, holder string) error
+	// Document archive (cold storage) methods
+	SaveDocumentArchive(archive *DocumentArchive) error
+	GetDocumentArchive(documentULID string) (*DocumentArchive, error)
+	ListDocumentArchives() ([]DocumentArchive, error)
+	DeleteDocumentArchive(documentULID string) error
+	// Legal hold methods
+	PlaceLegalHold(hold *LegalHold) error
+	ListLegalHolds() ([]LegalHold, error)
+	RemoveLegalHold(id, user, reason string) (*LegalHold, error)
+	ListLegalHoldEvents(limit int) ([]LegalHoldEvent, error)
+	// Redacted copy methods
+	SaveDocumentRedaction(redaction *DocumentRedaction) error
+	GetDocumentRedaction(documentULID string) (*DocumentRedaction, error)
+	ListDocumentRedactions(sourceULID string) ([]DocumentRedaction, error)
+	// Audit log and activity feed methods
+	RecordAuditEvent(event *AuditEvent) error
+	ListAuditEvents(before *ActivityCursor, limit int) ([]AuditEvent, error)
+	ListFinishedJobs(before *ActivityCursor, limit int) ([]Job, error)
+	// Ingest rejection methods
+	RecordIngestRejection(rejection *IngestRejection) error
+	ListIngestRejections(limit int) ([]IngestRejection, error)
+	PruneIngestRejections(keep []string) error
+	// Spreadsheet document methods
+	SaveSpreadsheetDetails(details *SpreadsheetDetails) error
+	GetSpreadsheetDetails(documentULID string) (*SpreadsheetDetails, error)
+	// Page rotation methods
+	SaveDocumentRotations(documentULID string, rotations []PageRotation) error
+	GetDocumentRotations(documentULID string) ([]PageRotation, error)
+	// Blank page methods
+	SaveBlankPages(documentULID string, pages []BlankPage) error
+	GetBlankPages(documentULID string) ([]BlankPage, error)
+	// Storage optimisation methods
+	SaveDocumentOptimisation(optimisation *DocumentOptimisation) error
+	GetDocumentOptimisation(documentULID string) (*DocumentOptimisation, error)
+	GetDocumentOptimisationByHash(originalHash string) (*DocumentOptimisation, error)
+	// Near-duplicate fingerprint methods
+	SaveDocumentFingerprint(fingerprint *DocumentFingerprint) error
+	ListDocumentFingerprints() ([]DocumentFingerprint, error)
+	// Extraction template methods
+	CreateExtractionTemplate(template *ExtractionTemplate) error
+	GetExtractionTemplate(ulid string) (*ExtractionTemplate, error)
+	ListExtractionTemplates() ([]ExtractionTemplate, error)
+	DeleteExtractionTemplate(ulid string) error
+	SaveDocumentFields(documentULID string, fields []DocumentField) error
+	GetDocumentFields(documentULID string) ([]DocumentField, error)
+	ListDocumentFields() ([]DocumentField, error)
+	// User account and session methods
+	CreateUser(user *User) error
+	GetUser(id string) (*User, error)
+	GetUserByUsername(username string) (*User, error)
+	ListUsers() ([]User, error)
+	UpdateUserPassword(id string, passwordHash string) error
+	UpdateUserRole(id string, role string) error
+	DeleteUser(id string) error
+	CreateSession(session *Session) error
+	GetSession(tokenHash string) (*Session, error)
+	DeleteSession(tokenHash string) error
+	DeleteUserSessions(userID string) error
+	DeleteExpiredSessions(now time.Time) error
+	// Folder permission methods
+	SetFolderPermission(permission *FolderPermission) error
+	ListFolderPermissions() ([]FolderPermission, error)
+	DeleteFolderPermission(folder string, userID string) error
+	// Job schedule methods
+	GetJobSchedules() (map[string]string, error)
+	SaveJobSchedules(schedules map[string]string) error
+	// Shared server state methods, for state an API server and a separate worker both follow
+	GetServerState(name string) (string, error)
+	SetServerState(name string, value string) error
+	// Tag methods
+	AddTag(documentULID string, tag string) error
+	RemoveTag(documentULID string, tag string) error
+	GetDocumentTags(documentULID string) ([]string, error)
+	GetDocumentsByTag(tag string) ([]Document, error)
+	ListTags() ([]Tag, error)
+	MergeTag(from string, into string, event *AuditEvent) (int, error)
+	// Client error report methods
+	RecordClientError(report *ClientError) error
+	ListClientErrors(limit int) ([]ClientError, error)
+	PruneClientErrors(keep int) (int, error)
+	// IndexUsage returns index scan statistics, or ErrIndexUsageUnsupported without PostgreSQL
+	IndexUsage() ([]IndexUsage, error)
 }
 
 // FetchConfigFromDB pulls the server config from the database
@@ -90,6 +271,9 @@ func WriteConfigToDB(serverConfig config.ServerConfig, db Repository) {
 	}
 }
 
+// ErrDuplicateDocument is returned by AddNewDocument for a file whose content is already stored
+var ErrDuplicateDocument = errors.New("duplicate document found on import (hash collision)")
+
 // AddNewDocument adds a new document to the database
 func AddNewDocument(filePath string, fullText string, db Repository) (*Document, error) {
 	serverConfig, err := FetchConfigFromDB(db)
@@ -97,17 +281,17 @@ func AddNewDocument(filePath string, fullText string, db Repository) (*Document,
 		Logger.Error("Unable to fetch config to add new document", "filePath", filePath, "error", err)
 	}
 	var newDocument Document
-	fileHash, err := calculateHash(filePath)
+	fileHashes, err := HashFile(filePath)
 	if err != nil {
 		return nil, err
 	}
-	duplicate := checkDuplicateDocument(fileHash, filePath, db)
+	duplicate := checkDuplicateDocument(fileHashes, filePath, db)
 	if duplicate {
-		err = errors.New("Duplicate document found on import (Hash collision) ! " + filePath)
+		err = fmt.Errorf("%w: %s", ErrDuplicateDocument, filePath)
 		Logger.Error("Duplicate document detected", "error", err)
-		return nil, err // TODO return actual error
+		return nil, err
 	}
-	newTime := time.Now()
+	newTime := Now()
 	newULID, err := CalculateUUID(newTime)
 	if err != nil {
 		Logger.Error("Cannot generate ULID", "filePath", filePath, "error", err)
@@ -132,10 +316,14 @@ func AddNewDocument(filePath string, fullText string, db Repository) (*Document,
 		documentFolder := filepath.ToSlash(serverConfig.DocumentPath + "/" + serverConfig.NewDocumentFolderRel)
 		newDocument.Folder = documentFolder
 	}
-	newDocument.Hash = fileHash
+	newDocument.Hash = fileHashes.Current()
 	newDocument.IngressTime = newTime
 	newDocument.ULID = newULID
 	newDocument.DocumentType = filepath.Ext(filePath)
+	newDocument.MIMEType = DetectMIMEType(filePath)
+	if info, err := os.Stat(filePath); err == nil {
+		newDocument.Size = info.Size()
+	}
 	newDocument.FullText = fullText
 	Logger.Debug("Adding document to database", "fullText", newDocument.FullText)
 	// PostgreSQL full-text search will be automatically indexed via trigger
@@ -256,8 +444,8 @@ func DeleteDocument(docULIDSt string, db Repository) error {
 	return nil
 }
 
-func checkDuplicateDocument(fileHash string, fileName string, db Repository) bool { // TODO: Check for duplicates before you do a shit ton of processing, why wasn't this obvious?
-	document, err := db.GetDocumentByHash(fileHash)
+func checkDuplicateDocument(fileHashes FileHashes, fileName string, db Repository) bool { // TODO: Check for duplicates before you do a shit ton of processing, why wasn't this obvious?
+	document, err := FindDocumentByHashes(db, fileHashes)
 	if err != nil || document == nil {
 		Logger.Info("No record found, assume no duplicate hash", "error", err)
 		return false
@@ -265,30 +453,3 @@ func checkDuplicateDocument(fileHash string, fileName string, db Repository) boo
 	Logger.Info("Duplicate document found on import (Hash collision)", "fileName", fileName, "existingDocument", document.Name)
 	return true
 }
-
-// calculate the hash of the incoming file
-func calculateHash(fileName string) (string, error) {
-	var fileHash string
-	file, err := os.Open(fileName)
-	if err != nil {
-		return fileHash, err
-	}
-	defer file.Close()
-	hash := md5.New()
-	_, err = io.Copy(hash, file)
-	if err != nil {
-		return fileHash, err
-	}
-	fileHash = fmt.Sprintf("%x", hash.Sum(nil))
-	return fileHash, nil
-}
-
-// CalculateUUID for the incoming file
-func CalculateUUID(time time.Time) (ulid.ULID, error) {
-	entropy := ulid.Monotonic(rand.New(rand.NewSource(time.UnixNano())), 0)
-	newULID, err := ulid.New(ulid.Timestamp(time), entropy)
-	if err != nil {
-		return newULID, err
-	}
-	return newULID, nil
-}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// DocumentArchive is the compressed copy of a document moved to cold storage. While it exists the
+// document's file is gone from its path; its record and text stay, so it is still found by search,
+// but the file has to be restored before it can be viewed or downloaded.
+type DocumentArchive struct {
+	DocumentULID   string    `json:"documentId"`
+	ArchivePath    string    `json:"archivePath"` // the gzip compressed file
+	ArchivedAt     time.Time `json:"archivedAt"`
+	CompressedSize int64     `json:"compressedSize"`
+}
+
+const documentArchiveColumns = `document_ulid, archive_path, archived_at, compressed_size`
+
+// scanDocumentArchive reads a row of documentArchiveColumns
+func scanDocumentArchive(row interface{ Scan(...any) error }) (*DocumentArchive, error) {
+	var archive DocumentArchive
+	if err := row.Scan(&archive.DocumentULID, &archive.ArchivePath, &archive.ArchivedAt, &archive.CompressedSize); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// SaveDocumentArchive records that a document has been moved to cold storage, replacing any earlier record
+func (p *PostgresDB) SaveDocumentArchive(archive *DocumentArchive) error {
+	_, err := p.db.Exec(`INSERT INTO document_archives (`+documentArchiveColumns+`) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (document_ulid) DO UPDATE SET
+			archive_path = EXCLUDED.archive_path,
+			archived_at = EXCLUDED.archived_at,
+			compressed_size = EXCLUDED.compressed_size`,
+		archive.DocumentULID, archive.ArchivePath, archive.ArchivedAt.UTC(), archive.CompressedSize)
+	return err
+}
+
+// GetDocumentArchive returns the cold storage record of a document, or sql.ErrNoRows when it is not archived
+func (p *PostgresDB) GetDocumentArchive(documentULID string) (*DocumentArchive, error) {
+	return scanDocumentArchive(p.db.QueryRow(`SELECT `+documentArchiveColumns+` FROM document_archives WHERE document_ulid = $1`, documentULID))
+}
+
+// ListDocumentArchives returns every archived document, most recently archived first
+func (p *PostgresDB) ListDocumentArchives() ([]DocumentArchive, error) {
+	rows, err := p.db.Query(`SELECT ` + documentArchiveColumns + ` FROM document_archives ORDER BY archived_at DESC, document_ulid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var archives []DocumentArchive
+	for rows.Next() {
+		archive, err := scanDocumentArchive(rows)
+		if err != nil {
+			return nil, err
+		}
+		archives = append(archives, *archive)
+	}
+	return archives, rows.Err()
+}
+
+// DeleteDocumentArchive removes the cold storage record of a document, or returns sql.ErrNoRows when it has none
+func (p *PostgresDB) DeleteDocumentArchive(documentULID string) error {
+	result, err := p.db.Exec(`DELETE FROM document_archives WHERE document_ulid = $1`, documentULID)
+	if err != nil {
+		return err
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
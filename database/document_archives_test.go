@@ -0,0 +1,54 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDocumentArchives(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// Two documents archived a day apart
+			db := open()
+			defer db.Close()
+			older := &DocumentArchive{DocumentULID: "01HZX0000000000000000000A1", ArchivePath: "/archive/a.pdf.gz", ArchivedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), CompressedSize: 100}
+			newer := &DocumentArchive{DocumentULID: "01HZX0000000000000000000B2", ArchivePath: "/archive/b.pdf.gz", ArchivedAt: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), CompressedSize: 200}
+			for _, archive := range []*DocumentArchive{older, newer} {
+				if err := db.SaveDocumentArchive(archive); err != nil {
+					t.Fatalf("SaveDocumentArchive failed: %v", err)
+				}
+			}
+
+			// They are read back and listed
+			got, err := db.GetDocumentArchive(older.DocumentULID)
+			if err != nil {
+				t.Fatalf("GetDocumentArchive failed: %v", err)
+			}
+			archives, err := db.ListDocumentArchives()
+			if err != nil {
+				t.Fatalf("ListDocumentArchives failed: %v", err)
+			}
+
+			// The record round-trips and the list is most recently archived first
+			if got.ArchivePath != older.ArchivePath || got.CompressedSize != 100 || !got.ArchivedAt.Equal(older.ArchivedAt) {
+				t.Errorf("Unexpected archive %+v", got)
+			}
+			if len(archives) != 2 || archives[0].DocumentULID != newer.DocumentULID {
+				t.Errorf("Unexpected archives %+v", archives)
+			}
+
+			// Deleting a record removes it, and a document that is not archived is not found
+			if err := db.DeleteDocumentArchive(older.DocumentULID); err != nil {
+				t.Fatalf("DeleteDocumentArchive failed: %v", err)
+			}
+			if _, err := db.GetDocumentArchive(older.DocumentULID); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows after deleting, got %v", err)
+			}
+			if err := db.DeleteDocumentArchive(older.DocumentULID); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows deleting twice, got %v", err)
+			}
+		})
+	}
+}
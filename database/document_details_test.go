@@ -0,0 +1,72 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestDocumentFileDetails(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// A PDF saved with its size and page count, and a text file saved before they were recorded
+			db := open()
+			defer db.Close()
+			pdf := &Document{
+				Name:         "report.pdf",
+				Path:         "/docs/report.pdf",
+				IngressTime:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Folder:       "/docs",
+				Hash:         "hash-pdf",
+				ULID:         ulid.Make(),
+				DocumentType: ".pdf",
+				FullText:     "quarterly report",
+				Size:         48213,
+				PageCount:    7,
+			}
+			text := &Document{
+				Name:         "notes.txt",
+				Path:         "/docs/notes.txt",
+				IngressTime:  time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				Folder:       "/docs",
+				Hash:         "hash-txt",
+				ULID:         ulid.Make(),
+				DocumentType: ".txt",
+				FullText:     "meeting notes",
+			}
+			for _, doc := range []*Document{pdf, text} {
+				if err := db.SaveDocument(doc); err != nil {
+					t.Fatalf("SaveDocument failed: %v", err)
+				}
+			}
+
+			// The text file's size is backfilled
+			if err := db.UpdateDocumentFileDetails(text.ULID.String(), 120, 0); err != nil {
+				t.Fatalf("UpdateDocumentFileDetails failed: %v", err)
+			}
+
+			// Both are read back with their details, from a single lookup and from search
+			for _, want := range []struct {
+				doc   *Document
+				size  int64
+				pages int
+			}{{pdf, 48213, 7}, {text, 120, 0}} {
+				got, err := db.GetDocumentByULID(want.doc.ULID.String())
+				if err != nil {
+					t.Fatalf("GetDocumentByULID failed: %v", err)
+				}
+				if got.Size != want.size || got.PageCount != want.pages {
+					t.Errorf("%s: expected size %d and %d pages, got %d and %d", want.doc.Name, want.size, want.pages, got.Size, got.PageCount)
+				}
+			}
+			found, err := db.SearchDocuments("quarterly")
+			if err != nil || len(found) != 1 {
+				t.Fatalf("Expected one search result, got %d, %v", len(found), err)
+			}
+			if found[0].Size != 48213 || found[0].PageCount != 7 {
+				t.Errorf("Expected the search result to carry its details, got %+v", found[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// DocumentLock is a check-out of a document by one holder until it expires, so two people editing the
+// same document do not overwrite each other. An expired lock is treated as no lock.
+type DocumentLock struct {
+	DocumentULID string    `json:"documentId"`
+	Holder       string    `json:"holder"`
+	AcquiredAt   time.Time `json:"acquiredAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// ErrDocumentLocked is returned by AcquireDocumentLock when another holder has the document checked out
+var ErrDocumentLocked = errors.New("document is locked by another holder")
+
+// lockResult returns the lock now on a document after an attempt to acquire it for holder
+func lockResult(lock *DocumentLock, err error, holder string) (*DocumentLock, error) {
+	if err != nil {
+		return nil, err
+	}
+	if lock.Holder != holder {
+		return lock, ErrDocumentLocked
+	}
+	return lock, nil
+}
+
+// AcquireDocumentLock locks a document for holder until expiresAt. A holder's own lock is extended; a
+// lock held by anyone else is returned with ErrDocumentLocked until it expires.
+func (p *PostgresDB) AcquireDocumentLock(documentULID, holder string, expiresAt time.Time) (*DocumentLock, error) {
+	now := Now().UTC()
+	_, err := p.db.Exec(`INSERT INTO document_locks (document_ulid, holder, acquired_at, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (document_ulid) DO UPDATE SET
+			acquired_at = CASE WHEN document_locks.holder = EXCLUDED.holder AND document_locks.expires_at > EXCLUDED.acquired_at
+				THEN document_locks.acquired_at ELSE EXCLUDED.acquired_at END,
+			holder = EXCLUDED.holder,
+			expires_at = EXCLUDED.expires_at
+		WHERE document_locks.holder = EXCLUDED.holder OR document_locks.expires_at <= EXCLUDED.acquired_at`,
+		documentULID, holder, now, expiresAt.UTC())
+	if err != nil {
+		return nil, err
+	}
+	lock, err := scanDocumentLock(p.db.QueryRow(`SELECT `+documentLockColumns+` FROM document_locks WHERE document_ulid = $1`, documentULID))
+	return lockResult(lock, err, holder)
+}
+
+const documentLockColumns = `document_ulid, holder, acquired_at, expires_at`
+
+// scanDocumentLock reads a row of documentLockColumns
+func scanDocumentLock(row interface{ Scan(...any) error }) (*DocumentLock, error) {
+	var lock DocumentLock
+	if err := row.Scan(&lock.DocumentULID, &lock.Holder, &lock.AcquiredAt, &lock.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// GetDocumentLock returns the unexpired lock on a document, or sql.ErrNoRows
+func (p *PostgresDB) GetDocumentLock(documentULID string) (*DocumentLock, error) {
+	return scanDocumentLock(p.db.QueryRow(`SELECT `+documentLockColumns+` FROM document_locks WHERE document_ulid = $1 AND expires_at > $2`,
+		documentULID, Now().UTC()))
+}
+
+// ListDocumentLocks returns every unexpired lock, soonest to expire first
+func (p *PostgresDB) ListDocumentLocks() ([]DocumentLock, error) {
+	rows, err := p.db.Query(`SELECT `+documentLockColumns+` FROM document_locks WHERE expires_at > $1 ORDER BY expires_at, document_ulid`, Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locks []DocumentLock
+	for rows.Next() {
+		lock, err := scanDocumentLock(rows)
+		if err != nil {
+			return nil, err
+		}
+		locks = append(locks, *lock)
+	}
+	return locks, rows.Err()
+}
+
+// ReleaseDocumentLock removes holder's lock on a document, or returns sql.ErrNoRows when holder has none
+func (p *PostgresDB) ReleaseDocumentLock(documentULID, holder string) error {
+	result, err := p.db.Exec(`DELETE FROM document_locks WHERE document_ulid = $1 AND holder = $2`, documentULID, holder)
+	if err != nil {
+		return err
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDocumentLocks(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// Alice has one document checked out and bob had another whose lock has expired
+			db := open()
+			defer db.Close()
+			now := time.Now()
+			first, err := db.AcquireDocumentLock("DOC1", "alice", now.Add(time.Minute))
+			if err != nil || first.Holder != "alice" {
+				t.Fatalf("AcquireDocumentLock failed: %v %+v", err, first)
+			}
+			if _, err := db.AcquireDocumentLock("DOC2", "bob", now.Add(-time.Minute)); err != nil {
+				t.Fatalf("AcquireDocumentLock failed: %v", err)
+			}
+
+			// Bob tries to take alice's document
+			held, err := db.AcquireDocumentLock("DOC1", "bob", now.Add(time.Hour))
+
+			// Bob is refused and told who has it until when
+			if !errors.Is(err, ErrDocumentLocked) || held == nil || held.Holder != "alice" {
+				t.Fatalf("Expected alice's lock and ErrDocumentLocked, got %+v %v", held, err)
+			}
+			if held.ExpiresAt.Sub(now.Add(time.Minute)).Abs() > time.Second {
+				t.Errorf("Expected alice's expiry to be unchanged, got %v", held.ExpiresAt)
+			}
+
+			// Alice extends their own lock, keeping when it was taken
+			extended, err := db.AcquireDocumentLock("DOC1", "alice", now.Add(time.Hour))
+			if err != nil || extended.ExpiresAt.Sub(now.Add(time.Hour)).Abs() > time.Second {
+				t.Errorf("Expected alice's lock to be extended, got %+v %v", extended, err)
+			}
+			if extended != nil && !extended.AcquiredAt.Equal(first.AcquiredAt) {
+				t.Errorf("Expected acquired time %v to be kept, got %v", first.AcquiredAt, extended.AcquiredAt)
+			}
+
+			// The expired lock is not reported and anyone may take the document
+			if _, err := db.GetDocumentLock("DOC2"); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected an expired lock to be sql.ErrNoRows, got %v", err)
+			}
+			if taken, err := db.AcquireDocumentLock("DOC2", "carol", now.Add(time.Minute)); err != nil || taken.Holder != "carol" {
+				t.Errorf("Expected carol to take the expired lock, got %+v %v", taken, err)
+			}
+			locks, err := db.ListDocumentLocks()
+			if err != nil || len(locks) != 2 || locks[0].DocumentULID != "DOC2" || locks[1].Holder != "alice" {
+				t.Errorf("Expected carol's then alice's lock, got %+v %v", locks, err)
+			}
+
+			// Bob and then alice release alice's lock
+			bobErr := db.ReleaseDocumentLock("DOC1", "bob")
+			aliceErr := db.ReleaseDocumentLock("DOC1", "alice")
+
+			// Only alice's release counts
+			if !errors.Is(bobErr, sql.ErrNoRows) || aliceErr != nil {
+				t.Errorf("Expected bob refused and alice released, got %v and %v", bobErr, aliceErr)
+			}
+			if _, err := db.GetDocumentLock("DOC1"); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected no lock after release, got %v", err)
+			}
+		})
+	}
+}
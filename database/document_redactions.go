@@ -0,0 +1,77 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RedactionRegion is a rectangle blacked out of one page, in points from the top left corner of the
+// page as it is displayed
+type RedactionRegion struct {
+	Page   int     `json:"page"` // 1-based
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// DocumentRedaction links a redacted copy to the document it was made from. The copy is a document
+// of its own; the record keeps which regions were removed and who removed them.
+type DocumentRedaction struct {
+	DocumentULID string            `json:"documentId"` // the redacted copy
+	SourceULID   string            `json:"sourceId"`   // the document it was made from
+	Regions      []RedactionRegion `json:"regions"`
+	CreatedBy    string            `json:"createdBy,omitempty"`
+	CreatedAt    time.Time         `json:"createdAt"`
+}
+
+const documentRedactionColumns = `document_ulid, source_ulid, regions, created_by, created_at`
+
+// scanDocumentRedaction reads a row of documentRedactionColumns
+func scanDocumentRedaction(row interface{ Scan(...any) error }) (*DocumentRedaction, error) {
+	var redaction DocumentRedaction
+	var regions string
+	if err := row.Scan(&redaction.DocumentULID, &redaction.SourceULID, &regions, &redaction.CreatedBy, &redaction.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(regions), &redaction.Regions); err != nil {
+		return nil, err
+	}
+	return &redaction, nil
+}
+
+// SaveDocumentRedaction records that a document is a redacted copy of another
+func (p *PostgresDB) SaveDocumentRedaction(redaction *DocumentRedaction) error {
+	regions, err := json.Marshal(redaction.Regions)
+	if err != nil {
+		return err
+	}
+	_, err = p.db.Exec(`INSERT INTO document_redactions (`+documentRedactionColumns+`) VALUES ($1, $2, $3, $4, $5)`,
+		redaction.DocumentULID, redaction.SourceULID, string(regions), redaction.CreatedBy, redaction.CreatedAt.UTC())
+	return err
+}
+
+// GetDocumentRedaction returns the record of a redacted copy, or sql.ErrNoRows when the document is not one
+func (p *PostgresDB) GetDocumentRedaction(documentULID string) (*DocumentRedaction, error) {
+	return scanDocumentRedaction(p.db.QueryRow(`SELECT `+documentRedactionColumns+` FROM document_redactions WHERE document_ulid = $1`, documentULID))
+}
+
+// ListDocumentRedactions returns the redacted copies made from a document, newest first
+func (p *PostgresDB) ListDocumentRedactions(sourceULID string) ([]DocumentRedaction, error) {
+	rows, err := p.db.Query(`SELECT `+documentRedactionColumns+` FROM document_redactions WHERE source_ulid = $1
+		ORDER BY created_at DESC, document_ulid DESC`, sourceULID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var redactions []DocumentRedaction
+	for rows.Next() {
+		redaction, err := scanDocumentRedaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		redactions = append(redactions, *redaction)
+	}
+	return redactions, rows.Err()
+}
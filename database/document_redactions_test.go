@@ -0,0 +1,54 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDocumentRedactions(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// Two redacted copies of one document, made a day apart
+			db := open()
+			defer db.Close()
+			source := "01HZX0000000000000000000S0"
+			older := &DocumentRedaction{DocumentULID: "01HZX0000000000000000000A1", SourceULID: source, CreatedBy: "alice", CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				Regions: []RedactionRegion{{Page: 1, X: 72, Y: 100.5, Width: 200, Height: 14}}}
+			newer := &DocumentRedaction{DocumentULID: "01HZX0000000000000000000B2", SourceULID: source, CreatedAt: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+				Regions: []RedactionRegion{{Page: 2, X: 0, Y: 0, Width: 50, Height: 50}, {Page: 3, X: 10, Y: 10, Width: 5, Height: 5}}}
+			for _, redaction := range []*DocumentRedaction{older, newer} {
+				if err := db.SaveDocumentRedaction(redaction); err != nil {
+					t.Fatalf("SaveDocumentRedaction failed: %v", err)
+				}
+			}
+
+			// A copy is read back and the source's copies are listed
+			got, err := db.GetDocumentRedaction(older.DocumentULID)
+			if err != nil {
+				t.Fatalf("GetDocumentRedaction failed: %v", err)
+			}
+			redactions, err := db.ListDocumentRedactions(source)
+			if err != nil {
+				t.Fatalf("ListDocumentRedactions failed: %v", err)
+			}
+
+			// The regions round-trip and the copies are listed newest first
+			if got.SourceULID != source || got.CreatedBy != "alice" || len(got.Regions) != 1 || got.Regions[0] != older.Regions[0] {
+				t.Errorf("Unexpected redaction %+v", got)
+			}
+			if len(redactions) != 2 || redactions[0].DocumentULID != newer.DocumentULID || len(redactions[0].Regions) != 2 {
+				t.Errorf("Unexpected redactions %+v", redactions)
+			}
+
+			// A document that is not a copy is not found, and one without copies lists none
+			if _, err := db.GetDocumentRedaction(source); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows, got %v", err)
+			}
+			if redactions, err := db.ListDocumentRedactions(older.DocumentULID); err != nil || len(redactions) != 0 {
+				t.Errorf("Expected no copies, got %v %v", redactions, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestGetDocumentTextRange(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// A document whose text has characters wider than a byte
+			db := open()
+			defer db.Close()
+			doc := &Document{
+				Name:         "café.txt",
+				Path:         "/docs/café.txt",
+				IngressTime:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Folder:       "/docs",
+				Hash:         "hash-cafe",
+				ULID:         ulid.Make(),
+				DocumentType: ".txt",
+				FullText:     "Café crème, très bien",
+			}
+			if err := db.SaveDocument(doc); err != nil {
+				t.Fatalf("SaveDocument failed: %v", err)
+			}
+
+			cases := []struct {
+				offset, limit int
+				want          string
+			}{
+				{0, 4, "Café"},
+				{5, 5, "crème"},
+				{17, 100, "bien"},
+				{21, 10, ""},
+				{50, 10, ""},
+			}
+			for _, c := range cases {
+				// A range of the text is read
+				text, total, err := db.GetDocumentTextRange(doc.ULID.String(), c.offset, c.limit)
+
+				// Offsets and lengths count characters, not bytes
+				if err != nil || text != c.want || total != 21 {
+					t.Errorf("Range %d+%d: expected %q of 21, got %q of %d: %v", c.offset, c.limit, c.want, text, total, err)
+				}
+			}
+
+			// An unknown document is not found
+			if _, _, err := db.GetDocumentTextRange(ulid.Make().String(), 0, 10); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows for an unknown document, got %v", err)
+			}
+		})
+	}
+}
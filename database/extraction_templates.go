@@ -0,0 +1,166 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// ExtractionField is one value an extraction template reads from a document, such as an invoice number.
+// The value is the first group of Pattern, or all of its match, found in the document's text, or in the
+// text inside Zone when one is given; a zone without a pattern reads all of its text.
+type ExtractionField struct {
+	Name    string           `json:"name"`
+	Pattern string           `json:"pattern,omitempty"` // regular expression
+	Zone    *RedactionRegion `json:"zone,omitempty"`    // area of a PDF page, in the points redaction regions use
+}
+
+// ExtractionTemplate reads structured fields from the documents of one correspondent, recognised by a
+// regular expression their text matches, such as the sender's VAT number
+type ExtractionTemplate struct {
+	ULID          ulid.ULID         `json:"id"`
+	Correspondent string            `json:"correspondent"`
+	Match         string            `json:"match"`
+	Fields        []ExtractionField `json:"fields"`
+	CreatedAt     time.Time         `json:"createdAt"`
+}
+
+// DocumentField is a value read from a document by an extraction template
+type DocumentField struct {
+	DocumentULID string `json:"documentId"`
+	Name         string `json:"name"`
+	Value        string `json:"value"`
+	TemplateULID string `json:"templateId"`
+}
+
+// prepareExtractionTemplate fills in the fields CreateExtractionTemplate sets on a new template
+func prepareExtractionTemplate(template *ExtractionTemplate) {
+	if template.ULID == (ulid.ULID{}) {
+		template.ULID = MakeULID()
+	}
+	if template.CreatedAt.IsZero() {
+		template.CreatedAt = Now()
+	}
+	template.CreatedAt = template.CreatedAt.UTC()
+}
+
+// CreateExtractionTemplate stores an extraction template, setting its ULID and CreatedAt when missing
+func (p *PostgresDB) CreateExtractionTemplate(template *ExtractionTemplate) error {
+	prepareExtractionTemplate(template)
+	fields, err := json.Marshal(template.Fields)
+	if err != nil {
+		return err
+	}
+	_, err = p.db.Exec(`INSERT INTO extraction_templates (ulid, correspondent, match_pattern, fields, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		template.ULID.String(), template.Correspondent, template.Match, string(fields), template.CreatedAt)
+	return err
+}
+
+const extractionTemplateColumns = `ulid, correspondent, match_pattern, fields, created_at`
+
+// scanExtractionTemplate reads a row of extractionTemplateColumns
+func scanExtractionTemplate(row interface{ Scan(...any) error }) (*ExtractionTemplate, error) {
+	var template ExtractionTemplate
+	var ulidStr, fields string
+	if err := row.Scan(&ulidStr, &template.Correspondent, &template.Match, &fields, &template.CreatedAt); err != nil {
+		return nil, err
+	}
+	parsed, err := ulid.Parse(ulidStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ULID: %w", err)
+	}
+	template.ULID = parsed
+	if err := json.Unmarshal([]byte(fields), &template.Fields); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetExtractionTemplate returns an extraction template by ULID, or sql.ErrNoRows
+func (p *PostgresDB) GetExtractionTemplate(ulidStr string) (*ExtractionTemplate, error) {
+	return scanExtractionTemplate(p.db.QueryRow(`SELECT `+extractionTemplateColumns+` FROM extraction_templates WHERE ulid = $1`, ulidStr))
+}
+
+// ListExtractionTemplates returns every extraction template by correspondent, oldest first for each
+func (p *PostgresDB) ListExtractionTemplates() ([]ExtractionTemplate, error) {
+	rows, err := p.db.Query(`SELECT ` + extractionTemplateColumns + ` FROM extraction_templates ORDER BY correspondent, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []ExtractionTemplate
+	for rows.Next() {
+		template, err := scanExtractionTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *template)
+	}
+	return templates, rows.Err()
+}
+
+// DeleteExtractionTemplate removes an extraction template; the fields it read are kept
+func (p *PostgresDB) DeleteExtractionTemplate(ulidStr string) error {
+	result, err := p.db.Exec(`DELETE FROM extraction_templates WHERE ulid = $1`, ulidStr)
+	if err != nil {
+		return err
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SaveDocumentFields replaces the fields read from a document with fields
+func (p *PostgresDB) SaveDocumentFields(documentULID string, fields []DocumentField) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM document_fields WHERE document_ulid = $1`, documentULID); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if _, err := tx.Exec(`INSERT INTO document_fields (document_ulid, name, value, template_ulid) VALUES ($1, $2, $3, $4)`,
+			documentULID, field.Name, field.Value, field.TemplateULID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDocumentFields returns the fields read from a document by name
+func (p *PostgresDB) GetDocumentFields(documentULID string) ([]DocumentField, error) {
+	return p.queryDocumentFields(`SELECT document_ulid, name, value, template_ulid FROM document_fields
+		WHERE document_ulid = $1 ORDER BY name`, documentULID)
+}
+
+// ListDocumentFields returns the fields read from every document, by document and then name
+func (p *PostgresDB) ListDocumentFields() ([]DocumentField, error) {
+	return p.queryDocumentFields(`SELECT document_ulid, name, value, template_ulid FROM document_fields
+		ORDER BY document_ulid, name`)
+}
+
+// queryDocumentFields runs a query selecting document_fields rows
+func (p *PostgresDB) queryDocumentFields(query string, args ...any) ([]DocumentField, error) {
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fields []DocumentField
+	for rows.Next() {
+		var field DocumentField
+		if err := rows.Scan(&field.DocumentULID, &field.Name, &field.Value, &field.TemplateULID); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, rows.Err()
+}
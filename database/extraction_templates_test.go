@@ -0,0 +1,80 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExtractionTemplates(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// Templates for two correspondents, one reading a field from a zone
+			db := open()
+			defer db.Close()
+			water := &ExtractionTemplate{Correspondent: "Water Board", Match: `GB 123 4567 89`,
+				Fields: []ExtractionField{{Name: "total", Pattern: `Total\s+£([\d.,]+)`}}}
+			energy := &ExtractionTemplate{Correspondent: "Acme Energy", Match: `(?i)acme energy`, CreatedAt: time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC),
+				Fields: []ExtractionField{
+					{Name: "invoice_number", Pattern: `Invoice (\w+)`},
+					{Name: "iban", Zone: &RedactionRegion{Page: 1, X: 300, Y: 700, Width: 250, Height: 20}},
+				}}
+			for _, template := range []*ExtractionTemplate{water, energy} {
+				if err := db.CreateExtractionTemplate(template); err != nil {
+					t.Fatalf("CreateExtractionTemplate failed: %v", err)
+				}
+			}
+
+			// A template reads back with its fields, and they are listed by correspondent
+			got, err := db.GetExtractionTemplate(energy.ULID.String())
+			if err != nil || got.Correspondent != "Acme Energy" || got.Match != energy.Match || len(got.Fields) != 2 ||
+				got.Fields[1].Zone == nil || *got.Fields[1].Zone != *energy.Fields[1].Zone || !got.CreatedAt.Equal(energy.CreatedAt) {
+				t.Fatalf("Unexpected template %+v: %v", got, err)
+			}
+			templates, err := db.ListExtractionTemplates()
+			if err != nil || len(templates) != 2 || templates[0].ULID != energy.ULID || templates[1].ULID != water.ULID {
+				t.Errorf("Expected both templates by correspondent, got %+v: %v", templates, err)
+			}
+
+			// Fields saved for a document replace those saved before
+			documentULID := "01HZX0000000000000000000D1"
+			if err := db.SaveDocumentFields(documentULID, []DocumentField{{Name: "total", Value: "10.00", TemplateULID: water.ULID.String()}}); err != nil {
+				t.Fatalf("SaveDocumentFields failed: %v", err)
+			}
+			read := []DocumentField{
+				{DocumentULID: documentULID, Name: "invoice_number", Value: "INV42", TemplateULID: energy.ULID.String()},
+				{DocumentULID: documentULID, Name: "iban", Value: "GB29 NWBK 6016 1331 9268 19", TemplateULID: energy.ULID.String()},
+			}
+			if err := db.SaveDocumentFields(documentULID, read); err != nil {
+				t.Fatalf("SaveDocumentFields failed: %v", err)
+			}
+			fields, err := db.GetDocumentFields(documentULID)
+			if want := []DocumentField{read[1], read[0]}; err != nil || !slices.Equal(fields, want) {
+				t.Errorf("Expected %v, got %v: %v", want, fields, err)
+			}
+
+			// Every document's fields are listed by document
+			earlier := DocumentField{DocumentULID: "01HZX0000000000000000000C1", Name: "total", Value: "10.00", TemplateULID: water.ULID.String()}
+			if err := db.SaveDocumentFields(earlier.DocumentULID, []DocumentField{earlier}); err != nil {
+				t.Fatalf("SaveDocumentFields failed: %v", err)
+			}
+			all, err := db.ListDocumentFields()
+			if want := []DocumentField{earlier, read[1], read[0]}; err != nil || !slices.Equal(all, want) {
+				t.Errorf("Expected %v, got %v: %v", want, all, err)
+			}
+
+			// A deleted template is gone, and deleting it again is sql.ErrNoRows
+			if err := db.DeleteExtractionTemplate(water.ULID.String()); err != nil {
+				t.Fatalf("DeleteExtractionTemplate failed: %v", err)
+			}
+			if _, err := db.GetExtractionTemplate(water.ULID.String()); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows for a deleted template, got %v", err)
+			}
+			if err := db.DeleteExtractionTemplate(water.ULID.String()); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows deleting twice, got %v", err)
+			}
+		})
+	}
+}
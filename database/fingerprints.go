@@ -0,0 +1,38 @@
+package database
+
+// DocumentFingerprint holds the similarity hashes of a document, compared to find near-duplicates such
+// as the same letter scanned twice, which exact hashes miss
+type DocumentFingerprint struct {
+	DocumentULID string `json:"documentId"`
+	ImageHash    string `json:"imageHash,omitempty"` // difference hash of the first page, 16 hex digits; empty when it was not rendered
+	TextHash     string `json:"textHash,omitempty"`  // simhash of the words, 16 hex digits; empty for too little text
+}
+
+// SaveDocumentFingerprint records a document's similarity hashes, replacing any earlier ones
+func (p *PostgresDB) SaveDocumentFingerprint(fingerprint *DocumentFingerprint) error {
+	_, err := p.db.Exec(`INSERT INTO document_fingerprints (document_ulid, image_hash, text_hash) VALUES ($1, $2, $3)
+		ON CONFLICT (document_ulid) DO UPDATE SET
+			image_hash = EXCLUDED.image_hash,
+			text_hash = EXCLUDED.text_hash`,
+		fingerprint.DocumentULID, fingerprint.ImageHash, fingerprint.TextHash)
+	return err
+}
+
+// ListDocumentFingerprints returns the similarity hashes of every fingerprinted document
+func (p *PostgresDB) ListDocumentFingerprints() ([]DocumentFingerprint, error) {
+	rows, err := p.db.Query(`SELECT document_ulid, image_hash, text_hash FROM document_fingerprints ORDER BY document_ulid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fingerprints []DocumentFingerprint
+	for rows.Next() {
+		var fingerprint DocumentFingerprint
+		if err := rows.Scan(&fingerprint.DocumentULID, &fingerprint.ImageHash, &fingerprint.TextHash); err != nil {
+			return nil, err
+		}
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	return fingerprints, rows.Err()
+}
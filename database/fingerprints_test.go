@@ -0,0 +1,38 @@
+package database
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDocumentFingerprints(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// No documents fingerprinted
+			db := open()
+			defer db.Close()
+			if fingerprints, err := db.ListDocumentFingerprints(); err != nil || len(fingerprints) != 0 {
+				t.Fatalf("Expected no fingerprints, got %v, %v", fingerprints, err)
+			}
+
+			// A PDF is fingerprinted by its text, an image by both, and the PDF again once rendered
+			pdf := DocumentFingerprint{DocumentULID: "01ARZ3NDEKTSV4RRFFQ69G5FAV", TextHash: "8f0c3a5e91d2b746"}
+			image := DocumentFingerprint{DocumentULID: "01ARZ3NDEKTSV4RRFFQ69G5FAW", ImageHash: "00ff00ff00ff00ff", TextHash: "8f0c3a5e91d2b747"}
+			for _, fingerprint := range []DocumentFingerprint{pdf, image} {
+				if err := db.SaveDocumentFingerprint(&fingerprint); err != nil {
+					t.Fatalf("SaveDocumentFingerprint failed: %v", err)
+				}
+			}
+			pdf.ImageHash = "00ff00ff00ff00fe"
+			if err := db.SaveDocumentFingerprint(&pdf); err != nil {
+				t.Fatalf("SaveDocumentFingerprint failed: %v", err)
+			}
+
+			// Both are listed, the PDF with its image hash
+			fingerprints, err := db.ListDocumentFingerprints()
+			if want := []DocumentFingerprint{pdf, image}; err != nil || !slices.Equal(fingerprints, want) {
+				t.Errorf("Expected %v, got %v, %v", want, fingerprints, err)
+			}
+		})
+	}
+}
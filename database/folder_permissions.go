@@ -0,0 +1,64 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// FolderPermission gives one account access to a folder and everything under it. A folder with any
+// permissions is restricted: only the accounts named, and administrators, can see it. Folders without
+// permissions of their own follow the nearest restricted folder above them, or are open to everyone.
+type FolderPermission struct {
+	Folder    string    `json:"folder"` // folder key relative to the document root, "/" for the root
+	UserID    string    `json:"userId"`
+	Access    string    `json:"access"` // AccessRead or AccessWrite
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Folder access levels
+const (
+	AccessRead  = "read"
+	AccessWrite = "write"
+)
+
+// SetFolderPermission gives an account access to a folder, replacing any it already had there
+func (p *PostgresDB) SetFolderPermission(permission *FolderPermission) error {
+	if permission.CreatedAt.IsZero() {
+		permission.CreatedAt = Now()
+	}
+	_, err := p.db.Exec(`INSERT INTO folder_permissions (folder, user_id, access, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (folder, user_id) DO UPDATE SET access = EXCLUDED.access`,
+		permission.Folder, permission.UserID, permission.Access, permission.CreatedAt.UTC())
+	return err
+}
+
+// ListFolderPermissions returns every folder permission ordered by folder
+func (p *PostgresDB) ListFolderPermissions() ([]FolderPermission, error) {
+	rows, err := p.db.Query(`SELECT folder, user_id, access, created_at FROM folder_permissions ORDER BY folder, user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []FolderPermission
+	for rows.Next() {
+		var permission FolderPermission
+		if err := rows.Scan(&permission.Folder, &permission.UserID, &permission.Access, &permission.CreatedAt); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, rows.Err()
+}
+
+// DeleteFolderPermission removes an account's access to a folder, returning sql.ErrNoRows if it had none
+func (p *PostgresDB) DeleteFolderPermission(folder string, userID string) error {
+	result, err := p.db.Exec(`DELETE FROM folder_permissions WHERE folder = $1 AND user_id = $2`, folder, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
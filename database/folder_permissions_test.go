@@ -0,0 +1,67 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestRolesAndFolderPermissions(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// An editor by default and a viewer
+			db := open()
+			defer db.Close()
+			bob := User{Username: "bob", PasswordHash: "hash-b"}
+			carol := User{Username: "carol", PasswordHash: "hash-c", Role: RoleViewer}
+			for _, user := range []*User{&bob, &carol} {
+				if err := db.CreateUser(user); err != nil {
+					t.Fatalf("CreateUser failed: %v", err)
+				}
+			}
+			if found, _ := db.GetUser(bob.ID); found.Role != RoleEditor {
+				t.Errorf("Expected bob to be an editor, got %q", found.Role)
+			}
+
+			// Bob is made an administrator and both are given access to folders, bob's twice
+			if err := db.UpdateUserRole(bob.ID, RoleAdmin); err != nil {
+				t.Fatalf("UpdateUserRole failed: %v", err)
+			}
+			for _, permission := range []FolderPermission{
+				{Folder: "medical", UserID: bob.ID, Access: AccessRead},
+				{Folder: "medical", UserID: bob.ID, Access: AccessWrite},
+				{Folder: "finance/2024", UserID: carol.ID, Access: AccessRead},
+			} {
+				if err := db.SetFolderPermission(&permission); err != nil {
+					t.Fatalf("SetFolderPermission failed: %v", err)
+				}
+			}
+
+			// The role is changed and the second permission replaced the first
+			if found, _ := db.GetUser(bob.ID); found.Role != RoleAdmin {
+				t.Errorf("Expected bob to be an administrator, got %q", found.Role)
+			}
+			permissions, err := db.ListFolderPermissions()
+			if err != nil || len(permissions) != 2 || permissions[0].Folder != "finance/2024" || permissions[1].Access != AccessWrite {
+				t.Errorf("Expected finance for carol then medical write for bob, got %+v, %v", permissions, err)
+			}
+
+			// Removing a permission twice, and removing an account, take their permissions away
+			if err := db.DeleteFolderPermission("medical", bob.ID); err != nil {
+				t.Errorf("DeleteFolderPermission failed: %v", err)
+			}
+			if err := db.DeleteFolderPermission("medical", bob.ID); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows, got %v", err)
+			}
+			if err := db.DeleteUser(carol.ID); err != nil {
+				t.Fatalf("DeleteUser failed: %v", err)
+			}
+			if permissions, _ := db.ListFolderPermissions(); len(permissions) != 0 {
+				t.Errorf("Expected no permissions left, got %+v", permissions)
+			}
+			if err := db.UpdateUserRole(carol.ID, RoleEditor); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows for a removed account, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"path"
+)
+
+// Folder is a directory in the document tree, keyed by its absolute slash-separated path
+type Folder struct {
+	ID       int
+	Path     string
+	Name     string
+	ParentID int    // 0 for the document root
+	Color    string // label colour as #rgb or #rrggbb, empty for none
+	Icon     string // emoji or short text shown instead of the folder icon, empty for the default
+}
+
+// EnsureFolder records a folder under parentPath (empty for the document root) unless it already exists
+func (p *PostgresDB) EnsureFolder(folderPath string, parentPath string) (*Folder, error) {
+	var parentID sql.NullInt64
+	if parentPath != "" {
+		if err := p.db.QueryRow(`SELECT id FROM folders WHERE path = $1`, parentPath).Scan(&parentID); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err := p.db.Exec(`INSERT INTO folders (path, name, parent_id) VALUES ($1, $2, $3) ON CONFLICT (path) DO NOTHING`,
+		folderPath, path.Base(folderPath), parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanFolder(p.db.QueryRow(`SELECT `+folderColumns+` FROM folders WHERE path = $1`, folderPath))
+}
+
+const folderColumns = `id, path, name, parent_id, color, icon`
+
+// scanFolder reads a row of folderColumns
+func scanFolder(row interface{ Scan(...any) error }) (*Folder, error) {
+	var folder Folder
+	var parentID sql.NullInt64
+	if err := row.Scan(&folder.ID, &folder.Path, &folder.Name, &parentID, &folder.Color, &folder.Icon); err != nil {
+		return nil, err
+	}
+	folder.ParentID = int(parentID.Int64)
+	return &folder, nil
+}
+
+// GetAllFolders returns every folder ordered by path, so parents come before their children
+func (p *PostgresDB) GetAllFolders() ([]Folder, error) {
+	rows, err := p.db.Query(`SELECT ` + folderColumns + ` FROM folders ORDER BY path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var folders []Folder
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, *folder)
+	}
+	return folders, rows.Err()
+}
+
+// SetFolderStyle sets the colour and icon of a folder by ID, or returns sql.ErrNoRows
+func (p *PostgresDB) SetFolderStyle(id int, color string, icon string) (*Folder, error) {
+	return scanFolder(p.db.QueryRow(`UPDATE folders SET color = $2, icon = $3 WHERE id = $1 RETURNING `+folderColumns,
+		id, color, icon))
+}
+
+// DeleteFolderTree removes a folder and everything below it, returning how many folders were removed
+func (p *PostgresDB) DeleteFolderTree(folderPath string) (int, error) {
+	prefix := folderPath + "/"
+	result, err := p.db.Exec(`DELETE FROM folders WHERE path = $1 OR substr(path, 1, $2) = $3`,
+		folderPath, len(prefix), prefix)
+	if err != nil {
+		return 0, err
+	}
+	removed, err := result.RowsAffected()
+	return int(removed), err
+}
+
+// CountDocumentsByFolder returns the number of documents directly in each folder path
+func (p *PostgresDB) CountDocumentsByFolder() (map[string]int, error) {
+	rows, err := p.db.Query(`SELECT folder, COUNT(*) FROM documents GROUP BY folder`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var folder string
+		var count int
+		if err := rows.Scan(&folder, &count); err != nil {
+			return nil, err
+		}
+		counts[folder] = count
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestSetFolderStyle(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// A root folder and two folders below it
+			db := open()
+			defer db.Close()
+			if _, err := db.EnsureFolder("/docs", ""); err != nil {
+				t.Fatalf("EnsureFolder failed: %v", err)
+			}
+			finance, err := db.EnsureFolder("/docs/Finance", "/docs")
+			if err != nil {
+				t.Fatalf("EnsureFolder failed: %v", err)
+			}
+			if _, err := db.EnsureFolder("/docs/Medical", "/docs"); err != nil {
+				t.Fatalf("EnsureFolder failed: %v", err)
+			}
+
+			// One folder is given a colour and icon
+			styled, err := db.SetFolderStyle(finance.ID, "#2e7d32", "💷")
+
+			// It is returned and listed with them, and the others keep none
+			if err != nil || styled.Path != "/docs/Finance" || styled.Color != "#2e7d32" || styled.Icon != "💷" {
+				t.Fatalf("Unexpected styled folder %+v, %v", styled, err)
+			}
+			folders, err := db.GetAllFolders()
+			if err != nil {
+				t.Fatalf("GetAllFolders failed: %v", err)
+			}
+			for _, folder := range folders {
+				wantColor, wantIcon := "", ""
+				if folder.ID == finance.ID {
+					wantColor, wantIcon = "#2e7d32", "💷"
+				}
+				if folder.Color != wantColor || folder.Icon != wantIcon {
+					t.Errorf("Unexpected style on %+v", folder)
+				}
+			}
+
+			// Ensuring the folder again keeps its style
+			again, err := db.EnsureFolder("/docs/Finance", "/docs")
+			if err != nil || again.Color != "#2e7d32" {
+				t.Errorf("Expected the style kept, got %+v, %v", again, err)
+			}
+
+			// Clearing it and styling a missing folder behave
+			if cleared, err := db.SetFolderStyle(finance.ID, "", ""); err != nil || cleared.Color != "" || cleared.Icon != "" {
+				t.Errorf("Expected the style cleared, got %+v, %v", cleared, err)
+			}
+			if _, err := db.SetFolderStyle(9999, "#fff", ""); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows for a missing folder, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Algorithms documents can be hashed with. Hashes are stored as "<algorithm>:<hex digest>"; one
+// without a prefix is an MD5, stored before the algorithm was recorded.
+const (
+	HashMD5    = "md5"
+	HashSHA1   = "sha1"
+	HashSHA256 = "sha256"
+)
+
+var (
+	hashMu        sync.RWMutex
+	hashAlgorithm = HashSHA256
+)
+
+// SetHashAlgorithm chooses the algorithm new and rehashed documents are stored with
+func SetHashAlgorithm(algorithm string) error {
+	switch algorithm {
+	case HashMD5, HashSHA1, HashSHA256:
+	default:
+		return fmt.Errorf("unknown hash algorithm %q, expected %s, %s or %s", algorithm, HashMD5, HashSHA1, HashSHA256)
+	}
+	hashMu.Lock()
+	defer hashMu.Unlock()
+	hashAlgorithm = algorithm
+	return nil
+}
+
+// CurrentHashAlgorithm is the algorithm new documents are stored with
+func CurrentHashAlgorithm() string {
+	hashMu.RLock()
+	defer hashMu.RUnlock()
+	return hashAlgorithm
+}
+
+// HashAlgorithmOf returns the algorithm of a stored hash
+func HashAlgorithmOf(stored string) string {
+	if algorithm, _, found := strings.Cut(stored, ":"); found {
+		return algorithm
+	}
+	return HashMD5
+}
+
+// FileHashes are the hex digests of a file under every supported algorithm. Working them all out in
+// one pass lets a file be matched against documents stored under any algorithm while they are rehashed.
+type FileHashes struct {
+	MD5, SHA1, SHA256 string
+}
+
+// Stored returns the hash under algorithm in the form it is stored in
+func (h FileHashes) Stored(algorithm string) string {
+	switch algorithm {
+	case HashMD5:
+		return HashMD5 + ":" + h.MD5
+	case HashSHA1:
+		return HashSHA1 + ":" + h.SHA1
+	default:
+		return HashSHA256 + ":" + h.SHA256
+	}
+}
+
+// Current is the hash to store for a new document
+func (h FileHashes) Current() string {
+	return h.Stored(CurrentHashAlgorithm())
+}
+
+// String is the current hash, for logs and error messages
+func (h FileHashes) String() string {
+	return h.Current()
+}
+
+// Matches reports whether stored, under whichever algorithm it was made with, is this file's hash
+func (h FileHashes) Matches(stored string) bool {
+	if stored == "" {
+		return false
+	}
+	if !strings.Contains(stored, ":") {
+		return stored == h.MD5
+	}
+	return stored == h.Stored(HashAlgorithmOf(stored))
+}
+
+// Candidates are the stored hashes a document with the same content could have
+func (h FileHashes) Candidates() []string {
+	current := CurrentHashAlgorithm()
+	candidates := []string{h.Stored(current)}
+	for _, algorithm := range []string{HashSHA256, HashSHA1, HashMD5} {
+		if algorithm != current {
+			candidates = append(candidates, h.Stored(algorithm))
+		}
+	}
+	return append(candidates, h.MD5)
+}
+
+// FileHasher is an io.Writer working out FileHashes of everything written to it
+type FileHasher struct {
+	md5, sha1, sha256 hash.Hash
+	writer            io.Writer
+}
+
+// NewFileHasher returns a hasher with nothing written yet
+func NewFileHasher() *FileHasher {
+	h := &FileHasher{md5: md5.New(), sha1: sha1.New(), sha256: sha256.New()}
+	h.writer = io.MultiWriter(h.md5, h.sha1, h.sha256)
+	return h
+}
+
+func (h *FileHasher) Write(p []byte) (int, error) {
+	return h.writer.Write(p)
+}
+
+// Sums returns the hashes of everything written so far
+func (h *FileHasher) Sums() FileHashes {
+	return FileHashes{
+		MD5:    fmt.Sprintf("%x", h.md5.Sum(nil)),
+		SHA1:   fmt.Sprintf("%x", h.sha1.Sum(nil)),
+		SHA256: fmt.Sprintf("%x", h.sha256.Sum(nil)),
+	}
+}
+
+// HashFile works out the hashes of the file at path
+func HashFile(path string) (FileHashes, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return FileHashes{}, err
+	}
+	defer file.Close()
+	hasher := NewFileHasher()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return FileHashes{}, err
+	}
+	return hasher.Sums(), nil
+}
+
+// FindDocumentByHashes returns the stored document with the same content as the hashed file, whichever
+// algorithm its hash was stored with, or nil when there is none. A document whose file was recompressed
+// is also found by the hash of its original. A lookup that fails does not stop the others; its error is
+// returned if none of them finds the document.
+func FindDocumentByHashes(db Repository, hashes FileHashes) (*Document, error) {
+	var lookupErr error
+	for _, candidate := range hashes.Candidates() {
+		document, err := db.GetDocumentByHash(candidate)
+		if err != nil {
+			lookupErr = err
+			continue
+		}
+		if document != nil {
+			return document, nil
+		}
+	}
+	for _, candidate := range hashes.Candidates() {
+		optimisation, err := db.GetDocumentOptimisationByHash(candidate)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			lookupErr = err
+			continue
+		}
+		// The document may have been deleted since
+		if document, err := db.GetDocumentByULID(optimisation.DocumentULID); err == nil && document != nil {
+			return document, nil
+		}
+	}
+	return nil, lookupErr
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestFileHashesMatchEveryAlgorithm(t *testing.T) {
+	// A file hashed under every algorithm
+	path := filepath.Join(t.TempDir(), "letter.txt")
+	if err := os.WriteFile(path, []byte("dear sir"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hashes, err := HashFile(path)
+	if err != nil {
+		t.Fatalf("HashFile failed: %v", err)
+	}
+
+	// New documents are stored with a prefixed SHA-256
+	if hashes.Current() != "sha256:"+hashes.SHA256 {
+		t.Errorf("Expected a prefixed SHA-256, got %s", hashes.Current())
+	}
+
+	// Hashes stored under any algorithm, and legacy unprefixed MD5s, match
+	for _, stored := range []string{hashes.MD5, "md5:" + hashes.MD5, "sha1:" + hashes.SHA1, "sha256:" + hashes.SHA256} {
+		if !hashes.Matches(stored) {
+			t.Errorf("Expected %s to match", stored)
+		}
+	}
+	for _, stored := range []string{"", hashes.SHA256, "sha1:" + hashes.MD5, "crc32:" + hashes.MD5} {
+		if hashes.Matches(stored) {
+			t.Errorf("Expected %q not to match", stored)
+		}
+	}
+	if HashAlgorithmOf(hashes.MD5) != HashMD5 || HashAlgorithmOf("sha1:"+hashes.SHA1) != HashSHA1 {
+		t.Error("Expected the algorithm of legacy and prefixed hashes")
+	}
+}
+
+func TestSetHashAlgorithm(t *testing.T) {
+	defer SetHashAlgorithm(HashSHA256)
+
+	if err := SetHashAlgorithm("crc32"); err == nil {
+		t.Error("Expected an unknown algorithm to be rejected")
+	}
+	if CurrentHashAlgorithm() != HashSHA256 {
+		t.Errorf("Expected the algorithm unchanged, got %s", CurrentHashAlgorithm())
+	}
+	if err := SetHashAlgorithm(HashSHA1); err != nil {
+		t.Fatalf("SetHashAlgorithm failed: %v", err)
+	}
+	hashes := FileHashes{MD5: "m", SHA1: "s1", SHA256: "s256"}
+	if hashes.Current() != "sha1:s1" || hashes.Candidates()[0] != "sha1:s1" {
+		t.Errorf("Expected SHA-1 hashes first, got %s and %v", hashes.Current(), hashes.Candidates())
+	}
+}
+
+func TestFindDocumentByHashesDuringRehash(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// A document stored before hashes were prefixed
+			db := open()
+			defer db.Close()
+			hashes := FileHashes{MD5: "5d41402abc4b2a76b9719d911017c592", SHA1: "aaf4c61d", SHA256: "2cf24dba"}
+			legacy := &Document{
+				Name:         "legacy.pdf",
+				Path:         "/docs/legacy.pdf",
+				IngressTime:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Folder:       "/docs",
+				Hash:         hashes.MD5,
+				ULID:         ulid.Make(),
+				DocumentType: ".pdf",
+			}
+			if err := db.SaveDocument(legacy); err != nil {
+				t.Fatalf("SaveDocument failed: %v", err)
+			}
+
+			// The same content is found by its legacy hash
+			found, err := FindDocumentByHashes(db, hashes)
+			if err != nil || found == nil || found.ULID != legacy.ULID {
+				t.Fatalf("Expected the legacy document, got %v, %v", found, err)
+			}
+
+			// The document is rehashed
+			if err := db.UpdateDocumentHash(legacy.ULID.String(), hashes.Current()); err != nil {
+				t.Fatalf("UpdateDocumentHash failed: %v", err)
+			}
+
+			// It is found by its new hash, and other content is not found
+			found, err = FindDocumentByHashes(db, hashes)
+			if err != nil || found == nil || found.Hash != "sha256:2cf24dba" {
+				t.Fatalf("Expected the rehashed document, got %v, %v", found, err)
+			}
+			found, err = FindDocumentByHashes(db, FileHashes{MD5: "other", SHA1: "other", SHA256: "other"})
+			if err != nil || found != nil {
+				t.Errorf("Expected no document for other content, got %v, %v", found, err)
+			}
+		})
+	}
+}
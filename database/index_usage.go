@@ -0,0 +1,41 @@
+package database
+
+import "errors"
+
+// ErrIndexUsageUnsupported is returned by IndexUsage on databases that keep no index statistics
+var ErrIndexUsageUnsupported = errors.New("index usage statistics need PostgreSQL")
+
+// IndexUsage is how often one index has been used since PostgreSQL's statistics were last reset
+type IndexUsage struct {
+	Table         string `json:"table"`
+	Index         string `json:"index"`
+	Scans         int64  `json:"scans"`         // index scans started
+	TuplesRead    int64  `json:"tuplesRead"`    // index entries returned by those scans
+	TuplesFetched int64  `json:"tuplesFetched"` // table rows fetched through the index
+	SizeBytes     int64  `json:"sizeBytes"`
+}
+
+// indexUsageQuery lists the indexes on godocs' tables, least used first, so an index that never helps stands out
+const indexUsageQuery = `SELECT relname, indexrelname, idx_scan, idx_tup_read, idx_tup_fetch, pg_relation_size(indexrelid)
+	FROM pg_stat_user_indexes
+	WHERE schemaname = current_schema()
+	ORDER BY idx_scan, relname, indexrelname`
+
+// IndexUsage returns the scan counts of every index
+func (p *PostgresDB) IndexUsage() ([]IndexUsage, error) {
+	rows, err := p.db.Query(indexUsageQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []IndexUsage
+	for rows.Next() {
+		var index IndexUsage
+		if err := rows.Scan(&index.Table, &index.Index, &index.Scans, &index.TuplesRead, &index.TuplesFetched, &index.SizeBytes); err != nil {
+			return nil, err
+		}
+		usage = append(usage, index)
+	}
+	return usage, rows.Err()
+}
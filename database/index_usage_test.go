@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFilterIndexes(t *testing.T) {
+	// A SQLite database with every migration applied
+	db := testRepositories()["sqlite"]().(*BunDB)
+	defer db.Close()
+
+	for query, index := range map[string]string{
+		"SELECT id FROM documents WHERE folder = '/bills' ORDER BY ingress_time DESC": "idx_documents_folder_ingress_time",
+		"SELECT id FROM documents WHERE document_type = '.pdf'":                      "idx_documents_document_type",
+	} {
+		// The query is planned
+		rows, err := db.db.QueryContext(context.Background(), "EXPLAIN QUERY PLAN "+query)
+		if err != nil {
+			t.Fatalf("Failed to explain %q: %v", query, err)
+		}
+		var plan []string
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+				t.Fatalf("Failed to read the plan: %v", err)
+			}
+			plan = append(plan, detail)
+		}
+		rows.Close()
+
+		// It uses the filter index
+		if !strings.Contains(strings.Join(plan, "\n"), index) {
+			t.Errorf("Expected %q to use %s, got %v", query, index, plan)
+		}
+	}
+}
+
+func TestIndexUsageNeedsPostgres(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			db := open()
+			defer db.Close()
+			if _, err := db.IndexUsage(); !errors.Is(err, ErrIndexUsageUnsupported) {
+				t.Errorf("Expected ErrIndexUsageUnsupported, got %v", err)
+			}
+		})
+	}
+}
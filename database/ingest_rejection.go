@@ -0,0 +1,67 @@
+package database
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// IngestRejection records a file left in the ingress folder because it cannot be ingested, so the UI can
+// say why it never appeared. There is one per path: the entry stays while the file does and goes once it
+// is removed or becomes ingestible.
+type IngestRejection struct {
+	ID         string    `json:"id"`
+	Path       string    `json:"path"`
+	Name       string    `json:"name"`
+	Reason     string    `json:"reason"`
+	RejectedAt time.Time `json:"rejectedAt"` // when the file was first rejected
+}
+
+// prepareIngestRejection fills in the fields RecordIngestRejection sets on a new entry
+func prepareIngestRejection(rejection *IngestRejection) {
+	if rejection.ID == "" {
+		rejection.ID = MakeULID().String()
+	}
+	if rejection.RejectedAt.IsZero() {
+		rejection.RejectedAt = Now()
+	}
+	rejection.RejectedAt = rejection.RejectedAt.UTC()
+}
+
+const ingestRejectionColumns = `id, path, name, reason, rejected_at`
+
+// RecordIngestRejection records that the file at rejection.Path was rejected. A path already recorded
+// keeps its ID and first rejection time and takes the new reason.
+func (p *PostgresDB) RecordIngestRejection(rejection *IngestRejection) error {
+	prepareIngestRejection(rejection)
+	return p.db.QueryRow(`INSERT INTO ingest_rejections (`+ingestRejectionColumns+`) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (path) DO UPDATE SET name = EXCLUDED.name, reason = EXCLUDED.reason
+		RETURNING id, rejected_at`,
+		rejection.ID, rejection.Path, rejection.Name, rejection.Reason, rejection.RejectedAt).Scan(&rejection.ID, &rejection.RejectedAt)
+}
+
+// ListIngestRejections returns up to limit rejected files, most recently rejected first
+func (p *PostgresDB) ListIngestRejections(limit int) ([]IngestRejection, error) {
+	rows, err := p.db.Query(`SELECT `+ingestRejectionColumns+` FROM ingest_rejections ORDER BY rejected_at DESC, id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rejections []IngestRejection
+	for rows.Next() {
+		var rejection IngestRejection
+		if err := rows.Scan(&rejection.ID, &rejection.Path, &rejection.Name, &rejection.Reason, &rejection.RejectedAt); err != nil {
+			return nil, err
+		}
+		rejections = append(rejections, rejection)
+	}
+	return rejections, rows.Err()
+}
+
+// PruneIngestRejections removes the entries for every path not in keep, the files a walk of the ingress
+// folder rejected again
+func (p *PostgresDB) PruneIngestRejections(keep []string) error {
+	_, err := p.db.Exec(`DELETE FROM ingest_rejections WHERE NOT (path = ANY($1))`, pq.Array(keep))
+	return err
+}
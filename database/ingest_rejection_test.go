@@ -0,0 +1,57 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIngestRejections(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// Two rejected files a minute apart
+			db := open()
+			defer db.Close()
+			defer SetClock(NewFixedClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), time.Minute))()
+			first := IngestRejection{Path: "/ingress/budget.xlsx", Reason: "unsupported file type"}
+			if err := db.RecordIngestRejection(&first); err != nil {
+				t.Fatalf("RecordIngestRejection failed: %v", err)
+			}
+			if err := db.RecordIngestRejection(&IngestRejection{Path: "/ingress/notes.docx", Reason: "unsupported file type"}); err != nil {
+				t.Fatalf("RecordIngestRejection failed: %v", err)
+			}
+
+			// The spreadsheet is rejected again by a later walk
+			again := IngestRejection{Path: "/ingress/budget.xlsx", Name: "budget.xlsx", Reason: "unsupported file type: .xlsx"}
+			if err := db.RecordIngestRejection(&again); err != nil {
+				t.Fatalf("RecordIngestRejection failed: %v", err)
+			}
+
+			// It keeps its first rejection time and takes the new reason
+			if again.ID != first.ID || !again.RejectedAt.Equal(first.RejectedAt) {
+				t.Errorf("Expected the first entry %s at %v, got %s at %v", first.ID, first.RejectedAt, again.ID, again.RejectedAt)
+			}
+			rejections, err := db.ListIngestRejections(10)
+			if err != nil {
+				t.Fatalf("ListIngestRejections failed: %v", err)
+			}
+			if len(rejections) != 2 || rejections[0].Path != "/ingress/notes.docx" || rejections[1].Reason != again.Reason || rejections[1].ID != again.ID {
+				t.Fatalf("Expected the docx then the updated xlsx, got %+v", rejections)
+			}
+
+			// Pruning drops the files no longer rejected
+			if err := db.PruneIngestRejections([]string{"/ingress/budget.xlsx"}); err != nil {
+				t.Fatalf("PruneIngestRejections failed: %v", err)
+			}
+			rejections, err = db.ListIngestRejections(10)
+			if err != nil || len(rejections) != 1 || rejections[0].Name != "budget.xlsx" {
+				t.Errorf("Expected only the xlsx to remain, got %+v, %v", rejections, err)
+			}
+			if err := db.PruneIngestRejections(nil); err != nil {
+				t.Fatalf("PruneIngestRejections failed: %v", err)
+			}
+			if rejections, _ := db.ListIngestRejections(10); len(rejections) != 0 {
+				t.Errorf("Expected no rejections once none are kept, got %+v", rejections)
+			}
+		})
+	}
+}
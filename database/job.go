@@ -25,6 +25,12 @@ const (
 	JobTypeCleanup        JobType = "cleanup"
 	JobTypeWordCloud      JobType = "wordcloud"
 	JobTypeSearchReindex  JobType = "search_reindex"
+	JobTypeURLRepair      JobType = "url_repair"
+	JobTypeBackup         JobType = "backup"
+	JobTypeRehash         JobType = "rehash"
+	JobTypeOptimise       JobType = "optimise"
+	JobTypeExport         JobType = "export"
+	JobTypeFingerprint    JobType = "fingerprint"
 )
 
 // Job represents a background job or operation
@@ -55,7 +61,7 @@ type JobSummary struct {
 
 // CreateJob creates a new job in the database
 func (p *PostgresDB) CreateJob(jobType JobType, message string) (*Job, error) {
-	now := time.Now()
+	now := Now()
 	jobID, err := CalculateUUID(now)
 	if err != nil {
 		return nil, err
@@ -108,13 +114,24 @@ func (p *PostgresDB) UpdateJobProgress(jobID ulid.ULID, progress int, currentSte
 		SET progress = $1, current_step = $2, updated_at = $3
 		WHERE id = $4
 	`
-	_, err := p.db.Exec(query, progress, currentStep, time.Now(), jobID.String())
+	_, err := p.db.Exec(query, progress, currentStep, Now(), jobID.String())
+	return err
+}
+
+// TouchJob records that an active job is still making progress, without changing what it reports
+func (p *PostgresDB) TouchJob(jobID ulid.ULID) error {
+	query := `
+		UPDATE jobs
+		SET updated_at = $1
+		WHERE id = $2 AND status IN ($3, $4)
+	`
+	_, err := p.db.Exec(query, Now(), jobID.String(), JobStatusPending, JobStatusRunning)
 	return err
 }
 
 // UpdateJobStatus updates the status of a job
 func (p *PostgresDB) UpdateJobStatus(jobID ulid.ULID, status JobStatus, message string) error {
-	now := time.Now()
+	now := Now()
 	var startedAt, completedAt interface{}
 
 	if status == JobStatusRunning {
@@ -135,7 +152,7 @@ func (p *PostgresDB) UpdateJobStatus(jobID ulid.ULID, status JobStatus, message
 
 // UpdateJobError updates a job with an error
 func (p *PostgresDB) UpdateJobError(jobID ulid.ULID, errorMsg string) error {
-	now := time.Now()
+	now := Now()
 	query := `
 		UPDATE jobs
 		SET status = $1, error = $2, updated_at = $3, completed_at = $4
@@ -147,7 +164,7 @@ func (p *PostgresDB) UpdateJobError(jobID ulid.ULID, errorMsg string) error {
 
 // CompleteJob marks a job as completed with optional result data
 func (p *PostgresDB) CompleteJob(jobID ulid.ULID, result string) error {
-	now := time.Now()
+	now := Now()
 	query := `
 		UPDATE jobs
 		SET status = $1, progress = 100, result = $2, updated_at = $3, completed_at = $4
@@ -303,7 +320,7 @@ func (p *PostgresDB) GetActiveJobs() ([]Job, error) {
 
 // DeleteOldJobs deletes completed jobs older than the specified duration
 func (p *PostgresDB) DeleteOldJobs(olderThan time.Duration) (int, error) {
-	cutoffTime := time.Now().Add(-olderThan)
+	cutoffTime := Now().Add(-olderThan)
 
 	query := `
 		DELETE FROM jobs
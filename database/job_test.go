@@ -0,0 +1,42 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTouchJob(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// A running job and a completed one, both last updated at nine
+			db := open()
+			defer db.Close()
+			start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+			restore := SetClock(NewFixedClock(start, time.Millisecond))
+			defer restore()
+			running, _ := db.CreateJob(JobTypeBackup, "backup")
+			done, _ := db.CreateJob(JobTypeCleanup, "cleanup")
+			db.UpdateJobStatus(running.ID, JobStatusRunning, "Exporting documents")
+			db.UpdateJobProgress(running.ID, 40, "Exporting")
+			db.CompleteJob(done.ID, "{}")
+
+			// Both are touched at ten
+			SetClock(NewFixedClock(start.Add(time.Hour), 0))
+			if err := db.TouchJob(running.ID); err != nil {
+				t.Fatalf("TouchJob failed: %v", err)
+			}
+			if err := db.TouchJob(done.ID); err != nil {
+				t.Fatalf("TouchJob failed: %v", err)
+			}
+
+			// Only the running job's update time moves, and its progress is kept
+			job, err := db.GetJob(running.ID)
+			if err != nil || !job.UpdatedAt.Equal(start.Add(time.Hour)) || job.Progress != 40 || job.CurrentStep != "Exporting" {
+				t.Errorf("Expected the running job touched with its progress kept, got %+v (%v)", job, err)
+			}
+			if job, _ := db.GetJob(done.ID); !job.UpdatedAt.Before(start.Add(time.Hour)) {
+				t.Errorf("Expected the finished job left alone, updated at %s", job.UpdatedAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// LegalHold stops a document, or every document under a folder, from being deleted by users, cleanup
+// or retention until it is lifted. Holds are placed and removed by administrators and every change is
+// kept as a LegalHoldEvent.
+type LegalHold struct {
+	ID       string    `json:"id"`
+	Scope    string    `json:"scope"`  // LegalHoldDocument or LegalHoldFolder
+	Target   string    `json:"target"` // document ULID, or folder key relative to the document root
+	Reason   string    `json:"reason"`
+	PlacedBy string    `json:"placedBy"`
+	PlacedAt time.Time `json:"placedAt"`
+}
+
+// LegalHoldEvent is one entry in the audit trail of legal holds
+type LegalHoldEvent struct {
+	ID        string    `json:"id"`
+	HoldID    string    `json:"holdId"`
+	Action    string    `json:"action"` // LegalHoldPlaced or LegalHoldRemoved
+	Scope     string    `json:"scope"`
+	Target    string    `json:"target"`
+	Reason    string    `json:"reason"`
+	User      string    `json:"user"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Legal hold scopes and audit actions
+const (
+	LegalHoldDocument = "document"
+	LegalHoldFolder   = "folder"
+	LegalHoldPlaced   = "placed"
+	LegalHoldRemoved  = "removed"
+)
+
+// ErrLegalHoldExists is returned by PlaceLegalHold when the document or folder is already on hold
+var ErrLegalHoldExists = errors.New("a legal hold is already in place")
+
+// prepareLegalHold fills in the fields PlaceLegalHold sets on a new hold
+func prepareLegalHold(hold *LegalHold) {
+	if hold.ID == "" {
+		hold.ID = MakeULID().String()
+	}
+	if hold.PlacedAt.IsZero() {
+		hold.PlacedAt = Now()
+	}
+	hold.PlacedAt = hold.PlacedAt.UTC()
+}
+
+// legalHoldEvent returns the audit entry for action on hold by user
+func legalHoldEvent(hold *LegalHold, action, user, reason string, at time.Time) *LegalHoldEvent {
+	return &LegalHoldEvent{
+		ID:        MakeULID().String(),
+		HoldID:    hold.ID,
+		Action:    action,
+		Scope:     hold.Scope,
+		Target:    hold.Target,
+		Reason:    reason,
+		User:      user,
+		CreatedAt: at.UTC(),
+	}
+}
+
+const legalHoldColumns = `id, scope, target, reason, placed_by, placed_at`
+
+// scanLegalHold reads a row of legalHoldColumns
+func scanLegalHold(row interface{ Scan(...any) error }) (*LegalHold, error) {
+	var hold LegalHold
+	if err := row.Scan(&hold.ID, &hold.Scope, &hold.Target, &hold.Reason, &hold.PlacedBy, &hold.PlacedAt); err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+const legalHoldEventColumns = `id, hold_id, action, scope, target, reason, user_name, created_at`
+
+// insertLegalHoldEvent adds an audit entry within the transaction that changed the hold
+func insertLegalHoldEvent(tx *sql.Tx, event *LegalHoldEvent) error {
+	_, err := tx.Exec(`INSERT INTO legal_hold_events (`+legalHoldEventColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		event.ID, event.HoldID, event.Action, event.Scope, event.Target, event.Reason, event.User, event.CreatedAt)
+	return err
+}
+
+// PlaceLegalHold stores a hold and its audit entry, setting its ID and PlacedAt when missing. A document
+// or folder already on hold is left alone and ErrLegalHoldExists returned.
+func (p *PostgresDB) PlaceLegalHold(hold *LegalHold) error {
+	prepareLegalHold(hold)
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT INTO legal_holds (`+legalHoldColumns+`) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (scope, target) DO NOTHING`,
+		hold.ID, hold.Scope, hold.Target, hold.Reason, hold.PlacedBy, hold.PlacedAt)
+	if err != nil {
+		return err
+	}
+	if inserted, _ := result.RowsAffected(); inserted == 0 {
+		return ErrLegalHoldExists
+	}
+	if err := insertLegalHoldEvent(tx, legalHoldEvent(hold, LegalHoldPlaced, hold.PlacedBy, hold.Reason, hold.PlacedAt)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListLegalHolds returns every hold in place, most recently placed first
+func (p *PostgresDB) ListLegalHolds() ([]LegalHold, error) {
+	rows, err := p.db.Query(`SELECT ` + legalHoldColumns + ` FROM legal_holds ORDER BY placed_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holds []LegalHold
+	for rows.Next() {
+		hold, err := scanLegalHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, *hold)
+	}
+	return holds, rows.Err()
+}
+
+// RemoveLegalHold lifts a hold, recording who lifted it and why, and returns it; sql.ErrNoRows when there is no such hold
+func (p *PostgresDB) RemoveLegalHold(id, user, reason string) (*LegalHold, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	hold, err := scanLegalHold(tx.QueryRow(`SELECT `+legalHoldColumns+` FROM legal_holds WHERE id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM legal_holds WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	if err := insertLegalHoldEvent(tx, legalHoldEvent(hold, LegalHoldRemoved, user, reason, Now())); err != nil {
+		return nil, err
+	}
+	return hold, tx.Commit()
+}
+
+// ListLegalHoldEvents returns the most recent limit entries of the legal hold audit trail, newest first
+func (p *PostgresDB) ListLegalHoldEvents(limit int) ([]LegalHoldEvent, error) {
+	rows, err := p.db.Query(`SELECT `+legalHoldEventColumns+` FROM legal_hold_events ORDER BY created_at DESC, id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []LegalHoldEvent
+	for rows.Next() {
+		var event LegalHoldEvent
+		if err := rows.Scan(&event.ID, &event.HoldID, &event.Action, &event.Scope, &event.Target, &event.Reason, &event.User, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLegalHolds(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// A document hold and a folder hold placed a day apart
+			db := open()
+			defer db.Close()
+			document := &LegalHold{Scope: LegalHoldDocument, Target: "01HZX0000000000000000000A1", Reason: "Smith v Jones", PlacedBy: "alice", PlacedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}
+			folder := &LegalHold{Scope: LegalHoldFolder, Target: "contracts/2023", Reason: "audit", PlacedBy: "bob", PlacedAt: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)}
+			for _, hold := range []*LegalHold{document, folder} {
+				if err := db.PlaceLegalHold(hold); err != nil {
+					t.Fatalf("PlaceLegalHold failed: %v", err)
+				}
+			}
+			if document.ID == "" {
+				t.Fatal("PlaceLegalHold should set the hold ID")
+			}
+
+			// The same document is put on hold again
+			err := db.PlaceLegalHold(&LegalHold{Scope: LegalHoldDocument, Target: document.Target, PlacedBy: "bob"})
+
+			// It is refused and the holds are listed most recently placed first
+			if !errors.Is(err, ErrLegalHoldExists) {
+				t.Errorf("Expected ErrLegalHoldExists, got %v", err)
+			}
+			holds, err := db.ListLegalHolds()
+			if err != nil {
+				t.Fatalf("ListLegalHolds failed: %v", err)
+			}
+			if len(holds) != 2 || holds[0].ID != folder.ID || holds[1].Reason != "Smith v Jones" || !holds[1].PlacedAt.Equal(document.PlacedAt) {
+				t.Errorf("Unexpected holds %+v", holds)
+			}
+
+			// The document hold is lifted
+			removed, err := db.RemoveLegalHold(document.ID, "carol", "case settled")
+			if err != nil {
+				t.Fatalf("RemoveLegalHold failed: %v", err)
+			}
+
+			// It is gone, lifting it again is not found, and the audit trail keeps all three changes
+			if removed.Target != document.Target {
+				t.Errorf("Unexpected removed hold %+v", removed)
+			}
+			if _, err := db.RemoveLegalHold(document.ID, "carol", ""); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows removing twice, got %v", err)
+			}
+			if holds, _ := db.ListLegalHolds(); len(holds) != 1 {
+				t.Errorf("Expected one hold left, got %+v", holds)
+			}
+			events, err := db.ListLegalHoldEvents(10)
+			if err != nil {
+				t.Fatalf("ListLegalHoldEvents failed: %v", err)
+			}
+			if len(events) != 3 || events[0].Action != LegalHoldRemoved || events[0].User != "carol" || events[0].Reason != "case settled" || events[0].HoldID != document.ID {
+				t.Errorf("Unexpected events %+v", events)
+			}
+			if events, _ := db.ListLegalHoldEvents(1); len(events) != 1 {
+				t.Errorf("Expected the limit to apply, got %d events", len(events))
+			}
+		})
+	}
+}
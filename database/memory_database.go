@@ -0,0 +1,1873 @@
+package database
+
+import (
+	"database/sql"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/oklog/ulid/v2"
+)
+
+// MemoryDB implements Repository with in-process maps. Nothing is persisted, so it suits unit tests and demos.
+// Lookups that find nothing return sql.ErrNoRows, like the SQL repositories.
+type MemoryDB struct {
+	mu           sync.RWMutex
+	documents    map[int]*Document
+	byPath       map[string]int // document path to ID, the upsert key
+	nextDocID    int
+	config       *config.ServerConfig
+	folders      map[string]*Folder
+	nextFolderID int
+	words        map[string]WordFrequency
+	wordMeta     WordCloudMetadata
+	jobs         map[ulid.ULID]*Job
+	accesses     map[string]*memoryAccess // keyed by document ULID
+	searches     []SearchQuery
+	collections  map[string]*memoryCollection // keyed by collection ULID
+	schedules    map[string]string
+	events       []DocumentEvent
+	smartFolders map[string]SmartFolder     // keyed by smart folder ULID
+	locks        map[string]DocumentLock    // keyed by document ULID
+	archives     map[string]DocumentArchive // keyed by document ULID
+	holds        map[string]LegalHold       // keyed by hold ID
+	holdEvents   []LegalHoldEvent
+	redactions   map[string]DocumentRedaction // keyed by redacted copy ULID
+	auditEvents  []AuditEvent
+	rejections   map[string]IngestRejection     // keyed by path
+	spreadsheets map[string]SpreadsheetDetails  // keyed by document ULID
+	users        map[string]User                // keyed by user ID
+	sessions     map[string]Session             // keyed by token hash
+	permissions  map[[2]string]FolderPermission // keyed by folder and user ID
+	tags         map[string]map[string]bool     // tag names keyed by document ULID
+	clientErrors []ClientError
+	rotations    map[string][]PageRotation       // keyed by document ULID
+	blankPages   map[string][]BlankPage          // keyed by document ULID
+	optimised    map[string]DocumentOptimisation // keyed by document ULID
+	fingerprints map[string]DocumentFingerprint  // keyed by document ULID
+	templates    map[string]ExtractionTemplate   // keyed by template ULID
+	fields       map[string][]DocumentField      // keyed by document ULID
+	state        map[string]string               // shared server state keyed by name
+}
+
+// memoryCollection is a collection and its document ULIDs in snapshot order
+type memoryCollection struct {
+	Collection
+	documentULIDs []string
+}
+
+// memoryAccess is the access counter and daily rollups for one document
+type memoryAccess struct {
+	count int
+	last  time.Time
+	daily map[string]int
+}
+
+// NewMemoryDB returns an empty in-memory repository
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		documents:    make(map[int]*Document),
+		byPath:       make(map[string]int),
+		folders:      make(map[string]*Folder),
+		words:        make(map[string]WordFrequency),
+		jobs:         make(map[ulid.ULID]*Job),
+		accesses:     make(map[string]*memoryAccess),
+		collections:  make(map[string]*memoryCollection),
+		schedules:    make(map[string]string),
+		smartFolders: make(map[string]SmartFolder),
+		locks:        make(map[string]DocumentLock),
+		archives:     make(map[string]DocumentArchive),
+		holds:        make(map[string]LegalHold),
+		redactions:   make(map[string]DocumentRedaction),
+		rejections:   make(map[string]IngestRejection),
+		spreadsheets: make(map[string]SpreadsheetDetails),
+		rotations:    make(map[string][]PageRotation),
+		blankPages:   make(map[string][]BlankPage),
+		optimised:    make(map[string]DocumentOptimisation),
+		fingerprints: make(map[string]DocumentFingerprint),
+		templates:    make(map[string]ExtractionTemplate),
+		fields:       make(map[string][]DocumentField),
+		users:        make(map[string]User),
+		sessions:     make(map[string]Session),
+		permissions:  make(map[[2]string]FolderPermission),
+		tags:         make(map[string]map[string]bool),
+		state:        make(map[string]string),
+	}
+}
+
+// Close does nothing; the data lives as long as the MemoryDB
+func (m *MemoryDB) Close() error {
+	return nil
+}
+
+// SaveDocument saves or updates a document, matching on path like the SQL upsert
+func (m *MemoryDB) SaveDocument(doc *Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saveDocument(doc)
+	return nil
+}
+
+func (m *MemoryDB) saveDocument(doc *Document) {
+	stored := *doc
+	id, ok := m.byPath[doc.Path]
+	if !ok {
+		m.nextDocID++
+		id = m.nextDocID
+		m.byPath[doc.Path] = id
+	}
+	stored.StormID = id
+	m.documents[id] = &stored
+	doc.StormID = stored.StormID
+}
+
+// SaveDocumentBatch upserts many documents and adds their word counts.
+// StormID is set on each document once it is saved.
+func (m *MemoryDB) SaveDocumentBatch(docs []*Document, wordCounts map[string]int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, doc := range docs {
+		m.saveDocument(doc)
+	}
+	m.addWordFrequencies(wordCounts)
+	return nil
+}
+
+// findDocument returns a copy of the first document matching, or sql.ErrNoRows
+func (m *MemoryDB) findDocument(match func(*Document) bool) (*Document, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, doc := range m.documents {
+		if match(doc) {
+			found := *doc
+			return &found, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// GetDocumentByID retrieves a document by ID
+func (m *MemoryDB) GetDocumentByID(id int) (*Document, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	doc, ok := m.documents[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	found := *doc
+	return &found, nil
+}
+
+// GetDocumentByULID retrieves a document by ULID
+func (m *MemoryDB) GetDocumentByULID(ulidStr string) (*Document, error) {
+	return m.findDocument(func(doc *Document) bool { return doc.ULID.String() == ulidStr })
+}
+
+// GetDocumentText retrieves only the full text of a document by ULID
+func (m *MemoryDB) GetDocumentText(ulidStr string) (string, error) {
+	doc, err := m.GetDocumentByULID(ulidStr)
+	if err != nil {
+		return "", err
+	}
+	return doc.FullText, nil
+}
+
+// GetDocumentTextRange returns up to limit characters of a document's full text starting at offset, and
+// the length of the whole text in characters
+func (m *MemoryDB) GetDocumentTextRange(ulidStr string, offset int, limit int) (string, int, error) {
+	doc, err := m.GetDocumentByULID(ulidStr)
+	if err != nil {
+		return "", 0, err
+	}
+	text := []rune(doc.FullText)
+	return string(page(text, offset, limit)), len(text), nil
+}
+
+// GetDocumentByPath retrieves a document by file path
+func (m *MemoryDB) GetDocumentByPath(path string) (*Document, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	id, ok := m.byPath[path]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	found := *m.documents[id]
+	return &found, nil
+}
+
+// GetDocumentByHash retrieves a document by hash, returning nil without an error when there is none
+func (m *MemoryDB) GetDocumentByHash(hash string) (*Document, error) {
+	doc, err := m.findDocument(func(doc *Document) bool { return doc.Hash == hash })
+	if err == sql.ErrNoRows {
+		return nil, nil // No duplicate found
+	}
+	return doc, err
+}
+
+// listDocuments returns copies of the documents matching, in the order given by less.
+// The full text is dropped unless withText is set, as the SQL list queries do.
+func (m *MemoryDB) listDocuments(match func(*Document) bool, less func(a, b *Document) bool, withText bool) []Document {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	docs := make([]Document, 0, len(m.documents))
+	for _, doc := range m.documents {
+		if match != nil && !match(doc) {
+			continue
+		}
+		found := *doc
+		if !withText {
+			found.FullText = ""
+		}
+		docs = append(docs, found)
+	}
+	sort.Slice(docs, func(i, j int) bool { return less(&docs[i], &docs[j]) })
+	return docs
+}
+
+// newestFirst orders by ingress time then ID, both descending
+func newestFirst(a, b *Document) bool {
+	if !a.IngressTime.Equal(b.IngressTime) {
+		return a.IngressTime.After(b.IngressTime)
+	}
+	return a.StormID > b.StormID
+}
+
+// page returns items[offset:offset+limit], clamped to the slice
+func page[T any](items []T, offset int, limit int) []T {
+	if offset >= len(items) || offset < 0 {
+		return []T{}
+	}
+	items = items[offset:]
+	if limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// GetNewestDocuments retrieves the newest documents
+func (m *MemoryDB) GetNewestDocuments(limit int) ([]Document, error) {
+	return page(m.listDocuments(nil, newestFirst, true), 0, limit), nil
+}
+
+// GetNewestDocumentsWithPagination retrieves documents with pagination support
+func (m *MemoryDB) GetNewestDocumentsWithPagination(pageNumber int, pageSize int) ([]Document, int, error) {
+	docs := m.listDocuments(nil, newestFirst, false)
+	return page(docs, (pageNumber-1)*pageSize, pageSize), len(docs), nil
+}
+
+// GetNewestDocumentsAfter retrieves up to limit documents, newest first, that come after cursor.
+// A nil cursor starts from the newest document.
+func (m *MemoryDB) GetNewestDocumentsAfter(cursor *DocumentCursor, limit int) ([]Document, error) {
+	var after func(*Document) bool
+	if cursor != nil {
+		after = func(doc *Document) bool {
+			return doc.IngressTime.Before(cursor.IngressTime) ||
+				(doc.IngressTime.Equal(cursor.IngressTime) && doc.StormID < cursor.ID)
+		}
+	}
+	return page(m.listDocuments(after, newestFirst, false), 0, limit), nil
+}
+
+// GetAllDocuments retrieves all documents
+func (m *MemoryDB) GetAllDocuments() ([]Document, error) {
+	return m.listDocuments(nil, func(a, b *Document) bool { return a.StormID < b.StormID }, true), nil
+}
+
+// GetDocumentsByFolder retrieves documents in a specific folder
+func (m *MemoryDB) GetDocumentsByFolder(folder string) ([]Document, error) {
+	inFolder := func(doc *Document) bool { return doc.Folder == folder }
+	return m.listDocuments(inFolder, func(a, b *Document) bool { return a.StormID < b.StormID }, false), nil
+}
+
+// GetDocumentsUnderFolder retrieves documents in a folder and all of its subfolders
+func (m *MemoryDB) GetDocumentsUnderFolder(folder string) ([]Document, error) {
+	prefix := strings.TrimSuffix(folder, "/") + "/"
+	under := func(doc *Document) bool { return doc.Folder == folder || strings.HasPrefix(doc.Folder, prefix) }
+	byFolderName := func(a, b *Document) bool {
+		if a.Folder != b.Folder {
+			return a.Folder < b.Folder
+		}
+		return a.Name < b.Name
+	}
+	return m.listDocuments(under, byFolderName, false), nil
+}
+
+// updateDocument applies change to the document with the given ULID, if there is one
+func (m *MemoryDB) updateDocument(ulidStr string, change func(*Document)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, doc := range m.documents {
+		if doc.ULID.String() == ulidStr {
+			change(doc)
+		}
+	}
+	return nil
+}
+
+// DeleteDocument deletes a document by ULID
+func (m *MemoryDB) DeleteDocument(ulidStr string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, doc := range m.documents {
+		if doc.ULID.String() == ulidStr {
+			delete(m.documents, id)
+			delete(m.byPath, doc.Path)
+			delete(m.accesses, ulidStr)
+			delete(m.tags, ulidStr)
+			delete(m.fields, ulidStr)
+		}
+	}
+	return nil
+}
+
+// RecordDocumentAccess counts one view or download of a document, on its counter and in today's rollup
+func (m *MemoryDB) RecordDocumentAccess(ulidStr string, at time.Time) error {
+	if _, err := m.GetDocumentByULID(ulidStr); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	access := m.accesses[ulidStr]
+	if access == nil {
+		access = &memoryAccess{daily: make(map[string]int)}
+		m.accesses[ulidStr] = access
+	}
+	access.count++
+	access.last = at
+	access.daily[accessDay(at)]++
+	return nil
+}
+
+// GetDocumentAccessStats lists documents by how often they were opened since the given day (zero for all time)
+func (m *MemoryDB) GetDocumentAccessStats(since time.Time, limit int, leastUsed bool) ([]DocumentAccess, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sinceDay := accessDay(since)
+	var stats []DocumentAccess
+	for _, doc := range m.documents {
+		stat := DocumentAccess{Document: *doc}
+		stat.FullText = ""
+		if access := m.accesses[doc.ULID.String()]; access != nil {
+			stat.AccessCount = access.count
+			last := access.last
+			stat.LastAccessed = &last
+			stat.PeriodCount = access.count
+			if !since.IsZero() {
+				stat.PeriodCount = 0
+				for day, count := range access.daily {
+					if day >= sinceDay {
+						stat.PeriodCount += count
+					}
+				}
+			}
+		}
+		if stat.PeriodCount > 0 || leastUsed {
+			stats = append(stats, stat)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := &stats[i], &stats[j]
+		if leastUsed {
+			a, b = b, a
+		}
+		if a.PeriodCount != b.PeriodCount {
+			return a.PeriodCount > b.PeriodCount
+		}
+		return newestFirst(&a.Document, &b.Document)
+	})
+	return page(stats, 0, limit), nil
+}
+
+// RecordSearch stores a search query; CreatedAt defaults to now
+func (m *MemoryDB) RecordSearch(query *SearchQuery) error {
+	if query.CreatedAt.IsZero() {
+		query.CreatedAt = Now()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	query.ID = len(m.searches) + 1
+	m.searches = append(m.searches, *query)
+	return nil
+}
+
+// GetSearchHistory returns a user's most recent searches, newest first
+func (m *MemoryDB) GetSearchHistory(user string, limit int) ([]SearchQuery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var queries []SearchQuery
+	for _, query := range m.searches {
+		if query.User == user {
+			queries = append(queries, query)
+		}
+	}
+	sort.SliceStable(queries, func(i, j int) bool {
+		if !queries[i].CreatedAt.Equal(queries[j].CreatedAt) {
+			return queries[i].CreatedAt.After(queries[j].CreatedAt)
+		}
+		return queries[i].ID > queries[j].ID
+	})
+	return page(queries, 0, limit), nil
+}
+
+// GetZeroResultSearches returns the terms most often searched without results since the given time
+func (m *MemoryDB) GetZeroResultSearches(since time.Time, limit int) ([]SearchTermCount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	byTerm := make(map[string]*SearchTermCount)
+	for _, query := range m.searches {
+		if query.ResultCount != 0 || query.CreatedAt.Before(since) {
+			continue
+		}
+		term := strings.ToLower(query.Term)
+		count := byTerm[term]
+		if count == nil {
+			count = &SearchTermCount{Term: term}
+			byTerm[term] = count
+		}
+		count.Searches++
+		if query.CreatedAt.After(count.LastSearched) {
+			count.LastSearched = query.CreatedAt
+		}
+	}
+	terms := make([]SearchTermCount, 0, len(byTerm))
+	for _, count := range byTerm {
+		terms = append(terms, *count)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Searches != terms[j].Searches {
+			return terms[i].Searches > terms[j].Searches
+		}
+		return terms[i].LastSearched.After(terms[j].LastSearched)
+	})
+	return page(terms, 0, limit), nil
+}
+
+// CreateCollection stores a collection and its documents in the given order.
+// The ULID and CreatedAt are set when missing and DocumentCount is set from the documents.
+func (m *MemoryDB) CreateCollection(collection *Collection, documentULIDs []string) error {
+	prepareCollection(collection, documentULIDs)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.collections[collection.ULID.String()] = &memoryCollection{
+		Collection:    *collection,
+		documentULIDs: append([]string(nil), documentULIDs...),
+	}
+	return nil
+}
+
+// GetCollection returns a collection by ULID, or sql.ErrNoRows
+func (m *MemoryDB) GetCollection(ulidStr string) (*Collection, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stored, ok := m.collections[ulidStr]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	collection := stored.Collection
+	return &collection, nil
+}
+
+// GetCollectionByShareToken returns the collection shared with a token, or sql.ErrNoRows
+func (m *MemoryDB) GetCollectionByShareToken(token string) (*Collection, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, stored := range m.collections {
+		if token != "" && stored.ShareToken == token {
+			collection := stored.Collection
+			return &collection, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// ListCollections returns every collection, newest first
+func (m *MemoryDB) ListCollections() ([]Collection, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	collections := make([]Collection, 0, len(m.collections))
+	for _, stored := range m.collections {
+		collections = append(collections, stored.Collection)
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		if !collections[i].CreatedAt.Equal(collections[j].CreatedAt) {
+			return collections[i].CreatedAt.After(collections[j].CreatedAt)
+		}
+		return collections[i].ULID.Compare(collections[j].ULID) > 0
+	})
+	return collections, nil
+}
+
+// GetCollectionDocuments returns a collection's documents in snapshot order, without their text.
+// Documents deleted since the snapshot are left out.
+func (m *MemoryDB) GetCollectionDocuments(ulidStr string) ([]Document, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stored, ok := m.collections[ulidStr]
+	if !ok {
+		return nil, nil
+	}
+	byULID := make(map[string]*Document, len(m.documents))
+	for _, doc := range m.documents {
+		byULID[doc.ULID.String()] = doc
+	}
+	var docs []Document
+	for _, documentULID := range stored.documentULIDs {
+		if doc, ok := byULID[documentULID]; ok {
+			listed := *doc
+			listed.FullText = ""
+			docs = append(docs, listed)
+		}
+	}
+	return docs, nil
+}
+
+// DeleteCollection removes a collection; its documents are not touched
+func (m *MemoryDB) DeleteCollection(ulidStr string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.collections[ulidStr]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.collections, ulidStr)
+	return nil
+}
+
+// UpdateDocumentURL updates the URL field of a document
+func (m *MemoryDB) UpdateDocumentURL(ulidStr string, url string) error {
+	return m.updateDocument(ulidStr, func(doc *Document) { doc.URL = url })
+}
+
+// UpdateDocumentFolder updates the Folder field of a document
+func (m *MemoryDB) UpdateDocumentFolder(ulidStr string, folder string) error {
+	return m.updateDocument(ulidStr, func(doc *Document) { doc.Folder = folder })
+}
+
+// UpdateDocumentFileDetails updates the size and page count of a document
+func (m *MemoryDB) UpdateDocumentFileDetails(ulidStr string, size int64, pageCount int) error {
+	return m.updateDocument(ulidStr, func(doc *Document) { doc.Size, doc.PageCount = size, pageCount })
+}
+
+// UpdateDocumentOCRStatus records how far text recognition has got for a document
+func (m *MemoryDB) UpdateDocumentOCRStatus(ulidStr string, status string) error {
+	return m.updateDocument(ulidStr, func(doc *Document) { doc.OCRStatus = status })
+}
+
+// UpdateDocumentHash replaces the stored hash of a document, e.g. with one under another algorithm
+func (m *MemoryDB) UpdateDocumentHash(ulidStr string, hash string) error {
+	return m.updateDocument(ulidStr, func(doc *Document) { doc.Hash = hash })
+}
+
+// SaveConfig saves server configuration
+func (m *MemoryDB) SaveConfig(cfg *config.ServerConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *cfg
+	stored.StormID = 1
+	m.config = &stored
+	return nil
+}
+
+// GetConfig retrieves server configuration
+func (m *MemoryDB) GetConfig() (*config.ServerConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.config == nil {
+		return nil, sql.ErrNoRows
+	}
+	cfg := *m.config
+	return &cfg, nil
+}
+
+// SearchDocuments does a case-insensitive substring match on full text and name, like the SQLite LIKE search
+func (m *MemoryDB) SearchDocuments(searchTerm string) ([]Document, error) {
+	term := strings.ToLower(searchTerm)
+	matches := func(doc *Document) bool {
+		return strings.Contains(strings.ToLower(doc.FullText), term) || strings.Contains(strings.ToLower(doc.Name), term)
+	}
+	return m.listDocuments(matches, newestFirst, false), nil
+}
+
+// SearchDocumentNames returns documents whose name contains fragment, case-insensitively, names that
+// start with it first and then newest first
+func (m *MemoryDB) SearchDocumentNames(fragment string, limit int) ([]Document, error) {
+	fragment = strings.ToLower(fragment)
+	matches := func(doc *Document) bool {
+		return strings.Contains(strings.ToLower(doc.Name), fragment)
+	}
+	startsFirst := func(a, b *Document) bool {
+		aStarts, bStarts := strings.HasPrefix(strings.ToLower(a.Name), fragment), strings.HasPrefix(strings.ToLower(b.Name), fragment)
+		if aStarts != bStarts {
+			return aStarts
+		}
+		return newestFirst(a, b)
+	}
+	return page(m.listDocuments(matches, startsFirst, false), 0, limit), nil
+}
+
+// ReindexSearchDocuments has nothing to rebuild for substring searches
+func (m *MemoryDB) ReindexSearchDocuments() (int, error) {
+	return 0, nil
+}
+
+// EnsureFolder records a folder under parentPath (empty for the document root) unless it already exists
+func (m *MemoryDB) EnsureFolder(folderPath string, parentPath string) (*Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.folders[folderPath]; ok {
+		folder := *existing
+		return &folder, nil
+	}
+	folder := &Folder{Path: folderPath, Name: path.Base(folderPath)}
+	if parentPath != "" {
+		parent, ok := m.folders[parentPath]
+		if !ok {
+			return nil, sql.ErrNoRows
+		}
+		folder.ParentID = parent.ID
+	}
+	m.nextFolderID++
+	folder.ID = m.nextFolderID
+	m.folders[folderPath] = folder
+	stored := *folder
+	return &stored, nil
+}
+
+// GetAllFolders returns every folder ordered by path, so parents come before their children
+func (m *MemoryDB) GetAllFolders() ([]Folder, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	folders := make([]Folder, 0, len(m.folders))
+	for _, folder := range m.folders {
+		folders = append(folders, *folder)
+	}
+	sort.Slice(folders, func(i, j int) bool { return folders[i].Path < folders[j].Path })
+	return folders, nil
+}
+
+// SetFolderStyle sets the colour and icon of a folder by ID, or returns sql.ErrNoRows
+func (m *MemoryDB) SetFolderStyle(id int, color string, icon string) (*Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, folder := range m.folders {
+		if folder.ID == id {
+			folder.Color = color
+			folder.Icon = icon
+			stored := *folder
+			return &stored, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// DeleteFolderTree removes a folder and everything below it, returning how many folders were removed
+func (m *MemoryDB) DeleteFolderTree(folderPath string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	removed := 0
+	for p := range m.folders {
+		if p == folderPath || strings.HasPrefix(p, folderPath+"/") {
+			delete(m.folders, p)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// CountDocumentsByFolder returns the number of documents directly in each folder path
+func (m *MemoryDB) CountDocumentsByFolder() (map[string]int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	counts := make(map[string]int)
+	for _, doc := range m.documents {
+		counts[doc.Folder]++
+	}
+	return counts, nil
+}
+
+// GetTopWords retrieves the top N most frequent words
+func (m *MemoryDB) GetTopWords(limit int) ([]WordFrequency, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	words := make([]WordFrequency, 0, len(m.words))
+	for _, word := range m.words {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if words[i].Frequency != words[j].Frequency {
+			return words[i].Frequency > words[j].Frequency
+		}
+		return words[i].Word < words[j].Word
+	})
+	return page(words, 0, limit), nil
+}
+
+// GetWordCloudMetadata retrieves metadata about the word cloud
+func (m *MemoryDB) GetWordCloudMetadata() (*WordCloudMetadata, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	meta := m.wordMeta
+	return &meta, nil
+}
+
+// RecalculateAllWordFrequencies performs a full recalculation of word frequencies
+func (m *MemoryDB) RecalculateAllWordFrequencies() error {
+	docs, err := m.GetAllDocuments()
+	if err != nil {
+		return err
+	}
+	tokenizer := NewWordTokenizer()
+	globalFrequencies := make(map[string]int)
+	for _, doc := range docs {
+		for word, count := range tokenizer.TokenizeAndCount(doc.FullText + " " + doc.Name) {
+			globalFrequencies[word] += count
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.words = make(map[string]WordFrequency, len(globalFrequencies))
+	m.addWordFrequencies(globalFrequencies)
+	m.wordMeta = WordCloudMetadata{
+		LastCalculation:    Now(),
+		TotalDocsProcessed: len(docs),
+		TotalWordsIndexed:  len(globalFrequencies),
+		Version:            m.wordMeta.Version + 1,
+	}
+	return nil
+}
+
+// UpdateWordFrequencies updates word frequencies after document ingestion
+func (m *MemoryDB) UpdateWordFrequencies(docID string) error {
+	doc, err := m.GetDocumentByULID(docID)
+	if err != nil {
+		return err
+	}
+	tokenizer := NewWordTokenizer()
+	return m.AddWordFrequencies(tokenizer.TokenizeAndCount(doc.FullText + " " + doc.Name))
+}
+
+// AddWordFrequencies adds pre-aggregated word counts
+func (m *MemoryDB) AddWordFrequencies(counts map[string]int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.addWordFrequencies(counts)
+	return nil
+}
+
+func (m *MemoryDB) addWordFrequencies(counts map[string]int) {
+	now := Now()
+	for word, count := range counts {
+		frequency := m.words[word]
+		frequency.Word = word
+		frequency.Frequency += count
+		frequency.Updated = now
+		m.words[word] = frequency
+	}
+}
+
+// CreateJob creates a new pending job
+func (m *MemoryDB) CreateJob(jobType JobType, message string) (*Job, error) {
+	now := Now()
+	jobID, err := CalculateUUID(now)
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{
+		ID:        jobID,
+		Type:      jobType,
+		Status:    JobStatusPending,
+		Message:   message,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *job
+	m.jobs[jobID] = &stored
+	return job, nil
+}
+
+// updateJob applies change to a job and stamps UpdatedAt, ignoring unknown IDs like an UPDATE would
+func (m *MemoryDB) updateJob(jobID ulid.ULID, change func(job *Job, now time.Time)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job, ok := m.jobs[jobID]; ok {
+		now := Now()
+		change(job, now)
+		job.UpdatedAt = now
+	}
+	return nil
+}
+
+// UpdateJobProgress updates the progress of a job
+func (m *MemoryDB) UpdateJobProgress(jobID ulid.ULID, progress int, currentStep string) error {
+	return m.updateJob(jobID, func(job *Job, now time.Time) {
+		job.Progress = progress
+		job.CurrentStep = currentStep
+	})
+}
+
+// TouchJob records that an active job is still making progress, without changing what it reports
+func (m *MemoryDB) TouchJob(jobID ulid.ULID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job, ok := m.jobs[jobID]; ok && (job.Status == JobStatusPending || job.Status == JobStatusRunning) {
+		job.UpdatedAt = Now()
+	}
+	return nil
+}
+
+// UpdateJobStatus updates the status of a job
+func (m *MemoryDB) UpdateJobStatus(jobID ulid.ULID, status JobStatus, message string) error {
+	return m.updateJob(jobID, func(job *Job, now time.Time) {
+		job.Status = status
+		job.Message = message
+		if status == JobStatusRunning && job.StartedAt == nil {
+			job.StartedAt = &now
+		}
+		if status == JobStatusCompleted || status == JobStatusFailed || status == JobStatusCancelled {
+			job.CompletedAt = &now
+		}
+	})
+}
+
+// UpdateJobError updates a job with an error
+func (m *MemoryDB) UpdateJobError(jobID ulid.ULID, errorMsg string) error {
+	return m.updateJob(jobID, func(job *Job, now time.Time) {
+		job.Status = JobStatusFailed
+		job.Error = errorMsg
+		job.CompletedAt = &now
+	})
+}
+
+// CompleteJob marks a job as completed with optional result data
+func (m *MemoryDB) CompleteJob(jobID ulid.ULID, result string) error {
+	return m.updateJob(jobID, func(job *Job, now time.Time) {
+		job.Status = JobStatusCompleted
+		job.Progress = 100
+		job.Result = result
+		job.CompletedAt = &now
+	})
+}
+
+// GetJob retrieves a job by ID
+func (m *MemoryDB) GetJob(jobID ulid.ULID) (*Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	job, ok := m.jobs[jobID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	found := *job
+	return &found, nil
+}
+
+// listJobs returns copies of the jobs matching, newest first
+func (m *MemoryDB) listJobs(match func(*Job) bool) []Job {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		if match == nil || match(job) {
+			jobs = append(jobs, *job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID.Compare(jobs[j].ID) > 0
+	})
+	return jobs
+}
+
+// GetRecentJobs retrieves the most recent jobs with pagination
+func (m *MemoryDB) GetRecentJobs(limit, offset int) ([]Job, error) {
+	return page(m.listJobs(nil), offset, limit), nil
+}
+
+// GetActiveJobs retrieves all running or pending jobs
+func (m *MemoryDB) GetActiveJobs() ([]Job, error) {
+	return m.listJobs(func(job *Job) bool {
+		return job.Status == JobStatusPending || job.Status == JobStatusRunning
+	}), nil
+}
+
+// DeleteOldJobs deletes finished jobs that completed longer ago than olderThan
+func (m *MemoryDB) DeleteOldJobs(olderThan time.Duration) (int, error) {
+	cutoffTime := Now().Add(-olderThan)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deleted := 0
+	for id, job := range m.jobs {
+		finished := job.Status == JobStatusCompleted || job.Status == JobStatusFailed || job.Status == JobStatusCancelled
+		if finished && job.CompletedAt != nil && job.CompletedAt.Before(cutoffTime) {
+			delete(m.jobs, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// GetJobSchedules returns a copy of the saved schedules, keyed by job name
+func (m *MemoryDB) GetJobSchedules() (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	schedules := make(map[string]string, len(m.schedules))
+	for name, spec := range m.schedules {
+		schedules[name] = spec
+	}
+	return schedules, nil
+}
+
+// SaveJobSchedules stores the given schedules; an empty spec removes the saved schedule
+func (m *MemoryDB) SaveJobSchedules(schedules map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, spec := range schedules {
+		if spec == "" {
+			delete(m.schedules, name)
+			continue
+		}
+		m.schedules[name] = spec
+	}
+	return nil
+}
+
+// GetServerState returns the shared state saved under name, empty when nothing is saved
+func (m *MemoryDB) GetServerState(name string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state[name], nil
+}
+
+// SetServerState saves shared state under name, replacing what was there
+func (m *MemoryDB) SetServerState(name string, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state[name] = value
+	return nil
+}
+
+// AddTag tags a document. Tagging a document twice does nothing.
+func (m *MemoryDB) AddTag(documentULID string, tag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tags[documentULID] == nil {
+		m.tags[documentULID] = make(map[string]bool)
+	}
+	m.tags[documentULID][tag] = true
+	return nil
+}
+
+// RemoveTag takes a tag off a document, returning sql.ErrNoRows if it did not carry it
+func (m *MemoryDB) RemoveTag(documentULID string, tag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.tags[documentULID][tag] {
+		return sql.ErrNoRows
+	}
+	delete(m.tags[documentULID], tag)
+	if len(m.tags[documentULID]) == 0 {
+		delete(m.tags, documentULID)
+	}
+	return nil
+}
+
+// GetDocumentTags returns the tags on a document in name order
+func (m *MemoryDB) GetDocumentTags(documentULID string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tags := make([]string, 0, len(m.tags[documentULID]))
+	for tag := range m.tags[documentULID] {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// GetDocumentsByTag returns the documents carrying a tag in name order, without their text
+func (m *MemoryDB) GetDocumentsByTag(tag string) ([]Document, error) {
+	m.mu.RLock()
+	tagged := make(map[string]bool)
+	for documentULID, tags := range m.tags {
+		if tags[tag] {
+			tagged[documentULID] = true
+		}
+	}
+	m.mu.RUnlock()
+	carries := func(doc *Document) bool { return tagged[doc.ULID.String()] }
+	byName := func(a, b *Document) bool {
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.StormID < b.StormID
+	}
+	return m.listDocuments(carries, byName, false), nil
+}
+
+// ListTags returns every tag in name order with how many documents carry it
+func (m *MemoryDB) ListTags() ([]Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	counts := make(map[string]int)
+	for _, tags := range m.tags {
+		for tag := range tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, Tag{Name: name, DocumentCount: count})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// MergeTag moves every document carrying from onto into, so renaming a tag and merging it into another are
+// the same change. Smart folders filtering by from follow it, and event is added to the audit log. It
+// returns how many documents carried from, or sql.ErrNoRows when none did.
+func (m *MemoryDB) MergeTag(from string, into string, event *AuditEvent) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	moved := 0
+	for _, tags := range m.tags {
+		if tags[from] {
+			delete(tags, from)
+			tags[into] = true
+			moved++
+		}
+	}
+	if moved == 0 {
+		return 0, sql.ErrNoRows
+	}
+	for ulidStr, folder := range m.smartFolders {
+		if folder.Tag == from {
+			folder.Tag = into
+			m.smartFolders[ulidStr] = folder
+		}
+	}
+	prepareAuditEvent(event)
+	m.auditEvents = append(m.auditEvents, *event)
+	return moved, nil
+}
+
+// IndexUsage is not available: the memory database has no indexes
+func (m *MemoryDB) IndexUsage() ([]IndexUsage, error) {
+	return nil, ErrIndexUsageUnsupported
+}
+
+// RecordDocumentEvents stores processing stages
+func (m *MemoryDB) RecordDocumentEvents(events []DocumentEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, event := range events {
+		event.ID = len(m.events) + 1
+		m.events = append(m.events, event)
+	}
+	return nil
+}
+
+// GetDocumentEvents returns a document's processing stages, oldest first
+func (m *MemoryDB) GetDocumentEvents(ulid string) ([]DocumentEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var events []DocumentEvent
+	for _, event := range m.events {
+		if event.DocumentULID == ulid {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	return events, nil
+}
+
+// CreateSmartFolder stores a smart folder, setting its ULID and CreatedAt when missing
+func (m *MemoryDB) CreateSmartFolder(folder *SmartFolder) error {
+	prepareSmartFolder(folder)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.smartFolders[folder.ULID.String()] = *folder
+	return nil
+}
+
+// GetSmartFolder returns a smart folder by ULID, or sql.ErrNoRows
+func (m *MemoryDB) GetSmartFolder(ulidStr string) (*SmartFolder, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	folder, ok := m.smartFolders[ulidStr]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &folder, nil
+}
+
+// ListSmartFolders returns every smart folder by name
+func (m *MemoryDB) ListSmartFolders() ([]SmartFolder, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	folders := make([]SmartFolder, 0, len(m.smartFolders))
+	for _, folder := range m.smartFolders {
+		folders = append(folders, folder)
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		if folders[i].Name != folders[j].Name {
+			return folders[i].Name < folders[j].Name
+		}
+		return folders[i].ULID.Compare(folders[j].ULID) < 0
+	})
+	return folders, nil
+}
+
+// DeleteSmartFolder removes a smart folder; the documents it showed are not touched
+func (m *MemoryDB) DeleteSmartFolder(ulidStr string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.smartFolders[ulidStr]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.smartFolders, ulidStr)
+	return nil
+}
+
+// AcquireDocumentLock locks a document for holder until expiresAt. A holder's own lock is extended; a
+// lock held by anyone else is returned with ErrDocumentLocked until it expires.
+func (m *MemoryDB) AcquireDocumentLock(documentULID, holder string, expiresAt time.Time) (*DocumentLock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := Now().UTC()
+	lock, held := m.locks[documentULID]
+	if held && lock.ExpiresAt.After(now) {
+		if lock.Holder != holder {
+			return &lock, ErrDocumentLocked
+		}
+	} else {
+		lock = DocumentLock{DocumentULID: documentULID, Holder: holder, AcquiredAt: now}
+	}
+	lock.ExpiresAt = expiresAt.UTC()
+	m.locks[documentULID] = lock
+	return &lock, nil
+}
+
+// GetDocumentLock returns the unexpired lock on a document, or sql.ErrNoRows
+func (m *MemoryDB) GetDocumentLock(documentULID string) (*DocumentLock, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	lock, ok := m.locks[documentULID]
+	if !ok || !lock.ExpiresAt.After(Now()) {
+		return nil, sql.ErrNoRows
+	}
+	return &lock, nil
+}
+
+// ListDocumentLocks returns every unexpired lock, soonest to expire first
+func (m *MemoryDB) ListDocumentLocks() ([]DocumentLock, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := Now()
+	locks := make([]DocumentLock, 0, len(m.locks))
+	for _, lock := range m.locks {
+		if lock.ExpiresAt.After(now) {
+			locks = append(locks, lock)
+		}
+	}
+	sort.Slice(locks, func(i, j int) bool {
+		if !locks[i].ExpiresAt.Equal(locks[j].ExpiresAt) {
+			return locks[i].ExpiresAt.Before(locks[j].ExpiresAt)
+		}
+		return locks[i].DocumentULID < locks[j].DocumentULID
+	})
+	return locks, nil
+}
+
+// ReleaseDocumentLock removes holder's lock on a document, or returns sql.ErrNoRows when holder has none
+func (m *MemoryDB) ReleaseDocumentLock(documentULID, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lock, ok := m.locks[documentULID]; !ok || lock.Holder != holder {
+		return sql.ErrNoRows
+	}
+	delete(m.locks, documentULID)
+	return nil
+}
+
+// SaveDocumentArchive records that a document has been moved to cold storage, replacing any earlier record
+func (m *MemoryDB) SaveDocumentArchive(archive *DocumentArchive) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *archive
+	stored.ArchivedAt = stored.ArchivedAt.UTC()
+	m.archives[archive.DocumentULID] = stored
+	return nil
+}
+
+// GetDocumentArchive returns the cold storage record of a document, or sql.ErrNoRows when it is not archived
+func (m *MemoryDB) GetDocumentArchive(documentULID string) (*DocumentArchive, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	archive, ok := m.archives[documentULID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &archive, nil
+}
+
+// ListDocumentArchives returns every archived document, most recently archived first
+func (m *MemoryDB) ListDocumentArchives() ([]DocumentArchive, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	archives := make([]DocumentArchive, 0, len(m.archives))
+	for _, archive := range m.archives {
+		archives = append(archives, archive)
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		if !archives[i].ArchivedAt.Equal(archives[j].ArchivedAt) {
+			return archives[i].ArchivedAt.After(archives[j].ArchivedAt)
+		}
+		return archives[i].DocumentULID < archives[j].DocumentULID
+	})
+	return archives, nil
+}
+
+// DeleteDocumentArchive removes the cold storage record of a document, or returns sql.ErrNoRows when it has none
+func (m *MemoryDB) DeleteDocumentArchive(documentULID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.archives[documentULID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.archives, documentULID)
+	return nil
+}
+
+// PlaceLegalHold stores a hold and its audit entry, setting its ID and PlacedAt when missing. A document
+// or folder already on hold is left alone and ErrLegalHoldExists returned.
+func (m *MemoryDB) PlaceLegalHold(hold *LegalHold) error {
+	prepareLegalHold(hold)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.holds {
+		if existing.Scope == hold.Scope && existing.Target == hold.Target {
+			return ErrLegalHoldExists
+		}
+	}
+	m.holds[hold.ID] = *hold
+	m.holdEvents = append(m.holdEvents, *legalHoldEvent(hold, LegalHoldPlaced, hold.PlacedBy, hold.Reason, hold.PlacedAt))
+	return nil
+}
+
+// ListLegalHolds returns every hold in place, most recently placed first
+func (m *MemoryDB) ListLegalHolds() ([]LegalHold, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	holds := make([]LegalHold, 0, len(m.holds))
+	for _, hold := range m.holds {
+		holds = append(holds, hold)
+	}
+	sort.Slice(holds, func(i, j int) bool {
+		if !holds[i].PlacedAt.Equal(holds[j].PlacedAt) {
+			return holds[i].PlacedAt.After(holds[j].PlacedAt)
+		}
+		return holds[i].ID > holds[j].ID
+	})
+	return holds, nil
+}
+
+// RemoveLegalHold lifts a hold, recording who lifted it and why, and returns it; sql.ErrNoRows when there is no such hold
+func (m *MemoryDB) RemoveLegalHold(id, user, reason string) (*LegalHold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hold, ok := m.holds[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	delete(m.holds, id)
+	m.holdEvents = append(m.holdEvents, *legalHoldEvent(&hold, LegalHoldRemoved, user, reason, Now()))
+	return &hold, nil
+}
+
+// ListLegalHoldEvents returns the most recent limit entries of the legal hold audit trail, newest first
+func (m *MemoryDB) ListLegalHoldEvents(limit int) ([]LegalHoldEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	events := make([]LegalHoldEvent, 0, len(m.holdEvents))
+	for i := len(m.holdEvents) - 1; i >= 0 && len(events) < limit; i-- {
+		events = append(events, m.holdEvents[i])
+	}
+	return events, nil
+}
+
+// RecordAuditEvent adds an entry to the audit log, setting its ID and CreatedAt when missing
+func (m *MemoryDB) RecordAuditEvent(event *AuditEvent) error {
+	prepareAuditEvent(event)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.auditEvents = append(m.auditEvents, *event)
+	return nil
+}
+
+// ListAuditEvents returns up to limit audit log entries after the cursor, newest first. A nil cursor
+// starts from the newest entry.
+func (m *MemoryDB) ListAuditEvents(before *ActivityCursor, limit int) ([]AuditEvent, error) {
+	m.mu.RLock()
+	events := make([]AuditEvent, 0, len(m.auditEvents))
+	for _, event := range m.auditEvents {
+		if before.follows(event.CreatedAt, event.ID) {
+			events = append(events, event)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].CreatedAt.Equal(events[j].CreatedAt) {
+			return events[i].CreatedAt.After(events[j].CreatedAt)
+		}
+		return events[i].ID > events[j].ID
+	})
+	return page(events, 0, limit), nil
+}
+
+// ListFinishedJobs returns up to limit jobs that completed, failed or were cancelled after the cursor,
+// most recently finished first. A nil cursor starts from the most recent.
+func (m *MemoryDB) ListFinishedJobs(before *ActivityCursor, limit int) ([]Job, error) {
+	jobs := m.listJobs(func(job *Job) bool {
+		finished := job.Status == JobStatusCompleted || job.Status == JobStatusFailed || job.Status == JobStatusCancelled
+		return finished && job.CompletedAt != nil && before.follows(*job.CompletedAt, job.ID.String())
+	})
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CompletedAt.Equal(*jobs[j].CompletedAt) {
+			return jobs[i].CompletedAt.After(*jobs[j].CompletedAt)
+		}
+		return jobs[i].ID.String() > jobs[j].ID.String()
+	})
+	return page(jobs, 0, limit), nil
+}
+
+// RecordIngestRejection records that the file at rejection.Path was rejected. A path already recorded
+// keeps its ID and first rejection time and takes the new reason.
+func (m *MemoryDB) RecordIngestRejection(rejection *IngestRejection) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.rejections[rejection.Path]; ok {
+		rejection.ID, rejection.RejectedAt = existing.ID, existing.RejectedAt
+	}
+	prepareIngestRejection(rejection)
+	m.rejections[rejection.Path] = *rejection
+	return nil
+}
+
+// ListIngestRejections returns up to limit rejected files, most recently rejected first
+func (m *MemoryDB) ListIngestRejections(limit int) ([]IngestRejection, error) {
+	m.mu.RLock()
+	rejections := make([]IngestRejection, 0, len(m.rejections))
+	for _, rejection := range m.rejections {
+		rejections = append(rejections, rejection)
+	}
+	m.mu.RUnlock()
+	sort.Slice(rejections, func(i, j int) bool {
+		if !rejections[i].RejectedAt.Equal(rejections[j].RejectedAt) {
+			return rejections[i].RejectedAt.After(rejections[j].RejectedAt)
+		}
+		return rejections[i].ID > rejections[j].ID
+	})
+	return page(rejections, 0, limit), nil
+}
+
+// PruneIngestRejections removes the entries for every path not in keep, the files a walk of the ingress
+// folder rejected again
+func (m *MemoryDB) PruneIngestRejections(keep []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for rejected := range m.rejections {
+		if !slices.Contains(keep, rejected) {
+			delete(m.rejections, rejected)
+		}
+	}
+	return nil
+}
+
+// RecordClientError stores an error report from the web UI
+func (m *MemoryDB) RecordClientError(report *ClientError) error {
+	prepareClientError(report)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clientErrors = append(m.clientErrors, *report)
+	return nil
+}
+
+// ListClientErrors returns up to limit error reports, newest first
+func (m *MemoryDB) ListClientErrors(limit int) ([]ClientError, error) {
+	m.mu.RLock()
+	reports := newestClientErrors(m.clientErrors)
+	m.mu.RUnlock()
+	return page(reports, 0, limit), nil
+}
+
+// PruneClientErrors removes all but the newest keep error reports and returns how many went
+func (m *MemoryDB) PruneClientErrors(keep int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reports := newestClientErrors(m.clientErrors)
+	if len(reports) <= keep {
+		return 0, nil
+	}
+	m.clientErrors = reports[:keep]
+	return len(reports) - keep, nil
+}
+
+// newestClientErrors returns a copy of reports, newest first
+func newestClientErrors(reports []ClientError) []ClientError {
+	sorted := slices.Clone(reports)
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].ReportedAt.Equal(sorted[j].ReportedAt) {
+			return sorted[i].ReportedAt.After(sorted[j].ReportedAt)
+		}
+		return sorted[i].ID > sorted[j].ID
+	})
+	return sorted
+}
+
+// SaveSpreadsheetDetails records a spreadsheet document's size, replacing what was recorded before
+func (m *MemoryDB) SaveSpreadsheetDetails(details *SpreadsheetDetails) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spreadsheets[details.DocumentULID] = *details
+	return nil
+}
+
+// GetSpreadsheetDetails returns a spreadsheet document's size, or sql.ErrNoRows when none was recorded
+func (m *MemoryDB) GetSpreadsheetDetails(documentULID string) (*SpreadsheetDetails, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	details, ok := m.spreadsheets[documentULID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &details, nil
+}
+
+// SaveDocumentRotations records the pages of a document that were rotated, replacing what was recorded before
+func (m *MemoryDB) SaveDocumentRotations(documentULID string, rotations []PageRotation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(rotations) == 0 {
+		delete(m.rotations, documentULID)
+		return nil
+	}
+	saved := slices.Clone(rotations)
+	sort.Slice(saved, func(i, j int) bool { return saved[i].Page < saved[j].Page })
+	m.rotations[documentULID] = saved
+	return nil
+}
+
+// GetDocumentRotations returns the rotated pages of a document in page order, none when no page was turned
+func (m *MemoryDB) GetDocumentRotations(documentULID string) ([]PageRotation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]PageRotation{}, m.rotations[documentULID]...), nil
+}
+
+// SaveBlankPages records the blank pages found in a document, replacing what was recorded before
+func (m *MemoryDB) SaveBlankPages(documentULID string, pages []BlankPage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(pages) == 0 {
+		delete(m.blankPages, documentULID)
+		return nil
+	}
+	saved := slices.Clone(pages)
+	sort.Slice(saved, func(i, j int) bool { return saved[i].Page < saved[j].Page })
+	m.blankPages[documentULID] = saved
+	return nil
+}
+
+// GetBlankPages returns the blank pages found in a document in page order, none when there were none
+func (m *MemoryDB) GetBlankPages(documentULID string) ([]BlankPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]BlankPage{}, m.blankPages[documentULID]...), nil
+}
+
+// SaveDocumentOptimisation records that a document's file was recompressed, replacing any earlier record
+func (m *MemoryDB) SaveDocumentOptimisation(optimisation *DocumentOptimisation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *optimisation
+	stored.OptimisedAt = stored.OptimisedAt.UTC()
+	m.optimised[optimisation.DocumentULID] = stored
+	return nil
+}
+
+// GetDocumentOptimisation returns how a document was recompressed, or sql.ErrNoRows when it was not
+func (m *MemoryDB) GetDocumentOptimisation(documentULID string) (*DocumentOptimisation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	optimisation, ok := m.optimised[documentULID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &optimisation, nil
+}
+
+// GetDocumentOptimisationByHash returns the recompression of the document whose original had the hash,
+// or sql.ErrNoRows when there is none
+func (m *MemoryDB) GetDocumentOptimisationByHash(originalHash string) (*DocumentOptimisation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, optimisation := range m.optimised {
+		if optimisation.OriginalHash == originalHash {
+			return &optimisation, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// SaveDocumentFingerprint records a document's similarity hashes, replacing any earlier ones
+func (m *MemoryDB) SaveDocumentFingerprint(fingerprint *DocumentFingerprint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fingerprints[fingerprint.DocumentULID] = *fingerprint
+	return nil
+}
+
+// ListDocumentFingerprints returns the similarity hashes of every fingerprinted document
+func (m *MemoryDB) ListDocumentFingerprints() ([]DocumentFingerprint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	fingerprints := make([]DocumentFingerprint, 0, len(m.fingerprints))
+	for _, fingerprint := range m.fingerprints {
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	sort.Slice(fingerprints, func(i, j int) bool { return fingerprints[i].DocumentULID < fingerprints[j].DocumentULID })
+	return fingerprints, nil
+}
+
+// CreateExtractionTemplate stores an extraction template, setting its ULID and CreatedAt when missing
+func (m *MemoryDB) CreateExtractionTemplate(template *ExtractionTemplate) error {
+	prepareExtractionTemplate(template)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *template
+	stored.Fields = slices.Clone(template.Fields)
+	m.templates[template.ULID.String()] = stored
+	return nil
+}
+
+// GetExtractionTemplate returns an extraction template by ULID, or sql.ErrNoRows
+func (m *MemoryDB) GetExtractionTemplate(ulidStr string) (*ExtractionTemplate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	template, ok := m.templates[ulidStr]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	template.Fields = slices.Clone(template.Fields)
+	return &template, nil
+}
+
+// ListExtractionTemplates returns every extraction template by correspondent, oldest first for each
+func (m *MemoryDB) ListExtractionTemplates() ([]ExtractionTemplate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	templates := make([]ExtractionTemplate, 0, len(m.templates))
+	for _, template := range m.templates {
+		template.Fields = slices.Clone(template.Fields)
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Correspondent != templates[j].Correspondent {
+			return templates[i].Correspondent < templates[j].Correspondent
+		}
+		return templates[i].ULID.Compare(templates[j].ULID) < 0
+	})
+	return templates, nil
+}
+
+// DeleteExtractionTemplate removes an extraction template; the fields it read are kept
+func (m *MemoryDB) DeleteExtractionTemplate(ulidStr string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.templates[ulidStr]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.templates, ulidStr)
+	return nil
+}
+
+// SaveDocumentFields replaces the fields read from a document with fields
+func (m *MemoryDB) SaveDocumentFields(documentULID string, fields []DocumentField) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(fields) == 0 {
+		delete(m.fields, documentULID)
+		return nil
+	}
+	stored := make([]DocumentField, 0, len(fields))
+	for _, field := range fields {
+		field.DocumentULID = documentULID
+		stored = append(stored, field)
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].Name < stored[j].Name })
+	m.fields[documentULID] = stored
+	return nil
+}
+
+// GetDocumentFields returns the fields read from a document by name
+func (m *MemoryDB) GetDocumentFields(documentULID string) ([]DocumentField, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.fields[documentULID]), nil
+}
+
+// ListDocumentFields returns the fields read from every document, by document and then name
+func (m *MemoryDB) ListDocumentFields() ([]DocumentField, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	documentULIDs := make([]string, 0, len(m.fields))
+	for documentULID := range m.fields {
+		documentULIDs = append(documentULIDs, documentULID)
+	}
+	sort.Strings(documentULIDs)
+	var fields []DocumentField
+	for _, documentULID := range documentULIDs {
+		fields = append(fields, m.fields[documentULID]...)
+	}
+	return fields, nil
+}
+
+// CreateUser adds an account, or returns ErrUsernameTaken
+func (m *MemoryDB) CreateUser(user *User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.users {
+		if existing.Username == user.Username {
+			return ErrUsernameTaken
+		}
+	}
+	prepareUser(user)
+	m.users[user.ID] = *user
+	return nil
+}
+
+// GetUser returns an account by ID, or sql.ErrNoRows
+func (m *MemoryDB) GetUser(id string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, ok := m.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &user, nil
+}
+
+// GetUserByUsername returns an account by username, or sql.ErrNoRows
+func (m *MemoryDB) GetUserByUsername(username string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, user := range m.users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// ListUsers returns every account in username order
+func (m *MemoryDB) ListUsers() ([]User, error) {
+	m.mu.RLock()
+	users := make([]User, 0, len(m.users))
+	for _, user := range m.users {
+		users = append(users, user)
+	}
+	m.mu.RUnlock()
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+// UpdateUserPassword replaces an account's password hash, returning sql.ErrNoRows for an unknown ID
+func (m *MemoryDB) UpdateUserPassword(id string, passwordHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.PasswordHash = passwordHash
+	m.users[id] = user
+	return nil
+}
+
+// DeleteUser removes an account and its sessions, returning sql.ErrNoRows for an unknown ID
+func (m *MemoryDB) DeleteUser(id string) error {
+	if err := m.DeleteUserSessions(id); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.users, id)
+	for key := range m.permissions {
+		if key[1] == id {
+			delete(m.permissions, key)
+		}
+	}
+	return nil
+}
+
+// UpdateUserRole changes an account's role, returning sql.ErrNoRows for an unknown ID
+func (m *MemoryDB) UpdateUserRole(id string, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.Role = role
+	m.users[id] = user
+	return nil
+}
+
+// SetFolderPermission gives an account access to a folder, replacing any it already had there
+func (m *MemoryDB) SetFolderPermission(permission *FolderPermission) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := [2]string{permission.Folder, permission.UserID}
+	if existing, ok := m.permissions[key]; ok {
+		permission.CreatedAt = existing.CreatedAt
+	} else if permission.CreatedAt.IsZero() {
+		permission.CreatedAt = Now()
+	}
+	m.permissions[key] = *permission
+	return nil
+}
+
+// ListFolderPermissions returns every folder permission ordered by folder
+func (m *MemoryDB) ListFolderPermissions() ([]FolderPermission, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	permissions := make([]FolderPermission, 0, len(m.permissions))
+	for _, permission := range m.permissions {
+		permissions = append(permissions, permission)
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		if permissions[i].Folder != permissions[j].Folder {
+			return permissions[i].Folder < permissions[j].Folder
+		}
+		return permissions[i].UserID < permissions[j].UserID
+	})
+	return permissions, nil
+}
+
+// DeleteFolderPermission removes an account's access to a folder, returning sql.ErrNoRows if it had none
+func (m *MemoryDB) DeleteFolderPermission(folder string, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := [2]string{folder, userID}
+	if _, ok := m.permissions[key]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.permissions, key)
+	return nil
+}
+
+// CreateSession records a new session
+func (m *MemoryDB) CreateSession(session *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *session
+	stored.CreatedAt, stored.ExpiresAt = stored.CreatedAt.UTC(), stored.ExpiresAt.UTC()
+	m.sessions[session.TokenHash] = stored
+	return nil
+}
+
+// GetSession returns the session with the token hash, expired or not, or sql.ErrNoRows
+func (m *MemoryDB) GetSession(tokenHash string) (*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	session, ok := m.sessions[tokenHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &session, nil
+}
+
+// DeleteSession ends a session; ending one that does not exist is not an error
+func (m *MemoryDB) DeleteSession(tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, tokenHash)
+	return nil
+}
+
+// DeleteUserSessions ends every session of an account
+func (m *MemoryDB) DeleteUserSessions(userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for tokenHash, session := range m.sessions {
+		if session.UserID == userID {
+			delete(m.sessions, tokenHash)
+		}
+	}
+	return nil
+}
+
+// DeleteExpiredSessions removes the sessions that expired before now
+func (m *MemoryDB) DeleteExpiredSessions(now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for tokenHash, session := range m.sessions {
+		if session.ExpiresAt.Before(now) {
+			delete(m.sessions, tokenHash)
+		}
+	}
+	return nil
+}
+
+// SaveDocumentRedaction records that a document is a redacted copy of another
+func (m *MemoryDB) SaveDocumentRedaction(redaction *DocumentRedaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *redaction
+	stored.Regions = append([]RedactionRegion(nil), redaction.Regions...)
+	stored.CreatedAt = stored.CreatedAt.UTC()
+	m.redactions[redaction.DocumentULID] = stored
+	return nil
+}
+
+// GetDocumentRedaction returns the record of a redacted copy, or sql.ErrNoRows when the document is not one
+func (m *MemoryDB) GetDocumentRedaction(documentULID string) (*DocumentRedaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	redaction, ok := m.redactions[documentULID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &redaction, nil
+}
+
+// ListDocumentRedactions returns the redacted copies made from a document, newest first
+func (m *MemoryDB) ListDocumentRedactions(sourceULID string) ([]DocumentRedaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var redactions []DocumentRedaction
+	for _, redaction := range m.redactions {
+		if redaction.SourceULID == sourceULID {
+			redactions = append(redactions, redaction)
+		}
+	}
+	sort.Slice(redactions, func(i, j int) bool {
+		if !redactions[i].CreatedAt.Equal(redactions[j].CreatedAt) {
+			return redactions[i].CreatedAt.After(redactions[j].CreatedAt)
+		}
+		return redactions[i].DocumentULID > redactions[j].DocumentULID
+	})
+	return redactions, nil
+}
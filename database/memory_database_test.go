@@ -0,0 +1,120 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/oklog/ulid/v2"
+)
+
+var _ Repository = (*MemoryDB)(nil)
+
+func TestMemoryDB(t *testing.T) {
+	if Logger == nil {
+		Logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	}
+	db := NewRepository(config.ServerConfig{DatabaseType: "memory"})
+	if _, ok := db.(*MemoryDB); !ok {
+		t.Fatalf("Expected DatabaseType memory to give a MemoryDB, got %T", db)
+	}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newDoc := func(path string, folder string, offset time.Duration, text string) *Document {
+		doc := &Document{Name: path[len(folder)+1:], Path: path, Folder: folder, IngressTime: base.Add(offset),
+			Hash: "hash-" + path, ULID: ulid.Make(), DocumentType: ".pdf", FullText: text}
+		if err := db.SaveDocument(doc); err != nil {
+			t.Fatalf("Failed to save document: %v", err)
+		}
+		return doc
+	}
+
+	t.Run("Upsert and lookups", func(t *testing.T) {
+		doc := newDoc("/docs/a/one.pdf", "/docs/a", 0, "Quarterly Invoice")
+		again := *doc
+		again.FullText = "updated"
+		if err := db.SaveDocument(&again); err != nil || again.StormID != doc.StormID {
+			t.Fatalf("Expected upsert to keep ID %d, got %d (%v)", doc.StormID, again.StormID, err)
+		}
+		if text, err := db.GetDocumentText(doc.ULID.String()); err != nil || text != "updated" {
+			t.Errorf("Expected updated text, got %q (%v)", text, err)
+		}
+		if _, err := db.GetDocumentByPath("/docs/missing.pdf"); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for a missing path, got %v", err)
+		}
+		if found, err := db.GetDocumentByHash("no-such-hash"); found != nil || err != nil {
+			t.Errorf("Expected nil, nil for a missing hash, got %v, %v", found, err)
+		}
+	})
+
+	t.Run("Listing, search and cursor pages", func(t *testing.T) {
+		newDoc("/docs/a/b/two.pdf", "/docs/a/b", time.Hour, "electricity bill")
+		newDoc("/docs/ab/three.pdf", "/docs/ab", 2*time.Hour, "invoice copy")
+
+		under, _ := db.GetDocumentsUnderFolder("/docs/a")
+		if len(under) != 2 || under[0].Name != "one.pdf" || under[1].Name != "two.pdf" || under[0].FullText != "" {
+			t.Errorf("Unexpected documents under /docs/a: %+v", under)
+		}
+		results, _ := db.SearchDocuments("INVOICE")
+		if len(results) != 1 || results[0].Name != "three.pdf" {
+			t.Errorf("Expected only three.pdf to match, got %+v", results)
+		}
+		first, _ := db.GetNewestDocumentsAfter(nil, 2)
+		rest, _ := db.GetNewestDocumentsAfter(CursorAfter(first[1]), 2)
+		if len(first) != 2 || first[0].Name != "three.pdf" || len(rest) != 1 || rest[0].Name != "one.pdf" {
+			t.Errorf("Unexpected cursor pages %+v then %+v", first, rest)
+		}
+		if _, total, _ := db.GetNewestDocumentsWithPagination(2, 2); total != 3 {
+			t.Errorf("Expected total 3, got %d", total)
+		}
+	})
+
+	t.Run("Folders", func(t *testing.T) {
+		for _, f := range [][2]string{{"/docs", ""}, {"/docs/a", "/docs"}, {"/docs/a/b", "/docs/a"}, {"/docs/ab", "/docs"}} {
+			if _, err := db.EnsureFolder(f[0], f[1]); err != nil {
+				t.Fatalf("Failed to ensure %s: %v", f[0], err)
+			}
+		}
+		if _, err := db.EnsureFolder("/elsewhere/x", "/elsewhere"); err == nil {
+			t.Error("Expected an error for a missing parent")
+		}
+		if removed, _ := db.DeleteFolderTree("/docs/a"); removed != 2 {
+			t.Errorf("Expected /docs/a and /docs/a/b removed, got %d", removed)
+		}
+		if folders, _ := db.GetAllFolders(); len(folders) != 2 || folders[1].Path != "/docs/ab" {
+			t.Errorf("Unexpected remaining folders %+v", folders)
+		}
+	})
+
+	t.Run("Word cloud", func(t *testing.T) {
+		// Names are counted too, so the shared extension comes out on top
+		if err := db.RecalculateAllWordFrequencies(); err != nil {
+			t.Fatalf("Recalculation failed: %v", err)
+		}
+		words, _ := db.GetTopWords(1)
+		meta, _ := db.GetWordCloudMetadata()
+		if len(words) != 1 || words[0].Word != "pdf" || words[0].Frequency != 3 || meta.TotalDocsProcessed != 3 || meta.Version != 1 {
+			t.Errorf("Unexpected word cloud %+v, %+v", words, meta)
+		}
+	})
+
+	t.Run("Jobs", func(t *testing.T) {
+		job, _ := db.CreateJob(JobTypeIngestion, "queued")
+		db.UpdateJobStatus(job.ID, JobStatusRunning, "working")
+		if active, _ := db.GetActiveJobs(); len(active) != 1 || active[0].StartedAt == nil {
+			t.Errorf("Expected one running job with a start time, got %+v", active)
+		}
+		db.CompleteJob(job.ID, `{"ok": true}`)
+		if done, _ := db.GetJob(job.ID); done.Status != JobStatusCompleted || done.Progress != 100 {
+			t.Errorf("Unexpected completed job %+v", done)
+		}
+		if deleted, _ := db.DeleteOldJobs(-time.Minute); deleted != 1 {
+			t.Errorf("Expected the completed job deleted, got %d", deleted)
+		}
+		if _, err := db.GetJob(job.ID); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows after delete, got %v", err)
+		}
+	})
+}
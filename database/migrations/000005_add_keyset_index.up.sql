@@ -0,0 +1,2 @@
+-- Composite index backing keyset (cursor) pagination of the latest documents
+CREATE INDEX IF NOT EXISTS idx_documents_ingress_time_id ON documents(ingress_time DESC, id DESC);
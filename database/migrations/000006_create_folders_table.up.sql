@@ -0,0 +1,12 @@
+-- Materialised folder tree; folders are keyed by absolute path and linked to their parent
+CREATE TABLE IF NOT EXISTS folders (
+    id SERIAL PRIMARY KEY,
+    path TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    parent_id INTEGER REFERENCES folders(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_folders_parent_id ON folders(parent_id);
+
+COMMENT ON TABLE folders IS 'Folder tree under the document root, maintained on create, move, delete and ingestion';
@@ -0,0 +1,2 @@
+-- Drop folder prefix index
+DROP INDEX IF EXISTS idx_documents_folder_pattern;
@@ -0,0 +1,2 @@
+-- Pattern index so recursive folder listings (folder LIKE 'prefix/%') use an index scan whatever the collation
+CREATE INDEX IF NOT EXISTS idx_documents_folder_pattern ON documents(folder text_pattern_ops);
@@ -0,0 +1,4 @@
+-- Drop document access statistics
+DROP TABLE IF EXISTS document_access_daily;
+ALTER TABLE documents DROP COLUMN IF EXISTS last_accessed;
+ALTER TABLE documents DROP COLUMN IF EXISTS access_count;
@@ -0,0 +1,15 @@
+-- All-time open counter on each document, bumped atomically whenever its file is served
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS access_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS last_accessed TIMESTAMP;
+
+-- Daily rollup of opens so popularity can be asked for over a recent window
+CREATE TABLE IF NOT EXISTS document_access_daily (
+    document_ulid TEXT NOT NULL,
+    day DATE NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (document_ulid, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_access_daily_day ON document_access_daily(day);
+
+COMMENT ON TABLE document_access_daily IS 'Per-document view and download counts by day, for the popular documents listing';
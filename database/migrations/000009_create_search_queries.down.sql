@@ -0,0 +1,2 @@
+-- Drop search history
+DROP TABLE IF EXISTS search_queries;
@@ -0,0 +1,14 @@
+-- Search history: one row per search, for the user's recent searches and zero-result analytics
+CREATE TABLE IF NOT EXISTS search_queries (
+    id SERIAL PRIMARY KEY,
+    term TEXT NOT NULL,
+    result_count INTEGER NOT NULL DEFAULT 0,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    user_name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_queries_user_created ON search_queries(user_name, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_search_queries_created_at ON search_queries(created_at);
+
+COMMENT ON TABLE search_queries IS 'Search terms with result counts and timings, to guide stop-word tuning and classification rules';
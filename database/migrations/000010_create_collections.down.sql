@@ -0,0 +1,3 @@
+-- Drop collections
+DROP TABLE IF EXISTS collection_documents;
+DROP TABLE IF EXISTS collections;
@@ -0,0 +1,20 @@
+-- Collections: named, immutable snapshots of a set of documents, optionally shared by token
+CREATE TABLE IF NOT EXISTS collections (
+    id SERIAL PRIMARY KEY,
+    ulid TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    term TEXT NOT NULL DEFAULT '',
+    share_token TEXT UNIQUE,
+    document_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The documents of each collection in the order they were snapshotted
+CREATE TABLE IF NOT EXISTS collection_documents (
+    collection_ulid TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    document_ulid TEXT NOT NULL,
+    PRIMARY KEY (collection_ulid, position)
+);
+
+COMMENT ON TABLE collections IS 'Saved document sets, such as a search result, handed on as one link';
@@ -0,0 +1,2 @@
+-- Drop the detected document content type
+ALTER TABLE documents DROP COLUMN IF EXISTS mime_type;
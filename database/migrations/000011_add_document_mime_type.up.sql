@@ -0,0 +1,2 @@
+-- Content type detected from each document's bytes at ingestion, served as Content-Type
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS mime_type TEXT NOT NULL DEFAULT '';
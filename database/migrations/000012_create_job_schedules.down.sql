@@ -0,0 +1,2 @@
+-- Drop saved job schedules
+DROP TABLE IF EXISTS job_schedules;
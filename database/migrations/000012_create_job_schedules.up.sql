@@ -0,0 +1,8 @@
+-- Cron schedules saved through the schedules API, overriding the SCHEDULE_* settings
+CREATE TABLE IF NOT EXISTS job_schedules (
+    name TEXT PRIMARY KEY,
+    spec TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE job_schedules IS 'Cron expression per scheduled job type, and the quiet hours window';
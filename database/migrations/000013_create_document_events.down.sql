@@ -0,0 +1,2 @@
+-- Drop the processing timeline
+DROP TABLE IF EXISTS document_events;
@@ -0,0 +1,12 @@
+-- Processing timeline: when each document reached each ingestion stage
+CREATE TABLE IF NOT EXISTS document_events (
+    id SERIAL PRIMARY KEY,
+    document_ulid TEXT NOT NULL,
+    stage TEXT NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_events_document ON document_events(document_ulid, at);
+
+COMMENT ON TABLE document_events IS 'Processing stages per document (received, text extracted, OCR, indexed, word cloud updated) for diagnosing slow documents';
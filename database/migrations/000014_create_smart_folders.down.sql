@@ -0,0 +1,2 @@
+-- Drop smart folders
+DROP TABLE IF EXISTS smart_folders;
@@ -0,0 +1,12 @@
+-- Smart folders: virtual folders whose documents are found by a saved query when they are listed
+CREATE TABLE IF NOT EXISTS smart_folders (
+    id SERIAL PRIMARY KEY,
+    ulid TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    term TEXT NOT NULL DEFAULT '',
+    date_from TEXT NOT NULL DEFAULT '',
+    date_to TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE smart_folders IS 'Saved queries shown as folders in the document tree, such as unpaid invoices';
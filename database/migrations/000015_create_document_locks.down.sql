@@ -0,0 +1,2 @@
+-- Drop document locks
+DROP TABLE IF EXISTS document_locks;
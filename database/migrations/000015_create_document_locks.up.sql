@@ -0,0 +1,9 @@
+-- Document locks: a check-out of a document by one holder until it expires
+CREATE TABLE IF NOT EXISTS document_locks (
+    document_ulid TEXT PRIMARY KEY,
+    holder TEXT NOT NULL,
+    acquired_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+COMMENT ON TABLE document_locks IS 'Who has each document checked out for editing, and until when';
@@ -0,0 +1,3 @@
+-- Drop the folder colour label and icon
+ALTER TABLE folders DROP COLUMN IF EXISTS icon;
+ALTER TABLE folders DROP COLUMN IF EXISTS color;
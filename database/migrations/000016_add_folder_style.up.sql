@@ -0,0 +1,3 @@
+-- Colour label and icon chosen for each folder, shown in the browse tree
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS color TEXT NOT NULL DEFAULT '';
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS icon TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Drop the document size and page count
+ALTER TABLE documents DROP COLUMN IF EXISTS page_count;
+ALTER TABLE documents DROP COLUMN IF EXISTS size;
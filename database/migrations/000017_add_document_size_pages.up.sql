@@ -0,0 +1,3 @@
+-- File size and PDF page count recorded at ingestion, so listings need not stat each file
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS page_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Drop document archives
+DROP TABLE IF EXISTS document_archives;
@@ -0,0 +1,9 @@
+-- Cold storage: documents whose file has been compressed and moved out of the document folder
+CREATE TABLE IF NOT EXISTS document_archives (
+    document_ulid TEXT PRIMARY KEY,
+    archive_path TEXT NOT NULL,
+    archived_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    compressed_size BIGINT NOT NULL DEFAULT 0
+);
+
+COMMENT ON TABLE document_archives IS 'Where the compressed file of each archived document is kept, and when it was archived';
@@ -0,0 +1,3 @@
+-- Drop legal holds and their audit trail
+DROP TABLE IF EXISTS legal_hold_events;
+DROP TABLE IF EXISTS legal_holds;
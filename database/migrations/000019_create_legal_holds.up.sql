@@ -0,0 +1,27 @@
+-- Legal holds: documents and folders that may not be deleted until the hold is lifted
+CREATE TABLE IF NOT EXISTS legal_holds (
+    id TEXT PRIMARY KEY,
+    scope TEXT NOT NULL,
+    target TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    placed_by TEXT NOT NULL DEFAULT '',
+    placed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (scope, target)
+);
+
+-- Every placement and removal of a hold, kept after the hold is lifted
+CREATE TABLE IF NOT EXISTS legal_hold_events (
+    id TEXT PRIMARY KEY,
+    hold_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    target TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    user_name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_legal_hold_events_created_at ON legal_hold_events(created_at);
+
+COMMENT ON TABLE legal_holds IS 'Documents (by ULID) and folders (by key) that delete, cleanup and retention must leave alone';
+COMMENT ON TABLE legal_hold_events IS 'Audit trail of legal holds being placed and removed';
@@ -0,0 +1,2 @@
+-- Drop redacted copy links
+DROP TABLE IF EXISTS document_redactions;
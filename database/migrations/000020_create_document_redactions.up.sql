@@ -0,0 +1,12 @@
+-- Redacted copies: documents made from another with regions blacked out
+CREATE TABLE IF NOT EXISTS document_redactions (
+    document_ulid TEXT PRIMARY KEY,
+    source_ulid TEXT NOT NULL,
+    regions TEXT NOT NULL DEFAULT '[]',
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_redactions_source ON document_redactions(source_ulid);
+
+COMMENT ON TABLE document_redactions IS 'Which document each redacted copy was made from, and the regions (JSON) removed from it';
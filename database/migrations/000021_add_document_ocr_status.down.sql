@@ -0,0 +1,2 @@
+-- Drop the document OCR status
+ALTER TABLE documents DROP COLUMN IF EXISTS ocr_status;
@@ -0,0 +1,3 @@
+-- How far text recognition has got for each document: pending, done, failed or skipped.
+-- Documents stored before it was recorded are left empty.
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS ocr_status TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Drop the audit log
+DROP INDEX IF EXISTS idx_jobs_completed_at;
+DROP TABLE IF EXISTS audit_events;
@@ -0,0 +1,15 @@
+-- Audit log of documents added, moved and deleted and settings changed, read with finished jobs by the activity feed
+CREATE TABLE IF NOT EXISTS audit_events (
+    id TEXT PRIMARY KEY,
+    action TEXT NOT NULL,
+    target TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT '',
+    user_name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_completed_at ON jobs(completed_at);
+
+COMMENT ON TABLE audit_events IS 'Changes to documents and settings, shown in the activity feed';
@@ -0,0 +1,2 @@
+-- Drop the ingest rejections
+DROP TABLE IF EXISTS ingest_rejections;
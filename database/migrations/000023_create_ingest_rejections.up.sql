@@ -0,0 +1,12 @@
+-- Files left in the ingress folder because they cannot be ingested, one per path, so the UI can say why
+CREATE TABLE IF NOT EXISTS ingest_rejections (
+    id TEXT PRIMARY KEY,
+    path TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    rejected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingest_rejections_rejected_at ON ingest_rejections(rejected_at);
+
+COMMENT ON TABLE ingest_rejections IS 'Unsupported files left in ingress, with the reason they were not ingested';
@@ -0,0 +1,2 @@
+-- Drop the spreadsheet details
+DROP TABLE IF EXISTS document_spreadsheets;
@@ -0,0 +1,9 @@
+-- Sheet, row and column counts of spreadsheet documents, recorded at ingestion
+CREATE TABLE IF NOT EXISTS document_spreadsheets (
+    document_ulid TEXT PRIMARY KEY,
+    sheets INTEGER NOT NULL DEFAULT 0,
+    row_count INTEGER NOT NULL DEFAULT 0,
+    column_count INTEGER NOT NULL DEFAULT 0
+);
+
+COMMENT ON TABLE document_spreadsheets IS 'Size of each spreadsheet document: sheets, non-empty rows and the widest row';
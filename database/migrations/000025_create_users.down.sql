@@ -0,0 +1,3 @@
+-- Drop the accounts and their sessions
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
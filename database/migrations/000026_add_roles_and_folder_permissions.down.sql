@@ -0,0 +1,3 @@
+-- Drop folder permissions and account roles
+DROP TABLE IF EXISTS folder_permissions;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
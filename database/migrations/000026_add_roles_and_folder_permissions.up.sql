@@ -0,0 +1,16 @@
+-- Account roles; accounts from before roles existed could do everything, so they become administrators
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'editor';
+UPDATE users SET role = 'admin';
+
+-- Per-folder access for accounts; a folder with any rows is only visible to the accounts named
+CREATE TABLE IF NOT EXISTS folder_permissions (
+    folder TEXT NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    access TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (folder, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_folder_permissions_user_id ON folder_permissions(user_id);
+
+COMMENT ON TABLE folder_permissions IS 'Read or write access to a folder, keyed relative to the document root, for one account';
@@ -0,0 +1,3 @@
+-- Drop the document filter indexes
+DROP INDEX IF EXISTS idx_documents_document_type;
+DROP INDEX IF EXISTS idx_documents_folder_ingress_time;
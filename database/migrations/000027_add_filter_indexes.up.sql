@@ -0,0 +1,3 @@
+-- Composite indexes for the common document filters: a folder newest first, and a document type
+CREATE INDEX IF NOT EXISTS idx_documents_folder_ingress_time ON documents(folder, ingress_time DESC);
+CREATE INDEX IF NOT EXISTS idx_documents_document_type ON documents(document_type);
@@ -0,0 +1,3 @@
+-- Drop the tags and their documents
+DROP TABLE IF EXISTS document_tags;
+DROP TABLE IF EXISTS tags;
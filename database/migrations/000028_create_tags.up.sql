@@ -0,0 +1,17 @@
+-- Tags, and the documents that carry each of them
+CREATE TABLE IF NOT EXISTS tags (
+    name TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS document_tags (
+    document_ulid TEXT NOT NULL,
+    tag TEXT NOT NULL REFERENCES tags(name) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (document_ulid, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_tags_tag ON document_tags(tag);
+
+COMMENT ON TABLE tags IS 'Tag names, lower case; a tag is removed with the last document carrying it';
+COMMENT ON TABLE document_tags IS 'Which documents carry which tags';
@@ -0,0 +1,2 @@
+-- Drop the client error reports
+DROP TABLE IF EXISTS client_errors;
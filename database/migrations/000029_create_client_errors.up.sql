@@ -0,0 +1,15 @@
+-- Errors the web UI reported from users' browsers
+CREATE TABLE IF NOT EXISTS client_errors (
+    id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL DEFAULT '',
+    message TEXT NOT NULL DEFAULT '',
+    stack TEXT NOT NULL DEFAULT '',
+    page TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    username TEXT NOT NULL DEFAULT '',
+    reported_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_client_errors_reported_at ON client_errors(reported_at);
+
+COMMENT ON TABLE client_errors IS 'Panics, uncaught errors and failed API calls reported by the web UI';
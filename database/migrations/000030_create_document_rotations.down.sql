@@ -0,0 +1,2 @@
+-- Drop the page rotations
+DROP TABLE IF EXISTS document_rotations;
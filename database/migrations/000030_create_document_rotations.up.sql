@@ -0,0 +1,9 @@
+-- Pages of scanned documents turned upright at ingestion
+CREATE TABLE IF NOT EXISTS document_rotations (
+    document_ulid TEXT NOT NULL,
+    page INTEGER NOT NULL,
+    degrees INTEGER NOT NULL,
+    PRIMARY KEY (document_ulid, page)
+);
+
+COMMENT ON TABLE document_rotations IS 'Clockwise rotation applied to each page of a scan that tesseract found sideways or upside down';
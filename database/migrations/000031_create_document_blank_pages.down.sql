@@ -0,0 +1,2 @@
+-- Drop the blank page records
+DROP TABLE IF EXISTS document_blank_pages;
@@ -0,0 +1,10 @@
+-- Blank pages found in scanned documents at ingestion
+CREATE TABLE IF NOT EXISTS document_blank_pages (
+    document_ulid TEXT NOT NULL,
+    page INTEGER NOT NULL,
+    ink DOUBLE PRECISION NOT NULL DEFAULT 0,
+    removed BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (document_ulid, page)
+);
+
+COMMENT ON TABLE document_blank_pages IS 'Pages of a scan with too little ink to hold content, numbered as scanned, and whether each was dropped from the stored file';
@@ -0,0 +1,2 @@
+-- Drop document optimisations
+DROP TABLE IF EXISTS document_optimisations;
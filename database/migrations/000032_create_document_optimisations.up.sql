@@ -0,0 +1,13 @@
+-- Scanned documents recompressed to save storage, with the hash of the original they replaced
+CREATE TABLE IF NOT EXISTS document_optimisations (
+    document_ulid TEXT PRIMARY KEY,
+    original_hash TEXT NOT NULL,
+    original_size BIGINT NOT NULL DEFAULT 0,
+    optimised_size BIGINT NOT NULL DEFAULT 0,
+    original_path TEXT NOT NULL DEFAULT '',
+    optimised_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_optimisations_original_hash ON document_optimisations (original_hash);
+
+COMMENT ON TABLE document_optimisations IS 'Documents whose file was recompressed, with the original hash kept for duplicate detection';
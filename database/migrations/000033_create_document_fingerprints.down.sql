@@ -0,0 +1,2 @@
+-- Drop document fingerprints
+DROP TABLE IF EXISTS document_fingerprints;
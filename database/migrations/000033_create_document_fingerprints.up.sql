@@ -0,0 +1,8 @@
+-- Similarity hashes of documents, compared to find near-duplicates for review
+CREATE TABLE IF NOT EXISTS document_fingerprints (
+    document_ulid TEXT PRIMARY KEY,
+    image_hash TEXT NOT NULL DEFAULT '',
+    text_hash TEXT NOT NULL DEFAULT ''
+);
+
+COMMENT ON TABLE document_fingerprints IS 'Perceptual hash of the first page and simhash of the text of each document, for finding near-duplicates';
@@ -0,0 +1,3 @@
+-- Drop extraction templates and the fields they read
+DROP TABLE IF EXISTS document_fields;
+DROP TABLE IF EXISTS extraction_templates;
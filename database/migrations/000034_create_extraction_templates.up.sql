@@ -0,0 +1,22 @@
+-- Extraction templates: how to read structured fields from the documents of a correspondent
+CREATE TABLE IF NOT EXISTS extraction_templates (
+    id SERIAL PRIMARY KEY,
+    ulid TEXT NOT NULL UNIQUE,
+    correspondent TEXT NOT NULL,
+    match_pattern TEXT NOT NULL,
+    fields TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE extraction_templates IS 'Regular expressions recognising a correspondent''s documents, and the fields (JSON) to read from them';
+
+-- Fields read from documents by extraction templates, such as invoice numbers and totals
+CREATE TABLE IF NOT EXISTS document_fields (
+    document_ulid TEXT NOT NULL,
+    name TEXT NOT NULL,
+    value TEXT NOT NULL DEFAULT '',
+    template_ulid TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (document_ulid, name)
+);
+
+COMMENT ON TABLE document_fields IS 'Values read from each document by the extraction template for its correspondent';
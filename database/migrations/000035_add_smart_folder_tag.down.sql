@@ -0,0 +1,2 @@
+-- Drop the smart folder tag filter
+ALTER TABLE smart_folders DROP COLUMN IF EXISTS tag;
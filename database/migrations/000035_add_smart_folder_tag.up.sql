@@ -0,0 +1,2 @@
+-- Tag a smart folder's documents must have, empty for any
+ALTER TABLE smart_folders ADD COLUMN IF NOT EXISTS tag TEXT NOT NULL DEFAULT '';
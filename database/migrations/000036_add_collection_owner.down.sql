@@ -0,0 +1,2 @@
+-- Drop the collection owner
+ALTER TABLE collections DROP COLUMN IF EXISTS created_by;
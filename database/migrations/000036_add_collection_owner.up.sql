@@ -0,0 +1,2 @@
+-- Account that created each collection, whose folder permissions its share link keeps
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS created_by TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Drop shared server state
+DROP TABLE IF EXISTS server_state;
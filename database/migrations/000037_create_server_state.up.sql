@@ -0,0 +1,8 @@
+-- State shared by the API server and a separate worker, such as read-only mode
+CREATE TABLE IF NOT EXISTS server_state (
+    name TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE server_state IS 'Read-only mode and the last document change made by the worker';
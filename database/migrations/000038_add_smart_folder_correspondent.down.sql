@@ -0,0 +1,2 @@
+-- Drop the smart folder correspondent filter
+ALTER TABLE smart_folders DROP COLUMN IF EXISTS correspondent;
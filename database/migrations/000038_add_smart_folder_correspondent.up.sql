@@ -0,0 +1,2 @@
+-- Correspondent a smart folder's documents must have been read for, empty for any
+ALTER TABLE smart_folders ADD COLUMN IF NOT EXISTS correspondent TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLength is how much of a file http.DetectContentType looks at
+const sniffLength = 512
+
+// extensionMIMETypes covers document types the mime package may not know on every platform
+var extensionMIMETypes = map[string]string{
+	".pdf":  "application/pdf",
+	".txt":  "text/plain; charset=utf-8",
+	".rtf":  "application/rtf",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".odf":  "application/vnd.oasis.opendocument.formula",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".csv":  "text/csv; charset=utf-8",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+}
+
+// genericMIMETypes are sniffed types that only say what family a file is in, so a type known from
+// the extension is more useful: RTF sniffs as plain text and DOCX as a zip archive
+var genericMIMETypes = map[string]bool{
+	"application/octet-stream":  true,
+	"application/zip":           true,
+	"text/plain; charset=utf-8": true,
+}
+
+// MIMETypeByExtension returns the content type for a file name from its extension alone,
+// application/octet-stream when it is not known
+func MIMETypeByExtension(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if mimeType, ok := extensionMIMETypes[ext]; ok {
+		return mimeType
+	}
+	if mimeType := mime.TypeByExtension(ext); mimeType != "" {
+		return mimeType
+	}
+	return "application/octet-stream"
+}
+
+// DetectMIMEType returns the content type of a file from its first bytes, so a scan saved as .pdf
+// that is really a JPEG is served as an image. The extension decides when the content is not
+// recognised or only matches a generic type, and when the file cannot be read.
+func DetectMIMEType(path string) string {
+	byExtension := MIMETypeByExtension(path)
+	file, err := os.Open(path)
+	if err != nil {
+		return byExtension
+	}
+	defer file.Close()
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return byExtension
+	}
+	sniffed := http.DetectContentType(head[:n])
+	if genericMIMETypes[sniffed] && byExtension != "application/octet-stream" {
+		return byExtension
+	}
+	return sniffed
+}
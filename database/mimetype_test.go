@@ -0,0 +1,34 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectMIMEType(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]struct {
+		content string
+		want    string
+	}{
+		"scan.pdf":      {"%PDF-1.7\n", "application/pdf"},
+		"letter.rtf":    {`{\rtf1\ansi hello}`, "application/rtf"},
+		"notes.txt":     {"plain words", "text/plain; charset=utf-8"},
+		"photo.pdf":     {"\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"}, // the content wins over a wrong extension
+		"page.TIFF":     {"II*\x00", "image/tiff"},
+		"unknown.xyz01": {"\x00\x01\x02", "application/octet-stream"},
+	}
+	for name, file := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(file.content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if got := DetectMIMEType(path); got != file.want {
+			t.Errorf("DetectMIMEType(%s) = %q, want %q", name, got, file.want)
+		}
+	}
+	if got := DetectMIMEType(filepath.Join(dir, "missing.jpg")); got != "image/jpeg" {
+		t.Errorf("Expected a missing file to fall back to its extension, got %q", got)
+	}
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestDocumentOCRStatus(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// A scan stored while it waits on OCR, and a batch holding a scan whose OCR failed
+			db := open()
+			defer db.Close()
+			scan := &Document{
+				Name:         "receipt.png",
+				Path:         "/docs/receipt.png",
+				IngressTime:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Folder:       "/docs",
+				Hash:         "hash-scan",
+				ULID:         ulid.Make(),
+				DocumentType: ".png",
+				OCRStatus:    OCRPending,
+			}
+			if err := db.SaveDocument(scan); err != nil {
+				t.Fatalf("SaveDocument failed: %v", err)
+			}
+			blurred := &Document{
+				Name:         "blurred.jpg",
+				Path:         "/docs/blurred.jpg",
+				IngressTime:  time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				Folder:       "/docs",
+				Hash:         "hash-blurred",
+				ULID:         ulid.Make(),
+				DocumentType: ".jpg",
+				OCRStatus:    OCRFailed,
+			}
+			if err := db.SaveDocumentBatch([]*Document{blurred}, nil); err != nil {
+				t.Fatalf("SaveDocumentBatch failed: %v", err)
+			}
+
+			// OCR finishes on the first scan
+			if err := db.UpdateDocumentOCRStatus(scan.ULID.String(), OCRDone); err != nil {
+				t.Fatalf("UpdateDocumentOCRStatus failed: %v", err)
+			}
+
+			// The newest documents carry their status
+			newest, _, err := db.GetNewestDocumentsWithPagination(1, 10)
+			if err != nil {
+				t.Fatalf("GetNewestDocumentsWithPagination failed: %v", err)
+			}
+			statuses := map[string]string{}
+			for _, doc := range newest {
+				statuses[doc.Name] = doc.OCRStatus
+			}
+			if statuses["receipt.png"] != OCRDone || statuses["blurred.jpg"] != OCRFailed {
+				t.Errorf("Expected receipt done and blurred failed, got %v", statuses)
+			}
+			got, err := db.GetDocumentByULID(blurred.ULID.String())
+			if err != nil || got.OCRStatus != OCRFailed {
+				t.Errorf("Expected the failed status from a single lookup, got %+v, %v", got, err)
+			}
+		})
+	}
+}
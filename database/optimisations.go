@@ -0,0 +1,52 @@
+package database
+
+import "time"
+
+// DocumentOptimisation records that a scanned document's file was recompressed to save storage. The
+// original's hash is kept so a copy of the original dropped into ingress again is still found as a
+// duplicate of the document.
+type DocumentOptimisation struct {
+	DocumentULID  string    `json:"documentId"`
+	OriginalHash  string    `json:"originalHash"`
+	OriginalSize  int64     `json:"originalSize"`
+	OptimisedSize int64     `json:"optimisedSize"`
+	OriginalPath  string    `json:"originalPath,omitempty"` // where the original was kept, empty when it was replaced
+	OptimisedAt   time.Time `json:"optimisedAt"`
+}
+
+const documentOptimisationColumns = `document_ulid, original_hash, original_size, optimised_size, original_path, optimised_at`
+
+// scanDocumentOptimisation reads a row of documentOptimisationColumns
+func scanDocumentOptimisation(row interface{ Scan(...any) error }) (*DocumentOptimisation, error) {
+	var optimisation DocumentOptimisation
+	if err := row.Scan(&optimisation.DocumentULID, &optimisation.OriginalHash, &optimisation.OriginalSize,
+		&optimisation.OptimisedSize, &optimisation.OriginalPath, &optimisation.OptimisedAt); err != nil {
+		return nil, err
+	}
+	return &optimisation, nil
+}
+
+// SaveDocumentOptimisation records that a document's file was recompressed, replacing any earlier record
+func (p *PostgresDB) SaveDocumentOptimisation(optimisation *DocumentOptimisation) error {
+	_, err := p.db.Exec(`INSERT INTO document_optimisations (`+documentOptimisationColumns+`) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (document_ulid) DO UPDATE SET
+			original_hash = EXCLUDED.original_hash,
+			original_size = EXCLUDED.original_size,
+			optimised_size = EXCLUDED.optimised_size,
+			original_path = EXCLUDED.original_path,
+			optimised_at = EXCLUDED.optimised_at`,
+		optimisation.DocumentULID, optimisation.OriginalHash, optimisation.OriginalSize, optimisation.OptimisedSize,
+		optimisation.OriginalPath, optimisation.OptimisedAt.UTC())
+	return err
+}
+
+// GetDocumentOptimisation returns how a document was recompressed, or sql.ErrNoRows when it was not
+func (p *PostgresDB) GetDocumentOptimisation(documentULID string) (*DocumentOptimisation, error) {
+	return scanDocumentOptimisation(p.db.QueryRow(`SELECT `+documentOptimisationColumns+` FROM document_optimisations WHERE document_ulid = $1`, documentULID))
+}
+
+// GetDocumentOptimisationByHash returns the recompression of the document whose original had the hash,
+// or sql.ErrNoRows when there is none
+func (p *PostgresDB) GetDocumentOptimisationByHash(originalHash string) (*DocumentOptimisation, error) {
+	return scanDocumentOptimisation(p.db.QueryRow(`SELECT `+documentOptimisationColumns+` FROM document_optimisations WHERE original_hash = $1 LIMIT 1`, originalHash))
+}
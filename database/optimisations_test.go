@@ -0,0 +1,71 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestDocumentOptimisations(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// A scanned document that has not been recompressed
+			db := open()
+			defer db.Close()
+			original := FileHashes{MD5: "5d41402abc4b2a76b9719d911017c592", SHA1: "aaf4c61d", SHA256: "2cf24dba"}
+			scan := &Document{
+				Name:         "scan.pdf",
+				Path:         "/docs/scan.pdf",
+				IngressTime:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Folder:       "/docs",
+				Hash:         "sha256:5f8a3c",
+				ULID:         ulid.Make(),
+				DocumentType: ".pdf",
+			}
+			if err := db.SaveDocument(scan); err != nil {
+				t.Fatalf("SaveDocument failed: %v", err)
+			}
+			if _, err := db.GetDocumentOptimisation(scan.ULID.String()); !errors.Is(err, sql.ErrNoRows) {
+				t.Fatalf("Expected sql.ErrNoRows before it is recompressed, got %v", err)
+			}
+
+			// Its recompression is recorded
+			saved := &DocumentOptimisation{
+				DocumentULID:  scan.ULID.String(),
+				OriginalHash:  original.Current(),
+				OriginalSize:  4_000_000,
+				OptimisedSize: 900_000,
+				OriginalPath:  "/originals/scan.pdf",
+				OptimisedAt:   time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC),
+			}
+			if err := db.SaveDocumentOptimisation(saved); err != nil {
+				t.Fatalf("SaveDocumentOptimisation failed: %v", err)
+			}
+
+			// It is returned for the document and for the original's hash
+			got, err := db.GetDocumentOptimisation(scan.ULID.String())
+			if err != nil {
+				t.Fatalf("GetDocumentOptimisation failed: %v", err)
+			}
+			if !got.OptimisedAt.Equal(saved.OptimisedAt) {
+				t.Errorf("Expected optimised at %v, got %v", saved.OptimisedAt, got.OptimisedAt)
+			}
+			got.OptimisedAt = saved.OptimisedAt
+			if *got != *saved {
+				t.Errorf("Expected %+v, got %+v", saved, got)
+			}
+			if got, err := db.GetDocumentOptimisationByHash(original.Current()); err != nil || got.DocumentULID != scan.ULID.String() {
+				t.Errorf("Expected the optimisation by original hash, got %+v, %v", got, err)
+			}
+
+			// A copy of the original is found as a duplicate of the document
+			found, err := FindDocumentByHashes(db, original)
+			if err != nil || found == nil || found.ULID != scan.ULID {
+				t.Errorf("Expected the recompressed document, got %v, %v", found, err)
+			}
+		})
+	}
+}
@@ -144,8 +144,8 @@ func (p *PostgresDB) Close() error {
 // SaveDocument saves or updates a document
 func (p *PostgresDB) SaveDocument(doc *Document) error {
 	query := `
-		INSERT INTO documents (name, path, ingress_time, folder, hash, ulid, document_type, full_text, url)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO documents (name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, full_text, url)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		ON CONFLICT(path) DO UPDATE SET
 			name = EXCLUDED.name,
 			ingress_time = EXCLUDED.ingress_time,
@@ -153,6 +153,10 @@ func (p *PostgresDB) SaveDocument(doc *Document) error {
 			hash = EXCLUDED.hash,
 			ulid = EXCLUDED.ulid,
 			document_type = EXCLUDED.document_type,
+			mime_type = EXCLUDED.mime_type,
+			size = EXCLUDED.size,
+			page_count = EXCLUDED.page_count,
+			ocr_status = EXCLUDED.ocr_status,
 			full_text = EXCLUDED.full_text,
 			url = EXCLUDED.url,
 			updated_at = CURRENT_TIMESTAMP
@@ -161,15 +165,108 @@ func (p *PostgresDB) SaveDocument(doc *Document) error {
 
 	err := p.db.QueryRow(query,
 		doc.Name, doc.Path, doc.IngressTime, doc.Folder, doc.Hash,
-		doc.ULID.String(), doc.DocumentType, doc.FullText, doc.URL,
+		doc.ULID.String(), doc.DocumentType, doc.MIMEType, doc.Size, doc.PageCount, doc.OCRStatus, doc.FullText, doc.URL,
 	).Scan(&doc.StormID)
 
 	return err
 }
 
+// SaveDocumentBatch upserts many documents and adds their word counts in a single transaction.
+// StormID is set on each document once it is saved.
+func (p *PostgresDB) SaveDocumentBatch(docs []*Document, wordCounts map[string]int) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if len(docs) > 0 {
+		values := make([]string, 0, len(docs))
+		args := make([]interface{}, 0, 13*len(docs))
+		for i, doc := range docs {
+			n := i * 13
+			values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+				n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+11, n+12, n+13))
+			args = append(args, doc.Name, doc.Path, doc.IngressTime, doc.Folder, doc.Hash,
+				doc.ULID.String(), doc.DocumentType, doc.MIMEType, doc.Size, doc.PageCount, doc.OCRStatus, doc.FullText, doc.URL)
+		}
+		query := `
+			INSERT INTO documents (name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, full_text, url)
+			VALUES ` + strings.Join(values, ", ") + `
+			ON CONFLICT(path) DO UPDATE SET
+				name = EXCLUDED.name,
+				ingress_time = EXCLUDED.ingress_time,
+				folder = EXCLUDED.folder,
+				hash = EXCLUDED.hash,
+				ulid = EXCLUDED.ulid,
+				document_type = EXCLUDED.document_type,
+				mime_type = EXCLUDED.mime_type,
+				size = EXCLUDED.size,
+				page_count = EXCLUDED.page_count,
+				ocr_status = EXCLUDED.ocr_status,
+				full_text = EXCLUDED.full_text,
+				url = EXCLUDED.url,
+				updated_at = CURRENT_TIMESTAMP
+			RETURNING id, path
+		`
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to insert documents: %w", err)
+		}
+		ids := make(map[string]int, len(docs))
+		for rows.Next() {
+			var id int
+			var path string
+			if err := rows.Scan(&id, &path); err != nil {
+				rows.Close()
+				return err
+			}
+			ids[path] = id
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			doc.StormID = ids[doc.Path]
+		}
+	}
+
+	if err := addWordFrequencies(tx, wordCounts); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// addWordFrequencies adds counts to the word frequency table using multi-row upserts
+func addWordFrequencies(tx *sql.Tx, counts map[string]int) error {
+	words := sortedWords(counts)
+	for start := 0; start < len(words); start += wordBatchSize {
+		end := min(start+wordBatchSize, len(words))
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, 2*(end-start))
+		for i, word := range words[start:end] {
+			values = append(values, fmt.Sprintf("($%d, $%d, CURRENT_TIMESTAMP)", 2*i+1, 2*i+2))
+			args = append(args, word, counts[word])
+		}
+		query := `INSERT INTO word_frequencies (word, frequency, last_updated) VALUES ` + strings.Join(values, ", ") + `
+			ON CONFLICT (word) DO UPDATE SET
+				frequency = word_frequencies.frequency + EXCLUDED.frequency,
+				last_updated = CURRENT_TIMESTAMP`
+		if _, err := tx.Exec(query, args...); err != nil {
+			return fmt.Errorf("failed to update word frequencies: %w", err)
+		}
+	}
+	return nil
+}
+
 // GetDocumentByID retrieves a document by ID
 func (p *PostgresDB) GetDocumentByID(id int) (*Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
+	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, full_text, url
 	          FROM documents WHERE id = $1`
 
 	doc := &Document{}
@@ -177,7 +274,7 @@ func (p *PostgresDB) GetDocumentByID(id int) (*Document, error) {
 
 	err := p.db.QueryRow(query, id).Scan(
 		&doc.StormID, &doc.Name, &doc.Path, &doc.IngressTime,
-		&doc.Folder, &doc.Hash, &ulidStr, &doc.DocumentType,
+		&doc.Folder, &doc.Hash, &ulidStr, &doc.DocumentType, &doc.MIMEType, &doc.Size, &doc.PageCount, &doc.OCRStatus,
 		&doc.FullText, &doc.URL,
 	)
 
@@ -196,7 +293,7 @@ func (p *PostgresDB) GetDocumentByID(id int) (*Document, error) {
 
 // GetDocumentByULID retrieves a document by ULID
 func (p *PostgresDB) GetDocumentByULID(ulidStr string) (*Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
+	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, full_text, url
 	          FROM documents WHERE ulid = $1`
 
 	doc := &Document{}
@@ -204,7 +301,7 @@ func (p *PostgresDB) GetDocumentByULID(ulidStr string) (*Document, error) {
 
 	err := p.db.QueryRow(query, ulidStr).Scan(
 		&doc.StormID, &doc.Name, &doc.Path, &doc.IngressTime,
-		&doc.Folder, &doc.Hash, &docUlidStr, &doc.DocumentType,
+		&doc.Folder, &doc.Hash, &docUlidStr, &doc.DocumentType, &doc.MIMEType, &doc.Size, &doc.PageCount, &doc.OCRStatus,
 		&doc.FullText, &doc.URL,
 	)
 
@@ -221,9 +318,32 @@ func (p *PostgresDB) GetDocumentByULID(ulidStr string) (*Document, error) {
 	return doc, nil
 }
 
+// GetDocumentText retrieves only the full text of a document by ULID
+func (p *PostgresDB) GetDocumentText(ulidStr string) (string, error) {
+	var fullText sql.NullString
+	err := p.db.QueryRow(`SELECT full_text FROM documents WHERE ulid = $1`, ulidStr).Scan(&fullText)
+	if err != nil {
+		return "", err
+	}
+	return fullText.String, nil
+}
+
+// GetDocumentTextRange returns up to limit characters of a document's full text starting at offset, and
+// the length of the whole text in characters, without reading the rest of the text
+func (p *PostgresDB) GetDocumentTextRange(ulidStr string, offset int, limit int) (string, int, error) {
+	var text string
+	var total int
+	err := p.db.QueryRow(`SELECT SUBSTR(COALESCE(full_text, ''), $2, $3), LENGTH(COALESCE(full_text, ''))
+		FROM documents WHERE ulid = $1`, ulidStr, offset+1, limit).Scan(&text, &total)
+	if err != nil {
+		return "", 0, err
+	}
+	return text, total, nil
+}
+
 // GetDocumentByPath retrieves a document by file path
 func (p *PostgresDB) GetDocumentByPath(path string) (*Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
+	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, full_text, url
 	          FROM documents WHERE path = $1`
 
 	doc := &Document{}
@@ -231,7 +351,7 @@ func (p *PostgresDB) GetDocumentByPath(path string) (*Document, error) {
 
 	err := p.db.QueryRow(query, path).Scan(
 		&doc.StormID, &doc.Name, &doc.Path, &doc.IngressTime,
-		&doc.Folder, &doc.Hash, &ulidStr, &doc.DocumentType,
+		&doc.Folder, &doc.Hash, &ulidStr, &doc.DocumentType, &doc.MIMEType, &doc.Size, &doc.PageCount, &doc.OCRStatus,
 		&doc.FullText, &doc.URL,
 	)
 
@@ -250,7 +370,7 @@ func (p *PostgresDB) GetDocumentByPath(path string) (*Document, error) {
 
 // GetDocumentByHash retrieves a document by hash
 func (p *PostgresDB) GetDocumentByHash(hash string) (*Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
+	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, full_text, url
 	          FROM documents WHERE hash = $1`
 
 	doc := &Document{}
@@ -258,7 +378,7 @@ func (p *PostgresDB) GetDocumentByHash(hash string) (*Document, error) {
 
 	err := p.db.QueryRow(query, hash).Scan(
 		&doc.StormID, &doc.Name, &doc.Path, &doc.IngressTime,
-		&doc.Folder, &doc.Hash, &ulidStr, &doc.DocumentType,
+		&doc.Folder, &doc.Hash, &ulidStr, &doc.DocumentType, &doc.MIMEType, &doc.Size, &doc.PageCount, &doc.OCRStatus,
 		&doc.FullText, &doc.URL,
 	)
 
@@ -288,7 +408,7 @@ func scanDocuments(rows *sql.Rows) ([]Document, error) {
 
 		err := rows.Scan(
 			&doc.StormID, &doc.Name, &doc.Path, &doc.IngressTime,
-			&doc.Folder, &doc.Hash, &ulidStr, &doc.DocumentType,
+			&doc.Folder, &doc.Hash, &ulidStr, &doc.DocumentType, &doc.MIMEType, &doc.Size, &doc.PageCount, &doc.OCRStatus,
 			&doc.FullText, &doc.URL,
 		)
 		if err != nil {
@@ -309,7 +429,7 @@ func scanDocuments(rows *sql.Rows) ([]Document, error) {
 
 // GetNewestDocuments retrieves the newest documents
 func (p *PostgresDB) GetNewestDocuments(limit int) ([]Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
+	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, full_text, url
 	          FROM documents ORDER BY ingress_time DESC LIMIT $1`
 
 	rows, err := p.db.Query(query, limit)
@@ -323,7 +443,7 @@ func (p *PostgresDB) GetNewestDocuments(limit int) ([]Document, error) {
 
 // GetAllDocuments retrieves all documents
 func (p *PostgresDB) GetAllDocuments() ([]Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
+	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, full_text, url
 	          FROM documents ORDER BY id`
 
 	rows, err := p.db.Query(query)
@@ -337,7 +457,7 @@ func (p *PostgresDB) GetAllDocuments() ([]Document, error) {
 
 // GetDocumentsByFolder retrieves documents in a specific folder
 func (p *PostgresDB) GetDocumentsByFolder(folder string) ([]Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
+	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, '' AS full_text, url
 	          FROM documents WHERE folder = $1`
 
 	rows, err := p.db.Query(query, folder)
@@ -349,10 +469,34 @@ func (p *PostgresDB) GetDocumentsByFolder(folder string) ([]Document, error) {
 	return scanDocuments(rows)
 }
 
+// GetDocumentsUnderFolder retrieves documents in a folder and all of its subfolders
+func (p *PostgresDB) GetDocumentsUnderFolder(folder string) ([]Document, error) {
+	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, '' AS full_text, url
+	          FROM documents WHERE folder = $1 OR folder LIKE $2 ESCAPE '\'
+	          ORDER BY folder, name`
+
+	rows, err := p.db.Query(query, folder, folderPrefixPattern(folder))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDocuments(rows)
+}
+
 // DeleteDocument deletes a document by ULID
 func (p *PostgresDB) DeleteDocument(ulidStr string) error {
 	query := `DELETE FROM documents WHERE ulid = $1`
-	_, err := p.db.Exec(query, ulidStr)
+	if _, err := p.db.Exec(query, ulidStr); err != nil {
+		return err
+	}
+	if _, err := p.db.Exec(`DELETE FROM document_access_daily WHERE document_ulid = $1`, ulidStr); err != nil {
+		return err
+	}
+	if _, err := p.db.Exec(`DELETE FROM document_tags WHERE document_ulid = $1`, ulidStr); err != nil {
+		return err
+	}
+	_, err := p.db.Exec(`DELETE FROM document_fields WHERE document_ulid = $1`, ulidStr)
 	return err
 }
 
@@ -370,6 +514,27 @@ func (p *PostgresDB) UpdateDocumentFolder(ulidStr string, folder string) error {
 	return err
 }
 
+// UpdateDocumentFileDetails updates the size and page count of a document
+func (p *PostgresDB) UpdateDocumentFileDetails(ulidStr string, size int64, pageCount int) error {
+	query := `UPDATE documents SET size = $1, page_count = $2, updated_at = CURRENT_TIMESTAMP WHERE ulid = $3`
+	_, err := p.db.Exec(query, size, pageCount, ulidStr)
+	return err
+}
+
+// UpdateDocumentOCRStatus records how far text recognition has got for a document
+func (p *PostgresDB) UpdateDocumentOCRStatus(ulidStr string, status string) error {
+	query := `UPDATE documents SET ocr_status = $1, updated_at = CURRENT_TIMESTAMP WHERE ulid = $2`
+	_, err := p.db.Exec(query, status, ulidStr)
+	return err
+}
+
+// UpdateDocumentHash replaces the stored hash of a document, e.g. with one under another algorithm
+func (p *PostgresDB) UpdateDocumentHash(ulidStr string, hash string) error {
+	query := `UPDATE documents SET hash = $1, updated_at = CURRENT_TIMESTAMP WHERE ulid = $2`
+	_, err := p.db.Exec(query, hash, ulidStr)
+	return err
+}
+
 // SaveConfig saves server configuration
 func (p *PostgresDB) SaveConfig(cfg *config.ServerConfig) error {
 	query := `
@@ -453,7 +618,7 @@ func (p *PostgresDB) GetNewestDocumentsWithPagination(page int, pageSize int) ([
 	}
 
 	// Get paginated documents
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
+	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, '' AS full_text, url
 	          FROM documents ORDER BY ingress_time DESC LIMIT $1 OFFSET $2`
 
 	rows, err := p.db.Query(query, pageSize, offset)
@@ -470,6 +635,27 @@ func (p *PostgresDB) GetNewestDocumentsWithPagination(page int, pageSize int) ([
 	return docs, totalCount, nil
 }
 
+// GetNewestDocumentsAfter retrieves up to limit documents, newest first, that come after cursor.
+// A nil cursor starts from the newest document.
+func (p *PostgresDB) GetNewestDocumentsAfter(cursor *DocumentCursor, limit int) ([]Document, error) {
+	var rows *sql.Rows
+	var err error
+	if cursor == nil {
+		rows, err = p.db.Query(`SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, '' AS full_text, url
+	          FROM documents ORDER BY ingress_time DESC, id DESC LIMIT $1`, limit)
+	} else {
+		rows, err = p.db.Query(`SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, '' AS full_text, url
+	          FROM documents WHERE (ingress_time, id) < ($1, $2)
+	          ORDER BY ingress_time DESC, id DESC LIMIT $3`, cursor.IngressTime, cursor.ID, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDocuments(rows)
+}
+
 // SearchDocuments performs full-text search using PostgreSQL's native search capabilities
 // Supports both prefix matching and phrase search
 func (p *PostgresDB) SearchDocuments(searchTerm string) ([]Document, error) {
@@ -477,7 +663,7 @@ func (p *PostgresDB) SearchDocuments(searchTerm string) ([]Document, error) {
 	// For prefix search: "test" becomes "test:*"
 	// For phrase search: "test document" becomes "test <-> document"
 
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
+	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, '' AS full_text, url
 	          FROM documents
 	          WHERE full_text_search @@ to_tsquery('english', $1)
 	          ORDER BY ts_rank(full_text_search, to_tsquery('english', $1)) DESC`
@@ -495,6 +681,25 @@ func (p *PostgresDB) SearchDocuments(searchTerm string) ([]Document, error) {
 	return scanDocuments(rows)
 }
 
+// SearchDocumentNames returns documents whose name contains fragment, case-insensitively, names that
+// start with it first and then newest first
+func (p *PostgresDB) SearchDocumentNames(fragment string, limit int) ([]Document, error) {
+	escaped := likeEscape(strings.ToLower(fragment))
+	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, mime_type, size, page_count, ocr_status, '' AS full_text, url
+	          FROM documents
+	          WHERE LOWER(name) LIKE $1 ESCAPE '\'
+	          ORDER BY CASE WHEN LOWER(name) LIKE $2 ESCAPE '\' THEN 0 ELSE 1 END, ingress_time DESC, id DESC
+	          LIMIT $3`
+
+	rows, err := p.db.Query(query, "%"+escaped+"%", escaped+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDocuments(rows)
+}
+
 // formatSearchTerm converts a search term into PostgreSQL tsquery format
 func formatSearchTerm(term string) string {
 	// Remove special characters that would break tsquery
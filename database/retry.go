@@ -0,0 +1,223 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/oklog/ulid/v2"
+)
+
+// RetryPolicy says how often a failed write is tried again and how long to wait in between
+type RetryPolicy struct {
+	Attempts int           // total tries including the first, 1 or less disables retrying
+	Backoff  time.Duration // wait before the first retry, doubled for each one after
+}
+
+// maxRetryBackoff caps the doubling so a long policy does not stall a job for minutes
+const maxRetryBackoff = 10 * time.Second
+
+// transientPostgresCodes are SQLSTATEs worth retrying besides the 08 (connection exception) class
+var transientPostgresCodes = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"53300": true, // too_many_connections
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// IsTransient reports whether err is a dropped connection, a busy database or another failure that
+// may succeed if the same statement is run again. Errors about the data itself are never transient.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Class() == "08" || transientPostgresCodes[pqErr.Code]
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	// SQLite drivers only say so in the message
+	message := err.Error()
+	return strings.Contains(message, "database is locked") || strings.Contains(message, "SQLITE_BUSY")
+}
+
+// RetryingRepository retries the write operations of a Repository when they fail with a transient
+// error. Reads are passed straight through. Each write is a single statement or transaction, so a
+// failed attempt has been rolled back before it is tried again.
+type RetryingRepository struct {
+	Repository
+	policy RetryPolicy
+	sleep  func(time.Duration) // replaced in tests
+}
+
+// NewRetryingRepository wraps repo so its writes are retried according to policy
+func NewRetryingRepository(repo Repository, policy RetryPolicy) *RetryingRepository {
+	return &RetryingRepository{Repository: repo, policy: policy, sleep: time.Sleep}
+}
+
+// retry runs write until it succeeds, fails with a permanent error or runs out of attempts
+func (r *RetryingRepository) retry(operation string, write func() error) error {
+	backoff := r.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		err := write()
+		if err == nil || attempt >= r.policy.Attempts || !IsTransient(err) {
+			return err
+		}
+		Logger.Warn("Retrying database write after transient error", "operation", operation,
+			"attempt", attempt, "of", r.policy.Attempts, "backoff", backoff, "error", err)
+		r.sleep(backoff)
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+// SaveDocument retries Repository.SaveDocument
+func (r *RetryingRepository) SaveDocument(doc *Document) error {
+	return r.retry("SaveDocument", func() error { return r.Repository.SaveDocument(doc) })
+}
+
+// SaveDocumentBatch retries Repository.SaveDocumentBatch
+func (r *RetryingRepository) SaveDocumentBatch(docs []*Document, wordCounts map[string]int) error {
+	return r.retry("SaveDocumentBatch", func() error { return r.Repository.SaveDocumentBatch(docs, wordCounts) })
+}
+
+// DeleteDocument retries Repository.DeleteDocument
+func (r *RetryingRepository) DeleteDocument(ulid string) error {
+	return r.retry("DeleteDocument", func() error { return r.Repository.DeleteDocument(ulid) })
+}
+
+// UpdateDocumentURL retries Repository.UpdateDocumentURL
+func (r *RetryingRepository) UpdateDocumentURL(ulid string, url string) error {
+	return r.retry("UpdateDocumentURL", func() error { return r.Repository.UpdateDocumentURL(ulid, url) })
+}
+
+// UpdateDocumentFolder retries Repository.UpdateDocumentFolder
+func (r *RetryingRepository) UpdateDocumentFolder(ulid string, folder string) error {
+	return r.retry("UpdateDocumentFolder", func() error { return r.Repository.UpdateDocumentFolder(ulid, folder) })
+}
+
+// UpdateDocumentFileDetails retries Repository.UpdateDocumentFileDetails
+func (r *RetryingRepository) UpdateDocumentFileDetails(ulid string, size int64, pageCount int) error {
+	return r.retry("UpdateDocumentFileDetails", func() error { return r.Repository.UpdateDocumentFileDetails(ulid, size, pageCount) })
+}
+
+// UpdateDocumentOCRStatus retries Repository.UpdateDocumentOCRStatus
+func (r *RetryingRepository) UpdateDocumentOCRStatus(ulid string, status string) error {
+	return r.retry("UpdateDocumentOCRStatus", func() error { return r.Repository.UpdateDocumentOCRStatus(ulid, status) })
+}
+
+// UpdateDocumentHash retries Repository.UpdateDocumentHash
+func (r *RetryingRepository) UpdateDocumentHash(ulid string, hash string) error {
+	return r.retry("UpdateDocumentHash", func() error { return r.Repository.UpdateDocumentHash(ulid, hash) })
+}
+
+// EnsureFolder retries Repository.EnsureFolder
+func (r *RetryingRepository) EnsureFolder(path string, parentPath string) (*Folder, error) {
+	var folder *Folder
+	err := r.retry("EnsureFolder", func() error {
+		var err error
+		folder, err = r.Repository.EnsureFolder(path, parentPath)
+		return err
+	})
+	return folder, err
+}
+
+// SetFolderStyle retries Repository.SetFolderStyle
+func (r *RetryingRepository) SetFolderStyle(id int, color string, icon string) (*Folder, error) {
+	var folder *Folder
+	err := r.retry("SetFolderStyle", func() error {
+		var err error
+		folder, err = r.Repository.SetFolderStyle(id, color, icon)
+		return err
+	})
+	return folder, err
+}
+
+// SaveDocumentArchive retries Repository.SaveDocumentArchive
+func (r *RetryingRepository) SaveDocumentArchive(archive *DocumentArchive) error {
+	return r.retry("SaveDocumentArchive", func() error { return r.Repository.SaveDocumentArchive(archive) })
+}
+
+// DeleteDocumentArchive retries Repository.DeleteDocumentArchive
+func (r *RetryingRepository) DeleteDocumentArchive(documentULID string) error {
+	return r.retry("DeleteDocumentArchive", func() error { return r.Repository.DeleteDocumentArchive(documentULID) })
+}
+
+// DeleteFolderTree retries Repository.DeleteFolderTree
+func (r *RetryingRepository) DeleteFolderTree(path string) (int, error) {
+	var removed int
+	err := r.retry("DeleteFolderTree", func() error {
+		var err error
+		removed, err = r.Repository.DeleteFolderTree(path)
+		return err
+	})
+	return removed, err
+}
+
+// RecalculateAllWordFrequencies retries Repository.RecalculateAllWordFrequencies
+func (r *RetryingRepository) RecalculateAllWordFrequencies() error {
+	return r.retry("RecalculateAllWordFrequencies", r.Repository.RecalculateAllWordFrequencies)
+}
+
+// AddWordFrequencies retries Repository.AddWordFrequencies
+func (r *RetryingRepository) AddWordFrequencies(counts map[string]int) error {
+	return r.retry("AddWordFrequencies", func() error { return r.Repository.AddWordFrequencies(counts) })
+}
+
+// UpdateJobProgress retries Repository.UpdateJobProgress
+func (r *RetryingRepository) UpdateJobProgress(jobID ulid.ULID, progress int, currentStep string) error {
+	return r.retry("UpdateJobProgress", func() error { return r.Repository.UpdateJobProgress(jobID, progress, currentStep) })
+}
+
+// UpdateJobStatus retries Repository.UpdateJobStatus
+func (r *RetryingRepository) UpdateJobStatus(jobID ulid.ULID, status JobStatus, message string) error {
+	return r.retry("UpdateJobStatus", func() error { return r.Repository.UpdateJobStatus(jobID, status, message) })
+}
+
+// UpdateJobError retries Repository.UpdateJobError
+func (r *RetryingRepository) UpdateJobError(jobID ulid.ULID, errorMsg string) error {
+	return r.retry("UpdateJobError", func() error { return r.Repository.UpdateJobError(jobID, errorMsg) })
+}
+
+// TouchJob retries Repository.TouchJob
+func (r *RetryingRepository) TouchJob(jobID ulid.ULID) error {
+	return r.retry("TouchJob", func() error { return r.Repository.TouchJob(jobID) })
+}
+
+// CompleteJob retries Repository.CompleteJob
+func (r *RetryingRepository) CompleteJob(jobID ulid.ULID, result string) error {
+	return r.retry("CompleteJob", func() error { return r.Repository.CompleteJob(jobID, result) })
+}
+
+// PlaceLegalHold retries Repository.PlaceLegalHold
+func (r *RetryingRepository) PlaceLegalHold(hold *LegalHold) error {
+	return r.retry("PlaceLegalHold", func() error { return r.Repository.PlaceLegalHold(hold) })
+}
+
+// RemoveLegalHold retries Repository.RemoveLegalHold
+func (r *RetryingRepository) RemoveLegalHold(id, user, reason string) (*LegalHold, error) {
+	var hold *LegalHold
+	err := r.retry("RemoveLegalHold", func() (err error) {
+		hold, err = r.Repository.RemoveLegalHold(id, user, reason)
+		return err
+	})
+	return hold, err
+}
+
+// SaveDocumentRedaction retries Repository.SaveDocumentRedaction
+func (r *RetryingRepository) SaveDocumentRedaction(redaction *DocumentRedaction) error {
+	return r.retry("SaveDocumentRedaction", func() error { return r.Repository.SaveDocumentRedaction(redaction) })
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/oklog/ulid/v2"
+)
+
+var _ Repository = (*RetryingRepository)(nil)
+
+// flakyRepository fails SaveDocument with err for the first failures calls
+type flakyRepository struct {
+	*MemoryDB
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyRepository) SaveDocument(doc *Document) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return f.MemoryDB.SaveDocument(doc)
+}
+
+func TestIsTransient(t *testing.T) {
+	for err, want := range map[error]bool{
+		driver.ErrBadConn:                                      true,
+		fmt.Errorf("save: %w", driver.ErrBadConn):              true,
+		&pq.Error{Code: "08006"}:                               true, // connection_failure
+		&pq.Error{Code: "40P01"}:                               true,
+		&pq.Error{Code: "23505"}:                               false, // unique_violation
+		errors.New("database is locked (5) (SQLITE_BUSY)"):     true,
+		sql.ErrNoRows:                                          false,
+		errors.New("UNIQUE constraint failed: documents.path"): false,
+	} {
+		if got := IsTransient(err); got != want {
+			t.Errorf("IsTransient(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
+
+func TestRetryingRepository(t *testing.T) {
+	if Logger == nil {
+		Logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	}
+	newDoc := func() *Document {
+		id := ulid.Make()
+		return &Document{Name: "a.pdf", Path: "/docs/" + id.String() + ".pdf", Folder: "/docs", ULID: id, IngressTime: time.Now()}
+	}
+	newRepo := func(failures int, err error) (*flakyRepository, *RetryingRepository, *[]time.Duration) {
+		flaky := &flakyRepository{MemoryDB: NewMemoryDB(), failures: failures, err: err}
+		retrying := NewRetryingRepository(flaky, RetryPolicy{Attempts: 4, Backoff: 100 * time.Millisecond})
+		var waits []time.Duration
+		retrying.sleep = func(d time.Duration) { waits = append(waits, d) }
+		return flaky, retrying, &waits
+	}
+
+	t.Run("Dropped connections are retried with growing backoff", func(t *testing.T) {
+		_, retrying, waits := newRepo(2, driver.ErrBadConn)
+		doc := newDoc()
+		if err := retrying.SaveDocument(doc); err != nil {
+			t.Fatalf("Expected the save to succeed after retries: %v", err)
+		}
+		if want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}; !reflect.DeepEqual(*waits, want) {
+			t.Errorf("Expected backoffs %v, got %v", want, *waits)
+		}
+		if saved, err := retrying.GetDocumentByULID(doc.ULID.String()); err != nil || saved.Path != doc.Path {
+			t.Errorf("Expected the document to be saved, got %+v, %v", saved, err)
+		}
+	})
+
+	t.Run("Permanent error is returned at once", func(t *testing.T) {
+		flaky, retrying, waits := newRepo(1, errors.New("UNIQUE constraint failed: documents.path"))
+		if err := retrying.SaveDocument(newDoc()); err == nil || flaky.calls != 1 || len(*waits) != 0 {
+			t.Errorf("Expected no retry for a permanent error, got %v after %d calls", err, flaky.calls)
+		}
+	})
+
+	t.Run("Last transient error is returned once the attempts run out", func(t *testing.T) {
+		flaky, retrying, _ := newRepo(10, driver.ErrBadConn)
+		if err := retrying.SaveDocument(newDoc()); !errors.Is(err, driver.ErrBadConn) || flaky.calls != 4 {
+			t.Errorf("Expected 4 attempts ending in ErrBadConn, got %v after %d calls", err, flaky.calls)
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package database
+
+import "fmt"
+
+// PageRotation is how far a page of a scan was turned clockwise at ingestion to stand it upright
+type PageRotation struct {
+	Page    int `json:"page"`    // from 1; an image is page 1
+	Degrees int `json:"degrees"` // 90, 180 or 270
+}
+
+// SaveDocumentRotations records the pages of a document that were rotated, replacing what was recorded before
+func (p *PostgresDB) SaveDocumentRotations(documentULID string, rotations []PageRotation) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM document_rotations WHERE document_ulid = $1`, documentULID); err != nil {
+		return err
+	}
+	for _, rotation := range rotations {
+		if _, err := tx.Exec(`INSERT INTO document_rotations (document_ulid, page, degrees) VALUES ($1, $2, $3)`,
+			documentULID, rotation.Page, rotation.Degrees); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDocumentRotations returns the rotated pages of a document in page order, none when no page was turned
+func (p *PostgresDB) GetDocumentRotations(documentULID string) ([]PageRotation, error) {
+	rows, err := p.db.Query(`SELECT page, degrees FROM document_rotations WHERE document_ulid = $1 ORDER BY page`, documentULID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rotations := []PageRotation{}
+	for rows.Next() {
+		var rotation PageRotation
+		if err := rows.Scan(&rotation.Page, &rotation.Degrees); err != nil {
+			return nil, err
+		}
+		rotations = append(rotations, rotation)
+	}
+	return rotations, rows.Err()
+}
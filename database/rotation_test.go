@@ -0,0 +1,45 @@
+package database
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDocumentRotations(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// No rotations recorded for a document
+			db := open()
+			defer db.Close()
+			const id = "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+			if rotations, err := db.GetDocumentRotations(id); err != nil || len(rotations) != 0 {
+				t.Fatalf("Expected no rotations before any are saved, got %v, %v", rotations, err)
+			}
+
+			// Rotations are saved, then saved again after a rescan found different pages turned
+			if err := db.SaveDocumentRotations(id, []PageRotation{{Page: 1, Degrees: 180}, {Page: 4, Degrees: 90}}); err != nil {
+				t.Fatalf("SaveDocumentRotations failed: %v", err)
+			}
+			if err := db.SaveDocumentRotations(id, []PageRotation{{Page: 3, Degrees: 270}, {Page: 2, Degrees: 90}}); err != nil {
+				t.Fatalf("SaveDocumentRotations failed: %v", err)
+			}
+
+			// Only the latest rotations are returned, in page order
+			rotations, err := db.GetDocumentRotations(id)
+			want := []PageRotation{{Page: 2, Degrees: 90}, {Page: 3, Degrees: 270}}
+			if err != nil || !slices.Equal(rotations, want) {
+				t.Errorf("Expected %v, got %v, %v", want, rotations, err)
+			}
+
+			// An empty list is saved
+			if err := db.SaveDocumentRotations(id, nil); err != nil {
+				t.Fatalf("SaveDocumentRotations failed: %v", err)
+			}
+
+			// Nothing is recorded
+			if rotations, err := db.GetDocumentRotations(id); err != nil || len(rotations) != 0 {
+				t.Errorf("Expected the rotations to be cleared, got %v, %v", rotations, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package database
+
+// GetJobSchedules returns the schedules saved through the schedules API, keyed by job name.
+// Jobs without a saved schedule fall back to the one in the environment.
+func (p *PostgresDB) GetJobSchedules() (map[string]string, error) {
+	rows, err := p.db.Query(`SELECT name, spec FROM job_schedules`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make(map[string]string)
+	for rows.Next() {
+		var name, spec string
+		if err := rows.Scan(&name, &spec); err != nil {
+			return nil, err
+		}
+		schedules[name] = spec
+	}
+	return schedules, rows.Err()
+}
+
+// SaveJobSchedules stores the given schedules in one transaction; an empty spec removes the saved schedule
+func (p *PostgresDB) SaveJobSchedules(schedules map[string]string) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := Now().UTC()
+	for name, spec := range schedules {
+		if spec == "" {
+			_, err = tx.Exec(`DELETE FROM job_schedules WHERE name = $1`, name)
+		} else {
+			_, err = tx.Exec(`INSERT INTO job_schedules (name, spec, updated_at) VALUES ($1, $2, $3)
+				ON CONFLICT (name) DO UPDATE SET spec = EXCLUDED.spec, updated_at = EXCLUDED.updated_at`, name, spec, now)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"time"
+)
+
+// SearchQuery is one search someone ran, kept for their search history and for zero-result analytics
+type SearchQuery struct {
+	ID          int       `json:"id"`
+	Term        string    `json:"term"`
+	ResultCount int       `json:"resultCount"`
+	DurationMs  int64     `json:"durationMs"`
+	User        string    `json:"user"` // empty when the request was not authenticated
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// SearchTermCount is how often a search term was run, case-insensitively
+type SearchTermCount struct {
+	Term         string    `json:"term"`
+	Searches     int       `json:"searches"`
+	LastSearched time.Time `json:"lastSearched"`
+}
+
+// RecordSearch stores a search query; CreatedAt defaults to now
+func (p *PostgresDB) RecordSearch(query *SearchQuery) error {
+	if query.CreatedAt.IsZero() {
+		query.CreatedAt = Now()
+	}
+	return p.db.QueryRow(`INSERT INTO search_queries (term, result_count, duration_ms, user_name, created_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		query.Term, query.ResultCount, query.DurationMs, query.User, query.CreatedAt.UTC()).Scan(&query.ID)
+}
+
+// GetSearchHistory returns a user's most recent searches, newest first
+func (p *PostgresDB) GetSearchHistory(user string, limit int) ([]SearchQuery, error) {
+	rows, err := p.db.Query(`SELECT id, term, result_count, duration_ms, user_name, created_at FROM search_queries
+		WHERE user_name = $1 ORDER BY created_at DESC, id DESC LIMIT $2`, user, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var queries []SearchQuery
+	for rows.Next() {
+		var query SearchQuery
+		if err := rows.Scan(&query.ID, &query.Term, &query.ResultCount, &query.DurationMs, &query.User, &query.CreatedAt); err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+	}
+	return queries, rows.Err()
+}
+
+// GetZeroResultSearches returns the terms most often searched without results since the given time
+func (p *PostgresDB) GetZeroResultSearches(since time.Time, limit int) ([]SearchTermCount, error) {
+	rows, err := p.db.Query(`SELECT LOWER(term), COUNT(*) AS searches, MAX(created_at) AS last_searched FROM search_queries
+		WHERE result_count = 0 AND created_at >= $1
+		GROUP BY LOWER(term) ORDER BY searches DESC, last_searched DESC LIMIT $2`, since.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var terms []SearchTermCount
+	for rows.Next() {
+		var term SearchTermCount
+		if err := rows.Scan(&term.Term, &term.Searches, &term.LastSearched); err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	return terms, rows.Err()
+}
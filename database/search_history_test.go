@@ -0,0 +1,56 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSearchHistory(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// Searches by two users over two months, some without results
+			db := open()
+			defer db.Close()
+			now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+			searches := []SearchQuery{
+				{Term: "passport", ResultCount: 0, User: "alice", CreatedAt: now.AddDate(0, -2, 0)},
+				{Term: "Invoice", ResultCount: 0, User: "alice", CreatedAt: now.Add(-3 * time.Hour)},
+				{Term: "invoice", ResultCount: 0, User: "bob", CreatedAt: now.Add(-2 * time.Hour)},
+				{Term: "boiler", ResultCount: 0, User: "bob", CreatedAt: now.Add(-90 * time.Minute)},
+				{Term: "receipt", ResultCount: 4, DurationMs: 12, User: "alice", CreatedAt: now.Add(-time.Hour)},
+			}
+			for i := range searches {
+				if err := db.RecordSearch(&searches[i]); err != nil {
+					t.Fatalf("RecordSearch failed: %v", err)
+				}
+				if searches[i].ID == 0 {
+					t.Errorf("Expected an ID for search %q", searches[i].Term)
+				}
+			}
+
+			// Reading alice's history and the zero-result terms of the last month
+			history, err := db.GetSearchHistory("alice", 2)
+			if err != nil {
+				t.Fatalf("GetSearchHistory failed: %v", err)
+			}
+			zero, err := db.GetZeroResultSearches(now.AddDate(0, -1, 0), 10)
+			if err != nil {
+				t.Fatalf("GetZeroResultSearches failed: %v", err)
+			}
+
+			// History is alice's own, newest first, and zero-result terms are grouped case-insensitively
+			if len(history) != 2 || history[0].Term != "receipt" || history[0].ResultCount != 4 || history[0].DurationMs != 12 || history[1].Term != "Invoice" {
+				t.Errorf("Unexpected history: %+v", history)
+			}
+			if !history[0].CreatedAt.Equal(now.Add(-time.Hour)) {
+				t.Errorf("Expected the search time to round-trip, got %v", history[0].CreatedAt)
+			}
+			if len(zero) != 2 || zero[0].Term != "invoice" || zero[0].Searches != 2 || zero[1].Term != "boiler" {
+				t.Errorf("Unexpected zero-result terms: %+v", zero)
+			}
+			if len(zero) > 0 && !zero[0].LastSearched.Equal(now.Add(-2*time.Hour)) {
+				t.Errorf("Expected invoice last searched at %v, got %v", now.Add(-2*time.Hour), zero[0].LastSearched)
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"github.com/oklog/ulid/v2"
 )
 
 func TestPostgresFullTextSearch(t *testing.T) {
@@ -133,3 +135,56 @@ func TestPostgresFullTextSearch(t *testing.T) {
 		}
 	})
 }
+
+func TestSearchDocumentNames(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// Documents whose names contain "inv" at the start, in the middle, with LIKE wildcards, or not at all
+			db := open()
+			defer db.Close()
+			base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+			names := []string{"Invoice_2024.pdf", "old_invoice.pdf", "Receipt.pdf", "Invoice_2025.pdf", "inv_100%.pdf"}
+			for i, docName := range names {
+				doc := &Document{
+					Name:         docName,
+					Path:         "/docs/" + docName,
+					IngressTime:  base.Add(time.Duration(i) * time.Hour),
+					Folder:       "/docs",
+					Hash:         fmt.Sprintf("hash%d", i),
+					ULID:         ulid.Make(),
+					DocumentType: ".pdf",
+					FullText:     "text",
+				}
+				if err := db.SaveDocument(doc); err != nil {
+					t.Fatalf("SaveDocument failed: %v", err)
+				}
+			}
+
+			// Matching names against a fragment and a fragment holding a wildcard
+			matches, err := db.SearchDocumentNames("INV", 3)
+			if err != nil {
+				t.Fatalf("SearchDocumentNames failed: %v", err)
+			}
+			literal, err := db.SearchDocumentNames("100%", 10)
+			if err != nil {
+				t.Fatalf("SearchDocumentNames failed: %v", err)
+			}
+
+			// Names starting with the fragment come first, newest first, and wildcards match literally
+			var got []string
+			for _, doc := range matches {
+				got = append(got, doc.Name)
+				if doc.FullText != "" {
+					t.Errorf("Expected no full text for %s", doc.Name)
+				}
+			}
+			want := []string{"inv_100%.pdf", "Invoice_2025.pdf", "Invoice_2024.pdf"}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+			if len(literal) != 1 || literal[0].Name != "inv_100%.pdf" {
+				t.Errorf("Expected only inv_100%%.pdf, got %+v", literal)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// GetServerState returns the shared state saved under name, empty when nothing is saved
+func (p *PostgresDB) GetServerState(name string) (string, error) {
+	var value string
+	err := p.db.QueryRow(`SELECT value FROM server_state WHERE name = $1`, name).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+// SetServerState saves shared state under name, replacing what was there
+func (p *PostgresDB) SetServerState(name string, value string) error {
+	_, err := p.db.Exec(`INSERT INTO server_state (name, value, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at`, name, value, Now().UTC())
+	return err
+}
@@ -0,0 +1,26 @@
+package database
+
+import "testing"
+
+func TestServerState(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			db := open()
+			defer db.Close()
+			if value, err := db.GetServerState("read_only"); err != nil || value != "" {
+				t.Fatalf("Expected nothing saved yet, got %q, %v", value, err)
+			}
+			for _, value := range []string{"on", "off"} {
+				if err := db.SetServerState("read_only", value); err != nil {
+					t.Fatalf("SetServerState failed: %v", err)
+				}
+				if got, err := db.GetServerState("read_only"); err != nil || got != value {
+					t.Errorf("Expected %q, got %q, %v", value, got, err)
+				}
+			}
+			if value, _ := db.GetServerState("documents_changed"); value != "" {
+				t.Errorf("Expected names to be kept apart, got %q", value)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// maxSlowQueries is how many of the most recent slow queries are kept for /api/admin/slow-queries
+const maxSlowQueries = 100
+
+// maxSlowQueryText is how much of a slow query's SQL is kept; inserts carry whole document texts
+const maxSlowQueryText = 1000
+
+// modulePrefix is stripped from caller names, leaving package.Function
+const modulePrefix = "github.com/drummonds/godocs/"
+
+// SlowQuery is a query that took longer than SLOW_QUERY_MS
+type SlowQuery struct {
+	Query      string    `json:"query"`
+	Operation  string    `json:"operation"`  // SELECT, INSERT, UPDATE, DELETE, ...
+	DurationMs int64     `json:"durationMs"` // how long the query took
+	Caller     string    `json:"caller"`     // the handler or job that ran it, such as engine.(*ServerHandler).SearchDocuments
+	Error      string    `json:"error,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// slowQueryLog is a rolling list of the most recent slow queries, shared by every database in the process
+type slowQueryLog struct {
+	mu      sync.Mutex
+	queries []SlowQuery
+}
+
+var slowQueries slowQueryLog
+
+// add keeps query, dropping the oldest once the list is full
+func (l *slowQueryLog) add(query SlowQuery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.queries) >= maxSlowQueries {
+		l.queries = append(l.queries[:0], l.queries[1:]...)
+	}
+	l.queries = append(l.queries, query)
+}
+
+// SlowQueries returns the recent slow queries, slowest first
+func SlowQueries() []SlowQuery {
+	slowQueries.mu.Lock()
+	queries := append([]SlowQuery(nil), slowQueries.queries...)
+	slowQueries.mu.Unlock()
+	sort.SliceStable(queries, func(i, j int) bool { return queries[i].DurationMs > queries[j].DurationMs })
+	return queries
+}
+
+// slowQueryHook is a Bun query hook that logs and records every query slower than threshold
+type slowQueryHook struct {
+	threshold time.Duration
+}
+
+// BeforeQuery does nothing; Bun times the query itself
+func (h slowQueryHook) BeforeQuery(ctx context.Context, _ *bun.QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery records the query if it took longer than the threshold
+func (h slowQueryHook) AfterQuery(ctx context.Context, event *bun.QueryEvent) {
+	duration := time.Since(event.StartTime)
+	if duration < h.threshold {
+		return
+	}
+	query := SlowQuery{
+		Query:      event.Query,
+		Operation:  event.Operation(),
+		DurationMs: duration.Milliseconds(),
+		Caller:     queryCaller(),
+		At:         Now(),
+	}
+	if len(query.Query) > maxSlowQueryText {
+		query.Query = query.Query[:maxSlowQueryText] + "..."
+	}
+	if event.Err != nil {
+		query.Error = event.Err.Error()
+	}
+	slowQueries.add(query)
+	Logger.Warn("Slow query", "durationMs", query.DurationMs, "caller", query.Caller, "operation", query.Operation, "query", query.Query)
+}
+
+// queryCaller names the function that asked the repository for the query: the first frame on the stack
+// outside this package, Bun and the standard library. Repository methods do not take a context, so the
+// call stack is the only place the handler or job behind a query can be found.
+func queryCaller() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		name := frame.Function
+		if strings.HasPrefix(name, modulePrefix) && !strings.HasPrefix(name, modulePrefix+"database.") {
+			return strings.TrimPrefix(name, modulePrefix)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
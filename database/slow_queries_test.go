@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+func TestSlowQueryHook(t *testing.T) {
+	testRepositories() // sets up the logger
+	slowQueries = slowQueryLog{}
+	t.Cleanup(func() { slowQueries = slowQueryLog{} })
+	hook := slowQueryHook{threshold: 100 * time.Millisecond}
+	run := func(query string, took time.Duration) {
+		hook.AfterQuery(context.Background(), &bun.QueryEvent{Query: query, StartTime: time.Now().Add(-took)})
+	}
+
+	// A fast query, two slow ones and one with a long inserted text run
+	run("SELECT 1", time.Millisecond)
+	run("SELECT * FROM documents WHERE folder = '/bills'", 300*time.Millisecond)
+	run("UPDATE documents SET name = 'a.pdf'", 150*time.Millisecond)
+	run("INSERT INTO documents (full_text) VALUES ('"+strings.Repeat("x", 5000)+"')", 200*time.Millisecond)
+
+	// Only the slow ones are listed, slowest first, with their operation and the SQL cut short
+	queries := SlowQueries()
+	if len(queries) != 3 {
+		t.Fatalf("Expected 3 slow queries, got %+v", queries)
+	}
+	if queries[0].Operation != "SELECT" || queries[0].DurationMs < 300 || queries[1].Operation != "INSERT" || queries[2].Operation != "UPDATE" {
+		t.Errorf("Expected the slowest first, got %+v", queries)
+	}
+	if len(queries[1].Query) > maxSlowQueryText+3 || queries[0].Caller == "" {
+		t.Errorf("Expected the insert cut short and a caller, got %d characters and %q", len(queries[1].Query), queries[0].Caller)
+	}
+
+	// More slow queries run than are kept
+	for i := 0; i < maxSlowQueries; i++ {
+		run(fmt.Sprintf("SELECT %d", i), 100*time.Millisecond)
+	}
+
+	// Only the most recent are kept
+	if queries := SlowQueries(); len(queries) != maxSlowQueries || queries[0].Query == "SELECT * FROM documents WHERE folder = '/bills'" {
+		t.Errorf("Expected the oldest to roll off, got %d starting with %q", len(queries), queries[0].Query)
+	}
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// SmartFolder is a virtual folder defined by a saved query. Its documents are found each time it is
+// listed, so they follow the documents rather than being moved into it.
+type SmartFolder struct {
+	ULID          ulid.ULID `json:"id"`
+	Name          string    `json:"name"`
+	Term          string    `json:"term,omitempty"`          // full-text search term
+	From          string    `json:"from,omitempty"`          // YYYY-MM-DD, documents ingested on or after this day
+	To            string    `json:"to,omitempty"`            // YYYY-MM-DD, documents ingested on or before this day
+	Tag           string    `json:"tag,omitempty"`           // only documents with this tag
+	Correspondent string    `json:"correspondent,omitempty"` // only documents read by this correspondent's extraction template
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// prepareSmartFolder fills in the fields CreateSmartFolder sets on a new smart folder
+func prepareSmartFolder(folder *SmartFolder) {
+	if folder.ULID == (ulid.ULID{}) {
+		folder.ULID = MakeULID()
+	}
+	if folder.CreatedAt.IsZero() {
+		folder.CreatedAt = Now()
+	}
+	folder.CreatedAt = folder.CreatedAt.UTC()
+}
+
+// CreateSmartFolder stores a smart folder, setting its ULID and CreatedAt when missing
+func (p *PostgresDB) CreateSmartFolder(folder *SmartFolder) error {
+	prepareSmartFolder(folder)
+	_, err := p.db.Exec(`INSERT INTO smart_folders (ulid, name, term, date_from, date_to, tag, correspondent, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		folder.ULID.String(), folder.Name, folder.Term, folder.From, folder.To, folder.Tag, folder.Correspondent, folder.CreatedAt)
+	return err
+}
+
+const smartFolderColumns = `ulid, name, term, date_from, date_to, tag, correspondent, created_at`
+
+// scanSmartFolder reads a row of smartFolderColumns
+func scanSmartFolder(row interface{ Scan(...any) error }) (*SmartFolder, error) {
+	var folder SmartFolder
+	var ulidStr string
+	if err := row.Scan(&ulidStr, &folder.Name, &folder.Term, &folder.From, &folder.To, &folder.Tag, &folder.Correspondent, &folder.CreatedAt); err != nil {
+		return nil, err
+	}
+	parsed, err := ulid.Parse(ulidStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ULID: %w", err)
+	}
+	folder.ULID = parsed
+	return &folder, nil
+}
+
+// GetSmartFolder returns a smart folder by ULID, or sql.ErrNoRows
+func (p *PostgresDB) GetSmartFolder(ulidStr string) (*SmartFolder, error) {
+	return scanSmartFolder(p.db.QueryRow(`SELECT `+smartFolderColumns+` FROM smart_folders WHERE ulid = $1`, ulidStr))
+}
+
+// ListSmartFolders returns every smart folder by name
+func (p *PostgresDB) ListSmartFolders() ([]SmartFolder, error) {
+	rows, err := p.db.Query(`SELECT ` + smartFolderColumns + ` FROM smart_folders ORDER BY name, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var folders []SmartFolder
+	for rows.Next() {
+		folder, err := scanSmartFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, *folder)
+	}
+	return folders, rows.Err()
+}
+
+// DeleteSmartFolder removes a smart folder; the documents it showed are not touched
+func (p *PostgresDB) DeleteSmartFolder(ulidStr string) error {
+	result, err := p.db.Exec(`DELETE FROM smart_folders WHERE ulid = $1`, ulidStr)
+	if err != nil {
+		return err
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package database
+
+// SpreadsheetDetails is the size of a spreadsheet document, recorded when it is ingested
+type SpreadsheetDetails struct {
+	DocumentULID string `json:"documentId"`
+	Sheets       int    `json:"sheets"`
+	Rows         int    `json:"rows"`    // non-empty rows across all sheets
+	Columns      int    `json:"columns"` // cells in the widest row
+}
+
+const spreadsheetDetailsColumns = `document_ulid, sheets, row_count, column_count`
+
+// SaveSpreadsheetDetails records a spreadsheet document's size, replacing what was recorded before
+func (p *PostgresDB) SaveSpreadsheetDetails(details *SpreadsheetDetails) error {
+	_, err := p.db.Exec(`INSERT INTO document_spreadsheets (`+spreadsheetDetailsColumns+`) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (document_ulid) DO UPDATE SET sheets = EXCLUDED.sheets, row_count = EXCLUDED.row_count, column_count = EXCLUDED.column_count`,
+		details.DocumentULID, details.Sheets, details.Rows, details.Columns)
+	return err
+}
+
+// GetSpreadsheetDetails returns a spreadsheet document's size, or sql.ErrNoRows when none was recorded
+func (p *PostgresDB) GetSpreadsheetDetails(documentULID string) (*SpreadsheetDetails, error) {
+	var details SpreadsheetDetails
+	err := p.db.QueryRow(`SELECT `+spreadsheetDetailsColumns+` FROM document_spreadsheets WHERE document_ulid = $1`, documentULID).
+		Scan(&details.DocumentULID, &details.Sheets, &details.Rows, &details.Columns)
+	if err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
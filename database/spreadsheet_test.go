@@ -0,0 +1,35 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestSpreadsheetDetails(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// No details recorded for a document
+			db := open()
+			defer db.Close()
+			const id = "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+			if _, err := db.GetSpreadsheetDetails(id); !errors.Is(err, sql.ErrNoRows) {
+				t.Fatalf("Expected sql.ErrNoRows before any are saved, got %v", err)
+			}
+
+			// Details are saved, then saved again after the spreadsheet grew
+			if err := db.SaveSpreadsheetDetails(&SpreadsheetDetails{DocumentULID: id, Sheets: 1, Rows: 10, Columns: 3}); err != nil {
+				t.Fatalf("SaveSpreadsheetDetails failed: %v", err)
+			}
+			if err := db.SaveSpreadsheetDetails(&SpreadsheetDetails{DocumentULID: id, Sheets: 2, Rows: 25, Columns: 4}); err != nil {
+				t.Fatalf("SaveSpreadsheetDetails failed: %v", err)
+			}
+
+			// The latest details are returned
+			details, err := db.GetSpreadsheetDetails(id)
+			if err != nil || *details != (SpreadsheetDetails{DocumentULID: id, Sheets: 2, Rows: 25, Columns: 4}) {
+				t.Errorf("Expected the replaced details, got %+v, %v", details, err)
+			}
+		})
+	}
+}
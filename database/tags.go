@@ -0,0 +1,145 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Tag is a label on documents, such as "tax" or "receipts", with how many documents carry it
+type Tag struct {
+	Name          string `json:"name"`
+	DocumentCount int    `json:"documentCount"`
+}
+
+// AddTag tags a document, creating the tag if it is new. Tagging a document twice does nothing.
+func (p *PostgresDB) AddTag(documentULID string, tag string) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO tags (name, created_at) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`, tag, Now().UTC()); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO document_tags (document_ulid, tag, created_at) VALUES ($1, $2, $3) ON CONFLICT (document_ulid, tag) DO NOTHING`,
+		documentULID, tag, Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveTag takes a tag off a document, returning sql.ErrNoRows if it did not carry it. The tag itself
+// goes once no document carries it.
+func (p *PostgresDB) RemoveTag(documentULID string, tag string) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM document_tags WHERE document_ulid = $1 AND tag = $2`, documentULID, tag)
+	if err != nil {
+		return err
+	}
+	if removed, _ := result.RowsAffected(); removed == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.Exec(`DELETE FROM tags WHERE name = $1 AND NOT EXISTS (SELECT 1 FROM document_tags WHERE tag = $1)`, tag); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetDocumentTags returns the tags on a document in name order
+func (p *PostgresDB) GetDocumentTags(documentULID string) ([]string, error) {
+	rows, err := p.db.Query(`SELECT tag FROM document_tags WHERE document_ulid = $1 ORDER BY tag`, documentULID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetDocumentsByTag returns the documents carrying a tag in name order, without their text
+func (p *PostgresDB) GetDocumentsByTag(tag string) ([]Document, error) {
+	rows, err := p.db.Query(`SELECT d.id, d.name, d.path, d.ingress_time, d.folder, d.hash, d.ulid, d.document_type, d.mime_type, d.size, d.page_count, d.ocr_status, '' AS full_text, d.url
+		FROM document_tags dt JOIN documents d ON d.ulid = dt.document_ulid
+		WHERE dt.tag = $1 ORDER BY d.name, d.id`, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDocuments(rows)
+}
+
+// ListTags returns every tag in name order with how many documents carry it
+func (p *PostgresDB) ListTags() ([]Tag, error) {
+	rows, err := p.db.Query(`SELECT tag, COUNT(*) FROM document_tags GROUP BY tag ORDER BY tag`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Name, &tag.DocumentCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// MergeTag moves every document carrying from onto into, creating into if it is new, so renaming a tag
+// and merging it into another are the same change. Smart folders filtering by from follow it, and event
+// is written to the audit log in the same transaction. It returns how many documents carried from, or
+// sql.ErrNoRows when none did.
+func (p *PostgresDB) MergeTag(from string, into string, event *AuditEvent) (int, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := Now().UTC()
+	if _, err := tx.Exec(`INSERT INTO tags (name, created_at) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`, into, now); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`INSERT INTO document_tags (document_ulid, tag, created_at)
+		SELECT document_ulid, $2, created_at FROM document_tags WHERE tag = $1
+		ON CONFLICT (document_ulid, tag) DO NOTHING`, from, into); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(`DELETE FROM document_tags WHERE tag = $1`, from)
+	if err != nil {
+		return 0, err
+	}
+	moved, _ := result.RowsAffected()
+	if moved == 0 {
+		return 0, sql.ErrNoRows
+	}
+	if _, err := tx.Exec(`DELETE FROM tags WHERE name = $1`, from); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`UPDATE smart_folders SET tag = $2 WHERE tag = $1`, from, into); err != nil {
+		return 0, err
+	}
+	prepareAuditEvent(event)
+	if _, err := tx.Exec(`INSERT INTO audit_events (`+auditEventColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		event.ID, event.Action, event.Target, event.Name, event.Detail, event.User, event.CreatedAt); err != nil {
+		return 0, err
+	}
+	return int(moved), tx.Commit()
+}
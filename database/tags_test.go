@@ -0,0 +1,143 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestTags(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// Three documents, two tagged tax (one of them twice) and one tagged receipts
+			db := open()
+			defer db.Close()
+			var docs []*Document
+			for i, docName := range []string{"c.pdf", "a.pdf", "b.pdf"} {
+				doc := &Document{
+					Name:         docName,
+					Path:         "/docs/" + docName,
+					IngressTime:  time.Date(2024, 1, 1+i, 0, 0, 0, 0, time.UTC),
+					Folder:       "/docs",
+					Hash:         fmt.Sprintf("hash%d", i),
+					ULID:         ulid.Make(),
+					DocumentType: ".pdf",
+					FullText:     "some text",
+				}
+				if err := db.SaveDocument(doc); err != nil {
+					t.Fatalf("SaveDocument failed: %v", err)
+				}
+				docs = append(docs, doc)
+			}
+			for _, tagging := range []struct {
+				doc *Document
+				tag string
+			}{{docs[0], "tax"}, {docs[1], "tax"}, {docs[1], "tax"}, {docs[1], "receipts"}} {
+				if err := db.AddTag(tagging.doc.ULID.String(), tagging.tag); err != nil {
+					t.Fatalf("AddTag failed: %v", err)
+				}
+			}
+
+			// The tags and their documents are read back
+			tags, err := db.ListTags()
+			tagged, _ := db.GetDocumentsByTag("tax")
+			onA, _ := db.GetDocumentTags(docs[1].ULID.String())
+
+			// Each tag is counted once per document and documents come in name order without their text
+			if err != nil || len(tags) != 2 || tags[0] != (Tag{Name: "receipts", DocumentCount: 1}) || tags[1] != (Tag{Name: "tax", DocumentCount: 2}) {
+				t.Errorf("Expected receipts 1 and tax 2, got %+v, %v", tags, err)
+			}
+			if len(tagged) != 2 || tagged[0].Name != "a.pdf" || tagged[1].Name != "c.pdf" || tagged[0].FullText != "" {
+				t.Errorf("Expected a.pdf then c.pdf, got %+v", tagged)
+			}
+			if len(onA) != 2 || onA[0] != "receipts" || onA[1] != "tax" {
+				t.Errorf("Expected receipts and tax on a.pdf, got %v", onA)
+			}
+			if none, err := db.GetDocumentTags(docs[2].ULID.String()); err != nil || len(none) != 0 {
+				t.Errorf("Expected no tags on b.pdf, got %v, %v", none, err)
+			}
+
+			// Removing the only receipts tag drops the tag, and removing it again is sql.ErrNoRows
+			if err := db.RemoveTag(docs[1].ULID.String(), "receipts"); err != nil {
+				t.Fatalf("RemoveTag failed: %v", err)
+			}
+			if err := db.RemoveTag(docs[1].ULID.String(), "receipts"); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows, got %v", err)
+			}
+			if tags, _ := db.ListTags(); len(tags) != 1 || tags[0].Name != "tax" {
+				t.Errorf("Expected only tax left, got %+v", tags)
+			}
+
+			// A deleted document loses its tags
+			if err := db.DeleteDocument(docs[0].ULID.String()); err != nil {
+				t.Fatalf("DeleteDocument failed: %v", err)
+			}
+			if tags, _ := db.ListTags(); len(tags) != 1 || tags[0].DocumentCount != 1 {
+				t.Errorf("Expected tax on one document, got %+v", tags)
+			}
+		})
+	}
+}
+
+func TestMergeTag(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			db := open()
+			defer db.Close()
+			var docs []*Document
+			for i, docName := range []string{"a.pdf", "b.pdf"} {
+				doc := &Document{Name: docName, Path: "/docs/" + docName, Folder: "/docs", Hash: fmt.Sprintf("hash%d", i), ULID: ulid.Make(), DocumentType: ".pdf"}
+				if err := db.SaveDocument(doc); err != nil {
+					t.Fatalf("SaveDocument failed: %v", err)
+				}
+				docs = append(docs, doc)
+			}
+			// a.pdf carries both taxes and tax, b.pdf only taxes
+			for _, tagging := range [][2]string{{docs[0].ULID.String(), "taxes"}, {docs[0].ULID.String(), "tax"}, {docs[1].ULID.String(), "taxes"}} {
+				if err := db.AddTag(tagging[0], tagging[1]); err != nil {
+					t.Fatalf("AddTag failed: %v", err)
+				}
+			}
+			folder := &SmartFolder{Name: "Taxes", Tag: "taxes"}
+			if err := db.CreateSmartFolder(folder); err != nil {
+				t.Fatalf("CreateSmartFolder failed: %v", err)
+			}
+
+			t.Run("merge into an existing tag", func(t *testing.T) {
+				moved, err := db.MergeTag("taxes", "tax", &AuditEvent{Action: AuditTagMerged, Target: "taxes", Detail: "tax", User: "alice"})
+				if err != nil || moved != 2 {
+					t.Fatalf("Expected 2 documents moved, got %d, %v", moved, err)
+				}
+				if tags, _ := db.ListTags(); len(tags) != 1 || tags[0] != (Tag{Name: "tax", DocumentCount: 2}) {
+					t.Errorf("Expected only tax on both documents, got %+v", tags)
+				}
+				if found, _ := db.GetSmartFolder(folder.ULID.String()); found == nil || found.Tag != "tax" {
+					t.Errorf("Expected the smart folder to follow the merge, got %+v", found)
+				}
+				if events, _ := db.ListAuditEvents(nil, 10); len(events) != 1 || events[0].Action != AuditTagMerged || events[0].User != "alice" {
+					t.Errorf("Expected the merge in the audit log, got %+v", events)
+				}
+			})
+			t.Run("rename to a new tag", func(t *testing.T) {
+				if moved, err := db.MergeTag("tax", "tax returns", &AuditEvent{Action: AuditTagRenamed, Target: "tax", Detail: "tax returns"}); err != nil || moved != 2 {
+					t.Fatalf("Expected 2 documents renamed, got %d, %v", moved, err)
+				}
+				if onA, _ := db.GetDocumentTags(docs[0].ULID.String()); len(onA) != 1 || onA[0] != "tax returns" {
+					t.Errorf("Expected a.pdf tagged tax returns, got %v", onA)
+				}
+			})
+			t.Run("unknown tag", func(t *testing.T) {
+				if _, err := db.MergeTag("missing", "tax", &AuditEvent{Action: AuditTagMerged}); !errors.Is(err, sql.ErrNoRows) {
+					t.Errorf("Expected sql.ErrNoRows, got %v", err)
+				}
+				if events, _ := db.ListAuditEvents(nil, 10); len(events) != 2 {
+					t.Errorf("Expected nothing audited for a failed merge, got %d entries", len(events))
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DocumentEvent is one processing stage a document went through, such as received, OCR or indexed
+type DocumentEvent struct {
+	ID           int       `json:"-"`
+	DocumentULID string    `json:"-"`
+	Stage        string    `json:"stage"`
+	Detail       string    `json:"detail,omitempty"` // e.g. the job or how the text was found
+	At           time.Time `json:"at"`
+}
+
+// RecordDocumentEvents stores processing stages in one statement
+func (p *PostgresDB) RecordDocumentEvents(events []DocumentEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(events))
+	args := make([]interface{}, 0, len(events)*4)
+	for i, event := range events {
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d)", i*4+1, i*4+2, i*4+3, i*4+4))
+		args = append(args, event.DocumentULID, event.Stage, event.Detail, event.At.UTC())
+	}
+	_, err := p.db.Exec(`INSERT INTO document_events (document_ulid, stage, detail, at) VALUES `+strings.Join(values, ", "), args...)
+	return err
+}
+
+// GetDocumentEvents returns a document's processing stages, oldest first
+func (p *PostgresDB) GetDocumentEvents(ulid string) ([]DocumentEvent, error) {
+	rows, err := p.db.Query(`SELECT id, document_ulid, stage, detail, at FROM document_events
+		WHERE document_ulid = $1 ORDER BY at, id`, ulid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []DocumentEvent
+	for rows.Next() {
+		var event DocumentEvent
+		if err := rows.Scan(&event.ID, &event.DocumentULID, &event.Stage, &event.Detail, &event.At); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
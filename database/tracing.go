@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/uptrace/bun"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the instrumentation behind query spans
+const tracerName = "github.com/drummonds/godocs/database"
+
+// traceQueryHook is a Bun query hook that gives every query a span, for OTLP_ENDPOINT. Repository methods
+// do not take a context, so a query's span starts a trace of its own; its code.function attribute names
+// the handler or job that ran it, as the slow query log does.
+type traceQueryHook struct {
+	system string // sqlite or postgres
+}
+
+// BeforeQuery starts the query's span
+func (h traceQueryHook) BeforeQuery(ctx context.Context, event *bun.QueryEvent) context.Context {
+	query := event.Query
+	if len(query) > maxSlowQueryText {
+		query = query[:maxSlowQueryText] + "..."
+	}
+	ctx, _ = otel.Tracer(tracerName).Start(ctx, event.Operation(), trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(event.StartTime), trace.WithAttributes(
+			attribute.String("db.system", h.system),
+			attribute.String("db.operation.name", event.Operation()),
+			attribute.String("db.query.text", query),
+			attribute.String("code.function", queryCaller()),
+		))
+	return ctx
+}
+
+// AfterQuery ends the query's span, marking it failed if the query was; finding no rows is not a failure
+func (h traceQueryHook) AfterQuery(ctx context.Context, event *bun.QueryEvent) {
+	span := trace.SpanFromContext(ctx)
+	if event.Err != nil && !errors.Is(event.Err, sql.ErrNoRows) {
+		span.RecordError(event.Err)
+		span.SetStatus(codes.Error, event.Err.Error())
+	}
+	span.End()
+}
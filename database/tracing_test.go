@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestTraceQueryHook(t *testing.T) {
+	// A tracer provider that keeps every span
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	hook := traceQueryHook{system: "sqlite"}
+	run := func(query string, err error) {
+		event := &bun.QueryEvent{Query: query, StartTime: time.Now().Add(-20 * time.Millisecond), Err: err}
+		hook.AfterQuery(hook.BeforeQuery(context.Background(), event), event)
+	}
+
+	// A query that finds nothing and one that fails run
+	run("SELECT * FROM documents WHERE ulid = 'x'", sql.ErrNoRows)
+	run("UPDATE documents SET name = 'a.pdf'", errors.New("database is locked"))
+
+	// Each has a span named by its operation, from when the query started, with its SQL and caller
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "SELECT" || spans[1].Name() != "UPDATE" {
+		t.Fatalf("Expected SELECT and UPDATE spans, got %d", len(spans))
+	}
+	if took := spans[0].EndTime().Sub(spans[0].StartTime()); took < 20*time.Millisecond {
+		t.Errorf("Expected the span to start with the query, got %s", took)
+	}
+	attributes := make(map[string]string)
+	for _, kv := range spans[0].Attributes() {
+		attributes[string(kv.Key)] = kv.Value.AsString()
+	}
+	if attributes["db.system"] != "sqlite" || attributes["db.query.text"] != "SELECT * FROM documents WHERE ulid = 'x'" || attributes["code.function"] == "" {
+		t.Errorf("Expected the system, query and caller, got %v", attributes)
+	}
+
+	// Finding no rows is not a failure, but the locked database is
+	if spans[0].Status().Code == codes.Error || spans[1].Status().Code != codes.Error {
+		t.Errorf("Expected only the update failed, got %v and %v", spans[0].Status(), spans[1].Status())
+	}
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrUsernameTaken is returned by CreateUser for a username another account has
+var ErrUsernameTaken = errors.New("username is already taken")
+
+// User is an account that may sign in when WEB_UI_AUTH is on
+type User struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`    // bcrypt
+	Role         string    `json:"role"` // RoleAdmin, RoleEditor or RoleViewer
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// Account roles. Administrators see and change everything and manage accounts; editors and viewers
+// see the folders their folder permissions allow, and viewers can never change them.
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// ValidRole reports whether role is one of the account roles
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleEditor || role == RoleViewer
+}
+
+// Session is a signed-in user. Only the SHA-256 of its token is stored, so the table cannot be used to
+// sign in.
+type Session struct {
+	TokenHash string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// prepareUser fills in the fields CreateUser sets on a new account
+func prepareUser(user *User) {
+	if user.ID == "" {
+		user.ID = MakeULID().String()
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = Now()
+	}
+	if user.Role == "" {
+		user.Role = RoleEditor
+	}
+	user.CreatedAt = user.CreatedAt.UTC()
+}
+
+const userColumns = `id, username, password_hash, role, created_at`
+
+const sessionColumns = `token_hash, user_id, created_at, expires_at`
+
+// CreateUser adds an account, or returns ErrUsernameTaken
+func (p *PostgresDB) CreateUser(user *User) error {
+	if _, err := p.GetUserByUsername(user.Username); err == nil {
+		return ErrUsernameTaken
+	}
+	prepareUser(user)
+	_, err := p.db.Exec(`INSERT INTO users (`+userColumns+`) VALUES ($1, $2, $3, $4, $5)`,
+		user.ID, user.Username, user.PasswordHash, user.Role, user.CreatedAt)
+	return err
+}
+
+// scanUser reads a row of userColumns
+func scanUser(row interface{ Scan(...any) error }) (*User, error) {
+	var user User
+	if err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUser returns an account by ID, or sql.ErrNoRows
+func (p *PostgresDB) GetUser(id string) (*User, error) {
+	return scanUser(p.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = $1`, id))
+}
+
+// GetUserByUsername returns an account by username, or sql.ErrNoRows
+func (p *PostgresDB) GetUserByUsername(username string) (*User, error) {
+	return scanUser(p.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE username = $1`, username))
+}
+
+// ListUsers returns every account in username order
+func (p *PostgresDB) ListUsers() ([]User, error) {
+	rows, err := p.db.Query(`SELECT ` + userColumns + ` FROM users ORDER BY username`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// UpdateUserPassword replaces an account's password hash, returning sql.ErrNoRows for an unknown ID
+func (p *PostgresDB) UpdateUserPassword(id string, passwordHash string) error {
+	result, err := p.db.Exec(`UPDATE users SET password_hash = $1 WHERE id = $2`, passwordHash, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateUserRole changes an account's role, returning sql.ErrNoRows for an unknown ID
+func (p *PostgresDB) UpdateUserRole(id string, role string) error {
+	result, err := p.db.Exec(`UPDATE users SET role = $1 WHERE id = $2`, role, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteUser removes an account with its sessions and folder permissions, returning sql.ErrNoRows for
+// an unknown ID
+func (p *PostgresDB) DeleteUser(id string) error {
+	if err := p.DeleteUserSessions(id); err != nil {
+		return err
+	}
+	if _, err := p.db.Exec(`DELETE FROM folder_permissions WHERE user_id = $1`, id); err != nil {
+		return err
+	}
+	result, err := p.db.Exec(`DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateSession records a new session
+func (p *PostgresDB) CreateSession(session *Session) error {
+	_, err := p.db.Exec(`INSERT INTO sessions (`+sessionColumns+`) VALUES ($1, $2, $3, $4)`,
+		session.TokenHash, session.UserID, session.CreatedAt.UTC(), session.ExpiresAt.UTC())
+	return err
+}
+
+// GetSession returns the session with the token hash, expired or not, or sql.ErrNoRows
+func (p *PostgresDB) GetSession(tokenHash string) (*Session, error) {
+	var session Session
+	err := p.db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE token_hash = $1`, tokenHash).
+		Scan(&session.TokenHash, &session.UserID, &session.CreatedAt, &session.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// DeleteSession ends a session; ending one that does not exist is not an error
+func (p *PostgresDB) DeleteSession(tokenHash string) error {
+	_, err := p.db.Exec(`DELETE FROM sessions WHERE token_hash = $1`, tokenHash)
+	return err
+}
+
+// DeleteUserSessions ends every session of an account
+func (p *PostgresDB) DeleteUserSessions(userID string) error {
+	_, err := p.db.Exec(`DELETE FROM sessions WHERE user_id = $1`, userID)
+	return err
+}
+
+// DeleteExpiredSessions removes the sessions that expired before now
+func (p *PostgresDB) DeleteExpiredSessions(now time.Time) error {
+	_, err := p.db.Exec(`DELETE FROM sessions WHERE expires_at < $1`, now.UTC())
+	return err
+}
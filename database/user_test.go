@@ -0,0 +1,93 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUsersAndSessions(t *testing.T) {
+	for name, open := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			// Two accounts
+			db := open()
+			defer db.Close()
+			now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+			defer SetClock(NewFixedClock(now, time.Second))()
+			bob := User{Username: "bob", PasswordHash: "hash-b"}
+			alice := User{Username: "alice", PasswordHash: "hash-a"}
+			for _, user := range []*User{&bob, &alice} {
+				if err := db.CreateUser(user); err != nil {
+					t.Fatalf("CreateUser failed: %v", err)
+				}
+			}
+
+			// Usernames are unique, and accounts are found by ID or username and listed in name order
+			if err := db.CreateUser(&User{Username: "bob", PasswordHash: "x"}); !errors.Is(err, ErrUsernameTaken) {
+				t.Errorf("Expected ErrUsernameTaken, got %v", err)
+			}
+			if found, err := db.GetUserByUsername("alice"); err != nil || found.ID != alice.ID || found.PasswordHash != "hash-a" {
+				t.Errorf("Expected alice, got %+v, %v", found, err)
+			}
+			if _, err := db.GetUserByUsername("carol"); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows for an unknown user, got %v", err)
+			}
+			users, err := db.ListUsers()
+			if err != nil || len(users) != 2 || users[0].Username != "alice" || users[1].Username != "bob" {
+				t.Errorf("Expected alice then bob, got %+v, %v", users, err)
+			}
+
+			// The password changes and sessions are started, one already expired
+			if err := db.UpdateUserPassword(bob.ID, "hash-b2"); err != nil {
+				t.Fatalf("UpdateUserPassword failed: %v", err)
+			}
+			sessions := []Session{
+				{TokenHash: "live", UserID: bob.ID, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+				{TokenHash: "stale", UserID: bob.ID, CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)},
+				{TokenHash: "alice", UserID: alice.ID, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+			}
+			for i := range sessions {
+				if err := db.CreateSession(&sessions[i]); err != nil {
+					t.Fatalf("CreateSession failed: %v", err)
+				}
+			}
+
+			// The new hash is stored and sessions are found by token hash
+			if found, _ := db.GetUser(bob.ID); found == nil || found.PasswordHash != "hash-b2" {
+				t.Errorf("Expected the new password hash, got %+v", found)
+			}
+			if session, err := db.GetSession("live"); err != nil || session.UserID != bob.ID || !session.ExpiresAt.Equal(now.Add(time.Hour)) {
+				t.Errorf("Expected bob's session, got %+v, %v", session, err)
+			}
+
+			// Expired sessions are removed, then one is ended and bob is deleted
+			if err := db.DeleteExpiredSessions(now); err != nil {
+				t.Fatalf("DeleteExpiredSessions failed: %v", err)
+			}
+			if _, err := db.GetSession("stale"); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected the expired session to be gone, got %v", err)
+			}
+			if err := db.DeleteSession("alice"); err != nil {
+				t.Fatalf("DeleteSession failed: %v", err)
+			}
+			if err := db.DeleteUser(bob.ID); err != nil {
+				t.Fatalf("DeleteUser failed: %v", err)
+			}
+
+			// Bob's sessions went with the account, and unknown IDs are reported
+			if _, err := db.GetSession("live"); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected bob's session to be gone, got %v", err)
+			}
+			if _, err := db.GetSession("alice"); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected the ended session to be gone, got %v", err)
+			}
+			if err := db.DeleteUser(bob.ID); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows deleting bob again, got %v", err)
+			}
+			if err := db.UpdateUserPassword(bob.ID, "x"); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows for an unknown user, got %v", err)
+			}
+		})
+	}
+}
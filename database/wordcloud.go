@@ -95,28 +95,22 @@ func (p *PostgresDB) UpdateWordFrequencies(docID string) error {
 
 	// Tokenize the document's full text and name
 	tokenizer := NewWordTokenizer()
-	combinedText := doc.FullText + " " + doc.Name
-	frequencies := tokenizer.TokenizeAndCount(combinedText)
+	return p.AddWordFrequencies(tokenizer.TokenizeAndCount(doc.FullText + " " + doc.Name))
+}
 
-	// Update word frequencies in database
+// AddWordFrequencies adds pre-aggregated word counts in batched upserts inside one transaction
+func (p *PostgresDB) AddWordFrequencies(counts map[string]int) error {
+	if len(counts) == 0 {
+		return nil
+	}
 	tx, err := p.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	for word, count := range frequencies {
-		query := `
-			INSERT INTO word_frequencies (word, frequency, last_updated)
-			VALUES ($1, $2, CURRENT_TIMESTAMP)
-			ON CONFLICT (word) DO UPDATE SET
-				frequency = word_frequencies.frequency + EXCLUDED.frequency,
-				last_updated = CURRENT_TIMESTAMP
-		`
-		_, err := tx.Exec(query, word, count)
-		if err != nil {
-			return fmt.Errorf("failed to update word frequency: %w", err)
-		}
+	if err := addWordFrequencies(tx, counts); err != nil {
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
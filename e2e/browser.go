@@ -0,0 +1,117 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// renderTimeout is how long a page has to load and run the WASM app before a wait gives up
+const renderTimeout = 30 * time.Second
+
+// browsers are the Chrome builds chromedp can drive, in the order they are looked for. Firefox is left
+// out: its headless mode does not speak the DevTools protocol chromedp needs.
+var browsers = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"}
+
+// FindBrowser returns the path of a Chrome or Chromium to test with: E2E_BROWSER when set, otherwise
+// the first of the usual names on PATH
+func FindBrowser() (string, bool) {
+	if path := os.Getenv("E2E_BROWSER"); path != "" {
+		return path, true
+	}
+	for _, name := range browsers {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// Browser is a headless browser tab pointed at a test server
+type Browser struct {
+	t      testing.TB
+	ctx    context.Context
+	server *Server
+}
+
+// NewBrowser opens a headless browser tab on srv, skipping the test in -short mode or when there is no
+// browser to drive. The browser closes when the test ends.
+func NewBrowser(t testing.TB, srv *Server) *Browser {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping browser test in short mode")
+	}
+	path, ok := FindBrowser()
+	if !ok {
+		t.Skip("No Chrome or Chromium found; set E2E_BROWSER to run the browser tests")
+	}
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.ExecPath(path),
+		chromedp.Headless,
+		chromedp.DisableGPU,
+		chromedp.NoSandbox,
+		chromedp.Flag("disable-dev-shm-usage", true),
+	)
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+	ctx, cancelTab := chromedp.NewContext(allocCtx)
+	t.Cleanup(func() {
+		cancelTab()
+		cancelAlloc()
+	})
+	if err := chromedp.Run(ctx); err != nil {
+		t.Skipf("Unable to start %s: %v", path, err)
+	}
+	return &Browser{t: t, ctx: ctx, server: srv}
+}
+
+// run runs chromedp actions in the tab, giving up after renderTimeout
+func (b *Browser) run(actions ...chromedp.Action) error {
+	ctx, cancel := context.WithTimeout(b.ctx, renderTimeout)
+	defer cancel()
+	return chromedp.Run(ctx, actions...)
+}
+
+// Navigate loads a URL, or a path on the test server, and waits for selector to appear
+func (b *Browser) Navigate(target, selector string) error {
+	if len(target) > 0 && target[0] == '/' {
+		target = b.server.URL + target
+	}
+	return b.run(
+		chromedp.Navigate(target),
+		chromedp.WaitVisible(selector, chromedp.ByQuery),
+	)
+}
+
+// Text returns the text of the first element matching selector
+func (b *Browser) Text(selector string) (string, error) {
+	var text string
+	err := b.run(chromedp.Text(selector, &text, chromedp.ByQuery))
+	return text, err
+}
+
+// Texts returns the text of every element matching selector, empty when there are none
+func (b *Browser) Texts(selector string) ([]string, error) {
+	var texts []string
+	err := b.run(chromedp.Evaluate(
+		`Array.from(document.querySelectorAll(`+jsString(selector)+`), el => el.textContent.trim())`, &texts))
+	return texts, err
+}
+
+// Attributes returns attribute name of every element matching selector, empty when there are none
+func (b *Browser) Attributes(selector, name string) ([]string, error) {
+	var values []string
+	err := b.run(chromedp.Evaluate(
+		`Array.from(document.querySelectorAll(`+jsString(selector)+`), el => el.getAttribute(`+jsString(name)+`) || "")`, &values))
+	return values, err
+}
+
+// jsString quotes s for use in a JavaScript expression
+func jsString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
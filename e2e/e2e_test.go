@@ -0,0 +1,127 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+// searchAPI returns the names /api/search finds for term
+func searchAPI(t *testing.T, srv *Server, term string) []string {
+	t.Helper()
+	resp, err := http.Get(srv.URL + "/api/search?term=" + url.QueryEscape(term))
+	if err != nil {
+		t.Fatalf("Search for %s failed: %v", term, err)
+	}
+	defer resp.Body.Close()
+	var result dto.FileSystem
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Search for %s answered %s: %v", term, resp.Status, err)
+	}
+	var names []string
+	for _, node := range result.FileSystem {
+		if !node.IsDir {
+			names = append(names, node.Name)
+		}
+	}
+	return names
+}
+
+func TestStartServerUploadsAndSeeds(t *testing.T) {
+	// A server started with one uploaded document and twenty seeded ones
+	srv := StartServer(t, Options{
+		Documents: map[string]string{"bills/gas.txt": "Quarterly gas bill from Northern Energy"},
+		Seed:      20,
+	})
+
+	// The documents are listed
+	documents, err := srv.Repository().GetNewestDocuments(100)
+
+	// All of them are there and the upload can be searched for
+	if err != nil || len(documents) != 21 {
+		t.Fatalf("Expected 21 documents, got %d: %v", len(documents), err)
+	}
+	if names := searchAPI(t, srv, "Northern"); len(names) != 1 || names[0] != "gas.txt" {
+		t.Errorf("Expected the search to find gas.txt, got %v", names)
+	}
+}
+
+func TestServerDeleteRemovesDocument(t *testing.T) {
+	// An uploaded document
+	srv := StartServer(t, Options{})
+	document := srv.Upload(t, "letters", "council.txt", "Council tax reminder")
+
+	// It is deleted
+	srv.Delete(t, document)
+
+	// Search no longer finds it
+	if names := searchAPI(t, srv, "Council"); len(names) != 0 {
+		t.Errorf("Expected nothing after the delete, got %v", names)
+	}
+}
+
+func TestEveryRouteRenders(t *testing.T) {
+	// A browser on a server with some documents
+	srv := StartServer(t, Options{Seed: 10})
+	browser := NewBrowser(t, srv)
+
+	for _, route := range Routes {
+		// The page is opened
+		err := browser.Page(route.Path).Open()
+
+		// The WASM app renders its component
+		if err != nil {
+			t.Errorf("%s did not render %s: %v", route.Path, route.Root, err)
+		}
+	}
+}
+
+func TestUploadSearchViewDelete(t *testing.T) {
+	// A browser on an empty server
+	srv := StartServer(t, Options{})
+	browser := NewBrowser(t, srv)
+
+	// A document is uploaded
+	document := srv.Upload(t, "insurance", "renewal.txt", "Home insurance renewal notice for Larkspur Cottage")
+
+	// The home page lists it
+	home := browser.Home()
+	if err := home.Open(); err != nil {
+		t.Fatalf("Home page did not render: %v", err)
+	}
+	if names, err := home.DocumentNames(); err != nil || len(names) != 1 || names[0] != "renewal.txt" {
+		t.Fatalf("Expected renewal.txt on the home page, got %v (%v)", names, err)
+	}
+
+	// It is searched for
+	search := browser.Search()
+	results, err := search.Search("Larkspur")
+
+	// It is the one result
+	if err != nil || len(results) != 1 || results[0].Name != "renewal.txt" {
+		t.Fatalf("Expected renewal.txt in the results, got %v (%v)", results, err)
+	}
+
+	// The result is opened
+	text, err := search.View(results[0])
+
+	// The document's text is shown
+	if err != nil || !strings.Contains(text, "Larkspur Cottage") {
+		t.Fatalf("Expected the document text, got %q (%v)", text, err)
+	}
+
+	// It is deleted
+	srv.Delete(t, document)
+
+	// Searching again finds nothing
+	if results, err := search.Search("Larkspur"); err != nil || len(results) != 0 {
+		t.Errorf("Expected no results after the delete, got %v (%v)", results, err)
+	}
+}
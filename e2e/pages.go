@@ -0,0 +1,148 @@
+package e2e
+
+import (
+	"net/url"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Route is a page of the web UI and the element its page component renders once the WASM app is running
+type Route struct {
+	Path string
+	Root string // CSS selector for the page component's outer element
+}
+
+// Routes are every page of the web UI, as routed in webapp.App
+var Routes = []Route{
+	{"/", ".home-page"},
+	{"/browse", ".browse-page"},
+	{"/ingest", ".ingest-page"},
+	{"/clean", ".clean-page"},
+	{"/search", ".search-page"},
+	{"/wordcloud", ".wordcloud-page"},
+	{"/jobs", ".jobs-page"},
+	{"/stats", ".stats-page"},
+	{"/about", ".about-page"},
+	{"/setup", ".setup-page"},
+	{"/collection", ".collection-page"},
+	{"/scan", ".scan-page"},
+	{"/capture", ".capture-page"},
+	{"/login", ".login-page"},
+}
+
+// Page is one route of the web UI in a browser
+type Page struct {
+	Route
+	b *Browser
+}
+
+// Page returns the page for path, which must be one of Routes
+func (b *Browser) Page(path string) *Page {
+	for _, route := range Routes {
+		if route.Path == path {
+			return &Page{Route: route, b: b}
+		}
+	}
+	b.t.Fatalf("No web UI route %s", path)
+	return nil
+}
+
+// Open loads the page and waits for the WASM app to render it
+func (p *Page) Open() error {
+	return p.b.Navigate(p.Path, p.Root)
+}
+
+// Text returns the text the page shows
+func (p *Page) Text() (string, error) {
+	return p.b.Text(p.Root)
+}
+
+// Browse is the folder tree page
+func (b *Browser) Browse() *Page { return b.Page("/browse") }
+
+// Ingest is the page that runs ingestion by hand
+func (b *Browser) Ingest() *Page { return b.Page("/ingest") }
+
+// Clean is the cleanup page
+func (b *Browser) Clean() *Page { return b.Page("/clean") }
+
+// WordCloud is the word cloud page
+func (b *Browser) WordCloud() *Page { return b.Page("/wordcloud") }
+
+// Jobs is the job history page
+func (b *Browser) Jobs() *Page { return b.Page("/jobs") }
+
+// Stats is the statistics page
+func (b *Browser) Stats() *Page { return b.Page("/stats") }
+
+// About is the about page
+func (b *Browser) About() *Page { return b.Page("/about") }
+
+// Setup is the first-run setup wizard
+func (b *Browser) Setup() *Page { return b.Page("/setup") }
+
+// Collection is the saved collection page
+func (b *Browser) Collection() *Page { return b.Page("/collection") }
+
+// Scan is the scanner page
+func (b *Browser) Scan() *Page { return b.Page("/scan") }
+
+// Capture is the camera capture page
+func (b *Browser) Capture() *Page { return b.Page("/capture") }
+
+// Login is the sign-in page
+func (b *Browser) Login() *Page { return b.Page("/login") }
+
+// HomePage lists the latest documents
+type HomePage struct {
+	*Page
+}
+
+// Home is the home page
+func (b *Browser) Home() *HomePage { return &HomePage{b.Page("/")} }
+
+// Open loads the home page and waits for the latest documents, or the message that there are none
+func (p *HomePage) Open() error {
+	return p.b.Navigate(p.Path, ".document-grid, .home-page .no-results")
+}
+
+// DocumentNames returns the names on the document cards, newest first
+func (p *HomePage) DocumentNames() ([]string, error) {
+	return p.b.Texts(".document-card h3")
+}
+
+// SearchPage searches the documents
+type SearchPage struct {
+	*Page
+}
+
+// Search is the search page
+func (b *Browser) Search() *SearchPage { return &SearchPage{b.Page("/search")} }
+
+// SearchResult is one document in the search results
+type SearchResult struct {
+	Name    string `json:"name"`
+	FileURL string `json:"fileURL"` // where the document opens, empty when it cannot be viewed
+}
+
+// Search searches for term and returns the results, none when nothing matched
+func (p *SearchPage) Search(term string) ([]SearchResult, error) {
+	// A fresh load with the term in the URL, so the results waited for are never the last search's
+	if err := p.b.Navigate(p.Path+"?term="+url.QueryEscape(term), ".search-results, .search-page .no-results"); err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	err := p.b.run(chromedp.Evaluate(`Array.from(document.querySelectorAll(".search-result-item"), el => ({
+		name: el.querySelector("h4").textContent.trim(),
+		fileURL: (el.querySelector("h4 a") || {}).href || "",
+	}))`, &results))
+	return results, err
+}
+
+// View opens a search result's document and returns the text the browser shows for it
+func (p *SearchPage) View(result SearchResult) (string, error) {
+	if err := p.b.Navigate(result.FileURL, "body"); err != nil {
+		return "", err
+	}
+	return p.b.Text("body")
+}
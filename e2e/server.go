@@ -0,0 +1,147 @@
+// Package e2e runs the whole godocs server, embedded web UI included, and drives it from a headless
+// Chrome or Chromium through chromedp, so UI regressions show up in go test rather than by clicking
+// around:
+//
+//	srv := e2e.StartServer(t, e2e.Options{Seed: 50})
+//	browser := e2e.NewBrowser(t, srv)
+//	results, err := browser.Search().Search("invoice")
+//
+// StartServer needs nothing beyond Go; NewBrowser skips the test when no browser is installed or
+// go test runs with -short. The WASM app is the one embedded in the godocs package, so run
+// task build:wasm first to test the current webapp code.
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs"
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/seed"
+)
+
+// Options sets up the data a test server starts with
+type Options struct {
+	Documents map[string]string // text documents to upload before the test, by path relative to the document root
+	Seed      int               // synthetic documents to add as well, spread over a few folders
+}
+
+// Server is a godocs server on a free local port with its database and folders in a temp dir
+type Server struct {
+	*godocs.Server
+	URL          string // base URL, such as http://127.0.0.1:41234
+	DocumentPath string // the document root
+
+	http *httptest.Server
+}
+
+// StartServer starts a godocs server on SQLite, uploads opts.Documents and seeds opts.Seed synthetic
+// documents. It runs without the scheduled jobs, so nothing changes the data behind a test's back, and
+// shuts down when the test ends.
+func StartServer(t testing.TB, opts Options) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	serverConfig := config.ServerConfig{
+		DatabaseType:   "sqlite",
+		DatabaseDbname: filepath.Join(dir, "godocs.sqlite"),
+		IngressPath:    filepath.Join(dir, "ingress"),
+		DocumentPath:   filepath.Join(dir, "documents"),
+	}
+	for _, folder := range []string{serverConfig.IngressPath, serverConfig.DocumentPath} {
+		if err := os.MkdirAll(folder, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The web UI sends an unconfigured server to the setup wizard; the environment marks this one as set up
+	t.Setenv("DATABASE_TYPE", serverConfig.DatabaseType)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	srv, err := godocs.New(serverConfig, godocs.WithLogger(logger), godocs.WithoutWorker())
+	if err != nil {
+		t.Fatalf("Failed to start godocs: %v", err)
+	}
+	s := &Server{Server: srv, DocumentPath: serverConfig.DocumentPath, http: httptest.NewServer(srv.Handler())}
+	s.URL = s.http.URL
+	t.Cleanup(func() {
+		s.http.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	})
+
+	for name, text := range opts.Documents {
+		s.Upload(t, filepath.Dir(name), filepath.Base(name), text)
+	}
+	if opts.Seed > 0 {
+		seedOpts := seed.Options{Count: opts.Seed, Root: s.DocumentPath, Folders: 5, Seed: 1, Files: true}
+		if _, err := seed.Generate(srv.Repository(), seedOpts); err != nil {
+			t.Fatalf("Failed to seed %d documents: %v", opts.Seed, err)
+		}
+	}
+	return s
+}
+
+// Upload stores a text document in folder, relative to the document root, through the upload API and
+// returns it. The web UI only uploads photos, from the capture page, which need OCR to become searchable.
+func (s *Server) Upload(t testing.TB, folder, name, text string) database.Document {
+	t.Helper()
+	var body strings.Builder
+	form := multipart.NewWriter(&body)
+	form.WriteField("folder", filepath.ToSlash(folder))
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(part, text)
+	form.Close()
+
+	resp, err := http.Post(s.URL+"/api/document/upload", form.FormDataContentType(), strings.NewReader(body.String()))
+	if err != nil {
+		t.Fatalf("Failed to upload %s: %v", name, err)
+	}
+	defer resp.Body.Close()
+	var path string
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&path) != nil {
+		t.Fatalf("Upload of %s answered %s", name, resp.Status)
+	}
+	document, err := s.Repository().GetDocumentByPath(filepath.FromSlash(path))
+	if err != nil {
+		t.Fatalf("Uploaded %s is not in the database: %v", name, err)
+	}
+	return *document
+}
+
+// Delete removes a document through the delete API
+func (s *Server) Delete(t testing.TB, document database.Document) {
+	t.Helper()
+	rel, err := filepath.Rel(s.DocumentPath, document.Path)
+	if err != nil {
+		t.Fatalf("%s is outside the document root: %v", document.Path, err)
+	}
+	query := url.Values{"id": {document.ULID.String()}, "path": {filepath.ToSlash(rel)}}
+	target := fmt.Sprintf("%s/api/document/?%s", s.URL, query.Encode())
+	req, err := http.NewRequest(http.MethodDelete, target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to delete %s: %v", document.Name, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Delete of %s answered %s", document.Name, resp.Status)
+	}
+}
@@ -0,0 +1,250 @@
+package engine
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// activityPageSize is the number of entries per page of the activity feed when no limit is given
+const activityPageSize = 20
+
+// recordActivity adds an entry to the audit log. The log is for information, like the timeline, so
+// failing to write it is logged rather than failing the change.
+func (serverHandler *ServerHandler) recordActivity(event database.AuditEvent) {
+	if err := serverHandler.DB.RecordAuditEvent(&event); err != nil {
+		Logger.Warn("Unable to record activity", "action", event.Action, "target", event.Target, "error", err)
+	}
+}
+
+// documentIngested records a newly ingested document in the activity feed, with a spreadsheet's size, its
+// near-duplicate fingerprint and the fields its correspondent's extraction template reads, and runs the
+// post-ingestion hooks
+func (serverHandler *ServerHandler) documentIngested(doc *database.Document, source string, items *jobItems) {
+	serverHandler.recordSpreadsheetDetails(doc)
+	serverHandler.recordFingerprint(doc)
+	serverHandler.recordDocumentFields(doc)
+	serverHandler.recordActivity(database.AuditEvent{
+		Action: database.AuditDocumentAdded,
+		Target: doc.ULID.String(),
+		Name:   doc.Name,
+		Detail: source,
+	})
+	serverHandler.runPostIngestHooks(doc, source, items)
+}
+
+// settingsChanged records a change to the server settings in the activity feed; what names the settings
+func (serverHandler *ServerHandler) settingsChanged(c echo.Context, what string) {
+	serverHandler.recordActivity(database.AuditEvent{
+		Action: database.AuditSettingsChanged,
+		Target: what,
+		User:   requestUser(c),
+	})
+}
+
+// encodeActivityCursor turns the position of the last entry on a page into the token for the next page
+func encodeActivityCursor(cursor *database.ActivityCursor) string {
+	raw := strconv.FormatInt(cursor.At.UnixNano(), 10) + "." + cursor.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeActivityCursor parses a token produced by encodeActivityCursor; an empty token starts from the newest entry
+func decodeActivityCursor(token string) (*database.ActivityCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(raw), ".")
+	if !ok || id == "" {
+		return nil, errInvalidCursor
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	return &database.ActivityCursor{At: time.Unix(0, unixNano).UTC(), ID: id}, nil
+}
+
+// auditActivity turns an audit log entry into a feed entry
+func auditActivity(event database.AuditEvent) dto.ActivityItem {
+	item := dto.ActivityItem{Kind: event.Action, User: event.User}
+	switch event.Action {
+	case database.AuditDocumentAdded:
+		item.DocumentULID = event.Target
+		item.Summary = "Added " + event.Name
+		if event.Detail != "" {
+			item.Summary += " from " + event.Detail
+		}
+	case database.AuditDocumentMoved:
+		item.DocumentULID = event.Target
+		item.Summary = "Moved " + event.Name + " to " + event.Detail
+	case database.AuditDocumentDeleted:
+		item.DocumentULID = event.Target
+		item.Summary = "Deleted " + event.Name
+	case database.AuditSettingsChanged:
+		item.Summary = "Changed " + event.Target + " settings"
+	case database.AuditTagRenamed:
+		item.Summary = "Renamed tag " + event.Target + " to " + event.Detail
+	case database.AuditTagMerged:
+		item.Summary = "Merged tag " + event.Target + " into " + event.Detail
+	default:
+		item.Summary = event.Action + " " + event.Target
+	}
+	return item
+}
+
+// jobActivity turns a finished job into a feed entry
+func jobActivity(job database.Job) dto.ActivityItem {
+	summary := "Job " + string(job.Status)
+	if name := strings.ReplaceAll(string(job.Type), "_", " "); name != "" {
+		summary = strings.ToUpper(name[:1]) + name[1:] + " job " + string(job.Status)
+	}
+	if job.Status == database.JobStatusFailed && job.Error != "" {
+		summary += ": " + job.Error
+	}
+	return dto.ActivityItem{
+		Kind:    "job",
+		Summary: summary,
+		JobID:   job.ID.String(),
+		JobType: string(job.Type),
+		Status:  string(job.Status),
+	}
+}
+
+// readableEvent reports whether an audit entry may be shown to access. An entry about a document needs
+// read access to the document's folder: its own while it exists, or the one recorded when it was deleted.
+// A document gone without its folder recorded is left out.
+func (serverHandler *ServerHandler) readableEvent(access *folderAccess, event database.AuditEvent) bool {
+	if access == nil {
+		return true
+	}
+	switch event.Action {
+	case database.AuditDocumentAdded, database.AuditDocumentMoved, database.AuditDocumentDeleted:
+	default:
+		return true
+	}
+	if event.Target != "" {
+		if document, err := serverHandler.DB.GetDocumentByULID(event.Target); err == nil && document != nil {
+			return access.canRead(document.Folder)
+		}
+	}
+	return event.Action == database.AuditDocumentDeleted && event.Detail != "" && access.canRead(event.Detail)
+}
+
+// readableAuditEvents lists up to limit audit entries after the cursor that access may see, reading
+// further back past those it may not
+func (serverHandler *ServerHandler) readableAuditEvents(access *folderAccess, before *database.ActivityCursor, limit int) ([]database.AuditEvent, error) {
+	var events []database.AuditEvent
+	for {
+		batch, err := serverHandler.DB.ListAuditEvents(before, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range batch {
+			if serverHandler.readableEvent(access, event) {
+				events = append(events, event)
+			}
+		}
+		if len(events) >= limit || len(batch) < limit {
+			return events[:min(len(events), limit)], nil
+		}
+		last := batch[len(batch)-1]
+		before = &database.ActivityCursor{At: last.CreatedAt, ID: last.ID}
+	}
+}
+
+// activityEntry is a feed entry with the time and ID it is ordered by
+type activityEntry struct {
+	at   time.Time
+	id   string
+	item dto.ActivityItem
+}
+
+// GetActivity returns recent activity: documents added, moved and deleted, settings changed and jobs finished
+// @Summary Activity feed
+// @Description A merged feed of the audit log and finished jobs, newest first. Follow nextCursor until hasNext is false to read further back.
+// @Description With folder permissions, entries about documents the signed-in account cannot read are left out.
+// @Tags Activity
+// @Produce json
+// @Param limit query int false "Entries per page (default 20, at most 200)"
+// @Param cursor query string false "Opaque cursor from a previous response's nextCursor; empty starts from the newest entry"
+// @Success 200 {object} dto.ActivityFeed "A page of activity"
+// @Failure 400 {object} dto.ErrorResponse "Invalid limit or cursor"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /activity [get]
+func (serverHandler *ServerHandler) GetActivity(c echo.Context) error {
+	before, err := decodeActivityCursor(c.QueryParam("cursor"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid cursor",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	limit, err := limitParam(c, activityPageSize)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		return err
+	}
+
+	// Read one extra entry from each source to learn whether another page follows
+	events, err := serverHandler.readableAuditEvents(access, before, limit+1)
+	if err != nil {
+		Logger.Error("Failed to list audit events", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to fetch activity",
+			"code":  dto.CodeInternal,
+		})
+	}
+	jobs, err := serverHandler.DB.ListFinishedJobs(before, limit+1)
+	if err != nil {
+		Logger.Error("Failed to list finished jobs", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to fetch activity",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	entries := make([]activityEntry, 0, len(events)+len(jobs))
+	for _, event := range events {
+		entries = append(entries, activityEntry{at: event.CreatedAt, id: event.ID, item: auditActivity(event)})
+	}
+	for _, job := range jobs {
+		entries = append(entries, activityEntry{at: *job.CompletedAt, id: job.ID.String(), item: jobActivity(job)})
+	}
+	// Ordered as the database pages them, so the cursor continues exactly after the last entry shown
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].at.Equal(entries[j].at) {
+			return entries[i].at.After(entries[j].at)
+		}
+		return entries[i].id > entries[j].id
+	})
+
+	feed := dto.ActivityFeed{Items: []dto.ActivityItem{}, PageSize: limit, HasNext: len(entries) > limit}
+	if feed.HasNext {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		feed.NextCursor = encodeActivityCursor(&database.ActivityCursor{At: last.at, ID: last.id})
+	}
+	for _, entry := range entries {
+		entry.item.At = entry.at.UTC().Format(time.RFC3339)
+		feed.Items = append(feed.Items, entry.item)
+	}
+	return c.JSON(http.StatusOK, feed)
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+// getActivity reads a page of the activity feed
+func getActivity(t *testing.T, handler *ServerHandler, query string) dto.ActivityFeed {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	if err := handler.GetActivity(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/activity?"+query, nil), rec)); err != nil {
+		t.Fatalf("GetActivity failed: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var feed dto.ActivityFeed
+	if err := json.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("Invalid feed: %v", err)
+	}
+	return feed
+}
+
+func TestActivityFeedMergesChangesAndJobs(t *testing.T) {
+	// A document that is ingested, moved by alice, a backup job that finishes, and the document deleted
+	handler := setupTestHandler(t)
+	defer database.SetClock(database.NewFixedClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), time.Minute))()
+	path := filepath.Join(handler.ServerConfig.DocumentPath, "bill.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.4"), 0644); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	doc := saveTestDocument(t, handler.DB, path, "")
+	handler.documentIngested(doc, "upload", nil)
+
+	move := httptest.NewRequest(http.MethodPatch, "/api/document/move/?folder=/finance&id="+doc.ULID.String(), nil)
+	move.SetBasicAuth("alice", "secret")
+	if err := handler.MoveDocuments(handler.Echo.NewContext(move, httptest.NewRecorder())); err != nil {
+		t.Fatalf("MoveDocuments failed: %v", err)
+	}
+	job, _ := handler.DB.CreateJob(database.JobTypeBackup, "backup")
+	handler.DB.CompleteJob(job.ID, `{}`)
+	remove := httptest.NewRequest(http.MethodDelete, "/api/document/?path=bill.pdf&id="+doc.ULID.String(), nil)
+	if err := handler.DeleteFile(handler.Echo.NewContext(remove, httptest.NewRecorder())); err != nil {
+		t.Fatalf("DeleteFile failed: %v", err)
+	}
+
+	// The feed is read two entries at a time
+	first := getActivity(t, handler, "limit=2")
+	second := getActivity(t, handler, "limit=2&cursor="+first.NextCursor)
+
+	// Every change and the job appear once, newest first
+	if !first.HasNext || second.HasNext {
+		t.Fatalf("Expected two pages, got hasNext %v then %v", first.HasNext, second.HasNext)
+	}
+	items := append(first.Items, second.Items...)
+	want := []struct{ kind, summary string }{
+		{database.AuditDocumentDeleted, "Deleted bill.pdf"},
+		{"job", "Backup job completed"},
+		{database.AuditDocumentMoved, "Moved bill.pdf to /finance"},
+		{database.AuditDocumentAdded, "Added bill.pdf from upload"},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("Expected %d entries, got %+v", len(want), items)
+	}
+	for i, w := range want {
+		if items[i].Kind != w.kind || items[i].Summary != w.summary {
+			t.Errorf("Entry %d: expected %s %q, got %s %q", i, w.kind, w.summary, items[i].Kind, items[i].Summary)
+		}
+	}
+	if items[2].User != "alice" || items[2].DocumentULID != doc.ULID.String() || items[1].JobID != job.ID.String() {
+		t.Errorf("Expected the move by alice and the job's ID, got %+v and %+v", items[2], items[1])
+	}
+
+	// A bad cursor is rejected
+	rec := httptest.NewRecorder()
+	handler.GetActivity(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/activity?cursor=!!", nil), rec))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad cursor, got %d", rec.Code)
+	}
+}
+
+func TestActivityFeedPagesEntriesSharingATime(t *testing.T) {
+	// Three changes and two jobs all recorded in the same instant, read two at a time
+	handler := setupTestHandler(t)
+	defer database.SetClock(database.NewFixedClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), 0))()
+	defer database.SetIDGenerator(database.NewSeededIDs(1))()
+	for _, name := range []string{"a.pdf", "b.pdf", "c.pdf"} {
+		handler.recordActivity(database.AuditEvent{Action: database.AuditDocumentAdded, Name: name})
+	}
+	for _, jobType := range []database.JobType{database.JobTypeBackup, database.JobTypeCleanup} {
+		job, _ := handler.DB.CreateJob(jobType, string(jobType))
+		handler.DB.CompleteJob(job.ID, `{}`)
+	}
+
+	seen := map[string]bool{}
+	cursor := ""
+	for pages := 1; ; pages++ {
+		feed := getActivity(t, handler, "limit=2&cursor="+cursor)
+		for _, item := range feed.Items {
+			if seen[item.Summary] {
+				t.Errorf("Page %d repeated %q", pages, item.Summary)
+			}
+			seen[item.Summary] = true
+		}
+		if !feed.HasNext {
+			break
+		}
+		if pages > 5 {
+			t.Fatal("Expected the feed to end")
+		}
+		cursor = feed.NextCursor
+	}
+	if len(seen) != 5 {
+		t.Errorf("Expected all five entries across the pages, got %v", seen)
+	}
+}
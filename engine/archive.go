@@ -0,0 +1,446 @@
+package engine
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// errAlreadyArchived is returned by archiveDocument for a document already in cold storage
+	errAlreadyArchived = errors.New("document is already archived")
+	// errNotArchived is returned by restoreDocument for a document that is not in cold storage
+	errNotArchived = errors.New("document is not archived")
+)
+
+// archiveFilePath is where a document's compressed file is kept in cold storage: beside the original,
+// or under ARCHIVE_PATH at the same place relative to the document folder
+func (serverHandler *ServerHandler) archiveFilePath(document database.Document) string {
+	archiveRoot := serverHandler.ServerConfig.ArchivePath
+	if archiveRoot == "" {
+		return filepath.FromSlash(document.Path) + ".gz"
+	}
+	relative, err := filepath.Rel(serverHandler.ServerConfig.DocumentPath, filepath.FromSlash(document.Path))
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		relative = filepath.Join(document.ULID.String(), document.Name) // not under the document folder
+	}
+	return filepath.Join(archiveRoot, relative) + ".gz"
+}
+
+// archiveDocument compresses a document's file into cold storage and removes the original. The
+// compressed file is read back and checked before the original goes, so a failure leaves it in place.
+func (serverHandler *ServerHandler) archiveDocument(document database.Document) (*database.DocumentArchive, error) {
+	if _, err := serverHandler.DB.GetDocumentArchive(document.ULID.String()); err == nil {
+		return nil, errAlreadyArchived
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	source, err := os.Open(document.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	archivePath := serverHandler.archiveFilePath(document)
+	if err := os.MkdirAll(filepath.Dir(archivePath), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create archive folder: %w", err)
+	}
+	partPath := archivePath + ".part"
+	sourceHash, err := writeGzipHashed(partPath, source)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compress %s: %w", document.Path, err)
+	}
+	if archivedHash, err := gunzipHash(partPath); err != nil || archivedHash != sourceHash {
+		os.Remove(partPath)
+		return nil, fmt.Errorf("%w: compressed copy of %s does not match it", errStorageFailed, document.Path)
+	}
+	if err := os.Rename(partPath, archivePath); err != nil {
+		os.Remove(partPath)
+		return nil, err
+	}
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	archive := &database.DocumentArchive{
+		DocumentULID:   document.ULID.String(),
+		ArchivePath:    filepath.ToSlash(archivePath),
+		ArchivedAt:     database.Now(),
+		CompressedSize: info.Size(),
+	}
+	if err := serverHandler.DB.SaveDocumentArchive(archive); err != nil {
+		os.Remove(archivePath)
+		return nil, err
+	}
+	source.Close()
+	if err := os.Remove(document.Path); err != nil {
+		Logger.Warn("Archived document but could not remove the original", "path", document.Path, "error", err)
+	}
+	Logger.Info("Archived document", "ulid", archive.DocumentULID, "path", document.Path, "archive", archivePath,
+		"size", document.Size, "compressedSize", archive.CompressedSize)
+	return archive, nil
+}
+
+// restoreDocument decompresses an archived document back to its path and removes the compressed copy
+func (serverHandler *ServerHandler) restoreDocument(document database.Document) error {
+	archive, err := serverHandler.DB.GetDocumentArchive(document.ULID.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return errNotArchived
+	}
+	if err != nil {
+		return err
+	}
+	compressed, err := os.Open(filepath.FromSlash(archive.ArchivePath))
+	if err != nil {
+		return fmt.Errorf("%w: %w", errStorageFailed, err)
+	}
+	defer compressed.Close()
+	reader, err := gzip.NewReader(compressed)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errStorageFailed, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(document.Path), os.ModePerm); err != nil {
+		return err
+	}
+	partPath := document.Path + ".restoring"
+	restoredHash, err := writeFileHashed(partPath, reader, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errStorageFailed, err)
+	}
+	if document.Hash != "" && !restoredHash.Matches(document.Hash) {
+		os.Remove(partPath)
+		return fmt.Errorf("%w: restored file does not match the document (expected: %s, got: %s)", errStorageFailed, document.Hash, restoredHash)
+	}
+	if err := os.Rename(partPath, document.Path); err != nil {
+		os.Remove(partPath)
+		return err
+	}
+	if err := serverHandler.DB.DeleteDocumentArchive(archive.DocumentULID); err != nil {
+		return err
+	}
+	compressed.Close()
+	if err := os.Remove(filepath.FromSlash(archive.ArchivePath)); err != nil {
+		Logger.Warn("Restored document but could not remove its compressed copy", "archive", archive.ArchivePath, "error", err)
+	}
+	Logger.Info("Restored archived document", "ulid", archive.DocumentULID, "path", document.Path)
+	return nil
+}
+
+// discardArchive removes the compressed copy and record of a deleted document, if it was archived
+func (serverHandler *ServerHandler) discardArchive(documentULID string) {
+	archive, err := serverHandler.DB.GetDocumentArchive(documentULID)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(filepath.FromSlash(archive.ArchivePath)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		Logger.Warn("Unable to remove compressed copy of deleted document", "archive", archive.ArchivePath, "error", err)
+	}
+	if err := serverHandler.DB.DeleteDocumentArchive(documentULID); err != nil {
+		Logger.Warn("Unable to remove archive record of deleted document", "ulid", documentULID, "error", err)
+	}
+}
+
+// writeGzipHashed compresses src into a new file at path and returns the hashes of the uncompressed
+// bytes. A partly written file is removed on failure.
+func writeGzipHashed(path string, src io.Reader) (database.FileHashes, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		return database.FileHashes{}, err
+	}
+	compressor, _ := gzip.NewWriterLevel(file, gzip.BestCompression)
+	fileHash, err := hashingCopy(compressor, src)
+	if closeErr := compressor.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return database.FileHashes{}, err
+	}
+	return fileHash, nil
+}
+
+// gunzipHash returns the hashes of a gzip file's uncompressed contents
+func gunzipHash(path string) (database.FileHashes, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return database.FileHashes{}, err
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return database.FileHashes{}, err
+	}
+	return hashingCopy(io.Discard, reader)
+}
+
+// archivedDocuments returns the ULIDs of every document in cold storage
+func (serverHandler *ServerHandler) archivedDocuments() (map[string]bool, error) {
+	archives, err := serverHandler.DB.ListDocumentArchives()
+	if err != nil {
+		return nil, err
+	}
+	archived := make(map[string]bool, len(archives))
+	for _, archive := range archives {
+		archived[archive.DocumentULID] = true
+	}
+	return archived, nil
+}
+
+// markArchived marks the documents listed as missing that are in cold storage as archived instead
+func (serverHandler *ServerHandler) markArchived(nodes []dto.FileTreeNode) {
+	var archived map[string]bool
+	for i := range nodes {
+		if !nodes[i].Missing {
+			continue
+		}
+		if archived == nil {
+			var err error
+			if archived, err = serverHandler.archivedDocuments(); err != nil {
+				Logger.Error("Unable to list archived documents", "error", err)
+				return
+			}
+		}
+		if archived[nodes[i].ULID] {
+			nodes[i].Missing, nodes[i].Archived = false, true
+		}
+	}
+}
+
+// archiveOldDocuments moves every document ingested before cutoff to cold storage, skipping those that
+// are checked out or whose file is missing. It returns how many were archived.
+func (serverHandler *ServerHandler) archiveOldDocuments(cutoff time.Time) (int, error) {
+	documents, err := listDocumentsByName(serverHandler.DB)
+	if err != nil {
+		return 0, err
+	}
+	archived, err := serverHandler.archivedDocuments()
+	if err != nil {
+		return 0, err
+	}
+	locks, err := serverHandler.DB.ListDocumentLocks()
+	if err != nil {
+		return 0, err
+	}
+	for _, lock := range locks {
+		archived[lock.DocumentULID] = true // left until it is checked back in
+	}
+	count := 0
+	for _, document := range documents {
+		if !document.IngressTime.Before(cutoff) || archived[document.ULID.String()] || fileMissing(document.Path) {
+			continue
+		}
+		if _, err := serverHandler.archiveDocument(document); err != nil {
+			Logger.Error("Unable to archive document", "path", document.Path, "error", err)
+			continue
+		}
+		count++
+	}
+	if count > 0 {
+		serverHandler.invalidateDocumentCache()
+	}
+	return count, nil
+}
+
+// scheduledArchive archives the documents older than ARCHIVE_AFTER_DAYS, daily when it is set
+func (serverHandler *ServerHandler) scheduledArchive() {
+	days := serverHandler.ServerConfig.ArchiveAfterDays
+	count, err := serverHandler.archiveOldDocuments(database.Now().AddDate(0, 0, -days))
+	if err != nil {
+		Logger.Error("Scheduled archiving failed", "error", err)
+		return
+	}
+	Logger.Info("Scheduled archiving completed", "archived", count, "olderThanDays", days)
+}
+
+// archiveTarget returns the document named by the id parameter, or writes the error response when it does
+// not exist, the account may not see it, or with write may not change it
+func (serverHandler *ServerHandler) archiveTarget(c echo.Context, write bool) (*database.Document, bool, error) {
+	id, ok, err := ulidParam(c, "id", "document")
+	if !ok {
+		return nil, false, err
+	}
+	document, err := serverHandler.DB.GetDocumentByULID(id.String())
+	if err != nil || document == nil {
+		return nil, false, documentNotFound(c)
+	}
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		return nil, false, err
+	}
+	if !access.canRead(document.Folder) {
+		return nil, false, documentNotFound(c)
+	}
+	if write && !access.canWrite(document.Folder) {
+		return nil, false, folderForbidden(c, document.Folder)
+	}
+	return document, true, nil
+}
+
+// documentArchived answers 409 for a document whose file is in cold storage
+func documentArchived(c echo.Context, archive *database.DocumentArchive) error {
+	return c.JSON(http.StatusConflict, map[string]interface{}{
+		"error":   "Document is archived; restore it before opening it",
+		"code":    dto.CodeArchived,
+		"archive": archive,
+	})
+}
+
+// ArchiveDocument moves a document's file to cold storage
+// @Summary Archive a document
+// @Description Compress the document's file into cold storage (ARCHIVE_PATH, or beside the original) and remove the original.
+// @Description The document stays searchable, but its file has to be restored before it can be viewed.
+// @Tags Documents
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Success 200 {object} map[string]interface{} "The archive record"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID"
+// @Failure 403 {object} map[string]interface{} "No write access to the document's folder"
+// @Failure 404 {object} map[string]interface{} "Document or its file not found"
+// @Failure 409 {object} map[string]interface{} "Document is already archived"
+// @Failure 423 {object} map[string]interface{} "Locked by another holder, with their lock"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /document/{id}/archive [post]
+func (serverHandler *ServerHandler) ArchiveDocument(c echo.Context) error {
+	document, ok, err := serverHandler.archiveTarget(c, true)
+	if !ok {
+		return err
+	}
+	lock, err := serverHandler.lockedAgainst(lockHolder(c), document.ULID.String())
+	if err != nil {
+		Logger.Error("Unable to check document lock", "ulid", document.ULID.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to archive document",
+			"code":  dto.CodeInternal,
+		})
+	}
+	if lock != nil {
+		return documentLocked(c, lock)
+	}
+	archive, err := serverHandler.archiveDocument(*document)
+	switch {
+	case errors.Is(err, errAlreadyArchived):
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": "Document is already archived",
+			"code":  dto.CodeConflict,
+		})
+	case errors.Is(err, os.ErrNotExist):
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document file is missing",
+			"code":  dto.CodeFileMissing,
+		})
+	case err != nil:
+		Logger.Error("Failed to archive document", "ulid", document.ULID.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to archive document",
+			"code":  dto.CodeStorageFailed,
+		})
+	}
+	serverHandler.invalidateDocumentCache()
+	return c.JSON(http.StatusOK, map[string]interface{}{"archive": archive})
+}
+
+// GetDocumentArchive reports where and when a document was archived
+// @Summary Get a document's archive record
+// @Tags Documents
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Success 200 {object} map[string]interface{} "The archive record"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID"
+// @Failure 404 {object} map[string]interface{} "Document not found or not archived"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /document/{id}/archive [get]
+func (serverHandler *ServerHandler) GetDocumentArchive(c echo.Context) error {
+	document, ok, err := serverHandler.archiveTarget(c, false)
+	if !ok {
+		return err
+	}
+	archive, err := serverHandler.DB.GetDocumentArchive(document.ULID.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document is not archived",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to get document archive", "ulid", document.ULID.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve archive record",
+			"code":  dto.CodeInternal,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"archive": archive})
+}
+
+// RestoreDocument brings an archived document's file back from cold storage
+// @Summary Restore an archived document
+// @Description Decompress the document's file back to its path, checking it against the document's hash, and remove the compressed copy.
+// @Tags Documents
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Success 204 "Document restored"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID"
+// @Failure 403 {object} map[string]interface{} "No write access to the document's folder"
+// @Failure 404 {object} map[string]interface{} "Document not found or not archived"
+// @Failure 500 {object} map[string]interface{} "The compressed copy could not be restored"
+// @Router /document/{id}/archive [delete]
+func (serverHandler *ServerHandler) RestoreDocument(c echo.Context) error {
+	document, ok, err := serverHandler.archiveTarget(c, true)
+	if !ok {
+		return err
+	}
+	err = serverHandler.restoreDocument(*document)
+	if errors.Is(err, errNotArchived) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document is not archived",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to restore archived document", "ulid", document.ULID.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to restore document",
+			"code":  dto.CodeStorageFailed,
+		})
+	}
+	serverHandler.invalidateDocumentCache()
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetArchivedDocuments lists the documents in cold storage
+// @Summary List archived documents
+// @Tags Documents
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Archive records, most recently archived first"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /archive [get]
+func (serverHandler *ServerHandler) GetArchivedDocuments(c echo.Context) error {
+	archives, err := serverHandler.DB.ListDocumentArchives()
+	if err != nil {
+		Logger.Error("Failed to list archived documents", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list archived documents",
+			"code":  dto.CodeInternal,
+		})
+	}
+	if archives == nil {
+		archives = []database.DocumentArchive{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"archives":  archives,
+		"afterDays": serverHandler.ServerConfig.ArchiveAfterDays, // 0 when documents are only archived by hand
+	})
+}
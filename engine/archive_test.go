@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/oklog/ulid/v2"
+)
+
+// saveArchivableDocument writes a document file and saves its record with the file's real hash
+func saveArchivableDocument(t *testing.T, handler *ServerHandler, name string, ingressTime time.Time) *database.Document {
+	t.Helper()
+	path := filepath.Join(handler.ServerConfig.DocumentPath, name)
+	if err := os.WriteFile(path, bytes.Repeat([]byte("annual statement "), 200), 0644); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	hash, err := calculateFileHash(path)
+	if err != nil {
+		t.Fatalf("Failed to hash document: %v", err)
+	}
+	doc := &database.Document{
+		Name:         name,
+		Path:         path,
+		IngressTime:  ingressTime,
+		Folder:       filepath.Dir(path),
+		Hash:         hash.Current(),
+		ULID:         ulid.Make(),
+		DocumentType: filepath.Ext(name),
+		FullText:     "annual statement",
+		URL:          "/document/view/placeholder",
+	}
+	if err := handler.DB.SaveDocument(doc); err != nil {
+		t.Fatalf("Failed to save document: %v", err)
+	}
+	return doc
+}
+
+// archiveRequest calls an archive endpoint for a document and returns the recorder
+func archiveRequest(handler *ServerHandler, method string, id string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	c := handler.Echo.NewContext(httptest.NewRequest(method, "/api/document/"+id+"/archive", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	switch method {
+	case http.MethodPost:
+		handler.ArchiveDocument(c)
+	case http.MethodGet:
+		handler.GetDocumentArchive(c)
+	case http.MethodDelete:
+		handler.RestoreDocument(c)
+	}
+	return rec
+}
+
+func TestArchiveAndRestoreDocument(t *testing.T) {
+	// A stored document
+	handler := setupTestHandler(t)
+	doc := saveArchivableDocument(t, handler, "statement.txt", time.Now())
+	original, _ := os.ReadFile(doc.Path)
+	id := doc.ULID.String()
+
+	// It is archived
+	rec := archiveRequest(handler, http.MethodPost, id)
+
+	// The original is replaced by a smaller compressed copy beside it
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected archive response %d %s", rec.Code, rec.Body.String())
+	}
+	var archived struct {
+		Archive database.DocumentArchive `json:"archive"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &archived)
+	if archived.Archive.ArchivePath != filepath.ToSlash(doc.Path)+".gz" || archived.Archive.CompressedSize >= int64(len(original)) {
+		t.Errorf("Unexpected archive %+v", archived.Archive)
+	}
+	if _, err := os.Stat(doc.Path); !os.IsNotExist(err) {
+		t.Errorf("Expected the original removed, got %v", err)
+	}
+	if rec := archiveRequest(handler, http.MethodPost, id); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 archiving twice, got %d", rec.Code)
+	}
+
+	// Viewing it asks for a restore, while search still finds it marked archived rather than missing
+	view := httptest.NewRecorder()
+	c := handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/document/view/"+id, nil), view)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	handler.ViewDocument(c)
+	if view.Code != http.StatusConflict || !bytes.Contains(view.Body.Bytes(), []byte(dto.CodeArchived)) {
+		t.Errorf("Expected 409 %s viewing an archived document, got %d %s", dto.CodeArchived, view.Code, view.Body.String())
+	}
+	search := httptest.NewRecorder()
+	handler.SearchDocuments(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/search?term=annual", nil), search))
+	var results dto.FileSystem
+	json.Unmarshal(search.Body.Bytes(), &results)
+	if len(results.FileSystem) != 2 || !results.FileSystem[1].Archived || results.FileSystem[1].Missing {
+		t.Errorf("Expected the document listed as archived, got %d %s", search.Code, search.Body.String())
+	}
+	if jobs, _ := handler.DB.GetRecentJobs(10, 0); len(jobs) != 0 {
+		t.Errorf("Expected no cleanup started for an archived document, got %+v", jobs)
+	}
+
+	// It is restored
+	rec = archiveRequest(handler, http.MethodDelete, id)
+
+	// The file is back as it was, the compressed copy is gone and it is no longer archived
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Unexpected restore response %d %s", rec.Code, rec.Body.String())
+	}
+	if restored, err := os.ReadFile(doc.Path); err != nil || !bytes.Equal(restored, original) {
+		t.Errorf("Expected the original contents restored, got %d bytes, %v", len(restored), err)
+	}
+	if _, err := os.Stat(doc.Path + ".gz"); !os.IsNotExist(err) {
+		t.Errorf("Expected the compressed copy removed, got %v", err)
+	}
+	if rec := archiveRequest(handler, http.MethodGet, id); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a restored document's archive, got %d", rec.Code)
+	}
+	if rec := archiveRequest(handler, http.MethodDelete, id); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 restoring twice, got %d", rec.Code)
+	}
+}
+
+func TestArchiveOldDocuments(t *testing.T) {
+	// An archive folder, two old documents, one of them checked out, and a recent one
+	handler := setupTestHandler(t)
+	handler.ServerConfig.ArchivePath = t.TempDir()
+	now := time.Now()
+	old := saveArchivableDocument(t, handler, "old.txt", now.AddDate(-2, 0, 0))
+	locked := saveArchivableDocument(t, handler, "locked.txt", now.AddDate(-2, 0, 0))
+	recent := saveArchivableDocument(t, handler, "recent.txt", now)
+	if _, err := handler.DB.AcquireDocumentLock(locked.ULID.String(), "alice", now.Add(time.Hour)); err != nil {
+		t.Fatalf("AcquireDocumentLock failed: %v", err)
+	}
+
+	// Documents older than a year are archived, and a cleanup runs afterwards
+	count, err := handler.archiveOldDocuments(now.AddDate(-1, 0, 0))
+	if err != nil {
+		t.Fatalf("archiveOldDocuments failed: %v", err)
+	}
+	job, err := handler.DB.CreateJob(database.JobTypeCleanup, "Test cleanup")
+	if err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	handler.cleanupJobFuncWithTracking(handler.DB, job.ID, false, OrphanPolicyReport)
+
+	// Only the old, unlocked document is archived, under the archive folder, and cleanup keeps it
+	if count != 1 {
+		t.Errorf("Expected one document archived, got %d", count)
+	}
+	if _, err := os.Stat(filepath.Join(handler.ServerConfig.ArchivePath, "old.txt.gz")); err != nil {
+		t.Errorf("Expected the compressed copy in the archive folder: %v", err)
+	}
+	for _, doc := range []*database.Document{locked, recent} {
+		if _, err := os.Stat(doc.Path); err != nil {
+			t.Errorf("Expected %s left in place: %v", doc.Name, err)
+		}
+	}
+	if _, err := handler.DB.GetDocumentByULID(old.ULID.String()); err != nil {
+		t.Errorf("Expected cleanup to keep the archived document: %v", err)
+	}
+}
@@ -0,0 +1,515 @@
+package engine
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// sessionCookie is the cookie holding the session token of a browser that signed in
+const sessionCookie = "godocs_session"
+
+// authUserKey is the echo context key holding the username of a signed-in request
+const authUserKey = "godocs.user"
+
+// authRoleKey is the echo context key holding the role of the account signed in to a request
+const authRoleKey = "godocs.role"
+
+// minPasswordLength is the shortest password accepted for a new account or password change
+const minPasswordLength = 8
+
+// defaultWebUIPassword is the WEB_UI_PASSWORD used when none is set, warned about when it creates an account
+const defaultWebUIPassword = "Password1"
+
+// publicAPIPaths can be used without signing in: signing in itself, the health check for load
+// balancers, the dropzone webhook, which has its own API key, and shared collection links, whose token
+// is their credential
+var publicAPIPaths = []string{"/api/auth/login", "/api/auth/status", "/api/health", "/api/integrations/dropzone", "/api/shared/"}
+
+// dummyPasswordHash is compared against when a username is unknown, so a failed sign-in takes as long
+// whether or not the account exists
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+
+// authRequired reports whether requests must sign in, as WEB_UI_AUTH sets
+func (serverHandler *ServerHandler) authRequired() bool {
+	return serverHandler.ServerConfig.WebUIPass
+}
+
+// hashSessionToken is how a session token is stored and looked up
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionToken is the token a request carries, as a Bearer token or in the session cookie
+func sessionToken(c echo.Context) string {
+	if token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if cookie, err := c.Cookie(sessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// sessionUser returns the account signed in with token, or nil when the token is unknown or has expired
+func (serverHandler *ServerHandler) sessionUser(token string) *database.User {
+	if token == "" {
+		return nil
+	}
+	session, err := serverHandler.DB.GetSession(hashSessionToken(token))
+	if err != nil || !session.ExpiresAt.After(database.Now()) {
+		return nil
+	}
+	user, err := serverHandler.DB.GetUser(session.UserID)
+	if err != nil {
+		return nil
+	}
+	return user
+}
+
+// RequireLogin answers 401 to API and document requests without a valid session while WEB_UI_AUTH is on,
+// and otherwise records the signed-in user for requestUser. The web UI shell and its assets stay public
+// so the login page can load, as do document links carrying a signature, which DocumentViewAuth checks.
+func (serverHandler *ServerHandler) RequireLogin() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !serverHandler.authRequired() {
+				return next(c)
+			}
+			path := c.Request().URL.Path
+			if user := serverHandler.sessionUser(sessionToken(c)); user != nil {
+				c.Set(authUserKey, user.Username)
+				c.Set(authRoleKey, user.Role)
+				return next(c)
+			}
+			switch {
+			case strings.HasPrefix(path, "/api/"):
+				for _, public := range publicAPIPaths {
+					if path == public || (strings.HasSuffix(public, "/") && strings.HasPrefix(path, public)) {
+						return next(c)
+					}
+				}
+			case strings.HasPrefix(path, documentViewPrefix):
+				if c.QueryParam("sig") != "" {
+					return next(c)
+				}
+			default:
+				return next(c)
+			}
+			return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"error": "Sign in to continue",
+				"code":  dto.CodeUnauthorized,
+			})
+		}
+	}
+}
+
+// EnsureInitialUser creates the first account from WEB_UI_USER and WEB_UI_PASSWORD when WEB_UI_AUTH is on
+// and there are none, so a new installation can be signed in to
+func (serverHandler *ServerHandler) EnsureInitialUser() error {
+	if !serverHandler.authRequired() {
+		return nil
+	}
+	users, err := serverHandler.DB.ListUsers()
+	if err != nil || len(users) > 0 {
+		return err
+	}
+	username, password := serverHandler.ServerConfig.ClientUsername, serverHandler.ServerConfig.ClientPassword
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := serverHandler.DB.CreateUser(&database.User{Username: username, PasswordHash: string(hash), Role: database.RoleAdmin}); err != nil {
+		return err
+	}
+	Logger.Info("Created the first account from WEB_UI_USER", "username", username)
+	if password == defaultWebUIPassword {
+		Logger.Warn("The first account has the default WEB_UI_PASSWORD; change it through PUT /api/users/:id/password", "username", username)
+	}
+	return nil
+}
+
+// loginRequest is a sign-in, or a new account with its role
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role,omitempty"` // for a new account: admin, editor (the default) or viewer
+}
+
+// Login signs in with a username and password
+// @Summary Sign in
+// @Description Start a session for an account. The session token is set as an HttpOnly cookie for the web UI and returned for API clients to send as a Bearer token. Sessions last SESSION_HOURS.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body loginRequest true "Username and password"
+// @Success 200 {object} dto.LoginResponse "Session"
+// @Failure 400 {object} dto.ErrorResponse "Username or password missing"
+// @Failure 401 {object} dto.ErrorResponse "Wrong username or password"
+// @Failure 404 {object} dto.ErrorResponse "WEB_UI_AUTH is off"
+// @Router /auth/login [post]
+func (serverHandler *ServerHandler) Login(c echo.Context) error {
+	if !serverHandler.authRequired() {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Accounts are not enabled; set WEB_UI_AUTH=true",
+			"code":  dto.CodeFeatureDisabled,
+		})
+	}
+	var request loginRequest
+	if err := c.Bind(&request); err != nil || request.Username == "" || request.Password == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Expected a JSON body with username and password",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	user, err := serverHandler.DB.GetUserByUsername(request.Username)
+	hash := dummyPasswordHash
+	if err == nil {
+		hash = []byte(user.PasswordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(request.Password)) != nil || err != nil {
+		Logger.Warn("Failed sign-in", "username", request.Username, "remote", c.RealIP())
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Wrong username or password",
+			"code":  dto.CodeUnauthorized,
+		})
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(raw)
+	now := database.Now()
+	session := &database.Session{
+		TokenHash: hashSessionToken(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(max(serverHandler.ServerConfig.SessionHours, 1)) * time.Hour),
+	}
+	if err := serverHandler.DB.DeleteExpiredSessions(now); err != nil {
+		Logger.Warn("Unable to remove expired sessions", "error", err)
+	}
+	if err := serverHandler.DB.CreateSession(session); err != nil {
+		Logger.Error("Failed to create session", "username", user.Username, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to sign in",
+			"code":  dto.CodeInternal,
+		})
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	Logger.Info("Signed in", "username", user.Username)
+	return c.JSON(http.StatusOK, dto.LoginResponse{
+		Token:     token,
+		Username:  user.Username,
+		ExpiresAt: session.ExpiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// Logout ends the request's session
+// @Summary Sign out
+// @Description End the current session and clear the session cookie.
+// @Tags Auth
+// @Success 204 "Signed out"
+// @Router /auth/logout [post]
+func (serverHandler *ServerHandler) Logout(c echo.Context) error {
+	if token := sessionToken(c); token != "" {
+		if err := serverHandler.DB.DeleteSession(hashSessionToken(token)); err != nil {
+			Logger.Warn("Unable to end session", "error", err)
+		}
+	}
+	c.SetCookie(&http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetAuthStatus says whether signing in is required and who is signed in
+// @Summary Sign-in status
+// @Description Whether WEB_UI_AUTH requires signing in, and the username of the session the request carries. The web UI sends users to the login page when one is required and nobody is signed in.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dto.AuthStatus "Sign-in status"
+// @Router /auth/status [get]
+func (serverHandler *ServerHandler) GetAuthStatus(c echo.Context) error {
+	status := dto.AuthStatus{AuthRequired: serverHandler.authRequired()}
+	if status.AuthRequired {
+		if user := serverHandler.sessionUser(sessionToken(c)); user != nil {
+			status.Username = user.Username
+		}
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+// userAdminOnly answers 403 for a request that needs an administrator to manage accounts
+func userAdminOnly(c echo.Context) error {
+	return c.JSON(http.StatusForbidden, map[string]interface{}{
+		"error": "Only administrators may manage accounts",
+		"code":  dto.CodeForbidden,
+	})
+}
+
+// hashNewPassword checks a new password is long enough and hashes it, or sends the error response
+func hashNewPassword(c echo.Context, password string) (string, bool, error) {
+	if len(password) < minPasswordLength {
+		return "", false, c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Password is too short",
+			"code":   dto.CodeValidation,
+			"fields": map[string]string{"password": "must be at least 8 characters"},
+		})
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", false, err
+	}
+	return string(hash), true, nil
+}
+
+// ListUsers returns every account
+// @Summary List accounts
+// @Description Every account in username order. Password hashes are never returned.
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} database.User "Accounts"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Router /users [get]
+func (serverHandler *ServerHandler) ListUsers(c echo.Context) error {
+	if !serverHandler.isAdmin(c) {
+		return userAdminOnly(c)
+	}
+	users, err := serverHandler.DB.ListUsers()
+	if err != nil {
+		Logger.Error("Failed to list users", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list users",
+			"code":  dto.CodeInternal,
+		})
+	}
+	if users == nil {
+		users = []database.User{}
+	}
+	return c.JSON(http.StatusOK, users)
+}
+
+// CreateUser adds an account
+// @Summary Add an account
+// @Description Add an account that may sign in, as an admin, editor (the default) or viewer. Passwords must be at least 8 characters.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body loginRequest true "Username, password and role"
+// @Success 201 {object} database.User "The new account"
+// @Failure 400 {object} dto.ErrorResponse "Username missing or password too short"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 409 {object} dto.ErrorResponse "Username taken"
+// @Router /users [post]
+func (serverHandler *ServerHandler) CreateUser(c echo.Context) error {
+	if !serverHandler.isAdmin(c) {
+		return userAdminOnly(c)
+	}
+	var request loginRequest
+	if err := c.Bind(&request); err != nil || strings.TrimSpace(request.Username) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Expected a JSON body with username and password",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	if request.Role != "" && !database.ValidRole(request.Role) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Unknown role",
+			"code":   dto.CodeValidation,
+			"fields": map[string]string{"role": "must be admin, editor or viewer"},
+		})
+	}
+	hash, ok, err := hashNewPassword(c, request.Password)
+	if !ok {
+		return err
+	}
+	user := &database.User{Username: strings.TrimSpace(request.Username), PasswordHash: hash, Role: request.Role}
+	if err := serverHandler.DB.CreateUser(user); errors.Is(err, database.ErrUsernameTaken) {
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": "Username is already taken",
+			"code":  dto.CodeConflict,
+		})
+	} else if err != nil {
+		Logger.Error("Failed to create user", "username", user.Username, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to create user",
+			"code":  dto.CodeInternal,
+		})
+	}
+	Logger.Info("Account created", "username", user.Username, "by", requestUser(c))
+	serverHandler.settingsChanged(c, "account "+user.Username)
+	return c.JSON(http.StatusCreated, user)
+}
+
+// DeleteUser removes an account and ends its sessions
+// @Summary Remove an account
+// @Description Remove an account and sign it out everywhere. You cannot remove your own account.
+// @Tags Auth
+// @Param id path string true "User ID"
+// @Success 204 "Removed"
+// @Failure 400 {object} dto.ErrorResponse "Your own account"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 404 {object} dto.ErrorResponse "No such account"
+// @Router /users/{id} [delete]
+func (serverHandler *ServerHandler) DeleteUser(c echo.Context) error {
+	if !serverHandler.isAdmin(c) {
+		return userAdminOnly(c)
+	}
+	user, err := serverHandler.DB.GetUser(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "User not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if user.Username == requestUser(c) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "You cannot remove your own account",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	if err := serverHandler.DB.DeleteUser(user.ID); err != nil {
+		Logger.Error("Failed to delete user", "username", user.Username, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to delete user",
+			"code":  dto.CodeInternal,
+		})
+	}
+	Logger.Info("Account removed", "username", user.Username, "by", requestUser(c))
+	serverHandler.settingsChanged(c, "account "+user.Username)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// roleRequest changes an account's role
+type roleRequest struct {
+	Role string `json:"role"`
+}
+
+// SetUserRole changes an account's role
+// @Summary Change a role
+// @Description Make an account an admin, editor or viewer. Administrators see and change every folder and manage accounts; editors and viewers see the folders their folder permissions allow, and viewers cannot change anything in them. You cannot change your own role.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body roleRequest true "New role"
+// @Success 200 {object} database.User "The account"
+// @Failure 400 {object} dto.ErrorResponse "Unknown role or your own account"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 404 {object} dto.ErrorResponse "No such account"
+// @Router /users/{id}/role [put]
+func (serverHandler *ServerHandler) SetUserRole(c echo.Context) error {
+	if !serverHandler.isAdmin(c) {
+		return userAdminOnly(c)
+	}
+	user, err := serverHandler.DB.GetUser(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "User not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	var request roleRequest
+	if err := c.Bind(&request); err != nil || !database.ValidRole(request.Role) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Unknown role",
+			"code":   dto.CodeValidation,
+			"fields": map[string]string{"role": "must be admin, editor or viewer"},
+		})
+	}
+	if user.Username == requestUser(c) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "You cannot change your own role",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	if err := serverHandler.DB.UpdateUserRole(user.ID, request.Role); err != nil {
+		Logger.Error("Failed to change role", "username", user.Username, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to change role",
+			"code":  dto.CodeInternal,
+		})
+	}
+	user.Role = request.Role
+	Logger.Info("Role changed", "username", user.Username, "role", user.Role, "by", requestUser(c))
+	serverHandler.settingsChanged(c, "account "+user.Username)
+	return c.JSON(http.StatusOK, user)
+}
+
+// passwordRequest changes a password
+type passwordRequest struct {
+	Password string `json:"password"`
+}
+
+// ChangePassword sets an account's password and signs it out everywhere else
+// @Summary Change a password
+// @Description Set the password of your own account, or, for administrators, anyone's. Every other session of the account is ended.
+// @Tags Auth
+// @Accept json
+// @Param id path string true "User ID"
+// @Param request body passwordRequest true "New password, at least 8 characters"
+// @Success 204 "Changed"
+// @Failure 400 {object} dto.ErrorResponse "Password too short"
+// @Failure 403 {object} dto.ErrorResponse "Another user's account"
+// @Failure 404 {object} dto.ErrorResponse "No such account"
+// @Router /users/{id}/password [put]
+func (serverHandler *ServerHandler) ChangePassword(c echo.Context) error {
+	user, err := serverHandler.DB.GetUser(c.Param("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "User not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if user.Username != requestUser(c) && !serverHandler.isAdmin(c) {
+		return userAdminOnly(c)
+	}
+	var request passwordRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Expected a JSON body with password",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	hash, ok, err := hashNewPassword(c, request.Password)
+	if !ok {
+		return err
+	}
+	if err := serverHandler.DB.UpdateUserPassword(user.ID, hash); err != nil {
+		Logger.Error("Failed to change password", "username", user.Username, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to change password",
+			"code":  dto.CodeInternal,
+		})
+	}
+	// Sign out everywhere else; the session making the change carries on
+	current, _ := serverHandler.DB.GetSession(hashSessionToken(sessionToken(c)))
+	serverHandler.DB.DeleteUserSessions(user.ID)
+	if current != nil && current.UserID == user.ID {
+		serverHandler.DB.CreateSession(current)
+	}
+	Logger.Info("Password changed", "username", user.Username, "by", requestUser(c))
+	return c.NoContent(http.StatusNoContent)
+}
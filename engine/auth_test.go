@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequireLogin(t *testing.T) {
+	// A server with WEB_UI_AUTH on, its first account from WEB_UI_USER, and alice as administrator
+	handler := setupTestHandler(t)
+	handler.ServerConfig.WebUIPass = true
+	handler.ServerConfig.ClientUsername = "alice"
+	handler.ServerConfig.ClientPassword = "correct horse"
+	handler.ServerConfig.SessionHours = 1
+	handler.ServerConfig.AdminUsers = []string{"alice"}
+	if err := handler.EnsureInitialUser(); err != nil {
+		t.Fatalf("EnsureInitialUser failed: %v", err)
+	}
+	handler.Echo.Use(handler.RequireLogin())
+	handler.Echo.POST("/api/auth/login", handler.Login)
+	handler.Echo.POST("/api/auth/logout", handler.Logout)
+	handler.Echo.GET("/api/auth/status", handler.GetAuthStatus)
+	handler.Echo.GET("/api/users", handler.ListUsers)
+	handler.Echo.POST("/api/users", handler.CreateUser)
+	handler.Echo.DELETE("/api/users/:id", handler.DeleteUser)
+	handler.Echo.PUT("/api/users/:id/password", handler.ChangePassword)
+	handler.Echo.GET("/api/collections", handler.ListCollections)
+	handler.Echo.GET("/", func(c echo.Context) error { return c.String(http.StatusOK, "app") })
+	serve := func(method, target, token, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec, response
+	}
+	login := func(username, password string) string {
+		t.Helper()
+		rec, response := serve(http.MethodPost, "/api/auth/login", "", `{"username":"`+username+`","password":"`+password+`"}`)
+		if rec.Code != http.StatusOK {
+			return ""
+		}
+		return response["token"].(string)
+	}
+
+	// Documents are asked for without signing in, and the web UI shell is loaded
+	rec, response := serve(http.MethodGet, "/api/collections", "", "")
+	shell, _ := serve(http.MethodGet, "/", "", "")
+
+	// The API refuses and the shell still loads so the login page can show
+	if rec.Code != http.StatusUnauthorized || response["code"] != string(dto.CodeUnauthorized) {
+		t.Errorf("Expected 401 without a session, got %d %v", rec.Code, response)
+	}
+	if shell.Code != http.StatusOK {
+		t.Errorf("Expected the web UI shell to load, got %d", shell.Code)
+	}
+
+	// Alice signs in with a wrong password, then the right one
+	wrong := login("alice", "wrong password")
+	token := login("alice", "correct horse")
+
+	// Only the right password gives a session, which sets a cookie and lets requests through
+	if wrong != "" || token == "" {
+		t.Fatalf("Expected only the right password to sign in, got %q and %q", wrong, token)
+	}
+	if rec, _ := serve(http.MethodGet, "/api/collections", token, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected a signed-in request to succeed, got %d", rec.Code)
+	}
+	if _, status := serve(http.MethodGet, "/api/auth/status", token, ""); status["authRequired"] != true || status["username"] != "alice" {
+		t.Errorf("Expected alice signed in, got %v", status)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"username":"alice","password":"correct horse"}`))
+	req.Header.Set("Content-Type", "application/json")
+	cookieRec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(cookieRec, req)
+	if cookie := cookieRec.Result().Cookies(); len(cookie) != 1 || cookie[0].Name != sessionCookie || !cookie[0].HttpOnly {
+		t.Errorf("Expected an HttpOnly session cookie, got %v", cookie)
+	}
+
+	// Alice adds bob, who signs in and tries to add an account of their own
+	rec, response = serve(http.MethodPost, "/api/users", token, `{"username":"bob","password":"bobs password"}`)
+	bobID, _ := response["id"].(string)
+	bobToken := login("bob", "bobs password")
+	refused, _ := serve(http.MethodPost, "/api/users", bobToken, `{"username":"carol","password":"carols password"}`)
+
+	// Bob's account is created without its password hash, and bob is not an administrator
+	if rec.Code != http.StatusCreated || bobID == "" || response["passwordHash"] != nil || bobToken == "" {
+		t.Fatalf("Expected bob to be created and signed in, got %d %v", rec.Code, response)
+	}
+	if refused.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for bob adding an account, got %d", refused.Code)
+	}
+	if rec, _ := serve(http.MethodPost, "/api/users", token, `{"username":"bob","password":"another one"}`); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a taken username, got %d", rec.Code)
+	}
+
+	// Bob changes their password to one too short, then to a good one
+	short, _ := serve(http.MethodPut, "/api/users/"+bobID+"/password", bobToken, `{"password":"short"}`)
+	changed, _ := serve(http.MethodPut, "/api/users/"+bobID+"/password", bobToken, `{"password":"a better password"}`)
+
+	// The short one is refused and the new one works, with the old one no longer signing in
+	if short.Code != http.StatusBadRequest || changed.Code != http.StatusNoContent {
+		t.Errorf("Expected 400 then 204, got %d and %d", short.Code, changed.Code)
+	}
+	if login("bob", "bobs password") != "" || login("bob", "a better password") == "" {
+		t.Error("Expected only the new password to sign in")
+	}
+
+	// Alice removes bob twice, and tries to remove the account alice is signed in with
+	removed, _ := serve(http.MethodDelete, "/api/users/"+bobID, token, "")
+	again, _ := serve(http.MethodDelete, "/api/users/"+bobID, token, "")
+	alice, _ := handler.DB.GetUserByUsername("alice")
+	own, _ := serve(http.MethodDelete, "/api/users/"+alice.ID, token, "")
+
+	// Bob's account and sessions are gone, the second removal finds nothing, and alice's stays
+	if removed.Code != http.StatusNoContent || again.Code != http.StatusNotFound || own.Code != http.StatusBadRequest {
+		t.Errorf("Expected 204, 404 then 400, got %d, %d and %d", removed.Code, again.Code, own.Code)
+	}
+	if rec, _ := serve(http.MethodGet, "/api/collections", bobToken, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected bob's session to end with the account, got %d", rec.Code)
+	}
+
+	// Alice signs out
+	serve(http.MethodPost, "/api/auth/logout", token, "")
+
+	// The token no longer works
+	if rec, _ := serve(http.MethodGet, "/api/collections", token, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 after signing out, got %d", rec.Code)
+	}
+}
+
+func TestRequireLoginOffByDefault(t *testing.T) {
+	// A server without WEB_UI_AUTH
+	handler := setupTestHandler(t)
+	handler.Echo.Use(handler.RequireLogin())
+	handler.Echo.GET("/api/collections", handler.ListCollections)
+	handler.Echo.POST("/api/auth/login", handler.Login)
+
+	// Documents are asked for without signing in
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/collections", nil))
+
+	// They are served and no account was created
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 without WEB_UI_AUTH, got %d", rec.Code)
+	}
+	if err := handler.EnsureInitialUser(); err != nil {
+		t.Fatal(err)
+	}
+	if users, _ := handler.DB.ListUsers(); len(users) != 0 {
+		t.Errorf("Expected no accounts, got %v", users)
+	}
+}
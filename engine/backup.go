@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/oklog/ulid/v2"
+)
+
+// backupFileName names a backup by when it was taken, so backups sort in time order
+func backupFileName(at time.Time) string {
+	return "godocs-" + at.Format("20060102-150405") + ".ndjson"
+}
+
+// scheduledBackup writes a metadata backup from the scheduler
+func (serverHandler *ServerHandler) scheduledBackup() {
+	job, err := serverHandler.startJob(database.JobTypeBackup, "Starting scheduled backup")
+	if errors.Is(err, errJobActive) {
+		Logger.Info("Skipping scheduled backup, one is already active", "jobID", job.ID.String())
+		return
+	}
+	if err != nil {
+		Logger.Error("Failed to create backup job", "error", err)
+		return
+	}
+	serverHandler.backupJobFunc(serverHandler.jobDB(), job.ID)
+}
+
+// backupJobFunc exports every document, with its full text, as NDJSON into BACKUP_PATH.
+// The export is written to a temporary file first, so a backup that fails part way never looks complete.
+func (serverHandler *ServerHandler) backupJobFunc(db database.Repository, jobID ulid.ULID) {
+	db.UpdateJobStatus(jobID, database.JobStatusRunning, "Exporting documents")
+
+	path, exported, err := serverHandler.writeBackup(database.Now(), newJobHeartbeat(db, jobID))
+	if err != nil {
+		Logger.Error("Backup failed", "exported", exported, "error", err)
+		db.UpdateJobError(jobID, fmt.Sprintf("Backup failed after %d documents: %v", exported, err))
+		return
+	}
+	result, _ := json.Marshal(map[string]interface{}{"file": path, "documents": exported})
+	if err := db.CompleteJob(jobID, string(result)); err != nil {
+		Logger.Error("Failed to mark backup job as complete", "error", err)
+	}
+	Logger.Info("Backup completed", "file", path, "documents", exported)
+}
+
+// writeBackup writes the backup file for the given time, returning its path and how many documents it holds
+func (serverHandler *ServerHandler) writeBackup(at time.Time, heartbeat *jobHeartbeat) (string, int, error) {
+	folder := serverHandler.ServerConfig.BackupPath
+	if folder == "" {
+		return "", 0, fmt.Errorf("no BACKUP_PATH is set")
+	}
+	if err := os.MkdirAll(folder, os.ModePerm); err != nil {
+		return "", 0, err
+	}
+	temp, err := os.CreateTemp(folder, ".godocs-backup-*.partial")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(temp.Name()) // no-op once renamed
+
+	writer := bufio.NewWriter(temp)
+	exported, err := serverHandler.writeDocumentsNDJSON(context.Background(), writer, heartbeat.beat, nil, true)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", exported, err
+	}
+	path := filepath.Join(folder, backupFileName(at))
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return "", exported, err
+	}
+	return path, exported, nil
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"net/http"
+	"strings"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// blankPagePolicy is what ingestion does about blank pages in scans, set by BLANK_PAGES
+type blankPagePolicy string
+
+const (
+	blankPagesOff    blankPagePolicy = "off"    // pages are not checked
+	blankPagesDetect blankPagePolicy = "detect" // blank pages are recorded but kept
+	blankPagesRemove blankPagePolicy = "remove" // blank pages are recorded and dropped from scanned PDFs
+)
+
+// blankPageMargin is the share of each edge left out when measuring ink, where scanners leave shadows,
+// punch holes and staples
+const blankPageMargin = 0.05
+
+// inkLevel is the grey level, out of 255, below which a pixel counts as ink
+const inkLevel = 128
+
+// parseBlankPagePolicy reads BLANK_PAGES; an empty value is off
+func parseBlankPagePolicy(value string) (blankPagePolicy, error) {
+	switch policy := blankPagePolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "", blankPagesOff:
+		return blankPagesOff, nil
+	case blankPagesDetect, blankPagesRemove:
+		return policy, nil
+	}
+	return blankPagesOff, fmt.Errorf("unknown BLANK_PAGES %q (expected off, detect or remove)", value)
+}
+
+// blankPages returns the configured blank page policy, off when BLANK_PAGES is not understood
+func (serverHandler *ServerHandler) blankPages() blankPagePolicy {
+	policy, err := parseBlankPagePolicy(serverHandler.ServerConfig.BlankPages)
+	if err != nil {
+		Logger.Warn("Not checking for blank pages", "error", err)
+	}
+	return policy
+}
+
+// inkCoverage returns the percent of a page, less its margins, that is inked
+func inkCoverage(page image.Image) float64 {
+	bounds := page.Bounds()
+	marginX := int(float64(bounds.Dx()) * blankPageMargin)
+	marginY := int(float64(bounds.Dy()) * blankPageMargin)
+	inner := image.Rect(bounds.Min.X+marginX, bounds.Min.Y+marginY, bounds.Max.X-marginX, bounds.Max.Y-marginY)
+	if inner.Empty() {
+		return 0
+	}
+	inked := 0
+	for y := inner.Min.Y; y < inner.Max.Y; y++ {
+		for x := inner.Min.X; x < inner.Max.X; x++ {
+			if color.GrayModel.Convert(page.At(x, y)).(color.Gray).Y < inkLevel {
+				inked++
+			}
+		}
+	}
+	return 100 * float64(inked) / float64(inner.Dx()*inner.Dy())
+}
+
+// isBlankPage reports whether a page has less ink than BLANK_PAGE_INK percent, and how much it has
+func (serverHandler *ServerHandler) isBlankPage(page image.Image) (bool, float64) {
+	ink := inkCoverage(page)
+	return ink < serverHandler.ServerConfig.BlankPageInk, ink
+}
+
+// documentBlankPages is the answer to GET /api/document/:id/blank-pages
+type documentBlankPages struct {
+	DocumentULID string               `json:"documentId"`
+	Pages        []database.BlankPage `json:"pages"`
+	Removed      int                  `json:"removed"` // pages dropped from the stored file
+}
+
+// GetDocumentBlankPages returns the blank pages found in a document when it was ingested
+// @Summary Blank pages
+// @Description The pages of a scan found blank at ingestion with BLANK_PAGES set, numbered as scanned, with the percent of each that was inked and whether it was dropped from the stored file. Empty for a document with no blank pages or ingested without the check.
+// @Tags Documents
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Success 200 {object} engine.documentBlankPages "Blank pages"
+// @Failure 400 {object} dto.ErrorResponse "Invalid ULID"
+// @Failure 404 {object} dto.ErrorResponse "Document not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /document/{id}/blank-pages [get]
+func (serverHandler *ServerHandler) GetDocumentBlankPages(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "document")
+	if !ok {
+		return err
+	}
+	doc, err := serverHandler.DB.GetDocumentByULID(id.String())
+	if err != nil || doc == nil || serverHandler.hiddenDocument(c, id.String()) {
+		return documentNotFound(c)
+	}
+	pages, err := serverHandler.DB.GetBlankPages(doc.ULID.String())
+	if err != nil {
+		Logger.Error("Failed to get blank pages", "ulid", doc.ULID.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to get blank pages",
+			"code":  dto.CodeInternal,
+		})
+	}
+	response := documentBlankPages{DocumentULID: doc.ULID.String(), Pages: pages}
+	for _, page := range pages {
+		if page.Removed {
+			response.Removed++
+		}
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/drummonds/godocs/database"
+	"github.com/jung-kurt/gofpdf"
+)
+
+func TestInkCoverage(t *testing.T) {
+	// A white page with a punch hole in its margin
+	page := imaging.New(200, 100, color.White)
+	draw.Draw(page, image.Rect(0, 0, 5, 5), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	// The margin is not counted, so the page has no ink
+	if ink := inkCoverage(page); ink != 0 {
+		t.Errorf("Expected no ink inside the margins, got %v%%", ink)
+	}
+
+	// Once the left half of the inside is inked, so is half the page
+	draw.Draw(page, image.Rect(10, 5, 100, 95), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	if ink := inkCoverage(page); ink != 50 {
+		t.Errorf("Expected 50%% ink, got %v%%", ink)
+	}
+}
+
+func TestParseBlankPagePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    blankPagePolicy
+		wantErr bool
+	}{
+		{"mixed case", "Remove", blankPagesRemove, false},
+		{"empty is off", "", blankPagesOff, false},
+		{"unknown policy", "drop", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := parseBlankPagePolicy(tt.value)
+			if (err != nil) != tt.wantErr || (!tt.wantErr && policy != tt.want) {
+				t.Errorf("parseBlankPagePolicy(%q) = %q, %v", tt.value, policy, err)
+			}
+		})
+	}
+}
+
+// writeDuplexScan writes a PDF without a text layer whose pages are inked, or left empty like the back
+// of a one-sided sheet through a duplex scanner
+func writeDuplexScan(t *testing.T, path string, inked ...bool) {
+	t.Helper()
+	out := gofpdf.New("P", "pt", "A4", "")
+	for _, ink := range inked {
+		out.AddPage()
+		if ink {
+			out.Rect(72, 72, 200, 100, "F")
+		}
+	}
+	if err := out.OutputFileAndClose(path); err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+}
+
+func TestPrepareScanRemovesBlankPages(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping PDFium rendering test in short mode")
+	}
+	// BLANK_PAGES=remove with AUTO_ROTATE, and two sideways sheets scanned duplex
+	handler := setupTestHandler(t)
+	handler.ServerConfig.BlankPages = "remove"
+	handler.ServerConfig.BlankPageInk = 0.1
+	handler.ServerConfig.TesseractPath = fakeOSDTesseract(t, osdOutput)
+	handler.ServerConfig.AutoRotate = true
+	handler.Echo.GET("/api/document/:id/blank-pages", handler.GetDocumentBlankPages)
+	path := filepath.Join(handler.ServerConfig.IngressPath, "letters.pdf")
+	writeDuplexScan(t, path, true, false, true, false)
+
+	// The scan is prepared
+	timeline := startTimeline("test")
+	changed := handler.prepareScan(path, timeline)
+
+	// The empty backs are gone and the rotations are numbered as the pages are now stored
+	if !changed || pdfPageCount(path) != 2 {
+		t.Fatalf("Expected the PDF rewritten with 2 pages, got %d pages (changed %v)", pdfPageCount(path), changed)
+	}
+	var removed []int
+	for _, page := range timeline.blankPages {
+		if page.Removed {
+			removed = append(removed, page.Page)
+		}
+	}
+	if !slices.Equal(removed, []int{2, 4}) {
+		t.Errorf("Expected pages 2 and 4 removed, got %+v", timeline.blankPages)
+	}
+	if want := []database.PageRotation{{Page: 1, Degrees: 90}, {Page: 2, Degrees: 90}}; !slices.Equal(timeline.rotations, want) {
+		t.Errorf("Expected %v, got %v", want, timeline.rotations)
+	}
+
+	// Once saved against the document, the count removed is served
+	doc := saveTestDocument(t, handler.DB, path, "")
+	timeline.save(handler.DB, doc.ULID)
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/document/"+doc.ULID.String()+"/blank-pages", nil))
+	var response documentBlankPages
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with JSON, got %d %s", rec.Code, rec.Body)
+	}
+	if response.Removed != 2 || len(response.Pages) != 2 {
+		t.Errorf("Expected 2 pages removed, got %+v", response)
+	}
+}
+
+func TestPrepareScanKeepsBlankPages(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping PDFium rendering test in short mode")
+	}
+	// BLANK_PAGES=detect, and a scan with an empty back
+	handler := setupTestHandler(t)
+	handler.ServerConfig.BlankPages = "detect"
+	handler.ServerConfig.BlankPageInk = 0.1
+	path := filepath.Join(handler.ServerConfig.IngressPath, "form.pdf")
+	writeDuplexScan(t, path, true, false)
+
+	// The scan is prepared
+	timeline := startTimeline("test")
+	changed := handler.prepareScan(path, timeline)
+
+	// The blank page is recorded and kept
+	if changed || pdfPageCount(path) != 2 || len(timeline.blankPages) != 1 || timeline.blankPages[0].Removed {
+		t.Errorf("Expected page 2 found and kept, got %+v (changed %v)", timeline.blankPages, changed)
+	}
+
+	// Removing never empties a document that is blank throughout
+	handler.ServerConfig.BlankPages = "remove"
+	path = filepath.Join(handler.ServerConfig.IngressPath, "empty.pdf")
+	writeDuplexScan(t, path, false, false)
+	timeline = startTimeline("test")
+	if handler.prepareScan(path, timeline) || pdfPageCount(path) != 2 || len(timeline.blankPages) != 2 || timeline.blankPages[0].Removed {
+		t.Errorf("Expected both pages kept, got %+v", timeline.blankPages)
+	}
+}
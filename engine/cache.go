@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/drummonds/godocs/cache"
+	"github.com/labstack/echo/v4"
+)
+
+// cachedResponse returns the cached body for key, if caching is enabled and it is present
+func (serverHandler *ServerHandler) cachedResponse(c echo.Context, key string) ([]byte, bool) {
+	if serverHandler.Cache == nil {
+		return nil, false
+	}
+	return serverHandler.Cache.Get(c.Request().Context(), key)
+}
+
+// cacheJSON stores payload under key and writes it as the JSON response
+func (serverHandler *ServerHandler) cacheJSON(c echo.Context, key string, payload interface{}) error {
+	if serverHandler.Cache == nil {
+		return c.JSON(http.StatusOK, payload)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return c.JSON(http.StatusOK, payload)
+	}
+	serverHandler.Cache.Set(c.Request().Context(), key, body)
+	return c.JSONBlob(http.StatusOK, body)
+}
+
+// invalidateDocumentCache drops every cached payload derived from the document set.
+// Call it after anything that adds, moves or removes documents.
+func (serverHandler *ServerHandler) invalidateDocumentCache() {
+	if serverHandler.isWorker {
+		serverHandler.reportWorkerChange()
+	}
+	if serverHandler.Cache == nil {
+		return
+	}
+	serverHandler.Cache.DeletePrefix(context.Background(), cache.DocumentKeys...)
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/cache"
+)
+
+func TestLatestDocumentsCacheInvalidatedOnMove(t *testing.T) {
+	// A handler with an in-memory cache and one document
+	handler := setupTestHandler(t)
+	handler.Cache = cache.NewLRU(10, 0)
+	doc := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "first.pdf"), "")
+	latest := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/api/documents/latest", nil)
+		rec := httptest.NewRecorder()
+		if err := handler.GetLatestDocuments(handler.Echo.NewContext(req, rec)); err != nil {
+			t.Fatalf("GetLatestDocuments failed: %v", err)
+		}
+		return rec.Body.String()
+	}
+	latest()
+
+	// The document is changed behind the cache, then moved via the API
+	saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "second.pdf"), "")
+	if strings.Contains(latest(), "second.pdf") {
+		t.Fatal("Expected the cached response before invalidation")
+	}
+	req := httptest.NewRequest(http.MethodPatch, "/api/document/move/?folder=archive&id="+doc.ULID.String(), nil)
+	rec := httptest.NewRecorder()
+	if err := handler.MoveDocuments(handler.Echo.NewContext(req, rec)); err != nil {
+		t.Fatalf("MoveDocuments failed: %v", err)
+	}
+
+	// The next request sees the fresh document list
+	if !strings.Contains(latest(), "second.pdf") {
+		t.Error("Expected the cache to be invalidated after a move")
+	}
+}
@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+)
+
+func TestCleanupDryRunChangesNothing(t *testing.T) {
+	// A database entry whose file is missing and an orphaned file on disk
+	handler := setupTestHandler(t)
+	missing := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "missing.pdf"), "")
+	orphanPath := filepath.Join(handler.ServerConfig.DocumentPath, "orphan.pdf")
+	if err := os.WriteFile(orphanPath, []byte("%PDF-1.4"), 0644); err != nil {
+		t.Fatalf("Failed to write orphan: %v", err)
+	}
+	job, err := handler.DB.CreateJob(database.JobTypeCleanup, "test")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	// Running the cleanup as a dry run
+	handler.cleanupJobFuncWithTracking(handler.DB, job.ID, true, OrphanPolicyIngress)
+
+	// Nothing is deleted or moved, and the report lists both problems
+	if _, err := handler.DB.GetDocumentByULID(missing.ULID.String()); err != nil {
+		t.Errorf("Dry run deleted the database entry: %v", err)
+	}
+	if _, err := os.Stat(orphanPath); err != nil {
+		t.Errorf("Dry run moved the orphaned file: %v", err)
+	}
+
+	completed, err := handler.DB.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	var report cleanupReport
+	if err := json.Unmarshal([]byte(completed.Result), &report); err != nil {
+		t.Fatalf("Job result is not a cleanup report: %v (%s)", err, completed.Result)
+	}
+	if !report.DryRun || report.Deleted != 0 || report.Moved != 0 {
+		t.Errorf("Unexpected dry run counts: %+v", report)
+	}
+	if len(report.MissingFiles) != 1 || report.MissingFiles[0].ULID != missing.ULID.String() {
+		t.Errorf("Expected missing document in report, got %+v", report.MissingFiles)
+	}
+	if len(report.OrphanedFiles) != 1 || report.OrphanedFiles[0] != orphanPath {
+		t.Errorf("Expected orphan in report, got %+v", report.OrphanedFiles)
+	}
+}
+
+func TestCleanupAppliesChanges(t *testing.T) {
+	// A database entry whose file is missing
+	handler := setupTestHandler(t)
+	missing := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "missing.pdf"), "")
+	job, err := handler.DB.CreateJob(database.JobTypeCleanup, "test")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	// Running the cleanup for real
+	handler.cleanupJobFuncWithTracking(handler.DB, job.ID, false, OrphanPolicyIngress)
+
+	// The entry is removed and counted
+	if _, err := handler.DB.GetDocumentByULID(missing.ULID.String()); err == nil {
+		t.Error("Expected missing document to be removed from the database")
+	}
+	completed, err := handler.DB.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	var report cleanupReport
+	if err := json.Unmarshal([]byte(completed.Result), &report); err != nil {
+		t.Fatalf("Job result is not a cleanup report: %v", err)
+	}
+	if report.DryRun || report.Deleted != 1 {
+		t.Errorf("Expected one deletion, got %+v", report)
+	}
+}
+
+func TestCleanupRelinksOrphansInPlace(t *testing.T) {
+	// An orphaned file with a companion OCR text file
+	handler := setupTestHandler(t)
+	orphanPath := filepath.Join(handler.ServerConfig.DocumentPath, "orphan.pdf")
+	if err := os.WriteFile(orphanPath, []byte("%PDF-1.4 relink"), 0644); err != nil {
+		t.Fatalf("Failed to write orphan: %v", err)
+	}
+	if err := os.WriteFile(orphanPath+".txt", []byte("recovered text"), 0644); err != nil {
+		t.Fatalf("Failed to write companion text: %v", err)
+	}
+	job, err := handler.DB.CreateJob(database.JobTypeCleanup, "test")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	// Running the cleanup with the relink policy
+	handler.cleanupJobFuncWithTracking(handler.DB, job.ID, false, OrphanPolicyRelink)
+
+	// The file stays put and gains a database entry carrying the companion text
+	if _, err := os.Stat(orphanPath); err != nil {
+		t.Errorf("Relink moved the orphaned file: %v", err)
+	}
+	doc, err := handler.DB.GetDocumentByPath(filepath.ToSlash(orphanPath))
+	if err != nil {
+		t.Fatalf("Expected relinked document in database: %v", err)
+	}
+	if doc.FullText != "recovered text" {
+		t.Errorf("Expected companion text to be reused, got %q", doc.FullText)
+	}
+	completed, err := handler.DB.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	var report cleanupReport
+	if err := json.Unmarshal([]byte(completed.Result), &report); err != nil {
+		t.Fatalf("Job result is not a cleanup report: %v", err)
+	}
+	if report.OrphanPolicy != OrphanPolicyRelink || report.Relinked != 1 || report.Moved != 0 {
+		t.Errorf("Unexpected relink counts: %+v", report)
+	}
+}
+
+func TestCleanupReportOnlyLeavesOrphans(t *testing.T) {
+	// An orphaned file
+	handler := setupTestHandler(t)
+	orphanPath := filepath.Join(handler.ServerConfig.DocumentPath, "orphan.pdf")
+	if err := os.WriteFile(orphanPath, []byte("%PDF-1.4"), 0644); err != nil {
+		t.Fatalf("Failed to write orphan: %v", err)
+	}
+	job, err := handler.DB.CreateJob(database.JobTypeCleanup, "test")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	// Running the cleanup with the report policy
+	handler.cleanupJobFuncWithTracking(handler.DB, job.ID, false, OrphanPolicyReport)
+
+	// The orphan is neither moved nor linked
+	if _, err := os.Stat(orphanPath); err != nil {
+		t.Errorf("Report policy moved the orphaned file: %v", err)
+	}
+	if _, err := handler.DB.GetDocumentByPath(filepath.ToSlash(orphanPath)); err == nil {
+		t.Error("Report policy created a database entry")
+	}
+}
+
+func TestParseOrphanPolicy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    OrphanPolicy
+		wantErr bool
+	}{
+		{"", OrphanPolicyIngress, false},
+		{"ingress", OrphanPolicyIngress, false},
+		{"RELINK", OrphanPolicyRelink, false},
+		{"report", OrphanPolicyReport, false},
+		{"delete", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseOrphanPolicy(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseOrphanPolicy(%q) = %q, %v; want %q, err=%v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
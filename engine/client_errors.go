@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// clientErrorWindow is the period the report limits count over
+	clientErrorWindow = time.Minute
+	// clientErrorsPerClient is how many error reports one address may send in a window, enough for a
+	// burst from one broken page without a looping one filling the table
+	clientErrorsPerClient = 10
+	// clientErrorsPerWindow is how many error reports are accepted in a window from everyone together
+	clientErrorsPerWindow = 100
+	// clientErrorsKept is how many error reports are kept; older ones are removed as new ones arrive
+	clientErrorsKept = 1000
+	// defaultClientErrors is the number of error reports listed when no limit is given
+	defaultClientErrors = 50
+)
+
+// Longest stored value of each error report field, in bytes; longer values are cut short
+const (
+	maxClientErrorMessage = 2000
+	maxClientErrorStack   = 16000
+	maxClientErrorField   = 500
+)
+
+// clientErrorLimiter counts the error reports accepted in the current window, per address and in total
+type clientErrorLimiter struct {
+	mu      sync.Mutex
+	started time.Time
+	counts  map[string]int
+	total   int
+}
+
+// allow counts a report from address at now, saying false when it is over either limit
+func (l *clientErrorLimiter) allow(address string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts == nil || now.Sub(l.started) >= clientErrorWindow {
+		l.started, l.counts, l.total = now, make(map[string]int), 0
+	}
+	if l.total >= clientErrorsPerWindow || l.counts[address] >= clientErrorsPerClient {
+		return false
+	}
+	l.counts[address]++
+	l.total++
+	return true
+}
+
+// truncateUTF8 cuts s to at most max bytes without splitting a character
+func truncateUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[:max]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// ReportClientError stores an error the web UI caught in the browser
+// @Summary Report a web UI error
+// @Description Called by the web UI for a Go panic, an uncaught JavaScript error or promise rejection, or an API call that failed, so problems users hit show up for administrators. Reports are limited to 10 a minute per address and 100 a minute in all; the newest 1000 are kept.
+// @Tags System
+// @Accept json
+// @Param report body dto.ClientErrorReport true "What went wrong"
+// @Success 204 "Stored"
+// @Failure 400 {object} dto.ErrorResponse "Malformed report"
+// @Failure 422 {object} dto.ErrorResponse "Unknown kind or no message"
+// @Failure 429 {object} dto.ErrorResponse "Too many reports"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /client-errors [post]
+func (serverHandler *ServerHandler) ReportClientError(c echo.Context) error {
+	var report dto.ClientErrorReport
+	if err := c.Bind(&report); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid error report",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	fields := map[string]string{}
+	switch report.Kind {
+	case database.ClientErrorPanic, database.ClientErrorError, database.ClientErrorRejection, database.ClientErrorFetch:
+	default:
+		fields["kind"] = "must be panic, error, rejection or fetch"
+	}
+	if report.Message == "" {
+		fields["message"] = "is required"
+	}
+	if len(fields) > 0 {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":  "Invalid error report",
+			"code":   dto.CodeValidation,
+			"fields": fields,
+		})
+	}
+	if !serverHandler.clientErrors.allow(c.RealIP(), time.Now()) {
+		return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+			"error": "Too many error reports, try again in a minute",
+			"code":  dto.CodeRateLimited,
+		})
+	}
+
+	stored := database.ClientError{
+		Kind:      report.Kind,
+		Message:   truncateUTF8(report.Message, maxClientErrorMessage),
+		Stack:     truncateUTF8(report.Stack, maxClientErrorStack),
+		Page:      truncateUTF8(report.Page, maxClientErrorField),
+		UserAgent: truncateUTF8(c.Request().UserAgent(), maxClientErrorField),
+		User:      requestUser(c),
+	}
+	if err := serverHandler.DB.RecordClientError(&stored); err != nil {
+		Logger.Error("Failed to record client error", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to record the error report",
+			"code":  dto.CodeInternal,
+		})
+	}
+	Logger.Warn("Web UI reported an error", "kind", stored.Kind, "message", stored.Message, "page", stored.Page, "user", stored.User)
+	if _, err := serverHandler.DB.PruneClientErrors(clientErrorsKept); err != nil {
+		Logger.Warn("Unable to prune client errors", "error", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetClientErrors lists the errors the web UI reported
+// @Summary Web UI errors
+// @Description The errors the web UI reported from users' browsers, newest first. With sign-in or ADMIN_USERS only administrators may read them.
+// @Tags Admin
+// @Produce json
+// @Param limit query int false "Most recent reports to return (default 50, at most 200)"
+// @Success 200 {object} dto.ClientErrors "Reports, newest first"
+// @Failure 400 {object} dto.ErrorResponse "Invalid limit"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /admin/client-errors [get]
+func (serverHandler *ServerHandler) GetClientErrors(c echo.Context) error {
+	if serverHandler.notAdmin(c) {
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": "Only administrators may read web UI errors",
+			"code":  dto.CodeForbidden,
+		})
+	}
+	limit, err := limitParam(c, defaultClientErrors)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	reports, err := serverHandler.DB.ListClientErrors(limit)
+	if err != nil {
+		Logger.Error("Failed to list client errors", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list web UI errors",
+			"code":  dto.CodeInternal,
+		})
+	}
+	response := dto.ClientErrors{Errors: make([]dto.ClientError, 0, len(reports))}
+	for _, report := range reports {
+		response.Errors = append(response.Errors, dto.ClientError{
+			ID:         report.ID,
+			Kind:       report.Kind,
+			Message:    report.Message,
+			Stack:      report.Stack,
+			Page:       report.Page,
+			UserAgent:  report.UserAgent,
+			User:       report.User,
+			ReportedAt: report.ReportedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return c.JSON(http.StatusOK, response)
+}
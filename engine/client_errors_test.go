@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+func TestClientErrorReports(t *testing.T) {
+	// A read-only server with alice as its administrator
+	handler := setupTestHandler(t)
+	handler.ServerConfig.ReadOnly = true
+	handler.ServerConfig.AdminUsers = []string{"alice"}
+	handler.Echo.Use(handler.ReadOnlyGuard())
+	handler.Echo.POST("/api/client-errors", handler.ReportClientError)
+	handler.Echo.GET("/api/admin/client-errors", handler.GetClientErrors)
+	serve := func(method, target, user, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "TestBrowser/1.0")
+		if user != "" {
+			req.SetBasicAuth(user, "secret")
+		}
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Bob's browser reports a panic, and a report without a message is sent
+	stack := strings.Repeat("goroutine 1 [running]:\n", 1000)
+	report, _ := json.Marshal(dto.ClientErrorReport{Kind: "panic", Message: "index out of range", Stack: stack, Page: "/search"})
+	stored := serve(http.MethodPost, "/api/client-errors", "bob", string(report))
+	invalid := serve(http.MethodPost, "/api/client-errors", "bob", `{"kind":"oops"}`)
+
+	// The panic is stored even in read-only mode and the empty report is refused
+	if stored.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d %s", stored.Code, stored.Body)
+	}
+	if invalid.Code != http.StatusUnprocessableEntity || !strings.Contains(invalid.Body.String(), "message") {
+		t.Errorf("Expected 422 naming the message, got %d %s", invalid.Code, invalid.Body)
+	}
+
+	// Bob and alice list the reports
+	refused, listed := serve(http.MethodGet, "/api/admin/client-errors", "bob", ""), serve(http.MethodGet, "/api/admin/client-errors", "alice", "")
+
+	// Only alice sees bob's panic, with the stack cut short
+	if refused.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for bob, got %d", refused.Code)
+	}
+	var response dto.ClientErrors
+	if err := json.Unmarshal(listed.Body.Bytes(), &response); err != nil || listed.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with JSON, got %d %s", listed.Code, listed.Body)
+	}
+	if len(response.Errors) != 1 {
+		t.Fatalf("Expected one report, got %+v", response.Errors)
+	}
+	got := response.Errors[0]
+	if got.Kind != "panic" || got.Message != "index out of range" || got.Page != "/search" || got.User != "bob" || got.UserAgent != "TestBrowser/1.0" {
+		t.Errorf("Expected bob's panic on /search, got %+v", got)
+	}
+	if len(got.Stack) != maxClientErrorStack {
+		t.Errorf("Expected the stack cut to %d bytes, got %d", maxClientErrorStack, len(got.Stack))
+	}
+
+	// The same browser keeps reporting
+	var last *httptest.ResponseRecorder
+	for i := 0; i < clientErrorsPerClient; i++ {
+		last = serve(http.MethodPost, "/api/client-errors", "bob", fmt.Sprintf(`{"kind":"fetch","message":"GET /api/search answered 500 (%d)"}`, i))
+	}
+
+	// Reports over the limit are refused
+	if last.Code != http.StatusTooManyRequests || !strings.Contains(last.Body.String(), string(dto.CodeRateLimited)) {
+		t.Errorf("Expected 429 rate limited, got %d %s", last.Code, last.Body)
+	}
+
+	// With sign-in and no ADMIN_USERS, a viewer account may not list the reports
+	handler.ServerConfig.AdminUsers = nil
+	handler.ServerConfig.WebUIPass = true
+	carol := signInAs(t, handler, "carol", database.RoleViewer)
+	handler.Echo.Use(handler.RequireLogin())
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/client-errors", nil)
+	req.Header.Set("Authorization", "Bearer "+carol)
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a viewer, got %d", rec.Code)
+	}
+}
+
+func TestClientErrorLimiter(t *testing.T) {
+	// One address that has used up its reports
+	var limiter clientErrorLimiter
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < clientErrorsPerClient; i++ {
+		if !limiter.allow("10.0.0.1", start) {
+			t.Fatalf("Report %d refused under the limit", i)
+		}
+	}
+
+	// It is refused, another address is not, and the next window starts afresh
+	if limiter.allow("10.0.0.1", start.Add(time.Second)) {
+		t.Error("Expected the address to be over its limit")
+	}
+	if !limiter.allow("10.0.0.2", start.Add(time.Second)) {
+		t.Error("Expected another address to be allowed")
+	}
+	if !limiter.allow("10.0.0.1", start.Add(clientErrorWindow)) {
+		t.Error("Expected the address to be allowed in the next window")
+	}
+
+	// Everyone together is held to the overall limit
+	limiter = clientErrorLimiter{}
+	for i := 0; i < clientErrorsPerWindow; i++ {
+		limiter.allow(fmt.Sprintf("10.0.%d.%d", i/200, i%200), start)
+	}
+	if limiter.allow("10.1.0.1", start) {
+		t.Error("Expected a new address to be refused once the overall limit is reached")
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	tests := []struct {
+		value string
+		max   int
+		want  string
+	}{
+		{"café", 4, "caf"}, // é is two bytes, so it is dropped rather than split
+		{"café", 5, "café"},
+		{"report", 3, "rep"},
+	}
+	for _, tt := range tests {
+		if got := truncateUTF8(tt.value, tt.max); got != tt.want {
+			t.Errorf("truncateUTF8(%q, %d) = %q, want %q", tt.value, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package engine
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// collectionRequest is the body of CreateCollection. Without documentIds the current results of term
+// are snapshotted.
+type collectionRequest struct {
+	Name        string   `json:"name"`
+	Term        string   `json:"term"`
+	DocumentIDs []string `json:"documentIds"`
+	Share       bool     `json:"share"`
+}
+
+// newShareToken returns a random, URL-safe token for a shared collection link
+func newShareToken() (string, error) {
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// collectionResponse describes a collection with the links to it
+func collectionResponse(collection *database.Collection) map[string]interface{} {
+	response := map[string]interface{}{
+		"collection": collection,
+		"url":        "/api/collections/" + collection.ULID.String(),
+	}
+	if collection.ShareToken != "" {
+		response["shareURL"] = "/api/shared/" + collection.ShareToken
+	}
+	return response
+}
+
+// CreateCollection saves a set of documents as a named collection
+// @Summary Create a collection
+// @Description Snapshot documents into a named, immutable collection: either the given document ULIDs, in order, or the current results of a search term.
+// @Description With share set, the collection also gets a share token so it can be opened by anyone with the link.
+// @Description Only documents the account may read can be added, and the link shows no more than the account may read when it is opened.
+// @Tags Collections
+// @Accept json
+// @Produce json
+// @Param collection body collectionRequest true "name, and term or documentIds, and share"
+// @Success 201 {object} map[string]interface{} "The collection with its url and shareURL"
+// @Failure 400 {object} map[string]interface{} "Missing name, no documents or invalid ULIDs"
+// @Failure 404 {object} map[string]interface{} "A document is in a folder the account may not read"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /collections [post]
+func (serverHandler *ServerHandler) CreateCollection(c echo.Context) error {
+	var request collectionRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	request.Term = strings.TrimSpace(request.Term)
+	if request.Name == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Collection name is required",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		return err
+	}
+	documentULIDs := make([]string, 0, len(request.DocumentIDs))
+	for _, id := range request.DocumentIDs {
+		parsed, err := parseULID(id)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid document ULID: " + id,
+				"code":  dto.CodeInvalidID,
+			})
+		}
+		if access != nil {
+			if document, err := serverHandler.DB.GetDocumentByULID(parsed.String()); err == nil && document != nil && !access.canRead(document.Folder) {
+				return documentNotFound(c)
+			}
+		}
+		documentULIDs = append(documentULIDs, parsed.String())
+	}
+	if len(documentULIDs) == 0 && request.Term != "" {
+		documents, err := serverHandler.DB.SearchDocuments(request.Term)
+		if err != nil {
+			Logger.Error("Failed to search for collection documents", "term", request.Term, "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to search for documents",
+				"code":  dto.CodeInternal,
+			})
+		}
+		for _, document := range access.readable(documents) {
+			documentULIDs = append(documentULIDs, document.ULID.String())
+		}
+	}
+	if len(documentULIDs) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "A collection needs documentIds or a term that finds documents",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	owner, _ := c.Get(authUserKey).(string)
+	collection := &database.Collection{Name: request.Name, Term: request.Term, CreatedBy: owner}
+	if request.Share {
+		token, err := newShareToken()
+		if err != nil {
+			Logger.Error("Failed to create share token", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to create share token",
+				"code":  dto.CodeInternal,
+			})
+		}
+		collection.ShareToken = token
+	}
+	if err := serverHandler.DB.CreateCollection(collection, documentULIDs); err != nil {
+		Logger.Error("Failed to create collection", "name", collection.Name, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to create collection",
+			"code":  dto.CodeInternal,
+		})
+	}
+	Logger.Info("Created collection", "ulid", collection.ULID.String(), "name", collection.Name, "documents", collection.DocumentCount, "shared", request.Share)
+	return c.JSON(http.StatusCreated, collectionResponse(collection))
+}
+
+// ListCollections lists the saved collections
+// @Summary List collections
+// @Description All saved collections, newest first, without their documents
+// @Tags Collections
+// @Produce json
+// @Success 200 {object} map[string]interface{} "collections and count"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /collections [get]
+func (serverHandler *ServerHandler) ListCollections(c echo.Context) error {
+	collections, err := serverHandler.DB.ListCollections()
+	if err != nil {
+		Logger.Error("Failed to list collections", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve collections",
+			"code":  dto.CodeInternal,
+		})
+	}
+	if collections == nil {
+		collections = []database.Collection{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"collections": collections,
+		"count":       len(collections),
+	})
+}
+
+// collectionWithDocuments responds with a collection and the documents in it that access may read, as
+// folder permissions stand now. Documents deleted or hidden since the snapshot are counted as missing.
+// With signLinks the document URLs are signed, so they open without other credentials until
+// SIGNED_URL_TTL runs out.
+func (serverHandler *ServerHandler) collectionWithDocuments(c echo.Context, collection *database.Collection, access *folderAccess, signLinks bool) error {
+	documents, err := serverHandler.DB.GetCollectionDocuments(collection.ULID.String())
+	if err != nil {
+		Logger.Error("Failed to get collection documents", "ulid", collection.ULID.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve collection documents",
+			"code":  dto.CodeInternal,
+		})
+	}
+	documents = access.readable(documents)
+	if documents == nil {
+		documents = []database.Document{}
+	}
+	if signLinks {
+		expires := database.Now().Add(time.Duration(serverHandler.ServerConfig.SignedURLTTL) * time.Second)
+		for i := range documents {
+			documents[i].URL = serverHandler.signedDocumentURL(documents[i].ULID.String(), expires)
+		}
+	}
+	response := collectionResponse(collection)
+	response["documents"] = documents
+	response["missing"] = collection.DocumentCount - len(documents)
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetCollection returns a collection and its documents
+// @Summary Get a collection
+// @Description A collection with its documents in snapshot order. missing counts documents deleted since the snapshot was taken.
+// @Tags Collections
+// @Produce json
+// @Param id path string true "Collection ULID"
+// @Success 200 {object} map[string]interface{} "collection, documents, missing, url and shareURL"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID"
+// @Failure 404 {object} map[string]interface{} "Collection not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /collections/{id} [get]
+func (serverHandler *ServerHandler) GetCollection(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "collection")
+	if !ok {
+		return err
+	}
+	collection, err := serverHandler.DB.GetCollection(id.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Collection not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to get collection", "ulid", id.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve collection",
+			"code":  dto.CodeInternal,
+		})
+	}
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		return err
+	}
+	return serverHandler.collectionWithDocuments(c, collection, access, false)
+}
+
+// GetSharedCollection opens a collection from its share link
+// @Summary Open a shared collection
+// @Description The collection shared with a token and its documents, with signed document URLs so the files open for whoever holds the link
+// @Tags Collections
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} map[string]interface{} "collection, documents, missing, url and shareURL"
+// @Failure 404 {object} map[string]interface{} "No collection is shared with this token"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /shared/{token} [get]
+func (serverHandler *ServerHandler) GetSharedCollection(c echo.Context) error {
+	collection, err := serverHandler.DB.GetCollectionByShareToken(c.Param("token"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Shared collection not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to get shared collection", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve collection",
+			"code":  dto.CodeInternal,
+		})
+	}
+	// Whoever holds the link sees no more than the account that shared it may read today
+	access, err := serverHandler.accountAccess(collection.CreatedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Shared collection not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to load folder permissions of collection owner", "owner", collection.CreatedBy, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve collection",
+			"code":  dto.CodeInternal,
+		})
+	}
+	return serverHandler.collectionWithDocuments(c, collection, access, true)
+}
+
+// DeleteCollection removes a collection, leaving its documents alone
+// @Summary Delete a collection
+// @Description Delete a collection and its share link. The documents themselves are not touched.
+// @Tags Collections
+// @Produce json
+// @Param id path string true "Collection ULID"
+// @Success 204 "Collection deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID"
+// @Failure 404 {object} map[string]interface{} "Collection not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /collections/{id} [delete]
+func (serverHandler *ServerHandler) DeleteCollection(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "collection")
+	if !ok {
+		return err
+	}
+	err = serverHandler.DB.DeleteCollection(id.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Collection not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to delete collection", "ulid", id.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to delete collection",
+			"code":  dto.CodeInternal,
+		})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
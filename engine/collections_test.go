@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/database"
+	"github.com/oklog/ulid/v2"
+)
+
+func TestCollectionsSnapshotSearchResults(t *testing.T) {
+	// Two tax documents and one other
+	handler := setupMemoryTestHandler(t, config.ServerConfig{DocumentPath: t.TempDir(), URLSigningKey: "key", SignedURLTTL: 60})
+	handler.Echo.POST("/api/collections", handler.CreateCollection)
+	handler.Echo.GET("/api/collections", handler.ListCollections)
+	handler.Echo.GET("/api/collections/:id", handler.GetCollection)
+	handler.Echo.DELETE("/api/collections/:id", handler.DeleteCollection)
+	handler.Echo.GET("/api/shared/:token", handler.GetSharedCollection)
+	first := saveTestDocument(t, handler.DB, "/docs/p60.pdf", "tax year 2023 p60")
+	saveTestDocument(t, handler.DB, "/docs/self-assessment.pdf", "tax year 2023 return")
+	saveTestDocument(t, handler.DB, "/docs/boiler.pdf", "boiler service")
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		return rec
+	}
+	type collectionBody struct {
+		Collection database.Collection `json:"collection"`
+		URL        string              `json:"url"`
+		ShareURL   string              `json:"shareURL"`
+		Documents  []database.Document `json:"documents"`
+		Missing    int                 `json:"missing"`
+	}
+	decode := func(rec *httptest.ResponseRecorder) collectionBody {
+		t.Helper()
+		var body collectionBody
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to decode %d %q: %v", rec.Code, rec.Body.String(), err)
+		}
+		return body
+	}
+
+	// The results of a search are saved as a shared collection
+	rec := serve(http.MethodPost, "/api/collections", `{"name":"2023 tax bundle","term":"tax year 2023","share":true}`)
+
+	// It holds both tax documents and has its own and a share link
+	created := decode(rec)
+	if rec.Code != http.StatusCreated || created.Collection.DocumentCount != 2 || created.ShareURL == "" {
+		t.Fatalf("Unexpected create response %d %s", rec.Code, rec.Body.String())
+	}
+	if created.URL != "/api/collections/"+created.Collection.ULID.String() {
+		t.Errorf("Unexpected collection URL %q", created.URL)
+	}
+
+	// A document is deleted after the snapshot and the share link is opened
+	if err := handler.DB.DeleteDocument(first.ULID.String()); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	shared := decode(serve(http.MethodGet, created.ShareURL, ""))
+
+	// The remaining document has a signed link and the deleted one is reported missing
+	if len(shared.Documents) != 1 || shared.Missing != 1 || !strings.Contains(shared.Documents[0].URL, "sig=") {
+		t.Errorf("Unexpected shared collection %+v", shared)
+	}
+	if owned := decode(serve(http.MethodGet, created.URL, "")); len(owned.Documents) != 1 || strings.Contains(owned.Documents[0].URL, "sig=") {
+		t.Errorf("Expected unsigned links on the collection itself, got %+v", owned.Documents)
+	}
+
+	// Bad requests are rejected, and deleting removes the collection and its share link
+	for _, body := range []string{`{"term":"tax"}`, `{"name":"empty","term":"nothing matches"}`, `{"name":"bad","documentIds":["nope"]}`} {
+		if rec := serve(http.MethodPost, "/api/collections", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+	if rec := serve(http.MethodDelete, created.URL, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting, got %d", rec.Code)
+	}
+	for _, target := range []string{created.URL, created.ShareURL, "/api/collections/" + ulid.Make().String()} {
+		if rec := serve(http.MethodGet, target, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", target, rec.Code)
+		}
+	}
+	var listed struct {
+		Count int `json:"count"`
+	}
+	json.Unmarshal(serve(http.MethodGet, "/api/collections", "").Body.Bytes(), &listed)
+	if listed.Count != 0 {
+		t.Errorf("Expected no collections left, got %d", listed.Count)
+	}
+}
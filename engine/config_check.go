@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/database"
+)
+
+// Results of one configuration check
+const (
+	CheckOK   = "ok"
+	CheckWarn = "warn" // works, but something is off or disabled
+	CheckFail = "fail" // the server would not start or would not work
+)
+
+// ConfigCheck is one line of the configuration report
+type ConfigCheck struct {
+	Name   string
+	Result string // CheckOK, CheckWarn or CheckFail
+	Detail string
+}
+
+// CheckConfig checks a loaded configuration without starting the server: the database connects, the
+// folders exist or can be created and are writable, OCR and PDF rendering work, sidecar services and
+// hook URLs answer, hook commands are found and schedules parse. Nothing is created or migrated.
+func CheckConfig(serverConfig config.ServerConfig) []ConfigCheck {
+	var checks []ConfigCheck
+	add := func(name, result, detail string) {
+		checks = append(checks, ConfigCheck{Name: name, Result: result, Detail: detail})
+	}
+	addErr := func(name string, err error, ok string) {
+		if err != nil {
+			add(name, CheckFail, err.Error())
+			return
+		}
+		add(name, CheckOK, ok)
+	}
+
+	if config.Configured() {
+		add("config", CheckOK, "settings found")
+		detail, err := database.CheckConnection(serverConfig)
+		addErr("database", err, detail)
+	} else {
+		add("config", CheckWarn, "no config file or settings, the server will start the setup wizard")
+		add("database", CheckWarn, "in-memory until setup is completed")
+	}
+
+	folders := []struct{ name, path string }{
+		{"document folder", serverConfig.DocumentPath},
+		{"ingress folder", serverConfig.IngressPath},
+		{"temp folder", serverConfig.TempPath},
+		{"backup folder", serverConfig.BackupPath},
+	}
+	if serverConfig.IngressMoveFolder != "" {
+		folders = append(folders, struct{ name, path string }{"ingress move folder", serverConfig.IngressMoveFolder})
+	}
+	if serverConfig.PreIngestCommand != "" || serverConfig.PreIngestURL != "" {
+		folders = append(folders, struct{ name, path string }{"quarantine folder", serverConfig.QuarantinePath})
+	}
+	if serverConfig.OriginalsPath != "" {
+		folders = append(folders, struct{ name, path string }{"originals folder", serverConfig.OriginalsPath})
+	}
+	for _, folder := range folders {
+		if folder.path == "" {
+			add(folder.name, CheckWarn, "not set")
+			continue
+		}
+		addErr(folder.name, checkWritableDir(folder.path), folder.path)
+	}
+
+	switch {
+	case serverConfig.TesseractServiceURL != "":
+		addService(&checks, "ocr service", serverConfig.TesseractServiceURL)
+	case serverConfig.TesseractPath != "":
+		if ocr := detectOCR(serverConfig); ocr.Available && ocr.TesseractPath == serverConfig.TesseractPath {
+			add("ocr", CheckOK, ocr.TesseractPath+", "+ocr.Version)
+			if languages := detectOCRLanguages(serverConfig); len(languages.Missing) > 0 {
+				add("ocr languages", CheckFail, "no language pack for "+strings.Join(languages.Missing, ", ")+"; installed: "+strings.Join(languages.Installed, ", "))
+			} else if len(languages.Configured) > 0 {
+				add("ocr languages", CheckOK, strings.Join(languages.Configured, "+"))
+			}
+		} else {
+			add("ocr", CheckFail, serverConfig.TesseractPath+" is not a working tesseract")
+		}
+	default:
+		add("ocr", CheckWarn, "TESSERACT_PATH and TESSERACT_SERVICE_URL are not set, scans are stored without text")
+	}
+	switch pdfRenderingMode(serverConfig) {
+	case "service":
+		addService(&checks, "pdf service", serverConfig.PDFServiceURL)
+	case "local":
+		add("pdf rendering", CheckOK, "built in")
+	default:
+		add("pdf rendering", CheckWarn, "built without PDFium and PDF_SERVICE_URL is not set, scanned PDFs are stored without text")
+	}
+
+	for _, hook := range []struct{ name, commandLine string }{
+		{"pre-ingestion command", serverConfig.PreIngestCommand},
+		{"post-ingestion command", serverConfig.PostIngestCommand},
+	} {
+		if hook.commandLine == "" {
+			continue
+		}
+		path, err := exec.LookPath(strings.Fields(hook.commandLine)[0])
+		addErr(hook.name, err, path)
+	}
+	hookURLs := serverConfig.PostIngestWebhooks
+	if serverConfig.PreIngestURL != "" {
+		hookURLs = append([]string{serverConfig.PreIngestURL}, hookURLs...)
+	}
+	for _, hookURL := range hookURLs {
+		// Hooks are only dialled, since any request would be taken as a real document
+		host := webhookHost(hookURL)
+		address := host
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			port := "80"
+			if strings.HasPrefix(hookURL, "https://") {
+				port = "443"
+			}
+			address = net.JoinHostPort(host, port)
+		}
+		conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+		if err == nil {
+			conn.Close()
+		}
+		addErr("hook "+host, err, "reachable")
+	}
+
+	jobs := slices.Sorted(maps.Keys(serverConfig.Schedules))
+	for _, job := range jobs {
+		if _, err := parseSchedule(serverConfig.Schedules[job]); err != nil {
+			add("schedule "+job, CheckFail, err.Error())
+		}
+	}
+	if _, err := parseQuietHours(serverConfig.QuietHours); err != nil {
+		add("quiet hours", CheckFail, err.Error())
+	}
+	if _, err := parseBlankPagePolicy(serverConfig.BlankPages); err != nil {
+		add("blank pages", CheckFail, err.Error())
+	}
+	if err := validateOptimiseSettings(serverConfig); err != nil {
+		add("storage optimisation", CheckFail, err.Error())
+	}
+	return checks
+}
+
+// addService appends the health of a sidecar service to checks
+func addService(checks *[]ConfigCheck, name, address string) {
+	status := checkService(name, address)
+	if !status.Healthy {
+		*checks = append(*checks, ConfigCheck{Name: name, Result: CheckFail, Detail: address + ": " + status.Error})
+		return
+	}
+	*checks = append(*checks, ConfigCheck{Name: name, Result: CheckOK, Detail: address})
+}
+
+// WriteConfigReport prints checks one per line and reports whether all of them passed; warnings pass
+func WriteConfigReport(w io.Writer, checks []ConfigCheck) bool {
+	marks := map[string]string{CheckOK: "  ok  ", CheckWarn: " warn ", CheckFail: " FAIL "}
+	failed := 0
+	for _, check := range checks {
+		if check.Result == CheckFail {
+			failed++
+		}
+		fmt.Fprintf(w, "[%s] %-24s %s\n", marks[check.Result], check.Name, check.Detail)
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "\n%d of %d checks failed\n", failed, len(checks))
+		return false
+	}
+	fmt.Fprintf(w, "\nAll %d checks passed\n", len(checks))
+	return true
+}
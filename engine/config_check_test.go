@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/config"
+)
+
+func TestCheckConfig(t *testing.T) {
+	// A configured sqlite deployment with a reachable webhook, a missing hook command,
+	// a bad schedule and an OCR sidecar that is down
+	t.Setenv("DATABASE_TYPE", "sqlite")
+	handler := setupTestHandler(t) // creates the sqlite file
+	webhook := httptest.NewServer(http.NotFoundHandler())
+	defer webhook.Close()
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	downAddress := closed.Addr().String()
+	closed.Close()
+	serverConfig := handler.ServerConfig
+	serverConfig.TempPath = filepath.Join(t.TempDir(), "not", "yet", "made")
+	serverConfig.BackupPath = t.TempDir()
+	serverConfig.TesseractServiceURL = "http://" + downAddress
+	serverConfig.PostIngestCommand = "godocs-no-such-command --flag"
+	serverConfig.PostIngestWebhooks = []string{webhook.URL + "/hook?token=secret"}
+	serverConfig.Schedules = config.JobSchedules{"ingest": "@every 10m", "cleanup": "every day"}
+
+	// The configuration is checked and reported
+	checks := CheckConfig(serverConfig)
+	var report bytes.Buffer
+	passed := WriteConfigReport(&report, checks)
+
+	// Each check has the expected result
+	results := make(map[string]string)
+	for _, check := range checks {
+		results[check.Name] = check.Result
+	}
+	for name, want := range map[string]string{
+		"config":                 CheckOK,
+		"database":               CheckOK,
+		"document folder":        CheckOK,
+		"temp folder":            CheckOK, // created on start
+		"ocr service":            CheckFail,
+		"post-ingestion command": CheckFail,
+		"hook " + webhook.Listener.Addr().String(): CheckOK,
+		"schedule cleanup":                         CheckFail,
+	} {
+		if results[name] != want {
+			t.Errorf("%s: expected %s, got %q", name, want, results[name])
+		}
+	}
+	if _, ok := results["schedule ingest"]; ok {
+		t.Errorf("Expected only failing schedules to be listed")
+	}
+
+	// The report fails, counts the failures and does not show the webhook's token
+	if passed || !strings.Contains(report.String(), "3 of ") || strings.Contains(report.String(), "secret") {
+		t.Errorf("Unexpected report:\n%s", report.String())
+	}
+}
+
+func TestCheckConfigUnconfigured(t *testing.T) {
+	// No config file or settings, as on first run
+	for _, key := range []string{"DATABASE_TYPE", "DATABASE_HOST", "DATABASE_NAME", "DOCUMENT_PATH", "INGRESS_PATH"} {
+		t.Setenv(key, "")
+	}
+	t.Chdir(t.TempDir())
+	serverConfig := config.ServerConfig{DatabaseType: "postgres", DatabaseHost: "127.0.0.1", DatabasePort: "1"}
+
+	// The configuration is checked
+	checks := CheckConfig(serverConfig)
+
+	// The database is not tried, since the server runs in memory until setup is completed
+	for _, check := range checks {
+		if check.Name == "database" && check.Result != CheckWarn {
+			t.Errorf("Expected the database to be a warning before setup, got %+v", check)
+		}
+	}
+}
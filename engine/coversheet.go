@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/labstack/echo/v4"
+	"github.com/oklog/ulid/v2"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// documentLink is the absolute view URL of a document, as printed on cover sheets and encoded in QR codes
+func (serverHandler *ServerHandler) documentLink(c echo.Context, id ulid.ULID) string {
+	return serverHandler.documentBaseURL(c) + documentViewURL(id)
+}
+
+// coverSheetQRSize is the side of the QR code image in pixels; it is printed at coverSheetQRMillimetres
+const (
+	coverSheetQRSize        = 512
+	coverSheetQRMillimetres = 60.0
+)
+
+// coverSheetField is one labelled line of a cover sheet
+type coverSheetField struct {
+	label string
+	value string
+}
+
+// coverSheetFields lists what a cover sheet says about a document
+func coverSheetFields(document *database.Document, link string) []coverSheetField {
+	fields := []coverSheetField{
+		{"Added", document.IngressTime.Format("2 January 2006 15:04")},
+		{"Folder", document.Folder},
+		{"Type", strings.TrimPrefix(document.DocumentType, ".")},
+	}
+	if info, err := os.Stat(document.Path); err == nil {
+		fields = append(fields, coverSheetField{"Size", formatFileSize(info.Size())})
+	}
+	return append(fields,
+		coverSheetField{"Document ID", document.ULID.String()},
+		coverSheetField{"Hash", document.Hash},
+		coverSheetField{"Link", link},
+	)
+}
+
+// formatFileSize gives a byte count in the largest unit that keeps it at or above one
+func formatFileSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// renderCoverSheet draws a one-page A4 summary of a document with a QR code of its link
+func renderCoverSheet(document *database.Document, link string, printed time.Time) ([]byte, error) {
+	qr, err := qrcode.Encode(link, qrcode.Medium, coverSheetQRSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(document.Name+" cover sheet", true)
+	pdf.SetCreator("godocs", false)
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(false, 20)
+	pdf.AddPage()
+	text := pdf.UnicodeTranslatorFromDescriptor("") // core fonts are cp1252
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.MultiCell(0, 9, text(document.Name), "", "L", false)
+	pdf.Ln(2)
+	pdf.SetDrawColor(52, 152, 219)
+	pdf.SetLineWidth(0.6)
+	pdf.Line(20, pdf.GetY(), 190, pdf.GetY())
+	pdf.Ln(6)
+
+	for _, field := range coverSheetFields(document, link) {
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(35, 7, text(field.label), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.MultiCell(0, 7, text(field.value), "", "L", false)
+	}
+
+	pdf.RegisterImageOptionsReader("qr", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qr))
+	qrTop := pdf.GetY() + 12
+	pdf.ImageOptions("qr", (210-coverSheetQRMillimetres)/2, qrTop, coverSheetQRMillimetres, coverSheetQRMillimetres, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, link)
+	pdf.SetY(qrTop + coverSheetQRMillimetres + 2)
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.CellFormat(0, 5, "Scan to open the stored copy", "", 1, "C", false, 0, "")
+
+	pdf.SetY(-25)
+	pdf.SetFont("Helvetica", "I", 8)
+	pdf.SetTextColor(120, 120, 120)
+	pdf.CellFormat(0, 5, text("Printed "+printed.Format("2 January 2006")+" - staple to the original"), "", 0, "C", false, 0, "")
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// GetCoverSheet renders a printable cover sheet for a document
+// @Summary Get a document cover sheet
+// @Description A one-page PDF with the document's name, date, folder, ID and a QR code linking back to it, to print and staple to the paper original.
+// @Description The link uses BASE_URL behind a reverse proxy and the request's host otherwise.
+// @Tags Documents
+// @Produce application/pdf
+// @Param id path string true "Document ULID"
+// @Success 200 {file} file "Cover sheet PDF"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID"
+// @Failure 404 {object} map[string]interface{} "Document not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /document/{id}/coversheet.pdf [get]
+func (serverHandler *ServerHandler) GetCoverSheet(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "document")
+	if !ok {
+		return err
+	}
+	if serverHandler.hiddenDocument(c, id.String()) {
+		return documentNotFound(c)
+	}
+	document, err := serverHandler.DB.GetDocumentByULID(id.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to get document for cover sheet", "ulid", id.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve document",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	link := serverHandler.documentLink(c, document.ULID)
+	sheet, err := renderCoverSheet(document, link, database.Now())
+	if err != nil {
+		Logger.Error("Failed to render cover sheet", "ulid", id.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to render cover sheet",
+			"code":  dto.CodeInternal,
+		})
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, contentDisposition(strings.TrimSuffix(document.Name, document.DocumentType)+" cover sheet.pdf"))
+	return c.Blob(http.StatusOK, "application/pdf", sheet)
+}
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestGetCoverSheet(t *testing.T) {
+	// A stored document behind a reverse proxy with a public base URL
+	handler := setupTestHandler(t)
+	handler.ServerConfig.UseReverseProxy = true
+	handler.ServerConfig.BaseURL = "https://docs.example.com/"
+	handler.Echo.GET("/api/document/:id/coversheet.pdf", handler.GetCoverSheet)
+	path := filepath.Join(handler.ServerConfig.DocumentPath, "Boiler service.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.4"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	doc := saveTestDocument(t, handler.DB, path, "boiler")
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	// The cover sheet is requested
+	rec := get("/api/document/" + doc.ULID.String() + "/coversheet.pdf")
+
+	// A one-page PDF comes back, named after the document
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" || !bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF-")) {
+		t.Fatalf("Expected a PDF, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != contentDisposition("Boiler service cover sheet.pdf") {
+		t.Errorf("Unexpected Content-Disposition %q", got)
+	}
+	if pages := bytes.Count(rec.Body.Bytes(), []byte("/Type /Page\n")); pages != 1 {
+		t.Errorf("Expected one page, got %d", pages)
+	}
+
+	// The fields link back to the document through the public base URL
+	fields := coverSheetFields(doc, handler.documentLink(nil, doc.ULID))
+	if link := fields[len(fields)-1]; link.value != "https://docs.example.com/document/view/"+doc.ULID.String() {
+		t.Errorf("Unexpected link %q", link.value)
+	}
+
+	// Bad and unknown IDs are rejected
+	if rec := get("/api/document/nope/coversheet.pdf"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", rec.Code)
+	}
+	if rec := get("/api/document/" + ulid.Make().String() + "/coversheet.pdf"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+}
+
+func TestFormatFileSize(t *testing.T) {
+	for size, want := range map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KB", 5 << 20: "5.0 MB"} {
+		if got := formatFileSize(size); got != want {
+			t.Errorf("formatFileSize(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/labstack/echo/v4"
+)
+
+// latestPageSize is the number of documents per page of the latest documents listing
+const latestPageSize = 20
+
+// maxCursorPageSize caps the limit a client can request in cursor mode and other limited listings
+const maxCursorPageSize = 200
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor turns a keyset position into the opaque token handed to API clients
+func encodeCursor(cursor *database.DocumentCursor) string {
+	raw := strconv.FormatInt(cursor.IngressTime.UnixNano(), 10) + "." + strconv.Itoa(cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a token produced by encodeCursor; an empty token starts from the newest document
+func decodeCursor(token string) (*database.DocumentCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return nil, errInvalidCursor
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	docID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	return &database.DocumentCursor{IngressTime: time.Unix(0, unixNano).UTC(), ID: docID}, nil
+}
+
+// limitParam reads an optional limit query parameter between 1 and maxCursorPageSize
+func limitParam(c echo.Context, defaultLimit int) (int, error) {
+	value := c.QueryParam("limit")
+	if value == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 || limit > maxCursorPageSize {
+		return 0, fmt.Errorf("Invalid limit, expected 1 to %d", maxCursorPageSize)
+	}
+	return limit, nil
+}
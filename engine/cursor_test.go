@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/oklog/ulid/v2"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := &database.DocumentCursor{IngressTime: time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC), ID: 42}
+	decoded, err := decodeCursor(encodeCursor(cursor))
+	if err != nil || !decoded.IngressTime.Equal(cursor.IngressTime) || decoded.ID != cursor.ID {
+		t.Errorf("Round trip gave %+v, %v", decoded, err)
+	}
+	if decoded, err := decodeCursor(""); decoded != nil || err != nil {
+		t.Errorf("Expected empty cursor to start at the top, got %+v, %v", decoded, err)
+	}
+	for _, token := range []string{"!!", "bm9kb3Q", "YS4x"} {
+		if _, err := decodeCursor(token); err == nil {
+			t.Errorf("Expected %q to be rejected", token)
+		}
+	}
+}
+
+func TestGetLatestDocumentsByCursor(t *testing.T) {
+	// Documents where several share an ingress time
+	handler := setupTestHandler(t)
+	base := time.Now().Add(-time.Hour)
+	total := 7
+	for i := 0; i < total; i++ {
+		doc := &database.Document{
+			Name:         fmt.Sprintf("doc%d.pdf", i),
+			Path:         filepath.Join(handler.ServerConfig.DocumentPath, fmt.Sprintf("doc%d.pdf", i)),
+			IngressTime:  base.Add(time.Duration(i/3) * time.Minute),
+			Folder:       handler.ServerConfig.DocumentPath,
+			Hash:         ulid.Make().String(),
+			ULID:         ulid.Make(),
+			DocumentType: ".pdf",
+		}
+		if err := handler.DB.SaveDocument(doc); err != nil {
+			t.Fatalf("Failed to save document: %v", err)
+		}
+	}
+	type page struct {
+		Documents  []database.Document `json:"documents"`
+		HasNext    bool                `json:"hasNext"`
+		NextCursor string              `json:"nextCursor"`
+	}
+	get := func(target string) (*httptest.ResponseRecorder, page) {
+		rec := httptest.NewRecorder()
+		if err := handler.GetLatestDocuments(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec)); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var p page
+		json.Unmarshal(rec.Body.Bytes(), &p)
+		return rec, p
+	}
+
+	// Following cursors two documents at a time
+	var seen []database.Document
+	cursor := ""
+	for requests := 0; requests < total; requests++ {
+		rec, p := get("/api/documents/latest?limit=2&cursor=" + url.QueryEscape(cursor))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		seen = append(seen, p.Documents...)
+		if !p.HasNext {
+			break
+		}
+		cursor = p.NextCursor
+	}
+
+	// Every document is visited once, newest first
+	if len(seen) != total {
+		t.Fatalf("Expected %d documents, got %d", total, len(seen))
+	}
+	ids := make(map[int]bool)
+	for i, doc := range seen {
+		if ids[doc.StormID] {
+			t.Errorf("Document %d returned twice", doc.StormID)
+		}
+		ids[doc.StormID] = true
+		if i > 0 && doc.IngressTime.After(seen[i-1].IngressTime) {
+			t.Errorf("Documents out of order at %d", i)
+		}
+	}
+
+	// Bad cursors and limits are rejected
+	for _, target := range []string{"/api/documents/latest?cursor=!!", "/api/documents/latest?cursor=&limit=0", "/api/documents/latest?cursor=&limit=1000"} {
+		if rec, _ := get(target); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
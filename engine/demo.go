@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/database"
+)
+
+// demoFS holds the sample documents loaded in demo mode, one folder per category
+//
+//go:embed demo
+var demoFS embed.FS
+
+// demoScanFolder holds rendered page images of the receipts, standing in for scans
+const demoScanFolder = "Scans"
+
+// DemoConfig switches serverConfig to demo mode: an in-memory database and ingress and document
+// folders in a fresh temp directory. The returned cleanup removes the directory.
+func DemoConfig(serverConfig *config.ServerConfig) (func(), error) {
+	dir, err := os.MkdirTemp("", "godocs-demo-*")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create demo directory: %w", err)
+	}
+	serverConfig.DatabaseType = "memory"
+	serverConfig.IngressPath = filepath.Join(dir, "ingress")
+	serverConfig.DocumentPath = filepath.Join(dir, "documents")
+	serverConfig.NewDocumentFolder = filepath.Join(serverConfig.DocumentPath, serverConfig.NewDocumentFolderRel)
+	return func() { os.RemoveAll(dir) }, nil
+}
+
+// LoadDemoDocuments copies the bundled sample documents into the document folder and records them,
+// adding a rendered page image of each receipt as if it had been scanned. Documents already
+// recorded are skipped. It returns how many documents were added.
+func (serverHandler *ServerHandler) LoadDemoDocuments() (int, error) {
+	added := 0
+	ingressTime := database.Now()
+	err := fs.WalkDir(demoFS, "demo", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := demoFS.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		text := string(content)
+		rel := strings.TrimPrefix(name, "demo/")
+		category := path.Dir(rel)
+		folder := filepath.Join(serverHandler.ServerConfig.DocumentPath, strings.ToUpper(category[:1])+category[1:])
+
+		// Space the samples out so the latest documents view has some history
+		ingressTime = ingressTime.Add(-36 * time.Hour)
+		stored, err := serverHandler.storeDemoDocument(filepath.Join(folder, path.Base(rel)), content, text, ingressTime)
+		if err != nil {
+			return err
+		}
+		if stored {
+			added++
+		}
+
+		if category != "receipts" {
+			return nil
+		}
+		var preview bytes.Buffer
+		if err := png.Encode(&preview, renderPagePreview(text)); err != nil {
+			return err
+		}
+		scanName := strings.TrimSuffix(path.Base(rel), path.Ext(rel)) + ".png"
+		scanPath := filepath.Join(serverHandler.ServerConfig.DocumentPath, demoScanFolder, scanName)
+		stored, err = serverHandler.storeDemoDocument(scanPath, preview.Bytes(), text, ingressTime.Add(time.Minute))
+		if stored {
+			added++
+		}
+		return err
+	})
+	if err != nil {
+		return added, fmt.Errorf("unable to load demo documents: %w", err)
+	}
+
+	if err := serverHandler.DB.RecalculateAllWordFrequencies(); err != nil {
+		Logger.Warn("Unable to build word cloud for demo documents", "error", err)
+	}
+	serverHandler.invalidateDocumentCache()
+	Logger.Info("Demo documents loaded", "count", added)
+	return added, nil
+}
+
+// storeDemoDocument writes content to destPath and records it with the given text, reporting whether it was new
+func (serverHandler *ServerHandler) storeDemoDocument(destPath string, content []byte, text string, ingressTime time.Time) (bool, error) {
+	db := serverHandler.DB
+	if existing, err := db.GetDocumentByPath(filepath.ToSlash(destPath)); err == nil && existing != nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(destPath, content, 0644); err != nil {
+		return false, err
+	}
+	fileHash, err := calculateFileHash(destPath)
+	if err != nil {
+		return false, err
+	}
+	newULID, err := database.CalculateUUID(ingressTime)
+	if err != nil {
+		return false, err
+	}
+	doc := &database.Document{
+		Name:         filepath.Base(destPath),
+		Path:         filepath.ToSlash(destPath),
+		IngressTime:  ingressTime,
+		Folder:       filepath.ToSlash(filepath.Dir(destPath)),
+		Hash:         fileHash.Current(),
+		ULID:         newULID,
+		DocumentType: filepath.Ext(destPath),
+		MIMEType:     database.DetectMIMEType(destPath),
+		OCRStatus:    database.OCRSkipped, // the text comes with the sample
+		FullText:     text,
+		URL:          documentViewURL(newULID),
+	}
+	doc.Size, doc.PageCount = fileDetails(destPath)
+	if err := db.SaveDocument(doc); err != nil {
+		return false, err
+	}
+	serverHandler.recordFolder(doc.Folder)
+	return true, nil
+}
+
+// renderPagePreview draws a small A4-shaped page with a grey bar for each line of text,
+// enough to look like a scanned document in the browser
+func renderPagePreview(text string) image.Image {
+	const width, height, margin, lineHeight = 420, 594, 32, 16
+	page := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(page, page.Bounds(), &image.Uniform{color.RGBA{250, 248, 242, 255}}, image.Point{}, draw.Src)
+
+	ink := &image.Uniform{color.RGBA{90, 90, 90, 255}}
+	heading := &image.Uniform{color.RGBA{30, 30, 30, 255}}
+	y := margin
+	for i, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if y+lineHeight > height-margin {
+			break
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		length := len(strings.TrimSpace(line))
+		if length > 0 {
+			x0 := margin + indent*6
+			x1 := min(x0+length*6, width-margin)
+			bar, colour := 6, ink
+			if i == 0 {
+				bar, colour = 10, heading
+			}
+			draw.Draw(page, image.Rect(x0, y, x1, y+bar), colour, image.Point{}, draw.Src)
+		}
+		y += lineHeight
+	}
+	return page
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drummonds/godocs/config"
+)
+
+func TestLoadDemoDocuments(t *testing.T) {
+	// A server switched to demo mode
+	serverConfig := config.ServerConfig{NewDocumentFolderRel: "New"}
+	cleanup, err := DemoConfig(&serverConfig)
+	if err != nil {
+		t.Fatalf("Failed to set up demo config: %v", err)
+	}
+	defer cleanup()
+	if serverConfig.DatabaseType != "memory" {
+		t.Fatalf("Expected the memory database, got %q", serverConfig.DatabaseType)
+	}
+	handler := setupMemoryTestHandler(t, serverConfig)
+
+	// The sample documents are loaded twice
+	added, err := handler.LoadDemoDocuments()
+	if err != nil {
+		t.Fatalf("Failed to load demo documents: %v", err)
+	}
+	again, err := handler.LoadDemoDocuments()
+	if err != nil {
+		t.Fatalf("Failed to reload demo documents: %v", err)
+	}
+
+	// Nine samples plus a scan of each of the three receipts are added once
+	if added != 12 || again != 0 {
+		t.Fatalf("Expected 12 documents then 0, got %d then %d", added, again)
+	}
+	results, err := handler.DB.SearchDocuments("direct debit")
+	if err != nil || len(results) < 2 {
+		t.Errorf("Expected the samples to be searchable, got %d results (%v)", len(results), err)
+	}
+	scans, _ := handler.DB.GetDocumentsByFolder(filepath.Join(serverConfig.DocumentPath, demoScanFolder))
+	if len(scans) != 3 {
+		t.Fatalf("Expected 3 receipt scans, got %d", len(scans))
+	}
+	file, err := os.Open(scans[0].Path)
+	if err != nil {
+		t.Fatalf("Scan file missing: %v", err)
+	}
+	defer file.Close()
+	if _, err := png.Decode(file); err != nil {
+		t.Errorf("Scan is not a valid PNG: %v", err)
+	}
+	if words, _ := handler.DB.GetTopWords(5); len(words) == 0 {
+		t.Error("Expected a word cloud built from the samples")
+	}
+}
@@ -0,0 +1,254 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// lockHolderHeader names the lock holder making a request. Changes to a locked document are refused
+// unless it names the lock's holder.
+const lockHolderHeader = "X-Lock-Holder"
+
+const (
+	// defaultLockTTL is how long a lock lasts when the request does not say
+	defaultLockTTL = 15 * time.Minute
+	// maxLockTTL is the longest a lock may be taken or extended for at once
+	maxLockTTL = 24 * time.Hour
+)
+
+// lockRequest is the body of LockDocument
+type lockRequest struct {
+	Holder     string `json:"holder"`     // falls back to the X-Lock-Holder header
+	TTLSeconds int    `json:"ttlSeconds"` // 0 for defaultLockTTL
+}
+
+// lockHolder returns who is making a request, from the X-Lock-Holder header or the holder query value
+func lockHolder(c echo.Context) string {
+	if holder := strings.TrimSpace(c.Request().Header.Get(lockHolderHeader)); holder != "" {
+		return holder
+	}
+	return strings.TrimSpace(c.QueryParam("holder"))
+}
+
+// documentLocked answers 423 with the lock that refused a change
+func documentLocked(c echo.Context, lock *database.DocumentLock) error {
+	return c.JSON(http.StatusLocked, map[string]interface{}{
+		"error": "Document is locked by " + lock.Holder,
+		"code":  dto.CodeLocked,
+		"lock":  lock,
+	})
+}
+
+// lockedAgainst returns the first lock on the documents held by someone other than holder, or nil
+func (serverHandler *ServerHandler) lockedAgainst(holder string, documentULIDs ...string) (*database.DocumentLock, error) {
+	for _, documentULID := range documentULIDs {
+		lock, err := serverHandler.DB.GetDocumentLock(documentULID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if lock.Holder != holder {
+			return lock, nil
+		}
+	}
+	return nil, nil
+}
+
+// lockedInFolder returns the first lock held by someone other than holder on a document under folder, or nil
+func (serverHandler *ServerHandler) lockedInFolder(holder, folder string) (*database.DocumentLock, error) {
+	locks, err := serverHandler.DB.ListDocumentLocks()
+	if err != nil {
+		return nil, err
+	}
+	prefix := filepath.ToSlash(folder) + "/"
+	for i := range locks {
+		if locks[i].Holder == holder {
+			continue
+		}
+		document, err := serverHandler.DB.GetDocumentByULID(locks[i].DocumentULID)
+		if err != nil {
+			continue // the document has gone; its lock will expire
+		}
+		if strings.HasPrefix(filepath.ToSlash(document.Path), prefix) {
+			return &locks[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// lockTarget parses the document ID of a lock request and checks the document exists and the account may
+// see it, or with write change it, answering the error response itself when not
+func (serverHandler *ServerHandler) lockTarget(c echo.Context, write bool) (string, bool, error) {
+	id, ok, err := ulidParam(c, "id", "document")
+	if !ok {
+		return "", false, err
+	}
+	document, err := serverHandler.DB.GetDocumentByULID(id.String())
+	if err != nil {
+		return "", false, documentNotFound(c)
+	}
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		return "", false, err
+	}
+	if !access.canRead(document.Folder) {
+		return "", false, documentNotFound(c)
+	}
+	if write && !access.canWrite(document.Folder) {
+		return "", false, folderForbidden(c, document.Folder)
+	}
+	return id.String(), true, nil
+}
+
+// LockDocument checks a document out so nobody else can change it until the lock is released or expires
+// @Summary Lock a document
+// @Description Check a document out for holder. Deleting or moving it is then refused (423) for requests whose X-Lock-Holder header names anyone else.
+// @Description Locking a document again as the same holder extends the lock. Locks expire after ttlSeconds (default 900, at most 86400).
+// @Tags Documents
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Param lock body lockRequest true "holder (or the X-Lock-Holder header) and optional ttlSeconds"
+// @Success 200 {object} map[string]interface{} "The lock with its holder and expiry"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID, holder or ttlSeconds"
+// @Failure 403 {object} map[string]interface{} "No write access to the document's folder"
+// @Failure 404 {object} map[string]interface{} "Document not found"
+// @Failure 423 {object} map[string]interface{} "Locked by another holder, with their lock"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /document/{id}/lock [post]
+func (serverHandler *ServerHandler) LockDocument(c echo.Context) error {
+	documentULID, ok, err := serverHandler.lockTarget(c, true)
+	if !ok {
+		return err
+	}
+	var request lockRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&request); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid request body",
+				"code":  dto.CodeBadRequest,
+			})
+		}
+	}
+	holder := strings.TrimSpace(request.Holder)
+	if holder == "" {
+		holder = lockHolder(c)
+	}
+	ttl := time.Duration(request.TTLSeconds) * time.Second
+	if ttl == 0 {
+		ttl = defaultLockTTL
+	}
+	problems := make(map[string]string)
+	if holder == "" {
+		problems["holder"] = "Say who is locking the document, in holder or the " + lockHolderHeader + " header"
+	}
+	if ttl < 0 || ttl > maxLockTTL {
+		problems["ttlSeconds"] = "Must be between 1 and 86400"
+	}
+	if len(problems) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "The lock request needs fixing",
+			"code":   dto.CodeValidation,
+			"fields": problems,
+		})
+	}
+
+	lock, err := serverHandler.DB.AcquireDocumentLock(documentULID, holder, database.Now().Add(ttl))
+	if errors.Is(err, database.ErrDocumentLocked) {
+		return documentLocked(c, lock)
+	}
+	if err != nil {
+		Logger.Error("Failed to lock document", "ulid", documentULID, "holder", holder, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to lock document",
+			"code":  dto.CodeInternal,
+		})
+	}
+	Logger.Info("Locked document", "ulid", documentULID, "holder", holder, "expires", lock.ExpiresAt)
+	return c.JSON(http.StatusOK, map[string]interface{}{"lock": lock})
+}
+
+// GetDocumentLock reports who has a document checked out
+// @Summary Get a document's lock
+// @Tags Documents
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Success 200 {object} map[string]interface{} "The lock with its holder and expiry"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID"
+// @Failure 404 {object} map[string]interface{} "Document not found or not locked"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /document/{id}/lock [get]
+func (serverHandler *ServerHandler) GetDocumentLock(c echo.Context) error {
+	documentULID, ok, err := serverHandler.lockTarget(c, false)
+	if !ok {
+		return err
+	}
+	lock, err := serverHandler.DB.GetDocumentLock(documentULID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document is not locked",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to get document lock", "ulid", documentULID, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve document lock",
+			"code":  dto.CodeInternal,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"lock": lock})
+}
+
+// UnlockDocument releases the caller's lock on a document
+// @Summary Unlock a document
+// @Description Release a lock. Only its holder, named in the X-Lock-Holder header or the holder query value, may release it before it expires.
+// @Tags Documents
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Param holder query string false "Lock holder, when the X-Lock-Holder header is not sent"
+// @Success 204 "Lock released"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID"
+// @Failure 404 {object} map[string]interface{} "Document not found or not locked"
+// @Failure 423 {object} map[string]interface{} "Locked by another holder, with their lock"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /document/{id}/lock [delete]
+func (serverHandler *ServerHandler) UnlockDocument(c echo.Context) error {
+	documentULID, ok, err := serverHandler.lockTarget(c, false)
+	if !ok {
+		return err
+	}
+	holder := lockHolder(c)
+	lock, err := serverHandler.DB.GetDocumentLock(documentULID)
+	if err == nil && lock.Holder != holder {
+		return documentLocked(c, lock)
+	}
+	if err == nil {
+		err = serverHandler.DB.ReleaseDocumentLock(documentULID, holder)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document is not locked",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to unlock document", "ulid", documentULID, "holder", holder, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to unlock document",
+			"code":  dto.CodeInternal,
+		})
+	}
+	Logger.Info("Unlocked document", "ulid", documentULID, "holder", holder)
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+func TestDocumentLocksGuardChanges(t *testing.T) {
+	// A document in a folder
+	handler := setupTestHandler(t)
+	handler.Echo.POST("/api/document/:id/lock", handler.LockDocument)
+	handler.Echo.GET("/api/document/:id/lock", handler.GetDocumentLock)
+	handler.Echo.DELETE("/api/document/:id/lock", handler.UnlockDocument)
+	handler.Echo.DELETE("/api/document/*", handler.DeleteFile)
+	handler.Echo.PATCH("/api/document/move/*", handler.MoveDocuments)
+	path := filepath.Join(handler.ServerConfig.DocumentPath, "contracts", "lease.pdf")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("%PDF-1.4"), 0644); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	id := saveTestDocument(t, handler.DB, path, "lease").ULID.String()
+	serve := func(method, target, holder, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if holder != "" {
+			req.Header.Set(lockHolderHeader, holder)
+		}
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec, response
+	}
+
+	// Alice checks the document out
+	rec, response := serve(http.MethodPost, "/api/document/"+id+"/lock", "", `{"holder":"alice","ttlSeconds":60}`)
+
+	// Alice holds the lock
+	if rec.Code != http.StatusOK || response["lock"].(map[string]interface{})["holder"] != "alice" {
+		t.Fatalf("Unexpected lock response %d %v", rec.Code, response)
+	}
+	if rec, response := serve(http.MethodGet, "/api/document/"+id+"/lock", "", ""); rec.Code != http.StatusOK || response["lock"].(map[string]interface{})["holder"] != "alice" {
+		t.Errorf("Expected alice's lock to be reported, got %d %v", rec.Code, response)
+	}
+
+	// Bob can neither take it, release it, move the document, nor delete it or its folder
+	locked := func(what string, rec *httptest.ResponseRecorder, response map[string]interface{}) {
+		t.Helper()
+		if rec.Code != http.StatusLocked || response["code"] != string(dto.CodeLocked) || response["lock"].(map[string]interface{})["holder"] != "alice" {
+			t.Errorf("Expected %s to be refused with alice's lock, got %d %v", what, rec.Code, response)
+		}
+	}
+	rec, response = serve(http.MethodPost, "/api/document/"+id+"/lock", "bob", "")
+	locked("locking", rec, response)
+	rec, response = serve(http.MethodDelete, "/api/document/"+id+"/lock", "bob", "")
+	locked("unlocking", rec, response)
+	rec, response = serve(http.MethodPatch, "/api/document/move/?folder=archive&id="+id, "bob", "")
+	locked("moving", rec, response)
+	rec, response = serve(http.MethodDelete, "/api/document/?id="+id+"&path=contracts/lease.pdf", "bob", "")
+	locked("deleting", rec, response)
+	rec, response = serve(http.MethodDelete, "/api/document/?path=contracts", "", "")
+	locked("deleting the folder", rec, response)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected the document to be kept: %v", err)
+	}
+
+	// Alice deletes the document they hold
+	rec, _ = serve(http.MethodDelete, "/api/document/?id="+id+"&path=contracts/lease.pdf", "alice", "")
+
+	// It is deleted and its lock goes with it
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected alice's delete to succeed, got %d %s", rec.Code, rec.Body.String())
+	}
+	if _, err := handler.DB.GetDocumentLock(id); err == nil {
+		t.Error("Expected the lock to be released with the document")
+	}
+}
+
+func TestLockDocumentValidation(t *testing.T) {
+	handler := setupTestHandler(t)
+	id := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "a.pdf"), "").ULID.String()
+	lock := func(target, body string) (int, dto.ErrorResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/document/"+target+"/lock", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := handler.Echo.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(target)
+		if err := handler.LockDocument(c); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var response dto.ErrorResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		wantCode int
+		want     dto.ErrorCode
+	}{
+		{"no holder and too long", id, `{"ttlSeconds":100000}`, http.StatusBadRequest, dto.CodeValidation},
+		{"malformed ID", "not-a-ulid", `{"holder":"alice"}`, http.StatusBadRequest, dto.CodeInvalidID},
+		{"unknown document", "01ARZ3NDEKTSV4RRFFQ69G5FAV", `{"holder":"alice"}`, http.StatusNotFound, dto.CodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, response := lock(tt.target, tt.body)
+			if code != tt.wantCode || response.Code != tt.want {
+				t.Errorf("Expected %d %s, got %d %+v", tt.wantCode, tt.want, code, response)
+			}
+			if tt.want == dto.CodeValidation && (response.Fields["holder"] == "" || response.Fields["ttlSeconds"] == "") {
+				t.Errorf("Expected holder and ttlSeconds problems, got %v", response.Fields)
+			}
+		})
+	}
+}
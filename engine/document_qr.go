@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	// defaultQRSize, minQRSize and maxQRSize bound the side of a document QR code in pixels
+	defaultQRSize = 256
+	minQRSize     = 64
+	maxQRSize     = 1024
+)
+
+// GetDocumentQR returns a QR code of a document's view URL
+// @Summary Get a document QR code
+// @Description A PNG QR code encoding the document's view URL, for labelling physical files. The /scan page of the web UI opens the document from it.
+// @Description The URL uses BASE_URL behind a reverse proxy and the request's host otherwise.
+// @Tags Documents
+// @Produce image/png
+// @Param id path string true "Document ULID"
+// @Param size query int false "Side of the image in pixels (default: 256, 64 to 1024)"
+// @Success 200 {file} file "QR code PNG"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID or size"
+// @Failure 404 {object} map[string]interface{} "Document not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /document/{id}/qr.png [get]
+func (serverHandler *ServerHandler) GetDocumentQR(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "document")
+	if !ok {
+		return err
+	}
+	if serverHandler.hiddenDocument(c, id.String()) {
+		return documentNotFound(c)
+	}
+	size := defaultQRSize
+	if value := c.QueryParam("size"); value != "" {
+		size, err = strconv.Atoi(value)
+		if err != nil || size < minQRSize || size > maxQRSize {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid size, expected 64 to 1024 pixels",
+				"code":  dto.CodeBadRequest,
+			})
+		}
+	}
+	if _, err := serverHandler.DB.GetDocumentByULID(id.String()); errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document not found",
+			"code":  dto.CodeNotFound,
+		})
+	} else if err != nil {
+		Logger.Error("Failed to get document for QR code", "ulid", id.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve document",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	png, err := qrcode.Encode(serverHandler.documentLink(c, id), qrcode.Medium, size)
+	if err != nil {
+		Logger.Error("Failed to encode QR code", "ulid", id.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to encode QR code",
+			"code":  dto.CodeInternal,
+		})
+	}
+	// The code only changes if BASE_URL does, so browsers and printers may keep it for a day
+	c.Response().Header().Set("Cache-Control", "private, max-age=86400")
+	return c.Blob(http.StatusOK, "image/png", png)
+}
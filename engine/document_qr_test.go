@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestGetDocumentQR(t *testing.T) {
+	// A stored document
+	handler := setupTestHandler(t)
+	handler.Echo.GET("/api/document/:id/qr.png", handler.GetDocumentQR)
+	doc := saveTestDocument(t, handler.DB, "/docs/archive/boiler.pdf", "boiler")
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	// Its QR code is requested at a chosen size
+	rec := get("/api/document/" + doc.ULID.String() + "/qr.png?size=128")
+
+	// A PNG of that size comes back
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("Expected a PNG, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	image, err := png.Decode(rec.Body)
+	if err != nil {
+		t.Fatalf("Failed to decode QR code: %v", err)
+	}
+	if bounds := image.Bounds(); bounds.Dx() != 128 || bounds.Dy() != 128 {
+		t.Errorf("Expected a 128 pixel square, got %v", bounds)
+	}
+
+	// Without a size the default is used
+	if rec := get("/api/document/" + doc.ULID.String() + "/qr.png"); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rec.Code)
+	} else if image, err := png.Decode(rec.Body); err != nil || image.Bounds().Dx() != defaultQRSize {
+		t.Errorf("Expected a %d pixel QR code, got %v (%v)", defaultQRSize, image, err)
+	}
+
+	// Bad sizes, bad IDs and unknown documents are rejected
+	for target, want := range map[string]int{
+		"/api/document/" + doc.ULID.String() + "/qr.png?size=8":    http.StatusBadRequest,
+		"/api/document/" + doc.ULID.String() + "/qr.png?size=huge": http.StatusBadRequest,
+		"/api/document/nope/qr.png":                                http.StatusBadRequest,
+		"/api/document/" + ulid.Make().String() + "/qr.png":        http.StatusNotFound,
+	} {
+		if rec := get(target); rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", target, want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// documentSearchLimit is how many hits SearchDocumentText returns when no limit is given
+	documentSearchLimit = 100
+	// snippetContext is how many characters of text either side of a hit its snippet shows
+	snippetContext = 40
+)
+
+// documentMatch is one hit of a search inside a document's text. Offset and Length count characters
+// (Unicode code points) of the text served by GetDocumentText.
+type documentMatch struct {
+	Offset  int    `json:"offset"`
+	Length  int    `json:"length"`
+	Page    int    `json:"page"`
+	Snippet string `json:"snippet"`
+}
+
+// termPattern matches a search term case-insensitively, with any run of whitespace between its
+// words so that a phrase still matches across line and page breaks
+func termPattern(term string) *regexp.Regexp {
+	words := strings.Fields(term)
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(words, `\s+`))
+}
+
+// findInText returns every hit of term in text, and the pages they are on. Pages are counted from the
+// page breaks in the text; text without any is a single page.
+func findInText(text, term string) []documentMatch {
+	var matches []documentMatch
+	page, pageCounted, runes, runesCounted := 1, 0, 0, 0
+	for _, hit := range termPattern(term).FindAllStringIndex(text, -1) {
+		page += strings.Count(text[pageCounted:hit[0]], pageBreak)
+		pageCounted = hit[0]
+		runes += utf8.RuneCountInString(text[runesCounted:hit[0]])
+		runesCounted = hit[0]
+		matches = append(matches, documentMatch{
+			Offset:  runes,
+			Length:  utf8.RuneCountInString(text[hit[0]:hit[1]]),
+			Page:    page,
+			Snippet: snippet(text, hit[0], hit[1]),
+		})
+	}
+	return matches
+}
+
+// snippet is the hit between start and end with some text either side, on one line
+func snippet(text string, start, end int) string {
+	from := start
+	for i := 0; i < snippetContext && from > 0; i++ {
+		_, size := utf8.DecodeLastRuneInString(text[:from])
+		from -= size
+	}
+	to := end
+	for i := 0; i < snippetContext && to < len(text); i++ {
+		_, size := utf8.DecodeRuneInString(text[to:])
+		to += size
+	}
+	return strings.Join(strings.Fields(text[from:to]), " ")
+}
+
+// SearchDocumentText finds a term inside one document
+// @Summary Search within a document
+// @Description Find every occurrence of a term in a document's text, case-insensitively, with its character offset, page number and a snippet, so a viewer can jump between hits.
+// @Description Words of the term may be separated by any whitespace in the text. Page numbers come from the page breaks kept when PDFs are ingested; other documents are a single page.
+// @Tags Documents
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Param term query string true "Text to find"
+// @Param limit query int false "Maximum hits to return (default: 100, max: 200)"
+// @Success 200 {object} map[string]interface{} "total, pageCount, pages holding hits, and matches with offset, length, page and snippet"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID, term or limit"
+// @Failure 404 {object} map[string]interface{} "Document not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /document/{id}/search [get]
+func (serverHandler *ServerHandler) SearchDocumentText(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "document")
+	if !ok {
+		return err
+	}
+	if serverHandler.hiddenDocument(c, id.String()) {
+		return documentNotFound(c)
+	}
+	term := strings.TrimSpace(c.QueryParam("term"))
+	if term == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Search term is required",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	limit, err := limitParam(c, documentSearchLimit)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	fullText, err := serverHandler.DB.GetDocumentText(id.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to get document text", "ulid", id.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve document text",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	matches := findInText(fullText, term)
+	pages := []int{}
+	for _, match := range matches {
+		if len(pages) == 0 || pages[len(pages)-1] != match.Page {
+			pages = append(pages, match.Page)
+		}
+	}
+	total := len(matches)
+	if matches == nil {
+		matches = []documentMatch{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"id":        id.String(),
+		"term":      term,
+		"total":     total,
+		"pageCount": strings.Count(fullText, pageBreak) + 1,
+		"pages":     pages,
+		"matches":   matches[:min(total, limit)],
+	})
+}
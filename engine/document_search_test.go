@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestFindInText(t *testing.T) {
+	// Three pages of text, with a phrase broken across a line and a hit after accented text
+	text := "Notice period: see clause 4.\fThe notice\nperiod is three months.\fCafé NOTICE."
+
+	// Searching for a word and a phrase
+	word := findInText(text, "notice")
+	phrase := findInText(text, "notice period")
+
+	// Hits carry character offsets, pages and snippets on one line
+	expected := []documentMatch{
+		{Offset: 0, Length: 6, Page: 1},
+		{Offset: 33, Length: 6, Page: 2},
+		{Offset: 69, Length: 6, Page: 3},
+	}
+	if len(word) != len(expected) {
+		t.Fatalf("Expected %d hits, got %+v", len(expected), word)
+	}
+	for i, want := range expected {
+		if word[i].Offset != want.Offset || word[i].Length != want.Length || word[i].Page != want.Page {
+			t.Errorf("Hit %d: expected %+v, got %+v", i, want, word[i])
+		}
+	}
+	if word[1].Snippet != "Notice period: see clause 4. The notice period is three months. Café NOTICE." {
+		t.Errorf("Unexpected snippet %q", word[1].Snippet)
+	}
+	if len(phrase) != 2 || phrase[1].Page != 2 || phrase[1].Length != 13 {
+		t.Errorf("Expected the phrase to match across the line break, got %+v", phrase)
+	}
+	if hits := findInText(text, "clause 4.*"); len(hits) != 0 {
+		t.Errorf("Expected the term to be matched literally, got %+v", hits)
+	}
+}
+
+func TestSearchDocumentText(t *testing.T) {
+	// A stored two-page document
+	handler := setupTestHandler(t)
+	handler.Echo.GET("/api/document/:id/search", handler.SearchDocumentText)
+	doc := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "contract.pdf"), "rent is due\frent review in May, rent cap")
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	// Searching inside it with a limit
+	rec := get("/api/document/" + doc.ULID.String() + "/search?term=Rent&limit=2")
+
+	// All hits are counted, the pages listed, and the matches limited
+	var response struct {
+		Total     int             `json:"total"`
+		PageCount int             `json:"pageCount"`
+		Pages     []int           `json:"pages"`
+		Matches   []documentMatch `json:"matches"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode %d %q: %v", rec.Code, rec.Body.String(), err)
+	}
+	if response.Total != 3 || response.PageCount != 2 || len(response.Pages) != 2 || len(response.Matches) != 2 {
+		t.Errorf("Unexpected response %s", rec.Body.String())
+	}
+
+	// Missing terms, bad IDs and unknown documents are rejected
+	for target, code := range map[string]int{
+		"/api/document/" + doc.ULID.String() + "/search":           http.StatusBadRequest,
+		"/api/document/not-a-ulid/search?term=rent":                http.StatusBadRequest,
+		"/api/document/" + ulid.Make().String() + "/search?term=x": http.StatusNotFound,
+	} {
+		if rec := get(target); rec.Code != code {
+			t.Errorf("%s: expected %d, got %d", target, code, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/drummonds/godocs/storage"
+)
+
+// documentStorage is the backend document files are written to and scanned through, the local
+// DocumentPath unless another was configured
+func (serverHandler *ServerHandler) documentStorage() storage.Backend {
+	if serverHandler.Storage != nil {
+		return serverHandler.Storage
+	}
+	return storage.NewLocal(serverHandler.ServerConfig.DocumentPath)
+}
+
+// documentKey is the storage key of a file under the document folder, its path relative to the folder
+// with "/" separators
+func documentKey(documentPath string, filePath string) (string, error) {
+	rel, err := filepath.Rel(filepath.FromSlash(documentPath), filepath.FromSlash(filePath))
+	if err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s is not inside the document folder %s", filePath, documentPath)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// readDocumentFile reads a whole file under the document folder through document storage
+func (serverHandler *ServerHandler) readDocumentFile(filePath string) ([]byte, error) {
+	key, err := documentKey(serverHandler.ServerConfig.DocumentPath, filePath)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := serverHandler.documentStorage().Read(key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// documentKeyPath is the path under the document folder of a storage key, as stored on a Document
+func documentKeyPath(documentPath string, key string) string {
+	return filepath.Join(filepath.FromSlash(documentPath), filepath.FromSlash(key))
+}
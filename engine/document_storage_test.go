@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/storage"
+)
+
+// recordingStorage is local storage that notes the keys written through it
+type recordingStorage struct {
+	*storage.Local
+	written []string
+}
+
+func (r *recordingStorage) Write(key string, content io.Reader) error {
+	r.written = append(r.written, key)
+	return r.Local.Write(key, content)
+}
+
+func TestDocumentsAreStoredThroughTheBackend(t *testing.T) {
+	// A handler with its own storage backend and a text file in ingress
+	handler := setupTestHandler(t)
+	documents := &recordingStorage{Local: storage.NewLocal(handler.ServerConfig.DocumentPath)}
+	handler.Storage = documents
+	os.WriteFile(filepath.Join(handler.ServerConfig.IngressPath, "gas.txt"), []byte("gas bill"), 0644)
+	job, err := handler.DB.CreateJob(database.JobTypeIngestion, "test")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	handler.ingressJobFuncWithTracking(handler.ServerConfig, handler.DB, job.ID)
+
+	// The file was written through the backend under its key
+	if len(documents.written) != 1 || documents.written[0] != "gas.txt" {
+		t.Fatalf("Expected gas.txt to be written through the backend, got %v", documents.written)
+	}
+
+	// A file is put straight into storage with an OCR text companion and orphans are looked for
+	documents.Local.Write("scans/found.pdf", io.LimitReader(zeroReader{}, 4))
+	documents.Local.Write("scans/found.pdf.txt", io.LimitReader(zeroReader{}, 4))
+	all, err := handler.DB.GetAllDocuments()
+	if err != nil {
+		t.Fatalf("GetAllDocuments failed: %v", err)
+	}
+	orphans, err := handler.findOrphanedDocuments(all)
+
+	// It is found by walking the backend, and its companion is not reported separately
+	want := documentKeyPath(handler.ServerConfig.DocumentPath, "scans/found.pdf")
+	if err != nil || len(orphans) != 1 || orphans[0] != want {
+		t.Errorf("Expected only %s to be orphaned, got %v, %v", want, orphans, err)
+	}
+}
+
+func TestDocumentKey(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "documents")
+	if key, err := documentKey(filepath.ToSlash(root), filepath.Join(root, "bills", "gas.pdf")); err != nil || key != "bills/gas.pdf" {
+		t.Errorf("Expected bills/gas.pdf, got %q, %v", key, err)
+	}
+	for _, outside := range []string{root, filepath.Join(root, "..", "elsewhere.pdf"), "gas.pdf"} {
+		if key, err := documentKey(root, outside); err == nil {
+			t.Errorf("Expected %s to be refused, got key %q", outside, key)
+		}
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
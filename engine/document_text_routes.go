@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// defaultTextChunk is how many characters of text a page holds when offset is given without limit
+	defaultTextChunk = 64 * 1024
+	// maxTextChunk is the most characters of text one page may hold
+	maxTextChunk = 1024 * 1024
+)
+
+// GetDocumentText streams the extracted full text of a single document, or one page of it
+// @Summary Get a document's full text
+// @Description Return the extracted (or OCR) text of a document as plain text. List and search responses leave the text out, so clients fetch it here when needed. With offset or limit the text comes a page at a time as JSON, so a viewer can show a huge OCR output without pulling it all at once; offsets and lengths count characters, as search match offsets do.
+// @Tags Documents
+// @Produce plain,json
+// @Param id path string true "Document ULID"
+// @Param offset query int false "Character to start the page at (default 0)"
+// @Param limit query int false "Characters in the page (default 65536, at most 1048576)"
+// @Success 200 {string} string "Document full text, or a dto.DocumentTextPage with offset or limit"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID, offset or limit"
+// @Failure 404 {object} map[string]interface{} "Document not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /document/{id}/text [get]
+func (serverHandler *ServerHandler) GetDocumentText(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "document")
+	if !ok {
+		return err
+	}
+	if serverHandler.hiddenDocument(c, id.String()) {
+		return documentNotFound(c)
+	}
+	if c.QueryParam("offset") != "" || c.QueryParam("limit") != "" {
+		return serverHandler.getDocumentTextPage(c, id.String())
+	}
+
+	fullText, err := serverHandler.DB.GetDocumentText(id.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to get document text", "ulid", id.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve document text",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	return c.Stream(http.StatusOK, echo.MIMETextPlainCharsetUTF8, strings.NewReader(fullText))
+}
+
+// getDocumentTextPage answers with one page of a document's text, the page given by the offset and
+// limit query parameters
+func (serverHandler *ServerHandler) getDocumentTextPage(c echo.Context, id string) error {
+	offset, limit := 0, defaultTextChunk
+	var err error
+	if value := c.QueryParam("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid offset, expected a character position of 0 or more",
+				"code":  dto.CodeBadRequest,
+			})
+		}
+	}
+	if value := c.QueryParam("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > maxTextChunk {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": fmt.Sprintf("Invalid limit, expected 1 to %d characters", maxTextChunk),
+				"code":  dto.CodeBadRequest,
+			})
+		}
+	}
+
+	text, total, err := serverHandler.DB.GetDocumentTextRange(id, offset, limit)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to get document text", "ulid", id, "offset", offset, "limit", limit, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve document text",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	page := dto.DocumentTextPage{Text: text, Offset: offset, Length: utf8.RuneCountInString(text), Total: total}
+	if end := offset + page.Length; end < total {
+		page.NextOffset = end
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+// fullTextParam reads the optional fullText query flag used by endpoints that can include document text
+func fullTextParam(c echo.Context) (bool, error) {
+	return boolQueryParam(c, "fullText")
+}
+
+// boolQueryParam reads an optional true/false query parameter, defaulting to false
+func boolQueryParam(c echo.Context, name string) (bool, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/oklog/ulid/v2"
+)
+
+func TestGetDocumentText(t *testing.T) {
+	// A stored document with extracted text
+	handler := setupTestHandler(t)
+	handler.Echo.GET("/api/document/:id", handler.GetDocument)
+	handler.Echo.GET("/api/document/:id/text", handler.GetDocumentText)
+	doc := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "a.pdf"), "invoice total 42")
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	// The text endpoint is called
+	rec := get("/api/document/" + doc.ULID.String() + "/text")
+
+	// The text comes back as plain text, and bad or unknown IDs are rejected
+	if rec.Code != http.StatusOK || rec.Body.String() != "invoice total 42" {
+		t.Errorf("Expected document text, got %d %q", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; charset=UTF-8" {
+		t.Errorf("Expected plain text content type, got %q", ct)
+	}
+	if rec := get("/api/document/not-a-ulid/text"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected invalid ULID to be rejected, got %d", rec.Code)
+	}
+	if rec := get("/api/document/" + ulid.Make().String() + "/text"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected unknown document to be 404, got %d", rec.Code)
+	}
+
+	// The document endpoint only includes the text when asked
+	for target, want := range map[string]string{
+		"/api/document/" + doc.ULID.String():                 "",
+		"/api/document/" + doc.ULID.String() + "?fullText=1": "invoice total 42",
+	} {
+		var got database.Document
+		rec := get(target)
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: response is not a document: %v", target, err)
+		}
+		if got.FullText != want {
+			t.Errorf("%s: expected FullText %q, got %q", target, want, got.FullText)
+		}
+	}
+	if rec := get("/api/document/" + doc.ULID.String() + "?fullText=maybe"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected invalid fullText to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestListQueriesSkipFullText(t *testing.T) {
+	// A document with text in a folder
+	handler := setupTestHandler(t)
+	doc := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "a.pdf"), "needle in the text")
+
+	// Listing by folder, newest and search
+	byFolder, err := handler.DB.GetDocumentsByFolder(doc.Folder)
+	if err != nil {
+		t.Fatalf("Failed to list folder: %v", err)
+	}
+	newest, _, err := handler.DB.GetNewestDocumentsWithPagination(1, 10)
+	if err != nil {
+		t.Fatalf("Failed to list newest: %v", err)
+	}
+	found, err := handler.DB.SearchDocuments("needle")
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+
+	// Each finds the document but leaves its text out
+	for name, docs := range map[string][]database.Document{"folder": byFolder, "newest": newest, "search": found} {
+		if len(docs) != 1 || docs[0].ULID != doc.ULID {
+			t.Errorf("%s: expected the document, got %+v", name, docs)
+			continue
+		}
+		if docs[0].FullText != "" {
+			t.Errorf("%s: expected no full text, got %q", name, docs[0].FullText)
+		}
+	}
+}
+
+func TestGetDocumentTextPages(t *testing.T) {
+	// A stored document with a long OCR output
+	handler := setupTestHandler(t)
+	handler.Echo.GET("/api/document/:id/text", handler.GetDocumentText)
+	text := strings.Repeat("ä", 10) + strings.Repeat("b", 15)
+	doc := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "scan.pdf"), text)
+	get := func(query string) (*httptest.ResponseRecorder, dto.DocumentTextPage) {
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/document/"+doc.ULID.String()+"/text?"+query, nil))
+		var page dto.DocumentTextPage
+		json.Unmarshal(rec.Body.Bytes(), &page)
+		return rec, page
+	}
+
+	// The text is read ten characters at a time
+	var pages []dto.DocumentTextPage
+	for offset := 0; ; {
+		rec, page := get(fmt.Sprintf("offset=%d&limit=10", offset))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+		}
+		pages = append(pages, page)
+		if page.NextOffset == 0 {
+			break
+		}
+		offset = page.NextOffset
+	}
+
+	// Three pages split on characters cover the whole text, with its length on each
+	if len(pages) != 3 {
+		t.Fatalf("Expected 3 pages, got %+v", pages)
+	}
+	if pages[0].Text != strings.Repeat("ä", 10) || pages[0].Length != 10 || pages[0].Total != 25 || pages[0].NextOffset != 10 {
+		t.Errorf("Expected the ten umlauts first, got %+v", pages[0])
+	}
+	if pages[2].Text != strings.Repeat("b", 5) || pages[2].Offset != 20 || pages[2].Length != 5 {
+		t.Errorf("Expected the last five characters, got %+v", pages[2])
+	}
+
+	// A limit alone starts at the beginning, and bad values are refused
+	if _, page := get("limit=3"); page.Text != "äää" || page.Offset != 0 {
+		t.Errorf("Expected the first three characters, got %+v", page)
+	}
+	for _, query := range []string{"offset=-1", "limit=0", "limit=x", fmt.Sprintf("limit=%d", maxTextChunk+1)} {
+		if rec, _ := get(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+	"github.com/oklog/ulid/v2"
+)
+
+// documentViewURL is the canonical view URL for a document; it only depends on the ULID so it survives moves and renames
+func documentViewURL(id ulid.ULID) string {
+	return documentViewPrefix + id.String()
+}
+
+// ViewDocument serves a document file, looking up its current path on every request.
+// Old-style links (lower-case ULIDs or a trailing file name) are redirected permanently to the canonical URL.
+// @Summary View a document file
+// @Description Serve the stored file for a document with its detected MIME type as Content-Type. Non-canonical links are answered with a 301 to /document/view/{id}.
+// @Tags Documents
+// @Produce octet-stream
+// @Param id path string true "Document ULID"
+// @Success 200 {file} file "Document file"
+// @Success 301 "Redirect to the canonical URL"
+// @Failure 404 {object} map[string]interface{} "Document or file not found"
+// @Router /document/view/{id} [get]
+func (serverHandler *ServerHandler) ViewDocument(c echo.Context) error {
+	raw := c.Param("id")
+	id, err := parseULID(raw)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if raw != id.String() || c.Param("*") != "" {
+		target := documentViewURL(id)
+		if query := c.Request().URL.RawQuery; query != "" {
+			target += "?" + query
+		}
+		return c.Redirect(http.StatusMovedPermanently, target)
+	}
+
+	document, err := serverHandler.DB.GetDocumentByULID(id.String())
+	if err == nil && document != nil {
+		// Signed links carry no session, so only a signed-in account's folder permissions apply
+		var access *folderAccess
+		if access, err = serverHandler.folderAccess(c); err == nil && !access.canRead(document.Folder) {
+			document = nil
+		}
+	}
+	if err != nil || document == nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if _, err := os.Stat(document.Path); err != nil {
+		if archive, archiveErr := serverHandler.DB.GetDocumentArchive(id.String()); archiveErr == nil {
+			return documentArchived(c, archive)
+		}
+		Logger.Warn("Document file missing", "ulid", id.String(), "path", document.Path, "error", err)
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document file is missing",
+			"code":  dto.CodeFileMissing,
+		})
+	}
+	if countsAsAccess(c.Request()) {
+		if err := serverHandler.DB.RecordDocumentAccess(id.String(), database.Now()); err != nil {
+			Logger.Warn("Unable to record document access", "ulid", id.String(), "error", err)
+		}
+	}
+	// Documents stored before the MIME type was recorded are sniffed as they are served
+	mimeType := document.MIMEType
+	if mimeType == "" {
+		mimeType = database.DetectMIMEType(document.Path)
+	}
+	c.Response().Header().Set(echo.HeaderContentType, mimeType)
+	c.Response().Header().Set(echo.HeaderContentDisposition, contentDisposition(document.Name))
+	return c.File(document.Path)
+}
+
+// countsAsAccess reports whether a file request is someone opening the document. PDF viewers fetch
+// the rest of a file in byte ranges after the first request, and HEAD requests only probe it.
+func countsAsAccess(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	byteRange := r.Header.Get("Range")
+	return byteRange == "" || strings.HasPrefix(byteRange, "bytes=0-")
+}
+
+// repairDocumentURLs rewrites stored URL fields that differ from the canonical view URL and returns how many changed
+func repairDocumentURLs(db database.Repository, heartbeat *jobHeartbeat) (int, error) {
+	repaired := 0
+	var cursor *database.DocumentCursor
+	for {
+		page, err := db.GetNewestDocumentsAfter(cursor, maxCursorPageSize)
+		if err != nil {
+			return repaired, err
+		}
+		for _, document := range page {
+			heartbeat.beat()
+			canonical := documentViewURL(document.ULID)
+			if document.URL == canonical {
+				continue
+			}
+			if err := db.UpdateDocumentURL(document.ULID.String(), canonical); err != nil {
+				return repaired, err
+			}
+			Logger.Debug("Repaired document URL", "ulid", document.ULID.String(), "old", document.URL)
+			repaired++
+		}
+		if len(page) < maxCursorPageSize {
+			return repaired, nil
+		}
+		cursor = database.CursorAfter(page[len(page)-1])
+	}
+}
+
+// RepairDocumentURLs starts a job that rewrites every stored document URL to its canonical form
+// @Summary Repair document URLs
+// @Description Rewrite stored document URL fields to the canonical /document/view/{id} form
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Job created with jobId"
+// @Failure 409 {object} map[string]interface{} "A URL repair job is already active; jobId is that job"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /documents/urls/repair [post]
+func (serverHandler *ServerHandler) RepairDocumentURLs(c echo.Context) error {
+	job, err := serverHandler.startJob(database.JobTypeURLRepair, "Starting document URL repair")
+	if errors.Is(err, errJobActive) {
+		return jobAlreadyActive(c, job)
+	}
+	if err != nil {
+		Logger.Error("Failed to create URL repair job", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to create URL repair job",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	go serverHandler.urlRepairJob(serverHandler.DB, job.ID)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Document URL repair started",
+		"jobId":   job.ID.String(),
+	})
+}
+
+// urlRepairJob runs repairDocumentURLs with job tracking
+func (serverHandler *ServerHandler) urlRepairJob(db database.Repository, jobID ulid.ULID) {
+	db.UpdateJobStatus(jobID, database.JobStatusRunning, "Checking document URLs")
+
+	repaired, err := repairDocumentURLs(db, newJobHeartbeat(db, jobID))
+	if err != nil {
+		Logger.Error("Document URL repair failed", "repaired", repaired, "error", err)
+		db.UpdateJobError(jobID, fmt.Sprintf("URL repair failed after %d documents: %v", repaired, err))
+		return
+	}
+	if repaired > 0 {
+		serverHandler.invalidateDocumentCache()
+	}
+	if err := db.CompleteJob(jobID, fmt.Sprintf(`{"repaired": %d}`, repaired)); err != nil {
+		Logger.Error("Failed to mark URL repair job as complete", "error", err)
+	}
+	Logger.Info("Document URL repair completed", "repaired", repaired)
+}
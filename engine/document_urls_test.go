@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestViewDocumentFollowsMovesAndRedirectsOldLinks(t *testing.T) {
+	// A document whose stored URL is stale and whose file has moved since it was added
+	handler := setupTestHandler(t)
+	oldPath := filepath.Join(handler.ServerConfig.DocumentPath, "a.txt")
+	doc := saveTestDocument(t, handler.DB, oldPath, "")
+	newPath := filepath.Join(handler.ServerConfig.DocumentPath, "moved", "a.txt")
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	if err := os.WriteFile(newPath, []byte("moved contents"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := handler.DB.DeleteDocument(doc.ULID.String()); err != nil {
+		t.Fatalf("Failed to remove old record: %v", err)
+	}
+	doc.StormID = 0
+	doc.Path = newPath
+	doc.Folder = filepath.Dir(newPath)
+	if err := handler.DB.SaveDocument(doc); err != nil {
+		t.Fatalf("Failed to save moved document: %v", err)
+	}
+	if err := handler.DB.UpdateDocumentURL(doc.ULID.String(), "http://old-host/document/view/"+doc.ULID.String()); err != nil {
+		t.Fatalf("Failed to set URL: %v", err)
+	}
+
+	// The server starts
+	if err := handler.AddDocumentViewRoutes(); err != nil {
+		t.Fatalf("Failed to add view routes: %v", err)
+	}
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	canonical := documentViewPrefix + doc.ULID.String()
+
+	// Startup repaired the stored URL
+	stored, err := handler.DB.GetDocumentByULID(doc.ULID.String())
+	if err != nil || stored.URL != canonical {
+		t.Errorf("Expected URL repaired to %s, got %q, %v", canonical, stored.URL, err)
+	}
+
+	// The canonical URL serves the file from its new location
+	if rec := get(canonical); rec.Code != http.StatusOK || rec.Body.String() != "moved contents" {
+		t.Errorf("Unexpected view response %d %q", rec.Code, rec.Body.String())
+	}
+
+	// The stored MIME type is sent as the Content-Type, whatever the file name says
+	doc.MIMEType = "application/pdf"
+	if err := handler.DB.SaveDocument(doc); err != nil {
+		t.Fatalf("Failed to save MIME type: %v", err)
+	}
+	if rec := get(canonical); rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("Expected the stored MIME type to be served, got %q", rec.Header().Get("Content-Type"))
+	}
+
+	// Old-style links redirect permanently, keeping the query string
+	for _, old := range []string{documentViewPrefix + strings.ToLower(doc.ULID.String()) + "?expires=1", canonical + "/a.txt?expires=1"} {
+		rec := get(old)
+		if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != canonical+"?expires=1" {
+			t.Errorf("%s: expected 301 to %s, got %d %q", old, canonical, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+
+	// Unknown documents and missing files are 404s
+	if rec := get(documentViewPrefix + "not-a-ulid"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an invalid ULID, got %d", rec.Code)
+	}
+	os.Remove(newPath)
+	if rec := get(canonical); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing file, got %d", rec.Code)
+	}
+}
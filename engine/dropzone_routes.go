@@ -0,0 +1,248 @@
+package engine
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+	"github.com/oklog/ulid/v2"
+)
+
+// dropzoneSourcePattern matches characters not allowed in a dropzone source name
+var dropzoneSourcePattern = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// dropzoneResult is stored as the job result so each pushed document keeps a record of where it came from
+type dropzoneResult struct {
+	Source       string    `json:"source"`
+	Filename     string    `json:"filename"`
+	Path         string    `json:"path"`
+	ReceivedAt   time.Time `json:"receivedAt"`
+	RemoteAddr   string    `json:"remoteAddr"`
+	UserAgent    string    `json:"userAgent"`
+	DocumentULID string    `json:"documentUlid,omitempty"`
+}
+
+// ReceiveDropzone accepts a document pushed by a scanning service and ingests it
+// @Summary Receive a document from a scan service webhook
+// @Description Accepts a file from services such as Scanbot or Office Lens, or any client that can POST a file.
+// @Description Send either multipart/form-data with a "file" field, or the raw file as the request body with a filename query parameter or X-Filename header.
+// @Description Requires the dropzone API key in the X-API-Key header or as a Bearer token. The file is written to the ingress folder under <DROPZONE_FOLDER>/<source> and ingested as a job.
+// @Description The route is outside the login, so bodies over DROPZONE_MAX_MB are refused before they are stored.
+// @Tags Integrations
+// @Accept multipart/form-data
+// @Produce json
+// @Param source query string false "Name of the sending service, e.g. scanbot (default: generic)"
+// @Param filename query string false "File name when sending a raw body"
+// @Success 202 {object} map[string]interface{} "Job created with jobId"
+// @Failure 400 {object} map[string]interface{} "No file in request"
+// @Failure 401 {object} map[string]interface{} "Missing or invalid API key"
+// @Failure 404 {object} map[string]interface{} "Dropzone integration is disabled"
+// @Failure 413 {object} map[string]interface{} "File over DROPZONE_MAX_MB"
+// @Failure 415 {object} map[string]interface{} "Unsupported file type"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /integrations/dropzone [post]
+func (serverHandler *ServerHandler) ReceiveDropzone(c echo.Context) error {
+	apiKey := serverHandler.ServerConfig.DropzoneAPIKey
+	if apiKey == "" {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Dropzone integration is disabled, set DROPZONE_API_KEY to enable it",
+			"code":  dto.CodeFeatureDisabled,
+		})
+	}
+	if !validDropzoneKey(c.Request(), apiKey) {
+		Logger.Warn("Rejected dropzone push with invalid API key", "remoteAddr", c.RealIP())
+		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Missing or invalid API key",
+			"code":  dto.CodeUnauthorized,
+		})
+	}
+	maxMB := serverHandler.ServerConfig.DropzoneMaxMB
+	if maxMB > 0 {
+		c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, int64(maxMB)<<20)
+	}
+
+	source := dropzoneSourcePattern.ReplaceAllString(strings.ToLower(c.FormValue("source")), "")
+	if source == "" {
+		source = "generic"
+	}
+
+	filename, content, err := dropzoneFile(c)
+	if dropzoneTooLarge(err) {
+		return dropzoneTooLargeResponse(c, maxMB)
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	defer content.Close()
+	if err := serverHandler.checkIngestible(filename); err != nil {
+		return c.JSON(http.StatusUnsupportedMediaType, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeUnsupportedType,
+		})
+	}
+
+	path, err := serverHandler.writeDropzoneFile(source, filename, content)
+	if dropzoneTooLarge(err) {
+		Logger.Warn("Refused dropzone push over the size limit", "source", source, "filename", filename, "maxMB", maxMB)
+		return dropzoneTooLargeResponse(c, maxMB)
+	}
+	if err != nil {
+		Logger.Error("Unable to store dropzone file", "source", source, "filename", filename, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to store file",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	job, err := serverHandler.DB.CreateJob(database.JobTypeIngestion, fmt.Sprintf("Dropzone document from %s: %s", source, filename))
+	if err != nil {
+		Logger.Error("Failed to create dropzone ingestion job", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to create job",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	result := dropzoneResult{
+		Source:     source,
+		Filename:   filename,
+		Path:       path,
+		ReceivedAt: database.Now(),
+		RemoteAddr: c.RealIP(),
+		UserAgent:  c.Request().UserAgent(),
+	}
+	Logger.Info("Received dropzone document", "source", source, "filename", filename, "jobId", job.ID.String())
+	go serverHandler.dropzoneJobFunc(serverHandler.DB, job.ID, result)
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"message": "Document received",
+		"jobId":   job.ID.String(),
+		"path":    path,
+	})
+}
+
+// validDropzoneKey checks the X-API-Key header or Bearer token against the configured key
+func validDropzoneKey(request *http.Request, apiKey string) bool {
+	provided := request.Header.Get("X-API-Key")
+	if provided == "" {
+		provided = strings.TrimPrefix(request.Header.Get(echo.HeaderAuthorization), "Bearer ")
+	}
+	return provided != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(apiKey)) == 1
+}
+
+// dropzoneTooLarge reports whether err came from reading past the DROPZONE_MAX_MB limit
+func dropzoneTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+// dropzoneTooLargeResponse sends the 413 for a push over the size limit
+func dropzoneTooLargeResponse(c echo.Context, maxMB int) error {
+	return c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{
+		"error": fmt.Sprintf("Files pushed to the dropzone are limited to %d MB", maxMB),
+		"code":  dto.CodeTooLarge,
+	})
+}
+
+// dropzoneFile returns the pushed file from a multipart "file" field or from the raw request body
+func dropzoneFile(c echo.Context) (string, io.ReadCloser, error) {
+	request := c.Request()
+	if strings.HasPrefix(request.Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if dropzoneTooLarge(err) {
+			return "", nil, err
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("multipart request has no file field")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return "", nil, err
+		}
+		return clientFileName(fileHeader.Filename), file, nil
+	}
+
+	filename := c.QueryParam("filename")
+	if filename == "" {
+		filename = request.Header.Get("X-Filename")
+	}
+	filename = clientFileName(filename)
+	if filename == "" || filename == "." || filename == ".." {
+		return "", nil, fmt.Errorf("raw uploads need a filename query parameter or X-Filename header")
+	}
+	return filename, request.Body, nil
+}
+
+// writeDropzoneFile saves the file under <ingress>/<DropzoneFolder>/<source>, never overwriting an earlier push
+func (serverHandler *ServerHandler) writeDropzoneFile(source, filename string, content io.Reader) (string, error) {
+	dir := filepath.Join(serverHandler.ServerConfig.IngressPath, serverHandler.ServerConfig.DropzoneFolder, source)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, filename)
+	if _, err := os.Stat(path); err == nil {
+		path = filepath.Join(dir, database.MakeULID().String()+"-"+filename)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(path), nil
+}
+
+// dropzoneJobFunc ingests a pushed file and records its source metadata as the job result
+func (serverHandler *ServerHandler) dropzoneJobFunc(db database.Repository, jobID ulid.ULID, result dropzoneResult) {
+	db.UpdateJobStatus(jobID, database.JobStatusRunning, "Ingesting "+result.Filename)
+
+	path, err := serverHandler.preIngest(result.Path)
+	if err != nil {
+		db.UpdateJobError(jobID, err.Error())
+		return
+	}
+	result.Path = path
+	// A pushed scan is as good as an upload, so it goes ahead of scheduled batches
+	release, _ := serverHandler.processingSlot(context.Background(), priorityInteractive)
+	doc, err := serverHandler.ingressDocumentWithError(result.Path, "ingress")
+	release()
+	if err != nil {
+		Logger.Error("Dropzone ingestion failed", "path", result.Path, "error", err)
+		db.UpdateJobError(jobID, err.Error())
+		return
+	}
+	if doc != nil {
+		result.DocumentULID = doc.ULID.String()
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		db.UpdateJobError(jobID, fmt.Sprintf("Failed to encode dropzone result: %v", err))
+		return
+	}
+	if err := db.CompleteJob(jobID, string(encoded)); err != nil {
+		Logger.Error("Failed to mark dropzone job as complete", "error", err)
+	}
+}
@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/oklog/ulid/v2"
+)
+
+// setupDropzoneTestHandler builds a handler with the dropzone webhook enabled
+func setupDropzoneTestHandler(t *testing.T) *ServerHandler {
+	t.Helper()
+	handler := setupTestHandler(t)
+	handler.ServerConfig.DropzoneAPIKey = "secret"
+	handler.ServerConfig.DropzoneFolder = "dropzone"
+	handler.Echo.POST("/api/integrations/dropzone", handler.ReceiveDropzone)
+	return handler
+}
+
+func TestDropzoneRejectsBadRequests(t *testing.T) {
+	handler := setupDropzoneTestHandler(t)
+	tests := []struct {
+		name   string
+		target string
+		key    string
+		want   int
+	}{
+		{"missing key", "/api/integrations/dropzone?filename=a.pdf", "", http.StatusUnauthorized},
+		{"wrong key", "/api/integrations/dropzone?filename=a.pdf", "nope", http.StatusUnauthorized},
+		{"no filename", "/api/integrations/dropzone", "secret", http.StatusBadRequest},
+		{"unsupported type", "/api/integrations/dropzone?filename=a.exe", "secret", http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader("data"))
+		if tt.key != "" {
+			req.Header.Set("X-API-Key", tt.key)
+		}
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: got %d, want %d (%s)", tt.name, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+
+	// Without a key configured the endpoint is disabled
+	handler.ServerConfig.DropzoneAPIKey = ""
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/integrations/dropzone?filename=a.pdf", strings.NewReader("data")))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected disabled dropzone to return 404, got %d", rec.Code)
+	}
+}
+
+func TestDropzoneStoresFileUnderSource(t *testing.T) {
+	// A multipart push from a scan service, sent twice with the same name
+	handler := setupDropzoneTestHandler(t)
+	push := func() map[string]string {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("source", "ScanBot")
+		part, _ := writer.CreateFormFile("file", "receipt.txt")
+		part.Write([]byte("receipt text"))
+		writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/integrations/dropzone", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+
+		// The webhook receives it
+		handler.Echo.ServeHTTP(rec, req)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("Expected 202, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Invalid response: %v", err)
+		}
+		return response
+	}
+	first, second := push(), push()
+
+	// Both files land in the source's ingress folder without overwriting each other
+	dir := filepath.ToSlash(filepath.Join(handler.ServerConfig.IngressPath, "dropzone", "scanbot"))
+	if first["path"] != dir+"/receipt.txt" {
+		t.Errorf("Unexpected path %q", first["path"])
+	}
+	if second["path"] == first["path"] || !strings.HasPrefix(second["path"], dir+"/") {
+		t.Errorf("Second push should get its own file, got %q", second["path"])
+	}
+	if content, err := os.ReadFile(second["path"]); err != nil || string(content) != "receipt text" {
+		t.Errorf("Stored file has %q, %v", content, err)
+	}
+
+	// The job result records the source
+	job := waitForTestJob(t, handler.DB, first["jobId"])
+	var result dropzoneResult
+	if err := json.Unmarshal([]byte(job.Result), &result); err != nil {
+		t.Fatalf("Job result is not a dropzone result: %v (%s)", err, job.Result)
+	}
+	if result.Source != "scanbot" || result.Filename != "receipt.txt" {
+		t.Errorf("Unexpected job result %+v", result)
+	}
+	waitForTestJob(t, handler.DB, second["jobId"])
+}
+
+func TestDropzoneRefusesFilesOverLimit(t *testing.T) {
+	handler := setupDropzoneTestHandler(t)
+	handler.ServerConfig.DropzoneMaxMB = 1
+	oversized := bytes.Repeat([]byte("x"), 1<<20+1)
+	multipartBody := func() (*bytes.Buffer, string) {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("file", "scan.txt")
+		part.Write(oversized)
+		writer.Close()
+		return &body, writer.FormDataContentType()
+	}
+	tests := []struct {
+		name string
+		body func() (*bytes.Buffer, string)
+	}{
+		{"raw body", func() (*bytes.Buffer, string) { return bytes.NewBuffer(oversized), "application/octet-stream" }},
+		{"multipart", multipartBody},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := tt.body()
+			req := httptest.NewRequest(http.MethodPost, "/api/integrations/dropzone?filename=scan.txt", body)
+			req.Header.Set("Content-Type", contentType)
+			req.Header.Set("X-API-Key", "secret")
+			rec := httptest.NewRecorder()
+			handler.Echo.ServeHTTP(rec, req)
+			if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), string(dto.CodeTooLarge)) {
+				t.Errorf("Expected 413 %s, got %d %s", dto.CodeTooLarge, rec.Code, rec.Body.String())
+			}
+		})
+	}
+	if entries, _ := os.ReadDir(filepath.Join(handler.ServerConfig.IngressPath, "dropzone", "generic")); len(entries) != 0 {
+		t.Errorf("Expected nothing stored, got %d files", len(entries))
+	}
+}
+
+// waitForTestJob polls until a job has finished
+func waitForTestJob(t *testing.T, db database.Repository, id string) *database.Job {
+	t.Helper()
+	jobID, err := ulid.Parse(id)
+	if err != nil {
+		t.Fatalf("Invalid job ID %q: %v", id, err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := db.GetJob(jobID)
+		if err == nil && (job.Status == database.JobStatusCompleted || job.Status == database.JobStatusFailed) {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish", id)
+	return nil
+}
@@ -0,0 +1,487 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"math/bits"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/disintegration/imaging"
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/engine/pdfrenderer"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+	"github.com/oklog/ulid/v2"
+)
+
+// Exact hashes only catch byte-for-byte copies, so the same letter scanned twice is stored twice. Each
+// document is also fingerprinted with a difference hash of its first page, which survives rescanning,
+// resizing and recompression, and a simhash of its words, which survives OCR reading a few of them
+// differently. Documents whose fingerprints are close are reported for someone to review; nothing is
+// ever removed automatically.
+
+// The most bits two fingerprints may differ by for their documents to be reported as near-duplicates.
+// Documents that both have text must have close text, and close first pages too when both have one;
+// imageOnlyDistance is the stricter limit for images without text, such as photos.
+const (
+	textDistance      = 3
+	imageDistance     = 12
+	imageOnlyDistance = 6
+)
+
+// minFingerprintWords is the fewest words a document needs for its text to be fingerprinted. Short texts,
+// such as a scanned receipt's total, are too alike to tell documents apart.
+const minFingerprintWords = 20
+
+// duplicatesPageSize is the number of near-duplicate pairs reported when no limit is given
+const duplicatesPageSize = 50
+
+// differenceHash returns the 64-bit difference hash of an image: shrunk to 9x8 in grey, each bit records
+// whether a pixel is brighter than the one to its right. A page with no detail, such as a blank one, hashes
+// to 0.
+func differenceHash(img image.Image) uint64 {
+	small := imaging.Resize(imaging.Grayscale(img), 9, 8, imaging.Box)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.Pix[small.PixOffset(x, y)] > small.Pix[small.PixOffset(x+1, y)] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// textSimhash returns the 64-bit simhash of the words in text, each word weighted by how often it occurs,
+// and false when there are fewer than minFingerprintWords words
+func textSimhash(text string) (uint64, bool) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) < minFingerprintWords {
+		return 0, false
+	}
+	counts := make(map[string]int)
+	for _, word := range words {
+		counts[word]++
+	}
+	var weights [64]int
+	for word, count := range counts {
+		hasher := fnv.New64a()
+		hasher.Write([]byte(word))
+		wordHash := hasher.Sum64()
+		for bit := range weights {
+			if wordHash&(1<<bit) != 0 {
+				weights[bit] += count
+			} else {
+				weights[bit] -= count
+			}
+		}
+	}
+	var hash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash, true
+}
+
+// formatFingerprint and parseFingerprint convert a hash to and from the 16 hex digits it is stored as; an
+// empty string is no hash
+func formatFingerprint(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+func parseFingerprint(value string) (uint64, bool) {
+	hash, err := strconv.ParseUint(value, 16, 64)
+	return hash, err == nil && value != ""
+}
+
+// imageFingerprint returns the difference hash of an image, or "" when it has no detail to compare
+func imageFingerprint(img image.Image) string {
+	if hash := differenceHash(img); hash != 0 {
+		return formatFingerprint(hash)
+	}
+	return ""
+}
+
+// textFingerprint returns the simhash of a document's text, or "" when it has too few words
+func textFingerprint(text string) string {
+	if hash, ok := textSimhash(text); ok {
+		return formatFingerprint(hash)
+	}
+	return ""
+}
+
+// recordFingerprint fingerprints a newly ingested document by its text and, for an image, its picture.
+// PDF pages are not rendered here, as starting PDFium costs seconds; the duplicate scan adds them. Like the
+// activity feed, failing to record it is logged rather than failing the ingestion.
+func (serverHandler *ServerHandler) recordFingerprint(doc *database.Document) {
+	fingerprint := database.DocumentFingerprint{DocumentULID: doc.ULID.String(), TextHash: textFingerprint(doc.FullText)}
+	if slices.Contains(scanImages, strings.ToLower(filepath.Ext(doc.Path))) {
+		if img, err := imaging.Open(doc.Path); err == nil {
+			fingerprint.ImageHash = imageFingerprint(img)
+		} else {
+			Logger.Debug("Unable to read image to fingerprint", "path", doc.Path, "error", err)
+		}
+	}
+	if fingerprint.ImageHash == "" && fingerprint.TextHash == "" {
+		return
+	}
+	if err := serverHandler.DB.SaveDocumentFingerprint(&fingerprint); err != nil {
+		Logger.Warn("Unable to record document fingerprint", "ulid", fingerprint.DocumentULID, "error", err)
+	}
+}
+
+// fingerprintScanResult counts what a duplicate scan did with each document
+type fingerprintScanResult struct {
+	Fingerprinted int `json:"fingerprinted"` // fingerprinted for the first time, or a PDF's first page added
+	Skipped       int `json:"skipped"`       // already fingerprinted, or nothing to fingerprint
+	Unrendered    int `json:"unrendered"`    // first page could not be read, so compared by text alone
+}
+
+// firstPageRenderer renders the first page of PDFs for fingerprinting, starting PDFium only once it is
+// needed and not retrying when it cannot be started
+type firstPageRenderer struct {
+	serviceURL string
+	renderer   pdfrenderer.Renderer
+	err        error
+}
+
+func (r *firstPageRenderer) render(path string) (image.Image, error) {
+	if r.renderer == nil && r.err == nil {
+		r.renderer, r.err = pdfrenderer.NewRenderer(r.serviceURL)
+		if r.err != nil {
+			Logger.Warn("PDF renderer unavailable, comparing PDFs by text alone", "error", r.err)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	var first image.Image
+	if _, err := r.renderer.RenderPages(path, 1, func(_ int, page image.Image) error {
+		first = page
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if first == nil {
+		return nil, errors.New("PDF has no pages")
+	}
+	return first, nil
+}
+
+func (r *firstPageRenderer) close() {
+	if r.renderer != nil {
+		r.renderer.Close()
+	}
+}
+
+// fingerprintDocuments fingerprints every document not fingerprinted before, and renders the first page
+// of PDFs fingerprinted at ingestion by their text alone
+func (serverHandler *ServerHandler) fingerprintDocuments(db database.Repository, heartbeat *jobHeartbeat) (fingerprintScanResult, error) {
+	var result fingerprintScanResult
+	existing, err := db.ListDocumentFingerprints()
+	if err != nil {
+		return result, err
+	}
+	fingerprints := make(map[string]database.DocumentFingerprint, len(existing))
+	for _, fingerprint := range existing {
+		fingerprints[fingerprint.DocumentULID] = fingerprint
+	}
+	renderer := &firstPageRenderer{serviceURL: serverHandler.ServerConfig.PDFServiceURL}
+	defer renderer.close()
+
+	var cursor *database.DocumentCursor
+	for {
+		page, err := db.GetNewestDocumentsAfter(cursor, maxCursorPageSize)
+		if err != nil {
+			return result, err
+		}
+		for _, document := range page {
+			heartbeat.beat()
+			documentULID := document.ULID.String()
+			ext := strings.ToLower(filepath.Ext(document.Path))
+			fingerprint, found := fingerprints[documentULID]
+			renderable := ext == ".pdf" || slices.Contains(scanImages, ext)
+			if found && (fingerprint.ImageHash != "" || !renderable) {
+				result.Skipped++
+				continue
+			}
+			if !found {
+				// Document lists leave out the text, so it is read on its own
+				text, err := db.GetDocumentText(documentULID)
+				if err != nil {
+					return result, err
+				}
+				fingerprint = database.DocumentFingerprint{DocumentULID: documentULID, TextHash: textFingerprint(text)}
+			}
+			if renderable {
+				var img image.Image
+				if ext == ".pdf" {
+					img, err = renderer.render(document.Path)
+				} else {
+					img, err = imaging.Open(document.Path)
+				}
+				if err == nil {
+					fingerprint.ImageHash = imageFingerprint(img)
+				} else {
+					Logger.Debug("Unable to read first page to fingerprint", "ulid", documentULID, "path", document.Path, "error", err)
+					result.Unrendered++
+				}
+			}
+			if fingerprint.ImageHash == "" && (found || fingerprint.TextHash == "") {
+				result.Skipped++
+				continue
+			}
+			if err := db.SaveDocumentFingerprint(&fingerprint); err != nil {
+				return result, err
+			}
+			result.Fingerprinted++
+		}
+		if len(page) < maxCursorPageSize {
+			return result, nil
+		}
+		cursor = database.CursorAfter(page[len(page)-1])
+	}
+}
+
+// ScanDuplicates starts a job that fingerprints documents for the duplicates report
+// @Summary Scan for near-duplicates
+// @Description Fingerprint, in the background, documents stored before near-duplicate detection and the first page of PDFs, which ingestion leaves to this scan as rendering them is slow. Run it before reading the duplicates report.
+// @Tags Documents
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Job created with jobId"
+// @Failure 409 {object} map[string]interface{} "A duplicate scan is already active; jobId is that job"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /duplicates/scan [post]
+func (serverHandler *ServerHandler) ScanDuplicates(c echo.Context) error {
+	job, err := serverHandler.startJob(database.JobTypeFingerprint, "Starting duplicate scan")
+	if errors.Is(err, errJobActive) {
+		return jobAlreadyActive(c, job)
+	}
+	if err != nil {
+		Logger.Error("Failed to create duplicate scan job", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to create duplicate scan job",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	go serverHandler.fingerprintJob(serverHandler.DB, job.ID)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Duplicate scan started",
+		"jobId":   job.ID.String(),
+	})
+}
+
+// fingerprintJob runs fingerprintDocuments with job tracking
+func (serverHandler *ServerHandler) fingerprintJob(db database.Repository, jobID ulid.ULID) {
+	db.UpdateJobStatus(jobID, database.JobStatusRunning, "Fingerprinting documents")
+
+	result, err := serverHandler.fingerprintDocuments(db, newJobHeartbeat(db, jobID))
+	if err != nil {
+		Logger.Error("Duplicate scan failed", "fingerprinted", result.Fingerprinted, "error", err)
+		db.UpdateJobError(jobID, fmt.Sprintf("Duplicate scan failed after %d documents: %v", result.Fingerprinted, err))
+		return
+	}
+	summary, _ := json.Marshal(result)
+	if err := db.CompleteJob(jobID, string(summary)); err != nil {
+		Logger.Error("Failed to mark duplicate scan job as complete", "error", err)
+	}
+	Logger.Info("Duplicate scan completed", "fingerprinted", result.Fingerprinted, "skipped", result.Skipped, "unrendered", result.Unrendered)
+}
+
+// parsedFingerprint is a stored fingerprint with its hashes parsed for comparing
+type parsedFingerprint struct {
+	id                string
+	image, text       uint64
+	hasImage, hasText bool
+}
+
+// duplicatePair is two fingerprints close enough to report, with the bits their hashes differ by; a
+// distance is nil when either document lacks that hash
+type duplicatePair struct {
+	first, second int
+	imageDistance *int
+	textDistance  *int
+}
+
+// closeness orders pairs, the most alike first
+func (pair duplicatePair) closeness() int {
+	closeness := 0
+	if pair.imageDistance != nil {
+		closeness += *pair.imageDistance
+	}
+	if pair.textDistance != nil {
+		closeness += *pair.textDistance
+	}
+	return closeness
+}
+
+// compareFingerprints returns the pair of a and b when they are near-duplicates
+func compareFingerprints(a, b parsedFingerprint) (imageBits, textBits *int, ok bool) {
+	if a.hasImage && b.hasImage {
+		distance := bits.OnesCount64(a.image ^ b.image)
+		imageBits = &distance
+	}
+	if a.hasText && b.hasText {
+		distance := bits.OnesCount64(a.text ^ b.text)
+		textBits = &distance
+		return imageBits, textBits, *textBits <= textDistance && (imageBits == nil || *imageBits <= imageDistance)
+	}
+	return imageBits, nil, imageBits != nil && *imageBits <= imageOnlyDistance
+}
+
+// nearDuplicates returns the pairs of fingerprints that are near-duplicates, most alike first. Only
+// fingerprints sharing a band of bits are compared: hashes differing by no more than the limits above
+// must match exactly in at least one of 4 bands of their text hash, or of 8 bands of their image hash.
+func nearDuplicates(fingerprints []parsedFingerprint) []duplicatePair {
+	type band struct {
+		kind  byte
+		index int
+		value uint64
+	}
+	buckets := make(map[band][]int)
+	for i, fingerprint := range fingerprints {
+		if fingerprint.hasText {
+			for index := 0; index < 4; index++ {
+				key := band{'t', index, fingerprint.text >> (16 * index) & 0xffff}
+				buckets[key] = append(buckets[key], i)
+			}
+		}
+		if fingerprint.hasImage {
+			for index := 0; index < 8; index++ {
+				key := band{'i', index, fingerprint.image >> (8 * index) & 0xff}
+				buckets[key] = append(buckets[key], i)
+			}
+		}
+	}
+
+	compared := make(map[[2]int]bool)
+	var pairs []duplicatePair
+	for _, bucket := range buckets {
+		for i, first := range bucket {
+			for _, second := range bucket[i+1:] {
+				if compared[[2]int{first, second}] {
+					continue
+				}
+				compared[[2]int{first, second}] = true
+				if imageBits, textBits, ok := compareFingerprints(fingerprints[first], fingerprints[second]); ok {
+					pairs = append(pairs, duplicatePair{first: first, second: second, imageDistance: imageBits, textDistance: textBits})
+				}
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].closeness() != pairs[j].closeness() {
+			return pairs[i].closeness() < pairs[j].closeness()
+		}
+		if pairs[i].first != pairs[j].first {
+			return pairs[i].first < pairs[j].first
+		}
+		return pairs[i].second < pairs[j].second
+	})
+	return pairs
+}
+
+// duplicateDocument is one side of a pair in the duplicates report
+type duplicateDocument struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Folder string `json:"folder"`
+	URL    string `json:"url"`
+}
+
+// duplicateEntry is a pair of near-duplicates in the duplicates report
+type duplicateEntry struct {
+	Documents     [2]duplicateDocument `json:"documents"`
+	ImageDistance *int                 `json:"imageDistance,omitempty"` // bits the first pages' hashes differ by, out of 64
+	TextDistance  *int                 `json:"textDistance,omitempty"`  // bits the texts' hashes differ by, out of 64
+}
+
+// GetDuplicates reports documents that look like copies of each other
+// @Summary Near-duplicate documents
+// @Description List pairs of documents whose first page or text is almost the same, such as the same letter scanned twice, most alike first. Pairs are for review: nothing is removed. Documents are compared by the fingerprints recorded at ingestion and by the duplicate scan, which adds the first page of PDFs.
+// @Tags Documents
+// @Produce json
+// @Param limit query int false "Maximum pairs to return (1-1000, default 50)"
+// @Success 200 {object} map[string]interface{} "pairs, each with both documents and the distances between their hashes, and the number of documents fingerprinted"
+// @Failure 400 {object} map[string]interface{} "Invalid limit"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /duplicates [get]
+func (serverHandler *ServerHandler) GetDuplicates(c echo.Context) error {
+	limit, err := limitParam(c, duplicatesPageSize)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	stored, err := serverHandler.DB.ListDocumentFingerprints()
+	if err != nil {
+		Logger.Error("Failed to list document fingerprints", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list document fingerprints",
+			"code":  dto.CodeInternal,
+		})
+	}
+	fingerprints := make([]parsedFingerprint, 0, len(stored))
+	for _, fingerprint := range stored {
+		parsed := parsedFingerprint{id: fingerprint.DocumentULID}
+		parsed.image, parsed.hasImage = parseFingerprint(fingerprint.ImageHash)
+		parsed.text, parsed.hasText = parseFingerprint(fingerprint.TextHash)
+		fingerprints = append(fingerprints, parsed)
+	}
+
+	// Fingerprints outlive deleted documents, so pairs with a document that is gone are passed over
+	documents := make(map[int]*duplicateDocument)
+	document := func(index int) *duplicateDocument {
+		if summary, looked := documents[index]; looked {
+			return summary
+		}
+		var summary *duplicateDocument
+		if doc, err := serverHandler.DB.GetDocumentByULID(fingerprints[index].id); err == nil && doc != nil {
+			summary = &duplicateDocument{
+				ID:     doc.ULID.String(),
+				Name:   doc.Name,
+				Folder: serverHandler.relativeFolder(doc.Folder),
+				URL:    doc.URL,
+			}
+		}
+		documents[index] = summary
+		return summary
+	}
+	entries := []duplicateEntry{}
+	for _, pair := range nearDuplicates(fingerprints) {
+		if len(entries) == limit {
+			break
+		}
+		first, second := document(pair.first), document(pair.second)
+		if first == nil || second == nil {
+			continue
+		}
+		entries = append(entries, duplicateEntry{
+			Documents:     [2]duplicateDocument{*first, *second},
+			ImageDistance: pair.imageDistance,
+			TextDistance:  pair.textDistance,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"pairs":         entries,
+		"fingerprinted": len(fingerprints),
+	})
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"math/bits"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/drummonds/godocs/database"
+)
+
+// letterText is the text of a letter long enough to fingerprint
+const letterText = `Dear Mr Smith, thank you for your payment of the annual service charge for flat 4, received on
+the 3rd of March. Your account is now up to date and the next invoice will be sent in September. Please
+contact the managing agent if you have any questions about the works planned for the roof this summer.`
+
+// drawLetter returns a page with a letterhead and lines of print, its ink shifted down by offset pixels
+func drawLetter(width, height, offset int, lines ...int) *image.NRGBA {
+	page := imaging.New(width, height, color.White)
+	draw.Draw(page, image.Rect(width/10, height/20+offset, width/2, height/8+offset), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	for _, line := range lines {
+		y := height*line/20 + offset
+		draw.Draw(page, image.Rect(width/10, y, width*9/10-line*width/60, y+height/80), image.NewUniform(color.Gray{Y: 40}), image.Point{}, draw.Src)
+	}
+	return page
+}
+
+// drawPhoto returns a picture that darkens from left to right, nothing like a page of print
+func drawPhoto(width, height int) *image.NRGBA {
+	photo := imaging.New(width, height, color.White)
+	for x := 0; x < width; x++ {
+		draw.Draw(photo, image.Rect(x, 0, x+1, height), image.NewUniform(color.Gray{Y: uint8(255 - 255*x/width)}), image.Point{}, draw.Src)
+	}
+	return photo
+}
+
+func TestFingerprints(t *testing.T) {
+	// A page, a rescan of it at another size and slightly lower, and a photo
+	page := drawLetter(850, 1100, 0, 4, 6, 8, 10, 12)
+	rescan := drawLetter(1275, 1650, 6, 4, 6, 8, 10, 12)
+	other := drawPhoto(850, 1100)
+
+	// The rescan's difference hash is close and the photo's is not
+	if distance := bits.OnesCount64(differenceHash(page) ^ differenceHash(rescan)); distance > imageOnlyDistance {
+		t.Errorf("Expected the rescan within %d bits, got %d", imageOnlyDistance, distance)
+	}
+	if distance := bits.OnesCount64(differenceHash(page) ^ differenceHash(other)); distance <= imageDistance {
+		t.Errorf("Expected the photo more than %d bits away, got %d", imageDistance, distance)
+	}
+	if differenceHash(imaging.New(100, 100, color.White)) != 0 {
+		t.Error("Expected a blank page to hash to 0")
+	}
+
+	// Text with a word misread by OCR has a close simhash, and other text a distant one
+	letter, _ := textSimhash(letterText)
+	misread, _ := textSimhash(strings.Replace(letterText, "September", "Septernber", 1))
+	reply, _ := textSimhash(`Dear Ms Jones, we have received your complaint about the noise from the building works next door
+and have passed it to the council. We expect a reply within twenty working days and will write again then.`)
+	if distance := bits.OnesCount64(letter ^ misread); distance > textDistance {
+		t.Errorf("Expected the misread letter within %d bits, got %d", textDistance, distance)
+	}
+	if distance := bits.OnesCount64(letter ^ reply); distance <= textDistance {
+		t.Errorf("Expected a different letter more than %d bits away, got %d", textDistance, distance)
+	}
+	if _, ok := textSimhash("Total due £12.40"); ok {
+		t.Error("Expected too few words not to be fingerprinted")
+	}
+}
+
+func TestDuplicatesReport(t *testing.T) {
+	// A letter scanned twice at different sizes, with a word read differently each time, and
+	// a picture whose text is almost the same, so alike text alone is not enough
+	handler := setupTestHandler(t)
+	handler.Echo.GET("/api/duplicates", handler.GetDuplicates)
+	scans := []struct {
+		name string
+		page image.Image
+		text string
+	}{
+		{"letter.png", drawLetter(850, 1100, 0, 4, 6, 8, 10, 12), letterText},
+		{"letter again.jpg", drawLetter(1275, 1650, 6, 4, 6, 8, 10, 12), strings.Replace(letterText, "roof", "rooi", 1)},
+		{"envelope.png", drawPhoto(850, 1100), strings.Replace(letterText, "Smith", "Jones", 1)},
+	}
+	var docs []*database.Document
+	for _, scan := range scans {
+		path := filepath.Join(handler.ServerConfig.DocumentPath, scan.name)
+		if err := imaging.Save(scan.page, path); err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, saveTestDocument(t, handler.DB, path, scan.text))
+	}
+
+	// The duplicate scan runs and the report is read
+	job, err := handler.DB.CreateJob(database.JobTypeFingerprint, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.fingerprintJob(handler.DB, job.ID)
+	finished, err := handler.DB.GetJob(job.ID)
+	if err != nil || finished.Status != database.JobStatusCompleted || !strings.Contains(finished.Result, `"fingerprinted":3`) {
+		t.Fatalf("Expected all three fingerprinted, got %+v: %v", finished, err)
+	}
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/duplicates", nil))
+	var report struct {
+		Pairs         []duplicateEntry `json:"pairs"`
+		Fingerprinted int              `json:"fingerprinted"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with a report, got %d %s", rec.Code, rec.Body)
+	}
+
+	// Only the two scans of the same letter are paired, with both distances, and both are kept
+	if report.Fingerprinted != 3 || len(report.Pairs) != 1 {
+		t.Fatalf("Expected one pair of three fingerprinted, got %+v", report)
+	}
+	pair := report.Pairs[0]
+	ids := []string{pair.Documents[0].ID, pair.Documents[1].ID}
+	if !(ids[0] == docs[0].ULID.String() && ids[1] == docs[1].ULID.String()) && !(ids[0] == docs[1].ULID.String() && ids[1] == docs[0].ULID.String()) {
+		t.Errorf("Expected the two scans paired, got %v", ids)
+	}
+	if pair.ImageDistance == nil || pair.TextDistance == nil {
+		t.Errorf("Expected image and text distances, got %+v", pair)
+	}
+	for _, doc := range docs[:2] {
+		if _, err := handler.DB.GetDocumentByULID(doc.ULID.String()); err != nil {
+			t.Errorf("Expected %s kept: %v", doc.Name, err)
+		}
+	}
+
+	// A pair whose document has been deleted is no longer reported
+	if err := handler.DB.DeleteDocument(docs[1].ULID.String()); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/duplicates", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || len(report.Pairs) != 0 {
+		t.Errorf("Expected no pairs once a copy is deleted, got %s", rec.Body)
+	}
+}
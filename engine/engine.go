@@ -2,6 +2,8 @@ package engine
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"image"
@@ -11,13 +13,18 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/disintegration/imaging"
 	"github.com/drummonds/godocs/config"
 	"github.com/drummonds/godocs/database"
 	"github.com/drummonds/godocs/engine/pdfrenderer"
+	"github.com/drummonds/godocs/storage"
 	"github.com/ledongthuc/pdf"
 	"github.com/oklog/ulid/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func (serverHandler *ServerHandler) ingressJobFunc(serverConfig config.ServerConfig, db database.Repository) {
@@ -41,6 +48,7 @@ func (serverHandler *ServerHandler) ingressJobFunc(serverConfig config.ServerCon
 	if err != nil {
 		Logger.Error("Error reading files in from ingress", "error", err)
 	}
+	var rejected []string
 	for _, filePath := range ingressPath {
 		Logger.Debug("Starting processing for file", "filePath", filePath)
 		fileStats, err := os.Stat(filePath)
@@ -56,8 +64,21 @@ func (serverHandler *ServerHandler) ingressJobFunc(serverConfig config.ServerCon
 			Logger.Info("Skipping ingress Folder", "filePath", filePath)
 			continue
 		}
+		if err := serverHandler.checkIngestible(filePath); err != nil {
+			serverHandler.rejectIngressFile(filePath, err)
+			rejected = append(rejected, filePath)
+			continue
+		}
+		filePath, err := serverHandler.preIngest(filePath)
+		if err != nil {
+			Logger.Error("Not ingesting file", "error", err)
+			continue
+		}
+		release, _ := serverHandler.processingSlot(context.Background(), priorityBulk)
 		serverHandler.ingressDocument(filePath, "ingress")
+		release()
 	}
+	serverHandler.pruneIngestRejections(rejected)
 	deleteEmptyIngressFolders(serverHandler.ServerConfig.IngressPath) //after ingress clean empty folders
 }
 
@@ -86,11 +107,18 @@ func (serverHandler *ServerHandler) ingressJobFuncWithTracking(serverConfig conf
 	Logger.Info("Starting Ingress Job with tracking", "path", serverConfig.IngressPath, "jobID", jobID)
 
 	// Scan for files
-	var ingressFiles []string
+	var ingressFiles, rejected []string
 	err = filepath.Walk(serverConfig.IngressPath, func(path string, info os.FileInfo, err error) error {
-		if err == nil && !info.IsDir() && path != serverConfig.IngressPath {
-			ingressFiles = append(ingressFiles, path)
+		if err != nil || info.IsDir() || path == serverConfig.IngressPath {
+			return nil
 		}
+		// Unsupported files stay in ingress rather than failing every run
+		if err := serverHandler.checkIngestible(path); err != nil {
+			serverHandler.rejectIngressFile(path, err)
+			rejected = append(rejected, path)
+			return nil
+		}
+		ingressFiles = append(ingressFiles, path)
 		return nil
 	})
 
@@ -99,6 +127,7 @@ func (serverHandler *ServerHandler) ingressJobFuncWithTracking(serverConfig conf
 		db.UpdateJobError(jobID, fmt.Sprintf("Scan failed: %v", err))
 		return
 	}
+	serverHandler.pruneIngestRejections(rejected)
 
 	totalFiles := len(ingressFiles)
 	if totalFiles == 0 {
@@ -112,16 +141,56 @@ func (serverHandler *ServerHandler) ingressJobFuncWithTracking(serverConfig conf
 	errorCount := 0
 	duplicateCount := 0
 
+	// Write documents in batches when configured; a batch also carries the word counts
+	batch := newIngestBatch(db, serverHandler.ServerConfig.IngestBatchSize)
+	// Files that were skipped, failed or stored without their text, each with its error code
+	var items jobItems
+	if batch != nil {
+		batch.items = &items
+		batch.saved = func(doc *database.Document) { serverHandler.documentIngested(doc, "ingress", &items) }
+	}
+	// Without batching, word counts are collected here and written once in the final phase
+	var wordCounts wordCounter
+
 	// Process each file with detailed step tracking
+	heartbeat := newJobHeartbeat(db, jobID)
 	for i, filePath := range ingressFiles {
+		heartbeat.beat()
 		fileName := filepath.Base(filePath)
 
 		Logger.Info("Processing file with step-based ingestion", "file", fileName, "number", i+1, "total", totalFiles)
 
-		// Process the document using new step-based approach
-		err := serverHandler.IngestDocumentWithSteps(filePath, db, jobID, i, totalFiles)
+		// A file the transform fails on has been quarantined and is listed with the job's failures
+		transformedPath, err := serverHandler.preIngest(filePath)
+		if err != nil {
+			items.add(filePath, err)
+			errorCount++
+			continue
+		}
+		filePath = transformedPath
+
+		// Process the document using new step-based approach, one slot at a time so uploads can interleave
+		release, _ := serverHandler.processingSlot(context.Background(), priorityBulk)
+		var doc *database.Document
+		if batch != nil {
+			var failed int
+			failed, err = serverHandler.ingestDocumentBatched(filePath, db, jobID, i, totalFiles, batch, &items)
+			errorCount += failed
+			processedFiles -= failed
+		} else {
+			doc, err = serverHandler.ingestDocumentWithSteps(filePath, db, jobID, i, totalFiles, &items)
+			if doc != nil {
+				wordCounts.add(doc)
+			}
+		}
+		release()
+		// Hooks run outside the processing slot so a slow one does not hold up uploads
+		if doc != nil && err == nil {
+			serverHandler.documentIngested(doc, "ingress", &items)
+		}
+		items.add(filePath, err)
 		if err != nil {
-			if len(err.Error()) >= 9 && err.Error()[:9] == "duplicate" {
+			if errors.Is(err, errDuplicateDocument) {
 				Logger.Info("Skipped duplicate document", "filePath", filePath)
 				duplicateCount++
 				processedFiles++ // Count as processed (successfully skipped)
@@ -134,18 +203,37 @@ func (serverHandler *ServerHandler) ingressJobFuncWithTracking(serverConfig conf
 		}
 	}
 
+	if batch != nil {
+		db.UpdateJobProgress(jobID, 92, "Writing documents to database")
+		failed := batch.flush()
+		errorCount += failed
+		processedFiles -= failed
+	}
+
 	// Clean up empty folders
 	deleteEmptyIngressFolders(serverConfig.IngressPath)
 
-	// Recalculate word cloud after ingestion
-	db.UpdateJobProgress(jobID, 95, "Updating word cloud")
-	Logger.Info("Recalculating word cloud after ingestion")
-	if err := db.RecalculateAllWordFrequencies(); err != nil {
-		Logger.Error("Word cloud recalculation failed after ingestion", "error", err)
+	// Record any new destination folders in the folder table
+	if _, err := syncFolders(db, serverConfig.DocumentPath, false); err != nil {
+		Logger.Error("Folder table update failed after ingestion", "error", err)
+	}
+
+	// Batches already added their word counts, otherwise write the counts gathered during the job
+	if batch == nil {
+		db.UpdateJobProgress(jobID, 95, "Updating word cloud")
+		if err := wordCounts.flush(db); err != nil {
+			Logger.Error("Word cloud update failed after ingestion", "error", err)
+		}
 	}
+	serverHandler.invalidateDocumentCache()
 
 	// Complete the job
-	result := fmt.Sprintf(`{"filesProcessed": %d, "filesTotal": %d, "errors": %d, "duplicates": %d}`, processedFiles, totalFiles, errorCount, duplicateCount)
+	itemsJSON, err := json.Marshal(items.list())
+	if err != nil {
+		Logger.Error("Failed to encode ingestion job items", "error", err)
+		itemsJSON = []byte("[]")
+	}
+	result := fmt.Sprintf(`{"filesProcessed": %d, "filesTotal": %d, "errors": %d, "duplicates": %d, "items": %s}`, processedFiles, totalFiles, errorCount, duplicateCount, itemsJSON)
 	if err := db.CompleteJob(jobID, result); err != nil {
 		Logger.Error("Failed to mark job as complete", "error", err)
 	}
@@ -153,8 +241,55 @@ func (serverHandler *ServerHandler) ingressJobFuncWithTracking(serverConfig conf
 	Logger.Info("Ingestion job completed", "jobID", jobID, "processed", processedFiles, "total", totalFiles, "errors", errorCount, "duplicates", duplicateCount)
 }
 
-// cleanupJobFuncWithTracking performs database cleanup with job tracking
-func (serverHandler *ServerHandler) cleanupJobFuncWithTracking(db database.Repository, jobID ulid.ULID) {
+// OrphanPolicy decides what cleanup does with files in document storage that have no database entry
+type OrphanPolicy string
+
+const (
+	// OrphanPolicyIngress moves orphans back to the ingress folder so they are fully reprocessed
+	OrphanPolicyIngress OrphanPolicy = "ingress"
+	// OrphanPolicyRelink creates a database entry for the orphan where it already lives
+	OrphanPolicyRelink OrphanPolicy = "relink"
+	// OrphanPolicyReport only lists orphans in the job result
+	OrphanPolicyReport OrphanPolicy = "report"
+)
+
+// ParseOrphanPolicy validates an orphan policy name, defaulting to OrphanPolicyIngress when empty
+func ParseOrphanPolicy(value string) (OrphanPolicy, error) {
+	switch OrphanPolicy(strings.ToLower(value)) {
+	case "", OrphanPolicyIngress:
+		return OrphanPolicyIngress, nil
+	case OrphanPolicyRelink:
+		return OrphanPolicyRelink, nil
+	case OrphanPolicyReport:
+		return OrphanPolicyReport, nil
+	}
+	return "", fmt.Errorf("unknown orphan policy %q (expected ingress, relink or report)", value)
+}
+
+// cleanupReport records what a cleanup run changed, or would change when run as a dry run
+type cleanupReport struct {
+	DryRun        bool                `json:"dryRun"`
+	OrphanPolicy  OrphanPolicy        `json:"orphanPolicy"`
+	Scanned       int                 `json:"scanned"`
+	Deleted       int                 `json:"deleted"`
+	Moved         int                 `json:"moved"`
+	Relinked      int                 `json:"relinked"`
+	Held          int                 `json:"held"` // missing or orphaned files left alone because of a legal hold
+	MissingFiles  []cleanupMissingDoc `json:"missingFiles"`
+	OrphanedFiles []string            `json:"orphanedFiles"`
+}
+
+// cleanupMissingDoc is a database entry whose file no longer exists on disk
+type cleanupMissingDoc struct {
+	ULID string `json:"ulid"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// cleanupJobFuncWithTracking performs database cleanup with job tracking.
+// When dryRun is set nothing is deleted or moved; the job result lists what would have been.
+// orphanPolicy selects how files without a database entry are handled.
+func (serverHandler *ServerHandler) cleanupJobFuncWithTracking(db database.Repository, jobID ulid.ULID, dryRun bool, orphanPolicy OrphanPolicy) {
 	defer func() {
 		if r := recover(); r != nil {
 			Logger.Error("Panic recovered in cleanup job", "panic", r, "jobID", jobID)
@@ -165,6 +300,13 @@ func (serverHandler *ServerHandler) cleanupJobFuncWithTracking(db database.Repos
 	// Mark job as running
 	db.UpdateJobStatus(jobID, database.JobStatusRunning, "Fetching documents from database")
 
+	report := cleanupReport{
+		DryRun:        dryRun,
+		OrphanPolicy:  orphanPolicy,
+		MissingFiles:  []cleanupMissingDoc{},
+		OrphanedFiles: []string{},
+	}
+
 	// Get all documents from database
 	documentsPtr, err := database.FetchAllDocuments(db)
 	if err != nil {
@@ -174,16 +316,27 @@ func (serverHandler *ServerHandler) cleanupJobFuncWithTracking(db database.Repos
 	}
 
 	if documentsPtr == nil {
-		result := `{"scanned": 0, "deleted": 0, "moved": 0}`
-		db.CompleteJob(jobID, result)
+		completeCleanupJob(db, jobID, report)
 		return
 	}
 
 	documents := *documentsPtr
 	totalDocs := len(documents)
-	deletedCount := 0
+	archived, err := serverHandler.archivedDocuments()
+	if err != nil {
+		Logger.Error("Failed to list archived documents for cleanup", "error", err)
+		db.UpdateJobError(jobID, fmt.Sprintf("Failed to list archived documents: %v", err))
+		return
+	}
+	holds, err := serverHandler.legalHolds()
+	if err != nil {
+		Logger.Error("Failed to list legal holds for cleanup", "error", err)
+		db.UpdateJobError(jobID, fmt.Sprintf("Failed to list legal holds: %v", err))
+		return
+	}
+	report.Scanned = totalDocs
 
-	Logger.Info("Starting database cleanup", "total_documents", totalDocs)
+	Logger.Info("Starting database cleanup", "total_documents", totalDocs, "dryRun", dryRun)
 	db.UpdateJobProgress(jobID, 10, fmt.Sprintf("Checking %d documents", totalDocs))
 
 	// Step 1: Check each document's file existence and remove orphaned DB entries
@@ -192,6 +345,9 @@ func (serverHandler *ServerHandler) cleanupJobFuncWithTracking(db database.Repos
 			Logger.Warn("Document has empty path, skipping", "id", doc.StormID, "name", doc.Name)
 			continue
 		}
+		if archived[doc.ULID.String()] {
+			continue // its file is in cold storage
+		}
 
 		// Update progress
 		progress := 10 + int((float64(i)/float64(totalDocs))*50)
@@ -199,6 +355,21 @@ func (serverHandler *ServerHandler) cleanupJobFuncWithTracking(db database.Repos
 
 		// Check if file exists
 		if _, err := os.Stat(doc.Path); os.IsNotExist(err) {
+			report.MissingFiles = append(report.MissingFiles, cleanupMissingDoc{
+				ULID: doc.ULID.String(),
+				Name: doc.Name,
+				Path: doc.Path,
+			})
+			if holds.document(&doc) != nil {
+				Logger.Info("File not found, keeping record under legal hold", "path", doc.Path, "id", doc.StormID)
+				report.Held++
+				continue
+			}
+			if dryRun {
+				Logger.Info("File not found, would remove from database", "path", doc.Path, "id", doc.StormID)
+				continue
+			}
+
 			Logger.Info("File not found, removing from database", "path", doc.Path, "id", doc.StormID)
 
 			// Delete from database
@@ -206,89 +377,148 @@ func (serverHandler *ServerHandler) cleanupJobFuncWithTracking(db database.Repos
 				Logger.Error("Failed to delete document from DB", "error", err, "id", doc.StormID)
 				continue
 			}
-			deletedCount++
+			report.Deleted++
 		}
 	}
 
-	// Step 2: Find orphaned files in document storage and move them to ingress
+	// Step 2: Find orphaned files in document storage and handle them according to the policy
 	db.UpdateJobProgress(jobID, 60, "Scanning for orphaned files")
-	movedCount := 0
 	orphanedFiles, err := serverHandler.findOrphanedDocuments(documents)
 	if err != nil {
 		Logger.Error("Failed to scan for orphaned documents", "error", err)
 		// Continue with cleanup even if orphan scan fails
 	} else {
+		report.OrphanedFiles = append(report.OrphanedFiles, orphanedFiles...)
 		totalOrphans := len(orphanedFiles)
 		for i, orphanPath := range orphanedFiles {
-			progress := 60 + int((float64(i)/float64(totalOrphans))*20)
-			db.UpdateJobProgress(jobID, progress, fmt.Sprintf("Moving orphan %d/%d", i+1, totalOrphans))
+			if orphanPolicy == OrphanPolicyIngress && holds.file(orphanPath) != nil {
+				Logger.Info("Orphaned document is under legal hold, leaving in place", "path", orphanPath)
+				report.Held++
+				continue
+			}
+			if dryRun || orphanPolicy == OrphanPolicyReport {
+				Logger.Info("Orphaned document found, leaving in place", "path", orphanPath, "policy", orphanPolicy, "dryRun", dryRun)
+				continue
+			}
 
-			if err := serverHandler.moveOrphanToIngress(orphanPath); err != nil {
-				Logger.Error("Failed to move orphaned document to ingress", "path", orphanPath, "error", err)
-			} else {
-				movedCount++
+			progress := 60 + int((float64(i)/float64(totalOrphans))*20)
+			switch orphanPolicy {
+			case OrphanPolicyRelink:
+				db.UpdateJobProgress(jobID, progress, fmt.Sprintf("Relinking orphan %d/%d", i+1, totalOrphans))
+				if err := serverHandler.relinkOrphan(orphanPath, db); err != nil {
+					Logger.Error("Failed to relink orphaned document", "path", orphanPath, "error", err)
+				} else {
+					report.Relinked++
+				}
+			default:
+				db.UpdateJobProgress(jobID, progress, fmt.Sprintf("Moving orphan %d/%d", i+1, totalOrphans))
+				if err := serverHandler.moveOrphanToIngress(orphanPath); err != nil {
+					Logger.Error("Failed to move orphaned document to ingress", "path", orphanPath, "error", err)
+				} else {
+					report.Moved++
+				}
 			}
 		}
 	}
 
-	// Step 3: Recalculate word cloud
-	db.UpdateJobProgress(jobID, 80, "Recalculating word cloud")
-	Logger.Info("Recalculating word cloud after database cleanup")
-	if err := db.RecalculateAllWordFrequencies(); err != nil {
-		Logger.Error("Word cloud recalculation failed after cleanup", "error", err)
+	// Step 3: Recalculate word cloud (nothing changed in a dry run)
+	if !dryRun {
+		db.UpdateJobProgress(jobID, 80, "Recalculating word cloud")
+		Logger.Info("Recalculating word cloud after database cleanup")
+		if err := db.RecalculateAllWordFrequencies(); err != nil {
+			Logger.Error("Word cloud recalculation failed after cleanup", "error", err)
+		}
+		serverHandler.invalidateDocumentCache()
 	}
 
-	// Complete the job
-	result := fmt.Sprintf(`{"scanned": %d, "deleted": %d, "moved": %d}`, totalDocs, deletedCount, movedCount)
-	if err := db.CompleteJob(jobID, result); err != nil {
+	completeCleanupJob(db, jobID, report)
+
+	Logger.Info("Database cleanup job completed", "jobID", jobID, "dryRun", dryRun, "orphanPolicy", orphanPolicy, "scanned", report.Scanned,
+		"deleted", report.Deleted, "moved", report.Moved, "relinked", report.Relinked, "held", report.Held, "missing", len(report.MissingFiles), "orphans", len(report.OrphanedFiles))
+}
+
+// completeCleanupJob stores the cleanup report as the job result
+func completeCleanupJob(db database.Repository, jobID ulid.ULID, report cleanupReport) {
+	result, err := json.Marshal(report)
+	if err != nil {
+		Logger.Error("Failed to encode cleanup report", "error", err)
+		db.UpdateJobError(jobID, fmt.Sprintf("Failed to encode cleanup report: %v", err))
+		return
+	}
+	if err := db.CompleteJob(jobID, string(result)); err != nil {
 		Logger.Error("Failed to mark cleanup job as complete", "error", err)
 	}
-
-	Logger.Info("Database cleanup job completed", "jobID", jobID, "scanned", totalDocs, "deleted", deletedCount, "moved", movedCount)
 }
 
-// ingressDocumentWithError is like ingressDocument but returns errors instead of just logging
-func (serverHandler *ServerHandler) ingressDocumentWithError(filePath string, source string) error {
+// ingressDocumentWithError is like ingressDocument but returns errors instead of just logging, and the
+// stored document. The file may be corrected before it is stored, so callers that need the document find
+// it by what this returns rather than by a hash of the file they passed in. Text and word files are not
+// stored, and give no document.
+func (serverHandler *ServerHandler) ingressDocumentWithError(filePath string, source string) (*database.Document, error) {
 	defer func() {
 		if r := recover(); r != nil {
 			Logger.Error("Panic recovered while processing document", "filePath", filePath, "panic", r)
 		}
 	}()
 
+	if err := serverHandler.checkProcessable(filePath); err != nil {
+		return nil, err
+	}
+	timeline := startTimeline(source)
+	serverHandler.prepareScan(filePath, timeline)
 	switch filepath.Ext(filePath) {
 	case ".pdf":
+		textLayer := timeline.step("read text layer")
 		fullText, err := pdfProcessing(filePath)
+		textLayer.End()
+		ocrStatus := database.OCRSkipped
 		if err != nil {
-			fullText, err = serverHandler.convertToImage(filePath)
+			timeline.mark(stageTextExtracted, "no text layer")
+			fullText, err = serverHandler.convertToImage(timeline.context(), filePath)
 			if err != nil {
-				return fmt.Errorf("OCR processing failed: %w", err)
+				return nil, fmt.Errorf("%w: %w", errOCRFailed, err)
 			}
+			timeline.mark(stageOCR, "")
+			ocrStatus = database.OCRDone
+		} else {
+			timeline.mark(stageTextExtracted, "PDF text layer")
 		}
 		if fullText == nil {
-			return fmt.Errorf("PDF processing returned nil text")
+			return nil, fmt.Errorf("%w: PDF processing returned nil text", errOCRFailed)
 		}
-		return serverHandler.addDocumentToDatabase(filePath, *fullText, source)
+		return serverHandler.addDocumentToDatabase(filePath, *fullText, ocrStatus, source, timeline)
 
 	case ".txt", ".rtf":
 		textProcessing(filePath)
-		return nil
+		return nil, nil
 
 	case ".doc", ".docx", ".odf":
 		wordDocProcessing(filePath)
-		return nil
+		return nil, nil
+
+	case ".xlsx", ".csv":
+		fullText, err := extractSpreadsheetText(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errExtractionFailed, err)
+		}
+		timeline.mark(stageTextExtracted, "spreadsheet cells")
+		return serverHandler.addDocumentToDatabase(filePath, fullText, database.OCRSkipped, source, timeline)
 
 	case ".tiff", ".jpg", ".jpeg", ".png":
+		ocr := timeline.step("ocr image")
 		fullText, err := serverHandler.ocrProcessing(filePath)
+		endSpan(ocr, err)
 		if err != nil {
-			return fmt.Errorf("OCR processing failed: %w", err)
+			return nil, fmt.Errorf("%w: %w", errOCRFailed, err)
 		}
 		if fullText == nil {
-			return fmt.Errorf("OCR processing returned nil text")
+			return nil, fmt.Errorf("%w: OCR returned nil text", errOCRFailed)
 		}
-		return serverHandler.addDocumentToDatabase(filePath, *fullText, source)
+		timeline.mark(stageOCR, "")
+		return serverHandler.addDocumentToDatabase(filePath, *fullText, database.OCRDone, source, timeline)
 
 	default:
-		return fmt.Errorf("unsupported file type: %s", filepath.Ext(filePath))
+		return nil, fmt.Errorf("%w: %s", errUnsupportedFileType, filepath.Ext(filePath))
 	}
 }
 
@@ -300,29 +530,49 @@ func (serverHandler *ServerHandler) ingressDocument(filePath string, source stri
 		}
 	}()
 
+	timeline := startTimeline(source)
+	serverHandler.prepareScan(filePath, timeline)
 	switch filepath.Ext(filePath) {
 	case ".pdf":
+		textLayer := timeline.step("read text layer")
 		fullText, err := pdfProcessing(filePath)
+		textLayer.End()
+		ocrStatus := database.OCRSkipped
 		if err != nil {
-			fullText, err = serverHandler.convertToImage(filePath)
+			timeline.mark(stageTextExtracted, "no text layer")
+			fullText, err = serverHandler.convertToImage(timeline.context(), filePath)
 			if err != nil {
 				Logger.Error("OCR Processing failed on file so not added to database", "filePath", filePath, "error", err)
 				return
 			}
+			timeline.mark(stageOCR, "")
+			ocrStatus = database.OCRDone
+		} else {
+			timeline.mark(stageTextExtracted, "PDF text layer")
 		}
 		// Check if fullText is nil before dereferencing
 		if fullText == nil {
 			Logger.Error("PDF processing returned nil text, skipping document", "filePath", filePath)
 			return
 		}
-		serverHandler.addDocumentToDatabase(filePath, *fullText, source)
+		serverHandler.addDocumentToDatabase(filePath, *fullText, ocrStatus, source, timeline)
 
 	case ".txt", ".rtf":
 		textProcessing(filePath)
 	case ".doc", ".docx", ".odf":
 		wordDocProcessing(filePath)
+	case ".xlsx", ".csv":
+		fullText, err := extractSpreadsheetText(filePath)
+		if err != nil {
+			Logger.Error("Spreadsheet text extraction failed on file", "filePath", filePath, "error", err)
+			return
+		}
+		timeline.mark(stageTextExtracted, "spreadsheet cells")
+		serverHandler.addDocumentToDatabase(filePath, fullText, database.OCRSkipped, source, timeline)
 	case ".tiff", ".jpg", ".jpeg", ".png":
+		ocr := timeline.step("ocr image")
 		fullText, err := serverHandler.ocrProcessing(filePath)
+		endSpan(ocr, err)
 		if err != nil {
 			Logger.Error("OCR Processing failed on file", "filePath", filePath, "error", err)
 			return
@@ -332,38 +582,73 @@ func (serverHandler *ServerHandler) ingressDocument(filePath string, source stri
 			Logger.Error("OCR processing returned nil text, skipping document", "filePath", filePath)
 			return
 		}
-		serverHandler.addDocumentToDatabase(filePath, *fullText, source)
+		timeline.mark(stageOCR, "")
+		serverHandler.addDocumentToDatabase(filePath, *fullText, database.OCRDone, source, timeline)
 	default:
 		Logger.Warn("Invalid file type", "file", filepath.Base((filePath)))
 	}
 }
 
-func (serverHandler *ServerHandler) addDocumentToDatabase(filePath string, fullText string, source string) error {
+// addDocumentToDatabase stores an ingress or uploaded file with its extracted text and OCR status, then saves its
+// timeline. It returns the stored document.
+func (serverHandler *ServerHandler) addDocumentToDatabase(filePath string, fullText string, ocrStatus string, source string, timeline *documentTimeline) (_ *database.Document, err error) {
+	saving := timeline.step("save document")
 	document, err := database.AddNewDocument(filePath, fullText, serverHandler.DB) //Adds everything but the URL, that is added afterwards
+	endSpan(saving, err)
 	if err != nil {
 		Logger.Error("Failed to add document to database", "document", document, "error", err) //TODO: Handle document that we were unable to add
-		return err
+		return nil, err
 	}
-	documentURL := "/document/view/" + document.ULID.String()
-	serverHandler.Echo.File(documentURL, document.Path)                                                 //Generating a direct URL to document so it is live immediately after add
+	timeline.mark(stageIndexed, "")
+	defer func() {
+		if err != nil {
+			timeline.mark(stageFailed, err.Error())
+		}
+		timeline.save(serverHandler.DB, document.ULID)
+	}()
+	documentURL := documentViewURL(document.ULID)                                                       //the view handler serves it as soon as the record exists
 	_, err = database.UpdateDocumentField(document.ULID.String(), "URL", documentURL, serverHandler.DB) //updating the database with the new file location
 	if err != nil {
 		Logger.Error("Unable to update document field", "field", "Path", "error", err)
-		return err
+		return nil, err
 	}
-	err = ingressCopyDocument(filePath, serverHandler.ServerConfig)
+	if err = serverHandler.DB.UpdateDocumentOCRStatus(document.ULID.String(), ocrStatus); err != nil {
+		Logger.Error("Unable to record OCR status", "filePath", filePath, "error", err)
+		return nil, err
+	}
+	document.OCRStatus = ocrStatus
+	if pages := pdfPageCount(filePath); pages > 0 {
+		if err = serverHandler.DB.UpdateDocumentFileDetails(document.ULID.String(), document.Size, pages); err != nil {
+			Logger.Error("Unable to record page count", "filePath", filePath, "error", err)
+			return nil, err
+		}
+	}
+	storing := timeline.step("store file")
+	copiedHash, err := ingressCopyDocument(filePath, serverHandler.ServerConfig, serverHandler.documentStorage())
+	endSpan(storing, err)
 	if err != nil {
 		Logger.Error("Error moving ingress file to new location", "filePath", filePath, "error", err)
-		return err
+		return nil, fmt.Errorf("%w: %w", errStorageFailed, err)
+	}
+	if !copiedHash.Matches(document.Hash) {
+		// The file changed after it was hashed for the duplicate check, so the stored hash is wrong
+		Logger.Error("Ingress file changed while it was being copied", "filePath", filePath, "expected", document.Hash, "copied", copiedHash)
+		return nil, fmt.Errorf("%w: hash mismatch after copy (expected: %s, got: %s)", errStorageFailed, document.Hash, copiedHash)
 	}
+	timeline.mark(stageStored, "")
 	if source == "ingress" { //if file was ingressed need to handle the original, if uploaded no problem
 		err := ingressCleanup(filePath, *document, serverHandler.ServerConfig, serverHandler.DB)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
+	serverHandler.recordFolder(document.Folder)
+	serverHandler.wordCounts.add(document)
+	serverHandler.wordCounts.flushSoon(serverHandler.DB)
+	serverHandler.invalidateDocumentCache()
 	Logger.Info("Added file to the database", "filePath", filePath)
-	return nil
+	serverHandler.documentIngested(document, source, nil)
+	return document, nil
 }
 
 func deleteEmptyIngressFolders(path string) {
@@ -414,29 +699,30 @@ func DeleteFile(filePath string) error {
 } */
 
 // ingressCopyDocument copies the document to document storage location
-func ingressCopyDocument(filePath string, serverConfig config.ServerConfig) error {
-	srcFile, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
+// and returns the hashes of the bytes copied
+func ingressCopyDocument(filePath string, serverConfig config.ServerConfig, documents storage.Backend) (database.FileHashes, error) {
+	// Build native paths with filepath.Join: string concatenation with "/" breaks on Windows drive letters and UNC shares
+	filePath = filepath.FromSlash(filePath)
 	var newFilePath string
 	if serverConfig.IngressPreserve == false { //if we are not saving the folder structure just read each file in with new path
-		newFilePath = filepath.ToSlash(serverConfig.NewDocumentFolder + "/" + filepath.Base(filePath))
+		newFilePath = filepath.Join(filepath.FromSlash(serverConfig.NewDocumentFolder), filepath.Base(filePath))
 	} else { //If we ARE preserving ingress structure, create a new full path by creating a relative path and joining it to the
-		basePath := serverConfig.IngressPath
-		newFileNameRoot := serverConfig.DocumentPath
+		basePath := filepath.FromSlash(serverConfig.IngressPath)
+		newFileNameRoot := filepath.FromSlash(serverConfig.DocumentPath)
 		relativePath, err := filepath.Rel(basePath, filePath)
 		if err != nil {
-			return err
+			return database.FileHashes{}, err
+		}
+		if !filepath.IsLocal(relativePath) {
+			return database.FileHashes{}, fmt.Errorf("%s is not inside the ingress folder %s", filePath, basePath)
 		}
 		newFilePath = filepath.Join(newFileNameRoot, relativePath)
-		os.MkdirAll(filepath.Dir(newFilePath), os.ModePerm) //creating the directory structure so we can write the file: TODO: not sure if os.WriteFile does this for us?  Don't think so.
 	}
-	err = os.WriteFile(newFilePath, srcFile, os.ModePerm)
+	key, err := documentKey(serverConfig.DocumentPath, newFilePath)
 	if err != nil {
-		return err
+		return database.FileHashes{}, err
 	}
-	return nil
+	return storeFileHashed(documents, key, filePath) // the backend creates the folder structure as it writes
 }
 
 // ingressCleanup cleans up the ingress folder after we have handled the documents //TODO: Maybe ALSO preserve folder structure from ingress folder here as well?
@@ -448,7 +734,7 @@ func ingressCleanup(fileName string, document database.Document, serverConfig co
 		}
 		return nil
 	}
-	newFile := filepath.FromSlash(serverConfig.IngressMoveFolder + "/" + filepath.Base(fileName)) //Moving ingress files to another location
+	newFile := filepath.Join(filepath.FromSlash(serverConfig.IngressMoveFolder), filepath.Base(filepath.FromSlash(fileName))) //Moving ingress files to another location
 	err := os.Rename(fileName, newFile)
 	if err != nil {
 		return err
@@ -456,6 +742,38 @@ func ingressCleanup(fileName string, document database.Document, serverConfig co
 	return nil
 }
 
+// pageBreak separates the pages of PDF text, as pdftotext does, so matches can be mapped back to pages
+const pageBreak = "\f"
+
+// fileDetails returns the size of a file and, for a PDF, its page count, as recorded on the document at
+// ingestion so listings need not read the file. Both are 0 when the file cannot be read.
+func fileDetails(file string) (int64, int) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return 0, 0
+	}
+	return info.Size(), pdfPageCount(file)
+}
+
+// pdfPageCount returns the number of pages in a PDF, reading only its page tree; 0 for other files or
+// a PDF that cannot be opened
+func pdfPageCount(file string) (pages int) {
+	if !strings.EqualFold(filepath.Ext(file), ".pdf") {
+		return 0
+	}
+	defer func() {
+		if recover() != nil { // the PDF reader panics on some malformed files
+			pages = 0
+		}
+	}()
+	pdfFile, result, err := pdf.Open(file)
+	if err != nil {
+		return 0
+	}
+	defer pdfFile.Close()
+	return result.NumPage()
+}
+
 func pdfProcessing(file string) (*string, error) {
 	fileName := filepath.Base((file))
 	var fullText string
@@ -466,15 +784,26 @@ func pdfProcessing(file string) (*string, error) {
 		return nil, err
 	}
 	defer pdfFile.Close()
-	var buf bytes.Buffer
-	bytes, err := result.GetPlainText()
-	if err != nil {
-		Logger.Error("Unable to convert PDF to text", "fileName", fileName)
-		return nil, err
+	// Pages are read one at a time so the text keeps its page breaks for in-document search
+	pageTexts := make([]string, 0, result.NumPage())
+	fonts := make(map[string]*pdf.Font)
+	for pageNumber := 1; pageNumber <= result.NumPage(); pageNumber++ {
+		page := result.Page(pageNumber)
+		for _, name := range page.Fonts() { // cache fonts so each charmap is parsed once
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		pageText, err := page.GetPlainText(fonts)
+		if err != nil {
+			Logger.Error("Unable to convert PDF to text", "fileName", fileName, "page", pageNumber)
+			return nil, err
+		}
+		pageTexts = append(pageTexts, pageText)
 	}
-	buf.ReadFrom(bytes)
-	fullText = buf.String() //writing from the buffer to the string
-	if fullText == "" {
+	fullText = strings.Join(pageTexts, pageBreak)
+	if strings.Trim(fullText, pageBreak) == "" {
 		err = errors.New("PDF Text Result is empty")
 		Logger.Info("PDF Text Result is empty, sending to OCR", "fileName", fileName, "error", err)
 		return nil, err
@@ -491,122 +820,122 @@ func wordDocProcessing(fileName string) {
 
 }
 
-func (serverHandler *ServerHandler) convertToImage(fileName string) (*string, error) {
-	var err error
+// convertToImage renders each page of a PDF to an image and OCRs it, one page at a time so that
+// memory use does not grow with the page count. OCR_MAX_PAGES and OCR_MAX_FILE_MB guard against huge files.
+// Each page's rendering and OCR get spans of their own within ctx's span.
+func (serverHandler *ServerHandler) convertToImage(ctx context.Context, fileName string) (text *string, err error) {
 	Logger.Info("Converting PDF To image for OCR using Go libraries", "fileName", fileName)
+	fileName = filepath.Clean(fileName)
+	ctx, span := startSpan(ctx, "ocr pdf")
+	defer func() { endSpan(span, err) }()
 
-	// Create output image path
-	imageName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
-	imageName = filepath.Base(fmt.Sprint(imageName + ".png"))
-	imageName = filepath.Join("temp", imageName)
-	imageName, err = filepath.Abs(imageName)
+	// Check if file exists and is readable
+	fileInfo, err := os.Stat(fileName)
 	if err != nil {
-		Logger.Error("Unable to edit absolute path string for temporary image for OCR", "fileName", fileName, "error", err)
+		Logger.Error("Unable to access PDF file", "fileName", fileName, "error", err)
 		return nil, err
 	}
-
-	err = os.MkdirAll(filepath.Dir(imageName), os.ModePerm)
-	if err != nil {
-		Logger.Error("Unable to create absolute path for temporary image for OCR (permissions?)", "dir", filepath.Dir(imageName), "error", err)
+	if maxMB := serverHandler.ServerConfig.OCRMaxFileMB; maxMB > 0 && fileInfo.Size() > int64(maxMB)<<20 {
+		err := fmt.Errorf("PDF is %d MB, larger than the OCR limit of %d MB", fileInfo.Size()>>20, maxMB)
+		Logger.Error("Refusing to OCR large PDF", "fileName", fileName, "error", err)
 		return nil, err
 	}
+	if !serverHandler.ocrConfigured() {
+		Logger.Info("Tesseract not configured, skipping PDF rendering for OCR", "fileName", fileName)
+		emptyText := ""
+		return &emptyText, nil
+	}
 
-	fileName = filepath.Clean(fileName)
-	imageName = filepath.Clean(imageName)
-	Logger.Info("Creating temp image for OCR at", "imageName", imageName)
-
-	// Check if file exists and is readable
-	if _, err := os.Stat(fileName); err != nil {
-		Logger.Error("Unable to access PDF file", "fileName", fileName, "error", err)
+	// Page images live in a private work directory that is removed when we are done
+	workDir, cleanup, err := serverHandler.newWorkDir("pdf-*")
+	if err != nil {
+		Logger.Error("Unable to create temp directory for OCR image (permissions?)", "tempRoot", serverHandler.tempRoot(), "error", err)
 		return nil, err
 	}
+	defer cleanup()
+	baseName := asciiName(strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))) // tesseract is handed ASCII-only paths
 
-	// Create PDFium renderer (pure Go, no CGo)
-	renderer, err := pdfrenderer.NewRenderer()
+	// Render with the PDF sidecar when PDF_SERVICE_URL is set, otherwise in process with PDFium (pure Go, no CGo)
+	renderer, err := pdfrenderer.NewRenderer(serverHandler.ServerConfig.PDFServiceURL)
 	if err != nil {
-		Logger.Error("Unable to create PDF renderer (PDFium)", "error", err)
+		Logger.Error("Unable to create PDF renderer", "pdfService", serverHandler.ServerConfig.PDFServiceURL, "error", err)
 		return nil, err
 	}
 	defer renderer.Close()
 
-	Logger.Debug("Using PDFium renderer (pure Go)")
-
-	// Render all pages of the PDF to images
-	images, err := renderer.RenderPDF(fileName)
+	Logger.Debug("Rendering PDF pages", "pdfService", serverHandler.ServerConfig.PDFServiceURL)
+
+	var pageTexts []string
+	maxPages := serverHandler.ServerConfig.OCRMaxPages
+	rendering := time.Now() // the renderer hands over each page once it is drawn, so its span starts here
+	pageCount, err := renderer.RenderPages(fileName, maxPages, func(pageIndex int, page image.Image) error {
+		pageNumber := attribute.Int("godocs.page", pageIndex+1)
+		_, render := otel.Tracer(tracerName).Start(ctx, "render page", trace.WithTimestamp(rendering), trace.WithAttributes(pageNumber))
+		render.End()
+		defer func() { rendering = time.Now() }()
+
+		_, ocr := startSpan(ctx, "ocr page", pageNumber)
+		imageName := filepath.Join(workDir, fmt.Sprintf("%s-%04d.png", baseName, pageIndex+1))
+		if err := writeOCRImage(imageName, page); err != nil {
+			Logger.Error("Unable to write page image", "imageName", imageName, "error", err)
+			endSpan(ocr, err)
+			return err
+		}
+		pageText, err := serverHandler.ocrProcessing(imageName)
+		os.Remove(imageName) // only one page image on disk at a time
+		endSpan(ocr, err)
+		if err != nil {
+			return err
+		}
+		pageTexts = append(pageTexts, *pageText)
+		return nil
+	})
+	span.SetAttributes(attribute.Int("godocs.pages", pageCount), attribute.Int("godocs.ocr_pages", len(pageTexts)))
 	if err != nil {
 		Logger.Error("Unable to render PDF pages", "fileName", fileName, "error", err)
 		return nil, err
 	}
 
-	Logger.Debug("PDF has pages", "count", len(images))
+	Logger.Debug("PDF has pages", "count", pageCount)
 
-	if len(images) == 0 {
+	if len(pageTexts) == 0 {
 		err := fmt.Errorf("no pages could be rendered from PDF")
 		Logger.Error("Failed to render any pages", "fileName", fileName)
 		return nil, err
 	}
-
-	// Combine all pages vertically (append)
-	var combinedImage image.Image
-	if len(images) == 1 {
-		combinedImage = images[0]
-	} else {
-		// Calculate total height and max width
-		totalHeight := 0
-		maxWidth := 0
-		for _, img := range images {
-			bounds := img.Bounds()
-			totalHeight += bounds.Dy()
-			if bounds.Dx() > maxWidth {
-				maxWidth = bounds.Dx()
-			}
-		}
-
-		// Create combined image
-		combined := image.NewRGBA(image.Rect(0, 0, maxWidth, totalHeight))
-		currentY := 0
-		for _, img := range images {
-			bounds := img.Bounds()
-			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-				for x := bounds.Min.X; x < bounds.Max.X; x++ {
-					combined.Set(x, currentY+y-bounds.Min.Y, img.At(x, y))
-				}
-			}
-			currentY += bounds.Dy()
-		}
-		combinedImage = combined
+	if len(pageTexts) < pageCount {
+		Logger.Warn("PDF has more pages than OCR_MAX_PAGES, remaining pages were not OCRed", "fileName", fileName, "pages", pageCount, "ocrPages", len(pageTexts))
 	}
 
+	Logger.Info("Successfully OCRed PDF pages", "fileName", fileName, "pages", len(pageTexts))
+	fullText := strings.Join(pageTexts, pageBreak)
+	return &fullText, nil
+}
+
+// writeOCRImage resizes a page to 1024px wide, sharpens it for OCR and saves it as PNG
+func writeOCRImage(imageName string, page image.Image) error {
 	// Resize to 1024px width while maintaining aspect ratio
-	resizedImage := imaging.Resize(combinedImage, 1024, 0, imaging.Lanczos)
+	resizedImage := imaging.Resize(page, 1024, 0, imaging.Lanczos)
 
 	// Apply basic sharpening to improve OCR quality
 	processedImage := imaging.Sharpen(resizedImage, 1.0)
 
-	// Save the processed image
 	outFile, err := os.Create(imageName)
 	if err != nil {
-		Logger.Error("Unable to create output image file", "imageName", imageName, "error", err)
-		return nil, err
-	}
-	defer outFile.Close()
-
-	err = png.Encode(outFile, processedImage)
-	if err != nil {
-		Logger.Error("Unable to encode PNG image", "imageName", imageName, "error", err)
-		return nil, err
+		return err
 	}
-
-	Logger.Info("Successfully converted PDF to image", "imageName", imageName)
-
-	fullText, err := serverHandler.ocrProcessing(imageName)
-	if err != nil {
-		return nil, err
+	if err := png.Encode(outFile, processedImage); err != nil {
+		outFile.Close()
+		return err
 	}
-	return fullText, nil
+	return outFile.Close()
 }
 
 func (serverHandler *ServerHandler) ocrProcessing(imageName string) (*string, error) {
+	// OCR goes to the tesseract sidecar when TESSERACT_SERVICE_URL is set
+	if serverHandler.ServerConfig.TesseractServiceURL != "" {
+		return serverHandler.tesseractServiceOCR(imageName)
+	}
 	// Check if Tesseract is configured
 	if serverHandler.ServerConfig.TesseractPath == "" {
 		Logger.Info("Tesseract not configured, skipping OCR processing", "imageName", imageName)
@@ -615,21 +944,27 @@ func (serverHandler *ServerHandler) ocrProcessing(imageName string) (*string, er
 	}
 
 	var fullText string
-	var err error
-	textFileName := filepath.Base(imageName)                                    //creating the path for the .txt that tesseract will output with the OCR results.
-	textFileName = strings.TrimSuffix(textFileName, filepath.Ext(textFileName)) //just get the name, no extension
-	fullpath := filepath.Join("temp", textFileName)
-	fullpath, err = filepath.Abs(fullpath)
+	workDir, cleanup, err := serverHandler.newWorkDir("ocr-*") //tesseract writes its .txt output here, removed when we are done
 	if err != nil {
-		Logger.Error("Unable to create full path for temp OCR File", "fullpath", fullpath)
-	}
-	textFileName = filepath.Clean(fullpath)
-	/* 	tempOCRFile, err := os.Create(fmt.Sprintf("temp/%s", imageName))
-	   	if err != nil {
-	   		Logger.Error("Unable to create temp file", "path", fmt.Sprintf("temp/%s", imageName), "error", err)
-	   		return nil, err
-	   	} */
-	tesseractArgs := []string{imageName, textFileName}                                       //outputting ocr to a txt file
+		Logger.Error("Unable to create temp directory for OCR output", "tempRoot", serverHandler.tempRoot(), "error", err)
+		return nil, err
+	}
+	defer cleanup()
+	safeName := asciiName(filepath.Base(imageName)) //tesseract builds include some that cannot open non-ASCII paths
+	inputName := imageName
+	if safeName != filepath.Base(imageName) {
+		inputName = filepath.Join(workDir, safeName)
+		if err := copyFile(imageName, inputName); err != nil {
+			Logger.Error("Unable to copy image to an ASCII temp name for OCR", "imageName", imageName, "error", err)
+			return nil, err
+		}
+	}
+	textFileName := strings.TrimSuffix(safeName, filepath.Ext(safeName)) //creating the path for the .txt that tesseract will output with the OCR results.
+	textFileName = filepath.Join(workDir, textFileName)
+	tesseractArgs := []string{inputName, textFileName} //outputting ocr to a txt file
+	if languages := splitOCRLanguages(serverHandler.ServerConfig.OCRLanguages); len(languages) > 0 {
+		tesseractArgs = append(tesseractArgs, "-l", strings.Join(languages, "+"))
+	}
 	tesseractCMD := exec.Command(serverHandler.ServerConfig.TesseractPath, tesseractArgs...) //get the path to tesseract
 	var stdBuffer bytes.Buffer
 	mw := io.MultiWriter(os.Stdout, &stdBuffer)
@@ -9,10 +9,12 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/drummonds/godocs/config"
 	"github.com/drummonds/godocs/database"
 	"github.com/labstack/echo/v4"
+	"github.com/oklog/ulid/v2"
 )
 
 // TestIngressDocumentNilPointerResilience tests that nil pointer issues don't crash the app
@@ -351,3 +353,66 @@ func truncateString(s string, maxLen int) string {
 	}
 	return s[:maxLen] + "..."
 }
+
+// setupTestHandler builds a ServerHandler backed by a throwaway SQLite database
+// with ingress and document folders under a temp directory
+func setupTestHandler(t testing.TB) *ServerHandler {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	database.Logger = logger
+	Logger = logger
+
+	tempDir := t.TempDir()
+	serverConfig := config.ServerConfig{
+		DatabaseType:   "sqlite",
+		DatabaseDbname: filepath.Join(tempDir, "test.sqlite"),
+		IngressPath:    filepath.Join(tempDir, "ingress"),
+		DocumentPath:   filepath.Join(tempDir, "documents"),
+	}
+	for _, dir := range []string{serverConfig.IngressPath, serverConfig.DocumentPath} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+
+	db := database.NewRepository(serverConfig)
+	t.Cleanup(func() { db.Close() })
+	if err := db.SaveConfig(&serverConfig); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	return &ServerHandler{DB: db, Echo: echo.New(), ServerConfig: serverConfig}
+}
+
+// setupMemoryTestHandler builds a ServerHandler backed by the in-memory repository for serverConfig
+func setupMemoryTestHandler(t testing.TB, serverConfig config.ServerConfig) *ServerHandler {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	database.Logger = logger
+	Logger = logger
+
+	db := database.NewMemoryDB()
+	if err := db.SaveConfig(&serverConfig); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	return &ServerHandler{DB: db, Echo: echo.New(), ServerConfig: serverConfig}
+}
+
+// saveTestDocument stores a document record pointing at path
+func saveTestDocument(t *testing.T, db database.Repository, path string, fullText string) *database.Document {
+	t.Helper()
+	doc := &database.Document{
+		Name:         filepath.Base(path),
+		Path:         path,
+		IngressTime:  time.Now(),
+		Folder:       filepath.Dir(path),
+		Hash:         ulid.Make().String(),
+		ULID:         ulid.Make(),
+		DocumentType: filepath.Ext(path),
+		FullText:     fullText,
+	}
+	if err := db.SaveDocument(doc); err != nil {
+		t.Fatalf("Failed to save document: %v", err)
+	}
+	return doc
+}
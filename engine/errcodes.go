@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+// Errors from processing a document, each matching an error code in dto
+var (
+	errDuplicateDocument = errors.New("duplicate document")
+	errOCRFailed         = errors.New("OCR processing failed")
+	errExtractionFailed  = errors.New("text extraction failed")
+	errStorageFailed     = errors.New("unable to store document")
+	errHookFailed        = errors.New("post-ingestion hook failed")     // the document stays ingested
+	errTransformFailed   = errors.New("pre-ingestion transform failed") // the file is quarantined
+)
+
+// errorCodes maps the errors handlers and jobs see to their catalogue codes, most specific first
+var errorCodes = []struct {
+	err  error
+	code dto.ErrorCode
+}{
+	{errDuplicateDocument, dto.CodeDuplicate},
+	{database.ErrDuplicateDocument, dto.CodeDuplicate},
+	{errUploadDuplicate, dto.CodeDuplicate},
+	{errUploadExists, dto.CodeNameConflict},
+	{errUnsupportedFileType, dto.CodeUnsupportedType},
+	{errQuotaExceeded, dto.CodeQuotaExceeded},
+	{errOCRFailed, dto.CodeOCRFailed},
+	{errExtractionFailed, dto.CodeExtractionFailed},
+	{errStorageFailed, dto.CodeStorageFailed},
+	{errHookFailed, dto.CodeHookFailed},
+	{errTransformFailed, dto.CodeTransformFailed},
+	{errInvalidUploadFolder, dto.CodeBadRequest},
+	{errInvalidIngressPath, dto.CodeBadRequest},
+	{errFolderOutsideRoot, dto.CodeBadRequest},
+}
+
+// errorCode returns the catalogue code for err, or dto.CodeInternal for an error without one
+func errorCode(err error) dto.ErrorCode {
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			return known.code
+		}
+	}
+	return dto.CodeInternal
+}
+
+// jobItems collects the files a job could not process cleanly, for the "items" list of its result.
+// A nil jobItems records nothing.
+type jobItems struct {
+	items []dto.JobItem
+}
+
+// add records the problem with a file under its error code
+func (j *jobItems) add(filePath string, err error) {
+	if j == nil || err == nil {
+		return
+	}
+	j.items = append(j.items, dto.JobItem{File: filepath.Base(filePath), Code: errorCode(err), Error: err.Error()})
+}
+
+// list returns the recorded items, never nil so the result always has the list
+func (j *jobItems) list() []dto.JobItem {
+	if j == nil || j.items == nil {
+		return []dto.JobItem{}
+	}
+	return j.items
+}
@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+func TestErrorCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		want dto.ErrorCode
+	}{
+		{fmt.Errorf("%w (hash: abc)", errDuplicateDocument), dto.CodeDuplicate},
+		{fmt.Errorf("%w: /ingress/a.pdf", database.ErrDuplicateDocument), dto.CodeDuplicate},
+		{fmt.Errorf("%w: %w", errOCRFailed, errors.New("tesseract exited 1")), dto.CodeOCRFailed},
+		{fmt.Errorf("step 2 failed (move/verify): %w: %w", errStorageFailed, errors.New("permission denied")), dto.CodeStorageFailed},
+		{fmt.Errorf("%w: .exe", errUnsupportedFileType), dto.CodeUnsupportedType},
+		{errUploadExists, dto.CodeNameConflict},
+		{errors.New("disk on fire"), dto.CodeInternal},
+	}
+	for _, c := range cases {
+		if got := errorCode(c.err); got != c.want {
+			t.Errorf("errorCode(%q) = %s, expected %s", c.err, got, c.want)
+		}
+	}
+
+	// A nil list records nothing and a job without problems reports an empty list
+	var none *jobItems
+	none.add("a.pdf", errOCRFailed)
+	if items := none.list(); items == nil || len(items) != 0 {
+		t.Errorf("Expected an empty list, got %v", items)
+	}
+}
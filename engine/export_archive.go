@@ -0,0 +1,410 @@
+package engine
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+	"github.com/oklog/ulid/v2"
+)
+
+// exportArchivePath starts a full export, which read-only mode allows as it changes nothing
+const exportArchivePath = "/api/admin/export"
+
+// Formats a full export can be written in
+const (
+	exportZip   = "zip"
+	exportTarGz = "tar.gz"
+)
+
+// exportManifestName is the entry listing everything in a full export
+const exportManifestName = "manifest.json"
+
+// exportFileName names a full export by when it was taken, so exports sort in time order
+func exportFileName(at time.Time, format string) string {
+	return "godocs-export-" + at.Format("20060102-150405") + "." + format
+}
+
+// parseExportFormat reads the format query of POST /api/admin/export, zip when it is empty
+func parseExportFormat(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", exportZip:
+		return exportZip, nil
+	case exportTarGz, "tgz":
+		return exportTarGz, nil
+	}
+	return "", fmt.Errorf("unknown export format %q, expected zip or tar.gz", value)
+}
+
+// exportSidecar is the metadata written beside each document in a full export
+type exportSidecar struct {
+	exportDocument
+	Tags []string `json:",omitempty"`
+}
+
+// exportManifestEntry is one document in a full export's manifest
+type exportManifestEntry struct {
+	ID       string `json:"id"`
+	File     string `json:"file,omitempty"` // entry of the document's file, empty when the file was missing
+	Metadata string `json:"metadata"`       // entry of its JSON sidecar
+	Hash     string `json:"hash"`
+	Size     int64  `json:"size"`
+}
+
+// exportManifest lists the documents in a full export
+type exportManifest struct {
+	ExportedAt time.Time             `json:"exportedAt"`
+	Documents  []exportManifestEntry `json:"documents"`
+	Missing    int                   `json:"missing"` // documents whose file could not be read, exported with their metadata only
+}
+
+// archiveWriter adds files to a zip or tar.gz export
+type archiveWriter interface {
+	add(name string, size int64, modified time.Time, content io.Reader) error
+	Close() error
+}
+
+// zipArchiveWriter writes a zip export, storing already compressed files as they are
+type zipArchiveWriter struct {
+	*zip.Writer
+}
+
+func (w zipArchiveWriter) add(name string, _ int64, modified time.Time, content io.Reader) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified}
+	if storedExtensions[strings.ToLower(path.Ext(name))] {
+		header.Method = zip.Store
+	}
+	entry, err := w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, content)
+	return err
+}
+
+// tarArchiveWriter writes a gzip compressed tar export
+type tarArchiveWriter struct {
+	tar  *tar.Writer
+	gzip *gzip.Writer
+}
+
+func newTarArchiveWriter(w io.Writer) *tarArchiveWriter {
+	compressor := gzip.NewWriter(w)
+	return &tarArchiveWriter{tar: tar.NewWriter(compressor), gzip: compressor}
+}
+
+func (w *tarArchiveWriter) add(name string, size int64, modified time.Time, content io.Reader) error {
+	if err := w.tar.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: size, ModTime: modified, Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := io.Copy(w.tar, content)
+	return err
+}
+
+func (w *tarArchiveWriter) Close() error {
+	if err := w.tar.Close(); err != nil {
+		return err
+	}
+	return w.gzip.Close()
+}
+
+// addJSON adds value to the export as an indented JSON entry
+func addJSON(archive archiveWriter, name string, modified time.Time, value interface{}) error {
+	encoded, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return archive.add(name, int64(len(encoded)), modified, strings.NewReader(string(encoded)))
+}
+
+// exportEntryNames returns where a document's file and sidecar go in a full export: its path below the
+// document folder under documents/, or under its ULID when that is taken or it is not in the folder
+func (serverHandler *ServerHandler) exportEntryNames(document database.Document, used map[string]bool) (file, sidecar string) {
+	file = "documents/" + zipEntryName(serverHandler.ServerConfig.DocumentPath, document)
+	if used[file] || used[file+".json"] {
+		file = "documents/" + document.ULID.String() + "/" + normaliseName(document.Name)
+	}
+	used[file], used[file+".json"] = true, true
+	return file, file + ".json"
+}
+
+// openExportFile opens a document's file for the export, with its size and modification time. A document
+// in cold storage is decompressed into workDir first, as a tar entry needs its size before its content.
+func (serverHandler *ServerHandler) openExportFile(document database.Document, workDir string) (*os.File, int64, time.Time, error) {
+	name := document.Path
+	if archive, err := serverHandler.DB.GetDocumentArchive(document.ULID.String()); err == nil {
+		name = filepath.Join(workDir, document.ULID.String())
+		if err := gunzipFile(filepath.FromSlash(archive.ArchivePath), name); err != nil {
+			return nil, 0, time.Time{}, err
+		}
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, 0, time.Time{}, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, time.Time{}, err
+	}
+	if name != document.Path {
+		os.Remove(name) // gone once the file is closed
+	}
+	return file, info.Size(), info.ModTime(), nil
+}
+
+// gunzipFile decompresses the gzip file source into destination
+func gunzipFile(source, destination string) error {
+	compressed, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer compressed.Close()
+	reader, err := gzip.NewReader(compressed)
+	if err != nil {
+		return err
+	}
+	_, err = writeFileHashed(destination, reader, 0600)
+	return err
+}
+
+// writeExportArchive writes every document's file and metadata, and the manifest listing them, to archive.
+// A file that cannot be read is left out and counted as missing in the manifest.
+func (serverHandler *ServerHandler) writeExportArchive(archive archiveWriter, at time.Time, heartbeat *jobHeartbeat) (exportManifest, error) {
+	manifest := exportManifest{ExportedAt: at, Documents: []exportManifestEntry{}}
+	workDir, cleanup, err := serverHandler.newWorkDir("export-*")
+	if err != nil {
+		return manifest, err
+	}
+	defer cleanup()
+
+	used := map[string]bool{exportManifestName: true}
+	var cursor *database.DocumentCursor
+	for {
+		documents, err := serverHandler.DB.GetNewestDocumentsAfter(cursor, exportBatchSize)
+		if err != nil {
+			return manifest, fmt.Errorf("unable to read documents: %w", err)
+		}
+		for _, document := range documents {
+			heartbeat.beat()
+			documentULID := document.ULID.String()
+			fileName, sidecarName := serverHandler.exportEntryNames(document, used)
+			entry := exportManifestEntry{ID: documentULID, File: fileName, Metadata: sidecarName, Hash: document.Hash}
+
+			file, size, modified, err := serverHandler.openExportFile(document, workDir)
+			if err != nil {
+				Logger.Warn("Leaving unreadable file out of export", "ulid", documentULID, "path", document.Path, "error", err)
+				entry.File = ""
+				manifest.Missing++
+			} else {
+				entry.Size = size
+				err = archive.add(fileName, size, modified, file)
+				file.Close()
+				if err != nil {
+					return manifest, fmt.Errorf("unable to export %s: %w", document.Path, err)
+				}
+			}
+
+			sidecar := exportSidecar{exportDocument: exportDocument{Document: document}}
+			// Batches are read without the text column, so fetch it one document at a time
+			if sidecar.FullText, err = serverHandler.DB.GetDocumentText(documentULID); err != nil {
+				return manifest, fmt.Errorf("unable to read full text of %s: %w", documentULID, err)
+			}
+			if sidecar.Tags, err = serverHandler.DB.GetDocumentTags(documentULID); err != nil {
+				return manifest, fmt.Errorf("unable to read tags of %s: %w", documentULID, err)
+			}
+			if err := addJSON(archive, sidecarName, document.IngressTime, sidecar); err != nil {
+				return manifest, fmt.Errorf("unable to export metadata of %s: %w", documentULID, err)
+			}
+			manifest.Documents = append(manifest.Documents, entry)
+		}
+		if len(documents) < exportBatchSize {
+			break
+		}
+		cursor = database.CursorAfter(documents[len(documents)-1])
+	}
+	if err := addJSON(archive, exportManifestName, at, manifest); err != nil {
+		return manifest, err
+	}
+	return manifest, archive.Close()
+}
+
+// writeExport writes a full export into BACKUP_PATH, returning its path and manifest. The export is
+// written to a temporary file first, so one that fails part way never looks complete.
+func (serverHandler *ServerHandler) writeExport(at time.Time, format string, heartbeat *jobHeartbeat) (string, exportManifest, error) {
+	folder := serverHandler.ServerConfig.BackupPath
+	if folder == "" {
+		return "", exportManifest{}, fmt.Errorf("no BACKUP_PATH is set")
+	}
+	if err := os.MkdirAll(folder, os.ModePerm); err != nil {
+		return "", exportManifest{}, err
+	}
+	temp, err := os.CreateTemp(folder, ".godocs-export-*.partial")
+	if err != nil {
+		return "", exportManifest{}, err
+	}
+	defer os.Remove(temp.Name()) // no-op once renamed
+
+	var archive archiveWriter = zipArchiveWriter{zip.NewWriter(temp)}
+	if format == exportTarGz {
+		archive = newTarArchiveWriter(temp)
+	}
+	manifest, err := serverHandler.writeExportArchive(archive, at, heartbeat)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", manifest, err
+	}
+	exportPath := filepath.Join(folder, exportFileName(at, format))
+	if err := os.Rename(temp.Name(), exportPath); err != nil {
+		return "", manifest, err
+	}
+	return exportPath, manifest, nil
+}
+
+// exportJob writes a full export with job tracking. The job result names the file, which
+// GET /api/admin/export/:jobId/download serves.
+func (serverHandler *ServerHandler) exportJob(db database.Repository, jobID ulid.ULID, format string) {
+	db.UpdateJobStatus(jobID, database.JobStatusRunning, "Exporting documents")
+
+	exportPath, manifest, err := serverHandler.writeExport(database.Now(), format, newJobHeartbeat(db, jobID))
+	if err != nil {
+		Logger.Error("Export failed", "exported", len(manifest.Documents), "error", err)
+		db.UpdateJobError(jobID, fmt.Sprintf("Export failed after %d documents: %v", len(manifest.Documents), err))
+		return
+	}
+	var size int64
+	if info, err := os.Stat(exportPath); err == nil {
+		size = info.Size()
+	}
+	result, _ := json.Marshal(map[string]interface{}{
+		"file":      exportPath,
+		"format":    format,
+		"documents": len(manifest.Documents),
+		"missing":   manifest.Missing,
+		"bytes":     size,
+	})
+	if err := db.CompleteJob(jobID, string(result)); err != nil {
+		Logger.Error("Failed to mark export job as complete", "error", err)
+	}
+	Logger.Info("Export completed", "file", exportPath, "documents", len(manifest.Documents), "missing", manifest.Missing)
+}
+
+// ExportArchive starts a job writing every document, with its metadata, into one archive
+// @Summary Export everything
+// @Description Start a job that writes every document's file, a JSON sidecar with its metadata, tags and full text, and a manifest.json listing them into a zip or tar.gz under BACKUP_PATH, for migrating or keeping an off-site backup.
+// @Description Documents in cold storage are exported decompressed. Download the archive from /api/admin/export/{jobId}/download once the job completes. With sign-in or ADMIN_USERS only administrators may export. Allowed in read-only mode.
+// @Tags Admin
+// @Produce json
+// @Param format query string false "zip (default) or tar.gz"
+// @Success 200 {object} map[string]interface{} "Job created with jobId and the download URL"
+// @Failure 400 {object} dto.ErrorResponse "Unknown format"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 409 {object} map[string]interface{} "An export is already running; jobId is that job"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /admin/export [post]
+func (serverHandler *ServerHandler) ExportArchive(c echo.Context) error {
+	if serverHandler.notAdmin(c) {
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": "Only administrators may export documents",
+			"code":  dto.CodeForbidden,
+		})
+	}
+	format, err := parseExportFormat(c.QueryParam("format"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	job, err := serverHandler.startJob(database.JobTypeExport, "Starting export")
+	if errors.Is(err, errJobActive) {
+		return jobAlreadyActive(c, job)
+	}
+	if err != nil {
+		Logger.Error("Failed to create export job", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to create export job",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	go serverHandler.exportJob(serverHandler.jobDB(), job.ID, format)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":  "Export started",
+		"jobId":    job.ID.String(),
+		"format":   format,
+		"download": exportArchivePath + "/" + job.ID.String() + "/download",
+	})
+}
+
+// DownloadExport serves the archive a completed export job wrote
+// @Summary Download an export
+// @Description The zip or tar.gz written by an export job. With sign-in or ADMIN_USERS only administrators may download it.
+// @Tags Admin
+// @Produce application/zip
+// @Produce application/gzip
+// @Param jobId path string true "Export job ULID"
+// @Success 200 {file} file "The export archive"
+// @Failure 400 {object} dto.ErrorResponse "Invalid job ID"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 404 {object} dto.ErrorResponse "No such export, or its file has been removed"
+// @Failure 409 {object} dto.ErrorResponse "The export has not completed"
+// @Router /admin/export/{jobId}/download [get]
+func (serverHandler *ServerHandler) DownloadExport(c echo.Context) error {
+	if serverHandler.notAdmin(c) {
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": "Only administrators may download exports",
+			"code":  dto.CodeForbidden,
+		})
+	}
+	jobID, ok, err := ulidParam(c, "jobId", "job")
+	if !ok {
+		return err
+	}
+	job, err := serverHandler.DB.GetJob(jobID)
+	if err != nil || job == nil || job.Type != database.JobTypeExport {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Export not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if job.Status != database.JobStatusCompleted {
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error":  "Export has not completed",
+			"code":   dto.CodeConflict,
+			"status": job.Status,
+		})
+	}
+	var result struct {
+		File string `json:"file"`
+	}
+	if err := json.Unmarshal([]byte(job.Result), &result); err != nil || result.File == "" {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Export not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if _, err := os.Stat(result.File); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Export file has been removed",
+			"code":  dto.CodeFileMissing,
+		})
+	}
+	return c.Attachment(result.File, filepath.Base(result.File))
+}
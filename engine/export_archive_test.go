@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+)
+
+// readExport returns the entries of a zip or tar.gz export by name
+func readExport(t *testing.T, body []byte, format string) map[string][]byte {
+	t.Helper()
+	entries := make(map[string][]byte)
+	if format == exportZip {
+		archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			t.Fatalf("Unreadable zip: %v", err)
+		}
+		for _, file := range archive.File {
+			reader, err := file.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries[file.Name], _ = io.ReadAll(reader)
+			reader.Close()
+		}
+		return entries
+	}
+	decompressed, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Unreadable tar.gz: %v", err)
+	}
+	archive := tar.NewReader(decompressed)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("Unreadable tar: %v", err)
+		}
+		entries[header.Name], _ = io.ReadAll(archive)
+	}
+}
+
+func TestExportArchive(t *testing.T) {
+	for _, format := range []string{exportZip, exportTarGz} {
+		t.Run(format, func(t *testing.T) {
+			// A tagged document, one in cold storage and one whose file is gone
+			handler := setupTestHandler(t)
+			handler.ServerConfig.BackupPath = t.TempDir()
+			handler.ServerConfig.TempPath = t.TempDir()
+			handler.Echo.POST("/api/admin/export", handler.ExportArchive)
+			handler.Echo.GET("/api/admin/export/:jobId/download", handler.DownloadExport)
+			gas := filepath.Join(handler.ServerConfig.DocumentPath, "bills", "gas.txt")
+			if err := os.MkdirAll(filepath.Dir(gas), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(gas, []byte("Quarterly gas bill"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			gasDoc := saveTestDocument(t, handler.DB, gas, "Quarterly gas bill")
+			if err := handler.DB.AddTag(gasDoc.ULID.String(), "utilities"); err != nil {
+				t.Fatal(err)
+			}
+			lease := filepath.Join(handler.ServerConfig.DocumentPath, "lease.txt")
+			if err := os.WriteFile(lease, []byte("Tenancy agreement"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			leaseDoc := saveTestDocument(t, handler.DB, lease, "")
+			if _, err := handler.archiveDocument(*leaseDoc); err != nil {
+				t.Fatalf("Failed to archive: %v", err)
+			}
+			saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "gone.pdf"), "")
+
+			// An export is started and downloaded once it completes
+			rec := httptest.NewRecorder()
+			handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/admin/export?format="+format, nil))
+			var started map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil || rec.Code != http.StatusOK {
+				t.Fatalf("Expected 200 with a job, got %d %s", rec.Code, rec.Body)
+			}
+			job := waitForTestJob(t, handler.DB, started["jobId"])
+			rec = httptest.NewRecorder()
+			handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, started["download"], nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected the export to download, got %d %s (job %+v)", rec.Code, rec.Body, job)
+			}
+
+			// It holds the files, decompressed from cold storage, each with its metadata
+			entries := readExport(t, rec.Body.Bytes(), format)
+			if string(entries["documents/bills/gas.txt"]) != "Quarterly gas bill" || string(entries["documents/lease.txt"]) != "Tenancy agreement" {
+				t.Errorf("Expected both files in the export, got entries %v", len(entries))
+			}
+			var sidecar exportSidecar
+			if err := json.Unmarshal(entries["documents/bills/gas.txt.json"], &sidecar); err != nil {
+				t.Fatalf("Unreadable sidecar: %v", err)
+			}
+			if sidecar.ULID != gasDoc.ULID || sidecar.FullText != "Quarterly gas bill" || len(sidecar.Tags) != 1 || sidecar.Tags[0] != "utilities" {
+				t.Errorf("Unexpected sidecar %+v", sidecar)
+			}
+
+			// The manifest lists all three, the missing file with its metadata only
+			var manifest exportManifest
+			if err := json.Unmarshal(entries[exportManifestName], &manifest); err != nil {
+				t.Fatalf("Unreadable manifest: %v", err)
+			}
+			if len(manifest.Documents) != 3 || manifest.Missing != 1 {
+				t.Fatalf("Expected 3 documents with 1 missing, got %+v", manifest)
+			}
+			for _, entry := range manifest.Documents {
+				if _, ok := entries[entry.Metadata]; !ok {
+					t.Errorf("Manifest lists %s, which is not in the export", entry.Metadata)
+				}
+				if entry.File != "" && string(entries[entry.File]) == "" {
+					t.Errorf("Manifest lists %s, which is not in the export", entry.File)
+				}
+			}
+		})
+	}
+}
+
+func TestExportArchiveRefusals(t *testing.T) {
+	// A server whose administrators are listed
+	handler := setupTestHandler(t)
+	handler.ServerConfig.AdminUsers = []string{"alex"}
+	handler.Echo.POST("/api/admin/export", handler.ExportArchive)
+	handler.Echo.GET("/api/admin/export/:jobId/download", handler.DownloadExport)
+
+	// Anyone else may not export
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/admin/export", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a user who is not an administrator, got %d", rec.Code)
+	}
+
+	// With sign-in and no ADMIN_USERS, a viewer may neither export nor download
+	handler.ServerConfig.AdminUsers = nil
+	handler.ServerConfig.WebUIPass = true
+	carol := signInAs(t, handler, "carol", database.RoleViewer)
+	handler.Echo.Use(handler.RequireLogin())
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/admin/export", nil),
+		httptest.NewRequest(http.MethodGet, "/api/admin/export/"+database.MakeULID().String()+"/download", nil),
+	} {
+		req.Header.Set("Authorization", "Bearer "+carol)
+		rec = httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for a viewer at %s, got %d", req.URL.Path, rec.Code)
+		}
+	}
+
+	// An unknown format and another job's ID are refused
+	handler.ServerConfig.WebUIPass = false
+	rec = httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/admin/export?format=rar", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown format, got %d", rec.Code)
+	}
+	other, err := handler.DB.CreateJob("rehash", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/export/"+other.ID.String()+"/download", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a job that is not an export, got %d", rec.Code)
+	}
+}
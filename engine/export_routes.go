@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// exportBatchSize is how many documents are read from the database per export batch
+const exportBatchSize = 200
+
+// exportDocument is a document as written by the NDJSON export; FullText is omitted unless requested
+type exportDocument struct {
+	database.Document
+	FullText string `json:",omitempty"`
+}
+
+// ExportDocumentsNDJSON streams every document's metadata as newline-delimited JSON
+// @Summary Export all document metadata
+// @Description Stream every document as one JSON object per line (newest first). Full text is left out unless fullText=true.
+// @Description Accounts that are not administrators only get the documents their folder permissions allow.
+// @Description Documents are read in batches and flushed as they are written, so slow clients slow the export rather than growing memory.
+// @Tags Documents
+// @Produce application/x-ndjson
+// @Param fullText query bool false "Include extracted full text (default: false)"
+// @Success 200 {string} string "One JSON document per line"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Router /documents/export.ndjson [get]
+func (serverHandler *ServerHandler) ExportDocumentsNDJSON(c echo.Context) error {
+	includeFullText, err := fullTextParam(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid fullText value, expected true or false",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		return err
+	}
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	response.Header().Set(echo.HeaderContentDisposition, `attachment; filename="documents.ndjson"`)
+	response.WriteHeader(http.StatusOK)
+
+	exported, err := serverHandler.writeDocumentsNDJSON(c.Request().Context(), response, response.Flush, access, includeFullText)
+	switch {
+	case errors.Is(err, context.Canceled):
+		Logger.Info("Document export cancelled by client", "exported", exported)
+	case err != nil:
+		// Headers are already sent, so all we can do is stop and log
+		Logger.Error("Document export failed", "exported", exported, "error", err)
+	default:
+		Logger.Info("Document export completed", "exported", exported, "fullText", includeFullText)
+	}
+	return nil
+}
+
+// writeDocumentsNDJSON writes every document the account may read to w as one JSON object per line, newest
+// first, calling flush after each batch; a nil access writes them all. It returns how many documents were
+// written, and stops early when ctx is cancelled.
+func (serverHandler *ServerHandler) writeDocumentsNDJSON(ctx context.Context, w io.Writer, flush func(), access *folderAccess, includeFullText bool) (int, error) {
+	encoder := json.NewEncoder(w)
+	exported := 0
+	var cursor *database.DocumentCursor
+	for {
+		if err := ctx.Err(); err != nil {
+			return exported, err
+		}
+		documents, err := serverHandler.DB.GetNewestDocumentsAfter(cursor, exportBatchSize)
+		if err != nil {
+			return exported, fmt.Errorf("unable to read documents: %w", err)
+		}
+		for _, doc := range access.readable(documents) {
+			record := exportDocument{Document: doc}
+			if includeFullText {
+				// Batches are read without the text column, so fetch it one document at a time
+				if record.FullText, err = serverHandler.DB.GetDocumentText(doc.ULID.String()); err != nil {
+					return exported, fmt.Errorf("unable to read full text of %s: %w", doc.ULID.String(), err)
+				}
+			}
+			if err := encoder.Encode(record); err != nil {
+				return exported, fmt.Errorf("unable to write document: %w", err)
+			}
+			exported++
+		}
+		flush()
+		if len(documents) < exportBatchSize {
+			return exported, nil
+		}
+		cursor = database.CursorAfter(documents[len(documents)-1])
+	}
+}
+
+// csvHeader lists the columns written by CSV exports of document lists
+var csvHeader = []string{"name", "folder", "date", "size", "tags", "url"}
+
+// wantsCSV reports whether the request asked for format=csv
+func wantsCSV(c echo.Context) bool {
+	return strings.EqualFold(c.QueryParam("format"), "csv")
+}
+
+// writeDocumentsCSV writes documents as a CSV attachment with one row per document, its tags separated
+// by semicolons
+func (serverHandler *ServerHandler) writeDocumentsCSV(c echo.Context, filename string, documents []database.Document) error {
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	response.WriteHeader(http.StatusOK)
+
+	baseURL := serverHandler.documentBaseURL(c)
+	writer := csv.NewWriter(response)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, doc := range documents {
+		size := ""
+		if info, err := os.Stat(doc.Path); err == nil {
+			size = strconv.FormatInt(info.Size(), 10)
+		}
+		tags, err := serverHandler.DB.GetDocumentTags(doc.ULID.String())
+		if err != nil {
+			Logger.Error("Failed to get document tags for export", "ulid", doc.ULID.String(), "error", err)
+		}
+		record := []string{
+			csvSafe(doc.Name),
+			csvSafe(serverHandler.relativeFolder(doc.Folder)),
+			doc.IngressTime.Format("2006-01-02 15:04:05"),
+			size,
+			csvSafe(strings.Join(tags, ";")),
+			csvSafe(baseURL + doc.URL),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// documentBaseURL is the scheme and host prefixed to document URLs in exports
+func (serverHandler *ServerHandler) documentBaseURL(c echo.Context) string {
+	if serverHandler.ServerConfig.UseReverseProxy {
+		return strings.TrimSuffix(serverHandler.ServerConfig.BaseURL, "/")
+	}
+	return c.Scheme() + "://" + c.Request().Host
+}
+
+// csvSafe stops spreadsheet applications from treating a cell as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
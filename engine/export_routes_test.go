@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+)
+
+func TestExportDocumentsNDJSON(t *testing.T) {
+	// More documents than fit in one export batch
+	handler := setupTestHandler(t)
+	total := exportBatchSize + 5
+	for i := 0; i < total; i++ {
+		saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, fmt.Sprintf("doc%03d.pdf", i)), "secret words")
+	}
+
+	export := func(target string) []map[string]interface{} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		if err := handler.ExportDocumentsNDJSON(handler.Echo.NewContext(req, rec)); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("Expected NDJSON content type, got %q", ct)
+		}
+		var lines []map[string]interface{}
+		scanner := bufio.NewScanner(strings.NewReader(rec.Body.String()))
+		for scanner.Scan() {
+			var line map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatalf("Line is not JSON: %v (%s)", err, scanner.Text())
+			}
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	// Exporting without full text
+	lines := export("/api/documents/export.ndjson")
+
+	// Every document is written once, without its text
+	if len(lines) != total {
+		t.Fatalf("Expected %d lines, got %d", total, len(lines))
+	}
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		if _, ok := line["FullText"]; ok {
+			t.Fatal("Expected full text to be omitted by default")
+		}
+		seen[line["Name"].(string)] = true
+	}
+	if len(seen) != total {
+		t.Errorf("Expected %d distinct documents, got %d", total, len(seen))
+	}
+
+	// Full text is included when requested
+	lines = export("/api/documents/export.ndjson?fullText=true")
+	if lines[0]["FullText"] != "secret words" {
+		t.Errorf("Expected full text in export, got %v", lines[0]["FullText"])
+	}
+}
+
+func TestGetFolderAsCSV(t *testing.T) {
+	// A tagged document whose name would be read as a formula, with its file on disk
+	handler := setupTestHandler(t)
+	path := filepath.Join(handler.ServerConfig.DocumentPath, "=SUM(A1).pdf")
+	if err := os.WriteFile(path, []byte("12345"), 0644); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	doc := saveTestDocument(t, handler.DB, path, "")
+	if _, err := database.UpdateDocumentField(doc.ULID.String(), "URL", "/document/view/"+doc.ULID.String(), handler.DB); err != nil {
+		t.Fatalf("Failed to set URL: %v", err)
+	}
+	handler.DB.AddTag(doc.ULID.String(), "tax")
+	handler.DB.AddTag(doc.ULID.String(), "2023")
+
+	// The folder is requested as CSV
+	req := httptest.NewRequest(http.MethodGet, "/api/folder/x?format=csv", nil)
+	rec := httptest.NewRecorder()
+	c := handler.Echo.NewContext(req, rec)
+	c.SetParamNames("folder")
+	c.SetParamValues(doc.Folder)
+	if err := handler.GetFolder(c); err != nil {
+		t.Fatalf("GetFolder failed: %v", err)
+	}
+
+	// A header and an escaped row are returned
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Expected CSV content type, got %q", ct)
+	}
+	records, err := csv.NewReader(strings.NewReader(rec.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("Response is not valid CSV: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected header and one row, got %v", records)
+	}
+	row := records[1]
+	if row[0] != "'=SUM(A1).pdf" || row[1] != "/" || row[3] != "5" || row[4] != "2023;tax" || row[5] != "http://example.com/document/view/"+doc.ULID.String() {
+		t.Errorf("Unexpected CSV row: %q", row)
+	}
+}
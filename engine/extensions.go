@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/drummonds/godocs/config"
+)
+
+// errUnsupportedFileType is returned for a file whose extension is not in PROCESSABLE_EXTENSIONS
+var errUnsupportedFileType = errors.New("unsupported file type")
+
+// processableExtensions returns the configured file extensions, or the defaults for a handler built without them
+func (serverHandler *ServerHandler) processableExtensions() []string {
+	if len(serverHandler.ServerConfig.IngestExtensions) == 0 {
+		return config.DefaultProcessableExtensions
+	}
+	return serverHandler.ServerConfig.IngestExtensions
+}
+
+// isProcessableDocument checks if a file is a document type that can be processed. Ingestion, uploads,
+// the dropzone, remote sources and the orphan scan all ask here so they agree on what is accepted.
+func (serverHandler *ServerHandler) isProcessableDocument(path string) bool {
+	return slices.Contains(serverHandler.processableExtensions(), strings.ToLower(filepath.Ext(path)))
+}
+
+// checkProcessable returns errUnsupportedFileType when path is not a processable document
+func (serverHandler *ServerHandler) checkProcessable(path string) error {
+	if serverHandler.isProcessableDocument(path) {
+		return nil
+	}
+	ext := filepath.Ext(path)
+	if ext == "" {
+		ext = "(none)"
+	}
+	return fmt.Errorf("%w: %s, accepted types are %s", errUnsupportedFileType, ext, strings.Join(serverHandler.processableExtensions(), " "))
+}
+
+// checkIngestible is checkProcessable for a file arriving to be ingested. With a pre-ingestion transform
+// configured any file is accepted, since the transform may turn it into a processable document; what it
+// leaves is checked then.
+func (serverHandler *ServerHandler) checkIngestible(path string) error {
+	if serverHandler.transformConfigured() {
+		return nil
+	}
+	return serverHandler.checkProcessable(path)
+}
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProcessableExtensionsAreShared(t *testing.T) {
+	// A store configured to take only text and markdown files
+	handler := setupTestHandler(t)
+	handler.ServerConfig.IngestExtensions = []string{".txt", ".md"}
+	upload := func(fileName string, fields map[string]string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := uploadRequest(t, fileName, "wombat "+fileName, fields)
+		if err := handler.UploadDocuments(handler.Echo.NewContext(req, rec)); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		return rec
+	}
+
+	// Files are uploaded to a folder and to ingress
+	// The configured types are accepted, whatever their case, and the default ones are not
+	if rec := upload("NOTES.MD", map[string]string{"folder": "notes"}); rec.Code != http.StatusOK {
+		t.Errorf("Expected a configured type to be stored, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := upload("scan.pdf", map[string]string{"folder": "notes"}); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for a type that is no longer configured, got %d", rec.Code)
+	}
+	if rec := upload("setup.exe", nil); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for an ingress upload, got %d", rec.Code)
+	}
+	if entries, _ := os.ReadDir(handler.ServerConfig.IngressPath); len(entries) != 0 {
+		t.Errorf("Expected refused uploads to leave ingress empty, found %d entries", len(entries))
+	}
+
+	// The orphan scan walks files that are not in the database
+	for _, name := range []string{"lost.md", "lost.pdf"} {
+		if err := os.WriteFile(filepath.Join(handler.ServerConfig.DocumentPath, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	documents, err := handler.DB.GetAllDocuments()
+	if err != nil {
+		t.Fatalf("Failed to list documents: %v", err)
+	}
+	orphans, err := handler.findOrphanedDocuments(documents)
+
+	// Only the configured type is reported
+	if err != nil || len(orphans) != 1 || filepath.Base(orphans[0]) != "lost.md" {
+		t.Errorf("Expected only lost.md to be an orphan, got %v, %v", orphans, err)
+	}
+}
+
+func TestProcessableExtensionsDefault(t *testing.T) {
+	// A handler built without any configured extensions
+	handler := &ServerHandler{}
+
+	// The built in list applies
+	for path, want := range map[string]bool{"a/scan.PDF": true, "photo.jpeg": true, "sheet.xlsx": false, "README": false} {
+		if got := handler.isProcessableDocument(path); got != want {
+			t.Errorf("isProcessableDocument(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
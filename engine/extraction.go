@@ -0,0 +1,474 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	// maxExtractionFields is the most fields one extraction template may read
+	maxExtractionFields = 50
+	// maxFieldValueLength is the longest value kept for a field, in bytes; a zone or pattern that
+	// takes in more than this has probably been drawn too large
+	maxFieldValueLength = 1000
+)
+
+// fieldNamePattern is what a field name may look like, so names are safe to use as keys and columns
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// templateRequest is the body of CreateExtractionTemplate, and of PreviewExtraction for a draft template
+type templateRequest struct {
+	Correspondent string                     `json:"correspondent"`
+	Match         string                     `json:"match"`
+	Fields        []database.ExtractionField `json:"fields"`
+}
+
+// compiledField is an extraction field with its pattern compiled
+type compiledField struct {
+	database.ExtractionField
+	pattern *regexp.Regexp // nil for a zone read whole
+}
+
+// compiledTemplate is an extraction template with its regular expressions compiled
+type compiledTemplate struct {
+	template database.ExtractionTemplate
+	match    *regexp.Regexp
+	fields   []compiledField
+}
+
+// compileTemplate checks a template and compiles its regular expressions, returning the problems with it
+// by field when it cannot be used
+func compileTemplate(template database.ExtractionTemplate) (*compiledTemplate, map[string]string) {
+	problems := make(map[string]string)
+	if strings.TrimSpace(template.Correspondent) == "" {
+		problems["correspondent"] = "A template needs the correspondent whose documents it reads"
+	}
+	compiled := &compiledTemplate{template: template}
+	var err error
+	if template.Match == "" {
+		problems["match"] = "A template needs a regular expression recognising the correspondent's documents"
+	} else if compiled.match, err = regexp.Compile(template.Match); err != nil {
+		problems["match"] = "Invalid regular expression: " + err.Error()
+	}
+	switch {
+	case len(template.Fields) == 0:
+		problems["fields"] = "A template needs at least one field to read"
+	case len(template.Fields) > maxExtractionFields:
+		problems["fields"] = fmt.Sprintf("A template may read at most %d fields", maxExtractionFields)
+	}
+	names := make(map[string]bool)
+	for i, field := range template.Fields {
+		prefix := fmt.Sprintf("fields[%d]", i)
+		switch {
+		case !fieldNamePattern.MatchString(field.Name):
+			problems[prefix+".name"] = "Expected lower case letters, digits and underscores, starting with a letter"
+		case names[field.Name]:
+			problems[prefix+".name"] = "Another field has this name"
+		}
+		names[field.Name] = true
+		if field.Pattern == "" && field.Zone == nil {
+			problems[prefix] = "A field needs a pattern, a zone, or both"
+		}
+		var pattern *regexp.Regexp
+		if field.Pattern != "" {
+			if pattern, err = regexp.Compile(field.Pattern); err != nil {
+				problems[prefix+".pattern"] = "Invalid regular expression: " + err.Error()
+			}
+		}
+		if zone := field.Zone; zone != nil && (zone.Page < 1 || zone.X < 0 || zone.Y < 0 || zone.Width <= 0 || zone.Height <= 0) {
+			problems[prefix+".zone"] = "Expected a page from 1, and a rectangle on it with a positive width and height"
+		}
+		compiled.fields = append(compiled.fields, compiledField{ExtractionField: field, pattern: pattern})
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return compiled, nil
+}
+
+// zoneTexts reads the text inside each field's zone from a PDF's text layer, keyed by field name. A PDF
+// without a text layer, such as a scan, has no text in any zone.
+func zoneTexts(path string, fields []compiledField) (texts map[string]string, err error) {
+	texts = make(map[string]string)
+	var zoned []compiledField
+	for _, field := range fields {
+		if field.Zone != nil {
+			zoned = append(zoned, field)
+		}
+	}
+	if len(zoned) == 0 || strings.ToLower(filepath.Ext(path)) != ".pdf" {
+		return texts, nil
+	}
+	pages, err := readPageGeometry(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if r := recover(); r != nil { // the reader panics on some malformed files
+			texts, err = nil, fmt.Errorf("unreadable PDF text: %v", r)
+		}
+	}()
+	file, reader, err := pdf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	for _, field := range zoned {
+		if field.Zone.Page > reader.NumPage() || field.Zone.Page > len(pages) {
+			continue
+		}
+		geometry := pages[field.Zone.Page-1]
+		zone := []database.RedactionRegion{*field.Zone}
+		texts[field.Name] = pageTextWhere(reader.Page(field.Zone.Page), geometry, func(piece pdf.Text) bool {
+			return geometry.covered(piece, zone)
+		})
+	}
+	return texts, nil
+}
+
+// extractFields reads a template's fields from a document with the given text and file. A field's value
+// is its pattern's first group, or all of its match, in the document's text or the text of its zone; a
+// zone without a pattern gives all of its text. Fields not found are left out. When the PDF's zones cannot
+// be read, the fields read from the text are returned with the error.
+func extractFields(compiled *compiledTemplate, documentULID, text, path string) ([]database.DocumentField, error) {
+	zones, zoneErr := zoneTexts(path, compiled.fields)
+	templateULID := compiled.template.ULID.String()
+	fields := []database.DocumentField{}
+	for _, field := range compiled.fields {
+		source := text
+		if field.Zone != nil {
+			source = zones[field.Name]
+		}
+		value := source
+		if field.pattern != nil {
+			match := field.pattern.FindStringSubmatch(source)
+			switch {
+			case match == nil:
+				continue
+			case len(match) > 1:
+				value = match[1]
+			default:
+				value = match[0]
+			}
+		}
+		value = strings.Join(strings.Fields(value), " ")
+		if value == "" {
+			continue
+		}
+		if len(value) > maxFieldValueLength {
+			value = strings.ToValidUTF8(value[:maxFieldValueLength], "")
+		}
+		fields = append(fields, database.DocumentField{DocumentULID: documentULID, Name: field.Name, Value: value, TemplateULID: templateULID})
+	}
+	return fields, zoneErr
+}
+
+// templateFor returns the first template, by correspondent, whose match recognises text, or nil. Stored
+// templates that no longer compile are logged and passed over.
+func templateFor(templates []database.ExtractionTemplate, text string) *compiledTemplate {
+	for _, template := range templates {
+		compiled, problems := compileTemplate(template)
+		if problems != nil {
+			Logger.Warn("Extraction template cannot be used", "ulid", template.ULID.String(), "correspondent", template.Correspondent, "problems", problems)
+			continue
+		}
+		if compiled.match.MatchString(text) {
+			return compiled
+		}
+	}
+	return nil
+}
+
+// documentCorrespondents returns the correspondent of every document whose fields an extraction template
+// read, by document ULID. A document has no correspondent of its own: it is the one named by the template
+// recorded against its fields, so documents no template recognised, or whose template was since deleted,
+// are left out.
+func documentCorrespondents(db database.Repository) (map[string]string, error) {
+	fields, err := db.ListDocumentFields()
+	if err != nil {
+		return nil, err
+	}
+	templates, err := db.ListExtractionTemplates()
+	if err != nil {
+		return nil, err
+	}
+	correspondents := make(map[string]string, len(templates))
+	for _, template := range templates {
+		correspondents[template.ULID.String()] = template.Correspondent
+	}
+	byDocument := make(map[string]string)
+	for _, field := range fields {
+		if correspondent := correspondents[field.TemplateULID]; correspondent != "" && byDocument[field.DocumentULID] == "" {
+			byDocument[field.DocumentULID] = correspondent
+		}
+	}
+	return byDocument, nil
+}
+
+// recordDocumentFields reads structured fields from a newly ingested document with the template for its
+// correspondent. Like the activity feed, failing to read them is logged rather than failing the ingestion.
+func (serverHandler *ServerHandler) recordDocumentFields(doc *database.Document) {
+	if doc.FullText == "" {
+		return
+	}
+	templates, err := serverHandler.DB.ListExtractionTemplates()
+	if err != nil {
+		Logger.Warn("Unable to list extraction templates", "error", err)
+		return
+	}
+	compiled := templateFor(templates, doc.FullText)
+	if compiled == nil {
+		return
+	}
+	documentULID := doc.ULID.String()
+	fields, err := extractFields(compiled, documentULID, doc.FullText, doc.Path)
+	if err != nil {
+		Logger.Warn("Unable to read zones of document, reading its text only", "ulid", documentULID, "path", doc.Path, "error", err)
+	}
+	if err := serverHandler.DB.SaveDocumentFields(documentULID, fields); err != nil {
+		Logger.Warn("Unable to record document fields", "ulid", documentULID, "error", err)
+		return
+	}
+	Logger.Info("Read document fields", "ulid", documentULID, "correspondent", compiled.template.Correspondent, "fields", len(fields))
+}
+
+// templateNotFound sends the 404 for an extraction template that does not exist
+func templateNotFound(c echo.Context) error {
+	return c.JSON(http.StatusNotFound, map[string]interface{}{
+		"error": "Extraction template not found",
+		"code":  dto.CodeNotFound,
+	})
+}
+
+// CreateExtractionTemplate saves a template reading structured fields from a correspondent's documents
+// @Summary Create an extraction template
+// @Description Save how to read fields such as an invoice number, total or IBAN from the documents of one correspondent. Documents whose text matches the match regular expression have each field read at ingestion: the first group of its pattern, or all of its match, in the document's text, or in the text inside its zone on a PDF page (points from the top left, as for redaction); a zone without a pattern gives all of its text.
+// @Description When several templates match a document, the first by correspondent is used.
+// @Tags Extraction
+// @Accept json
+// @Produce json
+// @Param template body templateRequest true "correspondent, match and fields"
+// @Success 201 {object} database.ExtractionTemplate "The template"
+// @Failure 400 {object} map[string]interface{} "Problems by field"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /extraction/templates [post]
+func (serverHandler *ServerHandler) CreateExtractionTemplate(c echo.Context) error {
+	var request templateRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	template := database.ExtractionTemplate{Correspondent: strings.TrimSpace(request.Correspondent), Match: request.Match, Fields: request.Fields}
+	if _, problems := compileTemplate(template); problems != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "The extraction template needs fixing",
+			"code":   dto.CodeValidation,
+			"fields": problems,
+		})
+	}
+	if err := serverHandler.DB.CreateExtractionTemplate(&template); err != nil {
+		Logger.Error("Failed to create extraction template", "correspondent", template.Correspondent, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to create extraction template",
+			"code":  dto.CodeInternal,
+		})
+	}
+	Logger.Info("Created extraction template", "ulid", template.ULID.String(), "correspondent", template.Correspondent, "fields", len(template.Fields))
+	return c.JSON(http.StatusCreated, template)
+}
+
+// ListExtractionTemplates lists the extraction templates
+// @Summary List extraction templates
+// @Description All extraction templates by correspondent
+// @Tags Extraction
+// @Produce json
+// @Success 200 {object} map[string]interface{} "templates and count"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /extraction/templates [get]
+func (serverHandler *ServerHandler) ListExtractionTemplates(c echo.Context) error {
+	templates, err := serverHandler.DB.ListExtractionTemplates()
+	if err != nil {
+		Logger.Error("Failed to list extraction templates", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve extraction templates",
+			"code":  dto.CodeInternal,
+		})
+	}
+	if templates == nil {
+		templates = []database.ExtractionTemplate{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"templates": templates,
+		"count":     len(templates),
+	})
+}
+
+// DeleteExtractionTemplate removes an extraction template
+// @Summary Delete an extraction template
+// @Description Stop reading fields with a template. Fields it has already read are kept.
+// @Tags Extraction
+// @Produce json
+// @Param id path string true "Template ULID"
+// @Success 204 "Deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID"
+// @Failure 404 {object} map[string]interface{} "Template not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /extraction/templates/{id} [delete]
+func (serverHandler *ServerHandler) DeleteExtractionTemplate(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "template")
+	if !ok {
+		return err
+	}
+	err = serverHandler.DB.DeleteExtractionTemplate(id.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return templateNotFound(c)
+	}
+	if err != nil {
+		Logger.Error("Failed to delete extraction template", "ulid", id.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to delete extraction template",
+			"code":  dto.CodeInternal,
+		})
+	}
+	Logger.Info("Deleted extraction template", "ulid", id.String())
+	return c.NoContent(http.StatusNoContent)
+}
+
+// previewRequest is the body of PreviewExtraction: a saved template's ID, or a draft template
+type previewRequest struct {
+	DocumentID string `json:"documentId"`
+	TemplateID string `json:"templateId"`
+	templateRequest
+}
+
+// PreviewExtraction runs a template against a stored document without saving what it reads
+// @Summary Preview an extraction template
+// @Description Run a saved template (templateId) or a draft one (correspondent, match and fields) against a document, to see what it would read before relying on it. matched says whether the template's match recognises the document; the fields are read either way, so a draft can be tried on a document of the correspondent it does not recognise yet. Nothing is saved.
+// @Tags Extraction
+// @Accept json
+// @Produce json
+// @Param preview body previewRequest true "documentId, and templateId or a draft template"
+// @Success 200 {object} map[string]interface{} "correspondent, matched, and the fields found, with zoneError when the PDF's zones could not be read"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID or problems with the draft template by field"
+// @Failure 404 {object} map[string]interface{} "Document or template not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /extraction/preview [post]
+func (serverHandler *ServerHandler) PreviewExtraction(c echo.Context) error {
+	var request previewRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	documentID, err := parseULID(request.DocumentID)
+	if err != nil {
+		return invalidID(c, "document")
+	}
+	template := database.ExtractionTemplate{Correspondent: strings.TrimSpace(request.Correspondent), Match: request.Match, Fields: request.Fields}
+	if request.TemplateID != "" {
+		templateID, err := parseULID(request.TemplateID)
+		if err != nil {
+			return invalidID(c, "template")
+		}
+		saved, err := serverHandler.DB.GetExtractionTemplate(templateID.String())
+		if errors.Is(err, sql.ErrNoRows) {
+			return templateNotFound(c)
+		}
+		if err != nil {
+			Logger.Error("Failed to get extraction template", "ulid", templateID.String(), "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to retrieve extraction template",
+				"code":  dto.CodeInternal,
+			})
+		}
+		template = *saved
+	}
+	compiled, problems := compileTemplate(template)
+	if problems != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "The extraction template needs fixing",
+			"code":   dto.CodeValidation,
+			"fields": problems,
+		})
+	}
+
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		return err
+	}
+	document, err := serverHandler.DB.GetDocumentByULID(documentID.String())
+	if err != nil || document == nil || !access.canRead(document.Folder) {
+		return documentNotFound(c)
+	}
+	text, err := serverHandler.DB.GetDocumentText(documentID.String())
+	if err != nil {
+		Logger.Error("Failed to get document text", "ulid", documentID.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve document text",
+			"code":  dto.CodeInternal,
+		})
+	}
+	response := map[string]interface{}{
+		"correspondent": compiled.template.Correspondent,
+		"matched":       compiled.match.MatchString(text),
+	}
+	fields, err := extractFields(compiled, documentID.String(), text, document.Path)
+	if err != nil {
+		response["zoneError"] = err.Error()
+	}
+	response["fields"] = fields
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetDocumentFields returns the structured fields read from a document
+// @Summary Get document fields
+// @Description The fields extraction templates read from a document at ingestion, by name, each with the template that read it
+// @Tags Extraction
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Success 200 {object} map[string]interface{} "fields and count"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID"
+// @Failure 404 {object} map[string]interface{} "Document not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /documents/{id}/fields [get]
+func (serverHandler *ServerHandler) GetDocumentFields(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "document")
+	if !ok {
+		return err
+	}
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		return err
+	}
+	if document, err := serverHandler.DB.GetDocumentByULID(id.String()); err != nil || document == nil || !access.canRead(document.Folder) {
+		return documentNotFound(c)
+	}
+	fields, err := serverHandler.DB.GetDocumentFields(id.String())
+	if err != nil {
+		Logger.Error("Failed to get document fields", "ulid", id.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve document fields",
+			"code":  dto.CodeInternal,
+		})
+	}
+	if fields == nil {
+		fields = []database.DocumentField{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"fields": fields,
+		"count":  len(fields),
+	})
+}
@@ -0,0 +1,287 @@
+package engine
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// The field names an extraction template uses for an invoice's date, amount, reference and vendor; the
+// first of each that a document has is exported. Without a vendor field, the template's correspondent is
+// the vendor.
+var (
+	invoiceDateFields      = []string{"date", "invoice_date", "tax_point"}
+	invoiceAmountFields    = []string{"total", "amount", "amount_due"}
+	invoiceReferenceFields = []string{"invoice_number", "reference"}
+	invoiceVendorFields    = []string{"vendor", "supplier"}
+)
+
+// invoiceDateLayouts are the dates read from invoices, British style: a date with slashes is day first
+var invoiceDateLayouts = []string{
+	"2006-01-02", "02/01/2006", "2/1/2006", "02.01.2006", "02-01-2006",
+	"2 January 2006", "2 Jan 2006", "January 2, 2006", "Jan 2, 2006", "January 2 2006", "Jan 2 2006",
+}
+
+// ordinalSuffix matches the "rd" of "3rd March 2024"
+var ordinalSuffix = regexp.MustCompile(`(\d)(st|nd|rd|th)\b`)
+
+// extractionCSVHeader lists the columns of a CSV export of extracted invoices
+var extractionCSVHeader = []string{"date", "vendor", "amount", "reference", "document", "url"}
+
+// extractedInvoice is one document's extracted fields as exported for bookkeeping
+type extractedInvoice struct {
+	date      time.Time
+	vendor    string
+	amount    string // with two decimal places and a leading minus for a credit; empty when none was read
+	reference string
+	document  database.Document
+}
+
+// parseInvoiceDate reads a date written on an invoice
+func parseInvoiceDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(ordinalSuffix.ReplaceAllString(value, "$1"))
+	for _, layout := range invoiceDateLayouts {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseInvoiceAmount reads an amount written on an invoice, such as "£1,234.50", "1.234,50 EUR" or
+// "(12.00)", giving it with two decimal places. Whichever of a comma or point comes last, with one or two
+// digits after it, is the decimal separator; any others separate thousands.
+func parseInvoiceAmount(value string) (string, bool) {
+	negative := strings.Contains(value, "-") || (strings.Contains(value, "(") && strings.Contains(value, ")"))
+	var number strings.Builder
+	for _, r := range value {
+		if (r >= '0' && r <= '9') || r == ',' || r == '.' {
+			number.WriteRune(r)
+		}
+	}
+	digits := strings.Trim(number.String(), ",.")
+	if digits == "" {
+		return "", false
+	}
+	whole, fraction := digits, ""
+	if separator := strings.LastIndexAny(digits, ",."); separator >= 0 && len(digits)-separator-1 <= 2 {
+		whole, fraction = digits[:separator], digits[separator+1:]
+	}
+	whole = strings.TrimLeft(strings.NewReplacer(",", "", ".", "").Replace(whole), "0")
+	if whole == "" {
+		whole = "0"
+	}
+	amount := whole + "." + (fraction + "00")[:2]
+	if negative && amount != "0.00" {
+		amount = "-" + amount
+	}
+	return amount, true
+}
+
+// firstField returns the value of the first of names that fields has
+func firstField(fields map[string]string, names []string) string {
+	for _, name := range names {
+		if value := fields[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// extractedInvoices returns the documents with extracted fields dated in the range, by date. A document
+// is dated by its extracted date, or the day it was ingested when none could be read, and only those the
+// account may read are included.
+func (serverHandler *ServerHandler) extractedInvoices(dates smartFolderRange, access *folderAccess) ([]extractedInvoice, error) {
+	fields, err := serverHandler.DB.ListDocumentFields()
+	if err != nil {
+		return nil, err
+	}
+	templates, err := serverHandler.DB.ListExtractionTemplates()
+	if err != nil {
+		return nil, err
+	}
+	correspondents := make(map[string]string, len(templates))
+	for _, template := range templates {
+		correspondents[template.ULID.String()] = template.Correspondent
+	}
+
+	invoices := []extractedInvoice{}
+	for start := 0; start < len(fields); {
+		end := start
+		byName := make(map[string]string)
+		correspondent := ""
+		for ; end < len(fields) && fields[end].DocumentULID == fields[start].DocumentULID; end++ {
+			byName[fields[end].Name] = fields[end].Value
+			if correspondent == "" {
+				correspondent = correspondents[fields[end].TemplateULID]
+			}
+		}
+		documentULID := fields[start].DocumentULID
+		start = end
+
+		document, err := serverHandler.DB.GetDocumentByULID(documentULID)
+		if err != nil || document == nil || !access.canRead(document.Folder) {
+			continue // deleted since its fields were read, or not the account's to see
+		}
+		invoice := extractedInvoice{
+			date:      document.IngressTime.In(time.Local),
+			vendor:    firstField(byName, invoiceVendorFields),
+			reference: firstField(byName, invoiceReferenceFields),
+			document:  *document,
+		}
+		if invoice.vendor == "" {
+			invoice.vendor = correspondent
+		}
+		if date, ok := parseInvoiceDate(firstField(byName, invoiceDateFields)); ok {
+			invoice.date = date
+		}
+		if amount, ok := parseInvoiceAmount(firstField(byName, invoiceAmountFields)); ok {
+			invoice.amount = amount
+		}
+		if dates.contains(invoice.date) {
+			invoices = append(invoices, invoice)
+		}
+	}
+	sort.SliceStable(invoices, func(i, j int) bool { return invoices[i].date.Before(invoices[j].date) })
+	return invoices, nil
+}
+
+// writeExtractionCSV writes invoices as CSV, one row each
+func writeExtractionCSV(w io.Writer, baseURL string, invoices []extractedInvoice) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(extractionCSVHeader); err != nil {
+		return err
+	}
+	for _, invoice := range invoices {
+		record := []string{
+			invoice.date.Format("2006-01-02"),
+			csvSafe(invoice.vendor),
+			invoice.amount,
+			csvSafe(invoice.reference),
+			csvSafe(invoice.document.Name),
+			csvSafe(baseURL + invoice.document.URL),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// qifValue keeps a value on the one line a QIF field has
+func qifValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// writeExtractionQIF writes invoices as QIF bank transactions, each amount paid out, for accounting
+// packages to import. Dates are MM/DD/YYYY, which QIF readers expect whatever the locale; invoices
+// without an amount cannot be transactions, so they are left out.
+func writeExtractionQIF(w io.Writer, invoices []extractedInvoice) error {
+	if _, err := io.WriteString(w, "!Type:Bank\n"); err != nil {
+		return err
+	}
+	for _, invoice := range invoices {
+		if invoice.amount == "" {
+			continue
+		}
+		amount := "-" + invoice.amount
+		if strings.HasPrefix(invoice.amount, "-") {
+			amount = invoice.amount[1:] // a credit note is money in
+		}
+		var entry strings.Builder
+		fmt.Fprintf(&entry, "D%s\nT%s\n", invoice.date.Format("01/02/2006"), amount)
+		if invoice.vendor != "" {
+			fmt.Fprintf(&entry, "P%s\n", qifValue(invoice.vendor))
+		}
+		if invoice.reference != "" {
+			fmt.Fprintf(&entry, "N%s\n", qifValue(invoice.reference))
+		}
+		fmt.Fprintf(&entry, "M%s\n^\n", qifValue(invoice.document.Name))
+		if _, err := io.WriteString(w, entry.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportExtractions exports the invoice fields read by extraction templates for bookkeeping
+// @Summary Export extracted invoices
+// @Description Download the date, vendor, amount and reference read from documents by extraction templates, by date, for importing into bookkeeping tools. Fields are taken by name: date, invoice_date or tax_point; total, amount or amount_due; invoice_number or reference; and vendor or supplier, falling back to the template's correspondent. A document without a readable date is dated by its ingestion.
+// @Description csv has a row per document; qif has a bank transaction paying out each amount, and leaves out documents without one.
+// @Tags Extraction
+// @Produce text/csv
+// @Produce application/x-qif
+// @Param from query string false "First day, YYYY-MM-DD, inclusive"
+// @Param to query string false "Last day, YYYY-MM-DD, inclusive"
+// @Param format query string false "csv (default) or qif"
+// @Success 200 {file} file "The export as an attachment"
+// @Failure 400 {object} map[string]interface{} "Invalid dates or format"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /extractions/export [get]
+func (serverHandler *ServerHandler) ExportExtractions(c echo.Context) error {
+	format := strings.ToLower(c.QueryParam("format"))
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "qif" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid format, expected csv or qif",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	from, to := strings.TrimSpace(c.QueryParam("from")), strings.TrimSpace(c.QueryParam("to"))
+	dates, err := parseSmartFolderRange(from, to)
+	if err != nil || (from != "" && to != "" && to < from) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid from or to, expected dates as YYYY-MM-DD with to not before from",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		Logger.Error("Failed to load folder permissions", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to load folder permissions",
+			"code":  dto.CodeInternal,
+		})
+	}
+	invoices, err := serverHandler.extractedInvoices(dates, access)
+	if err != nil {
+		Logger.Error("Failed to collect extracted invoices", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to collect extracted invoices",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	response := c.Response()
+	contentType := "text/csv; charset=utf-8"
+	if format == "qif" {
+		contentType = "application/x-qif"
+	}
+	response.Header().Set(echo.HeaderContentType, contentType)
+	response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="extractions.%s"`, format))
+	response.WriteHeader(http.StatusOK)
+	if format == "qif" {
+		err = writeExtractionQIF(response, invoices)
+	} else {
+		err = writeExtractionCSV(response, serverHandler.documentBaseURL(c), invoices)
+	}
+	if err != nil {
+		// Headers are already sent, so all we can do is stop and log
+		Logger.Error("Extraction export failed", "format", format, "error", err)
+		return nil
+	}
+	Logger.Info("Exported extracted invoices", "format", format, "documents", len(invoices), "from", from, "to", to)
+	return nil
+}
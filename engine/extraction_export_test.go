@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+)
+
+func TestParseInvoiceAmount(t *testing.T) {
+	for value, want := range map[string]string{
+		"£1,234.50":    "1234.50",
+		"1.234,50 EUR": "1234.50",
+		"GBP 12.5":     "12.50",
+		"1,234":        "1234.00",
+		"0.99":         "0.99",
+		"(12.00)":      "-12.00",
+		"-40":          "-40.00",
+	} {
+		if got, ok := parseInvoiceAmount(value); !ok || got != want {
+			t.Errorf("Expected %q to read as %s, got %q", value, want, got)
+		}
+	}
+	if _, ok := parseInvoiceAmount("TBC"); ok {
+		t.Error("Expected no amount without digits")
+	}
+}
+
+func TestParseInvoiceDate(t *testing.T) {
+	want := time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local)
+	for _, value := range []string{"2024-03-04", "04/03/2024", "4/3/2024", "04.03.2024", "4th March 2024", "4 Mar 2024", "March 4, 2024"} {
+		if got, ok := parseInvoiceDate(value); !ok || !got.Equal(want) {
+			t.Errorf("Expected %q to read as 4 March 2024, got %v", value, got)
+		}
+	}
+	if _, ok := parseInvoiceDate("next Tuesday"); ok {
+		t.Error("Expected no date from words")
+	}
+}
+
+func TestExportExtractions(t *testing.T) {
+	// Invoices read by a template in March and April, a credit note with its own vendor, and a
+	// document whose amount could not be read
+	handler := setupTestHandler(t)
+	handler.Echo.GET("/api/extractions/export", handler.ExportExtractions)
+	template := &database.ExtractionTemplate{Correspondent: "Acme Energy", Match: "Acme",
+		Fields: []database.ExtractionField{{Name: "total", Pattern: `Total (\S+)`}}}
+	if err := handler.DB.CreateExtractionTemplate(template); err != nil {
+		t.Fatal(err)
+	}
+	invoices := []struct {
+		name   string
+		fields map[string]string
+	}{
+		{"april.pdf", map[string]string{"date": "2 April 2024", "total": "£1,050.00", "invoice_number": "INV-2"}},
+		{"march.pdf", map[string]string{"invoice_date": "15/03/2024", "amount": "99.5", "invoice_number": "INV-1"}},
+		{"credit.pdf", map[string]string{"date": "2024-03-20", "total": "-10.00", "vendor": "=Acme Refunds"}},
+		{"estimate.pdf", map[string]string{"date": "2024-03-25", "total": "TBC"}},
+	}
+	for _, invoice := range invoices {
+		doc := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, invoice.name), "")
+		var fields []database.DocumentField
+		for name, value := range invoice.fields {
+			fields = append(fields, database.DocumentField{Name: name, Value: value, TemplateULID: template.ULID.String()})
+		}
+		if err := handler.DB.SaveDocumentFields(doc.ULID.String(), fields); err != nil {
+			t.Fatal(err)
+		}
+	}
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	// March is exported as CSV
+	rec := serve("/api/extractions/export?from=2024-03-01&to=2024-03-31")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("Expected a CSV, got %d %s", rec.Code, rec.Body)
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Unreadable CSV: %v", err)
+	}
+
+	// March's three documents are listed by date, with vendors, amounts and references
+	want := [][]string{
+		{"2024-03-15", "Acme Energy", "99.50", "INV-1", "march.pdf"},
+		{"2024-03-20", "'=Acme Refunds", "-10.00", "", "credit.pdf"},
+		{"2024-03-25", "Acme Energy", "", "", "estimate.pdf"},
+	}
+	if len(rows) != len(want)+1 || strings.Join(rows[0], ",") != strings.Join(extractionCSVHeader, ",") {
+		t.Fatalf("Expected a header and %d rows, got %v", len(want), rows)
+	}
+	for i, row := range want {
+		if got := rows[i+1][:5]; strings.Join(got, "|") != strings.Join(row, "|") {
+			t.Errorf("Row %d: expected %v, got %v", i+1, row, got)
+		}
+	}
+
+	// As QIF, the invoices are payments and the credit note money in, without the estimate
+	rec = serve("/api/extractions/export?from=2024-03-01&format=qif")
+	wantQIF := "!Type:Bank\n" +
+		"D03/15/2024\nT-99.50\nPAcme Energy\nNINV-1\nMmarch.pdf\n^\n" +
+		"D03/20/2024\nT10.00\nP=Acme Refunds\nMcredit.pdf\n^\n" +
+		"D04/02/2024\nT-1050.00\nPAcme Energy\nNINV-2\nMapril.pdf\n^\n"
+	if rec.Code != http.StatusOK || rec.Body.String() != wantQIF {
+		t.Errorf("Expected QIF\n%s\ngot %d\n%s", wantQIF, rec.Code, rec.Body)
+	}
+
+	// An unknown format or a range ending before it starts is refused
+	for _, query := range []string{"format=ofx", "from=2024-04-01&to=2024-03-01", "from=March"} {
+		if rec := serve("/api/extractions/export?" + query); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, rec.Code)
+		}
+	}
+}
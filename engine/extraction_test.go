@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// invoiceText is the text layer of the invoice writeInvoicePDF draws
+const invoiceText = "Acme Energy Ltd\nInvoice INV-2024-0042\nTotal GBP 123.45\nPay to GB29 NWBK 6016 1331 9268 19"
+
+// writeInvoicePDF writes a one page A4 invoice, its IBAN on a line of its own near the bottom
+func writeInvoicePDF(t *testing.T, path string) {
+	t.Helper()
+	out := gofpdf.New("P", "pt", "A4", "")
+	out.AddPage()
+	out.SetFont("Helvetica", "", 12)
+	out.Text(72, 100, "Acme Energy Ltd")
+	out.Text(72, 130, "Invoice INV-2024-0042")
+	out.Text(72, 160, "Total GBP 123.45")
+	out.Text(72, 700, "Pay to")
+	out.Text(300, 700, "GB29 NWBK 6016 1331 9268 19")
+	if err := out.OutputFileAndClose(path); err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+}
+
+// acmeTemplate reads the invoice number and total by pattern, and the IBAN from its zone
+const acmeTemplate = `{"correspondent": "Acme Energy", "match": "(?i)acme energy ltd", "fields": [
+	{"name": "invoice_number", "pattern": "Invoice (\\S+)"},
+	{"name": "total", "pattern": "Total GBP ([\\d.,]+)"},
+	{"name": "iban", "zone": {"page": 1, "x": 290, "y": 685, "width": 260, "height": 22}},
+	{"name": "account_number", "pattern": "Account (\\d+)"}]}`
+
+func TestCompileTemplate(t *testing.T) {
+	// A template with something wrong in every part
+	template := database.ExtractionTemplate{
+		Match: "(unclosed",
+		Fields: []database.ExtractionField{
+			{Name: "Total", Pattern: `\d+`},
+			{Name: "iban", Zone: &database.RedactionRegion{Page: 0, Width: 10, Height: 10}},
+			{Name: "iban", Pattern: "[a-"},
+			{Name: "note"},
+		},
+	}
+
+	// Each problem is named by field
+	_, problems := compileTemplate(template)
+	for _, field := range []string{"correspondent", "match", "fields[0].name", "fields[1].zone", "fields[2].name", "fields[2].pattern", "fields[3]"} {
+		if problems[field] == "" {
+			t.Errorf("Expected a problem with %s, got %v", field, problems)
+		}
+	}
+
+	// A good template compiles
+	template = database.ExtractionTemplate{Correspondent: "Water Board", Match: "GB 123 4567 89",
+		Fields: []database.ExtractionField{{Name: "total", Pattern: `Total ([\d.]+)`}}}
+	if compiled, problems := compileTemplate(template); problems != nil || len(compiled.fields) != 1 {
+		t.Errorf("Expected the template to compile, got %v", problems)
+	}
+}
+
+func TestExtractionTemplates(t *testing.T) {
+	// A template for a correspondent's invoices, created through the API
+	handler := setupTestHandler(t)
+	handler.Echo.POST("/api/extraction/templates", handler.CreateExtractionTemplate)
+	handler.Echo.GET("/api/extraction/templates", handler.ListExtractionTemplates)
+	handler.Echo.POST("/api/extraction/preview", handler.PreviewExtraction)
+	handler.Echo.GET("/api/documents/:id/fields", handler.GetDocumentFields)
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := serve(http.MethodPost, "/api/extraction/templates", acmeTemplate)
+	var template database.ExtractionTemplate
+	if err := json.Unmarshal(rec.Body.Bytes(), &template); err != nil || rec.Code != http.StatusCreated || len(template.Fields) != 4 {
+		t.Fatalf("Expected 201 with the template, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(http.MethodPost, "/api/extraction/templates", `{"correspondent": "Acme", "match": "(", "fields": []}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid template, got %d", rec.Code)
+	}
+
+	// One of their invoices, and a letter from someone else, are ingested
+	invoicePath := filepath.Join(handler.ServerConfig.DocumentPath, "invoice.pdf")
+	writeInvoicePDF(t, invoicePath)
+	invoice := saveTestDocument(t, handler.DB, invoicePath, invoiceText)
+	handler.documentIngested(invoice, "upload", nil)
+	letter := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "letter.txt"), "Invoice INV-1 from the Water Board")
+	handler.documentIngested(letter, "upload", nil)
+
+	// The invoice's fields were read by pattern and from the zone, and the one not found left out
+	rec = serve(http.MethodGet, "/api/documents/"+invoice.ULID.String()+"/fields", "")
+	var response struct {
+		Fields []database.DocumentField `json:"fields"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with fields, got %d %s", rec.Code, rec.Body)
+	}
+	values := make(map[string]string)
+	for _, field := range response.Fields {
+		values[field.Name] = field.Value
+		if field.TemplateULID != template.ULID.String() {
+			t.Errorf("Expected %s read by the template, got %q", field.Name, field.TemplateULID)
+		}
+	}
+	want := map[string]string{"invoice_number": "INV-2024-0042", "total": "123.45", "iban": "GB29 NWBK 6016 1331 9268 19"}
+	if len(values) != len(want) {
+		t.Errorf("Expected fields %v, got %v", want, values)
+	}
+	for name, value := range want {
+		if values[name] != value {
+			t.Errorf("Expected %s %q, got %q", name, value, values[name])
+		}
+	}
+
+	// Nothing was read from the letter, which the template does not recognise
+	if fields, err := handler.DB.GetDocumentFields(letter.ULID.String()); err != nil || len(fields) != 0 {
+		t.Errorf("Expected no fields for the letter, got %v: %v", fields, err)
+	}
+
+	// A draft template previewed on the letter reads its invoice number without saving it
+	rec = serve(http.MethodPost, "/api/extraction/preview", `{"documentId": "`+letter.ULID.String()+`", "correspondent": "Water Board",
+		"match": "Water Board", "fields": [{"name": "invoice_number", "pattern": "Invoice (\\S+)"}]}`)
+	var preview struct {
+		Matched bool                     `json:"matched"`
+		Fields  []database.DocumentField `json:"fields"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &preview); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with a preview, got %d %s", rec.Code, rec.Body)
+	}
+	if !preview.Matched || len(preview.Fields) != 1 || preview.Fields[0].Value != "INV-1" {
+		t.Errorf("Expected the draft to match and read INV-1, got %+v", preview)
+	}
+	if fields, _ := handler.DB.GetDocumentFields(letter.ULID.String()); len(fields) != 0 {
+		t.Errorf("Expected the preview not to save fields, got %v", fields)
+	}
+
+	// The saved template previewed on the letter does not match it
+	rec = serve(http.MethodPost, "/api/extraction/preview", `{"documentId": "`+letter.ULID.String()+`", "templateId": "`+template.ULID.String()+`"}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &preview); err != nil || rec.Code != http.StatusOK || preview.Matched {
+		t.Errorf("Expected the saved template not to match the letter, got %d %s", rec.Code, rec.Body)
+	}
+
+	// An unknown document or template is 404
+	if rec := serve(http.MethodPost, "/api/extraction/preview", `{"documentId": "`+database.MakeULID().String()+`", "templateId": "`+template.ULID.String()+`"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown document, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "/api/extraction/preview", `{"documentId": "`+letter.ULID.String()+`", "templateId": "`+database.MakeULID().String()+`"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown template, got %d", rec.Code)
+	}
+
+	// With the document root restricted to another account, bob can neither read nor preview the invoice
+	handler.ServerConfig.WebUIPass = true
+	bob := signInAs(t, handler, "bob", database.RoleEditor)
+	signInAs(t, handler, "carol", database.RoleViewer)
+	carol, _ := handler.DB.GetUserByUsername("carol")
+	handler.DB.SetFolderPermission(&database.FolderPermission{Folder: "/", UserID: carol.ID, Access: database.AccessRead})
+	handler.Echo.Use(handler.RequireLogin())
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/documents/"+invoice.ULID.String()+"/fields", nil),
+		httptest.NewRequest(http.MethodPost, "/api/extraction/preview", strings.NewReader(`{"documentId": "`+invoice.ULID.String()+`", "templateId": "`+template.ULID.String()+`"}`)),
+	} {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+bob)
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for bob on %s, got %d", req.URL.Path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// writeTestPages writes a PDF with the given number of blank pages
+func writeTestPages(t *testing.T, path string, pages int) {
+	t.Helper()
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	for i := 0; i < pages; i++ {
+		pdf.AddPage()
+	}
+	if err := pdf.OutputFileAndClose(path); err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+}
+
+func TestFileDetails(t *testing.T) {
+	// A three page PDF, a text file and a corrupt PDF
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "report.pdf")
+	writeTestPages(t, pdfPath, 3)
+	textPath := filepath.Join(dir, "notes.txt")
+	os.WriteFile(textPath, []byte("meeting notes"), 0644)
+	brokenPath := filepath.Join(dir, "broken.pdf")
+	os.WriteFile(brokenPath, []byte("%PDF-1.4 not really"), 0644)
+
+	// Each reports its size, and only the readable PDF a page count
+	info, _ := os.Stat(pdfPath)
+	if size, pages := fileDetails(pdfPath); size != info.Size() || pages != 3 {
+		t.Errorf("Expected %d bytes and 3 pages, got %d and %d", info.Size(), size, pages)
+	}
+	if size, pages := fileDetails(textPath); size != 13 || pages != 0 {
+		t.Errorf("Expected 13 bytes and no pages, got %d and %d", size, pages)
+	}
+	if size, pages := fileDetails(brokenPath); size == 0 || pages != 0 {
+		t.Errorf("Expected a size and no pages for a corrupt PDF, got %d and %d", size, pages)
+	}
+	if size, pages := fileDetails(filepath.Join(dir, "missing.pdf")); size != 0 || pages != 0 {
+		t.Errorf("Expected nothing for a missing file, got %d and %d", size, pages)
+	}
+}
+
+func TestSearchWithMissingFile(t *testing.T) {
+	// A document whose file has gone missing since it was ingested
+	handler := setupTestHandler(t)
+	doc := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "gone.pdf"), "invoice from the plumber")
+	if err := handler.DB.UpdateDocumentFileDetails(doc.ULID.String(), 2048, 2); err != nil {
+		t.Fatalf("UpdateDocumentFileDetails failed: %v", err)
+	}
+
+	// It is found by a search
+	rec := httptest.NewRecorder()
+	handler.SearchDocuments(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/search?term=plumber", nil), rec))
+
+	// The search succeeds and lists it with the recorded size and page count
+	var response dto.FileSystem
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if len(response.FileSystem) != 2 || response.FileSystem[1].Size != 2048 || response.FileSystem[1].PageCount != 2 {
+		t.Errorf("Expected the document with its details, got %+v", response.FileSystem)
+	}
+	waitForMissingFileCheck(t, handler)
+}
+
+func TestBackfillFileDetails(t *testing.T) {
+	// A PDF and a missing file stored before sizes were recorded
+	handler := setupTestHandler(t)
+	pdfPath := filepath.Join(handler.ServerConfig.DocumentPath, "statement.pdf")
+	writeTestPages(t, pdfPath, 2)
+	stored := saveTestDocument(t, handler.DB, pdfPath, "bank statement")
+	missing := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "gone.pdf"), "")
+
+	// The startup backfill runs
+	handler.backfillFileDetails()
+
+	// The PDF has its size and page count, and the missing file is left alone
+	info, _ := os.Stat(pdfPath)
+	got, err := handler.DB.GetDocumentByULID(stored.ULID.String())
+	if err != nil || got.Size != info.Size() || got.PageCount != 2 {
+		t.Errorf("Expected %d bytes and 2 pages, got %+v, %v", info.Size(), got, err)
+	}
+	if got, err := handler.DB.GetDocumentByULID(missing.ULID.String()); err != nil || got.Size != 0 {
+		t.Errorf("Expected the missing file untouched, got %+v, %v", got, err)
+	}
+}
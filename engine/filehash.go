@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"io"
+	"os"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/storage"
+)
+
+// hashingCopy streams src into dst and returns the hashes of the bytes copied, so a file is hashed in
+// the same pass that stores it and never has to fit in memory
+func hashingCopy(dst io.Writer, src io.Reader) (database.FileHashes, error) {
+	hasher := database.NewFileHasher()
+	if _, err := io.Copy(dst, io.TeeReader(src, hasher)); err != nil {
+		return database.FileHashes{}, err
+	}
+	return hasher.Sums(), nil
+}
+
+// writeFileHashed streams src into a new or truncated file at path and returns the hashes of what was
+// written. A partly written file is removed on failure.
+func writeFileHashed(path string, src io.Reader, perm os.FileMode) (database.FileHashes, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return database.FileHashes{}, err
+	}
+	fileHash, err := hashingCopy(file, src)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return database.FileHashes{}, err
+	}
+	return fileHash, nil
+}
+
+// storeFileHashed copies sourcePath into documents under key and returns the hashes of the bytes copied
+func storeFileHashed(documents storage.Backend, key string, sourcePath string) (database.FileHashes, error) {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return database.FileHashes{}, err
+	}
+	defer source.Close()
+	hasher := database.NewFileHasher()
+	if err := documents.Write(key, io.TeeReader(source, hasher)); err != nil {
+		return database.FileHashes{}, err
+	}
+	return hasher.Sums(), nil
+}
+
+// copyFileHashed copies sourcePath to destPath and returns the hashes of the bytes copied
+func copyFileHashed(sourcePath, destPath string) (database.FileHashes, error) {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return database.FileHashes{}, err
+	}
+	defer source.Close()
+	return writeFileHashed(destPath, source, os.ModePerm)
+}
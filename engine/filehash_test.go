@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+)
+
+func TestCopyFileHashedStreamsLargeFiles(t *testing.T) {
+	// A file larger than the copy buffer many times over
+	dir := t.TempDir()
+	source := filepath.Join(dir, "large.pdf")
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1<<20) // 16MB
+	if err := os.WriteFile(source, content, 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	// It is copied
+	dest := filepath.Join(dir, "copy.pdf")
+	copiedHash, err := copyFileHashed(source, dest)
+
+	// The copy is identical and the hash matches one computed separately
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	wantHash, _ := calculateFileHash(source)
+	if copiedHash != wantHash {
+		t.Errorf("Expected hash %s, got %s", wantHash, copiedHash)
+	}
+	if copied, _ := os.ReadFile(dest); !bytes.Equal(copied, content) {
+		t.Error("Copied file differs from the source")
+	}
+}
+
+func TestWriteFileHashedRemovesPartialFile(t *testing.T) {
+	// A reader that fails part way through
+	dest := filepath.Join(t.TempDir(), "partial.pdf")
+	failing := io.MultiReader(bytes.NewReader([]byte("%PDF-1.7")), iotest.ErrReader(errors.New("connection reset")))
+
+	// It is written
+	_, err := writeFileHashed(dest, failing, 0644)
+
+	// The error is returned and nothing is left behind
+	if err == nil {
+		t.Fatal("Expected the read error to be returned")
+	}
+	if _, statErr := os.Stat(dest); !os.IsNotExist(statErr) {
+		t.Errorf("Expected the partial file to be removed, stat: %v", statErr)
+	}
+}
@@ -0,0 +1,188 @@
+package engine
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+// normaliseName puts a file or folder name into Unicode NFC, so a name typed on Linux or Windows and the
+// decomposed form macOS produces are stored and compared the same way
+func normaliseName(name string) string {
+	return norm.NFC.String(name)
+}
+
+// clientFileName returns the last element of a file name sent by a client, treating both "/" and "\" as
+// separators whatever the server OS, since some Windows browsers and scanners send the full local path
+func clientFileName(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return normaliseName(name)
+}
+
+// normalisePath applies normaliseName to every element of a path
+func normalisePath(path string) string {
+	return filepath.FromSlash(norm.NFC.String(filepath.ToSlash(path)))
+}
+
+// transliterations covers letters that do not decompose into an ASCII base letter plus accents
+var transliterations = map[rune]string{
+	'ß': "ss", 'Æ': "AE", 'æ': "ae", 'Ø': "O", 'ø': "o", 'Œ': "OE", 'œ': "oe",
+	'Ð': "D", 'ð': "d", 'Þ': "TH", 'þ': "th", 'Ł': "L", 'ł': "l", 'Đ': "D", 'đ': "d",
+}
+
+// asciiName transliterates a file name to plain ASCII for temp files and tools that cannot cope with
+// non-ASCII paths: accents are stripped, a few letters are spelt out, and anything else becomes "_".
+// The extension is kept so tools can still tell the file type.
+func asciiName(name string) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	var b strings.Builder
+	for _, r := range norm.NFD.String(base) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// drop combining accents left by the decomposition
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_.", r)):
+			b.WriteRune(r)
+		case transliterations[r] != "":
+			b.WriteString(transliterations[r])
+		default:
+			b.WriteRune('_')
+		}
+	}
+	ascii := strings.Trim(b.String(), "_.")
+	if ascii == "" {
+		ascii = "file"
+	}
+	return ascii + asciiExtension(ext)
+}
+
+// asciiExtension keeps an extension only when it is already plain ASCII
+func asciiExtension(ext string) string {
+	for _, r := range ext {
+		if r >= unicode.MaxASCII || !(r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return ""
+		}
+	}
+	return ext
+}
+
+// contentDisposition builds an inline Content-Disposition header carrying the original name:
+// an ASCII fallback for old clients and the UTF-8 name percent-encoded per RFC 6266 / RFC 8187
+func contentDisposition(name string) string {
+	return `inline` + dispositionFilename(name)
+}
+
+// attachmentDisposition is contentDisposition for downloads that should be saved rather than shown
+func attachmentDisposition(name string) string {
+	return `attachment` + dispositionFilename(name)
+}
+
+// dispositionFilename is the filename parameters shared by the Content-Disposition headers
+func dispositionFilename(name string) string {
+	return `; filename="` + asciiName(name) + `"; filename*=UTF-8''` + encodeExtValue(normaliseName(name))
+}
+
+// encodeExtValue percent-encodes every byte outside the RFC 8187 attr-char set
+func encodeExtValue(value string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < utf8.RuneSelf && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)) || strings.IndexByte("!#$&+-.^_`|~", c) >= 0) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0x0f])
+	}
+	return b.String()
+}
+
+// nameOrder orders file and folder names for display by the rules of a locale (SORT_LOCALE; empty for
+// language-neutral ordering), with each run of digits compared by its value so file2 comes before file10.
+// Case, accents and leading zeros only decide between names that are otherwise equal. It is not safe for
+// concurrent use, so each caller makes its own.
+type nameOrder struct {
+	loose  *collate.Collator // letters only, for the first pass
+	strict *collate.Collator // every difference, to break ties
+}
+
+// newNameOrder returns the name order for locale, falling back to language-neutral for an invalid tag
+func newNameOrder(locale string) *nameOrder {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		tag = language.Und
+	}
+	return &nameOrder{loose: collate.New(tag, collate.Loose), strict: collate.New(tag)}
+}
+
+// compare returns a negative number when name a comes before b, a positive one when after and 0 when
+// they are the same
+func (o *nameOrder) compare(a, b string) int {
+	restA, restB := a, b
+	for restA != "" && restB != "" {
+		chunkA, chunkB := leadingChunk(restA), leadingChunk(restB)
+		var order int
+		if isDigit(chunkA[0]) && isDigit(chunkB[0]) {
+			order = compareNumbers(chunkA, chunkB)
+		} else {
+			order = o.loose.CompareString(chunkA, chunkB)
+		}
+		if order != 0 {
+			return order
+		}
+		restA, restB = restA[len(chunkA):], restB[len(chunkB):]
+	}
+	if restA != restB {
+		return len(restA) - len(restB) // the name that ran out first is a prefix of the other
+	}
+	if order := o.strict.CompareString(a, b); order != 0 {
+		return order
+	}
+	return strings.Compare(a, b)
+}
+
+// comparePaths orders slash-separated folder paths element by element, so a parent comes before its
+// children and the children of each folder are in name order
+func (o *nameOrder) comparePaths(a, b string) int {
+	namesA, namesB := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(namesA) && i < len(namesB); i++ {
+		if order := o.compare(namesA[i], namesB[i]); order != 0 {
+			return order
+		}
+	}
+	return len(namesA) - len(namesB)
+}
+
+// leadingChunk returns the run of ASCII digits, or of anything else, that name starts with
+func leadingChunk(name string) string {
+	digits := isDigit(name[0])
+	end := 1
+	for end < len(name) && isDigit(name[end]) == digits {
+		end++
+	}
+	return name[:end]
+}
+
+// isDigit reports whether b is an ASCII digit
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
+
+// compareNumbers compares two runs of digits by value, however long they are
+func compareNumbers(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
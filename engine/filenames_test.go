@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestASCIIName(t *testing.T) {
+	cases := map[string]string{
+		"Größe Übersicht.pdf": "Grosse_Ubersicht.pdf",
+		"Øresund æble.png":    "Oresund_aeble.png",
+		"报告.png":              "file.png",
+		"🧾 receipt.txt":       "receipt.txt",
+		"plain-name_1.tiff":   "plain-name_1.tiff",
+		"scan.ñpg":            "scan",
+	}
+	for name, want := range cases {
+		if got := asciiName(name); got != want {
+			t.Errorf("asciiName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestClientFileName(t *testing.T) {
+	cases := map[string]string{
+		`C:\Users\me\Scans\bill.pdf`: "bill.pdf",
+		"scans/2024/bill.pdf":        "bill.pdf",
+		"bill.pdf":                   "bill.pdf",
+		`..\..\evil.pdf`:             "evil.pdf",
+	}
+	for name, want := range cases {
+		if got := clientFileName(name); got != want {
+			t.Errorf("clientFileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestNameOrder(t *testing.T) {
+	for locale, want := range map[string][]string{
+		"": {"2023", "2024", "apple", "ärende.pdf", "Banana", "file0.pdf", "file000001.pdf", "file1.pdf", "File1.pdf",
+			"file2.pdf", "file10.pdf", "scan-0.tiff", "scan-1.tiff", "zebra"},
+		// Swedish sorts ä after z
+		"sv": {"2023", "2024", "apple", "Banana", "file0.pdf", "file000001.pdf", "file1.pdf", "File1.pdf",
+			"file2.pdf", "file10.pdf", "scan-0.tiff", "scan-1.tiff", "zebra", "ärende.pdf"},
+	} {
+		// Names in scrambled order
+		names := []string{"file10.pdf", "scan-1.tiff", "zebra", "File1.pdf", "ärende.pdf", "2024", "file2.pdf",
+			"apple", "file000001.pdf", "scan-0.tiff", "Banana", "file1.pdf", "2023", "file0.pdf"}
+
+		// They are sorted for the locale
+		order := newNameOrder(locale)
+		sort.SliceStable(names, func(i, j int) bool { return order.compare(names[i], names[j]) < 0 })
+
+		// Numbers are compared by value and letters by the locale
+		if !slices.Equal(names, want) {
+			t.Errorf("%q: got %v, want %v", locale, names, want)
+		}
+	}
+}
+
+func TestContentDispositionEncodesUnicode(t *testing.T) {
+	// The decomposed (macOS) spelling is normalised before encoding
+	got := contentDisposition(norm.NFD.String("Größe (1).pdf"))
+	want := `inline; filename="Grosse__1.pdf"; filename*=UTF-8''Gr%C3%B6%C3%9Fe%20%281%29.pdf`
+	if got != want {
+		t.Errorf("contentDisposition = %q, want %q", got, want)
+	}
+}
+
+func TestUnicodeNamesEndToEnd(t *testing.T) {
+	// Files with umlauts, CJK and emoji in decomposed (NFD) form under an NFD folder in ingress
+	handler := setupTestHandler(t)
+	handler.ServerConfig.IngressPreserve = true
+	if err := handler.DB.SaveConfig(&handler.ServerConfig); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	folder := "Ünterlagen"
+	names := []string{"Größe.txt", "报告 2024.txt", "🧾 Quittung.txt"}
+	ingressFolder := filepath.Join(handler.ServerConfig.IngressPath, norm.NFD.String(folder))
+	if err := os.MkdirAll(ingressFolder, 0755); err != nil {
+		t.Fatalf("Failed to create ingress folder: %v", err)
+	}
+	for i, name := range names {
+		if err := os.WriteFile(filepath.Join(ingressFolder, norm.NFD.String(name)), []byte("inhalt "+string(rune('a'+i))), 0644); err != nil {
+			t.Fatalf("Failed to write ingress file: %v", err)
+		}
+	}
+	job, err := handler.DB.CreateJob(database.JobTypeIngestion, "test")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	// The files are ingested
+	handler.ingressJobFuncWithTracking(handler.ServerConfig, handler.DB, job.ID)
+
+	// Names and paths are stored in NFC and the files sit at those paths
+	documentFolder := filepath.Join(handler.ServerConfig.DocumentPath, folder)
+	for _, name := range names {
+		path := filepath.ToSlash(filepath.Join(documentFolder, name))
+		doc, err := handler.DB.GetDocumentByPath(path)
+		if err != nil {
+			t.Fatalf("Document %q not found: %v", name, err)
+		}
+		if doc.Name != name {
+			t.Errorf("Expected NFC name %q, got %q", name, doc.Name)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("File missing at NFC path: %v", err)
+		}
+	}
+
+	// The recursive folder listing answers a percent-encoded Unicode folder
+	handler.Echo.GET("/api/folder/:folder", handler.GetFolder)
+	if err := handler.AddDocumentViewRoutes(); err != nil {
+		t.Fatalf("Failed to add view routes: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/folder/"+url.PathEscape(documentFolder)+"?recursive=true", nil))
+	var docs []database.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &docs); err != nil || len(docs) != len(names) {
+		t.Fatalf("Expected %d documents in the folder, got %d (%s)", len(names), len(docs), rec.Body.String())
+	}
+
+	// Every document is served under its ULID with the Unicode name in Content-Disposition
+	for _, doc := range docs {
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, doc.URL, nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Disposition") != contentDisposition(doc.Name) {
+			t.Errorf("Unexpected view of %q: %d %q", doc.Name, rec.Code, rec.Header().Get("Content-Disposition"))
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// folderAccess is what one signed-in account may do in each folder. A nil *folderAccess allows everything:
+// nobody is signed in with an account, or the account is an administrator.
+type folderAccess struct {
+	root       string            // folder key of the document root
+	role       string            // the account's role
+	granted    map[string]string // the account's access, by folder key relative to the root
+	restricted map[string]bool   // folders with any permissions, by folder key relative to the root
+}
+
+// folderAccess loads the folder permissions of the account signed in to a request
+func (serverHandler *ServerHandler) folderAccess(c echo.Context) (*folderAccess, error) {
+	username, _ := c.Get(authUserKey).(string)
+	if username == "" || serverHandler.isAdmin(c) {
+		return nil, nil
+	}
+	return serverHandler.accountAccess(username)
+}
+
+// accountAccess loads the folder permissions of an account by username, for work done on its behalf
+// outside its own requests, such as opening a collection it shared
+func (serverHandler *ServerHandler) accountAccess(username string) (*folderAccess, error) {
+	if username == "" || slices.Contains(serverHandler.ServerConfig.AdminUsers, username) {
+		return nil, nil
+	}
+	user, err := serverHandler.DB.GetUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == database.RoleAdmin {
+		return nil, nil
+	}
+	permissions, err := serverHandler.DB.ListFolderPermissions()
+	if err != nil {
+		return nil, err
+	}
+	access := &folderAccess{
+		root:       folderKey(serverHandler.ServerConfig.DocumentPath),
+		role:       user.Role,
+		granted:    make(map[string]string),
+		restricted: make(map[string]bool),
+	}
+	for _, permission := range permissions {
+		access.restricted[permission.Folder] = true
+		if permission.UserID == user.ID {
+			access.granted[permission.Folder] = permission.Access
+		}
+	}
+	return access, nil
+}
+
+// level is the access the account has to folder, an absolute path: that given by the nearest folder at or
+// above it with permissions, or write when there is none. Viewers never have more than read, and nobody
+// has any outside the document root.
+func (a *folderAccess) level(folder string) string {
+	if a == nil {
+		return database.AccessWrite
+	}
+	key := folderKey(folder)
+	if !insideRoot(a.root, key) {
+		return ""
+	}
+	access := database.AccessWrite
+	for rel := relativeFolderKey(a.root, key); ; rel = parentFolderKey(rel) {
+		if a.restricted[rel] {
+			access = a.granted[rel]
+			break
+		}
+		if rel == "/" {
+			break
+		}
+	}
+	if a.role == database.RoleViewer && access == database.AccessWrite {
+		access = database.AccessRead
+	}
+	return access
+}
+
+// parentFolderKey is the folder above a folder key relative to the root, "/" for a top-level folder
+func parentFolderKey(rel string) string {
+	if parent := path.Dir(rel); parent != "." {
+		return parent
+	}
+	return "/"
+}
+
+// canRead reports whether the account may see the documents in folder
+func (a *folderAccess) canRead(folder string) bool {
+	return a.level(folder) != ""
+}
+
+// canWrite reports whether the account may change the documents in folder
+func (a *folderAccess) canWrite(folder string) bool {
+	return a.level(folder) == database.AccessWrite
+}
+
+// below calls fn with each restricted folder under folder, an absolute path, until it returns false
+func (a *folderAccess) below(folder string, fn func(rel string) bool) {
+	prefix := strings.TrimSuffix(relativeFolderKey(a.root, folderKey(folder)), "/") + "/"
+	for rel := range a.restricted {
+		if (prefix == "/" || strings.HasPrefix(rel, prefix)) && rel != "/" && !fn(rel) {
+			return
+		}
+	}
+}
+
+// canWriteAll reports whether the account may change folder and every folder under it, as deleting it needs
+func (a *folderAccess) canWriteAll(folder string) bool {
+	if !a.canWrite(folder) {
+		return false
+	}
+	if a == nil {
+		return true
+	}
+	all := true
+	a.below(folder, func(rel string) bool {
+		all = a.granted[rel] == database.AccessWrite
+		return all
+	})
+	return all
+}
+
+// visible reports whether folder belongs in the account's browse tree: it can be read, or it leads to a
+// folder below that can
+func (a *folderAccess) visible(folder string) bool {
+	if a.canRead(folder) {
+		return true
+	}
+	found := false
+	a.below(folder, func(rel string) bool {
+		found = a.granted[rel] != ""
+		return !found
+	})
+	return found
+}
+
+// readable keeps the documents whose folder the account may read
+func (a *folderAccess) readable(documents []database.Document) []database.Document {
+	if a == nil {
+		return documents
+	}
+	kept := documents[:0:0]
+	for _, document := range documents {
+		if a.canRead(document.Folder) {
+			kept = append(kept, document)
+		}
+	}
+	return kept
+}
+
+// filterTree removes the folders and documents the account may not see from a browse tree. Folders that
+// only lead to a readable folder stay without their documents, and smart folders keep the documents that
+// can be seen in their own folder.
+func (a *folderAccess) filterTree(tree *dto.FileSystem) {
+	if a == nil {
+		return
+	}
+	folders := make(map[string]string) // folder node ID -> folder path
+	for _, node := range tree.FileSystem {
+		if node.IsDir && strings.HasPrefix(node.ID, "folder-") {
+			folders[node.ID] = node.FullPath
+		}
+	}
+	readableDocuments := make(map[string]bool) // ULIDs seen in a folder the account can read
+	for _, node := range tree.FileSystem {
+		if folder, ok := folders[node.ParentID]; ok && !node.IsDir && a.canRead(folder) {
+			readableDocuments[node.ULID] = true
+		}
+	}
+	kept := tree.FileSystem[:0]
+	keptIDs := make(map[string]bool)
+	for _, node := range tree.FileSystem {
+		switch {
+		case node.ParentID != "" && !keptIDs[node.ParentID]:
+			continue // under a folder already removed; parents come before their children
+		case node.IsDir && strings.HasPrefix(node.ID, "folder-"):
+			if !a.visible(node.FullPath) {
+				continue
+			}
+		case !node.IsDir:
+			if !readableDocuments[node.ULID] {
+				continue
+			}
+		}
+		keptIDs[node.ID] = true
+		kept = append(kept, node)
+	}
+	tree.FileSystem = kept
+	directoriesFirst(tree)
+}
+
+// hiddenDocument reports whether the document with ULID id is in a folder the account may not read, so a
+// handler answers as though it did not exist. A document that cannot be found is not hidden; the handler
+// reports it missing itself.
+func (serverHandler *ServerHandler) hiddenDocument(c echo.Context, id string) bool {
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		return true
+	}
+	if access == nil {
+		return false
+	}
+	document, err := serverHandler.DB.GetDocumentByULID(id)
+	return err == nil && document != nil && !access.canRead(document.Folder)
+}
+
+// documentNotFound answers 404 for a document that does not exist or that the account may not see
+func documentNotFound(c echo.Context) error {
+	return c.JSON(http.StatusNotFound, map[string]interface{}{
+		"error": "Document not found",
+		"code":  dto.CodeNotFound,
+	})
+}
+
+// folderForbidden answers 403 for a change to a folder the account may not write to
+func folderForbidden(c echo.Context, folder string) error {
+	return c.JSON(http.StatusForbidden, map[string]interface{}{
+		"error": "You do not have write access to " + filepath.Base(folder),
+		"code":  dto.CodeForbidden,
+	})
+}
+
+// folderPermissionRequest gives an account access to a folder
+type folderPermissionRequest struct {
+	Folder   string `json:"folder"`   // relative to the document root, "/" for the root
+	Username string `json:"username"` // the account
+	Access   string `json:"access"`   // read or write
+}
+
+// folderPermissionView is a folder permission with the username of its account
+type folderPermissionView struct {
+	database.FolderPermission
+	Username string `json:"username"`
+}
+
+// folderPermissionKey validates a folder given relative to the document root, returning its folder key
+func (serverHandler *ServerHandler) folderPermissionKey(folder string) (string, bool) {
+	root := folderKey(serverHandler.ServerConfig.DocumentPath)
+	if strings.Trim(folder, "/") == "" {
+		return "/", true
+	}
+	destination, err := uploadFolderPath(serverHandler.ServerConfig.DocumentPath, folder)
+	if err != nil {
+		return "", false
+	}
+	return relativeFolderKey(root, folderKey(destination)), true
+}
+
+// ListFolderPermissions returns every folder permission
+// @Summary List folder permissions
+// @Description Every folder permission with the username it is for. A folder with permissions can only be seen by the accounts named and administrators.
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} folderPermissionView "Folder permissions"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Router /folder-permissions [get]
+func (serverHandler *ServerHandler) ListFolderPermissions(c echo.Context) error {
+	if !serverHandler.isAdmin(c) {
+		return userAdminOnly(c)
+	}
+	permissions, err := serverHandler.DB.ListFolderPermissions()
+	if err != nil {
+		Logger.Error("Failed to list folder permissions", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list folder permissions",
+			"code":  dto.CodeInternal,
+		})
+	}
+	views := make([]folderPermissionView, 0, len(permissions))
+	for _, permission := range permissions {
+		view := folderPermissionView{FolderPermission: permission}
+		if user, err := serverHandler.DB.GetUser(permission.UserID); err == nil {
+			view.Username = user.Username
+		}
+		views = append(views, view)
+	}
+	return c.JSON(http.StatusOK, views)
+}
+
+// SetFolderPermission gives an account read or write access to a folder
+// @Summary Set a folder permission
+// @Description Give an account read or write access to a folder and everything under it, replacing any it had there. The folder becomes restricted: accounts without a permission on it, or on a restricted folder below, no longer see it.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body folderPermissionRequest true "Folder, username and access"
+// @Success 200 {object} folderPermissionView "The permission"
+// @Failure 400 {object} dto.ErrorResponse "Invalid folder, username or access"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Router /folder-permissions [put]
+func (serverHandler *ServerHandler) SetFolderPermission(c echo.Context) error {
+	if !serverHandler.isAdmin(c) {
+		return userAdminOnly(c)
+	}
+	var request folderPermissionRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	problems := make(map[string]string)
+	folder, ok := serverHandler.folderPermissionKey(request.Folder)
+	if !ok {
+		problems["folder"] = "must be a folder under the document root"
+	}
+	user, err := serverHandler.DB.GetUserByUsername(request.Username)
+	if err != nil {
+		problems["username"] = "no such account"
+	}
+	if request.Access != database.AccessRead && request.Access != database.AccessWrite {
+		problems["access"] = "must be read or write"
+	}
+	if len(problems) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "The folder permission needs fixing",
+			"code":   dto.CodeValidation,
+			"fields": problems,
+		})
+	}
+	permission := &database.FolderPermission{Folder: folder, UserID: user.ID, Access: request.Access}
+	if err := serverHandler.DB.SetFolderPermission(permission); err != nil {
+		Logger.Error("Failed to set folder permission", "folder", folder, "username", user.Username, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to set folder permission",
+			"code":  dto.CodeInternal,
+		})
+	}
+	Logger.Info("Folder permission set", "folder", folder, "username", user.Username, "access", request.Access, "by", requestUser(c))
+	serverHandler.settingsChanged(c, "folder permissions for "+folder)
+	return c.JSON(http.StatusOK, folderPermissionView{FolderPermission: *permission, Username: user.Username})
+}
+
+// DeleteFolderPermission removes an account's access to a folder
+// @Summary Remove a folder permission
+// @Description Remove an account's permission on a folder. Once a folder has no permissions left it is open to every account again.
+// @Tags Auth
+// @Param folder query string true "Folder, relative to the document root"
+// @Param username query string true "Account"
+// @Success 204 "Removed"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 404 {object} dto.ErrorResponse "No such permission"
+// @Router /folder-permissions [delete]
+func (serverHandler *ServerHandler) DeleteFolderPermission(c echo.Context) error {
+	if !serverHandler.isAdmin(c) {
+		return userAdminOnly(c)
+	}
+	notFound := func() error {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Folder permission not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	folder, ok := serverHandler.folderPermissionKey(c.QueryParam("folder"))
+	user, err := serverHandler.DB.GetUserByUsername(c.QueryParam("username"))
+	if !ok || err != nil {
+		return notFound()
+	}
+	if err := serverHandler.DB.DeleteFolderPermission(folder, user.ID); errors.Is(err, sql.ErrNoRows) {
+		return notFound()
+	} else if err != nil {
+		Logger.Error("Failed to remove folder permission", "folder", folder, "username", user.Username, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to remove folder permission",
+			"code":  dto.CodeInternal,
+		})
+	}
+	Logger.Info("Folder permission removed", "folder", folder, "username", user.Username, "by", requestUser(c))
+	serverHandler.settingsChanged(c, "folder permissions for "+folder)
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,443 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+// signInAs creates an account with role and a session for it, returning the session token
+func signInAs(t *testing.T, handler *ServerHandler, username, role string) string {
+	t.Helper()
+	user := &database.User{Username: username, PasswordHash: "unused", Role: role}
+	if err := handler.DB.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	token := "token-" + username
+	session := &database.Session{TokenHash: hashSessionToken(token), UserID: user.ID, CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	if err := handler.DB.CreateSession(session); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	return token
+}
+
+func TestFolderPermissions(t *testing.T) {
+	// Invoices in the root, finance, medical and medical/2024, and an administrator, an editor and a viewer
+	handler := setupTestHandler(t)
+	handler.ServerConfig.WebUIPass = true
+	root := handler.ServerConfig.DocumentPath
+	docs := map[string]*database.Document{}
+	for _, name := range []string{"notes.pdf", "finance/a.pdf", "medical/b.pdf", "medical/2024/c.pdf"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("%PDF"), 0644)
+		docs[name] = saveTestDocument(t, handler.DB, path, "invoice")
+	}
+	alice := signInAs(t, handler, "alice", database.RoleAdmin)
+	bob := signInAs(t, handler, "bob", database.RoleEditor)
+	carol := signInAs(t, handler, "carol", database.RoleViewer)
+	handler.Echo.Use(handler.RequireLogin())
+	handler.Echo.PUT("/api/folder-permissions", handler.SetFolderPermission)
+	handler.Echo.GET("/api/documents/filesystem", handler.GetDocumentFileSystem)
+	handler.Echo.GET("/api/folder/:folder", handler.GetFolder)
+	handler.Echo.GET("/api/search", handler.SearchDocuments)
+	handler.Echo.GET("/api/document/:id", handler.GetDocument)
+	handler.Echo.DELETE("/api/document/*", handler.DeleteFile)
+	handler.Echo.PATCH("/api/document/move/*", handler.MoveDocuments)
+	serve := func(method, target, token, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		return rec
+	}
+	names := func(rec *httptest.ResponseRecorder) string {
+		var tree dto.FileSystem
+		json.Unmarshal(rec.Body.Bytes(), &tree)
+		var found []string
+		for _, node := range tree.FileSystem {
+			found = append(found, node.Name)
+		}
+		return strings.Join(found, ",")
+	}
+
+	// Alice restricts medical to bob, finance to carol, and medical/2024 to carol too; bob tries the same
+	for _, permission := range []string{
+		`{"folder":"medical","username":"bob","access":"write"}`,
+		`{"folder":"finance","username":"carol","access":"write"}`,
+		`{"folder":"medical/2024","username":"carol","access":"read"}`,
+	} {
+		if rec := serve(http.MethodPut, "/api/folder-permissions", alice, permission); rec.Code != http.StatusOK {
+			t.Fatalf("Expected the permission to be set, got %d %s", rec.Code, rec.Body)
+		}
+	}
+	if rec := serve(http.MethodPut, "/api/folder-permissions", bob, `{"folder":"finance","username":"bob","access":"write"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for bob setting permissions, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPut, "/api/folder-permissions", alice, `{"folder":"../etc","username":"bob","access":"own"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a folder outside the root, got %d", rec.Code)
+	}
+
+	// Each browse tree only has what its account may see; medical stays in carol's as the way to 2024
+	for token, want := range map[string]string{
+		alice: "documents,finance,medical,2024,a.pdf,b.pdf,c.pdf,notes.pdf",
+		bob:   "documents,medical,b.pdf,notes.pdf",
+		carol: "documents,finance,medical,2024,a.pdf,c.pdf,notes.pdf",
+	} {
+		if got := names(serve(http.MethodGet, "/api/documents/filesystem", token, "")); got != want {
+			t.Errorf("Expected tree %s, got %s", want, got)
+		}
+	}
+
+	// Search, folder listings and documents are filtered the same way
+	if got := names(serve(http.MethodGet, "/api/search?term=invoice", bob, "")); got != "Search Results,notes.pdf,b.pdf" && got != "Search Results,b.pdf,notes.pdf" {
+		t.Errorf("Expected bob to find notes and b, got %s", got)
+	}
+	medical := url.PathEscape(filepath.Join(root, "medical"))
+	if rec := serve(http.MethodGet, "/api/folder/"+medical, carol, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for carol listing medical, got %d", rec.Code)
+	}
+	var listed []database.Document
+	json.Unmarshal(serve(http.MethodGet, "/api/folder/"+medical+"?recursive=true", bob, "").Body.Bytes(), &listed)
+	if len(listed) != 1 || listed[0].Name != "b.pdf" {
+		t.Errorf("Expected bob to list only b.pdf under medical, got %+v", listed)
+	}
+	if rec := serve(http.MethodGet, "/api/document/"+docs["finance/a.pdf"].ULID.String(), bob, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for bob opening a finance document, got %d", rec.Code)
+	}
+
+	// Carol, a viewer, cannot delete even in finance; bob cannot move into finance or delete
+	// medical, which has a folder bob cannot change, but can move notes into medical
+	if rec := serve(http.MethodDelete, "/api/document/?path=finance/a.pdf", carol, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for carol deleting, got %d", rec.Code)
+	}
+	finance := url.QueryEscape(filepath.Join(root, "finance"))
+	if rec := serve(http.MethodPatch, "/api/document/move/?folder="+finance+"&id="+docs["notes.pdf"].ULID.String(), bob, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for bob moving into finance, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/api/document/?path=medical", bob, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for bob deleting medical, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPatch, "/api/document/move/?folder="+url.QueryEscape(filepath.Join(root, "medical"))+"&id="+docs["notes.pdf"].ULID.String(), bob, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected bob to move notes into medical, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(http.MethodDelete, "/api/document/?path=medical/b.pdf&id="+docs["medical/b.pdf"].ULID.String(), bob, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected bob to delete in medical, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestAdminEndpointsWithAccounts(t *testing.T) {
+	// Sign-in with an administrator and a viewer, and no ADMIN_USERS
+	handler := setupTestHandler(t)
+	handler.ServerConfig.WebUIPass = true
+	alice := signInAs(t, handler, "alice", database.RoleAdmin)
+	carol := signInAs(t, handler, "carol", database.RoleViewer)
+	handler.Echo.Use(handler.RequireLogin())
+	handler.Echo.GET("/api/admin/slow-queries", handler.GetSlowQueries)
+	handler.Echo.PUT("/api/read-only", handler.SetReadOnly)
+
+	// Only the administrator may read slow queries or switch read-only mode
+	for token, want := range map[string]int{alice: http.StatusOK, carol: http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/slow-queries", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("Expected %d reading slow queries, got %d", want, rec.Code)
+		}
+	}
+	req := httptest.NewRequest(http.MethodPut, "/api/read-only", strings.NewReader(`{"readOnly": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+carol)
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a viewer switching read-only mode, got %d", rec.Code)
+	}
+}
+
+func TestFolderPermissionsOnDocumentRoutes(t *testing.T) {
+	// A document in the root and one in finance, restricted to carol, a viewer; bob is an editor
+	handler := setupTestHandler(t)
+	handler.ServerConfig.WebUIPass = true
+	root := handler.ServerConfig.DocumentPath
+	docs := map[string]*database.Document{}
+	for _, name := range []string{"notes.pdf", "finance/a.pdf"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("%PDF"), 0644)
+		docs[name] = saveTestDocument(t, handler.DB, path, "invoice")
+	}
+	sheet := filepath.Join(root, "finance", "totals.csv")
+	os.WriteFile(sheet, []byte("month,total\njan,12\n"), 0644)
+	docs["finance/totals.csv"] = saveTestDocument(t, handler.DB, sheet, "month total")
+	bob := signInAs(t, handler, "bob", database.RoleEditor)
+	carol := signInAs(t, handler, "carol", database.RoleViewer)
+	user, _ := handler.DB.GetUserByUsername("carol")
+	folder, _ := handler.folderPermissionKey("finance")
+	handler.DB.SetFolderPermission(&database.FolderPermission{Folder: folder, UserID: user.ID, Access: database.AccessRead})
+	handler.Echo.Use(handler.RequireLogin())
+	handler.Echo.GET("/api/document/:id/preview", handler.GetSpreadsheetPreview)
+	handler.Echo.GET("/api/document/:id/text", handler.GetDocumentText)
+	handler.Echo.GET("/api/document/:id/search", handler.SearchDocumentText)
+	handler.Echo.GET("/api/document/:id/signed-url", handler.GetSignedDocumentURL)
+	handler.Echo.GET("/api/document/:id/qr.png", handler.GetDocumentQR)
+	handler.Echo.GET("/api/document/:id/timeline", handler.GetDocumentTimeline)
+	handler.Echo.GET("/api/document/:id/rotation", handler.GetDocumentRotations)
+	handler.Echo.GET("/api/document/:id/blank-pages", handler.GetDocumentBlankPages)
+	handler.Echo.POST("/api/document/:id/lock", handler.LockDocument)
+	handler.Echo.POST("/api/document/:id/archive", handler.ArchiveDocument)
+	handler.Echo.GET("/api/documents/latest", handler.GetLatestDocuments)
+	handler.Echo.GET("/api/documents/export.ndjson", handler.ExportDocumentsNDJSON)
+	handler.Echo.GET("/api/folder/:folder/download", handler.DownloadFolder)
+	serve := func(method, target, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(lockHolderHeader, token)
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		return rec
+	}
+	finance := "/api/document/" + docs["finance/a.pdf"].ULID.String()
+
+	// Bob cannot read the finance document by any route, nor lock it
+	for _, target := range []string{"/text", "/search?term=invoice", "/signed-url", "/qr.png", "/timeline", "/rotation", "/blank-pages"} {
+		if rec := serve(http.MethodGet, finance+target, bob); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for bob reading %s, got %d", target, rec.Code)
+		}
+		if rec := serve(http.MethodGet, finance+target, carol); rec.Code != http.StatusOK {
+			t.Errorf("Expected carol to read %s, got %d %s", target, rec.Code, rec.Body)
+		}
+	}
+	totals := "/api/document/" + docs["finance/totals.csv"].ULID.String() + "/preview"
+	if rec := serve(http.MethodGet, totals, bob); rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "jan") {
+		t.Errorf("Expected 404 for bob previewing the finance spreadsheet, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, totals, carol); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "jan") {
+		t.Errorf("Expected carol to preview the finance spreadsheet, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(http.MethodPost, finance+"/lock", bob); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for bob locking the finance document, got %d", rec.Code)
+	}
+
+	// Carol can read it but not lock or archive it
+	for _, target := range []string{"/lock", "/archive"} {
+		if rec := serve(http.MethodPost, finance+target, carol); rec.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for carol on %s, got %d", target, rec.Code)
+		}
+	}
+
+	// Bob's latest documents, export and folder download leave the finance document out
+	var latest struct {
+		Documents  []database.Document `json:"documents"`
+		TotalCount int                 `json:"totalCount"`
+	}
+	json.Unmarshal(serve(http.MethodGet, "/api/documents/latest", bob).Body.Bytes(), &latest)
+	if len(latest.Documents) != 1 || latest.TotalCount != 1 || latest.Documents[0].Name != "notes.pdf" {
+		t.Errorf("Expected bob's latest documents to be notes only, got %+v", latest)
+	}
+	json.Unmarshal(serve(http.MethodGet, "/api/documents/latest?cursor=", bob).Body.Bytes(), &latest)
+	if len(latest.Documents) != 1 || latest.Documents[0].Name != "notes.pdf" {
+		t.Errorf("Expected bob's first cursor page to be notes only, got %+v", latest)
+	}
+	if export := serve(http.MethodGet, "/api/documents/export.ndjson", bob).Body.String(); strings.Count(export, "\n") != 1 || strings.Contains(export, "a.pdf") {
+		t.Errorf("Expected bob's export to hold notes only, got %s", export)
+	}
+	if rec := serve(http.MethodGet, "/api/folder/"+url.PathEscape(filepath.Join(root, "finance"))+"/download", bob); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for bob downloading finance, got %d", rec.Code)
+	}
+}
+
+func TestFolderPermissionsOnCollections(t *testing.T) {
+	// notes.pdf is in the root and finance/a.pdf is restricted to carol; bob is an editor
+	handler := setupTestHandler(t)
+	handler.ServerConfig.WebUIPass = true
+	handler.ServerConfig.URLSigningKey = "key"
+	root := handler.ServerConfig.DocumentPath
+	docs := map[string]*database.Document{}
+	for _, name := range []string{"notes.pdf", "finance/a.pdf"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("%PDF"), 0644)
+		docs[name] = saveTestDocument(t, handler.DB, path, "invoice")
+	}
+	bob := signInAs(t, handler, "bob", database.RoleEditor)
+	carol := signInAs(t, handler, "carol", database.RoleViewer)
+	user, _ := handler.DB.GetUserByUsername("carol")
+	folder, _ := handler.folderPermissionKey("finance")
+	handler.DB.SetFolderPermission(&database.FolderPermission{Folder: folder, UserID: user.ID, Access: database.AccessRead})
+	handler.Echo.Use(handler.RequireLogin())
+	handler.Echo.POST("/api/collections", handler.CreateCollection)
+	handler.Echo.GET("/api/collections/:id", handler.GetCollection)
+	handler.Echo.GET("/api/shared/:token", handler.GetSharedCollection)
+	type collectionView struct {
+		Collection database.Collection `json:"collection"`
+		ShareURL   string              `json:"shareURL"`
+		Documents  []database.Document `json:"documents"`
+		Missing    int                 `json:"missing"`
+	}
+	serve := func(method, target, token, body string) (*httptest.ResponseRecorder, collectionView) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		var view collectionView
+		json.Unmarshal(rec.Body.Bytes(), &view)
+		return rec, view
+	}
+	both := `{"name":"both","share":true,"documentIds":["` + docs["notes.pdf"].ULID.String() + `","` + docs["finance/a.pdf"].ULID.String() + `"]}`
+
+	t.Run("hidden documents cannot be collected", func(t *testing.T) {
+		if rec, _ := serve(http.MethodPost, "/api/collections", bob, both); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for bob collecting the finance document, got %d %s", rec.Code, rec.Body)
+		}
+		rec, view := serve(http.MethodPost, "/api/collections", bob, `{"name":"invoices","term":"invoice","share":true}`)
+		if rec.Code != http.StatusCreated || view.Collection.DocumentCount != 1 {
+			t.Fatalf("Expected bob's search to collect notes only, got %d %s", rec.Code, rec.Body)
+		}
+		_, shared := serve(http.MethodGet, view.ShareURL, "", "")
+		if len(shared.Documents) != 1 || shared.Documents[0].Name != "notes.pdf" {
+			t.Errorf("Expected bob's share link to hold notes only, got %+v", shared.Documents)
+		}
+	})
+
+	t.Run("reads keep to what can be read now", func(t *testing.T) {
+		rec, view := serve(http.MethodPost, "/api/collections", carol, both)
+		if rec.Code != http.StatusCreated || view.Collection.CreatedBy != "carol" {
+			t.Fatalf("Expected carol to collect both documents, got %d %s", rec.Code, rec.Body)
+		}
+		if _, shared := serve(http.MethodGet, view.ShareURL, "", ""); len(shared.Documents) != 2 {
+			t.Errorf("Expected carol's share link to hold both documents, got %+v", shared.Documents)
+		}
+		_, opened := serve(http.MethodGet, "/api/collections/"+view.Collection.ULID.String(), bob, "")
+		if len(opened.Documents) != 1 || opened.Documents[0].Name != "notes.pdf" || opened.Missing != 1 {
+			t.Errorf("Expected bob to see notes only with one missing, got %+v", opened)
+		}
+
+		handler.DB.DeleteFolderPermission(folder, user.ID)
+		dave := &database.User{Username: "dave", PasswordHash: "unused", Role: database.RoleViewer}
+		handler.DB.CreateUser(dave)
+		handler.DB.SetFolderPermission(&database.FolderPermission{Folder: folder, UserID: dave.ID, Access: database.AccessRead})
+		_, shared := serve(http.MethodGet, view.ShareURL, "", "")
+		if len(shared.Documents) != 1 || shared.Documents[0].Name != "notes.pdf" || shared.Missing != 1 {
+			t.Errorf("Expected the share link to drop finance once carol lost it, got %+v", shared)
+		}
+	})
+}
+
+func TestFolderPermissionsOnRescanSuggestionsAndActivity(t *testing.T) {
+	// notes.pdf is in the root and finance/a.pdf is restricted to carol, a viewer; bob is an editor
+	handler := setupTestHandler(t)
+	handler.ServerConfig.WebUIPass = true
+	defer database.SetClock(database.NewFixedClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), time.Minute))()
+	root := handler.ServerConfig.DocumentPath
+	for _, name := range []string{"notes.pdf", "finance/a.pdf"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("%PDF"), 0644)
+		doc := saveTestDocument(t, handler.DB, path, "invoice")
+		handler.recordActivity(database.AuditEvent{Action: database.AuditDocumentAdded, Target: doc.ULID.String(), Name: doc.Name})
+	}
+	handler.recordActivity(database.AuditEvent{Action: database.AuditDocumentDeleted, Name: "b.pdf", Detail: filepath.Join(root, "finance")})
+	handler.recordActivity(database.AuditEvent{Action: database.AuditDocumentDeleted, Name: "old.pdf"})
+	bob := signInAs(t, handler, "bob", database.RoleEditor)
+	carol := signInAs(t, handler, "carol", database.RoleViewer)
+	user, _ := handler.DB.GetUserByUsername("carol")
+	folder, _ := handler.folderPermissionKey("finance")
+	handler.DB.SetFolderPermission(&database.FolderPermission{Folder: folder, UserID: user.ID, Access: database.AccessRead})
+	handler.Echo.Use(handler.RequireLogin())
+	handler.Echo.POST("/api/document/rescan", handler.RescanDocument)
+	handler.Echo.GET("/api/search/suggest", handler.SuggestSearch)
+	handler.Echo.GET("/api/activity", handler.GetActivity)
+	serve := func(method, target, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("rescan", func(t *testing.T) {
+		tests := []struct {
+			path, token string
+			want        int
+		}{
+			{"finance/a.pdf", bob, http.StatusNotFound},
+			{"finance/a.pdf", carol, http.StatusForbidden},
+			{"notes.pdf", bob, http.StatusOK},
+		}
+		for _, tt := range tests {
+			if rec := serve(http.MethodPost, "/api/document/rescan?force=true&path="+url.QueryEscape(tt.path), tt.token); rec.Code != tt.want {
+				t.Errorf("Rescanning %s: expected %d, got %d %s", tt.path, tt.want, rec.Code, rec.Body)
+			}
+		}
+	})
+
+	t.Run("suggestions", func(t *testing.T) {
+		tests := []struct {
+			token string
+			want  int
+		}{
+			{bob, 1},
+			{carol, 2},
+		}
+		for _, tt := range tests {
+			var suggestions dto.SearchCompletions
+			json.Unmarshal(serve(http.MethodGet, "/api/search/suggest?prefix=pdf", tt.token).Body.Bytes(), &suggestions)
+			if len(suggestions.Documents) != tt.want {
+				t.Errorf("Expected %d suggested documents, got %+v", tt.want, suggestions.Documents)
+			}
+			for _, document := range suggestions.Documents {
+				if tt.token == bob && document.Name != "notes.pdf" {
+					t.Errorf("Expected bob to be offered notes only, got %s", document.Name)
+				}
+			}
+		}
+	})
+
+	t.Run("activity", func(t *testing.T) {
+		tests := []struct {
+			token string
+			want  []string
+		}{
+			{bob, []string{"Added notes.pdf"}},
+			{carol, []string{"Deleted b.pdf", "Added a.pdf", "Added notes.pdf"}},
+		}
+		for _, tt := range tests {
+			var feed dto.ActivityFeed
+			json.Unmarshal(serve(http.MethodGet, "/api/activity?limit=1", tt.token).Body.Bytes(), &feed)
+			var got []string
+			for _, item := range feed.Items {
+				got = append(got, item.Summary)
+			}
+			for feed.HasNext {
+				next := feed.NextCursor
+				feed = dto.ActivityFeed{}
+				json.Unmarshal(serve(http.MethodGet, "/api/activity?limit=1&cursor="+next, tt.token).Body.Bytes(), &feed)
+				for _, item := range feed.Items {
+					got = append(got, item.Summary)
+				}
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"archive/zip"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// storedExtensions are already compressed, so they are stored in zips as they are rather than deflated again
+var storedExtensions = map[string]bool{
+	".pdf": true, ".jpg": true, ".jpeg": true, ".png": true, ".tiff": true, ".docx": true, ".zip": true,
+}
+
+// zipEntryName is where a document goes in a folder's zip: its path below the folder, or just its
+// name when it is not under the folder on disk
+func zipEntryName(folder string, document database.Document) string {
+	relative, err := filepath.Rel(filepath.FromSlash(folder), filepath.FromSlash(document.Path))
+	relative = filepath.ToSlash(relative)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, "../") {
+		return normaliseName(document.Name)
+	}
+	return normaliseName(path.Clean(relative))
+}
+
+// addZipEntry copies one document file into the zip
+func addZipEntry(archive *zip.Writer, name string, document database.Document) error {
+	file, err := os.Open(document.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	if storedExtensions[strings.ToLower(filepath.Ext(name))] {
+		header.Method = zip.Store
+	}
+	entry, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
+
+// DownloadFolder streams a zip of the documents in a folder
+// @Summary Download a folder as a zip
+// @Description Stream a zip of every document in a folder, or with recursive=true the whole branch with subfolders kept as paths in the zip.
+// @Description The zip is written while the files are read, one at a time, so memory use does not grow with the folder. Files missing from disk are left out,
+// @Description as are the documents in folders the account's folder permissions do not let it read.
+// @Tags Folders
+// @Produce application/zip
+// @Param folder path string true "Folder path (URL-encoded)"
+// @Param recursive query bool false "Include subfolders (default: false)"
+// @Success 200 {file} file "Zip of the folder's documents"
+// @Failure 400 {object} map[string]interface{} "Invalid recursive value"
+// @Failure 404 {object} map[string]interface{} "No documents in the folder"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /folder/{folder}/download [get]
+func (serverHandler *ServerHandler) DownloadFolder(c echo.Context) error {
+	folderName := c.Param("folder")
+	// Echo leaves path parameters escaped, and folder paths arrive with their slashes encoded
+	if unescaped, err := url.PathUnescape(folderName); err == nil {
+		folderName = unescaped
+	}
+	recursive, err := boolQueryParam(c, "recursive")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid recursive value, expected true or false",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	var documents []database.Document
+	if recursive {
+		documents, err = serverHandler.DB.GetDocumentsUnderFolder(folderName)
+	} else {
+		documents, err = serverHandler.DB.GetDocumentsByFolder(folderName)
+	}
+	if err != nil {
+		Logger.Error("Failed to list folder for download", "folder", folderName, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list folder documents",
+			"code":  dto.CodeInternal,
+		})
+	}
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		return err
+	}
+	documents = access.readable(documents)
+	if len(documents) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "No documents in folder",
+			"code":  dto.CodeNotFound,
+		})
+	}
+
+	zipName := path.Base(strings.TrimSuffix(filepath.ToSlash(folderName), "/"))
+	if zipName == "." || zipName == "/" {
+		zipName = "documents"
+	}
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "application/zip")
+	response.Header().Set(echo.HeaderContentDisposition, attachmentDisposition(zipName+".zip"))
+	response.WriteHeader(http.StatusOK)
+
+	ctx := c.Request().Context()
+	archive := zip.NewWriter(response)
+	added := 0
+	for _, document := range documents {
+		if ctx.Err() != nil {
+			Logger.Info("Folder download cancelled by client", "folder", folderName, "added", added)
+			return nil
+		}
+		if err := addZipEntry(archive, zipEntryName(folderName, document), document); err != nil {
+			if os.IsNotExist(err) {
+				Logger.Warn("Leaving missing file out of folder download", "path", document.Path)
+				continue
+			}
+			// Headers are already sent, so all we can do is stop and log
+			Logger.Error("Folder download failed", "folder", folderName, "path", document.Path, "error", err)
+			return nil
+		}
+		added++
+		response.Flush()
+	}
+	if err := archive.Close(); err != nil {
+		Logger.Error("Failed to finish folder download", "folder", folderName, "error", err)
+		return nil
+	}
+	Logger.Info("Folder downloaded", "folder", folderName, "recursive", recursive, "documents", added)
+	return nil
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestDownloadFolder(t *testing.T) {
+	// A year folder with a receipt, a subfolder with another, and a record whose file has gone
+	handler := setupTestHandler(t)
+	handler.Echo.GET("/api/folder/:folder/download", handler.DownloadFolder)
+	year := filepath.ToSlash(filepath.Join(handler.ServerConfig.DocumentPath, "2024"))
+	files := map[string]string{
+		"2024/receipt.pdf":     "receipt contents",
+		"2024/march/notes.txt": "march notes",
+		"2024/march/gone.pdf":  "",
+		"2025/other-year.txt":  "not this year",
+	}
+	for name, contents := range files {
+		path := filepath.Join(handler.ServerConfig.DocumentPath, filepath.FromSlash(name))
+		if contents != "" {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("Failed to create folder: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+		}
+		saveTestDocument(t, handler.DB, filepath.ToSlash(path), contents)
+	}
+	download := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/folder/"+url.PathEscape(year)+"/download"+query, nil))
+		return rec
+	}
+	entries := func(rec *httptest.ResponseRecorder) map[string]string {
+		t.Helper()
+		archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		if err != nil {
+			t.Fatalf("Response is not a zip (%d): %v", rec.Code, err)
+		}
+		contents := make(map[string]string)
+		for _, file := range archive.File {
+			reader, err := file.Open()
+			if err != nil {
+				t.Fatalf("Failed to open %s: %v", file.Name, err)
+			}
+			data, _ := io.ReadAll(reader)
+			reader.Close()
+			contents[file.Name] = string(data)
+		}
+		return contents
+	}
+
+	// Downloading the folder on its own and with its subfolders
+	flat := download("")
+	branch := download("?recursive=true")
+
+	// The zips hold the files below the folder, with subfolder paths, leaving out the missing file
+	if flat.Header().Get("Content-Type") != "application/zip" || flat.Header().Get("Content-Disposition") != attachmentDisposition("2024.zip") {
+		t.Errorf("Unexpected headers %v", flat.Header())
+	}
+	if got := entries(flat); len(got) != 1 || got["receipt.pdf"] != "receipt contents" {
+		t.Errorf("Unexpected flat zip %v", got)
+	}
+	got := entries(branch)
+	var names []string
+	for name := range got {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "march/notes.txt" || names[1] != "receipt.pdf" || got["march/notes.txt"] != "march notes" {
+		t.Errorf("Unexpected recursive zip %v", got)
+	}
+
+	// An empty folder is not found
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/folder/"+url.PathEscape(year+"/empty")+"/download", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an empty folder, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,337 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+var errFolderOutsideRoot = errors.New("folder is outside the document root")
+
+// folderSummary is one entry of the GET /api/folders listing
+type folderSummary struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Path          string `json:"path"` // relative to the document root, "/" for the root itself
+	ParentID      int    `json:"parentId"`
+	DocumentCount int    `json:"documentCount"`
+	Color         string `json:"color,omitempty"`
+	Icon          string `json:"icon,omitempty"`
+}
+
+// maxFolderIconLength is the most characters a folder icon may have, enough for any emoji sequence
+const maxFolderIconLength = 16
+
+// folderColorPattern matches the #rgb and #rrggbb colours folders may be labelled with
+var folderColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// folderKey returns the form folders are stored under: absolute, cleaned and slash-separated
+func folderKey(folder string) string {
+	if abs, err := filepath.Abs(folder); err == nil {
+		folder = abs
+	}
+	return filepath.ToSlash(filepath.Clean(folder))
+}
+
+// insideRoot reports whether folder is the root or below it; both must already be folder keys
+func insideRoot(root, folder string) bool {
+	return folder == root || strings.HasPrefix(folder, strings.TrimSuffix(root, "/")+"/")
+}
+
+// ensureFolder records folder and every folder between it and the document root
+func ensureFolder(db database.Repository, rootPath string, folder string) error {
+	root := folderKey(rootPath)
+	folder = folderKey(folder)
+	if !insideRoot(root, folder) {
+		return errFolderOutsideRoot
+	}
+	if _, err := db.EnsureFolder(root, ""); err != nil {
+		return err
+	}
+	parent := root
+	rel := strings.TrimPrefix(strings.TrimPrefix(folder, root), "/")
+	if rel == "" {
+		return nil
+	}
+	for _, name := range strings.Split(rel, "/") {
+		current := strings.TrimSuffix(parent, "/") + "/" + name
+		if _, err := db.EnsureFolder(current, parent); err != nil {
+			return err
+		}
+		parent = current
+	}
+	return nil
+}
+
+// recordFolder keeps the folder table in step with a document or folder change.
+// Failures are logged rather than returned since the filesystem change has already happened.
+func (serverHandler *ServerHandler) recordFolder(folder string) {
+	if err := ensureFolder(serverHandler.DB, serverHandler.ServerConfig.DocumentPath, folder); err != nil {
+		Logger.Warn("Unable to record folder", "folder", folder, "error", err)
+	}
+}
+
+// syncFolders adds any folder holding documents that is missing from the folder table.
+// With walk set the document directory is scanned too, which picks up empty folders created before the table existed.
+func syncFolders(db database.Repository, rootPath string, walk bool) (int, error) {
+	root := folderKey(rootPath)
+	existing, err := db.GetAllFolders()
+	if err != nil {
+		return 0, err
+	}
+	known := make(map[string]bool, len(existing))
+	for _, folder := range existing {
+		known[folder.Path] = true
+	}
+
+	counts, err := db.CountDocumentsByFolder()
+	if err != nil {
+		return 0, err
+	}
+	var candidates []string
+	for folder := range counts {
+		candidates = append(candidates, folderKey(folder))
+	}
+	if walk {
+		err := filepath.WalkDir(rootPath, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				candidates = append(candidates, folderKey(path))
+			}
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+	}
+	candidates = append(candidates, root)
+	sort.Strings(candidates)
+
+	added := 0
+	for _, folder := range candidates {
+		if known[folder] || !insideRoot(root, folder) {
+			continue
+		}
+		if err := ensureFolder(db, root, folder); err != nil {
+			return added, err
+		}
+		known[folder] = true
+		added++
+	}
+	return added, nil
+}
+
+// backfillFolders fills the folder table from the document directory at startup
+func (serverHandler *ServerHandler) backfillFolders() {
+	added, err := syncFolders(serverHandler.DB, serverHandler.ServerConfig.DocumentPath, true)
+	if err != nil {
+		Logger.Error("Unable to backfill folder table", "error", err)
+		return
+	}
+	if added > 0 {
+		Logger.Info("Backfilled folder table", "folders", added)
+	}
+}
+
+// relativeFolderKey returns a folder key relative to the root key, using "/" for the root itself
+func relativeFolderKey(root, folder string) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(folder, root), "/")
+	if rel == "" {
+		return "/"
+	}
+	return rel
+}
+
+// rootFolders returns the folders under the document root, parents before children
+func rootFolders(db database.Repository, root string) ([]database.Folder, error) {
+	folders, err := db.GetAllFolders()
+	if err != nil {
+		return nil, err
+	}
+	var inside []database.Folder
+	for _, folder := range folders {
+		if insideRoot(root, folder.Path) {
+			inside = append(inside, folder)
+		}
+	}
+	return inside, nil
+}
+
+// GetFolders lists every folder in the document tree with its document count
+// @Summary List folders
+// @Description Retrieve the folder tree as a flat list with parent IDs and the number of documents directly in each folder
+// @Tags Folders
+// @Accept json
+// @Produce json
+// @Success 200 {array} folderSummary "Folders, parents before children"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /folders [get]
+func (serverHandler *ServerHandler) GetFolders(context echo.Context) error {
+	root := folderKey(serverHandler.ServerConfig.DocumentPath)
+	if _, err := syncFolders(serverHandler.DB, root, false); err != nil {
+		Logger.Error("Unable to sync folder table", "error", err)
+		return context.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list folders",
+			"code":  dto.CodeInternal,
+		})
+	}
+	folders, err := rootFolders(serverHandler.DB, root)
+	if err != nil {
+		Logger.Error("Unable to list folders", "error", err)
+		return context.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list folders",
+			"code":  dto.CodeInternal,
+		})
+	}
+	counts, err := serverHandler.DB.CountDocumentsByFolder()
+	if err != nil {
+		Logger.Error("Unable to count documents by folder", "error", err)
+		return context.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list folders",
+			"code":  dto.CodeInternal,
+		})
+	}
+	documentCounts := make(map[string]int, len(counts))
+	for folder, count := range counts {
+		documentCounts[folderKey(folder)] += count
+	}
+
+	summaries := make([]folderSummary, 0, len(folders))
+	for _, folder := range folders {
+		summaries = append(summaries, newFolderSummary(root, folder, documentCounts[folder.Path]))
+	}
+	return context.JSON(http.StatusOK, summaries)
+}
+
+// newFolderSummary returns the listing entry of a folder under root
+func newFolderSummary(root string, folder database.Folder, documentCount int) folderSummary {
+	return folderSummary{
+		ID:            folder.ID,
+		Name:          folder.Name,
+		Path:          relativeFolderKey(root, folder.Path),
+		ParentID:      folder.ParentID,
+		DocumentCount: documentCount,
+		Color:         folder.Color,
+		Icon:          folder.Icon,
+	}
+}
+
+// folderStyleRequest is the body of PATCH /api/folders/:id; a field left out is not changed
+type folderStyleRequest struct {
+	Color *string `json:"color"` // #rgb or #rrggbb, empty to remove
+	Icon  *string `json:"icon"`  // an emoji or short text, empty for the default folder icon
+}
+
+// validateFolderStyle returns the problem with each field, or nil when the style can be saved
+func validateFolderStyle(color, icon string) map[string]string {
+	problems := make(map[string]string)
+	if color != "" && !folderColorPattern.MatchString(color) {
+		problems["color"] = "must be a colour such as #2e7d32 or #c00"
+	}
+	if utf8.RuneCountInString(icon) > maxFolderIconLength {
+		problems["icon"] = fmt.Sprintf("must be at most %d characters", maxFolderIconLength)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
+
+// UpdateFolderStyle sets the colour label and icon of a folder
+// @Summary Set a folder's colour and icon
+// @Description Set the colour label and icon shown for a folder in the browse tree. Fields left out are kept; an empty value removes it.
+// @Tags Folders
+// @Accept json
+// @Produce json
+// @Param id path int true "Folder ID from GET /api/folders"
+// @Param style body folderStyleRequest true "color (#rgb or #rrggbb) and icon"
+// @Success 200 {object} folderSummary "The folder"
+// @Failure 400 {object} dto.ErrorResponse "Invalid ID, body or style"
+// @Failure 404 {object} dto.ErrorResponse "Folder not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /folders/{id} [patch]
+func (serverHandler *ServerHandler) UpdateFolderStyle(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil || id <= 0 {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid folder ID",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	var request folderStyleRequest
+	if err := context.Bind(&request); err != nil {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	root := folderKey(serverHandler.ServerConfig.DocumentPath)
+	folders, err := rootFolders(serverHandler.DB, root)
+	if err != nil {
+		Logger.Error("Unable to list folders", "error", err)
+		return context.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to update folder",
+			"code":  dto.CodeInternal,
+		})
+	}
+	index := slices.IndexFunc(folders, func(folder database.Folder) bool { return folder.ID == id })
+	if index < 0 {
+		return context.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Folder not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	color, icon := folders[index].Color, folders[index].Icon
+	if request.Color != nil {
+		color = strings.ToLower(strings.TrimSpace(*request.Color))
+	}
+	if request.Icon != nil {
+		icon = strings.TrimSpace(*request.Icon)
+	}
+	if problems := validateFolderStyle(color, icon); problems != nil {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "The folder style needs fixing",
+			"code":   dto.CodeValidation,
+			"fields": problems,
+		})
+	}
+
+	folder, err := serverHandler.DB.SetFolderStyle(id, color, icon)
+	if err != nil {
+		Logger.Error("Unable to update folder style", "id", id, "error", err)
+		return context.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to update folder",
+			"code":  dto.CodeInternal,
+		})
+	}
+	serverHandler.invalidateDocumentCache()
+	Logger.Info("Updated folder style", "folder", folder.Path, "color", color, "icon", icon)
+
+	counts, err := serverHandler.DB.CountDocumentsByFolder()
+	if err != nil {
+		Logger.Warn("Unable to count documents by folder", "error", err)
+	}
+	documentCount := 0
+	for path, count := range counts {
+		if folderKey(path) == folder.Path {
+			documentCount += count
+		}
+	}
+	return context.JSON(http.StatusOK, newFolderSummary(root, *folder, documentCount))
+}
@@ -0,0 +1,242 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestFolderTableFollowsDocumentTree(t *testing.T) {
+	// An empty folder made before the table existed and a document in a nested folder
+	handler := setupTestHandler(t)
+	root := handler.ServerConfig.DocumentPath
+	if err := os.MkdirAll(filepath.Join(root, "empty"), 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	nested := filepath.Join(root, "bills", "2024")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	docPath := filepath.Join(nested, "gas.pdf")
+	if err := os.WriteFile(docPath, []byte("%PDF-1.4"), 0644); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	saveTestDocument(t, handler.DB, docPath, "")
+
+	// Startup backfills the table and the folders are listed
+	handler.backfillFolders()
+	rec := httptest.NewRecorder()
+	if err := handler.GetFolders(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/folders", nil), rec)); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	// Every folder is listed parents first with its document count
+	var folders []folderSummary
+	if err := json.Unmarshal(rec.Body.Bytes(), &folders); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	byPath := make(map[string]folderSummary)
+	for _, folder := range folders {
+		byPath[folder.Path] = folder
+	}
+	if len(folders) != 4 || folders[0].Path != "/" {
+		t.Fatalf("Expected root, bills, bills/2024 and empty, got %+v", folders)
+	}
+	if byPath["bills/2024"].ParentID != byPath["bills"].ID || byPath["bills/2024"].DocumentCount != 1 {
+		t.Errorf("Unexpected nested folder %+v", byPath["bills/2024"])
+	}
+
+	// The tree API places the document under its folder without walking the directory
+	tree, err := fileTree(root, handler.DB, "")
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	parents := make(map[string]string)
+	for _, node := range tree.FileSystem {
+		parents[node.Name] = node.ParentID
+	}
+	if len(tree.FileSystem) != 5 || parents["gas.pdf"] != "folder-"+strconv.Itoa(byPath["bills/2024"].ID) {
+		t.Errorf("Unexpected tree %+v", tree.FileSystem)
+	}
+
+	// A folder is deleted
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/api/document?path=bills", nil)
+	if err := handler.DeleteFile(handler.Echo.NewContext(req, rec)); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Delete failed: %d %v", rec.Code, err)
+	}
+
+	// Its subtree leaves the table
+	remaining, err := handler.DB.GetAllFolders()
+	if err != nil {
+		t.Fatalf("Failed to list folders: %v", err)
+	}
+	if len(remaining) != 2 {
+		t.Errorf("Expected root and empty to remain, got %+v", remaining)
+	}
+}
+
+func TestEnsureFolderRejectsPathsOutsideRoot(t *testing.T) {
+	handler := setupTestHandler(t)
+	if err := ensureFolder(handler.DB, handler.ServerConfig.DocumentPath, handler.ServerConfig.IngressPath); err != errFolderOutsideRoot {
+		t.Errorf("Expected errFolderOutsideRoot, got %v", err)
+	}
+}
+
+func TestGetFolderRecursive(t *testing.T) {
+	// Documents in a folder, a subfolder and a sibling folder sharing the name prefix
+	handler := setupTestHandler(t)
+	root := handler.ServerConfig.DocumentPath
+	for _, rel := range []string{"bills/gas.pdf", "bills/2024/water.pdf", "bills-old/phone.pdf"} {
+		saveTestDocument(t, handler.DB, filepath.Join(root, rel), "")
+	}
+	handler.Echo.GET("/api/folder/:folder", handler.GetFolder)
+	get := func(target string) (*httptest.ResponseRecorder, []string) {
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var docs []struct{ Name string }
+		json.Unmarshal(rec.Body.Bytes(), &docs)
+		var names []string
+		for _, doc := range docs {
+			names = append(names, doc.Name)
+		}
+		return rec, names
+	}
+	folder := "/api/folder/" + url.PathEscape(filepath.Join(root, "bills"))
+
+	// The folder is listed with and without recursion
+	_, direct := get(folder)
+	rec, all := get(folder + "?recursive=true")
+
+	// Only the recursive listing includes the subfolder, and neither includes the sibling
+	if len(direct) != 1 || direct[0] != "gas.pdf" {
+		t.Errorf("Expected only gas.pdf, got %v", direct)
+	}
+	if rec.Code != http.StatusOK || len(all) != 2 || all[0] != "gas.pdf" || all[1] != "water.pdf" {
+		t.Errorf("Expected gas.pdf and water.pdf, got %d %v", rec.Code, all)
+	}
+	if rec, _ := get(folder + "?recursive=maybe"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid recursive value, got %d", rec.Code)
+	}
+}
+
+func TestUpdateFolderStyle(t *testing.T) {
+	// Finance and Medical folders
+	handler := setupTestHandler(t)
+	root := handler.ServerConfig.DocumentPath
+	for _, name := range []string{"Finance", "Medical"} {
+		if err := os.MkdirAll(filepath.Join(root, name), 0755); err != nil {
+			t.Fatalf("Failed to create folder: %v", err)
+		}
+	}
+	handler.backfillFolders()
+	folders, _ := rootFolders(handler.DB, folderKey(root))
+	ids := make(map[string]int)
+	for _, folder := range folders {
+		ids[folder.Name] = folder.ID
+	}
+	patch := func(id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/folders/"+id, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := handler.Echo.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		if err := handler.UpdateFolderStyle(c); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return rec
+	}
+	finance := strconv.Itoa(ids["Finance"])
+
+	// Finance is given a colour and icon, then only its colour is changed
+	first := patch(finance, `{"color":"#2E7D32","icon":"💷"}`)
+	rec := patch(finance, `{"color":"#1565c0"}`)
+
+	// The colour is replaced and the icon kept
+	var summary folderSummary
+	json.Unmarshal(rec.Body.Bytes(), &summary)
+	if first.Code != http.StatusOK || rec.Code != http.StatusOK || summary.Path != "Finance" || summary.Color != "#1565c0" || summary.Icon != "💷" {
+		t.Fatalf("Unexpected response %d %d %s", first.Code, rec.Code, rec.Body.String())
+	}
+
+	// The tree gives Finance its style and leaves Medical plain
+	tree, err := fileTree(root, handler.DB, "")
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	for _, node := range tree.FileSystem {
+		switch node.Name {
+		case "Finance":
+			if node.Color != "#1565c0" || node.Icon != "💷" {
+				t.Errorf("Expected Finance styled in the tree, got %+v", node)
+			}
+		case "Medical":
+			if node.Color != "" || node.Icon != "" {
+				t.Errorf("Expected Medical unstyled, got %+v", node)
+			}
+		}
+	}
+
+	// Bad styles, IDs and missing folders are refused
+	for _, test := range []struct {
+		id, body string
+		code     int
+	}{
+		{finance, `{"color":"green"}`, http.StatusBadRequest},
+		{finance, `{"icon":"` + strings.Repeat("x", maxFolderIconLength+1) + `"}`, http.StatusBadRequest},
+		{"abc", `{}`, http.StatusBadRequest},
+		{"9999", `{"color":"#fff"}`, http.StatusNotFound},
+	} {
+		if rec := patch(test.id, test.body); rec.Code != test.code {
+			t.Errorf("%s %s: expected %d, got %d %s", test.id, test.body, test.code, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestFileTreeNaturalOrder(t *testing.T) {
+	// Folders and documents whose names sort differently by character and by number
+	handler := setupTestHandler(t)
+	root := handler.ServerConfig.DocumentPath
+	for _, name := range []string{"2024", "Archive", "10", "9"} {
+		if err := os.MkdirAll(filepath.Join(root, name), 0755); err != nil {
+			t.Fatalf("Failed to create folder: %v", err)
+		}
+	}
+	for _, name := range []string{"scan10.pdf", "Scan2.pdf", "a.pdf", "scan1.pdf"} {
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, []byte("%PDF-1.4"), 0644); err != nil {
+			t.Fatalf("Failed to write document: %v", err)
+		}
+		saveTestDocument(t, handler.DB, path, "")
+	}
+	handler.backfillFolders()
+
+	// The tree is built
+	tree, err := fileTree(root, handler.DB, "en")
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+
+	// The root lists its folders first, then its documents, each in natural order
+	want := []string{"9", "10", "2024", "Archive", "a.pdf", "scan1.pdf", "Scan2.pdf", "scan10.pdf"}
+	if got := tree.FileSystem[0].ChildrenIDs; !slices.Equal(got, want) {
+		t.Errorf("Expected children %v, got %v", want, got)
+	}
+	var listed []string
+	for _, node := range tree.FileSystem[1:] {
+		listed = append(listed, node.Name)
+	}
+	if !slices.Equal(listed, want) {
+		t.Errorf("Expected the nodes in the same order, got %v", listed)
+	}
+}
@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+)
+
+// maxHookOutput is how much of a hook's output is kept in the log and the document's timeline
+const maxHookOutput = 2048
+
+// hookDocument is the metadata a post-ingestion hook receives, as JSON on a command's standard input
+// or a webhook's request body
+type hookDocument struct {
+	Event       string    `json:"event"` // always "document.ingested"
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Path        string    `json:"path"`
+	Folder      string    `json:"folder"`
+	Hash        string    `json:"hash"`
+	MIMEType    string    `json:"mimeType,omitempty"`
+	URL         string    `json:"url"`
+	IngressTime time.Time `json:"ingressTime"`
+	Source      string    `json:"source"` // how the document arrived: ingress or upload
+}
+
+// environment returns the metadata as GODOCS_* variables for a hook command
+func (h hookDocument) environment() []string {
+	return []string{
+		"GODOCS_EVENT=" + h.Event,
+		"GODOCS_DOCUMENT_ID=" + h.ID,
+		"GODOCS_DOCUMENT_NAME=" + h.Name,
+		"GODOCS_DOCUMENT_PATH=" + h.Path,
+		"GODOCS_DOCUMENT_FOLDER=" + h.Folder,
+		"GODOCS_DOCUMENT_HASH=" + h.Hash,
+		"GODOCS_DOCUMENT_MIME_TYPE=" + h.MIMEType,
+		"GODOCS_DOCUMENT_URL=" + h.URL,
+		"GODOCS_SOURCE=" + h.Source,
+	}
+}
+
+// runPostIngestHooks runs POST_INGEST_COMMAND and POSTs to each of POST_INGEST_WEBHOOKS for a document
+// that has just been ingested. Each hook has POST_INGEST_TIMEOUT seconds. What it printed or answered
+// is logged and kept on the document's timeline; a failure is listed on the job under items but does
+// not undo the ingestion.
+func (serverHandler *ServerHandler) runPostIngestHooks(doc *database.Document, source string, items *jobItems) {
+	cfg := serverHandler.ServerConfig
+	if cfg.PostIngestCommand == "" && len(cfg.PostIngestWebhooks) == 0 {
+		return
+	}
+	payload := hookDocument{
+		Event:       "document.ingested",
+		ID:          doc.ULID.String(),
+		Name:        doc.Name,
+		Path:        doc.Path,
+		Folder:      doc.Folder,
+		Hash:        doc.Hash,
+		MIMEType:    doc.MIMEType,
+		URL:         documentViewURL(doc.ULID),
+		IngressTime: doc.IngressTime,
+		Source:      source,
+	}
+	if cfg.UseReverseProxy {
+		payload.URL = strings.TrimSuffix(cfg.BaseURL, "/") + payload.URL
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		Logger.Error("Unable to encode document for post-ingestion hooks", "ulid", payload.ID, "error", err)
+		return
+	}
+	timeout := time.Duration(cfg.PostIngestTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	type hook struct {
+		name string
+		run  func(ctx context.Context) (string, error)
+	}
+	var hooks []hook
+	if cfg.PostIngestCommand != "" {
+		hooks = append(hooks, hook{"command", func(ctx context.Context) (string, error) {
+			return runHookCommand(ctx, cfg.PostIngestCommand, body, payload.environment())
+		}})
+	}
+	for _, url := range cfg.PostIngestWebhooks {
+		hooks = append(hooks, hook{"webhook " + webhookHost(url), func(ctx context.Context) (string, error) {
+			return postHookWebhook(ctx, url, body)
+		}})
+	}
+	for _, h := range hooks {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		started := time.Now()
+		output, err := h.run(ctx)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", timeout) // rather than the signal that killed it
+		}
+		cancel()
+		output = truncateHookOutput(output)
+		if err != nil {
+			Logger.Error("Post-ingestion hook failed", "hook", h.name, "ulid", payload.ID, "output", output, "error", err)
+			recordStage(serverHandler.DB, []string{payload.ID}, stageHook, h.name+" failed: "+err.Error()+hookOutputDetail(output))
+			items.add(doc.Path, fmt.Errorf("%w: %s: %w", errHookFailed, h.name, err))
+			continue
+		}
+		Logger.Info("Post-ingestion hook ran", "hook", h.name, "ulid", payload.ID, "duration", time.Since(started), "output", output)
+		recordStage(serverHandler.DB, []string{payload.ID}, stageHook, h.name+hookOutputDetail(output))
+	}
+}
+
+// runHookCommand runs the command line, split on spaces and without a shell, with the document's JSON
+// on standard input and its metadata in the environment. It returns what the command wrote to
+// standard output and standard error.
+func runHookCommand(ctx context.Context, commandLine string, body []byte, env []string) (string, error) {
+	args := strings.Fields(commandLine)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.WaitDelay = time.Second // don't wait on pipes held open by a killed command's children
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	return output.String(), err
+}
+
+// postHookWebhook POSTs the document's JSON to url and returns the start of the response body.
+// Any status other than 2xx is a failure.
+func postHookWebhook(ctx context.Context, url string, body []byte) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "godocs")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	answer, _ := io.ReadAll(io.LimitReader(response.Body, maxHookOutput+1))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return string(answer), fmt.Errorf("answered %s", response.Status)
+	}
+	return string(answer), nil
+}
+
+// webhookHost names a webhook by its host, so tokens in its path or query stay out of logs
+func webhookHost(url string) string {
+	rest := url[strings.Index(url, "://")+3:]
+	if end := strings.IndexAny(rest, "/?#"); end >= 0 {
+		rest = rest[:end]
+	}
+	if at := strings.LastIndex(rest, "@"); at >= 0 {
+		rest = rest[at+1:]
+	}
+	return rest
+}
+
+// truncateHookOutput trims a hook's output to maxHookOutput bytes
+func truncateHookOutput(output string) string {
+	output = strings.TrimSpace(output)
+	if len(output) > maxHookOutput {
+		output = output[:maxHookOutput] + "…"
+	}
+	return output
+}
+
+// hookOutputDetail appends a hook's output to its timeline detail
+func hookOutputDetail(output string) string {
+	if output == "" {
+		return ""
+	}
+	return ": " + output
+}
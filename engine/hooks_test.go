@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+// hookStages returns the details of a document's hook stages
+func hookStages(t *testing.T, db database.Repository, doc *database.Document) []string {
+	t.Helper()
+	events, err := db.GetDocumentEvents(doc.ULID.String())
+	if err != nil {
+		t.Fatalf("GetDocumentEvents failed: %v", err)
+	}
+	var details []string
+	for _, event := range events {
+		if event.Stage == stageHook {
+			details = append(details, event.Detail)
+		}
+	}
+	return details
+}
+
+func TestPostIngestHooksReceiveDocument(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat is not available")
+	}
+	// A webhook and a command that echoes what it is sent
+	var received hookDocument
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON webhook, got %q", r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte("booked"))
+	}))
+	defer webhook.Close()
+	handler := setupTestHandler(t)
+	handler.ServerConfig.PostIngestCommand = "cat"
+	handler.ServerConfig.PostIngestWebhooks = []string{webhook.URL + "/invoices?token=secret"}
+	handler.ServerConfig.PostIngestTimeout = 5
+	doc := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "invoice.pdf"), "text")
+	var items jobItems
+
+	// The hooks run for the document
+	handler.runPostIngestHooks(doc, "upload", &items)
+
+	// The webhook was sent the document's metadata
+	if received.ID != doc.ULID.String() || received.Name != "invoice.pdf" || received.Source != "upload" || received.URL != documentViewURL(doc.ULID) {
+		t.Errorf("Unexpected webhook payload: %+v", received)
+	}
+
+	// Both hooks are on the timeline with their output, and the webhook's token is not
+	stages := hookStages(t, handler.DB, doc)
+	if len(stages) != 2 {
+		t.Fatalf("Expected two hook stages, got %v", stages)
+	}
+	if !strings.HasPrefix(stages[0], "command: ") || !strings.Contains(stages[0], `"id":"`+doc.ULID.String()+`"`) {
+		t.Errorf("Expected the command's output on the timeline, got %q", stages[0])
+	}
+	if !strings.HasSuffix(stages[1], ": booked") || strings.Contains(stages[1], "secret") {
+		t.Errorf("Expected the webhook's answer without its token, got %q", stages[1])
+	}
+	if len(items.items) != 0 {
+		t.Errorf("Expected no failed items, got %v", items.items)
+	}
+}
+
+func TestPostIngestHookFailures(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not available")
+	}
+	// A command that outlives the timeout and a webhook that refuses the document
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such ledger", http.StatusUnprocessableEntity)
+	}))
+	defer webhook.Close()
+	handler := setupTestHandler(t)
+	handler.ServerConfig.PostIngestCommand = "sleep 10"
+	handler.ServerConfig.PostIngestWebhooks = []string{webhook.URL}
+	handler.ServerConfig.PostIngestTimeout = 1
+	doc := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "invoice.pdf"), "text")
+	var items jobItems
+
+	// The hooks run for the document
+	handler.runPostIngestHooks(doc, "ingress", &items)
+
+	// Both failures are listed on the job with the hook error code
+	if len(items.items) != 2 {
+		t.Fatalf("Expected two failed items, got %v", items.items)
+	}
+	for _, item := range items.items {
+		if item.Code != dto.CodeHookFailed || item.File != doc.Name {
+			t.Errorf("Expected a %s item for the document, got %+v", dto.CodeHookFailed, item)
+		}
+	}
+	if !strings.Contains(items.items[0].Error, "timed out") {
+		t.Errorf("Expected the command to time out, got %q", items.items[0].Error)
+	}
+
+	// The timeline keeps what the webhook answered
+	stages := hookStages(t, handler.DB, doc)
+	if len(stages) != 2 || !strings.Contains(stages[1], "422") || !strings.Contains(stages[1], "no such ledger") {
+		t.Errorf("Expected the refusal on the timeline, got %v", stages)
+	}
+}
+
+func TestPostIngestHooksUnconfigured(t *testing.T) {
+	// No hooks configured
+	handler := setupTestHandler(t)
+	doc := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "a.pdf"), "text")
+
+	// A document is ingested
+	handler.runPostIngestHooks(doc, "ingress", nil)
+
+	// Nothing is recorded
+	if stages := hookStages(t, handler.DB, doc); len(stages) != 0 {
+		t.Errorf("Expected no hook stages, got %v", stages)
+	}
+}
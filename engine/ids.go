@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+	"github.com/oklog/ulid/v2"
+)
+
+// parseULID reads a document, collection, job or smart folder ID from a request. Upper and lower case
+// are both accepted; use the result's String(), the upper case form, in queries and links.
+func parseULID(raw string) (ulid.ULID, error) {
+	return ulid.ParseStrict(strings.TrimSpace(raw))
+}
+
+// invalidID answers 400 GODOCS_INVALID_ID for an ID of the given kind ("document", "job", ...)
+func invalidID(c echo.Context, kind string) error {
+	return c.JSON(http.StatusBadRequest, map[string]interface{}{
+		"error": "Invalid " + kind + " ULID",
+		"code":  dto.CodeInvalidID,
+	})
+}
+
+// ulidParam reads the ULID path parameter name. When it is not a ULID the 400 response has been sent,
+// ok is false and the handler returns err.
+func ulidParam(c echo.Context, name, kind string) (id ulid.ULID, ok bool, err error) {
+	id, err = parseULID(c.Param(name))
+	if err != nil {
+		return id, false, invalidID(c, kind)
+	}
+	return id, true, nil
+}
+
+// ulidQuery reads every value of the query parameter name as ULIDs in canonical form. When one is not
+// a ULID the 400 response has been sent, ok is false and the handler returns err.
+func ulidQuery(c echo.Context, name, kind string) (ids []string, ok bool, err error) {
+	for _, raw := range c.QueryParams()[name] {
+		id, err := parseULID(raw)
+		if err != nil {
+			return nil, false, invalidID(c, kind)
+		}
+		ids = append(ids, id.String())
+	}
+	return ids, true, nil
+}
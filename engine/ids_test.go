@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+func TestIDsValidatedAtTheBoundary(t *testing.T) {
+	// A document and a job
+	handler := setupTestHandler(t)
+	handler.Echo.GET("/api/document/:id", handler.GetDocument)
+	handler.Echo.GET("/api/document/:id/text", handler.GetDocumentText)
+	handler.Echo.GET("/api/jobs/:id", handler.GetJob)
+	handler.Echo.DELETE("/api/document/*", handler.DeleteFile)
+	handler.Echo.PATCH("/api/document/move/*", handler.MoveDocuments)
+	document := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "a.pdf"), "text")
+	job, err := handler.DB.CreateJob(database.JobTypeCleanup, "test")
+	if err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	serve := func(method, target string) (int, map[string]interface{}) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response
+	}
+	lower := strings.ToLower(document.ULID.String())
+
+	t.Run("Lower case IDs find the same document and job", func(t *testing.T) {
+		if code, response := serve(http.MethodGet, "/api/document/"+lower); code != http.StatusOK || response["ULID"] != document.ULID.String() {
+			t.Errorf("Expected the document for a lower case ID, got %d %v", code, response)
+		}
+		if code, _ := serve(http.MethodGet, "/api/document/"+lower+"/text"); code != http.StatusOK {
+			t.Errorf("Expected the text for a lower case ID, got %d", code)
+		}
+		if code, _ := serve(http.MethodGet, "/api/jobs/"+strings.ToLower(job.ID.String())); code != http.StatusOK {
+			t.Errorf("Expected the job for a lower case ID, got %d", code)
+		}
+	})
+
+	t.Run("Malformed IDs are refused before reaching the database", func(t *testing.T) {
+		for _, request := range []struct{ method, target string }{
+			{http.MethodGet, "/api/document/not-a-ulid"},
+			{http.MethodGet, "/api/document/" + document.ULID.String() + "X"},
+			{http.MethodGet, "/api/document/80000000000000000000000000"}, // overflows 128 bits
+			{http.MethodGet, "/api/document/01ARZ3NDEKTSV4RRFFQ69G5FA!/text"},
+			{http.MethodGet, "/api/jobs/12345"},
+			{http.MethodDelete, "/api/document/?id=bad&path=a.pdf"},
+			{http.MethodPatch, "/api/document/move/?folder=x&id=" + lower + "&id=bad"},
+		} {
+			code, response := serve(request.method, request.target)
+			if code != http.StatusBadRequest || response["code"] != string(dto.CodeInvalidID) {
+				t.Errorf("%s %s: expected 400 %s, got %d %v", request.method, request.target, dto.CodeInvalidID, code, response)
+			}
+		}
+		if moved, err := handler.DB.GetDocumentByULID(document.ULID.String()); err != nil || moved.Folder != document.Folder {
+			t.Errorf("Expected a move with a bad ID to change nothing, got %+v %v", moved, err)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// GetIndexUsage reports how often each database index has been used
+// @Summary Index usage
+// @Description Scan counts for every index from PostgreSQL's statistics, least used first. An index still at zero scans after a busy week is only slowing down writes; pair it with /admin/slow-queries to see which are missing. With sign-in or ADMIN_USERS only administrators may read it.
+// @Tags System
+// @Produce json
+// @Success 200 {array} database.IndexUsage "Index usage, least used first"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 404 {object} dto.ErrorResponse "The database is not PostgreSQL"
+// @Router /admin/index-usage [get]
+func (serverHandler *ServerHandler) GetIndexUsage(c echo.Context) error {
+	if serverHandler.notAdmin(c) {
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": "Only administrators may read index usage",
+			"code":  dto.CodeForbidden,
+		})
+	}
+	usage, err := serverHandler.DB.IndexUsage()
+	if errors.Is(err, database.ErrIndexUsageUnsupported) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Index usage statistics are only kept by PostgreSQL",
+			"code":  dto.CodeFeatureDisabled,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to read index usage", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to read index usage",
+			"code":  dto.CodeInternal,
+		})
+	}
+	if usage == nil {
+		usage = []database.IndexUsage{}
+	}
+	return c.JSON(http.StatusOK, usage)
+}
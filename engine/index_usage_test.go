@@ -0,0 +1,29 @@
+package engine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetIndexUsageNeedsPostgres(t *testing.T) {
+	// A SQLite server with alice as its administrator
+	handler := setupTestHandler(t)
+	handler.ServerConfig.AdminUsers = []string{"alice"}
+	handler.Echo.GET("/api/admin/index-usage", handler.GetIndexUsage)
+	get := func(user string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/index-usage", nil)
+		req.SetBasicAuth(user, "secret")
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Bob is refused and alice is told SQLite keeps no index statistics
+	if code := get("bob"); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for bob, got %d", code)
+	}
+	if code := get("alice"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 on SQLite, got %d", code)
+	}
+}
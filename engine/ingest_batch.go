@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"strconv"
+
+	"github.com/drummonds/godocs/database"
+)
+
+// maxIngestBatchSize keeps multi-row inserts well inside database parameter limits
+const maxIngestBatchSize = 500
+
+// ingestBatch collects ingested documents and their word counts so an ingestion job
+// writes them in one transaction per batch instead of several statements per document
+type ingestBatch struct {
+	db         database.Repository
+	size       int
+	docs       []*database.Document
+	wordCounts map[string]int
+	hashes     map[string]string // hash -> name of documents waiting to be written
+	tokenizer  *database.WordTokenizer
+	items      *jobItems                    // documents that could not be written are listed here
+	saved      func(doc *database.Document) // called for each document once it is written, when set
+}
+
+// newIngestBatch returns a batch that flushes every size documents, or nil when size is too small for batching to help
+func newIngestBatch(db database.Repository, size int) *ingestBatch {
+	if size <= 1 {
+		return nil
+	}
+	if size > maxIngestBatchSize {
+		size = maxIngestBatchSize
+	}
+	return &ingestBatch{
+		db:         db,
+		size:       size,
+		wordCounts: make(map[string]int),
+		hashes:     make(map[string]string),
+		tokenizer:  database.NewWordTokenizer(),
+	}
+}
+
+// pending returns the name of a waiting document with the given hash, so duplicates within one batch are caught
+func (batch *ingestBatch) pending(hash string) (string, bool) {
+	name, ok := batch.hashes[hash]
+	return name, ok
+}
+
+// add queues a fully processed document and flushes when the batch is full.
+// It returns how many queued documents could not be written.
+func (batch *ingestBatch) add(doc *database.Document) int {
+	batch.docs = append(batch.docs, doc)
+	batch.hashes[doc.Hash] = doc.Name
+	for word, count := range batch.tokenizer.TokenizeAndCount(doc.FullText + " " + doc.Name) {
+		batch.wordCounts[word] += count
+	}
+	if len(batch.docs) >= batch.size {
+		return batch.flush()
+	}
+	return 0
+}
+
+// flush writes the queued documents and word counts and returns how many documents could not be written.
+// If the batch transaction fails each document is retried on its own so one bad row does not lose the rest.
+func (batch *ingestBatch) flush() int {
+	if len(batch.docs) == 0 {
+		return 0
+	}
+	docs := batch.docs
+	err := batch.db.SaveDocumentBatch(docs, batch.wordCounts)
+	batch.docs = nil
+	batch.wordCounts = make(map[string]int)
+	batch.hashes = make(map[string]string)
+	if err == nil {
+		ulids := make([]string, 0, len(docs))
+		for _, doc := range docs {
+			ulids = append(ulids, doc.ULID.String())
+		}
+		recordStage(batch.db, ulids, stageIndexed, "batch of "+strconv.Itoa(len(docs)))
+		recordStage(batch.db, ulids, stageWordCloud, "")
+		Logger.Info("Wrote ingestion batch", "documents", len(docs))
+		batch.afterSave(docs...)
+		return 0
+	}
+
+	Logger.Warn("Ingestion batch failed, saving documents one at a time", "documents", len(docs), "error", err)
+	failed := 0
+	for _, doc := range docs {
+		if err := batch.db.SaveDocument(doc); err != nil {
+			Logger.Error("Failed to save ingested document; its file is in document storage without a database entry",
+				"path", doc.Path, "error", err)
+			recordStage(batch.db, []string{doc.ULID.String()}, stageFailed, err.Error())
+			batch.items.add(doc.Path, err)
+			failed++
+			continue
+		}
+		recordStage(batch.db, []string{doc.ULID.String()}, stageIndexed, "")
+		batch.afterSave(doc)
+		if err := batch.db.UpdateWordFrequencies(doc.ULID.String()); err != nil {
+			Logger.Warn("Failed to update word frequencies", "ulid", doc.ULID.String(), "error", err)
+			continue
+		}
+		recordStage(batch.db, []string{doc.ULID.String()}, stageWordCloud, "")
+	}
+	return failed
+}
+
+// afterSave passes documents that have been written to saved
+func (batch *ingestBatch) afterSave(docs ...*database.Document) {
+	if batch.saved == nil {
+		return
+	}
+	for _, doc := range docs {
+		batch.saved(doc)
+	}
+}
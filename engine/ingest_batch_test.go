@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+func TestIngestJobWritesBatches(t *testing.T) {
+	for _, batchSize := range []int{1, 2} {
+		t.Run(fmt.Sprintf("batch size %d", batchSize), func(t *testing.T) {
+			testIngestJob(t, batchSize)
+		})
+	}
+}
+
+// testIngestJob runs an ingestion job over five text files and a duplicate with the given batch size
+func testIngestJob(t *testing.T, batchSize int) {
+	// Five text files and a copy of one of them in the ingress folder
+	handler := setupTestHandler(t)
+	handler.ServerConfig.IngestBatchSize = batchSize
+	for i := 0; i < 5; i++ {
+		path := filepath.Join(handler.ServerConfig.IngressPath, fmt.Sprintf("note%d.txt", i))
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("platypus note %d", i)), 0644); err != nil {
+			t.Fatalf("Failed to write ingress file: %v", err)
+		}
+	}
+	copyPath := filepath.Join(handler.ServerConfig.IngressPath, "zz-copy.txt")
+	if err := os.WriteFile(copyPath, []byte("platypus note 0"), 0644); err != nil {
+		t.Fatalf("Failed to write ingress file: %v", err)
+	}
+	job, err := handler.DB.CreateJob(database.JobTypeIngestion, "test")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	// The ingestion job runs
+	handler.ingressJobFuncWithTracking(handler.ServerConfig, handler.DB, job.ID)
+
+	// Every distinct file is stored with its text and URL, and the copy is skipped
+	documents, err := handler.DB.GetAllDocuments()
+	if err != nil {
+		t.Fatalf("Failed to list documents: %v", err)
+	}
+	if len(documents) != 5 {
+		t.Fatalf("Expected 5 documents, got %d", len(documents))
+	}
+	for _, doc := range documents {
+		if !strings.HasPrefix(doc.FullText, "platypus note") || doc.URL != "/document/view/"+doc.ULID.String() {
+			t.Errorf("Unexpected document %s: text %q, url %q", doc.Name, doc.FullText, doc.URL)
+		}
+		if _, err := os.Stat(doc.Path); err != nil {
+			t.Errorf("Document file missing: %v", err)
+		}
+	}
+	if _, err := os.Stat(copyPath); !os.IsNotExist(err) {
+		t.Errorf("Expected duplicate ingress file to be removed, got %v", err)
+	}
+
+	// The word counts were written once, without a full recalculation
+	words, err := handler.DB.GetTopWords(10)
+	if err != nil {
+		t.Fatalf("Failed to get top words: %v", err)
+	}
+	counts := make(map[string]int)
+	for _, w := range words {
+		counts[w.Word] = w.Frequency
+	}
+	if counts["platypus"] != 5 {
+		t.Errorf("Expected platypus counted 5 times, got %+v", words)
+	}
+	completed, err := handler.DB.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if !strings.Contains(completed.Result, `"errors": 0`) || !strings.Contains(completed.Result, `"duplicates": 1`) {
+		t.Errorf("Unexpected job result %s", completed.Result)
+	}
+
+	// The skipped copy is listed against its file with the duplicate code
+	var result struct {
+		Items []dto.JobItem `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(completed.Result), &result); err != nil {
+		t.Fatalf("Job result is not JSON: %v", err)
+	}
+	if len(result.Items) != 1 || result.Items[0].File != "zz-copy.txt" || result.Items[0].Code != dto.CodeDuplicate {
+		t.Errorf("Expected the copy listed as a duplicate, got %+v", result.Items)
+	}
+}
+
+func TestNewIngestBatchSize(t *testing.T) {
+	if batch := newIngestBatch(nil, 1); batch != nil {
+		t.Error("Expected a batch size of 1 to disable batching")
+	}
+	if batch := newIngestBatch(nil, 10000); batch == nil || batch.size != maxIngestBatchSize {
+		t.Errorf("Expected batch size to be capped at %d", maxIngestBatchSize)
+	}
+}
@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultIngestRejections is the number of rejected files listed when no limit is given
+const defaultIngestRejections = 50
+
+// rejectIngressFile leaves a file the ingress walk cannot ingest where it is and records why, so the
+// UI can tell the user rather than the file silently never appearing
+func (serverHandler *ServerHandler) rejectIngressFile(path string, reason error) {
+	Logger.Warn("Leaving unsupported file in ingress", "filePath", path, "reason", reason)
+	rejection := database.IngestRejection{Path: path, Name: filepath.Base(path), Reason: reason.Error()}
+	if err := serverHandler.DB.RecordIngestRejection(&rejection); err != nil {
+		Logger.Warn("Unable to record ingest rejection", "filePath", path, "error", err)
+	}
+}
+
+// pruneIngestRejections forgets the files a completed walk of the ingress folder did not reject again,
+// because they were removed or can now be ingested
+func (serverHandler *ServerHandler) pruneIngestRejections(rejected []string) {
+	if err := serverHandler.DB.PruneIngestRejections(rejected); err != nil {
+		Logger.Warn("Unable to prune ingest rejections", "error", err)
+	}
+}
+
+// GetIngestRejections lists the files left in the ingress folder because they cannot be ingested
+// @Summary Rejected ingress files
+// @Description Files the last walk of the ingress folder left in place, with the reason, most recently rejected first. An entry stays until the file is removed or becomes ingestible.
+// @Tags Admin
+// @Produce json
+// @Param limit query int false "Most recent entries to return (default 50, at most 200)"
+// @Success 200 {object} dto.IngestRejections "Rejections, newest first"
+// @Failure 400 {object} dto.ErrorResponse "Invalid limit"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /ingest/rejections [get]
+func (serverHandler *ServerHandler) GetIngestRejections(c echo.Context) error {
+	limit, err := limitParam(c, defaultIngestRejections)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	rejections, err := serverHandler.DB.ListIngestRejections(limit)
+	if err != nil {
+		Logger.Error("Failed to list ingest rejections", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list ingest rejections",
+			"code":  dto.CodeInternal,
+		})
+	}
+	response := dto.IngestRejections{Rejections: make([]dto.IngestRejection, 0, len(rejections))}
+	for _, rejection := range rejections {
+		response.Rejections = append(response.Rejections, dto.IngestRejection{
+			Path:       rejection.Path,
+			Name:       rejection.Name,
+			Reason:     rejection.Reason,
+			RejectedAt: rejection.RejectedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+func TestIngestRejectionsRecordedAndPruned(t *testing.T) {
+	// A spreadsheet and a text file in ingress
+	handler := setupTestHandler(t)
+	spreadsheet := filepath.Join(handler.ServerConfig.IngressPath, "budget.xlsx")
+	for path, content := range map[string]string{spreadsheet: "cells", filepath.Join(handler.ServerConfig.IngressPath, "note.txt"): "a note"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write ingress file: %v", err)
+		}
+	}
+	runIngress := func() {
+		t.Helper()
+		job, err := handler.DB.CreateJob(database.JobTypeIngestion, "test")
+		if err != nil {
+			t.Fatalf("Failed to create job: %v", err)
+		}
+		handler.ingressJobFuncWithTracking(handler.ServerConfig, handler.DB, job.ID)
+	}
+
+	// Ingestion runs and the rejections are listed
+	runIngress()
+	handler.Echo.GET("/api/ingest/rejections", handler.GetIngestRejections)
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/ingest/rejections", nil))
+
+	// Only the spreadsheet is listed, with why it was left
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body dto.IngestRejections
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(body.Rejections) != 1 || body.Rejections[0].Path != spreadsheet || body.Rejections[0].Name != "budget.xlsx" {
+		t.Fatalf("Expected the spreadsheet to be rejected, got %+v", body.Rejections)
+	}
+	if reason := body.Rejections[0].Reason; !strings.Contains(reason, "unsupported file type: .xlsx") {
+		t.Errorf("Expected the reason to name the file type, got %q", reason)
+	}
+	if _, err := os.Stat(spreadsheet); err != nil {
+		t.Errorf("Expected the spreadsheet to stay in ingress: %v", err)
+	}
+
+	// The entry goes once the file is removed and ingestion runs again
+	if err := os.Remove(spreadsheet); err != nil {
+		t.Fatalf("Failed to remove spreadsheet: %v", err)
+	}
+	runIngress()
+	if rejections, err := handler.DB.ListIngestRejections(10); err != nil || len(rejections) != 0 {
+		t.Errorf("Expected no rejections after the file was removed, got %+v, %v", rejections, err)
+	}
+}
+
+func TestGetIngestRejectionsInvalidLimit(t *testing.T) {
+	// A server
+	handler := setupTestHandler(t)
+	handler.Echo.GET("/api/ingest/rejections", handler.GetIngestRejections)
+
+	// The rejections are asked for with a bad limit
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/ingest/rejections?limit=none", nil))
+
+	// The request is refused
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", rec.Code)
+	}
+}
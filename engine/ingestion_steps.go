@@ -1,12 +1,10 @@
 package engine
 
 import (
-	"crypto/md5"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"time"
 
 	"github.com/drummonds/godocs/database"
 	"github.com/oklog/ulid/v2"
@@ -17,17 +15,28 @@ import (
 // Step 2: Move file to documents folder and verify hash
 // Step 3: Extract text and update search/wordcloud
 func (serverHandler *ServerHandler) IngestDocumentWithSteps(filePath string, db database.Repository, jobID ulid.ULID, fileNum, totalFiles int) error {
+	_, err := serverHandler.ingestDocumentWithSteps(filePath, db, jobID, fileNum, totalFiles, nil)
+	return err
+}
+
+// ingestDocumentWithSteps is IngestDocumentWithSteps returning the stored document, so the job can count its words.
+// A document stored without its text is listed in items.
+func (serverHandler *ServerHandler) ingestDocumentWithSteps(filePath string, db database.Repository, jobID ulid.ULID, fileNum, totalFiles int, items *jobItems) (*database.Document, error) {
 	fileName := filepath.Base(filePath)
 	baseProgress := int((float64(fileNum) / float64(totalFiles)) * 90) // Reserve 90% for file processing, 10% for final steps
+	timeline := startTimeline("job " + jobID.String())
+	serverHandler.prepareScan(filePath, timeline) // before the hash, so the stored file is the corrected one
 
 	// Step 1: Calculate hash and check for duplicates
 	stepMsg := fmt.Sprintf("[%d/%d] %s - Step 1: Calculating hash", fileNum+1, totalFiles, fileName)
 	db.UpdateJobProgress(jobID, baseProgress, stepMsg)
 	Logger.Info("Step 1: Calculating hash", "filePath", filePath)
 
+	hashing := timeline.step("hash file")
 	fileHash, err := calculateFileHash(filePath)
+	endSpan(hashing, err)
 	if err != nil {
-		return fmt.Errorf("step 1 failed (hash calculation): %w", err)
+		return nil, fmt.Errorf("step 1 failed (hash calculation): %w", err)
 	}
 
 	// Check for duplicates
@@ -38,15 +47,16 @@ func (serverHandler *ServerHandler) IngestDocumentWithSteps(filePath string, db
 		if err := os.Remove(filePath); err != nil {
 			Logger.Error("Failed to remove duplicate file", "filePath", filePath, "error", err)
 		}
-		return fmt.Errorf("duplicate document (hash: %s)", fileHash)
+		return nil, fmt.Errorf("%w (hash: %s)", errDuplicateDocument, fileHash)
 	}
 
 	// Create initial database record with hash
-	doc, err := serverHandler.createInitialDocument(filePath, fileHash, db)
+	doc, err := serverHandler.createInitialDocument(filePath, fileHash.Current(), db)
 	if err != nil {
-		return fmt.Errorf("step 1 failed (create record): %w", err)
+		return nil, fmt.Errorf("step 1 failed (create record): %w", err)
 	}
 
+	serverHandler.invalidateDocumentCache() // lists show the new document, pending OCR, while it is processed
 	Logger.Info("Step 1 complete: Document record created", "ulid", doc.ULID.String(), "hash", fileHash)
 
 	// Step 2: Move file and verify hash
@@ -54,12 +64,15 @@ func (serverHandler *ServerHandler) IngestDocumentWithSteps(filePath string, db
 	db.UpdateJobProgress(jobID, baseProgress+10, stepMsg)
 	Logger.Info("Step 2: Moving file to documents folder", "from", filePath, "to", doc.Path)
 
-	err = serverHandler.moveAndVerifyFile(filePath, doc.Path, fileHash)
+	storing := timeline.step("store file")
+	err = serverHandler.moveAndVerifyFile(filePath, doc.Path, doc.Hash)
+	endSpan(storing, err)
 	if err != nil {
 		// Rollback: delete the database record
 		db.DeleteDocument(doc.ULID.String())
-		return fmt.Errorf("step 2 failed (move/verify): %w", err)
+		return nil, fmt.Errorf("step 2 failed (move/verify): %w: %w", errStorageFailed, err)
 	}
+	timeline.mark(stageStored, "")
 
 	Logger.Info("Step 2 complete: File moved and hash verified", "path", doc.Path)
 
@@ -69,23 +82,30 @@ func (serverHandler *ServerHandler) IngestDocumentWithSteps(filePath string, db
 	db.UpdateJobProgress(jobID, baseProgress+20, stepMsg)
 	Logger.Info("Step 3: Extracting text and updating search", "filePath", doc.Path)
 
-	fullText, err := serverHandler.extractText(doc.Path)
+	fullText, ocrStatus, err := serverHandler.extractText(doc.Path, timeline)
 	if err != nil {
 		Logger.Warn("Text extraction failed, storing document without text", "error", err, "fileName", fileName)
+		items.add(filePath, err)
 		fullText = "" // Store document even if text extraction fails
 	}
+	doc.OCRStatus = ocrStatus
 
 	// Update document with full text - if this fails, log error but don't fail the ingestion
+	saving := timeline.step("save document")
 	err = serverHandler.updateDocumentText(doc, fullText, db)
+	endSpan(saving, err)
 	if err != nil {
 		Logger.Error("Failed to update document text, but document is still saved", "error", err, "ulid", doc.ULID.String())
 		// Don't return error - the document record and file already exist, which is the important part
+		timeline.mark(stageFailed, err.Error())
+	} else {
+		timeline.mark(stageIndexed, "")
+		serverHandler.invalidateDocumentCache() // so lists pick up the final OCR status
 	}
+	timeline.save(db, doc.ULID)
 
-	// Add document view route
-	documentURL := "/document/view/" + doc.ULID.String()
-	serverHandler.Echo.File(documentURL, doc.Path)
-	_, err = database.UpdateDocumentField(doc.ULID.String(), "URL", documentURL, db)
+	// Record the view URL; the view handler finds the file from the database
+	_, err = database.UpdateDocumentField(doc.ULID.String(), "URL", documentViewURL(doc.ULID), db)
 	if err != nil {
 		Logger.Error("Unable to update document URL field", "error", err, "ulid", doc.ULID.String())
 		// Don't fail - this is not critical
@@ -94,28 +114,93 @@ func (serverHandler *ServerHandler) IngestDocumentWithSteps(filePath string, db
 	Logger.Info("Step 3 complete: Text extracted and indexed", "textLength", len(fullText), "fileName", fileName)
 	Logger.Info("Document ingestion complete", "fileName", fileName, "ulid", doc.ULID.String())
 
-	return nil
+	return doc, nil
 }
 
-// calculateFileHash computes MD5 hash of a file
-func calculateFileHash(filePath string) (string, error) {
-	file, err := os.Open(filePath)
+// ingestDocumentBatched runs the same steps as IngestDocumentWithSteps but queues the finished
+// document on batch instead of writing it, so the database sees one transaction per batch.
+// It returns how many earlier queued documents failed to save if adding this one flushed the batch.
+// The file is in document storage before its record is written; a crash in between leaves an orphan
+// that the cleanup job picks up.
+func (serverHandler *ServerHandler) ingestDocumentBatched(filePath string, db database.Repository, jobID ulid.ULID, fileNum, totalFiles int, batch *ingestBatch, items *jobItems) (int, error) {
+	fileName := filepath.Base(filePath)
+	baseProgress := int((float64(fileNum) / float64(totalFiles)) * 90)
+	timeline := startTimeline("job " + jobID.String())
+	serverHandler.prepareScan(filePath, timeline)
+
+	// Step 1: Calculate hash and check for duplicates, including documents still waiting in the batch
+	stepMsg := fmt.Sprintf("[%d/%d] %s - Step 1: Calculating hash", fileNum+1, totalFiles, fileName)
+	db.UpdateJobProgress(jobID, baseProgress, stepMsg)
+
+	hashing := timeline.step("hash file")
+	fileHash, err := calculateFileHash(filePath)
+	endSpan(hashing, err)
 	if err != nil {
-		return "", err
+		return 0, fmt.Errorf("step 1 failed (hash calculation): %w", err)
+	}
+	duplicate, existingDoc := serverHandler.checkDuplicate(fileHash, fileName, db)
+	existingName := ""
+	if duplicate {
+		existingName = existingDoc.Name
+	} else {
+		existingName, duplicate = batch.pending(fileHash.Current())
+	}
+	if duplicate {
+		Logger.Info("Duplicate document detected, skipping", "fileName", fileName, "existingDoc", existingName)
+		if err := os.Remove(filePath); err != nil {
+			Logger.Error("Failed to remove duplicate file", "filePath", filePath, "error", err)
+		}
+		return 0, fmt.Errorf("%w (hash: %s)", errDuplicateDocument, fileHash)
 	}
-	defer file.Close()
 
-	hash := md5.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
+	doc, err := newDocumentRecord(filePath, fileHash.Current(), db)
+	if err != nil {
+		return 0, fmt.Errorf("step 1 failed (create record): %w", err)
+	}
+
+	// Step 2: Move file and verify hash; nothing has been written yet so there is nothing to roll back
+	stepMsg = fmt.Sprintf("[%d/%d] %s - Step 2: Moving file", fileNum+1, totalFiles, fileName)
+	db.UpdateJobProgress(jobID, baseProgress+10, stepMsg)
+	storing := timeline.step("store file")
+	err = serverHandler.moveAndVerifyFile(filePath, doc.Path, doc.Hash)
+	endSpan(storing, err)
+	if err != nil {
+		return 0, fmt.Errorf("step 2 failed (move/verify): %w: %w", errStorageFailed, err)
+	}
+	timeline.mark(stageStored, "")
+
+	// Step 3: Extract text; as in the unbatched path a failed extraction still stores the document
+	stepMsg = fmt.Sprintf("[%d/%d] %s - Step 3: Extracting text", fileNum+1, totalFiles, fileName)
+	db.UpdateJobProgress(jobID, baseProgress+20, stepMsg)
+	fullText, ocrStatus, err := serverHandler.extractText(doc.Path, timeline)
+	if err != nil {
+		Logger.Warn("Text extraction failed, storing document without text", "error", err, "fileName", fileName)
+		items.add(filePath, err)
+		fullText = ""
 	}
+	doc.FullText = fullText
+	doc.OCRStatus = ocrStatus
+	doc.URL = documentViewURL(doc.ULID)
+	// The batch marks the indexed and word cloud stages when it writes the document
+	timeline.save(db, doc.ULID)
+
+	Logger.Info("Document processed, queued for batch write", "fileName", fileName, "ulid", doc.ULID.String())
+	return batch.add(doc), nil
+}
 
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+// calculateFileHash computes the hashes of a file under every supported algorithm
+func calculateFileHash(filePath string) (database.FileHashes, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return database.FileHashes{}, err
+	}
+	defer file.Close()
+	return hashingCopy(io.Discard, file)
 }
 
-// checkDuplicate checks if a document with the same hash already exists
-func (serverHandler *ServerHandler) checkDuplicate(fileHash string, fileName string, db database.Repository) (bool, *database.Document) {
-	document, err := db.GetDocumentByHash(fileHash)
+// checkDuplicate checks if a document with the same content already exists, whichever algorithm its hash was stored with
+func (serverHandler *ServerHandler) checkDuplicate(fileHash database.FileHashes, fileName string, db database.Repository) (bool, *database.Document) {
+	document, err := database.FindDocumentByHashes(db, fileHash)
 	if err != nil || document == nil {
 		return false, nil
 	}
@@ -125,25 +210,43 @@ func (serverHandler *ServerHandler) checkDuplicate(fileHash string, fileName str
 
 // createInitialDocument creates a minimal document record with hash
 func (serverHandler *ServerHandler) createInitialDocument(filePath string, fileHash string, db database.Repository) (*database.Document, error) {
+	doc, err := newDocumentRecord(filePath, fileHash, db)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save initial document record
+	if err := db.SaveDocument(doc); err != nil {
+		return nil, fmt.Errorf("unable to save document: %w", err)
+	}
+
+	return doc, nil
+}
+
+// newDocumentRecord builds the document for an ingress file, including its destination path, without saving it
+func newDocumentRecord(filePath string, fileHash string, db database.Repository) (*database.Document, error) {
 	serverConfig, err := database.FetchConfigFromDB(db)
 	if err != nil {
 		return nil, fmt.Errorf("unable to fetch config: %w", err)
 	}
 
-	newTime := time.Now()
+	newTime := database.Now()
 	newULID, err := database.CalculateUUID(newTime)
 	if err != nil {
 		return nil, fmt.Errorf("cannot generate ULID: %w", err)
 	}
 
 	doc := &database.Document{
-		Name:         filepath.Base(filePath),
+		Name:         normaliseName(filepath.Base(filePath)),
 		Hash:         fileHash,
 		IngressTime:  newTime,
 		ULID:         newULID,
 		DocumentType: filepath.Ext(filePath),
+		MIMEType:     database.DetectMIMEType(filePath),
+		OCRStatus:    database.OCRPending,
 		FullText:     "", // Will be populated in step 3
 	}
+	doc.Size, doc.PageCount = fileDetails(filePath)
 
 	// Calculate destination path
 	if serverConfig.IngressPreserve {
@@ -153,19 +256,13 @@ func (serverHandler *ServerHandler) createInitialDocument(filePath string, fileH
 		if err != nil {
 			return nil, err
 		}
-		newFilePath := filepath.Join(newFileNameRoot, relativePath)
+		newFilePath := filepath.Join(newFileNameRoot, normalisePath(relativePath))
 		doc.Path = filepath.ToSlash(newFilePath)
-		doc.Folder = filepath.Dir(newFilePath)
+		doc.Folder = filepath.ToSlash(filepath.Dir(newFilePath))
 	} else {
-		documentPath := filepath.ToSlash(serverConfig.DocumentPath + "/" + serverConfig.NewDocumentFolderRel + "/" + filepath.Base(filePath))
-		doc.Path = documentPath
-		documentFolder := filepath.ToSlash(serverConfig.DocumentPath + "/" + serverConfig.NewDocumentFolderRel)
-		doc.Folder = documentFolder
-	}
-
-	// Save initial document record
-	if err := db.SaveDocument(doc); err != nil {
-		return nil, fmt.Errorf("unable to save document: %w", err)
+		documentFolder := filepath.Join(serverConfig.DocumentPath, serverConfig.NewDocumentFolderRel)
+		doc.Path = filepath.ToSlash(filepath.Join(documentFolder, doc.Name))
+		doc.Folder = filepath.ToSlash(documentFolder)
 	}
 
 	return doc, nil
@@ -173,33 +270,28 @@ func (serverHandler *ServerHandler) createInitialDocument(filePath string, fileH
 
 // moveAndVerifyFile moves the file to the documents folder and verifies the hash matches
 func (serverHandler *ServerHandler) moveAndVerifyFile(sourcePath, destPath, expectedHash string) error {
-	// Create destination directory if needed
-	if err := os.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create destination directory: %w", err)
+	// Refuse before copying anything if the file would take its folder over a FOLDER_QUOTAS limit
+	if info, err := os.Stat(sourcePath); err == nil {
+		if err := serverHandler.checkQuota(destPath, info.Size()); err != nil {
+			return err
+		}
 	}
 
-	// Read source file
-	srcFile, err := os.ReadFile(sourcePath)
+	key, err := documentKey(serverHandler.ServerConfig.DocumentPath, destPath)
 	if err != nil {
-		return fmt.Errorf("failed to read source file: %w", err)
-	}
-
-	// Write to destination
-	if err := os.WriteFile(destPath, srcFile, os.ModePerm); err != nil {
-		return fmt.Errorf("failed to write destination file: %w", err)
+		return err
 	}
+	documents := serverHandler.documentStorage()
 
-	// Verify hash of destination file
-	destHash, err := calculateFileHash(destPath)
+	// Stream the copy, hashing the bytes as they are written rather than reading the file again
+	destHash, err := storeFileHashed(documents, key, sourcePath)
 	if err != nil {
-		// Cleanup: remove the copied file
-		os.Remove(destPath)
-		return fmt.Errorf("failed to verify destination file: %w", err)
+		return fmt.Errorf("failed to copy file: %w", err)
 	}
 
-	if destHash != expectedHash {
+	if !destHash.Matches(expectedHash) {
 		// Cleanup: remove the corrupted file
-		os.Remove(destPath)
+		documents.Delete(key)
 		return fmt.Errorf("hash mismatch after copy (expected: %s, got: %s)", expectedHash, destHash)
 	}
 
@@ -208,60 +300,80 @@ func (serverHandler *ServerHandler) moveAndVerifyFile(sourcePath, destPath, expe
 		Logger.Warn("Failed to delete source file after successful copy", "sourcePath", sourcePath, "error", err)
 		// Don't fail the operation - the file was copied successfully
 	}
+	serverHandler.noteQuotaUsage(destPath)
 
 	return nil
 }
 
-// extractText extracts text from the document based on file type
-func (serverHandler *ServerHandler) extractText(filePath string) (string, error) {
+// extractText extracts text from the document based on file type, marking the text and OCR stages on timeline.
+// It also returns the document's OCR status: done when OCR produced the text, skipped when none was needed
+// (a PDF text layer, a text file, a type that cannot be OCR'd) and failed when no text could be had.
+func (serverHandler *ServerHandler) extractText(filePath string, timeline *documentTimeline) (string, string, error) {
 	switch filepath.Ext(filePath) {
 	case ".pdf":
 		// Try direct PDF text extraction first
+		textLayer := timeline.step("read text layer")
 		fullText, err := pdfProcessing(filePath)
+		textLayer.End() // a PDF without a text layer is OCRed, so its error is not a failure
 		if err != nil || fullText == nil || *fullText == "" {
+			timeline.mark(stageTextExtracted, "no text layer")
 			// Fallback to OCR
-			fullText, err = serverHandler.convertToImage(filePath)
+			fullText, err = serverHandler.convertToImage(timeline.context(), filePath)
 			if err != nil {
-				return "", fmt.Errorf("OCR processing failed: %w", err)
+				return "", database.OCRFailed, fmt.Errorf("%w: %w", errOCRFailed, err)
 			}
 			if fullText == nil {
-				return "", fmt.Errorf("PDF processing returned nil text")
+				return "", database.OCRFailed, fmt.Errorf("%w: PDF processing returned nil text", errOCRFailed)
 			}
-			return *fullText, nil
+			timeline.mark(stageOCR, "")
+			return *fullText, database.OCRDone, nil
 		}
-		return *fullText, nil
+		timeline.mark(stageTextExtracted, "PDF text layer")
+		return *fullText, database.OCRSkipped, nil
 
 	case ".tiff", ".jpg", ".jpeg", ".png":
+		ocr := timeline.step("ocr image")
 		fullText, err := serverHandler.ocrProcessing(filePath)
+		endSpan(ocr, err)
 		if err != nil {
-			return "", fmt.Errorf("OCR processing failed: %w", err)
+			return "", database.OCRFailed, fmt.Errorf("%w: %w", errOCRFailed, err)
 		}
 		if fullText == nil {
-			return "", fmt.Errorf("OCR processing returned nil text")
+			return "", database.OCRFailed, fmt.Errorf("%w: OCR returned nil text", errOCRFailed)
 		}
-		return *fullText, nil
+		timeline.mark(stageOCR, "")
+		return *fullText, database.OCRDone, nil
 
 	case ".txt", ".rtf":
 		// For text files, read content directly
 		content, err := os.ReadFile(filePath)
 		if err != nil {
-			return "", fmt.Errorf("failed to read text file: %w", err)
+			return "", database.OCRFailed, fmt.Errorf("%w: unable to read text file: %w", errExtractionFailed, err)
 		}
-		return string(content), nil
+		timeline.mark(stageTextExtracted, "")
+		return string(content), database.OCRSkipped, nil
+
+	case ".xlsx", ".csv":
+		fullText, err := extractSpreadsheetText(filePath)
+		if err != nil {
+			return "", database.OCRFailed, fmt.Errorf("%w: %w", errExtractionFailed, err)
+		}
+		timeline.mark(stageTextExtracted, "spreadsheet cells")
+		return fullText, database.OCRSkipped, nil
 
 	case ".doc", ".docx", ".odf":
 		// These are not currently supported for text extraction
-		return "", fmt.Errorf("text extraction not supported for %s files", filepath.Ext(filePath))
+		return "", database.OCRSkipped, fmt.Errorf("%w: not supported for %s files", errExtractionFailed, filepath.Ext(filePath))
 
 	default:
-		return "", fmt.Errorf("unsupported file type: %s", filepath.Ext(filePath))
+		return "", database.OCRSkipped, fmt.Errorf("%w: %s", errUnsupportedFileType, filepath.Ext(filePath))
 	}
 }
 
-// updateDocumentText updates the document with extracted text
+// updateDocumentText updates the document with extracted text (and any other changed fields such as the hash)
 func (serverHandler *ServerHandler) updateDocumentText(doc *database.Document, fullText string, db database.Repository) error {
-	_, err := database.UpdateDocumentField(doc.ULID.String(), "FullText", fullText, db)
-	if err != nil {
+	doc.FullText = fullText
+	if err := db.SaveDocument(doc); err != nil { // upserts on path
 		return fmt.Errorf("unable to update full text: %w", err)
 	}
 
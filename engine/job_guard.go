@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+	"github.com/oklog/ulid/v2"
+)
+
+// defaultJobTimeout is how long an active job may go without an update, unless JOB_TIMEOUTS gives its
+// type another timeout, before it is taken to have died with the server and is failed
+const defaultJobTimeout = time.Hour
+
+// jobWatchdogSchedule is how often the watchdog looks for active jobs that have timed out
+const jobWatchdogSchedule = "@every 5m"
+
+// jobHeartbeatInterval is the least time between the heartbeats written for one job
+const jobHeartbeatInterval = 30 * time.Second
+
+// errJobActive is returned by startJob while a job of the same type is pending or running
+var errJobActive = errors.New("a job of this type is already active")
+
+// startJob creates a job of jobType unless one is already active, so repeated triggers do not start
+// overlapping jobs over the same files. The active job is returned with errJobActive. Active jobs that
+// have timed out are marked failed and do not count.
+func (serverHandler *ServerHandler) startJob(jobType database.JobType, message string) (*database.Job, error) {
+	serverHandler.jobStarts.Lock()
+	defer serverHandler.jobStarts.Unlock()
+
+	active, err := serverHandler.activeJob(jobType)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return active, errJobActive
+	}
+	return serverHandler.DB.CreateJob(jobType, message)
+}
+
+// activeJob returns the pending or running job of jobType, or nil, failing any that have timed out
+func (serverHandler *ServerHandler) activeJob(jobType database.JobType) (*database.Job, error) {
+	jobs, err := serverHandler.DB.GetActiveJobs()
+	if err != nil {
+		return nil, err
+	}
+	var active *database.Job
+	for i := range jobs {
+		job := &jobs[i]
+		if job.Type != jobType {
+			continue
+		}
+		if serverHandler.failIfTimedOut(job) {
+			continue
+		}
+		if active == nil {
+			active = job
+		}
+	}
+	return active, nil
+}
+
+// jobTimeout is how long an active job of jobType may go without an update
+func (serverHandler *ServerHandler) jobTimeout(jobType database.JobType) time.Duration {
+	if timeout, ok := serverHandler.ServerConfig.JobTimeouts[string(jobType)]; ok {
+		return timeout
+	}
+	return defaultJobTimeout
+}
+
+// failIfTimedOut marks an active job failed, and reports true, when it has gone past its timeout
+// without an update: it crashed, or the server stopped, without finishing it
+func (serverHandler *ServerHandler) failIfTimedOut(job *database.Job) bool {
+	timeout := serverHandler.jobTimeout(job.Type)
+	if database.Now().Sub(job.UpdatedAt) <= timeout {
+		return false
+	}
+	Logger.Warn("Failing timed out job", "jobID", job.ID.String(), "type", job.Type, "updated", job.UpdatedAt, "timeout", timeout)
+	message := fmt.Sprintf("Timed out: no progress for %s, last at %s", timeout, job.UpdatedAt.Format(time.RFC3339))
+	if err := serverHandler.DB.UpdateJobError(job.ID, message); err != nil {
+		Logger.Error("Failed to mark timed out job as failed", "jobID", job.ID.String(), "error", err)
+	}
+	return true
+}
+
+// failTimedOutJobs is the job watchdog: it fails every active job that has timed out, so a job that
+// died does not show as running forever, and returns how many it failed
+func (serverHandler *ServerHandler) failTimedOutJobs() int {
+	jobs, err := serverHandler.DB.GetActiveJobs()
+	if err != nil {
+		Logger.Error("Job watchdog unable to list active jobs", "error", err)
+		return 0
+	}
+	failed := 0
+	for i := range jobs {
+		if serverHandler.failIfTimedOut(&jobs[i]) {
+			failed++
+		}
+	}
+	return failed
+}
+
+// jobHeartbeat keeps an active job's update time current from inside a long loop, so the watchdog
+// does not take a job that is working through a long list for dead. Beats closer together than
+// jobHeartbeatInterval are not written. A nil *jobHeartbeat does nothing.
+type jobHeartbeat struct {
+	db    database.Repository
+	jobID ulid.ULID
+	last  time.Time
+}
+
+// newJobHeartbeat returns the heartbeat for a job that has just reported progress
+func newJobHeartbeat(db database.Repository, jobID ulid.ULID) *jobHeartbeat {
+	return &jobHeartbeat{db: db, jobID: jobID, last: database.Now()}
+}
+
+// beat records that the job is still making progress
+func (h *jobHeartbeat) beat() {
+	if h == nil {
+		return
+	}
+	now := database.Now()
+	if now.Sub(h.last) < jobHeartbeatInterval {
+		return
+	}
+	h.last = now
+	if err := h.db.TouchJob(h.jobID); err != nil {
+		Logger.Warn("Unable to record job heartbeat", "jobID", h.jobID.String(), "error", err)
+	}
+}
+
+// jobAlreadyActive answers 409 with the ID of the job a trigger would have overlapped
+func jobAlreadyActive(c echo.Context, job *database.Job) error {
+	return c.JSON(http.StatusConflict, map[string]interface{}{
+		"error":  fmt.Sprintf("A %s job is already %s", strings.ReplaceAll(string(job.Type), "_", " "), job.Status),
+		"code":   dto.CodeConflict,
+		"jobId":  job.ID.String(),
+		"status": job.Status,
+	})
+}
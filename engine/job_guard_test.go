@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/oklog/ulid/v2"
+)
+
+func TestIngestTriggerRefusedWhileActive(t *testing.T) {
+	// An ingestion job already running
+	handler := setupTestHandler(t)
+	running, err := handler.DB.CreateJob(database.JobTypeIngestion, "Starting document ingestion")
+	if err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	handler.DB.UpdateJobStatus(running.ID, database.JobStatusRunning, "Scanning ingress folder")
+	trigger := func() (int, map[string]interface{}) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		if err := handler.RunIngestNow(handler.Echo.NewContext(httptest.NewRequest(http.MethodPost, "/api/ingest", nil), rec)); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		return rec.Code, response
+	}
+
+	// Ingestion is triggered again
+	code, response := trigger()
+
+	// It is refused with the running job's ID and no second job is created
+	if code != http.StatusConflict || response["code"] != string(dto.CodeConflict) || response["jobId"] != running.ID.String() {
+		t.Fatalf("Expected 409 with job %s, got %d %v", running.ID, code, response)
+	}
+	if active, _ := handler.DB.GetActiveJobs(); len(active) != 1 {
+		t.Errorf("Expected only the running job to be active, got %d", len(active))
+	}
+
+	// Other job types are not held up by it
+	if job, err := handler.startJob(database.JobTypeURLRepair, "repair"); err != nil {
+		t.Errorf("Expected a URL repair job to start, got %v", err)
+	} else {
+		handler.DB.CompleteJob(job.ID, "{}")
+	}
+
+	// The running job finishes and ingestion is triggered again
+	handler.DB.CompleteJob(running.ID, `{"filesProcessed": 0}`)
+	code, response = trigger()
+
+	// A new job starts
+	if code != http.StatusOK || response["jobId"] == running.ID.String() {
+		t.Fatalf("Expected a new job, got %d %v", code, response)
+	}
+	started, _ := ulid.Parse(response["jobId"].(string))
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if job, err := handler.DB.GetJob(started); err == nil && job.Status == database.JobStatusCompleted {
+			return
+		}
+	}
+	t.Errorf("The new ingestion job did not finish")
+}
+
+func TestWatchdogFailsTimedOutJobs(t *testing.T) {
+	// A running ingestion job and a running backup job, with backups timing out after ten minutes
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	t.Cleanup(database.SetClock(database.NewFixedClock(start, time.Millisecond)))
+	handler := setupTestHandler(t)
+	handler.ServerConfig.JobTimeouts = map[string]time.Duration{"backup": 10 * time.Minute}
+	ingestion, _ := handler.DB.CreateJob(database.JobTypeIngestion, "ingest")
+	backup, _ := handler.DB.CreateJob(database.JobTypeBackup, "backup")
+	handler.DB.UpdateJobStatus(ingestion.ID, database.JobStatusRunning, "Scanning ingress folder")
+	handler.DB.UpdateJobStatus(backup.ID, database.JobStatusRunning, "Exporting documents")
+	status := func(id ulid.ULID) *database.Job {
+		t.Helper()
+		job, err := handler.DB.GetJob(id)
+		if err != nil {
+			t.Fatalf("GetJob failed: %v", err)
+		}
+		return job
+	}
+
+	// The watchdog runs twenty minutes later
+	database.SetClock(database.NewFixedClock(start.Add(20*time.Minute), 0))
+	failed := handler.failTimedOutJobs()
+
+	// Only the backup has timed out, and says so
+	if job := status(backup.ID); failed != 1 || job.Status != database.JobStatusFailed || !strings.HasPrefix(job.Error, "Timed out: no progress for 10m0s") {
+		t.Errorf("Expected the backup to time out, got %d failed and %s %q", failed, job.Status, job.Error)
+	}
+	if job := status(ingestion.ID); job.Status != database.JobStatusRunning {
+		t.Errorf("Expected ingestion to keep running within its hour, got %s", job.Status)
+	}
+
+	// It runs again two hours on
+	database.SetClock(database.NewFixedClock(start.Add(2*time.Hour), 0))
+	failed = handler.failTimedOutJobs()
+
+	// Ingestion has timed out on the default too
+	if job := status(ingestion.ID); failed != 1 || job.Status != database.JobStatusFailed {
+		t.Errorf("Expected ingestion to time out, got %d failed and %s", failed, job.Status)
+	}
+}
+
+func TestJobHeartbeatKeepsJobAlive(t *testing.T) {
+	// A running job with a heartbeat
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	t.Cleanup(database.SetClock(database.NewFixedClock(start, time.Millisecond)))
+	handler := setupTestHandler(t)
+	job, _ := handler.DB.CreateJob(database.JobTypeRehash, "rehash")
+	handler.DB.UpdateJobStatus(job.ID, database.JobStatusRunning, "Rehashing documents")
+	heartbeat := newJobHeartbeat(handler.DB, job.ID)
+
+	// It beats straight away, then fifty minutes in
+	database.SetClock(database.NewFixedClock(start.Add(10*time.Second), 0))
+	heartbeat.beat()
+	quick, _ := handler.DB.GetJob(job.ID)
+	database.SetClock(database.NewFixedClock(start.Add(50*time.Minute), 0))
+	heartbeat.beat()
+
+	// The quick beat is not written, the later one is, and the job outlives its first hour
+	if !quick.UpdatedAt.Before(start.Add(10 * time.Second)) {
+		t.Errorf("Expected a beat within %s not to be written, updated at %s", jobHeartbeatInterval, quick.UpdatedAt)
+	}
+	database.SetClock(database.NewFixedClock(start.Add(90*time.Minute), 0))
+	if failed := handler.failTimedOutJobs(); failed != 0 {
+		t.Errorf("Expected the job to be alive, %d failed", failed)
+	}
+
+	// A nil heartbeat, as the jobs' functions get outside a job, does nothing
+	var none *jobHeartbeat
+	none.beat()
+}
@@ -0,0 +1,16 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/drummonds/godocs/database"
+)
+
+// jobDB is the repository for long running jobs: their writes are retried when the connection drops
+// mid-job, as ephemeral and remote PostgreSQL connections sometimes do, instead of failing the whole run
+func (serverHandler *ServerHandler) jobDB() database.Repository {
+	return database.NewRetryingRepository(serverHandler.DB, database.RetryPolicy{
+		Attempts: serverHandler.ServerConfig.DBRetryAttempts,
+		Backoff:  time.Duration(serverHandler.ServerConfig.DBRetryBackoffMS) * time.Millisecond,
+	})
+}
@@ -5,8 +5,8 @@ import (
 	"strconv"
 
 	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
 	"github.com/labstack/echo/v4"
-	"github.com/oklog/ulid/v2"
 )
 
 // GetJob retrieves a job by ID
@@ -21,20 +21,17 @@ import (
 // @Failure 404 {object} map[string]interface{} "Job not found"
 // @Router /jobs/{id} [get]
 func (serverHandler *ServerHandler) GetJob(c echo.Context) error {
-	jobIDStr := c.Param("id")
-
-	jobID, err := ulid.Parse(jobIDStr)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"error": "Invalid job ID format",
-		})
+	jobID, ok, err := ulidParam(c, "id", "job")
+	if !ok {
+		return err
 	}
 
 	job, err := serverHandler.DB.GetJob(jobID)
 	if err != nil {
-		Logger.Error("Failed to get job", "jobID", jobIDStr, "error", err)
+		Logger.Error("Failed to get job", "jobID", jobID.String(), "error", err)
 		return c.JSON(http.StatusNotFound, map[string]interface{}{
 			"error": "Job not found",
+			"code":  dto.CodeNotFound,
 		})
 	}
 
@@ -73,6 +70,7 @@ func (serverHandler *ServerHandler) GetRecentJobs(c echo.Context) error {
 		Logger.Error("Failed to get recent jobs", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error": "Failed to retrieve jobs",
+			"code":  dto.CodeInternal,
 		})
 	}
 
@@ -98,6 +96,7 @@ func (serverHandler *ServerHandler) GetActiveJobs(c echo.Context) error {
 		Logger.Error("Failed to get active jobs", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error": "Failed to retrieve active jobs",
+			"code":  dto.CodeInternal,
 		})
 	}
 
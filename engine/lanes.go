@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultIngestWorkers is how many documents are processed at once when INGEST_WORKERS is not set
+const defaultIngestWorkers = 2
+
+// priority is the lane a document waits in for a processing slot
+type priority int
+
+const (
+	// priorityBulk is for scheduled and triggered ingestion, rescans and remote sources
+	priorityBulk priority = iota
+	// priorityInteractive is for documents someone has just uploaded and is waiting to see
+	priorityInteractive
+)
+
+// processingLanes hands out the slots in which documents are extracted and OCRed. Interactive
+// documents are always served before bulk ones, and when there is more than one slot bulk work
+// may not take the last, so an upload never waits behind a large batch. Bulk jobs take a slot per
+// document, so uploads interleave with a batch that is already running.
+type processingLanes struct {
+	mu       sync.Mutex
+	busy     int
+	bulkBusy int
+	waiting  [2][]chan struct{} // first in, first out within each priority
+}
+
+// bulkLimit is how many slots bulk work may hold at once
+func bulkLimit(slots int) int {
+	if slots > 1 {
+		return slots - 1
+	}
+	return slots
+}
+
+// grantable reports whether a document of the given priority may take a slot now. Bulk work also waits
+// while an interactive document is queued, so a freed slot goes to the upload.
+func (l *processingLanes) grantable(slots int, p priority) bool {
+	if l.busy >= slots {
+		return false
+	}
+	if p == priorityInteractive {
+		return true
+	}
+	return l.bulkBusy < bulkLimit(slots) && len(l.waiting[priorityInteractive]) == 0
+}
+
+// take marks a slot as held
+func (l *processingLanes) take(p priority) {
+	l.busy++
+	if p == priorityBulk {
+		l.bulkBusy++
+	}
+}
+
+// acquire waits for a slot, returning the function that gives it back. It fails only when ctx is
+// cancelled while waiting.
+func (l *processingLanes) acquire(ctx context.Context, slots int, p priority) (func(), error) {
+	l.mu.Lock()
+	if len(l.waiting[p]) == 0 && l.grantable(slots, p) {
+		l.take(p)
+		l.mu.Unlock()
+		return l.releaser(slots, p), nil
+	}
+	ready := make(chan struct{})
+	l.waiting[p] = append(l.waiting[p], ready)
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return l.releaser(slots, p), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		select {
+		case <-ready:
+			// The slot was granted as we gave up, so pass it on
+			l.release(slots, p)
+		default:
+			l.waiting[p] = removeWaiter(l.waiting[p], ready)
+			l.grant(slots)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// releaser returns a function that releases the slot once, however often it is called
+func (l *processingLanes) releaser(slots int, p priority) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.release(slots, p)
+		})
+	}
+}
+
+// release gives a slot back and grants any that waiting documents can now take; l.mu must be held
+func (l *processingLanes) release(slots int, p priority) {
+	l.busy--
+	if p == priorityBulk {
+		l.bulkBusy--
+	}
+	l.grant(slots)
+}
+
+// grant wakes waiting documents, interactive first, while slots allow; l.mu must be held
+func (l *processingLanes) grant(slots int) {
+	for _, p := range []priority{priorityInteractive, priorityBulk} {
+		for len(l.waiting[p]) > 0 && l.grantable(slots, p) {
+			ready := l.waiting[p][0]
+			l.waiting[p] = l.waiting[p][1:]
+			l.take(p)
+			close(ready)
+		}
+	}
+}
+
+// removeWaiter drops a cancelled waiter from its queue
+func removeWaiter(queue []chan struct{}, ready chan struct{}) []chan struct{} {
+	for i, waiter := range queue {
+		if waiter == ready {
+			return append(queue[:i], queue[i+1:]...)
+		}
+	}
+	return queue
+}
+
+// processingSlot waits for a slot to process a document in, returning the function that gives it back
+func (serverHandler *ServerHandler) processingSlot(ctx context.Context, p priority) (func(), error) {
+	slots := serverHandler.ServerConfig.IngestWorkers
+	if slots <= 0 {
+		slots = defaultIngestWorkers
+	}
+	return serverHandler.lanes.acquire(ctx, slots, p)
+}
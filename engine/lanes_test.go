@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// waitForSlot starts acquiring a slot in the background and returns the channel its release function arrives on
+func waitForSlot(lanes *processingLanes, slots int, p priority) chan func() {
+	granted := make(chan func(), 1)
+	go func() {
+		release, err := lanes.acquire(context.Background(), slots, p)
+		if err == nil {
+			granted <- release
+		}
+	}()
+	return granted
+}
+
+// queued waits until the lane holds n waiting documents
+func queued(t *testing.T, lanes *processingLanes, p priority, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		lanes.mu.Lock()
+		waiting := len(lanes.waiting[p])
+		lanes.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Expected %d waiting in lane %d", n, p)
+}
+
+func TestProcessingLanesKeepASlotForUploads(t *testing.T) {
+	// Two slots with a scheduled batch holding the one bulk work may use
+	var lanes processingLanes
+	bulk, err := lanes.acquire(context.Background(), 2, priorityBulk)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	nextBulk := waitForSlot(&lanes, 2, priorityBulk)
+	queued(t, &lanes, priorityBulk, 1)
+
+	// A document is uploaded
+	upload, err := lanes.acquire(context.Background(), 2, priorityInteractive)
+
+	// It is processed straight away while the next batch document waits
+	if err != nil {
+		t.Fatalf("Upload did not get the reserved slot: %v", err)
+	}
+	upload()
+	select {
+	case <-nextBulk:
+		t.Fatal("Bulk work took the slot kept for uploads")
+	default:
+	}
+
+	// The batch carries on once its own slot is free
+	bulk()
+	select {
+	case release := <-nextBulk:
+		release()
+	case <-time.After(5 * time.Second):
+		t.Fatal("Batch did not continue")
+	}
+}
+
+func TestProcessingLanesServeUploadsFirst(t *testing.T) {
+	// One slot held by a batch document, with another batch document queued first
+	var lanes processingLanes
+	bulk, _ := lanes.acquire(context.Background(), 1, priorityBulk)
+	nextBulk := waitForSlot(&lanes, 1, priorityBulk)
+	queued(t, &lanes, priorityBulk, 1)
+	upload := waitForSlot(&lanes, 1, priorityInteractive)
+	queued(t, &lanes, priorityInteractive, 1)
+
+	// The batch document finishes
+	bulk()
+
+	// The upload goes next, ahead of the queued batch document
+	var release func()
+	select {
+	case release = <-upload:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Upload was not given the free slot")
+	}
+	select {
+	case <-nextBulk:
+		t.Fatal("Batch document ran alongside the upload in a single slot")
+	default:
+	}
+	release()
+	select {
+	case release := <-nextBulk:
+		release()
+		release() // releasing twice must not free a second slot
+	case <-time.After(5 * time.Second):
+		t.Fatal("Batch did not continue after the upload")
+	}
+	if lanes.busy != 0 || lanes.bulkBusy != 0 {
+		t.Errorf("Expected every slot free, got %d busy and %d bulk", lanes.busy, lanes.bulkBusy)
+	}
+}
+
+func TestProcessingLanesCancelledWait(t *testing.T) {
+	// One slot in use and an upload whose client gives up while waiting
+	var lanes processingLanes
+	bulk, _ := lanes.acquire(context.Background(), 1, priorityBulk)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := lanes.acquire(ctx, 1, priorityInteractive)
+		done <- err
+	}()
+	queued(t, &lanes, priorityInteractive, 1)
+
+	// The request is cancelled
+	cancel()
+
+	// The wait ends with the context error and the upload leaves the queue
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	queued(t, &lanes, priorityInteractive, 0)
+	bulk()
+	if release, err := lanes.acquire(context.Background(), 1, priorityBulk); err != nil {
+		t.Errorf("Slot was not freed: %v", err)
+	} else {
+		release()
+	}
+}
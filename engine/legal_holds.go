@@ -0,0 +1,339 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultLegalHoldEvents is how much of the legal hold audit trail is returned when no limit is given
+const defaultLegalHoldEvents = 100
+
+// legalHoldRequest is the body of PlaceLegalHold; exactly one of DocumentID and Folder is set
+type legalHoldRequest struct {
+	DocumentID string `json:"documentId"` // ULID of the document to hold
+	Folder     string `json:"folder"`     // folder to hold, relative to the document root
+	Reason     string `json:"reason"`     // why the content must be kept, such as a case reference
+}
+
+// isAdmin reports whether the user making a request is signed in with an administrator account or named
+// in ADMIN_USERS
+func (serverHandler *ServerHandler) isAdmin(c echo.Context) bool {
+	if role, _ := c.Get(authRoleKey).(string); role == database.RoleAdmin {
+		return true
+	}
+	user := requestUser(c)
+	return user != "" && slices.Contains(serverHandler.ServerConfig.AdminUsers, user)
+}
+
+// notAdmin reports whether a request to an endpoint for administrators must be refused: accounts are in use
+// (WEB_UI_AUTH) or ADMIN_USERS names the administrators, and the caller is not one. With neither, anyone
+// who can reach the server is trusted with them.
+func (serverHandler *ServerHandler) notAdmin(c echo.Context) bool {
+	return (serverHandler.authRequired() || len(serverHandler.ServerConfig.AdminUsers) > 0) && !serverHandler.isAdmin(c)
+}
+
+// adminOnly answers 403 for a request that needs an administrator
+func adminOnly(c echo.Context) error {
+	return c.JSON(http.StatusForbidden, map[string]interface{}{
+		"error": "Only administrators may manage legal holds",
+		"code":  dto.CodeForbidden,
+	})
+}
+
+// legalHoldRefused answers 423 with the legal hold that refused a deletion
+func legalHoldRefused(c echo.Context, hold *database.LegalHold) error {
+	return c.JSON(http.StatusLocked, map[string]interface{}{
+		"error": "Content is under legal hold: " + hold.Reason,
+		"code":  dto.CodeLegalHold,
+		"hold":  hold,
+	})
+}
+
+// legalHoldSet is the legal holds in place, resolved to paths so deletions can be checked against them
+type legalHoldSet struct {
+	documents map[string]*database.LegalHold // keyed by document ULID
+	paths     map[string]*database.LegalHold // document holds keyed by the document's file path
+	folders   map[string]*database.LegalHold // folder holds keyed by absolute folder key
+}
+
+// legalHolds loads the holds in place. A held document that no longer exists is only held by ULID.
+func (serverHandler *ServerHandler) legalHolds() (*legalHoldSet, error) {
+	holds, err := serverHandler.DB.ListLegalHolds()
+	if err != nil {
+		return nil, err
+	}
+	root := serverHandler.ServerConfig.DocumentPath
+	set := &legalHoldSet{
+		documents: make(map[string]*database.LegalHold),
+		paths:     make(map[string]*database.LegalHold),
+		folders:   make(map[string]*database.LegalHold),
+	}
+	for i := range holds {
+		hold := &holds[i]
+		switch hold.Scope {
+		case database.LegalHoldDocument:
+			set.documents[hold.Target] = hold
+			if document, err := serverHandler.DB.GetDocumentByULID(hold.Target); err == nil {
+				set.paths[filepath.ToSlash(filepath.Clean(document.Path))] = hold
+			}
+		case database.LegalHoldFolder:
+			set.folders[folderKey(filepath.Join(root, filepath.FromSlash(hold.Target)))] = hold
+		}
+	}
+	return set, nil
+}
+
+// file returns the hold on a folder containing the file at path, or nil
+func (set *legalHoldSet) file(path string) *database.LegalHold {
+	folder := folderKey(filepath.Dir(path))
+	for {
+		if hold, ok := set.folders[folder]; ok {
+			return hold
+		}
+		parent := filepath.ToSlash(filepath.Dir(folder))
+		if parent == folder {
+			return nil
+		}
+		folder = parent
+	}
+}
+
+// document returns the hold keeping a document, on it or on a folder containing it, or nil
+func (set *legalHoldSet) document(document *database.Document) *database.LegalHold {
+	if hold, ok := set.documents[document.ULID.String()]; ok {
+		return hold
+	}
+	return set.file(document.Path)
+}
+
+// folder returns a hold that deleting folder would break: on the folder, a folder containing it, or a
+// folder or document inside it. Otherwise nil.
+func (set *legalHoldSet) folder(folder string) *database.LegalHold {
+	folder = folderKey(folder)
+	if hold, ok := set.folders[folder]; ok {
+		return hold
+	}
+	if hold := set.file(folder); hold != nil {
+		return hold
+	}
+	prefix := strings.TrimSuffix(folder, "/") + "/"
+	for _, holds := range []map[string]*database.LegalHold{set.folders, set.paths} {
+		for path, hold := range holds {
+			if strings.HasPrefix(path, prefix) {
+				return hold
+			}
+		}
+	}
+	return nil
+}
+
+// legalHoldTarget validates a hold request, returning the hold to place or the problem with each field
+func (serverHandler *ServerHandler) legalHoldTarget(request legalHoldRequest) (*database.LegalHold, map[string]string) {
+	problems := make(map[string]string)
+	hold := &database.LegalHold{Reason: strings.TrimSpace(request.Reason)}
+	if hold.Reason == "" {
+		problems["reason"] = "is required"
+	}
+	documentID := strings.TrimSpace(request.DocumentID)
+	folder := strings.TrimSpace(request.Folder)
+	switch {
+	case documentID != "" && folder != "":
+		problems["folder"] = "give either documentId or folder, not both"
+	case documentID != "":
+		id, err := parseULID(documentID)
+		if err != nil {
+			problems["documentId"] = "must be a document ULID"
+			break
+		}
+		hold.Scope, hold.Target = database.LegalHoldDocument, id.String()
+	case folder != "":
+		path, err := uploadFolderPath(serverHandler.ServerConfig.DocumentPath, folder)
+		if err != nil {
+			problems["folder"] = "must be a folder under the document root"
+			break
+		}
+		hold.Scope, hold.Target = database.LegalHoldFolder, relativeFolderKey(folderKey(serverHandler.ServerConfig.DocumentPath), folderKey(path))
+	default:
+		problems["documentId"] = "give either documentId or folder"
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return hold, nil
+}
+
+// PlaceLegalHold puts a document or folder on legal hold so it cannot be deleted
+// @Summary Place a legal hold
+// @Description Hold a document, or every document under a folder, so deleting it, deleting a folder containing it and cleanup are refused (423) until the hold is removed.
+// @Description Only users named in ADMIN_USERS may place holds; each one is recorded in the audit trail.
+// @Tags Legal holds
+// @Accept json
+// @Produce json
+// @Param hold body legalHoldRequest true "documentId or folder, and the reason"
+// @Success 201 {object} database.LegalHold "The hold"
+// @Failure 400 {object} dto.ErrorResponse "Invalid body or fields"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 404 {object} dto.ErrorResponse "Document or folder not found"
+// @Failure 409 {object} dto.ErrorResponse "Already on hold"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /holds [post]
+func (serverHandler *ServerHandler) PlaceLegalHold(c echo.Context) error {
+	if !serverHandler.isAdmin(c) {
+		return adminOnly(c)
+	}
+	var request legalHoldRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	hold, problems := serverHandler.legalHoldTarget(request)
+	if problems != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "The legal hold needs fixing",
+			"code":   dto.CodeValidation,
+			"fields": problems,
+		})
+	}
+
+	exists := false
+	if hold.Scope == database.LegalHoldDocument {
+		_, err := serverHandler.DB.GetDocumentByULID(hold.Target)
+		exists = err == nil
+	} else {
+		info, err := os.Stat(filepath.Join(serverHandler.ServerConfig.DocumentPath, filepath.FromSlash(hold.Target)))
+		exists = err == nil && info.IsDir()
+	}
+	if !exists {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "No " + hold.Scope + " " + hold.Target,
+			"code":  dto.CodeNotFound,
+		})
+	}
+
+	hold.PlacedBy = requestUser(c)
+	err := serverHandler.DB.PlaceLegalHold(hold)
+	if errors.Is(err, database.ErrLegalHoldExists) {
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": "The " + hold.Scope + " is already on legal hold",
+			"code":  dto.CodeConflict,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to place legal hold", "scope", hold.Scope, "target", hold.Target, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to place legal hold",
+			"code":  dto.CodeInternal,
+		})
+	}
+	Logger.Info("Placed legal hold", "id", hold.ID, "scope", hold.Scope, "target", hold.Target, "user", hold.PlacedBy)
+	return c.JSON(http.StatusCreated, hold)
+}
+
+// GetLegalHolds lists the legal holds in place
+// @Summary List legal holds
+// @Tags Legal holds
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Holds, most recently placed first"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /holds [get]
+func (serverHandler *ServerHandler) GetLegalHolds(c echo.Context) error {
+	holds, err := serverHandler.DB.ListLegalHolds()
+	if err != nil {
+		Logger.Error("Failed to list legal holds", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list legal holds",
+			"code":  dto.CodeInternal,
+		})
+	}
+	if holds == nil {
+		holds = []database.LegalHold{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"holds": holds})
+}
+
+// RemoveLegalHold lifts a legal hold
+// @Summary Remove a legal hold
+// @Description Lift a hold so its content may be deleted again. Only users named in ADMIN_USERS may remove holds; the removal and its reason are recorded in the audit trail.
+// @Tags Legal holds
+// @Produce json
+// @Param id path string true "Hold ID"
+// @Param reason query string false "Why the hold is lifted"
+// @Success 204 "Hold removed"
+// @Failure 400 {object} dto.ErrorResponse "Invalid ID"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 404 {object} dto.ErrorResponse "Hold not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /holds/{id} [delete]
+func (serverHandler *ServerHandler) RemoveLegalHold(c echo.Context) error {
+	if !serverHandler.isAdmin(c) {
+		return adminOnly(c)
+	}
+	id, ok, err := ulidParam(c, "id", "legal hold")
+	if !ok {
+		return err
+	}
+	user := requestUser(c)
+	hold, err := serverHandler.DB.RemoveLegalHold(id.String(), user, strings.TrimSpace(c.QueryParam("reason")))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Legal hold not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to remove legal hold", "id", id, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to remove legal hold",
+			"code":  dto.CodeInternal,
+		})
+	}
+	Logger.Info("Removed legal hold", "id", hold.ID, "scope", hold.Scope, "target", hold.Target, "user", user)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetLegalHoldEvents returns the audit trail of legal holds
+// @Summary Legal hold audit trail
+// @Description Every placement and removal of a legal hold, with who made it and why. Only users named in ADMIN_USERS may read it.
+// @Tags Legal holds
+// @Produce json
+// @Param limit query int false "Most recent entries to return (default 100)"
+// @Success 200 {object} map[string]interface{} "Events, newest first"
+// @Failure 400 {object} dto.ErrorResponse "Invalid limit"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /holds/events [get]
+func (serverHandler *ServerHandler) GetLegalHoldEvents(c echo.Context) error {
+	if !serverHandler.isAdmin(c) {
+		return adminOnly(c)
+	}
+	limit, err := limitParam(c, defaultLegalHoldEvents)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	events, err := serverHandler.DB.ListLegalHoldEvents(limit)
+	if err != nil {
+		Logger.Error("Failed to list legal hold events", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list legal hold events",
+			"code":  dto.CodeInternal,
+		})
+	}
+	if events == nil {
+		events = []database.LegalHoldEvent{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"events": events})
+}
@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+func TestLegalHoldsBlockDeletion(t *testing.T) {
+	// Alice is an administrator and there are two documents in two folders
+	handler := setupTestHandler(t)
+	handler.ServerConfig.AdminUsers = []string{"alice"}
+	handler.Echo.POST("/api/holds", handler.PlaceLegalHold)
+	handler.Echo.GET("/api/holds", handler.GetLegalHolds)
+	handler.Echo.GET("/api/holds/events", handler.GetLegalHoldEvents)
+	handler.Echo.DELETE("/api/holds/:id", handler.RemoveLegalHold)
+	handler.Echo.DELETE("/api/document/*", handler.DeleteFile)
+	var ids []string
+	for _, name := range []string{"cases/smith/lease.pdf", "cases/jones/will.pdf"} {
+		path := filepath.Join(handler.ServerConfig.DocumentPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create folder: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("%PDF-1.4"), 0644); err != nil {
+			t.Fatalf("Failed to write document: %v", err)
+		}
+		ids = append(ids, saveTestDocument(t, handler.DB, path, "case").ULID.String())
+	}
+	serve := func(method, target, user, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if user != "" {
+			req.SetBasicAuth(user, "secret")
+		}
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec, response
+	}
+
+	// Bob, who is not an administrator, tries to place a hold
+	rec, response := serve(http.MethodPost, "/api/holds", "bob", `{"documentId":"`+ids[0]+`","reason":"Smith v Jones"}`)
+
+	// Bob is refused
+	if rec.Code != http.StatusForbidden || response["code"] != string(dto.CodeForbidden) {
+		t.Errorf("Expected 403 for bob, got %d %v", rec.Code, response)
+	}
+
+	// Alice holds the first document and the second document's folder
+	rec, response = serve(http.MethodPost, "/api/holds", "alice", `{"documentId":"`+ids[0]+`","reason":"Smith v Jones"}`)
+	if rec.Code != http.StatusCreated || response["placedBy"] != "alice" || response["scope"] != database.LegalHoldDocument {
+		t.Fatalf("Unexpected hold response %d %v", rec.Code, response)
+	}
+	documentHold := response["id"].(string)
+	if rec, response := serve(http.MethodPost, "/api/holds", "alice", `{"folder":"/cases/jones/","reason":"probate"}`); rec.Code != http.StatusCreated || response["target"] != "cases/jones" {
+		t.Fatalf("Unexpected folder hold response %d %v", rec.Code, response)
+	}
+
+	// Holding either again conflicts, and a bad or missing target is refused
+	if rec, response := serve(http.MethodPost, "/api/holds", "alice", `{"folder":"cases/jones","reason":"again"}`); rec.Code != http.StatusConflict || response["code"] != string(dto.CodeConflict) {
+		t.Errorf("Expected 409 holding the folder again, got %d %v", rec.Code, response)
+	}
+	if rec, response := serve(http.MethodPost, "/api/holds", "alice", `{"documentId":"nope"}`); rec.Code != http.StatusBadRequest || response["code"] != string(dto.CodeValidation) {
+		t.Errorf("Expected a validation error, got %d %v", rec.Code, response)
+	} else if fields := response["fields"].(map[string]interface{}); fields["documentId"] == nil || fields["reason"] == nil {
+		t.Errorf("Expected documentId and reason problems, got %v", fields)
+	}
+	if rec, _ := serve(http.MethodPost, "/api/holds", "alice", `{"folder":"cases/brown","reason":"audit"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a folder that does not exist, got %d", rec.Code)
+	}
+
+	// The documents, their folders and the folder containing both cannot be deleted
+	held := func(what, target string) {
+		t.Helper()
+		rec, response := serve(http.MethodDelete, target, "alice", "")
+		if rec.Code != http.StatusLocked || response["code"] != string(dto.CodeLegalHold) || response["hold"] == nil {
+			t.Errorf("Expected deleting %s to be refused by a legal hold, got %d %v", what, rec.Code, response)
+		}
+	}
+	held("the held document", "/api/document/?id="+ids[0]+"&path=cases/smith/lease.pdf")
+	held("a document in the held folder", "/api/document/?id="+ids[1]+"&path=cases/jones/will.pdf")
+	held("the folder of the held document", "/api/document/?path=cases/smith")
+	held("the held folder", "/api/document/?path=cases/jones")
+	held("the folder containing both", "/api/document/?path=cases")
+	if rec, response := serve(http.MethodGet, "/api/holds", "", ""); rec.Code != http.StatusOK || len(response["holds"].([]interface{})) != 2 {
+		t.Errorf("Expected both holds listed, got %d %v", rec.Code, response)
+	}
+
+	// Alice lifts the document hold
+	rec, _ = serve(http.MethodDelete, "/api/holds/"+documentHold+"?reason=settled", "alice", "")
+
+	// The document can be deleted, but the held folder still cannot
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 removing the hold, got %d", rec.Code)
+	}
+	if rec, _ := serve(http.MethodDelete, "/api/holds/"+documentHold, "alice", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 removing the hold twice, got %d", rec.Code)
+	}
+	if rec, response := serve(http.MethodDelete, "/api/document/?id="+ids[0]+"&path=cases/smith/lease.pdf", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the document to be deleted once released, got %d %v", rec.Code, response)
+	}
+	held("the held folder", "/api/document/?path=cases")
+
+	// The audit trail records every change for administrators only
+	if rec, _ := serve(http.MethodGet, "/api/holds/events", "bob", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 reading the audit trail as bob, got %d", rec.Code)
+	}
+	rec, response = serve(http.MethodGet, "/api/holds/events", "alice", "")
+	events, _ := response["events"].([]interface{})
+	if rec.Code != http.StatusOK || len(events) != 3 {
+		t.Fatalf("Expected three audit events, got %d %v", rec.Code, response)
+	}
+	if latest := events[0].(map[string]interface{}); latest["action"] != database.LegalHoldRemoved || latest["reason"] != "settled" || latest["user"] != "alice" {
+		t.Errorf("Unexpected latest event %v", latest)
+	}
+}
+
+func TestCleanupKeepsHeldContent(t *testing.T) {
+	// A held document whose file is missing, and an orphaned file in a held folder
+	handler := setupTestHandler(t)
+	missing := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "missing.pdf"), "")
+	orphan := filepath.Join(handler.ServerConfig.DocumentPath, "evidence", "orphan.pdf")
+	if err := os.MkdirAll(filepath.Dir(orphan), 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	if err := os.WriteFile(orphan, []byte("%PDF-1.4"), 0644); err != nil {
+		t.Fatalf("Failed to write orphan: %v", err)
+	}
+	for _, hold := range []*database.LegalHold{
+		{Scope: database.LegalHoldDocument, Target: missing.ULID.String(), Reason: "audit"},
+		{Scope: database.LegalHoldFolder, Target: "evidence", Reason: "audit"},
+	} {
+		if err := handler.DB.PlaceLegalHold(hold); err != nil {
+			t.Fatalf("Failed to place hold: %v", err)
+		}
+	}
+	job, err := handler.DB.CreateJob(database.JobTypeCleanup, "test")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	// Running the cleanup for real
+	handler.cleanupJobFuncWithTracking(handler.DB, job.ID, false, OrphanPolicyIngress)
+
+	// The record and the orphan are both kept, and counted as held
+	if _, err := handler.DB.GetDocumentByULID(missing.ULID.String()); err != nil {
+		t.Errorf("Expected the held record to be kept: %v", err)
+	}
+	if _, err := os.Stat(orphan); err != nil {
+		t.Errorf("Expected the orphan in the held folder to stay: %v", err)
+	}
+	completed, err := handler.DB.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	var report cleanupReport
+	if err := json.Unmarshal([]byte(completed.Result), &report); err != nil {
+		t.Fatalf("Job result is not a cleanup report: %v", err)
+	}
+	if report.Held != 2 || report.Deleted != 0 || report.Moved != 0 || len(report.MissingFiles) != 1 {
+		t.Errorf("Expected two held and nothing changed, got %+v", report)
+	}
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/internal/seed"
+	"github.com/labstack/echo/v4"
+)
+
+// BenchmarkLoad measures the search, tree and latest endpoints against seeded databases.
+// The 100k database takes a while to seed so it is skipped with -short:
+//
+//	go test ./engine -run '^$' -bench Load -benchtime 20x
+func BenchmarkLoad(b *testing.B) {
+	for _, size := range []int{10_000, 100_000} {
+		b.Run(fmt.Sprintf("docs=%d", size), func(b *testing.B) {
+			if size > 10_000 && testing.Short() {
+				b.Skip("skipping large seed in short mode")
+			}
+			handler := setupTestHandler(b)
+			saved, err := seed.Generate(handler.DB, seed.Options{
+				Count:   size,
+				Root:    handler.ServerConfig.DocumentPath,
+				Folders: 100,
+				Seed:    1,
+				Start:   time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+				Files:   true,
+			})
+			if err != nil || saved != size {
+				b.Fatalf("Seeding failed after %d documents: %v", saved, err)
+			}
+
+			endpoints := []struct {
+				name    string
+				target  string
+				handler echo.HandlerFunc
+			}{
+				{"search", "/api/search?term=invoice", handler.SearchDocuments},
+				{"tree", "/api/documents/filesystem", handler.GetDocumentFileSystem},
+				{"latest", "/api/documents/latest", handler.GetLatestDocuments},
+				{"latest-cursor", "/api/documents/latest?cursor=", handler.GetLatestDocuments},
+			}
+			for _, endpoint := range endpoints {
+				b.Run(endpoint.name, func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						rec := httptest.NewRecorder()
+						req := httptest.NewRequest(http.MethodGet, endpoint.target, nil)
+						if err := endpoint.handler(handler.Echo.NewContext(req, rec)); err != nil || rec.Code != http.StatusOK {
+							b.Fatalf("%s returned %d: %v", endpoint.target, rec.Code, err)
+						}
+					}
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+// missingFileTracker remembers the documents that search and tree responses found missing from
+// document storage, so each is handed to the cleanup subsystem once rather than on every request
+type missingFileTracker struct {
+	mu       sync.Mutex
+	reported map[string]bool // by ULID
+}
+
+// fresh returns the ULIDs of nodes that have not been reported before and marks them reported
+func (m *missingFileTracker) fresh(nodes []dto.FileTreeNode) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.reported == nil {
+		m.reported = make(map[string]bool)
+	}
+	var ulids []string
+	for _, node := range nodes {
+		if !node.Missing || m.reported[node.ULID] {
+			continue
+		}
+		m.reported[node.ULID] = true
+		ulids = append(ulids, node.ULID)
+	}
+	return ulids
+}
+
+// fileMissing reports whether a document's file is gone from document storage. Other stat errors,
+// such as permissions, are not treated as missing since cleanup would delete the record.
+func fileMissing(path string) bool {
+	_, err := os.Stat(path)
+	return errors.Is(err, os.ErrNotExist)
+}
+
+// reportMissingFiles starts a dry-run cleanup when a response listed documents marked missing that
+// have not been reported before. The dry run lists every missing file in its job result without
+// deleting anything, since storage may only be unmounted; removing them is left to a cleanup run.
+func (serverHandler *ServerHandler) reportMissingFiles(nodes []dto.FileTreeNode) {
+	ulids := serverHandler.missingFiles.fresh(nodes)
+	if len(ulids) == 0 {
+		return
+	}
+	Logger.Warn("Documents listed whose files are missing, checking document storage", "documents", ulids)
+	job, err := serverHandler.startJob(database.JobTypeCleanup, "Checking for missing files (dry run)")
+	if errors.Is(err, errJobActive) {
+		return // the running cleanup finds them
+	}
+	if err != nil {
+		Logger.Error("Failed to create cleanup job for missing files", "error", err)
+		return
+	}
+	go serverHandler.cleanupJobFuncWithTracking(serverHandler.jobDB(), job.ID, true, OrphanPolicyReport)
+}
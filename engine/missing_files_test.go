@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+// waitForMissingFileCheck waits for the dry-run cleanup started for missing files and returns its report
+func waitForMissingFileCheck(t *testing.T, handler *ServerHandler) cleanupReport {
+	t.Helper()
+	jobs, err := handler.DB.GetRecentJobs(10, 0)
+	if err != nil {
+		t.Fatalf("GetRecentJobs failed: %v", err)
+	}
+	var cleanups []database.Job
+	for _, job := range jobs {
+		if job.Type == database.JobTypeCleanup {
+			cleanups = append(cleanups, job)
+		}
+	}
+	if len(cleanups) != 1 {
+		t.Fatalf("Expected one cleanup job, got %d", len(cleanups))
+	}
+	job := waitForTestJob(t, handler.DB, cleanups[0].ID.String())
+	var report cleanupReport
+	if err := json.Unmarshal([]byte(job.Result), &report); err != nil {
+		t.Fatalf("Job result is not a cleanup report: %v (%s)", err, job.Result)
+	}
+	return report
+}
+
+func TestSearchMarksMissingFiles(t *testing.T) {
+	// Two matching documents, one of whose files has gone missing
+	handler := setupTestHandler(t)
+	presentPath := filepath.Join(handler.ServerConfig.DocumentPath, "present.txt")
+	if err := os.WriteFile(presentPath, []byte("boiler invoice"), 0644); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	present := saveTestDocument(t, handler.DB, presentPath, "boiler invoice")
+	missing := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "gone.txt"), "boiler service")
+
+	// They are searched for twice
+	var response dto.FileSystem
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		handler.SearchDocuments(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/search?term=boiler", nil), rec))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Unexpected response %d %s", rec.Code, rec.Body.String())
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+
+	// Both are listed and only the missing one is marked
+	marked := make(map[string]bool)
+	for _, node := range response.FileSystem[1:] {
+		marked[node.ULID] = node.Missing
+	}
+	if len(marked) != 2 || marked[present.ULID.String()] || !marked[missing.ULID.String()] {
+		t.Errorf("Expected only %s marked missing, got %+v", missing.Name, response.FileSystem)
+	}
+
+	// One dry-run cleanup was started, which reports the file without deleting its entry
+	report := waitForMissingFileCheck(t, handler)
+	if !report.DryRun || len(report.MissingFiles) != 1 || report.MissingFiles[0].ULID != missing.ULID.String() {
+		t.Errorf("Unexpected cleanup report %+v", report)
+	}
+	if _, err := handler.DB.GetDocumentByULID(missing.ULID.String()); err != nil {
+		t.Errorf("Expected the entry kept for a cleanup run to remove: %v", err)
+	}
+}
+
+func TestFileTreeMarksMissingFiles(t *testing.T) {
+	// A document whose file has gone missing
+	handler := setupTestHandler(t)
+	missing := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "gone.pdf"), "")
+	handler.backfillFolders()
+
+	// The document tree is fetched
+	rec := httptest.NewRecorder()
+	if err := handler.GetDocumentFileSystem(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/documents/filesystem", nil), rec)); err != nil {
+		t.Fatalf("GetDocumentFileSystem failed: %v", err)
+	}
+
+	// The document is listed and marked rather than the tree carrying an error
+	var tree dto.FileSystem
+	if err := json.Unmarshal(rec.Body.Bytes(), &tree); err != nil {
+		t.Fatalf("Failed to decode tree: %v", err)
+	}
+	found := false
+	for _, node := range tree.FileSystem {
+		if node.ULID == missing.ULID.String() {
+			found = node.Missing
+		}
+	}
+	if !found || tree.Error != "" {
+		t.Errorf("Expected %s marked missing without an error, got %+v", missing.Name, tree)
+	}
+	if report := waitForMissingFileCheck(t, handler); len(report.MissingFiles) != 1 {
+		t.Errorf("Expected the missing file reported to cleanup, got %+v", report)
+	}
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// pushBulletURL is where notifications are pushed when PUSHBULLET_TOKEN is set
+var pushBulletURL = "https://api.pushbullet.com/v2/pushes"
+
+// notify logs a warning for the administrator and, when PUSHBULLET_TOKEN is set, pushes it to their devices.
+// The push is sent in the background so a slow or unreachable service never holds up ingestion.
+func (serverHandler *ServerHandler) notify(title, message string) {
+	Logger.Warn(message, "notification", title)
+	token := serverHandler.ServerConfig.PushBulletToken
+	if token == "" {
+		return
+	}
+	go func() {
+		body, err := json.Marshal(map[string]string{"type": "note", "title": title, "body": message})
+		if err != nil {
+			return
+		}
+		request, err := http.NewRequest(http.MethodPost, pushBulletURL, bytes.NewReader(body))
+		if err != nil {
+			Logger.Error("Unable to build notification", "error", err)
+			return
+		}
+		request.Header.Set("Access-Token", token)
+		request.Header.Set("Content-Type", "application/json")
+		client := &http.Client{Timeout: 10 * time.Second}
+		response, err := client.Do(request)
+		if err != nil {
+			Logger.Error("Unable to send notification", "error", err)
+			return
+		}
+		response.Body.Close()
+		if response.StatusCode >= 300 {
+			Logger.Error("Notification was refused", "status", response.StatusCode)
+		}
+	}()
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/labstack/echo/v4"
+)
+
+// ocrLanguageTimeout is how long tesseract --list-langs may take
+const ocrLanguageTimeout = 5 * time.Second
+
+// ocrLanguages reports the tesseract language packs installed and whether OCR_LANGUAGES can use them
+type ocrLanguages struct {
+	Source     string   `json:"source"`          // local, service, or none when OCR is not configured
+	Installed  []string `json:"installed"`       // language packs tesseract lists, empty when they cannot be listed
+	Configured []string `json:"configured"`      // OCR_LANGUAGES, empty for tesseract's default (eng)
+	Missing    []string `json:"missing"`         // configured languages that are not installed
+	Error      string   `json:"error,omitempty"` // why the installed packs could not be listed
+}
+
+// splitOCRLanguages splits an OCR_LANGUAGES value such as "eng+deu" into its languages. Commas are
+// accepted as well as tesseract's plus signs.
+func splitOCRLanguages(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == '+' || r == ',' || r == ' '
+	})
+}
+
+// listTesseractLanguages runs tesseract --list-langs and returns the language packs it names
+func listTesseractLanguages(tesseractPath string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ocrLanguageTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, tesseractPath, "--list-langs").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s --list-langs failed: %w", tesseractPath, err)
+	}
+	return parseTesseractLanguages(string(output)), nil
+}
+
+// parseTesseractLanguages reads the output of tesseract --list-langs: a heading naming the tessdata
+// folder, then one language per line. osd, orientation and script detection, is not a language.
+func parseTesseractLanguages(output string) []string {
+	var languages []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "osd" || strings.HasPrefix(line, "List of available languages") || strings.Contains(line, " ") {
+			continue
+		}
+		languages = append(languages, line)
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+// detectOCRLanguages lists the installed language packs of the local tesseract and checks
+// OCR_LANGUAGES against them. The tesseract sidecar has no way to list its packs, so they go unchecked.
+func detectOCRLanguages(serverConfig config.ServerConfig) ocrLanguages {
+	status := ocrLanguages{Installed: []string{}, Configured: splitOCRLanguages(serverConfig.OCRLanguages), Missing: []string{}}
+	if status.Configured == nil {
+		status.Configured = []string{}
+	}
+	switch {
+	case serverConfig.TesseractServiceURL != "":
+		status.Source = "service"
+		status.Error = "the tesseract service does not list its language packs"
+		return status
+	case serverConfig.TesseractPath != "":
+		status.Source = "local"
+	default:
+		status.Source = "none"
+		return status
+	}
+	installed, err := listTesseractLanguages(serverConfig.TesseractPath)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Installed = installed
+	for _, language := range status.Configured {
+		if !slices.Contains(installed, language) {
+			status.Missing = append(status.Missing, language)
+		}
+	}
+	return status
+}
+
+// ocrLanguageChecks warns at startup about configured OCR languages that have no installed pack, as
+// tesseract fails on every page asked to use one and the documents are stored without text
+func (serverHandler *ServerHandler) ocrLanguageChecks() {
+	status := detectOCRLanguages(serverHandler.ServerConfig)
+	switch {
+	case status.Source != "local":
+		return
+	case status.Error != "":
+		Logger.Warn("Unable to list tesseract language packs", "error", status.Error)
+	case len(status.Missing) > 0:
+		Logger.Warn("OCR_LANGUAGES names language packs tesseract does not have; OCR will fail until they are installed (on Debian and Ubuntu, apt install tesseract-ocr-<language>)",
+			"missing", strings.Join(status.Missing, ", "), "installed", strings.Join(status.Installed, ", "))
+	default:
+		Logger.Info("Tesseract language packs", "installed", strings.Join(status.Installed, ", "), "configured", serverHandler.ServerConfig.OCRLanguages)
+	}
+}
+
+// GetOCRLanguages reports the installed tesseract language packs
+// @Summary OCR language packs
+// @Description The language packs the local tesseract has installed (tesseract --list-langs, run on each request), the languages OCR_LANGUAGES asks for, and any of those that are missing. The tesseract sidecar cannot list its packs, so with TESSERACT_SERVICE_URL set they are not checked.
+// @Tags System
+// @Produce json
+// @Success 200 {object} map[string]interface{} "source, installed, configured, missing and any error"
+// @Router /about/ocr [get]
+func (serverHandler *ServerHandler) GetOCRLanguages(c echo.Context) error {
+	return c.JSON(http.StatusOK, detectOCRLanguages(serverHandler.ServerConfig))
+}
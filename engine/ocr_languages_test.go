@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/drummonds/godocs/config"
+)
+
+// tesseract 5 output for --list-langs
+const listLangsOutput = `List of available languages in "/usr/share/tesseract-ocr/5/tessdata/" (3):
+eng
+osd
+fra
+`
+
+func TestParseTesseractLanguages(t *testing.T) {
+	t.Run("Heading and osd are left out and the languages sorted", func(t *testing.T) {
+		if got := parseTesseractLanguages(listLangsOutput); !slices.Equal(got, []string{"eng", "fra"}) {
+			t.Errorf("Expected eng and fra, got %v", got)
+		}
+	})
+	t.Run("OCR_LANGUAGES splits on plus and comma", func(t *testing.T) {
+		if got := splitOCRLanguages("eng+deu, fra"); !slices.Equal(got, []string{"eng", "deu", "fra"}) {
+			t.Errorf("Expected three languages, got %v", got)
+		}
+	})
+}
+
+func TestGetOCRLanguages(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Fake tesseract is a shell script")
+	}
+	// A tesseract with English and French packs, and OCR_LANGUAGES asking for English and German
+	tesseract := filepath.Join(t.TempDir(), "tesseract")
+	os.WriteFile(tesseract, []byte("#!/bin/sh\ncat <<'EOF'\n"+listLangsOutput+"EOF\n"), 0755)
+	handler := setupTestHandler(t)
+	handler.ServerConfig.TesseractPath = tesseract
+	handler.ServerConfig.OCRLanguages = "eng+deu"
+	handler.Echo.GET("/api/about/ocr", handler.GetOCRLanguages)
+
+	// The language packs are asked for
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/about/ocr", nil))
+
+	// German is reported missing
+	var status ocrLanguages
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with JSON, got %d %s", rec.Code, rec.Body)
+	}
+	if status.Source != "local" || !slices.Equal(status.Installed, []string{"eng", "fra"}) || !slices.Equal(status.Missing, []string{"deu"}) {
+		t.Errorf("Expected deu missing from eng and fra, got %+v", status)
+	}
+
+	// The sidecar's packs are not checked, and without OCR there is nothing to list
+	if status := detectOCRLanguages(config.ServerConfig{TesseractServiceURL: "tesseract:8884", OCRLanguages: "deu"}); status.Source != "service" || len(status.Missing) != 0 || status.Error == "" {
+		t.Errorf("Expected the sidecar to go unchecked, got %+v", status)
+	}
+	if status := detectOCRLanguages(config.ServerConfig{}); status.Source != "none" || status.Installed == nil {
+		t.Errorf("Expected no OCR and empty lists, got %+v", status)
+	}
+}
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/engine/pdfrenderer"
+)
+
+// ocrServiceClient sends page images to the tesseract sidecar; a page rarely takes more than a few seconds
+var ocrServiceClient = &http.Client{Timeout: 2 * time.Minute}
+
+// tesseractServiceResponse is the reply from a tesseract-server sidecar
+type tesseractServiceResponse struct {
+	Data struct {
+		Exit struct {
+			Code int `json:"code"`
+		} `json:"exit"`
+		Stdout string `json:"stdout"`
+		Stderr string `json:"stderr"`
+	} `json:"data"`
+}
+
+// ocrConfigured reports whether OCR can run, either with a local tesseract or the tesseract sidecar
+func (serverHandler *ServerHandler) ocrConfigured() bool {
+	return serverHandler.ServerConfig.TesseractPath != "" || serverHandler.ServerConfig.TesseractServiceURL != ""
+}
+
+// pdfRenderingMode names where PDF pages are rendered for OCR: "service", "local" or "unavailable"
+// for a -tags nopdfium build without PDF_SERVICE_URL
+func pdfRenderingMode(serverConfig config.ServerConfig) string {
+	switch {
+	case serverConfig.PDFServiceURL != "":
+		return "service"
+	case pdfrenderer.LocalRendering:
+		return "local"
+	default:
+		return "unavailable"
+	}
+}
+
+// tesseractServiceOCR sends an image to the tesseract sidecar (POST <service>/tesseract with multipart
+// "options" and "file" fields) and returns the recognised text. Like local OCR, an image tesseract cannot
+// read gives empty text, but an unreachable or failing service is an error so the file stays in ingress.
+func (serverHandler *ServerHandler) tesseractServiceOCR(imageName string) (*string, error) {
+	endpoint, err := parseServiceAddress(serverHandler.ServerConfig.TesseractServiceURL)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + "/tesseract"
+
+	image, err := os.Open(imageName)
+	if err != nil {
+		return nil, err
+	}
+	defer image.Close()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	options := []byte("{}")
+	if languages := splitOCRLanguages(serverHandler.ServerConfig.OCRLanguages); len(languages) > 0 {
+		if options, err = json.Marshal(map[string][]string{"languages": languages}); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.WriteField("options", string(options)); err != nil {
+		return nil, err
+	}
+	part, err := writer.CreateFormFile("file", asciiName(filepath.Base(imageName)))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, image); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	resp, err := ocrServiceClient.Post(endpoint.String(), writer.FormDataContentType(), &body)
+	if err != nil {
+		Logger.Error("Tesseract service request failed", "url", endpoint.String(), "imageName", imageName, "error", err)
+		return nil, fmt.Errorf("tesseract service request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		Logger.Error("Tesseract service returned an error", "status", resp.Status, "imageName", imageName, "detail", string(detail))
+		return nil, fmt.Errorf("tesseract service returned %s", resp.Status)
+	}
+	var result tesseractServiceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("unable to decode tesseract service response: %w", err)
+	}
+	fullText := result.Data.Stdout
+	if result.Data.Exit.Code != 0 {
+		Logger.Warn("Tesseract service could not OCR image, storing document without text", "imageName", imageName, "detail", result.Data.Stderr)
+		fullText = ""
+	}
+	return &fullText, nil
+}
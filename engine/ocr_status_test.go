@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/oklog/ulid/v2"
+)
+
+func TestExtractTextOCRStatus(t *testing.T) {
+	// A text file, a Word document and a text file that has gone
+	dir := t.TempDir()
+	notes := filepath.Join(dir, "notes.txt")
+	os.WriteFile(notes, []byte("meeting notes"), 0644)
+	letter := filepath.Join(dir, "letter.docx")
+	os.WriteFile(letter, []byte("not really a docx"), 0644)
+	gone := filepath.Join(dir, "gone.txt")
+	handler := &ServerHandler{}
+
+	// Files needing no OCR are skipped, even when their text cannot be extracted
+	if text, status, err := handler.extractText(notes, nil); err != nil || text != "meeting notes" || status != database.OCRSkipped {
+		t.Errorf("Expected the notes skipped, got %q, %q, %v", text, status, err)
+	}
+	if _, status, err := handler.extractText(letter, nil); !errors.Is(err, errExtractionFailed) || status != database.OCRSkipped {
+		t.Errorf("Expected the letter skipped with an extraction error, got %q, %v", status, err)
+	}
+
+	// A document whose text could not be read is marked failed
+	if _, status, err := handler.extractText(gone, nil); err == nil || status != database.OCRFailed {
+		t.Errorf("Expected the missing file failed, got %q, %v", status, err)
+	}
+}
+
+func TestSearchResultsCarryOCRStatus(t *testing.T) {
+	// A scan still waiting on OCR
+	documents := []database.Document{{Name: "receipt.png", ULID: ulid.Make(), Path: "/docs/receipt.png", OCRStatus: database.OCRPending}}
+
+	// It is listed as a search result
+	tree := convertDocumentsToFileTree(documents)
+
+	// Its node carries the status for the badge
+	found := false
+	for _, node := range tree {
+		if node.Name == "receipt.png" {
+			found = true
+			if node.OCRStatus != database.OCRPending {
+				t.Errorf("Expected the result to be pending, got %+v", node)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected receipt.png among the results, got %+v", tree)
+	}
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/engine/pdfrenderer"
+)
+
+func TestConvertToImageRefusesLargePDF(t *testing.T) {
+	// A PDF larger than the configured OCR size limit
+	handler := setupTestHandler(t)
+	handler.ServerConfig.OCRMaxFileMB = 1
+	handler.ServerConfig.TesseractPath = "/usr/bin/tesseract"
+	path := filepath.Join(t.TempDir(), "huge.pdf")
+	if err := os.WriteFile(path, make([]byte, 2<<20), 0644); err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+
+	// Converting it for OCR
+	_, err := handler.convertToImage(context.Background(), path)
+
+	// It is rejected before rendering
+	if err == nil || !strings.Contains(err.Error(), "OCR limit") {
+		t.Errorf("Expected size guard error, got %v", err)
+	}
+}
+
+func TestRenderPagesStreamsOnePageAtATime(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping PDFium rendering test in short mode")
+	}
+	// A one page PDF
+	path := filepath.Join(t.TempDir(), "page.pdf")
+	if err := createSimpleTestPDF(path, "Streamed"); err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	renderer, err := pdfrenderer.NewRenderer("")
+	if err != nil {
+		t.Skipf("PDFium renderer unavailable: %v", err)
+	}
+	defer renderer.Close()
+
+	// Rendering with a page cap of zero (all pages)
+	var rendered []int
+	pageCount, err := renderer.RenderPages(path, 0, func(pageIndex int, page image.Image) error {
+		if page.Bounds().Dx() == 0 {
+			t.Errorf("Page %d rendered empty", pageIndex)
+		}
+		rendered = append(rendered, pageIndex)
+		return nil
+	})
+
+	// Each page is handed over once, in order
+	if err != nil || pageCount != 1 || len(rendered) != 1 || rendered[0] != 0 {
+		t.Errorf("Unexpected render: count=%d rendered=%v err=%v", pageCount, rendered, err)
+	}
+}
+
+func TestOCRUsesTesseractService(t *testing.T) {
+	// A tesseract sidecar and no local tesseract
+	var fileName, options string
+	sidecar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tesseract" {
+			http.NotFound(w, r)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err == nil {
+			file.Close()
+			fileName = header.Filename
+		}
+		options = r.FormValue("options")
+		w.Write([]byte(`{"data":{"exit":{"code":0},"stdout":"Invoice 42\n","stderr":""}}`))
+	}))
+	defer sidecar.Close()
+	handler := setupTestHandler(t)
+	handler.ServerConfig.TesseractPath = ""
+	handler.ServerConfig.TesseractServiceURL = strings.TrimPrefix(sidecar.URL, "http://")
+	imageName := filepath.Join(t.TempDir(), "Rechnung ü.png")
+	os.WriteFile(imageName, []byte("png"), 0644)
+
+	// The image is OCRed
+	text, err := handler.ocrProcessing(imageName)
+
+	// The sidecar's text is used and it was sent an ASCII file name
+	if err != nil || text == nil || *text != "Invoice 42\n" {
+		t.Fatalf("Expected text from the sidecar, got %v, %v", text, err)
+	}
+	if fileName != "Rechnung_u.png" || options != "{}" {
+		t.Errorf("Unexpected upload: file=%q options=%q", fileName, options)
+	}
+	if !handler.ocrConfigured() {
+		t.Error("Expected OCR to count as configured with only the sidecar")
+	}
+}
+
+func TestOCRServiceFailureIsAnError(t *testing.T) {
+	// A tesseract sidecar that is failing
+	sidecar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer sidecar.Close()
+	handler := setupTestHandler(t)
+	handler.ServerConfig.TesseractServiceURL = sidecar.URL
+	imageName := filepath.Join(t.TempDir(), "page.png")
+	os.WriteFile(imageName, []byte("png"), 0644)
+
+	// The image is OCRed
+	_, err := handler.ocrProcessing(imageName)
+
+	// Ingestion sees an error rather than silently storing no text
+	if err == nil {
+		t.Error("Expected an error from a failing tesseract service")
+	}
+}
@@ -0,0 +1,363 @@
+package engine
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/engine/pdfrenderer"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+	"github.com/oklog/ulid/v2"
+)
+
+// scanRenderDPI is the resolution pdfrenderer renders PDF pages at, so the most a recompressed PDF keeps
+const scanRenderDPI = 150
+
+// longestPageInches is the long side of US legal, the longest common paper size. A scanned image is
+// downsampled until its long side is no more than OPTIMISE_DPI dots for each of these inches.
+const longestPageInches = 14
+
+// colourTolerance is how far apart, out of 255, a pixel's channels may be for it still to count as grey,
+// and colourShare the share of pixels further apart than that for a scan to be kept in colour. Scanners
+// fringe the edges of black print with a little colour, which these allow for.
+const (
+	colourTolerance = 24
+	colourShare     = 0.001
+)
+
+// errNotSmaller is returned by optimiseDocument when recompressing saved less than OPTIMISE_MIN_SAVING
+var errNotSmaller = errors.New("recompressed file is not enough smaller")
+
+// optimiseResult counts what a storage optimisation pass did with each document
+type optimiseResult struct {
+	Optimised   int   `json:"optimised"`   // recompressed, with the smaller file stored in its place
+	Skipped     int   `json:"skipped"`     // not a scan, already recompressed, changed on disk or not enough smaller
+	Held        int   `json:"held"`        // under legal hold, locked or archived, so left alone
+	Failed      int   `json:"failed"`      // could not be read, recompressed or replaced
+	BytesBefore int64 `json:"bytesBefore"` // size of the recompressed documents before
+	BytesAfter  int64 `json:"bytesAfter"`  // and after
+	BytesSaved  int64 `json:"bytesSaved"`
+}
+
+// validateOptimiseSettings checks the OPTIMISE_* settings are in range. A DPI or quality of 0 keeps the
+// scanned resolution or uses the JPEG encoder's default quality.
+func validateOptimiseSettings(cfg config.ServerConfig) error {
+	switch {
+	case cfg.OptimiseDPI != 0 && cfg.OptimiseDPI < 72:
+		return fmt.Errorf("OPTIMISE_DPI is %d, at least 72 is needed to keep scans readable", cfg.OptimiseDPI)
+	case cfg.OptimiseJPEGQuality < 0 || cfg.OptimiseJPEGQuality > 100:
+		return fmt.Errorf("OPTIMISE_JPEG_QUALITY is %d, expected 1 to 100", cfg.OptimiseJPEGQuality)
+	case cfg.OptimiseMinSaving < 0 || cfg.OptimiseMinSaving > 99:
+		return fmt.Errorf("OPTIMISE_MIN_SAVING is %d, expected a percentage from 0 to 99", cfg.OptimiseMinSaving)
+	}
+	return nil
+}
+
+// isColour reports whether enough of an image's pixels have colour for it not to be stored in greyscale
+func isColour(img image.Image) bool {
+	pixels := imaging.Clone(img)
+	allowed := int(float64(len(pixels.Pix)/4) * colourShare)
+	coloured := 0
+	for i := 0; i+2 < len(pixels.Pix); i += 4 {
+		r, g, b := int(pixels.Pix[i]), int(pixels.Pix[i+1]), int(pixels.Pix[i+2])
+		if max(r, g, b)-min(r, g, b) > colourTolerance {
+			if coloured++; coloured > allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// optimiseJPEGQuality is the quality recompressed pages are encoded at
+func (serverHandler *ServerHandler) optimiseJPEGQuality() int {
+	if quality := serverHandler.ServerConfig.OptimiseJPEGQuality; quality > 0 {
+		return quality
+	}
+	return jpeg.DefaultQuality
+}
+
+// recompressPage shrinks a scanned page to at most maxWidth pixels wide, 0 for no limit, and turns it
+// grey when it has no colour, so it is encoded with one channel instead of three
+func recompressPage(page image.Image, maxWidth int) image.Image {
+	if maxWidth > 0 && page.Bounds().Dx() > maxWidth {
+		page = imaging.Resize(page, maxWidth, 0, imaging.Lanczos)
+	}
+	if isColour(page) {
+		return page
+	}
+	grey := image.NewGray(page.Bounds())
+	draw.Draw(grey, grey.Bounds(), page, page.Bounds().Min, draw.Src)
+	return grey
+}
+
+// originalFilePath is where a recompressed document's original is kept under OPTIMISE_ORIGINALS_PATH: at
+// the same place relative to the document folder
+func (serverHandler *ServerHandler) originalFilePath(document database.Document) string {
+	relative, err := filepath.Rel(serverHandler.ServerConfig.DocumentPath, filepath.FromSlash(document.Path))
+	if err != nil || !filepath.IsLocal(relative) {
+		relative = filepath.Join(document.ULID.String(), document.Name) // not under the document folder
+	}
+	return filepath.Join(serverHandler.ServerConfig.OriginalsPath, relative)
+}
+
+// recompressImage writes a smaller copy of a scanned image to destination in the same format: no more
+// than OPTIMISE_DPI across the longest page, greyscale when it has no colour, and JPEGs at
+// OPTIMISE_JPEG_QUALITY
+func (serverHandler *ServerHandler) recompressImage(source, destination string) error {
+	img, err := imaging.Open(source)
+	if err != nil {
+		return err
+	}
+	longest := serverHandler.ServerConfig.OptimiseDPI * longestPageInches
+	if bounds := img.Bounds(); longest > 0 && max(bounds.Dx(), bounds.Dy()) > longest {
+		img = imaging.Fit(img, longest, longest, imaging.Lanczos)
+	}
+	return imaging.Save(recompressPage(img, 0), destination,
+		imaging.JPEGQuality(serverHandler.optimiseJPEGQuality()), imaging.PNGCompressionLevel(png.BestCompression))
+}
+
+// recompressPDF writes a smaller copy of a scanned PDF to destination, each page rendered and stored as
+// a JPEG no more than OPTIMISE_DPI, in greyscale when it has no colour
+func (serverHandler *ServerHandler) recompressPDF(source, destination string) error {
+	pages, err := readPageGeometry(source)
+	if err != nil {
+		return err
+	}
+	renderer, err := pdfrenderer.NewRenderer(serverHandler.ServerConfig.PDFServiceURL)
+	if err != nil {
+		return err
+	}
+	defer renderer.Close()
+	dpi := scanRenderDPI
+	if configured := serverHandler.ServerConfig.OptimiseDPI; configured > 0 {
+		dpi = min(configured, scanRenderDPI)
+	}
+	return writeImagePDF(renderer, source, destination, pages, serverHandler.optimiseJPEGQuality(), func(_ int, page image.Image) image.Image {
+		return recompressPage(page, page.Bounds().Dx()*dpi/scanRenderDPI)
+	})
+}
+
+// optimiseDocument recompresses a scanned document and stores the smaller file in its place, keeping the
+// original under OPTIMISE_ORIGINALS_PATH when it is set. The original's hash is recorded so a copy of it
+// ingested again is found as a duplicate. It returns the sizes before and after; a document that is not a
+// scan, or whose file no longer matches its hash, returns zero sizes and no error, and one that would not
+// be made small enough returns errNotSmaller.
+func (serverHandler *ServerHandler) optimiseDocument(document database.Document) (before, after int64, err error) {
+	ext := strings.ToLower(filepath.Ext(document.Path))
+	switch {
+	case ext == ".pdf":
+		if _, err := pdfProcessing(document.Path); err == nil {
+			return 0, 0, nil // a text layer means it was not scanned
+		}
+	case !slices.Contains(scanImages, ext):
+		return 0, 0, nil
+	}
+	hashes, err := database.HashFile(document.Path)
+	if err != nil {
+		return 0, 0, err
+	}
+	if document.Hash != "" && !hashes.Matches(document.Hash) {
+		Logger.Warn("Document changed on disk, not recompressing", "ulid", document.ULID.String(), "path", document.Path)
+		return 0, 0, nil
+	}
+	info, err := os.Stat(document.Path)
+	if err != nil {
+		return 0, 0, err
+	}
+	before = info.Size()
+
+	workDir, cleanup, err := serverHandler.newWorkDir("optimise-*")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cleanup()
+	recompressed := filepath.Join(workDir, filepath.Base(document.Path))
+	if ext == ".pdf" {
+		err = serverHandler.recompressPDF(document.Path, recompressed)
+	} else {
+		err = serverHandler.recompressImage(document.Path, recompressed)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if info, err = os.Stat(recompressed); err != nil {
+		return 0, 0, err
+	}
+	after = info.Size()
+	if after*100 > before*int64(100-serverHandler.ServerConfig.OptimiseMinSaving) {
+		return before, after, errNotSmaller
+	}
+
+	optimisation := &database.DocumentOptimisation{
+		DocumentULID:  document.ULID.String(),
+		OriginalHash:  document.Hash,
+		OriginalSize:  before,
+		OptimisedSize: after,
+		OptimisedAt:   database.Now(),
+	}
+	if optimisation.OriginalHash == "" {
+		optimisation.OriginalHash = hashes.Current()
+	}
+	if serverHandler.ServerConfig.OriginalsPath != "" {
+		keptPath := serverHandler.originalFilePath(document)
+		if err := os.MkdirAll(filepath.Dir(keptPath), os.ModePerm); err != nil {
+			return 0, 0, fmt.Errorf("unable to create originals folder: %w", err)
+		}
+		if err := copyFile(document.Path, keptPath); err != nil {
+			return 0, 0, fmt.Errorf("unable to keep original of %s: %w", document.Path, err)
+		}
+		optimisation.OriginalPath = filepath.ToSlash(keptPath)
+	}
+	if _, err := replaceTransformed(document.Path, recompressed); err != nil {
+		return 0, 0, err
+	}
+	stored, err := database.HashFile(document.Path)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := serverHandler.DB.UpdateDocumentHash(document.ULID.String(), stored.Current()); err != nil {
+		return 0, 0, err
+	}
+	if err := serverHandler.DB.UpdateDocumentFileDetails(document.ULID.String(), after, document.PageCount); err != nil {
+		return 0, 0, err
+	}
+	if err := serverHandler.DB.SaveDocumentOptimisation(optimisation); err != nil {
+		return 0, 0, err
+	}
+	return before, after, nil
+}
+
+// optimiseDocuments recompresses every scanned document not recompressed before. Documents under legal
+// hold, locked or in cold storage are left alone.
+func (serverHandler *ServerHandler) optimiseDocuments(db database.Repository, heartbeat *jobHeartbeat) (optimiseResult, error) {
+	var result optimiseResult
+	holds, err := serverHandler.legalHolds()
+	if err != nil {
+		return result, err
+	}
+	var cursor *database.DocumentCursor
+	for {
+		page, err := db.GetNewestDocumentsAfter(cursor, maxCursorPageSize)
+		if err != nil {
+			return result, err
+		}
+		for _, document := range page {
+			heartbeat.beat()
+			documentULID := document.ULID.String()
+			if _, err := db.GetDocumentOptimisation(documentULID); err == nil {
+				result.Skipped++
+				continue
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				return result, err
+			}
+			if holds.document(&document) != nil {
+				result.Held++
+				continue
+			}
+			if lock, err := serverHandler.lockedAgainst("", documentULID); err != nil || lock != nil {
+				result.Held++
+				continue
+			}
+			if _, err := db.GetDocumentArchive(documentULID); err == nil {
+				result.Held++
+				continue
+			}
+			before, after, err := serverHandler.optimiseDocument(document)
+			switch {
+			case errors.Is(err, errNotSmaller):
+				Logger.Debug("Recompressing saved too little, keeping document as it is", "ulid", documentULID, "size", before, "recompressed", after)
+				result.Skipped++
+			case err != nil:
+				Logger.Warn("Unable to recompress document", "ulid", documentULID, "path", document.Path, "error", err)
+				result.Failed++
+			case before == 0:
+				result.Skipped++
+			default:
+				Logger.Info("Recompressed document", "ulid", documentULID, "path", document.Path, "size", before, "recompressed", after)
+				result.Optimised++
+				result.BytesBefore += before
+				result.BytesAfter += after
+			}
+		}
+		if len(page) < maxCursorPageSize {
+			result.BytesSaved = result.BytesBefore - result.BytesAfter
+			return result, nil
+		}
+		cursor = database.CursorAfter(page[len(page)-1])
+	}
+}
+
+// OptimiseDocuments starts a job that recompresses scanned documents to save storage
+// @Summary Optimise storage
+// @Description Recompress scanned PDFs and images in the background: downsampled to OPTIMISE_DPI, greyscale where a scan has no colour, and re-encoded as JPEG at OPTIMISE_JPEG_QUALITY. A file is only replaced when it comes out at least OPTIMISE_MIN_SAVING percent smaller, and the original is kept under OPTIMISE_ORIGINALS_PATH when it is set.
+// @Description PDFs with a text layer, documents already recompressed, and documents under legal hold, locked or archived are left alone. The job result reports the bytes saved.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Job created with jobId"
+// @Failure 409 {object} map[string]interface{} "An optimisation job is already active; jobId is that job"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /documents/optimise [post]
+func (serverHandler *ServerHandler) OptimiseDocuments(c echo.Context) error {
+	if err := validateOptimiseSettings(serverHandler.ServerConfig); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeInternal,
+		})
+	}
+	job, err := serverHandler.startJob(database.JobTypeOptimise, "Starting storage optimisation")
+	if errors.Is(err, errJobActive) {
+		return jobAlreadyActive(c, job)
+	}
+	if err != nil {
+		Logger.Error("Failed to create optimisation job", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to create optimisation job",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	go serverHandler.optimiseJob(serverHandler.DB, job.ID)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":        "Storage optimisation started",
+		"jobId":          job.ID.String(),
+		"keepsOriginals": serverHandler.ServerConfig.OriginalsPath != "",
+	})
+}
+
+// optimiseJob runs optimiseDocuments with job tracking
+func (serverHandler *ServerHandler) optimiseJob(db database.Repository, jobID ulid.ULID) {
+	db.UpdateJobStatus(jobID, database.JobStatusRunning, "Recompressing scanned documents")
+
+	result, err := serverHandler.optimiseDocuments(db, newJobHeartbeat(db, jobID))
+	if err != nil {
+		Logger.Error("Storage optimisation failed", "optimised", result.Optimised, "error", err)
+		db.UpdateJobError(jobID, fmt.Sprintf("Optimisation failed after %d documents: %v", result.Optimised, err))
+		return
+	}
+	if result.Optimised > 0 {
+		serverHandler.invalidateDocumentCache()
+	}
+	summary, _ := json.Marshal(result)
+	if err := db.CompleteJob(jobID, string(summary)); err != nil {
+		Logger.Error("Failed to mark optimisation job as complete", "error", err)
+	}
+	Logger.Info("Storage optimisation completed", "optimised", result.Optimised, "skipped", result.Skipped,
+		"held", result.Held, "failed", result.Failed, "bytesSaved", result.BytesSaved)
+}
@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/drummonds/godocs/database"
+)
+
+func TestRecompressPage(t *testing.T) {
+	// A black and white page whose print has a scanner's colour fringe
+	page := imaging.New(200, 100, color.White)
+	draw.Draw(page, image.Rect(20, 20, 180, 40), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	page.Set(20, 41, color.RGBA{R: 90, G: 60, B: 40, A: 255})
+
+	// It has no colour, so it comes back grey and at most the width asked for
+	if isColour(page) {
+		t.Error("Expected the fringe not to count as colour")
+	}
+	recompressed := recompressPage(page, 100)
+	if _, ok := recompressed.(*image.Gray); !ok || recompressed.Bounds().Dx() != 100 || recompressed.Bounds().Dy() != 50 {
+		t.Errorf("Expected a 100x50 grey page, got %T %v", recompressed, recompressed.Bounds())
+	}
+
+	// A page with a coloured logo stays in colour
+	draw.Draw(page, image.Rect(150, 60, 190, 90), image.NewUniform(color.RGBA{R: 200, A: 255}), image.Point{}, draw.Src)
+	if _, ok := recompressPage(page, 0).(*image.Gray); ok {
+		t.Error("Expected the coloured page to stay in colour")
+	}
+}
+
+// saveScannedDocument stores the file at path as a document whose hash is of its content
+func saveScannedDocument(t *testing.T, handler *ServerHandler, path string) (*database.Document, database.FileHashes) {
+	t.Helper()
+	hashes, err := database.HashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := saveTestDocument(t, handler.DB, path, "")
+	if err := handler.DB.UpdateDocumentHash(doc.ULID.String(), hashes.Current()); err != nil {
+		t.Fatal(err)
+	}
+	doc.Hash = hashes.Current()
+	return doc, hashes
+}
+
+// runOptimiseJob runs the storage optimisation job to the end and returns its result
+func runOptimiseJob(t *testing.T, handler *ServerHandler) optimiseResult {
+	t.Helper()
+	job, err := handler.DB.CreateJob(database.JobTypeOptimise, "test")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	handler.optimiseJob(handler.DB, job.ID)
+	finished, err := handler.DB.GetJob(job.ID)
+	if err != nil || finished.Status != database.JobStatusCompleted {
+		t.Fatalf("Expected the job to complete, got %+v: %v", finished, err)
+	}
+	var result optimiseResult
+	if err := json.Unmarshal([]byte(finished.Result), &result); err != nil {
+		t.Fatalf("Unreadable job result %q: %v", finished.Result, err)
+	}
+	return result
+}
+
+func TestOptimiseDocumentsRecompressesScans(t *testing.T) {
+	// A large colour JPEG of a grey page, with originals kept and scans downsampled to 100 DPI
+	handler := setupTestHandler(t)
+	handler.ServerConfig.TempPath = t.TempDir()
+	handler.ServerConfig.OriginalsPath = t.TempDir()
+	handler.ServerConfig.OptimiseDPI = 100
+	handler.ServerConfig.OptimiseJPEGQuality = 60
+	handler.ServerConfig.OptimiseMinSaving = 10
+	scan := imaging.New(2000, 2800, color.White)
+	noise := rand.New(rand.NewSource(1))
+	for y := 200; y < 2600; y += 3 {
+		for x := 200; x < 1800; x++ {
+			if noise.Intn(4) == 0 {
+				scan.Set(x, y, color.Gray{Y: uint8(noise.Intn(128))})
+			}
+		}
+	}
+	path := filepath.Join(handler.ServerConfig.DocumentPath, "letters", "scan.jpg")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := imaging.Save(scan, path, imaging.JPEGQuality(100)); err != nil {
+		t.Fatal(err)
+	}
+	doc, original := saveScannedDocument(t, handler, path)
+	before, _ := os.Stat(path)
+
+	// The optimisation job runs
+	result := runOptimiseJob(t, handler)
+
+	// The scan was stored smaller, grey, and no more than 1400 pixels along its long side
+	if result.Optimised != 1 || result.BytesSaved <= 0 || result.BytesBefore != before.Size() {
+		t.Fatalf("Expected one document recompressed with bytes saved, got %+v", result)
+	}
+	stored, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stored.Close()
+	config, format, err := image.DecodeConfig(stored)
+	if err != nil || format != "jpeg" || config.ColorModel != color.GrayModel || config.Height != 1400 {
+		t.Errorf("Expected a 1400 pixel high grey JPEG, got %s %+v: %v", format, config, err)
+	}
+
+	// Its hash and size are of the new file, and the original is kept under the originals folder
+	updated, err := handler.DB.GetDocumentByULID(doc.ULID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashes, err := database.HashFile(path); err != nil || !hashes.Matches(updated.Hash) || updated.Size != result.BytesAfter {
+		t.Errorf("Expected the stored hash and size of the recompressed file, got %+v: %v", updated, err)
+	}
+	kept := filepath.Join(handler.ServerConfig.OriginalsPath, "letters", "scan.jpg")
+	if hashes, err := database.HashFile(kept); err != nil || !hashes.Matches(original.Current()) {
+		t.Errorf("Expected the original kept at %s: %v", kept, err)
+	}
+
+	// A copy of the original is still found as a duplicate
+	if found, err := database.FindDocumentByHashes(handler.DB, original); err != nil || found == nil || found.ULID != doc.ULID {
+		t.Errorf("Expected the original to match the recompressed document, got %v: %v", found, err)
+	}
+
+	// Running again leaves the recompressed scan alone
+	if again := runOptimiseJob(t, handler); again.Optimised != 0 || again.Skipped != 1 {
+		t.Errorf("Expected the document skipped the second time, got %+v", again)
+	}
+}
+
+func TestOptimiseDocumentsKeepsWhatItCannotShrink(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping PDFium rendering test in short mode")
+	}
+	// A scanned PDF drawn with vector shapes, smaller than any page image of it, and a document
+	// that is not a scan
+	handler := setupTestHandler(t)
+	handler.ServerConfig.TempPath = t.TempDir()
+	handler.ServerConfig.OptimiseMinSaving = 10
+	path := filepath.Join(handler.ServerConfig.DocumentPath, "form.pdf")
+	writeDuplexScan(t, path, true)
+	doc, original := saveScannedDocument(t, handler, path)
+	notes := filepath.Join(handler.ServerConfig.DocumentPath, "notes.txt")
+	if err := os.WriteFile(notes, []byte("Meter reading"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	saveScannedDocument(t, handler, notes)
+
+	// The optimisation job runs
+	result := runOptimiseJob(t, handler)
+
+	// Neither was changed
+	if result.Optimised != 0 || result.Skipped != 2 || result.Failed != 0 {
+		t.Errorf("Expected both documents skipped, got %+v", result)
+	}
+	if hashes, err := database.HashFile(path); err != nil || !hashes.Matches(original.Current()) {
+		t.Errorf("Expected the PDF left as it was: %v", err)
+	}
+	if _, err := handler.DB.GetDocumentOptimisation(doc.ULID.String()); err == nil {
+		t.Error("Expected no optimisation recorded")
+	}
+}
@@ -0,0 +1,235 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// otlpTracesPath is where an OTLP/HTTP collector takes traces when OTLP_ENDPOINT gives only its address
+const otlpTracesPath = "/v1/traces"
+
+// otlpExporter sends spans to an OpenTelemetry collector over OTLP/HTTP, in the protocol's JSON encoding,
+// which every collector and tracing backend that speaks OTLP/HTTP accepts
+type otlpExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+// newOTLPExporter returns an exporter for endpoint, a collector's address such as http://collector:4318
+// or the full URL of its traces endpoint
+func newOTLPExporter(endpoint string) (*otlpExporter, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("expected an http or https URL, got %q", endpoint)
+	}
+	if parsed.Path == "" || parsed.Path == "/" {
+		parsed.Path = otlpTracesPath
+	}
+	return &otlpExporter{endpoint: parsed.String(), client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// The OTLP JSON encoding of a batch of spans. IDs are hex, 64-bit integers are strings and enums are numbers.
+type (
+	otlpTraces struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Events            []otlpEvent    `json:"events,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpEvent struct {
+		TimeUnixNano string         `json:"timeUnixNano"`
+		Name         string         `json:"name"`
+		Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+// OTLP status codes, which number ok and error the other way round from the Go API's codes
+const (
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+// otlpValue encodes an attribute value as an OTLP AnyValue
+func otlpValue(value attribute.Value) map[string]any {
+	array := func(values []map[string]any) map[string]any {
+		return map[string]any{"arrayValue": map[string]any{"values": values}}
+	}
+	switch value.Type() {
+	case attribute.BOOL:
+		return map[string]any{"boolValue": value.AsBool()}
+	case attribute.INT64:
+		return map[string]any{"intValue": strconv.FormatInt(value.AsInt64(), 10)}
+	case attribute.FLOAT64:
+		return map[string]any{"doubleValue": value.AsFloat64()}
+	case attribute.BOOLSLICE:
+		var values []map[string]any
+		for _, v := range value.AsBoolSlice() {
+			values = append(values, otlpValue(attribute.BoolValue(v)))
+		}
+		return array(values)
+	case attribute.INT64SLICE:
+		var values []map[string]any
+		for _, v := range value.AsInt64Slice() {
+			values = append(values, otlpValue(attribute.Int64Value(v)))
+		}
+		return array(values)
+	case attribute.FLOAT64SLICE:
+		var values []map[string]any
+		for _, v := range value.AsFloat64Slice() {
+			values = append(values, otlpValue(attribute.Float64Value(v)))
+		}
+		return array(values)
+	case attribute.STRINGSLICE:
+		var values []map[string]any
+		for _, v := range value.AsStringSlice() {
+			values = append(values, otlpValue(attribute.StringValue(v)))
+		}
+		return array(values)
+	default:
+		return map[string]any{"stringValue": value.Emit()}
+	}
+}
+
+// otlpAttributes encodes a list of attributes
+func otlpAttributes(attributes []attribute.KeyValue) []otlpKeyValue {
+	encoded := make([]otlpKeyValue, 0, len(attributes))
+	for _, kv := range attributes {
+		encoded = append(encoded, otlpKeyValue{Key: string(kv.Key), Value: otlpValue(kv.Value)})
+	}
+	return encoded
+}
+
+// otlpTime encodes a time as nanoseconds since the epoch
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// otlpEncode encodes spans, grouped by resource and then by the tracer that made them
+func otlpEncode(spans []sdktrace.ReadOnlySpan) otlpTraces {
+	var traces otlpTraces
+	resources := make(map[string]int)
+	scopes := make(map[[2]string]int)
+	for _, span := range spans {
+		resourceKey := span.Resource().Encoded(attribute.DefaultEncoder())
+		r, ok := resources[resourceKey]
+		if !ok {
+			r = len(traces.ResourceSpans)
+			resources[resourceKey] = r
+			traces.ResourceSpans = append(traces.ResourceSpans, otlpResourceSpans{
+				Resource: otlpResource{Attributes: otlpAttributes(span.Resource().Attributes())},
+			})
+		}
+		scope := span.InstrumentationScope()
+		scopeKey := [2]string{resourceKey, scope.Name}
+		s, ok := scopes[scopeKey]
+		if !ok {
+			s = len(traces.ResourceSpans[r].ScopeSpans)
+			scopes[scopeKey] = s
+			traces.ResourceSpans[r].ScopeSpans = append(traces.ResourceSpans[r].ScopeSpans, otlpScopeSpans{
+				Scope: otlpScope{Name: scope.Name, Version: scope.Version},
+			})
+		}
+
+		encoded := otlpSpan{
+			TraceID:           span.SpanContext().TraceID().String(),
+			SpanID:            span.SpanContext().SpanID().String(),
+			Name:              span.Name(),
+			Kind:              int(span.SpanKind()), // the Go API numbers kinds as OTLP does
+			StartTimeUnixNano: otlpTime(span.StartTime()),
+			EndTimeUnixNano:   otlpTime(span.EndTime()),
+			Attributes:        otlpAttributes(span.Attributes()),
+		}
+		if span.Parent().IsValid() {
+			encoded.ParentSpanID = span.Parent().SpanID().String()
+		}
+		for _, event := range span.Events() {
+			encoded.Events = append(encoded.Events, otlpEvent{
+				TimeUnixNano: otlpTime(event.Time),
+				Name:         event.Name,
+				Attributes:   otlpAttributes(event.Attributes),
+			})
+		}
+		switch span.Status().Code {
+		case codes.Ok:
+			encoded.Status = otlpStatus{Code: otlpStatusOK}
+		case codes.Error:
+			encoded.Status = otlpStatus{Code: otlpStatusError, Message: span.Status().Description}
+		}
+		traces.ResourceSpans[r].ScopeSpans[s].Spans = append(traces.ResourceSpans[r].ScopeSpans[s].Spans, encoded)
+	}
+	return traces
+}
+
+// ExportSpans sends a batch of finished spans to the collector
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(otlpEncode(spans))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := e.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("collector answered %s: %s", response.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// Shutdown has nothing to release; the tracer provider flushes its batch before calling it
+func (e *otlpExporter) Shutdown(context.Context) error {
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build nopdfium
+
+package pdfrenderer
+
+// LocalRendering reports whether this build can render PDFs without the PDF service. Builds with
+// -tags nopdfium leave out PDFium and its WebAssembly runtime, for small ARM and NAS binaries that
+// hand all rendering to the PDF sidecar.
+const LocalRendering = false
+
+// newLocalRenderer always fails in nopdfium builds
+func newLocalRenderer() (Renderer, error) {
+	return nil, ErrNoLocalRenderer
+}
+
+// RewritePDF always fails in nopdfium builds, as the PDF service cannot rewrite PDFs
+func RewritePDF(pdfBytes []byte, rotate map[int]int, remove []int) ([]byte, error) {
+	return nil, ErrNoLocalRenderer
+}
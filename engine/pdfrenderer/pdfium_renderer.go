@@ -1,16 +1,28 @@
+//go:build !nopdfium
+
 package pdfrenderer
 
 import (
 	"fmt"
 	"image"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/enums"
 	"github.com/klippa-app/go-pdfium/requests"
 	"github.com/klippa-app/go-pdfium/webassembly"
 )
 
+// LocalRendering reports whether this build can render PDFs without the PDF service
+const LocalRendering = true
+
+// newLocalRenderer returns the built-in renderer
+func newLocalRenderer() (Renderer, error) {
+	return NewPDFiumRenderer()
+}
+
 // PDFiumRenderer implements PDF rendering using go-pdfium with WebAssembly (pure Go, no CGo)
 type PDFiumRenderer struct {
 	pool     pdfium.Pool
@@ -45,10 +57,23 @@ func NewPDFiumRenderer() (*PDFiumRenderer, error) {
 
 // RenderPDF converts all pages of a PDF file to images using go-pdfium WebAssembly
 func (r *PDFiumRenderer) RenderPDF(filename string) ([]image.Image, error) {
+	var images []image.Image
+	_, err := r.RenderPages(filename, 0, func(pageIndex int, page image.Image) error {
+		images = append(images, page)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// RenderPages renders up to maxPages pages (0 for all) one at a time, handing each image to fn
+func (r *PDFiumRenderer) RenderPages(filename string, maxPages int, fn func(pageIndex int, page image.Image) error) (int, error) {
 	// Read the PDF file
 	pdfBytes, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("unable to read PDF file: %w", err)
+		return 0, fmt.Errorf("unable to read PDF file: %w", err)
 	}
 
 	// Open the PDF document
@@ -56,7 +81,7 @@ func (r *PDFiumRenderer) RenderPDF(filename string) ([]image.Image, error) {
 		File: &pdfBytes,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("unable to open PDF document: %w", err)
+		return 0, fmt.Errorf("unable to open PDF document: %w", err)
 	}
 	defer r.instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
 		Document: doc.Document,
@@ -67,14 +92,17 @@ func (r *PDFiumRenderer) RenderPDF(filename string) ([]image.Image, error) {
 		Document: doc.Document,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("unable to get page count: %w", err)
+		return 0, fmt.Errorf("unable to get page count: %w", err)
 	}
 
 	numPages := pageCountResp.PageCount
-	images := make([]image.Image, 0, numPages)
+	renderPages := numPages
+	if maxPages > 0 && maxPages < renderPages {
+		renderPages = maxPages
+	}
 
 	// Render each page at 150 DPI (optimized for OCR quality)
-	for pageIndex := 0; pageIndex < numPages; pageIndex++ {
+	for pageIndex := 0; pageIndex < renderPages; pageIndex++ {
 		pageRender, err := r.instance.RenderPageInDPI(&requests.RenderPageInDPI{
 			DPI: 150, // Match the DPI mentioned in original convertToImage function
 			Page: requests.Page{
@@ -85,17 +113,79 @@ func (r *PDFiumRenderer) RenderPDF(filename string) ([]image.Image, error) {
 			},
 		})
 		if err != nil {
-			return nil, fmt.Errorf("unable to render page %d: %w", pageIndex, err)
+			return numPages, fmt.Errorf("unable to render page %d: %w", pageIndex, err)
 		}
 
-		// Extract the image from the result
-		images = append(images, pageRender.Result.Image)
+		err = fn(pageIndex, pageRender.Result.Image)
 
 		// Clean up WebAssembly resources for this page
 		pageRender.Cleanup()
+		if err != nil {
+			return numPages, err
+		}
 	}
 
-	return images, nil
+	return numPages, nil
+}
+
+// RewritePages deletes the pages at the remove indexes and turns others clockwise, on top of any rotation
+// they already have, and returns the rewritten PDF. rotate maps a page index to 90, 180 or 270. Indexes
+// are of the pages as they were before any were removed.
+func (r *PDFiumRenderer) RewritePages(pdfBytes []byte, rotate map[int]int, remove []int) ([]byte, error) {
+	doc, err := r.instance.OpenDocument(&requests.OpenDocument{
+		File: &pdfBytes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open PDF document: %w", err)
+	}
+	defer r.instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
+		Document: doc.Document,
+	})
+
+	// Pages are removed before any is loaded to be turned, last first so the indexes still hold
+	removed := slices.Sorted(slices.Values(remove))
+	for i := len(removed) - 1; i >= 0; i-- {
+		if _, err := r.instance.FPDFPage_Delete(&requests.FPDFPage_Delete{Document: doc.Document, PageIndex: removed[i]}); err != nil {
+			return nil, fmt.Errorf("unable to remove page %d: %w", removed[i], err)
+		}
+	}
+
+	for pageIndex, turn := range rotate {
+		if slices.Contains(removed, pageIndex) {
+			continue
+		}
+		shift, _ := slices.BinarySearch(removed, pageIndex) // pages removed before this one
+		page := requests.Page{ByIndex: &requests.PageByIndex{Document: doc.Document, Index: pageIndex - shift}}
+		current, err := r.instance.FPDFPage_GetRotation(&requests.FPDFPage_GetRotation{Page: page})
+		if err != nil {
+			return nil, fmt.Errorf("unable to get rotation of page %d: %w", pageIndex, err)
+		}
+		// The page rotation counts quarter turns clockwise
+		rotation := enums.FPDF_PAGE_ROTATION((int(current.PageRotation) + turn/90) % 4)
+		if _, err := r.instance.FPDFPage_SetRotation(&requests.FPDFPage_SetRotation{Page: page, Rotate: rotation}); err != nil {
+			return nil, fmt.Errorf("unable to rotate page %d: %w", pageIndex, err)
+		}
+	}
+
+	saved, err := r.instance.FPDF_SaveAsCopy(&requests.FPDF_SaveAsCopy{
+		Document: doc.Document,
+		Flags:    requests.SaveFlagNoIncremental,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to save rewritten PDF: %w", err)
+	}
+	return *saved.FileBytes, nil
+}
+
+// RewritePDF removes and turns pages of a PDF with the built-in PDFium; see PDFiumRenderer.RewritePages.
+// The PDF service only renders, so rewriting always happens in process.
+func RewritePDF(pdfBytes []byte, rotate map[int]int, remove []int) ([]byte, error) {
+	renderer, err := NewPDFiumRenderer()
+	if err != nil {
+		return nil, err
+	}
+	defer renderer.Close()
+	return renderer.RewritePages(pdfBytes, rotate, remove)
 }
 
 // Close cleans up resources used by the PDFium renderer
@@ -1,6 +1,7 @@
 package pdfrenderer
 
 import (
+	"errors"
 	"image"
 )
 
@@ -10,11 +11,22 @@ type Renderer interface {
 	// Returns a slice of images, one per page
 	RenderPDF(filename string) ([]image.Image, error)
 
+	// RenderPages renders pages one at a time and passes each to fn, so only one page is held in memory.
+	// At most maxPages pages are rendered (0 means all). It returns the number of pages in the document.
+	RenderPages(filename string, maxPages int, fn func(pageIndex int, page image.Image) error) (int, error)
+
 	// Close cleans up any resources used by the renderer
 	Close() error
 }
 
-// NewRenderer creates a new PDFium-based PDF renderer (pure Go, no CGo)
-func NewRenderer() (Renderer, error) {
-	return NewPDFiumRenderer()
+// ErrNoLocalRenderer is returned when the binary was built with -tags nopdfium and no PDF service is configured
+var ErrNoLocalRenderer = errors.New("built without the PDFium renderer (-tags nopdfium): set PDF_SERVICE_URL to render PDFs")
+
+// NewRenderer returns a renderer that delegates to the PDF sidecar service at serviceURL when it is set,
+// otherwise the built-in PDFium renderer
+func NewRenderer(serviceURL string) (Renderer, error) {
+	if serviceURL != "" {
+		return NewServiceRenderer(serviceURL)
+	}
+	return newLocalRenderer()
 }
@@ -0,0 +1,123 @@
+package pdfrenderer
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg" // the service may answer with JPEG pages
+	_ "image/png"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serviceDPI matches the resolution the PDFium renderer uses for OCR
+const serviceDPI = 150
+
+// ServiceRenderer renders PDFs with the PDF sidecar service instead of in process.
+//
+// It POSTs the PDF to <service>/render?dpi=150&maxPages=N and expects a multipart/mixed reply with one
+// image part (PNG or JPEG) per rendered page, in page order, and the document's total page count in the
+// X-Page-Count header. Parts are decoded as they arrive so only one page is held in memory.
+type ServiceRenderer struct {
+	renderURL string
+	client    *http.Client
+}
+
+// NewServiceRenderer creates a renderer for the PDF service at address, e.g. "pdf-service:3000" or
+// "http://pdf-service:3000"
+func NewServiceRenderer(address string) (*ServiceRenderer, error) {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	base, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PDF service address %q: %w", address, err)
+	}
+	if base.Host == "" {
+		return nil, fmt.Errorf("no host in PDF service address %q", address)
+	}
+	base.Path = strings.TrimSuffix(base.Path, "/") + "/render"
+	return &ServiceRenderer{
+		renderURL: base.String(),
+		client:    &http.Client{Timeout: 10 * time.Minute}, // large scans take a while to render
+	}, nil
+}
+
+// RenderPDF converts all pages of a PDF file to images using the PDF service
+func (r *ServiceRenderer) RenderPDF(filename string) ([]image.Image, error) {
+	var images []image.Image
+	_, err := r.RenderPages(filename, 0, func(pageIndex int, page image.Image) error {
+		images = append(images, page)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// RenderPages sends the PDF to the service and hands each page image to fn as it is received
+func (r *ServiceRenderer) RenderPages(filename string, maxPages int, fn func(pageIndex int, page image.Image) error) (int, error) {
+	pdfFile, err := os.Open(filename)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read PDF file: %w", err)
+	}
+	defer pdfFile.Close()
+
+	query := url.Values{"dpi": {strconv.Itoa(serviceDPI)}}
+	if maxPages > 0 {
+		query.Set("maxPages", strconv.Itoa(maxPages))
+	}
+	req, err := http.NewRequest(http.MethodPost, r.renderURL+"?"+query.Encode(), pdfFile)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/pdf")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("PDF service request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, fmt.Errorf("PDF service returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return 0, fmt.Errorf("PDF service returned %q, expected multipart page images", resp.Header.Get("Content-Type"))
+	}
+	pageCount, _ := strconv.Atoi(resp.Header.Get("X-Page-Count"))
+
+	reader := multipart.NewReader(resp.Body, params["boundary"])
+	pageIndex := 0
+	for ; maxPages == 0 || pageIndex < maxPages; pageIndex++ {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return max(pageCount, pageIndex), fmt.Errorf("unable to read page %d from PDF service: %w", pageIndex, err)
+		}
+		page, _, err := image.Decode(part)
+		part.Close()
+		if err != nil {
+			return max(pageCount, pageIndex), fmt.Errorf("unable to decode page %d from PDF service: %w", pageIndex, err)
+		}
+		if err := fn(pageIndex, page); err != nil {
+			return max(pageCount, pageIndex), err
+		}
+	}
+	return max(pageCount, pageIndex), nil
+}
+
+// Close has nothing to release; each render is a single request
+func (r *ServiceRenderer) Close() error {
+	return nil
+}
@@ -0,0 +1,85 @@
+package pdfrenderer
+
+import (
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakePDFService answers /render with pages of increasing width, recording the request
+func fakePDFService(t *testing.T, pages int, got *http.Request, gotBody *string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*got, *gotBody = *r, string(body)
+		if r.URL.Path != "/render" {
+			http.NotFound(w, r)
+			return
+		}
+		writer := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+writer.Boundary())
+		w.Header().Set("X-Page-Count", "5")
+		for i := 0; i < pages; i++ {
+			part, _ := writer.CreatePart(map[string][]string{"Content-Type": {"image/png"}})
+			png.Encode(part, image.NewGray(image.Rect(0, 0, 10+i, 20)))
+		}
+		writer.Close()
+	}))
+}
+
+func TestServiceRendererStreamsPages(t *testing.T) {
+	// A PDF service that renders the first three of five pages
+	var got http.Request
+	var gotBody string
+	service := fakePDFService(t, 3, &got, &gotBody)
+	defer service.Close()
+	pdf := filepath.Join(t.TempDir(), "scan.pdf")
+	os.WriteFile(pdf, []byte("%PDF-1.4 test"), 0644)
+
+	// Rendering with a cap of three pages, addressed without a scheme like a compose service name
+	renderer, err := NewRenderer(strings.TrimPrefix(service.URL, "http://"))
+	if err != nil {
+		t.Fatalf("NewRenderer failed: %v", err)
+	}
+	var widths []int
+	pageCount, err := renderer.RenderPages(pdf, 3, func(pageIndex int, page image.Image) error {
+		widths = append(widths, page.Bounds().Dx())
+		return nil
+	})
+
+	// The PDF is posted with the DPI and cap, and each page arrives in order
+	if err != nil {
+		t.Fatalf("RenderPages failed: %v", err)
+	}
+	if gotBody != "%PDF-1.4 test" || got.URL.Query().Get("maxPages") != "3" || got.URL.Query().Get("dpi") != "150" {
+		t.Errorf("Unexpected request %s body=%q", got.URL, gotBody)
+	}
+	if pageCount != 5 || len(widths) != 3 || widths[0] != 10 || widths[2] != 12 {
+		t.Errorf("Expected 5 pages with 3 rendered in order, got count=%d widths=%v", pageCount, widths)
+	}
+}
+
+func TestServiceRendererReportsServiceErrors(t *testing.T) {
+	// A PDF service that rejects the document
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "encrypted PDF", http.StatusUnprocessableEntity)
+	}))
+	defer service.Close()
+	pdf := filepath.Join(t.TempDir(), "locked.pdf")
+	os.WriteFile(pdf, []byte("%PDF"), 0644)
+	renderer, _ := NewServiceRenderer(service.URL)
+
+	_, err := renderer.RenderPDF(pdf)
+
+	// The service's reason is passed on
+	if err == nil || !strings.Contains(err.Error(), "encrypted PDF") {
+		t.Errorf("Expected the service error, got %v", err)
+	}
+}
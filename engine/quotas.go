@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// errQuotaExceeded is returned when storing a file would take a folder over its FOLDER_QUOTAS limit
+var errQuotaExceeded = errors.New("storage quota exceeded")
+
+// quotaUsage is how much of one folder quota is in use
+type quotaUsage struct {
+	Folder     string  `json:"folder"` // relative to the document root, "" for the whole root
+	LimitBytes int64   `json:"limitBytes"`
+	UsedBytes  int64   `json:"usedBytes"`
+	Percent    float64 `json:"percent"`
+	Warning    int     `json:"warning,omitempty"` // highest QUOTA_WARN_PERCENT reached
+	Exceeded   bool    `json:"exceeded"`
+}
+
+// quotaWarnings remembers the highest warning sent for each folder, so each threshold is notified once
+// as a folder fills up and again only after it has dropped below it
+type quotaWarnings struct {
+	mu     sync.Mutex
+	warned map[string]int
+}
+
+// raise records level as the folder's current warning and reports whether it is higher than the last one sent
+func (w *quotaWarnings) raise(folder string, level int) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.warned == nil {
+		w.warned = make(map[string]int)
+	}
+	previous := w.warned[folder]
+	w.warned[folder] = level
+	return level > previous
+}
+
+// quotaFolders returns the quota folders that contain destPath, outermost first. Quotas are keyed by folder
+// for now; a tenant quota would be one more source of limits here.
+func (serverHandler *ServerHandler) quotaFolders(destPath string) []string {
+	root := folderKey(serverHandler.ServerConfig.DocumentPath)
+	target := folderKey(filepath.Dir(destPath))
+	var folders []string
+	for folder := range serverHandler.ServerConfig.FolderQuotas {
+		if insideRoot(path.Join(root, folder), target) {
+			folders = append(folders, folder)
+		}
+	}
+	sort.Strings(folders)
+	return folders
+}
+
+// folderUsage adds up the size of every file under a quota folder
+func (serverHandler *ServerHandler) folderUsage(folder string) (int64, error) {
+	var used int64
+	root := filepath.Join(serverHandler.ServerConfig.DocumentPath, filepath.FromSlash(folder))
+	err := filepath.WalkDir(root, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			used += info.Size()
+		}
+		return nil
+	})
+	return used, err
+}
+
+// usage describes one quota folder with used bytes in it
+func (serverHandler *ServerHandler) usage(folder string, used int64) quotaUsage {
+	limit := serverHandler.ServerConfig.FolderQuotas[folder]
+	usage := quotaUsage{Folder: folder, LimitBytes: limit, UsedBytes: used, Exceeded: used > limit}
+	if limit > 0 {
+		usage.Percent = float64(used) * 100 / float64(limit)
+	}
+	for _, percent := range serverHandler.ServerConfig.QuotaWarnPercents {
+		if usage.Percent >= float64(percent) {
+			usage.Warning = percent
+		}
+	}
+	return usage
+}
+
+// checkQuota returns errQuotaExceeded when adding size bytes at destPath would take a folder over its quota
+func (serverHandler *ServerHandler) checkQuota(destPath string, size int64) error {
+	for _, folder := range serverHandler.quotaFolders(destPath) {
+		used, err := serverHandler.folderUsage(folder)
+		if err != nil {
+			return fmt.Errorf("unable to measure quota folder %q: %w", folder, err)
+		}
+		if limit := serverHandler.ServerConfig.FolderQuotas[folder]; used+size > limit {
+			return fmt.Errorf("%w: folder %q has %s of %s left, the file needs %s", errQuotaExceeded,
+				"/"+folder, formatFileSize(max(limit-used, 0)), formatFileSize(limit), formatFileSize(size))
+		}
+	}
+	return nil
+}
+
+// noteQuotaUsage warns when storing a file at destPath has taken a folder past a QUOTA_WARN_PERCENT threshold
+func (serverHandler *ServerHandler) noteQuotaUsage(destPath string) {
+	for _, folder := range serverHandler.quotaFolders(destPath) {
+		used, err := serverHandler.folderUsage(folder)
+		if err != nil {
+			Logger.Warn("Unable to measure quota folder", "folder", folder, "error", err)
+			continue
+		}
+		usage := serverHandler.usage(folder, used)
+		if !serverHandler.quotaWarnings.raise(folder, usage.Warning) {
+			continue
+		}
+		serverHandler.notify("godocs storage quota",
+			fmt.Sprintf("Folder /%s is %.0f%% full: %s of %s used", folder, usage.Percent, formatFileSize(used), formatFileSize(usage.LimitBytes)))
+	}
+}
+
+// GetQuota reports storage use against every folder quota
+// @Summary Get storage quota usage
+// @Description Used and allowed bytes for each folder in FOLDER_QUOTAS, with the highest QUOTA_WARN_PERCENT threshold reached.
+// @Description Uploads and ingested files that would take a folder over its quota are refused.
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "quotas and warnPercents"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /quota [get]
+func (serverHandler *ServerHandler) GetQuota(c echo.Context) error {
+	folders := make([]string, 0, len(serverHandler.ServerConfig.FolderQuotas))
+	for folder := range serverHandler.ServerConfig.FolderQuotas {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+
+	quotas := make([]quotaUsage, 0, len(folders))
+	for _, folder := range folders {
+		used, err := serverHandler.folderUsage(folder)
+		if err != nil {
+			Logger.Error("Failed to measure quota folder", "folder", folder, "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to measure folder usage",
+				"code":  dto.CodeInternal,
+			})
+		}
+		quotas = append(quotas, serverHandler.usage(folder, used))
+	}
+	warnPercents := serverHandler.ServerConfig.QuotaWarnPercents
+	if warnPercents == nil {
+		warnPercents = []int{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"quotas":       quotas,
+		"warnPercents": warnPercents,
+	})
+}
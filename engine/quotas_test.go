@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFolderQuotas(t *testing.T) {
+	// A 100 byte quota on the tax folder, warning at half full, with notifications pushed to a test server
+	pushes := make(chan string, 4)
+	pushServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		pushes <- r.Header.Get("Access-Token") + " " + string(body)
+	}))
+	defer pushServer.Close()
+	previousURL := pushBulletURL
+	pushBulletURL = pushServer.URL
+	defer func() { pushBulletURL = previousURL }()
+
+	handler := setupTestHandler(t)
+	handler.ServerConfig.FolderQuotas = map[string]int64{"tax": 100}
+	handler.ServerConfig.QuotaWarnPercents = []int{50, 90}
+	handler.ServerConfig.PushBulletToken = "token"
+	upload := func(fileName, content, folder string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := uploadRequest(t, fileName, content, map[string]string{"folder": folder})
+		if err := handler.UploadDocuments(handler.Echo.NewContext(req, rec)); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		return rec
+	}
+
+	// 60 bytes are uploaded into a subfolder of tax
+	if rec := upload("p60.txt", strings.Repeat("a", 60), "tax/2024"); rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// One warning is pushed for passing 50%
+	select {
+	case push := <-pushes:
+		if !strings.HasPrefix(push, "token ") || !strings.Contains(push, "Folder /tax is 60% full") {
+			t.Errorf("Unexpected notification %q", push)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No quota notification was sent")
+	}
+
+	// A further 50 bytes are refused, while other folders are not limited
+	if rec := upload("p11d.txt", strings.Repeat("b", 50), "tax"); rec.Code != http.StatusInsufficientStorage {
+		t.Errorf("Expected 507 over quota, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(handler.ServerConfig.DocumentPath, "tax", "p11d.txt")); !os.IsNotExist(err) {
+		t.Error("A refused upload should not be stored")
+	}
+	if rec := upload("manual.txt", strings.Repeat("c", 500), "manuals"); rec.Code != http.StatusOK {
+		t.Errorf("Expected folders without a quota to accept uploads, got %d", rec.Code)
+	}
+	if err := handler.checkQuota(filepath.Join(handler.ServerConfig.DocumentPath, "tax", "x.pdf"), 41); !errors.Is(err, errQuotaExceeded) {
+		t.Errorf("Expected errQuotaExceeded, got %v", err)
+	}
+
+	// The usage endpoint reports the tax folder
+	rec := httptest.NewRecorder()
+	if err := handler.GetQuota(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/quota", nil), rec)); err != nil {
+		t.Fatalf("GetQuota failed: %v", err)
+	}
+	var response struct {
+		Quotas       []quotaUsage `json:"quotas"`
+		WarnPercents []int        `json:"warnPercents"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	want := quotaUsage{Folder: "tax", LimitBytes: 100, UsedBytes: 60, Percent: 60, Warning: 50}
+	if len(response.Quotas) != 1 || response.Quotas[0] != want || len(response.WarnPercents) != 2 {
+		t.Errorf("Unexpected quota usage %+v", response)
+	}
+	select {
+	case push := <-pushes:
+		t.Errorf("The 50%% warning should only be sent once, got %q", push)
+	default:
+	}
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// readOnlyPath is the endpoint that switches read-only mode, the one change still accepted while it is on
+// besides signing in and out under authPrefix, error reports from the web UI and starting a full export
+const readOnlyPath = "/api/read-only"
+
+// authPrefix is where signing in and out happens, which does not change any documents
+const authPrefix = "/api/auth/"
+
+// clientErrorsPath is where the web UI reports errors, which are wanted most during maintenance
+const clientErrorsPath = "/api/client-errors"
+
+// defaultReadOnlyMessage is given to clients whose changes are refused when no READ_ONLY_MESSAGE is set
+const defaultReadOnlyMessage = "godocs is in read-only mode for maintenance; changes are disabled until it ends"
+
+// readOnlyStateKey is the shared server state holding read-only mode as switched through the API
+const readOnlyStateKey = "read_only"
+
+// readOnlyMode is read-only mode as last switched through the API. It is saved in the database, so a
+// separate worker follows it and it outlasts restarts; until it is first switched READ_ONLY and
+// READ_ONLY_MESSAGE apply.
+type readOnlyMode struct {
+	Enabled bool      `json:"enabled"`
+	Message string    `json:"message"`
+	Since   time.Time `json:"since"`
+}
+
+// savedReadOnlyMode returns read-only mode as last switched through the API, or nil when it never was
+func (serverHandler *ServerHandler) savedReadOnlyMode() (*readOnlyMode, error) {
+	value, err := serverHandler.DB.GetServerState(readOnlyStateKey)
+	if err != nil || value == "" {
+		return nil, err
+	}
+	var mode readOnlyMode
+	if err := json.Unmarshal([]byte(value), &mode); err != nil {
+		return nil, err
+	}
+	return &mode, nil
+}
+
+// readOnlyStatus says whether changes are being refused, and the message given for them
+func (serverHandler *ServerHandler) readOnlyStatus() dto.ReadOnlyStatus {
+	status := dto.ReadOnlyStatus{ReadOnly: serverHandler.ServerConfig.ReadOnly, Message: serverHandler.ServerConfig.ReadOnlyMessage}
+	mode, err := serverHandler.savedReadOnlyMode()
+	if err != nil {
+		Logger.Error("Unable to read read-only mode, using the environment", "error", err)
+	}
+	if mode != nil {
+		status.ReadOnly, status.Message = mode.Enabled, mode.Message
+		if mode.Enabled {
+			status.Since = mode.Since.UTC().Format(time.RFC3339)
+		}
+	}
+	if !status.ReadOnly {
+		status.Message = ""
+	} else if status.Message == "" {
+		status.Message = defaultReadOnlyMessage
+	}
+	return status
+}
+
+// ReadOnlyGuard refuses every API request that could change something with 503 while read-only mode is
+// on. Reads, search and document viewing carry on, as does switching the mode off again.
+func (serverHandler *ServerHandler) ReadOnlyGuard() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			request := c.Request()
+			switch request.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+			if !strings.HasPrefix(request.URL.Path, "/api/") || request.URL.Path == readOnlyPath || request.URL.Path == clientErrorsPath || request.URL.Path == exportArchivePath || strings.HasPrefix(request.URL.Path, authPrefix) {
+				return next(c)
+			}
+			status := serverHandler.readOnlyStatus()
+			if !status.ReadOnly {
+				return next(c)
+			}
+			return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+				"error": status.Message,
+				"code":  dto.CodeReadOnly,
+			})
+		}
+	}
+}
+
+// unlessReadOnly wraps a scheduled job that changes documents so it is skipped while read-only mode is on.
+// The mode is looked up before every run, so a separate worker stops as soon as the API server switches it.
+func (serverHandler *ServerHandler) unlessReadOnly(name string, run func()) func() {
+	return func() {
+		if serverHandler.readOnlyStatus().ReadOnly {
+			Logger.Info("Skipping scheduled job in read-only mode", "job", name)
+			return
+		}
+		run()
+	}
+}
+
+// GetReadOnly returns whether the server is in read-only mode
+// @Summary Read-only mode
+// @Description Whether changes are being refused for maintenance, with the message given for them.
+// @Tags System
+// @Produce json
+// @Success 200 {object} dto.ReadOnlyStatus "Read-only mode"
+// @Router /read-only [get]
+func (serverHandler *ServerHandler) GetReadOnly(c echo.Context) error {
+	return c.JSON(http.StatusOK, serverHandler.readOnlyStatus())
+}
+
+// readOnlyRequest switches read-only mode
+type readOnlyRequest struct {
+	ReadOnly *bool  `json:"readOnly"`
+	Message  string `json:"message"`
+}
+
+// SetReadOnly turns read-only mode on or off until it is switched again
+// @Summary Switch read-only mode
+// @Description Turn read-only mode on, with an optional message for users, or off. While it is on every POST, PUT, PATCH and DELETE under /api except this one answers 503 with GODOCS_READ_ONLY, and scheduled jobs that change documents are skipped. With sign-in or ADMIN_USERS only administrators may switch it. The switch is saved in the database, so a separate worker follows it and it outlasts restarts.
+// @Tags System
+// @Accept json
+// @Produce json
+// @Param request body readOnlyRequest true "readOnly and an optional message"
+// @Success 200 {object} dto.ReadOnlyStatus "Read-only mode"
+// @Failure 400 {object} dto.ErrorResponse "readOnly missing"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 500 {object} dto.ErrorResponse "Unable to save read-only mode"
+// @Router /read-only [put]
+func (serverHandler *ServerHandler) SetReadOnly(c echo.Context) error {
+	if serverHandler.notAdmin(c) {
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": "Only administrators may switch read-only mode",
+			"code":  dto.CodeForbidden,
+		})
+	}
+	var request readOnlyRequest
+	if err := c.Bind(&request); err != nil || request.ReadOnly == nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Expected a JSON body with readOnly true or false",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	mode := readOnlyMode{Enabled: *request.ReadOnly, Message: strings.TrimSpace(request.Message)}
+	if mode.Enabled {
+		mode.Since = database.Now()
+		if saved, err := serverHandler.savedReadOnlyMode(); err == nil && saved != nil && saved.Enabled {
+			mode.Since = saved.Since
+		}
+	}
+	value, err := json.Marshal(mode)
+	if err == nil {
+		err = serverHandler.DB.SetServerState(readOnlyStateKey, string(value))
+	}
+	if err != nil {
+		Logger.Error("Unable to save read-only mode", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Unable to save read-only mode",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	Logger.Info("Read-only mode switched", "readOnly", *request.ReadOnly, "user", requestUser(c))
+	serverHandler.settingsChanged(c, "read-only mode")
+	return c.JSON(http.StatusOK, serverHandler.readOnlyStatus())
+}
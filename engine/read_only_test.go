@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+func TestReadOnlyMode(t *testing.T) {
+	// A server started with READ_ONLY set and alice as its administrator
+	handler := setupTestHandler(t)
+	handler.ServerConfig.ReadOnly = true
+	handler.ServerConfig.AdminUsers = []string{"alice"}
+	handler.Echo.Use(handler.ReadOnlyGuard())
+	handler.Echo.GET("/api/read-only", handler.GetReadOnly)
+	handler.Echo.PUT("/api/read-only", handler.SetReadOnly)
+	handler.Echo.GET("/api/collections", handler.ListCollections)
+	handler.Echo.POST("/api/collections", handler.CreateCollection)
+	serve := func(method, target, user, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if user != "" {
+			req.SetBasicAuth(user, "secret")
+		}
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec, response
+	}
+
+	// A collection is created and collections are listed
+	rec, response := serve(http.MethodPost, "/api/collections", "", `{"name":"Tax"}`)
+	listed, _ := serve(http.MethodGet, "/api/collections", "", "")
+
+	// The change is refused with the maintenance message and the read still works
+	if rec.Code != http.StatusServiceUnavailable || response["code"] != string(dto.CodeReadOnly) || response["error"] != defaultReadOnlyMessage {
+		t.Errorf("Expected 503 read-only, got %d %v", rec.Code, response)
+	}
+	if listed.Code != http.StatusOK {
+		t.Errorf("Expected reads to carry on, got %d", listed.Code)
+	}
+
+	// Bob, who is not an administrator, tries to turn read-only mode off
+	rec, _ = serve(http.MethodPut, "/api/read-only", "bob", `{"readOnly":false}`)
+
+	// They are refused
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for bob, got %d", rec.Code)
+	}
+
+	// Alice turns it off
+	rec, response = serve(http.MethodPut, "/api/read-only", "alice", `{"readOnly":false}`)
+
+	// Changes reach their handlers again
+	if rec.Code != http.StatusOK || response["readOnly"] != false {
+		t.Fatalf("Expected read-only mode off, got %d %v", rec.Code, response)
+	}
+	if rec, response = serve(http.MethodPost, "/api/collections", "", `{"name":"Tax"}`); response["code"] == string(dto.CodeReadOnly) {
+		t.Errorf("Expected the request to be handled, got %d %v", rec.Code, response)
+	}
+
+	// Alice turns it back on with a message
+	serve(http.MethodPut, "/api/read-only", "alice", `{"readOnly":true,"message":"Moving storage, back at 10:00"}`)
+	rec, response = serve(http.MethodGet, "/api/read-only", "", "")
+
+	// The status carries the message and when it began, and scheduled changes are skipped
+	if response["readOnly"] != true || response["message"] != "Moving storage, back at 10:00" || response["since"] == nil {
+		t.Errorf("Expected read-only with the message, got %v", response)
+	}
+	ran := false
+	handler.unlessReadOnly("ingest", func() { ran = true })()
+	if ran {
+		t.Error("Expected the scheduled job to be skipped")
+	}
+
+	// A request without readOnly is refused
+	if rec, _ := serve(http.MethodPut, "/api/read-only", "alice", `{"message":"?"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without readOnly, got %d", rec.Code)
+	}
+}
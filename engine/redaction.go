@@ -0,0 +1,490 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/engine/pdfrenderer"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/labstack/echo/v4"
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	// maxRedactionRegions is the most rectangles one redaction may black out
+	maxRedactionRegions = 500
+	// redactedPageQuality is the JPEG quality each redacted page image is stored at
+	redactedPageQuality = 90
+)
+
+// redactRequest is the body of RedactDocument
+type redactRequest struct {
+	Regions []database.RedactionRegion `json:"regions"`
+}
+
+// pageGeometry is the size of a PDF page as it is displayed, and what is needed to map points on it
+// back to the page's own coordinates
+type pageGeometry struct {
+	mediaX, mediaY float64 // lower left corner of the media box
+	width, height  float64 // displayed size in points, after rotation
+	rotate         int     // clockwise rotation in degrees: 0, 90, 180 or 270
+}
+
+// inheritedKey looks key up on a page dictionary and then on its parents, as MediaBox and Rotate are inherited
+func inheritedKey(v pdf.Value, key string) pdf.Value {
+	for depth := 0; depth < 32 && !v.IsNull(); depth++ {
+		if value := v.Key(key); !value.IsNull() {
+			return value
+		}
+		v = v.Key("Parent")
+	}
+	return pdf.Value{}
+}
+
+// readPageGeometry returns the displayed size of every page of a PDF
+func readPageGeometry(path string) (pages []pageGeometry, err error) {
+	defer func() {
+		if r := recover(); r != nil { // the reader panics on some malformed files
+			pages, err = nil, fmt.Errorf("unreadable PDF: %v", r)
+		}
+	}()
+	file, reader, err := pdf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	for number := 1; number <= reader.NumPage(); number++ {
+		page := reader.Page(number)
+		box := inheritedKey(page.V, "MediaBox")
+		if box.Len() != 4 {
+			return nil, fmt.Errorf("page %d has no media box", number)
+		}
+		geometry := pageGeometry{
+			mediaX: math.Min(box.Index(0).Float64(), box.Index(2).Float64()),
+			mediaY: math.Min(box.Index(1).Float64(), box.Index(3).Float64()),
+			width:  math.Abs(box.Index(2).Float64() - box.Index(0).Float64()),
+			height: math.Abs(box.Index(3).Float64() - box.Index(1).Float64()),
+			rotate: int((inheritedKey(page.V, "Rotate").Int64()%360 + 360) % 360),
+		}
+		if geometry.rotate == 90 || geometry.rotate == 270 {
+			geometry.width, geometry.height = geometry.height, geometry.width
+		}
+		pages = append(pages, geometry)
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("PDF has no pages")
+	}
+	return pages, nil
+}
+
+// validateRedactionRegions returns the problem with each region, or nil when they can all be redacted
+func validateRedactionRegions(regions []database.RedactionRegion, pages []pageGeometry) map[string]string {
+	problems := make(map[string]string)
+	if len(regions) == 0 {
+		problems["regions"] = "give at least one region to black out"
+	}
+	if len(regions) > maxRedactionRegions {
+		problems["regions"] = fmt.Sprintf("at most %d regions may be redacted at once", maxRedactionRegions)
+	}
+	for i, region := range regions {
+		field := fmt.Sprintf("regions[%d]", i)
+		switch {
+		case region.Page < 1 || region.Page > len(pages):
+			problems[field+".page"] = fmt.Sprintf("must be a page from 1 to %d", len(pages))
+		case region.Width <= 0 || region.Height <= 0:
+			problems[field] = "width and height must be positive"
+		case region.X < 0 || region.Y < 0 || region.X >= pages[region.Page-1].width || region.Y >= pages[region.Page-1].height:
+			problems[field] = fmt.Sprintf("must start on the page, which is %.0f by %.0f points", pages[region.Page-1].width, pages[region.Page-1].height)
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
+
+// regionsOnPage returns the regions of a 1-based page
+func regionsOnPage(regions []database.RedactionRegion, page int) []database.RedactionRegion {
+	var onPage []database.RedactionRegion
+	for _, region := range regions {
+		if region.Page == page {
+			onPage = append(onPage, region)
+		}
+	}
+	return onPage
+}
+
+// covered reports whether a piece of text drawn on an unrotated page overlaps any of the regions
+func (g pageGeometry) covered(text pdf.Text, regions []database.RedactionRegion) bool {
+	for _, region := range regions {
+		left := g.mediaX + region.X
+		top := g.mediaY + g.height - region.Y
+		if text.X < left+region.Width && text.X+text.W > left && text.Y < top && text.Y+text.FontSize > top-region.Height {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedPageText returns the text of a page without the text under any of the regions. Text on a
+// rotated page is not mapped onto the displayed regions, so none of it is kept.
+func redactedPageText(page pdf.Page, geometry pageGeometry, regions []database.RedactionRegion) string {
+	return pageTextWhere(page, geometry, func(piece pdf.Text) bool { return !geometry.covered(piece, regions) })
+}
+
+// pageTextWhere returns the pieces of text on a page that keep accepts, with line breaks and spaces
+// where the pieces are apart. A rotated page has none, as its text is not mapped onto the displayed page.
+func pageTextWhere(page pdf.Page, geometry pageGeometry, keep func(pdf.Text) bool) string {
+	if geometry.rotate != 0 {
+		return ""
+	}
+	var text strings.Builder
+	var last pdf.Text
+	for i, piece := range page.Content().Text {
+		if !keep(piece) {
+			continue
+		}
+		if i > 0 && text.Len() > 0 {
+			switch {
+			case math.Abs(piece.Y-last.Y) > last.FontSize/2:
+				text.WriteString("\n")
+			case piece.X > last.X+last.W+piece.FontSize/5:
+				text.WriteString(" ")
+			}
+		}
+		text.WriteString(piece.S)
+		last = piece
+	}
+	return text.String()
+}
+
+// redactedText returns the text of a PDF with the text under the regions removed, page by page. It is
+// empty when the PDF has no text layer, as for a scan.
+func redactedText(path string, pages []pageGeometry, regions []database.RedactionRegion) (fullText string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			fullText, err = "", fmt.Errorf("unreadable PDF text: %v", r)
+		}
+	}()
+	file, reader, err := pdf.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	pageTexts := make([]string, 0, reader.NumPage())
+	fonts := make(map[string]*pdf.Font)
+	for number := 1; number <= reader.NumPage() && number <= len(pages); number++ {
+		page := reader.Page(number)
+		if onPage := regionsOnPage(regions, number); len(onPage) > 0 {
+			pageTexts = append(pageTexts, redactedPageText(page, pages[number-1], onPage))
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		pageText, err := page.GetPlainText(fonts)
+		if err != nil {
+			return "", err
+		}
+		pageTexts = append(pageTexts, pageText)
+	}
+	fullText = strings.Join(pageTexts, pageBreak)
+	if strings.TrimSpace(strings.ReplaceAll(fullText, pageBreak, "")) == "" {
+		return "", nil
+	}
+	return fullText, nil
+}
+
+// blackOut paints the regions onto a rendered page, scaling points to the image's pixels
+func blackOut(page image.Image, geometry pageGeometry, regions []database.RedactionRegion) *image.RGBA {
+	bounds := page.Bounds()
+	canvas := image.NewRGBA(bounds)
+	draw.Draw(canvas, bounds, page, bounds.Min, draw.Src)
+	scaleX := float64(bounds.Dx()) / geometry.width
+	scaleY := float64(bounds.Dy()) / geometry.height
+	for _, region := range regions {
+		box := image.Rect(
+			bounds.Min.X+int(math.Floor(region.X*scaleX)),
+			bounds.Min.Y+int(math.Floor(region.Y*scaleY)),
+			bounds.Min.X+int(math.Ceil((region.X+region.Width)*scaleX)),
+			bounds.Min.Y+int(math.Ceil((region.Y+region.Height)*scaleY)),
+		).Intersect(bounds)
+		draw.Draw(canvas, box, image.Black, image.Point{}, draw.Src)
+	}
+	return canvas
+}
+
+// writeRedactedPDF renders every page of source, blacks out the regions and writes the pages to
+// destination as images. Nothing of the original page content survives, so text or vector drawing
+// under a black box cannot be copied out of the copy.
+func writeRedactedPDF(renderer pdfrenderer.Renderer, source, destination string, pages []pageGeometry, regions []database.RedactionRegion) error {
+	return writeImagePDF(renderer, source, destination, pages, redactedPageQuality, func(pageIndex int, page image.Image) image.Image {
+		return blackOut(page, pages[pageIndex], regionsOnPage(regions, pageIndex+1))
+	})
+}
+
+// writeImagePDF renders every page of source, passes it through redraw and writes the pages to
+// destination as JPEG images of the given quality, each at its page's displayed size
+func writeImagePDF(renderer pdfrenderer.Renderer, source, destination string, pages []pageGeometry, quality int, redraw func(pageIndex int, page image.Image) image.Image) error {
+	out := gofpdf.NewCustom(&gofpdf.InitType{UnitStr: "pt", Size: gofpdf.SizeType{Wd: pages[0].width, Ht: pages[0].height}})
+	out.SetMargins(0, 0, 0)
+	out.SetAutoPageBreak(false, 0)
+	rendered, err := renderer.RenderPages(source, 0, func(pageIndex int, page image.Image) error {
+		if pageIndex >= len(pages) {
+			return fmt.Errorf("renderer found more pages than the PDF lists")
+		}
+		geometry := pages[pageIndex]
+		var encoded bytes.Buffer
+		if err := jpeg.Encode(&encoded, redraw(pageIndex, page), &jpeg.Options{Quality: quality}); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("page-%d", pageIndex+1)
+		options := gofpdf.ImageOptions{ImageType: "JPG"}
+		out.AddPageFormat("P", gofpdf.SizeType{Wd: geometry.width, Ht: geometry.height})
+		out.RegisterImageOptionsReader(name, options, &encoded)
+		out.ImageOptions(name, 0, 0, geometry.width, geometry.height, false, options, 0, "")
+		return out.Error()
+	})
+	if err != nil {
+		return err
+	}
+	if rendered != len(pages) {
+		return fmt.Errorf("rendered %d pages of %d", rendered, len(pages))
+	}
+	return out.OutputFileAndClose(destination)
+}
+
+// redactedPath returns a free name for the redacted copy of the document at path, beside it
+func redactedPath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext) + "-redacted"
+	destination := base + ext
+	for n := 2; ; n++ {
+		if _, err := os.Stat(destination); os.IsNotExist(err) {
+			return destination
+		}
+		destination = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+}
+
+// redactDocument makes the redacted copy of source and stores it as a new document linked to it
+func (serverHandler *ServerHandler) redactDocument(source *database.Document, pages []pageGeometry, regions []database.RedactionRegion, user string) (*database.Document, *database.DocumentRedaction, error) {
+	timeline := startTimeline("redaction of " + source.ULID.String())
+	fullText, err := redactedText(source.Path, pages, regions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	workDir, cleanup, err := serverHandler.newWorkDir("redact-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cleanup()
+	renderer, err := pdfrenderer.NewRenderer(serverHandler.ServerConfig.PDFServiceURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer renderer.Close()
+	destPath := redactedPath(source.Path)
+	stagedPath := filepath.Join(workDir, filepath.Base(destPath))
+	if err := writeRedactedPDF(renderer, source.Path, stagedPath, pages, regions); err != nil {
+		return nil, nil, err
+	}
+	fileHash, err := calculateFileHash(stagedPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newTime := database.Now()
+	newULID, err := database.CalculateUUID(newTime)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot generate ULID: %w", err)
+	}
+	doc := &database.Document{
+		Name:         filepath.Base(destPath),
+		Path:         filepath.ToSlash(destPath),
+		IngressTime:  newTime,
+		Folder:       filepath.ToSlash(filepath.Dir(destPath)),
+		Hash:         fileHash.Current(),
+		ULID:         newULID,
+		DocumentType: filepath.Ext(destPath),
+		MIMEType:     database.DetectMIMEType(stagedPath),
+		URL:          documentViewURL(newULID),
+	}
+	doc.Size, doc.PageCount = fileDetails(stagedPath)
+	if err := serverHandler.moveAndVerifyFile(stagedPath, destPath, doc.Hash); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", errStorageFailed, err)
+	}
+	timeline.mark(stageStored, "")
+
+	if fullText == "" {
+		// A scan has no text layer to filter, so OCR the copy: the black boxes hide what they cover
+		if fullText, doc.OCRStatus, err = serverHandler.extractText(destPath, timeline); err != nil {
+			Logger.Warn("Text extraction failed, storing redacted copy without text", "path", destPath, "error", err)
+			fullText = ""
+		}
+	} else {
+		timeline.mark(stageTextExtracted, "original text outside the redacted regions")
+		doc.OCRStatus = database.OCRSkipped
+	}
+	doc.FullText = fullText
+
+	if err := serverHandler.DB.SaveDocument(doc); err != nil {
+		if removeErr := os.Remove(destPath); removeErr != nil {
+			Logger.Error("Unable to remove redacted copy after failed save", "path", destPath, "error", removeErr)
+		}
+		return nil, nil, fmt.Errorf("unable to save document: %w", err)
+	}
+	timeline.mark(stageIndexed, "")
+	timeline.save(serverHandler.DB, doc.ULID)
+
+	redaction := &database.DocumentRedaction{
+		DocumentULID: doc.ULID.String(),
+		SourceULID:   source.ULID.String(),
+		Regions:      regions,
+		CreatedBy:    user,
+		CreatedAt:    newTime,
+	}
+	if err := serverHandler.DB.SaveDocumentRedaction(redaction); err != nil {
+		Logger.Error("Unable to link redacted copy to its document", "ulid", doc.ULID.String(), "source", redaction.SourceULID, "error", err)
+	}
+	serverHandler.recordFolder(filepath.Dir(destPath))
+	serverHandler.wordCounts.add(doc)
+	serverHandler.wordCounts.flushSoon(serverHandler.DB)
+	serverHandler.invalidateDocumentCache()
+	Logger.Info("Stored redacted copy", "source", redaction.SourceULID, "ulid", redaction.DocumentULID, "path", doc.Path, "regions", len(regions), "user", user)
+	return doc, redaction, nil
+}
+
+// RedactDocument makes a copy of a PDF with regions blacked out, for sharing a document that holds
+// account numbers or other details that must not leave
+// @Summary Redact a document
+// @Description Black out rectangles on a PDF's pages and store the result as a new document beside it, linked to the original. Regions are in points from the top left corner of the page as displayed.
+// @Description The copy's pages are images, so nothing under a box can be recovered from it, and the text under the boxes is left out of its search index. The original is not changed.
+// @Tags Documents
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Param redaction body redactRequest true "Regions to black out"
+// @Success 201 {object} map[string]interface{} "The new document and its redaction record"
+// @Failure 400 {object} dto.ErrorResponse "Invalid ULID, body or regions"
+// @Failure 403 {object} dto.ErrorResponse "No write access to the document's folder"
+// @Failure 404 {object} dto.ErrorResponse "Document or its file not found"
+// @Failure 409 {object} dto.ErrorResponse "Document is archived"
+// @Failure 415 {object} dto.ErrorResponse "Not a readable PDF"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Failure 503 {object} dto.ErrorResponse "No PDF renderer in this build"
+// @Router /document/{id}/redact [post]
+func (serverHandler *ServerHandler) RedactDocument(c echo.Context) error {
+	source, ok, err := serverHandler.archiveTarget(c, true)
+	if !ok {
+		return err
+	}
+	var request redactRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	if archive, err := serverHandler.DB.GetDocumentArchive(source.ULID.String()); err == nil {
+		return documentArchived(c, archive)
+	}
+	if fileMissing(source.Path) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document file is missing",
+			"code":  dto.CodeFileMissing,
+		})
+	}
+	var pages []pageGeometry
+	if strings.EqualFold(filepath.Ext(source.Path), ".pdf") {
+		pages, err = readPageGeometry(source.Path)
+	}
+	if pages == nil {
+		Logger.Warn("Unable to redact document", "path", source.Path, "error", err)
+		return c.JSON(http.StatusUnsupportedMediaType, map[string]interface{}{
+			"error": "Only readable PDFs can be redacted",
+			"code":  dto.CodeUnsupportedType,
+		})
+	}
+	if problems := validateRedactionRegions(request.Regions, pages); problems != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "The redaction needs fixing",
+			"code":   dto.CodeValidation,
+			"fields": problems,
+		})
+	}
+
+	release, err := serverHandler.processingSlot(c.Request().Context(), priorityInteractive)
+	if err != nil {
+		return err
+	}
+	doc, redaction, err := serverHandler.redactDocument(source, pages, request.Regions, requestUser(c))
+	release()
+	switch {
+	case errors.Is(err, pdfrenderer.ErrNoLocalRenderer):
+		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeFeatureDisabled,
+		})
+	case errors.Is(err, errQuotaExceeded):
+		return c.JSON(http.StatusInsufficientStorage, map[string]interface{}{"error": err.Error(), "code": dto.CodeQuotaExceeded})
+	case err != nil:
+		Logger.Error("Unable to redact document", "ulid", source.ULID.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to redact document",
+			"code":  errorCode(err),
+		})
+	}
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"document":  doc,
+		"redaction": redaction,
+	})
+}
+
+// GetDocumentRedactions lists the redacted copies of a document, and the document it was redacted from
+// @Summary List redacted copies
+// @Tags Documents
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Success 200 {object} map[string]interface{} "redactions made from the document, newest first, and redactedFrom when it is itself a copy"
+// @Failure 400 {object} dto.ErrorResponse "Invalid ULID"
+// @Failure 404 {object} dto.ErrorResponse "Document not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /document/{id}/redactions [get]
+func (serverHandler *ServerHandler) GetDocumentRedactions(c echo.Context) error {
+	document, ok, err := serverHandler.archiveTarget(c, false)
+	if !ok {
+		return err
+	}
+	redactions, err := serverHandler.DB.ListDocumentRedactions(document.ULID.String())
+	if err != nil {
+		Logger.Error("Failed to list redacted copies", "ulid", document.ULID.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list redacted copies",
+			"code":  dto.CodeInternal,
+		})
+	}
+	if redactions == nil {
+		redactions = []database.DocumentRedaction{}
+	}
+	response := map[string]interface{}{"redactions": redactions, "redactedFrom": nil}
+	if from, err := serverHandler.DB.GetDocumentRedaction(document.ULID.String()); err == nil {
+		response["redactedFrom"] = from
+	}
+	return c.JSON(http.StatusOK, response)
+}
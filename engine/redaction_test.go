@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"encoding/json"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/engine/pdfrenderer"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// writeStatementPDF writes a one page A4 PDF with an account number near the top and a note below it
+func writeStatementPDF(t *testing.T, path string) {
+	t.Helper()
+	out := gofpdf.New("P", "pt", "A4", "")
+	out.AddPage()
+	out.SetFont("Helvetica", "", 12)
+	out.Text(72, 100, "Account 12345678")
+	out.Text(72, 300, "Public note")
+	if err := out.OutputFileAndClose(path); err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+}
+
+func TestRedactDocument(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping PDFium rendering test in short mode")
+	}
+	// A statement whose account number is to be blacked out
+	handler := setupTestHandler(t)
+	handler.Echo.POST("/api/document/:id/redact", handler.RedactDocument)
+	handler.Echo.GET("/api/document/:id/redactions", handler.GetDocumentRedactions)
+	path := filepath.Join(handler.ServerConfig.DocumentPath, "bank", "statement.pdf")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	writeStatementPDF(t, path)
+	source := saveTestDocument(t, handler.DB, path, "Account 12345678\nPublic note")
+	serve := func(method, target, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec, response
+	}
+
+	// A box over the account number is redacted
+	rec, response := serve(http.MethodPost, "/api/document/"+source.ULID.String()+"/redact", `{"regions":[{"page":1,"x":60,"y":85,"width":200,"height":20}]}`)
+
+	// A linked copy is stored beside the original, whose index keeps only the text outside the box
+	if rec.Code == http.StatusServiceUnavailable {
+		t.Skipf("PDF renderer unavailable: %v", response)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %v", rec.Code, response)
+	}
+	copyID := response["redaction"].(map[string]interface{})["documentId"].(string)
+	redacted, err := handler.DB.GetDocumentByULID(copyID)
+	if err != nil {
+		t.Fatalf("Redacted copy not stored: %v", err)
+	}
+	if redacted.Path != filepath.ToSlash(filepath.Join(filepath.Dir(path), "statement-redacted.pdf")) || redacted.PageCount != 1 {
+		t.Errorf("Unexpected copy %s with %d pages", redacted.Path, redacted.PageCount)
+	}
+	if strings.Contains(redacted.FullText, "12345678") || !strings.Contains(redacted.FullText, "Public note") {
+		t.Errorf("Expected only the unredacted text indexed, got %q", redacted.FullText)
+	}
+
+	// The copy has no text layer left and the box is burned into its page
+	if text, err := pdfProcessing(redacted.Path); err == nil {
+		t.Errorf("Expected no text layer in the copy, got %q", *text)
+	}
+	renderer, err := pdfrenderer.NewRenderer("")
+	if err != nil {
+		t.Fatalf("Renderer unavailable: %v", err)
+	}
+	defer renderer.Close()
+	renderer.RenderPages(redacted.Path, 0, func(pageIndex int, page image.Image) error {
+		scale := float64(page.Bounds().Dx()) / 595.28
+		r, g, b, _ := page.At(int(150*scale), int(95*scale)).RGBA()
+		if r+g+b > 0x3000 {
+			t.Errorf("Expected the redacted region to be black, got %d %d %d", r>>8, g>>8, b>>8)
+		}
+		r, g, b, _ = page.At(int(400*scale), int(500*scale)).RGBA()
+		if r+g+b < 0x20000 {
+			t.Errorf("Expected the rest of the page to stay white, got %d %d %d", r>>8, g>>8, b>>8)
+		}
+		return nil
+	})
+
+	// Each document points at the other, and the original is unchanged
+	if rec, response := serve(http.MethodGet, "/api/document/"+source.ULID.String()+"/redactions", ""); rec.Code != http.StatusOK || len(response["redactions"].([]interface{})) != 1 || response["redactedFrom"] != nil {
+		t.Errorf("Unexpected redactions of the original %d %v", rec.Code, response)
+	}
+	if rec, response := serve(http.MethodGet, "/api/document/"+copyID+"/redactions", ""); rec.Code != http.StatusOK || response["redactedFrom"].(map[string]interface{})["sourceId"] != source.ULID.String() {
+		t.Errorf("Unexpected redactions of the copy %d %v", rec.Code, response)
+	}
+	if text, err := pdfProcessing(path); err != nil || !strings.Contains(*text, "12345678") {
+		t.Errorf("Expected the original to keep its text, got %v", err)
+	}
+
+	// Redacting again picks a new name
+	if rec, _ := serve(http.MethodPost, "/api/document/"+source.ULID.String()+"/redact", `{"regions":[{"page":1,"x":0,"y":0,"width":10,"height":10}]}`); rec.Code != http.StatusCreated {
+		t.Errorf("Expected a second copy, got %d", rec.Code)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), "statement-redacted-2.pdf")); err != nil {
+		t.Errorf("Expected statement-redacted-2.pdf: %v", err)
+	}
+}
+
+func TestRedactDocumentValidation(t *testing.T) {
+	// A one page PDF and a text document
+	handler := setupTestHandler(t)
+	handler.Echo.POST("/api/document/:id/redact", handler.RedactDocument)
+	path := filepath.Join(handler.ServerConfig.DocumentPath, "statement.pdf")
+	writeStatementPDF(t, path)
+	pdfID := saveTestDocument(t, handler.DB, path, "").ULID.String()
+	notes := filepath.Join(handler.ServerConfig.DocumentPath, "notes.txt")
+	os.WriteFile(notes, []byte("notes"), 0644)
+	textID := saveTestDocument(t, handler.DB, notes, "notes").ULID.String()
+	redact := func(id, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodPost, "/api/document/"+id+"/redact", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response
+	}
+
+	// Regions off the page or without size are refused field by field
+	code, response := redact(pdfID, `{"regions":[{"page":2,"x":0,"y":0,"width":10,"height":10},{"page":1,"x":0,"y":0,"width":0,"height":10},{"page":1,"x":900,"y":0,"width":10,"height":10}]}`)
+	if code != http.StatusBadRequest || response["code"] != string(dto.CodeValidation) {
+		t.Fatalf("Expected a validation error, got %d %v", code, response)
+	}
+	fields := response["fields"].(map[string]interface{})
+	for _, field := range []string{"regions[0].page", "regions[1]", "regions[2]"} {
+		if fields[field] == nil {
+			t.Errorf("Expected a problem with %s, got %v", field, fields)
+		}
+	}
+	if code, response := redact(pdfID, `{"regions":[]}`); code != http.StatusBadRequest || response["fields"].(map[string]interface{})["regions"] == nil {
+		t.Errorf("Expected no regions to be refused, got %d %v", code, response)
+	}
+
+	// Only PDFs can be redacted, and an unknown document is not found
+	if code, response := redact(textID, `{"regions":[{"page":1,"x":0,"y":0,"width":10,"height":10}]}`); code != http.StatusUnsupportedMediaType || response["code"] != string(dto.CodeUnsupportedType) {
+		t.Errorf("Expected 415 for a text document, got %d %v", code, response)
+	}
+	if code, _ := redact(database.Document{}.ULID.String(), `{"regions":[]}`); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown document, got %d", code)
+	}
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+	"github.com/oklog/ulid/v2"
+)
+
+// rehashResult counts what a rehash pass did with each document not yet stored under the current algorithm
+type rehashResult struct {
+	Rehashed int // stored hash replaced with the current algorithm's
+	Changed  int // file no longer matches its stored hash, left for the rescan job
+	Missing  int // file could not be read
+}
+
+// rehashDocuments stores every document's hash under the current algorithm. A file is only rehashed when
+// it still matches its old hash, so a file changed on disk keeps the hash the rescan job compares against.
+func rehashDocuments(db database.Repository, heartbeat *jobHeartbeat) (rehashResult, error) {
+	var result rehashResult
+	algorithm := database.CurrentHashAlgorithm()
+	var cursor *database.DocumentCursor
+	for {
+		page, err := db.GetNewestDocumentsAfter(cursor, maxCursorPageSize)
+		if err != nil {
+			return result, err
+		}
+		for _, document := range page {
+			heartbeat.beat()
+			if document.Hash != "" && database.HashAlgorithmOf(document.Hash) == algorithm {
+				continue
+			}
+			hashes, err := database.HashFile(document.Path)
+			if err != nil {
+				// Archived documents are rehashed when they are restored
+				if _, archiveErr := db.GetDocumentArchive(document.ULID.String()); archiveErr == nil {
+					continue
+				}
+				Logger.Warn("Unable to rehash document", "ulid", document.ULID.String(), "path", document.Path, "error", err)
+				result.Missing++
+				continue
+			}
+			if document.Hash != "" && !hashes.Matches(document.Hash) {
+				Logger.Warn("Document changed on disk, not rehashing", "ulid", document.ULID.String(), "path", document.Path)
+				result.Changed++
+				continue
+			}
+			if err := db.UpdateDocumentHash(document.ULID.String(), hashes.Current()); err != nil {
+				return result, err
+			}
+			result.Rehashed++
+		}
+		if len(page) < maxCursorPageSize {
+			return result, nil
+		}
+		cursor = database.CursorAfter(page[len(page)-1])
+	}
+}
+
+// RehashDocuments starts a job that moves every document's stored hash to the configured algorithm
+// @Summary Rehash documents
+// @Description Re-hash document files in the background and store the hashes under the configured HASH_ALGORITHM. Files that no longer match their stored hash are left for the rescan job.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Job created with jobId"
+// @Failure 409 {object} map[string]interface{} "A rehash job is already active; jobId is that job"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /documents/rehash [post]
+func (serverHandler *ServerHandler) RehashDocuments(c echo.Context) error {
+	job, err := serverHandler.startJob(database.JobTypeRehash, "Starting document rehash")
+	if errors.Is(err, errJobActive) {
+		return jobAlreadyActive(c, job)
+	}
+	if err != nil {
+		Logger.Error("Failed to create rehash job", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to create rehash job",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	go serverHandler.rehashJob(serverHandler.DB, job.ID)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":   "Document rehash started",
+		"jobId":     job.ID.String(),
+		"algorithm": database.CurrentHashAlgorithm(),
+	})
+}
+
+// rehashJob runs rehashDocuments with job tracking
+func (serverHandler *ServerHandler) rehashJob(db database.Repository, jobID ulid.ULID) {
+	db.UpdateJobStatus(jobID, database.JobStatusRunning, "Rehashing documents with "+database.CurrentHashAlgorithm())
+
+	result, err := rehashDocuments(db, newJobHeartbeat(db, jobID))
+	if err != nil {
+		Logger.Error("Document rehash failed", "rehashed", result.Rehashed, "error", err)
+		db.UpdateJobError(jobID, fmt.Sprintf("Rehash failed after %d documents: %v", result.Rehashed, err))
+		return
+	}
+	if result.Rehashed > 0 {
+		serverHandler.invalidateDocumentCache()
+	}
+	if err := db.CompleteJob(jobID, fmt.Sprintf(`{"rehashed": %d, "changed": %d, "missing": %d}`, result.Rehashed, result.Changed, result.Missing)); err != nil {
+		Logger.Error("Failed to mark rehash job as complete", "error", err)
+	}
+	Logger.Info("Document rehash completed", "rehashed", result.Rehashed, "changed", result.Changed, "missing", result.Missing)
+}
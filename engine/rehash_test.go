@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+)
+
+func TestRehashJobMovesLegacyHashes(t *testing.T) {
+	// A document with a legacy MD5 hash, one already on SHA-256, one edited since it was
+	// hashed and one whose file has gone
+	handler := setupTestHandler(t)
+	legacyPath := saveTextDocument(t, handler, "legacy.txt", "legacy words")
+	legacyHashes, _ := database.HashFile(legacyPath)
+	legacy, _ := handler.DB.GetDocumentByPath(legacyPath)
+	if err := handler.DB.UpdateDocumentHash(legacy.ULID.String(), legacyHashes.MD5); err != nil {
+		t.Fatalf("Failed to store legacy hash: %v", err)
+	}
+	currentPath := saveTextDocument(t, handler, "current.txt", "current words")
+	editedPath := saveTextDocument(t, handler, "edited.txt", "original words")
+	editedHashes, _ := database.HashFile(editedPath)
+	edited, _ := handler.DB.GetDocumentByPath(editedPath)
+	if err := handler.DB.UpdateDocumentHash(edited.ULID.String(), editedHashes.MD5); err != nil {
+		t.Fatalf("Failed to store legacy hash: %v", err)
+	}
+	if err := os.WriteFile(editedPath, []byte("edited words"), 0644); err != nil {
+		t.Fatalf("Failed to edit document: %v", err)
+	}
+	saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "gone.txt"), "")
+
+	// The rehash job runs
+	rec := httptest.NewRecorder()
+	if err := handler.RehashDocuments(handler.Echo.NewContext(httptest.NewRequest(http.MethodPost, "/api/documents/rehash", nil), rec)); err != nil {
+		t.Fatalf("RehashDocuments failed: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var started map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &started)
+	job := waitForTestJob(t, handler.DB, started["jobId"])
+
+	// The legacy document is rehashed, and the edited and missing ones are counted
+	if job.Status != database.JobStatusCompleted {
+		t.Fatalf("Expected the job to complete, got %s: %s", job.Status, job.Error)
+	}
+	var result map[string]int
+	if err := json.Unmarshal([]byte(job.Result), &result); err != nil {
+		t.Fatalf("Invalid job result %q: %v", job.Result, err)
+	}
+	if result["rehashed"] != 1 || result["changed"] != 1 || result["missing"] != 1 {
+		t.Errorf("Unexpected job result %v", result)
+	}
+	stored, _ := handler.DB.GetDocumentByPath(legacyPath)
+	if stored.Hash != legacyHashes.Current() {
+		t.Errorf("Expected the legacy hash replaced, got %s", stored.Hash)
+	}
+	stored, _ = handler.DB.GetDocumentByPath(editedPath)
+	if stored.Hash != editedHashes.MD5 {
+		t.Errorf("Expected the edited document left for rescan, got %s", stored.Hash)
+	}
+	current, _ := handler.DB.GetDocumentByPath(currentPath)
+	if database.HashAlgorithmOf(current.Hash) != database.HashSHA256 {
+		t.Errorf("Expected the current document untouched, got %s", current.Hash)
+	}
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/sources"
+)
+
+// remoteIngester pulls documents from a remote source into the ingress folder and ingests them
+type remoteIngester struct {
+	source sources.Source
+	seen   map[string]time.Time // files left on the source (IngressDelete=false) by modification time
+}
+
+// newRemoteIngester creates an ingester for a remote source
+func newRemoteIngester(source sources.Source) *remoteIngester {
+	return &remoteIngester{source: source, seen: make(map[string]time.Time)}
+}
+
+// remoteIngestJobFunc copies new files from a remote source into the ingress folder, keeping their
+// folder structure so IngressPreserve applies, ingests them and optionally writes the result back
+func (serverHandler *ServerHandler) remoteIngestJobFunc(ingester *remoteIngester) {
+	defer func() {
+		if r := recover(); r != nil {
+			Logger.Error("Panic recovered in remote ingest job", "source", ingester.source.Name(), "panic", r)
+		}
+	}()
+
+	source := ingester.source
+	files, err := source.List()
+	if err != nil {
+		Logger.Error("Unable to list remote source", "source", source.Name(), "error", err)
+		return
+	}
+	Logger.Info("Starting remote ingest", "source", source.Name(), "files", len(files))
+
+	ingested := 0
+	for _, file := range files {
+		if serverHandler.checkIngestible(file.Path) != nil {
+			continue
+		}
+		if modTime, ok := ingester.seen[file.Path]; ok && modTime.Equal(file.ModTime) {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(file.Path)) {
+			Logger.Warn("Skipping remote file outside the source folder", "source", source.Name(), "path", file.Path)
+			continue
+		}
+		localPath := filepath.Join(serverHandler.ServerConfig.IngressPath, filepath.FromSlash(file.Path))
+		if _, err := os.Stat(localPath); err == nil {
+			Logger.Debug("Remote file already waiting in ingress", "source", source.Name(), "path", file.Path)
+			continue
+		}
+
+		if err := serverHandler.downloadRemoteFile(source, file.Path, localPath); err != nil {
+			Logger.Error("Unable to download remote file", "source", source.Name(), "path", file.Path, "error", err)
+			continue
+		}
+		transformed, err := serverHandler.preIngest(localPath)
+		if err != nil {
+			ingester.seen[file.Path] = file.ModTime // quarantined; only a changed file is tried again
+			continue
+		}
+		release, _ := serverHandler.processingSlot(context.Background(), priorityBulk)
+		doc, err := serverHandler.ingressDocumentWithError(transformed, "ingress")
+		release()
+		if err != nil {
+			// The file stays in the ingress folder where the regular ingress job retries it
+			Logger.Error("Remote file ingestion failed", "source", source.Name(), "path", file.Path, "error", err)
+			continue
+		}
+		ingested++
+
+		if writer, ok := source.(sources.WriteBacker); ok && writer.CanWriteBack() && doc != nil {
+			serverHandler.writeBackDocument(writer, source.Name(), doc)
+		}
+		if serverHandler.ServerConfig.IngressDelete {
+			if err := source.Remove(file.Path); err != nil {
+				Logger.Error("Unable to remove ingested file from remote source", "source", source.Name(), "path", file.Path, "error", err)
+				ingester.seen[file.Path] = file.ModTime
+			}
+		} else {
+			ingester.seen[file.Path] = file.ModTime
+		}
+	}
+
+	if ingested > 0 {
+		deleteEmptyIngressFolders(serverHandler.ServerConfig.IngressPath)
+	}
+	Logger.Info("Remote ingest complete", "source", source.Name(), "ingested", ingested)
+}
+
+// downloadRemoteFile copies a remote file to localPath, creating folders as needed. It is downloaded into a
+// private work directory and only moved into the ingress folder once complete, so the ingress job never
+// picks up a partly downloaded file.
+func (serverHandler *ServerHandler) downloadRemoteFile(source sources.Source, remotePath, localPath string) error {
+	content, err := source.Open(remotePath)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	workDir, cleanup, err := serverHandler.newWorkDir("remote-*")
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	staged := filepath.Join(workDir, filepath.Base(localPath))
+	if _, err := writeFileHashed(staged, content, 0666); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(localPath), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Rename(staged, localPath); err == nil {
+		return nil
+	}
+	// The work directory is on another filesystem, so the finished file is copied in under a temporary name
+	_, err = replaceTransformed(localPath, staged)
+	return err
+}
+
+// writeBackDocument uploads the stored copy of a freshly ingested document to the source's
+// write-back folder, at the same path it has below the document folder
+func (serverHandler *ServerHandler) writeBackDocument(writer sources.WriteBacker, sourceName string, doc *database.Document) {
+	relative, err := filepath.Rel(serverHandler.ServerConfig.DocumentPath, filepath.FromSlash(doc.Path))
+	if err != nil {
+		Logger.Error("Document is outside the document folder, not writing back", "path", doc.Path, "error", err)
+		return
+	}
+	file, err := os.Open(doc.Path)
+	if err != nil {
+		Logger.Error("Unable to open document for write-back", "path", doc.Path, "error", err)
+		return
+	}
+	defer file.Close()
+	if err := writer.WriteBack(filepath.ToSlash(relative), file); err != nil {
+		Logger.Error("Unable to write document back to remote source", "source", sourceName, "path", relative, "error", err)
+		return
+	}
+	Logger.Info("Wrote processed document back to remote source", "source", sourceName, "path", relative)
+}
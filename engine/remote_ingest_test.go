@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/sources"
+)
+
+// fakeSource is an in-memory remote source
+type fakeSource struct {
+	files     map[string]string
+	removed   []string
+	writeBack map[string]string
+}
+
+func (f *fakeSource) Name() string { return "fake" }
+
+func (f *fakeSource) List() ([]sources.File, error) {
+	var files []sources.File
+	for path, content := range f.files {
+		files = append(files, sources.File{Path: path, Size: int64(len(content)), ModTime: time.Unix(1700000000, 0)})
+	}
+	return files, nil
+}
+
+func (f *fakeSource) Open(path string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewBufferString(f.files[path])), nil
+}
+
+func (f *fakeSource) Remove(path string) error {
+	f.removed = append(f.removed, path)
+	delete(f.files, path)
+	return nil
+}
+
+func (f *fakeSource) CanWriteBack() bool { return f.writeBack != nil }
+
+func (f *fakeSource) WriteBack(path string, content io.Reader) error {
+	data, err := io.ReadAll(content)
+	f.writeBack[path] = string(data)
+	return err
+}
+
+func TestRemoteIngestPreservesStructure(t *testing.T) {
+	// A remote source with a nested document and a file type we cannot process
+	handler := setupTestHandler(t)
+	handler.ServerConfig.IngressDelete = false
+	source := &fakeSource{files: map[string]string{"bills/2024/power.txt": "power bill", "notes.xyz": "skip"}}
+	ingester := newRemoteIngester(source)
+
+	// The remote ingest runs twice
+	handler.remoteIngestJobFunc(ingester)
+	os.Remove(filepath.Join(handler.ServerConfig.IngressPath, "bills", "2024", "power.txt"))
+	handler.remoteIngestJobFunc(ingester)
+
+	// The document is pulled once into the same relative path and left on the source
+	if _, seen := ingester.seen["bills/2024/power.txt"]; !seen {
+		t.Error("Expected the document to be remembered after the first pull")
+	}
+	if _, err := os.Stat(filepath.Join(handler.ServerConfig.IngressPath, "bills", "2024", "power.txt")); !os.IsNotExist(err) {
+		t.Errorf("Unchanged remote file was pulled again: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(handler.ServerConfig.IngressPath, "notes.xyz")); !os.IsNotExist(err) {
+		t.Errorf("Unsupported file was pulled: %v", err)
+	}
+	if len(source.removed) != 0 {
+		t.Errorf("Files were removed from the source with IngressDelete off: %v", source.removed)
+	}
+}
+
+func TestRemoteIngestRemovesWhenIngressDelete(t *testing.T) {
+	// A remote source and IngressDelete enabled
+	handler := setupTestHandler(t)
+	handler.ServerConfig.IngressDelete = true
+	source := &fakeSource{files: map[string]string{"scan.txt": "text"}}
+
+	// The remote ingest runs
+	handler.remoteIngestJobFunc(newRemoteIngester(source))
+
+	// The file is downloaded into ingress and removed from the source
+	if content, err := os.ReadFile(filepath.Join(handler.ServerConfig.IngressPath, "scan.txt")); err != nil || string(content) != "text" {
+		t.Errorf("Expected downloaded file in ingress, got %q, %v", content, err)
+	}
+	if len(source.removed) != 1 || source.removed[0] != "scan.txt" {
+		t.Errorf("Expected scan.txt to be removed from the source, got %v", source.removed)
+	}
+}
+
+// interruptedSource is a remote source whose downloads break part way, calling during before they do
+type interruptedSource struct {
+	fakeSource
+	during func()
+}
+
+func (s *interruptedSource) Open(path string) (io.ReadCloser, error) {
+	return io.NopCloser(io.MultiReader(strings.NewReader(s.files[path][:4]), interruptedReader(s.during))), nil
+}
+
+// interruptedReader fails every read, as a dropped connection does
+type interruptedReader func()
+
+func (during interruptedReader) Read([]byte) (int, error) {
+	during()
+	return 0, errors.New("connection reset")
+}
+
+func TestRemoteIngestKeepsPartialDownloadsOutOfIngress(t *testing.T) {
+	// A remote source whose connection drops while a scan is downloading
+	handler := setupTestHandler(t)
+	handler.ServerConfig.TempPath = t.TempDir()
+	var midway []string
+	source := &interruptedSource{fakeSource: fakeSource{files: map[string]string{"scans/scan.pdf": "%PDF-1.4"}}}
+	source.during = func() {
+		filepath.Walk(handler.ServerConfig.IngressPath, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				midway = append(midway, path)
+			}
+			return nil
+		})
+	}
+
+	// The remote ingest runs
+	handler.remoteIngestJobFunc(newRemoteIngester(source))
+
+	// Nothing reached ingress, during the download or after it failed
+	if len(midway) != 0 {
+		t.Errorf("Expected the download kept out of ingress until complete, found %v", midway)
+	}
+	if _, err := os.Stat(filepath.Join(handler.ServerConfig.IngressPath, "scans", "scan.pdf")); !os.IsNotExist(err) {
+		t.Errorf("Expected no partial file left in ingress: %v", err)
+	}
+}
+
+func TestRemoteIngestSkipsPathsOutsideSource(t *testing.T) {
+	// A remote source listing a file above its own folder beside an ordinary one
+	handler := setupTestHandler(t)
+	handler.ServerConfig.IngressDelete = false
+	source := &fakeSource{files: map[string]string{"../escape.txt": "escape", "kept.txt": "kept"}}
+
+	// The remote ingest runs
+	handler.remoteIngestJobFunc(newRemoteIngester(source))
+
+	// Only the ordinary file is downloaded, and nothing is written outside ingress
+	if _, err := os.Stat(filepath.Join(handler.ServerConfig.IngressPath, "..", "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected a path above the source skipped, got %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(handler.ServerConfig.IngressPath, "kept.txt")); err != nil || string(content) != "kept" {
+		t.Errorf("Expected kept.txt downloaded into ingress, got %q, %v", content, err)
+	}
+}
+
+func TestRemoteIngestWritesBackCorrectedScan(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping PDFium rendering test in short mode")
+	}
+	// BLANK_PAGES=remove, and a write-back source with a letter scanned duplex, its back blank
+	handler := setupTestHandler(t)
+	handler.ServerConfig.BlankPages = "remove"
+	handler.ServerConfig.BlankPageInk = 0.1
+	handler.ServerConfig.TesseractPath = fakeOSDTesseract(t, osdOutput)
+	handler.ServerConfig.IngressDelete = false
+	handler.ServerConfig.IngressMoveFolder = t.TempDir()
+	handler.ServerConfig.NewDocumentFolder = handler.ServerConfig.DocumentPath
+	scan := filepath.Join(t.TempDir(), "letter.pdf")
+	writeDuplexScan(t, scan, true, false)
+	letter, err := os.ReadFile(scan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &fakeSource{files: map[string]string{"letter.pdf": string(letter)}, writeBack: map[string]string{}}
+
+	// The remote ingest runs, storing the letter without its blank page
+	handler.remoteIngestJobFunc(newRemoteIngester(source))
+
+	// The stored document, not the file as downloaded, is written back
+	if len(source.writeBack) != 1 {
+		t.Fatalf("Expected the document written back, got %v", source.writeBack)
+	}
+	for path, content := range source.writeBack {
+		written := filepath.Join(t.TempDir(), "written.pdf")
+		os.WriteFile(written, []byte(content), 0644)
+		if pages := pdfPageCount(written); pages != 1 {
+			t.Errorf("Expected %s written back with its blank page removed, got %d pages", path, pages)
+		}
+	}
+}
+
+func TestWriteBackDocumentKeepsRelativePath(t *testing.T) {
+	// A stored document below the document folder
+	handler := setupTestHandler(t)
+	path := filepath.Join(handler.ServerConfig.DocumentPath, "bills", "power.pdf")
+	os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err := os.WriteFile(path, []byte("%PDF"), 0644); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	doc := saveTestDocument(t, handler.DB, path, "")
+	source := &fakeSource{writeBack: map[string]string{}}
+
+	handler.writeBackDocument(source, source.Name(), doc)
+
+	// It is uploaded at the same relative path
+	if source.writeBack["bills/power.pdf"] != "%PDF" {
+		t.Errorf("Unexpected write-back %v", source.writeBack)
+	}
+}
@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// rescanDocument re-hashes a document's file and, if it changed on disk (or force is set),
+// re-extracts its text and updates the hash and search index. Callers recalculate the word cloud.
+func (serverHandler *ServerHandler) rescanDocument(doc *database.Document, db database.Repository, force bool) (bool, error) {
+	fileHash, err := calculateFileHash(doc.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash document: %w", err)
+	}
+	if fileHash.Matches(doc.Hash) && !force {
+		return false, nil
+	}
+
+	fullText, ocrStatus, err := serverHandler.extractText(doc.Path, nil)
+	if err != nil {
+		Logger.Warn("Text extraction failed during rescan, storing document without text", "path", doc.Path, "error", err)
+		fullText = ""
+	}
+	doc.Hash = fileHash.Current()
+	doc.OCRStatus = ocrStatus
+	if err := serverHandler.updateDocumentText(doc, fullText, db); err != nil {
+		return false, err
+	}
+	serverHandler.recordSpreadsheetDetails(doc)
+	serverHandler.invalidateDocumentCache()
+
+	Logger.Info("Rescanned modified document", "path", doc.Path, "ulid", doc.ULID.String(), "hash", fileHash)
+	return true, nil
+}
+
+// RescanDocument re-extracts text for a document that was modified directly on disk
+// @Summary Rescan a document
+// @Description Re-hash a document file and, if it changed (or force=true), re-extract its text and update the index
+// @Tags Documents
+// @Accept json
+// @Produce json
+// @Param path query string true "File path relative to document root"
+// @Param force query bool false "Re-extract even if the hash is unchanged (default: false)"
+// @Success 200 {object} map[string]interface{} "Whether the document changed, with its ULID and hash"
+// @Failure 400 {object} map[string]interface{} "Invalid path"
+// @Failure 403 {object} map[string]interface{} "No write access to the document's folder"
+// @Failure 404 {object} map[string]interface{} "Document not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /document/rescan [post]
+func (serverHandler *ServerHandler) RescanDocument(c echo.Context) error {
+	pathParam := c.QueryParam("path")
+	if pathParam == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "path is required",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	force := false
+	if forceParam := c.QueryParam("force"); forceParam != "" {
+		parsed, err := strconv.ParseBool(forceParam)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid force value, expected true or false",
+				"code":  dto.CodeBadRequest,
+			})
+		}
+		force = parsed
+	}
+
+	documentPath := filepath.Join(serverHandler.ServerConfig.DocumentPath, pathParam)
+	rel, err := filepath.Rel(serverHandler.ServerConfig.DocumentPath, documentPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "path must point to a file inside the document folder",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		return err
+	}
+	doc, err := serverHandler.DB.GetDocumentByPath(filepath.ToSlash(documentPath))
+	if err != nil || doc == nil || !access.canRead(doc.Folder) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "No document found at " + pathParam,
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if !access.canWrite(doc.Folder) {
+		return folderForbidden(c, doc.Folder)
+	}
+
+	release, err := serverHandler.processingSlot(c.Request().Context(), priorityInteractive)
+	if err != nil {
+		return err
+	}
+	changed, err := serverHandler.rescanDocument(doc, serverHandler.DB, force)
+	release()
+	if err != nil {
+		Logger.Error("Document rescan failed", "path", documentPath, "error", err)
+		if os.IsNotExist(err) {
+			return c.JSON(http.StatusNotFound, map[string]interface{}{
+				"error": "Document file is missing on disk",
+				"code":  dto.CodeFileMissing,
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to rescan document",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	if changed {
+		go func() {
+			if err := serverHandler.DB.RecalculateAllWordFrequencies(); err != nil {
+				Logger.Error("Word cloud recalculation failed after rescan", "error", err)
+			}
+			serverHandler.invalidateDocumentCache()
+		}()
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"changed": changed,
+		"ulid":    doc.ULID.String(),
+		"hash":    doc.Hash,
+	})
+}
+
+// changedFileJobFunc looks for documents modified on disk since the previous scan and rescans them.
+// The first scan after startup hashes every document; later scans only hash files with a newer mtime.
+func (serverHandler *ServerHandler) changedFileJobFunc(db database.Repository) {
+	defer func() {
+		if r := recover(); r != nil {
+			Logger.Error("Panic recovered in changed file job", "panic", r)
+		}
+	}()
+
+	scanStart := time.Now()
+	documents, err := db.GetAllDocuments()
+	if err != nil {
+		Logger.Error("Changed file scan could not list documents", "error", err)
+		return
+	}
+
+	checked, changed := 0, 0
+	for i := range documents {
+		doc := &documents[i]
+		info, err := os.Stat(doc.Path)
+		if err != nil {
+			continue // missing files are handled by the cleanup job
+		}
+		if !serverHandler.lastChangeScan.IsZero() && !info.ModTime().After(serverHandler.lastChangeScan) {
+			continue
+		}
+		checked++
+		release, _ := serverHandler.processingSlot(context.Background(), priorityBulk)
+		updated, err := serverHandler.rescanDocument(doc, db, false)
+		release()
+		if err != nil {
+			Logger.Error("Failed to rescan changed document", "path", doc.Path, "error", err)
+			continue
+		}
+		if updated {
+			changed++
+		}
+	}
+	serverHandler.lastChangeScan = scanStart
+	if changed > 0 {
+		if err := db.RecalculateAllWordFrequencies(); err != nil {
+			Logger.Error("Word cloud recalculation failed after changed file scan", "error", err)
+		}
+		serverHandler.invalidateDocumentCache()
+	}
+
+	Logger.Info("Changed file scan completed", "documents", len(documents), "checked", checked, "changed", changed)
+}
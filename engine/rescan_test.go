@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// saveTextDocument writes a text file under the document root and records it with its real hash
+func saveTextDocument(t *testing.T, handler *ServerHandler, name string, content string) string {
+	t.Helper()
+	path := filepath.ToSlash(filepath.Join(handler.ServerConfig.DocumentPath, name))
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	doc := saveTestDocument(t, handler.DB, path, content)
+	hash, err := calculateFileHash(path)
+	if err != nil {
+		t.Fatalf("Failed to hash document: %v", err)
+	}
+	doc.Hash = hash.Current()
+	if err := handler.DB.SaveDocument(doc); err != nil {
+		t.Fatalf("Failed to save hash: %v", err)
+	}
+	return path
+}
+
+func TestRescanDocumentUpdatesModifiedFile(t *testing.T) {
+	// An indexed text document that is then edited on disk
+	handler := setupTestHandler(t)
+	path := saveTextDocument(t, handler, "notes.txt", "original words")
+	if err := os.WriteFile(path, []byte("edited words"), 0644); err != nil {
+		t.Fatalf("Failed to edit document: %v", err)
+	}
+
+	// The document is rescanned via the API
+	req := httptest.NewRequest(http.MethodPost, "/api/document/rescan?path=notes.txt", nil)
+	rec := httptest.NewRecorder()
+	if err := handler.RescanDocument(handler.Echo.NewContext(req, rec)); err != nil {
+		t.Fatalf("RescanDocument failed: %v", err)
+	}
+
+	// The change is reported and the stored text and hash follow the file
+	var response map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || response["changed"] != true {
+		t.Fatalf("Expected a changed document, got %d: %s", rec.Code, rec.Body.String())
+	}
+	doc, err := handler.DB.GetDocumentByPath(path)
+	if err != nil {
+		t.Fatalf("Failed to load document: %v", err)
+	}
+	expectedHash, _ := calculateFileHash(path)
+	if doc.FullText != "edited words" || doc.Hash != expectedHash.Current() {
+		t.Errorf("Expected updated text and hash, got %q / %s", doc.FullText, doc.Hash)
+	}
+}
+
+func TestRescanDocumentRejectsPathsOutsideDocuments(t *testing.T) {
+	handler := setupTestHandler(t)
+	for _, target := range []string{"/api/document/rescan", "/api/document/rescan?path=../secret.txt"} {
+		req := httptest.NewRequest(http.MethodPost, target, nil)
+		rec := httptest.NewRecorder()
+		if err := handler.RescanDocument(handler.Echo.NewContext(req, rec)); err != nil {
+			t.Fatalf("RescanDocument failed: %v", err)
+		}
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestChangedFileJobOnlyRescansModifiedFiles(t *testing.T) {
+	// Two indexed documents and a completed baseline scan
+	handler := setupTestHandler(t)
+	unchanged := saveTextDocument(t, handler, "unchanged.txt", "same")
+	edited := saveTextDocument(t, handler, "edited.txt", "before")
+	handler.changedFileJobFunc(handler.DB)
+
+	// One file is edited after the scan and the detector runs again
+	if err := os.WriteFile(edited, []byte("after"), 0644); err != nil {
+		t.Fatalf("Failed to edit document: %v", err)
+	}
+	future := handler.lastChangeScan.Add(time.Second)
+	if err := os.Chtimes(edited, future, future); err != nil {
+		t.Fatalf("Failed to bump mtime: %v", err)
+	}
+	handler.changedFileJobFunc(handler.DB)
+
+	// Only the edited document has new text
+	if doc, _ := handler.DB.GetDocumentByPath(edited); doc == nil || doc.FullText != "after" {
+		t.Errorf("Expected edited document to be re-extracted, got %+v", doc)
+	}
+	if doc, _ := handler.DB.GetDocumentByPath(unchanged); doc == nil || doc.FullText != "same" {
+		t.Errorf("Expected unchanged document to keep its text, got %+v", doc)
+	}
+}
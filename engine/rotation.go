@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// osdMinConfidence is the orientation confidence tesseract must report before a page is turned. Below it a
+// page with little text is about as likely to be turned the wrong way as the right one.
+const osdMinConfidence = 2.0
+
+// errNoOrientation is returned for tesseract OSD output without a rotation in it
+var errNoOrientation = errors.New("no orientation in tesseract output")
+
+// autoRotating reports whether scans are turned upright at ingestion. It needs AUTO_ROTATE and a local
+// tesseract, since the tesseract sidecar only OCRs.
+func (serverHandler *ServerHandler) autoRotating() bool {
+	cfg := serverHandler.ServerConfig
+	return cfg.AutoRotate && cfg.TesseractPath != "" && cfg.TesseractServiceURL == ""
+}
+
+// parseOSD reads the output of tesseract --psm 0: the clockwise turn that stands the page upright and
+// how confident tesseract is of it
+func parseOSD(output string) (degrees int, confidence float64, err error) {
+	found := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Rotate":
+			if degrees, err = strconv.Atoi(value); err != nil {
+				return 0, 0, fmt.Errorf("rotation %q: %w", value, err)
+			}
+			found = true
+		case "Orientation confidence":
+			if confidence, err = strconv.ParseFloat(value, 64); err != nil {
+				return 0, 0, fmt.Errorf("orientation confidence %q: %w", value, err)
+			}
+		}
+	}
+	if !found {
+		return 0, 0, errNoOrientation
+	}
+	if degrees%90 != 0 || degrees < 0 || degrees >= 360 {
+		return 0, 0, fmt.Errorf("rotation of %d degrees is not a quarter turn", degrees)
+	}
+	return degrees, confidence, nil
+}
+
+// detectOrientation asks tesseract which way up an image is and returns the clockwise turn, 90, 180 or
+// 270, that stands it upright. It returns 0 for an image that is upright or that tesseract is unsure of,
+// such as a page with too little text.
+func (serverHandler *ServerHandler) detectOrientation(imageName string) (int, error) {
+	inputName := imageName
+	if safeName := asciiName(filepath.Base(imageName)); safeName != filepath.Base(imageName) {
+		workDir, cleanup, err := serverHandler.newWorkDir("osd-*")
+		if err != nil {
+			return 0, err
+		}
+		defer cleanup()
+		inputName = filepath.Join(workDir, safeName) // tesseract builds include some that cannot open non-ASCII paths
+		if err := copyFile(imageName, inputName); err != nil {
+			return 0, err
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(serverHandler.ServerConfig.TesseractPath, inputName, "stdout", "--psm", "0")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("tesseract OSD failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	degrees, confidence, err := parseOSD(stdout.String())
+	if err != nil {
+		return 0, err
+	}
+	if confidence < osdMinConfidence {
+		Logger.Debug("Orientation too uncertain to turn page", "imageName", imageName, "rotate", degrees, "confidence", confidence)
+		return 0, nil
+	}
+	return degrees, nil
+}
+
+// rotateClockwise turns an image clockwise by a quarter turn multiple; imaging turns counter-clockwise
+func rotateClockwise(img image.Image, degrees int) image.Image {
+	switch degrees {
+	case 90:
+		return imaging.Rotate270(img)
+	case 180:
+		return imaging.Rotate180(img)
+	case 270:
+		return imaging.Rotate90(img)
+	}
+	return img
+}
+
+// documentRotations is the answer to GET /api/document/:id/rotation
+type documentRotations struct {
+	DocumentULID string                  `json:"documentId"`
+	Pages        []database.PageRotation `json:"pages"`
+}
+
+// GetDocumentRotations returns the pages of a document that were turned upright when it was ingested
+// @Summary Page rotations
+// @Description The pages of a scan that tesseract found sideways or upside down, with the degrees each was turned clockwise before the document was stored. Empty for a document that needed no turning or was ingested without AUTO_ROTATE.
+// @Tags Documents
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Success 200 {object} engine.documentRotations "Page rotations"
+// @Failure 400 {object} dto.ErrorResponse "Invalid ULID"
+// @Failure 404 {object} dto.ErrorResponse "Document not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /document/{id}/rotation [get]
+func (serverHandler *ServerHandler) GetDocumentRotations(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "document")
+	if !ok {
+		return err
+	}
+	doc, err := serverHandler.DB.GetDocumentByULID(id.String())
+	if err != nil || doc == nil || serverHandler.hiddenDocument(c, id.String()) {
+		return documentNotFound(c)
+	}
+	rotations, err := serverHandler.DB.GetDocumentRotations(doc.ULID.String())
+	if err != nil {
+		Logger.Error("Failed to get page rotations", "ulid", doc.ULID.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to get page rotations",
+			"code":  dto.CodeInternal,
+		})
+	}
+	return c.JSON(http.StatusOK, documentRotations{DocumentULID: doc.ULID.String(), Pages: rotations})
+}
@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/engine/pdfrenderer"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// osdOutput is what tesseract --psm 0 prints for a page that needs a quarter turn clockwise
+const osdOutput = `Page number: 0
+Orientation in degrees: 270
+Rotate: 90
+Orientation confidence: 6.21
+Script: Latin
+Script confidence: 2.08
+`
+
+// fakeOSDTesseract writes a tesseract that answers orientation detection with osd and OCRs every image
+// as "Rotated invoice"
+func fakeOSDTesseract(t *testing.T, osd string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Fake tesseract is a shell script")
+	}
+	tesseract := filepath.Join(t.TempDir(), "tesseract")
+	script := "#!/bin/sh\nif [ \"$2\" = stdout ]; then\ncat <<'EOF'\n" + osd + "EOF\nelse\necho 'Rotated invoice' > \"$2.txt\"\nfi\n"
+	if err := os.WriteFile(tesseract, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return tesseract
+}
+
+func TestParseOSD(t *testing.T) {
+	t.Run("Rotation and confidence", func(t *testing.T) {
+		if degrees, confidence, err := parseOSD(osdOutput); err != nil || degrees != 90 || confidence != 6.21 {
+			t.Errorf("Expected 90 degrees at 6.21, got %d at %v: %v", degrees, confidence, err)
+		}
+	})
+	t.Run("Too little text", func(t *testing.T) {
+		if _, _, err := parseOSD("Too few characters. Skipping this page\n"); err != errNoOrientation {
+			t.Errorf("Expected errNoOrientation, got %v", err)
+		}
+	})
+	t.Run("Not a quarter turn", func(t *testing.T) {
+		if _, _, err := parseOSD("Rotate: 45\n"); err == nil {
+			t.Error("Expected an error for a rotation that is not a quarter turn")
+		}
+	})
+}
+
+func TestIngestTurnsImageUpright(t *testing.T) {
+	// AUTO_ROTATE on, and a wide scan in ingress whose top left pixel is red
+	handler := setupTestHandler(t)
+	handler.ServerConfig.TesseractPath = fakeOSDTesseract(t, osdOutput)
+	handler.ServerConfig.AutoRotate = true
+	handler.Echo.GET("/api/document/:id/rotation", handler.GetDocumentRotations)
+	scan := imaging.New(40, 20, color.White)
+	scan.Set(0, 0, color.RGBA{R: 255, A: 255})
+	path := filepath.Join(handler.ServerConfig.IngressPath, "invoice.png")
+	if err := imaging.Save(scan, path); err != nil {
+		t.Fatal(err)
+	}
+	job, err := handler.DB.CreateJob(database.JobTypeIngestion, "test")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	handler.ingressJobFuncWithTracking(handler.ServerConfig, handler.DB, job.ID)
+
+	// The stored image was turned a quarter clockwise, so the red pixel is now top right
+	doc, err := handler.DB.GetDocumentByPath(filepath.ToSlash(filepath.Join(handler.ServerConfig.DocumentPath, "invoice.png")))
+	if err != nil || doc == nil {
+		t.Fatalf("Expected the document to be stored: %v", err)
+	}
+	stored, err := imaging.Open(doc.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := stored.Bounds(); bounds.Dx() != 20 || bounds.Dy() != 40 {
+		t.Fatalf("Expected a 20x40 image, got %v", bounds)
+	}
+	if r, _, _, _ := stored.At(19, 0).RGBA(); r>>8 != 255 {
+		t.Errorf("Expected the red pixel at the top right")
+	}
+	// The hash is of the turned file, and the rotation is recorded and served
+	if hash, err := calculateFileHash(doc.Path); err != nil || !hash.Matches(doc.Hash) {
+		t.Errorf("Expected the stored hash to be of the rotated file: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/document/"+doc.ULID.String()+"/rotation", nil))
+	var response documentRotations
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with JSON, got %d %s", rec.Code, rec.Body)
+	}
+	if !slices.Equal(response.Pages, []database.PageRotation{{Page: 1, Degrees: 90}}) {
+		t.Errorf("Expected page 1 turned 90 degrees, got %+v", response.Pages)
+	}
+}
+
+func TestPrepareScanLeavesScansAlone(t *testing.T) {
+	// A sideways scan
+	handler := setupTestHandler(t)
+	handler.ServerConfig.TesseractPath = fakeOSDTesseract(t, osdOutput)
+	path := filepath.Join(handler.ServerConfig.IngressPath, "receipt.png")
+	if err := imaging.Save(imaging.New(40, 20, color.White), path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is turned with AUTO_ROTATE off
+	timeline := startTimeline("test")
+	if handler.prepareScan(path, timeline) || len(timeline.rotations) != 0 {
+		t.Error("Expected no rotation with AUTO_ROTATE off")
+	}
+
+	// Or when tesseract is unsure which way up the page is
+	handler.ServerConfig.AutoRotate = true
+	handler.ServerConfig.TesseractPath = fakeOSDTesseract(t, "Rotate: 180\nOrientation confidence: 0.4\n")
+	if handler.prepareScan(path, timeline) {
+		t.Error("Expected no rotation at low confidence")
+	}
+	if img, err := imaging.Open(path); err != nil || img.Bounds().Dx() != 40 {
+		t.Errorf("Expected the scan to be unchanged: %v", err)
+	}
+}
+
+func TestPrepareScanRotatesPDFPages(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping PDFium rendering test in short mode")
+	}
+	// A scanned A4 page, a PDF with a drawing but no text layer
+	handler := setupTestHandler(t)
+	handler.ServerConfig.TesseractPath = fakeOSDTesseract(t, osdOutput)
+	handler.ServerConfig.AutoRotate = true
+	path := filepath.Join(handler.ServerConfig.IngressPath, "scan.pdf")
+	out := gofpdf.New("P", "pt", "A4", "")
+	out.AddPage()
+	out.Rect(72, 72, 200, 100, "F")
+	if err := out.OutputFileAndClose(path); err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+
+	// It is turned upright
+	timeline := startTimeline("test")
+	turned := handler.prepareScan(path, timeline)
+
+	// The page renders landscape and the turn is kept for the document
+	if !turned || !slices.Equal(timeline.rotations, []database.PageRotation{{Page: 1, Degrees: 90}}) {
+		t.Fatalf("Expected page 1 turned 90 degrees, got %v", timeline.rotations)
+	}
+	renderer, err := pdfrenderer.NewRenderer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer renderer.Close()
+	var size image.Rectangle
+	if _, err := renderer.RenderPages(path, 0, func(_ int, page image.Image) error {
+		size = page.Bounds()
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to render the rotated PDF: %v", err)
+	}
+	if size.Dx() <= size.Dy() {
+		t.Errorf("Expected a landscape page after the turn, got %v", size)
+	}
+}
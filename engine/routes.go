@@ -1,19 +1,24 @@
 package engine
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/drummonds/godocs/cache"
 	"github.com/drummonds/godocs/config"
 	"github.com/drummonds/godocs/database"
 	"github.com/drummonds/godocs/internal/build"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/drummonds/godocs/storage"
 	"github.com/labstack/echo/v4"
 )
 
@@ -22,6 +27,20 @@ type ServerHandler struct {
 	DB           database.Repository
 	Echo         *echo.Echo
 	ServerConfig config.ServerConfig
+	Cache        cache.Cache     // optional, nil disables response caching
+	Storage      storage.Backend // where document files are kept, nil for the local DocumentPath
+
+	lastChangeScan time.Time          // when the changed file detector last ran
+	wordCounts     wordCounter        // word cloud counts from single-document ingestion waiting to be written
+	schedules      jobScheduler       // scheduled jobs, not running until InitializeSchedules
+	vocabulary     searchVocabulary   // word cloud words for search suggestions and autocomplete
+	quotaWarnings  quotaWarnings      // FOLDER_QUOTAS warnings already sent
+	lanes          processingLanes    // processing slots shared by uploads and ingestion jobs
+	jobStarts      sync.Mutex         // held by startJob between checking for an active job and creating one
+	updates        updateChecker      // the last release check, when UPDATE_CHECK is on
+	missingFiles   missingFileTracker // documents already handed to cleanup as missing
+	clientErrors   clientErrorLimiter // error reports accepted from the web UI in the current minute
+	isWorker       bool               // set by RunWorker, which reports document changes through the database
 }
 
 /* type Node struct {
@@ -38,34 +57,19 @@ type ServerHandler struct {
 	Parent       *Node   `json:"-"`
 } */
 
-type fullFileSystem struct {
-	FileSystem []fileTreeStruct `json:"fileSystem"`
-	Error      string           `json:"error"`
-}
-
-type fileTreeStruct struct {
-	ID          string   `json:"id"`
-	ULIDStr     string   `json:"ulid"`
-	Name        string   `json:"name"`
-	Size        int64    `json:"size"`
-	ModDate     string   `json:"modDate"`
-	Openable    bool     `json:"openable"`
-	ParentID    string   `json:"parentID"`
-	IsDir       bool     `json:"isDir"`
-	ChildrenIDs []string `json:"childrenIDs"`
-	FullPath    string   `json:"fullPath"`
-	FileURL     string   `json:"fileURL"`
-}
-
-// AddDocumentViewRoutes adds all of the current documents to an echo route
+// AddDocumentViewRoutes registers the document view handler and repairs stored URLs left over from older versions.
+// Files are looked up per request, so documents added, moved or renamed later need no route of their own.
 func (serverHandler *ServerHandler) AddDocumentViewRoutes() error {
-	documents, err := database.FetchAllDocuments(serverHandler.DB)
+	serverHandler.Echo.GET(documentViewPrefix+":id", serverHandler.ViewDocument)
+	serverHandler.Echo.GET(documentViewPrefix+":id/*", serverHandler.ViewDocument)
+
+	repaired, err := repairDocumentURLs(serverHandler.DB, nil)
 	if err != nil {
+		Logger.Error("Unable to repair document URLs", "repaired", repaired, "error", err)
 		return err
 	}
-	for _, document := range *documents {
-		documentURL := "/document/view/" + document.ULID.String()
-		serverHandler.Echo.File(documentURL, document.Path)
+	if repaired > 0 {
+		Logger.Info("Repaired document URLs", "count", repaired)
 	}
 	return nil
 }
@@ -78,14 +82,25 @@ func (serverHandler *ServerHandler) AddDocumentViewRoutes() error {
 // @Produce json
 // @Param id query string false "Document ULID"
 // @Param path query string false "File path relative to document root"
+// @Param X-Lock-Holder header string false "Lock holder, needed to delete a locked document"
 // @Success 200 {string} string "Document Deleted" or "Folder Deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid document ULID"
+// @Failure 403 {object} map[string]interface{} "No write access to the folder, or to one under it"
 // @Failure 404 {object} map[string]interface{} "File not found"
+// @Failure 423 {object} map[string]interface{} "The document, or one in the folder, is locked by another holder or under legal hold"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /document [delete]
 func (serverHandler *ServerHandler) DeleteFile(context echo.Context) error {
 	var err error
 	params := context.QueryParams()
 	ulidStr := params.Get("id")
+	if ulidStr != "" {
+		id, err := parseULID(ulidStr)
+		if err != nil {
+			return invalidID(context, "document")
+		}
+		ulidStr = id.String()
+	}
 	path := params.Get("path")
 	path = filepath.Join(serverHandler.ServerConfig.DocumentPath, path)
 	path, err = filepath.Abs(path)
@@ -102,12 +117,49 @@ func (serverHandler *ServerHandler) DeleteFile(context echo.Context) error {
 		Logger.Error("Unable to get information for file", "path", path, "error", err)
 		return context.JSON(http.StatusNotFound, err)
 	}
+	access, err := serverHandler.folderAccess(context)
+	if err != nil {
+		return err
+	}
+	if fileInfo.IsDir() && !access.canWriteAll(path) {
+		return folderForbidden(context, path)
+	}
+	if !fileInfo.IsDir() && !access.canWrite(filepath.Dir(path)) {
+		return folderForbidden(context, filepath.Dir(path))
+	}
+	holder := lockHolder(context)
+	holds, err := serverHandler.legalHolds()
+	if err != nil {
+		Logger.Error("Unable to check legal holds", "path", path, "error", err)
+		return context.JSON(http.StatusInternalServerError, err)
+	}
 	if fileInfo.IsDir() { //If a directory, just delete it and all children
+		if hold := holds.folder(path); hold != nil {
+			return legalHoldRefused(context, hold)
+		}
+		lock, err := serverHandler.lockedInFolder(holder, path)
+		if err != nil {
+			Logger.Error("Unable to check document locks in folder", "path", path, "error", err)
+			return context.JSON(http.StatusInternalServerError, err)
+		}
+		if lock != nil {
+			return documentLocked(context, lock)
+		}
 		err = DeleteFile(path)
 		if err != nil {
 			Logger.Error("Unable to delete folder from document filesystem", "path", path, "error", err)
 			return context.JSON(http.StatusInternalServerError, err)
 		}
+		if _, err := serverHandler.DB.DeleteFolderTree(folderKey(path)); err != nil {
+			Logger.Warn("Unable to remove folder from folder table", "path", path, "error", err)
+		}
+		serverHandler.recordActivity(database.AuditEvent{
+			Action: database.AuditDocumentDeleted,
+			Name:   "folder " + folderKey(path),
+			Detail: path,
+			User:   requestUser(context),
+		})
+		serverHandler.invalidateDocumentCache()
 		return context.JSON(http.StatusOK, "Folder Deleted")
 	}
 	document, _, err := database.FetchDocument(ulidStr, serverHandler.DB)
@@ -115,6 +167,17 @@ func (serverHandler *ServerHandler) DeleteFile(context echo.Context) error {
 		Logger.Error("Unable to delete folder from document filesystem", "path", path, "error", err)
 		return context.JSON(http.StatusNotFound, err)
 	}
+	if hold := holds.document(&document); hold != nil {
+		return legalHoldRefused(context, hold)
+	}
+	lock, err := serverHandler.lockedAgainst(holder, ulidStr)
+	if err != nil {
+		Logger.Error("Unable to check document lock", "ulid", ulidStr, "error", err)
+		return context.JSON(http.StatusInternalServerError, err)
+	}
+	if lock != nil {
+		return documentLocked(context, lock)
+	}
 	err = database.DeleteDocument(ulidStr, serverHandler.DB)
 	if err != nil {
 		Logger.Error("Unable to delete document from database", "name", document.Name, "error", err)
@@ -126,6 +189,16 @@ func (serverHandler *ServerHandler) DeleteFile(context echo.Context) error {
 		return context.JSON(http.StatusNotFound, err)
 	}
 	// PostgreSQL full-text search index is automatically updated via trigger when document is deleted
+	serverHandler.DB.ReleaseDocumentLock(ulidStr, holder) // the holder's lock, if any, goes with the document
+	serverHandler.discardArchive(ulidStr)
+	serverHandler.recordActivity(database.AuditEvent{
+		Action: database.AuditDocumentDeleted,
+		Target: ulidStr,
+		Name:   document.Name,
+		Detail: document.Folder, // the document is gone, so the feed checks its folder from here
+		User:   requestUser(context),
+	})
+	serverHandler.invalidateDocumentCache()
 	return context.JSON(http.StatusOK, "Document Deleted")
 }
 
@@ -136,9 +209,14 @@ func (serverHandler *ServerHandler) DeleteFile(context echo.Context) error {
 // @Accept multipart/form-data
 // @Produce json
 // @Param path formData string false "Upload path (relative to ingress folder)"
+// @Param folder formData string false "Destination folder relative to the document root; the document is stored there directly, bypassing ingress"
 // @Param file formData file true "Document file to upload"
 // @Success 200 {string} string "Path to uploaded file"
 // @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 403 {object} map[string]interface{} "No write access to the folder the document would be stored in"
+// @Failure 409 {object} map[string]interface{} "Duplicate document or file name already in the folder"
+// @Failure 415 {object} map[string]interface{} "File type not in PROCESSABLE_EXTENSIONS"
+// @Failure 422 {object} map[string]interface{} "A pre-ingestion transform failed on the file, which was quarantined"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /document/upload [post]
 func (serverHandler *ServerHandler) UploadDocuments(context echo.Context) error {
@@ -150,8 +228,25 @@ func (serverHandler *ServerHandler) UploadDocuments(context echo.Context) error
 		return err
 	}
 	defer file.Close()
+	fileName := clientFileName(fileHeader.Filename)
+	if err := serverHandler.checkIngestible(fileName); err != nil {
+		return context.JSON(http.StatusUnsupportedMediaType, map[string]interface{}{"error": err.Error(), "code": dto.CodeUnsupportedType})
+	}
+	if folder := request.FormValue("folder"); folder != "" {
+		return serverHandler.uploadToFolder(context, normalisePath(folder), fileName, file)
+	}
 	//Upload it to the ingress folder so if there is an issue it will stick there and not in the documents folder which will cause issues.
-	path := filepath.ToSlash(serverHandler.ServerConfig.IngressPath + "/" + uploadPath + fileHeader.Filename)
+	path, err := ingressUploadPath(serverHandler.ServerConfig.IngressPath, uploadPath, fileName)
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error(), "code": dto.CodeBadRequest})
+	}
+	access, err := serverHandler.folderAccess(context)
+	if err != nil {
+		return err
+	}
+	if destination := filepath.Dir(serverHandler.ingressDestination(path)); !access.canWrite(destination) {
+		return folderForbidden(context, destination)
+	}
 	_, err = os.Stat(filepath.Dir(path)) //since this is the ingress folder we MAY need to create the directory path.
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -162,15 +257,30 @@ func (serverHandler *ServerHandler) UploadDocuments(context echo.Context) error
 			}
 		}
 	}
+	if err := serverHandler.checkQuota(serverHandler.ingressDestination(path), fileHeader.Size); err != nil {
+		if errors.Is(err, errQuotaExceeded) {
+			return context.JSON(http.StatusInsufficientStorage, map[string]interface{}{"error": err.Error(), "code": dto.CodeQuotaExceeded})
+		}
+		Logger.Error("Unable to check storage quota", "path", path, "error", err)
+		return err
+	}
 	Logger.Debug("Creating path for file upload to ingress", "dir", filepath.Dir(path))
-	body, err := io.ReadAll(file) //get the file, write it to the filesystem
-	err = os.WriteFile(path, body, 0644)
-	if err != nil {
+	// Stream the upload to disk so large files never have to fit in memory
+	if _, err := writeFileHashed(path, file, 0644); err != nil {
 		Logger.Error("Unable to write uploaded file", "path", path, "error", err)
 		return err
 	}
+	if path, err = serverHandler.preIngest(path); err != nil {
+		return transformRefused(context, err)
+	}
+	// Uploads take the interactive lane, so they are processed ahead of any scheduled batch
+	release, err := serverHandler.processingSlot(request.Context(), priorityInteractive)
+	if err != nil {
+		return err // the client went away; the file stays in ingress for the next ingestion run
+	}
 	serverHandler.ingressDocument(path, "upload") //ingress the document into the database
-	return context.JSON(http.StatusOK, path)
+	release()
+	return context.JSON(http.StatusOK, filepath.ToSlash(path))
 }
 
 // MoveDocuments will accept an API call from the frontend to move a document or documents
@@ -181,8 +291,11 @@ func (serverHandler *ServerHandler) UploadDocuments(context echo.Context) error
 // @Produce json
 // @Param folder query string true "Target folder path"
 // @Param id query []string true "Document ULID(s) to move"
+// @Param X-Lock-Holder header string false "Lock holder, needed to move a locked document"
 // @Success 200 {string} string "Ok"
 // @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 403 {object} map[string]interface{} "No write access to the target folder or a document's folder"
+// @Failure 423 {object} map[string]interface{} "A document is locked by another holder"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /document/move [patch]
 func (serverHandler *ServerHandler) MoveDocuments(context echo.Context) error {
@@ -191,8 +304,32 @@ func (serverHandler *ServerHandler) MoveDocuments(context echo.Context) error {
 	docIDs = context.QueryParams()
 	newFolder = docIDs.Get("folder")
 	fmt.Println("newfolder: ", newFolder)
-	fmt.Println("ID's: ", docIDs["id"])
-	for _, docID := range docIDs["id"] { //fetching all the needed documents
+	ids, ok, err := ulidQuery(context, "id", "document")
+	if !ok {
+		return err
+	}
+	fmt.Println("ID's: ", ids)
+	access, err := serverHandler.folderAccess(context)
+	if err != nil {
+		return err
+	}
+	if !access.canWrite(newFolder) {
+		return folderForbidden(context, newFolder)
+	}
+	for _, docID := range ids {
+		if document, err := serverHandler.DB.GetDocumentByULID(docID); err == nil && document != nil && !access.canWrite(document.Folder) {
+			return folderForbidden(context, document.Folder)
+		}
+	}
+	lock, err := serverHandler.lockedAgainst(lockHolder(context), ids...)
+	if err != nil {
+		Logger.Error("Unable to check document locks (MoveDocuments)", "error", err)
+		return context.JSON(http.StatusInternalServerError, err)
+	}
+	if lock != nil {
+		return documentLocked(context, lock)
+	}
+	for _, docID := range ids { //fetching all the needed documents
 		//document, httpStatus, err := database.FetchDocument(docID, serverHandler.DB)
 		//if err != nil {
 		//	Logger.Error("GetDocument API call failed (MoveDocuments)", "error", err)
@@ -204,19 +341,34 @@ func (serverHandler *ServerHandler) MoveDocuments(context echo.Context) error {
 			Logger.Error("GetDocument API call failed (MoveDocuments)", "error", err)
 			return context.JSON(httpStatus, err)
 		}
+		name := docID
+		if document, err := serverHandler.DB.GetDocumentByULID(docID); err == nil && document != nil {
+			name = document.Name
+		}
+		serverHandler.recordActivity(database.AuditEvent{
+			Action: database.AuditDocumentMoved,
+			Target: docID,
+			Name:   name,
+			Detail: newFolder,
+			User:   requestUser(context),
+		})
 	}
+	serverHandler.recordFolder(newFolder)
+	serverHandler.invalidateDocumentCache()
 	return context.JSON(http.StatusOK, "Ok")
 }
 
 // SearchDocuments will take the search terms and search all documents using PostgreSQL full-text search
 // @Summary Search documents
-// @Description Search all documents using PostgreSQL full-text search
+// @Description Search all documents using PostgreSQL full-text search.
+// @Description A search that finds nothing answers 200 with an empty fileSystem and "did you mean" suggestions when there are any.
 // @Tags Search
 // @Accept json
 // @Produce json
 // @Param term query string true "Search term"
-// @Success 200 {object} fullFileSystem "Search results"
-// @Success 204 "No results found"
+// @Param format query string false "Set to csv to download results as CSV (name, folder, date, size, tags, url)"
+// @Success 200 {object} dto.FileSystem "Search results"
+// @Success 204 "No results found and no spelling suggestions"
 // @Failure 404 {string} string "Empty search term"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /search [get]
@@ -228,28 +380,38 @@ func (serverHandler *ServerHandler) SearchDocuments(context echo.Context) error
 	}
 
 	Logger.Debug("Performing PostgreSQL full-text search", "searchTerm", searchTerm)
+	started := time.Now()
 	documents, err := serverHandler.DB.SearchDocuments(searchTerm)
 	if err != nil {
 		Logger.Error("Search failed", "error", err)
 		return context.JSON(http.StatusInternalServerError, err)
 	}
+	access, err := serverHandler.folderAccess(context)
+	if err != nil {
+		return err
+	}
+	documents = access.readable(documents)
+	serverHandler.recordSearch(context, searchTerm, len(documents), time.Since(started))
+
+	if wantsCSV(context) {
+		return serverHandler.writeDocumentsCSV(context, "search.csv", documents)
+	}
 
 	if len(documents) == 0 {
 		Logger.Info("Search returned no results", "searchTerm", searchTerm)
+		if suggestions := serverHandler.searchSuggestions(searchTerm); len(suggestions) > 0 {
+			return context.JSON(http.StatusOK, dto.FileSystem{FileSystem: []dto.FileTreeNode{}, Suggestions: suggestions})
+		}
 		return context.JSON(http.StatusNoContent, nil)
 	}
 
-	fullResults, err := convertDocumentsToFileTree(documents)
-	if err != nil {
-		Logger.Error("Unable to convert search results to file tree", "error", err)
-		return context.JSON(http.StatusNotFound, err)
-	}
-
-	// Wrap the results in fullFileSystem struct to match frontend expectations
-	response := fullFileSystem{
-		FileSystem: *fullResults,
+	// Wrap the results in dto.FileSystem struct to match frontend expectations
+	response := dto.FileSystem{
+		FileSystem: convertDocumentsToFileTree(documents),
 		Error:      "",
 	}
+	serverHandler.markArchived(response.FileSystem)
+	serverHandler.reportMissingFiles(response.FileSystem)
 	return context.JSON(http.StatusOK, response)
 }
 
@@ -270,6 +432,7 @@ func (serverHandler *ServerHandler) ReindexSearchDocuments(context echo.Context)
 		Logger.Error("Reindex failed", "error", err)
 		return context.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error":   "Reindex failed",
+			"code":    dto.CodeInternal,
 			"message": err.Error(),
 		})
 	}
@@ -283,60 +446,105 @@ func (serverHandler *ServerHandler) ReindexSearchDocuments(context echo.Context)
 
 // GetDocument will return a document by ULID
 // @Summary Get a document by ID
-// @Description Retrieve document details by ULID
+// @Description Retrieve document details by ULID. FullText is empty unless fullText=true.
 // @Tags Documents
 // @Accept json
 // @Produce json
 // @Param id path string true "Document ULID"
+// @Param fullText query bool false "Include extracted full text (default: false, see /document/{id}/text)"
 // @Success 200 {object} database.Document "Document details"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
 // @Failure 404 {object} map[string]interface{} "Document not found"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /document/{id} [get]
 func (serverHandler *ServerHandler) GetDocument(context echo.Context) error {
-	ulidStr := context.Param("id")
-	document, httpStatus, err := database.FetchDocument(ulidStr, serverHandler.DB)
+	includeFullText, err := fullTextParam(context)
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid fullText value, expected true or false",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	id, ok, err := ulidParam(context, "id", "document")
+	if !ok {
+		return err
+	}
+	document, httpStatus, err := database.FetchDocument(id.String(), serverHandler.DB)
 	if err != nil {
 		Logger.Error("GetDocument API call failed", "error", err)
 		return context.JSON(httpStatus, err)
 	}
+	if access, err := serverHandler.folderAccess(context); err != nil || !access.canRead(document.Folder) {
+		return context.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if !includeFullText {
+		document.FullText = ""
+	}
 	return context.JSON(httpStatus, document)
 
 }
 
 // GetDocumentFileSystem will scan the document folder and get the complete tree to send to the frontend
 // @Summary Get document filesystem tree
-// @Description Retrieve the complete document folder structure as a tree
+// @Description Retrieve the complete document folder structure as a tree. Within each folder, subfolders come before documents
+// @Description and names are in natural order (file2 before file10) by the collation of SORT_LOCALE.
+// @Description Accounts that are not administrators only see the folders their folder permissions allow.
 // @Tags Documents
 // @Accept json
 // @Produce json
-// @Success 200 {object} fullFileSystem "Complete filesystem tree"
+// @Success 200 {object} dto.FileSystem "Complete filesystem tree"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /documents/filesystem [get]
 func (serverHandler *ServerHandler) GetDocumentFileSystem(context echo.Context) error {
-	fileSystem, err := fileTree(serverHandler.ServerConfig.DocumentPath, serverHandler.DB)
+	access, err := serverHandler.folderAccess(context)
 	if err != nil {
 		return err
 	}
+	if access != nil {
+		// Trees filtered for one account are not cached
+		fileSystem, err := fileTree(serverHandler.ServerConfig.DocumentPath, serverHandler.DB, serverHandler.ServerConfig.SortLocale)
+		if err != nil {
+			return err
+		}
+		access.filterTree(fileSystem)
+		serverHandler.markArchived(fileSystem.FileSystem)
+		serverHandler.reportMissingFiles(fileSystem.FileSystem)
+		return context.JSON(http.StatusOK, fileSystem)
+	}
+	if body, ok := serverHandler.cachedResponse(context, cache.KeyFileSystem); ok {
+		return context.JSONBlob(http.StatusOK, body)
+	}
+	fileSystem, err := fileTree(serverHandler.ServerConfig.DocumentPath, serverHandler.DB, serverHandler.ServerConfig.SortLocale)
+	if err != nil {
+		return err
+	}
+	serverHandler.markArchived(fileSystem.FileSystem)
+	serverHandler.reportMissingFiles(fileSystem.FileSystem)
 	//fileSystem := fileSystem{FolderTree: *folderTree, FileTree: *documents}
-	return context.JSON(http.StatusOK, fileSystem)
+	return serverHandler.cacheJSON(context, cache.KeyFileSystem, fileSystem)
 
 }
 
-func convertDocumentsToFileTree(documents []database.Document) (fullFileTree *[]fileTreeStruct, err error) {
-	var fileTree []fileTreeStruct
-	var currentFile fileTreeStruct
+// convertDocumentsToFileTree lists search results under a "Search Results" root. Size and page count
+// come from the record; a document whose file is missing is kept and marked missing.
+func convertDocumentsToFileTree(documents []database.Document) []dto.FileTreeNode {
+	var fileTree []dto.FileTreeNode
+	var currentFile dto.FileTreeNode
 	for _, document := range documents {
-		documentInfo, err := os.Stat(document.Path)
-		if err != nil {
-			return nil, err
-		}
 		currentFile.ID = document.ULID.String()
-		currentFile.ULIDStr = currentFile.ID
-		currentFile.Size = documentInfo.Size()
+		currentFile.ULID = currentFile.ID
+		currentFile.MIMEType = document.MIMEType
+		currentFile.Size = document.Size
+		currentFile.PageCount = document.PageCount
+		currentFile.OCRStatus = document.OCRStatus
 		currentFile.Name = document.Name
 		currentFile.Openable = true
-		currentFile.ModDate = documentInfo.ModTime().String()
+		currentFile.ModDate = document.IngressTime.String()
 		currentFile.IsDir = false
+		currentFile.Missing = fileMissing(document.Path)
 		currentFile.FullPath = document.Path
 		currentFile.FileURL = document.URL
 		currentFile.ParentID = "SearchResults"
@@ -349,111 +557,169 @@ func convertDocumentsToFileTree(documents []database.Document) (fullFileTree *[]
 		}
 		return ids
 	}
-	rootDir := fileTreeStruct{ //creating a fake root directory to display results in
+	rootDir := dto.FileTreeNode{ //creating a fake root directory to display results in
 		ID:          "SearchResults",
 		Size:        0,
 		Name:        "Search Results",
 		Openable:    true,
-		ModDate:     time.Now().String(),
+		ModDate:     database.Now().String(),
 		IsDir:       true,
 		FullPath:    "null",
 		ChildrenIDs: childrenIDs(),
 	}
-	fileTree = append([]fileTreeStruct{rootDir}, fileTree...)
-	return &fileTree, nil
+	fileTree = append([]dto.FileTreeNode{rootDir}, fileTree...)
+	return fileTree
 }
 
-func fileTree(rootPath string, db database.Repository) (fileTree *fullFileSystem, err error) {
-	absRoot, err := filepath.Abs(rootPath)
+// fileTree builds the browse tree from the folder table and document records. Folders come first with
+// the root at the top, then documents, each in natural name order for locale within their folder.
+func fileTree(rootPath string, db database.Repository, locale string) (fileTree *dto.FileSystem, err error) {
+	root := folderKey(rootPath)
+	if _, err := syncFolders(db, root, false); err != nil {
+		return nil, err
+	}
+	folders, err := rootFolders(db, root)
 	if err != nil {
 		return nil, err
 	}
-	var fullFileTree fullFileSystem
-	var currentFile fileTreeStruct
+	order := newNameOrder(locale)
+	sort.SliceStable(folders, func(i, j int) bool {
+		return order.comparePaths(folders[i].Path, folders[j].Path) < 0
+	})
 
-	walkFunc := func(path string, info os.FileInfo, err error) error {
-		newTime := time.Now()
-		if err != nil {
-			return err
+	var fullFileTree dto.FileSystem
+	folderIndex := make(map[string]int, len(folders)) // folder key -> position in the tree
+	positionByID := make(map[int]int, len(folders))
+	for _, folder := range folders {
+		node := dto.FileTreeNode{
+			ID:       "folder-" + strconv.Itoa(folder.ID),
+			Name:     folder.Name,
+			Openable: true,
+			IsDir:    true,
+			FullPath: filepath.FromSlash(folder.Path),
+			Color:    folder.Color,
+			Icon:     folder.Icon,
 		}
-		// Reset currentFile struct for each iteration to avoid data pollution
-		currentFile = fileTreeStruct{}
-		currentFile.Name = info.Name()
-		currentFile.FullPath = path
-
-		for _, fileElement := range fullFileTree.FileSystem { //Find the parentID
-			if fileElement.FullPath == filepath.Dir(path) {
-				currentFile.ParentID = fileElement.ID
-			}
+		if parent, ok := positionByID[folder.ParentID]; ok && folder.Path != root {
+			node.ParentID = fullFileTree.FileSystem[parent].ID
+			fullFileTree.FileSystem[parent].ChildrenIDs = append(fullFileTree.FileSystem[parent].ChildrenIDs, folder.Name)
 		}
+		folderIndex[folder.Path] = len(fullFileTree.FileSystem)
+		positionByID[folder.ID] = len(fullFileTree.FileSystem)
+		fullFileTree.FileSystem = append(fullFileTree.FileSystem, node)
+	}
 
-		if info.IsDir() {
-			ULID, err := database.CalculateUUID(newTime)
-			//fmt.Println("New ULID for: ", path, ULID.String())
-			if err != nil {
-				return err
-			}
-			currentFile.ID = ULID.String() + filepath.Base(path) //TODO, should I store the entire filesystem layout?  Most likely yes?
-			currentFile.IsDir = true
-			currentFile.Openable = true
-			childIDs, err := getChildrenIDs(path)
-			if err != nil {
-				return err
-			}
-			currentFile.ChildrenIDs = *childIDs
-			/* 			if path == rootPath {
-				fullFileTree = append(fullFileTree, currentFile)
-				return nil
-			} */
-		} else { //for files process size, moddate, ulid
-			currentFile.Size = info.Size()
-			currentFile.Openable = true
-			currentFile.IsDir = false
-			currentFile.ModDate = info.ModTime().String()
-
-			document, err := database.FetchDocumentFromPath(path, db)
-			if err != nil {
-				fullFileTree.Error = fmt.Sprintf("Document found in directory without database entry, please investigate: %s", path)
-			}
-			currentFile.FileURL = document.URL
-			currentFile.ID = document.ULID.String()
-			currentFile.ULIDStr = document.ULID.String()
-		}
+	documents, err := listDocumentsByName(db)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(documents, func(i, j int) bool {
+		return order.compare(documents[i].Name, documents[j].Name) < 0
+	})
 
+	for _, document := range documents {
+		parent, ok := folderIndex[folderKey(document.Folder)]
+		if !ok {
+			continue
+		}
+		currentFile := documentTreeNode(document, fullFileTree.FileSystem[parent].ID)
+		fullFileTree.FileSystem[parent].ChildrenIDs = append(fullFileTree.FileSystem[parent].ChildrenIDs, document.Name)
 		fullFileTree.FileSystem = append(fullFileTree.FileSystem, currentFile)
-		return nil
 	}
-	err = filepath.Walk(absRoot, walkFunc)
-	if err != nil {
-		return nil, err
+	if len(fullFileTree.FileSystem) > 0 {
+		if err := appendSmartFolders(&fullFileTree, db, documents); err != nil {
+			return nil, err
+		}
 	}
+	directoriesFirst(&fullFileTree)
 	return &fullFileTree, nil
 }
 
-func getChildrenIDs(rootPath string) (*[]string, error) {
-	results, err := os.ReadDir(rootPath)
-	if err != nil {
-		return nil, err
+// directoriesFirst moves every folder, smart folders included, ahead of the documents in the tree and
+// in each folder's ChildrenIDs, keeping the order within each group
+func directoriesFirst(tree *dto.FileSystem) {
+	nodes := tree.FileSystem
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].IsDir && !nodes[j].IsDir })
+	position := make(map[string]int, len(nodes))
+	for i := range nodes {
+		position[nodes[i].ID] = i
+		nodes[i].ChildrenIDs = nil
+	}
+	for i := range nodes {
+		if parent, ok := position[nodes[i].ParentID]; ok && parent != i {
+			nodes[parent].ChildrenIDs = append(nodes[parent].ChildrenIDs, nodes[i].Name)
+		}
+	}
+}
+
+// documentTreeNode is the tree node of a document, with its size and date from the file. When the file
+// cannot be read they come from the record, and a file gone from document storage is marked missing.
+func documentTreeNode(document database.Document, parentID string) dto.FileTreeNode {
+	node := dto.FileTreeNode{
+		ID:        document.ULID.String(),
+		ULID:      document.ULID.String(),
+		MIMEType:  document.MIMEType,
+		Name:      document.Name,
+		PageCount: document.PageCount,
+		OCRStatus: document.OCRStatus,
+		Openable:  true,
+		ParentID:  parentID,
+		FullPath:  filepath.FromSlash(document.Path),
+		FileURL:   document.URL,
+		Size:      document.Size,
+		ModDate:   document.IngressTime.String(),
 	}
-	var childIDs []string
-	for _, result := range results {
-		childIDs = append(childIDs, result.Name())
+	info, err := os.Stat(document.Path)
+	if err != nil {
+		node.Missing = errors.Is(err, os.ErrNotExist)
+		return node
 	}
-	return &childIDs, nil
+	node.Size = info.Size()
+	node.ModDate = info.ModTime().String()
+	return node
+}
 
+// listDocumentsByName returns every document without its text, sorted by name. It pages through
+// documents by keyset so the full text is never loaded.
+func listDocumentsByName(db database.Repository) ([]database.Document, error) {
+	var documents []database.Document
+	var cursor *database.DocumentCursor
+	for {
+		page, err := db.GetNewestDocumentsAfter(cursor, maxCursorPageSize)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, page...)
+		if len(page) < maxCursorPageSize {
+			break
+		}
+		cursor = database.CursorAfter(page[len(page)-1])
+	}
+	sort.SliceStable(documents, func(i, j int) bool { return documents[i].Name < documents[j].Name })
+	return documents, nil
 }
 
 // GetLatestDocuments gets the latest documents that were ingressed
 // @Summary Get latest documents
-// @Description Retrieve the most recently ingested documents with pagination
+// @Description Retrieve the most recently ingested documents with pagination.
+// @Description Page mode (page=N) is what the UI uses. Passing cursor (empty for the first page) switches to keyset pagination,
+// @Description which stays fast for deep scans: follow nextCursor until hasNext is false.
+// @Description Accounts that are not administrators only see the documents their folder permissions allow.
 // @Tags Documents
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number (default: 1)"
+// @Param cursor query string false "Opaque cursor from a previous response's nextCursor; empty starts from the newest document"
+// @Param limit query int false "Documents per page in cursor mode (default: 20, max: 200)"
 // @Success 200 {object} map[string]interface{} "Paginated documents with metadata"
+// @Failure 400 {object} map[string]interface{} "Invalid cursor or limit"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /documents/latest [get]
 func (serverHandler *ServerHandler) GetLatestDocuments(context echo.Context) error {
+	if context.QueryParams().Has("cursor") {
+		return serverHandler.getLatestDocumentsByCursor(context)
+	}
+
 	// Get page parameter (default to 1)
 	page := 1
 	if pageParam := context.QueryParam("page"); pageParam != "" {
@@ -462,22 +728,42 @@ func (serverHandler *ServerHandler) GetLatestDocuments(context echo.Context) err
 		}
 	}
 
-	// Fixed page size of 20
-	pageSize := 20
+	pageSize := latestPageSize
+
+	access, err := serverHandler.folderAccess(context)
+	if err != nil {
+		return err
+	}
+	cacheKey := cache.KeyLatest + strconv.Itoa(page)
+	if access == nil {
+		if body, ok := serverHandler.cachedResponse(context, cacheKey); ok {
+			return context.JSONBlob(http.StatusOK, body)
+		}
+	}
 
 	// Get paginated documents and total count
-	documents, totalCount, err := serverHandler.DB.GetNewestDocumentsWithPagination(page, pageSize)
+	var documents []database.Document
+	var totalCount int
+	if access != nil {
+		// Pages filtered for one account are counted over the documents it may read, and not cached
+		documents, err = serverHandler.readableNewest(access, nil, 0)
+		totalCount = len(documents)
+		documents = documents[min((page-1)*pageSize, totalCount):min(page*pageSize, totalCount)]
+	} else {
+		documents, totalCount, err = serverHandler.DB.GetNewestDocumentsWithPagination(page, pageSize)
+	}
 	if err != nil {
 		Logger.Error("Can't find latest documents", "error", err)
 		return context.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error": "Failed to fetch documents",
+			"code":  dto.CodeInternal,
 		})
 	}
 
 	// Calculate pagination metadata
 	totalPages := (totalCount + pageSize - 1) / pageSize // Ceiling division
 
-	return context.JSON(http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"documents":   documents,
 		"page":        page,
 		"pageSize":    pageSize,
@@ -485,27 +771,147 @@ func (serverHandler *ServerHandler) GetLatestDocuments(context echo.Context) err
 		"totalPages":  totalPages,
 		"hasNext":     page < totalPages,
 		"hasPrevious": page > 1,
-	})
+	}
+	// Let API clients switch to cursor mode from any page
+	if page < totalPages && len(documents) > 0 {
+		response["nextCursor"] = encodeCursor(database.CursorAfter(documents[len(documents)-1]))
+	}
+	if access != nil {
+		return context.JSON(http.StatusOK, response)
+	}
+	return serverHandler.cacheJSON(context, cacheKey, response)
+}
+
+// readableNewest reads the documents after cursor, newest first, that the account may read, stopping once
+// it has limit of them; with a limit of 0 it reads them all
+func (serverHandler *ServerHandler) readableNewest(access *folderAccess, cursor *database.DocumentCursor, limit int) ([]database.Document, error) {
+	var kept []database.Document
+	for {
+		batch, err := serverHandler.DB.GetNewestDocumentsAfter(cursor, maxCursorPageSize)
+		if err != nil {
+			return nil, err
+		}
+		kept = append(kept, access.readable(batch)...)
+		if limit > 0 && len(kept) >= limit {
+			return kept[:limit], nil
+		}
+		if len(batch) < maxCursorPageSize {
+			return kept, nil
+		}
+		cursor = database.CursorAfter(batch[len(batch)-1])
+	}
+}
+
+// getLatestDocumentsByCursor serves the keyset-paginated form of GetLatestDocuments
+func (serverHandler *ServerHandler) getLatestDocumentsByCursor(context echo.Context) error {
+	cursor, err := decodeCursor(context.QueryParam("cursor"))
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid cursor",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	limit := latestPageSize
+	if limitParam := context.QueryParam("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 || limit > maxCursorPageSize {
+			return context.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": fmt.Sprintf("Invalid limit, expected 1 to %d", maxCursorPageSize),
+				"code":  dto.CodeBadRequest,
+			})
+		}
+	}
+
+	access, err := serverHandler.folderAccess(context)
+	if err != nil {
+		return err
+	}
+	// Read one extra document to learn whether another page follows
+	var documents []database.Document
+	if access != nil {
+		documents, err = serverHandler.readableNewest(access, cursor, limit+1)
+	} else {
+		documents, err = serverHandler.DB.GetNewestDocumentsAfter(cursor, limit+1)
+	}
+	if err != nil {
+		Logger.Error("Can't find latest documents", "error", err)
+		return context.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to fetch documents",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	hasNext := len(documents) > limit
+	if hasNext {
+		documents = documents[:limit]
+	}
+	if documents == nil {
+		documents = []database.Document{}
+	}
+	response := map[string]interface{}{
+		"documents": documents,
+		"pageSize":  limit,
+		"hasNext":   hasNext,
+	}
+	if hasNext {
+		response["nextCursor"] = encodeCursor(database.CursorAfter(documents[len(documents)-1]))
+	}
+	return context.JSON(http.StatusOK, response)
 }
 
 // GetFolder fetches all the documents in the folder
 // @Summary Get folder contents
-// @Description Retrieve all documents in a specific folder
+// @Description Retrieve all documents in a specific folder. With recursive=true documents in every subfolder are included too.
 // @Tags Folders
 // @Accept json
 // @Produce json
 // @Param folder path string true "Folder name"
+// @Param recursive query bool false "Include documents in subfolders (default: false)"
+// @Param format query string false "Set to csv to download the listing as CSV (name, folder, date, size, tags, url)"
 // @Success 200 {array} database.Document "List of documents in folder"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /folder/{folder} [get]
 func (serverHandler *ServerHandler) GetFolder(context echo.Context) error {
 	folderName := context.Param("folder")
+	// Echo leaves path parameters escaped, and folder paths arrive with their slashes encoded
+	if unescaped, err := url.PathUnescape(folderName); err == nil {
+		folderName = unescaped
+	}
+	recursive, err := boolQueryParam(context, "recursive")
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid recursive value, expected true or false",
+			"code":  dto.CodeBadRequest,
+		})
+	}
 
-	folderContents, err := database.FetchFolder(folderName, serverHandler.DB)
+	access, err := serverHandler.folderAccess(context)
+	if err != nil {
+		return err
+	}
+	if !access.canRead(folderName) {
+		return context.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Folder not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+
+	var folderContents []database.Document
+	if recursive {
+		folderContents, err = serverHandler.DB.GetDocumentsUnderFolder(folderName)
+	} else {
+		folderContents, err = database.FetchFolder(folderName, serverHandler.DB)
+	}
 	if err != nil {
 		Logger.Error("API GetFolder call failed", "error", err)
 		return err
 	}
+	folderContents = access.readable(folderContents)
+	if wantsCSV(context) {
+		return serverHandler.writeDocumentsCSV(context, "folder.csv", folderContents)
+	}
 	return context.JSON(http.StatusOK, folderContents)
 
 }
@@ -523,8 +929,8 @@ func (serverHandler *ServerHandler) GetFolder(context echo.Context) error {
 // @Router /folder [post]
 func (serverHandler *ServerHandler) CreateFolder(context echo.Context) error {
 	params := context.QueryParams()
-	folderName := params.Get("folder")
-	folderPath := params.Get("path")
+	folderName := normaliseName(params.Get("folder"))
+	folderPath := normalisePath(params.Get("path"))
 	fullFolder := filepath.Join(folderPath, folderName)
 	fullFolder = filepath.Join(serverHandler.ServerConfig.DocumentPath, fullFolder)
 	fullFolder = filepath.Clean(fullFolder)
@@ -534,6 +940,8 @@ func (serverHandler *ServerHandler) CreateFolder(context echo.Context) error {
 		Logger.Error("Unable to create directory", "error", err)
 		return err
 	}
+	serverHandler.recordFolder(fullFolder)
+	serverHandler.invalidateDocumentCache()
 	serverHandler.GetDocumentFileSystem(context)
 	return context.JSON(http.StatusOK, fullFolder)
 }
@@ -628,16 +1036,24 @@ func (serverHandler *ServerHandler) CreateFolder(context echo.Context) error {
 
 // GetAboutInfo returns information about the application configuration
 // @Summary Get application information
-// @Description Retrieve information about the application configuration, version, and database
+// @Description Retrieve information about the application configuration, version, build and database, plus the health of any sidecar services.
+// @Description With UPDATE_CHECK on, "update" says whether a newer release has been published, from a check made daily.
 // @Tags Admin
 // @Accept json
 // @Produce json
 // @Success 200 {object} map[string]interface{} "Application information"
 // @Router /about [get]
 func (serverHandler *ServerHandler) GetAboutInfo(c echo.Context) error {
+	// Sidecar health must be live, so the about payload is only cached without sidecars
+	hasServices := len(serverHandler.configuredServices()) > 0
+	if !hasServices {
+		if body, ok := serverHandler.cachedResponse(c, cache.KeyAbout); ok {
+			return c.JSONBlob(http.StatusOK, body)
+		}
+	}
 
 	// Determine OCR status
-	ocrConfigured := serverHandler.ServerConfig.TesseractPath != ""
+	ocrConfigured := serverHandler.ocrConfigured()
 
 	// Get database type
 	dbType := serverHandler.ServerConfig.DatabaseType
@@ -647,17 +1063,30 @@ func (serverHandler *ServerHandler) GetAboutInfo(c echo.Context) error {
 
 	aboutInfo := map[string]interface{}{
 		"version":       build.Version,
+		"build":         build.Current(),
 		"ocrConfigured": ocrConfigured,
 		"ocrPath":       serverHandler.ServerConfig.TesseractPath,
+		"ocrService":    serverHandler.ServerConfig.TesseractServiceURL,
+		"pdfService":    serverHandler.ServerConfig.PDFServiceURL,
+		"pdfRendering":  pdfRenderingMode(serverHandler.ServerConfig),
 		"databaseType":  dbType,
 		"databaseHost":  dbHost,
 		"databasePort":  dbPort,
 		"databaseName":  dbName,
 		"ingressPath":   serverHandler.ServerConfig.IngressPath,
 		"documentPath":  serverHandler.ServerConfig.DocumentPath,
+		"extensions":    serverHandler.processableExtensions(),
+		"services":      []serviceStatus{},
+	}
+	if serverHandler.ServerConfig.UpdateCheck {
+		aboutInfo["update"] = serverHandler.updates.current()
 	}
 
-	return c.JSON(http.StatusOK, aboutInfo)
+	if hasServices {
+		aboutInfo["services"] = serverHandler.serviceHealth()
+		return c.JSON(http.StatusOK, aboutInfo)
+	}
+	return serverHandler.cacheJSON(c, cache.KeyAbout, aboutInfo)
 }
 
 // RunIngestNow triggers the ingestion process manually
@@ -667,22 +1096,28 @@ func (serverHandler *ServerHandler) GetAboutInfo(c echo.Context) error {
 // @Accept json
 // @Produce json
 // @Success 200 {object} map[string]interface{} "Job created with job ID"
+// @Failure 409 {object} map[string]interface{} "An ingestion job is already active; jobId is that job"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /ingest [post]
 func (serverHandler *ServerHandler) RunIngestNow(c echo.Context) error {
 	Logger.Info("Manual ingestion triggered via API")
 
-	// Create a job to track the ingestion
-	job, err := serverHandler.DB.CreateJob(database.JobTypeIngestion, "Starting document ingestion")
+	// Create a job to track the ingestion, unless one is already walking the ingress folder
+	job, err := serverHandler.startJob(database.JobTypeIngestion, "Starting document ingestion")
+	if errors.Is(err, errJobActive) {
+		return jobAlreadyActive(c, job)
+	}
 	if err != nil {
 		Logger.Error("Failed to create ingestion job", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error": "Failed to create job",
+			"code":  dto.CodeInternal,
 		})
 	}
 
 	// Run ingestion in a goroutine so we can return immediately
 	go func() {
-		serverHandler.ingressJobFuncWithTracking(serverHandler.ServerConfig, serverHandler.DB, job.ID)
+		serverHandler.ingressJobFuncWithTracking(serverHandler.ServerConfig, serverHandler.jobDB(), job.ID)
 	}()
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -694,33 +1129,66 @@ func (serverHandler *ServerHandler) RunIngestNow(c echo.Context) error {
 // CleanDatabase checks all documents and removes entries for missing files,
 // and moves orphaned files (not in database) back to ingress for reprocessing
 // @Summary Clean database
-// @Description Remove database entries for missing files and move orphaned files to ingress
+// @Description Remove database entries for missing files and move orphaned files to ingress.
+// @Description With dryRun=true nothing is changed; the job result reports what would be deleted or moved.
 // @Tags Admin
 // @Accept json
 // @Produce json
+// @Param dryRun query bool false "Report changes without applying them (default: false)"
+// @Param orphans query string false "Orphan file policy: ingress (default), relink or report"
 // @Success 200 {object} map[string]interface{} "Job created with jobId"
+// @Failure 409 {object} map[string]interface{} "A cleanup job is already active; jobId is that job"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /clean [post]
 func (serverHandler *ServerHandler) CleanDatabase(c echo.Context) error {
-	Logger.Info("Database cleanup triggered via API")
+	dryRun := false
+	if dryRunParam := c.QueryParam("dryRun"); dryRunParam != "" {
+		parsed, err := strconv.ParseBool(dryRunParam)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid dryRun value, expected true or false",
+				"code":  dto.CodeBadRequest,
+			})
+		}
+		dryRun = parsed
+	}
+	orphanPolicy, err := ParseOrphanPolicy(c.QueryParam("orphans"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	Logger.Info("Database cleanup triggered via API", "dryRun", dryRun, "orphanPolicy", orphanPolicy)
+
+	message := "Starting database cleanup"
+	if dryRun {
+		message = "Starting database cleanup (dry run)"
+	}
 
 	// Create a job to track the cleanup
-	job, err := serverHandler.DB.CreateJob(database.JobTypeCleanup, "Starting database cleanup")
+	job, err := serverHandler.startJob(database.JobTypeCleanup, message)
+	if errors.Is(err, errJobActive) {
+		return jobAlreadyActive(c, job)
+	}
 	if err != nil {
 		Logger.Error("Failed to create cleanup job", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error": "Failed to create cleanup job",
+			"code":  dto.CodeInternal,
 		})
 	}
 
 	// Run cleanup in goroutine with job tracking
 	go func() {
-		serverHandler.cleanupJobFuncWithTracking(serverHandler.DB, job.ID)
+		serverHandler.cleanupJobFuncWithTracking(serverHandler.jobDB(), job.ID, dryRun, orphanPolicy)
 	}()
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "Database cleanup started",
-		"jobId":   job.ID.String(),
+		"message":      "Database cleanup started",
+		"jobId":        job.ID.String(),
+		"dryRun":       dryRun,
+		"orphanPolicy": orphanPolicy,
 	})
 }
 
@@ -743,60 +1211,44 @@ func (serverHandler *ServerHandler) findOrphanedDocuments(documents []database.D
 	var orphanedFiles []string
 	documentPath := serverHandler.ServerConfig.DocumentPath
 
-	// Walk through the document directory
-	err := filepath.Walk(documentPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			Logger.Warn("Error accessing path during orphan scan", "path", path, "error", err)
-			return nil // Continue walking
-		}
+	// List everything in document storage first, so companion files can be matched to their main file
+	stored := make(map[string]bool)
+	var keys []string
+	err := serverHandler.documentStorage().Walk("", func(object storage.Object) error {
+		stored[object.Key] = true
+		keys = append(keys, object.Key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// Skip directories
-		if info.IsDir() {
-			return nil
-		}
+	for _, key := range keys {
+		path := documentKeyPath(documentPath, key)
 
 		// Skip companion files (.yaml and .txt) - they'll be handled with their main file
-		ext := filepath.Ext(path)
+		ext := filepath.Ext(key)
 		if ext == ".yaml" || ext == ".txt" {
 			// Check if this is a companion file (base file + .yaml or .txt)
-			basePath := path[:len(path)-len(ext)]
-			if _, err := os.Stat(basePath); err == nil {
+			if stored[key[:len(key)-len(ext)]] {
 				// This is a companion file, skip it for now
-				return nil
+				continue
 			}
 		}
 
 		// Check if this file is in the database
 		if !dbPaths[path] {
 			// Check if it's a document file type we care about
-			if isProcessableDocument(path) {
+			if serverHandler.isProcessableDocument(path) {
 				Logger.Info("Found orphaned document", "path", path)
 				orphanedFiles = append(orphanedFiles, path)
 			}
 		}
-
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
 	}
 
 	return orphanedFiles, nil
 }
 
-// isProcessableDocument checks if a file is a document type that can be processed
-func isProcessableDocument(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	processableExts := []string{".pdf", ".txt", ".rtf", ".doc", ".docx", ".odf", ".tiff", ".jpg", ".jpeg", ".png"}
-	for _, validExt := range processableExts {
-		if ext == validExt {
-			return true
-		}
-	}
-	return false
-}
-
 // moveOrphanToIngress moves an orphaned document (and its companion files) to the ingress folder
 func (serverHandler *ServerHandler) moveOrphanToIngress(docPath string) error {
 	ingressPath := serverHandler.ServerConfig.IngressPath
@@ -848,3 +1300,59 @@ func (serverHandler *ServerHandler) moveOrphanToIngress(docPath string) error {
 
 	return nil
 }
+
+// relinkOrphan creates a database entry for an orphaned document where it already lives.
+// Text is taken from a companion .txt OCR file when present so the document is not OCR'd again.
+func (serverHandler *ServerHandler) relinkOrphan(docPath string, db database.Repository) error {
+	fileHash, err := calculateFileHash(docPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash orphan: %w", err)
+	}
+	if duplicate, existing := serverHandler.checkDuplicate(fileHash, filepath.Base(docPath), db); duplicate {
+		return fmt.Errorf("orphan duplicates existing document %s", existing.ULID.String())
+	}
+
+	fullText, ocrStatus := "", ""
+	if txt, err := serverHandler.readDocumentFile(docPath + ".txt"); err == nil {
+		fullText, ocrStatus = string(txt), database.OCRDone
+	}
+
+	newTime := database.Now()
+	newULID, err := database.CalculateUUID(newTime)
+	if err != nil {
+		return fmt.Errorf("cannot generate ULID: %w", err)
+	}
+	doc := &database.Document{
+		Name:         normaliseName(filepath.Base(docPath)),
+		Path:         filepath.ToSlash(docPath),
+		IngressTime:  newTime,
+		Folder:       filepath.ToSlash(filepath.Dir(docPath)),
+		Hash:         fileHash.Current(),
+		ULID:         newULID,
+		DocumentType: filepath.Ext(docPath),
+		MIMEType:     database.DetectMIMEType(docPath),
+		OCRStatus:    ocrStatus,
+		FullText:     fullText,
+		URL:          documentViewURL(newULID),
+	}
+	doc.Size, doc.PageCount = fileDetails(docPath)
+	if err := db.SaveDocument(doc); err != nil {
+		return fmt.Errorf("unable to save document: %w", err)
+	}
+	serverHandler.recordFolder(doc.Folder) // the cleanup job recalculates the word cloud once it finishes
+
+	Logger.Info("Relinked orphaned document in place", "path", docPath, "ulid", doc.ULID.String())
+	return nil
+}
+
+// relativeFolder returns a folder relative to the document root, using "/" for the root itself
+func (serverHandler *ServerHandler) relativeFolder(folder string) string {
+	rel, err := filepath.Rel(serverHandler.ServerConfig.DocumentPath, folder)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(folder)
+	}
+	if rel == "." {
+		return "/"
+	}
+	return filepath.ToSlash(rel)
+}
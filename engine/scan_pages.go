@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/engine/pdfrenderer"
+)
+
+// scanImages are the image types checked page by page like the pages of a scanned PDF
+var scanImages = []string{".tiff", ".tif", ".jpg", ".jpeg", ".png"}
+
+// scanChanges is what checking the pages of a scan found and did
+type scanChanges struct {
+	rotations  []database.PageRotation // numbered as stored
+	blankPages []database.BlankPage    // numbered as scanned
+	changed    bool                    // the file was rewritten
+}
+
+// prepareScan checks a scanned image, or each page of a PDF without a text layer, before the file is
+// hashed, so the stored file and its hash are of the corrected scan. With AUTO_ROTATE pages that are
+// sideways or upside down are turned upright, and BLANK_PAGES finds blank pages and drops them from a
+// PDF. What was done is marked on the timeline, which records it against the document. It reports
+// whether the file changed; a file that cannot be checked or rewritten is logged and stored as it is.
+func (serverHandler *ServerHandler) prepareScan(filePath string, timeline *documentTimeline) bool {
+	rotate := serverHandler.autoRotating()
+	blanks := serverHandler.blankPages()
+	if !rotate && blanks == blankPagesOff {
+		return false
+	}
+	var changes scanChanges
+	var err error
+	ext := strings.ToLower(filepath.Ext(filePath))
+	switch {
+	case ext == ".pdf":
+		changes, err = serverHandler.preparePDF(filePath, rotate, blanks)
+	case slices.Contains(scanImages, ext):
+		changes, err = serverHandler.prepareImage(filePath, rotate, blanks)
+	default:
+		return false
+	}
+	if err != nil {
+		Logger.Warn("Unable to check the pages of a scan, storing it as it is", "filePath", filePath, "error", err)
+		return false
+	}
+	if len(changes.blankPages) > 0 {
+		Logger.Info("Found blank pages in scan", "filePath", filePath, "pages", len(changes.blankPages), "policy", blanks)
+	}
+	if len(changes.rotations) > 0 {
+		Logger.Info("Turned scan upright", "filePath", filePath, "pages", len(changes.rotations))
+	}
+	timeline.blank(changes.blankPages)
+	timeline.rotated(changes.rotations)
+	return changes.changed
+}
+
+// prepareImage rotates an image file in place when tesseract finds it is not upright. A blank image is
+// recorded but never removed, as it is the whole document.
+func (serverHandler *ServerHandler) prepareImage(filePath string, rotate bool, blanks blankPagePolicy) (scanChanges, error) {
+	var changes scanChanges
+	if blanks != blankPagesOff {
+		img, err := imaging.Open(filePath)
+		if err != nil {
+			return changes, err
+		}
+		if blank, ink := serverHandler.isBlankPage(img); blank {
+			changes.blankPages = []database.BlankPage{{Page: 1, Ink: ink}}
+			return changes, nil // a blank page has no way up
+		}
+	}
+	if !rotate {
+		return changes, nil
+	}
+	degrees, err := serverHandler.detectOrientation(filePath)
+	if err != nil || degrees == 0 {
+		return changes, err
+	}
+	img, err := imaging.Open(filePath)
+	if err != nil {
+		return changes, err
+	}
+	workDir, cleanup, err := serverHandler.newWorkDir("rotate-*")
+	if err != nil {
+		return changes, err
+	}
+	defer cleanup()
+	rotated := filepath.Join(workDir, filepath.Base(filePath))
+	if err := imaging.Save(rotateClockwise(img, degrees), rotated, imaging.JPEGQuality(95)); err != nil {
+		return changes, err
+	}
+	if _, err := replaceTransformed(filePath, rotated); err != nil {
+		return changes, err
+	}
+	changes.rotations = []database.PageRotation{{Page: 1, Degrees: degrees}}
+	changes.changed = true
+	return changes, nil
+}
+
+// preparePDF renders each page of a scanned PDF, up to OCR_MAX_PAGES, to measure its ink and have tesseract
+// check which way up it is, then rewrites the PDF with blank pages removed and the rest upright. A PDF with
+// a text layer was not scanned and is left alone, and one that is blank throughout keeps its pages.
+func (serverHandler *ServerHandler) preparePDF(filePath string, rotate bool, blanks blankPagePolicy) (scanChanges, error) {
+	var changes scanChanges
+	if _, err := pdfProcessing(filePath); err == nil {
+		return changes, nil
+	}
+	if maxMB := serverHandler.ServerConfig.OCRMaxFileMB; maxMB > 0 {
+		if info, err := os.Stat(filePath); err == nil && info.Size() > int64(maxMB)<<20 {
+			return changes, nil // too large to render, so it is not OCRed either
+		}
+	}
+	workDir, cleanup, err := serverHandler.newWorkDir("scan-*")
+	if err != nil {
+		return changes, err
+	}
+	defer cleanup()
+	renderer, err := pdfrenderer.NewRenderer(serverHandler.ServerConfig.PDFServiceURL)
+	if err != nil {
+		return changes, err
+	}
+	defer renderer.Close()
+
+	turns := make(map[int]int)
+	var remove []int
+	pageCount, err := renderer.RenderPages(filePath, serverHandler.ServerConfig.OCRMaxPages, func(pageIndex int, page image.Image) error {
+		if blanks != blankPagesOff {
+			if blank, ink := serverHandler.isBlankPage(page); blank {
+				changes.blankPages = append(changes.blankPages, database.BlankPage{Page: pageIndex + 1, Ink: ink})
+				remove = append(remove, pageIndex)
+				return nil
+			}
+		}
+		if !rotate {
+			return nil
+		}
+		imageName := filepath.Join(workDir, fmt.Sprintf("page-%04d.png", pageIndex+1))
+		if err := writeOCRImage(imageName, page); err != nil {
+			return err
+		}
+		degrees, err := serverHandler.detectOrientation(imageName)
+		os.Remove(imageName)
+		if err != nil {
+			Logger.Debug("Unable to detect page orientation", "filePath", filePath, "page", pageIndex+1, "error", err)
+			return nil // a page without enough text is left as it is
+		}
+		if degrees != 0 {
+			turns[pageIndex] = degrees
+		}
+		return nil
+	})
+	if err != nil {
+		return scanChanges{}, err
+	}
+	if blanks != blankPagesRemove || len(remove) == pageCount {
+		remove = nil
+	}
+	for i := range changes.blankPages {
+		changes.blankPages[i].Removed = remove != nil
+	}
+	for pageIndex := range pageCount {
+		if degrees, ok := turns[pageIndex]; ok {
+			removedBefore, _ := slices.BinarySearch(remove, pageIndex)
+			changes.rotations = append(changes.rotations, database.PageRotation{Page: pageIndex + 1 - removedBefore, Degrees: degrees})
+		}
+	}
+	if len(turns) == 0 && len(remove) == 0 {
+		return changes, nil
+	}
+
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		return scanChanges{}, err
+	}
+	rewritten, err := pdfrenderer.RewritePDF(original, turns, remove)
+	if err != nil {
+		return scanChanges{}, err
+	}
+	prepared := filepath.Join(workDir, filepath.Base(filePath))
+	if err := os.WriteFile(prepared, rewritten, 0644); err != nil {
+		return scanChanges{}, err
+	}
+	if _, err := replaceTransformed(filePath, prepared); err != nil {
+		return scanChanges{}, err
+	}
+	changes.changed = true
+	return changes, nil
+}
@@ -3,15 +3,18 @@ package engine
 import (
 	"fmt"
 	"log/slog"
+	"time"
 
 	database "github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/sources"
 	"github.com/robfig/cron/v3"
 )
 
 // Logger is global since we will need it everywhere
 var Logger *slog.Logger
 
-// InitializeSchedules starts all the cron jobs (currently just one)
+// InitializeSchedules starts the scheduled jobs. Their cron expressions come from the SCHEDULE_* settings,
+// or the schedules API when one has been saved there, and may be changed while running with applySchedules.
 func (serverHandler *ServerHandler) InitializeSchedules(db database.Repository) {
 	serverConfig, err := database.FetchConfigFromDB(db)
 	if err != nil {
@@ -20,14 +23,64 @@ func (serverHandler *ServerHandler) InitializeSchedules(db database.Repository)
 
 	// Run ingress job immediately at startup in a goroutine
 	Logger.Info("Running ingress job at startup")
-	go serverHandler.ingressJobFunc(serverConfig, db)
-
-	c := cron.New()
-	var ingressJob cron.Job
-	ingressJob = cron.FuncJob(func() { serverHandler.ingressJobFunc(serverConfig, db) })
-	ingressJob = cron.NewChain(cron.SkipIfStillRunning(cron.DefaultLogger)).Then(ingressJob) //ensure we don't kick off another if old one is still running
-	c.AddJob(fmt.Sprintf("@every %dm", serverConfig.IngressInterval), ingressJob)
-	//c.AddJob("@every 1m", ingressJob)
-	Logger.Info("Adding Ingress Job scheduler", "interval_minutes", serverConfig.IngressInterval)
-	c.Start()
+	go serverHandler.unlessReadOnly("ingest", func() { serverHandler.ingressJobFunc(serverConfig, db) })()
+
+	// Jobs that change documents are skipped in read-only mode; backups and the release check still run
+	s := &serverHandler.schedules
+	s.addScheduledJob("ingest", "", serverHandler.unlessReadOnly("ingest", func() { serverHandler.scheduledIngest(serverConfig, db) }))
+	s.addScheduledJob("cleanup", "", serverHandler.unlessReadOnly("cleanup", serverHandler.scheduledCleanup))
+	s.addScheduledJob("backup", "", serverHandler.scheduledBackup)
+	s.addScheduledJob("reindex", "", serverHandler.unlessReadOnly("reindex", serverHandler.scheduledReindex))
+
+	// The watchdog fails jobs that stopped reporting progress, such as those running when the server died
+	s.addScheduledJob("job-watchdog", jobWatchdogSchedule, func() { serverHandler.failTimedOutJobs() })
+
+	// Changed file detector uses the live config since the rescan interval is not stored in the database
+	if rescanInterval := serverHandler.ServerConfig.RescanInterval; rescanInterval > 0 {
+		s.addScheduledJob("rescan", fmt.Sprintf("@every %dm", rescanInterval), serverHandler.unlessReadOnly("rescan", func() { serverHandler.changedFileJobFunc(db) }))
+	}
+
+	// The release check runs now and then daily, only when asked for since it calls out to GitHub
+	if serverHandler.ServerConfig.UpdateCheck {
+		s.addScheduledJob("update-check", "@daily", serverHandler.checkForUpdate)
+		go serverHandler.checkForUpdate()
+	}
+
+	// Documents are moved to cold storage daily once they are ARCHIVE_AFTER_DAYS old
+	if serverHandler.ServerConfig.ArchiveAfterDays > 0 {
+		s.addScheduledJob("archive", "@daily", serverHandler.unlessReadOnly("archive", serverHandler.scheduledArchive))
+	}
+
+	// Remote ingest sources (e.g. Nextcloud) are polled on the ingest schedule
+	for _, source := range sources.FromConfig(serverHandler.ServerConfig) {
+		ingester := newRemoteIngester(source)
+		s.addScheduledJob("remote:"+source.Name(), "", serverHandler.unlessReadOnly("remote:"+source.Name(), func() { serverHandler.remoteIngestJobFunc(ingester) }))
+	}
+
+	s.mu.Lock()
+	s.cron = cron.New()
+	s.entries = make(map[string]cron.EntryID)
+	s.deferred = make(map[string]*time.Timer)
+	s.now = time.Now
+	s.runAfter = time.AfterFunc
+	s.mu.Unlock()
+	serverHandler.applySchedules()
+	s.cron.Start()
+}
+
+// StopSchedules stops the scheduled jobs and waits for any that are running to finish
+func (serverHandler *ServerHandler) StopSchedules() {
+	s := &serverHandler.schedules
+	s.mu.Lock()
+	c := s.cron
+	s.cron = nil
+	for name, timer := range s.deferred {
+		timer.Stop()
+		delete(s.deferred, name)
+	}
+	s.mu.Unlock()
+	if c == nil {
+		return
+	}
+	<-c.Stop().Done()
 }
@@ -0,0 +1,474 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+	"github.com/robfig/cron/v3"
+)
+
+// scheduledJobs are the jobs that can be given a cron expression, in the order they are listed
+var scheduledJobs = []string{"ingest", "cleanup", "backup", "reindex"}
+
+// quietJobs are the OCR-heavy jobs that wait for quiet hours to end; the changed file scan and remote
+// sources are keyed by scheduler name, so they are matched by prefix in deferredDuringQuietHours
+var quietJobs = []string{"ingest", "rescan", "remote:"}
+
+// quietHoursKey is the saved schedule that holds the quiet hours window
+const quietHoursKey = "quiet_hours"
+
+// scheduleOff turns a job, or the quiet hours, off regardless of the environment setting
+const scheduleOff = "off"
+
+// scheduleParser reads cron expressions: five fields, or descriptors such as @daily and @every 15m
+var scheduleParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// parseSchedule validates a cron expression; an empty one or "off" is valid and returns nil
+func parseSchedule(spec string) (cron.Schedule, error) {
+	if spec == "" || spec == scheduleOff {
+		return nil, nil
+	}
+	schedule, err := scheduleParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+	}
+	return schedule, nil
+}
+
+// quietHours is a daily window, in server time, when OCR-heavy jobs are deferred. The end may be
+// earlier than the start, for a window that runs past midnight. The zero value has no window.
+type quietHours struct {
+	start, end int // minutes after midnight
+	set        bool
+}
+
+// parseQuietHours reads an HH:MM-HH:MM window; an empty value or "off" gives no window
+func parseQuietHours(value string) (quietHours, error) {
+	if value == "" || value == scheduleOff {
+		return quietHours{}, nil
+	}
+	from, to, found := strings.Cut(value, "-")
+	if !found {
+		return quietHours{}, fmt.Errorf("quiet hours %q must look like 22:00-06:00", value)
+	}
+	start, err := parseClock(strings.TrimSpace(from))
+	if err != nil {
+		return quietHours{}, err
+	}
+	end, err := parseClock(strings.TrimSpace(to))
+	if err != nil {
+		return quietHours{}, err
+	}
+	if start == end {
+		return quietHours{}, fmt.Errorf("quiet hours %q start and end at the same time", value)
+	}
+	return quietHours{start: start, end: end, set: true}, nil
+}
+
+// parseClock reads an HH:MM time of day as minutes after midnight
+func parseClock(value string) (int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
+
+// contains reports whether t falls inside the window
+func (q quietHours) contains(t time.Time) bool {
+	if !q.set {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if q.start < q.end {
+		return minute >= q.start && minute < q.end
+	}
+	return minute >= q.start || minute < q.end
+}
+
+// until returns when the window that t falls inside closes
+func (q quietHours) until(t time.Time) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	end := midnight.Add(time.Duration(q.end) * time.Minute)
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// String formats the window as HH:MM-HH:MM
+func (q quietHours) String() string {
+	if !q.set {
+		return ""
+	}
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", q.start/60, q.start%60, q.end/60, q.end%60)
+}
+
+// deferredDuringQuietHours reports whether a scheduler job waits for quiet hours to end
+func deferredDuringQuietHours(name string) bool {
+	for _, quiet := range quietJobs {
+		if name == quiet || (strings.HasSuffix(quiet, ":") && strings.HasPrefix(name, quiet)) {
+			return true
+		}
+	}
+	return false
+}
+
+// jobScheduler runs the scheduled jobs. Each job keeps the same cron.Job across reschedules,
+// so SkipIfStillRunning still holds when a schedule is changed while the job is running.
+type jobScheduler struct {
+	mu       sync.Mutex
+	cron     *cron.Cron
+	jobs     map[string]cron.Job     // by scheduler name
+	specs    map[string]string       // fixed schedules of the jobs that are not in scheduledJobs
+	entries  map[string]cron.EntryID // scheduled jobs by name
+	quiet    quietHours              // in force for the running scheduler
+	deferred map[string]*time.Timer  // jobs waiting for quiet hours to end
+	now      func() time.Time        // time.Now, replaced in tests
+	runAfter func(time.Duration, func()) *time.Timer
+}
+
+// effectiveSchedules merges the schedules saved through the API over those in the environment.
+// It returns each job's cron expression, where each one came from, and the quiet hours and their source.
+func (serverHandler *ServerHandler) effectiveSchedules() (map[string]string, map[string]string, string, string, error) {
+	specs := make(map[string]string, len(scheduledJobs))
+	sources := make(map[string]string, len(scheduledJobs))
+	for _, name := range scheduledJobs {
+		specs[name] = serverHandler.ServerConfig.Schedules[name]
+		sources[name] = "environment"
+	}
+	// Configs built without LoadConfig have no schedules, so keep ingesting on the ingress interval
+	if serverHandler.ServerConfig.Schedules == nil && serverHandler.ServerConfig.IngressInterval > 0 {
+		specs["ingest"] = fmt.Sprintf("@every %dm", serverHandler.ServerConfig.IngressInterval)
+	}
+	quiet, quietSource := serverHandler.ServerConfig.QuietHours, "environment"
+
+	saved, err := serverHandler.DB.GetJobSchedules()
+	if err != nil {
+		return specs, sources, quiet, quietSource, err
+	}
+	for name, spec := range saved {
+		if name == quietHoursKey {
+			quiet, quietSource = spec, "saved"
+			continue
+		}
+		if _, known := specs[name]; known {
+			specs[name], sources[name] = spec, "saved"
+		}
+	}
+	return specs, sources, quiet, quietSource, nil
+}
+
+// addScheduledJob registers a job with the scheduler, wrapped so only one run happens at a time.
+// spec is its fixed schedule, or empty for a job in scheduledJobs that follows its cron expression.
+func (s *jobScheduler) addScheduledJob(name, spec string, run func()) {
+	if s.jobs == nil {
+		s.jobs = make(map[string]cron.Job)
+		s.specs = make(map[string]string)
+	}
+	s.jobs[name] = cron.NewChain(cron.SkipIfStillRunning(cron.DefaultLogger)).Then(cron.FuncJob(run))
+	if spec != "" {
+		s.specs[name] = spec
+	}
+}
+
+// applySchedules (re)schedules every job from the effective schedules. Invalid expressions from the
+// environment are logged and leave that job unscheduled; the API refuses them before they are saved.
+func (serverHandler *ServerHandler) applySchedules() {
+	specs, _, quiet, _, err := serverHandler.effectiveSchedules()
+	if err != nil {
+		Logger.Error("Unable to read saved job schedules, using the environment", "error", err)
+	}
+	s := &serverHandler.schedules
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cron == nil {
+		return
+	}
+
+	window, err := parseQuietHours(quiet)
+	if err != nil {
+		Logger.Error("Ignoring quiet hours", "error", err)
+	}
+	s.quiet = window
+	for name, id := range s.entries {
+		s.cron.Remove(id)
+		delete(s.entries, name)
+	}
+	for name, job := range s.jobs {
+		spec, fixed := s.specs[name]
+		switch {
+		case fixed:
+		case strings.HasPrefix(name, "remote:"):
+			// Remote sources are polled on the ingest schedule
+			spec = specs["ingest"]
+		default:
+			spec = specs[name]
+		}
+		schedule, err := parseSchedule(spec)
+		if err != nil {
+			Logger.Error("Job not scheduled", "job", name, "error", err)
+			continue
+		}
+		if schedule == nil {
+			Logger.Info("Job schedule disabled", "job", name)
+			continue
+		}
+		if deferredDuringQuietHours(name) {
+			job = serverHandler.quietHoursJob(name, job)
+		}
+		s.entries[name] = s.cron.Schedule(schedule, job)
+		Logger.Info("Job scheduled", "job", name, "schedule", spec, "deferredDuringQuietHours", deferredDuringQuietHours(name) && window.set)
+	}
+}
+
+// quietHoursJob defers a run that falls inside quiet hours until they end. Deferred runs of the
+// same job collapse into one, since each run picks up everything the skipped ones would have.
+func (serverHandler *ServerHandler) quietHoursJob(name string, job cron.Job) cron.Job {
+	return cron.FuncJob(func() {
+		s := &serverHandler.schedules
+		s.mu.Lock()
+		now := s.now()
+		if !s.quiet.contains(now) {
+			s.mu.Unlock()
+			job.Run()
+			return
+		}
+		defer s.mu.Unlock()
+		if s.deferred[name] != nil {
+			return
+		}
+		resume := s.quiet.until(now)
+		Logger.Info("Deferring job until quiet hours end", "job", name, "until", resume)
+		s.deferred[name] = s.runAfter(resume.Sub(now), func() {
+			s.mu.Lock()
+			delete(s.deferred, name)
+			s.mu.Unlock()
+			job.Run()
+		})
+	})
+}
+
+// scheduledIngest walks the ingress folder from the scheduler, unless a triggered ingestion job is doing so
+func (serverHandler *ServerHandler) scheduledIngest(serverConfig config.ServerConfig, db database.Repository) {
+	if active, err := serverHandler.activeJob(database.JobTypeIngestion); err == nil && active != nil {
+		Logger.Info("Skipping scheduled ingestion, one is already active", "jobID", active.ID.String())
+		return
+	}
+	serverHandler.ingressJobFunc(serverConfig, db)
+}
+
+// scheduledCleanup runs a database cleanup from the scheduler with the default orphan policy
+func (serverHandler *ServerHandler) scheduledCleanup() {
+	job, err := serverHandler.startJob(database.JobTypeCleanup, "Starting scheduled database cleanup")
+	if errors.Is(err, errJobActive) {
+		Logger.Info("Skipping scheduled cleanup, one is already active", "jobID", job.ID.String())
+		return
+	}
+	if err != nil {
+		Logger.Error("Failed to create scheduled cleanup job", "error", err)
+		return
+	}
+	serverHandler.cleanupJobFuncWithTracking(serverHandler.jobDB(), job.ID, false, OrphanPolicyIngress)
+}
+
+// scheduledReindex rebuilds the search index from the scheduler
+func (serverHandler *ServerHandler) scheduledReindex() {
+	job, err := serverHandler.startJob(database.JobTypeSearchReindex, "Starting scheduled search reindex")
+	if errors.Is(err, errJobActive) {
+		Logger.Info("Skipping scheduled reindex, one is already active", "jobID", job.ID.String())
+		return
+	}
+	if err != nil {
+		Logger.Error("Failed to create scheduled reindex job", "error", err)
+		return
+	}
+	db := serverHandler.jobDB()
+	db.UpdateJobStatus(job.ID, database.JobStatusRunning, "Reindexing documents")
+	count, err := db.ReindexSearchDocuments()
+	if err != nil {
+		Logger.Error("Scheduled reindex failed", "error", err)
+		db.UpdateJobError(job.ID, fmt.Sprintf("Reindex failed: %v", err))
+		return
+	}
+	if err := db.CompleteJob(job.ID, fmt.Sprintf(`{"documentsReindexed": %d}`, count)); err != nil {
+		Logger.Error("Failed to mark reindex job as complete", "error", err)
+	}
+	Logger.Info("Scheduled search reindex completed", "documents", count)
+}
+
+// jobSchedule is one scheduled job as listed by the schedules API
+type jobSchedule struct {
+	Job        string     `json:"job"`
+	Schedule   string     `json:"schedule"` // cron expression, empty or "off" when the job is not scheduled
+	Source     string     `json:"source"`   // environment or saved
+	NextRun    *time.Time `json:"nextRun,omitempty"`
+	QuietHours bool       `json:"quietHours"` // deferred while quiet hours are in force
+}
+
+// schedulesRequest changes job schedules; a job left out keeps its schedule and an empty value
+// goes back to the environment setting
+type schedulesRequest struct {
+	Schedules  map[string]string `json:"schedules"`
+	QuietHours *string           `json:"quietHours"`
+}
+
+// validate checks every expression in the request, returning problems keyed by field
+func (req schedulesRequest) validate() map[string]string {
+	problems := make(map[string]string)
+	for name, spec := range req.Schedules {
+		if !slices.Contains(scheduledJobs, name) {
+			problems["schedules."+name] = "unknown job, expected one of " + strings.Join(scheduledJobs, ", ")
+			continue
+		}
+		if _, err := parseSchedule(strings.TrimSpace(spec)); err != nil {
+			problems["schedules."+name] = err.Error()
+		}
+	}
+	if req.QuietHours != nil {
+		if _, err := parseQuietHours(strings.TrimSpace(*req.QuietHours)); err != nil {
+			problems["quietHours"] = err.Error()
+		}
+	}
+	return problems
+}
+
+// listSchedules describes each job with its next run after now
+func listSchedules(specs, sources map[string]string, now time.Time) []jobSchedule {
+	list := make([]jobSchedule, 0, len(scheduledJobs))
+	for _, name := range scheduledJobs {
+		entry := jobSchedule{Job: name, Schedule: specs[name], Source: sources[name], QuietHours: deferredDuringQuietHours(name)}
+		if schedule, err := parseSchedule(specs[name]); err == nil && schedule != nil {
+			next := schedule.Next(now)
+			entry.NextRun = &next
+		}
+		list = append(list, entry)
+	}
+	return list
+}
+
+// schedulesResponse is the body returned by both schedules endpoints
+func schedulesResponse(specs, sources map[string]string, quiet, quietSource string, now time.Time) map[string]interface{} {
+	window, _ := parseQuietHours(quiet)
+	return map[string]interface{}{
+		"schedules":        listSchedules(specs, sources, now),
+		"quietHours":       window.String(),
+		"quietHoursSource": quietSource,
+		"quietNow":         window.contains(now),
+	}
+}
+
+// GetSchedules lists the job schedules
+// @Summary Get job schedules
+// @Description Cron expression, where it came from and next run for the ingest, cleanup, backup and reindex jobs,
+// @Description and the quiet hours window during which OCR-heavy jobs are deferred.
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "schedules, quietHours, quietHoursSource and quietNow"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /schedules [get]
+func (serverHandler *ServerHandler) GetSchedules(c echo.Context) error {
+	specs, sources, quiet, quietSource, err := serverHandler.effectiveSchedules()
+	if err != nil {
+		Logger.Error("Failed to read job schedules", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to read job schedules",
+			"code":  dto.CodeInternal,
+		})
+	}
+	return c.JSON(http.StatusOK, schedulesResponse(specs, sources, quiet, quietSource, database.Now()))
+}
+
+// UpdateSchedules validates, saves and applies job schedules
+// @Summary Update job schedules
+// @Description Set cron expressions (five fields, or descriptors such as @daily and @every 15m) per job, and the quiet hours window as HH:MM-HH:MM.
+// @Description "off" disables a job or the quiet hours; an empty value goes back to the environment setting. Changes apply without a restart.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param schedules body schedulesRequest true "Schedules to change"
+// @Param dryRun query bool false "Only validate, and show the resulting schedules without saving them"
+// @Success 200 {object} map[string]interface{} "schedules, quietHours, quietHoursSource and quietNow"
+// @Failure 400 {object} map[string]interface{} "Invalid schedules, with problems by field"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /schedules [put]
+func (serverHandler *ServerHandler) UpdateSchedules(c echo.Context) error {
+	dryRun := false
+	if dryRunParam := c.QueryParam("dryRun"); dryRunParam != "" {
+		parsed, err := strconv.ParseBool(dryRunParam)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid dryRun value, expected true or false",
+				"code":  dto.CodeBadRequest,
+			})
+		}
+		dryRun = parsed
+	}
+	var req schedulesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid schedules request", "code": dto.CodeBadRequest})
+	}
+	if problems := req.validate(); len(problems) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Some schedules need fixing",
+			"code":   dto.CodeValidation,
+			"fields": problems,
+		})
+	}
+
+	changes := make(map[string]string, len(req.Schedules)+1)
+	for name, spec := range req.Schedules {
+		changes[name] = strings.TrimSpace(spec)
+	}
+	if req.QuietHours != nil {
+		changes[quietHoursKey] = strings.TrimSpace(*req.QuietHours)
+	}
+
+	if dryRun {
+		specs, sources, quiet, quietSource, err := serverHandler.effectiveSchedules()
+		if err != nil {
+			Logger.Error("Failed to read job schedules", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to read job schedules",
+				"code":  dto.CodeInternal,
+			})
+		}
+		for name, spec := range changes {
+			source := "saved"
+			if spec == "" {
+				spec, source = serverHandler.ServerConfig.Schedules[name], "environment"
+			}
+			if name == quietHoursKey {
+				if spec == "" {
+					spec = serverHandler.ServerConfig.QuietHours
+				}
+				quiet, quietSource = spec, source
+				continue
+			}
+			specs[name], sources[name] = spec, source
+		}
+		return c.JSON(http.StatusOK, schedulesResponse(specs, sources, quiet, quietSource, database.Now()))
+	}
+
+	if err := serverHandler.DB.SaveJobSchedules(changes); err != nil {
+		Logger.Error("Failed to save job schedules", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to save job schedules",
+			"code":  dto.CodeInternal,
+		})
+	}
+	Logger.Info("Job schedules updated via API", "changes", changes)
+	serverHandler.settingsChanged(c, "job schedule")
+	serverHandler.applySchedules()
+	return serverHandler.GetSchedules(c)
+}
@@ -0,0 +1,221 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/robfig/cron/v3"
+)
+
+func TestParseQuietHours(t *testing.T) {
+	at := func(clock string) time.Time {
+		parsed, _ := time.Parse("2006-01-02 15:04", "2026-03-10 "+clock)
+		return parsed
+	}
+	overnight, err := parseQuietHours("22:00-06:00")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for clock, want := range map[string]bool{"21:59": false, "22:00": true, "23:30": true, "05:59": true, "06:00": false, "12:00": false} {
+		if got := overnight.contains(at(clock)); got != want {
+			t.Errorf("22:00-06:00 contains %s = %v, expected %v", clock, got, want)
+		}
+	}
+	if got, want := overnight.until(at("23:30")), at("06:00").AddDate(0, 0, 1); !got.Equal(want) {
+		t.Errorf("Expected the window to close at %v, got %v", want, got)
+	}
+	if got, want := overnight.until(at("01:00")), at("06:00"); !got.Equal(want) {
+		t.Errorf("Expected the window to close at %v, got %v", want, got)
+	}
+	if overnight.String() != "22:00-06:00" {
+		t.Errorf("Unexpected window %q", overnight.String())
+	}
+
+	daytime, _ := parseQuietHours("09:00 - 17:30")
+	if !daytime.contains(at("17:29")) || daytime.contains(at("17:30")) || daytime.contains(at("08:59")) {
+		t.Errorf("09:00-17:30 does not cover the right times")
+	}
+
+	for _, value := range []string{"", "off"} {
+		if window, err := parseQuietHours(value); err != nil || window.set {
+			t.Errorf("Expected %q to give no window, got %v, %v", value, window, err)
+		}
+	}
+	for _, value := range []string{"22:00", "25:00-06:00", "22:00-6", "08:00-08:00"} {
+		if _, err := parseQuietHours(value); err == nil {
+			t.Errorf("Expected %q to be refused", value)
+		}
+	}
+}
+
+func TestSchedulesAPI(t *testing.T) {
+	// A handler that ingests every 10 minutes from the environment and has nothing saved
+	handler := setupTestHandler(t)
+	handler.ServerConfig.Schedules = config.JobSchedules{"ingest": "@every 10m"}
+	call := func(method, target, body string) (int, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := handler.Echo.NewContext(req, rec)
+		var err error
+		if method == http.MethodGet {
+			err = handler.GetSchedules(c)
+		} else {
+			err = handler.UpdateSchedules(c)
+		}
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, target, err)
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		return rec.Code, response
+	}
+	schedule := func(response map[string]interface{}, job string) map[string]interface{} {
+		t.Helper()
+		for _, entry := range response["schedules"].([]interface{}) {
+			if entry := entry.(map[string]interface{}); entry["job"] == job {
+				return entry
+			}
+		}
+		t.Fatalf("No schedule for %s in %v", job, response)
+		return nil
+	}
+
+	// Invalid expressions, an unknown job and bad quiet hours are sent
+	code, response := call(http.MethodPut, "/api/schedules", `{"schedules":{"cleanup":"61 * * * *","nightly":"@daily"},"quietHours":"late"}`)
+
+	// Each problem is reported against its field
+	if code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d: %v", code, response)
+	}
+	fields := response["fields"].(map[string]interface{})
+	for _, field := range []string{"schedules.cleanup", "schedules.nightly", "quietHours"} {
+		if fields[field] == nil {
+			t.Errorf("Expected a problem with %s, got %v", field, fields)
+		}
+	}
+
+	// A valid change is only validated
+	code, response = call(http.MethodPut, "/api/schedules?dryRun=true", `{"schedules":{"cleanup":"0 3 * * *"}}`)
+
+	// The preview has the new schedule but nothing is saved
+	if code != http.StatusOK || schedule(response, "cleanup")["schedule"] != "0 3 * * *" {
+		t.Fatalf("Unexpected dry run response %d: %v", code, response)
+	}
+	if _, response = call(http.MethodGet, "/api/schedules", ""); schedule(response, "cleanup")["schedule"] != "" {
+		t.Errorf("Dry run saved the cleanup schedule: %v", response)
+	}
+
+	// The cleanup schedule and quiet hours are saved and ingestion is turned off
+	code, response = call(http.MethodPut, "/api/schedules", `{"schedules":{"cleanup":"0 3 * * *","ingest":"off"},"quietHours":"22:00-06:00"}`)
+
+	// They are listed as saved, with the next cleanup run
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %v", code, response)
+	}
+	cleanup := schedule(response, "cleanup")
+	if cleanup["source"] != "saved" || cleanup["nextRun"] == nil {
+		t.Errorf("Unexpected cleanup schedule %v", cleanup)
+	}
+	if ingest := schedule(response, "ingest"); ingest["schedule"] != "off" || ingest["nextRun"] != nil || ingest["quietHours"] != true {
+		t.Errorf("Unexpected ingest schedule %v", ingest)
+	}
+	if response["quietHours"] != "22:00-06:00" || response["quietHoursSource"] != "saved" {
+		t.Errorf("Unexpected quiet hours %v", response)
+	}
+
+	// Ingestion is reset
+	_, response = call(http.MethodPut, "/api/schedules", `{"schedules":{"ingest":""}}`)
+
+	// It goes back to the environment schedule and the others stay saved
+	if ingest := schedule(response, "ingest"); ingest["schedule"] != "@every 10m" || ingest["source"] != "environment" {
+		t.Errorf("Expected ingest to follow the environment again, got %v", ingest)
+	}
+	if schedule(response, "cleanup")["source"] != "saved" {
+		t.Errorf("Cleanup schedule was lost: %v", response)
+	}
+}
+
+func TestQuietHoursDefersOCRJobs(t *testing.T) {
+	// A scheduler inside 22:00-06:00 quiet hours, with timers captured instead of started
+	handler := setupMemoryTestHandler(t, config.ServerConfig{})
+	now := time.Date(2026, 3, 10, 23, 0, 0, 0, time.Local)
+	var delays []time.Duration
+	var deferredRuns []func()
+	s := &handler.schedules
+	s.quiet, _ = parseQuietHours("22:00-06:00")
+	s.deferred = make(map[string]*time.Timer)
+	s.now = func() time.Time { return now }
+	s.runAfter = func(delay time.Duration, run func()) *time.Timer {
+		delays = append(delays, delay)
+		deferredRuns = append(deferredRuns, run)
+		return time.NewTimer(time.Hour)
+	}
+	runs := 0
+	job := handler.quietHoursJob("ingest", cron.FuncJob(func() { runs++ }))
+
+	// The job fires twice during quiet hours
+	job.Run()
+	job.Run()
+
+	// It is deferred once, until the window closes
+	if runs != 0 {
+		t.Fatalf("Job ran during quiet hours")
+	}
+	if len(delays) != 1 || delays[0] != 7*time.Hour {
+		t.Fatalf("Expected one 7 hour deferral, got %v", delays)
+	}
+
+	// Quiet hours end
+	deferredRuns[0]()
+	now = now.Add(7 * time.Hour)
+	job.Run()
+
+	// The deferred run and the next scheduled one both happen
+	if runs != 2 || len(s.deferred) != 0 {
+		t.Errorf("Expected 2 runs and nothing deferred, got %d runs and %v", runs, s.deferred)
+	}
+	if !deferredDuringQuietHours("remote:nextcloud") || deferredDuringQuietHours("backup") {
+		t.Errorf("Remote sources should wait for quiet hours and backups should not")
+	}
+}
+
+func TestWriteBackup(t *testing.T) {
+	// Two documents and a backup folder that does not exist yet
+	handler := setupMemoryTestHandler(t, config.ServerConfig{BackupPath: filepath.Join(t.TempDir(), "backups")})
+	saveTestDocument(t, handler.DB, "/docs/a.pdf", "first text")
+	saveTestDocument(t, handler.DB, "/docs/b.pdf", "second text")
+
+	// A backup is written
+	at := time.Date(2026, 3, 10, 2, 0, 0, 0, time.UTC)
+	path, exported, err := handler.writeBackup(at, nil)
+
+	// One file named for the time holds both documents with their text, and no partial file is left
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if exported != 2 || filepath.Base(path) != "godocs-20260310-020000.ndjson" {
+		t.Errorf("Unexpected backup %s of %d documents", path, exported)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read backup: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(content)), "\n"); len(lines) != 2 || !strings.Contains(string(content), "second text") {
+		t.Errorf("Unexpected backup content %s", content)
+	}
+	entries, _ := os.ReadDir(handler.ServerConfig.BackupPath)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the backup file, found %d entries", len(entries))
+	}
+}
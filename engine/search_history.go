@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// searchAnalyticsDefaultDays is the window GetSearchAnalytics looks back over when days is not given
+const searchAnalyticsDefaultDays = 30
+
+// requestUser names who made a request: the account signed in through RequireLogin, the basic auth user,
+// or the user an authenticating reverse proxy passed on in Remote-User or X-Forwarded-User. It is empty
+// when none is present.
+func requestUser(c echo.Context) string {
+	if user, ok := c.Get(authUserKey).(string); ok && user != "" {
+		return user
+	}
+	if user, _, ok := c.Request().BasicAuth(); ok {
+		return user
+	}
+	for _, header := range []string{"Remote-User", "X-Forwarded-User"} {
+		if user := strings.TrimSpace(c.Request().Header.Get(header)); user != "" {
+			return user
+		}
+	}
+	return ""
+}
+
+// recordSearch adds a search to the search history; a failure is logged rather than failing the search
+func (serverHandler *ServerHandler) recordSearch(c echo.Context, term string, resultCount int, duration time.Duration) {
+	query := &database.SearchQuery{
+		Term:        strings.TrimSpace(term),
+		ResultCount: resultCount,
+		DurationMs:  duration.Milliseconds(),
+		User:        requestUser(c),
+	}
+	if err := serverHandler.DB.RecordSearch(query); err != nil {
+		Logger.Warn("Unable to record search", "term", query.Term, "error", err)
+	}
+}
+
+// GetSearchHistory returns the current user's recent searches
+// @Summary Get search history
+// @Description Recent searches by the current user, newest first. The user comes from basic auth or the
+// @Description Remote-User / X-Forwarded-User header of an authenticating proxy; without either, the shared anonymous history is returned.
+// @Tags Search
+// @Produce json
+// @Param limit query int false "Maximum searches to return (default: 20, max: 200)"
+// @Success 200 {object} map[string]interface{} "Searches with term, resultCount, durationMs and createdAt"
+// @Failure 400 {object} map[string]interface{} "Invalid limit"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /search/history [get]
+func (serverHandler *ServerHandler) GetSearchHistory(c echo.Context) error {
+	limit, err := limitParam(c, latestPageSize)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	user := requestUser(c)
+	searches, err := serverHandler.DB.GetSearchHistory(user, limit)
+	if err != nil {
+		Logger.Error("Failed to get search history", "user", user, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve search history",
+			"code":  dto.CodeInternal,
+		})
+	}
+	if searches == nil {
+		searches = []database.SearchQuery{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"user":     user,
+		"searches": searches,
+		"count":    len(searches),
+	})
+}
+
+// GetSearchAnalytics reports the searches that most often found nothing
+// @Summary Get search analytics
+// @Description Terms most often searched without any results, grouped case-insensitively, to guide stop-word tuning and classification rules
+// @Tags Admin
+// @Produce json
+// @Param days query int false "Look back over the last N days (default: 30)"
+// @Param limit query int false "Maximum terms to return (default: 20, max: 200)"
+// @Success 200 {object} map[string]interface{} "zeroResults with term, searches and lastSearched"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /search/analytics [get]
+func (serverHandler *ServerHandler) GetSearchAnalytics(c echo.Context) error {
+	days := searchAnalyticsDefaultDays
+	if value := c.QueryParam("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid days value, expected 1 or more",
+				"code":  dto.CodeBadRequest,
+			})
+		}
+		days = parsed
+	}
+	limit, err := limitParam(c, latestPageSize)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	zeroResults, err := serverHandler.DB.GetZeroResultSearches(database.Now().AddDate(0, 0, -days), limit)
+	if err != nil {
+		Logger.Error("Failed to get zero-result searches", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve search analytics",
+			"code":  dto.CodeInternal,
+		})
+	}
+	if zeroResults == nil {
+		zeroResults = []database.SearchTermCount{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"days":        days,
+		"zeroResults": zeroResults,
+	})
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+)
+
+func TestSearchHistoryAndAnalytics(t *testing.T) {
+	// One document and searches by a proxy-authenticated user, a basic auth user and an anonymous user
+	handler := setupTestHandler(t)
+	manual := filepath.Join(handler.ServerConfig.DocumentPath, "boiler.txt")
+	if err := os.WriteFile(manual, []byte("boiler service manual"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	saveTestDocument(t, handler.DB, manual, "boiler service manual")
+	search := func(term string, setUser func(*http.Request)) {
+		req := httptest.NewRequest(http.MethodGet, "/api/search?term="+term, nil)
+		setUser(req)
+		handler.SearchDocuments(handler.Echo.NewContext(req, httptest.NewRecorder()))
+	}
+	alice := func(req *http.Request) { req.Header.Set("Remote-User", "alice") }
+	bob := func(req *http.Request) { req.SetBasicAuth("bob", "secret") }
+	anonymous := func(*http.Request) {}
+	search("boiler", alice)
+	search("passport", alice)
+	search("Passport", bob)
+	search("mortgage", anonymous)
+
+	// Alice reads their own history and an admin reads the analytics
+	historyRec := httptest.NewRecorder()
+	historyReq := httptest.NewRequest(http.MethodGet, "/api/search/history", nil)
+	alice(historyReq)
+	if err := handler.GetSearchHistory(handler.Echo.NewContext(historyReq, historyRec)); err != nil {
+		t.Fatalf("GetSearchHistory returned error: %v", err)
+	}
+	analyticsRec := httptest.NewRecorder()
+	if err := handler.GetSearchAnalytics(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/search/analytics", nil), analyticsRec)); err != nil {
+		t.Fatalf("GetSearchAnalytics returned error: %v", err)
+	}
+
+	// Alice sees only their own searches with their result counts, newest first
+	var history struct {
+		User     string
+		Searches []database.SearchQuery
+	}
+	if err := json.Unmarshal(historyRec.Body.Bytes(), &history); err != nil {
+		t.Fatalf("Failed to decode history %s: %v", historyRec.Body.String(), err)
+	}
+	if history.User != "alice" || len(history.Searches) != 2 || history.Searches[0].Term != "passport" || history.Searches[1].ResultCount != 1 {
+		t.Errorf("Unexpected history: %s", historyRec.Body.String())
+	}
+
+	// The analytics group zero-result terms across users, most searched first
+	var analytics struct {
+		ZeroResults []database.SearchTermCount
+	}
+	if err := json.Unmarshal(analyticsRec.Body.Bytes(), &analytics); err != nil {
+		t.Fatalf("Failed to decode analytics %s: %v", analyticsRec.Body.String(), err)
+	}
+	if len(analytics.ZeroResults) != 2 || analytics.ZeroResults[0].Term != "passport" || analytics.ZeroResults[0].Searches != 2 || analytics.ZeroResults[1].Term != "mortgage" {
+		t.Errorf("Unexpected analytics: %s", analyticsRec.Body.String())
+	}
+
+	// Invalid parameters are rejected
+	for _, target := range []string{"/api/search/analytics?days=0", "/api/search/analytics?limit=x"} {
+		rec := httptest.NewRecorder()
+		handler.GetSearchAnalytics(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// minSuggestPrefix is the shortest prefix that gets suggestions; shorter ones match too much to be useful
+	minSuggestPrefix = 2
+	// maxSuggestWords and maxSuggestDocuments cap each list in the search box dropdown
+	maxSuggestWords     = 8
+	maxSuggestDocuments = 5
+	// suggestMaxAge is how long, in seconds, the browser may reuse suggestions for the same prefix
+	suggestMaxAge = "60"
+)
+
+// lastSearchWord returns the word being typed at the end of a search box prefix, lower-cased, or
+// an empty string when the prefix ends with a separator or a number
+func lastSearchWord(prefix string) string {
+	parts := searchWords(prefix)
+	if len(parts) == 0 {
+		return ""
+	}
+	last := parts[len(parts)-1]
+	if !unicode.IsLetter([]rune(last)[0]) {
+		return ""
+	}
+	return last
+}
+
+// SuggestSearch completes what is typed in the search box
+// @Summary Autocomplete search terms
+// @Description Completes the last word of the prefix from the word cloud vocabulary, most frequent first, and lists documents whose name contains the prefix.
+// @Description Prefixes shorter than two characters get empty lists. Responses may be cached by the browser for a minute and carry an ETag, so a debounced search box can repeat requests cheaply.
+// @Tags Search
+// @Produce json
+// @Param prefix query string true "Text typed so far"
+// @Success 200 {object} map[string]interface{} "prefix, words, and documents with id, name, folder and url"
+// @Success 304 "Suggestions unchanged since the ETag in If-None-Match"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /search/suggest [get]
+func (serverHandler *ServerHandler) SuggestSearch(c echo.Context) error {
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		return err
+	}
+	prefix := strings.TrimSpace(c.QueryParam("prefix"))
+	suggestion := dto.SearchCompletions{Prefix: prefix, Words: []string{}, Documents: []dto.SuggestedDocument{}}
+
+	if len([]rune(prefix)) >= minSuggestPrefix {
+		if word := lastSearchWord(prefix); word != "" {
+			words, err := serverHandler.vocabulary.completions(serverHandler, word, maxSuggestWords)
+			if err != nil {
+				Logger.Error("Failed to complete search word", "prefix", prefix, "error", err)
+				return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"error": "Failed to retrieve suggestions",
+					"code":  dto.CodeInternal,
+				})
+			}
+			suggestion.Words = append(suggestion.Words, words...)
+		}
+
+		fetch := maxSuggestDocuments
+		if access != nil {
+			fetch = math.MaxInt32 // filtered before the limit is applied
+		}
+		documents, err := serverHandler.DB.SearchDocumentNames(prefix, fetch)
+		if err != nil {
+			Logger.Error("Failed to match document names", "prefix", prefix, "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to retrieve suggestions",
+				"code":  dto.CodeInternal,
+			})
+		}
+		documents = access.readable(documents)
+		if len(documents) > maxSuggestDocuments {
+			documents = documents[:maxSuggestDocuments]
+		}
+		for _, document := range documents {
+			suggestion.Documents = append(suggestion.Documents, dto.SuggestedDocument{
+				ID:     document.ULID.String(),
+				Name:   document.Name,
+				Folder: document.Folder,
+				URL:    document.URL,
+			})
+		}
+	}
+
+	body, err := json.Marshal(suggestion)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	header := c.Response().Header()
+	header.Set("Cache-Control", "private, max-age="+suggestMaxAge)
+	header.Set("ETag", etag)
+	if c.Request().Header.Get("If-None-Match") == etag {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSONBlob(http.StatusOK, body)
+}
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+func TestSuggestSearch(t *testing.T) {
+	// Word cloud words and documents, some named after what is being typed
+	handler := setupMemoryTestHandler(t, config.ServerConfig{DocumentPath: t.TempDir()})
+	saveTestDocument(t, handler.DB, "/docs/Invoice_March.pdf", "text")
+	saveTestDocument(t, handler.DB, "/docs/receipt.pdf", "text")
+	if err := handler.DB.AddWordFrequencies(map[string]int{"invoice": 5, "inventory": 2, "in": 9, "receipt": 1}); err != nil {
+		t.Fatalf("Failed to add word frequencies: %v", err)
+	}
+	suggest := func(prefix, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/search/suggest?prefix="+url.QueryEscape(prefix), nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		if err := handler.SuggestSearch(handler.Echo.NewContext(req, rec)); err != nil {
+			t.Fatalf("SuggestSearch failed: %v", err)
+		}
+		return rec
+	}
+
+	rec := suggest("Inv", "")
+
+	// Longer words come most frequent first, with the matching document, and the response is cacheable
+	var response dto.SearchCompletions
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode %d %q: %v", rec.Code, rec.Body.String(), err)
+	}
+	if !reflect.DeepEqual(response.Words, []string{"invoice", "inventory"}) {
+		t.Errorf("Expected invoice then inventory, got %q", response.Words)
+	}
+	if len(response.Documents) != 1 || response.Documents[0].Name != "Invoice_March.pdf" {
+		t.Errorf("Expected Invoice_March.pdf, got %+v", response.Documents)
+	}
+	if rec.Header().Get("Cache-Control") != "private, max-age=60" || rec.Header().Get("ETag") == "" {
+		t.Errorf("Expected caching headers, got %v", rec.Header())
+	}
+
+	// Repeating the request with its ETag is not modified
+	if again := suggest("Inv", rec.Header().Get("ETag")); again.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching ETag, got %d", again.Code)
+	}
+
+	// Only the last word is completed, and a one-letter prefix gets nothing
+	var lastWord, short dto.SearchCompletions
+	json.Unmarshal(suggest("march rec", "").Body.Bytes(), &lastWord)
+	json.Unmarshal(suggest("i", "").Body.Bytes(), &short)
+	if !reflect.DeepEqual(lastWord.Words, []string{"receipt"}) {
+		t.Errorf("Expected the last word completed, got %+v", lastWord)
+	}
+	if len(short.Words) != 0 || len(short.Documents) != 0 {
+		t.Errorf("Expected no suggestions for one letter, got %+v", short)
+	}
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// serviceClient is used to probe sidecar services; the timeout keeps health checks responsive
+var serviceClient = &http.Client{Timeout: 5 * time.Second}
+
+// serviceStatus is the health of one sidecar service
+type serviceStatus struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// configuredServices returns the sidecar services to check, keyed by name
+func (serverHandler *ServerHandler) configuredServices() map[string]string {
+	services := make(map[string]string)
+	if serverHandler.ServerConfig.PDFServiceURL != "" {
+		services["pdf"] = serverHandler.ServerConfig.PDFServiceURL
+	}
+	if serverHandler.ServerConfig.TesseractServiceURL != "" {
+		services["tesseract"] = serverHandler.ServerConfig.TesseractServiceURL
+	}
+	return services
+}
+
+// parseServiceAddress parses a configured service address; compose service names such as
+// "tesseract:8884" get an http:// scheme
+func parseServiceAddress(address string) (*url.URL, error) {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("no host in service address %q", address)
+	}
+	return parsed, nil
+}
+
+// serviceHealthURL turns a configured service address into the URL to probe.
+// /health is probed when no path is given.
+func serviceHealthURL(address string) (string, error) {
+	parsed, err := parseServiceAddress(address)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Path == "" || parsed.Path == "/" {
+		parsed.Path = "/health"
+	}
+	return parsed.String(), nil
+}
+
+// checkService probes a sidecar service once; any 2xx response counts as healthy
+func checkService(name, address string) serviceStatus {
+	status := serviceStatus{Name: name, URL: address}
+	healthURL, err := serviceHealthURL(address)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	resp, err := serviceClient.Get(healthURL)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		status.Error = fmt.Sprintf("health check returned %s", resp.Status)
+		return status
+	}
+	status.Healthy = true
+	return status
+}
+
+// serviceHealth checks every configured sidecar service once, sorted by name
+func (serverHandler *ServerHandler) serviceHealth() []serviceStatus {
+	statuses := []serviceStatus{}
+	for name, address := range serverHandler.configuredServices() {
+		statuses = append(statuses, checkService(name, address))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// waitForServices retries each configured sidecar until it answers, so godocs can start
+// before its sidecars in a compose stack. It returns an error naming any that never came up.
+func (serverHandler *ServerHandler) waitForServices(retries int, interval time.Duration) error {
+	var failed []string
+	for name, address := range serverHandler.configuredServices() {
+		status := checkService(name, address)
+		for attempt := 1; !status.Healthy && attempt <= retries; attempt++ {
+			Logger.Warn("Sidecar service not ready, retrying", "service", name, "url", address, "attempt", attempt, "error", status.Error)
+			time.Sleep(interval)
+			status = checkService(name, address)
+		}
+		if !status.Healthy {
+			Logger.Error("Sidecar service unavailable", "service", name, "url", address, "error", status.Error)
+			failed = append(failed, fmt.Sprintf("%s (%s): %s", name, address, status.Error))
+			continue
+		}
+		Logger.Info("Sidecar service healthy", "service", name, "url", address)
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("sidecar services unavailable: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// GetHealth reports whether the server and its sidecar services are up
+// @Summary Health check
+// @Description Returns 200 when the server and every configured sidecar service (PDF_SERVICE_URL, TESSERACT_SERVICE_URL) are healthy, 503 otherwise.
+// @Description Suitable for container orchestration health checks.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Healthy"
+// @Failure 503 {object} map[string]interface{} "A sidecar service is unhealthy"
+// @Router /health [get]
+func (serverHandler *ServerHandler) GetHealth(c echo.Context) error {
+	services := serverHandler.serviceHealth()
+	status, code := "healthy", http.StatusOK
+	for _, service := range services {
+		if !service.Healthy {
+			status, code = "unhealthy", http.StatusServiceUnavailable
+		}
+	}
+	return c.JSON(code, map[string]interface{}{
+		"status":   status,
+		"service":  "godocs",
+		"services": services,
+	})
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServiceHealthURL(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+		wantErr bool
+	}{
+		{"tesseract:8884", "http://tesseract:8884/health", false},
+		{"http://pdf-service:3000/", "http://pdf-service:3000/health", false},
+		{"https://ocr.example.com/ping", "https://ocr.example.com/ping", false},
+		{"http://", "", true},
+	}
+	for _, tt := range tests {
+		got, err := serviceHealthURL(tt.address)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("serviceHealthURL(%q) = %q, %v; want %q, err=%v", tt.address, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWaitForServicesRetriesUntilHealthy(t *testing.T) {
+	// A sidecar that only becomes healthy on the third probe
+	probes := 0
+	sidecar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes++
+		if r.URL.Path != "/health" || probes < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer sidecar.Close()
+	handler := setupTestHandler(t)
+	handler.ServerConfig.PDFServiceURL = sidecar.URL
+
+	// Waiting with enough retries
+	err := handler.waitForServices(5, time.Millisecond)
+
+	// Startup succeeds once the sidecar answers
+	if err != nil || probes != 3 {
+		t.Errorf("Expected success after 3 probes, got %d probes, %v", probes, err)
+	}
+}
+
+func TestWaitForServicesFailsLoudly(t *testing.T) {
+	// A tesseract sidecar that never answers
+	sidecar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer sidecar.Close()
+	handler := setupTestHandler(t)
+	handler.ServerConfig.TesseractServiceURL = sidecar.URL
+
+	err := handler.waitForServices(2, time.Millisecond)
+
+	// The error names the missing service
+	if err == nil || !strings.Contains(err.Error(), "tesseract") {
+		t.Errorf("Expected error naming tesseract, got %v", err)
+	}
+}
+
+func TestGetHealthReportsUnhealthySidecar(t *testing.T) {
+	// One healthy and one unreachable sidecar
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+	handler := setupTestHandler(t)
+	handler.ServerConfig.PDFServiceURL = healthy.URL
+	handler.ServerConfig.TesseractServiceURL = down.URL
+	handler.Echo.GET("/api/health", handler.GetHealth)
+
+	// The health endpoint is called
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+
+	// It returns 503 with both services listed
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503, got %d", rec.Code)
+	}
+	var response struct {
+		Status   string          `json:"status"`
+		Services []serviceStatus `json:"services"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if response.Status != "unhealthy" || len(response.Services) != 2 ||
+		!response.Services[0].Healthy || response.Services[1].Healthy {
+		t.Errorf("Unexpected health response %+v", response)
+	}
+}
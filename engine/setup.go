@@ -0,0 +1,312 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// setupDatabaseTypes are the database choices offered by the setup wizard
+var setupDatabaseTypes = []string{"sqlite", "postgres", "cockroachdb", "ephemeral", "memory"}
+
+// setupMinPasswordLength is the shortest admin password the wizard accepts
+const setupMinPasswordLength = 8
+
+// setupDatabase is the database step of the setup wizard
+type setupDatabase struct {
+	Type     string `json:"type"`
+	Host     string `json:"host,omitempty"`
+	Port     string `json:"port,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+	Name     string `json:"name,omitempty"`
+	SSLMode  string `json:"sslmode,omitempty"`
+}
+
+// setupRequest holds every answer from the setup wizard
+type setupRequest struct {
+	Database      setupDatabase `json:"database"`
+	DocumentPath  string        `json:"documentPath"`
+	IngressPath   string        `json:"ingressPath"`
+	TesseractPath string        `json:"tesseractPath,omitempty"`
+	Extensions    string        `json:"extensions,omitempty"` // comma separated, e.g. "pdf,png,jpg"
+	AdminUser     string        `json:"adminUser"`
+	AdminPassword string        `json:"adminPassword,omitempty"`
+}
+
+// ocrStatus reports what OCR support was found on this machine
+type ocrStatus struct {
+	TesseractPath string `json:"tesseractPath"`
+	Available     bool   `json:"available"`
+	Version       string `json:"version,omitempty"`
+	ServiceURL    string `json:"serviceURL,omitempty"`
+}
+
+// setupStatus is returned by GET /api/setup
+type setupStatus struct {
+	Required      bool         `json:"required"`
+	ConfigFile    string       `json:"configFile"`
+	DatabaseTypes []string     `json:"databaseTypes"`
+	Defaults      setupRequest `json:"defaults"`
+	OCR           ocrStatus    `json:"ocr"`
+}
+
+// detectOCR looks for tesseract at the configured path, then on PATH, and asks it for its version
+func detectOCR(serverConfig config.ServerConfig) ocrStatus {
+	status := ocrStatus{ServiceURL: serverConfig.TesseractServiceURL}
+	candidates := []string{serverConfig.TesseractPath}
+	if found, err := exec.LookPath("tesseract"); err == nil {
+		candidates = append(candidates, found)
+	}
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if info, err := os.Stat(candidate); err != nil || info.IsDir() {
+			continue
+		}
+		status.TesseractPath = candidate
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		output, err := exec.CommandContext(ctx, candidate, "--version").CombinedOutput()
+		cancel()
+		if err != nil {
+			Logger.Warn("Tesseract found but did not run", "path", candidate, "error", err)
+			continue
+		}
+		status.Available = true
+		status.Version = strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0])
+		return status
+	}
+	return status
+}
+
+// checkWritableDir reports whether dir, or the nearest folder above it that exists, accepts new files.
+// Nothing is created apart from a probe file that is removed straight away.
+func checkWritableDir(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("must be an absolute path")
+	}
+	existing := filepath.Clean(dir)
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a folder", existing)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return fmt.Errorf("no existing parent folder")
+		}
+		existing = parent
+	}
+	probe, err := os.CreateTemp(existing, ".godocs-write-check-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable", existing)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// validateSetup checks every wizard answer and returns a message for each field that is wrong
+func validateSetup(req setupRequest) map[string]string {
+	problems := make(map[string]string)
+	db := req.Database
+	switch {
+	case !slices.Contains(setupDatabaseTypes, db.Type):
+		problems["database.type"] = "must be one of " + strings.Join(setupDatabaseTypes, ", ")
+	case db.Type == "postgres" || db.Type == "cockroachdb":
+		if db.Host == "" {
+			problems["database.host"] = "is required"
+		}
+		if db.Name == "" {
+			problems["database.name"] = "is required"
+		}
+		if db.Port != "" {
+			if port, err := strconv.Atoi(db.Port); err != nil || port < 1 || port > 65535 {
+				problems["database.port"] = "must be a port number"
+			}
+		}
+	case db.Type == "sqlite":
+		if db.Name == "" {
+			problems["database.name"] = "is required"
+		} else if err := checkWritableDir(absPath(filepath.Dir(db.Name))); err != nil {
+			problems["database.name"] = err.Error()
+		}
+	}
+
+	for field, dir := range map[string]string{"documentPath": req.DocumentPath, "ingressPath": req.IngressPath} {
+		if dir == "" {
+			problems[field] = "is required"
+		} else if err := checkWritableDir(dir); err != nil {
+			problems[field] = err.Error()
+		}
+	}
+	if req.DocumentPath != "" && filepath.Clean(req.DocumentPath) == filepath.Clean(req.IngressPath) {
+		problems["ingressPath"] = "must be different from the document path"
+	}
+
+	if req.TesseractPath != "" {
+		if info, err := os.Stat(req.TesseractPath); err != nil || info.IsDir() {
+			problems["tesseractPath"] = "no tesseract executable at this path"
+		}
+	}
+
+	if strings.TrimSpace(req.Extensions) != "" {
+		if _, err := config.ParseExtensions(req.Extensions); err != nil {
+			problems["extensions"] = err.Error()
+		}
+	}
+
+	if strings.TrimSpace(req.AdminUser) == "" {
+		problems["adminUser"] = "is required"
+	}
+	if len(req.AdminPassword) < setupMinPasswordLength {
+		problems["adminPassword"] = fmt.Sprintf("must be at least %d characters", setupMinPasswordLength)
+	}
+	return problems
+}
+
+// absPath makes a relative path absolute against the working directory
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// setupSettings turns wizard answers into the environment variables config.SetupServer reads
+func setupSettings(req setupRequest) map[string]string {
+	settings := map[string]string{
+		"DATABASE_TYPE":   req.Database.Type,
+		"DOCUMENT_PATH":   filepath.Clean(req.DocumentPath),
+		"INGRESS_PATH":    filepath.Clean(req.IngressPath),
+		"WEB_UI_AUTH":     "true",
+		"WEB_UI_USER":     strings.TrimSpace(req.AdminUser),
+		"WEB_UI_PASSWORD": req.AdminPassword,
+	}
+	db := req.Database
+	for key, value := range map[string]string{
+		"DATABASE_HOST":     db.Host,
+		"DATABASE_PORT":     db.Port,
+		"DATABASE_USER":     db.User,
+		"DATABASE_PASSWORD": db.Password,
+		"DATABASE_NAME":     db.Name,
+		"DATABASE_SSLMODE":  db.SSLMode,
+		"TESSERACT_PATH":    req.TesseractPath,
+	} {
+		if value != "" {
+			settings[key] = value
+		}
+	}
+	if extensions, err := config.ParseExtensions(req.Extensions); err == nil {
+		settings["PROCESSABLE_EXTENSIONS"] = strings.Join(extensions, ",")
+	}
+	return settings
+}
+
+// GetSetup reports whether first-run setup is needed, with suggested answers and detected OCR support
+// @Summary Get first-run setup status
+// @Description Report whether godocs still needs configuring, the database types on offer, suggested paths and whether tesseract was found
+// @Tags Setup
+// @Produce json
+// @Success 200 {object} setupStatus "Setup status"
+// @Router /setup [get]
+func (serverHandler *ServerHandler) GetSetup(c echo.Context) error {
+	cfg := serverHandler.ServerConfig
+	ocr := detectOCR(cfg)
+	return c.JSON(http.StatusOK, setupStatus{
+		Required:      !config.Configured(),
+		ConfigFile:    absPath(config.SetupFile),
+		DatabaseTypes: setupDatabaseTypes,
+		Defaults: setupRequest{
+			Database:      setupDatabase{Type: "sqlite", Name: absPath(filepath.Join("databases", "godocs.sqlite"))},
+			DocumentPath:  absPath("documents"),
+			IngressPath:   absPath("ingress"),
+			TesseractPath: ocr.TesseractPath,
+			Extensions:    strings.Join(config.DefaultProcessableExtensions, ","),
+			AdminUser:     "admin",
+		},
+		OCR: ocr,
+	})
+}
+
+// SaveSetup validates the wizard answers and, unless dryRun is set, creates the folders and writes the config file.
+// It is refused once godocs is configured; the new settings apply after a restart.
+// @Summary Save first-run setup
+// @Description Validate the setup wizard answers (database, document and ingress folders, accepted file types, tesseract, admin account) and write them to the config file. Only allowed while no configuration exists.
+// @Tags Setup
+// @Accept json
+// @Produce json
+// @Param dryRun query bool false "Only validate the answers (default: false)"
+// @Param setup body setupRequest true "Setup answers"
+// @Success 200 {object} map[string]interface{} "Answers are valid, or were saved"
+// @Failure 400 {object} map[string]interface{} "Invalid answers, with a message per field"
+// @Failure 409 {object} map[string]interface{} "godocs is already configured"
+// @Failure 500 {object} map[string]interface{} "Unable to write the configuration"
+// @Router /setup [post]
+func (serverHandler *ServerHandler) SaveSetup(c echo.Context) error {
+	if config.Configured() {
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": "godocs is already configured; edit the config file to change settings",
+			"code":  dto.CodeConflict,
+		})
+	}
+	dryRun, err := boolQueryParam(c, "dryRun")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "dryRun must be true or false", "code": dto.CodeBadRequest})
+	}
+	var req setupRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid setup request", "code": dto.CodeBadRequest})
+	}
+	if problems := validateSetup(req); len(problems) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Some settings need fixing",
+			"code":   dto.CodeValidation,
+			"fields": problems,
+		})
+	}
+	if dryRun {
+		return c.JSON(http.StatusOK, map[string]interface{}{"valid": true})
+	}
+
+	for _, dir := range []string{req.DocumentPath, req.IngressPath} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			Logger.Error("Unable to create folder during setup", "path", dir, "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Unable to create " + dir, "code": dto.CodeInternal})
+		}
+	}
+	if req.Database.Type == "sqlite" {
+		if err := os.MkdirAll(filepath.Dir(absPath(req.Database.Name)), 0755); err != nil {
+			Logger.Error("Unable to create database folder during setup", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Unable to create the database folder", "code": dto.CodeInternal})
+		}
+	}
+	if err := config.WriteSetupFile(setupSettings(req)); err != nil {
+		Logger.Error("Unable to write setup config file", "path", config.SetupFile, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Unable to write the config file", "code": dto.CodeInternal})
+	}
+
+	Logger.Info("First-run setup saved", "configFile", absPath(config.SetupFile), "databaseType", req.Database.Type)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":         "Setup saved. Restart godocs to start using the new settings.",
+		"configFile":      absPath(config.SetupFile),
+		"restartRequired": true,
+	})
+}
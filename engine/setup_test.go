@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/joho/godotenv"
+	"github.com/labstack/echo/v4"
+)
+
+// unconfigured points the config files at an empty temp dir and clears the environment,
+// so the server looks like a fresh install. It returns the temp dir.
+func unconfigured(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files, setupFile := config.ConfigFiles, config.SetupFile
+	config.ConfigFiles = []string{filepath.Join(dir, "godocs.env")}
+	config.SetupFile = config.ConfigFiles[0]
+	t.Cleanup(func() { config.ConfigFiles, config.SetupFile = files, setupFile })
+	for _, key := range []string{"DATABASE_TYPE", "DATABASE_HOST", "DATABASE_NAME", "DOCUMENT_PATH", "INGRESS_PATH"} {
+		t.Setenv(key, "")
+	}
+	return dir
+}
+
+// postSetup sends body to SaveSetup and returns the recorder
+func postSetup(t *testing.T, handler *ServerHandler, query string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/setup"+query, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	if err := handler.SaveSetup(handler.Echo.NewContext(req, rec)); err != nil {
+		t.Fatalf("SaveSetup returned error: %v", err)
+	}
+	return rec
+}
+
+func TestGetSetupOnFreshInstall(t *testing.T) {
+	// No config file and no settings in the environment
+	unconfigured(t)
+	handler := setupMemoryTestHandler(t, config.ServerConfig{})
+
+	// The setup status is requested
+	req := httptest.NewRequest(http.MethodGet, "/api/setup", nil)
+	rec := httptest.NewRecorder()
+	if err := handler.GetSetup(handler.Echo.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetSetup returned error: %v", err)
+	}
+
+	// Setup is required and absolute default paths are suggested
+	var status setupStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !status.Required {
+		t.Error("Expected setup to be required")
+	}
+	if status.Defaults.Database.Type != "sqlite" || !filepath.IsAbs(status.Defaults.DocumentPath) {
+		t.Errorf("Unexpected defaults: %+v", status.Defaults)
+	}
+}
+
+func TestSaveSetupValidatesFields(t *testing.T) {
+	// A fresh install
+	unconfigured(t)
+	handler := setupMemoryTestHandler(t, config.ServerConfig{})
+
+	// Answers with a relative document path, matching folders, a bad file type and a short password are checked
+	rec := postSetup(t, handler, "?dryRun=true", `{"database":{"type":"postgres"},"documentPath":"docs","ingressPath":"docs","extensions":"pdf,tar.gz","adminUser":"admin","adminPassword":"short"}`)
+
+	// Each bad field is reported
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result struct {
+		Fields map[string]string `json:"fields"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	for _, field := range []string{"database.host", "database.name", "documentPath", "ingressPath", "extensions", "adminPassword"} {
+		if result.Fields[field] == "" {
+			t.Errorf("Expected a problem reported for %s, got %v", field, result.Fields)
+		}
+	}
+	if _, ok := result.Fields["adminUser"]; ok {
+		t.Errorf("Did not expect a problem with adminUser")
+	}
+}
+
+func TestSaveSetupWritesConfigOnce(t *testing.T) {
+	// A fresh install and valid answers
+	dir := unconfigured(t)
+	handler := setupMemoryTestHandler(t, config.ServerConfig{})
+	body, _ := json.Marshal(setupRequest{
+		Database:      setupDatabase{Type: "sqlite", Name: filepath.Join(dir, "db", "godocs.sqlite")},
+		DocumentPath:  filepath.Join(dir, "documents"),
+		IngressPath:   filepath.Join(dir, "ingress"),
+		Extensions:    "PDF, png",
+		AdminUser:     "owner",
+		AdminPassword: "correct horse",
+	})
+
+	// They are checked, then saved
+	dryRun := postSetup(t, handler, "?dryRun=true", string(body))
+	_, statErr := os.Stat(config.SetupFile)
+	saved := postSetup(t, handler, "", string(body))
+
+	// The dry run writes nothing, the save writes the settings and further saves are refused
+	if dryRun.Code != http.StatusOK {
+		t.Fatalf("Expected dry run to pass, got %d: %s", dryRun.Code, dryRun.Body.String())
+	}
+	if !os.IsNotExist(statErr) {
+		t.Error("Expected the dry run not to write the config file")
+	}
+	if saved.Code != http.StatusOK {
+		t.Fatalf("Expected save to succeed, got %d: %s", saved.Code, saved.Body.String())
+	}
+	settings, err := godotenv.Read(config.SetupFile)
+	if err != nil {
+		t.Fatalf("Config file not written: %v", err)
+	}
+	if settings["DATABASE_TYPE"] != "sqlite" || settings["WEB_UI_USER"] != "owner" || settings["WEB_UI_PASSWORD"] != "correct horse" {
+		t.Errorf("Unexpected settings: %v", settings)
+	}
+	if settings["DOCUMENT_PATH"] != filepath.Join(dir, "documents") {
+		t.Errorf("Expected document path to be saved, got %q", settings["DOCUMENT_PATH"])
+	}
+	if settings["PROCESSABLE_EXTENSIONS"] != ".pdf,.png" {
+		t.Errorf("Expected normalised file types to be saved, got %q", settings["PROCESSABLE_EXTENSIONS"])
+	}
+	if again := postSetup(t, handler, "", string(body)); again.Code != http.StatusConflict {
+		t.Errorf("Expected 409 once configured, got %d", again.Code)
+	}
+}
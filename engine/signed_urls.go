@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// documentViewPrefix is the path every document file is served under
+const documentViewPrefix = "/document/view/"
+
+// maxSignedURLTTL caps how long a requested signed URL may stay valid
+const maxSignedURLTTL = 7 * 24 * time.Hour
+
+// signDocumentURL returns the HMAC signature for a document ULID and expiry unix time
+func (serverHandler *ServerHandler) signDocumentURL(ulidStr string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(serverHandler.ServerConfig.URLSigningKey))
+	fmt.Fprintf(mac, "%s.%d", ulidStr, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signedDocumentURL builds a view URL for the document that stops working at expires
+func (serverHandler *ServerHandler) signedDocumentURL(ulidStr string, expires time.Time) string {
+	unix := expires.Unix()
+	return fmt.Sprintf("%s%s?expires=%d&sig=%s", documentViewPrefix, ulidStr, unix, serverHandler.signDocumentURL(ulidStr, unix))
+}
+
+// verifyDocumentSignature checks the expires and sig query values for a document ULID
+func (serverHandler *ServerHandler) verifyDocumentSignature(ulidStr, expiresParam, sig string, now time.Time) bool {
+	expires, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil || now.Unix() > expires {
+		return false
+	}
+	expected := serverHandler.signDocumentURL(ulidStr, expires)
+	return hmac.Equal([]byte(expected), []byte(sig))
+}
+
+// DocumentViewAuth is middleware guarding /document/view/ routes.
+// A signed URL that has expired or been tampered with is always rejected; unsigned URLs
+// are only rejected when RequireSignedURLs is set.
+func (serverHandler *ServerHandler) DocumentViewAuth() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			path := c.Request().URL.Path
+			if !strings.HasPrefix(path, documentViewPrefix) {
+				return next(c)
+			}
+			sig := c.QueryParam("sig")
+			if sig == "" && !serverHandler.ServerConfig.RequireSignedURLs {
+				return next(c)
+			}
+			ulidStr := strings.TrimPrefix(path, documentViewPrefix)
+			if !serverHandler.verifyDocumentSignature(ulidStr, c.QueryParam("expires"), sig, database.Now()) {
+				return c.JSON(http.StatusForbidden, map[string]interface{}{
+					"error": "Document link is invalid or has expired",
+					"code":  dto.CodeForbidden,
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// GetSignedDocumentURL issues a short-lived signed view URL for a document
+// @Summary Get a signed document URL
+// @Description Generate a temporary URL for viewing a document that works without auth headers and expires automatically
+// @Tags Documents
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Param ttl query int false "Seconds the link stays valid (default: SIGNED_URL_TTL, max: 7 days)"
+// @Success 200 {object} map[string]interface{} "Signed URL and expiry time"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID or ttl"
+// @Failure 404 {object} map[string]interface{} "Document not found"
+// @Router /document/{id}/signed-url [get]
+func (serverHandler *ServerHandler) GetSignedDocumentURL(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "document")
+	if !ok {
+		return err
+	}
+
+	ttl := time.Duration(serverHandler.ServerConfig.SignedURLTTL) * time.Second
+	if ttlParam := c.QueryParam("ttl"); ttlParam != "" {
+		seconds, err := strconv.Atoi(ttlParam)
+		if err != nil || seconds <= 0 || time.Duration(seconds)*time.Second > maxSignedURLTTL {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid ttl, expected seconds between 1 and 604800",
+				"code":  dto.CodeBadRequest,
+			})
+		}
+		ttl = time.Duration(seconds) * time.Second
+	}
+
+	document, err := serverHandler.DB.GetDocumentByULID(id.String())
+	if err == nil && document != nil {
+		// A link works without a session, so only documents the account may read are signed
+		var access *folderAccess
+		if access, err = serverHandler.folderAccess(c); err == nil && !access.canRead(document.Folder) {
+			document = nil
+		}
+	}
+	if err != nil || document == nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+
+	expires := database.Now().Add(ttl)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"url":     serverHandler.signedDocumentURL(id.String(), expires),
+		"expires": expires.UTC().Format(time.RFC3339),
+	})
+}
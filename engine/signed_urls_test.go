@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestVerifyDocumentSignature(t *testing.T) {
+	handler := &ServerHandler{}
+	handler.ServerConfig.URLSigningKey = "test-key"
+	now := time.Unix(1700000000, 0)
+	expires := now.Add(time.Minute).Unix()
+	sig := handler.signDocumentURL("01HXYZ", expires)
+
+	tests := []struct {
+		name    string
+		ulid    string
+		expires int64
+		at      time.Time
+		want    bool
+	}{
+		{"valid", "01HXYZ", expires, now, true},
+		{"expired", "01HXYZ", expires, now.Add(2 * time.Minute), false},
+		{"other document", "01HABC", expires, now, false},
+		{"extended expiry", "01HXYZ", expires + 3600, now, false},
+	}
+	for _, tt := range tests {
+		got := handler.verifyDocumentSignature(tt.ulid, strconv.FormatInt(tt.expires, 10), sig, tt.at)
+		if got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSignedDocumentURLRoundTrip(t *testing.T) {
+	// A document served under /document/view with signatures required
+	handler := setupTestHandler(t)
+	handler.ServerConfig.URLSigningKey = "test-key"
+	handler.ServerConfig.SignedURLTTL = 60
+	handler.ServerConfig.RequireSignedURLs = true
+	handler.Echo.Use(handler.DocumentViewAuth())
+	handler.Echo.GET("/api/document/:id/signed-url", handler.GetSignedDocumentURL)
+	doc := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "a.pdf"), "")
+	handler.Echo.GET(documentViewPrefix+doc.ULID.String(), func(c echo.Context) error {
+		return c.String(http.StatusOK, "file")
+	})
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	// A signed URL is requested
+	rec := get("/api/document/" + doc.ULID.String() + "/signed-url")
+	var response map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Failed to get signed URL: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// The signed URL serves the file while the bare and tampered ones are refused
+	if rec := get(response["url"]); rec.Code != http.StatusOK {
+		t.Errorf("Expected signed URL to work, got %d", rec.Code)
+	}
+	if rec := get(documentViewPrefix + doc.ULID.String()); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected unsigned URL to be refused, got %d", rec.Code)
+	}
+	if rec := get(response["url"] + "x"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected tampered URL to be refused, got %d", rec.Code)
+	}
+	if rec := get("/api/document/not-a-ulid/signed-url"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected invalid ULID to be rejected, got %d", rec.Code)
+	}
+}
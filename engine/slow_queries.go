@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// GetSlowQueries lists the slowest recent database queries
+// @Summary Slow queries
+// @Description The most recent queries, up to 100, that took at least SLOW_QUERY_MS, slowest first, with the handler or job that ran each. Use it to find a missing index as the document count grows. With sign-in or ADMIN_USERS only administrators may read it.
+// @Tags System
+// @Produce json
+// @Param limit query int false "Return at most this many"
+// @Success 200 {object} map[string]interface{} "thresholdMs and queries"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Router /admin/slow-queries [get]
+func (serverHandler *ServerHandler) GetSlowQueries(c echo.Context) error {
+	if serverHandler.notAdmin(c) {
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": "Only administrators may read slow queries",
+			"code":  dto.CodeForbidden,
+		})
+	}
+	queries := database.SlowQueries()
+	if limit, err := strconv.Atoi(c.QueryParam("limit")); err == nil && limit >= 0 && limit < len(queries) {
+		queries = queries[:limit]
+	}
+	if queries == nil {
+		queries = []database.SlowQuery{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"thresholdMs": serverHandler.ServerConfig.SlowQueryMS,
+		"queries":     queries,
+	})
+}
@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetSlowQueries(t *testing.T) {
+	// A server logging queries over 250ms with alice as its administrator
+	handler := setupTestHandler(t)
+	handler.ServerConfig.SlowQueryMS = 250
+	handler.ServerConfig.AdminUsers = []string{"alice"}
+	handler.Echo.GET("/api/admin/slow-queries", handler.GetSlowQueries)
+	get := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/slow-queries?limit=5", nil)
+		req.SetBasicAuth(user, "secret")
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Bob and alice ask for the slow queries
+	refused, listed := get("bob"), get("alice")
+
+	// Only alice is answered, with the threshold and a list
+	if refused.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for bob, got %d", refused.Code)
+	}
+	var response struct {
+		ThresholdMs int               `json:"thresholdMs"`
+		Queries     []json.RawMessage `json:"queries"`
+	}
+	if err := json.Unmarshal(listed.Body.Bytes(), &response); err != nil || listed.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with JSON, got %d %s", listed.Code, listed.Body)
+	}
+	if response.ThresholdMs != 250 || response.Queries == nil || len(response.Queries) > 5 {
+		t.Errorf("Expected the threshold and at most 5 queries, got %+v", response)
+	}
+}
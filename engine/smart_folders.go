@@ -0,0 +1,368 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// smartFolderRequest is the body of CreateSmartFolder
+type smartFolderRequest struct {
+	Name          string `json:"name"`
+	Term          string `json:"term"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	Tag           string `json:"tag"`
+	Correspondent string `json:"correspondent"`
+}
+
+// smartFolderDateLayout is how smart folder dates are written
+const smartFolderDateLayout = "2006-01-02"
+
+// smartFolderRange is the ingestion times a smart folder covers; zero ends are open
+type smartFolderRange struct {
+	from  time.Time
+	until time.Time // exclusive, the day after the folder's To date
+}
+
+// parseSmartFolderRange reads a smart folder's From and To days in local time
+func parseSmartFolderRange(from, to string) (smartFolderRange, error) {
+	var dates smartFolderRange
+	var err error
+	if from != "" {
+		if dates.from, err = time.ParseInLocation(smartFolderDateLayout, from, time.Local); err != nil {
+			return dates, err
+		}
+	}
+	if to != "" {
+		if dates.until, err = time.ParseInLocation(smartFolderDateLayout, to, time.Local); err != nil {
+			return dates, err
+		}
+		dates.until = dates.until.AddDate(0, 0, 1)
+	}
+	return dates, nil
+}
+
+// contains reports whether a document ingested at t falls in the range
+func (dates smartFolderRange) contains(t time.Time) bool {
+	if !dates.from.IsZero() && t.Before(dates.from) {
+		return false
+	}
+	return dates.until.IsZero() || t.Before(dates.until)
+}
+
+// validateSmartFolder returns the problems with a new smart folder by field, or nil
+func validateSmartFolder(request smartFolderRequest) map[string]string {
+	problems := make(map[string]string)
+	if request.Name == "" {
+		problems["name"] = "A smart folder needs a name"
+	}
+	parsed := make(map[string]time.Time)
+	for field, value := range map[string]string{"from": request.From, "to": request.To} {
+		if value == "" {
+			continue
+		}
+		day, err := time.ParseInLocation(smartFolderDateLayout, value, time.Local)
+		if err != nil {
+			problems[field] = "Expected a date as YYYY-MM-DD"
+			continue
+		}
+		parsed[field] = day
+	}
+	if from, ok := parsed["from"]; ok {
+		if to, ok := parsed["to"]; ok && to.Before(from) {
+			problems["to"] = "Must not be before from"
+		}
+	}
+	if request.Tag != "" {
+		if _, ok := normalizeTag(request.Tag); !ok {
+			problems["tag"] = "A tag is 1 to 50 characters without a slash"
+		}
+	}
+	if request.Term == "" && request.From == "" && request.To == "" && request.Tag == "" && request.Correspondent == "" {
+		problems["term"] = "A smart folder needs a search term, a date range, a tag or a correspondent"
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
+
+// smartFolderDocuments finds the documents a smart folder shows now, without their text and sorted by
+// name. all is every document, as listDocumentsByName returns, for folders with only a date range.
+func smartFolderDocuments(db database.Repository, folder database.SmartFolder, all []database.Document) ([]database.Document, error) {
+	dates, err := parseSmartFolderRange(folder.From, folder.To)
+	if err != nil {
+		return nil, err
+	}
+	candidates := all
+	if folder.Term != "" {
+		if candidates, err = db.SearchDocuments(folder.Term); err != nil {
+			return nil, err
+		}
+	}
+	var tagged map[string]bool
+	if folder.Tag != "" {
+		documents, err := db.GetDocumentsByTag(folder.Tag)
+		if err != nil {
+			return nil, err
+		}
+		if folder.Term == "" {
+			candidates = documents
+		}
+		tagged = make(map[string]bool, len(documents))
+		for _, document := range documents {
+			tagged[document.ULID.String()] = true
+		}
+	}
+	var correspondents map[string]string
+	if folder.Correspondent != "" {
+		if correspondents, err = documentCorrespondents(db); err != nil {
+			return nil, err
+		}
+	}
+	documents := []database.Document{}
+	for _, document := range candidates {
+		if tagged != nil && !tagged[document.ULID.String()] {
+			continue
+		}
+		if correspondents != nil && !strings.EqualFold(correspondents[document.ULID.String()], folder.Correspondent) {
+			continue
+		}
+		if dates.contains(document.IngressTime) {
+			document.FullText = ""
+			documents = append(documents, document)
+		}
+	}
+	sort.SliceStable(documents, func(i, j int) bool { return documents[i].Name < documents[j].Name })
+	return documents, nil
+}
+
+// appendSmartFolders adds each smart folder to the tree under the root, beside the real folders,
+// with the documents it finds now. Document nodes get IDs of their own so a document can also be
+// listed in its real folder.
+func appendSmartFolders(tree *dto.FileSystem, db database.Repository, all []database.Document) error {
+	folders, err := db.ListSmartFolders()
+	if err != nil {
+		return err
+	}
+	root := &tree.FileSystem[0]
+	for _, folder := range folders {
+		documents, err := smartFolderDocuments(db, folder, all)
+		if err != nil {
+			return err
+		}
+		folderID := folder.ULID.String()
+		node := dto.FileTreeNode{
+			ID:          "smart-" + folderID,
+			Name:        folder.Name,
+			Openable:    true,
+			IsDir:       true,
+			ParentID:    root.ID,
+			SmartFolder: folderID,
+		}
+		var children []dto.FileTreeNode
+		for _, document := range documents {
+			child := documentTreeNode(document, node.ID)
+			child.ID = node.ID + "-" + child.ULID
+			child.SmartFolder = folderID
+			node.ChildrenIDs = append(node.ChildrenIDs, document.Name)
+			children = append(children, child)
+		}
+		root = &tree.FileSystem[0] // appending may have moved the slice
+		root.ChildrenIDs = append(root.ChildrenIDs, folder.Name)
+		tree.FileSystem = append(tree.FileSystem, node)
+		tree.FileSystem = append(tree.FileSystem, children...)
+	}
+	return nil
+}
+
+// smartFolderResponse describes a smart folder with the link to its documents
+func smartFolderResponse(folder *database.SmartFolder) map[string]interface{} {
+	return map[string]interface{}{
+		"smartFolder": folder,
+		"url":         "/api/smartfolders/" + folder.ULID.String(),
+	}
+}
+
+// CreateSmartFolder saves a query as a smart folder
+// @Summary Create a smart folder
+// @Description Save a query as a virtual folder that appears in the document tree beside the real folders.
+// @Description Its documents are found each time it is listed: those matching term, ingested between from and to (inclusive, YYYY-MM-DD)
+// @Description, tagged tag and read by the extraction template of correspondent (matched ignoring case).
+// @Tags Folders
+// @Accept json
+// @Produce json
+// @Param smartFolder body smartFolderRequest true "name, and any of term, a from/to date range, tag and correspondent"
+// @Success 201 {object} map[string]interface{} "The smart folder with its url"
+// @Failure 400 {object} map[string]interface{} "Problems by field"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /smartfolders [post]
+func (serverHandler *ServerHandler) CreateSmartFolder(c echo.Context) error {
+	var request smartFolderRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	request.Term = strings.TrimSpace(request.Term)
+	request.From = strings.TrimSpace(request.From)
+	request.To = strings.TrimSpace(request.To)
+	request.Correspondent = strings.TrimSpace(request.Correspondent)
+	if tag, ok := normalizeTag(request.Tag); ok {
+		request.Tag = tag
+	}
+	if problems := validateSmartFolder(request); problems != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "The smart folder needs fixing",
+			"code":   dto.CodeValidation,
+			"fields": problems,
+		})
+	}
+
+	folder := &database.SmartFolder{Name: request.Name, Term: request.Term, From: request.From, To: request.To, Tag: request.Tag,
+		Correspondent: request.Correspondent}
+	if err := serverHandler.DB.CreateSmartFolder(folder); err != nil {
+		Logger.Error("Failed to create smart folder", "name", folder.Name, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to create smart folder",
+			"code":  dto.CodeInternal,
+		})
+	}
+	serverHandler.invalidateDocumentCache()
+	Logger.Info("Created smart folder", "ulid", folder.ULID.String(), "name", folder.Name, "term", folder.Term, "from", folder.From, "to", folder.To, "tag", folder.Tag, "correspondent", folder.Correspondent)
+	return c.JSON(http.StatusCreated, smartFolderResponse(folder))
+}
+
+// ListSmartFolders lists the smart folders
+// @Summary List smart folders
+// @Description All smart folders by name, with how many documents each finds now
+// @Tags Folders
+// @Produce json
+// @Success 200 {object} map[string]interface{} "smartFolders and count"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /smartfolders [get]
+func (serverHandler *ServerHandler) ListSmartFolders(c echo.Context) error {
+	folders, err := serverHandler.DB.ListSmartFolders()
+	if err == nil && len(folders) > 0 {
+		var all []database.Document
+		if all, err = listDocumentsByName(serverHandler.DB); err == nil {
+			listed := make([]map[string]interface{}, 0, len(folders))
+			for i := range folders {
+				var documents []database.Document
+				if documents, err = smartFolderDocuments(serverHandler.DB, folders[i], all); err != nil {
+					break
+				}
+				response := smartFolderResponse(&folders[i])
+				response["documentCount"] = len(documents)
+				listed = append(listed, response)
+			}
+			if err == nil {
+				return c.JSON(http.StatusOK, map[string]interface{}{
+					"smartFolders": listed,
+					"count":        len(listed),
+				})
+			}
+		}
+	}
+	if err != nil {
+		Logger.Error("Failed to list smart folders", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve smart folders",
+			"code":  dto.CodeInternal,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"smartFolders": []map[string]interface{}{},
+		"count":        0,
+	})
+}
+
+// GetSmartFolder returns a smart folder and the documents it finds now
+// @Summary Get a smart folder
+// @Description A smart folder with the documents its query finds now, sorted by name and without their text
+// @Tags Folders
+// @Produce json
+// @Param id path string true "Smart folder ULID"
+// @Success 200 {object} map[string]interface{} "smartFolder, url, documents and count"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID"
+// @Failure 404 {object} map[string]interface{} "Smart folder not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /smartfolders/{id} [get]
+func (serverHandler *ServerHandler) GetSmartFolder(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "smart folder")
+	if !ok {
+		return err
+	}
+	folder, err := serverHandler.DB.GetSmartFolder(id.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Smart folder not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	var documents []database.Document
+	if err == nil {
+		var all []database.Document
+		if folder.Term == "" && folder.Tag == "" {
+			all, err = listDocumentsByName(serverHandler.DB)
+		}
+		if err == nil {
+			documents, err = smartFolderDocuments(serverHandler.DB, *folder, all)
+		}
+	}
+	if err != nil {
+		Logger.Error("Failed to get smart folder", "ulid", id.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve smart folder",
+			"code":  dto.CodeInternal,
+		})
+	}
+	response := smartFolderResponse(folder)
+	response["documents"] = documents
+	response["count"] = len(documents)
+	return c.JSON(http.StatusOK, response)
+}
+
+// DeleteSmartFolder removes a smart folder, leaving its documents alone
+// @Summary Delete a smart folder
+// @Description Delete a smart folder. The documents it showed stay where they are.
+// @Tags Folders
+// @Produce json
+// @Param id path string true "Smart folder ULID"
+// @Success 204 "Smart folder deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid ULID"
+// @Failure 404 {object} map[string]interface{} "Smart folder not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /smartfolders/{id} [delete]
+func (serverHandler *ServerHandler) DeleteSmartFolder(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "smart folder")
+	if !ok {
+		return err
+	}
+	err = serverHandler.DB.DeleteSmartFolder(id.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Smart folder not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to delete smart folder", "ulid", id.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to delete smart folder",
+			"code":  dto.CodeInternal,
+		})
+	}
+	serverHandler.invalidateDocumentCache()
+	return c.NoContent(http.StatusNoContent)
+}
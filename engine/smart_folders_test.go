@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+func TestSmartFoldersAppearInTree(t *testing.T) {
+	// Two unpaid invoices in different folders, a statement and a letter
+	handler := setupTestHandler(t)
+	handler.Echo.POST("/api/smartfolders", handler.CreateSmartFolder)
+	handler.Echo.GET("/api/smartfolders", handler.ListSmartFolders)
+	handler.Echo.GET("/api/smartfolders/:id", handler.GetSmartFolder)
+	handler.Echo.DELETE("/api/smartfolders/:id", handler.DeleteSmartFolder)
+	root := handler.ServerConfig.DocumentPath
+	docs := make(map[string]string) // path -> ULID
+	for path, text := range map[string]string{
+		"bills/gas.pdf":       "unpaid invoice for gas",
+		"bills/water.pdf":     "water statement",
+		"work/laptop.pdf":     "unpaid invoice for a laptop",
+		"letters/pension.pdf": "pension letter",
+	} {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("Failed to create folder: %v", err)
+		}
+		if err := os.WriteFile(full, []byte("%PDF-1.4"), 0644); err != nil {
+			t.Fatalf("Failed to write document: %v", err)
+		}
+		docs[path] = saveTestDocument(t, handler.DB, full, text).ULID.String()
+	}
+	serve := func(method, target, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		var response map[string]interface{}
+		if rec.Body.Len() > 0 {
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode %d %q: %v", rec.Code, rec.Body.String(), err)
+			}
+		}
+		return rec, response
+	}
+
+	// A query is saved as a smart folder
+	rec, created := serve(http.MethodPost, "/api/smartfolders", `{"name":"Unpaid invoices","term":"unpaid invoice"}`)
+
+	// It is created with its own link
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Unexpected create response %d %v", rec.Code, created)
+	}
+	id := created["smartFolder"].(map[string]interface{})["id"].(string)
+	if created["url"] != "/api/smartfolders/"+id {
+		t.Errorf("Unexpected smart folder URL %v", created["url"])
+	}
+
+	// The tree lists it under the root with both invoices, which stay in their real folders too
+	tree, err := fileTree(root, handler.DB, "")
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	var smart *dto.FileTreeNode
+	var inSmart, named []string
+	for i, node := range tree.FileSystem {
+		switch {
+		case node.ID == "smart-"+id:
+			smart = &tree.FileSystem[i]
+		case node.ParentID == "smart-"+id:
+			inSmart = append(inSmart, node.Name)
+		case !node.IsDir:
+			named = append(named, node.Name)
+		}
+	}
+	if smart == nil || !smart.IsDir || smart.SmartFolder != id || smart.ParentID != tree.FileSystem[0].ID {
+		t.Fatalf("Expected the smart folder under the root, got %+v", tree.FileSystem)
+	}
+	if strings.Join(inSmart, ",") != "gas.pdf,laptop.pdf" || len(named) != 4 {
+		t.Errorf("Expected both invoices in the smart folder and all four documents in place, got %v and %v", inSmart, named)
+	}
+
+	// A date range that ends before today finds nothing, and one that covers today finds everything
+	yesterday := time.Now().AddDate(0, 0, -1).Format(smartFolderDateLayout)
+	rec, _ = serve(http.MethodPost, "/api/smartfolders", `{"name":"Old","from":"2020-01-01","to":"`+yesterday+`"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Failed to create date range folder: %d %s", rec.Code, rec.Body.String())
+	}
+	rec, _ = serve(http.MethodPost, "/api/smartfolders", `{"name":"Recent","from":"`+yesterday+`"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Failed to create date range folder: %d %s", rec.Code, rec.Body.String())
+	}
+	_, listed := serve(http.MethodGet, "/api/smartfolders", "")
+	counts := make(map[string]float64)
+	for _, entry := range listed["smartFolders"].([]interface{}) {
+		folder := entry.(map[string]interface{})
+		counts[folder["smartFolder"].(map[string]interface{})["name"].(string)] = folder["documentCount"].(float64)
+	}
+	if counts["Unpaid invoices"] != 2 || counts["Old"] != 0 || counts["Recent"] != 4 {
+		t.Errorf("Unexpected document counts %v", counts)
+	}
+
+	// A tag finds the documents tagged with it, and narrows a search term to them
+	handler.DB.AddTag(docs["bills/gas.pdf"], "tax")
+	handler.DB.AddTag(docs["bills/water.pdf"], "tax")
+	rec, created = serve(http.MethodPost, "/api/smartfolders", `{"name":"Tax","tag":" Tax "}`)
+	if rec.Code != http.StatusCreated || created["smartFolder"].(map[string]interface{})["tag"] != "tax" {
+		t.Fatalf("Expected the tag folder created with its tag normalized, got %d %s", rec.Code, rec.Body.String())
+	}
+	_, tagged := serve(http.MethodGet, "/api/smartfolders/"+created["smartFolder"].(map[string]interface{})["id"].(string), "")
+	if tagged["count"] != float64(2) {
+		t.Errorf("Expected both tax documents, got %v", tagged)
+	}
+	rec, created = serve(http.MethodPost, "/api/smartfolders", `{"name":"Unpaid tax","term":"unpaid invoice","tag":"tax"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Failed to create tag and term folder: %d %s", rec.Code, rec.Body.String())
+	}
+	_, tagged = serve(http.MethodGet, "/api/smartfolders/"+created["smartFolder"].(map[string]interface{})["id"].(string), "")
+	if documents, _ := tagged["documents"].([]interface{}); len(documents) != 1 || documents[0].(map[string]interface{})["Name"] != "gas.pdf" {
+		t.Errorf("Expected only the unpaid gas invoice, got %v", tagged)
+	}
+
+	// A correspondent finds the documents its extraction template read, ignoring case
+	template := &database.ExtractionTemplate{Correspondent: "Acme Utilities", Match: "gas|water",
+		Fields: []database.ExtractionField{{Name: "total", Pattern: `Total (\S+)`}}}
+	if err := handler.DB.CreateExtractionTemplate(template); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"bills/gas.pdf", "bills/water.pdf"} {
+		fields := []database.DocumentField{{Name: "total", Value: "10.00", TemplateULID: template.ULID.String()}}
+		if err := handler.DB.SaveDocumentFields(docs[path], fields); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec, created = serve(http.MethodPost, "/api/smartfolders", `{"name":"Acme","correspondent":" acme utilities "}`)
+	if rec.Code != http.StatusCreated || created["smartFolder"].(map[string]interface{})["correspondent"] != "acme utilities" {
+		t.Fatalf("Expected the correspondent folder created with its correspondent trimmed, got %d %s", rec.Code, rec.Body.String())
+	}
+	_, read := serve(http.MethodGet, "/api/smartfolders/"+created["smartFolder"].(map[string]interface{})["id"].(string), "")
+	if read["count"] != float64(2) {
+		t.Errorf("Expected both Acme documents, got %v", read)
+	}
+	rec, created = serve(http.MethodPost, "/api/smartfolders", `{"name":"Unpaid Acme","term":"unpaid invoice","correspondent":"Acme Utilities"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Failed to create correspondent and term folder: %d %s", rec.Code, rec.Body.String())
+	}
+	_, read = serve(http.MethodGet, "/api/smartfolders/"+created["smartFolder"].(map[string]interface{})["id"].(string), "")
+	if documents, _ := read["documents"].([]interface{}); len(documents) != 1 || documents[0].(map[string]interface{})["Name"] != "gas.pdf" {
+		t.Errorf("Expected only the unpaid gas invoice, got %v", read)
+	}
+
+	// The smart folder is deleted
+	rec, _ = serve(http.MethodDelete, "/api/smartfolders/"+id, "")
+
+	// It is gone and the documents are kept
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec, response := serve(http.MethodGet, "/api/smartfolders/"+id, ""); rec.Code != http.StatusNotFound || response["code"] != string(dto.CodeNotFound) {
+		t.Errorf("Expected 404 %s after delete, got %d %v", dto.CodeNotFound, rec.Code, response)
+	}
+	if documents, err := handler.DB.SearchDocuments("unpaid invoice"); err != nil || len(documents) != 2 {
+		t.Errorf("Expected the invoices to be kept, got %d: %v", len(documents), err)
+	}
+}
+
+func TestSmartFolderValidation(t *testing.T) {
+	cases := []struct {
+		body   string
+		fields []string
+	}{
+		{`{"term":"invoice"}`, []string{"name"}},
+		{`{"name":"Bad dates","from":"2024-13-01","to":"yesterday"}`, []string{"from", "to"}},
+		{`{"name":"Backwards","from":"2024-06-01","to":"2024-05-01"}`, []string{"to"}},
+		{`{"name":"Tagged","tag":"unpaid/paid"}`, []string{"tag"}},
+		{`{"name":"Everything"}`, []string{"term"}},
+		{`{"name":"Blank","correspondent":"  "}`, []string{"term"}},
+	}
+	handler := setupMemoryTestHandler(t, config.ServerConfig{DocumentPath: t.TempDir()})
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/smartfolders", strings.NewReader(c.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		if err := handler.CreateSmartFolder(handler.Echo.NewContext(req, rec)); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var response dto.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		if rec.Code != http.StatusBadRequest || response.Code != dto.CodeValidation || len(response.Fields) != len(c.fields) {
+			t.Errorf("%s: expected 400 with %v, got %d %+v", c.body, c.fields, rec.Code, response)
+			continue
+		}
+		for _, field := range c.fields {
+			if response.Fields[field] == "" {
+				t.Errorf("%s: expected a problem with %s, got %v", c.body, field, response.Fields)
+			}
+		}
+	}
+	if folders, err := handler.DB.ListSmartFolders(); err != nil || len(folders) != 0 {
+		t.Errorf("Expected nothing saved, got %v: %v", folders, err)
+	}
+}
@@ -0,0 +1,257 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// vocabularySize is how many of the most frequent word cloud words are used for corrections and completions
+	vocabularySize = 20000
+	// vocabularyTTL is how long the vocabulary is kept before word_frequencies is read again
+	vocabularyTTL = 10 * time.Minute
+	// maxSpellSuggestions caps the "did you mean" alternatives offered for one search
+	maxSpellSuggestions = 3
+)
+
+// searchVocabulary is the most frequent words of the word_frequencies table, for spelling corrections
+// (a trigram index finds candidates and edit distance ranks them) and search box completions
+type searchVocabulary struct {
+	mu       sync.Mutex
+	loadedAt time.Time
+	words    []string       // vocabulary, most frequent first
+	known    map[string]int // word to its index in words
+	trigrams map[string][]int
+}
+
+// spellWord is a correction candidate for one search word
+type spellWord struct {
+	word       string
+	distance   int
+	similarity float64
+	rank       int // position in the vocabulary, lower is more frequent
+}
+
+// searchWords splits a search term into lower-case words and the separators between them, so
+// corrections can be put back into the original term
+func searchWords(term string) []string {
+	var parts []string
+	var current strings.Builder
+	inWord := false
+	for _, r := range strings.ToLower(term) {
+		isWordRune := unicode.IsLetter(r) || r == '\'' || r == '-'
+		if isWordRune != inWord && current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+		inWord = isWordRune
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		parts = append(parts, current.String())
+	}
+	return parts
+}
+
+// wordTrigrams returns the distinct trigrams of a word padded with spaces, as pg_trgm does
+func wordTrigrams(word string) []string {
+	padded := []rune("  " + word + " ")
+	seen := make(map[string]bool, len(padded))
+	var trigrams []string
+	for i := 0; i+3 <= len(padded); i++ {
+		trigram := string(padded[i : i+3])
+		if !seen[trigram] {
+			seen[trigram] = true
+			trigrams = append(trigrams, trigram)
+		}
+	}
+	return trigrams
+}
+
+// editDistance is the optimal string alignment distance: insertions, deletions, substitutions and
+// transpositions of adjacent letters each cost one
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(ra)][len(rb)]
+}
+
+// maxEdits is how far a correction may be from a word of the given length
+func maxEdits(length int) int {
+	if length <= 4 {
+		return 1
+	}
+	return 2
+}
+
+// load reads the vocabulary from the word cloud table when it is missing or stale
+func (vocabulary *searchVocabulary) load(serverHandler *ServerHandler) error {
+	if vocabulary.known != nil && time.Since(vocabulary.loadedAt) < vocabularyTTL {
+		return nil
+	}
+	frequencies, err := serverHandler.DB.GetTopWords(vocabularySize)
+	if err != nil {
+		return err
+	}
+	vocabulary.words = make([]string, 0, len(frequencies))
+	vocabulary.known = make(map[string]int, len(frequencies))
+	vocabulary.trigrams = make(map[string][]int)
+	for _, frequency := range frequencies {
+		index := len(vocabulary.words)
+		vocabulary.words = append(vocabulary.words, frequency.Word)
+		vocabulary.known[frequency.Word] = index
+		for _, trigram := range wordTrigrams(frequency.Word) {
+			vocabulary.trigrams[trigram] = append(vocabulary.trigrams[trigram], index)
+		}
+	}
+	vocabulary.loadedAt = time.Now()
+	return nil
+}
+
+// corrections returns the best vocabulary words for a word that is not in the vocabulary, best first
+func (vocabulary *searchVocabulary) corrections(word string) []spellWord {
+	trigrams := wordTrigrams(word)
+	shared := make(map[int]int)
+	for _, trigram := range trigrams {
+		for _, index := range vocabulary.trigrams[trigram] {
+			shared[index]++
+		}
+	}
+
+	var candidates []spellWord
+	length := len([]rune(word))
+	for index, count := range shared {
+		candidate := vocabulary.words[index]
+		distance := editDistance(word, candidate)
+		if distance > maxEdits(length) {
+			continue
+		}
+		union := len(trigrams) + len(wordTrigrams(candidate)) - count
+		candidates = append(candidates, spellWord{
+			word:       candidate,
+			distance:   distance,
+			similarity: float64(count) / float64(union),
+			rank:       index,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if a.similarity != b.similarity {
+			return a.similarity > b.similarity
+		}
+		return a.rank < b.rank
+	})
+	return candidates[:min(len(candidates), maxSpellSuggestions)]
+}
+
+// suggest returns corrected versions of a search term, best first. Words of two letters or fewer,
+// numbers and words already in the vocabulary are left alone.
+func (vocabulary *searchVocabulary) suggest(serverHandler *ServerHandler, term string) ([]string, error) {
+	vocabulary.mu.Lock()
+	defer vocabulary.mu.Unlock()
+	if err := vocabulary.load(serverHandler); err != nil {
+		return nil, err
+	}
+
+	parts := searchWords(term)
+	options := make([][]spellWord, len(parts))
+	misspelled := false
+	for i, part := range parts {
+		if len([]rune(part)) < 3 || !unicode.IsLetter([]rune(part)[0]) {
+			continue
+		}
+		if _, ok := vocabulary.known[part]; ok {
+			continue
+		}
+		options[i] = vocabulary.corrections(part)
+		misspelled = misspelled || len(options[i]) > 0
+	}
+	if !misspelled {
+		return nil, nil
+	}
+
+	// The nth suggestion takes each word's nth correction, or its best one when it has fewer
+	var suggestions []string
+	seen := make(map[string]bool)
+	for n := 0; n < maxSpellSuggestions; n++ {
+		var suggestion strings.Builder
+		for i, part := range parts {
+			switch {
+			case len(options[i]) > n:
+				suggestion.WriteString(options[i][n].word)
+			case len(options[i]) > 0:
+				suggestion.WriteString(options[i][0].word)
+			default:
+				suggestion.WriteString(part)
+			}
+		}
+		if text := strings.TrimSpace(suggestion.String()); !seen[text] {
+			seen[text] = true
+			suggestions = append(suggestions, text)
+		}
+	}
+	return suggestions, nil
+}
+
+// searchSuggestions offers "did you mean" alternatives for a search that found nothing, keeping only
+// those that find documents. Failures are logged and give no suggestions.
+func (serverHandler *ServerHandler) searchSuggestions(term string) []string {
+	candidates, err := serverHandler.vocabulary.suggest(serverHandler, term)
+	if err != nil {
+		Logger.Warn("Unable to compute search suggestions", "term", term, "error", err)
+		return nil
+	}
+	var suggestions []string
+	for _, candidate := range candidates {
+		if documents, err := serverHandler.DB.SearchDocuments(candidate); err == nil && len(documents) > 0 {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return suggestions
+}
+
+// completions returns up to limit vocabulary words that start with prefix and are longer than it, most
+// frequent first
+func (vocabulary *searchVocabulary) completions(serverHandler *ServerHandler, prefix string, limit int) ([]string, error) {
+	vocabulary.mu.Lock()
+	defer vocabulary.mu.Unlock()
+	if err := vocabulary.load(serverHandler); err != nil {
+		return nil, err
+	}
+
+	var words []string
+	for _, word := range vocabulary.words {
+		if len(words) >= limit {
+			break
+		}
+		if len(word) > len(prefix) && strings.HasPrefix(word, prefix) {
+			words = append(words, word)
+		}
+	}
+	return words, nil
+}
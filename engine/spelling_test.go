@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/internal/dto"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"invoice", "invoice", 0},
+		{"invoce", "invoice", 1},
+		{"inovice", "invoice", 1}, // adjacent letters swapped
+		{"recipt", "receipt", 1},
+		{"morgage", "mortgage", 1},
+		{"boiler", "toilet", 2},
+		{"", "tax", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.distance {
+			t.Errorf("editDistance(%q, %q) = %d, expected %d", tt.a, tt.b, got, tt.distance)
+		}
+	}
+}
+
+func TestSearchSuggestionsCorrectMisspelledWords(t *testing.T) {
+	// Documents whose words are in the word cloud
+	handler := setupMemoryTestHandler(t, config.ServerConfig{DocumentPath: t.TempDir()})
+	for name, text := range map[string]string{
+		"boiler.txt":   "boiler invoice for the service",
+		"mortgage.txt": "mortgage statement",
+	} {
+		path := filepath.Join(handler.ServerConfig.DocumentPath, name)
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		saveTestDocument(t, handler.DB, path, text)
+	}
+	if err := handler.DB.AddWordFrequencies(map[string]int{"boiler": 1, "service": 1, "invoice": 3, "mortgage": 1, "statement": 1}); err != nil {
+		t.Fatalf("Failed to add word frequencies: %v", err)
+	}
+
+	// Misspelled words are corrected in place, keeping the rest of the term
+	tests := []struct {
+		term        string
+		suggestions []string
+	}{
+		{"boiler invoce", []string{"boiler invoice"}},
+		{"Morgage statment", []string{"mortgage statement"}},
+		{"xylophone", nil},
+		{"invoice", nil},
+	}
+	for _, tt := range tests {
+		if got := handler.searchSuggestions(tt.term); !reflect.DeepEqual(got, tt.suggestions) {
+			t.Errorf("searchSuggestions(%q) = %q, expected %q", tt.term, got, tt.suggestions)
+		}
+	}
+
+	// Searching for a misspelled term through the API
+	rec := httptest.NewRecorder()
+	handler.SearchDocuments(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/search?term=servise", nil), rec))
+
+	// The empty result carries the suggestion instead of a 204
+	var response dto.FileSystem
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode %d %q: %v", rec.Code, rec.Body.String(), err)
+	}
+	if rec.Code != http.StatusOK || len(response.FileSystem) != 0 || !reflect.DeepEqual(response.Suggestions, []string{"service"}) {
+		t.Errorf("Unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+}
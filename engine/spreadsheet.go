@@ -0,0 +1,321 @@
+package engine
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/drummonds/godocs/database"
+)
+
+// spreadsheetExtensions are the spreadsheet types text is extracted from. They are not processed by default;
+// add them to PROCESSABLE_EXTENSIONS to ingest them.
+var spreadsheetExtensions = []string{".xlsx", ".csv"}
+
+// maxSpreadsheetPartBytes caps how much of any one part of an xlsx file is read, so a small zip cannot
+// expand into gigabytes of XML
+const maxSpreadsheetPartBytes = 64 << 20
+
+// maxSpreadsheetColumns is the widest sheet Excel allows; cells referenced beyond it are ignored
+const maxSpreadsheetColumns = 16384
+
+// errSpreadsheetTooLarge is returned for an xlsx part larger than maxSpreadsheetPartBytes
+var errSpreadsheetTooLarge = errors.New("spreadsheet part too large")
+
+// isSpreadsheet reports whether path is one of the spreadsheetExtensions
+func isSpreadsheet(path string) bool {
+	return slices.Contains(spreadsheetExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// sheet is one worksheet's non-empty rows, each cut after its last non-empty cell
+type sheet struct {
+	Name string // empty for a CSV file
+	Rows [][]string
+}
+
+// spreadsheet is the cell text of a workbook or CSV file
+type spreadsheet struct {
+	Sheets []sheet
+}
+
+// readSpreadsheet reads the cell text of an xlsx or csv file
+func readSpreadsheet(path string) (*spreadsheet, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return readCSV(path)
+	case ".xlsx":
+		return readXLSX(path)
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedFileType, filepath.Ext(path))
+	}
+}
+
+// readCSV reads a CSV file as a single unnamed sheet. Rows may have different numbers of fields.
+func readCSV(path string) (*spreadsheet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if row := trimRow(record); len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return &spreadsheet{Sheets: []sheet{{Rows: rows}}}, nil
+}
+
+// trimRow drops a row's trailing empty cells, leaving nothing for a row with no text
+func trimRow(row []string) []string {
+	end := len(row)
+	for end > 0 && strings.TrimSpace(row[end-1]) == "" {
+		end--
+	}
+	return row[:end]
+}
+
+// The parts of SpreadsheetML read from an xlsx file
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a shared or inline string: plain text, or runs of formatted text
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (text xlsxText) String() string {
+	if len(text.Runs) == 0 {
+		return text.T
+	}
+	var sb strings.Builder
+	for _, run := range text.Runs {
+		sb.WriteString(run.T)
+	}
+	return sb.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX reads each worksheet of an xlsx workbook in tab order
+func readXLSX(filePath string) (*spreadsheet, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid xlsx file: %w", err)
+	}
+	defer archive.Close()
+	parts := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		parts[file.Name] = file
+	}
+
+	var workbook xlsxWorkbook
+	if err := decodeXLSXPart(parts, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var relationships xlsxRelationships
+	if err := decodeXLSXPart(parts, "xl/_rels/workbook.xml.rels", &relationships); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(relationships.Relationships))
+	for _, relationship := range relationships.Relationships {
+		target := relationship.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[relationship.ID] = target
+	}
+	// A workbook with no text cells has no shared strings part
+	var sharedStrings xlsxSharedStrings
+	if _, ok := parts["xl/sharedStrings.xml"]; ok {
+		if err := decodeXLSXPart(parts, "xl/sharedStrings.xml", &sharedStrings); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &spreadsheet{}
+	for _, workbookSheet := range workbook.Sheets {
+		var worksheet xlsxWorksheet
+		if err := decodeXLSXPart(parts, targets[workbookSheet.RID], &worksheet); err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", workbookSheet.Name, err)
+		}
+		current := sheet{Name: workbookSheet.Name}
+		for _, worksheetRow := range worksheet.Rows {
+			var row []string
+			for i, cell := range worksheetRow.Cells {
+				column := i
+				if cell.Ref != "" {
+					column = columnIndex(cell.Ref)
+				}
+				if column >= maxSpreadsheetColumns {
+					continue
+				}
+				for len(row) <= column {
+					row = append(row, "")
+				}
+				row[column] = xlsxCellText(cell.Type, cell.Value, cell.Inline, sharedStrings.Items)
+			}
+			if row = trimRow(row); len(row) > 0 {
+				current.Rows = append(current.Rows, row)
+			}
+		}
+		result.Sheets = append(result.Sheets, current)
+	}
+	return result, nil
+}
+
+// decodeXLSXPart decodes the XML part name of an xlsx file into v
+func decodeXLSXPart(parts map[string]*zip.File, name string, v any) error {
+	part, ok := parts[name]
+	if !ok {
+		return fmt.Errorf("not a valid xlsx file: %s is missing", name)
+	}
+	if part.UncompressedSize64 > maxSpreadsheetPartBytes {
+		return fmt.Errorf("%w: %s", errSpreadsheetTooLarge, name)
+	}
+	reader, err := part.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return xml.NewDecoder(io.LimitReader(reader, maxSpreadsheetPartBytes)).Decode(v)
+}
+
+// columnIndex turns the letters of a cell reference such as "AB12" into a 0-based column
+func columnIndex(ref string) int {
+	column := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+	}
+	return max(column-1, 0)
+}
+
+// xlsxCellText is the text of a cell: its shared or inline string, TRUE/FALSE for a boolean, and
+// otherwise the stored value. Dates are stored as day numbers and are shown as such.
+func xlsxCellText(cellType, value string, inline xlsxText, shared []xlsxText) string {
+	switch cellType {
+	case "s":
+		index, err := strconv.Atoi(value)
+		if err != nil || index < 0 || index >= len(shared) {
+			return ""
+		}
+		return shared[index].String()
+	case "inlineStr":
+		return inline.String()
+	case "b":
+		if value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return value
+	}
+}
+
+// text is the spreadsheet's cells for the search index: each sheet's name on a line of its own followed
+// by its rows, one per line with tabs between cells, and a blank line between sheets
+func (s *spreadsheet) text() string {
+	var sb strings.Builder
+	for i, current := range s.Sheets {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if current.Name != "" {
+			sb.WriteString(current.Name + "\n")
+		}
+		for _, row := range current.Rows {
+			sb.WriteString(strings.Join(row, "\t") + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// details counts the sheets, the rows across them and the columns of the widest row
+func (s *spreadsheet) details(documentULID string) database.SpreadsheetDetails {
+	details := database.SpreadsheetDetails{DocumentULID: documentULID, Sheets: len(s.Sheets)}
+	for _, current := range s.Sheets {
+		details.Rows += len(current.Rows)
+		for _, row := range current.Rows {
+			details.Columns = max(details.Columns, len(row))
+		}
+	}
+	return details
+}
+
+// extractSpreadsheetText reads a spreadsheet's cell text for indexing, sheet by sheet
+func extractSpreadsheetText(filePath string) (string, error) {
+	workbook, err := readSpreadsheet(filePath)
+	if err != nil {
+		return "", err
+	}
+	return workbook.text(), nil
+}
+
+// recordSpreadsheetDetails stores the sheet, row and column counts of a spreadsheet document. They are
+// metadata, so a file that cannot be read is logged rather than failing the ingestion.
+func (serverHandler *ServerHandler) recordSpreadsheetDetails(doc *database.Document) {
+	if !isSpreadsheet(doc.Path) {
+		return
+	}
+	workbook, err := readSpreadsheet(doc.Path)
+	if err != nil {
+		Logger.Warn("Unable to read spreadsheet details", "path", doc.Path, "error", err)
+		return
+	}
+	details := workbook.details(doc.ULID.String())
+	if err := serverHandler.DB.SaveSpreadsheetDetails(&details); err != nil {
+		Logger.Warn("Unable to record spreadsheet details", "ulid", doc.ULID.String(), "error", err)
+	}
+}
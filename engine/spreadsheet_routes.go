@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"net/http"
+	"os"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// spreadsheetPreviewRows is how many rows of each sheet the preview shows
+const spreadsheetPreviewRows = 500
+
+// previewSheet is a sheet as the preview shows it
+type previewSheet struct {
+	Name  string
+	Rows  [][]string
+	Total int // rows in the sheet, more than len(Rows) when it was cut short
+}
+
+// spreadsheetPreviewTemplate renders a spreadsheet as one HTML table per sheet
+var spreadsheetPreviewTemplate = template.Must(template.New("preview").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 1rem; color: #333; }
+table { border-collapse: collapse; margin-bottom: 1.5rem; font-size: 0.9rem; }
+th, td { border: 1px solid #ddd; padding: 0.25rem 0.5rem; text-align: left; vertical-align: top; white-space: pre-wrap; }
+th { background: #f5f5f5; color: #666; font-weight: normal; }
+.summary, .truncated { color: #666; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p class="summary">{{.Details.Sheets}} sheet{{if ne .Details.Sheets 1}}s{{end}}, {{.Details.Rows}} row{{if ne .Details.Rows 1}}s{{end}}, {{.Details.Columns}} column{{if ne .Details.Columns 1}}s{{end}}</p>
+{{range .Sheets}}
+{{if .Name}}<h2>{{.Name}}</h2>{{end}}
+{{if .Rows}}<table>
+{{range $i, $row := .Rows}}<tr><th scope="row">{{$i | inc}}</th>{{range $row}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{if gt .Total (len .Rows)}}<p class="truncated">Showing the first {{len .Rows}} of {{.Total}} rows.</p>{{end}}
+{{else}}<p class="truncated">This sheet is empty.</p>{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// spreadsheetDocument looks up the spreadsheet document named by the id path parameter. When it is not
+// one the error response has been sent, ok is false and the handler returns err.
+func (serverHandler *ServerHandler) spreadsheetDocument(c echo.Context) (doc *database.Document, ok bool, err error) {
+	id, ok, err := ulidParam(c, "id", "document")
+	if !ok {
+		return nil, false, err
+	}
+	doc, err = serverHandler.DB.GetDocumentByULID(id.String())
+	if err != nil || doc == nil || serverHandler.hiddenDocument(c, id.String()) {
+		return nil, false, documentNotFound(c)
+	}
+	if !isSpreadsheet(doc.Path) {
+		return nil, false, c.JSON(http.StatusUnsupportedMediaType, map[string]interface{}{
+			"error": "Document is not a spreadsheet",
+			"code":  dto.CodeUnsupportedType,
+		})
+	}
+	return doc, true, nil
+}
+
+// GetSpreadsheetDetails returns the sheet, row and column counts recorded for a spreadsheet document
+// @Summary Spreadsheet details
+// @Description The number of sheets, non-empty rows across them and cells in the widest row, recorded when an xlsx or csv document was ingested or rescanned.
+// @Tags Documents
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Success 200 {object} database.SpreadsheetDetails "Spreadsheet details"
+// @Failure 400 {object} dto.ErrorResponse "Invalid ULID"
+// @Failure 404 {object} dto.ErrorResponse "Document not found, or no details recorded"
+// @Failure 415 {object} dto.ErrorResponse "Document is not a spreadsheet"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /document/{id}/spreadsheet [get]
+func (serverHandler *ServerHandler) GetSpreadsheetDetails(c echo.Context) error {
+	doc, ok, err := serverHandler.spreadsheetDocument(c)
+	if !ok {
+		return err
+	}
+	details, err := serverHandler.DB.GetSpreadsheetDetails(doc.ULID.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "No spreadsheet details recorded, rescan the document",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to get spreadsheet details", "ulid", doc.ULID.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to get spreadsheet details",
+			"code":  dto.CodeInternal,
+		})
+	}
+	return c.JSON(http.StatusOK, details)
+}
+
+// GetSpreadsheetPreview renders a spreadsheet document as HTML tables
+// @Summary Preview a spreadsheet
+// @Description A simple HTML page with a table for each sheet of an xlsx or csv document, showing at most the first 500 rows of each. Cells are shown as stored, so dates appear as Excel day numbers.
+// @Tags Documents
+// @Produce html
+// @Param id path string true "Document ULID"
+// @Success 200 {string} string "HTML preview"
+// @Failure 400 {object} dto.ErrorResponse "Invalid ULID"
+// @Failure 404 {object} dto.ErrorResponse "Document or file not found"
+// @Failure 415 {object} dto.ErrorResponse "Document is not a spreadsheet"
+// @Failure 422 {object} dto.ErrorResponse "The file could not be read as a spreadsheet"
+// @Router /document/{id}/preview [get]
+func (serverHandler *ServerHandler) GetSpreadsheetPreview(c echo.Context) error {
+	doc, ok, err := serverHandler.spreadsheetDocument(c)
+	if !ok {
+		return err
+	}
+	if _, err := os.Stat(doc.Path); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document file is missing",
+			"code":  dto.CodeFileMissing,
+		})
+	}
+	workbook, err := readSpreadsheet(doc.Path)
+	if err != nil {
+		Logger.Warn("Unable to read spreadsheet for preview", "path", doc.Path, "error", err)
+		return c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{
+			"error": "The file could not be read as a spreadsheet",
+			"code":  dto.CodeExtractionFailed,
+		})
+	}
+
+	page := struct {
+		Name    string
+		Details database.SpreadsheetDetails
+		Sheets  []previewSheet
+	}{Name: doc.Name, Details: workbook.details(doc.ULID.String())}
+	for _, current := range workbook.Sheets {
+		preview := previewSheet{Name: current.Name, Rows: current.Rows, Total: len(current.Rows)}
+		if len(preview.Rows) > spreadsheetPreviewRows {
+			preview.Rows = preview.Rows[:spreadsheetPreviewRows]
+		}
+		page.Sheets = append(page.Sheets, preview)
+	}
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	c.Response().WriteHeader(http.StatusOK)
+	return spreadsheetPreviewTemplate.Execute(c.Response(), page)
+}
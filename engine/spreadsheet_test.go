@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"archive/zip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+)
+
+// writeTestXLSX writes a minimal two-sheet workbook: shared, inline, numeric and boolean cells, with a
+// gap at column B of the first sheet
+func writeTestXLSX(t *testing.T, path string) {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="March" sheetId="1" r:id="rId1"/><sheet name="Notes" sheetId="2" r:id="rId2"/></sheets>
+</workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/>
+</Relationships>`,
+		"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>Payee</t></si><si><r><t>Acme </t></r><r><t>Energy</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="inlineStr"><is><t>Amount</t></is></c></row>
+<row r="3"><c r="A3" t="s"><v>1</v></c><c r="C3"><v>42.5</v></c><c r="D3" t="b"><v>1</v></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="inlineStr"><is><t>Paid &lt;late&gt;</t></is></c></row>
+</sheetData></worksheet>`,
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create workbook: %v", err)
+	}
+	defer file.Close()
+	archive := zip.NewWriter(file)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Failed to write workbook: %v", err)
+	}
+}
+
+func TestReadSpreadsheet(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Workbook", func(t *testing.T) {
+		workbookPath := filepath.Join(dir, "statement.xlsx")
+		writeTestXLSX(t, workbookPath)
+		workbook, err := readSpreadsheet(workbookPath)
+		if err != nil {
+			t.Fatalf("readSpreadsheet failed: %v", err)
+		}
+
+		// Each sheet's cells are in place under its name, shared, inline and boolean cells resolved
+		want := "March\nPayee\t\tAmount\nAcme Energy\t\t42.5\tTRUE\n\nNotes\nPaid <late>\n"
+		if text := workbook.text(); text != want {
+			t.Errorf("Expected text %q, got %q", want, text)
+		}
+		if details := workbook.details("doc"); details.Sheets != 2 || details.Rows != 3 || details.Columns != 4 {
+			t.Errorf("Expected 2 sheets, 3 rows and 4 columns, got %+v", details)
+		}
+	})
+
+	t.Run("CSV with ragged rows and a blank line", func(t *testing.T) {
+		csvPath := filepath.Join(dir, "statement.csv")
+		if err := os.WriteFile(csvPath, []byte("Date,Payee,Amount\n2024-03-01,\"Acme, Ltd\",-12.00\n\n2024-03-02,Refund\n"), 0644); err != nil {
+			t.Fatalf("Failed to write CSV: %v", err)
+		}
+		sheetless, err := readSpreadsheet(csvPath)
+		if err != nil {
+			t.Fatalf("readSpreadsheet failed: %v", err)
+		}
+
+		// It is one unnamed sheet of its non-empty rows
+		if text := sheetless.text(); text != "Date\tPayee\tAmount\n2024-03-01\tAcme, Ltd\t-12.00\n2024-03-02\tRefund\n" {
+			t.Errorf("Unexpected CSV text %q", text)
+		}
+		if details := sheetless.details("doc"); details.Sheets != 1 || details.Rows != 3 || details.Columns != 3 {
+			t.Errorf("Expected 1 sheet, 3 rows and 3 columns, got %+v", details)
+		}
+	})
+
+	t.Run("Not a workbook", func(t *testing.T) {
+		broken := filepath.Join(dir, "broken.xlsx")
+		os.WriteFile(broken, []byte("not a workbook"), 0644)
+		if _, err := readSpreadsheet(broken); err == nil {
+			t.Error("Expected an error for a file that is not a workbook")
+		}
+	})
+}
+
+func TestSpreadsheetIngestionAndPreview(t *testing.T) {
+	// Spreadsheets are enabled and a workbook is in ingress
+	handler := setupTestHandler(t)
+	handler.ServerConfig.IngestExtensions = []string{".pdf", ".xlsx", ".csv"}
+	writeTestXLSX(t, filepath.Join(handler.ServerConfig.IngressPath, "statement.xlsx"))
+	job, err := handler.DB.CreateJob(database.JobTypeIngestion, "test")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	handler.ingressJobFuncWithTracking(handler.ServerConfig, handler.DB, job.ID)
+
+	// The cells are searchable and the size is recorded
+	doc, err := handler.DB.GetDocumentByPath(filepath.ToSlash(filepath.Join(handler.ServerConfig.DocumentPath, "statement.xlsx")))
+	if err != nil {
+		t.Fatalf("Workbook was not ingested: %v", err)
+	}
+	if !strings.Contains(doc.FullText, "Acme Energy") || doc.OCRStatus != database.OCRSkipped {
+		t.Errorf("Expected the cell text without OCR, got %q (%s)", doc.FullText, doc.OCRStatus)
+	}
+	details, err := handler.DB.GetSpreadsheetDetails(doc.ULID.String())
+	if err != nil || details.Sheets != 2 || details.Rows != 3 || details.Columns != 4 {
+		t.Fatalf("Expected the spreadsheet details to be recorded, got %+v, %v", details, err)
+	}
+
+	// The preview shows each sheet as a table, with cell text escaped
+	handler.Echo.GET("/api/document/:id/preview", handler.GetSpreadsheetPreview)
+	handler.Echo.GET("/api/document/:id/spreadsheet", handler.GetSpreadsheetDetails)
+	rec := httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/document/"+doc.ULID.String()+"/preview", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("Expected an HTML preview, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"<h2>March</h2>", "<td>Acme Energy</td>", "Paid &lt;late&gt;", "2 sheets, 3 rows, 4 columns"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the preview to contain %q, got %s", want, body)
+		}
+	}
+	rec = httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/document/"+doc.ULID.String()+"/spreadsheet", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"rows":3`) {
+		t.Errorf("Expected the recorded details, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// A document that is not a spreadsheet has no preview
+	other := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, "bill.pdf"), "")
+	rec = httptest.NewRecorder()
+	handler.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/document/"+other.ULID.String()+"/preview", nil))
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for a PDF, got %d", rec.Code)
+	}
+}
@@ -3,6 +3,7 @@ package engine
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/drummonds/godocs/config"
 	"github.com/drummonds/godocs/database"
@@ -16,9 +17,47 @@ func (serverHandler *ServerHandler) StartupChecks() error {
 		return err
 	}
 	tesseractChecks(serverConfig)
+	serverHandler.ocrLanguageChecks()
+	if pdfRenderingMode(serverHandler.ServerConfig) == "unavailable" {
+		Logger.Warn("Built without PDFium (-tags nopdfium) and PDF_SERVICE_URL is not set, scanned PDFs will be stored without OCR text")
+	}
 	ingressDirectoryChecks(serverConfig)
 	documentDirectoryChecks(serverConfig)
-	return nil
+	serverHandler.tempDirectoryChecks()
+	serverHandler.backfillFolders()
+	go serverHandler.backfillFileDetails()
+	// Sidecar settings come from the live config since they are not stored in the database
+	retryInterval := time.Duration(serverHandler.ServerConfig.ServiceCheckInterval) * time.Second
+	return serverHandler.waitForServices(serverHandler.ServerConfig.ServiceCheckRetries, retryInterval)
+}
+
+// backfillFileDetails records the size and page count of documents stored before they were kept on
+// the record. Files that are missing are left for the cleanup job to report.
+func (serverHandler *ServerHandler) backfillFileDetails() {
+	documents, err := listDocumentsByName(serverHandler.DB)
+	if err != nil {
+		Logger.Error("Unable to list documents to backfill file details", "error", err)
+		return
+	}
+	updated := 0
+	for _, document := range documents {
+		if document.Size > 0 {
+			continue
+		}
+		size, pages := fileDetails(document.Path)
+		if size == 0 {
+			continue
+		}
+		if err := serverHandler.DB.UpdateDocumentFileDetails(document.ULID.String(), size, pages); err != nil {
+			Logger.Error("Unable to backfill file details", "path", document.Path, "error", err)
+			return
+		}
+		updated++
+	}
+	if updated > 0 {
+		serverHandler.invalidateDocumentCache()
+		Logger.Info("Backfilled document sizes and page counts", "documents", updated)
+	}
 }
 
 func tesseractChecks(serverConfig config.ServerConfig) error {
@@ -0,0 +1,293 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// statsBucket holds the document count and total file size for one period and group
+type statsBucket struct {
+	Period    string `json:"period"`
+	Group     string `json:"group"`
+	Count     int    `json:"count"`
+	TotalSize int64  `json:"totalSize"`
+}
+
+// statsIntervals maps supported interval names to a function that labels a time with its bucket
+var statsIntervals = map[string]func(time.Time) string{
+	"day":   func(t time.Time) string { return t.Format("2006-01-02") },
+	"week":  func(t time.Time) string { y, w := t.ISOWeek(); return fmt.Sprintf("%04d-W%02d", y, w) },
+	"month": func(t time.Time) string { return t.Format("2006-01") },
+	"year":  func(t time.Time) string { return t.Format("2006") },
+}
+
+// GetStatsTimeseries returns document counts and sizes bucketed by ingestion time and grouped by folder, tag or
+// correspondent
+// @Summary Get document statistics over time
+// @Description Count documents and total file size per time bucket, optionally grouped by folder, tag or correspondent.
+// @Description Grouped by tag, a document counts under each of its tags, and untagged documents under an empty group.
+// @Description Grouped by correspondent, a document counts under the correspondent of the extraction template its
+// @Description fields were read with, and documents no template recognised under an empty group.
+// @Tags Stats
+// @Accept json
+// @Produce json
+// @Param groupBy query string false "Grouping: none (default), folder, tag or correspondent"
+// @Param interval query string false "Bucket size: day, week, month (default) or year"
+// @Param from query string false "Only include documents ingested on or after this date (YYYY-MM-DD)"
+// @Param to query string false "Only include documents ingested before this date (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{} "Buckets with period, group, count and totalSize"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /stats/timeseries [get]
+func (serverHandler *ServerHandler) GetStatsTimeseries(c echo.Context) error {
+	groupBy := strings.ToLower(c.QueryParam("groupBy"))
+	switch groupBy {
+	case "", "none":
+		groupBy = "none"
+	case "folder", "tag", "correspondent":
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid groupBy value, expected none, folder, tag or correspondent",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	interval := strings.ToLower(c.QueryParam("interval"))
+	if interval == "" {
+		interval = "month"
+	}
+	label, ok := statsIntervals[interval]
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid interval value, expected day, week, month or year",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	var from, to time.Time
+	for name, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := c.QueryParam(name); value != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"error": fmt.Sprintf("Invalid %s date, expected YYYY-MM-DD", name),
+					"code":  dto.CodeBadRequest,
+				})
+			}
+			*target = parsed
+		}
+	}
+
+	documents, err := serverHandler.DB.GetAllDocuments()
+	if err != nil {
+		Logger.Error("Failed to get documents for stats", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve documents",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	var groups map[string][]string
+	switch groupBy {
+	case "tag":
+		if groups, err = serverHandler.documentTagMap(); err != nil {
+			Logger.Error("Failed to get document tags for stats", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to retrieve document tags",
+				"code":  dto.CodeInternal,
+			})
+		}
+	case "correspondent":
+		correspondents, err := documentCorrespondents(serverHandler.DB)
+		if err != nil {
+			Logger.Error("Failed to get document correspondents for stats", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to retrieve document correspondents",
+				"code":  dto.CodeInternal,
+			})
+		}
+		groups = make(map[string][]string, len(correspondents))
+		for documentULID, correspondent := range correspondents {
+			groups[documentULID] = []string{correspondent}
+		}
+	}
+
+	buckets := serverHandler.buildStatsBuckets(documents, groupBy, groups, label, from, to)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"groupBy":  groupBy,
+		"interval": interval,
+		"buckets":  buckets,
+		"count":    len(buckets),
+	})
+}
+
+// documentTagMap returns the tags of every tagged document, by document ULID
+func (serverHandler *ServerHandler) documentTagMap() (map[string][]string, error) {
+	tags, err := serverHandler.DB.ListTags()
+	if err != nil {
+		return nil, err
+	}
+	byDocument := make(map[string][]string)
+	for _, tag := range tags {
+		documents, err := serverHandler.DB.GetDocumentsByTag(tag.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range documents {
+			byDocument[doc.ULID.String()] = append(byDocument[doc.ULID.String()], tag.Name)
+		}
+	}
+	return byDocument, nil
+}
+
+// buildStatsBuckets aggregates documents into period/group buckets sorted by period then group. Grouped by
+// tag or correspondent, groups gives each document's tags or correspondent; a document counts once under
+// each, and those with none in the empty group. A zero from or to leaves that end of the range open.
+func (serverHandler *ServerHandler) buildStatsBuckets(documents []database.Document, groupBy string, groups map[string][]string, label func(time.Time) string, from, to time.Time) []statsBucket {
+	byKey := make(map[[2]string]*statsBucket)
+	for _, doc := range documents {
+		if !from.IsZero() && doc.IngressTime.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !doc.IngressTime.Before(to) {
+			continue
+		}
+
+		docGroups := []string{""}
+		switch groupBy {
+		case "folder":
+			docGroups = []string{serverHandler.relativeFolder(doc.Folder)}
+		case "tag", "correspondent":
+			if named := groups[doc.ULID.String()]; len(named) > 0 {
+				docGroups = named
+			}
+		}
+		var size int64
+		if info, err := os.Stat(doc.Path); err == nil {
+			size = info.Size()
+		}
+		for _, group := range docGroups {
+			key := [2]string{label(doc.IngressTime.Local()), group}
+			bucket, ok := byKey[key]
+			if !ok {
+				bucket = &statsBucket{Period: key[0], Group: key[1]}
+				byKey[key] = bucket
+			}
+			bucket.Count++
+			bucket.TotalSize += size
+		}
+	}
+
+	buckets := make([]statsBucket, 0, len(byKey))
+	for _, bucket := range byKey {
+		buckets = append(buckets, *bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Period != buckets[j].Period {
+			return buckets[i].Period < buckets[j].Period
+		}
+		return buckets[i].Group < buckets[j].Group
+	})
+	return buckets
+}
+
+// popularDefaultDays is the window GetPopularDocuments looks back over when days is not given
+const popularDefaultDays = 30
+
+// GetPopularDocuments lists documents by how often their files were viewed or downloaded
+// @Summary Get popular documents
+// @Description List documents by views and downloads of their files, most opened first.
+// @Description order=least lists every document, never-opened and oldest first, to find clutter that can be pruned.
+// @Description Accounts that are not administrators only see the documents their folder permissions allow.
+// @Tags Stats
+// @Produce json
+// @Param days query int false "Count opens over the last N days (default: 30, 0 for all time)"
+// @Param limit query int false "Maximum documents to return (default: 20, max: 200)"
+// @Param order query string false "most (default) or least"
+// @Success 200 {object} map[string]interface{} "Documents with accessCount, periodCount and lastAccessed"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /documents/popular [get]
+func (serverHandler *ServerHandler) GetPopularDocuments(c echo.Context) error {
+	days := popularDefaultDays
+	if value := c.QueryParam("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid days value, expected 0 or more",
+				"code":  dto.CodeBadRequest,
+			})
+		}
+		days = parsed
+	}
+	limit, err := limitParam(c, latestPageSize)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	order := strings.ToLower(c.QueryParam("order"))
+	switch order {
+	case "":
+		order = "most"
+	case "most", "least":
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid order value, expected most or least",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+
+	// Rollups are bucketed by UTC day, so the window includes today and the days-1 before it
+	var since time.Time
+	if days > 0 {
+		since = database.Now().UTC().AddDate(0, 0, 1-days)
+	}
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		return err
+	}
+	fetch := limit
+	if access != nil {
+		fetch = math.MaxInt32 // filtered before the limit is applied
+	}
+	documents, err := serverHandler.DB.GetDocumentAccessStats(since, fetch, order == "least")
+	if err != nil {
+		Logger.Error("Failed to get document access stats", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve document access statistics",
+			"code":  dto.CodeInternal,
+		})
+	}
+	if access != nil {
+		kept := documents[:0]
+		for _, document := range documents {
+			if access.canRead(document.Folder) && len(kept) < limit {
+				kept = append(kept, document)
+			}
+		}
+		documents = kept
+	}
+	if documents == nil {
+		documents = []database.DocumentAccess{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"documents": documents,
+		"days":      days,
+		"order":     order,
+		"count":     len(documents),
+	})
+}
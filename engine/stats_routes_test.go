@@ -0,0 +1,226 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+)
+
+func TestBuildStatsBucketsByFolderAndMonth(t *testing.T) {
+	// Documents in two folders across two months, one with a file on disk
+	handler := &ServerHandler{}
+	handler.ServerConfig.DocumentPath = t.TempDir()
+	invoices := filepath.Join(handler.ServerConfig.DocumentPath, "invoices")
+	if err := os.MkdirAll(invoices, 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	onDisk := filepath.Join(invoices, "a.pdf")
+	if err := os.WriteFile(onDisk, []byte("12345"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	jan := time.Date(2026, 1, 15, 12, 0, 0, 0, time.Local)
+	feb := time.Date(2026, 2, 3, 12, 0, 0, 0, time.Local)
+	documents := []database.Document{
+		{Path: onDisk, Folder: invoices, IngressTime: jan},
+		{Path: filepath.Join(invoices, "b.pdf"), Folder: invoices, IngressTime: jan},
+		{Path: filepath.Join(handler.ServerConfig.DocumentPath, "c.pdf"), Folder: handler.ServerConfig.DocumentPath, IngressTime: feb},
+	}
+
+	// Bucketing by month and folder
+	buckets := handler.buildStatsBuckets(documents, "folder", nil, statsIntervals["month"], time.Time{}, time.Time{})
+
+	// Each month/folder pair is counted and sized
+	expected := []statsBucket{
+		{Period: "2026-01", Group: "invoices", Count: 2, TotalSize: 5},
+		{Period: "2026-02", Group: "/", Count: 1, TotalSize: 0},
+	}
+	if len(buckets) != len(expected) {
+		t.Fatalf("Expected %d buckets, got %+v", len(expected), buckets)
+	}
+	for i := range expected {
+		if buckets[i] != expected[i] {
+			t.Errorf("Bucket %d: expected %+v, got %+v", i, expected[i], buckets[i])
+		}
+	}
+
+	// A date range excludes documents outside it
+	buckets = handler.buildStatsBuckets(documents, "none", nil, statsIntervals["year"], feb.AddDate(0, 0, -1), time.Time{})
+	if len(buckets) != 1 || buckets[0].Count != 1 || buckets[0].Period != "2026" {
+		t.Errorf("Expected one 2026 document after the from date, got %+v", buckets)
+	}
+}
+
+func TestBuildStatsBucketsByTag(t *testing.T) {
+	// A document tagged tax and receipts, one tagged tax, and one untagged, all in one month
+	handler := setupTestHandler(t)
+	var docs []*database.Document
+	for _, name := range []string{"a.pdf", "b.pdf", "c.pdf"} {
+		docs = append(docs, saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, name), "text"))
+	}
+	handler.DB.AddTag(docs[0].ULID.String(), "tax")
+	handler.DB.AddTag(docs[0].ULID.String(), "receipts")
+	handler.DB.AddTag(docs[1].ULID.String(), "tax")
+
+	// The stats are grouped by tag
+	req := httptest.NewRequest(http.MethodGet, "/api/stats/timeseries?groupBy=tag", nil)
+	rec := httptest.NewRecorder()
+	if err := handler.GetStatsTimeseries(handler.Echo.NewContext(req, rec)); err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	var response struct {
+		Buckets []statsBucket `json:"buckets"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with buckets, got %d %s", rec.Code, rec.Body)
+	}
+
+	// Each document counts under each of its tags, the untagged one in the empty group
+	counts := make(map[string]int)
+	for _, bucket := range response.Buckets {
+		counts[bucket.Group] += bucket.Count
+	}
+	if len(counts) != 3 || counts["tax"] != 2 || counts["receipts"] != 1 || counts[""] != 1 {
+		t.Errorf("Expected tax 2, receipts 1 and untagged 1, got %v", counts)
+	}
+}
+
+func TestBuildStatsBucketsByCorrespondent(t *testing.T) {
+	// Two documents read by the Acme template, one by the Bolt template and one no template recognised
+	handler := setupTestHandler(t)
+	templates := make(map[string]*database.ExtractionTemplate)
+	for _, correspondent := range []string{"Acme", "Bolt"} {
+		template := &database.ExtractionTemplate{Correspondent: correspondent, Match: correspondent,
+			Fields: []database.ExtractionField{{Name: "total", Pattern: `Total (\S+)`}}}
+		if err := handler.DB.CreateExtractionTemplate(template); err != nil {
+			t.Fatal(err)
+		}
+		templates[correspondent] = template
+	}
+	for name, correspondent := range map[string]string{"a.pdf": "Acme", "b.pdf": "Acme", "c.pdf": "Bolt", "d.pdf": ""} {
+		doc := saveTestDocument(t, handler.DB, filepath.Join(handler.ServerConfig.DocumentPath, name), "text")
+		if correspondent == "" {
+			continue
+		}
+		fields := []database.DocumentField{{Name: "total", Value: "1.00", TemplateULID: templates[correspondent].ULID.String()}}
+		if err := handler.DB.SaveDocumentFields(doc.ULID.String(), fields); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats/timeseries?groupBy=correspondent", nil)
+	rec := httptest.NewRecorder()
+	if err := handler.GetStatsTimeseries(handler.Echo.NewContext(req, rec)); err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	var response struct {
+		Buckets []statsBucket `json:"buckets"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with buckets, got %d %s", rec.Code, rec.Body)
+	}
+	counts := make(map[string]int)
+	for _, bucket := range response.Buckets {
+		counts[bucket.Group] += bucket.Count
+	}
+	if len(counts) != 3 || counts["Acme"] != 2 || counts["Bolt"] != 1 || counts[""] != 1 {
+		t.Errorf("Expected Acme 2, Bolt 1 and 1 without a correspondent, got %v", counts)
+	}
+}
+
+func TestGetStatsTimeseriesRejectsInvalidParams(t *testing.T) {
+	handler := setupTestHandler(t)
+	tests := []string{
+		"/api/stats/timeseries?interval=hour",
+		"/api/stats/timeseries?groupBy=colour",
+		"/api/stats/timeseries?from=yesterday",
+	}
+	for _, target := range tests {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		if err := handler.GetStatsTimeseries(handler.Echo.NewContext(req, rec)); err != nil {
+			t.Fatalf("%s: handler returned error: %v", target, err)
+		}
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+
+	// Valid defaults return an empty bucket list for an empty database
+	req := httptest.NewRequest(http.MethodGet, "/api/stats/timeseries", nil)
+	rec := httptest.NewRecorder()
+	if err := handler.GetStatsTimeseries(handler.Echo.NewContext(req, rec)); err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	var response struct {
+		Interval string        `json:"interval"`
+		Buckets  []statsBucket `json:"buckets"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || response.Interval != "month" || response.Buckets == nil {
+		t.Errorf("Unexpected default response %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGetPopularDocumentsCountsViews(t *testing.T) {
+	// Two documents with files on disk
+	handler := setupTestHandler(t)
+	var docs []*database.Document
+	for _, name := range []string{"manual.pdf", "receipt.pdf"} {
+		path := filepath.Join(handler.ServerConfig.DocumentPath, name)
+		if err := os.WriteFile(path, []byte("contents of "+name), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		docs = append(docs, saveTestDocument(t, handler.DB, path, ""))
+	}
+	handler.Echo.GET(documentViewPrefix+":id", handler.ViewDocument)
+	view := func(doc *database.Document, method string, byteRange string) {
+		req := httptest.NewRequest(method, documentViewPrefix+doc.ULID.String(), nil)
+		if byteRange != "" {
+			req.Header.Set("Range", byteRange)
+		}
+		handler.Echo.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// The manual is opened twice, once with a PDF viewer fetching the rest in ranges, and the receipt once
+	view(docs[0], http.MethodGet, "")
+	view(docs[0], http.MethodGet, "bytes=0-")
+	view(docs[0], http.MethodGet, "bytes=5-")
+	view(docs[1], http.MethodGet, "")
+	view(docs[1], http.MethodHead, "")
+
+	// The popular listing counts one view per open, most opened first
+	rec := httptest.NewRecorder()
+	if err := handler.GetPopularDocuments(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/documents/popular", nil), rec)); err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	var response struct {
+		Documents []database.DocumentAccess
+		Days      int
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response %s: %v", rec.Body.String(), err)
+	}
+	if rec.Code != http.StatusOK || response.Days != popularDefaultDays || len(response.Documents) != 2 {
+		t.Fatalf("Unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if response.Documents[0].ULID != docs[0].ULID || response.Documents[0].PeriodCount != 2 || response.Documents[1].PeriodCount != 1 {
+		t.Errorf("Expected the manual with 2 views then the receipt with 1, got %+v", response.Documents)
+	}
+
+	// Invalid parameters are rejected
+	for _, target := range []string{"?days=-1", "?limit=0", "?limit=500", "?order=random"} {
+		rec := httptest.NewRecorder()
+		handler.GetPopularDocuments(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/documents/popular"+target, nil), rec))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
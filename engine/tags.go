@@ -0,0 +1,398 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// maxTagLength is the longest tag name, in characters
+const maxTagLength = 50
+
+// tagRequest is the body of AddDocumentTag
+type tagRequest struct {
+	Tag string `json:"tag"`
+}
+
+// normalizeTag trims and lower-cases a tag name so "Tax" and "tax " are the same tag. Tags appear in URL
+// paths, so they may not contain a slash.
+func normalizeTag(raw string) (string, bool) {
+	tag := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+	if tag == "" || utf8.RuneCountInString(tag) > maxTagLength || strings.Contains(tag, "/") {
+		return "", false
+	}
+	return tag, true
+}
+
+// tagParam reads and normalizes the tag path parameter
+func tagParam(c echo.Context) (string, bool) {
+	raw := c.Param("tag")
+	if unescaped, err := url.PathUnescape(raw); err == nil {
+		raw = unescaped
+	}
+	return normalizeTag(raw)
+}
+
+// invalidTag answers 400 for a tag name normalizeTag refused
+func invalidTag(c echo.Context) error {
+	return c.JSON(http.StatusBadRequest, map[string]interface{}{
+		"error": "A tag is 1 to 50 characters without a slash",
+		"code":  dto.CodeValidation,
+	})
+}
+
+// taggedDocument looks up the document a tag request is for and checks the signed-in account may read it,
+// or also change it when write is set, answering the error response itself when not
+func (serverHandler *ServerHandler) taggedDocument(c echo.Context, write bool) (*database.Document, bool, error) {
+	id, ok, err := ulidParam(c, "id", "document")
+	if !ok {
+		return nil, false, err
+	}
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		Logger.Error("Failed to load folder permissions", "error", err)
+		return nil, false, c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to load folder permissions",
+			"code":  dto.CodeInternal,
+		})
+	}
+	document, err := serverHandler.DB.GetDocumentByULID(id.String())
+	if err != nil || !access.canRead(document.Folder) {
+		return nil, false, c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if write && !access.canWrite(document.Folder) {
+		return nil, false, folderForbidden(c, document.Folder)
+	}
+	return document, true, nil
+}
+
+// documentTagsResponse answers with the tags now on a document
+func (serverHandler *ServerHandler) documentTagsResponse(c echo.Context, status int, document *database.Document) error {
+	tags, err := serverHandler.DB.GetDocumentTags(document.ULID.String())
+	if err != nil {
+		Logger.Error("Failed to get document tags", "ulid", document.ULID.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to get document tags",
+			"code":  dto.CodeInternal,
+		})
+	}
+	return c.JSON(status, map[string]interface{}{
+		"id":   document.ULID.String(),
+		"tags": tags,
+	})
+}
+
+// ListTags returns every tag with how many documents carry it
+// @Summary List tags
+// @Description Every tag in name order with the number of documents carrying it. A signed-in account restricted by folder permissions only has the documents it can read counted.
+// @Tags Tags
+// @Produce json
+// @Success 200 {array} dto.Tag "Tags"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /tags [get]
+func (serverHandler *ServerHandler) ListTags(c echo.Context) error {
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		Logger.Error("Failed to load folder permissions", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to load folder permissions",
+			"code":  dto.CodeInternal,
+		})
+	}
+	tags, err := serverHandler.DB.ListTags()
+	if err != nil {
+		Logger.Error("Failed to list tags", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list tags",
+			"code":  dto.CodeInternal,
+		})
+	}
+	visible := make([]dto.Tag, 0, len(tags))
+	for _, tag := range tags {
+		if access != nil {
+			documents, err := serverHandler.DB.GetDocumentsByTag(tag.Name)
+			if err != nil {
+				Logger.Error("Failed to get tagged documents", "tag", tag.Name, "error", err)
+				continue
+			}
+			if tag.DocumentCount = len(access.readable(documents)); tag.DocumentCount == 0 {
+				continue
+			}
+		}
+		visible = append(visible, dto.Tag{Name: tag.Name, DocumentCount: tag.DocumentCount})
+	}
+	return c.JSON(http.StatusOK, visible)
+}
+
+// GetDocumentsByTag returns the documents carrying a tag
+// @Summary Documents with a tag
+// @Description The documents carrying a tag in name order, without their text. An unknown tag has no documents.
+// @Tags Tags
+// @Produce json
+// @Param tag path string true "Tag name"
+// @Success 200 {array} database.Document "Tagged documents"
+// @Failure 400 {object} dto.ErrorResponse "Invalid tag"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /tags/{tag}/documents [get]
+func (serverHandler *ServerHandler) GetDocumentsByTag(c echo.Context) error {
+	tag, ok := tagParam(c)
+	if !ok {
+		return invalidTag(c)
+	}
+	access, err := serverHandler.folderAccess(c)
+	if err != nil {
+		Logger.Error("Failed to load folder permissions", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to load folder permissions",
+			"code":  dto.CodeInternal,
+		})
+	}
+	documents, err := serverHandler.DB.GetDocumentsByTag(tag)
+	if err != nil {
+		Logger.Error("Failed to get tagged documents", "tag", tag, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to get tagged documents",
+			"code":  dto.CodeInternal,
+		})
+	}
+	return c.JSON(http.StatusOK, access.readable(documents))
+}
+
+// GetDocumentTags returns the tags on a document
+// @Summary Tags on a document
+// @Tags Tags
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Success 200 {object} map[string]interface{} "id and tags"
+// @Failure 400 {object} dto.ErrorResponse "Invalid ULID"
+// @Failure 404 {object} dto.ErrorResponse "Document not found"
+// @Router /document/{id}/tags [get]
+func (serverHandler *ServerHandler) GetDocumentTags(c echo.Context) error {
+	document, ok, err := serverHandler.taggedDocument(c, false)
+	if !ok {
+		return err
+	}
+	return serverHandler.documentTagsResponse(c, http.StatusOK, document)
+}
+
+// AddDocumentTag tags a document
+// @Summary Tag a document
+// @Description Add a tag to a document, creating the tag if it is new. Tags are lower-cased and trimmed, so "Tax" and "tax" are the same tag; tagging a document twice does nothing.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Param tag body tagRequest true "Tag name"
+// @Success 200 {object} map[string]interface{} "id and the document's tags"
+// @Failure 400 {object} dto.ErrorResponse "Invalid ULID or tag"
+// @Failure 403 {object} dto.ErrorResponse "No write access to the document's folder"
+// @Failure 404 {object} dto.ErrorResponse "Document not found"
+// @Router /document/{id}/tags [post]
+func (serverHandler *ServerHandler) AddDocumentTag(c echo.Context) error {
+	document, ok, err := serverHandler.taggedDocument(c, true)
+	if !ok {
+		return err
+	}
+	var request tagRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	tag, ok := normalizeTag(request.Tag)
+	if !ok {
+		return invalidTag(c)
+	}
+	if err := serverHandler.DB.AddTag(document.ULID.String(), tag); err != nil {
+		Logger.Error("Failed to tag document", "ulid", document.ULID.String(), "tag", tag, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to tag document",
+			"code":  dto.CodeInternal,
+		})
+	}
+	serverHandler.invalidateDocumentCache() // smart folders in the cached tree may filter by the tag
+	return serverHandler.documentTagsResponse(c, http.StatusOK, document)
+}
+
+// RemoveDocumentTag takes a tag off a document
+// @Summary Untag a document
+// @Description Remove a tag from a document. A tag no document carries any more is no longer listed.
+// @Tags Tags
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Param tag path string true "Tag name"
+// @Success 200 {object} map[string]interface{} "id and the document's remaining tags"
+// @Failure 400 {object} dto.ErrorResponse "Invalid ULID or tag"
+// @Failure 403 {object} dto.ErrorResponse "No write access to the document's folder"
+// @Failure 404 {object} dto.ErrorResponse "Document not found or not tagged"
+// @Router /document/{id}/tags/{tag} [delete]
+func (serverHandler *ServerHandler) RemoveDocumentTag(c echo.Context) error {
+	document, ok, err := serverHandler.taggedDocument(c, true)
+	if !ok {
+		return err
+	}
+	tag, ok := tagParam(c)
+	if !ok {
+		return invalidTag(c)
+	}
+	if err := serverHandler.DB.RemoveTag(document.ULID.String(), tag); errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "The document is not tagged " + tag,
+			"code":  dto.CodeNotFound,
+		})
+	} else if err != nil {
+		Logger.Error("Failed to untag document", "ulid", document.ULID.String(), "tag", tag, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to untag document",
+			"code":  dto.CodeInternal,
+		})
+	}
+	serverHandler.invalidateDocumentCache()
+	return serverHandler.documentTagsResponse(c, http.StatusOK, document)
+}
+
+// renameTagRequest is the body of RenameTag
+type renameTagRequest struct {
+	Name string `json:"name"`
+}
+
+// mergeTagRequest is the body of MergeTag
+type mergeTagRequest struct {
+	Into string `json:"into"`
+}
+
+// RenameTag renames a tag on every document carrying it
+// @Summary Rename a tag
+// @Description Rename a tag everywhere in one transaction: every document carrying it and every smart folder filtering by it move to the new name, and the rename is written to the activity feed. The new name is normalised like any tag. To fold a tag into one that already exists, merge it instead. With sign-in or ADMIN_USERS only administrators may rename tags.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param tag path string true "Tag name"
+// @Param request body renameTagRequest true "New tag name"
+// @Success 200 {object} dto.Tag "The renamed tag"
+// @Failure 400 {object} dto.ErrorResponse "Invalid tag or the same name"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 404 {object} dto.ErrorResponse "No document carries the tag"
+// @Failure 409 {object} dto.ErrorResponse "A tag with the new name exists"
+// @Router /tags/{tag} [put]
+func (serverHandler *ServerHandler) RenameTag(c echo.Context) error {
+	var request renameTagRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	return serverHandler.retag(c, request.Name, database.AuditTagRenamed)
+}
+
+// MergeTag moves every document carrying a tag onto another tag
+// @Summary Merge a tag into another
+// @Description Merge a tag into an existing one in one transaction: every document carrying it is tagged with the other instead, smart folders filtering by it follow, the tag goes and the merge is written to the activity feed. With sign-in or ADMIN_USERS only administrators may merge tags.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param tag path string true "Tag to merge away"
+// @Param request body mergeTagRequest true "Tag to merge into"
+// @Success 200 {object} dto.Tag "The tag merged into"
+// @Failure 400 {object} dto.ErrorResponse "Invalid tag or the same tag"
+// @Failure 403 {object} dto.ErrorResponse "Not an administrator"
+// @Failure 404 {object} dto.ErrorResponse "Either tag is not on any document"
+// @Router /tags/{tag}/merge [post]
+func (serverHandler *ServerHandler) MergeTag(c echo.Context) error {
+	var request mergeTagRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+			"code":  dto.CodeBadRequest,
+		})
+	}
+	return serverHandler.retag(c, request.Into, database.AuditTagMerged)
+}
+
+// retag moves the tag in the path onto the tag named into, for a rename (action AuditTagRenamed, into must
+// be new) or a merge (AuditTagMerged, into must exist)
+func (serverHandler *ServerHandler) retag(c echo.Context, into string, action string) error {
+	if serverHandler.notAdmin(c) {
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": "Only administrators may rename or merge tags",
+			"code":  dto.CodeForbidden,
+		})
+	}
+	from, ok := tagParam(c)
+	if !ok {
+		return invalidTag(c)
+	}
+	into, ok = normalizeTag(into)
+	if !ok {
+		return invalidTag(c)
+	}
+	if into == from {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "The tag is already called " + into,
+			"code":  dto.CodeValidation,
+		})
+	}
+
+	tags, err := serverHandler.DB.ListTags()
+	if err != nil {
+		Logger.Error("Failed to list tags", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list tags",
+			"code":  dto.CodeInternal,
+		})
+	}
+	exists := func(name string) bool {
+		return slices.ContainsFunc(tags, func(tag database.Tag) bool { return tag.Name == name })
+	}
+	switch {
+	case !exists(from):
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "No document is tagged " + from,
+			"code":  dto.CodeNotFound,
+		})
+	case action == database.AuditTagRenamed && exists(into):
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": "A tag called " + into + " exists; merge into it instead",
+			"code":  dto.CodeConflict,
+		})
+	case action == database.AuditTagMerged && !exists(into):
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "No document is tagged " + into,
+			"code":  dto.CodeNotFound,
+		})
+	}
+
+	event := &database.AuditEvent{Action: action, Target: from, Detail: into, User: requestUser(c)}
+	if _, err := serverHandler.DB.MergeTag(from, into, event); errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "No document is tagged " + from,
+			"code":  dto.CodeNotFound,
+		})
+	} else if err != nil {
+		Logger.Error("Failed to retag documents", "from", from, "into", into, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retag documents",
+			"code":  dto.CodeInternal,
+		})
+	}
+	serverHandler.invalidateDocumentCache() // smart folders in the cached tree may filter by the tag
+
+	documents, err := serverHandler.DB.GetDocumentsByTag(into)
+	if err != nil {
+		Logger.Error("Failed to get tagged documents", "tag", into, "error", err)
+	}
+	return c.JSON(http.StatusOK, dto.Tag{Name: into, DocumentCount: len(documents)})
+}
@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/oklog/ulid/v2"
+)
+
+func TestDocumentTags(t *testing.T) {
+	// A P60 and a boiler service report
+	handler := setupMemoryTestHandler(t, config.ServerConfig{DocumentPath: t.TempDir()})
+	handler.Echo.GET("/api/tags", handler.ListTags)
+	handler.Echo.GET("/api/tags/:tag/documents", handler.GetDocumentsByTag)
+	handler.Echo.GET("/api/document/:id/tags", handler.GetDocumentTags)
+	handler.Echo.POST("/api/document/:id/tags", handler.AddDocumentTag)
+	handler.Echo.DELETE("/api/document/:id/tags/:tag", handler.RemoveDocumentTag)
+	p60 := saveTestDocument(t, handler.DB, "/docs/p60.pdf", "tax year 2023 p60")
+	boiler := saveTestDocument(t, handler.DB, "/docs/boiler.pdf", "boiler service")
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		return rec
+	}
+	tagsOf := func(rec *httptest.ResponseRecorder) []string {
+		t.Helper()
+		var body struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to decode %d %q: %v", rec.Code, rec.Body.String(), err)
+		}
+		return body.Tags
+	}
+
+	// The P60 is tagged "Tax " and "2023", and the boiler report "tax"
+	serve(http.MethodPost, "/api/document/"+p60.ULID.String()+"/tags", `{"tag":"Tax "}`)
+	rec := serve(http.MethodPost, "/api/document/"+p60.ULID.String()+"/tags", `{"tag":"2023"}`)
+	serve(http.MethodPost, "/api/document/"+boiler.ULID.String()+"/tags", `{"tag":"tax"}`)
+
+	// The tags are normalised, so both documents share one tax tag
+	if tags := tagsOf(rec); rec.Code != http.StatusOK || strings.Join(tags, ",") != "2023,tax" {
+		t.Errorf("Expected 2023 and tax on the P60, got %d %v", rec.Code, tags)
+	}
+	var listed []dto.Tag
+	json.Unmarshal(serve(http.MethodGet, "/api/tags", "").Body.Bytes(), &listed)
+	if len(listed) != 2 || listed[1] != (dto.Tag{Name: "tax", DocumentCount: 2}) {
+		t.Errorf("Expected 2023 and tax on two documents, got %+v", listed)
+	}
+	var tagged []database.Document
+	json.Unmarshal(serve(http.MethodGet, "/api/tags/TAX/documents", "").Body.Bytes(), &tagged)
+	if len(tagged) != 2 || tagged[0].Name != "boiler.pdf" {
+		t.Errorf("Expected both documents by name, got %+v", tagged)
+	}
+
+	// The boiler report is untagged
+	rec = serve(http.MethodDelete, "/api/document/"+boiler.ULID.String()+"/tags/tax", "")
+
+	// It has no tags left and only the P60 carries tax
+	if tags := tagsOf(rec); rec.Code != http.StatusOK || len(tags) != 0 {
+		t.Errorf("Expected no tags left, got %d %v", rec.Code, tags)
+	}
+	json.Unmarshal(serve(http.MethodGet, "/api/tags/tax/documents", "").Body.Bytes(), &tagged)
+	if len(tagged) != 1 || tagged[0].ULID != p60.ULID {
+		t.Errorf("Expected only the P60, got %+v", tagged)
+	}
+
+	// Untagging again, an unknown document and a bad tag are refused
+	for _, refused := range []struct {
+		method, target, body string
+		code                 int
+	}{
+		{http.MethodDelete, "/api/document/" + boiler.ULID.String() + "/tags/tax", "", http.StatusNotFound},
+		{http.MethodGet, "/api/document/" + ulid.Make().String() + "/tags", "", http.StatusNotFound},
+		{http.MethodPost, "/api/document/" + p60.ULID.String() + "/tags", `{"tag":"  "}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/document/" + p60.ULID.String() + "/tags", `{"tag":"a/b"}`, http.StatusBadRequest},
+	} {
+		if rec := serve(refused.method, refused.target, refused.body); rec.Code != refused.code {
+			t.Errorf("Expected %d for %s %s, got %d", refused.code, refused.method, refused.target, rec.Code)
+		}
+	}
+}
+
+func TestTagsFollowFolderPermissions(t *testing.T) {
+	// A tagged document in a medical folder only alice may read
+	handler := setupTestHandler(t)
+	handler.ServerConfig.WebUIPass = true
+	handler.Echo.Use(handler.RequireLogin())
+	handler.Echo.GET("/api/tags", handler.ListTags)
+	handler.Echo.POST("/api/document/:id/tags", handler.AddDocumentTag)
+	doc := saveTestDocument(t, handler.DB, handler.ServerConfig.DocumentPath+"/medical/scan.pdf", "x-ray")
+	handler.DB.AddTag(doc.ULID.String(), "health")
+	alice := signInAs(t, handler, "alice", database.RoleViewer)
+	bob := signInAs(t, handler, "bob", database.RoleEditor)
+	aliceUser, _ := handler.DB.GetUserByUsername("alice")
+	handler.DB.SetFolderPermission(&database.FolderPermission{Folder: "medical", UserID: aliceUser.ID, Access: database.AccessRead})
+	serve := func(method, target, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.Echo.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Alice sees the tag but may not change it; bob sees neither the tag nor the document
+	if rec := serve(http.MethodGet, "/api/tags", alice, ""); !strings.Contains(rec.Body.String(), `"health"`) {
+		t.Errorf("Expected alice to see the health tag, got %s", rec.Body)
+	}
+	if rec := serve(http.MethodPost, "/api/document/"+doc.ULID.String()+"/tags", alice, `{"tag":"x-ray"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for alice, a viewer, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/api/tags", bob, ""); strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("Expected no tags for bob, got %s", rec.Body)
+	}
+	if rec := serve(http.MethodPost, "/api/document/"+doc.ULID.String()+"/tags", bob, `{"tag":"x-ray"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for bob, got %d", rec.Code)
+	}
+}
+
+func TestRenameAndMergeTags(t *testing.T) {
+	handler := setupMemoryTestHandler(t, config.ServerConfig{DocumentPath: t.TempDir(), AdminUsers: []string{"alice"}})
+	handler.Echo.PUT("/api/tags/:tag", handler.RenameTag)
+	handler.Echo.POST("/api/tags/:tag/merge", handler.MergeTag)
+	p60 := saveTestDocument(t, handler.DB, "/docs/p60.pdf", "tax year 2023 p60")
+	boiler := saveTestDocument(t, handler.DB, "/docs/boiler.pdf", "boiler service")
+	for _, tagging := range [][2]string{{p60.ULID.String(), "taxes"}, {boiler.ULID.String(), "house"}, {boiler.ULID.String(), "tax"}} {
+		if err := handler.DB.AddTag(tagging[0], tagging[1]); err != nil {
+			t.Fatalf("AddTag failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		user     string
+		body     string
+		wantCode int
+		wantTag  dto.Tag
+	}{
+		{"only administrators", http.MethodPut, "/api/tags/taxes", "bob", `{"name":"tax returns"}`, http.StatusForbidden, dto.Tag{}},
+		{"unknown tag", http.MethodPut, "/api/tags/bills", "alice", `{"name":"invoices"}`, http.StatusNotFound, dto.Tag{}},
+		{"same name", http.MethodPut, "/api/tags/taxes", "alice", `{"name":" Taxes"}`, http.StatusBadRequest, dto.Tag{}},
+		{"rename onto an existing tag", http.MethodPut, "/api/tags/taxes", "alice", `{"name":"tax"}`, http.StatusConflict, dto.Tag{}},
+		{"merge into an unknown tag", http.MethodPost, "/api/tags/taxes/merge", "alice", `{"into":"bills"}`, http.StatusNotFound, dto.Tag{}},
+		{"merge", http.MethodPost, "/api/tags/taxes/merge", "alice", `{"into":"Tax"}`, http.StatusOK, dto.Tag{Name: "tax", DocumentCount: 2}},
+		{"rename", http.MethodPut, "/api/tags/house", "alice", `{"name":"Home"}`, http.StatusOK, dto.Tag{Name: "home", DocumentCount: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetBasicAuth(tt.user, "secret")
+			rec := httptest.NewRecorder()
+			handler.Echo.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("Expected %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			var tag dto.Tag
+			if json.Unmarshal(rec.Body.Bytes(), &tag); tt.wantCode == http.StatusOK && tag != tt.wantTag {
+				t.Errorf("Expected %+v, got %+v", tt.wantTag, tag)
+			}
+		})
+	}
+
+	tags, _ := handler.DB.GetDocumentTags(p60.ULID.String())
+	if strings.Join(tags, ",") != "tax" {
+		t.Errorf("Expected the P60 tagged tax after the merge, got %v", tags)
+	}
+	events, _ := handler.DB.ListAuditEvents(nil, 10)
+	if len(events) != 2 || auditActivity(events[0]).Summary != "Renamed tag house to home" || auditActivity(events[1]).Summary != "Merged tag taxes into tax" || events[1].User != "alice" {
+		t.Errorf("Expected the merge then the rename by alice in the activity feed, got %+v", events)
+	}
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// staleTempAge is how old leftover temp entries must be before the startup sweep removes them
+const staleTempAge = time.Hour
+
+// tempRoot is the directory OCR artifacts are written under
+func (serverHandler *ServerHandler) tempRoot() string {
+	if serverHandler.ServerConfig.TempPath != "" {
+		return serverHandler.ServerConfig.TempPath
+	}
+	return filepath.Join(os.TempDir(), "godocs")
+}
+
+// newWorkDir creates a private subdirectory of the temp root for one processing step.
+// The returned cleanup removes it and everything written into it.
+func (serverHandler *ServerHandler) newWorkDir(pattern string) (string, func(), error) {
+	root := serverHandler.tempRoot()
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", nil, err
+	}
+	dir, err := os.MkdirTemp(root, pattern)
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			Logger.Warn("Unable to remove temp directory", "dir", dir, "error", err)
+		}
+	}
+	return dir, cleanup, nil
+}
+
+// sweepTempDir removes entries in the temp root left behind by earlier runs (e.g. after a crash)
+func sweepTempDir(root string, olderThan time.Duration) (int, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	cutoff := time.Now().Add(-olderThan)
+	removed := 0
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(root, entry.Name())); err != nil {
+			Logger.Warn("Unable to remove stale temp entry", "path", entry.Name(), "error", err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// tempDirectoryChecks creates the temp root and sweeps stale OCR artifacts from it
+func (serverHandler *ServerHandler) tempDirectoryChecks() error {
+	root := serverHandler.tempRoot()
+	if err := os.MkdirAll(root, 0755); err != nil {
+		Logger.Error("Unable to create temp directory", "path", root, "error", err)
+		return err
+	}
+	removed, err := sweepTempDir(root, staleTempAge)
+	if err != nil {
+		Logger.Error("Unable to sweep temp directory", "path", root, "error", err)
+		return err
+	}
+	Logger.Info("Temp directory ready", "path", root, "staleRemoved", removed)
+	return nil
+}
+
+// copyFile copies src to dst, used to give tools a temp copy under a safe name
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewWorkDirCleanup(t *testing.T) {
+	// A handler with a configured temp root
+	handler := setupTestHandler(t)
+	handler.ServerConfig.TempPath = filepath.Join(t.TempDir(), "tmp")
+
+	// A work directory is created, used and cleaned up
+	dir, cleanup, err := handler.newWorkDir("ocr-*")
+	if err != nil {
+		t.Fatalf("newWorkDir failed: %v", err)
+	}
+	if filepath.Dir(dir) != handler.ServerConfig.TempPath {
+		t.Errorf("Work directory %s is not under the temp root", dir)
+	}
+	os.WriteFile(filepath.Join(dir, "page.png"), []byte("png"), 0644)
+	cleanup()
+
+	// Nothing is left behind
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Work directory still exists: %v", err)
+	}
+}
+
+func TestSweepTempDirRemovesStaleEntries(t *testing.T) {
+	// A temp root with one old and one fresh work directory
+	root := t.TempDir()
+	stale := filepath.Join(root, "ocr-old")
+	fresh := filepath.Join(root, "ocr-new")
+	for _, dir := range []string{stale, fresh} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(stale, old, old)
+
+	// Sweeping entries older than an hour
+	removed, err := sweepTempDir(root, time.Hour)
+
+	// Only the stale directory is removed
+	if err != nil || removed != 1 {
+		t.Fatalf("Expected 1 removal, got %d, %v", removed, err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("Stale directory was not removed")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("Fresh directory was removed: %v", err)
+	}
+	if removed, err := sweepTempDir(filepath.Join(root, "missing"), time.Hour); err != nil || removed != 0 {
+		t.Errorf("Missing root should be a no-op, got %d, %v", removed, err)
+	}
+}
@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+	"github.com/oklog/ulid/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Stages recorded on a document's processing timeline
+const (
+	stageReceived      = "received"
+	stageRotated       = "rotated"     // a sideways or upside-down scan was turned upright, before it was hashed
+	stageBlankPages    = "blank_pages" // blank pages were found in a scan, and dropped when BLANK_PAGES is remove
+	stageStored        = "stored"      // copied into document storage and its hash verified
+	stageTextExtracted = "text_extracted"
+	stageOCR           = "ocr"
+	stageIndexed       = "indexed" // saved with its text, so search finds it
+	stageWordCloud     = "wordcloud_updated"
+	stageHook          = "hook" // a post-ingestion command or webhook ran, with its output
+	stageFailed        = "failed"
+)
+
+// documentTimeline collects the stages of one document as it is processed. Stages are kept until
+// save, since a document has no ULID when it is received. A nil timeline records nothing.
+// The timeline also carries the document's ingestion span, which the spans of its steps are children of.
+type documentTimeline struct {
+	events     []database.DocumentEvent
+	rotations  []database.PageRotation // pages turned upright, saved with the stages
+	blankPages []database.BlankPage    // blank pages found, saved with the stages
+	ctx        context.Context
+	span       trace.Span // ended on save
+}
+
+// startTimeline begins a timeline with the received stage; detail says where the document came from
+func startTimeline(detail string) *documentTimeline {
+	timeline := &documentTimeline{}
+	timeline.ctx, timeline.span = startSpan(context.Background(), "ingest document", attribute.String("godocs.source", detail))
+	timeline.mark(stageReceived, detail)
+	return timeline
+}
+
+// context returns the context holding the document's ingestion span
+func (t *documentTimeline) context() context.Context {
+	if t == nil || t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
+// step starts the span of an ingestion step, such as OCR or saving, within the document's span
+func (t *documentTimeline) step(name string, attributes ...attribute.KeyValue) trace.Span {
+	_, span := startSpan(t.context(), name, attributes...)
+	return span
+}
+
+// mark records that the document reached a stage now
+func (t *documentTimeline) mark(stage, detail string) {
+	if t == nil {
+		return
+	}
+	t.events = append(t.events, database.DocumentEvent{Stage: stage, Detail: detail, At: database.Now()})
+}
+
+// rotated marks the rotated stage and keeps the pages turned, to be recorded against the document on save
+func (t *documentTimeline) rotated(rotations []database.PageRotation) {
+	if t == nil || len(rotations) == 0 {
+		return
+	}
+	turned := make([]string, 0, len(rotations))
+	for _, rotation := range rotations {
+		turned = append(turned, fmt.Sprintf("page %d by %d°", rotation.Page, rotation.Degrees))
+	}
+	t.mark(stageRotated, strings.Join(turned, ", "))
+	t.rotations = append(t.rotations, rotations...)
+}
+
+// blank marks the blank pages stage and keeps the pages found, to be recorded against the document on save
+func (t *documentTimeline) blank(pages []database.BlankPage) {
+	if t == nil || len(pages) == 0 {
+		return
+	}
+	numbers := make([]string, 0, len(pages))
+	for _, page := range pages {
+		numbers = append(numbers, strconv.Itoa(page.Page))
+	}
+	detail := "kept pages " + strings.Join(numbers, ", ")
+	if pages[0].Removed {
+		detail = "removed pages " + strings.Join(numbers, ", ")
+	}
+	t.mark(stageBlankPages, detail)
+	t.blankPages = append(t.blankPages, pages...)
+}
+
+// save writes the stages recorded so far against the document, with any pages turned upright or found
+// blank. A timeline is only for diagnosis, so failing to write it is logged rather than failing the ingestion.
+func (t *documentTimeline) save(db database.Repository, id ulid.ULID) {
+	if t == nil {
+		return
+	}
+	if t.span != nil {
+		t.span.SetAttributes(attribute.String("godocs.document.id", id.String()))
+		t.span.End()
+	}
+	if len(t.rotations) > 0 {
+		if err := db.SaveDocumentRotations(id.String(), t.rotations); err != nil {
+			Logger.Warn("Unable to record page rotations", "ulid", id.String(), "error", err)
+		}
+		t.rotations = nil
+	}
+	if len(t.blankPages) > 0 {
+		if err := db.SaveBlankPages(id.String(), t.blankPages); err != nil {
+			Logger.Warn("Unable to record blank pages", "ulid", id.String(), "error", err)
+		}
+		t.blankPages = nil
+	}
+	if len(t.events) == 0 {
+		return
+	}
+	for i := range t.events {
+		t.events[i].DocumentULID = id.String()
+	}
+	if err := db.RecordDocumentEvents(t.events); err != nil {
+		Logger.Warn("Unable to record document timeline", "ulid", id.String(), "error", err)
+	}
+	t.events = nil
+}
+
+// recordStage marks the same stage on several documents at once, for work done a batch at a time
+func recordStage(db database.Repository, ulids []string, stage, detail string) {
+	if len(ulids) == 0 {
+		return
+	}
+	now := database.Now()
+	events := make([]database.DocumentEvent, 0, len(ulids))
+	for _, id := range ulids {
+		events = append(events, database.DocumentEvent{DocumentULID: id, Stage: stage, Detail: detail, At: now})
+	}
+	if err := db.RecordDocumentEvents(events); err != nil {
+		Logger.Warn("Unable to record document timeline", "stage", stage, "documents", len(ulids), "error", err)
+	}
+}
+
+// timelineStage is one stage as returned by the timeline API
+type timelineStage struct {
+	database.DocumentEvent
+	SincePreviousMs int64 `json:"sincePreviousMs"`
+	SinceReceivedMs int64 `json:"sinceReceivedMs"`
+}
+
+// GetDocumentTimeline returns when a document reached each processing stage
+// @Summary Get a document's processing timeline
+// @Description When the document was received, stored, had its text extracted or OCRed, was indexed and was counted in the word cloud,
+// @Description with the time since the previous stage, to find where a slow or stuck document spent its time.
+// @Description Documents ingested before timelines were recorded have no stages.
+// @Tags Documents
+// @Produce json
+// @Param id path string true "Document ULID"
+// @Success 200 {object} map[string]interface{} "id, name, stages and totalMs"
+// @Failure 400 {object} map[string]interface{} "Invalid document ULID"
+// @Failure 404 {object} map[string]interface{} "Document not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /document/{id}/timeline [get]
+func (serverHandler *ServerHandler) GetDocumentTimeline(c echo.Context) error {
+	id, ok, err := ulidParam(c, "id", "document")
+	if !ok {
+		return err
+	}
+	if serverHandler.hiddenDocument(c, id.String()) {
+		return documentNotFound(c)
+	}
+	doc, err := serverHandler.DB.GetDocumentByULID(id.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document not found",
+			"code":  dto.CodeNotFound,
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to look up document for timeline", "ulid", id.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve document",
+			"code":  dto.CodeInternal,
+		})
+	}
+	events, err := serverHandler.DB.GetDocumentEvents(doc.ULID.String())
+	if err != nil {
+		Logger.Error("Failed to read document timeline", "ulid", doc.ULID.String(), "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve document timeline",
+			"code":  dto.CodeInternal,
+		})
+	}
+
+	stages := make([]timelineStage, 0, len(events))
+	var totalMs int64
+	for i, event := range events {
+		stage := timelineStage{DocumentEvent: event}
+		if i > 0 {
+			stage.SincePreviousMs = event.At.Sub(events[i-1].At).Milliseconds()
+			stage.SinceReceivedMs = event.At.Sub(events[0].At).Milliseconds()
+			totalMs = stage.SinceReceivedMs
+		}
+		stages = append(stages, stage)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"id":      doc.ULID.String(),
+		"name":    doc.Name,
+		"stages":  stages,
+		"totalMs": totalMs,
+	})
+}
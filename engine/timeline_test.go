@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/oklog/ulid/v2"
+)
+
+func TestDocumentTimeline(t *testing.T) {
+	// A text file uploaded into a folder
+	handler := setupTestHandler(t)
+	rec := httptest.NewRecorder()
+	req := uploadRequest(t, "note.txt", "wombat timeline", map[string]string{"folder": "bills"})
+	if err := handler.UploadDocuments(handler.Echo.NewContext(req, rec)); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Upload failed: %v %s", err, rec.Body.String())
+	}
+	doc, err := handler.DB.GetDocumentByPath(filepath.ToSlash(filepath.Join(handler.ServerConfig.DocumentPath, "bills", "note.txt")))
+	if err != nil {
+		t.Fatalf("Uploaded document not found: %v", err)
+	}
+	recordStage(handler.DB, []string{doc.ULID.String()}, stageWordCloud, "")
+	timeline := func(id string) (int, map[string]interface{}) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		c := handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/document/"+id+"/timeline", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		if err := handler.GetDocumentTimeline(c); err != nil {
+			t.Fatalf("Timeline request failed: %v", err)
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		return rec.Code, response
+	}
+
+	// Its timeline is requested
+	code, response := timeline(doc.ULID.String())
+
+	// Every stage it went through is listed in order, timed from the one before
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %v", code, response)
+	}
+	want := []string{stageReceived, stageStored, stageTextExtracted, stageIndexed, stageWordCloud}
+	stages := response["stages"].([]interface{})
+	if len(stages) != len(want) {
+		t.Fatalf("Expected stages %v, got %v", want, stages)
+	}
+	for i, entry := range stages {
+		stage := entry.(map[string]interface{})
+		if stage["stage"] != want[i] {
+			t.Errorf("Expected stage %d to be %s, got %v", i, want[i], stage["stage"])
+		}
+		if stage["sincePreviousMs"].(float64) < 0 {
+			t.Errorf("Stage %s finished before the one before it: %v", want[i], stage)
+		}
+	}
+	if stages[0].(map[string]interface{})["detail"] != "upload" || response["name"] != "note.txt" {
+		t.Errorf("Unexpected timeline %v", response)
+	}
+
+	// A malformed ULID is refused and an unknown document is not found
+	if code, response := timeline("not-a-ulid"); code != http.StatusBadRequest || response["code"] != string(dto.CodeInvalidID) {
+		t.Errorf("Expected 400 %s for a malformed ULID, got %d %v", dto.CodeInvalidID, code, response)
+	}
+	if code, response := timeline(ulid.Make().String()); code != http.StatusNotFound || response["code"] != string(dto.CodeNotFound) {
+		t.Errorf("Expected 404 %s for an unknown document, got %d %v", dto.CodeNotFound, code, response)
+	}
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the instrumentation behind the engine's spans: requests, ingestion, rendering and OCR
+const tracerName = "github.com/drummonds/godocs/engine"
+
+// tracingShutdownTimeout is how long the spans still buffered at exit have to reach the collector
+const tracingShutdownTimeout = 5 * time.Second
+
+// SetupTracing sends the spans of requests, ingestion steps and database queries to the OpenTelemetry
+// collector at OTLP_ENDPOINT, keeping TRACE_SAMPLE_RATIO of the traces. Without an endpoint nothing is
+// traced. The function it returns flushes the spans not yet sent; call it on the way out.
+func SetupTracing(serverConfig config.ServerConfig) func() {
+	if serverConfig.OTLPEndpoint == "" {
+		return func() {}
+	}
+	exporter, err := newOTLPExporter(serverConfig.OTLPEndpoint)
+	if err != nil {
+		Logger.Error("Not tracing, OTLP_ENDPOINT is invalid", "error", err)
+		return func() {}
+	}
+	serviceName := serverConfig.TraceServiceName
+	if serviceName == "" {
+		serviceName = "godocs"
+	}
+	sampler := sdktrace.AlwaysSample()
+	if ratio := serverConfig.TraceSampleRatio; ratio > 0 && ratio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(ratio)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)), // a request traced upstream is traced here too
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		Logger.Warn("Unable to send traces", "endpoint", exporter.endpoint, "error", err)
+	}))
+	Logger.Info("Tracing to OpenTelemetry collector", "endpoint", exporter.endpoint, "service", serviceName, "sampleRatio", serverConfig.TraceSampleRatio)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			Logger.Warn("Unable to flush traces", "error", err)
+		}
+	}
+}
+
+// startSpan starts a span as a child of any in ctx. Until SetupTracing installs a provider, spans record nothing.
+func startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// endSpan ends span, marking it failed when err is not nil
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceRequests gives every request a span named after its route, continuing a trace begun by the caller
+// when the request carries a traceparent header. Spans started while handling the request are its children.
+func (serverHandler *ServerHandler) TraceRequests() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if serverHandler.ServerConfig.OTLPEndpoint == "" {
+			return next
+		}
+		return func(c echo.Context) error {
+			request := c.Request()
+			route := c.Path() // the route pattern, such as /api/documents/:id, so every document shares a name
+			name := request.Method
+			if route != "" {
+				name += " " + route
+			}
+			ctx := propagation.TraceContext{}.Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+			ctx, span := otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+				attribute.String("http.request.method", request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", request.URL.Path),
+			))
+			defer span.End()
+			c.SetRequest(request.WithContext(ctx))
+
+			err := next(c)
+			status := c.Response().Status
+			if err != nil {
+				// The error handler has not written the response yet, so its status comes from the error
+				status = http.StatusInternalServerError
+				var httpError *echo.HTTPError
+				if errors.As(err, &httpError) {
+					status = httpError.Code
+				}
+				span.RecordError(err)
+			}
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordSpans installs a tracer provider that keeps every span, until the test ends
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	return recorder
+}
+
+// spanNamed returns the first ended span called name
+func spanNamed(recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestOTLPExporter(t *testing.T) {
+	// A collector, and an exporter given only its address
+	var body []byte
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpTracesPath || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected JSON posted to %s, got %s %s", otlpTracesPath, r.Header.Get("Content-Type"), r.URL.Path)
+		}
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer collector.Close()
+	exporter, err := newOTLPExporter(collector.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newOTLPExporter("collector:4318"); err == nil {
+		t.Error("Expected an address without a scheme to be refused")
+	}
+
+	// A request span with a failed child is exported
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "godocs-test"))))
+	ctx, parent := provider.Tracer(tracerName).Start(context.Background(), "GET /api/documents/:id")
+	_, child := provider.Tracer(tracerName).Start(ctx, "ocr page")
+	child.SetAttributes(attribute.Int("godocs.page", 3), attribute.StringSlice("languages", []string{"eng", "deu"}))
+	endSpan(child, errors.New("tesseract crashed"))
+	parent.End()
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The collector gets both in OTLP JSON, under the service, the child pointing at its parent
+	var traces otlpTraces
+	if err := json.Unmarshal(body, &traces); err != nil {
+		t.Fatalf("Expected OTLP JSON, got %s: %v", body, err)
+	}
+	if len(traces.ResourceSpans) != 1 || len(traces.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Expected one resource and scope, got %s", body)
+	}
+	resourceSpans := traces.ResourceSpans[0]
+	if attributes := resourceSpans.Resource.Attributes; len(attributes) != 1 || attributes[0].Value["stringValue"] != "godocs-test" {
+		t.Errorf("Expected the service name on the resource, got %+v", attributes)
+	}
+	spans := resourceSpans.ScopeSpans[0].Spans
+	if resourceSpans.ScopeSpans[0].Scope.Name != tracerName || len(spans) != 2 {
+		t.Fatalf("Expected two spans from the engine, got %s", body)
+	}
+	ocr, request := spans[0], spans[1] // exported as they end
+	if len(ocr.TraceID) != 32 || ocr.TraceID != request.TraceID || ocr.ParentSpanID != request.SpanID || request.ParentSpanID != "" {
+		t.Errorf("Expected the OCR span a child of the request in one trace, got %+v and %+v", ocr, request)
+	}
+	if ocr.Status.Code != otlpStatusError || ocr.Status.Message != "tesseract crashed" || len(ocr.Events) != 1 {
+		t.Errorf("Expected the OCR span failed with the error recorded, got %+v", ocr)
+	}
+	values := make(map[string]map[string]any)
+	for _, kv := range ocr.Attributes {
+		values[kv.Key] = kv.Value
+	}
+	if values["godocs.page"]["intValue"] != "3" || values["languages"]["arrayValue"] == nil {
+		t.Errorf("Expected the page as a string integer and the languages as an array, got %v", values)
+	}
+	if ocr.StartTimeUnixNano == "" || ocr.StartTimeUnixNano > ocr.EndTimeUnixNano {
+		t.Errorf("Expected start and end times, got %s and %s", ocr.StartTimeUnixNano, ocr.EndTimeUnixNano)
+	}
+}
+
+func TestTraceRequests(t *testing.T) {
+	// Tracing turned on, and a route that works and one that fails
+	recorder := recordSpans(t)
+	handler := setupTestHandler(t)
+	handler.ServerConfig.OTLPEndpoint = "http://localhost:4318"
+	handler.Echo.Use(handler.TraceRequests())
+	handler.Echo.GET("/api/documents/:id", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	handler.Echo.GET("/api/broken", func(c echo.Context) error { return errors.New("database is down") })
+
+	// Both are requested, the first by a caller that has started a trace
+	req := httptest.NewRequest(http.MethodGet, "/api/documents/01HQ", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.Echo.ServeHTTP(httptest.NewRecorder(), req)
+	handler.Echo.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/broken", nil))
+
+	// Each has a span named after its route, the first in the caller's trace
+	document := spanNamed(recorder, "GET /api/documents/:id")
+	if document == nil {
+		t.Fatalf("Expected a span for the document route, got %d spans", len(recorder.Ended()))
+	}
+	if document.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the caller's trace continued, got %s", document.SpanContext().TraceID())
+	}
+	broken := spanNamed(recorder, "GET /api/broken")
+	if broken == nil || broken.Status().Code != codes.Error {
+		t.Fatalf("Expected a failed span for the broken route, got %+v", broken)
+	}
+	for _, kv := range broken.Attributes() {
+		if kv.Key == "http.response.status_code" && kv.Value.AsInt64() != http.StatusInternalServerError {
+			t.Errorf("Expected status 500 on the span, got %d", kv.Value.AsInt64())
+		}
+	}
+}
+
+func TestIngestionSpans(t *testing.T) {
+	// Tracing turned on and a PDF with a text layer
+	recorder := recordSpans(t)
+	handler := setupTestHandler(t)
+	path := filepath.Join(handler.ServerConfig.DocumentPath, "invoice.pdf")
+	writeInvoicePDF(t, path)
+
+	// Its text is read and the document saved
+	timeline := startTimeline("upload")
+	if _, _, err := handler.extractText(path, timeline); err != nil {
+		t.Fatal(err)
+	}
+	timeline.save(handler.DB, database.MakeULID())
+
+	// Reading the text layer is a step within the document's span, which is not OCRed
+	ingest, textLayer := spanNamed(recorder, "ingest document"), spanNamed(recorder, "read text layer")
+	if ingest == nil || textLayer == nil {
+		t.Fatalf("Expected the document and text layer spans, got %d spans", len(recorder.Ended()))
+	}
+	if textLayer.Parent().SpanID() != ingest.SpanContext().SpanID() {
+		t.Error("Expected reading the text layer a child of the document's span")
+	}
+	if spanNamed(recorder, "ocr pdf") != nil {
+		t.Error("Expected no OCR for a PDF with a text layer")
+	}
+}
@@ -0,0 +1,256 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// transformConfigured reports whether files pass through PRE_INGEST_COMMAND or PRE_INGEST_URL before
+// ingestion. When they do, every file in ingress is offered to the transform, not just supported
+// documents, so proprietary formats can be converted.
+func (serverHandler *ServerHandler) transformConfigured() bool {
+	return serverHandler.ServerConfig.PreIngestCommand != "" || serverHandler.ServerConfig.PreIngestURL != ""
+}
+
+// preIngest runs the pre-ingestion transforms on filePath and returns the file to ingest in its place.
+// The command runs first, then the service, each on the previous one's output; either may leave the
+// file unchanged. The original is only replaced once every transform has succeeded, by a file in the
+// same folder named as the transform chose. If a transform fails or times out, or leaves a file that
+// is not a supported document, the original is moved to the quarantine folder with a note of why and
+// errTransformFailed is returned.
+func (serverHandler *ServerHandler) preIngest(filePath string) (string, error) {
+	if !serverHandler.transformConfigured() {
+		return filePath, nil
+	}
+	workDir, cleanup, err := serverHandler.newWorkDir("transform-*")
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	transformed, err := serverHandler.transformFile(filePath, workDir)
+	if err == nil {
+		err = serverHandler.checkProcessable(transformed)
+	}
+	if err == nil && transformed != filePath {
+		transformed, err = replaceTransformed(filePath, transformed)
+	}
+	if err != nil {
+		Logger.Error("Pre-ingestion transform failed, quarantining file", "filePath", filePath, "error", err)
+		if quarantined, qErr := serverHandler.quarantine(filePath, err); qErr != nil {
+			Logger.Error("Unable to quarantine file", "filePath", filePath, "error", qErr)
+		} else {
+			Logger.Info("Quarantined file", "filePath", filePath, "quarantined", quarantined)
+		}
+		return "", fmt.Errorf("%w: %w", errTransformFailed, err)
+	}
+	if transformed != filePath {
+		Logger.Info("Pre-ingestion transform replaced file", "filePath", filePath, "replacement", transformed)
+	}
+	return transformed, nil
+}
+
+// transformFile runs the configured transforms, each writing into its own folder under workDir, and
+// returns the final file, which is filePath itself when nothing changed it
+func (serverHandler *ServerHandler) transformFile(filePath, workDir string) (string, error) {
+	cfg := serverHandler.ServerConfig
+	timeout := time.Duration(cfg.PreIngestTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 120 * time.Second
+	}
+	type transform struct {
+		name string
+		run  func(ctx context.Context, input, outputDir string) (string, error)
+	}
+	var transforms []transform
+	if cfg.PreIngestCommand != "" {
+		transforms = append(transforms, transform{"command", func(ctx context.Context, input, outputDir string) (string, error) {
+			return runTransformCommand(ctx, cfg.PreIngestCommand, input, outputDir)
+		}})
+	}
+	if cfg.PreIngestURL != "" {
+		transforms = append(transforms, transform{"service", func(ctx context.Context, input, outputDir string) (string, error) {
+			return postTransformService(ctx, cfg.PreIngestURL, input, outputDir)
+		}})
+	}
+
+	current := filePath
+	for _, t := range transforms {
+		outputDir := filepath.Join(workDir, t.name)
+		if err := os.Mkdir(outputDir, 0755); err != nil {
+			return "", err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		output, err := t.run(ctx, current, outputDir)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", timeout) // rather than the signal that killed it
+		}
+		cancel()
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", t.name, err)
+		}
+		if output != "" {
+			current = output
+		}
+	}
+	return current, nil
+}
+
+// runTransformCommand runs the command line, split on spaces and without a shell, with the input file
+// and an empty output folder as its last two arguments, also given as GODOCS_INPUT_PATH and
+// GODOCS_OUTPUT_DIR. A command that writes nothing leaves the file unchanged; one that writes a single
+// file replaces it. It returns that file, or "" when there is none.
+func runTransformCommand(ctx context.Context, commandLine, input, outputDir string) (string, error) {
+	args := append(strings.Fields(commandLine), input, outputDir)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), "GODOCS_INPUT_PATH="+input, "GODOCS_OUTPUT_DIR="+outputDir)
+	cmd.WaitDelay = time.Second // don't wait on pipes held open by a killed command's children
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%w%s", err, hookOutputDetail(truncateHookOutput(string(output))))
+	}
+	if len(output) > 0 {
+		Logger.Debug("Pre-ingestion command output", "input", input, "output", truncateHookOutput(string(output)))
+	}
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case len(entries) == 0:
+		return "", nil
+	case len(entries) > 1 || !entries[0].Type().IsRegular():
+		return "", fmt.Errorf("wrote %d entries to its output folder, expected one file", len(entries))
+	}
+	return filepath.Join(outputDir, entries[0].Name()), nil
+}
+
+// postTransformService POSTs the file to url, named in the X-Godocs-Filename header. A 204 answer leaves
+// the file unchanged; a 200 answer's body replaces it, named by its Content-Disposition filename when
+// it gives one. It returns the replacement written to outputDir, or "" when there is none.
+func postTransformService(ctx context.Context, url, input, outputDir string) (string, error) {
+	file, err := os.Open(input)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, file)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", database.DetectMIMEType(input))
+	request.Header.Set("X-Godocs-Filename", filepath.Base(input))
+	request.Header.Set("User-Agent", "godocs")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusNoContent:
+		return "", nil
+	case http.StatusOK:
+	default:
+		answer, _ := io.ReadAll(io.LimitReader(response.Body, maxHookOutput+1))
+		return "", fmt.Errorf("answered %s%s", response.Status, hookOutputDetail(truncateHookOutput(string(answer))))
+	}
+
+	name := filepath.Base(input)
+	if _, params, err := mime.ParseMediaType(response.Header.Get("Content-Disposition")); err == nil {
+		if chosen := filepath.Base(filepath.Clean("/" + params["filename"])); chosen != "/" && chosen != "." {
+			name = chosen
+		}
+	}
+	output := filepath.Join(outputDir, name)
+	if _, err := writeFileHashed(output, response.Body, 0644); err != nil {
+		return "", err
+	}
+	return output, nil
+}
+
+// replaceTransformed puts a transform's output in place of the original, in the original's folder under
+// the output's name, and returns where it now is. A different file already using that name is left
+// alone and the replacement fails.
+func replaceTransformed(original, produced string) (string, error) {
+	destination := filepath.Join(filepath.Dir(original), filepath.Base(produced))
+	if destination != original {
+		if _, err := os.Stat(destination); err == nil {
+			return "", fmt.Errorf("%s already exists beside %s", filepath.Base(destination), filepath.Base(original))
+		}
+	}
+	// Copied rather than renamed, since the work directory may be on another filesystem
+	if err := copyFile(produced, destination+".part"); err != nil {
+		os.Remove(destination + ".part")
+		return "", err
+	}
+	if err := os.Rename(destination+".part", destination); err != nil {
+		os.Remove(destination + ".part")
+		return "", err
+	}
+	if destination != original {
+		if err := os.Remove(original); err != nil {
+			Logger.Warn("Unable to remove original after transform", "filePath", original, "error", err)
+		}
+	}
+	return destination, nil
+}
+
+// quarantine moves a file a transform failed on into the quarantine folder, keeping its path under
+// ingress, with a .error.txt note beside it saying why. A name already in quarantine gets a numbered
+// suffix rather than being overwritten.
+func (serverHandler *ServerHandler) quarantine(filePath string, reason error) (string, error) {
+	relative, err := filepath.Rel(serverHandler.ServerConfig.IngressPath, filePath)
+	if err != nil || !filepath.IsLocal(relative) {
+		relative = filepath.Base(filePath)
+	}
+	destination := filepath.Join(serverHandler.ServerConfig.QuarantinePath, relative)
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return "", err
+	}
+	ext := filepath.Ext(destination)
+	base := destination[:len(destination)-len(ext)]
+	for n := 1; ; n++ {
+		if _, err := os.Stat(destination); os.IsNotExist(err) {
+			break
+		}
+		destination = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+	if err := os.Rename(filePath, destination); err != nil {
+		// The ingress folder may be on another filesystem
+		if err := copyFile(filePath, destination); err != nil {
+			return "", err
+		}
+		if err := os.Remove(filePath); err != nil {
+			Logger.Warn("Unable to remove quarantined file from ingress", "filePath", filePath, "error", err)
+		}
+	}
+	var note bytes.Buffer
+	fmt.Fprintf(&note, "%s\n%s\n%s\n", filePath, database.Now().Format(time.RFC3339), reason)
+	if err := os.WriteFile(destination+".error.txt", note.Bytes(), 0644); err != nil {
+		Logger.Warn("Unable to write quarantine note", "filePath", destination, "error", err)
+	}
+	return destination, nil
+}
+
+// transformRefused answers 422 for an upload a pre-ingestion transform failed on; the file is in quarantine
+func transformRefused(c echo.Context, err error) error {
+	if !errors.Is(err, errTransformFailed) {
+		Logger.Error("Unable to transform upload", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Unable to transform upload", "code": dto.CodeInternal})
+	}
+	return c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{"error": err.Error(), "code": dto.CodeTransformFailed})
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupTransformTestHandler returns a handler with an ingress folder holding name, and a quarantine folder
+func setupTransformTestHandler(t *testing.T, name, content string) (*ServerHandler, string) {
+	t.Helper()
+	handler := setupTestHandler(t)
+	handler.ServerConfig.TempPath = t.TempDir()
+	handler.ServerConfig.QuarantinePath = t.TempDir()
+	handler.ServerConfig.PreIngestTimeout = 5
+	filePath := filepath.Join(handler.ServerConfig.IngressPath, "bank", name)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	return handler, filePath
+}
+
+func TestPreIngestCommandReplacesFile(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	// A locked statement and a command that "decrypts" it into a PDF
+	handler, filePath := setupTransformTestHandler(t, "statement.pdf.locked", "ciphertext")
+	script := filepath.Join(t.TempDir(), "unlock.sh")
+	os.WriteFile(script, []byte("#!/bin/sh\nprintf decrypted > \"$2/$(basename \"$1\" .locked)\"\n"), 0755)
+	handler.ServerConfig.PreIngestCommand = script
+
+	// The locked file is accepted for ingestion although it is not a supported type
+	if err := handler.checkIngestible(filePath); err != nil {
+		t.Fatalf("Expected the locked file to be accepted for transforming, got %v", err)
+	}
+
+	// The file is transformed before ingestion
+	transformed, err := handler.preIngest(filePath)
+
+	// The decrypted PDF replaces it in the same folder
+	if err != nil {
+		t.Fatalf("preIngest failed: %v", err)
+	}
+	if transformed != filepath.Join(filepath.Dir(filePath), "statement.pdf") {
+		t.Errorf("Expected statement.pdf beside the original, got %s", transformed)
+	}
+	if content, _ := os.ReadFile(transformed); string(content) != "decrypted" {
+		t.Errorf("Expected the command's output, got %q", content)
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("Expected the original to be replaced, got %v", err)
+	}
+}
+
+func TestPreIngestFailureQuarantines(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	for name, test := range map[string]struct{ command, reason string }{
+		"fails":       {"#!/bin/sh\necho wrong password >&2\nexit 3\n", "wrong password"},
+		"times out":   {"#!/bin/sh\nexec sleep 10\n", "timed out"},
+		"unsupported": {"#!/bin/sh\nprintf x > \"$2/statement.xyz\"\n", "unsupported file type: .xyz"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// A command that cannot produce a document
+			handler, filePath := setupTransformTestHandler(t, "statement.pdf", "original")
+			handler.ServerConfig.PreIngestTimeout = 1
+			script := filepath.Join(t.TempDir(), "transform.sh")
+			os.WriteFile(script, []byte(test.command), 0755)
+			handler.ServerConfig.PreIngestCommand = script
+
+			// The file is transformed before ingestion
+			_, err := handler.preIngest(filePath)
+
+			// It fails with the transform error and the original is quarantined with a note
+			if !errors.Is(err, errTransformFailed) || !strings.Contains(err.Error(), test.reason) {
+				t.Fatalf("Expected errTransformFailed saying %q, got %v", test.reason, err)
+			}
+			if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+				t.Errorf("Expected the file to leave ingress, got %v", err)
+			}
+			quarantined := filepath.Join(handler.ServerConfig.QuarantinePath, "bank", "statement.pdf")
+			if content, _ := os.ReadFile(quarantined); string(content) != "original" {
+				t.Errorf("Expected the original in quarantine, got %q", content)
+			}
+			if note, _ := os.ReadFile(quarantined + ".error.txt"); !strings.Contains(string(note), test.reason) {
+				t.Errorf("Expected the note to say why, got %q", note)
+			}
+		})
+	}
+}
+
+func TestPreIngestService(t *testing.T) {
+	// A conversion service that converts .pages files, leaves PDFs alone and refuses the rest
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.Header.Get("X-Godocs-Filename")
+		switch filepath.Ext(name) {
+		case ".pages":
+			w.Header().Set("Content-Disposition", `attachment; filename="../letter.pdf"`)
+			w.Write([]byte("converted"))
+		case ".pdf":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "cannot convert "+name, http.StatusUnprocessableEntity)
+		}
+	}))
+	defer service.Close()
+
+	for _, test := range []struct {
+		name, wantName, wantContent string
+		wantErr                     bool
+	}{
+		{"letter.pages", "letter.pdf", "converted", false},
+		{"letter.pdf", "letter.pdf", "original", false},
+		{"letter.key", "", "", true},
+	} {
+		// A file is sent to the service before ingestion
+		handler, filePath := setupTransformTestHandler(t, test.name, "original")
+		handler.ServerConfig.PreIngestURL = service.URL
+		transformed, err := handler.preIngest(filePath)
+
+		// It is converted, kept or quarantined as the service answered
+		if test.wantErr {
+			if !errors.Is(err, errTransformFailed) || !strings.Contains(err.Error(), "cannot convert letter.key") {
+				t.Errorf("%s: expected the refusal, got %v", test.name, err)
+			}
+			if _, statErr := os.Stat(filepath.Join(handler.ServerConfig.QuarantinePath, "bank", test.name)); statErr != nil {
+				t.Errorf("%s: expected it in quarantine, got %v", test.name, statErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: preIngest failed: %v", test.name, err)
+		}
+		if filepath.Base(transformed) != test.wantName || filepath.Dir(transformed) != filepath.Dir(filePath) {
+			t.Errorf("%s: expected %s in the same folder, got %s", test.name, test.wantName, transformed)
+		}
+		if content, _ := os.ReadFile(transformed); string(content) != test.wantContent {
+			t.Errorf("%s: expected %q, got %q", test.name, test.wantContent, content)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drummonds/godocs/cache"
+	"github.com/drummonds/godocs/internal/build"
+)
+
+// updateStatus is the result of the last release check, reported by /api/about when UPDATE_CHECK is on
+type updateStatus struct {
+	Available bool      `json:"available"`
+	Latest    string    `json:"latest,omitempty"` // tag of the newest release
+	URL       string    `json:"url,omitempty"`    // its release page
+	CheckedAt time.Time `json:"checkedAt,omitempty"`
+	Error     string    `json:"error,omitempty"` // why the last check failed; the previous result is kept
+}
+
+// updateChecker holds the last release check
+type updateChecker struct {
+	mu     sync.Mutex
+	status updateStatus
+}
+
+// current returns the last release check
+func (u *updateChecker) current() updateStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.status
+}
+
+// checkForUpdate asks GitHub for the latest release and records whether it is newer than this build.
+// It runs at startup and then daily when UPDATE_CHECK is on.
+func (serverHandler *ServerHandler) checkForUpdate() {
+	latest, url, err := fetchLatestRelease(serverHandler.ServerConfig.UpdateCheckURL)
+	u := &serverHandler.updates
+	u.mu.Lock()
+	previous := u.status
+	u.status.CheckedAt = time.Now()
+	if err != nil {
+		u.status.Error = err.Error()
+	} else {
+		u.status = updateStatus{
+			Available: newerVersion(latest, build.Version),
+			Latest:    latest,
+			URL:       url,
+			CheckedAt: u.status.CheckedAt,
+		}
+	}
+	status := u.status
+	u.mu.Unlock()
+
+	if err != nil {
+		Logger.Warn("Unable to check for a newer release", "error", err)
+	} else if status.Available && !previous.Available {
+		Logger.Info("A newer godocs release is available", "current", build.Version, "latest", latest, "url", url)
+	}
+	if serverHandler.Cache != nil {
+		serverHandler.Cache.DeletePrefix(context.Background(), cache.KeyAbout)
+	}
+}
+
+// fetchLatestRelease returns the tag and page of the latest release from the GitHub releases API
+func fetchLatestRelease(url string) (string, string, error) {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("User-Agent", "godocs/"+build.Version)
+	response, err := serviceClient.Do(request)
+	if err != nil {
+		return "", "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("release check returned %s", response.Status)
+	}
+	var release struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&release); err != nil {
+		return "", "", fmt.Errorf("unable to read release: %w", err)
+	}
+	if release.TagName == "" {
+		return "", "", fmt.Errorf("release has no tag")
+	}
+	return release.TagName, release.HTMLURL, nil
+}
+
+// newerVersion reports whether release tag latest is a higher version than current. current may be a
+// git describe version such as v1.2.3-4-gabc1234, which counts as v1.2.3; a development build, or any
+// version that does not start with a number, is never out of date.
+func newerVersion(latest, current string) bool {
+	l, ok := versionNumbers(latest)
+	if !ok {
+		return false
+	}
+	c, ok := versionNumbers(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// versionNumbers reads the major, minor and patch numbers from the start of a version such as
+// v1.2.3, 1.2 or v1.2.3-rc1
+func versionNumbers(version string) ([3]int, bool) {
+	var numbers [3]int
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if end := strings.IndexAny(version, "-+"); end >= 0 {
+		version = version[:end]
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) > 3 {
+		return numbers, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return numbers, false
+		}
+		numbers[i] = n
+	}
+	return numbers, true
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drummonds/godocs/internal/build"
+)
+
+func TestUpdateCheckReportedInAbout(t *testing.T) {
+	// A release API whose latest release is v1.3.0
+	releases := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name":"v1.3.0","html_url":"https://example.com/releases/v1.3.0"}`))
+	}))
+	defer releases.Close()
+	handler := setupTestHandler(t)
+	handler.ServerConfig.UpdateCheck = true
+	handler.ServerConfig.UpdateCheckURL = releases.URL
+
+	for _, test := range []struct {
+		version   string
+		available bool
+	}{
+		{"v1.2.9", true},
+		{"v1.2.9-4-gabc1234", true}, // git describe, after v1.2.9
+		{"v1.3.0", false},
+		{"v1.10.0", false},
+		{"development", false},
+	} {
+		// A build of that version checks and the about information is fetched
+		previous := build.Version
+		build.Version = test.version
+		handler.checkForUpdate()
+		rec := httptest.NewRecorder()
+		err := handler.GetAboutInfo(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/about", nil), rec))
+		build.Version = previous
+		if err != nil {
+			t.Fatalf("GetAboutInfo failed: %v", err)
+		}
+
+		// The update flag says whether the release is newer, beside the build metadata
+		var about struct {
+			Build  build.Info    `json:"build"`
+			Update *updateStatus `json:"update"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &about); err != nil {
+			t.Fatalf("Unable to read about: %v", err)
+		}
+		if about.Build.GoVersion == "" || about.Build.Version != test.version {
+			t.Errorf("%s: expected the build metadata, got %+v", test.version, about.Build)
+		}
+		if about.Update == nil || about.Update.Available != test.available || about.Update.Latest != "v1.3.0" {
+			t.Errorf("%s: expected available=%v for v1.3.0, got %+v", test.version, test.available, about.Update)
+		}
+	}
+}
+
+func TestUpdateCheckOff(t *testing.T) {
+	// The release check is not turned on
+	handler := setupTestHandler(t)
+
+	// The about information is fetched
+	rec := httptest.NewRecorder()
+	if err := handler.GetAboutInfo(handler.Echo.NewContext(httptest.NewRequest(http.MethodGet, "/api/about", nil), rec)); err != nil {
+		t.Fatalf("GetAboutInfo failed: %v", err)
+	}
+
+	// No update status is reported
+	var about map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &about)
+	if _, ok := about["update"]; ok {
+		t.Errorf("Expected no update status, got %v", about["update"])
+	}
+	if _, ok := about["build"]; !ok {
+		t.Errorf("Expected the build metadata")
+	}
+}
@@ -0,0 +1,197 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	errInvalidUploadFolder = errors.New("upload folder must be a relative path inside the document root")
+	errUploadExists        = errors.New("a file with that name already exists in the folder")
+	errUploadDuplicate     = errors.New("an identical document already exists")
+	errInvalidIngressPath  = errors.New("upload path must be a relative path inside the ingress folder")
+)
+
+// ingressUploadPath resolves where an upload lands in the ingress folder. subfolder is optional, may use
+// "/" or "\" whatever the server OS, and must not escape the ingress folder.
+func ingressUploadPath(ingressPath string, subfolder string, fileName string) (string, error) {
+	subfolder = strings.TrimLeft(strings.ReplaceAll(subfolder, `\`, "/"), "/")
+	destination := filepath.Join(ingressPath, normalisePath(filepath.FromSlash(subfolder)), fileName)
+	if fileName == "" || !insideRoot(folderKey(ingressPath), folderKey(filepath.Dir(destination))) {
+		return "", errInvalidIngressPath
+	}
+	return destination, nil
+}
+
+// ingressDestination is where a file in the ingress folder will be stored once it is ingested
+func (serverHandler *ServerHandler) ingressDestination(ingressFile string) string {
+	if !serverHandler.ServerConfig.IngressPreserve {
+		return filepath.Join(serverHandler.ServerConfig.NewDocumentFolder, normaliseName(filepath.Base(ingressFile)))
+	}
+	relative, err := filepath.Rel(serverHandler.ServerConfig.IngressPath, ingressFile)
+	if err != nil {
+		relative = filepath.Base(ingressFile)
+	}
+	return filepath.Join(serverHandler.ServerConfig.DocumentPath, normalisePath(relative))
+}
+
+// uploadFolderPath resolves a folder given relative to the document root, rejecting anything that escapes it
+func uploadFolderPath(documentPath string, folder string) (string, error) {
+	folder = strings.TrimLeft(filepath.ToSlash(folder), "/")
+	if folder == "" {
+		return "", errInvalidUploadFolder
+	}
+	destination := filepath.Join(documentPath, filepath.FromSlash(folder))
+	if !insideRoot(folderKey(documentPath), folderKey(destination)) {
+		return "", errInvalidUploadFolder
+	}
+	return destination, nil
+}
+
+// ingestIntoFolder stores an uploaded file directly in destFolder under the document root.
+// It runs the same steps as ingestion (hash, duplicate check, move and verify, text extraction)
+// but skips the ingress folder, so the user's chosen folder is kept.
+func (serverHandler *ServerHandler) ingestIntoFolder(sourcePath string, fileHash database.FileHashes, destFolder string) (*database.Document, error) {
+	db := serverHandler.DB
+	fileName := filepath.Base(sourcePath)
+	timeline := startTimeline("upload")
+	if serverHandler.prepareScan(sourcePath, timeline) {
+		var err error
+		if fileHash, err = calculateFileHash(sourcePath); err != nil {
+			return nil, fmt.Errorf("cannot hash prepared upload: %w", err)
+		}
+	}
+
+	if duplicate, existing := serverHandler.checkDuplicate(fileHash, fileName, db); duplicate {
+		return existing, errUploadDuplicate
+	}
+
+	destPath := filepath.Join(destFolder, fileName)
+	if _, err := os.Stat(destPath); err == nil {
+		return nil, errUploadExists
+	}
+
+	newTime := database.Now()
+	newULID, err := database.CalculateUUID(newTime)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate ULID: %w", err)
+	}
+	doc := &database.Document{
+		Name:         fileName,
+		Path:         filepath.ToSlash(destPath),
+		IngressTime:  newTime,
+		Folder:       filepath.ToSlash(destFolder),
+		Hash:         fileHash.Current(),
+		ULID:         newULID,
+		DocumentType: filepath.Ext(fileName),
+		MIMEType:     database.DetectMIMEType(sourcePath),
+		URL:          documentViewURL(newULID),
+	}
+	doc.Size, doc.PageCount = fileDetails(sourcePath)
+
+	if err := serverHandler.moveAndVerifyFile(sourcePath, destPath, doc.Hash); err != nil {
+		return nil, fmt.Errorf("move/verify failed: %w", err)
+	}
+	timeline.mark(stageStored, "")
+
+	fullText, ocrStatus, err := serverHandler.extractText(destPath, timeline)
+	if err != nil {
+		Logger.Warn("Text extraction failed, storing document without text", "error", err, "fileName", fileName)
+		fullText = ""
+	}
+	doc.FullText = fullText
+	doc.OCRStatus = ocrStatus
+
+	if err := db.SaveDocument(doc); err != nil {
+		// Nothing references the file yet, so take it back out rather than leave an orphan
+		if removeErr := os.Remove(destPath); removeErr != nil {
+			Logger.Error("Unable to remove upload after failed save", "path", destPath, "error", removeErr)
+		}
+		return nil, fmt.Errorf("unable to save document: %w", err)
+	}
+	timeline.mark(stageIndexed, "")
+	timeline.save(db, doc.ULID)
+	serverHandler.recordFolder(destFolder)
+	serverHandler.wordCounts.add(doc)
+	serverHandler.wordCounts.flushSoon(db)
+	serverHandler.invalidateDocumentCache()
+
+	Logger.Info("Uploaded document stored in folder", "path", doc.Path, "ulid", doc.ULID.String())
+	serverHandler.documentIngested(doc, "upload", nil)
+	return doc, nil
+}
+
+// uploadToFolder serves UploadDocuments when a destination folder is given
+func (serverHandler *ServerHandler) uploadToFolder(context echo.Context, folder string, fileName string, file io.Reader) error {
+	destFolder, err := uploadFolderPath(serverHandler.ServerConfig.DocumentPath, folder)
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error(), "code": dto.CodeBadRequest})
+	}
+	access, err := serverHandler.folderAccess(context)
+	if err != nil {
+		return err
+	}
+	if !access.canWrite(destFolder) {
+		return folderForbidden(context, destFolder)
+	}
+	fileName = filepath.Base(filepath.Clean("/" + fileName))
+	if fileName == "/" || fileName == "." {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Missing file name", "code": dto.CodeBadRequest})
+	}
+
+	// Stage the upload in a private work directory so a failed upload never reaches document storage
+	workDir, cleanup, err := serverHandler.newWorkDir("upload-*")
+	if err != nil {
+		Logger.Error("Unable to create upload work directory", "error", err)
+		return context.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Unable to stage upload", "code": dto.CodeInternal})
+	}
+	defer cleanup()
+	stagedPath := filepath.Join(workDir, fileName)
+	fileHash, err := writeFileHashed(stagedPath, file, 0644)
+	if err != nil {
+		Logger.Error("Unable to write uploaded file", "path", stagedPath, "error", err)
+		return context.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Unable to stage upload", "code": dto.CodeInternal})
+	}
+
+	if transformed, err := serverHandler.preIngest(stagedPath); err != nil {
+		return transformRefused(context, err)
+	} else if transformed != stagedPath {
+		stagedPath = transformed
+		if fileHash, err = calculateFileHash(stagedPath); err != nil {
+			Logger.Error("Unable to hash transformed upload", "path", stagedPath, "error", err)
+			return context.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Unable to stage upload", "code": dto.CodeInternal})
+		}
+	}
+
+	release, err := serverHandler.processingSlot(context.Request().Context(), priorityInteractive)
+	if err != nil {
+		return err
+	}
+	doc, err := serverHandler.ingestIntoFolder(stagedPath, fileHash, destFolder)
+	release()
+	switch {
+	case errors.Is(err, errUploadDuplicate):
+		return context.JSON(http.StatusConflict, map[string]interface{}{
+			"error": err.Error(),
+			"code":  dto.CodeDuplicate,
+			"ulid":  doc.ULID.String(),
+		})
+	case errors.Is(err, errUploadExists):
+		return context.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error(), "code": dto.CodeNameConflict})
+	case errors.Is(err, errQuotaExceeded):
+		return context.JSON(http.StatusInsufficientStorage, map[string]interface{}{"error": err.Error(), "code": dto.CodeQuotaExceeded})
+	case err != nil:
+		Logger.Error("Unable to store upload in folder", "folder", destFolder, "error", err)
+		return context.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Unable to store upload", "code": dto.CodeInternal})
+	}
+	return context.JSON(http.StatusOK, doc.Path)
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/drummonds/godocs/storage"
+)
+
+// uploadRequest builds a multipart upload of content with the given form fields
+func uploadRequest(t *testing.T, fileName string, content string, fields map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatalf("Failed to write field: %v", err)
+		}
+	}
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	part.Write([]byte(content))
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/document/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestUploadToFolderBypassesIngress(t *testing.T) {
+	// A fresh document store
+	handler := setupTestHandler(t)
+	upload := func(fileName, content, folder string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := uploadRequest(t, fileName, content, map[string]string{"folder": folder})
+		if err := handler.UploadDocuments(handler.Echo.NewContext(req, rec)); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		return rec
+	}
+
+	// A text file is uploaded with a destination folder
+	rec := upload("note.txt", "wombat receipt", "bills/2024")
+
+	// It is stored in that folder with its text, and nothing is left in ingress
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	wantPath := filepath.Join(handler.ServerConfig.DocumentPath, "bills", "2024", "note.txt")
+	doc, err := handler.DB.GetDocumentByPath(filepath.ToSlash(wantPath))
+	if err != nil {
+		t.Fatalf("Uploaded document not found: %v", err)
+	}
+	if doc.Folder != filepath.Dir(wantPath) || doc.FullText != "wombat receipt" {
+		t.Errorf("Unexpected document folder %q text %q", doc.Folder, doc.FullText)
+	}
+	if doc.MIMEType != "text/plain; charset=utf-8" {
+		t.Errorf("Expected the detected MIME type to be stored, got %q", doc.MIMEType)
+	}
+	if entries, _ := os.ReadDir(handler.ServerConfig.IngressPath); len(entries) != 0 {
+		t.Errorf("Expected ingress to stay empty, found %d entries", len(entries))
+	}
+	folders, err := handler.DB.GetAllFolders()
+	if err != nil || len(folders) != 3 {
+		t.Errorf("Expected root, bills and bills/2024 recorded, got %+v, %v", folders, err)
+	}
+
+	// Duplicates, name clashes and folders outside the root are refused
+	if rec := upload("copy.txt", "wombat receipt", "bills"); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), string(dto.CodeDuplicate)) {
+		t.Errorf("Expected 409 %s for a duplicate, got %d %s", dto.CodeDuplicate, rec.Code, rec.Body.String())
+	}
+	if rec := upload("note.txt", "different text", "bills/2024"); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), string(dto.CodeNameConflict)) {
+		t.Errorf("Expected 409 %s for a name clash, got %d %s", dto.CodeNameConflict, rec.Code, rec.Body.String())
+	}
+	if rec := upload("escape.txt", "escape", "../outside"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a folder outside the root, got %d", rec.Code)
+	}
+}
+
+func TestIngressUploadPath(t *testing.T) {
+	ingress := t.TempDir()
+	cases := []struct {
+		subfolder string
+		want      string
+	}{
+		{"", filepath.Join(ingress, "scan.pdf")},
+		{"bills/", filepath.Join(ingress, "bills", "scan.pdf")},
+		{`bills\2024`, filepath.Join(ingress, "bills", "2024", "scan.pdf")},
+		{"/bills", filepath.Join(ingress, "bills", "scan.pdf")},
+		{"../outside", ""},
+		{`bills\..\..\outside`, ""},
+	}
+	for _, tc := range cases {
+		got, err := ingressUploadPath(ingress, tc.subfolder, "scan.pdf")
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("ingressUploadPath(%q) = %q, expected it to be rejected", tc.subfolder, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("ingressUploadPath(%q) = %q, %v, want %q", tc.subfolder, got, err, tc.want)
+		}
+	}
+}
+
+func TestIngressCopyDocumentPaths(t *testing.T) {
+	// Ingress and document folders with a file in an ingress subfolder, given with "/" separators
+	root := t.TempDir()
+	serverConfig := config.ServerConfig{
+		IngressPath:     filepath.Join(root, "ingress"),
+		DocumentPath:    filepath.Join(root, "documents"),
+		IngressPreserve: true,
+	}
+	source := filepath.Join(serverConfig.IngressPath, "bills", "scan.pdf")
+	os.MkdirAll(filepath.Dir(source), 0755)
+	os.WriteFile(source, []byte("%PDF"), 0644)
+
+	// It is copied preserving the folder structure, and a file outside ingress is offered
+	copiedHash, err := ingressCopyDocument(filepath.ToSlash(source), serverConfig, storage.NewLocal(serverConfig.DocumentPath))
+	outside := filepath.Join(root, "elsewhere.pdf")
+	os.WriteFile(outside, []byte("%PDF"), 0644)
+	_, outsideErr := ingressCopyDocument(outside, serverConfig, storage.NewLocal(serverConfig.DocumentPath))
+
+	// The copy lands in the same subfolder of the documents folder and the outside file is refused
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(serverConfig.DocumentPath, "bills", "scan.pdf")); err != nil {
+		t.Errorf("Expected the copy in documents/bills: %v", err)
+	}
+	if sourceHash, _ := calculateFileHash(source); copiedHash != sourceHash {
+		t.Errorf("Expected the hash of the copied bytes %s, got %s", sourceHash, copiedHash)
+	}
+	if outsideErr == nil {
+		t.Error("Expected a file outside the ingress folder to be refused")
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/drummonds/godocs/cache"
 	"github.com/drummonds/godocs/database"
+	"github.com/drummonds/godocs/internal/dto"
 	"github.com/labstack/echo/v4"
 )
 
@@ -27,12 +29,18 @@ func (serverHandler *ServerHandler) GetWordCloud(c echo.Context) error {
 		}
 	}
 
+	cacheKey := cache.KeyWordCloud + strconv.Itoa(limit)
+	if body, ok := serverHandler.cachedResponse(c, cacheKey); ok {
+		return c.JSONBlob(http.StatusOK, body)
+	}
+
 	// Get top words from database
 	words, err := serverHandler.DB.GetTopWords(limit)
 	if err != nil {
 		Logger.Error("Failed to get word cloud data", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error": "Failed to retrieve word cloud data",
+			"code":  dto.CodeInternal,
 		})
 	}
 
@@ -53,7 +61,7 @@ func (serverHandler *ServerHandler) GetWordCloud(c echo.Context) error {
 		}
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
+	return serverHandler.cacheJSON(c, cacheKey, map[string]interface{}{
 		"words":    words,
 		"metadata": metadata,
 		"count":    len(words),
@@ -78,6 +86,7 @@ func (serverHandler *ServerHandler) RecalculateWordCloud(c echo.Context) error {
 		} else {
 			Logger.Info("Word cloud recalculation completed successfully")
 		}
+		serverHandler.invalidateDocumentCache()
 	}()
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
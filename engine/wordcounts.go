@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+)
+
+// wordFlushDelay is how long single-document ingestion waits before writing word counts,
+// so a burst of uploads reaches the database as one batched upsert
+const wordFlushDelay = 5 * time.Second
+
+// wordCounter aggregates word cloud counts in memory until they are flushed in one batched write.
+// The zero value is ready to use.
+type wordCounter struct {
+	mu        sync.Mutex
+	counts    map[string]int
+	documents []string // ULIDs of the counted documents, marked on their timelines once written
+	scheduled bool     // a background flush is waiting to run
+}
+
+// add counts the words of a document's text and name
+func (w *wordCounter) add(doc *database.Document) {
+	frequencies := database.NewWordTokenizer().TokenizeAndCount(doc.FullText + " " + doc.Name)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.counts == nil {
+		w.counts = make(map[string]int)
+	}
+	for word, count := range frequencies {
+		w.counts[word] += count
+	}
+	w.documents = append(w.documents, doc.ULID.String())
+}
+
+// flush writes the pending counts; on failure they are kept for the next flush
+func (w *wordCounter) flush(db database.Repository) error {
+	w.mu.Lock()
+	counts, documents := w.counts, w.documents
+	w.counts, w.documents = nil, nil
+	w.mu.Unlock()
+	if len(counts) == 0 {
+		return nil
+	}
+
+	if err := db.AddWordFrequencies(counts); err != nil {
+		w.mu.Lock()
+		if w.counts == nil {
+			w.counts = make(map[string]int)
+		}
+		for word, count := range counts {
+			w.counts[word] += count
+		}
+		w.documents = append(w.documents, documents...)
+		w.mu.Unlock()
+		return err
+	}
+	recordStage(db, documents, stageWordCloud, "")
+	Logger.Debug("Flushed word counts", "words", len(counts))
+	return nil
+}
+
+// flushSoon schedules a background flush unless one is already waiting
+func (w *wordCounter) flushSoon(db database.Repository) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.scheduled {
+		return
+	}
+	w.scheduled = true
+	time.AfterFunc(wordFlushDelay, func() {
+		w.mu.Lock()
+		w.scheduled = false
+		w.mu.Unlock()
+		if err := w.flush(db); err != nil {
+			Logger.Error("Failed to write word counts", "error", err)
+		}
+	})
+}
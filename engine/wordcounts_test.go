@@ -0,0 +1,35 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/drummonds/godocs/database"
+)
+
+func TestWordCounterFlush(t *testing.T) {
+	// Two documents counted in memory
+	handler := setupTestHandler(t)
+	var counter wordCounter
+	counter.add(&database.Document{Name: "a.pdf", FullText: "wombat wombat"})
+	counter.add(&database.Document{Name: "b.pdf", FullText: "wombat echidna"})
+
+	if err := counter.flush(handler.DB); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if err := counter.flush(handler.DB); err != nil {
+		t.Fatalf("Empty flush failed: %v", err)
+	}
+
+	// The aggregated counts are written exactly once
+	words, err := handler.DB.GetTopWords(10)
+	if err != nil {
+		t.Fatalf("Failed to get top words: %v", err)
+	}
+	counts := make(map[string]int)
+	for _, w := range words {
+		counts[w.Word] = w.Frequency
+	}
+	if counts["wombat"] != 3 || counts["echidna"] != 1 {
+		t.Errorf("Unexpected counts %v", counts)
+	}
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/drummonds/godocs/cache"
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/database"
+)
+
+// workerReloadInterval is how often a separate worker looks for job schedules saved through the API server
+const workerReloadInterval = time.Minute
+
+// workerChangeInterval is how often an API server started with -no-worker looks for documents changed by the worker
+const workerChangeInterval = 10 * time.Second
+
+// workerChangeStateKey is the shared server state the worker sets to a new ULID whenever it changes documents
+const workerChangeStateKey = "worker_documents_changed"
+
+// CheckWorkerConfig reports why a worker could not share the API server's database. Memory and
+// ephemeral databases live inside one process, so a worker started with them would ingest into a
+// database nobody else can see.
+func CheckWorkerConfig(serverConfig config.ServerConfig) error {
+	switch serverConfig.DatabaseType {
+	case "memory", "ephemeral":
+		return fmt.Errorf("DATABASE_TYPE %s cannot be shared with the API server; use postgres, cockroachdb or sqlite", serverConfig.DatabaseType)
+	}
+	return nil
+}
+
+// RunWorker runs the scheduled jobs (ingestion with OCR, cleanup, backups, reindexing, rescans, remote
+// sources and archiving) without serving the API, until stop is closed. It shares the database and document
+// folders with an API server started with -no-worker. Schedules saved through that server's schedules API are
+// picked up within workerReloadInterval.
+func (serverHandler *ServerHandler) RunWorker(stop <-chan struct{}) {
+	serverHandler.isWorker = true
+	serverHandler.InitializeSchedules(serverHandler.DB)
+	defer serverHandler.StopSchedules()
+
+	applied := serverHandler.scheduleFingerprint()
+	ticker := time.NewTicker(workerReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			applied = serverHandler.reloadSchedules(applied)
+		}
+	}
+}
+
+// scheduleFingerprint summarises the effective job schedules and quiet hours, to notice when they change
+func (serverHandler *ServerHandler) scheduleFingerprint() string {
+	specs, _, quiet, _, err := serverHandler.effectiveSchedules()
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprint(specs, quiet)
+}
+
+// reloadSchedules reschedules the jobs when the effective schedules differ from those applied, returning
+// the ones now applied. Rescheduling when nothing changed would restart every @every interval.
+func (serverHandler *ServerHandler) reloadSchedules(applied string) string {
+	current := serverHandler.scheduleFingerprint()
+	if current == "" || current == applied {
+		return applied
+	}
+	Logger.Info("Job schedules changed, rescheduling")
+	serverHandler.applySchedules()
+	return current
+}
+
+// reportWorkerChange tells API servers sharing the database that the worker changed documents, so they
+// drop their cached listings even when their cache is in memory
+func (serverHandler *ServerHandler) reportWorkerChange() {
+	if err := serverHandler.DB.SetServerState(workerChangeStateKey, database.MakeULID().String()); err != nil {
+		Logger.Error("Unable to report document changes to the API server", "error", err)
+	}
+}
+
+// FollowWorkerChanges drops the cached document listings whenever a separate worker reports a change,
+// looking every workerChangeInterval until the returned function is called. An API server started with
+// -no-worker runs it, since the worker cannot reach a cache held in the server's memory.
+func (serverHandler *ServerHandler) FollowWorkerChanges() func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	seen := serverHandler.workerChange()
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(workerChangeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				seen = serverHandler.followWorkerChange(seen)
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// workerChange returns the last change the worker reported, empty when it has not reported one
+func (serverHandler *ServerHandler) workerChange() string {
+	change, err := serverHandler.DB.GetServerState(workerChangeStateKey)
+	if err != nil {
+		Logger.Error("Unable to look for document changes by the worker", "error", err)
+	}
+	return change
+}
+
+// followWorkerChange drops the cached document listings when the worker reported a change since seen,
+// returning the change now seen
+func (serverHandler *ServerHandler) followWorkerChange(seen string) string {
+	change := serverHandler.workerChange()
+	if change == "" || change == seen {
+		return seen
+	}
+	if serverHandler.Cache != nil {
+		serverHandler.Cache.DeletePrefix(context.Background(), cache.DocumentKeys...)
+	}
+	return change
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/cache"
+	"github.com/drummonds/godocs/config"
+)
+
+func TestCheckWorkerConfig(t *testing.T) {
+	for databaseType, shareable := range map[string]bool{"postgres": true, "sqlite": true, "memory": false, "ephemeral": false} {
+		if err := CheckWorkerConfig(config.ServerConfig{DatabaseType: databaseType}); (err == nil) != shareable {
+			t.Errorf("Expected %s shareable=%v, got %v", databaseType, shareable, err)
+		}
+	}
+}
+
+func TestWorkerReloadsSavedSchedules(t *testing.T) {
+	// A worker with the schedules it started with applied
+	handler := setupTestHandler(t)
+	applied := handler.scheduleFingerprint()
+
+	// Nothing has changed, so nothing is rescheduled
+	if got := handler.reloadSchedules(applied); got != applied {
+		t.Errorf("Expected the schedules to be left alone, got %q", got)
+	}
+
+	// The API server saves a new ingest schedule to the shared database
+	if err := handler.DB.SaveJobSchedules(map[string]string{"ingest": "@every 5m"}); err != nil {
+		t.Fatalf("SaveJobSchedules failed: %v", err)
+	}
+
+	// The worker picks it up on its next look
+	if got := handler.reloadSchedules(applied); got == applied || got != handler.scheduleFingerprint() {
+		t.Errorf("Expected the saved schedule to be applied, got %q", got)
+	}
+}
+
+func TestWorkerFollowsReadOnlyMode(t *testing.T) {
+	// The API server and a worker share one database
+	server := setupTestHandler(t)
+	server.ServerConfig.AdminUsers = []string{"alice"}
+	server.Echo.PUT("/api/read-only", server.SetReadOnly)
+	worker := &ServerHandler{DB: server.DB, ServerConfig: server.ServerConfig}
+
+	for _, readOnly := range []bool{true, false} {
+		req := httptest.NewRequest(http.MethodPut, "/api/read-only", strings.NewReader(fmt.Sprintf(`{"readOnly":%v}`, readOnly)))
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth("alice", "secret")
+		rec := httptest.NewRecorder()
+		server.Echo.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected read-only mode switched, got %d: %s", rec.Code, rec.Body.String())
+		}
+		ran := false
+		worker.unlessReadOnly("ingest", func() { ran = true })()
+		if ran == readOnly {
+			t.Errorf("Expected the worker's job to run=%v with readOnly=%v", !readOnly, readOnly)
+		}
+	}
+}
+
+func TestAPIServerFollowsWorkerChanges(t *testing.T) {
+	// The API server keeps its cache in memory, where the worker cannot reach it
+	server := setupTestHandler(t)
+	server.Cache = cache.NewLRU(10, 0)
+	worker := &ServerHandler{DB: server.DB, ServerConfig: server.ServerConfig, isWorker: true}
+	server.Cache.Set(context.Background(), cache.KeyFileSystem, []byte("{}"))
+	seen := server.workerChange()
+
+	if got := server.followWorkerChange(seen); got != seen {
+		t.Errorf("Expected nothing to follow yet, got %q", got)
+	}
+	if _, found := server.Cache.Get(context.Background(), cache.KeyFileSystem); !found {
+		t.Fatal("Expected the cached tree to be kept while the worker changed nothing")
+	}
+
+	worker.invalidateDocumentCache()
+	seen = server.followWorkerChange(seen)
+	if _, found := server.Cache.Get(context.Background(), cache.KeyFileSystem); found {
+		t.Error("Expected the cached tree to be dropped after the worker changed documents")
+	}
+	if seen == "" || server.followWorkerChange(seen) != seen {
+		t.Errorf("Expected the change to be followed once, got %q", seen)
+	}
+}
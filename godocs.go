@@ -0,0 +1,522 @@
+// Package godocs is the godocs document management server as a library, so another Go program can run
+// the engine, API and web UI in process:
+//
+//	serverConfig, logger := config.SetupServer()
+//	server, err := godocs.New(serverConfig, godocs.WithLogger(logger))
+//	if err != nil {
+//		return err
+//	}
+//	go server.Start()
+//	defer server.Shutdown(context.Background())
+//	docs, _ := server.Repository().GetNewestDocuments(10)
+//
+// The engine packages log through package-level loggers, so only one Server should run per process.
+// The godocs command in cmd/godocs is a thin wrapper around this package.
+package godocs
+
+import (
+	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	cache "github.com/drummonds/godocs/cache"
+	config "github.com/drummonds/godocs/config"
+	database "github.com/drummonds/godocs/database"
+	engine "github.com/drummonds/godocs/engine"
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/drummonds/godocs/sources"
+	"github.com/drummonds/godocs/webapp"
+)
+
+//go:embed web/app.wasm web/wasm_exec.js
+var webFS embed.FS
+
+//go:embed webapp/webapp.css webapp/wordcloud.css
+var webappFS embed.FS
+
+//go:embed public/built/favicon.ico public/built/404.html
+var publicFS embed.FS
+
+// Logger is global since we will need it everywhere
+var Logger *slog.Logger
+
+// injectGlobals injects all of our globals into their packages
+func injectGlobals(logger *slog.Logger) {
+	Logger = logger
+	database.Logger = Logger
+	config.Logger = Logger
+	engine.Logger = Logger
+	cache.Logger = Logger
+	sources.Logger = Logger
+}
+
+// Server is a godocs instance: database, ingestion schedules, REST API and web UI
+type Server struct {
+	config      config.ServerConfig
+	logger      *slog.Logger
+	demo        bool
+	noWorker    bool
+	portRetries int
+
+	echo     *echo.Echo
+	db       database.Repository
+	handler  *engine.ServerHandler
+	cleanups []func()
+
+	stopFollowing func() // stops following document changes by a separate worker
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithLogger sets the logger every godocs package logs through (default slog.Default())
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithDemo runs on an in-memory database and temporary folders preloaded with the bundled sample documents
+func WithDemo() Option {
+	return func(s *Server) {
+		s.demo = true
+	}
+}
+
+// WithoutWorker serves the API and web UI without running the scheduled ingestion, OCR, cleanup and backup
+// jobs, for when a godocs-worker process sharing the database and document folders runs them elsewhere
+func WithoutWorker() Option {
+	return func(s *Server) {
+		s.noWorker = true
+	}
+}
+
+// WithPortRetries lets Start try up to retries following ports when the configured port is in use
+func WithPortRetries(retries int) Option {
+	return func(s *Server) {
+		s.portRetries = retries
+	}
+}
+
+// New opens the database, runs the startup checks, starts the ingestion schedules (unless WithoutWorker)
+// and registers the routes.
+// Nothing listens until Start is called; call Shutdown to release everything New opened.
+func New(serverConfig config.ServerConfig, opts ...Option) (*Server, error) {
+	s := &Server{config: serverConfig, logger: slog.Default()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	injectGlobals(s.logger) //inject the logger into all of the packages
+
+	if s.demo {
+		cleanupDemo, err := engine.DemoConfig(&s.config)
+		if err != nil {
+			return nil, fmt.Errorf("unable to start demo mode: %w", err)
+		}
+		s.cleanups = append(s.cleanups, cleanupDemo)
+	}
+	s.cleanups = append(s.cleanups, engine.SetupTracing(s.config)) // flushes the last spans on Shutdown
+
+	// An empty key would sign with a key anyone knows, letting /document/view links be forged
+	if s.config.URLSigningKey == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("unable to generate URL signing key: %w", err)
+		}
+		s.config.URLSigningKey = hex.EncodeToString(key)
+		Logger.Warn("No URL signing key configured, generated a random key; signed document links will stop working on restart")
+	}
+
+	// Setup database (handles ephemeral, postgres, cockroachdb, sqlite, memory)
+	Logger.Info("Setting up database", "type", s.config.DatabaseType)
+	db, err := database.OpenRepository(s.config)
+	if err != nil {
+		s.Shutdown(context.Background())
+		return nil, fmt.Errorf("unable to open database: %w", err)
+	}
+	s.db = db
+	Logger.Info("Database setup complete")
+	database.WriteConfigToDB(s.config, s.db) //writing the config to the database
+	Logger.Info("Config written to DB")
+
+	e := echo.New()
+	e.HideBanner = true
+	s.echo = e
+	s.handler = &engine.ServerHandler{DB: s.db, Echo: e, ServerConfig: s.config, Cache: cache.New(s.config)} //injecting the database into the handler for routes
+
+	// Custom 404 handler
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		code := http.StatusInternalServerError
+		if he, ok := err.(*echo.HTTPError); ok {
+			code = he.Code
+		}
+
+		// For 404 errors, serve custom HTML page
+		if code == http.StatusNotFound {
+			// Check if this is an API request
+			if strings.HasPrefix(c.Request().URL.Path, "/api/") {
+				// Return JSON for API endpoints
+				c.JSON(http.StatusNotFound, map[string]string{
+					"error":   "Not Found",
+					"code":    string(dto.CodeNotFound),
+					"message": "The requested API endpoint does not exist",
+					"path":    c.Request().URL.Path,
+				})
+				return
+			}
+
+			// For non-API requests, serve custom 404 HTML from embedded filesystem
+			if data, err := publicFS.ReadFile("public/built/404.html"); err == nil {
+				c.HTMLBlob(http.StatusNotFound, data)
+				return
+			}
+
+			// Fallback: serve inline HTML if embedded file doesn't exist
+			c.HTML(http.StatusNotFound, `<!DOCTYPE html>
+<html>
+<head><title>404 - Not Found</title></head>
+<body style="font-family: sans-serif; text-align: center; padding: 50px;">
+	<h1>404 - Page Not Found</h1>
+	<p>The page you're looking for doesn't exist.</p>
+	<a href="/" style="color: #3498db; text-decoration: none; font-size: 18px;">← Go to Home Page</a>
+</body>
+</html>`)
+			return
+		}
+
+		// For other errors, use default handler
+		e.DefaultHTTPErrorHandler(err, c)
+	}
+
+	if s.noWorker {
+		Logger.Info("Not running scheduled jobs; a separate worker runs them")
+		s.stopFollowing = s.handler.FollowWorkerChanges()
+	} else {
+		Logger.Info("About to initialize schedules")
+		s.handler.InitializeSchedules(s.db) //initialize all the cron jobs
+		Logger.Info("Schedules initialized, about to run startup checks")
+	}
+	// Run all the sanity checks; a sidecar service that never comes up is fatal
+	if err := s.handler.StartupChecks(); err != nil {
+		s.Shutdown(context.Background())
+		return nil, fmt.Errorf("startup checks failed: %w", err)
+	}
+	Logger.Info("Startup checks complete")
+	if err := s.handler.EnsureInitialUser(); err != nil {
+		Logger.Error("Unable to create the first account", "error", err)
+	}
+	if s.demo {
+		if _, err := s.handler.LoadDemoDocuments(); err != nil {
+			Logger.Error("Unable to load demo documents", "error", err)
+		}
+	}
+	s.routes()
+	return s, nil
+}
+
+// routes registers the middleware, web UI assets, API and document view routes
+func (s *Server) routes() {
+	e := s.echo
+	e.Use(s.handler.TraceRequests()) // Span per request when OTLP_ENDPOINT is set
+	e.Use(middleware.CORSWithConfig(middleware.DefaultCORSConfig))
+	e.Use(s.handler.DocumentViewAuth()) // Check signatures on /document/view links
+	e.Use(s.handler.RequireLogin())     // Require a session when WEB_UI_AUTH is on
+	e.Use(s.handler.ReadOnlyGuard())    // Refuse changes during maintenance windows
+
+	Logger.Info("Setting up go-app WASM UI")
+	appHandler := webapp.Handler()
+
+	// Serve wasm_exec.js from embedded filesystem (go-app expects it here)
+	e.GET("/wasm_exec.js", func(c echo.Context) error {
+		data, err := webFS.ReadFile("web/wasm_exec.js")
+		if err != nil {
+			return c.String(http.StatusNotFound, "wasm_exec.js not found")
+		}
+		return c.Blob(http.StatusOK, "application/javascript", data)
+	})
+
+	// Register go-app specific resources
+	e.GET("/app.js", echo.WrapHandler(appHandler))
+	e.GET("/app.css", echo.WrapHandler(appHandler))
+	e.GET("/manifest.webmanifest", echo.WrapHandler(appHandler))
+
+	// Serve static assets from embedded filesystem
+	webSubFS, _ := fs.Sub(webFS, "web")
+	e.GET("/web/*", echo.WrapHandler(http.StripPrefix("/web/", http.FileServer(http.FS(webSubFS)))))
+
+	// Serve CSS files from embedded filesystem
+	e.GET("/webapp/webapp.css", func(c echo.Context) error {
+		data, err := webappFS.ReadFile("webapp/webapp.css")
+		if err != nil {
+			return c.String(http.StatusNotFound, "webapp.css not found")
+		}
+		return c.Blob(http.StatusOK, "text/css", data)
+	})
+
+	e.GET("/webapp/wordcloud.css", func(c echo.Context) error {
+		data, err := webappFS.ReadFile("webapp/wordcloud.css")
+		if err != nil {
+			return c.String(http.StatusNotFound, "wordcloud.css not found")
+		}
+		return c.Blob(http.StatusOK, "text/css", data)
+	})
+
+	// Serve favicon from embedded filesystem
+	e.GET("/favicon.ico", func(c echo.Context) error {
+		data, err := publicFS.ReadFile("public/built/favicon.ico")
+		if err != nil {
+			return c.String(http.StatusNotFound, "favicon.ico not found")
+		}
+		return c.Blob(http.StatusOK, "image/x-icon", data)
+	})
+
+	// Inject backend API URL into the page
+	e.GET("/config.js", func(c echo.Context) error {
+		configJS := fmt.Sprintf(`
+// godocs Frontend Configuration
+window.godocs_config = {
+    apiURL: "%s",
+    newDocumentCount: %d
+};
+console.log("godocs Config loaded:", window.godocs_config);
+`, s.config.ServerAPIURL, s.config.NewDocumentNumber)
+		c.Response().Header().Set("Content-Type", "application/javascript")
+		return c.String(http.StatusOK, configJS)
+	})
+
+	//injecting database into the context so we can access it
+	//Start the API routes - all under /api/* prefix for clarity
+
+	// Document API routes
+	e.GET("/api/documents/latest", s.handler.GetLatestDocuments)
+	e.GET("/api/documents/filesystem", s.handler.GetDocumentFileSystem)
+	e.GET("/api/documents/export.ndjson", s.handler.ExportDocumentsNDJSON)
+	e.GET("/api/documents/popular", s.handler.GetPopularDocuments)
+	e.GET("/api/document/:id", s.handler.GetDocument)
+	e.GET("/api/document/:id/text", s.handler.GetDocumentText)
+	e.GET("/api/document/:id/timeline", s.handler.GetDocumentTimeline)
+	e.GET("/api/document/:id/search", s.handler.SearchDocumentText)
+	e.GET("/api/document/:id/coversheet.pdf", s.handler.GetCoverSheet)
+	e.GET("/api/document/:id/qr.png", s.handler.GetDocumentQR)
+	e.GET("/api/document/:id/signed-url", s.handler.GetSignedDocumentURL)
+	e.POST("/api/document/:id/lock", s.handler.LockDocument)
+	e.GET("/api/document/:id/lock", s.handler.GetDocumentLock)
+	e.DELETE("/api/document/:id/lock", s.handler.UnlockDocument)
+	e.POST("/api/document/:id/archive", s.handler.ArchiveDocument)
+	e.GET("/api/document/:id/archive", s.handler.GetDocumentArchive)
+	e.DELETE("/api/document/:id/archive", s.handler.RestoreDocument)
+	e.GET("/api/archive", s.handler.GetArchivedDocuments)
+	e.POST("/api/document/:id/redact", s.handler.RedactDocument)
+	e.GET("/api/document/:id/redactions", s.handler.GetDocumentRedactions)
+	e.GET("/api/document/:id/spreadsheet", s.handler.GetSpreadsheetDetails)
+	e.GET("/api/document/:id/rotation", s.handler.GetDocumentRotations)
+	e.GET("/api/document/:id/blank-pages", s.handler.GetDocumentBlankPages)
+	e.GET("/api/document/:id/preview", s.handler.GetSpreadsheetPreview)
+	e.GET("/api/document/:id/tags", s.handler.GetDocumentTags)
+	e.POST("/api/document/:id/tags", s.handler.AddDocumentTag)
+	e.DELETE("/api/document/:id/tags/:tag", s.handler.RemoveDocumentTag)
+	e.POST("/api/holds", s.handler.PlaceLegalHold)
+	e.GET("/api/holds", s.handler.GetLegalHolds)
+	e.GET("/api/holds/events", s.handler.GetLegalHoldEvents)
+	e.DELETE("/api/holds/:id", s.handler.RemoveLegalHold)
+	e.DELETE("/api/document/*", s.handler.DeleteFile)
+	e.PATCH("/api/document/move/*", s.handler.MoveDocuments)
+	e.POST("/api/document/upload", s.handler.UploadDocuments)
+	e.POST("/api/document/rescan", s.handler.RescanDocument)
+
+	// Folder API routes
+	e.GET("/api/folders", s.handler.GetFolders)
+	e.PATCH("/api/folders/:id", s.handler.UpdateFolderStyle)
+	e.GET("/api/folder/:folder", s.handler.GetFolder)
+	e.GET("/api/folder/:folder/download", s.handler.DownloadFolder)
+	e.POST("/api/folder/*", s.handler.CreateFolder)
+
+	// Search API routes
+	e.GET("/api/search", s.handler.SearchDocuments)
+	e.POST("/api/search/reindex", s.handler.ReindexSearchDocuments)
+	e.GET("/api/search/history", s.handler.GetSearchHistory)
+	e.GET("/api/search/analytics", s.handler.GetSearchAnalytics)
+	e.GET("/api/search/suggest", s.handler.SuggestSearch)
+	e.GET("/api/tags", s.handler.ListTags)
+	e.GET("/api/tags/:tag/documents", s.handler.GetDocumentsByTag)
+	e.PUT("/api/tags/:tag", s.handler.RenameTag)
+	e.POST("/api/tags/:tag/merge", s.handler.MergeTag)
+	e.GET("/api/collections", s.handler.ListCollections)
+	e.POST("/api/collections", s.handler.CreateCollection)
+	e.GET("/api/collections/:id", s.handler.GetCollection)
+	e.DELETE("/api/collections/:id", s.handler.DeleteCollection)
+	e.GET("/api/shared/:token", s.handler.GetSharedCollection)
+	e.GET("/api/smartfolders", s.handler.ListSmartFolders)
+	e.POST("/api/smartfolders", s.handler.CreateSmartFolder)
+	e.GET("/api/smartfolders/:id", s.handler.GetSmartFolder)
+	e.DELETE("/api/smartfolders/:id", s.handler.DeleteSmartFolder)
+
+	// Extraction template routes
+	e.GET("/api/extraction/templates", s.handler.ListExtractionTemplates)
+	e.POST("/api/extraction/templates", s.handler.CreateExtractionTemplate)
+	e.DELETE("/api/extraction/templates/:id", s.handler.DeleteExtractionTemplate)
+	e.POST("/api/extraction/preview", s.handler.PreviewExtraction)
+	e.GET("/api/documents/:id/fields", s.handler.GetDocumentFields)
+	e.GET("/api/extractions/export", s.handler.ExportExtractions)
+
+	// Admin API routes
+	e.POST("/api/ingest", s.handler.RunIngestNow)
+	e.GET("/api/ingest/rejections", s.handler.GetIngestRejections)
+	e.POST("/api/clean", s.handler.CleanDatabase)
+	e.POST("/api/documents/urls/repair", s.handler.RepairDocumentURLs)
+	e.POST("/api/documents/rehash", s.handler.RehashDocuments)
+	e.POST("/api/documents/optimise", s.handler.OptimiseDocuments)
+	e.GET("/api/duplicates", s.handler.GetDuplicates)
+	e.POST("/api/duplicates/scan", s.handler.ScanDuplicates)
+	e.GET("/api/about", s.handler.GetAboutInfo)
+	e.GET("/api/about/ocr", s.handler.GetOCRLanguages)
+	e.GET("/api/quota", s.handler.GetQuota)
+	e.GET("/api/schedules", s.handler.GetSchedules)
+	e.PUT("/api/schedules", s.handler.UpdateSchedules)
+	e.GET("/api/setup", s.handler.GetSetup)
+	e.POST("/api/setup", s.handler.SaveSetup)
+	e.GET("/api/health", s.handler.GetHealth)
+	e.GET("/api/read-only", s.handler.GetReadOnly)
+	e.PUT("/api/read-only", s.handler.SetReadOnly)
+	e.GET("/api/admin/slow-queries", s.handler.GetSlowQueries)
+	e.GET("/api/admin/index-usage", s.handler.GetIndexUsage)
+	e.GET("/api/admin/client-errors", s.handler.GetClientErrors)
+	e.POST("/api/admin/export", s.handler.ExportArchive)
+	e.GET("/api/admin/export/:jobId/download", s.handler.DownloadExport)
+	e.POST("/api/client-errors", s.handler.ReportClientError)
+	e.POST("/api/auth/login", s.handler.Login)
+	e.POST("/api/auth/logout", s.handler.Logout)
+	e.GET("/api/auth/status", s.handler.GetAuthStatus)
+	e.GET("/api/users", s.handler.ListUsers)
+	e.POST("/api/users", s.handler.CreateUser)
+	e.DELETE("/api/users/:id", s.handler.DeleteUser)
+	e.PUT("/api/users/:id/password", s.handler.ChangePassword)
+	e.PUT("/api/users/:id/role", s.handler.SetUserRole)
+	e.GET("/api/folder-permissions", s.handler.ListFolderPermissions)
+	e.PUT("/api/folder-permissions", s.handler.SetFolderPermission)
+	e.DELETE("/api/folder-permissions", s.handler.DeleteFolderPermission)
+
+	// Word cloud API routes
+	e.GET("/api/wordcloud", s.handler.GetWordCloud)
+	e.POST("/api/wordcloud/recalculate", s.handler.RecalculateWordCloud)
+
+	// Statistics API routes
+	e.GET("/api/stats/timeseries", s.handler.GetStatsTimeseries)
+
+	// Integration routes
+	e.POST("/api/integrations/dropzone", s.handler.ReceiveDropzone)
+
+	// Job tracking API routes
+	e.GET("/api/jobs", s.handler.GetRecentJobs)
+	e.GET("/api/jobs/active", s.handler.GetActiveJobs)
+	e.GET("/api/jobs/:id", s.handler.GetJob)
+	e.GET("/api/activity", s.handler.GetActivity)
+
+	// Document view routes (serve actual files - not JSON, so not under /api/*)
+	s.handler.AddDocumentViewRoutes() //Add all existing documents to direct view links
+
+	// Serve go-app handler for all other routes (must be last)
+	// The WASM app handles its own client-side routing and 404s via NotFoundPage component
+	e.Any("/*", echo.WrapHandler(appHandler))
+}
+
+// Start serves HTTP on the configured address until Shutdown is called, when it returns nil.
+// With WithPortRetries, a port that is already in use is skipped for the next one.
+func (s *Server) Start() error {
+	if s.config.ListenAddrIP == "" {
+		Logger.Info("No Ip Addr set, binding on ALL addresses")
+	}
+	startPort := s.config.ListenAddrPort
+	for attempt := 0; ; attempt++ {
+		addr := fmt.Sprintf("%s:%s", s.config.ListenAddrIP, s.config.ListenAddrPort)
+		Logger.Info("Attempting to start server", "address", addr, "attempt", attempt+1)
+		if attempt > 0 {
+			Logger.Warn("Server starting on alternative port due to conflicts", "requested_port", startPort, "port", s.config.ListenAddrPort)
+		}
+
+		err := s.echo.Start(addr)
+		switch {
+		case errors.Is(err, http.ErrServerClosed):
+			Logger.Info("Server stopped")
+			return nil
+		case isAddressInUse(err) && attempt < s.portRetries:
+			Logger.Warn("Port already in use, trying next port", "port", s.config.ListenAddrPort, "attempt", attempt+1, "max_attempts", s.portRetries+1)
+			portNum := 0
+			fmt.Sscanf(s.config.ListenAddrPort, "%d", &portNum)
+			s.config.ListenAddrPort = fmt.Sprintf("%d", portNum+1)
+		case isAddressInUse(err):
+			return fmt.Errorf("no free port from %s to %s: %w", startPort, s.config.ListenAddrPort, err)
+		default:
+			return err
+		}
+	}
+}
+
+// Shutdown stops the HTTP server, letting in-flight requests finish until ctx expires, stops the
+// ingestion schedules and closes the database. It is safe to call more than once.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		if s.echo != nil {
+			s.shutdownErr = s.echo.Shutdown(ctx)
+		}
+		if s.handler != nil {
+			s.handler.StopSchedules()
+		}
+		if s.stopFollowing != nil {
+			s.stopFollowing()
+		}
+		if s.db != nil {
+			if err := s.db.Close(); s.shutdownErr == nil {
+				s.shutdownErr = err
+			}
+		}
+		for _, cleanup := range s.cleanups {
+			cleanup()
+		}
+	})
+	return s.shutdownErr
+}
+
+// Repository gives direct access to the document database
+func (s *Server) Repository() database.Repository {
+	return s.db
+}
+
+// Handler returns the HTTP handler for the API and web UI, for mounting in another server instead of calling Start
+func (s *Server) Handler() http.Handler {
+	return s.echo
+}
+
+// Config returns the configuration the server is running with, including any demo mode or port changes
+func (s *Server) Config() config.ServerConfig {
+	return s.config
+}
+
+// Addr returns the address the server is listening on, or nil before Start has bound it
+func (s *Server) Addr() net.Addr {
+	return s.echo.ListenerAddr()
+}
+
+// isAddressInUse checks if the error is due to address already in use
+func isAddressInUse(err error) bool {
+	if err == nil {
+		return false
+	}
+	errStr := err.Error()
+	return strings.Contains(errStr, "address already in use")
+}
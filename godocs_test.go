@@ -0,0 +1,120 @@
+package godocs
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	config "github.com/drummonds/godocs/config"
+)
+
+// newTestServer builds a Server on the in-memory database with its folders in a temp dir
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	serverConfig := config.ServerConfig{
+		DatabaseType:   "memory",
+		IngressPath:    filepath.Join(dir, "ingress"),
+		DocumentPath:   filepath.Join(dir, "documents"),
+		ListenAddrIP:   "127.0.0.1",
+		ListenAddrPort: "0",
+	}
+	for _, folder := range []string{serverConfig.IngressPath, serverConfig.DocumentPath} {
+		if err := os.MkdirAll(folder, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	srv, err := New(serverConfig, WithLogger(logger))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+	return srv
+}
+
+func TestServerHandlerServesAPI(t *testing.T) {
+	// An embedded server that has not been started
+	srv := newTestServer(t)
+
+	// Calling the health endpoint through its handler
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+
+	// The API answers and the repository is available to the host program
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 from /api/health, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := srv.Repository().GetNewestDocuments(10); err != nil {
+		t.Errorf("Repository query failed: %v", err)
+	}
+}
+
+func TestServerStartAndShutdown(t *testing.T) {
+	// An embedded server listening on a free port
+	srv := newTestServer(t)
+	started := make(chan error, 1)
+	go func() { started <- srv.Start() }()
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.Addr() == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if srv.Addr() == nil {
+		t.Fatal("Server did not start listening")
+	}
+	resp, err := http.Get("http://" + srv.Addr().String() + "/api/health")
+	if err != nil {
+		t.Fatalf("Request to running server failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// Shutting it down, twice as a host program's deferred cleanup might
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = srv.Shutdown(ctx)
+	secondErr := srv.Shutdown(ctx)
+
+	// Start returns cleanly and both shutdowns succeed
+	if err != nil || secondErr != nil {
+		t.Errorf("Shutdown failed: %v, %v", err, secondErr)
+	}
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Errorf("Expected Start to return nil after Shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Start did not return after Shutdown")
+	}
+}
+
+func TestNewGeneratesURLSigningKey(t *testing.T) {
+	// A host program that builds its own ServerConfig leaves the key empty
+	first := newTestServer(t)
+	second := newTestServer(t)
+	key := first.handler.ServerConfig.URLSigningKey
+	if len(key) != 64 {
+		t.Fatalf("Expected a 32 byte hex signing key, got %q", key)
+	}
+	if key == second.handler.ServerConfig.URLSigningKey {
+		t.Error("Expected each server to generate its own signing key")
+	}
+}
+
+func TestNewReturnsDatabaseErrors(t *testing.T) {
+	// An unusable database must come back as an error, not end the host program
+	srv, err := New(config.ServerConfig{DatabaseType: "unknown"})
+	if err == nil {
+		srv.Shutdown(context.Background())
+		t.Fatal("Expected an error for an unknown database type")
+	}
+	if srv != nil {
+		t.Error("Expected no server alongside the error")
+	}
+}
@@ -1,3 +1,45 @@
 package build
 
-var Version = "development"
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time with -ldflags "-X github.com/drummonds/godocs/internal/build.Version=..."; Commit and
+// Date fall back to what the Go toolchain stamped into the binary from version control
+var (
+	Version = "development"
+	Commit  = "" // git commit hash
+	Date    = "" // build date, RFC 3339 in UTC
+)
+
+// Info is the metadata of the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"goVersion"`
+	Modified  bool   `json:"modified,omitempty"` // built from a working tree with uncommitted changes
+}
+
+// Current returns the metadata of the running binary
+func Current() Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
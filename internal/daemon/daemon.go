@@ -0,0 +1,136 @@
+// Package daemon runs godocs under the platform service manager (Windows services, systemd, launchd)
+// and implements the install/uninstall/start/stop subcommands.
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/kardianos/service"
+)
+
+// Actions are the service subcommands Control accepts
+var Actions = []string{"install", "uninstall", "start", "stop", "restart", "status"}
+
+// WorkDirFlag is the flag an installed service is started with so it finds its config files and
+// relative paths: Windows starts services in the system directory and ignores WorkingDirectory.
+const WorkDirFlag = "workdir"
+
+// stopTimeout is how long the service manager waits for the server to shut down
+const stopTimeout = 30 * time.Second
+
+// Config describes the service for name, started from the current working directory with extra args
+func Config(name string, displayName string, description string, args ...string) (*service.Config, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &service.Config{
+		Name:             name,
+		DisplayName:      displayName,
+		Description:      description,
+		Arguments:        append([]string{"-" + WorkDirFlag, wd}, args...),
+		WorkingDirectory: wd,
+		Option:           service.KeyValue{"Restart": "on-failure", "OnFailure": "restart"},
+	}
+	if runtime.GOOS == "linux" {
+		// systemd unit lines; on Windows Dependencies names other services instead
+		cfg.Dependencies = []string{"After=network-online.target", "Wants=network-online.target"}
+	}
+	return cfg, nil
+}
+
+// IsAction reports whether arg is one of the service subcommands
+func IsAction(arg string) bool {
+	return slices.Contains(Actions, arg)
+}
+
+// Control runs a service subcommand and returns a message describing the result
+func Control(cfg *service.Config, action string) (string, error) {
+	svc, err := service.New(&program{}, cfg)
+	if err != nil {
+		return "", err
+	}
+	if action == "status" {
+		status, err := svc.Status()
+		if errors.Is(err, service.ErrNotInstalled) {
+			return cfg.Name + " is not installed", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return cfg.Name + " is " + statusName(status), nil
+	}
+	if !IsAction(action) {
+		return "", fmt.Errorf("unknown service action %q, expected one of %v", action, Actions)
+	}
+	if err := service.Control(svc, action); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s: %s done", cfg.Name, action), nil
+}
+
+// statusName describes a service status
+func statusName(status service.Status) string {
+	switch status {
+	case service.StatusRunning:
+		return "running"
+	case service.StatusStopped:
+		return "stopped"
+	default:
+		return "in an unknown state"
+	}
+}
+
+// Run calls serve directly when started from a terminal. When started by the service manager it runs
+// serve in the background and closes the stop channel when the manager asks the service to stop;
+// serve must then shut down and return. A nil stop channel means serve runs until the process exits.
+func Run(cfg *service.Config, serve func(stop <-chan struct{})) error {
+	if service.Interactive() {
+		serve(nil)
+		return nil
+	}
+	p := &program{serve: serve, stop: make(chan struct{}), done: make(chan struct{})}
+	svc, err := service.New(p, cfg)
+	if err != nil {
+		return err
+	}
+	return svc.Run()
+}
+
+// program adapts serve to the service.Interface the service manager calls
+type program struct {
+	serve func(stop <-chan struct{})
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// Start must not block, so the server runs in its own goroutine
+func (p *program) Start(s service.Service) error {
+	go func() {
+		defer close(p.done)
+		p.serve(p.stop)
+		select {
+		case <-p.stop:
+		default:
+			// The server stopped without being asked; exit so the service manager can restart it
+			os.Exit(1)
+		}
+	}()
+	return nil
+}
+
+// Stop asks the server to shut down and waits for it
+func (p *program) Stop(s service.Service) error {
+	close(p.stop)
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(stopTimeout):
+		return fmt.Errorf("server did not stop within %s", stopTimeout)
+	}
+}
@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestConfigStartsInWorkingDirectory(t *testing.T) {
+	// godocs installed from the current folder in demo mode
+	wd, _ := os.Getwd()
+
+	// The service definition is built
+	cfg, err := Config("godocs", "godocs", "test", "-demo")
+	if err != nil {
+		t.Fatalf("Config failed: %v", err)
+	}
+
+	// The service is told to change back to this folder and keeps the extra flags
+	want := []string{"-" + WorkDirFlag, wd, "-demo"}
+	if !slices.Equal(cfg.Arguments, want) {
+		t.Errorf("Expected arguments %v, got %v", want, cfg.Arguments)
+	}
+	if cfg.WorkingDirectory != wd {
+		t.Errorf("Expected working directory %s, got %s", wd, cfg.WorkingDirectory)
+	}
+}
+
+func TestIsAction(t *testing.T) {
+	for _, arg := range []string{"install", "uninstall", "status"} {
+		if !IsAction(arg) {
+			t.Errorf("Expected %q to be a service action", arg)
+		}
+	}
+	for _, arg := range []string{"", "serve", "-demo"} {
+		if IsAction(arg) {
+			t.Errorf("Did not expect %q to be a service action", arg)
+		}
+	}
+}
+
+func TestProgramStopWaitsForServer(t *testing.T) {
+	// A server running under the service manager
+	stopped := false
+	p := &program{
+		serve: func(stop <-chan struct{}) {
+			<-stop
+			time.Sleep(10 * time.Millisecond)
+			stopped = true
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if err := p.Start(nil); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// The service manager stops it
+	err := p.Stop(nil)
+
+	// Stop returns only once the server has finished shutting down
+	if err != nil || !stopped {
+		t.Errorf("Expected a clean stop after the server returned, got err=%v stopped=%v", err, stopped)
+	}
+}
@@ -0,0 +1,143 @@
+// Package dto holds the JSON response types shared by the engine handlers and the WASM webapp.
+// Both sides compile against the same structs, so a renamed field or tag breaks the build instead of
+// silently leaving the web UI with empty values. It must only import the standard library, as it is
+// built for js/wasm as well as the server.
+package dto
+
+// FileTreeNode is a folder or document in the document tree and in search results
+type FileTreeNode struct {
+	ID          string   `json:"id"`
+	ULID        string   `json:"ulid"`
+	MIMEType    string   `json:"mimeType,omitempty"` // documents only, for choosing how to preview them
+	Name        string   `json:"name"`
+	Size        int64    `json:"size"`
+	PageCount   int      `json:"pageCount,omitempty"` // PDFs only
+	OCRStatus   string   `json:"ocrStatus,omitempty"` // documents only: pending, done, failed or skipped
+	ModDate     string   `json:"modDate"`
+	Openable    bool     `json:"openable"`
+	ParentID    string   `json:"parentID"`
+	IsDir       bool     `json:"isDir"`
+	Missing     bool     `json:"missing,omitempty"`  // documents only, when the file is gone from document storage
+	Archived    bool     `json:"archived,omitempty"` // documents only, when the file is in cold storage
+	ChildrenIDs []string `json:"childrenIDs"`
+	FullPath    string   `json:"fullPath"`
+	FileURL     string   `json:"fileURL"`
+	SmartFolder string   `json:"smartFolder,omitempty"` // the smart folder's ULID, on smart folders and the documents listed in them
+	Color       string   `json:"color,omitempty"`       // folders only, the colour label set through PATCH /api/folders/:id
+	Icon        string   `json:"icon,omitempty"`        // folders only, shown instead of the folder icon
+}
+
+// FileSystem is the flat document tree returned by the filesystem and search endpoints
+type FileSystem struct {
+	FileSystem  []FileTreeNode `json:"fileSystem"`
+	Error       string         `json:"error"`
+	Suggestions []string       `json:"suggestions,omitempty"` // corrected search terms when a search found nothing
+}
+
+// SearchCompletions is the autocomplete response for what has been typed in the search box
+type SearchCompletions struct {
+	Prefix    string              `json:"prefix"`
+	Words     []string            `json:"words"`
+	Documents []SuggestedDocument `json:"documents"`
+}
+
+// SuggestedDocument is a document whose name matches what has been typed in the search box
+type SuggestedDocument struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Folder string `json:"folder"`
+	URL    string `json:"url"`
+}
+
+// ActivityItem is one entry of the activity feed: a change from the audit log or a finished job
+type ActivityItem struct {
+	Kind         string `json:"kind"` // document_added, document_moved, document_deleted, settings_changed or job
+	At           string `json:"at"`   // RFC 3339
+	Summary      string `json:"summary"`
+	DocumentULID string `json:"documentUlid,omitempty"`
+	JobID        string `json:"jobId,omitempty"`
+	JobType      string `json:"jobType,omitempty"`
+	Status       string `json:"status,omitempty"` // jobs only: completed, failed or cancelled
+	User         string `json:"user,omitempty"`
+}
+
+// ActivityFeed is one page of the activity feed, newest first
+type ActivityFeed struct {
+	Items      []ActivityItem `json:"items"`
+	PageSize   int            `json:"pageSize"`
+	HasNext    bool           `json:"hasNext"`
+	NextCursor string         `json:"nextCursor,omitempty"` // pass as cursor to read the next page
+}
+
+// IngestRejection is a file ingestion left in the ingress folder, with why
+type IngestRejection struct {
+	Path       string `json:"path"`
+	Name       string `json:"name"`
+	Reason     string `json:"reason"`
+	RejectedAt string `json:"rejectedAt"` // RFC 3339, when the file was first rejected
+}
+
+// IngestRejections lists the rejected ingress files, most recently rejected first
+type IngestRejections struct {
+	Rejections []IngestRejection `json:"rejections"`
+}
+
+// DocumentTextPage is one page of a document's extracted text. Offsets and lengths count characters.
+type DocumentTextPage struct {
+	Text       string `json:"text"`
+	Offset     int    `json:"offset"`
+	Length     int    `json:"length"`               // characters in this page
+	Total      int    `json:"total"`                // characters in the whole text
+	NextOffset int    `json:"nextOffset,omitempty"` // where the next page starts, absent on the last page
+}
+
+// ClientErrorReport is an error the web UI caught in the browser and sends to the server
+type ClientErrorReport struct {
+	Kind    string `json:"kind"` // panic, error, rejection or fetch
+	Message string `json:"message"`
+	Stack   string `json:"stack,omitempty"`
+	Page    string `json:"page,omitempty"` // the UI route the error happened on
+}
+
+// ClientError is an error report from the web UI as stored
+type ClientError struct {
+	ID         string `json:"id"`
+	Kind       string `json:"kind"`
+	Message    string `json:"message"`
+	Stack      string `json:"stack,omitempty"`
+	Page       string `json:"page,omitempty"`
+	UserAgent  string `json:"userAgent,omitempty"`
+	User       string `json:"user,omitempty"`
+	ReportedAt string `json:"reportedAt"` // RFC 3339
+}
+
+// ClientErrors lists the web UI's error reports, newest first
+type ClientErrors struct {
+	Errors []ClientError `json:"errors"`
+}
+
+// AuthStatus says whether signing in is required and who is signed in
+type AuthStatus struct {
+	AuthRequired bool   `json:"authRequired"`
+	Username     string `json:"username,omitempty"` // empty when nobody is signed in
+}
+
+// LoginResponse is a new session. The token is also set as a cookie; API clients send it as a Bearer token.
+type LoginResponse struct {
+	Token     string `json:"token"`
+	Username  string `json:"username"`
+	ExpiresAt string `json:"expiresAt"` // RFC 3339
+}
+
+// ReadOnlyStatus says whether the server is refusing changes for maintenance
+type ReadOnlyStatus struct {
+	ReadOnly bool   `json:"readOnly"`
+	Message  string `json:"message,omitempty"` // why, shown to users while read-only
+	Since    string `json:"since,omitempty"`   // RFC 3339, when read-only mode was last turned on through the API
+}
+
+// Tag is a label on documents with how many of them carry it
+type Tag struct {
+	Name          string `json:"name"`
+	DocumentCount int    `json:"documentCount"`
+}
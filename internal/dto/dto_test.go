@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGoldenResponsesDecode checks that the recorded backend responses decode into the shared types
+// without fields the webapp would drop
+func TestGoldenResponsesDecode(t *testing.T) {
+	for _, name := range []string{"filesystem", "search", "search_suggestions"} {
+		t.Run(name, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("..", "..", "testdata", "golden", name+".json"))
+			if err != nil {
+				t.Fatalf("Failed to read golden file: %v", err)
+			}
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.DisallowUnknownFields()
+			var fileSystem FileSystem
+			if err := decoder.Decode(&fileSystem); err != nil {
+				t.Fatalf("The %s response does not match FileSystem: %v", name, err)
+			}
+			for _, node := range fileSystem.FileSystem {
+				if !node.IsDir && (node.ULID == "" || node.FileURL == "") {
+					t.Errorf("Document %q is missing its ULID or URL", node.Name)
+				}
+			}
+		})
+	}
+}
+
+// TestSearchCompletionsRoundTrip checks the autocomplete response keeps every field through JSON
+func TestSearchCompletionsRoundTrip(t *testing.T) {
+	sent := SearchCompletions{
+		Prefix:    "inv",
+		Words:     []string{"invoice"},
+		Documents: []SuggestedDocument{{ID: "01ARZ3NDEKTSV4RRFFQ69G5FAV", Name: "Invoice.pdf", Folder: "/docs", URL: "/document/view/01ARZ3NDEKTSV4RRFFQ69G5FAV"}},
+	}
+	body, err := json.Marshal(sent)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	var received SearchCompletions
+	if err := json.Unmarshal(body, &received); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if received.Prefix != sent.Prefix || len(received.Words) != 1 || received.Documents[0] != sent.Documents[0] {
+		t.Errorf("Round trip changed the completions: %+v", received)
+	}
+}
@@ -0,0 +1,73 @@
+package dto
+
+// ErrorCode identifies a kind of failure. Codes are returned as "code" beside the English "error"
+// message in every API error response, and against each file an ingestion job could not process, so
+// clients branch on the code rather than the wording. A code is never renamed or reused once released.
+type ErrorCode string
+
+const (
+	// CodeBadRequest is a malformed request body, parameter or query value
+	CodeBadRequest ErrorCode = "GODOCS_BAD_REQUEST"
+	// CodeValidation is a request whose values were refused; "fields" names each problem
+	CodeValidation ErrorCode = "GODOCS_VALIDATION"
+	// CodeInvalidID is a document, collection, smart folder or job ID that is not a valid ULID
+	CodeInvalidID ErrorCode = "GODOCS_INVALID_ID"
+	// CodeUnauthorized is a missing or wrong API key or signature
+	CodeUnauthorized ErrorCode = "GODOCS_UNAUTHORIZED"
+	// CodeForbidden is a request that is understood but not allowed
+	CodeForbidden ErrorCode = "GODOCS_FORBIDDEN"
+	// CodeNotFound is a document, collection, job or folder that does not exist
+	CodeNotFound ErrorCode = "GODOCS_NOT_FOUND"
+	// CodeFileMissing is a document whose record exists but whose file is gone from storage
+	CodeFileMissing ErrorCode = "GODOCS_FILE_MISSING"
+	// CodeFeatureDisabled is an endpoint for a feature that is not configured
+	CodeFeatureDisabled ErrorCode = "GODOCS_FEATURE_DISABLED"
+	// CodeDuplicate is a document whose content is already stored
+	CodeDuplicate ErrorCode = "GODOCS_DUPLICATE"
+	// CodeNameConflict is a file name already used in the destination folder
+	CodeNameConflict ErrorCode = "GODOCS_NAME_CONFLICT"
+	// CodeArchived is a document whose file is in cold storage; restore it before viewing it
+	CodeArchived ErrorCode = "GODOCS_ARCHIVED"
+	// CodeConflict is a request that clashes with the current state, such as a job already running
+	CodeConflict ErrorCode = "GODOCS_CONFLICT"
+	// CodeLocked is a document checked out by another holder; the response includes its "lock"
+	CodeLocked ErrorCode = "GODOCS_LOCKED"
+	// CodeLegalHold is a document or folder under legal hold, which may not be deleted; the response includes its "hold"
+	CodeLegalHold ErrorCode = "GODOCS_LEGAL_HOLD"
+	// CodeUnsupportedType is a file whose type is not processed
+	CodeUnsupportedType ErrorCode = "GODOCS_UNSUPPORTED_TYPE"
+	// CodeQuotaExceeded is a file that would take document storage over its quota
+	CodeQuotaExceeded ErrorCode = "GODOCS_QUOTA_EXCEEDED"
+	// CodeOCRFailed is a scan or image that could not be OCRed; the document is stored without text
+	CodeOCRFailed ErrorCode = "GODOCS_OCR_FAILED"
+	// CodeExtractionFailed is a document whose text could not be read; it is stored without text
+	CodeExtractionFailed ErrorCode = "GODOCS_EXTRACTION_FAILED"
+	// CodeStorageFailed is a file that could not be copied into document storage or failed its hash check
+	CodeStorageFailed ErrorCode = "GODOCS_STORAGE_FAILED"
+	// CodeHookFailed is a post-ingestion command or webhook that failed or timed out; the document is ingested
+	CodeHookFailed ErrorCode = "GODOCS_HOOK_FAILED"
+	// CodeTransformFailed is a file a pre-ingestion transform failed on; it is moved to the quarantine folder
+	CodeTransformFailed ErrorCode = "GODOCS_TRANSFORM_FAILED"
+	// CodeReadOnly is a change refused because the server is in read-only mode for maintenance
+	CodeReadOnly ErrorCode = "GODOCS_READ_ONLY"
+	// CodeTooLarge is a request body over the size the endpoint accepts
+	CodeTooLarge ErrorCode = "GODOCS_TOO_LARGE"
+	// CodeRateLimited is a request refused because too many like it arrived recently; retry after a minute
+	CodeRateLimited ErrorCode = "GODOCS_RATE_LIMITED"
+	// CodeInternal is any other server failure
+	CodeInternal ErrorCode = "GODOCS_INTERNAL"
+)
+
+// ErrorResponse is the JSON error envelope returned by the API
+type ErrorResponse struct {
+	Error  string            `json:"error"`
+	Code   ErrorCode         `json:"code"`
+	Fields map[string]string `json:"fields,omitempty"` // with CodeValidation, the problem with each field
+}
+
+// JobItem is a file an ingestion job could not process cleanly, listed in the job result under "items"
+type JobItem struct {
+	File  string    `json:"file"`
+	Code  ErrorCode `json:"code"`
+	Error string    `json:"error"`
+}
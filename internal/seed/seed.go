@@ -0,0 +1,206 @@
+// Package seed generates synthetic documents for capacity and load testing.
+// Documents are written straight through the Repository, bypassing ingestion.
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/database"
+	"github.com/oklog/ulid/v2"
+)
+
+// batchSize is how many documents are saved per SaveDocumentBatch call
+const batchSize = 500
+
+// Options controls what Generate produces. The same Seed always gives the same documents.
+type Options struct {
+	Count    int       // number of documents to create
+	Root     string    // document root; folders are created beneath it
+	Folders  int       // number of distinct leaf folders to spread documents over
+	Seed     int64     // random seed
+	Start    time.Time // newest ingress time; older documents step back from it
+	Files    bool      // also create a sparse file of a plausible size at each document path
+	Progress func(done int)
+}
+
+var categories = []string{"bank", "utilities", "insurance", "tax", "medical", "receipts", "letters", "manuals", "payslips", "property"}
+
+var documentTypes = []string{".pdf", ".pdf", ".pdf", ".txt", ".png", ".tiff"}
+
+// vocabulary is sampled with a Zipf distribution so the word frequencies look like real documents
+var vocabulary = strings.Fields(`
+the of and to a in for is on that by this with you it not or be are from at as your all have
+account statement invoice total amount balance payment due date reference number customer
+policy premium cover claim renewal period annual monthly tax return income allowance
+electricity gas water meter reading tariff supplier energy bill charges credit debit
+hospital appointment doctor prescription patient clinic referral results treatment
+receipt purchase order delivery item quantity price vat subtotal discount refund warranty
+letter dear sincerely regards please contact enclosed information notice change address
+manual installation instructions warning safety model serial product guide operation
+salary payslip employer deductions pension national insurance net gross hours overtime
+property mortgage rent lease landlord tenant deposit council survey valuation completion
+january february march april may june july august september october november december
+bank transfer interest rate overdraft standing direct savings current card transaction
+`)
+
+// Generate creates opts.Count documents and returns how many were saved
+func Generate(db database.Repository, opts Options) (int, error) {
+	if opts.Count <= 0 {
+		return 0, nil
+	}
+	if opts.Folders <= 0 {
+		opts.Folders = 1
+	}
+	if opts.Start.IsZero() {
+		opts.Start = time.Now()
+	}
+	root := path.Clean(strings.ReplaceAll(opts.Root, "\\", "/"))
+	rng := rand.New(rand.NewSource(opts.Seed))
+	zipf := rand.NewZipf(rng, 1.2, 1, uint64(len(vocabulary)-1))
+
+	folders, err := createFolders(db, root, opts.Folders)
+	if err != nil {
+		return 0, err
+	}
+
+	tokenizer := database.NewWordTokenizer()
+	saved := 0
+	for saved < opts.Count {
+		n := min(batchSize, opts.Count-saved)
+		batch := make([]*database.Document, 0, n)
+		wordCounts := make(map[string]int)
+		for i := 0; i < n; i++ {
+			doc := newDocument(rng, zipf, folders, opts.Start, saved+i)
+			for word, count := range tokenizer.TokenizeAndCount(doc.FullText) {
+				wordCounts[word] += count
+			}
+			if opts.Files {
+				if err := writeFile(doc); err != nil {
+					return saved, err
+				}
+			}
+			batch = append(batch, doc)
+		}
+		if err := db.SaveDocumentBatch(batch, wordCounts); err != nil {
+			return saved, fmt.Errorf("unable to save batch at document %d: %w", saved, err)
+		}
+		saved += n
+		if opts.Progress != nil {
+			opts.Progress(saved)
+		}
+	}
+	return saved, nil
+}
+
+// createFolders records count leaf folders of the form <root>/<category>/<year>[/part-N] and their parents
+func createFolders(db database.Repository, root string, count int) ([]string, error) {
+	if _, err := db.EnsureFolder(root, ""); err != nil {
+		return nil, fmt.Errorf("unable to record root folder: %w", err)
+	}
+	recorded := map[string]bool{root: true}
+	ensure := func(folder string) error {
+		for _, f := range []string{path.Dir(path.Dir(folder)), path.Dir(folder), folder} {
+			if recorded[f] || !strings.HasPrefix(f, root+"/") {
+				continue
+			}
+			if _, err := db.EnsureFolder(f, path.Dir(f)); err != nil {
+				return fmt.Errorf("unable to record folder %s: %w", f, err)
+			}
+			recorded[f] = true
+		}
+		return nil
+	}
+
+	leaves := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		category := categories[i%len(categories)]
+		year := 2024 - (i/len(categories))%10
+		folder := fmt.Sprintf("%s/%s/%d", root, category, year)
+		if part := i / (len(categories) * 10); part > 0 {
+			folder = fmt.Sprintf("%s/part-%d", folder, part)
+		}
+		if err := ensure(folder); err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, folder)
+	}
+	return leaves, nil
+}
+
+// newDocument builds the i'th synthetic document; sizes range from a few lines to several pages of text
+func newDocument(rng *rand.Rand, zipf *rand.Zipf, folders []string, start time.Time, i int) *database.Document {
+	folder := folders[rng.Intn(len(folders))]
+	ext := documentTypes[rng.Intn(len(documentTypes))]
+	category := path.Base(path.Dir(folder))
+	if strings.HasPrefix(path.Base(folder), "part-") {
+		category = path.Base(path.Dir(path.Dir(folder)))
+	}
+	name := fmt.Sprintf("%s-%06d%s", category, i, ext)
+	ingressTime := start.Add(-time.Duration(i) * time.Minute)
+
+	var words int
+	switch r := rng.Intn(10); {
+	case r < 6:
+		words = 20 + rng.Intn(200)
+	case r < 9:
+		words = 200 + rng.Intn(800)
+	default:
+		words = 1000 + rng.Intn(4000)
+	}
+	var text strings.Builder
+	text.WriteString(category)
+	for w := 0; w < words; w++ {
+		if w%12 == 11 {
+			text.WriteString(".\n")
+		} else {
+			text.WriteByte(' ')
+		}
+		text.WriteString(vocabulary[zipf.Uint64()])
+	}
+
+	id := ulid.MustNew(ulid.Timestamp(ingressTime), rng)
+	size := int64(text.Len())
+	ocrStatus := database.OCRSkipped
+	if ext != ".txt" {
+		size *= 40 // scanned pages are much larger than their text
+		ocrStatus = database.OCRDone
+	}
+	return &database.Document{
+		Name:         name,
+		Path:         folder + "/" + name,
+		IngressTime:  ingressTime,
+		Folder:       folder,
+		Hash:         fmt.Sprintf("seed-%016x-%d", rng.Uint64(), i),
+		ULID:         id,
+		DocumentType: ext,
+		MIMEType:     database.MIMETypeByExtension(ext),
+		FullText:     text.String(),
+		Size:         size,
+		OCRStatus:    ocrStatus,
+		URL:          "/document/view/" + id.String(),
+	}
+}
+
+// writeFile creates a sparse file for doc so endpoints that stat the file see a realistic size
+// without the seed using that much disk
+func writeFile(doc *database.Document) error {
+	filePath := filepath.FromSlash(doc.Path)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("unable to create folder for %s: %w", doc.Path, err)
+	}
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", doc.Path, err)
+	}
+	err = file.Truncate(doc.Size)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
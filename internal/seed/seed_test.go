@@ -0,0 +1,61 @@
+package seed
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+	"github.com/drummonds/godocs/database"
+)
+
+func newTestRepository(t *testing.T) database.Repository {
+	t.Helper()
+	database.Logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	db := database.NewRepository(config.ServerConfig{DatabaseType: "sqlite", DatabaseDbname: filepath.Join(t.TempDir(), "seed.sqlite")})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestGenerateIsRepeatable(t *testing.T) {
+	// Two empty databases and the same options
+	root := t.TempDir()
+	opts := Options{Count: 1200, Root: root, Folders: 12, Seed: 7, Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Files: true}
+	first, second := newTestRepository(t), newTestRepository(t)
+
+	// Both are seeded
+	for _, db := range []database.Repository{first, second} {
+		saved, err := Generate(db, opts)
+		if err != nil || saved != opts.Count {
+			t.Fatalf("Generate saved %d documents: %v", saved, err)
+		}
+	}
+
+	// The documents, folders and sparse files match
+	a, err := first.GetNewestDocuments(opts.Count)
+	if err != nil {
+		t.Fatalf("Failed to list documents: %v", err)
+	}
+	b, err := second.GetNewestDocuments(opts.Count)
+	if err != nil || len(a) != opts.Count || len(b) != opts.Count {
+		t.Fatalf("Expected %d documents, got %d and %d (%v)", opts.Count, len(a), len(b), err)
+	}
+	for i := range a {
+		if a[i].Path != b[i].Path || a[i].Hash != b[i].Hash || a[i].ULID != b[i].ULID {
+			t.Fatalf("Document %d differs: %s vs %s", i, a[i].Path, b[i].Path)
+		}
+	}
+	if info, err := os.Stat(filepath.FromSlash(a[0].Path)); err != nil || info.Size() == 0 {
+		t.Errorf("Expected a sized file at %s: %v", a[0].Path, err)
+	}
+	folders, err := first.GetAllFolders()
+	if err != nil {
+		t.Fatalf("Failed to list folders: %v", err)
+	}
+	// root + 10 categories + 12 category/year leaves
+	if len(folders) != 23 {
+		t.Errorf("Expected 23 folders, got %d", len(folders))
+	}
+}
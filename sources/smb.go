@@ -0,0 +1,151 @@
+package sources
+
+import (
+	"io"
+	"net"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hirochachacha/go-smb2"
+)
+
+// smbDialTimeout bounds how long connecting to the SMB server may take
+const smbDialTimeout = 30 * time.Second
+
+// SMB pulls documents from an SMB/CIFS share without needing an OS level mount.
+// The session is kept open between polls and re-established when it stops responding.
+type SMB struct {
+	name       string
+	address    string
+	shareName  string
+	initiator  *smb2.NTLMInitiator
+	ingestRoot string
+
+	conn    net.Conn
+	session *smb2.Session
+	share   *smb2.Share
+}
+
+// NewSMB creates an SMB source for share on host (port 445 unless given as host:port),
+// reading from ingestRoot inside the share
+func NewSMB(name, host, share, user, password, domain, ingestRoot string) *SMB {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "445")
+	}
+	return &SMB{
+		name:       name,
+		address:    host,
+		shareName:  share,
+		initiator:  &smb2.NTLMInitiator{User: user, Password: password, Domain: domain},
+		ingestRoot: smbPath(ingestRoot),
+	}
+}
+
+// Name identifies the source
+func (s *SMB) Name() string {
+	return s.name
+}
+
+// List returns every file below the ingest folder
+func (s *SMB) List() ([]File, error) {
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	var files []File
+	if err := s.walk("", &files); err != nil {
+		s.disconnect()
+		return nil, err
+	}
+	return files, nil
+}
+
+// walk appends the files below the relative folder dir
+func (s *SMB) walk(dir string, files *[]File) error {
+	entries, err := s.share.ReadDir(s.remotePath(dir))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		relative := path.Join(dir, entry.Name())
+		if entry.IsDir() {
+			if err := s.walk(relative, files); err != nil {
+				return err
+			}
+			continue
+		}
+		*files = append(*files, File{Path: relative, Size: entry.Size(), ModTime: entry.ModTime()})
+	}
+	return nil
+}
+
+// Open streams a file from the ingest folder
+func (s *SMB) Open(file string) (io.ReadCloser, error) {
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s.share.Open(s.remotePath(file))
+}
+
+// Remove deletes a file from the ingest folder
+func (s *SMB) Remove(file string) error {
+	if err := s.connect(); err != nil {
+		return err
+	}
+	return s.share.Remove(s.remotePath(file))
+}
+
+// connect reuses the current session while the share still answers, otherwise opens a new one
+func (s *SMB) connect() error {
+	if s.share != nil {
+		if _, err := s.share.Stat(s.remotePath("")); err == nil {
+			return nil
+		}
+		s.disconnect()
+	}
+
+	conn, err := net.DialTimeout("tcp", s.address, smbDialTimeout)
+	if err != nil {
+		return err
+	}
+	dialer := &smb2.Dialer{Initiator: s.initiator}
+	session, err := dialer.Dial(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	share, err := session.Mount(s.shareName)
+	if err != nil {
+		session.Logoff()
+		conn.Close()
+		return err
+	}
+	s.conn, s.session, s.share = conn, session, share
+	Logger.Debug("Connected to SMB share", "source", s.name, "address", s.address, "share", s.shareName)
+	return nil
+}
+
+// disconnect closes the current session, ignoring errors from an already broken connection
+func (s *SMB) disconnect() {
+	if s.share != nil {
+		s.share.Umount()
+	}
+	if s.session != nil {
+		s.session.Logoff()
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.conn, s.session, s.share = nil, nil, nil
+}
+
+// remotePath joins a relative path onto the ingest folder
+func (s *SMB) remotePath(file string) string {
+	return smbPath(path.Join(s.ingestRoot, file))
+}
+
+// smbPath turns a configured folder into the relative form go-smb2 expects ("" for the share root)
+func smbPath(folder string) string {
+	folder = path.Clean("/" + strings.ReplaceAll(strings.TrimSpace(folder), `\`, "/"))
+	return strings.TrimPrefix(folder, "/")
+}
@@ -0,0 +1,55 @@
+// Package sources provides remote file stores that documents can be ingested from,
+// such as a Nextcloud WebDAV share or an SMB/CIFS share on a NAS.
+package sources
+
+import (
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/drummonds/godocs/config"
+)
+
+// Logger is global since we will need it everywhere
+var Logger *slog.Logger = slog.Default()
+
+// File is a file found on a remote source
+type File struct {
+	Path    string // slash separated, relative to the source's ingest folder
+	Size    int64
+	ModTime time.Time
+}
+
+// Source is a remote folder that documents are pulled from
+type Source interface {
+	// Name identifies the source in logs and job messages
+	Name() string
+	// List returns every file below the ingest folder, recursing into subfolders
+	List() ([]File, error)
+	// Open streams the content of a listed file
+	Open(path string) (io.ReadCloser, error)
+	// Remove deletes a listed file once it has been ingested
+	Remove(path string) error
+}
+
+// WriteBacker is implemented by sources that can store processed documents back on the remote side
+type WriteBacker interface {
+	// CanWriteBack reports whether a write-back folder is configured
+	CanWriteBack() bool
+	// WriteBack stores a document at path relative to the write-back folder, creating folders as needed
+	WriteBack(path string, content io.Reader) error
+}
+
+// FromConfig creates every remote source configured in the server config
+func FromConfig(serverConfig config.ServerConfig) []Source {
+	var configured []Source
+	if serverConfig.NextcloudURL != "" {
+		configured = append(configured, NewWebDAV("nextcloud", serverConfig.NextcloudURL, serverConfig.NextcloudUser,
+			serverConfig.NextcloudPassword, serverConfig.NextcloudIngestPath, serverConfig.NextcloudWriteBack))
+	}
+	if serverConfig.SMBHost != "" && serverConfig.SMBShare != "" {
+		configured = append(configured, NewSMB("smb", serverConfig.SMBHost, serverConfig.SMBShare, serverConfig.SMBUser,
+			serverConfig.SMBPassword, serverConfig.SMBDomain, serverConfig.SMBIngestPath))
+	}
+	return configured
+}
@@ -0,0 +1,44 @@
+package sources
+
+import (
+	"testing"
+
+	"github.com/drummonds/godocs/config"
+)
+
+func TestFromConfig(t *testing.T) {
+	// A config with both remote sources set up
+	serverConfig := config.ServerConfig{
+		NextcloudURL: "https://cloud.example.com/remote.php/dav/files/alice/",
+		SMBHost:      "nas.local",
+		SMBShare:     "scans",
+	}
+
+	configured := FromConfig(serverConfig)
+
+	// Both are returned, and none without configuration
+	if len(configured) != 2 || configured[0].Name() != "nextcloud" || configured[1].Name() != "smb" {
+		t.Fatalf("Unexpected sources %v", configured)
+	}
+	if smb := configured[1].(*SMB); smb.address != "nas.local:445" {
+		t.Errorf("Expected default SMB port, got %s", smb.address)
+	}
+	if configured := FromConfig(config.ServerConfig{SMBHost: "nas.local"}); len(configured) != 0 {
+		t.Errorf("Expected no sources without a share name, got %v", configured)
+	}
+}
+
+func TestSMBPath(t *testing.T) {
+	tests := map[string]string{
+		"":               "",
+		"/":              "",
+		`\Scans\Inbox\`:  "Scans/Inbox",
+		"Scans/../Inbox": "Inbox",
+		" /Scans/2024/ ": "Scans/2024",
+	}
+	for input, want := range tests {
+		if got := smbPath(input); got != want {
+			t.Errorf("smbPath(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package sources
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/studio-b12/gowebdav"
+)
+
+// WebDAV pulls documents from a WebDAV share such as Nextcloud's
+// (https://cloud.example.com/remote.php/dav/files/<user>/)
+type WebDAV struct {
+	name          string
+	client        *gowebdav.Client
+	ingestRoot    string
+	writeBackRoot string
+}
+
+// NewWebDAV creates a WebDAV source reading from ingestRoot and, when writeBackRoot is set,
+// writing processed documents below it
+func NewWebDAV(name, url, user, password, ingestRoot, writeBackRoot string) *WebDAV {
+	return &WebDAV{
+		name:          name,
+		client:        gowebdav.NewClient(url, user, password),
+		ingestRoot:    cleanRemotePath(ingestRoot),
+		writeBackRoot: writeBackRoot,
+	}
+}
+
+// Name identifies the source
+func (w *WebDAV) Name() string {
+	return w.name
+}
+
+// List walks the ingest folder and returns all files in it
+func (w *WebDAV) List() ([]File, error) {
+	var files []File
+	err := w.walk("", &files)
+	return files, err
+}
+
+// walk appends the files below the relative folder dir
+func (w *WebDAV) walk(dir string, files *[]File) error {
+	entries, err := w.client.ReadDir(path.Join(w.ingestRoot, dir))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		relative := path.Join(dir, entry.Name())
+		if entry.IsDir() {
+			if err := w.walk(relative, files); err != nil {
+				return err
+			}
+			continue
+		}
+		*files = append(*files, File{Path: relative, Size: entry.Size(), ModTime: entry.ModTime()})
+	}
+	return nil
+}
+
+// Open streams a file from the ingest folder
+func (w *WebDAV) Open(file string) (io.ReadCloser, error) {
+	return w.client.ReadStream(path.Join(w.ingestRoot, file))
+}
+
+// Remove deletes a file from the ingest folder
+func (w *WebDAV) Remove(file string) error {
+	return w.client.Remove(path.Join(w.ingestRoot, file))
+}
+
+// CanWriteBack reports whether a write-back folder is configured
+func (w *WebDAV) CanWriteBack() bool {
+	return w.writeBackRoot != ""
+}
+
+// WriteBack uploads a processed document below the write-back folder
+func (w *WebDAV) WriteBack(file string, content io.Reader) error {
+	target := path.Join(cleanRemotePath(w.writeBackRoot), file)
+	if err := w.client.MkdirAll(path.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	return w.client.WriteStream(target, content, 0644)
+}
+
+// cleanRemotePath normalises a configured remote folder to an absolute slash separated path
+func cleanRemotePath(folder string) string {
+	return path.Clean("/" + strings.TrimSpace(folder))
+}
@@ -0,0 +1,95 @@
+package sources
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+// newTestWebDAV starts an in-memory WebDAV server holding the given files
+func newTestWebDAV(t *testing.T, files map[string]string) (*WebDAV, webdav.FileSystem) {
+	t.Helper()
+	fs := webdav.NewMemFS()
+	ctx := context.Background()
+	for _, dir := range []string{"/Scans", "/Scans/sub"} {
+		if err := fs.Mkdir(ctx, dir, os.ModePerm); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	for name, content := range files {
+		file, err := fs.OpenFile(ctx, name, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		file.Write([]byte(content))
+		file.Close()
+	}
+	server := httptest.NewServer(&webdav.Handler{FileSystem: fs, LockSystem: webdav.NewMemLS()})
+	t.Cleanup(server.Close)
+	return NewWebDAV("nextcloud", server.URL, "alice", "secret", "Scans/", "/Processed"), fs
+}
+
+func TestWebDAVListAndRead(t *testing.T) {
+	// A share with files at the top level and in a subfolder
+	source, _ := newTestWebDAV(t, map[string]string{
+		"/Scans/a.pdf":     "first",
+		"/Scans/sub/b.pdf": "second",
+	})
+
+	// Listing the ingest folder
+	files, err := source.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	// Files are returned relative to the ingest folder, including nested ones
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	sort.Strings(paths)
+	if len(paths) != 2 || paths[0] != "a.pdf" || paths[1] != "sub/b.pdf" {
+		t.Fatalf("Unexpected files %v", paths)
+	}
+	content, err := source.Open("sub/b.pdf")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer content.Close()
+	if data, _ := io.ReadAll(content); string(data) != "second" {
+		t.Errorf("Unexpected content %q", data)
+	}
+}
+
+func TestWebDAVRemoveAndWriteBack(t *testing.T) {
+	// A share with one file and a write-back folder configured
+	source, fs := newTestWebDAV(t, map[string]string{"/Scans/a.pdf": "first"})
+
+	// The file is removed and a processed copy written back into a new folder
+	if err := source.Remove("a.pdf"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := source.WriteBack("2024/a.pdf", bytes.NewBufferString("processed")); err != nil {
+		t.Fatalf("WriteBack failed: %v", err)
+	}
+
+	// The ingest folder is empty and the copy exists under the write-back folder
+	ctx := context.Background()
+	if _, err := fs.Stat(ctx, "/Scans/a.pdf"); !os.IsNotExist(err) {
+		t.Errorf("Expected file to be removed, got %v", err)
+	}
+	file, err := fs.OpenFile(ctx, "/Processed/2024/a.pdf", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Written back file missing: %v", err)
+	}
+	defer file.Close()
+	if data, _ := io.ReadAll(file); string(data) != "processed" {
+		t.Errorf("Unexpected written back content %q", data)
+	}
+}
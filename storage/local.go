@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Local stores files in a folder on local disk, the document folder godocs has always used
+type Local struct {
+	Root string
+}
+
+// NewLocal stores files under the folder root
+func NewLocal(root string) *Local {
+	return &Local{Root: filepath.FromSlash(root)}
+}
+
+// path is the native path of key under the root
+func (l *Local) path(key string) string {
+	return filepath.Join(l.Root, filepath.FromSlash(key))
+}
+
+// Read opens the file stored under key
+func (l *Local) Read(key string) (io.ReadCloser, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	return os.Open(l.path(key))
+}
+
+// Write stores content under key, creating its folders as needed. A partly written file is removed.
+func (l *Local) Write(key string, content io.Reader) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	filePath := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filePath)
+		return err
+	}
+	return nil
+}
+
+// Delete removes the file stored under key, leaving its folder in place
+func (l *Local) Delete(key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(l.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Walk calls fn for every file under the folder prefix in lexical order. A prefix that does not exist has
+// no files; a folder that cannot be read is logged and skipped.
+func (l *Local) Walk(prefix string, fn func(Object) error) error {
+	if err := validPrefix(prefix); err != nil {
+		return err
+	}
+	start := l.Root
+	if prefix != "" {
+		start = l.path(prefix)
+	}
+	if _, err := os.Stat(start); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return filepath.WalkDir(start, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if filePath == start {
+				return err
+			}
+			Logger.Warn("Error accessing path during storage walk", "path", filePath, "error", err)
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			Logger.Warn("Error accessing path during storage walk", "path", filePath, "error", err)
+			return nil
+		}
+		rel, err := filepath.Rel(l.Root, filePath)
+		if err != nil {
+			return err
+		}
+		return fn(Object{Key: path.Clean(filepath.ToSlash(rel)), Size: info.Size(), ModTime: info.ModTime()})
+	})
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalBackend(t *testing.T) {
+	// An empty local store
+	root := t.TempDir()
+	backend := NewLocal(filepath.ToSlash(root))
+
+	// Files are written, one into a folder that does not exist yet
+	for key, content := range map[string]string{"bills/2024/scan.pdf": "%PDF scan", "notes.txt": "notes"} {
+		if err := backend.Write(key, strings.NewReader(content)); err != nil {
+			t.Fatalf("Write %s failed: %v", key, err)
+		}
+	}
+
+	// They are ordinary files under the root and read back by key
+	if _, err := os.Stat(filepath.Join(root, "bills", "2024", "scan.pdf")); err != nil {
+		t.Errorf("Expected the file under the root: %v", err)
+	}
+	reader, err := backend.Read("bills/2024/scan.pdf")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "%PDF scan" {
+		t.Errorf("Expected the stored content, got %q", content)
+	}
+
+	// Walking reports slash-separated keys, for the whole store or one folder
+	var all, bills []Object
+	backend.Walk("", func(object Object) error { all = append(all, object); return nil })
+	backend.Walk("bills", func(object Object) error { bills = append(bills, object); return nil })
+	if len(all) != 2 || all[0].Key != "bills/2024/scan.pdf" || all[0].Size != 9 || all[1].Key != "notes.txt" {
+		t.Errorf("Expected both files, got %+v", all)
+	}
+	if len(bills) != 1 || bills[0].Key != "bills/2024/scan.pdf" {
+		t.Errorf("Expected only the scan under bills, got %+v", bills)
+	}
+	if err := backend.Walk("missing", func(Object) error { return errors.New("called") }); err != nil {
+		t.Errorf("Expected a missing folder to have no files, got %v", err)
+	}
+
+	// A deleted file is not found and deleting it again is not an error
+	if err := backend.Delete("notes.txt"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := backend.Read("notes.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+	if err := backend.Delete("notes.txt"); err != nil {
+		t.Errorf("Expected deleting a missing file to succeed, got %v", err)
+	}
+}
+
+func TestLocalBackendRejectsKeysOutsideRoot(t *testing.T) {
+	backend := NewLocal(t.TempDir())
+	for _, key := range []string{"", ".", "../escape.pdf", "/etc/passwd", "bills//scan.pdf", "bills/"} {
+		if err := backend.Write(key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Write(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+	if err := backend.Walk("..", func(Object) error { return nil }); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Walk(..) = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestLocalWriteFailureLeavesNothing(t *testing.T) {
+	// The content fails part way through
+	root := t.TempDir()
+	backend := NewLocal(root)
+	err := backend.Write("scan.pdf", io.MultiReader(strings.NewReader("%PDF"), failingReader{}))
+
+	// The error is returned and no partial file is left
+	if err == nil {
+		t.Fatal("Expected the read error")
+	}
+	if _, statErr := os.Stat(filepath.Join(root, "scan.pdf")); !errors.Is(statErr, fs.ErrNotExist) {
+		t.Errorf("Expected no partial file, got %v", statErr)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("disk went away") }
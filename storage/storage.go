@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"time"
+)
+
+// Logger is global since we will need it everywhere
+var Logger *slog.Logger = slog.Default()
+
+// ErrInvalidKey is returned for a key that is empty, absolute, or climbs out of the store with ".."
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Object is a stored file as Walk reports it
+type Object struct {
+	Key     string // slash-separated path relative to the root of the store
+	Size    int64
+	ModTime time.Time
+}
+
+// Backend stores document files by key. Keys are slash-separated paths relative to the root of the store,
+// such as "bills/2024/scan.pdf", whatever the operating system or backend. Reading a missing key returns an
+// error that wraps fs.ErrNotExist; deleting one is not an error.
+type Backend interface {
+	// Read opens the file stored under key; the caller closes it
+	Read(key string) (io.ReadCloser, error)
+	// Write stores content under key, replacing any existing file. Nothing is left behind when it fails.
+	Write(key string, content io.Reader) error
+	// Delete removes the file stored under key
+	Delete(key string) error
+	// Walk calls fn for every file under the folder prefix, or every file in the store when prefix is "",
+	// stopping at the first error fn returns
+	Walk(prefix string, fn func(Object) error) error
+}
+
+// validKey checks a key names a file inside the store
+func validKey(key string) error {
+	if key == "." || !fs.ValidPath(key) {
+		return keyError(key)
+	}
+	return nil
+}
+
+// validPrefix checks a Walk prefix names a folder inside the store, "" being the whole store
+func validPrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	return validKey(prefix)
+}
+
+// keyError reports an invalid key in the form os functions use for a bad path
+func keyError(key string) error {
+	return &fs.PathError{Op: "storage", Path: key, Err: ErrInvalidKey}
+}
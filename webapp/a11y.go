@@ -0,0 +1,60 @@
+package webapp
+
+import (
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// statusMessage is a polite live region, so screen readers announce progress such as "Loading..."
+// without interrupting what is being read
+func statusMessage(class string, body ...app.UI) app.HTMLDiv {
+	return app.Div().Class(class).Role("status").Body(body...)
+}
+
+// alertMessage is an assertive live region for errors, announced as soon as it appears
+func alertMessage(body ...app.UI) app.HTMLDiv {
+	return app.Div().Class("error").Role("alert").Body(body...)
+}
+
+// focusElement moves keyboard focus to the element with the given ID once the current render has
+// been applied, so it works for elements that are only about to appear
+func focusElement(ctx app.Context, id string) {
+	ctx.Defer(func(ctx app.Context) {
+		if element := app.Window().GetElementByID(id); element.Truthy() {
+			element.Call("focus")
+		}
+	})
+}
+
+// moveHighlight moves the highlighted entry of a list of count options in response to an arrow key.
+// Positions count from 1, with 0 meaning nothing is highlighted; moving past either end wraps around.
+// It reports false for keys it does not handle.
+func moveHighlight(current, count int, key string) (int, bool) {
+	if count == 0 {
+		return 0, false
+	}
+	switch key {
+	case "ArrowDown":
+		return current%count + 1, true
+	case "ArrowUp":
+		if current <= 1 {
+			return count, true
+		}
+		return current - 1, true
+	}
+	return current, false
+}
+
+// treeToggleKey is the expanded state a folder toggle should have after a key press: Enter and Space
+// toggle it, the right arrow opens it and the left arrow closes it, as in the WAI-ARIA tree pattern.
+// It reports false for keys it does not handle.
+func treeToggleKey(key string, expanded bool) (bool, bool) {
+	switch key {
+	case "Enter", " ":
+		return !expanded, true
+	case "ArrowRight":
+		return true, true
+	case "ArrowLeft":
+		return false, true
+	}
+	return expanded, false
+}
@@ -0,0 +1,135 @@
+package webapp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+func TestMoveHighlight(t *testing.T) {
+	tests := []struct {
+		name            string
+		current, count  int
+		key             string
+		expected        int
+		expectedHandled bool
+	}{
+		{"down from nothing picks the first", 0, 3, "ArrowDown", 1, true},
+		{"down moves on", 1, 3, "ArrowDown", 2, true},
+		{"down from the last wraps to the first", 3, 3, "ArrowDown", 1, true},
+		{"up from nothing picks the last", 0, 3, "ArrowUp", 3, true},
+		{"up from the first wraps to the last", 1, 3, "ArrowUp", 3, true},
+		{"up moves back", 3, 3, "ArrowUp", 2, true},
+		{"other keys are left alone", 2, 3, "a", 2, false},
+		{"an empty list has nothing to highlight", 0, 0, "ArrowDown", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, handled := moveHighlight(tt.current, tt.count, tt.key)
+			if got != tt.expected || handled != tt.expectedHandled {
+				t.Errorf("moveHighlight(%d, %d, %q) = %d, %v, want %d, %v", tt.current, tt.count, tt.key, got, handled, tt.expected, tt.expectedHandled)
+			}
+		})
+	}
+}
+
+func TestTreeToggleKey(t *testing.T) {
+	tests := []struct {
+		key             string
+		expanded        bool
+		expected        bool
+		expectedHandled bool
+	}{
+		{"Enter", false, true, true},
+		{" ", true, false, true},
+		{"ArrowRight", false, true, true},
+		{"ArrowRight", true, true, true},
+		{"ArrowLeft", true, false, true},
+		{"Tab", true, true, false},
+	}
+	for _, tt := range tests {
+		got, handled := treeToggleKey(tt.key, tt.expanded)
+		if got != tt.expected || handled != tt.expectedHandled {
+			t.Errorf("treeToggleKey(%q, %v) = %v, %v, want %v, %v", tt.key, tt.expanded, got, handled, tt.expected, tt.expectedHandled)
+		}
+	}
+}
+
+func TestLiveRegions(t *testing.T) {
+	if html := app.HTMLString(statusMessage("loading", app.Text("Loading..."))); !strings.Contains(html, `role="status"`) || !strings.Contains(html, `class="loading"`) {
+		t.Errorf("Expected a status region, got %s", html)
+	}
+	if html := app.HTMLString(alertMessage(app.Text("Error: boom"))); !strings.Contains(html, `role="alert"`) || !strings.Contains(html, `class="error"`) {
+		t.Errorf("Expected an alert region, got %s", html)
+	}
+}
+
+func TestSearchCompletionsAreAListbox(t *testing.T) {
+	// Two word completions and a document, with the second word highlighted
+	page := &SearchPage{
+		searchTerm: "inv",
+		completions: SearchCompletions{
+			Prefix:    "inv",
+			Words:     []string{"invoice", "inventory"},
+			Documents: []SuggestedDocument{{Name: "Invoice.pdf", URL: "/document/view/1"}},
+		},
+		highlighted: 2,
+	}
+
+	html := app.HTMLString(page.renderCompletions())
+
+	// It is a listbox of options in order, with only the highlighted one selected
+	for _, want := range []string{`role="listbox"`, `id="search-completion-1"`, `id="search-completion-3"`, `role="option"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected %s in %s", want, html)
+		}
+	}
+	// The HTML encoder writes a true value as a bare attribute; the browser gets "true" from setAttribute
+	if selected := openingTag(html, `id="search-completion-2"`); strings.Count(html, `aria-selected="false"`) != 2 || !strings.Contains(selected, " aria-selected") || strings.Contains(selected, `aria-selected="false"`) {
+		t.Errorf("Expected only the second completion selected, got %s", html)
+	}
+	if completionID(page.highlighted) != "search-completion-2" || completionID(0) != "" {
+		t.Errorf("Unexpected completion IDs %q %q", completionID(page.highlighted), completionID(0))
+	}
+	if page.completionCount() != 3 {
+		t.Errorf("Expected 3 completions, got %d", page.completionCount())
+	}
+}
+
+func TestBrowseTreeRoles(t *testing.T) {
+	// An expanded root folder with a document in it
+	page := &BrowsePage{
+		expandedDirs: map[string]bool{"root": true},
+		fileSystem: FileSystem{FileSystem: []FileTreeNode{
+			{ID: "root", Name: "Documents", IsDir: true},
+			{ID: "doc", ParentID: "root", Name: "invoice.pdf"},
+		}},
+	}
+
+	html := app.HTMLString(page.renderNode(page.fileSystem.FileSystem[0], 0))
+
+	// The folder is an expanded tree item whose toggle is focusable, and the document sits in its group
+	for _, want := range []string{`role="treeitem"`, `aria-level="2"`, `role="group"`, `aria-label="Collapse Documents"`, `tabindex="0"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected %s in %s", want, html)
+		}
+	}
+	if folder := openingTag(html, `aria-level="1"`); !strings.Contains(folder, " aria-expanded") || strings.Contains(folder, `aria-expanded="false"`) {
+		t.Errorf("Expected the folder to be marked expanded, got %s", folder)
+	}
+	if document := openingTag(html, `aria-level="2"`); strings.Contains(document, "aria-expanded") {
+		t.Errorf("Expected a document not to be expandable, got %s", document)
+	}
+}
+
+// openingTag is the opening tag of the first element in html with the given attribute, whose order
+// the HTML encoder does not fix
+func openingTag(html, attribute string) string {
+	at := strings.Index(html, attribute)
+	if at < 0 {
+		return ""
+	}
+	start := strings.LastIndex(html[:at], "<")
+	return html[start : at+strings.Index(html[at:], ">")+1]
+}
@@ -3,22 +3,50 @@ package webapp
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/maxence-charriere/go-app/v10/pkg/app"
 )
 
 // AboutInfo represents the about information from the API
 type AboutInfo struct {
-	Version       string `json:"version"`
-	OCRConfigured bool   `json:"ocrConfigured"`
-	OCRPath       string `json:"ocrPath"`
-	DatabaseType  string `json:"databaseType"`
-	DatabaseHost  string `json:"databaseHost"`
-	DatabasePort  string `json:"databasePort"`
-	DatabaseName  string `json:"databaseName"`
-	IsEphemeral   bool   `json:"isEphemeral"`
-	IngressPath   string `json:"ingressPath"`
-	DocumentPath  string `json:"documentPath"`
+	Version       string          `json:"version"`
+	OCRConfigured bool            `json:"ocrConfigured"`
+	OCRPath       string          `json:"ocrPath"`
+	DatabaseType  string          `json:"databaseType"`
+	DatabaseHost  string          `json:"databaseHost"`
+	DatabasePort  string          `json:"databasePort"`
+	DatabaseName  string          `json:"databaseName"`
+	IsEphemeral   bool            `json:"isEphemeral"`
+	IngressPath   string          `json:"ingressPath"`
+	DocumentPath  string          `json:"documentPath"`
+	Extensions    []string        `json:"extensions"`
+	Services      []ServiceStatus `json:"services"`
+	Build         BuildInfo       `json:"build"`
+	Update        *UpdateStatus   `json:"update"` // only when the server checks for releases
+}
+
+// BuildInfo is the metadata of the server binary reported by /api/about
+type BuildInfo struct {
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"goVersion"`
+	Modified  bool   `json:"modified"`
+}
+
+// UpdateStatus is the result of the server's last release check
+type UpdateStatus struct {
+	Available bool   `json:"available"`
+	Latest    string `json:"latest"`
+	URL       string `json:"url"`
+}
+
+// ServiceStatus is the health of a sidecar service reported by /api/about
+type ServiceStatus struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error"`
 }
 
 // AboutPage displays information about the application
@@ -93,11 +121,20 @@ func (a *AboutPage) Render() app.UI {
 
 	return app.Div().Class("about-page").Body(
 		app.H2().Text("About godocs"),
+		app.If(a.aboutInfo.Update != nil && a.aboutInfo.Update.Available, func() app.UI {
+			return app.Div().Class("update-available").Body(
+				app.Text("Update available: "),
+				app.A().Href(a.aboutInfo.Update.URL).Target("_blank").Rel("noopener").Text(a.aboutInfo.Update.Latest),
+			)
+		}),
 		app.Div().Class("about-content").Body(
 			app.Div().Class("about-section").Body(
 				app.H3().Text("Application Information"),
 				app.Div().Class("info-grid").Body(
 					a.renderInfoItem("Version", a.aboutInfo.Version),
+					a.renderInfoItem("Commit", a.getCommit()),
+					a.renderInfoItem("Build Date", a.aboutInfo.Build.Date),
+					a.renderInfoItem("Go Version", a.aboutInfo.Build.GoVersion),
 					a.renderInfoItem("Database", a.getDatabaseDisplay()),
 					a.renderInfoItem("OCR Status", a.getOCRStatus()),
 				),
@@ -142,6 +179,9 @@ func (a *AboutPage) Render() app.UI {
 					}),
 				),
 			),
+			app.If(len(a.aboutInfo.Services) > 0, func() app.UI {
+				return a.renderServices()
+			}),
 			app.Div().Class("about-section").Body(
 				app.H3().Text("Document Storage"),
 				app.Div().Class("config-details").Body(
@@ -153,6 +193,10 @@ func (a *AboutPage) Render() app.UI {
 						app.Strong().Text("Ingestion Folder: "),
 						app.Text(a.aboutInfo.IngressPath),
 					),
+					app.P().Body(
+						app.Strong().Text("Accepted File Types: "),
+						app.Text(strings.Join(a.aboutInfo.Extensions, " ")),
+					),
 				),
 			),
 			app.Div().Class("about-section").Body(
@@ -164,6 +208,25 @@ func (a *AboutPage) Render() app.UI {
 	)
 }
 
+// renderServices lists the sidecar services and whether they are reachable
+func (a *AboutPage) renderServices() app.UI {
+	items := make([]app.UI, 0, len(a.aboutInfo.Services))
+	for _, service := range a.aboutInfo.Services {
+		status, class := "Healthy", "service-healthy"
+		if !service.Healthy {
+			status, class = "Unavailable: "+service.Error, "service-unhealthy"
+		}
+		items = append(items, app.P().Body(
+			app.Strong().Text(service.Name+" ("+service.URL+"): "),
+			app.Span().Class(class).Text(status),
+		))
+	}
+	return app.Div().Class("about-section").Body(
+		app.H3().Text("Sidecar Services"),
+		app.Div().Class("config-details").Body(items...),
+	)
+}
+
 // renderInfoItem creates an info item display
 func (a *AboutPage) renderInfoItem(label, value string) app.UI {
 	return app.Div().Class("info-item").Body(
@@ -172,6 +235,21 @@ func (a *AboutPage) renderInfoItem(label, value string) app.UI {
 	)
 }
 
+// getCommit returns the short commit hash, marked when built from uncommitted changes
+func (a *AboutPage) getCommit() string {
+	commit := a.aboutInfo.Build.Commit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	if commit == "" {
+		return "unknown"
+	}
+	if a.aboutInfo.Build.Modified {
+		commit += " (modified)"
+	}
+	return commit
+}
+
 // getDatabaseDisplay returns a user-friendly database display name
 func (a *AboutPage) getDatabaseDisplay() string {
 	switch a.aboutInfo.DatabaseType {
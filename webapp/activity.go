@@ -0,0 +1,157 @@
+package webapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// ActivityItem is one entry of the activity feed
+type ActivityItem = dto.ActivityItem
+
+// activityWidgetSize is how many entries the home page widget loads at a time
+const activityWidgetSize = 10
+
+// ActivityWidget lists recent activity on the home page: documents added, moved and deleted, settings
+// changed and jobs finished, with a button to read further back
+type ActivityWidget struct {
+	app.Compo
+	items      []ActivityItem
+	nextCursor string
+	hasNext    bool
+	loading    bool
+	error      string
+}
+
+// OnMount loads the newest entries
+func (a *ActivityWidget) OnMount(ctx app.Context) {
+	a.fetch(ctx, "")
+}
+
+// fetch loads a page of the feed after cursor, appending it to what is shown
+func (a *ActivityWidget) fetch(ctx app.Context, cursor string) {
+	a.loading = true
+	a.error = ""
+	query := url.Values{"limit": {fmt.Sprint(activityWidgetSize)}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	app.Window().Call("fetch", BuildAPIURL("/api/activity?"+query.Encode())).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+		if len(args) == 0 {
+			return nil
+		}
+		status := args[0].Get("status").Int()
+		args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+			ctx.Dispatch(func(ctx app.Context) {
+				a.loading = false
+				if status < 200 || status >= 300 {
+					a.error = fmt.Sprintf("Failed to load activity (status %d)", status)
+					return
+				}
+				var feed dto.ActivityFeed
+				if err := json.Unmarshal([]byte(jsonStr), &feed); err != nil {
+					a.error = fmt.Sprintf("Failed to parse activity: %v", err)
+					return
+				}
+				a.items = append(a.items, feed.Items...)
+				a.hasNext, a.nextCursor = feed.HasNext, feed.NextCursor
+			})
+			return nil
+		}))
+		return nil
+	})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+		ctx.Dispatch(func(ctx app.Context) {
+			a.loading = false
+			a.error = "Network error: Could not connect to server"
+		})
+		return nil
+	}))
+}
+
+// activityIcons marks each kind of entry
+var activityIcons = map[string]string{
+	"document_added":   "📄",
+	"document_moved":   "📁",
+	"document_deleted": "🗑",
+	"settings_changed": "⚙",
+	"job":              "⏱",
+}
+
+// formatActivityTime shows when an entry happened in the browser's local time
+func formatActivityTime(at string) string {
+	t, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return at
+	}
+	return t.Local().Format("2 Jan 15:04")
+}
+
+// renderActivityItem shows one entry, linking to the document or the jobs page where there is one
+func renderActivityItem(item ActivityItem) app.UI {
+	var summary app.UI = app.Text(item.Summary)
+	switch {
+	case item.DocumentULID != "" && item.Kind != "document_deleted":
+		summary = app.A().Href(BuildAPIURL("/document/view/" + item.DocumentULID)).Target("_blank").Text(item.Summary)
+	case item.JobID != "":
+		summary = app.A().Href("/jobs").Text(item.Summary)
+	}
+	class := "activity-item activity-" + item.Kind
+	if item.Status != "" {
+		class += " activity-" + item.Status
+	}
+	return app.Li().Class(class).Body(
+		app.Span().Class("activity-icon").Aria("hidden", true).Text(activityIcons[item.Kind]),
+		app.Span().Class("activity-summary").Body(summary),
+		app.If(item.User != "", func() app.UI {
+			return app.Span().Class("activity-user").Text(" by " + item.User)
+		}),
+		app.Time().Class("activity-time").DateTime(item.At).Text(formatActivityTime(item.At)),
+	)
+}
+
+// Render renders the activity widget
+func (a *ActivityWidget) Render() app.UI {
+	var body app.UI
+	switch {
+	case a.error != "" && len(a.items) == 0:
+		body = alertMessage(app.Text(a.error))
+	case a.loading && len(a.items) == 0:
+		body = statusMessage("loading", app.Text("Loading..."))
+	case len(a.items) == 0:
+		body = app.P().Class("no-results").Text("Nothing has happened yet.")
+	default:
+		body = app.Ul().Class("activity-list").Body(
+			app.Range(a.items).Slice(func(i int) app.UI {
+				return renderActivityItem(a.items[i])
+			}),
+		)
+	}
+
+	return app.Section().
+		Class("activity-widget").
+		Aria("labelledby", "activity-heading").
+		Body(
+			app.H3().ID("activity-heading").Text("Recent Activity"),
+			body,
+			app.If(a.error != "" && len(a.items) > 0, func() app.UI {
+				return alertMessage(app.Text(a.error))
+			}),
+			app.If(a.hasNext, func() app.UI {
+				text := "Show more"
+				if a.loading {
+					text = "Loading..."
+				}
+				return app.Button().Class("btn btn-secondary activity-more").Disabled(a.loading).Text(text).OnClick(func(ctx app.Context, e app.Event) {
+					a.fetch(ctx, a.nextCursor)
+				})
+			}),
+		)
+}
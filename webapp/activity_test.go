@@ -0,0 +1,30 @@
+package webapp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+func TestRenderActivityItem(t *testing.T) {
+	t.Run("A moved document links to it and names who moved it", func(t *testing.T) {
+		html := app.HTMLString(renderActivityItem(ActivityItem{Kind: "document_moved", Summary: "Moved bill.pdf to /finance", DocumentULID: "01ARZ3NDEKTSV4RRFFQ69G5FAV", User: "alice", At: "2024-06-01T12:00:00Z"}))
+		if !strings.Contains(html, `href="/document/view/01ARZ3NDEKTSV4RRFFQ69G5FAV"`) || !strings.Contains(html, "by alice") {
+			t.Errorf("Expected a document link and the user, got %s", html)
+		}
+	})
+
+	t.Run("A deleted document is not linked", func(t *testing.T) {
+		if html := app.HTMLString(renderActivityItem(ActivityItem{Kind: "document_deleted", Summary: "Deleted bill.pdf", DocumentULID: "01ARZ3NDEKTSV4RRFFQ69G5FAV"})); strings.Contains(html, "href") {
+			t.Errorf("Expected no link to a deleted document, got %s", html)
+		}
+	})
+
+	t.Run("A failed job links to the jobs page and is marked failed", func(t *testing.T) {
+		html := app.HTMLString(renderActivityItem(ActivityItem{Kind: "job", Summary: "Backup job failed: disk full", JobID: "01ARZ3NDEKTSV4RRFFQ69G5FAV", Status: "failed"}))
+		if !strings.Contains(html, `href="/jobs"`) || !strings.Contains(html, "activity-failed") {
+			t.Errorf("Expected a failed job linking to the jobs page, got %s", html)
+		}
+	})
+}
@@ -9,8 +9,63 @@ type App struct {
 	app.Compo
 }
 
-// Render renders the app
+// OnMount sends a browser that is not signed in to the login page, and a new installation to the setup wizard
+func (a *App) OnMount(ctx app.Context) {
+	path := app.Window().URL().Path
+	if path == "/login" {
+		return
+	}
+	a.requireLogin(ctx)
+	if path == "/setup" {
+		return
+	}
+	ctx.Async(func() {
+		res := app.Window().Call("fetch", BuildAPIURL("/api/setup"))
+		res.Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 || !args[0].Get("ok").Bool() {
+				return nil
+			}
+			args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+				if len(args) > 0 && args[0].Get("required").Truthy() {
+					ctx.Dispatch(func(ctx app.Context) {
+						ctx.Navigate("/setup")
+					})
+				}
+				return nil
+			}))
+			return nil
+		}))
+	})
+}
+
+// requireLogin navigates to the login page when the server requires signing in and nobody is
+func (a *App) requireLogin(ctx app.Context) {
+	ctx.Async(func() {
+		res := app.Window().Call("fetch", BuildAPIURL("/api/auth/status"), map[string]any{"credentials": "include"})
+		res.Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 || !args[0].Get("ok").Bool() {
+				return nil
+			}
+			args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+				if len(args) > 0 && args[0].Get("authRequired").Truthy() && !args[0].Get("username").Truthy() {
+					ctx.Dispatch(func(ctx app.Context) {
+						ctx.Navigate("/login")
+					})
+				}
+				return nil
+			}))
+			return nil
+		}))
+	})
+}
+
+// Render renders the app, or only the sign-in form on the login page
 func (a *App) Render() app.UI {
+	if app.Window().URL().Path == "/login" {
+		return app.Div().Class("app-container").Body(
+			app.Main().Class("main-content").Body(&LoginPage{}),
+		)
+	}
 	return app.Div().
 		Class("app-container").
 		Body(
@@ -21,10 +76,13 @@ func (a *App) Render() app.UI {
 				&Sidebar{},
 				app.Main().Class("main-content").Body(
 					app.Div().Class("content").Body(
+						&ReadOnlyBanner{},
+						&RejectionBanner{},
 						a.renderPage(),
 					),
 				),
 			),
+			&BottomNav{},
 		)
 }
 
@@ -45,8 +103,18 @@ func (a *App) renderPage() app.UI {
 		return &WordCloudPage{}
 	case "/jobs":
 		return &JobsPage{}
+	case "/stats":
+		return &StatsPage{}
 	case "/about":
 		return &AboutPage{}
+	case "/setup":
+		return &SetupPage{}
+	case "/collection":
+		return &CollectionPage{}
+	case "/scan":
+		return &ScanPage{}
+	case "/capture":
+		return &CapturePage{}
 	default:
 		return &NotFoundPage{}
 	}
@@ -0,0 +1,57 @@
+package webapp
+
+import (
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// bottomNavItems are the core routes, the ones wanted when checking a document quickly on a phone
+var bottomNavItems = []navItem{
+	{"🏠", "Home", "/"},
+	{"📁", "Browse", "/browse"},
+	{"🔍", "Search", "/search"},
+	{"📷", "Scan", "/scan"},
+	{"⚙️", "Jobs", "/jobs"},
+}
+
+// BottomNav is the navigation bar along the bottom of the screen, within thumb reach. It is only
+// shown on phone-sized screens, where it stands in for the navbar links.
+type BottomNav struct {
+	app.Compo
+	path string
+}
+
+// OnMount is called when the component is mounted
+func (b *BottomNav) OnMount(ctx app.Context) {
+	b.path = ctx.Page().URL().Path
+}
+
+// OnNav is called when navigation occurs
+func (b *BottomNav) OnNav(ctx app.Context) {
+	b.path = ctx.Page().URL().Path
+}
+
+// Render renders the bottom navigation bar
+func (b *BottomNav) Render() app.UI {
+	return app.Nav().
+		Class("bottom-nav").
+		Aria("label", "Quick navigation").
+		Body(
+			app.Range(bottomNavItems).Slice(func(i int) app.UI {
+				item := bottomNavItems[i]
+				class := "bottom-nav-item"
+				current := ""
+				if b.path == item.href {
+					class += " bottom-nav-item-active"
+					current = "page"
+				}
+				return app.A().
+					Href(item.href).
+					Class(class).
+					Aria("current", current).
+					Body(
+						app.Span().Class("bottom-nav-icon").Aria("hidden", true).Text(item.icon),
+						app.Span().Class("bottom-nav-label").Text(item.label),
+					)
+			}),
+		)
+}
@@ -0,0 +1,45 @@
+package webapp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+func TestBottomNavMarksCurrentRoute(t *testing.T) {
+	// The search page is showing
+	nav := &BottomNav{path: "/search"}
+
+	// Rendering the bottom navigation
+	html := app.HTMLString(nav.Render())
+
+	// Every core route is linked, and only search is marked as the current page
+	for _, item := range bottomNavItems {
+		if !strings.Contains(html, `href="`+item.href+`"`) {
+			t.Errorf("Expected a link to %s in %s", item.href, html)
+		}
+	}
+	if strings.Count(html, `aria-current="page"`) != 1 || !strings.Contains(openingTag(html, `href="/search"`), `aria-current="page"`) {
+		t.Errorf("Expected only search to be current, got %s", html)
+	}
+}
+
+func TestSidebarBackdrop(t *testing.T) {
+	t.Run("A closed sidebar has no backdrop to catch taps", func(t *testing.T) {
+		closed := app.HTMLString((&Sidebar{path: "/"}).Render())
+		if strings.Contains(closed, "sidebar-backdrop") || strings.Contains(closed, "sidebar-open") {
+			t.Errorf("Expected no backdrop while closed, got %s", closed)
+		}
+	})
+
+	t.Run("An open sidebar slides in over a backdrop, and marks the current page", func(t *testing.T) {
+		open := app.HTMLString((&Sidebar{isOpen: true, path: "/browse"}).Render())
+		if !strings.Contains(open, "sidebar-backdrop") || !strings.Contains(open, "sidebar-open") {
+			t.Errorf("Expected an open sidebar with a backdrop, got %s", open)
+		}
+		if !strings.Contains(openingTag(open, `href="/browse"`), `aria-current="page"`) {
+			t.Errorf("Expected browse to be current, got %s", open)
+		}
+	})
+}
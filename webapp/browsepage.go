@@ -3,30 +3,17 @@ package webapp
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 
+	"github.com/drummonds/godocs/internal/dto"
 	"github.com/maxence-charriere/go-app/v10/pkg/app"
 )
 
-// FileTreeNode represents a node in the file tree
-type FileTreeNode struct {
-	ID          string   `json:"id"`
-	ULID        string   `json:"ulid"`
-	Name        string   `json:"name"`
-	Size        int64    `json:"size"`
-	ModDate     string   `json:"modDate"`
-	Openable    bool     `json:"openable"`
-	ParentID    string   `json:"parentID"`
-	IsDir       bool     `json:"isDir"`
-	ChildrenIDs []string `json:"childrenIDs"`
-	FullPath    string   `json:"fullPath"`
-	FileURL     string   `json:"fileURL"`
-}
+// FileTreeNode is a folder or document in the tree returned by the API
+type FileTreeNode = dto.FileTreeNode
 
-// FileSystem represents the API response
-type FileSystem struct {
-	FileSystem []FileTreeNode `json:"fileSystem"`
-	Error      string         `json:"error"`
-}
+// FileSystem is the document tree returned by the filesystem and search endpoints
+type FileSystem = dto.FileSystem
 
 // BrowsePage displays the document file tree
 type BrowsePage struct {
@@ -36,6 +23,19 @@ type BrowsePage struct {
 	loading      bool
 	error        string
 	expandedDirs map[string]bool
+
+	// "Show all in this branch" view of every document under one folder
+	branch        *FileTreeNode
+	branchDocs    []Document
+	branchLoading bool
+	branchError   string
+
+	// Tag chips above the tree; choosing one lists the documents carrying it instead
+	tags       []dto.Tag
+	activeTag  string
+	tagDocs    []Document
+	tagLoading bool
+	tagError   string
 }
 
 // OnMount is called when the component is mounted
@@ -43,6 +43,7 @@ func (b *BrowsePage) OnMount(ctx app.Context) {
 	b.loading = true
 	b.expandedDirs = make(map[string]bool)
 	b.fetchFileSystem(ctx)
+	b.fetchTags(ctx)
 }
 
 // fetchFileSystem fetches the file tree from the API
@@ -92,11 +93,245 @@ func (b *BrowsePage) fetchFileSystem(ctx app.Context) {
 	})
 }
 
+// fetchTags loads the tags for the filter chips; without any the chips are not shown
+func (b *BrowsePage) fetchTags(ctx app.Context) {
+	ctx.Async(func() {
+		app.Window().Call("fetch", BuildAPIURL("/api/tags")).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 || !args[0].Get("ok").Bool() {
+				return nil
+			}
+			args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+				if len(args) == 0 {
+					return nil
+				}
+				jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+				var tags []dto.Tag
+				if err := json.Unmarshal([]byte(jsonStr), &tags); err != nil {
+					app.Log("Failed to parse tags:", err)
+					return nil
+				}
+				ctx.Dispatch(func(ctx app.Context) {
+					b.tags = tags
+				})
+				return nil
+			}))
+			return nil
+		}))
+	})
+}
+
+// selectTag lists the documents carrying a tag, or goes back to the tree when it is already chosen
+func (b *BrowsePage) selectTag(ctx app.Context, tag string) {
+	if b.activeTag == tag {
+		b.activeTag = ""
+		return
+	}
+	b.activeTag = tag
+	b.tagDocs = nil
+	b.tagLoading = true
+	b.tagError = ""
+
+	ctx.Async(func() {
+		res := app.Window().Call("fetch", BuildAPIURL("/api/tags/"+url.PathEscape(tag)+"/documents"))
+
+		res.Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			status := args[0].Get("status").Int()
+			args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+				if len(args) == 0 {
+					return nil
+				}
+				jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+				ctx.Dispatch(func(ctx app.Context) {
+					if b.activeTag != tag {
+						return // another chip was chosen meanwhile
+					}
+					b.tagLoading = false
+					if status < 200 || status >= 300 {
+						b.tagError = fmt.Sprintf("Failed to load tagged documents (status %d)", status)
+						return
+					}
+					var docs []Document
+					if err := json.Unmarshal([]byte(jsonStr), &docs); err != nil {
+						b.tagError = fmt.Sprintf("Failed to parse response: %v", err)
+						return
+					}
+					b.tagDocs = docs
+				})
+				return nil
+			}))
+			return nil
+		})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+			ctx.Dispatch(func(ctx app.Context) {
+				b.tagLoading = false
+				b.tagError = "Network error"
+			})
+			return nil
+		}))
+	})
+}
+
+// renderTagChips renders a toggle chip for each tag with its document count, the chosen one pressed
+func renderTagChips(tags []dto.Tag, active string, onSelect func(tag string) app.EventHandler) app.UI {
+	if len(tags) == 0 {
+		return nil
+	}
+	return app.Div().Class("tag-chips").Role("toolbar").Aria("label", "Filter by tag").Body(
+		app.Range(tags).Slice(func(i int) app.UI {
+			tag := tags[i]
+			chip := app.Button().
+				Class("tag-chip").
+				Aria("pressed", tag.Name == active).
+				Text(fmt.Sprintf("%s (%d)", tag.Name, tag.DocumentCount)).
+				OnClick(onSelect(tag.Name))
+			if tag.Name == active {
+				chip = chip.Class("tag-chip-active")
+			}
+			return chip
+		}),
+	)
+}
+
+// renderTagDocuments renders the documents carrying the chosen tag
+func (b *BrowsePage) renderTagDocuments() app.UI {
+	switch {
+	case b.tagLoading:
+		return statusMessage("loading", app.Text("Loading..."))
+	case b.tagError != "":
+		return alertMessage(app.Text("Error: " + b.tagError))
+	case len(b.tagDocs) == 0:
+		return app.Div().Class("no-results").Text("No documents tagged " + b.activeTag + ".")
+	}
+	return app.Ul().Class("branch-list").Aria("label", "Documents tagged "+b.activeTag).Body(
+		app.Range(b.tagDocs).Slice(func(i int) app.UI {
+			doc := b.tagDocs[i]
+			return app.Li().Body(
+				app.A().Href(doc.URL).Target("_blank").Text(doc.Name),
+				app.Span().Class("branch-folder").Text(" "+doc.Folder),
+			)
+		}),
+	)
+}
+
 // toggleDir toggles a directory's expanded state
 func (b *BrowsePage) toggleDir(ctx app.Context, id string) {
 	b.expandedDirs[id] = !b.expandedDirs[id]
 }
 
+// branchButtonID is the element ID of a folder's "Show all" button, which gets focus back when its
+// branch view is closed
+func branchButtonID(node FileTreeNode) string {
+	return "show-all-" + node.ID
+}
+
+// closeBranch hides the branch view and returns focus to the button that opened it
+func (b *BrowsePage) closeBranch(ctx app.Context) {
+	if b.branch == nil {
+		return
+	}
+	focusElement(ctx, branchButtonID(*b.branch))
+	b.branch = nil
+}
+
+// showBranch lists every document under a folder, including its subfolders
+func (b *BrowsePage) showBranch(ctx app.Context, node FileTreeNode) {
+	b.branch = &node
+	b.branchDocs = nil
+	b.branchLoading = true
+	b.branchError = ""
+	focusElement(ctx, "branch-heading")
+
+	ctx.Async(func() {
+		res := app.Window().Call("fetch", BuildAPIURL("/api/folder/"+url.PathEscape(node.FullPath)+"?recursive=true"))
+
+		res.Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			response := args[0]
+			status := response.Get("status").Int()
+
+			response.Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+				if len(args) == 0 {
+					return nil
+				}
+				jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+
+				ctx.Dispatch(func(ctx app.Context) {
+					b.branchLoading = false
+					if status < 200 || status >= 300 {
+						b.branchError = fmt.Sprintf("Failed to load folder (status %d)", status)
+						return
+					}
+					var docs []Document
+					if err := json.Unmarshal([]byte(jsonStr), &docs); err != nil {
+						b.branchError = fmt.Sprintf("Failed to parse response: %v", err)
+						return
+					}
+					b.branchDocs = docs
+				})
+				return nil
+			}))
+			return nil
+		})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+			ctx.Dispatch(func(ctx app.Context) {
+				b.branchLoading = false
+				b.branchError = "Network error"
+			})
+			return nil
+		}))
+	})
+}
+
+// renderBranch renders the documents of the selected branch
+func (b *BrowsePage) renderBranch() app.UI {
+	var body app.UI
+	switch {
+	case b.branchLoading:
+		body = statusMessage("loading", app.Text("Loading..."))
+	case b.branchError != "":
+		body = alertMessage(app.Text("Error: " + b.branchError))
+	case len(b.branchDocs) == 0:
+		body = app.Div().Class("no-results").Text("No documents in this branch.")
+	default:
+		body = app.Ul().Class("branch-list").Body(
+			app.Range(b.branchDocs).Slice(func(i int) app.UI {
+				doc := b.branchDocs[i]
+				return app.Li().Body(
+					app.A().Href(doc.URL).Target("_blank").Text(doc.Name),
+					app.Span().Class("branch-folder").Text(" "+doc.Folder),
+				)
+			}),
+		)
+	}
+
+	return app.Div().
+		Class("branch-view").
+		Role("region").
+		Aria("labelledby", "branch-heading").
+		OnKeyDown(func(ctx app.Context, e app.Event) {
+			if e.Get("key").String() == "Escape" {
+				b.closeBranch(ctx)
+			}
+		}).
+		Body(
+			app.Div().Class("branch-header").Body(
+				// Focused when the view opens, so keyboard and screen reader users land on it
+				app.H3().ID("branch-heading").TabIndex(-1).Text(fmt.Sprintf("All documents in %s (%d)", b.branch.Name, len(b.branchDocs))),
+				app.A().
+					Class("btn btn-secondary").
+					Href(BuildAPIURL("/api/folder/"+url.PathEscape(b.branch.FullPath)+"/download?recursive=true")).
+					Text("Download zip"),
+				app.Button().Class("btn btn-secondary").Text("Close").OnClick(func(ctx app.Context, e app.Event) {
+					b.closeBranch(ctx)
+				}),
+			),
+			body,
+		)
+}
+
 // getChildren returns the children of a node
 func (b *BrowsePage) getChildren(parentID string) []FileTreeNode {
 	var children []FileTreeNode
@@ -115,7 +350,11 @@ func (b *BrowsePage) renderNode(node FileTreeNode, depth int) app.UI {
 
 	iconText := "📄"
 	if node.IsDir {
-		if isExpanded {
+		if node.SmartFolder != "" {
+			iconText = "🔍" // a saved query, not a real folder
+		} else if node.Icon != "" {
+			iconText = node.Icon
+		} else if isExpanded {
 			iconText = "📂"
 		} else {
 			iconText = "📁"
@@ -133,31 +372,75 @@ func (b *BrowsePage) renderNode(node FileTreeNode, depth int) app.UI {
 	if !node.IsDir && node.Size > 0 {
 		sizeUI = app.Span().Class("tree-node-size").Text(fmt.Sprintf(" (%s)", formatBytes(node.Size)))
 	}
+	if node.Archived {
+		sizeUI = app.Span().Class("tree-node-archived").Title("In cold storage; restore it from search or the archive API to open it").Text("archived")
+	}
+	if node.Missing {
+		sizeUI = app.Span().Class("tree-node-missing").Title("The file is gone from document storage; a cleanup run removes the entry").Text("file missing")
+	}
 
 	var childrenUI app.UI
 	if node.IsDir && isExpanded && len(children) > 0 {
-		childrenUI = app.Div().Class("tree-node-children").Body(
+		childrenUI = app.Div().Class("tree-node-children").Role("group").Body(
 			app.Range(children).Slice(func(i int) app.UI {
 				return b.renderNode(children[i], depth+1)
 			}),
 		)
 	}
 
-	return app.Div().
-		Class("tree-node").
+	content := app.Div().Class("tree-node-content")
+	if node.Color != "" {
+		// The folder's colour label, so folders such as Finance and Medical stand apart
+		content = content.Class("tree-node-labelled").Style("border-left-color", node.Color)
+	}
+
+	// Folder icons are the expand/collapse toggles, so they take focus and answer to the keyboard
+	icon := app.Span().Class("tree-node-icon").Text(iconText)
+	item := app.Div().Class("tree-node").Role("treeitem").Aria("level", depth+1)
+	if node.IsDir {
+		label := "Expand " + node.Name
+		if isExpanded {
+			label = "Collapse " + node.Name
+		}
+		icon = icon.
+			Role("button").
+			TabIndex(0).
+			Aria("label", label).
+			OnClick(func(ctx app.Context, e app.Event) {
+				b.toggleDir(ctx, node.ID)
+			}).
+			OnKeyDown(func(ctx app.Context, e app.Event) {
+				if expanded, ok := treeToggleKey(e.Get("key").String(), b.expandedDirs[node.ID]); ok {
+					e.PreventDefault()
+					b.expandedDirs[node.ID] = expanded
+				}
+			})
+		item = item.Aria("expanded", isExpanded)
+	} else {
+		icon = icon.Aria("hidden", true)
+	}
+
+	return item.
 		Style("padding-left", fmt.Sprintf("%dpx", depth*20)).
 		Body(
-			app.Div().Class("tree-node-content").Body(
-				app.Span().
-					Class("tree-node-icon").
-					Text(iconText).
-					OnClick(func(ctx app.Context, e app.Event) {
-						if node.IsDir {
-							b.toggleDir(ctx, node.ID)
-						}
-					}),
+			content.Body(
+				icon,
 				app.Span().Class("tree-node-name").Body(nameUI),
 				sizeUI,
+				ocrBadge(node.OCRStatus),
+				app.If(!node.IsDir, func() app.UI {
+					return &DocumentEditor{ULID: node.ULID, FullPath: node.FullPath, Inline: true}
+				}),
+				app.If(node.IsDir && node.SmartFolder == "", func() app.UI {
+					return app.Button().
+						Class("tree-node-branch").
+						ID(branchButtonID(node)).
+						Title("Show all documents in this folder and its subfolders").
+						Text("Show all").
+						OnClick(func(ctx app.Context, e app.Event) {
+							b.showBranch(ctx, node)
+						})
+				}),
 			),
 			childrenUI,
 		)
@@ -181,14 +464,16 @@ func formatBytes(bytes int64) string {
 func (b *BrowsePage) Render() app.UI {
 	var content app.UI
 
-	if b.loading {
-		content = app.Div().Class("loading").Body(app.Text("Loading..."))
+	if b.activeTag != "" {
+		content = b.renderTagDocuments()
+	} else if b.loading {
+		content = statusMessage("loading", app.Text("Loading..."))
 	} else if b.error != "" {
-		content = app.Div().Class("error").Body(app.Text("Error: " + b.error))
+		content = alertMessage(app.Text("Error: " + b.error))
 	} else if b.fileSystem.Error != "" {
-		content = app.Div().Class("warning").Body(app.Text("Warning: " + b.fileSystem.Error))
+		content = app.Div().Class("warning").Role("status").Body(app.Text("Warning: " + b.fileSystem.Error))
 	} else if len(b.fileSystem.FileSystem) > 0 {
-		content = app.Div().Class("file-tree").Body(b.renderNode(b.fileSystem.FileSystem[0], 0))
+		content = app.Div().Class("file-tree").Role("tree").Aria("label", "Documents").Body(b.renderNode(b.fileSystem.FileSystem[0], 0))
 	} else {
 		content = app.Text("No documents found")
 	}
@@ -197,6 +482,12 @@ func (b *BrowsePage) Render() app.UI {
 		Class("browse-page").
 		Body(
 			app.H2().Text("Browse Documents"),
+			renderTagChips(b.tags, b.activeTag, func(tag string) app.EventHandler {
+				return func(ctx app.Context, e app.Event) {
+					b.selectTag(ctx, tag)
+				}
+			}),
+			app.If(b.branch != nil, b.renderBranch),
 			content,
 		)
 }
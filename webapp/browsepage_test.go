@@ -0,0 +1,29 @@
+package webapp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+func TestRenderTagChips(t *testing.T) {
+	noop := func(string) app.EventHandler { return func(app.Context, app.Event) {} }
+
+	// Two tags with tax chosen
+	html := app.HTMLString(renderTagChips([]dto.Tag{{Name: "receipts", DocumentCount: 3}, {Name: "tax", DocumentCount: 12}}, "tax", noop))
+
+	// Each tag is a chip with its count and only tax is pressed
+	if !strings.Contains(html, ">receipts (3)<") || !strings.Contains(html, ">tax (12)<") {
+		t.Errorf("Expected a chip per tag with its count, got %s", html)
+	}
+	if strings.Count(html, "tag-chip-active") != 1 || strings.Count(html, `aria-pressed="false"`) != 1 {
+		t.Errorf("Expected only the chosen tag pressed, got %s", html)
+	}
+
+	// Without tags there are no chips
+	if renderTagChips(nil, "", noop) != nil {
+		t.Error("Expected no chips without tags")
+	}
+}
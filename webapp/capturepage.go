@@ -0,0 +1,396 @@
+package webapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// captureMaxCrop is the most, in percent, that can be trimmed from one edge of a captured photo
+const captureMaxCrop = 40
+
+// captureQuality is the JPEG quality a captured photo is uploaded at: plenty for OCR, and small
+// enough to send over a phone connection
+const captureQuality = 0.92
+
+// captureEdges name the edges of a photo in the order captureEdit.crop holds them
+var captureEdges = []string{"Top", "Right", "Bottom", "Left"}
+
+// captureEdit is how a captured photo is turned and trimmed before it is uploaded
+type captureEdit struct {
+	turns int    // quarter turns clockwise, 0 to 3
+	crop  [4]int // percent trimmed from the top, right, bottom and left of the turned photo
+}
+
+// rotate turns the photo by a number of quarter turns, clockwise when positive
+func (e *captureEdit) rotate(quarters int) {
+	e.turns = ((e.turns+quarters)%4 + 4) % 4
+}
+
+// setCrop sets the percent trimmed from one edge, kept between 0 and captureMaxCrop
+func (e *captureEdit) setCrop(edge, percent int) {
+	e.crop[edge] = max(0, min(percent, captureMaxCrop))
+}
+
+// turnedSize is the size of a width by height photo once turned
+func (e captureEdit) turnedSize(width, height int) (int, int) {
+	if e.turns%2 == 1 {
+		return height, width
+	}
+	return width, height
+}
+
+// cropRect is the part of the turned photo that is kept, as x, y, width and height
+func (e captureEdit) cropRect(width, height int) (int, int, int, int) {
+	w, h := e.turnedSize(width, height)
+	x, y := w*e.crop[3]/100, h*e.crop[0]/100
+	return x, y, w - x - w*e.crop[1]/100, h - y - h*e.crop[2]/100
+}
+
+// captureFileName names an uploaded photo after when it was taken, so captures sort in order in ingress
+func captureFileName(taken time.Time) string {
+	return taken.Format("capture-20060102-150405") + ".jpg"
+}
+
+// CapturePage turns a phone into a scanner: it photographs a paper document with the camera, lets it
+// be turned and trimmed, and uploads it into the normal ingestion and OCR pipeline
+type CapturePage struct {
+	app.Compo
+	streaming bool
+	stream    app.Value
+
+	photo       app.Value // canvas holding the photo as taken
+	photoWidth  int
+	photoHeight int
+	edit        captureEdit
+
+	uploading bool
+	uploaded  string // where the last upload was stored
+	error     string
+}
+
+// OnDismount turns the camera off when leaving the page
+func (p *CapturePage) OnDismount() {
+	p.stopCamera()
+}
+
+// stopCamera stops every track of the camera stream
+func (p *CapturePage) stopCamera() {
+	p.streaming = false
+	if p.stream == nil || !p.stream.Truthy() {
+		return
+	}
+	tracks := p.stream.Call("getTracks")
+	for i := 0; i < tracks.Length(); i++ {
+		tracks.Index(i).Call("stop")
+	}
+	p.stream = nil
+}
+
+// startCamera opens the rear camera at the highest resolution it offers, since OCR reads small print better
+func (p *CapturePage) startCamera(ctx app.Context, e app.Event) {
+	mediaDevices := app.Window().Get("navigator").Get("mediaDevices")
+	if !mediaDevices.Truthy() {
+		p.error = "No camera is available here; the page must be opened over HTTPS or from localhost. Choose a photo instead."
+		return
+	}
+	p.error = ""
+	p.uploaded = ""
+
+	constraints := map[string]any{
+		"video": map[string]any{
+			"facingMode": "environment",
+			"width":      map[string]any{"ideal": 3840},
+			"height":     map[string]any{"ideal": 2160},
+		},
+		"audio": false,
+	}
+	mediaDevices.Call("getUserMedia", constraints).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+		if len(args) == 0 {
+			return nil
+		}
+		stream := args[0]
+		ctx.Dispatch(func(ctx app.Context) {
+			p.stream = stream
+			p.streaming = true
+			video := app.Window().GetElementByID("capture-video")
+			video.Set("srcObject", stream)
+			video.Call("play")
+		})
+		return nil
+	})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+		ctx.Dispatch(func(ctx app.Context) {
+			p.error = "Could not open the camera; allow camera access and try again, or choose a photo instead"
+		})
+		return nil
+	}))
+}
+
+// takePhoto keeps the current camera frame and turns the camera off
+func (p *CapturePage) takePhoto(ctx app.Context, e app.Event) {
+	video := app.Window().GetElementByID("capture-video")
+	width, height := video.Get("videoWidth").Int(), video.Get("videoHeight").Int()
+	if width == 0 || height == 0 {
+		p.error = "The camera is not ready yet; try again in a moment"
+		return
+	}
+	canvas := newCanvas(width, height)
+	canvas.Call("getContext", "2d").Call("drawImage", video, 0, 0, width, height)
+	p.stopCamera()
+	p.setPhoto(ctx, canvas, width, height)
+}
+
+// onFileChosen loads a photo picked from the device, for browsers that cannot stream the camera.
+// On a phone the picker offers the camera app directly.
+func (p *CapturePage) onFileChosen(ctx app.Context, e app.Event) {
+	files := ctx.JSSrc().Get("files")
+	if !files.Truthy() || files.Length() == 0 {
+		return
+	}
+	objectURL := app.Window().Get("URL").Call("createObjectURL", files.Index(0))
+	image := app.Window().Get("Image").New()
+	image.Set("onload", app.FuncOf(func(this app.Value, args []app.Value) any {
+		width, height := image.Get("naturalWidth").Int(), image.Get("naturalHeight").Int()
+		canvas := newCanvas(width, height)
+		canvas.Call("getContext", "2d").Call("drawImage", image, 0, 0, width, height)
+		app.Window().Get("URL").Call("revokeObjectURL", objectURL)
+		ctx.Dispatch(func(ctx app.Context) {
+			p.setPhoto(ctx, canvas, width, height)
+		})
+		return nil
+	}))
+	image.Set("onerror", app.FuncOf(func(this app.Value, args []app.Value) any {
+		app.Window().Get("URL").Call("revokeObjectURL", objectURL)
+		ctx.Dispatch(func(ctx app.Context) {
+			p.error = "That file is not an image this browser can open"
+		})
+		return nil
+	}))
+	image.Set("src", objectURL)
+}
+
+// setPhoto starts editing a new photo, shown once the preview canvas has been rendered
+func (p *CapturePage) setPhoto(ctx app.Context, canvas app.Value, width, height int) {
+	p.photo = canvas
+	p.photoWidth, p.photoHeight = width, height
+	p.edit = captureEdit{}
+	p.uploaded = ""
+	p.error = ""
+	ctx.Defer(func(ctx app.Context) {
+		p.drawPreview()
+	})
+}
+
+// drawPreview draws the photo, turned and trimmed, into the preview canvas, which is also what gets uploaded
+func (p *CapturePage) drawPreview() {
+	preview := app.Window().GetElementByID("capture-preview")
+	if p.photo == nil || !preview.Truthy() {
+		return
+	}
+	turnedWidth, turnedHeight := p.edit.turnedSize(p.photoWidth, p.photoHeight)
+	turned := newCanvas(turnedWidth, turnedHeight)
+	turnedContext := turned.Call("getContext", "2d")
+	turnedContext.Call("translate", float64(turnedWidth)/2, float64(turnedHeight)/2)
+	turnedContext.Call("rotate", float64(p.edit.turns)*math.Pi/2)
+	turnedContext.Call("drawImage", p.photo, -float64(p.photoWidth)/2, -float64(p.photoHeight)/2)
+
+	x, y, width, height := p.edit.cropRect(p.photoWidth, p.photoHeight)
+	preview.Set("width", width)
+	preview.Set("height", height)
+	preview.Call("getContext", "2d").Call("drawImage", turned, x, y, width, height, 0, 0, width, height)
+}
+
+// newCanvas creates an off-screen canvas
+func newCanvas(width, height int) app.Value {
+	canvas := app.Window().Get("document").Call("createElement", "canvas")
+	canvas.Set("width", width)
+	canvas.Set("height", height)
+	return canvas
+}
+
+// onRotate returns a handler turning the photo by a number of quarter turns
+func (p *CapturePage) onRotate(quarters int) app.EventHandler {
+	return func(ctx app.Context, e app.Event) {
+		p.edit.rotate(quarters)
+		p.drawPreview()
+	}
+}
+
+// onCrop returns a handler trimming one edge of the photo to its slider's value
+func (p *CapturePage) onCrop(edge int) app.EventHandler {
+	return func(ctx app.Context, e app.Event) {
+		percent, err := strconv.Atoi(ctx.JSSrc().Get("value").String())
+		if err != nil {
+			return
+		}
+		p.edit.setCrop(edge, percent)
+		p.drawPreview()
+	}
+}
+
+// onRetake drops the photo so another can be taken
+func (p *CapturePage) onRetake(ctx app.Context, e app.Event) {
+	p.photo = nil
+	p.error = ""
+	p.startCamera(ctx, e)
+}
+
+// upload sends the edited photo to the upload API, which puts it through ingestion and OCR
+func (p *CapturePage) upload(ctx app.Context, e app.Event) {
+	preview := app.Window().GetElementByID("capture-preview")
+	if !preview.Truthy() {
+		return
+	}
+	p.uploading = true
+	p.error = ""
+	name := captureFileName(time.Now())
+
+	preview.Call("toBlob", app.FuncOf(func(this app.Value, args []app.Value) any {
+		if len(args) == 0 || !args[0].Truthy() {
+			ctx.Dispatch(func(ctx app.Context) {
+				p.uploading = false
+				p.error = "Could not encode the photo"
+			})
+			return nil
+		}
+		form := app.Window().Get("FormData").New()
+		form.Call("append", "file", args[0], name)
+		app.Window().Call("fetch", BuildAPIURL("/api/document/upload"), map[string]any{
+			"method": "POST",
+			"body":   form,
+		}).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			status := args[0].Get("status").Int()
+			args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+				if len(args) == 0 {
+					return nil
+				}
+				jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+				ctx.Dispatch(func(ctx app.Context) {
+					p.uploading = false
+					if status < 200 || status >= 300 {
+						var failure struct {
+							Error string `json:"error"`
+						}
+						if json.Unmarshal([]byte(jsonStr), &failure); failure.Error == "" {
+							failure.Error = fmt.Sprintf("status %d", status)
+						}
+						p.error = "Upload failed: " + failure.Error
+						return
+					}
+					var path string
+					json.Unmarshal([]byte(jsonStr), &path)
+					p.uploaded = path
+					p.photo = nil
+				})
+				return nil
+			}))
+			return nil
+		})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+			ctx.Dispatch(func(ctx app.Context) {
+				p.uploading = false
+				p.error = "Network error: Could not connect to server"
+			})
+			return nil
+		}))
+		return nil
+	}), "image/jpeg", captureQuality)
+}
+
+// Render renders the capture page
+func (p *CapturePage) Render() app.UI {
+	var status app.UI
+	switch {
+	case p.error != "":
+		status = alertMessage(app.Text(p.error))
+	case p.uploading:
+		status = statusMessage("loading", app.Text("Uploading and reading the photo..."))
+	case p.uploaded != "":
+		status = statusMessage("capture-uploaded",
+			app.Text("Uploaded as "+p.uploaded+". It is searchable as soon as OCR has read it. "),
+			app.A().Href("/search").Text("Go to search"),
+		)
+	}
+
+	var controls app.UI
+	switch {
+	case p.photo != nil:
+		controls = p.renderEditor()
+	case p.streaming:
+		controls = app.Div().Class("capture-controls").Body(
+			app.Button().Class("btn-primary").OnClick(p.takePhoto).Text("📸 Take photo"),
+			app.Button().Class("btn-secondary").OnClick(func(ctx app.Context, e app.Event) { p.stopCamera() }).Text("Cancel"),
+		)
+	default:
+		controls = app.Div().Class("capture-controls").Body(
+			app.Button().Class("btn-primary").OnClick(p.startCamera).Text("📷 Scan with camera"),
+			app.Label().Class("btn-secondary capture-file").Body(
+				app.Text("Choose a photo"),
+				app.Input().
+					Type("file").
+					Accept("image/*").
+					Attr("capture", "environment").
+					OnChange(p.onFileChosen),
+			),
+		)
+	}
+
+	return app.Div().Class("capture-page").Body(
+		app.H2().Text("Scan with camera"),
+		app.P().Text("Photograph a paper document flat and well lit, turn and trim it, then upload it to be read and filed like any other scan."),
+		status,
+		controls,
+		// Always rendered so the camera stream has somewhere to go as soon as it opens
+		app.Video().
+			ID("capture-video").
+			Class("scan-video").
+			Hidden(!p.streaming).
+			Muted(true).
+			Attr("playsinline", true),
+	)
+}
+
+// renderEditor shows the photo with the controls to turn, trim and upload it
+func (p *CapturePage) renderEditor() app.UI {
+	crops := make([]app.UI, len(captureEdges))
+	for i, edge := range captureEdges {
+		id := "capture-crop-" + edge
+		crops[i] = app.Div().Class("capture-crop").Body(
+			app.Label().For(id).Text(edge),
+			app.Input().
+				ID(id).
+				Type("range").
+				Min(0).
+				Max(captureMaxCrop).
+				Value(p.edit.crop[i]).
+				Aria("valuetext", fmt.Sprintf("%d%% trimmed", p.edit.crop[i])).
+				OnInput(p.onCrop(i)),
+		)
+	}
+
+	uploadText := "Upload"
+	if p.uploading {
+		uploadText = "Uploading..."
+	}
+	return app.Div().Class("capture-editor").Body(
+		app.Canvas().ID("capture-preview").Class("capture-preview").Aria("label", "Photo to upload"),
+		app.Div().Class("capture-controls").Body(
+			app.Button().Class("btn-secondary").Aria("label", "Turn left").OnClick(p.onRotate(-1)).Text("⟲"),
+			app.Button().Class("btn-secondary").Aria("label", "Turn right").OnClick(p.onRotate(1)).Text("⟳"),
+		),
+		app.FieldSet().Class("capture-crops").Body(
+			app.Legend().Text("Trim edges"),
+			app.Div().Class("capture-crop-list").Body(crops...),
+		),
+		app.Div().Class("capture-controls").Body(
+			app.Button().Class("btn-primary").Disabled(p.uploading).OnClick(p.upload).Text(uploadText),
+			app.Button().Class("btn-secondary").Disabled(p.uploading).OnClick(p.onRetake).Text("Retake"),
+		),
+	)
+}
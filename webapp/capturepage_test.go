@@ -0,0 +1,72 @@
+package webapp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCaptureEdit(t *testing.T) {
+	t.Run("Turning wraps round in quarter turns", func(t *testing.T) {
+		var edit captureEdit
+		edit.rotate(-1)
+		if edit.turns != 3 {
+			t.Errorf("Turning left from upright should give 3 quarter turns, got %d", edit.turns)
+		}
+		edit.rotate(2)
+		if edit.turns != 1 {
+			t.Errorf("Expected 1 quarter turn, got %d", edit.turns)
+		}
+	})
+
+	t.Run("A quarter turn swaps width and height", func(t *testing.T) {
+		edit := captureEdit{turns: 1}
+		if w, h := edit.turnedSize(4000, 3000); w != 3000 || h != 4000 {
+			t.Errorf("Expected 3000x4000, got %dx%d", w, h)
+		}
+		edit.rotate(1)
+		if w, h := edit.turnedSize(4000, 3000); w != 4000 || h != 3000 {
+			t.Errorf("Expected 4000x3000 upside down, got %dx%d", w, h)
+		}
+	})
+
+	t.Run("Trimming applies to the turned photo and is clamped", func(t *testing.T) {
+		edit := captureEdit{turns: 1}
+		edit.setCrop(0, 10) // top
+		edit.setCrop(1, -5) // right, nothing
+		edit.setCrop(2, 90) // bottom, at most captureMaxCrop
+		edit.setCrop(3, 25) // left
+		if edit.crop != [4]int{10, 0, captureMaxCrop, 25} {
+			t.Fatalf("Unexpected crop %v", edit.crop)
+		}
+		x, y, w, h := edit.cropRect(4000, 3000)
+		if x != 750 || y != 400 || w != 2250 || h != 2000 {
+			t.Errorf("Expected 750,400 2250x2000, got %d,%d %dx%d", x, y, w, h)
+		}
+	})
+}
+
+func TestCaptureFileName(t *testing.T) {
+	taken := time.Date(2026, 3, 7, 9, 5, 2, 0, time.UTC)
+	if got := captureFileName(taken); got != "capture-20260307-090502.jpg" {
+		t.Errorf("Unexpected file name %q", got)
+	}
+}
+
+// TestCapturePageRenderStates tests that the capture page renders before, during and after capturing
+func TestCapturePageRenderStates(t *testing.T) {
+	pages := map[string]*CapturePage{
+		"idle":      {},
+		"streaming": {streaming: true},
+		"uploading": {uploading: true},
+		"uploaded":  {uploaded: "/ingress/capture-20260307-090502.jpg"},
+		"error":     {error: "Could not open the camera"},
+	}
+	for name, page := range pages {
+		if page.Render() == nil {
+			t.Errorf("The %s capture page should render", name)
+		}
+	}
+	if (&CapturePage{edit: captureEdit{crop: [4]int{5, 5, 5, 5}}}).renderEditor() == nil {
+		t.Error("The photo editor should render")
+	}
+}
@@ -2,6 +2,7 @@ package webapp
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/maxence-charriere/go-app/v10/pkg/app"
@@ -11,6 +12,8 @@ import (
 type CleanPage struct {
 	app.Compo
 	running      bool
+	dryRun       bool
+	orphanPolicy string
 	result       string
 	error        string
 	deletedCount int
@@ -30,13 +33,28 @@ func (c *CleanPage) Render() app.UI {
 		Body(
 			app.H2().Text("Database Cleanup"),
 			app.P().Text("This tool will scan all documents in the database and verify that their files still exist on disk. Any database entries for missing files will be removed."),
-			app.P().Text("It will also find documents in storage that are not in the database and move them to the ingress folder for reprocessing (including any .yaml metadata and .txt OCR files)."),
+			app.P().Text("It will also find documents in storage that are not in the database. By default they are moved to the ingress folder for reprocessing (including any .yaml metadata and .txt OCR files); they can instead be re-linked in place, reusing any .txt OCR file, or just reported."),
 
 			app.Div().Class("warning").Body(
 				app.P().Text("⚠️ Warning: This operation will permanently delete database entries for missing files. Make sure you have a backup if needed."),
 			),
 
 			app.Div().Class("clean-controls").Body(
+				app.Label().For("orphan-policy").Text("Orphaned files: "),
+				app.Select().
+					ID("orphan-policy").
+					Disabled(c.running).
+					OnChange(c.onOrphanPolicyChange).
+					Body(
+						app.Option().Value("ingress").Selected(c.orphanPolicy == "" || c.orphanPolicy == "ingress").Text("Move to ingress (reprocess)"),
+						app.Option().Value("relink").Selected(c.orphanPolicy == "relink").Text("Re-link in place"),
+						app.Option().Value("report").Selected(c.orphanPolicy == "report").Text("Report only"),
+					),
+				app.Button().
+					Class("btn-secondary").
+					Disabled(c.running).
+					OnClick(c.onDryRunClick).
+					Body(app.Text("Preview Changes (Dry Run)")),
 				app.Button().
 					Class("btn-danger").
 					Disabled(c.running).
@@ -77,7 +95,9 @@ func (c *CleanPage) renderStatus() app.UI {
 			details = append(details, fmt.Sprintf("Moved %d orphaned documents to ingress", c.movedCount))
 		}
 
-		if len(details) > 0 {
+		if c.dryRun {
+			resultMsg = c.result + " - Dry run: no changes were made. See the Jobs page for the full report."
+		} else if len(details) > 0 {
 			resultMsg = fmt.Sprintf("%s - %s.", c.result, joinStrings(details, ", "))
 		} else {
 			resultMsg = c.result + " - No issues found. Database is clean!"
@@ -92,8 +112,25 @@ func (c *CleanPage) renderStatus() app.UI {
 	return app.Div()
 }
 
+// onOrphanPolicyChange records the selected orphan handling policy
+func (c *CleanPage) onOrphanPolicyChange(ctx app.Context, e app.Event) {
+	c.orphanPolicy = ctx.JSSrc().Get("value").String()
+}
+
+// onDryRunClick starts a cleanup that only reports what it would change
+func (c *CleanPage) onDryRunClick(ctx app.Context, e app.Event) {
+	c.dryRun = true
+	c.start(ctx)
+}
+
 // onCleanClick handles the clean button click
 func (c *CleanPage) onCleanClick(ctx app.Context, e app.Event) {
+	c.dryRun = false
+	c.start(ctx)
+}
+
+// start resets the page state and triggers the cleanup
+func (c *CleanPage) start(ctx app.Context) {
 	c.running = true
 	c.result = ""
 	c.error = ""
@@ -107,7 +144,11 @@ func (c *CleanPage) onCleanClick(ctx app.Context, e app.Event) {
 // runClean calls the API to trigger database cleaning
 func (c *CleanPage) runClean(ctx app.Context) {
 	ctx.Async(func() {
-		res := app.Window().Call("fetch", BuildAPIURL("/api/clean"), map[string]interface{}{
+		cleanURL := "/api/clean?orphans=" + url.QueryEscape(c.orphanPolicy)
+		if c.dryRun {
+			cleanURL += "&dryRun=true"
+		}
+		res := app.Window().Call("fetch", BuildAPIURL(cleanURL), map[string]interface{}{
 			"method": "POST",
 		})
 
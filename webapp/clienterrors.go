@@ -0,0 +1,240 @@
+package webapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// clientErrorsPath is where errors caught in the browser are sent
+const clientErrorsPath = "/api/client-errors"
+
+// maxReportsPerPage is how many errors one page load sends, so a render loop that fails every frame
+// does not flood the server; the server limits reports as well
+const maxReportsPerPage = 20
+
+var (
+	// browserFetch is window.fetch as it was before InstallErrorReporting wrapped it, used to send
+	// reports so a failing report is not itself reported
+	browserFetch app.Value
+	// reportsSent counts the reports sent since the page loaded
+	reportsSent int
+)
+
+// ReportClientError sends an error caught in the browser to the server with the page it happened on.
+// It does nothing outside the browser or once maxReportsPerPage have been sent.
+func ReportClientError(kind, message, stack string) {
+	if !app.IsClient || reportsSent >= maxReportsPerPage {
+		return
+	}
+	reportsSent++
+	report := dto.ClientErrorReport{
+		Kind:    kind,
+		Message: message,
+		Stack:   stack,
+		Page:    app.Window().Get("location").Get("pathname").String(),
+	}
+	body, err := json.Marshal(report)
+	if err != nil {
+		return
+	}
+	fetch := browserFetch
+	if fetch == nil || !fetch.Truthy() {
+		fetch = app.Window().Get("fetch")
+	}
+	// keepalive lets the report finish even when a panic stops the app straight after
+	fetch.Invoke(BuildAPIURL(clientErrorsPath), map[string]any{
+		"method":      "POST",
+		"headers":     map[string]any{"Content-Type": "application/json"},
+		"body":        string(body),
+		"credentials": "include",
+		"keepalive":   true,
+	}).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+		return nil
+	}))
+}
+
+// RecoverPanic reports a panic in the app to the server and panics again, so the app still stops as
+// it would have. Defer it first thing in main, before app.RunWhenOnBrowser, which runs event handlers
+// and dispatched updates on the main goroutine.
+func RecoverPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	ReportClientError("panic", fmt.Sprint(r), string(debug.Stack()))
+	panic(r)
+}
+
+// InstallErrorReporting reports uncaught JavaScript errors, unhandled promise rejections and API calls
+// that fail or answer with a server error
+func InstallErrorReporting() {
+	if !app.IsClient {
+		return
+	}
+	window := app.Window()
+	window.Call("addEventListener", "error", app.FuncOf(func(this app.Value, args []app.Value) any {
+		if len(args) == 0 {
+			return nil
+		}
+		event := args[0]
+		message := event.Get("message").String()
+		if source := event.Get("filename"); source.Truthy() {
+			message += fmt.Sprintf(" (%s:%d)", source.String(), event.Get("lineno").Int())
+		}
+		ReportClientError("error", message, jsStack(event.Get("error")))
+		return nil
+	}))
+	window.Call("addEventListener", "unhandledrejection", app.FuncOf(func(this app.Value, args []app.Value) any {
+		if len(args) == 0 {
+			return nil
+		}
+		reason := args[0].Get("reason")
+		ReportClientError("rejection", jsMessage(reason), jsStack(reason))
+		return nil
+	}))
+
+	browserFetch = window.Get("fetch")
+	if !browserFetch.Truthy() {
+		return
+	}
+	original := browserFetch.Call("bind", window)
+	browserFetch = original
+	window.Set("fetch", app.FuncOf(func(this app.Value, args []app.Value) any {
+		params := make([]any, len(args))
+		for i, arg := range args {
+			params[i] = arg
+		}
+		promise := original.Invoke(params...)
+		target := fetchTarget(args)
+		if !strings.Contains(target, "/api/") || strings.Contains(target, clientErrorsPath) {
+			return promise
+		}
+		// Watch the response on a side branch so callers get the promise exactly as fetch gave it
+		promise.Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) > 0 && args[0].Get("status").Int() >= 500 {
+				ReportClientError("fetch", fmt.Sprintf("%s answered %d %s", target, args[0].Get("status").Int(), args[0].Get("statusText").String()), "")
+			}
+			return nil
+		}), app.FuncOf(func(this app.Value, args []app.Value) any {
+			message := "request failed"
+			if len(args) > 0 {
+				message = jsMessage(args[0])
+			}
+			ReportClientError("fetch", target+": "+message, "")
+			return nil
+		}))
+		return promise
+	}))
+}
+
+// fetchTarget returns the URL a fetch call was made with, a string or a Request
+func fetchTarget(args []app.Value) string {
+	if len(args) == 0 {
+		return ""
+	}
+	if args[0].Type() == app.TypeString {
+		return args[0].String()
+	}
+	return args[0].Get("url").String()
+}
+
+// jsMessage describes a thrown JavaScript value, usually an Error
+func jsMessage(value app.Value) string {
+	if value.Type() == app.TypeObject && value.Get("message").Truthy() {
+		return value.Get("message").String()
+	}
+	return app.Window().Get("String").Invoke(value).String()
+}
+
+// jsStack returns a JavaScript Error's stack, or nothing for other values
+func jsStack(value app.Value) string {
+	if value.Type() == app.TypeObject && value.Get("stack").Truthy() {
+		return value.Get("stack").String()
+	}
+	return ""
+}
+
+// ClientErrorList shows administrators the errors users' browsers reported. It shows nothing to
+// everyone else, as the server refuses them the list.
+type ClientErrorList struct {
+	app.Compo
+	errors []dto.ClientError
+}
+
+// OnMount loads the reported errors
+func (l *ClientErrorList) OnMount(ctx app.Context) {
+	app.Window().Call("fetch", BuildAPIURL("/api/admin/client-errors"), map[string]any{"credentials": "include"}).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+		if len(args) == 0 || !args[0].Get("ok").Bool() {
+			return nil
+		}
+		args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+			var response dto.ClientErrors
+			if err := json.Unmarshal([]byte(jsonStr), &response); err != nil {
+				app.Log("Failed to parse client errors:", err)
+				return nil
+			}
+			ctx.Dispatch(func(ctx app.Context) {
+				l.errors = response.Errors
+			})
+			return nil
+		}))
+		return nil
+	}))
+}
+
+// renderClientErrors lists reported errors newest first, each with its stack folded away
+func renderClientErrors(reports []dto.ClientError) app.UI {
+	if len(reports) == 0 {
+		return app.Div().Class("client-errors-empty")
+	}
+	return app.Section().Class("client-errors").Aria("label", "Web UI errors").Body(
+		app.H3().Text("Web UI Errors"),
+		app.P().Text("Errors reported from users' browsers, newest first."),
+		app.Ul().Body(
+			app.Range(reports).Slice(func(i int) app.UI {
+				report := reports[i]
+				var context []string
+				for _, part := range []string{report.Page, report.User, formatReportedAt(report.ReportedAt)} {
+					if part != "" {
+						context = append(context, part)
+					}
+				}
+				return app.Li().Class("client-error client-error-"+report.Kind).Title(report.UserAgent).Body(
+					app.Span().Class("client-error-kind").Text(report.Kind),
+					app.Span().Class("client-error-message").Text(report.Message),
+					app.Div().Class("client-error-context").Text(strings.Join(context, " · ")),
+					app.If(report.Stack != "", func() app.UI {
+						return app.Details().Body(
+							app.Summary().Text("Stack"),
+							app.Pre().Class("client-error-stack").Text(report.Stack),
+						)
+					}),
+				)
+			}),
+		),
+	)
+}
+
+// formatReportedAt shows an RFC 3339 report time in local time, or as given when it does not parse
+func formatReportedAt(reportedAt string) string {
+	t, err := time.Parse(time.RFC3339, reportedAt)
+	if err != nil {
+		return reportedAt
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// Render renders the list, or an empty placeholder when there is nothing to show
+func (l *ClientErrorList) Render() app.UI {
+	return renderClientErrors(l.errors)
+}
@@ -0,0 +1,41 @@
+package webapp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+func TestClientErrorList(t *testing.T) {
+	reports := []dto.ClientError{
+		{Kind: "panic", Message: "index out of range [3] with length 3", Stack: "goroutine 1 [running]:", Page: "/search", User: "bob", ReportedAt: "2024-06-01T12:02:00Z"},
+		{Kind: "fetch", Message: "/api/search answered 500 Internal Server Error", Page: "/search", ReportedAt: "not a time"},
+	}
+
+	t.Run("Each report shows its kind, message and where it happened, with the stack folded away", func(t *testing.T) {
+		html := app.HTMLString(renderClientErrors(reports))
+		for _, want := range []string{"Web UI Errors", "client-error-panic", "index out of range", "/search · bob", "<summary>Stack</summary>", "goroutine 1", "not a time"} {
+			if !strings.Contains(html, want) {
+				t.Errorf("Expected %q in %s", want, html)
+			}
+		}
+		if strings.Count(html, "<details") != 1 {
+			t.Errorf("Expected a stack only for the panic, got %s", html)
+		}
+	})
+
+	t.Run("No reports, or none the user may read, shows nothing", func(t *testing.T) {
+		if html := app.HTMLString(renderClientErrors(nil)); strings.Contains(html, "Web UI Errors") {
+			t.Errorf("Expected nothing without reports, got %s", html)
+		}
+	})
+
+	t.Run("Reporting outside the browser does nothing", func(t *testing.T) {
+		ReportClientError("error", "ignored", "")
+		if reportsSent != 0 {
+			t.Errorf("Expected no report outside the browser, got %d", reportsSent)
+		}
+	})
+}
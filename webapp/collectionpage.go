@@ -0,0 +1,135 @@
+package webapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// Collection is a saved, immutable set of documents
+type Collection struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Term          string `json:"term"`
+	ShareToken    string `json:"shareToken"`
+	DocumentCount int    `json:"documentCount"`
+	CreatedAt     string `json:"createdAt"`
+}
+
+// CollectionDocument is a document listed in a collection
+type CollectionDocument struct {
+	ULID   string `json:"ULID"`
+	Name   string `json:"Name"`
+	Folder string `json:"Folder"`
+	URL    string `json:"URL"`
+}
+
+// CollectionView is a collection with its documents, as returned by the collection endpoints
+type CollectionView struct {
+	Collection Collection           `json:"collection"`
+	Documents  []CollectionDocument `json:"documents"`
+	Missing    int                  `json:"missing"`
+	ShareURL   string               `json:"shareURL"`
+	Error      string               `json:"error"`
+}
+
+// collectionPageURL is where the web UI shows a collection, by ID or by share token
+func collectionPageURL(collection Collection, shared bool) string {
+	if shared {
+		return "/collection?share=" + url.QueryEscape(collection.ShareToken)
+	}
+	return "/collection?id=" + url.QueryEscape(collection.ID)
+}
+
+// CollectionPage shows a saved collection, opened by ID or from a share link
+type CollectionPage struct {
+	app.Compo
+	view    CollectionView
+	loading bool
+	error   string
+}
+
+// OnMount loads the collection named in the URL
+func (p *CollectionPage) OnMount(ctx app.Context) {
+	query := ctx.Page().URL().Query()
+	var target string
+	switch {
+	case query.Get("share") != "":
+		target = "/api/shared/" + url.PathEscape(query.Get("share"))
+	case query.Get("id") != "":
+		target = "/api/collections/" + url.PathEscape(query.Get("id"))
+	default:
+		p.error = "No collection given"
+		return
+	}
+
+	p.loading = true
+	ctx.Async(func() {
+		res := app.Window().Call("fetch", BuildAPIURL(target))
+		res.Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+				if len(args) == 0 {
+					return nil
+				}
+				jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+
+				var view CollectionView
+				ctx.Dispatch(func(ctx app.Context) {
+					p.loading = false
+					if err := json.Unmarshal([]byte(jsonStr), &view); err != nil {
+						p.error = fmt.Sprintf("Failed to parse response: %v", err)
+						return
+					}
+					p.error = view.Error
+					p.view = view
+				})
+				return nil
+			}))
+			return nil
+		})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+			ctx.Dispatch(func(ctx app.Context) {
+				p.loading = false
+				p.error = "Network error"
+			})
+			return nil
+		}))
+	})
+}
+
+// Render renders the collection page
+func (p *CollectionPage) Render() app.UI {
+	if p.loading {
+		return app.Div().Class("collection-page").Body(app.Div().Class("loading").Text("Loading collection..."))
+	}
+	if p.error != "" {
+		return app.Div().Class("collection-page").Body(app.Div().Class("error").Text("Error: " + p.error))
+	}
+
+	collection := p.view.Collection
+	var source, missing app.UI
+	if collection.Term != "" {
+		source = app.P().Class("collection-term").Text("Saved from the search: " + collection.Term)
+	}
+	if p.view.Missing > 0 {
+		missing = app.P().Class("collection-missing").Text(fmt.Sprintf("%d of %d documents have been deleted since this collection was saved", p.view.Missing, collection.DocumentCount))
+	}
+	return app.Div().Class("collection-page").Body(
+		app.H2().Text(collection.Name),
+		source,
+		missing,
+		app.Ul().Class("collection-documents").Body(
+			app.Range(p.view.Documents).Slice(func(i int) app.UI {
+				document := p.view.Documents[i]
+				return app.Li().Body(
+					app.A().Href(document.URL).Target("_blank").Text("📄 "+document.Name),
+					app.Span().Class("collection-folder").Text(document.Folder),
+				)
+			}),
+		),
+	)
+}
@@ -0,0 +1,46 @@
+package webapp
+
+import (
+	"testing"
+)
+
+// TestCollectionPageRenderStates tests that the collection page renders in each state
+func TestCollectionPageRenderStates(t *testing.T) {
+	pages := map[string]*CollectionPage{
+		"loading": {loading: true},
+		"error":   {error: "Shared collection not found"},
+		"loaded": {view: CollectionView{
+			Collection: Collection{ID: "01ABCDEFGHIJKLMNOPQRSTUVWX", Name: "2023 tax bundle", Term: "tax", DocumentCount: 3},
+			Documents:  []CollectionDocument{{ULID: "01ABCDEFGHIJKLMNOPQRSTUVWY", Name: "P60.pdf", Folder: "/tax", URL: "/document/view/01ABCDEFGHIJKLMNOPQRSTUVWY"}},
+			Missing:    2,
+		}},
+	}
+	for name, page := range pages {
+		if page.Render() == nil {
+			t.Errorf("%s: Render should return non-nil UI", name)
+		}
+	}
+}
+
+// TestCollectionPageURL tests the web UI links to a collection
+func TestCollectionPageURL(t *testing.T) {
+	collection := Collection{ID: "01ABCDEFGHIJKLMNOPQRSTUVWX", ShareToken: "a+b"}
+	if got := collectionPageURL(collection, false); got != "/collection?id=01ABCDEFGHIJKLMNOPQRSTUVWX" {
+		t.Errorf("Unexpected collection URL %q", got)
+	}
+	if got := collectionPageURL(collection, true); got != "/collection?share=a%2Bb" {
+		t.Errorf("Unexpected share URL %q", got)
+	}
+}
+
+// TestSearchPageSaveCollection tests the save as collection form on the search results
+func TestSearchPageSaveCollection(t *testing.T) {
+	page := &SearchPage{searchTerm: "tax", searched: true, collectionName: "2023 tax bundle"}
+	if page.renderSaveCollection() == nil {
+		t.Error("The save form should be rendered")
+	}
+	page.saved = &Collection{ID: "01ABCDEFGHIJKLMNOPQRSTUVWX", Name: "2023 tax bundle", ShareToken: "token"}
+	if page.renderSaveCollection() == nil {
+		t.Error("The saved collection links should be rendered")
+	}
+}
@@ -0,0 +1,177 @@
+package webapp
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// DocumentEditor lets a document be refiled from a list, so correcting where it lives does not need a
+// trip to another page. A saved change shows straight away and is rolled back if the server refuses it.
+type DocumentEditor struct {
+	app.Compo
+	ULID     string
+	FullPath string // the document's path when the list was loaded
+	Inline   bool   // render just the edit button, for rows that already show the name
+
+	folder string // folder shown since the last save, empty until one
+	open   bool
+	input  string
+	saving bool
+	err    string
+}
+
+// documentFolder is the folder part of a document path, whichever separator the server uses
+func documentFolder(fullPath string) string {
+	if i := strings.LastIndexAny(fullPath, `/\`); i >= 0 {
+		return fullPath[:i]
+	}
+	return ""
+}
+
+// documentName is the file name part of a document path
+func documentName(fullPath string) string {
+	return fullPath[strings.LastIndexAny(fullPath, `/\`)+1:]
+}
+
+// currentFolder is the folder the document is shown in
+func (d *DocumentEditor) currentFolder() string {
+	if d.folder != "" {
+		return d.folder
+	}
+	return documentFolder(d.FullPath)
+}
+
+// moveErrorMessage explains why the server refused a move
+func moveErrorMessage(status int) string {
+	switch status {
+	case 423:
+		return "Document is locked by someone else"
+	case 400:
+		return "Folder was not accepted"
+	default:
+		return fmt.Sprintf("Failed to move (status %d)", status)
+	}
+}
+
+func (d *DocumentEditor) onToggle(ctx app.Context, e app.Event) {
+	e.PreventDefault()
+	d.open = !d.open
+	d.input = d.currentFolder()
+	d.err = ""
+}
+
+func (d *DocumentEditor) onSave(ctx app.Context, e app.Event) {
+	e.PreventDefault()
+	folder := strings.TrimRight(strings.TrimSpace(d.input), `/\`)
+	if folder == "" {
+		d.err = "Enter a folder"
+		return
+	}
+	previous := d.currentFolder()
+	d.open = false
+	if folder == previous {
+		return
+	}
+	d.folder = folder
+	d.saving = true
+	d.err = ""
+
+	query := url.Values{"folder": {folder}, "id": {d.ULID}}
+	app.Window().Call("fetch", BuildAPIURL("/api/document/move/?"+query.Encode()), map[string]interface{}{
+		"method": "PATCH",
+	}).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+		if len(args) == 0 {
+			return nil
+		}
+		status := args[0].Get("status").Int()
+		ctx.Dispatch(func(ctx app.Context) {
+			d.saving = false
+			if status < 200 || status >= 300 {
+				d.folder = previous
+				d.err = moveErrorMessage(status)
+			}
+		})
+		return nil
+	})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+		ctx.Dispatch(func(ctx app.Context) {
+			d.saving = false
+			d.folder = previous
+			d.err = "Network error: Could not connect to server"
+		})
+		return nil
+	}))
+}
+
+// renderPopover is the edit form, shown under the button while it is open
+func (d *DocumentEditor) renderPopover() app.UI {
+	if !d.open {
+		return nil
+	}
+	inputID := "edit-folder-" + d.ULID
+	return app.Form().
+		Class("document-edit-popover").
+		Role("dialog").
+		Aria("label", "Edit "+documentName(d.FullPath)).
+		OnSubmit(d.onSave).
+		OnKeyDown(func(ctx app.Context, e app.Event) {
+			if e.Get("key").String() == "Escape" {
+				d.open = false
+			}
+		}).
+		Body(
+			app.Label().For(inputID).Text("Folder"),
+			app.Input().
+				ID(inputID).
+				Type("text").
+				Value(d.input).
+				AutoFocus(true).
+				OnInput(func(ctx app.Context, e app.Event) {
+					d.input = ctx.JSSrc().Get("value").String()
+				}),
+			app.Button().Type("submit").Class("btn btn-primary").Text("Save"),
+			app.Button().Type("button").Class("btn btn-secondary").Text("Cancel").OnClick(d.onToggle),
+		)
+}
+
+// Render shows the document's path, or just the button when Inline, with the edit popover
+func (d *DocumentEditor) Render() app.UI {
+	if d.ULID == "" {
+		if d.Inline {
+			return app.Span()
+		}
+		return app.P().Class("result-path").Text(d.FullPath)
+	}
+	editText := "Edit"
+	if d.saving {
+		editText = "Saving..."
+	}
+	edit := app.Button().
+		Class("document-edit").
+		Title("Change the folder this document is filed in").
+		Aria("expanded", d.open).
+		Disabled(d.saving).
+		Text(editText).
+		OnClick(d.onToggle)
+	errorUI := app.If(d.err != "", func() app.UI {
+		return app.Span().Class("error").Role("alert").Text(" " + d.err)
+	})
+
+	if d.Inline {
+		var movedUI app.UI
+		if d.folder != "" && d.folder != documentFolder(d.FullPath) {
+			movedUI = app.Span().Class("document-moved").Text("moved to " + d.folder)
+		}
+		return app.Span().Class("document-editor").Body(edit, movedUI, errorUI, d.renderPopover())
+	}
+	path := d.FullPath
+	if d.folder != "" {
+		path = d.folder + "/" + documentName(d.FullPath)
+	}
+	return app.Div().Class("document-editor").Body(
+		app.P().Class("result-path").Body(app.Text(path+" "), edit, errorUI),
+		d.renderPopover(),
+	)
+}
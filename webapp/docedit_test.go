@@ -0,0 +1,58 @@
+package webapp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+func TestDocumentFolderAndName(t *testing.T) {
+	for path, want := range map[string][2]string{
+		"/documents/Finance/bill.pdf":   {"/documents/Finance", "bill.pdf"},
+		`C:\documents\Finance\bill.pdf`: {`C:\documents\Finance`, "bill.pdf"},
+		"bill.pdf":                      {"", "bill.pdf"},
+	} {
+		if folder, name := documentFolder(path), documentName(path); folder != want[0] || name != want[1] {
+			t.Errorf("%s: expected %q and %q, got %q and %q", path, want[0], want[1], folder, name)
+		}
+	}
+}
+
+func TestDocumentEditorShowsSavedFolder(t *testing.T) {
+	// A search result whose folder was just changed
+	editor := &DocumentEditor{ULID: "01ABCDEFGHIJKLMNOPQRSTUVWX", FullPath: "/documents/Inbox/bill.pdf"}
+	editor.folder = "/documents/Finance"
+
+	// The new path shows before the server has answered
+	html := app.HTMLString(editor)
+	if !strings.Contains(html, "/documents/Finance/bill.pdf") || strings.Contains(html, "Inbox") {
+		t.Errorf("Expected the new path, got %s", html)
+	}
+
+	// A tree row notes where the document went
+	inline := &DocumentEditor{ULID: editor.ULID, FullPath: editor.FullPath, Inline: true}
+	inline.folder = "/documents/Finance"
+	if html := app.HTMLString(inline); !strings.Contains(html, "moved to /documents/Finance") {
+		t.Errorf("Expected the move noted, got %s", html)
+	}
+
+	// The popover is shown only while open, filled with the current folder
+	if strings.Contains(html, "document-edit-popover") {
+		t.Error("Expected the popover closed")
+	}
+	editor.open = true
+	editor.input = editor.currentFolder()
+	if html := app.HTMLString(editor); !strings.Contains(html, "document-edit-popover") || !strings.Contains(html, `value="/documents/Finance"`) {
+		t.Errorf("Expected the popover with the folder, got %s", html)
+	}
+}
+
+func TestMoveErrorMessage(t *testing.T) {
+	if !strings.Contains(moveErrorMessage(423), "locked") {
+		t.Error("Expected a locked document to be explained")
+	}
+	if moveErrorMessage(500) != "Failed to move (status 500)" {
+		t.Errorf("Unexpected message %q", moveErrorMessage(500))
+	}
+}
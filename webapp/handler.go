@@ -15,7 +15,14 @@ func Handler() http.Handler {
 	app.Route("/clean", func() app.Composer { return &App{} })
 	app.Route("/search", func() app.Composer { return &App{} })
 	app.Route("/wordcloud", func() app.Composer { return &App{} })
+	app.Route("/jobs", func() app.Composer { return &App{} })
+	app.Route("/stats", func() app.Composer { return &App{} })
 	app.Route("/about", func() app.Composer { return &App{} })
+	app.Route("/setup", func() app.Composer { return &App{} })
+	app.Route("/collection", func() app.Composer { return &App{} })
+	app.Route("/scan", func() app.Composer { return &App{} })
+	app.Route("/login", func() app.Composer { return &App{} })
+	app.Route("/capture", func() app.Composer { return &App{} })
 	app.RunWhenOnBrowser()
 
 	// Create and return the handler
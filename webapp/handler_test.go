@@ -39,10 +39,34 @@ func TestHandlerRoutes(t *testing.T) {
 			name: "Word Cloud page",
 			path: "/wordcloud",
 		},
+		{
+			name: "Jobs page",
+			path: "/jobs",
+		},
 		{
 			name: "About page",
 			path: "/about",
 		},
+		{
+			name: "Setup page",
+			path: "/setup",
+		},
+		{
+			name: "Collection page",
+			path: "/collection",
+		},
+		{
+			name: "Scan page",
+			path: "/scan",
+		},
+		{
+			name: "Capture page",
+			path: "/capture",
+		},
+		{
+			name: "Login page",
+			path: "/login",
+		},
 	}
 
 	for _, tt := range tests {
@@ -17,6 +17,8 @@ type Document struct {
 	Hash         string `json:"Hash"`
 	ULID         string `json:"ULID"`
 	DocumentType string `json:"DocumentType"`
+	MIMEType     string `json:"MIMEType"`
+	OCRStatus    string `json:"OCRStatus"`
 	FullText     string `json:"FullText"`
 	URL          string `json:"URL"`
 }
@@ -83,6 +85,14 @@ func (h *HomePage) fetchDocuments(ctx app.Context, page int) {
 						h.totalCount = resp.TotalCount
 						h.hasNext = resp.HasNext
 						h.hasPrevious = resp.HasPrevious
+						if anyOCRPending(h.documents) {
+							// Keep the badges current until OCR finishes, unless another page has been chosen
+							ctx.After(ocrPollInterval, func(ctx app.Context) {
+								if h.currentPage == page && !h.loading {
+									h.fetchDocuments(ctx, page)
+								}
+							})
+						}
 					}
 					h.loading = false
 				})
@@ -140,6 +150,7 @@ func (h *HomePage) Render() app.UI {
 			),
 			content,
 			h.renderPagination(),
+			&ActivityWidget{},
 		)
 }
 
@@ -201,6 +212,7 @@ func (d *DocumentCard) Render() app.UI {
 			),
 			app.Div().Class("document-info").Body(
 				app.H3().Text(d.Document.Name),
+				ocrBadge(d.Document.OCRStatus),
 				app.P().
 					Class("document-date").
 					Text("Ingested: "+d.Document.IngressTime),
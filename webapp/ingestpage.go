@@ -96,6 +96,9 @@ func (i *IngestPage) runIngest(ctx app.Context) {
 					i.running = false
 					if status >= 200 && status < 300 {
 						i.result = "Ingestion completed successfully! " + text
+					} else if status == 409 {
+						// Another ingestion job is still working through the ingress folder
+						i.result = "Ingestion is already running, follow it on the Jobs page: " + text
 					} else {
 						i.error = "Ingestion failed: " + text
 					}
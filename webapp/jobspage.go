@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/drummonds/godocs/internal/dto"
 	"github.com/maxence-charriere/go-app/v10/pkg/app"
 )
 
@@ -66,19 +67,20 @@ func (j *JobsPage) Render() app.UI {
 			),
 
 			j.renderStatus(),
+			&ClientErrorList{},
 		)
 }
 
 // renderStatus renders the jobs list or status messages
 func (j *JobsPage) renderStatus() app.UI {
 	if j.loading && len(j.jobs) == 0 {
-		return app.Div().Class("loading").Body(
+		return statusMessage("loading",
 			app.Text("Loading jobs..."),
 		)
 	}
 
 	if j.error != "" {
-		return app.Div().Class("error").Body(
+		return alertMessage(
 			app.Text("Error: " + j.error),
 		)
 	}
@@ -110,8 +112,9 @@ func (j *JobsPage) renderJobsList() []app.UI {
 func (j *JobsPage) renderJob(job *Job) app.UI {
 	statusClass := "job-card job-" + job.Status
 
-	return app.Div().
+	return app.Article().
 		Class(statusClass).
+		Aria("label", j.formatJobType(job.Type)+", "+job.Status).
 		Body(
 			app.Div().Class("job-header").Body(
 				app.Div().Class("job-type").Body(
@@ -127,11 +130,18 @@ func (j *JobsPage) renderJob(job *Job) app.UI {
 			app.If(job.Status == "running",
 				func() app.UI {
 					return app.Div().Class("job-progress").Body(
-						app.Div().Class("progress-bar").Body(
-							app.Div().
-								Class("progress-fill").
-								Style("width", fmt.Sprintf("%d%%", job.Progress)),
-						),
+						app.Div().
+							Class("progress-bar").
+							Role("progressbar").
+							Aria("label", j.formatJobType(job.Type)).
+							Aria("valuemin", 0).
+							Aria("valuemax", 100).
+							Aria("valuenow", job.Progress).
+							Body(
+								app.Div().
+									Class("progress-fill").
+									Style("width", fmt.Sprintf("%d%%", job.Progress)),
+							),
 						app.Div().Class("progress-text").Body(
 							app.Text(fmt.Sprintf("%d%% - %s", job.Progress, job.CurrentStep)),
 						),
@@ -190,6 +200,18 @@ func (j *JobsPage) formatJobType(jobType string) string {
 		return "Word Cloud Recalculation"
 	case "search_reindex":
 		return "Search Reindex"
+	case "url_repair":
+		return "Document URL Repair"
+	case "backup":
+		return "Metadata Backup"
+	case "rehash":
+		return "Document Rehash"
+	case "optimise":
+		return "Storage Optimisation"
+	case "export":
+		return "Full Export"
+	case "fingerprint":
+		return "Duplicate Scan"
 	default:
 		return strings.Title(jobType)
 	}
@@ -265,6 +287,12 @@ func (j *JobsPage) formatResult(result string) string {
 	if val, ok := data["moved"]; ok && val.(float64) > 0 {
 		parts = append(parts, fmt.Sprintf("Moved: %.0f", val))
 	}
+	var withItems struct {
+		Items []dto.JobItem `json:"items"`
+	}
+	if json.Unmarshal([]byte(result), &withItems) == nil && len(withItems.Items) > 0 {
+		parts = append(parts, summariseJobItems(withItems.Items))
+	}
 
 	if len(parts) > 0 {
 		return strings.Join(parts, ", ")
@@ -273,6 +301,37 @@ func (j *JobsPage) formatResult(result string) string {
 	return result
 }
 
+// jobItemLabels describe the error codes a job can record against a file
+var jobItemLabels = map[dto.ErrorCode]string{
+	dto.CodeDuplicate:        "duplicate",
+	dto.CodeUnsupportedType:  "unsupported type",
+	dto.CodeOCRFailed:        "OCR failed",
+	dto.CodeExtractionFailed: "no text extracted",
+	dto.CodeStorageFailed:    "storage failed",
+	dto.CodeQuotaExceeded:    "over quota",
+}
+
+// summariseJobItems counts the files a job could not process cleanly by error code, in the order each code first appears
+func summariseJobItems(items []dto.JobItem) string {
+	counts := make(map[dto.ErrorCode]int)
+	var order []dto.ErrorCode
+	for _, item := range items {
+		if counts[item.Code] == 0 {
+			order = append(order, item.Code)
+		}
+		counts[item.Code]++
+	}
+	parts := make([]string, 0, len(order))
+	for _, code := range order {
+		label, ok := jobItemLabels[code]
+		if !ok {
+			label = "failed"
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", counts[code], label))
+	}
+	return "Issues: " + strings.Join(parts, ", ")
+}
+
 // onRefreshClick handles the refresh button click
 func (j *JobsPage) onRefreshClick(ctx app.Context, e app.Event) {
 	j.loadJobs(ctx)
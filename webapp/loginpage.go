@@ -0,0 +1,169 @@
+package webapp
+
+import (
+	"encoding/json"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// LoginPage signs in when the server has WEB_UI_AUTH on. The session is kept in an HttpOnly cookie set by
+// the server, so the page only needs to send the username and password.
+type LoginPage struct {
+	app.Compo
+	username string
+	password string
+	busy     bool
+	error    string
+}
+
+// Render renders the sign-in form
+func (l *LoginPage) Render() app.UI {
+	return renderLoginForm(l.username, l.busy, l.error, l.onInput, l.onSubmit)
+}
+
+// renderLoginForm renders the sign-in form with any error from the last attempt
+func renderLoginForm(username string, busy bool, errorMsg string, onInput func(field string) app.EventHandler, onSubmit app.EventHandler) app.UI {
+	var problem app.UI = app.Text("")
+	if errorMsg != "" {
+		problem = alertMessage(app.Text(errorMsg))
+	}
+	label := "Sign in"
+	if busy {
+		label = "Signing in..."
+	}
+	return app.Div().Class("login-page").Body(
+		app.H2().Text("Sign in to godocs"),
+		problem,
+		app.Form().OnSubmit(onSubmit).Body(
+			app.Div().Class("setup-field").Body(
+				app.Label().For("login-username").Text("Username"),
+				app.Input().ID("login-username").Type("text").AutoComplete(true).Name("username").
+					Value(username).AutoFocus(true).OnChange(onInput("username")),
+			),
+			app.Div().Class("setup-field").Body(
+				app.Label().For("login-password").Text("Password"),
+				app.Input().ID("login-password").Type("password").Name("password").OnChange(onInput("password")),
+			),
+			app.Button().Type("submit").Class("btn-primary").Disabled(busy).Text(label),
+		),
+	)
+}
+
+// onInput keeps a form field up to date as it is typed
+func (l *LoginPage) onInput(field string) app.EventHandler {
+	return func(ctx app.Context, e app.Event) {
+		value := ctx.JSSrc().Get("value").String()
+		if field == "username" {
+			l.username = value
+		} else {
+			l.password = value
+		}
+	}
+}
+
+// onSubmit posts the credentials and goes to the home page once signed in
+func (l *LoginPage) onSubmit(ctx app.Context, e app.Event) {
+	e.PreventDefault()
+	body, _ := json.Marshal(map[string]string{"username": l.username, "password": l.password})
+	l.busy = true
+	l.error = ""
+	ctx.Async(func() {
+		res := app.Window().Call("fetch", BuildAPIURL("/api/auth/login"), map[string]any{
+			"method":      "POST",
+			"headers":     map[string]any{"Content-Type": "application/json"},
+			"body":        string(body),
+			"credentials": "include",
+		})
+		res.Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			ok := args[0].Get("ok").Bool()
+			args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+				if len(args) == 0 {
+					return nil
+				}
+				jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+				var result dto.ErrorResponse
+				json.Unmarshal([]byte(jsonStr), &result)
+				ctx.Dispatch(func(ctx app.Context) {
+					l.busy = false
+					if !ok {
+						l.error = result.Error
+						return
+					}
+					l.password = ""
+					// A full load, so every component fetches again with the new session
+					app.Window().Get("location").Set("href", "/")
+				})
+				return nil
+			}))
+			return nil
+		})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+			ctx.Dispatch(func(ctx app.Context) {
+				l.busy = false
+				l.error = "Network error: Could not connect to server"
+			})
+			return nil
+		}))
+	})
+}
+
+// SignOut shows who is signed in, with a button to sign out, when the server has WEB_UI_AUTH on
+type SignOut struct {
+	app.Compo
+	username string
+}
+
+// OnMount loads who is signed in
+func (s *SignOut) OnMount(ctx app.Context) {
+	app.Window().Call("fetch", BuildAPIURL("/api/auth/status"), map[string]any{"credentials": "include"}).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+		if len(args) == 0 || !args[0].Get("ok").Bool() {
+			return nil
+		}
+		args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+			var status dto.AuthStatus
+			if err := json.Unmarshal([]byte(jsonStr), &status); err != nil {
+				app.Log("Failed to parse sign-in status:", err)
+				return nil
+			}
+			ctx.Dispatch(func(ctx app.Context) {
+				s.username = status.Username
+			})
+			return nil
+		}))
+		return nil
+	}))
+}
+
+// Render renders the signed-in user and sign-out button, or nothing when nobody is signed in
+func (s *SignOut) Render() app.UI {
+	return renderSignOut(s.username, s.onSignOut)
+}
+
+// renderSignOut renders the signed-in username with a sign-out button
+func renderSignOut(username string, onSignOut app.EventHandler) app.UI {
+	if username == "" {
+		return app.Span().Class("signout-empty")
+	}
+	return app.Span().Class("signout").Body(
+		app.Span().Class("signout-user").Text(username),
+		app.Button().Class("signout-button").OnClick(onSignOut).Text("Sign out"),
+	)
+}
+
+// onSignOut ends the session and returns to the login page
+func (s *SignOut) onSignOut(ctx app.Context, e app.Event) {
+	app.Window().Call("fetch", BuildAPIURL("/api/auth/logout"), map[string]any{
+		"method":      "POST",
+		"credentials": "include",
+	}).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+		app.Window().Get("location").Set("href", "/login")
+		return nil
+	}))
+}
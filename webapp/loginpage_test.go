@@ -0,0 +1,43 @@
+package webapp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+func TestLoginForm(t *testing.T) {
+	noInput := func(string) app.EventHandler { return func(app.Context, app.Event) {} }
+	noSubmit := func(app.Context, app.Event) {}
+
+	// The form after a failed sign-in
+	html := app.HTMLString(renderLoginForm("alice", false, "Wrong username or password", noInput, noSubmit))
+
+	// It keeps the username, labels both fields, and announces the error
+	for _, want := range []string{`value="alice"`, `for="login-username"`, `type="password"`, `role="alert"`, "Wrong username or password"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected %s in %s", want, html)
+		}
+	}
+
+	// The button is disabled while signing in
+	if html := app.HTMLString(renderLoginForm("", true, "", noInput, noSubmit)); !strings.Contains(html, "Signing in...") || !strings.Contains(html, "disabled") {
+		t.Errorf("Expected a disabled button, got %s", html)
+	}
+}
+
+func TestSignOut(t *testing.T) {
+	t.Run("Signed-in user is shown with a sign-out button", func(t *testing.T) {
+		html := app.HTMLString(renderSignOut("alice", func(app.Context, app.Event) {}))
+		if !strings.Contains(html, "alice") || !strings.Contains(html, "Sign out") {
+			t.Errorf("Expected alice and a sign-out button, got %s", html)
+		}
+	})
+
+	t.Run("Nothing shows when nobody is signed in", func(t *testing.T) {
+		if html := app.HTMLString(renderSignOut("", nil)); strings.Contains(html, "Sign out") {
+			t.Errorf("Expected no sign-out button, got %s", html)
+		}
+	})
+}
@@ -19,6 +19,7 @@ type NavBar struct {
 	app.Compo
 	activeJobCount int
 	refreshTicker  *time.Ticker
+	sidebarOpen    bool
 }
 
 // Render renders the navigation bar
@@ -29,12 +30,15 @@ func (n *NavBar) Render() app.UI {
 			app.Button().
 				Class("hamburger-menu").
 				ID("menu-toggle").
+				Aria("label", "Menu").
+				Aria("controls", "sidebar").
+				Aria("expanded", n.sidebarOpen).
 				OnClick(n.onMenuToggle).
 				Body(
 					// Three horizontal lines for hamburger menu
-					app.Span().Class("hamburger-line"),
-					app.Span().Class("hamburger-line"),
-					app.Span().Class("hamburger-line"),
+					app.Span().Class("hamburger-line").Aria("hidden", true),
+					app.Span().Class("hamburger-line").Aria("hidden", true),
+					app.Span().Class("hamburger-line").Aria("hidden", true),
 				),
 			app.Div().Class("navbar-brand").Body(
 				app.H1().Text("godocs"),
@@ -42,7 +46,7 @@ func (n *NavBar) Render() app.UI {
 					app.Text(n.getVersionInfo()),
 				),
 			),
-			app.Div().Class("navbar-menu").Body(
+			app.Div().Class("navbar-menu").Role("navigation").Aria("label", "Main").Body(
 				app.A().
 					Href("/").
 					Class("navbar-item").
@@ -68,27 +72,18 @@ func (n *NavBar) Render() app.UI {
 					Class("navbar-item").
 					Body(app.Text("Jobs")),
 			),
+			&SignOut{},
 		)
 }
 
 // onMenuToggle handles the hamburger menu click
 func (n *NavBar) onMenuToggle(ctx app.Context, e app.Event) {
-	// Dispatch a custom event to toggle the sidebar
-	ctx.Dispatch(func(ctx app.Context) {
-		ctx.LocalStorage().Set("sidebar-open", !n.isSidebarOpen(ctx))
-		ctx.Reload()
-	})
-}
-
-// isSidebarOpen checks if the sidebar is currently open
-func (n *NavBar) isSidebarOpen(ctx app.Context) bool {
-	var isOpen bool
-	ctx.LocalStorage().Get("sidebar-open", &isOpen)
-	return isOpen
+	setSidebarOpen(ctx, !n.sidebarOpen)
 }
 
 // OnMount is called when the component is mounted
 func (n *NavBar) OnMount(ctx app.Context) {
+	ctx.ObserveState(sidebarState, &n.sidebarOpen)
 	n.loadActiveJobCount(ctx)
 
 	// Start auto-refresh every 5 seconds
@@ -0,0 +1,37 @@
+package webapp
+
+import (
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// ocrPollInterval is how often the home page reloads while a document on it is still waiting on OCR
+const ocrPollInterval = 5 * time.Second
+
+// ocrBadge shows a document's OCR status. Documents that needed no OCR, or were stored before the
+// status was recorded, get no badge.
+func ocrBadge(status string) app.UI {
+	var label, title string
+	switch status {
+	case "pending":
+		label, title = "OCR pending", "Text recognition has not finished, so search cannot find this document yet"
+	case "failed":
+		label, title = "OCR failed", "No text could be recognised, so search cannot find this document; rescan it to try again"
+	case "done":
+		label, title = "OCR", "The text was recognised by OCR and may contain mistakes"
+	default:
+		return nil
+	}
+	return app.Span().Class("ocr-badge ocr-badge-" + status).Title(title).Text(label)
+}
+
+// anyOCRPending reports whether any of documents is still waiting on OCR
+func anyOCRPending(documents []Document) bool {
+	for _, document := range documents {
+		if document.OCRStatus == "pending" {
+			return true
+		}
+	}
+	return false
+}
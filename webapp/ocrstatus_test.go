@@ -0,0 +1,32 @@
+package webapp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+func TestOCRBadge(t *testing.T) {
+	// Pending, failed and OCR'd documents get a badge; the rest do not
+	for status, label := range map[string]string{"pending": "OCR pending", "failed": "OCR failed", "done": "OCR"} {
+		html := app.HTMLString(ocrBadge(status))
+		if !strings.Contains(html, "ocr-badge-"+status) || !strings.Contains(html, ">"+label+"<") {
+			t.Errorf("Expected a %q badge for %s, got %s", label, status, html)
+		}
+	}
+	for _, status := range []string{"skipped", ""} {
+		if ocrBadge(status) != nil {
+			t.Errorf("Expected no badge for %q", status)
+		}
+	}
+}
+
+func TestAnyOCRPending(t *testing.T) {
+	if anyOCRPending([]Document{{OCRStatus: "done"}, {OCRStatus: ""}}) {
+		t.Error("Expected nothing pending")
+	}
+	if !anyOCRPending([]Document{{OCRStatus: "done"}, {OCRStatus: "pending"}}) {
+		t.Error("Expected a pending document to be noticed")
+	}
+}
@@ -0,0 +1,56 @@
+package webapp
+
+import (
+	"encoding/json"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// ReadOnlyBanner says when the server is in read-only mode for maintenance, so users know why uploads,
+// moves and deletions are refused while browsing and search carry on
+type ReadOnlyBanner struct {
+	app.Compo
+	status dto.ReadOnlyStatus
+}
+
+// OnMount loads whether the server is read-only
+func (b *ReadOnlyBanner) OnMount(ctx app.Context) {
+	app.Window().Call("fetch", BuildAPIURL("/api/read-only")).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+		if len(args) == 0 || !args[0].Get("ok").Bool() {
+			return nil
+		}
+		args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+			var status dto.ReadOnlyStatus
+			if err := json.Unmarshal([]byte(jsonStr), &status); err != nil {
+				app.Log("Failed to parse read-only status:", err)
+				return nil
+			}
+			ctx.Dispatch(func(ctx app.Context) {
+				b.status = status
+			})
+			return nil
+		}))
+		return nil
+	}))
+}
+
+// renderReadOnlyBanner shows the maintenance message while the server is read-only
+func renderReadOnlyBanner(status dto.ReadOnlyStatus) app.UI {
+	if !status.ReadOnly {
+		return app.Div().Class("readonly-banner-empty")
+	}
+	return app.Div().Class("readonly-banner").Role("status").Body(
+		app.Strong().Text("Read-only mode"),
+		app.Text(": "+status.Message),
+	)
+}
+
+// Render renders the banner, or an empty placeholder when changes are allowed
+func (b *ReadOnlyBanner) Render() app.UI {
+	return renderReadOnlyBanner(b.status)
+}
@@ -0,0 +1,24 @@
+package webapp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+func TestReadOnlyBanner(t *testing.T) {
+	t.Run("Banner shows the maintenance message while read-only", func(t *testing.T) {
+		html := app.HTMLString(renderReadOnlyBanner(dto.ReadOnlyStatus{ReadOnly: true, Message: "Moving storage, back at 10:00"}))
+		if !strings.Contains(html, "readonly-banner\"") || !strings.Contains(html, "Moving storage, back at 10:00") {
+			t.Errorf("Expected the maintenance message, got %s", html)
+		}
+	})
+
+	t.Run("Nothing shows once changes are allowed", func(t *testing.T) {
+		if html := app.HTMLString(renderReadOnlyBanner(dto.ReadOnlyStatus{})); strings.Contains(html, "readonly-banner\"") {
+			t.Errorf("Expected no banner, got %s", html)
+		}
+	})
+}
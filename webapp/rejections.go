@@ -0,0 +1,113 @@
+package webapp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// rejectionsDismissedKey is the local storage key holding when the newest rejection dismissed was rejected
+const rejectionsDismissedKey = "godocs.rejectionsDismissed"
+
+// rejectionBannerShown is how many rejected files the banner names before summing up the rest
+const rejectionBannerShown = 3
+
+// RejectionBanner tells the user about files ingestion left in the ingress folder, so a spreadsheet that
+// never appeared is explained. It shows only rejections newer than the last ones dismissed.
+type RejectionBanner struct {
+	app.Compo
+	rejections []dto.IngestRejection
+	dismissed  string
+}
+
+// OnMount loads the rejections and when they were last dismissed
+func (b *RejectionBanner) OnMount(ctx app.Context) {
+	ctx.LocalStorage().Get(rejectionsDismissedKey, &b.dismissed)
+	app.Window().Call("fetch", BuildAPIURL("/api/ingest/rejections")).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+		if len(args) == 0 || !args[0].Get("ok").Bool() {
+			return nil
+		}
+		args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+			var response dto.IngestRejections
+			if err := json.Unmarshal([]byte(jsonStr), &response); err != nil {
+				app.Log("Failed to parse ingest rejections:", err)
+				return nil
+			}
+			ctx.Dispatch(func(ctx app.Context) {
+				b.rejections = response.Rejections
+			})
+			return nil
+		}))
+		return nil
+	}))
+}
+
+// onDismiss hides the banner until a file is rejected after the newest one shown
+func (b *RejectionBanner) onDismiss(ctx app.Context, e app.Event) {
+	if len(b.rejections) == 0 {
+		return
+	}
+	b.dismissed = b.rejections[0].RejectedAt
+	if err := ctx.LocalStorage().Set(rejectionsDismissedKey, b.dismissed); err != nil {
+		app.Log("Failed to remember dismissed rejections:", err)
+	}
+}
+
+// newRejections returns the rejections made after dismissed, newest first as given. RFC 3339 times in
+// UTC sort as strings.
+func newRejections(rejections []dto.IngestRejection, dismissed string) []dto.IngestRejection {
+	for i, rejection := range rejections {
+		if rejection.RejectedAt <= dismissed {
+			return rejections[:i]
+		}
+	}
+	return rejections
+}
+
+// renderRejectionBanner names the newest rejected files and why they were left, with a dismiss button
+func renderRejectionBanner(rejections []dto.IngestRejection, onDismiss app.EventHandler) app.UI {
+	if len(rejections) == 0 {
+		return app.Div().Class("rejection-banner-empty")
+	}
+	shown := rejections
+	if len(shown) > rejectionBannerShown {
+		shown = shown[:rejectionBannerShown]
+	}
+	heading := "A file in the ingress folder was not ingested"
+	if len(rejections) > 1 {
+		heading = fmt.Sprintf("%d files in the ingress folder were not ingested", len(rejections))
+	}
+	return app.Div().Class("rejection-banner").Role("status").Body(
+		app.Div().Class("rejection-banner-text").Body(
+			app.Strong().Text(heading),
+			app.Ul().Body(
+				app.Range(shown).Slice(func(i int) app.UI {
+					return app.Li().Title(shown[i].Path).Body(
+						app.Span().Class("rejection-name").Text(shown[i].Name),
+						app.Text(": "+shown[i].Reason),
+					)
+				}),
+				app.If(len(rejections) > len(shown), func() app.UI {
+					return app.Li().Text(fmt.Sprintf("and %d more", len(rejections)-len(shown)))
+				}),
+			),
+		),
+		app.Button().
+			Class("rejection-dismiss").
+			Aria("label", "Dismiss").
+			Title("Hide until another file is rejected").
+			Text("×").
+			OnClick(onDismiss),
+	)
+}
+
+// Render renders the banner, or an empty placeholder when there is nothing new
+func (b *RejectionBanner) Render() app.UI {
+	return renderRejectionBanner(newRejections(b.rejections, b.dismissed), b.onDismiss)
+}
@@ -0,0 +1,38 @@
+package webapp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+func TestRejectionBanner(t *testing.T) {
+	rejections := []dto.IngestRejection{
+		{Path: "/ingress/budget.xlsx", Name: "budget.xlsx", Reason: "unsupported file type: .xlsx", RejectedAt: "2024-06-01T12:02:00Z"},
+		{Path: "/ingress/notes.docx", Name: "notes.docx", Reason: "unsupported file type: .docx", RejectedAt: "2024-06-01T12:01:00Z"},
+	}
+
+	t.Run("Only rejections after the last dismissed are new", func(t *testing.T) {
+		if fresh := newRejections(rejections, "2024-06-01T12:01:00Z"); len(fresh) != 1 || fresh[0].Name != "budget.xlsx" {
+			t.Errorf("Expected only the spreadsheet to be new, got %+v", fresh)
+		}
+		if fresh := newRejections(rejections, ""); len(fresh) != 2 {
+			t.Errorf("Expected both to be new before any dismissal, got %+v", fresh)
+		}
+	})
+
+	t.Run("Banner names each file and why it was left", func(t *testing.T) {
+		html := app.HTMLString(renderRejectionBanner(rejections, nil))
+		if !strings.Contains(html, "2 files in the ingress folder were not ingested") || !strings.Contains(html, "unsupported file type: .xlsx") || !strings.Contains(html, "notes.docx") {
+			t.Errorf("Expected the files and reasons, got %s", html)
+		}
+	})
+
+	t.Run("Nothing new shows no banner", func(t *testing.T) {
+		if html := app.HTMLString(renderRejectionBanner(newRejections(rejections, "2024-06-01T12:02:00Z"), nil)); strings.Contains(html, "rejection-banner\"") {
+			t.Errorf("Expected no banner once dismissed, got %s", html)
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package webapp
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+	"github.com/oklog/ulid/v2"
+)
+
+// documentViewPrefix is the path of the document viewer, followed by a document ULID
+const documentViewPrefix = "/document/view/"
+
+// scanInterval is how often a camera frame is checked for a QR code
+const scanInterval = 300 * time.Millisecond
+
+// scannedDocumentURL turns what a QR code or the manual box holds into the document viewer path:
+// either a document view URL, from any host since BASE_URL may differ from this UI, or a bare ULID
+func scannedDocumentURL(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if id, err := ulid.ParseStrict(text); err == nil {
+		return documentViewPrefix + id.String(), true
+	}
+	parsed, err := url.Parse(text)
+	if err != nil {
+		return "", false
+	}
+	// A reverse proxy may mount godocs below a path, so the viewer need not start the path
+	index := strings.Index(parsed.Path, documentViewPrefix)
+	if index < 0 {
+		return "", false
+	}
+	target := parsed.Path[index:]
+	if _, err := ulid.ParseStrict(strings.TrimPrefix(target, documentViewPrefix)); err != nil {
+		return "", false
+	}
+	if parsed.RawQuery != "" {
+		target += "?" + parsed.RawQuery
+	}
+	return target, true
+}
+
+// ScanPage reads a document QR code, from a cover sheet or label, with the camera and opens the document
+type ScanPage struct {
+	app.Compo
+	scanning bool
+	error    string
+	manual   string
+	stream   app.Value
+	detector app.Value
+}
+
+// OnDismount turns the camera off when leaving the page
+func (p *ScanPage) OnDismount() {
+	p.stopCamera()
+}
+
+// stopCamera stops every track of the camera stream
+func (p *ScanPage) stopCamera() {
+	p.scanning = false
+	if p.stream == nil || !p.stream.Truthy() {
+		return
+	}
+	tracks := p.stream.Call("getTracks")
+	for i := 0; i < tracks.Length(); i++ {
+		tracks.Index(i).Call("stop")
+	}
+	p.stream = nil
+}
+
+// startCamera asks for the rear camera and starts looking for QR codes in it
+func (p *ScanPage) startCamera(ctx app.Context, e app.Event) {
+	mediaDevices := app.Window().Get("navigator").Get("mediaDevices")
+	if !mediaDevices.Truthy() {
+		p.error = "No camera is available here; the page must be opened over HTTPS or from localhost"
+		return
+	}
+	detectorClass := app.Window().Get("BarcodeDetector")
+	if !detectorClass.Truthy() {
+		p.error = "This browser cannot read QR codes; enter the link or document ID below instead"
+		return
+	}
+	p.detector = detectorClass.New(map[string]any{"formats": []any{"qr_code"}})
+	p.error = ""
+
+	constraints := map[string]any{"video": map[string]any{"facingMode": "environment"}, "audio": false}
+	mediaDevices.Call("getUserMedia", constraints).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+		if len(args) == 0 {
+			return nil
+		}
+		stream := args[0]
+		ctx.Dispatch(func(ctx app.Context) {
+			p.stream = stream
+			p.scanning = true
+			video := app.Window().GetElementByID("scan-video")
+			video.Set("srcObject", stream)
+			video.Call("play")
+			ctx.After(scanInterval, p.scanFrame)
+		})
+		return nil
+	})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+		ctx.Dispatch(func(ctx app.Context) {
+			p.error = "Could not open the camera; allow camera access and try again"
+		})
+		return nil
+	}))
+}
+
+// onStopClick turns the camera off
+func (p *ScanPage) onStopClick(ctx app.Context, e app.Event) {
+	p.stopCamera()
+}
+
+// scanFrame looks for a QR code in the current video frame, and schedules the next look until one is found
+func (p *ScanPage) scanFrame(ctx app.Context) {
+	if !p.scanning {
+		return
+	}
+	video := app.Window().GetElementByID("scan-video")
+	// Until the first frame arrives there is nothing to detect
+	if video.Get("readyState").Int() < 2 {
+		ctx.After(scanInterval, p.scanFrame)
+		return
+	}
+	p.detector.Call("detect", video).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+		var found []string
+		if len(args) > 0 {
+			for i := 0; i < args[0].Length(); i++ {
+				found = append(found, args[0].Index(i).Get("rawValue").String())
+			}
+		}
+		ctx.Dispatch(func(ctx app.Context) {
+			for _, text := range found {
+				if p.open(text) {
+					return
+				}
+				p.error = "That QR code is not a godocs document: " + text
+			}
+			ctx.After(scanInterval, p.scanFrame)
+		})
+		return nil
+	})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+		ctx.Dispatch(func(ctx app.Context) {
+			ctx.After(scanInterval, p.scanFrame)
+		})
+		return nil
+	}))
+}
+
+// open goes to the document viewer for a scanned or typed code, reporting whether it was a document
+func (p *ScanPage) open(text string) bool {
+	target, ok := scannedDocumentURL(text)
+	if !ok {
+		return false
+	}
+	p.stopCamera()
+	// The viewer is served by the API, not this app, so this is a full page load
+	app.Window().Get("location").Set("href", BuildAPIURL(target))
+	return true
+}
+
+// onManualChange keeps the typed link or ID
+func (p *ScanPage) onManualChange(ctx app.Context, e app.Event) {
+	p.manual = ctx.JSSrc().Get("value").String()
+}
+
+// onManualSubmit opens the typed link or ID
+func (p *ScanPage) onManualSubmit(ctx app.Context, e app.Event) {
+	e.PreventDefault()
+	if !p.open(p.manual) {
+		p.error = "Enter a document link or a 26 character document ID"
+	}
+}
+
+// Render renders the scan page
+func (p *ScanPage) Render() app.UI {
+	var button app.UI
+	if p.scanning {
+		button = app.Button().Class("btn-secondary").OnClick(p.onStopClick).Text("Stop camera")
+	} else {
+		button = app.Button().Class("btn-primary").OnClick(p.startCamera).Text("📷 Start camera")
+	}
+	var errorUI app.UI
+	if p.error != "" {
+		errorUI = app.Div().Class("error").Text(p.error)
+	}
+
+	return app.Div().Class("scan-page").Body(
+		app.H2().Text("Scan a QR code"),
+		app.P().Body(
+			app.Text("Point the camera at the QR code on a cover sheet or label to open the stored document. To add a paper document instead, "),
+			app.A().Href("/capture").Text("scan it with the camera"),
+			app.Text("."),
+		),
+		button,
+		errorUI,
+		// Always rendered so the camera stream has somewhere to go as soon as it opens
+		app.Video().
+			ID("scan-video").
+			Class("scan-video").
+			Hidden(!p.scanning).
+			Muted(true).
+			Attr("playsinline", true),
+		app.Form().Class("scan-manual").OnSubmit(p.onManualSubmit).Body(
+			app.Label().For("scan-manual-input").Text("Or enter a document link or ID"),
+			app.Input().
+				ID("scan-manual-input").
+				Type("text").
+				Value(p.manual).
+				Placeholder("https://docs.example.com/document/view/01H...").
+				OnInput(p.onManualChange),
+			app.Button().Class("btn-primary").Type("submit").Text("Open"),
+		),
+	)
+}
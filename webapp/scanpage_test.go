@@ -0,0 +1,40 @@
+package webapp
+
+import "testing"
+
+// TestScannedDocumentURL tests which scanned codes open a document, and where
+func TestScannedDocumentURL(t *testing.T) {
+	tests := []struct {
+		text   string
+		target string
+		ok     bool
+	}{
+		{"https://docs.example.com/document/view/01ARZ3NDEKTSV4RRFFQ69G5FAV", "/document/view/01ARZ3NDEKTSV4RRFFQ69G5FAV", true},
+		{"http://localhost:8000/godocs/document/view/01ARZ3NDEKTSV4RRFFQ69G5FAV?expires=1&signature=abc", "/document/view/01ARZ3NDEKTSV4RRFFQ69G5FAV?expires=1&signature=abc", true},
+		{" 01arz3ndektsv4rrffq69g5fav\n", "/document/view/01ARZ3NDEKTSV4RRFFQ69G5FAV", true},
+		{"https://docs.example.com/document/view/not-a-ulid", "", false},
+		{"https://example.com/01ARZ3NDEKTSV4RRFFQ69G5FAV", "", false},
+		{"WIFI:S:home;T:WPA;P:secret;;", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		target, ok := scannedDocumentURL(tt.text)
+		if target != tt.target || ok != tt.ok {
+			t.Errorf("scannedDocumentURL(%q) = %q, %v; expected %q, %v", tt.text, target, ok, tt.target, tt.ok)
+		}
+	}
+}
+
+// TestScanPageRenderStates tests that the scan page renders with and without the camera on
+func TestScanPageRenderStates(t *testing.T) {
+	pages := map[string]*ScanPage{
+		"idle":     {},
+		"scanning": {scanning: true},
+		"error":    {error: "This browser cannot read QR codes", manual: "01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+	}
+	for name, page := range pages {
+		if page.Render() == nil {
+			t.Errorf("The %s scan page should render", name)
+		}
+	}
+}
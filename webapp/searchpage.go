@@ -4,10 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"path"
+	"strings"
+	"time"
 
+	"github.com/drummonds/godocs/internal/dto"
 	"github.com/maxence-charriere/go-app/v10/pkg/app"
 )
 
+// suggestDelay is how long typing must pause before the search box asks for completions
+const suggestDelay = 250 * time.Millisecond
+
+// SearchCompletions is the autocomplete response for what has been typed in the search box
+type SearchCompletions = dto.SearchCompletions
+
+// SuggestedDocument is a document whose name matches what has been typed
+type SuggestedDocument = dto.SuggestedDocument
+
 // SearchPage provides full-text search functionality
 type SearchPage struct {
 	app.Compo
@@ -16,6 +29,15 @@ type SearchPage struct {
 	loading      bool
 	error        string
 	searched     bool
+	completions  SearchCompletions
+	typed        int // input events so far, so only the last one in a pause fetches completions
+	highlighted  int // position, from 1, of the completion picked with the arrow keys; 0 for none
+
+	collectionName string
+	share          bool
+	saving         bool
+	saved          *Collection
+	saveError      string
 }
 
 // OnMount is called when the component is mounted
@@ -35,14 +57,22 @@ func (s *SearchPage) Render() app.UI {
 	var content app.UI
 
 	if s.loading {
-		content = app.Div().Class("loading").Body(app.Text("Searching..."))
+		content = statusMessage("loading", app.Text("Searching..."))
 	} else if s.error != "" {
-		content = app.Div().Class("error").Body(app.Text("Error: " + s.error))
+		content = alertMessage(app.Text("Error: " + s.error))
 	} else if s.searched && len(s.searchResult.FileSystem) == 0 {
-		content = app.Div().Class("no-results").Body(app.Text("No results found for: " + s.searchTerm))
+		content = statusMessage("no-results",
+			app.Text("No results found for: "+s.searchTerm),
+			s.renderSuggestions(),
+		)
 	} else if s.searched && len(s.searchResult.FileSystem) > 0 {
 		content = app.Div().Class("search-results").Body(
-			app.H3().Text(fmt.Sprintf("Found %d results", len(s.searchResult.FileSystem)-1)),
+			app.H3().Aria("live", "polite").Text(fmt.Sprintf("Found %d results", len(s.searchResult.FileSystem)-1)),
+			app.A().
+				Class("csv-download").
+				Href(BuildAPIURL("/api/search?format=csv&term="+url.QueryEscape(s.searchTerm))).
+				Text("Download as CSV"),
+			s.renderSaveCollection(),
 			app.Div().Class("result-list").Body(
 				app.Range(s.searchResult.FileSystem).Slice(func(i int) app.UI {
 					node := s.searchResult.FileSystem[i]
@@ -64,13 +94,42 @@ func (s *SearchPage) Render() app.UI {
 					Type("text").
 					Class("search-input").
 					Placeholder("Enter search term...").
+					Aria("label", "Search term").
+					Role("combobox").
+					Aria("autocomplete", "list").
+					Aria("controls", "search-completions").
+					Aria("expanded", s.completionCount() > 0).
+					Aria("activedescendant", completionID(s.highlighted)).
 					Value(s.searchTerm).
 					OnInput(func(ctx app.Context, e app.Event) {
 						s.searchTerm = ctx.JSSrc().Get("value").String()
+						s.highlighted = 0
+						s.typed++
+						typed := s.typed
+						ctx.After(suggestDelay, func(ctx app.Context) {
+							if typed == s.typed {
+								s.fetchCompletions(ctx)
+							}
+						})
 					}).
 					OnKeyDown(func(ctx app.Context, e app.Event) {
-						if e.Get("key").String() == "Enter" {
+						switch key := e.Get("key").String(); key {
+						case "ArrowDown", "ArrowUp":
+							if highlighted, ok := moveHighlight(s.highlighted, s.completionCount(), key); ok {
+								e.PreventDefault()
+								s.highlighted = highlighted
+							}
+						case "Enter":
+							if s.highlighted > 0 {
+								s.chooseCompletion(ctx, s.highlighted)
+								return
+							}
 							s.performSearch(ctx)
+						case "Escape":
+							s.completions = SearchCompletions{}
+							s.highlighted = 0
+							s.saved = nil
+							s.saveError = ""
 						}
 					}),
 				app.Button().
@@ -79,11 +138,238 @@ func (s *SearchPage) Render() app.UI {
 					OnClick(func(ctx app.Context, e app.Event) {
 						s.performSearch(ctx)
 					}),
+				s.renderCompletions(),
 			),
 			content,
 		)
 }
 
+// renderSuggestions offers the "did you mean" corrections from a search that found nothing as links that search again
+func (s *SearchPage) renderSuggestions() app.UI {
+	if len(s.searchResult.Suggestions) == 0 {
+		return nil
+	}
+	return app.P().Class("search-suggestions").Body(
+		app.Text("Did you mean: "),
+		app.Range(s.searchResult.Suggestions).Slice(func(i int) app.UI {
+			suggestion := s.searchResult.Suggestions[i]
+			return app.A().
+				Href("/search?term=" + url.QueryEscape(suggestion)).
+				Text(suggestion).
+				OnClick(func(ctx app.Context, e app.Event) {
+					e.PreventDefault()
+					s.searchTerm = suggestion
+					s.performSearch(ctx)
+				})
+		}),
+	)
+}
+
+// renderCompletions shows the dropdown under the search box: words complete the last word typed and
+// search, documents open directly. It is the listbox of the search box's combobox, so the arrow keys
+// move through it while focus stays in the box.
+func (s *SearchPage) renderCompletions() app.UI {
+	if s.completionCount() == 0 {
+		return nil
+	}
+	return app.Ul().Class("search-completions").ID("search-completions").Role("listbox").Aria("label", "Suggestions").Body(
+		app.Range(s.completions.Words).Slice(func(i int) app.UI {
+			word := s.completions.Words[i]
+			return s.completionOption(i+1, "completion-word").Text(word).
+				OnClick(func(ctx app.Context, e app.Event) {
+					s.chooseCompletion(ctx, i+1)
+				})
+		}),
+		app.Range(s.completions.Documents).Slice(func(i int) app.UI {
+			document := s.completions.Documents[i]
+			return s.completionOption(len(s.completions.Words)+i+1, "completion-document").Body(
+				app.A().Href(document.URL).Target("_blank").TabIndex(-1).Text("📄 "+document.Name),
+				app.Span().Class("completion-folder").Text(document.Folder),
+			)
+		}),
+	)
+}
+
+// completionOption is the list item for the completion at position, marked selected when highlighted
+func (s *SearchPage) completionOption(position int, class string) app.HTMLLi {
+	if position == s.highlighted {
+		class += " completion-highlighted"
+	}
+	return app.Li().
+		Class(class).
+		ID(completionID(position)).
+		Role("option").
+		Aria("selected", position == s.highlighted)
+}
+
+// completionCount is how many completions the dropdown offers, words first then documents
+func (s *SearchPage) completionCount() int {
+	return len(s.completions.Words) + len(s.completions.Documents)
+}
+
+// completionID is the element ID of the completion at position, or "" for none
+func completionID(position int) string {
+	if position <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("search-completion-%d", position)
+}
+
+// chooseCompletion acts on the completion at position: a word completes the search term and searches,
+// a document opens in a new tab
+func (s *SearchPage) chooseCompletion(ctx app.Context, position int) {
+	if position <= len(s.completions.Words) {
+		s.searchTerm = completeLastWord(s.searchTerm, s.completions.Words[position-1])
+		s.performSearch(ctx)
+		return
+	}
+	document := s.completions.Documents[position-len(s.completions.Words)-1]
+	s.completions = SearchCompletions{}
+	s.highlighted = 0
+	app.Window().Call("open", document.URL, "_blank")
+}
+
+// completeLastWord replaces the word being typed at the end of term with its completion
+func completeLastWord(term, word string) string {
+	cut := strings.LastIndexAny(term, " \t") + 1
+	return term[:cut] + word
+}
+
+// fetchCompletions asks the server for completions of the search term. Responses for a term that has
+// since changed are dropped.
+func (s *SearchPage) fetchCompletions(ctx app.Context) {
+	prefix := strings.TrimSpace(s.searchTerm)
+	if len([]rune(prefix)) < 2 {
+		s.completions = SearchCompletions{}
+		return
+	}
+
+	ctx.Async(func() {
+		suggestURL := BuildAPIURL("/api/search/suggest?prefix=" + url.QueryEscape(prefix))
+		app.Window().Call("fetch", suggestURL).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 || !args[0].Get("ok").Bool() {
+				return nil
+			}
+			args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+				if len(args) == 0 {
+					return nil
+				}
+				jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+
+				var completions SearchCompletions
+				ctx.Dispatch(func(ctx app.Context) {
+					if err := json.Unmarshal([]byte(jsonStr), &completions); err != nil {
+						return
+					}
+					if completions.Prefix == strings.TrimSpace(s.searchTerm) && !s.loading {
+						s.completions = completions
+						s.highlighted = 0
+					}
+				})
+				return nil
+			}))
+			return nil
+		}))
+	})
+}
+
+// renderSaveCollection offers to save the results as a named collection, and links to it once saved
+func (s *SearchPage) renderSaveCollection() app.UI {
+	if s.saved != nil {
+		links := []app.UI{
+			app.Text("Saved as "),
+			app.A().Href(collectionPageURL(*s.saved, false)).Text(s.saved.Name),
+		}
+		if s.saved.ShareToken != "" {
+			links = append(links, app.Text(" · share link: "), app.A().Href(collectionPageURL(*s.saved, true)).Text(collectionPageURL(*s.saved, true)))
+		}
+		return app.P().Class("save-collection").Body(links...)
+	}
+
+	var saveError app.UI
+	if s.saveError != "" {
+		saveError = app.Span().Class("error").Role("alert").Text(s.saveError)
+	}
+	return app.Div().Class("save-collection").Body(
+		app.Input().
+			Type("text").
+			Aria("label", "Collection name").
+			Placeholder("Collection name, e.g. 2023 tax bundle").
+			Value(s.collectionName).
+			OnInput(func(ctx app.Context, e app.Event) {
+				s.collectionName = ctx.JSSrc().Get("value").String()
+			}),
+		app.Label().Body(
+			app.Input().
+				Type("checkbox").
+				Checked(s.share).
+				OnChange(func(ctx app.Context, e app.Event) {
+					s.share = ctx.JSSrc().Get("checked").Bool()
+				}),
+			app.Text(" Share link"),
+		),
+		app.Button().
+			Text("Save as collection").
+			Disabled(s.saving || s.collectionName == "").
+			OnClick(func(ctx app.Context, e app.Event) {
+				s.saveCollection(ctx)
+			}),
+		saveError,
+	)
+}
+
+// saveCollection snapshots the results of the current search into a collection
+func (s *SearchPage) saveCollection(ctx app.Context) {
+	body, err := json.Marshal(map[string]any{"name": s.collectionName, "term": s.searchTerm, "share": s.share})
+	if err != nil {
+		s.saveError = err.Error()
+		return
+	}
+	s.saving = true
+	s.saveError = ""
+
+	ctx.Async(func() {
+		res := app.Window().Call("fetch", BuildAPIURL("/api/collections"), map[string]any{
+			"method":  "POST",
+			"headers": map[string]any{"Content-Type": "application/json"},
+			"body":    string(body),
+		})
+		res.Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+				if len(args) == 0 {
+					return nil
+				}
+				jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+
+				var view CollectionView
+				ctx.Dispatch(func(ctx app.Context) {
+					s.saving = false
+					if err := json.Unmarshal([]byte(jsonStr), &view); err != nil {
+						s.saveError = fmt.Sprintf("Failed to parse response: %v", err)
+						return
+					}
+					if view.Error != "" {
+						s.saveError = view.Error
+						return
+					}
+					s.saved = &view.Collection
+				})
+				return nil
+			}))
+			return nil
+		})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+			ctx.Dispatch(func(ctx app.Context) {
+				s.saving = false
+				s.saveError = "Network error"
+			})
+			return nil
+		}))
+	})
+}
+
 // performSearch executes the search
 func (s *SearchPage) performSearch(ctx app.Context) {
 	if s.searchTerm == "" {
@@ -94,6 +380,8 @@ func (s *SearchPage) performSearch(ctx app.Context) {
 	s.loading = true
 	s.error = ""
 	s.searched = false
+	s.completions = SearchCompletions{}
+	s.highlighted = 0
 
 	ctx.Async(func() {
 		encodedTerm := url.QueryEscape(s.searchTerm)
@@ -149,10 +437,161 @@ func (s *SearchPage) performSearch(ctx app.Context) {
 	})
 }
 
+// TimelineStage is one processing stage from /api/document/:id/timeline
+type TimelineStage struct {
+	Stage           string `json:"stage"`
+	Detail          string `json:"detail,omitempty"`
+	At              string `json:"at"`
+	SincePreviousMs int64  `json:"sincePreviousMs"`
+}
+
+// timelineStageNames are the labels shown for each processing stage
+var timelineStageNames = map[string]string{
+	"received":          "Received",
+	"rotated":           "Turned upright",
+	"blank_pages":       "Blank pages",
+	"stored":            "Stored",
+	"text_extracted":    "Text extracted",
+	"ocr":               "OCR",
+	"indexed":           "Indexed",
+	"wordcloud_updated": "Word cloud updated",
+	"hook":              "Post-ingestion hook",
+	"failed":            "Failed",
+}
+
+// formatStageDuration shows the time a stage took in the unit that suits it
+func formatStageDuration(ms int64) string {
+	switch {
+	case ms < 1000:
+		return fmt.Sprintf("%d ms", ms)
+	case ms < 60_000:
+		return fmt.Sprintf("%.1f s", float64(ms)/1000)
+	default:
+		return (time.Duration(ms) * time.Millisecond).Round(time.Second).String()
+	}
+}
+
 // SearchResultItem displays a single search result
 type SearchResultItem struct {
 	app.Compo
 	Node FileTreeNode
+
+	timeline      []TimelineStage
+	timelineOpen  bool
+	timelineError string
+
+	restoring    bool
+	restoreError string
+}
+
+// onRestoreClick brings an archived document back from cold storage so it can be opened
+func (s *SearchResultItem) onRestoreClick(ctx app.Context, e app.Event) {
+	e.PreventDefault()
+	s.restoring = true
+	s.restoreError = ""
+	url := BuildAPIURL("/api/document/" + s.Node.ULID + "/archive")
+	app.Window().Call("fetch", url, map[string]interface{}{
+		"method": "DELETE",
+	}).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+		if len(args) == 0 {
+			return nil
+		}
+		status := args[0].Get("status").Int()
+		ctx.Dispatch(func(ctx app.Context) {
+			s.restoring = false
+			if status < 200 || status >= 300 {
+				s.restoreError = fmt.Sprintf("Failed to restore (status %d)", status)
+				return
+			}
+			s.Node.Archived = false
+		})
+		return nil
+	})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+		ctx.Dispatch(func(ctx app.Context) {
+			s.restoring = false
+			s.restoreError = "Network error: Could not connect to server"
+		})
+		return nil
+	}))
+}
+
+// onTimelineClick shows or hides the document's processing timeline, loading it when first shown
+func (s *SearchResultItem) onTimelineClick(ctx app.Context, e app.Event) {
+	e.PreventDefault()
+	s.timelineOpen = !s.timelineOpen
+	if !s.timelineOpen || s.timeline != nil {
+		return
+	}
+	s.timelineError = ""
+	url := BuildAPIURL("/api/document/" + s.Node.ULID + "/timeline")
+	app.Window().Call("fetch", url).Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+		if len(args) == 0 {
+			return nil
+		}
+		status := args[0].Get("status").Int()
+		args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+			ctx.Dispatch(func(ctx app.Context) {
+				if status < 200 || status >= 300 {
+					s.timelineError = fmt.Sprintf("Failed to load timeline (status %d)", status)
+					return
+				}
+				var resp struct {
+					Stages []TimelineStage `json:"stages"`
+				}
+				if err := json.Unmarshal([]byte(jsonStr), &resp); err != nil {
+					s.timelineError = fmt.Sprintf("Failed to parse timeline: %v", err)
+					return
+				}
+				s.timeline = append([]TimelineStage{}, resp.Stages...)
+			})
+			return nil
+		}))
+		return nil
+	})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+		ctx.Dispatch(func(ctx app.Context) {
+			s.timelineError = "Network error: Could not connect to server"
+		})
+		return nil
+	}))
+}
+
+// renderTimeline lists the processing stages with the time each one took
+func (s *SearchResultItem) renderTimeline() app.UI {
+	if !s.timelineOpen {
+		return nil
+	}
+	switch {
+	case s.timelineError != "":
+		return app.P().Class("error").Text(s.timelineError)
+	case s.timeline == nil:
+		return app.P().Class("result-timeline").Text("Loading timeline...")
+	case len(s.timeline) == 0:
+		return app.P().Class("result-timeline").Text("No processing timeline was recorded for this document")
+	}
+	return app.Ol().Class("result-timeline").Body(
+		app.Range(s.timeline).Slice(func(i int) app.UI {
+			stage := s.timeline[i]
+			name := timelineStageNames[stage.Stage]
+			if name == "" {
+				name = stage.Stage
+			}
+			if stage.Detail != "" {
+				name += " (" + stage.Detail + ")"
+			}
+			took := ""
+			if i > 0 {
+				took = "+" + formatStageDuration(stage.SincePreviousMs)
+			}
+			return app.Li().Class("timeline-stage-"+stage.Stage).Body(
+				app.Span().Class("timeline-stage").Text(name),
+				app.Span().Class("timeline-took").Text(took),
+			)
+		}),
+	)
 }
 
 // Render renders the search result item
@@ -169,22 +608,75 @@ func (s *SearchResultItem) Render() app.UI {
 		sizeUI = app.P().Class("result-size").Text(fmt.Sprintf("Size: %s", formatBytes(s.Node.Size)))
 	}
 
+	if s.Node.Missing {
+		sizeUI = app.P().Class("result-missing").Text("File missing from document storage")
+	}
+
+	var archiveUI app.UI
+	if s.Node.Archived {
+		restoreText := "Restore"
+		if s.restoring {
+			restoreText = "Restoring..."
+		}
+		archiveUI = app.P().Class("result-archived").Body(
+			app.Text("Archived in cold storage, restore it to open it "),
+			app.Button().Class("result-restore").Disabled(s.restoring).Text(restoreText).OnClick(s.onRestoreClick),
+			app.If(s.restoreError != "", func() app.UI {
+				return app.Span().Class("error").Role("alert").Text(" " + s.restoreError)
+			}),
+		)
+	}
+
 	var dateUI app.UI
 	if s.Node.ModDate != "" {
 		dateUI = app.P().Class("result-date").Text(fmt.Sprintf("Modified: %s", s.Node.ModDate))
 	}
 
+	var coverSheetUI, timelineUI, previewUI app.UI
+	if s.Node.ULID != "" && isSpreadsheetName(s.Node.FullPath) {
+		previewUI = app.A().
+			Class("result-coversheet").
+			Href(BuildAPIURL("/api/document/" + s.Node.ULID + "/preview")).
+			Target("_blank").
+			Text("Preview spreadsheet")
+	}
+	if s.Node.ULID != "" {
+		coverSheetUI = app.A().
+			Class("result-coversheet").
+			Href(BuildAPIURL("/api/document/" + s.Node.ULID + "/coversheet.pdf")).
+			Target("_blank").
+			Text("Print cover sheet")
+		timelineUI = app.A().
+			Class("result-coversheet").
+			Href("#").
+			Role("button").
+			Aria("expanded", s.timelineOpen).
+			OnClick(s.onTimelineClick).
+			Text("Processing timeline")
+	}
+
 	return app.Div().
 		Class("search-result-item").
 		Body(
-			app.Div().Class("result-icon").Body(
+			app.Div().Class("result-icon").Aria("hidden", true).Body(
 				app.Text("📄"),
 			),
 			app.Div().Class("result-info").Body(
 				app.H4().Body(nameUI),
-				app.P().Class("result-path").Text(s.Node.FullPath),
+				&DocumentEditor{ULID: s.Node.ULID, FullPath: s.Node.FullPath},
 				sizeUI,
+				archiveUI,
 				dateUI,
+				previewUI,
+				coverSheetUI,
+				timelineUI,
+				s.renderTimeline(),
 			),
 		)
 }
+
+// isSpreadsheetName reports whether a document is a spreadsheet the server can preview
+func isSpreadsheetName(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".xlsx" || ext == ".csv"
+}
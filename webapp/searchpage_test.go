@@ -1,7 +1,10 @@
 package webapp
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
 )
 
 // TestSearchPageInitialState tests the initial state of the search page
@@ -73,6 +76,25 @@ func TestSearchPageRenderStates(t *testing.T) {
 		}
 	})
 
+	t.Run("No results state offers spelling suggestions", func(t *testing.T) {
+		page := &SearchPage{
+			searchTerm:   "invoce",
+			searched:     true,
+			searchResult: FileSystem{FileSystem: []FileTreeNode{}, Suggestions: []string{"invoice"}},
+		}
+
+		if page.renderSuggestions() == nil {
+			t.Error("Suggestions should be rendered when the search returned some")
+		}
+		if page.Render() == nil {
+			t.Error("No results state with suggestions should return non-nil UI")
+		}
+		page.searchResult.Suggestions = nil
+		if page.renderSuggestions() != nil {
+			t.Error("Nothing should be rendered without suggestions")
+		}
+	})
+
 	t.Run("Success state with results returns valid UI", func(t *testing.T) {
 		page := &SearchPage{
 			loading:    false,
@@ -107,6 +129,44 @@ func TestSearchPageRenderStates(t *testing.T) {
 	})
 }
 
+// TestSearchPageCompletions tests the autocomplete dropdown
+func TestSearchPageCompletions(t *testing.T) {
+	t.Run("Dropdown renders words and documents", func(t *testing.T) {
+		page := &SearchPage{
+			searchTerm: "boiler inv",
+			completions: SearchCompletions{
+				Prefix:    "boiler inv",
+				Words:     []string{"invoice", "inventory"},
+				Documents: []SuggestedDocument{{ID: "01ABCDEFGHIJKLMNOPQRSTUVWX", Name: "Invoice.pdf", Folder: "/docs", URL: "/document/view/01ABCDEFGHIJKLMNOPQRSTUVWX"}},
+			},
+		}
+
+		if page.renderCompletions() == nil {
+			t.Error("Completions should be rendered when there are some")
+		}
+		if page.Render() == nil {
+			t.Error("Search page with completions should return non-nil UI")
+		}
+		page.completions = SearchCompletions{}
+		if page.renderCompletions() != nil {
+			t.Error("Nothing should be rendered without completions")
+		}
+	})
+
+	t.Run("Choosing a word replaces the word being typed", func(t *testing.T) {
+		tests := []struct{ term, word, expected string }{
+			{"inv", "invoice", "invoice"},
+			{"boiler inv", "invoice", "boiler invoice"},
+			{"boiler ", "invoice", "boiler invoice"},
+		}
+		for _, tt := range tests {
+			if got := completeLastWord(tt.term, tt.word); got != tt.expected {
+				t.Errorf("completeLastWord(%q, %q) = %q, want %q", tt.term, tt.word, got, tt.expected)
+			}
+		}
+	})
+}
+
 // TestSearchPageStateManagement tests state transitions
 func TestSearchPageStateManagement(t *testing.T) {
 	t.Run("Loading state should have correct flags", func(t *testing.T) {
@@ -227,6 +287,22 @@ func TestSearchResultItemRender(t *testing.T) {
 			t.Error("Should return non-nil UI with minimal data")
 		}
 	})
+
+	t.Run("Render a spreadsheet with a preview link", func(t *testing.T) {
+		item := &SearchResultItem{
+			Node: FileTreeNode{
+				ID:       "doc4",
+				ULID:     "01ABCDEFGHIJKLMNOPQRSTUVWX",
+				Name:     "Statement.xlsx",
+				FullPath: "/documents/Bank/Statement.xlsx",
+			},
+		}
+
+		html := app.HTMLString(item.Render())
+		if !strings.Contains(html, "/api/document/01ABCDEFGHIJKLMNOPQRSTUVWX/preview") {
+			t.Errorf("Expected a preview link for a spreadsheet, got %s", html)
+		}
+	})
 }
 
 // TestFileSystemStruct tests the FileSystem data structure
@@ -606,3 +682,19 @@ func TestFormatBytes(t *testing.T) {
 		})
 	}
 }
+
+// TestFormatStageDuration tests the durations shown on a processing timeline
+func TestFormatStageDuration(t *testing.T) {
+	tests := map[int64]string{
+		0:       "0 ms",
+		999:     "999 ms",
+		1500:    "1.5 s",
+		59_900:  "59.9 s",
+		125_400: "2m5s",
+	}
+	for ms, want := range tests {
+		if got := formatStageDuration(ms); got != want {
+			t.Errorf("formatStageDuration(%d) = %q, want %q", ms, got, want)
+		}
+	}
+}
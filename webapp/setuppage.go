@@ -0,0 +1,337 @@
+package webapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/drummonds/godocs/internal/dto"
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// SetupDatabase is the database step of the first-run setup wizard
+type SetupDatabase struct {
+	Type     string `json:"type"`
+	Host     string `json:"host,omitempty"`
+	Port     string `json:"port,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+	Name     string `json:"name,omitempty"`
+	SSLMode  string `json:"sslmode,omitempty"`
+}
+
+// SetupRequest holds every answer sent to POST /api/setup
+type SetupRequest struct {
+	Database      SetupDatabase `json:"database"`
+	DocumentPath  string        `json:"documentPath"`
+	IngressPath   string        `json:"ingressPath"`
+	TesseractPath string        `json:"tesseractPath,omitempty"`
+	Extensions    string        `json:"extensions,omitempty"`
+	AdminUser     string        `json:"adminUser"`
+	AdminPassword string        `json:"adminPassword,omitempty"`
+}
+
+// SetupStatus is returned by GET /api/setup
+type SetupStatus struct {
+	Required      bool         `json:"required"`
+	ConfigFile    string       `json:"configFile"`
+	DatabaseTypes []string     `json:"databaseTypes"`
+	Defaults      SetupRequest `json:"defaults"`
+	OCR           struct {
+		TesseractPath string `json:"tesseractPath"`
+		Available     bool   `json:"available"`
+		Version       string `json:"version"`
+		ServiceURL    string `json:"serviceURL"`
+	} `json:"ocr"`
+}
+
+// setupSteps names the wizard steps in order
+var setupSteps = []string{"Database", "Folders", "OCR", "Admin account", "Review"}
+
+// setupStepFields are the fields checked before leaving each step
+var setupStepFields = [][]string{
+	{"database.type", "database.host", "database.port", "database.name"},
+	{"documentPath", "ingressPath", "extensions"},
+	{"tesseractPath"},
+	{"adminUser", "adminPassword"},
+}
+
+// SetupPage walks through configuring a new installation
+type SetupPage struct {
+	app.Compo
+	status   SetupStatus
+	form     SetupRequest
+	step     int
+	loading  bool
+	busy     bool
+	error    string
+	fields   map[string]string
+	savedMsg string
+}
+
+// OnMount loads the setup status and suggested answers
+func (s *SetupPage) OnMount(ctx app.Context) {
+	s.loading = true
+	ctx.Async(func() {
+		res := app.Window().Call("fetch", BuildAPIURL("/api/setup"))
+		res.Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+				if len(args) == 0 {
+					return nil
+				}
+				jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+				ctx.Dispatch(func(ctx app.Context) {
+					s.loading = false
+					if err := json.Unmarshal([]byte(jsonStr), &s.status); err != nil {
+						s.error = fmt.Sprintf("Failed to parse response: %v", err)
+						return
+					}
+					s.form = s.status.Defaults
+				})
+				return nil
+			}))
+			return nil
+		})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+			ctx.Dispatch(func(ctx app.Context) {
+				s.loading = false
+				s.error = "Network error"
+			})
+			return nil
+		}))
+	})
+}
+
+// Render renders the setup wizard
+func (s *SetupPage) Render() app.UI {
+	page := app.Div().Class("setup-page")
+	switch {
+	case s.loading:
+		return page.Body(app.H2().Text("Set up godocs"), app.Div().Class("loading").Text("Loading..."))
+	case s.savedMsg != "":
+		return page.Body(
+			app.H2().Text("Set up godocs"),
+			app.Div().Class("success").Body(
+				app.P().Text(s.savedMsg),
+				app.P().Text("Settings were written to "+s.status.ConfigFile+"."),
+			),
+		)
+	case !s.status.Required && s.error == "":
+		return page.Body(
+			app.H2().Text("Set up godocs"),
+			app.P().Text("godocs is already configured. Edit "+s.status.ConfigFile+" or the environment to change settings."),
+		)
+	}
+
+	return page.Body(
+		app.H2().Text("Set up godocs"),
+		app.Ol().Class("setup-steps").Body(
+			app.Range(setupSteps).Slice(func(i int) app.UI {
+				return app.Li().Class(map[bool]string{true: "active", false: ""}[i == s.step]).Text(setupSteps[i])
+			}),
+		),
+		app.If(s.error != "", func() app.UI {
+			return app.Div().Class("error").Text(s.error)
+		}),
+		app.Div().Class("setup-step").Body(s.renderStep()),
+		app.Div().Class("setup-buttons").Body(
+			app.If(s.step > 0, func() app.UI {
+				return app.Button().Class("btn-secondary").Disabled(s.busy).OnClick(s.onBack).Text("Back")
+			}),
+			app.If(s.step < len(setupSteps)-1, func() app.UI {
+				return app.Button().Class("btn-primary").Disabled(s.busy).OnClick(s.onNext).Text("Next")
+			}).Else(func() app.UI {
+				return app.Button().Class("btn-primary").Disabled(s.busy).OnClick(s.onSave).Text("Save settings")
+			}),
+		),
+	)
+}
+
+// renderStep renders the inputs for the current step
+func (s *SetupPage) renderStep() app.UI {
+	db := &s.form.Database
+	switch s.step {
+	case 0:
+		inputs := []app.UI{
+			app.Label().For("setup-db-type").Text("Database type"),
+			app.Select().ID("setup-db-type").OnChange(s.bind(&db.Type)).Body(
+				app.Range(s.status.DatabaseTypes).Slice(func(i int) app.UI {
+					dbType := s.status.DatabaseTypes[i]
+					return app.Option().Value(dbType).Selected(dbType == db.Type).Text(dbType)
+				}),
+			),
+			s.fieldError("database.type"),
+		}
+		switch db.Type {
+		case "postgres", "cockroachdb":
+			inputs = append(inputs,
+				s.textInput("Host", "database.host", &db.Host, "text"),
+				s.textInput("Port", "database.port", &db.Port, "text"),
+				s.textInput("User", "database.user", &db.User, "text"),
+				s.textInput("Password", "database.password", &db.Password, "password"),
+				s.textInput("Database name", "database.name", &db.Name, "text"),
+				s.textInput("SSL mode", "database.sslmode", &db.SSLMode, "text"),
+			)
+		case "sqlite":
+			inputs = append(inputs, s.textInput("Database file", "database.name", &db.Name, "text"))
+		case "ephemeral", "memory":
+			inputs = append(inputs, app.P().Class("setup-hint").Text("Data is lost when godocs stops; use this for trying godocs out."))
+		}
+		return app.Div().Body(inputs...)
+	case 1:
+		return app.Div().Body(
+			app.P().Class("setup-hint").Text("Folders are created if they do not exist. Files dropped into the ingress folder are processed and moved into the document folder."),
+			s.textInput("Document folder", "documentPath", &s.form.DocumentPath, "text"),
+			s.textInput("Ingress folder", "ingressPath", &s.form.IngressPath, "text"),
+			s.textInput("File types to ingest", "extensions", &s.form.Extensions, "text"),
+		)
+	case 2:
+		ocr := s.status.OCR
+		found := "Tesseract was not found; scanned documents will be stored without text until it is installed."
+		if ocr.Available {
+			found = "Found " + ocr.Version + " at " + ocr.TesseractPath + "."
+		}
+		return app.Div().Body(
+			app.P().Text(found),
+			app.If(ocr.ServiceURL != "", func() app.UI {
+				return app.P().Text("Tesseract sidecar service: " + ocr.ServiceURL)
+			}),
+			s.textInput("Tesseract path (optional)", "tesseractPath", &s.form.TesseractPath, "text"),
+		)
+	case 3:
+		return app.Div().Body(
+			app.P().Class("setup-hint").Text("The web interface will ask for this account."),
+			s.textInput("Username", "adminUser", &s.form.AdminUser, "text"),
+			s.textInput("Password", "adminPassword", &s.form.AdminPassword, "password"),
+		)
+	default:
+		rows := [][2]string{
+			{"Database", db.Type + " " + strings.TrimSpace(db.Host+" "+db.Name)},
+			{"Document folder", s.form.DocumentPath},
+			{"Ingress folder", s.form.IngressPath},
+			{"File types", s.form.Extensions},
+			{"Tesseract", s.form.TesseractPath},
+			{"Admin user", s.form.AdminUser},
+		}
+		return app.Div().Body(
+			app.Dl().Class("setup-review").Body(
+				app.Range(rows).Slice(func(i int) app.UI {
+					return app.Div().Body(app.Dt().Text(rows[i][0]), app.Dd().Text(rows[i][1]))
+				}),
+			),
+			app.P().Class("setup-hint").Text("Settings are saved to "+s.status.ConfigFile+". Restart godocs afterwards to use them."),
+		)
+	}
+}
+
+// textInput renders a labelled input bound to value, with any validation message for field
+func (s *SetupPage) textInput(label string, field string, value *string, inputType string) app.UI {
+	id := "setup-" + strings.ReplaceAll(field, ".", "-")
+	return app.Div().Class("setup-field").Body(
+		app.Label().For(id).Text(label),
+		app.Input().ID(id).Type(inputType).Value(*value).OnChange(s.bind(value)),
+		s.fieldError(field),
+	)
+}
+
+// fieldError renders the validation message for field, if there is one
+func (s *SetupPage) fieldError(field string) app.UI {
+	if msg := s.fields[field]; msg != "" {
+		return app.Div().Class("field-error").Text(msg)
+	}
+	return app.Text("")
+}
+
+// bind returns a change handler that copies the input value into target
+func (s *SetupPage) bind(target *string) app.EventHandler {
+	return func(ctx app.Context, e app.Event) {
+		*target = ctx.JSSrc().Get("value").String()
+	}
+}
+
+// onBack returns to the previous step
+func (s *SetupPage) onBack(ctx app.Context, e app.Event) {
+	s.step--
+	s.error = ""
+}
+
+// onNext validates the answers so far and moves on if the current step has no problems
+func (s *SetupPage) onNext(ctx app.Context, e app.Event) {
+	s.post(ctx, true, func(fields map[string]string) {
+		for _, field := range setupStepFields[s.step] {
+			if fields[field] != "" {
+				return
+			}
+		}
+		s.step++
+	})
+}
+
+// onSave writes the settings
+func (s *SetupPage) onSave(ctx app.Context, e app.Event) {
+	s.post(ctx, false, nil)
+}
+
+// post sends the answers to /api/setup, as a dry run when validating a step.
+// afterValidate is called with the field problems found during a dry run.
+func (s *SetupPage) post(ctx app.Context, dryRun bool, afterValidate func(fields map[string]string)) {
+	body, err := json.Marshal(s.form)
+	if err != nil {
+		s.error = err.Error()
+		return
+	}
+	s.busy = true
+	s.error = ""
+	target := "/api/setup"
+	if dryRun {
+		target += "?dryRun=true"
+	}
+	ctx.Async(func() {
+		res := app.Window().Call("fetch", BuildAPIURL(target), map[string]any{
+			"method":  "POST",
+			"headers": map[string]any{"Content-Type": "application/json"},
+			"body":    string(body),
+		})
+		res.Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			status := args[0].Get("status").Int()
+			args[0].Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+				if len(args) == 0 {
+					return nil
+				}
+				jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+				var result struct {
+					dto.ErrorResponse
+					Message string `json:"message"`
+				}
+				json.Unmarshal([]byte(jsonStr), &result)
+				ctx.Dispatch(func(ctx app.Context) {
+					s.busy = false
+					s.fields = result.Fields
+					switch {
+					case result.Code == dto.CodeValidation && dryRun:
+						afterValidate(result.Fields)
+					case status >= 300:
+						s.error = result.Error
+					case dryRun:
+						afterValidate(nil)
+					default:
+						s.savedMsg = result.Message
+					}
+				})
+				return nil
+			}))
+			return nil
+		})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+			ctx.Dispatch(func(ctx app.Context) {
+				s.busy = false
+				s.error = "Network error: Could not connect to server"
+			})
+			return nil
+		}))
+	})
+}
@@ -4,20 +4,57 @@ import (
 	"github.com/maxence-charriere/go-app/v10/pkg/app"
 )
 
-// Sidebar is the left sidebar menu component
+// sidebarState is the app state holding whether the sidebar is open, shared by the hamburger menu
+// and the sidebar so toggling it needs no page reload
+const sidebarState = "sidebarOpen"
+
+// narrowScreen is the media query for phone-sized screens, matching the breakpoint in webapp.css
+const narrowScreen = "(max-width: 768px)"
+
+// navItem is an entry in the sidebar or the bottom navigation bar
+type navItem struct {
+	icon, label, href string
+}
+
+// sidebarItems are every page of the app, in menu order
+var sidebarItems = []navItem{
+	{"🏠", "Home", "/"},
+	{"📁", "Browse Documents", "/browse"},
+	{"📥", "Ingest Now", "/ingest"},
+	{"🧹", "Clean Database", "/clean"},
+	{"🔍", "Search", "/search"},
+	{"📷", "Scan QR Code", "/scan"},
+	{"📸", "Scan with Camera", "/capture"},
+	{"⚙️", "Jobs", "/jobs"},
+	{"📊", "Word Cloud", "/wordcloud"},
+	{"📈", "Statistics", "/stats"},
+	{"ℹ️", "About", "/about"},
+}
+
+// Sidebar is the left sidebar menu component. On a phone it slides over the page, with a backdrop
+// that closes it, and closes itself once a page is chosen.
 type Sidebar struct {
 	app.Compo
 	isOpen bool
+	path   string
 }
 
 // OnMount is called when the component is mounted
 func (s *Sidebar) OnMount(ctx app.Context) {
-	s.isOpen = s.getSidebarState(ctx)
+	s.path = ctx.Page().URL().Path
+	ctx.ObserveState(sidebarState, &s.isOpen)
+	if s.isOpen && isNarrowScreen() {
+		setSidebarOpen(ctx, false)
+	}
 }
 
 // OnNav is called when navigation occurs
 func (s *Sidebar) OnNav(ctx app.Context) {
-	s.isOpen = s.getSidebarState(ctx)
+	s.path = ctx.Page().URL().Path
+	// A phone has no room for the menu beside the page just chosen
+	if s.isOpen && isNarrowScreen() {
+		setSidebarOpen(ctx, false)
+	}
 }
 
 // Render renders the sidebar
@@ -27,45 +64,73 @@ func (s *Sidebar) Render() app.UI {
 		class += " sidebar-open"
 	}
 
-	return app.Aside().
-		Class(class).
+	items := make([]app.UI, len(sidebarItems))
+	for i, item := range sidebarItems {
+		items[i] = s.renderNavItem(item)
+	}
+
+	return app.Div().
+		Class("sidebar-container").
 		Body(
-			app.Div().Class("sidebar-header").Body(
-				app.H2().Text("Menu"),
-			),
-			app.Nav().Class("sidebar-nav").Body(
-				s.renderNavItem("🏠", "Home", "/"),
-				s.renderNavItem("📁", "Browse Documents", "/browse"),
-				s.renderNavItem("📥", "Ingest Now", "/ingest"),
-				s.renderNavItem("🧹", "Clean Database", "/clean"),
-				s.renderNavItem("🔍", "Search", "/search"),
-				s.renderNavItem("⚙️", "Jobs", "/jobs"),
-				s.renderNavItem("📊", "Word Cloud", "/wordcloud"),
-				s.renderNavItem("ℹ️", "About", "/about"),
-			),
+			app.If(s.isOpen, func() app.UI {
+				return app.Div().
+					Class("sidebar-backdrop").
+					Aria("hidden", true).
+					OnClick(func(ctx app.Context, e app.Event) {
+						setSidebarOpen(ctx, false)
+					})
+			}),
+			app.Aside().
+				Class(class).
+				ID("sidebar").
+				Aria("label", "Menu").
+				OnKeyDown(func(ctx app.Context, e app.Event) {
+					if e.Get("key").String() == "Escape" {
+						setSidebarOpen(ctx, false)
+						focusElement(ctx, "menu-toggle")
+					}
+				}).
+				Body(
+					app.Div().Class("sidebar-header").Body(
+						app.H2().Text("Menu"),
+						app.Button().
+							Class("sidebar-close").
+							Aria("label", "Close menu").
+							Text("✕").
+							OnClick(func(ctx app.Context, e app.Event) {
+								setSidebarOpen(ctx, false)
+							}),
+					),
+					app.Nav().Class("sidebar-nav").Body(items...),
+				),
 		)
 }
 
 // renderNavItem creates a navigation item
-func (s *Sidebar) renderNavItem(icon, label, href string) app.UI {
-	currentPath := app.Window().URL().Path
+func (s *Sidebar) renderNavItem(item navItem) app.UI {
 	class := "sidebar-item"
-	if currentPath == href {
+	current := ""
+	if s.path == item.href {
 		class += " sidebar-item-active"
+		current = "page"
 	}
 
 	return app.A().
-		Href(href).
+		Href(item.href).
 		Class(class).
+		Aria("current", current).
 		Body(
-			app.Span().Class("sidebar-icon").Text(icon),
-			app.Span().Class("sidebar-label").Text(label),
+			app.Span().Class("sidebar-icon").Aria("hidden", true).Text(item.icon),
+			app.Span().Class("sidebar-label").Text(item.label),
 		)
 }
 
-// getSidebarState retrieves the sidebar open/closed state from local storage
-func (s *Sidebar) getSidebarState(ctx app.Context) bool {
-	var isOpen bool
-	ctx.LocalStorage().Get("sidebar-open", &isOpen)
-	return isOpen
+// setSidebarOpen opens or closes the sidebar, remembering the choice for the next visit
+func setSidebarOpen(ctx app.Context, open bool) {
+	ctx.SetState(sidebarState, open).Persist()
+}
+
+// isNarrowScreen reports whether the app is showing on a phone-sized screen
+func isNarrowScreen() bool {
+	return app.Window().Call("matchMedia", narrowScreen).Get("matches").Bool()
 }
@@ -0,0 +1,173 @@
+package webapp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// StatsBucket is one period/group entry from the stats timeseries API
+type StatsBucket struct {
+	Period    string `json:"period"`
+	Group     string `json:"group"`
+	Count     int    `json:"count"`
+	TotalSize int64  `json:"totalSize"`
+}
+
+// StatsResponse represents the stats timeseries API response
+type StatsResponse struct {
+	GroupBy  string        `json:"groupBy"`
+	Interval string        `json:"interval"`
+	Buckets  []StatsBucket `json:"buckets"`
+}
+
+// StatsPage charts how many documents were ingested over time
+type StatsPage struct {
+	app.Compo
+	buckets  []StatsBucket
+	groupBy  string
+	interval string
+	loading  bool
+	error    string
+}
+
+// OnMount is called when the component is mounted
+func (s *StatsPage) OnMount(ctx app.Context) {
+	s.groupBy = "none"
+	s.interval = "month"
+	s.loadStats(ctx)
+}
+
+// Render renders the stats page
+func (s *StatsPage) Render() app.UI {
+	return app.Div().
+		Class("stats-page").
+		Body(
+			app.H2().Text("Document Statistics"),
+			app.P().Text("Number of documents ingested over time, optionally split by folder."),
+
+			app.Div().Class("stats-controls").Body(
+				app.Label().For("stats-interval").Text("Interval: "),
+				app.Select().
+					ID("stats-interval").
+					OnChange(s.onIntervalChange).
+					Body(
+						app.Option().Value("day").Selected(s.interval == "day").Text("Day"),
+						app.Option().Value("week").Selected(s.interval == "week").Text("Week"),
+						app.Option().Value("month").Selected(s.interval == "month").Text("Month"),
+						app.Option().Value("year").Selected(s.interval == "year").Text("Year"),
+					),
+				app.Label().For("stats-group").Text(" Group by: "),
+				app.Select().
+					ID("stats-group").
+					OnChange(s.onGroupByChange).
+					Body(
+						app.Option().Value("none").Selected(s.groupBy == "none").Text("Nothing"),
+						app.Option().Value("folder").Selected(s.groupBy == "folder").Text("Folder"),
+						app.Option().Value("tag").Selected(s.groupBy == "tag").Text("Tag"),
+						app.Option().Value("correspondent").Selected(s.groupBy == "correspondent").Text("Correspondent"),
+					),
+			),
+
+			s.renderChart(),
+		)
+}
+
+// renderChart renders the buckets as horizontal bars scaled to the largest count
+func (s *StatsPage) renderChart() app.UI {
+	if s.loading {
+		return app.Div().Class("loading").Text("Loading statistics...")
+	}
+	if s.error != "" {
+		return app.Div().Class("error").Text("Error: " + s.error)
+	}
+	if len(s.buckets) == 0 {
+		return app.Div().Class("no-results").Text("No documents in this range.")
+	}
+
+	maxCount := 0
+	for _, b := range s.buckets {
+		if b.Count > maxCount {
+			maxCount = b.Count
+		}
+	}
+
+	rows := make([]app.UI, 0, len(s.buckets))
+	for _, b := range s.buckets {
+		label := b.Period
+		if b.Group != "" {
+			label = b.Period + " · " + b.Group
+		}
+		width := float64(b.Count) / float64(maxCount) * 100
+		rows = append(rows, app.Div().Class("stats-row").Body(
+			app.Div().Class("stats-label").Text(label),
+			app.Div().Class("stats-bar-track").Body(
+				app.Div().Class("stats-bar").Style("width", fmt.Sprintf("%.1f%%", width)),
+			),
+			app.Div().Class("stats-value").Text(fmt.Sprintf("%d (%s)", b.Count, formatBytes(b.TotalSize))),
+		))
+	}
+	return app.Div().Class("stats-chart").Body(rows...)
+}
+
+// onIntervalChange reloads the stats for the selected interval
+func (s *StatsPage) onIntervalChange(ctx app.Context, e app.Event) {
+	s.interval = ctx.JSSrc().Get("value").String()
+	s.loadStats(ctx)
+}
+
+// onGroupByChange reloads the stats for the selected grouping
+func (s *StatsPage) onGroupByChange(ctx app.Context, e app.Event) {
+	s.groupBy = ctx.JSSrc().Get("value").String()
+	s.loadStats(ctx)
+}
+
+// loadStats fetches the timeseries from the API
+func (s *StatsPage) loadStats(ctx app.Context) {
+	s.loading = true
+	s.error = ""
+	groupBy, interval := s.groupBy, s.interval
+
+	ctx.Async(func() {
+		url := BuildAPIURL(fmt.Sprintf("/api/stats/timeseries?groupBy=%s&interval=%s", groupBy, interval))
+		res := app.Window().Call("fetch", url)
+
+		res.Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+			if len(args) == 0 {
+				return nil
+			}
+			response := args[0]
+			status := response.Get("status").Int()
+
+			response.Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) any {
+				if len(args) == 0 {
+					return nil
+				}
+				jsonStr := app.Window().Get("JSON").Call("stringify", args[0]).String()
+
+				ctx.Dispatch(func(ctx app.Context) {
+					s.loading = false
+					if status < 200 || status >= 300 {
+						s.error = fmt.Sprintf("Failed to load statistics (status %d)", status)
+						return
+					}
+					var resp StatsResponse
+					if err := json.Unmarshal([]byte(jsonStr), &resp); err != nil {
+						s.error = fmt.Sprintf("Failed to parse response: %v", err)
+						return
+					}
+					s.buckets = resp.Buckets
+				})
+				return nil
+			}))
+			return nil
+		})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) any {
+			ctx.Dispatch(func(ctx app.Context) {
+				s.loading = false
+				s.error = "Network error: Could not connect to server"
+			})
+			return nil
+		}))
+	})
+}
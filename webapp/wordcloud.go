@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"net/url"
 	"sort"
 	"time"
 
@@ -58,13 +59,13 @@ func (w *WordCloudPage) Render() app.UI {
 			),
 
 			app.If(w.loading, func() app.UI {
-				return app.Div().Class("loading").Body(
+				return statusMessage("loading",
 					app.P().Text("Loading word cloud..."),
 				)
 			}),
 
 			app.If(!w.loading && w.error != "", func() app.UI {
-				return app.Div().Class("error").Body(
+				return alertMessage(
 					app.P().Text("Error: "+w.error),
 					app.Button().
 						Class("retry-button").
@@ -151,23 +152,24 @@ func (w *WordCloudPage) renderWordCloud() app.UI {
 		fontSize := w.calculateFontSize(word.Frequency, minFreq, maxFreq)
 		color := w.getWordColor(i, len(w.words))
 
-		wordElements[i] = app.Span().
+		// Links rather than clickable spans, so each word can be reached and followed from the keyboard
+		wordElements[i] = app.A().
 			Class("word-cloud-item").
+			Href("/search?term=" + url.QueryEscape(word.Word)).
 			Style("font-size", fmt.Sprintf("%.1fpx", fontSize)).
 			Style("color", color).
 			Style("margin", "5px 10px").
 			Style("display", "inline-block").
 			Style("cursor", "pointer").
 			Title(fmt.Sprintf("%s: %d occurrences", word.Word, word.Frequency)).
-			Text(word.Word).
-			OnClick(func(ctx app.Context, e app.Event) {
-				// Navigate to search page with this word
-				ctx.Navigate("/search?term=" + word.Word)
-			})
+			Aria("label", fmt.Sprintf("%s, %d occurrences", word.Word, word.Frequency)).
+			Text(word.Word)
 	}
 
 	return app.Div().
 		Class("word-cloud-words").
+		Role("group").
+		Aria("label", "Most frequent words, largest first").
 		Style("text-align", "center").
 		Style("line-height", "2").
 		Body(wordElements...)